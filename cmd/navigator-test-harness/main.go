@@ -0,0 +1,70 @@
+// navigator-test-harness runs navigator against a configuration with its
+// durations scaled down and its tenants served by the built-in echo
+// backend. Lifecycle events are written to stdout as JSON lines; commands
+// read from stdin, one per line, drive it: "reload" re-reads the
+// configuration and has navigator reload it, and "stop" shuts it down.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rubys/navigator/internal/harness"
+)
+
+func main() {
+	configFile := flag.String("config", "config/navigator.yml", "Configuration file to run")
+	scale := flag.Float64("scale", 0.01, "Factor applied to every duration in the configuration")
+	binary := flag.String("navigator", "", "Navigator binary (default: $NAVIGATOR_BINARY, else navigator on the PATH)")
+	flag.Parse()
+
+	h, err := harness.Start(harness.Options{
+		Binary: *binary,
+		Config: *configFile,
+		Scale:  *scale,
+		Output: os.Stderr,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "navigator-test-harness: %v\n", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			switch command := strings.TrimSpace(scanner.Text()); command {
+			case "":
+			case "reload":
+				if err := h.Reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "navigator-test-harness: reload: %v\n", err)
+				}
+			case "stop":
+				_ = h.Stop()
+				return
+			default:
+				fmt.Fprintf(os.Stderr, "navigator-test-harness: unknown command %q (use reload or stop)\n", command)
+			}
+		}
+		// Closing stdin stops navigator too
+		_ = h.Stop()
+	}()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for event := range h.Events {
+		if err := encoder.Encode(event); err != nil {
+			_ = h.Stop()
+			os.Exit(1)
+		}
+		if event.Type == harness.Exited {
+			_ = h.Stop() // Waits for a stop in progress to clean up
+			if event.Details["error"] != nil {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}
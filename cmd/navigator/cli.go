@@ -0,0 +1,554 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultConfigFile is the configuration commands use when none is given
+const defaultConfigFile = "config/navigator.yml"
+
+// cli is where commands read and write, and what the command line asked
+// main to serve
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	serve      bool   // Run the server
+	configFile string // Configuration to serve
+}
+
+// action runs a command with its arguments left after its flags
+type action func(c *cli, args []string) error
+
+// command is a navigator subcommand. A command either has subcommands or
+// defines its flags with setup, which returns the action they configure.
+type command struct {
+	name        string
+	args        string   // Positional arguments, for usage, e.g. "<url> [config-file]"
+	summary     string   // One line, for the command list
+	words       []string // Values completed for its first argument, e.g. shell names
+	setup       func(flags *flag.FlagSet) action
+	subcommands []*command
+}
+
+// usageError is a command line navigator can't make sense of; it exits
+// with status 2 rather than 1
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// usagef returns a usageError
+func usagef(format string, args ...interface{}) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// globalFlags may appear anywhere on the command line and apply to every
+// configuration loaded, including reloads; stripConfigFlags handles them
+var globalFlags = []struct{ name, usage string }{
+	{"strict-config", "reject unknown config keys (also strict: true in the file)"},
+	{"fail-on-deprecated", "reject legacy config keys instead of migrating them"},
+}
+
+// legacyArgs are the forms the first argument took before subcommands,
+// and the command each is now
+var legacyArgs = map[string]string{
+	"--dry-run":    "dry-run",
+	"--smoke-test": "smoke-test",
+	"--validate":   "validate",
+	"--check":      "validate",
+	"--help":       "help",
+	"-h":           "help",
+	"--version":    "version",
+	"-v":           "version",
+}
+
+// commandTree returns navigator's commands
+func commandTree() []*command {
+	return []*command{
+		{
+			name:    "serve",
+			args:    "[config-file]",
+			summary: "Run the server (the default; a bare config file also serves it)",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					file, err := optionalConfigFile("serve", args)
+					c.serve, c.configFile = err == nil, file
+					return err
+				}
+			},
+		},
+		{
+			name:    "reload",
+			args:    "[config-file]",
+			summary: "Reload the configuration of a running server",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					file, err := optionalConfigFile("reload", args)
+					if err != nil {
+						return err
+					}
+					return utils.SendReloadSignal(reloadPIDFile(file))
+				}
+			},
+		},
+		{
+			name:    "validate",
+			args:    "[config-file]",
+			summary: "Check a configuration and its htpasswd file without running anything",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					file, err := optionalConfigFile("validate", args)
+					if err != nil {
+						return err
+					}
+					return validateConfig(c.stdout, file)
+				}
+			},
+		},
+		{
+			name:    "explain",
+			args:    "<url> [config-file]",
+			summary: "Show how a URL would be routed, as JSON",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					if len(args) == 0 || len(args) > 2 {
+						return usagef("explain requires a URL and at most a config file")
+					}
+					file, _ := optionalConfigFile("explain", args[1:])
+					return explainURL(c.stdout, args[0], file)
+				}
+			},
+		},
+		{
+			name:    "dry-run",
+			args:    "[config-file]",
+			summary: "Show commands, hooks, and routes as JSON without running them",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					file, err := optionalConfigFile("dry-run", args)
+					if err != nil {
+						return err
+					}
+					return dryRun(c.stdout, file)
+				}
+			},
+		},
+		{
+			name:    "smoke-test",
+			args:    "[config-file]",
+			summary: "Start every tenant once, request its root path, and report as JSON",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					file, err := optionalConfigFile("smoke-test", args)
+					if err != nil {
+						return err
+					}
+					return smokeTest(c.stdout, file)
+				}
+			},
+		},
+		{
+			name:    "replay",
+			summary: "Replay an access log in-process and report routes and latency",
+			setup:   replayCommand,
+		},
+		{
+			name:    "config",
+			summary: "Work with configuration files",
+			subcommands: []*command{
+				{
+					name:    "schema",
+					summary: "Write the JSON Schema for the config file",
+					setup: func(flags *flag.FlagSet) action {
+						return func(c *cli, args []string) error {
+							if len(args) > 0 {
+								return usagef("config schema takes no arguments")
+							}
+							return writeConfigSchema(c.stdout)
+						}
+					},
+				},
+				{
+					name:    "migrate",
+					args:    "<config-file>",
+					summary: "Write the config rewritten to the current schema",
+					setup: func(flags *flag.FlagSet) action {
+						return func(c *cli, args []string) error {
+							if len(args) != 1 {
+								return usagef("config migrate requires a config file")
+							}
+							return migrateConfig(c.stdout, c.stderr, args[0])
+						}
+					},
+				},
+			},
+		},
+		{
+			name:    "auth",
+			summary: "Manage htpasswd users",
+			subcommands: []*command{
+				{name: "adduser", args: "<htpasswd-file> <user>", summary: "Add a user or change their password", setup: authCommand("adduser")},
+				{name: "deluser", args: "<htpasswd-file> <user>", summary: "Remove a user", setup: authCommand("deluser")},
+				{name: "verify", args: "<htpasswd-file> <user>", summary: "Check a user's password", setup: authCommand("verify")},
+				{name: "list", args: "<htpasswd-file>", summary: "List users and their password formats", setup: authCommand("list")},
+			},
+		},
+		{
+			name:    "flight",
+			summary: "List and show requests kept by the flight recorder",
+			subcommands: []*command{
+				{name: "ls", summary: "List the failed requests kept, oldest first", setup: flightCommand("ls")},
+				{name: "show", args: "<id>", summary: "Show a kept request", setup: flightCommand("show")},
+			},
+		},
+		{
+			name:    "completion",
+			args:    "bash|zsh|fish",
+			summary: "Write a shell completion script",
+			words:   []string{"bash", "zsh", "fish"},
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					if len(args) != 1 {
+						return usagef("completion requires bash, zsh, or fish")
+					}
+					return writeCompletion(c.stdout, args[0], commandTree())
+				}
+			},
+		},
+		{
+			name:    "version",
+			summary: "Show version information",
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					printVersion(c.stdout)
+					return nil
+				}
+			},
+		},
+		{
+			name:    "help",
+			args:    "[command]",
+			summary: "Show help for navigator or a command",
+			words:   commandNames(),
+			setup: func(flags *flag.FlagSet) action {
+				return func(c *cli, args []string) error {
+					if len(args) == 0 {
+						printHelp(c.stdout)
+						return nil
+					}
+					return c.run(append(args, "--help"))
+				}
+			},
+		},
+	}
+}
+
+// commandNames returns the names of the top-level commands, for completing
+// help's argument
+func commandNames() []string {
+	return []string{"serve", "reload", "validate", "explain", "dry-run", "smoke-test", "replay", "config", "auth", "flight", "completion", "version"}
+}
+
+// run runs the command named by args, which exclude the program name and
+// the global flags. With no command, or a config file in its place, it
+// asks main to serve.
+func (c *cli) run(args []string) error {
+	if len(args) == 0 {
+		c.serve, c.configFile = true, defaultConfigFile
+		return nil
+	}
+
+	first := args[0]
+	if first == "-s" {
+		// "navigator -s reload [config-file]", as nginx spells it
+		if len(args) < 2 || args[1] != "reload" {
+			return usagef("option -s requires 'reload'")
+		}
+		first, args = "reload", args[1:]
+	} else if name, ok := legacyArgs[first]; ok {
+		first = name
+	}
+
+	for _, cmd := range commandTree() {
+		if cmd.name == first {
+			return c.execute(cmd, cmd.name, args[1:])
+		}
+	}
+	if strings.HasPrefix(first, "-") {
+		return usagef("unknown flag %s (run 'navigator --help' for usage)", first)
+	}
+	if !looksLikeConfigFile(first) {
+		return usagef("unknown command %q (run 'navigator --help' for usage)", first)
+	}
+
+	// navigator [config-file]
+	file, err := optionalConfigFile("navigator", args)
+	c.serve, c.configFile = err == nil, file
+	return err
+}
+
+// execute parses a command's flags and runs it, or picks its subcommand.
+// path is the command's name as typed, e.g. "auth adduser".
+func (c *cli) execute(cmd *command, path string, args []string) error {
+	if cmd.subcommands != nil {
+		if len(args) == 0 {
+			return usagef("%s requires a command: %s", path, strings.Join(subcommandNames(cmd), ", "))
+		}
+		if isHelp(args[0]) {
+			printCommandHelp(c.stdout, cmd, path, nil)
+			return nil
+		}
+		for _, sub := range cmd.subcommands {
+			if sub.name == args[0] {
+				return c.execute(sub, path+" "+sub.name, args[1:])
+			}
+		}
+		return usagef("unknown %s command %q (use %s)", path, args[0], strings.Join(subcommandNames(cmd), ", "))
+	}
+
+	flags := flag.NewFlagSet("navigator "+path, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	run := cmd.setup(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandHelp(c.stdout, cmd, path, flags)
+			return nil
+		}
+		return usagef("%s: %v (run 'navigator %s --help' for usage)", path, err, path)
+	}
+	return run(c, flags.Args())
+}
+
+// optionalConfigFile returns the config file in args, or the default when
+// there is none
+func optionalConfigFile(command string, args []string) (string, error) {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return "", usagef("%s: flags must come before the config file, got %s", command, arg)
+		}
+	}
+	switch len(args) {
+	case 0:
+		return defaultConfigFile, nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", usagef("%s takes at most one config file, got %s", command, strings.Join(args, " "))
+	}
+}
+
+// looksLikeConfigFile reports whether an argument that names no command is
+// a config file: it exists, or has a directory or an extension. Anything
+// else is more likely a mistyped command.
+func looksLikeConfigFile(arg string) bool {
+	if _, err := os.Stat(arg); err == nil {
+		return true
+	}
+	return strings.ContainsRune(arg, '/') || strings.ContainsRune(arg, filepath.Separator) || filepath.Ext(arg) != ""
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help" || arg == "help"
+}
+
+func subcommandNames(cmd *command) []string {
+	var names []string
+	for _, sub := range cmd.subcommands {
+		names = append(names, sub.name)
+	}
+	return names
+}
+
+// stripConfigFlags removes --strict-config and --fail-on-deprecated from
+// args, making every configuration load, including reloads, reject unknown
+// or legacy keys
+func stripConfigFlags(args []string) []string {
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--strict-config", "-strict-config":
+			config.SetStrict(true)
+		case "--fail-on-deprecated", "-fail-on-deprecated":
+			config.SetFailOnDeprecated(true)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// reloadPIDFile returns the PID file "navigator reload [config-file]"
+// signals through: the config file's server.pid_file, or the default when
+// the config can't be loaded
+func reloadPIDFile(configFile string) string {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return config.NavigatorPIDFile
+	}
+	return cfg.Server.PIDFile
+}
+
+// validateConfig loads configFile and its htpasswd file as serving it would,
+// reporting the first problem found
+func validateConfig(out io.Writer, configFile string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if _, err := auth.LoadAuthConfig(&cfg.Auth); err != nil {
+		return fmt.Errorf("failed to load auth file: %w", err)
+	}
+	fmt.Fprintf(out, "%s: OK (%d tenants, %d reverse proxies, %d managed processes)\n", configFile,
+		len(cfg.Applications.Tenants), len(cfg.Routes.ReverseProxies), len(cfg.ManagedProcesses))
+	return nil
+}
+
+// replayCommand defines replay's flags
+func replayCommand(flags *flag.FlagSet) action {
+	configFile := flags.String("config", defaultConfigFile, "configuration to replay against")
+	logFile := flags.String("log", "", "JSON access log to replay")
+	rate := flags.Float64("rate", 0, "requests per second (0 = one after another)")
+	stub := flags.Bool("stub", false, "answer reverse proxies and tenants with a synthetic responder")
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return usagef("replay takes no arguments, got %s", strings.Join(args, " "))
+		}
+		if *logFile == "" {
+			return usagef("replay requires --log")
+		}
+		return replayLog(c.stdout, *configFile, *logFile, *rate, *stub)
+	}
+}
+
+// authCommand defines the flags of an auth subcommand
+func authCommand(name string) func(flags *flag.FlagSet) action {
+	return func(flags *flag.FlagSet) action {
+		var opts authOptions
+		if name == "adduser" || name == "verify" {
+			flags.BoolVar(&opts.passwordStdin, "password-stdin", false, "read the password from stdin")
+		}
+		if name == "adduser" {
+			flags.IntVar(&opts.cost, "cost", bcrypt.DefaultCost, "bcrypt cost")
+		}
+		if name == "adduser" || name == "deluser" {
+			flags.BoolVar(&opts.reload, "reload", false, "signal a running Navigator to reload afterwards")
+			flags.StringVar(&opts.configFile, "config", defaultConfigFile, "configuration naming the PID file, for --reload")
+		}
+		return func(c *cli, args []string) error {
+			operands := 2
+			if name == "list" {
+				operands = 1
+			}
+			if len(args) != operands {
+				return usagef("usage: navigator auth %s [flags] <htpasswd-file>%s", name,
+					strings.Repeat(" <user>", operands-1))
+			}
+			return manageAuth(c.stdin, c.stdout, name, args, opts)
+		}
+	}
+}
+
+// printVersion writes the version, and the commit or build time of
+// development builds
+func printVersion(out io.Writer) {
+	switch {
+	case version != "dev":
+		fmt.Fprintf(out, "Navigator %s\n", version)
+	case commit != "none":
+		fmt.Fprintf(out, "Navigator %s (commit: %s)\n", version, commit[:min(8, len(commit))])
+	default:
+		fmt.Fprintf(out, "Navigator %s (built: %s)\n", version, buildTime)
+	}
+}
+
+// printHelp writes the list of commands
+func printHelp(out io.Writer) {
+	fmt.Fprintln(out, "Navigator - Web application server")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  navigator [config-file]       Start the server (same as navigator serve)")
+	fmt.Fprintln(out, "  navigator <command> [flags] [arguments]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commandTree() {
+		if cmd.subcommands == nil {
+			printCommandLine(out, cmd.name, cmd)
+			continue
+		}
+		for _, sub := range cmd.subcommands {
+			printCommandLine(out, cmd.name+" "+sub.name, sub)
+		}
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Global flags (anywhere on the command line):")
+	for _, global := range globalFlags {
+		fmt.Fprintf(out, "  --%-27s %s\n", global.name, global.usage)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Run 'navigator <command> --help' for a command's flags.")
+	fmt.Fprintln(out, "Default config file: "+defaultConfigFile)
+	fmt.Fprintln(out, "Earlier forms still work: navigator -s reload, --dry-run, --smoke-test, --help, --version")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Signals:")
+	fmt.Fprintln(out, "  SIGHUP   Reload configuration without restart")
+	fmt.Fprintln(out, "  SIGTERM  Graceful shutdown (a second signal aborts in-flight requests)")
+	fmt.Fprintln(out, "  SIGINT   Graceful shutdown, like SIGTERM")
+	fmt.Fprintln(out, "  SIGQUIT  Write a diagnostic bundle without shutting down (also SIGUSR1)")
+}
+
+// printCommandLine writes a command's entry in the command list
+func printCommandLine(out io.Writer, path string, cmd *command) {
+	usage := strings.TrimSpace(path + " " + cmd.args)
+	if len(usage) > 28 {
+		fmt.Fprintf(out, "  %s\n  %-29s %s\n", usage, "", cmd.summary)
+		return
+	}
+	fmt.Fprintf(out, "  %-29s %s\n", usage, cmd.summary)
+}
+
+// printCommandHelp writes a command's usage, its subcommands, and its flags
+func printCommandHelp(out io.Writer, cmd *command, path string, flags *flag.FlagSet) {
+	usage := "navigator " + path
+	if cmd.subcommands != nil {
+		usage += " <command>"
+	}
+	if flags != nil && hasFlags(flags) {
+		usage += " [flags]"
+	}
+	if cmd.args != "" {
+		usage += " " + cmd.args
+	}
+	fmt.Fprintf(out, "Usage: %s\n\n%s\n", usage, cmd.summary)
+
+	if cmd.subcommands != nil {
+		fmt.Fprintln(out, "\nCommands:")
+		for _, sub := range cmd.subcommands {
+			printCommandLine(out, sub.name, sub)
+		}
+	}
+	if flags != nil && hasFlags(flags) {
+		fmt.Fprintln(out, "\nFlags:")
+		flags.SetOutput(out)
+		flags.PrintDefaults()
+		flags.SetOutput(io.Discard)
+	}
+}
+
+func hasFlags(flags *flag.FlagSet) bool {
+	found := false
+	flags.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/flight"
+)
+
+func runCLI(args ...string) (*cli, string, error) {
+	var out bytes.Buffer
+	c := &cli{stdin: strings.NewReader(""), stdout: &out, stderr: &out}
+	err := c.run(args)
+	return c, out.String(), err
+}
+
+func TestCLIServe(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no arguments", nil, "config/navigator.yml"},
+		{"bare config file", []string{"custom-config.yml"}, "custom-config.yml"},
+		{"bare config path", []string{"/etc/navigator/production"}, "/etc/navigator/production"},
+		{"serve", []string{"serve"}, "config/navigator.yml"},
+		{"serve config file", []string{"serve", "custom-config.yml"}, "custom-config.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, err := runCLI(tt.args...)
+			if err != nil {
+				t.Fatalf("run(%q) error = %v", tt.args, err)
+			}
+			if !c.serve || c.configFile != tt.want {
+				t.Errorf("run(%q) serve = %v, config file = %q, want %q", tt.args, c.serve, c.configFile, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLIUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"-s alone", []string{"-s"}, "option -s requires 'reload'"},
+		{"-s with wrong argument", []string{"-s", "invalid"}, "option -s requires 'reload'"},
+		{"unknown flag", []string{"--bogus"}, "unknown flag --bogus"},
+		{"mistyped command", []string{"valdate"}, `unknown command "valdate"`},
+		{"unknown command flag", []string{"replay", "--bogus"}, "flag provided but not defined: -bogus"},
+		{"replay without a log", []string{"replay"}, "replay requires --log"},
+		{"explain without a URL", []string{"explain"}, "explain requires a URL"},
+		{"serve two files", []string{"serve", "a.yml", "b.yml"}, "at most one config file"},
+		{"flag after config file", []string{"validate", "a.yml", "--verbose"}, "flags must come before the config file"},
+		{"config without a command", []string{"config"}, "config requires a command: schema, migrate"},
+		{"unknown subcommand", []string{"auth", "addusr"}, `unknown auth command "addusr"`},
+		{"auth without a user", []string{"auth", "adduser", "htpasswd"}, "navigator auth adduser [flags] <htpasswd-file> <user>"},
+		{"completion without a shell", []string{"completion"}, "completion requires bash, zsh, or fish"},
+		{"completion for an unknown shell", []string{"completion", "tcsh"}, `unknown shell "tcsh"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, err := runCLI(tt.args...)
+			var usage usageError
+			if !errors.As(err, &usage) {
+				t.Fatalf("run(%q) error = %v, want a usage error", tt.args, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("run(%q) error = %q, want it to contain %q", tt.args, err, tt.want)
+			}
+			if c.serve {
+				t.Errorf("run(%q) asked to serve", tt.args)
+			}
+		})
+	}
+}
+
+func TestCLIHelp(t *testing.T) {
+	_, out, err := runCLI("--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Navigator - Web application server",
+		"Usage:",
+		"navigator [config-file]",
+		"reload [config-file]",
+		"auth adduser <htpasswd-file> <user>",
+		"completion bash|zsh|fish",
+		"--strict-config",
+		"navigator -s reload",
+		"Default config file: config/navigator.yml",
+		"Signals:",
+		"SIGHUP",
+		"SIGTERM",
+		"SIGINT",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Help text missing expected content: %q\nGot: %s", expected, out)
+		}
+	}
+
+	// Each command's --help, and help <command>, describe its flags
+	for _, args := range [][]string{{"replay", "--help"}, {"replay", "-h"}, {"help", "replay"}} {
+		c, out, err := runCLI(args...)
+		if err != nil || c.serve {
+			t.Fatalf("run(%q) error = %v, serve = %v", args, err, c.serve)
+		}
+		if !strings.HasPrefix(out, "Usage: navigator replay [flags]") || !strings.Contains(out, "-stub") {
+			t.Errorf("run(%q) = %q", args, out)
+		}
+	}
+	if _, out, _ := runCLI("auth", "--help"); !strings.Contains(out, "deluser") {
+		t.Errorf("auth --help = %q", out)
+	}
+	if _, out, _ := runCLI("help", "auth", "adduser"); !strings.Contains(out, "-password-stdin") {
+		t.Errorf("help auth adduser = %q", out)
+	}
+}
+
+func TestCLICommands(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "navigator.yml")
+	content := "applications:\n  tenants:\n    - name: one\n      path: /one/\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"validate", configFile}, {"--validate", configFile}} {
+		if _, out, err := runCLI(args...); err != nil || out != configFile+": OK (1 tenants, 0 reverse proxies, 0 managed processes)\n" {
+			t.Errorf("run(%q) = %q, %v", args, out, err)
+		}
+	}
+	if _, _, err := runCLI("validate", filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("validate should fail for a missing config file")
+	}
+
+	for _, args := range [][]string{{"version"}, {"--version"}, {"-v"}} {
+		if _, out, err := runCLI(args...); err != nil || !strings.HasPrefix(out, "Navigator ") {
+			t.Errorf("run(%q) = %q, %v", args, out, err)
+		}
+	}
+
+	if _, out, err := runCLI("config", "schema"); err != nil || !strings.Contains(out, `"applications"`) {
+		t.Errorf("config schema = %.80q, %v", out, err)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := map[string][]string{
+		"bash": {"complete -o filenames -F _navigator navigator", "'auth adduser')", "--password-stdin", "--config|-config)", "bash zsh fish"},
+		"zsh":  {"#compdef navigator", "'auth adduser')", "'--password-stdin:read the password from stdin'", "compadd -- bash zsh fish", "_files"},
+		"fish": {
+			"complete -c navigator -n '__fish_use_subcommand' -a auth -d 'Manage htpasswd users'",
+			"complete -c navigator -n '__fish_seen_subcommand_from auth; and not __fish_seen_subcommand_from adduser deluser verify list' -a adduser",
+			"complete -c navigator -n '__fish_seen_subcommand_from replay' -l log -r -F",
+			"complete -c navigator -n '__fish_seen_subcommand_from replay' -l stub -d",
+			"'Check a user'\\''s password'",
+		},
+	}
+	for shell, expected := range tests {
+		t.Run(shell, func(t *testing.T) {
+			_, out, err := runCLI("completion", shell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range expected {
+				if !strings.Contains(out, want) {
+					t.Errorf("%s completion lacks %q:\n%s", shell, want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestFlightCommands(t *testing.T) {
+	dir := t.TempDir()
+	entry := &flight.Entry{
+		Method:         "POST",
+		URI:            "/api/charge",
+		Status:         502,
+		UpstreamError:  "dial tcp 127.0.0.1:9: connect: connection refused",
+		RequestHeaders: []string{"[REDACTED]", "Content-Type: application/json"},
+		RequestBody:    "{\"amount\":",
+		Timing:         flight.Timing{Total: 12.5, FirstByte: 12.25},
+	}
+	if _, err := (flight.Ring{Dir: dir}).Write(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := runCLI("flight", "ls", "--dir", dir)
+	if err != nil || !strings.Contains(out, entry.ID) || !strings.Contains(out, "POST /api/charge") || !strings.Contains(out, "connection refused") {
+		t.Errorf("flight ls = %q, %v", out, err)
+	}
+
+	_, out, err = runCLI("flight", "show", "--dir", dir, entry.ID)
+	for _, want := range []string{"Status:         502", "Upstream error: dial tcp", "total 12.5ms, first byte 12.2ms", "Request headers:\n  [REDACTED]\n", "Request body:\n  {\"amount\":\n"} {
+		if err != nil || !strings.Contains(out, want) {
+			t.Errorf("flight show lacks %q: %q, %v", want, out, err)
+		}
+	}
+
+	if _, out, err := runCLI("flight", "show", "--dir", dir, "--json", entry.ID); err != nil || !strings.Contains(out, `"upstream_error": "dial tcp`) {
+		t.Errorf("flight show --json = %q, %v", out, err)
+	}
+	if _, _, err := runCLI("flight", "show", "--dir", dir, "20250101-000000.000000-0001"); err == nil {
+		t.Error("flight show should fail for a missing entry")
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completionFlag is a flag offered for completion
+type completionFlag struct {
+	name, usage string
+	value       bool // Takes a value
+	file        bool // Its value is a file
+}
+
+// completionEntry is a command line completion distinguishes: the words of
+// a command path, and what may follow them
+type completionEntry struct {
+	path        string // e.g. "auth adduser"; "" for navigator itself
+	subcommands []*command
+	flags       []completionFlag
+	words       []string // Values of the first argument
+	files       bool     // Arguments are files
+}
+
+// writeCompletion writes a completion script for shell describing tree
+func writeCompletion(out io.Writer, shell string, tree []*command) error {
+	entries := completionEntries(tree)
+	switch shell {
+	case "bash":
+		writeBashCompletion(out, entries)
+	case "zsh":
+		writeZshCompletion(out, entries)
+	case "fish":
+		writeFishCompletion(out, entries)
+	default:
+		return usagef("completion: unknown shell %q (use bash, zsh, or fish)", shell)
+	}
+	return nil
+}
+
+// completionEntries flattens tree into navigator's own entry, followed by
+// an entry for each command and subcommand
+func completionEntries(tree []*command) []completionEntry {
+	root := completionEntry{subcommands: tree, files: true}
+	for _, global := range globalFlags {
+		root.flags = append(root.flags, completionFlag{name: global.name, usage: global.usage})
+	}
+	entries := []completionEntry{root}
+
+	var walk func(prefix string, commands []*command)
+	walk = func(prefix string, commands []*command) {
+		for _, cmd := range commands {
+			entry := completionEntry{
+				path:        strings.TrimSpace(prefix + " " + cmd.name),
+				subcommands: cmd.subcommands,
+				words:       cmd.words,
+				files:       strings.Contains(cmd.args, "file"),
+			}
+			if cmd.setup != nil {
+				flags := flag.NewFlagSet(entry.path, flag.ContinueOnError)
+				cmd.setup(flags)
+				flags.VisitAll(func(f *flag.Flag) {
+					entry.flags = append(entry.flags, newCompletionFlag(f))
+				})
+			}
+			entries = append(entries, entry)
+			walk(entry.path, cmd.subcommands)
+		}
+	}
+	walk("", tree)
+	return entries
+}
+
+func newCompletionFlag(f *flag.Flag) completionFlag {
+	cf := completionFlag{name: f.Name, usage: f.Usage, value: true}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		cf.value = false
+	}
+	if getter, ok := f.Value.(flag.Getter); ok && cf.value {
+		_, cf.file = getter.Get().(string)
+	}
+	return cf
+}
+
+// completionNames returns the subcommand names, flags, and words an entry
+// completes to
+func (e completionEntry) completionNames() []string {
+	var names []string
+	for _, sub := range e.subcommands {
+		names = append(names, sub.name)
+	}
+	for _, f := range e.flags {
+		names = append(names, "--"+f.name)
+	}
+	return append(names, e.words...)
+}
+
+// shellQuote quotes s for sh, zsh, and fish
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeBashCompletion(out io.Writer, entries []completionEntry) {
+	fmt.Fprintln(out, "# bash completion for navigator; load with: source <(navigator completion bash)")
+	fmt.Fprintln(out, "_navigator() {")
+	fmt.Fprintln(out, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" cmd="" word next i`)
+	fmt.Fprintln(out, "    for ((i = 1; i < COMP_CWORD; i++)); do")
+	fmt.Fprintln(out, `        word="${COMP_WORDS[i]}"`)
+	fmt.Fprintln(out, `        [[ $word == -* ]] && continue`)
+	fmt.Fprintln(out, `        next="${cmd:+$cmd }$word"`)
+	fmt.Fprintln(out, `        case "$next" in`)
+	var paths []string
+	for _, e := range entries[1:] {
+		paths = append(paths, shellQuote(e.path))
+	}
+	fmt.Fprintf(out, "            %s) cmd=\"$next\" ;;\n", strings.Join(paths, "|"))
+	fmt.Fprintln(out, "            *) break ;;")
+	fmt.Fprintln(out, "        esac")
+	fmt.Fprintln(out, "    done")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, `    case "$cmd" in`)
+	for _, e := range entries {
+		fmt.Fprintf(out, "        %s)\n", shellQuote(e.path))
+		var fileFlags []string
+		for _, f := range e.flags {
+			if f.file {
+				fileFlags = append(fileFlags, "--"+f.name, "-"+f.name)
+			}
+		}
+		if len(fileFlags) > 0 {
+			fmt.Fprintf(out, "            case \"$prev\" in %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;; esac\n",
+				strings.Join(fileFlags, "|"))
+		}
+		fmt.Fprintf(out, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(e.completionNames(), " ")))
+		if e.files {
+			fmt.Fprintln(out, `            [[ $cur != -* ]] && COMPREPLY+=($(compgen -f -- "$cur"))`)
+		}
+		fmt.Fprintln(out, "            ;;")
+	}
+	fmt.Fprintln(out, "    esac")
+	fmt.Fprintln(out, "}")
+	fmt.Fprintln(out, "complete -o filenames -F _navigator navigator")
+}
+
+func writeZshCompletion(out io.Writer, entries []completionEntry) {
+	fmt.Fprintln(out, "#compdef navigator")
+	fmt.Fprintln(out, "# zsh completion for navigator; load with: source <(navigator completion zsh)")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "_navigator() {")
+	fmt.Fprintln(out, `    local cmd="" word next i`)
+	fmt.Fprintln(out, "    for ((i = 2; i < CURRENT; i++)); do")
+	fmt.Fprintln(out, `        word="${words[i]}"`)
+	fmt.Fprintln(out, `        [[ $word == -* ]] && continue`)
+	fmt.Fprintln(out, `        next="${cmd:+$cmd }$word"`)
+	fmt.Fprintln(out, `        case "$next" in`)
+	var paths []string
+	for _, e := range entries[1:] {
+		paths = append(paths, shellQuote(e.path))
+	}
+	fmt.Fprintf(out, "            (%s) cmd=\"$next\" ;;\n", strings.Join(paths, "|"))
+	fmt.Fprintln(out, "            (*) break ;;")
+	fmt.Fprintln(out, "        esac")
+	fmt.Fprintln(out, "    done")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "    local -a commands flags")
+	fmt.Fprintln(out, `    case "$cmd" in`)
+	for _, e := range entries {
+		fmt.Fprintf(out, "        (%s)\n", shellQuote(e.path))
+		var fileFlags []string
+		for _, f := range e.flags {
+			if f.file {
+				fileFlags = append(fileFlags, "--"+f.name, "-"+f.name)
+			}
+		}
+		if len(fileFlags) > 0 {
+			fmt.Fprintf(out, "            case \"${words[CURRENT-1]}\" in (%s) _files; return ;; esac\n", strings.Join(fileFlags, "|"))
+		}
+		var commands, flags []string
+		for _, sub := range e.subcommands {
+			commands = append(commands, shellQuote(sub.name+":"+zshDescription(sub.summary)))
+		}
+		for _, f := range e.flags {
+			flags = append(flags, shellQuote("--"+f.name+":"+zshDescription(f.usage)))
+		}
+		if len(commands) > 0 {
+			fmt.Fprintf(out, "            commands=(%s)\n", strings.Join(commands, " "))
+			fmt.Fprintln(out, "            _describe -t commands 'navigator command' commands")
+		}
+		if len(flags) > 0 {
+			fmt.Fprintf(out, "            flags=(%s)\n", strings.Join(flags, " "))
+			fmt.Fprintln(out, "            _describe -t flags 'flag' flags")
+		}
+		if len(e.words) > 0 {
+			fmt.Fprintf(out, "            compadd -- %s\n", strings.Join(e.words, " "))
+		}
+		if e.files {
+			fmt.Fprintln(out, "            _files")
+		}
+		fmt.Fprintln(out, "            ;;")
+	}
+	fmt.Fprintln(out, "    esac")
+	fmt.Fprintln(out, "}")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, `if [[ $zsh_eval_context[-1] == loadautofunc ]]; then`)
+	fmt.Fprintln(out, `    _navigator "$@"`)
+	fmt.Fprintln(out, "else")
+	fmt.Fprintln(out, "    compdef _navigator navigator")
+	fmt.Fprintln(out, "fi")
+}
+
+// zshDescription escapes the colons _describe would take as separators
+func zshDescription(s string) string {
+	return strings.ReplaceAll(s, ":", `\:`)
+}
+
+func writeFishCompletion(out io.Writer, entries []completionEntry) {
+	fmt.Fprintln(out, "# fish completion for navigator; load with: navigator completion fish | source")
+	fmt.Fprintln(out, "complete -c navigator -f")
+	for _, e := range entries {
+		condition := fishCondition(e)
+		for _, sub := range e.subcommands {
+			fmt.Fprintf(out, "complete -c navigator -n %s -a %s -d %s\n", shellQuote(condition), sub.name, shellQuote(sub.summary))
+		}
+		for _, f := range e.flags {
+			line := fmt.Sprintf("complete -c navigator -n %s -l %s", shellQuote(condition), f.name)
+			if f.value {
+				line += " -r"
+			}
+			if f.file {
+				line += " -F"
+			}
+			fmt.Fprintf(out, "%s -d %s\n", line, shellQuote(f.usage))
+		}
+		if len(e.words) > 0 {
+			fmt.Fprintf(out, "complete -c navigator -n %s -a %s\n", shellQuote(condition), shellQuote(strings.Join(e.words, " ")))
+		}
+		if e.files {
+			fmt.Fprintf(out, "complete -c navigator -n %s -F\n", shellQuote(condition))
+		}
+	}
+}
+
+// fishCondition returns the fish test for the command line being at e:
+// each word of its path seen, and none of its subcommands
+func fishCondition(e completionEntry) string {
+	if e.path == "" {
+		return "__fish_use_subcommand"
+	}
+	var tests []string
+	for _, word := range strings.Fields(e.path) {
+		tests = append(tests, "__fish_seen_subcommand_from "+word)
+	}
+	if len(e.subcommands) > 0 {
+		var names []string
+		for _, sub := range e.subcommands {
+			names = append(names, sub.name)
+		}
+		tests = append(tests, "not __fish_seen_subcommand_from "+strings.Join(names, " "))
+	}
+	return strings.Join(tests, "; and ")
+}
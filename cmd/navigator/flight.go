@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/flight"
+)
+
+// flightCommand defines the flags of a flight subcommand
+func flightCommand(name string) func(flags *flag.FlagSet) action {
+	return func(flags *flag.FlagSet) action {
+		dir := flags.String("dir", "", "flight recorder directory (default: logging.flight_recorder.directory)")
+		configFile := flags.String("config", defaultConfigFile, "configuration naming the directory")
+		asJSON := false
+		if name == "show" {
+			flags.BoolVar(&asJSON, "json", false, "write the entry as JSON")
+		}
+		return func(c *cli, args []string) error {
+			if name == "ls" && len(args) > 0 {
+				return usagef("flight ls takes no arguments, got %s", strings.Join(args, " "))
+			}
+			if name == "show" && len(args) != 1 {
+				return usagef("flight show requires an entry id")
+			}
+			directory, err := flightDirectory(*dir, *configFile)
+			if err != nil {
+				return err
+			}
+			if name == "ls" {
+				return listFlightEntries(c.stdout, directory)
+			}
+			return showFlightEntry(c.stdout, directory, args[0], asJSON)
+		}
+	}
+}
+
+// flightDirectory returns dir, or the flight recorder directory of
+// configFile when dir is empty
+func flightDirectory(dir, configFile string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.Logging.FlightRecorder.Directory == "" {
+		return "", usagef("%s has no logging.flight_recorder.directory; use --dir", configFile)
+	}
+	return cfg.Logging.FlightRecorder.Directory, nil
+}
+
+// listFlightEntries writes a line for each entry in dir, oldest first
+func listFlightEntries(out io.Writer, dir string) error {
+	entries, err := flight.List(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tTIME\tSTATUS\tREQUEST\tERROR")
+	for _, entry := range entries {
+		problem := entry.UpstreamError
+		if problem == "" {
+			problem = entry.ErrorMessage
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s %s\t%s\n", entry.ID, entry.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			entry.Status, entry.Method, entry.URI, problem)
+	}
+	return table.Flush()
+}
+
+// showFlightEntry writes the entry in dir with the given ID
+func showFlightEntry(out io.Writer, dir, id string, asJSON bool) error {
+	entry, err := flight.Read(dir, id)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no flight recorder entry %s in %s", id, dir)
+	}
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entry)
+	}
+
+	for _, field := range []struct{ label, value string }{
+		{"ID", entry.ID},
+		{"Time", entry.Timestamp.Local().Format("2006-01-02 15:04:05.000 MST")},
+		{"Request", entry.Method + " " + entry.URI},
+		{"Request ID", entry.RequestID},
+		{"Client IP", entry.ClientIP},
+		{"Tenant", entry.Tenant},
+		{"Status", fmt.Sprint(entry.Status)},
+		{"Response type", entry.ResponseType},
+		{"Upstream error", entry.UpstreamError},
+		{"Error", entry.ErrorMessage},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(out, "%-16s%s\n", field.label+":", field.value)
+		}
+	}
+
+	timing := entry.Timing
+	parts := []string{fmt.Sprintf("total %.1fms", timing.Total)}
+	for _, part := range []struct {
+		label string
+		ms    float64
+	}{
+		{"first byte", timing.FirstByte},
+		{"queue", timing.Queue},
+		{"startup queued", timing.StartupQueued},
+		{"boot", timing.Boot},
+	} {
+		if part.ms > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.1fms", part.label, part.ms))
+		}
+	}
+	fmt.Fprintf(out, "%-16s%s\n", "Timing:", strings.Join(parts, ", "))
+
+	writeLines(out, "Request headers", entry.RequestHeaders)
+	if entry.RequestBody != "" {
+		label := "Request body"
+		if entry.RequestTruncated {
+			label += " (truncated)"
+		}
+		writeLines(out, label, strings.Split(strings.TrimRight(entry.RequestBody, "\n"), "\n"))
+	}
+	writeLines(out, "Response headers", entry.ResponseHeaders)
+	return nil
+}
+
+// writeLines writes a labelled, indented block, unless lines is empty
+func writeLines(out io.Writer, label string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s:\n", label)
+	for _, line := range lines {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+}
@@ -4,24 +4,42 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/rubys/navigator/internal/auth"
 	"github.com/rubys/navigator/internal/cable"
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/diagnostics"
+	"github.com/rubys/navigator/internal/events"
+	"github.com/rubys/navigator/internal/flight"
+	"github.com/rubys/navigator/internal/fly"
 	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/logging"
 	"github.com/rubys/navigator/internal/process"
 	"github.com/rubys/navigator/internal/proxy"
+	"github.com/rubys/navigator/internal/replay"
+	"github.com/rubys/navigator/internal/scheduler"
 	"github.com/rubys/navigator/internal/server"
 	"github.com/rubys/navigator/internal/utils"
+	"github.com/rubys/navigator/internal/warmer"
+	"github.com/rubys/navigator/internal/worker"
+	"golang.org/x/term"
 )
 
 var (
@@ -30,21 +48,38 @@ var (
 	buildTime = "unknown" // Build timestamp
 )
 
+// pidFile is held while this process serves, and released by exit
+var pidFile *utils.PIDFile
+
+// exit releases the PID file, which os.Exit would skip, and exits with code
+func exit(code int) {
+	pidFile.Release()
+	os.Exit(code)
+}
+
 func main() {
 	// Initialize basic logger
 	initLogger()
 
-	// Handle command line arguments
-	if err := handleCommandLineArgs(); err != nil {
+	// --strict-config and --fail-on-deprecated may appear anywhere; the
+	// remaining arguments are positional
+	os.Args = stripConfigFlags(os.Args)
+
+	// Run the command; only serve goes on to start the server
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	if err := c.run(os.Args[1:]); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintf(os.Stderr, "navigator: %v\n", err)
+			os.Exit(2)
+		}
 		slog.Error("Command failed", "error", err)
 		os.Exit(1)
 	}
-
-	// Determine config file path
-	configFile := "config/navigator.yml"
-	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
-		configFile = os.Args[1]
+	if !c.serve {
+		return
 	}
+	configFile := c.configFile
 
 	// Load configuration
 	cfg, err := config.LoadConfig(configFile)
@@ -58,13 +93,20 @@ func main() {
 		"reverseProxies", len(cfg.Routes.ReverseProxies),
 		"cgiScripts", len(cfg.Server.CGIScripts))
 
+	// List every port in use and warn about likely conflicts
+	config.CheckPorts(cfg).Log()
+
 	// Configure proxy settings
 	slog.Debug("Initial proxy configuration",
 		"trust_proxy", cfg.Server.TrustProxy,
 		"disable_compression", cfg.Server.DisableCompression,
 		"config_file", configFile)
 	proxy.SetTrustProxy(cfg.Server.TrustProxy)
+	proxy.SetForwardedPrecedence(cfg.Server.ForwardedPrecedence)
+	proxy.SetProxyProtocol(cfg.Server.ProxyProtocol)
 	proxy.SetDisableCompression(cfg.Server.DisableCompression)
+	proxy.SetUseEnvProxy(cfg.Proxy.UseEnvProxy)
+	server.ConfigureProxyProtocol(cfg)
 
 	// Log maintenance mode status
 	if cfg.Maintenance.Enabled {
@@ -74,54 +116,70 @@ func main() {
 	// Setup logging format based on configuration
 	setupLogging(cfg)
 
-	// Write PID file
-	if err := utils.WritePIDFile(config.NavigatorPIDFile); err != nil {
-		slog.Error("Failed to write PID file", "error", err)
-		os.Exit(1)
+	// Multi-process mode: this process supervises workers instead of serving
+	if cfg.Server.Workers > 1 && !worker.IsWorker() {
+		if worker.Supported() {
+			os.Exit(runSupervisor(cfg))
+		}
+		slog.Warn("server.workers requires SO_REUSEPORT, running a single process",
+			"workers", cfg.Server.Workers)
+	}
+
+	// Write PID file (workers are signalled through their supervisor)
+	if !worker.IsWorker() {
+		pidFile, err = utils.AcquirePIDFile(cfg.Server.PIDFile)
+		if err != nil {
+			slog.Error("Failed to write PID file", "error", err)
+			os.Exit(1)
+		}
+		defer pidFile.Release()
+	}
+
+	// Secondary workers forward tenant requests to the primary worker
+	if worker.IsSecondary() {
+		applySecondaryWorkerConfig(cfg)
+		server.SetPrimaryWorkerURL("http://" + worker.PrimaryAddr())
+	}
+
+	// Lifecycle events are reported once, by the primary worker
+	if !worker.IsSecondary() {
+		events.Configure(cfg.Hooks.Events)
 	}
-	defer utils.RemovePIDFile(config.NavigatorPIDFile)
 
 	// Create managers
 	processManager := process.NewManager(cfg)
 	appManager := process.NewAppManager(cfg)
 
 	// Create reload channel for resume hook config reload
-	resumeReloadChan := make(chan string, 1)
-	idleManager := idle.NewManager(cfg, configFile, configLoadTime, func(path string) {
+	resumeReloadChan := make(chan utils.ReloadDecision, 1)
+	idleManager := idle.NewManager(cfg, configFile, configLoadTime, func(request utils.ReloadDecision) {
 		// Non-blocking send to avoid deadlock if channel is full
 		select {
-		case resumeReloadChan <- path:
+		case resumeReloadChan <- request:
 		default:
 		}
 	})
+	idleManager.SetTenantActivity(appManager.LastActivity)
 
 	// Load authentication if configured
-	var basicAuth *auth.BasicAuth
-	if cfg.Auth.Enabled && cfg.Auth.HTPasswd != "" {
-		realm := cfg.Auth.Realm
-		if realm == "" {
-			realm = "Restricted" // Default realm
-		}
-		basicAuth, err = auth.LoadAuthFile(
-			cfg.Auth.HTPasswd,
-			realm,
-			cfg.Auth.PublicPaths,
-		)
-		if err != nil {
-			slog.Error("Failed to load auth file", "error", err)
-			os.Exit(1)
-		}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		slog.Error("Failed to load auth file", "error", err)
+		exit(1)
 	}
 
-	// Start managed processes
-	if err := processManager.StartManagedProcesses(); err != nil {
-		slog.Error("Failed to start managed processes", "error", err)
-	}
+	// Managed processes and server hooks run once, in the primary worker
+	if !worker.IsSecondary() {
+		// Start managed processes
+		if err := processManager.StartManagedProcesses(); err != nil {
+			slog.Error("Failed to start managed processes", "error", err)
+		}
 
-	// Execute server start hooks
-	if err := process.ExecuteServerHooks(cfg.Hooks.Start, "start"); err != nil {
-		slog.Error("Failed to execute start hooks", "error", err)
-		os.Exit(1)
+		// Execute server start hooks
+		if err := process.ExecuteServerHooks(context.Background(), cfg.Hooks.Start, "start"); err != nil {
+			slog.Error("Failed to execute start hooks", "error", err)
+			exit(1)
+		}
 	}
 
 	// Create and run server lifecycle
@@ -138,117 +196,463 @@ func main() {
 
 	if err := lifecycle.Run(); err != nil {
 		slog.Error("Server lifecycle failed", "error", err)
-		os.Exit(1)
+		exit(1)
+	}
+}
+
+// runSupervisor starts server.workers worker processes sharing the listen
+// port and forwards signals to them until shutdown. Returns the process exit
+// code.
+func runSupervisor(cfg *config.Config) int {
+	count := cfg.Server.Workers
+	pidFile, err := utils.AcquirePIDFile(cfg.Server.PIDFile)
+	if err != nil {
+		slog.Error("Failed to write PID file", "error", err)
+		return 1
+	}
+	defer pidFile.Release()
+
+	primaryAddr, err := worker.AllocatePrimaryAddr()
+	if err != nil {
+		slog.Error("Failed to start workers", "error", err)
+		return 1
 	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	supervisor := worker.NewSupervisor(count, cfg.WorkerShutdownTimeout(), func(index int) (*exec.Cmd, error) {
+		return worker.Command(index, primaryAddr)
+	})
+	if err := supervisor.Start(); err != nil {
+		slog.Error("Failed to start workers", "error", err)
+		return 1
+	}
+
+	slog.Info("Navigator supervisor running", "version", version, "workers", count, "primary", primaryAddr)
+	supervisor.Run(sigChan)
+	slog.Info("Navigator shutdown complete")
+	return 0
+}
+
+// applySecondaryWorkerConfig disables responsibilities that belong to the
+// primary worker: only the primary suspends or stops the machine when idle.
+// Each worker's idle timer sees only the requests it handled, so a secondary
+// that happened to get no connections would otherwise suspend the machine
+// while the others are busy.
+func applySecondaryWorkerConfig(cfg *config.Config) {
+	cfg.Server.Idle.Action = ""
 }
 
 func getLogLevel() slog.Level {
-	logLevel := slog.LevelInfo
-	if lvl := os.Getenv("LOG_LEVEL"); lvl != "" {
-		switch strings.ToLower(lvl) {
-		case "debug":
-			logLevel = slog.LevelDebug
-		case "info":
-			logLevel = slog.LevelInfo
-		case "warn", "warning":
-			logLevel = slog.LevelWarn
-		case "error":
-			logLevel = slog.LevelError
-		}
+	return parseLogLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo)
+}
+
+// parseLogLevel returns the level named by name, or fallback if it names none
+func parseLogLevel(name string, fallback slog.Level) slog.Level {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug
+	case "info":
+		return slog.LevelInfo
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
 	}
-	return logLevel
+	return fallback
 }
 
 func initLogger() {
 	opts := &slog.HandlerOptions{
 		Level: getLogLevel(),
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stdout, opts))
-	slog.SetDefault(logger)
+	format := process.ResolveLogFormat("", config.LogFormatText, os.Stdout)
+	slog.SetDefault(slog.New(newLogHandler(format, os.Stdout, opts)))
 }
 
-func setupLogging(cfg *config.Config) {
-	// Check if JSON logging is configured
-	if cfg.Logging.Format == "json" {
-		// Switch to JSON handler
-		opts := &slog.HandlerOptions{
-			Level: getLogLevel(),
+// newLogHandler returns the slog handler writing format to output
+func newLogHandler(format string, output io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	switch format {
+	case config.LogFormatJSON:
+		return slog.NewJSONHandler(output, opts)
+	case config.LogFormatPretty:
+		return logging.NewPrettyHandler(output, opts, logging.ColorEnabled(output))
+	}
+	return slog.NewTextHandler(output, opts)
+}
+
+// appLogFile is the file Navigator's own log is written to, if any, closed
+// when setupLogging replaces it
+var appLogFile io.Closer
+
+// accessLogSinks opens the outputs of logging.access.sinks, resolving each
+// sink's format against its output. Vector, when enabled, receives every
+// entry in the access log's format.
+func accessLogSinks(logging config.LogConfig, format string) []server.AccessLogSink {
+	var sinks []server.AccessLogSink
+	for i := range logging.Access.Sinks {
+		sink := &logging.Access.Sinks[i]
+		output, err := process.OpenLogDestination(sink.Destination, os.Stdout)
+		if err != nil {
+			slog.Error("Failed to open access log destination", "destination", sink.Destination, "error", err)
+			continue
+		}
+		sinkFormat := sink.Format
+		if sinkFormat == "" {
+			sinkFormat = logging.Access.Format
 		}
-		jsonLogger := slog.New(slog.NewJSONHandler(os.Stdout, opts))
-		slog.SetDefault(jsonLogger)
+		sinks = append(sinks, server.AccessLogSink{
+			Output: output,
+			Format: process.ResolveLogFormat(sinkFormat, config.LogFormatJSON, output),
+			Filter: &sink.Filter,
+		})
+	}
+	if logging.Vector.Enabled && logging.Vector.Socket != "" {
+		sinks = append(sinks, server.AccessLogSink{Output: process.NewVectorWriter(logging.Vector.Socket), Format: format})
+	}
+	return sinks
+}
 
+// setupLogging builds the operational log (logging.app) and the access log
+// (logging.access) pipelines; on reload, files are reopened
+func setupLogging(cfg *config.Config) {
+	app := cfg.Logging.App
+	output, err := process.OpenLogDestination(app.Destination, os.Stdout)
+	if err != nil {
+		slog.Error("Failed to open log destination, using stdout", "destination", app.Destination, "error", err)
+		output = os.Stdout
+	}
+	opts := &slog.HandlerOptions{
+		Level: parseLogLevel(app.Level, getLogLevel()),
+	}
+	format := app.Format
+	if format == "" {
+		format = cfg.Logging.Format
+	}
+	format = process.ResolveLogFormat(format, config.LogFormatText, output)
+	slog.SetDefault(slog.New(newLogHandler(format, output, opts)))
+	if appLogFile != nil {
+		_ = appLogFile.Close()
+	}
+	appLogFile = nil
+	if output != os.Stdout && output != os.Stderr {
+		appLogFile, _ = output.(io.Closer)
+	}
+	if format == config.LogFormatJSON {
 		// Log the format switch (like the original navigator)
 		slog.Info("Switched to JSON logging format")
 	}
 
-	// Configure access log output destinations
-	accessLogWriter := process.CreateAccessLogWriter(cfg.Logging, os.Stdout)
-	server.SetAccessLogWriter(accessLogWriter)
+	// Configure access log output destinations or sinks, format, and sampling
+	access := cfg.Logging.Access
+	if len(access.Sinks) > 0 {
+		access.Format = process.ResolveLogFormat(access.Format, config.LogFormatJSON, os.Stdout)
+		server.SetAccessLogSinks(accessLogSinks(cfg.Logging, access.Format))
+	} else {
+		accessLogWriter := process.CreateAccessLogWriter(cfg.Logging, os.Stdout)
+		server.SetAccessLogWriter(accessLogWriter)
+		access.Format = process.ResolveLogFormat(access.Format, config.LogFormatJSON, accessLogWriter)
+	}
+	server.ConfigureAccessLog(access)
+
+	// Configure (or remove) request/response body capture
+	if err := server.ConfigureBodyCapture(cfg.Logging.Capture); err != nil {
+		slog.Error("Failed to configure body capture", "error", err)
+	}
+
+	// Start (or stop) the janitor keeping log files within logging.disk_budget
+	process.ConfigureDiskBudget(cfg.Logging.DiskBudget)
 }
 
-func handleCommandLineArgs() error {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "-s":
-			if len(os.Args) > 2 && os.Args[2] == "reload" {
-				return utils.SendReloadSignal(config.NavigatorPIDFile)
-			}
-			return fmt.Errorf("option -s requires 'reload'")
-
-		case "--help", "-h":
-			printHelp()
-			os.Exit(0)
-
-		case "--version", "-v":
-			if version != "dev" {
-				fmt.Printf("Navigator %s\n", version)
-			} else if commit != "none" {
-				fmt.Printf("Navigator %s (commit: %s)\n", version, commit[:8])
-			} else {
-				fmt.Printf("Navigator %s (built: %s)\n", version, buildTime)
-			}
-			os.Exit(0)
+// writeConfigSchema writes the JSON Schema for navigator.yml
+func writeConfigSchema(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config.Schema())
+}
+
+// migrateConfig writes configFile rewritten to the current schema, and
+// lists the legacy keys it moved on stderr
+func migrateConfig(out, stderr io.Writer, configFile string) error {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	migrated, deprecations, err := config.MigrateYAML(content)
+	if err != nil {
+		return err
+	}
+	for _, deprecation := range deprecations {
+		fmt.Fprintln(stderr, deprecation)
+	}
+	_, err = out.Write(migrated)
+	return err
+}
+
+// explainURL writes the route trace for target as JSON. Logging goes to
+// stderr so the output stays parseable.
+func explainURL(out io.Writer, target, configFile string) error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: getLogLevel()})))
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to load auth file: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(server.Explain(cfg, basicAuth, req))
+}
+
+// dryRunReport is everything a configuration would have Navigator execute,
+// followed by its routing tables
+type dryRunReport struct {
+	Config string `json:"config"`
+	*process.Plan
+	Routes *server.RouteTable `json:"routes"`
+}
+
+// dryRun loads and validates configFile, then writes what Navigator would
+// execute and how it would route requests as JSON. Nothing is executed and
+// no PID file is written.
+func dryRun(out io.Writer, configFile string) error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: getLogLevel()})))
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if _, err := auth.LoadAuthConfig(&cfg.Auth); err != nil {
+		return fmt.Errorf("failed to load auth file: %w", err)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dryRunReport{
+		Config: configFile,
+		Plan:   process.NewPlan(cfg),
+		Routes: server.Routes(cfg),
+	})
+}
+
+// smokeTest starts every tenant of configFile once, requests its root path,
+// and writes the results as JSON. Logging goes to stderr so the output stays
+// parseable; a tenant answering with a 5xx fails the test.
+func smokeTest(out io.Writer, configFile string) error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: getLogLevel()})))
+	server.SetAccessLogWriter(io.Discard)
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	results, err := replay.Smoke(cfg)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		return err
+	}
+	failed := 0
+	for _, result := range results {
+		if !result.OK {
+			failed++
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tenants failed the smoke test", failed, len(results))
+	}
 	return nil
 }
 
-func printHelp() {
-	fmt.Println("Navigator - Web application server")
-	fmt.Println()
-	fmt.Println("Usage:")
-	fmt.Println("  navigator [config-file]     Start server with optional config file")
-	fmt.Println("  navigator -s reload         Reload configuration of running server")
-	fmt.Println("  navigator --help            Show this help message")
-	fmt.Println("  navigator --version         Show version information")
-	fmt.Println()
-	fmt.Println("Default config file: config/navigator.yml")
-	fmt.Println()
-	fmt.Println("Signals:")
-	fmt.Println("  SIGHUP   Reload configuration without restart")
-	fmt.Println("  SIGTERM  Graceful shutdown")
-	fmt.Println("  SIGINT   Immediate shutdown")
+// replayLog replays the requests in logFile, a JSON access log, against
+// configFile at rate requests per second (0 = one after another), and
+// writes the report as JSON. Logging goes to stderr and access logging is
+// discarded so the output stays parseable.
+func replayLog(out io.Writer, configFile, logFile string, rate float64, stub bool) error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: getLogLevel()})))
+	server.SetAccessLogWriter(io.Discard)
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	file, err := os.Open(logFile)
+	if err != nil {
+		return fmt.Errorf("failed to open access log: %w", err)
+	}
+	defer file.Close()
+	requests, skipped, err := replay.ParseLog(file)
+	if err != nil {
+		return fmt.Errorf("failed to read access log: %w", err)
+	}
+
+	report, err := replay.Run(cfg, requests, replay.Options{Rate: rate, Stub: stub})
+	if err != nil {
+		return err
+	}
+	report.Skipped = skipped
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// authOptions are the flags of the auth commands
+type authOptions struct {
+	passwordStdin bool   // Read the password from the first line of stdin
+	cost          int    // bcrypt cost of new passwords
+	reload        bool   // Signal a running Navigator to reload afterwards
+	configFile    string // Configuration naming the PID file, for reload
+}
+
+// manageAuth edits an htpasswd file: command is "adduser", "deluser",
+// "verify", or "list", and args are the file and, except for list, the
+// user. Passwords are prompted for on a terminal, or read from the first
+// line of stdin with --password-stdin.
+func manageAuth(stdin io.Reader, out io.Writer, command string, args []string, opts authOptions) error {
+	file, err := auth.ReadHtpasswd(args[0])
+	if err != nil {
+		return err
+	}
+	var user string
+	if len(args) > 1 {
+		user = args[1]
+	}
+
+	switch command {
+	case "adduser":
+		password, err := readPassword(stdin, out, opts.passwordStdin, true)
+		if err != nil {
+			return err
+		}
+		if err := file.SetPassword(user, password, opts.cost); err != nil {
+			return err
+		}
+	case "deluser":
+		if !file.Delete(user) {
+			return fmt.Errorf("user %q not found", user)
+		}
+	case "verify":
+		password, err := readPassword(stdin, out, opts.passwordStdin, false)
+		if err != nil {
+			return err
+		}
+		matched, err := file.Verify(user, password)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("password for %q does not match", user)
+		}
+		fmt.Fprintf(out, "Password for %s matches\n", user)
+		return nil
+	case "list":
+		for _, entry := range file.Entries() {
+			fmt.Fprintf(out, "%s\t%s\n", entry.User, entry.Format)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown auth command %q", command)
+	}
+
+	if err := file.Save(); err != nil {
+		return err
+	}
+	if opts.reload {
+		return utils.SendReloadSignal(reloadPIDFile(opts.configFile))
+	}
+	return nil
+}
+
+// readPassword reads a password from the first line of stdin, or prompts
+// for it on the terminal, twice when confirm is set
+func readPassword(stdin io.Reader, out io.Writer, fromStdin, confirm bool) (string, error) {
+	if fromStdin {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	terminal, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(terminal.Fd())) {
+		return "", fmt.Errorf("stdin is not a terminal; use --password-stdin")
+	}
+	fmt.Fprint(out, "Password: ")
+	password, err := term.ReadPassword(int(terminal.Fd()))
+	fmt.Fprintln(out)
+	if err != nil || !confirm {
+		return string(password), err
+	}
+	fmt.Fprint(out, "Confirm password: ")
+	again, err := term.ReadPassword(int(terminal.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", err
+	}
+	if string(again) != string(password) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(password), nil
 }
 
 // ServerLifecycle manages the HTTP server lifecycle and signal handling
 type ServerLifecycle struct {
-	configFile       string
-	cfg              *config.Config
-	configLoadTime   time.Time // When the current config was loaded (used for reload detection)
-	appManager       *process.AppManager
-	processManager   *process.Manager
-	basicAuth        *auth.BasicAuth
-	idleManager      *idle.Manager
-	cableHandler     *cable.Handler
-	srv              *http.Server
-	reloadChan       chan string // Channel for triggering config reload from CGI scripts
-	resumeReloadChan chan string // Channel for triggering config reload from resume hooks
+	configFile        string
+	cfg               *config.Config
+	configLoadTime    time.Time // When the current config was loaded (used for reload detection)
+	appManager        *process.AppManager
+	processManager    *process.Manager
+	basicAuth         *auth.BasicAuth
+	idleManager       *idle.Manager
+	cableHandler      *cable.Handler
+	srv               *http.Server
+	connections       server.ConnectionTracker      // Requests in progress, reported when shutdown drains them
+	reloadChan        chan utils.ReloadDecision     // Reload requests from CGI scripts and ready hooks
+	resumeReloadChan  chan utils.ReloadDecision     // Reload requests from resume hooks
+	diagnosticsChan   chan chan *diagnostics.Bundle // Diagnostics endpoint requests, answered by the signal loop
+	cancelReloadHooks context.CancelFunc            // Stops hooks still running from the previous reload
+
+	// Reloads are serialized by the signal loop: one requested while ready
+	// hooks from the last are running waits for them to be cancelled
+	reloadHooksDone chan error            // Ready hooks finished, with their error
+	hooksRunning    bool                  // Ready hooks from startup or the last reload are running
+	hooksSource     string                // What requested the reload they belong to
+	pendingReload   *utils.ReloadDecision // Reload waiting for them to stop
+	reloads         server.ReloadHistory
+
+	clock clock.Clock // Times the shutdown drain delay and timeout (nil = real time)
 }
 
 // Run starts the server and handles signals until shutdown
 func (l *ServerLifecycle) Run() error {
-	// Create reload channel for CGI scripts
-	l.reloadChan = make(chan string, 1)
+	// Create reload channel for CGI scripts and ready hooks
+	l.reloadChan = make(chan utils.ReloadDecision, 1)
+	l.reloadHooksDone = make(chan error, 1)
+	server.SetReloadHistory(&l.reloads)
+
+	// Diagnostics are collected by the signal loop so they never race a reload
+	l.diagnosticsChan = make(chan chan *diagnostics.Bundle)
+	server.SetDiagnosticsProvider(l.requestDiagnostics)
+	server.SetManagedProcessSource(l.processManager)
+	server.SetBuildInfo(server.BuildInfo{Version: version, Commit: commit, BuildTime: buildTime})
+	if fly := utils.Fly(); fly.Region != "" {
+		slog.Info("Running on Fly.io", "region", fly.Region, "machine", fly.MachineID, "alloc", fly.AllocID)
+	}
 
 	// Create WebSocket/Cable handler
 	l.cableHandler = cable.NewHandler(slog.Default())
@@ -262,50 +666,74 @@ func (l *ServerLifecycle) Run() error {
 		l.idleManager,
 		l.cableHandler,
 		func() string { return l.configFile }, // Get current config file
-		func() time.Time { return l.configLoadTime }, // Get config load time for reload detection
-		func(path string) { l.reloadChan <- path },   // Trigger reload
+		func() time.Time { return l.configLoadTime },                   // Get config load time for reload detection
+		func(request utils.ReloadDecision) { l.reloadChan <- request }, // Trigger reload
 	)
 
 	// Create HTTP server
 	addr := fmt.Sprintf(":%s", l.cfg.Server.Listen)
 	l.srv = &http.Server{
-		Addr:    addr,
-		Handler: handler,
+		Addr:           addr,
+		Handler:        handler,
+		ConnState:      l.connections.ConnState,
+		MaxHeaderBytes: l.cfg.Server.MaxHeaderBytes, // 0 uses the net/http default
 	}
+	server.ApplyTimeouts(l.srv, l.cfg.Server.Timeouts)
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	signal.Notify(sigChan, append([]os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP}, diagnostics.Signals...)...)
 
 	// Start server in goroutine
-	serverErrors := make(chan error, 1)
-	hookReloadChan := make(chan bool, 1)
+	serverErrors := make(chan error, 2)
 
 	// Start HTTP server listener
-	go func() {
-		slog.Info("Navigator starting", "version", version, "address", addr)
-		serverErrors <- l.srv.ListenAndServe()
-	}()
+	if worker.IsWorker() {
+		if err := l.serveWorker(addr, serverErrors); err != nil {
+			return err
+		}
+	} else {
+		listener, err := server.Listen(addr, l.cfg.Server.Timeouts)
+		if err != nil {
+			return err
+		}
+		go func() {
+			slog.Info("Navigator starting", "version", version, "address", addr)
+			serverErrors <- l.srv.Serve(server.ProxyProtocolListener(listener))
+		}()
+	}
+
+	// Scheduled tasks, warmers, and scale-ups run once, in the primary worker
+	if !worker.IsSecondary() {
+		scheduler.Configure(l.cfg, scheduleURL(l.cfg))
+		warmer.Configure(l.cfg, scheduleURL(l.cfg), l.appManager)
+		fly.Configure(l.cfg, l.appManager.InFlight)
+	}
 
 	// Execute ready hooks asynchronously after server starts listening
-	// This allows the server to serve maintenance pages while hooks run
-	go func() {
+	// This allows the server to serve maintenance pages while hooks run.
+	// A reload requested meanwhile cancels them and waits for them to stop.
+	readyHooks, configFile, configLoadTime := l.cfg.Hooks.Ready, l.configFile, l.configLoadTime
+	hooksCtx, cancelHooks := context.WithCancel(context.Background())
+	l.cancelReloadHooks = cancelHooks
+	l.hooksSource = "startup"
+	l.runReadyHooks(hooksCtx, func(ctx context.Context) error {
 		// Give server a moment to start listening
 		time.Sleep(100 * time.Millisecond)
 
 		// Execute server ready hooks with reload check
 		// Pass configLoadTime to detect changes since config was loaded (including during suspend)
-		result := process.ExecuteServerHooksWithReload(l.cfg.Hooks.Ready, "ready", l.configFile, l.configLoadTime)
+		result := process.ExecuteServerHooksWithReload(ctx, readyHooks, "ready", configFile, configLoadTime)
 		if result.Error != nil {
 			slog.Error("Failed to execute ready hooks", "error", result.Error)
-		} else if result.ReloadDecision.ShouldReload {
+		} else if result.ReloadDecision.Requested() {
 			slog.Info("Ready hook triggered config reload",
 				"reason", result.ReloadDecision.Reason,
 				"configFile", result.ReloadDecision.NewConfigFile)
-			l.configFile = result.ReloadDecision.NewConfigFile
-			hookReloadChan <- true
+			l.reloadChan <- result.ReloadDecision
 		}
-	}()
+		return result.Error
+	})
 
 	// Handle signals and server errors
 	for {
@@ -317,45 +745,232 @@ func (l *ServerLifecycle) Run() error {
 			}
 			return nil
 
-		case <-hookReloadChan:
-			l.handleReload()
-
-		case configPath := <-l.reloadChan:
-			// CGI script triggered reload
-			if configPath != l.configFile {
-				l.configFile = configPath
-			}
-			l.handleReload()
+		case request := <-l.reloadChan:
+			// CGI script or ready hook triggered reload
+			l.scheduleReload(request)
 
-		case configPath := <-l.resumeReloadChan:
+		case request := <-l.resumeReloadChan:
 			// Resume hook triggered reload
-			if configPath != l.configFile {
-				l.configFile = configPath
-			}
-			l.handleReload()
+			l.scheduleReload(request)
+
+		case err := <-l.reloadHooksDone:
+			l.finishReadyHooks(err)
+
+		case reply := <-l.diagnosticsChan:
+			reply <- l.collectDiagnostics()
 
 		case sig := <-sigChan:
 			switch sig {
 			case syscall.SIGHUP:
-				l.handleReload()
+				l.scheduleReload(utils.ReloadDecision{ShouldReload: true, Source: "SIGHUP"})
 
 			case syscall.SIGTERM, syscall.SIGINT:
-				return l.handleShutdown(sig)
+				return l.handleShutdown(sig, sigChan)
+
+			default:
+				if diagnostics.IsSignal(sig) {
+					l.handleDiagnostics(sig)
+				}
 			}
 		}
 	}
 }
 
-// handleReload reloads configuration without restarting the server
-func (l *ServerLifecycle) handleReload() {
+// serveWorker listens on the shared port with SO_REUSEPORT. The primary worker
+// also accepts tenant requests forwarded from secondary workers.
+func (l *ServerLifecycle) serveWorker(addr string, serverErrors chan<- error) error {
+	index, _ := worker.Index()
+
+	listener, err := worker.Listen(addr, l.cfg.Server.Timeouts.TCPKeepAlive.Std())
+	if err != nil {
+		return err
+	}
+	go func() {
+		slog.Info("Navigator worker starting", "version", version, "address", addr, "worker", index)
+		serverErrors <- l.srv.Serve(server.ProxyProtocolListener(listener))
+	}()
+
+	if worker.IsSecondary() {
+		return nil
+	}
+
+	internal, err := net.Listen("tcp", worker.PrimaryAddr())
+	if err != nil {
+		return fmt.Errorf("failed to listen for worker requests: %w", err)
+	}
+	go func() {
+		serverErrors <- l.srv.Serve(internal)
+	}()
+	return nil
+}
+
+// scheduleReload handles a reload requested by a signal, hook, or CGI
+// script. While ready hooks from startup or the last reload are running, a
+// reload cancels them and waits for them to stop, so two sets of hooks never
+// overlap; requests arriving meanwhile are merged into the waiting one.
+func (l *ServerLifecycle) scheduleReload(request utils.ReloadDecision) {
+	switch {
+	case request.ValidateOnly || !l.hooksRunning || (!request.ShouldReload && l.pendingReload == nil):
+		l.handleReloadRequest(request)
+		return
+
+	case l.pendingReload != nil:
+		slog.Info("Reload coalesced with the pending reload",
+			"source", request.Source,
+			"reason", request.Reason,
+			"pendingSource", l.pendingReload.Source)
+		l.reloads.Record(server.ReloadAttempt{
+			Source:     request.Source,
+			Outcome:    server.ReloadCoalesced,
+			Reason:     request.Reason,
+			ConfigFile: request.NewConfigFile,
+		})
+		merged := mergeReloadRequests(*l.pendingReload, request)
+		l.pendingReload = &merged
+
+	default:
+		slog.Info("Reload waiting for ready hooks to be cancelled",
+			"source", request.Source,
+			"hooksSource", l.hooksSource)
+		l.pendingReload = &request
+		l.cancelReloadHooks()
+	}
+	l.reloads.SetState(true, true)
+}
+
+// mergeReloadRequests folds a later reload request into a pending one: the
+// later config file wins and both sets of tenants are restarted
+func mergeReloadRequests(pending, later utils.ReloadDecision) utils.ReloadDecision {
+	pending.ShouldReload = pending.ShouldReload || later.ShouldReload
+	if later.NewConfigFile != "" {
+		pending.NewConfigFile = later.NewConfigFile
+	}
+	for _, name := range later.RestartTenants {
+		if !slices.Contains(pending.RestartTenants, name) {
+			pending.RestartTenants = append(pending.RestartTenants, name)
+		}
+	}
+	return pending
+}
+
+// runReadyHooks runs ready hooks in the background with ctx, which a newer
+// reload cancels. The signal loop learns they finished from reloadHooksDone.
+func (l *ServerLifecycle) runReadyHooks(ctx context.Context, run func(ctx context.Context) error) {
+	l.hooksRunning = true
+	l.reloads.SetState(true, l.pendingReload != nil)
+	done := l.reloadHooksDone
+	go func() {
+		var err error
+		if !worker.IsSecondary() {
+			err = run(ctx)
+		}
+		if done != nil {
+			done <- err
+		}
+	}()
+}
+
+// finishReadyHooks records ready hooks that finished, then starts the
+// reload that was waiting for them, if any
+func (l *ServerLifecycle) finishReadyHooks(err error) {
+	l.hooksRunning = false
+	if errors.Is(err, context.Canceled) {
+		slog.Info("Ready hooks cancelled by a newer reload", "source", l.hooksSource)
+		l.reloads.Record(server.ReloadAttempt{Source: l.hooksSource, Outcome: server.ReloadCancelled})
+	}
+
+	pending := l.pendingReload
+	l.pendingReload = nil
+	l.reloads.SetState(false, false)
+	if pending != nil {
+		l.handleReloadRequest(*pending)
+	}
+}
+
+// handleReloadRequest reloads the configuration a hook or CGI script asked
+// for, then restarts the tenants it named. A validate_only request validates
+// the configuration and changes nothing.
+func (l *ServerLifecycle) handleReloadRequest(request utils.ReloadDecision) {
+	slog.Info("Reload requested",
+		"source", request.Source,
+		"reload", request.ShouldReload,
+		"validateOnly", request.ValidateOnly,
+		"reason", request.Reason,
+		"configFile", request.NewConfigFile,
+		"restartTenants", request.RestartTenants)
+
+	if request.ValidateOnly {
+		report := server.ValidateConfigFile(request.NewConfigFile, l.cfg)
+		report.Log(request.Source)
+		attempt := server.ReloadAttempt{
+			Source:     request.Source,
+			Outcome:    server.ReloadValidated,
+			Reason:     request.Reason,
+			ConfigFile: request.NewConfigFile,
+			Errors:     report.Errors,
+			Warnings:   report.Warnings,
+		}
+		if !report.Valid {
+			attempt.Outcome = server.ReloadInvalid
+		}
+		l.reloads.Record(attempt)
+		return
+	}
+
+	if request.ShouldReload {
+		if request.NewConfigFile != "" {
+			l.configFile = request.NewConfigFile
+		}
+		attempt := server.ReloadAttempt{
+			Source:     request.Source,
+			Outcome:    server.ReloadApplied,
+			Reason:     request.Reason,
+			ConfigFile: l.configFile,
+		}
+		if l.handleReload() {
+			l.hooksSource = request.Source
+		} else {
+			attempt.Outcome = server.ReloadFailed
+		}
+		l.reloads.Record(attempt)
+	}
+
+	for _, name := range request.RestartTenants {
+		tenant := l.cfg.TenantByName(name)
+		if tenant == nil {
+			slog.Warn("Cannot restart unknown tenant", "tenant", name, "source", request.Source)
+			continue
+		}
+		if l.appManager.RestartApp(tenant.Name, "restart") {
+			slog.Info("Restarting tenant", "tenant", tenant.Name, "source", request.Source)
+		} else {
+			slog.Info("Tenant not running, nothing to restart", "tenant", tenant.Name, "source", request.Source)
+		}
+	}
+}
+
+// scheduleURL is where scheduled HTTP tasks and warmers send requests:
+// Navigator's own listener
+func scheduleURL(cfg *config.Config) string {
+	return "http://127.0.0.1:" + cfg.Server.Listen
+}
+
+// handleReload reloads configuration without restarting the server,
+// reporting whether the new configuration loaded
+func (l *ServerLifecycle) handleReload() bool {
 	slog.Info("Received SIGHUP, reloading configuration")
 
 	// Load new configuration
 	newConfig, err := config.LoadConfig(l.configFile)
 	if err != nil {
 		slog.Error("Failed to reload configuration", "error", err)
-		return
+		events.Emit(events.ReloadFailed, map[string]interface{}{
+			"config_file": l.configFile,
+			"error":       err.Error(),
+		})
+		return false
 	}
+	config.CheckPorts(newConfig).Log()
 
 	// DEBUG: Log the trust_proxy value from loaded config
 	slog.Debug("Loaded config trust_proxy value",
@@ -363,17 +978,26 @@ func (l *ServerLifecycle) handleReload() {
 		"config_file", l.configFile)
 
 	// Replace config and update load time
+	if worker.IsSecondary() {
+		applySecondaryWorkerConfig(newConfig)
+	}
 	l.cfg = newConfig
 	l.configLoadTime = time.Now()
 
 	// Update configuration in all managers
 	l.appManager.UpdateConfig(newConfig)
-	l.processManager.UpdateManagedProcesses(newConfig)
+	if !worker.IsSecondary() {
+		l.processManager.UpdateManagedProcesses(newConfig)
+	}
 	l.idleManager.UpdateConfig(newConfig, l.configFile, l.configLoadTime)
 
 	// Update proxy settings
 	proxy.SetTrustProxy(newConfig.Server.TrustProxy)
+	proxy.SetForwardedPrecedence(newConfig.Server.ForwardedPrecedence)
+	proxy.SetProxyProtocol(newConfig.Server.ProxyProtocol)
 	proxy.SetDisableCompression(newConfig.Server.DisableCompression)
+	proxy.SetUseEnvProxy(newConfig.Proxy.UseEnvProxy)
+	server.ConfigureProxyProtocol(newConfig)
 	slog.Debug("Set proxy configuration",
 		"trust_proxy", newConfig.Server.TrustProxy,
 		"disable_compression", newConfig.Server.DisableCompression)
@@ -381,31 +1005,30 @@ func (l *ServerLifecycle) handleReload() {
 	// Update logging format if changed
 	setupLogging(newConfig)
 
+	// Hooks still running from the previous reload are for a stale config
+	if l.cancelReloadHooks != nil {
+		l.cancelReloadHooks()
+	}
+	hooksCtx, cancelHooks := context.WithCancel(context.Background())
+	l.cancelReloadHooks = cancelHooks
+
 	// Execute server start hooks BEFORE loading auth
 	// This is important because hooks may update the htpasswd file
-	if err := process.ExecuteServerHooks(newConfig.Hooks.Start, "start"); err != nil {
-		slog.Error("Failed to execute start hooks after reload", "error", err)
+	if !worker.IsSecondary() {
+		if err := process.ExecuteServerHooks(hooksCtx, newConfig.Hooks.Start, "start"); err != nil {
+			slog.Error("Failed to execute start hooks after reload", "error", err)
+		}
 	}
 
 	// Reload auth if configured (AFTER hooks execute, since they may update htpasswd)
-	if newConfig.Auth.Enabled && newConfig.Auth.HTPasswd != "" {
-		realm := newConfig.Auth.Realm
-		if realm == "" {
-			realm = "Restricted"
-		}
-		newAuth, err := auth.LoadAuthFile(
-			newConfig.Auth.HTPasswd,
-			realm,
-			newConfig.Auth.PublicPaths,
-		)
-		if err != nil {
-			slog.Warn("Failed to reload auth file", "file", newConfig.Auth.HTPasswd, "error", err)
-		} else {
-			l.basicAuth = newAuth
-			slog.Info("Reloaded authentication", "file", newConfig.Auth.HTPasswd)
-		}
+	newAuth, err := auth.LoadAuthConfig(&newConfig.Auth)
+	if err != nil {
+		slog.Warn("Failed to reload auth files", "error", err)
 	} else {
-		l.basicAuth = nil
+		l.basicAuth = newAuth
+		if files := newAuth.Files(); len(files) > 0 {
+			slog.Info("Reloaded authentication", "files", files)
+		}
 	}
 
 	// Update server handler if server is running (AFTER auth is loaded)
@@ -417,38 +1040,188 @@ func (l *ServerLifecycle) handleReload() {
 			l.idleManager,
 			l.cableHandler,
 			func() string { return l.configFile }, // Get current config file
-			func() time.Time { return l.configLoadTime }, // Get config load time for reload detection
-			func(path string) { l.reloadChan <- path },   // Trigger reload
+			func() time.Time { return l.configLoadTime },                   // Get config load time for reload detection
+			func(request utils.ReloadDecision) { l.reloadChan <- request }, // Trigger reload
 		)
 		l.srv.Handler = newHandler
 	}
 
 	slog.Info("Configuration reloaded successfully")
+	if !worker.IsSecondary() {
+		scheduler.Configure(newConfig, scheduleURL(newConfig))
+		warmer.Configure(newConfig, scheduleURL(newConfig), l.appManager)
+		fly.Configure(newConfig, l.appManager.InFlight)
+		events.Configure(newConfig.Hooks.Events)
+		events.Emit(events.ReloadSucceeded, map[string]interface{}{
+			"config_file": l.configFile,
+		})
+	}
 
 	// Execute ready hooks asynchronously after reload completes
 	// This allows optimizations (prerender, cache warming, etc.) to run
 	// while Navigator continues serving requests with the new configuration
-	go func() {
-		if err := process.ExecuteServerHooks(newConfig.Hooks.Ready, "ready"); err != nil {
+	l.runReadyHooks(hooksCtx, func(ctx context.Context) error {
+		err := process.ExecuteServerHooks(ctx, newConfig.Hooks.Ready, "ready")
+		if err != nil {
 			slog.Error("Failed to execute ready hooks after reload", "error", err)
 		}
-	}()
+		return err
+	})
+	return true
+}
+
+// collectDiagnostics gathers a diagnostic bundle from the running components
+func (l *ServerLifecycle) collectDiagnostics() *diagnostics.Bundle {
+	return diagnostics.Collect(diagnostics.Sources{
+		Version:   version,
+		Config:    l.cfg,
+		Apps:      l.appManager,
+		Processes: l.processManager,
+		Cable:     l.cableHandler,
+	})
+}
+
+// requestDiagnostics asks the signal loop for a bundle; returns nil if the
+// loop does not answer (e.g. during shutdown)
+func (l *ServerLifecycle) requestDiagnostics() *diagnostics.Bundle {
+	reply := make(chan *diagnostics.Bundle, 1)
+	select {
+	case l.diagnosticsChan <- reply:
+		return <-reply
+	case <-time.After(5 * time.Second):
+		return nil
+	}
 }
 
-// handleShutdown performs graceful shutdown with context propagation
-func (l *ServerLifecycle) handleShutdown(sig os.Signal) error {
+// handleDiagnostics writes a diagnostic bundle without interrupting service
+func (l *ServerLifecycle) handleDiagnostics(sig os.Signal) {
+	path, err := diagnostics.Write(l.cfg.Server.Diagnostics.Dir, l.collectDiagnostics())
+	if err != nil {
+		slog.Error("Failed to write diagnostic bundle", "signal", sig, "error", err)
+		return
+	}
+	slog.Info("Wrote diagnostic bundle", "signal", sig, "path", path)
+}
+
+// shutdownSignals maps the signal names accepted by immediate_signal
+var shutdownSignals = map[string]os.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// isImmediateSignal reports whether sig aborts in-flight requests instead of
+// draining them, given server.shutdown.immediate_signal and whether a drain
+// is already in progress
+func isImmediateSignal(immediate string, sig os.Signal, draining bool) bool {
+	switch immediate {
+	case config.ImmediateSignalNone:
+		return false
+	case "", config.ImmediateSignalSecond:
+		return draining
+	}
+	return sig == shutdownSignals[immediate]
+}
+
+// drainRequests stops accepting connections and waits for in-flight requests
+// to finish. Requests still running when the context expires or an immediate
+// signal arrives are aborted; completed is false if that happened.
+func (l *ServerLifecycle) drainRequests(ctx context.Context, signals <-chan os.Signal) (drained, aborted int, completed bool) {
+	immediate := func(sig os.Signal) bool {
+		if !isImmediateSignal(l.cfg.Server.Shutdown.ImmediateSignal, sig, true) {
+			slog.Info("Already shutting down", "signal", sig)
+			return false
+		}
+		slog.Warn("Received another shutdown signal, aborting requests", "signal", sig)
+		return true
+	}
+
+	if delay := time.Duration(l.cfg.Server.HealthCheck.DrainDelay); delay > 0 {
+		slog.Info("Draining before shutdown", "delay", delay)
+		timer := clock.Or(l.clock).NewTimer(delay)
+		defer timer.Stop()
+		for waiting := true; waiting; {
+			select {
+			case <-timer.C():
+				waiting = false
+			case sig := <-signals:
+				if immediate(sig) {
+					return 0, l.abortRequests(), false
+				}
+			}
+		}
+	}
+
+	inFlight := l.connections.Busy()
+	done := make(chan error, 1)
+	go func() { done <- l.srv.Shutdown(ctx) }()
+
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				return inFlight, 0, true
+			}
+			slog.Warn("Shutdown timeout reached, aborting requests", "error", err)
+			aborted = l.abortRequests()
+			return max(inFlight-aborted, 0), aborted, false
+
+		case sig := <-signals:
+			if immediate(sig) {
+				aborted = l.abortRequests()
+				<-done
+				return max(inFlight-aborted, 0), aborted, false
+			}
+		}
+	}
+}
+
+// abortRequests closes the listener and every connection at once, returning
+// the number of requests that were still in progress
+func (l *ServerLifecycle) abortRequests() int {
+	aborted := l.connections.Busy()
+	if err := l.srv.Close(); err != nil {
+		slog.Error("Server close failed", "error", err)
+	}
+	return aborted
+}
+
+// handleShutdown drains in-flight requests, then stops every component. A
+// further signal can abort the drain, as set by server.shutdown.immediate_signal.
+func (l *ServerLifecycle) handleShutdown(sig os.Signal, signals <-chan os.Signal) error {
 	slog.Info("Received shutdown signal", "signal", sig)
 
 	// Stop idle manager
 	l.idleManager.Stop()
+	scheduler.Stop()
+	warmer.Stop()
+	fly.Stop()
+
+	// Ready hooks of a reload don't hold up stopping the tenants
+	if l.cancelReloadHooks != nil {
+		l.cancelReloadHooks()
+	}
 
 	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeout := l.cfg.Server.Shutdown.Timeout.OrDefault(config.DefaultShutdownTimeout)
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	deadline := clock.Or(l.clock).AfterFunc(timeout, cancel)
+	defer deadline.Stop()
+
+	// Report not ready before the listener closes so load balancers move on
+	server.SetDraining(true)
 
-	// Shutdown server
-	if err := l.srv.Shutdown(ctx); err != nil {
-		slog.Error("Server shutdown failed", "error", err)
+	var drained, aborted int
+	completed := false
+	if isImmediateSignal(l.cfg.Server.Shutdown.ImmediateSignal, sig, false) {
+		aborted = l.abortRequests()
+	} else {
+		drained, aborted, completed = l.drainRequests(ctx, signals)
+	}
+	slog.Info("Stopped serving requests", "drained", drained, "aborted", aborted)
+	if !completed {
+		// An aborted shutdown does not wait for the remaining components either
+		cancel()
 	}
 
 	// Shutdown WebSocket handler
@@ -458,12 +1231,19 @@ func (l *ServerLifecycle) handleShutdown(sig os.Signal) error {
 		}
 	}
 
+	// Write queued access log entries while Vector is still running
+	server.FlushAccessLog()
+	flight.Flush()
+
 	// Stop all applications with context
 	l.appManager.CleanupWithContext(ctx)
 
 	// Stop managed processes with context
 	l.processManager.StopManagedProcessesWithContext(ctx)
 
+	// Deliver the events reported while stopping
+	events.Flush(config.EventFlushTimeout)
+
 	slog.Info("Navigator shutdown complete")
 	return nil
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManageAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte("# Accounts\nlegacy:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(stdin string, args ...string) (string, error) {
+		var out bytes.Buffer
+		c := &cli{stdin: strings.NewReader(stdin), stdout: &out, stderr: &out}
+		err := c.run(append([]string{"auth"}, args...))
+		return out.String(), err
+	}
+
+	if _, err := run("s3cret\n", "adduser", "--password-stdin", "--cost", "4", path, "admin"); err != nil {
+		t.Fatalf("adduser: %v", err)
+	}
+	if out, err := run("s3cret\n", "verify", "--password-stdin", path, "admin"); err != nil || !strings.Contains(out, "matches") {
+		t.Errorf("verify = %q, %v", out, err)
+	}
+	if _, err := run("wrong\n", "verify", "--password-stdin", path, "admin"); err == nil {
+		t.Error("verify should fail for a wrong password")
+	}
+	if out, _ := run("", "list", path); out != "legacy\tsha\nadmin\tbcrypt\n" {
+		t.Errorf("list = %q", out)
+	}
+	if _, err := run("", "deluser", path, "legacy"); err != nil {
+		t.Fatalf("deluser: %v", err)
+	}
+	if _, err := run("", "deluser", path, "legacy"); err == nil {
+		t.Error("deluser should fail for a missing user")
+	}
+	if content, _ := os.ReadFile(path); !strings.HasPrefix(string(content), "# Accounts\nadmin:$2") {
+		t.Errorf("htpasswd = %q", content)
+	}
+
+	// Without a terminal, a password has to come from stdin
+	if _, err := run("", "adduser", path, "other"); err == nil || !strings.Contains(err.Error(), "--password-stdin") {
+		t.Errorf("adduser without a terminal: error = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+//go:build unix
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/diagnostics"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// waitForBundles waits until dir holds count diagnostic bundle files
+func waitForBundles(t *testing.T, dir string, count int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		files, _ := filepath.Glob(filepath.Join(dir, "navigator-*.json"))
+		if len(files) >= count || time.Now().After(deadline) {
+			if len(files) < count {
+				t.Fatalf("expected %d diagnostic bundles in %s, found %d", count, dir, len(files))
+			}
+			return files
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestDiagnosticSignalWritesBundle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	tempDir := t.TempDir()
+	bundleDir := filepath.Join(tempDir, "diagnostics")
+	configFile := filepath.Join(tempDir, "navigator.yml")
+	configContent := fmt.Sprintf(`
+server:
+  listen: "%d"
+  diagnostics:
+    dir: %s
+    path: /_navigator/diagnostics
+applications:
+  env:
+    SECRET_KEY_BASE: do-not-leak
+  tenants: []
+`, port, bundleDir)
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	lifecycle := &ServerLifecycle{
+		configFile:     configFile,
+		cfg:            cfg,
+		appManager:     process.NewAppManager(cfg),
+		processManager: process.NewManager(cfg),
+		idleManager:    idle.NewManager(cfg, "", time.Time{}, nil),
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- lifecycle.Run() }()
+
+	// The endpoint answers once the server is listening
+	url := fmt.Sprintf("http://127.0.0.1:%d/_navigator/diagnostics", port)
+	var bundle diagnostics.Bundle
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			decodeErr := json.NewDecoder(resp.Body).Decode(&bundle)
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK && decodeErr == nil {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("diagnostics endpoint did not respond: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if bundle.PID != os.Getpid() || bundle.Goroutines == "" {
+		t.Errorf("endpoint returned incomplete bundle: pid=%d goroutines=%d bytes", bundle.PID, len(bundle.Goroutines))
+	}
+
+	// Each diagnostic signal writes a bundle and Navigator keeps running
+	for i, sig := range diagnostics.Signals {
+		if err := syscall.Kill(os.Getpid(), sig.(syscall.Signal)); err != nil {
+			t.Fatal(err)
+		}
+		waitForBundles(t, bundleDir, i+1)
+		time.Sleep(5 * time.Millisecond) // Bundle names have millisecond resolution
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned after diagnostic signal: %v", err)
+	default:
+	}
+
+	files := waitForBundles(t, bundleDir, len(diagnostics.Signals))
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written map[string]interface{}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("bundle is not valid JSON: %v", err)
+	}
+	for _, section := range []string{"config", "tenants", "managed_processes", "websockets", "memory", "goroutines"} {
+		if _, ok := written[section]; !ok {
+			t.Errorf("bundle missing %q", section)
+		}
+	}
+	if strings.Contains(string(data), "do-not-leak") {
+		t.Error("bundle should redact environment values")
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not shut down after SIGTERM")
+	}
+}
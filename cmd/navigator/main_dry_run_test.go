@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "navigator.yml")
+	yaml := `
+managed_processes:
+  - name: worker
+    command: bin/jobs
+    env: {QUEUE: default}
+hooks:
+  server:
+    start:
+      - command: bin/prepare
+        timeout: 30s
+applications:
+  pools:
+    start_port: 5000
+  tenants:
+    - name: boston
+      path: /boston/
+      runtime: node
+      server: server.js
+      args: ["--port", "{{port}}"]
+routes:
+  reverse_proxies:
+    - name: api
+      prefix: /api/
+      target: http://localhost:9000
+`
+	if err := os.WriteFile(configFile, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := dryRun(&out, configFile); err != nil {
+		t.Fatalf("dryRun: %v", err)
+	}
+	var report struct {
+		ManagedProcesses []struct {
+			Name string            `json:"name"`
+			Env  map[string]string `json:"env"`
+		} `json:"managed_processes"`
+		Hooks []struct {
+			Type    string `json:"type"`
+			Command string `json:"command"`
+			Timeout string `json:"timeout"`
+		} `json:"hooks"`
+		Tenants []struct {
+			Port    int `json:"port"`
+			Command struct {
+				Command string   `json:"command"`
+				Args    []string `json:"args"`
+			} `json:"command"`
+		} `json:"tenants"`
+		Routes struct {
+			ReverseProxies []struct {
+				Target string `json:"target"`
+			} `json:"reverse_proxies"`
+		} `json:"routes"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("output is not a report: %v\n%s", err, out.String())
+	}
+
+	if len(report.ManagedProcesses) != 1 || report.ManagedProcesses[0].Env["QUEUE"] != "default" {
+		t.Errorf("managed_processes = %+v", report.ManagedProcesses)
+	}
+	if len(report.Hooks) != 1 || report.Hooks[0].Type != "server.start" || report.Hooks[0].Timeout != "30s" {
+		t.Errorf("hooks = %+v", report.Hooks)
+	}
+	if len(report.Tenants) != 1 || report.Tenants[0].Port != 5000 ||
+		report.Tenants[0].Command.Command != "node" || report.Tenants[0].Command.Args[2] != "5000" {
+		t.Errorf("tenants = %+v", report.Tenants)
+	}
+	if len(report.Routes.ReverseProxies) != 1 || report.Routes.ReverseProxies[0].Target != "http://localhost:9000" {
+		t.Errorf("routes = %+v", report.Routes)
+	}
+
+	if err := dryRun(&out, filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
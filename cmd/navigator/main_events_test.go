@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func TestReloadFailureEmitsEvent(t *testing.T) {
+	received := make(chan events.Event, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event events.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		received <- event
+	}))
+	defer receiver.Close()
+	events.Configure([]config.EventHookConfig{{URL: receiver.URL, Timeout: config.Duration(time.Second)}})
+	defer events.Configure(nil)
+
+	cfg := &config.Config{}
+	lifecycle := &ServerLifecycle{
+		configFile:     "nonexistent-config.yml",
+		cfg:            cfg,
+		appManager:     process.NewAppManager(cfg),
+		processManager: process.NewManager(cfg),
+		idleManager:    idle.NewManager(cfg, "", time.Time{}, nil),
+	}
+	lifecycle.handleReload()
+
+	select {
+	case event := <-received:
+		if event.Type != events.ReloadFailed {
+			t.Errorf("type = %q, want %q", event.Type, events.ReloadFailed)
+		}
+		if event.Details["config_file"] != "nonexistent-config.yml" {
+			t.Errorf("config_file = %v", event.Details["config_file"])
+		}
+		if event.Details["error"] == "" || event.Details["error"] == nil {
+			t.Error("expected the load error in the details")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload.failed was not delivered")
+	}
+}
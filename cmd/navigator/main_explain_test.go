@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/server"
+)
+
+func TestExplainURL(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "about.html"), []byte("about"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	htpasswd := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "navigator.yml")
+	yaml := `
+server:
+  static:
+    public_dir: ` + dir + `
+    try_files: [.html]
+auth:
+  enabled: true
+  htpasswd: ` + htpasswd + `
+  public_paths: [/about]
+applications:
+  tenants:
+    - name: main
+      path: /app/
+`
+	if err := os.WriteFile(configFile, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := explainURL(&out, "/about", configFile); err != nil {
+		t.Fatalf("explainURL: %v", err)
+	}
+	var trace server.RouteTrace
+	if err := json.Unmarshal(out.Bytes(), &trace); err != nil {
+		t.Fatalf("output is not a trace: %v\n%s", err, out.String())
+	}
+	if trace.Disposition != "static" || trace.Target != filepath.Join(dir, "about.html") {
+		t.Errorf("disposition = %q %q, want static about.html", trace.Disposition, trace.Target)
+	}
+
+	if err := explainURL(&out, "/about", filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,122 @@
+//go:build unix
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
+)
+
+// pollHealth fetches the detailed health check until accept returns true
+func pollHealth(t *testing.T, url string, accept func(status int, report server.DetailedHealth) bool) server.DetailedHealth {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			var report server.DetailedHealth
+			decodeErr := json.NewDecoder(resp.Body).Decode(&report)
+			_ = resp.Body.Close()
+			if decodeErr == nil && accept(resp.StatusCode, report) {
+				return report
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health check never reached the expected state: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func fileHash(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDetailedHealthCheckShutdown(t *testing.T) {
+	server.SetDraining(false)
+	t.Cleanup(func() { server.SetDraining(false) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	configFile := filepath.Join(t.TempDir(), "navigator.yml")
+	configContent := fmt.Sprintf(`
+server:
+  listen: "%d"
+  health_check:
+    path: /up
+    detailed_path: /_navigator/health
+    drain_delay: 1s
+applications:
+  tenants: []
+`, port)
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	lifecycle := &ServerLifecycle{
+		configFile:     configFile,
+		cfg:            cfg,
+		appManager:     process.NewAppManager(cfg),
+		processManager: process.NewManager(cfg),
+		idleManager:    idle.NewManager(cfg, "", time.Time{}, nil),
+	}
+	done := make(chan error, 1)
+	go func() { done <- lifecycle.Run() }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/_navigator/health", port)
+	report := pollHealth(t, url, func(status int, report server.DetailedHealth) bool {
+		return status == http.StatusOK
+	})
+	original := fileHash(t, configFile)
+	if !report.Ready || report.ConfigHash != original || report.Version != version {
+		t.Errorf("report = %+v, want ready with config hash %s and version %s", report, original, version)
+	}
+
+	// Reloads changing the reported hash are covered end to end by
+	// internal/harness
+	// Ready turns false while the listener is still accepting connections
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	pollHealth(t, url, func(status int, report server.DetailedHealth) bool {
+		return status == http.StatusServiceUnavailable && !report.Ready
+	})
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not shut down after SIGTERM")
+	}
+}
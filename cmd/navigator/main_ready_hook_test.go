@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -151,7 +152,7 @@ logging:
 	}
 
 	// Execute ready hooks (simulating initial start)
-	err = process.ExecuteServerHooks(cfg.Hooks.Ready, "ready")
+	err = process.ExecuteServerHooks(context.Background(), cfg.Hooks.Ready, "ready")
 	if err != nil {
 		t.Fatalf("Failed to execute ready hooks: %v", err)
 	}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+func TestCGIReloadRequestRestartsTenant(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("CGI test script uses /bin/sh")
+	}
+
+	tempDir := t.TempDir()
+	script := filepath.Join(tempDir, "restart.cgi")
+	if err := os.WriteFile(script, []byte(`#!/bin/sh
+echo '{"restart_tenants": ["boston"], "reason": "seating chart changed"}' > "$NAVIGATOR_RELOAD_FILE"
+echo "Content-Type: text/plain"
+echo
+echo ok
+`), 0755); err != nil {
+		t.Fatalf("Failed to write CGI script: %v", err)
+	}
+	configFile := filepath.Join(tempDir, "navigator.yml")
+	if err := os.WriteFile(configFile, []byte(`
+server:
+  cgi_scripts:
+    - path: /restart
+      script: `+script+`
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      name: boston
+    - path: /showcase/2025/raleigh/
+      name: raleigh
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	appManager := process.NewAppManager(cfg)
+	appManager.StubApps(4999)
+	t.Cleanup(appManager.Cleanup)
+	boston, _ := appManager.GetOrStartApp("boston")
+	raleigh, _ := appManager.GetOrStartApp("raleigh")
+
+	lifecycle := &ServerLifecycle{
+		configFile:     configFile,
+		cfg:            cfg,
+		configLoadTime: time.Now(),
+		appManager:     appManager,
+		processManager: process.NewManager(cfg),
+		idleManager:    idle.NewManager(cfg, configFile, time.Now(), nil),
+		reloadChan:     make(chan utils.ReloadDecision, 1),
+	}
+	handler := server.CreateHandler(cfg, appManager, nil, lifecycle.idleManager, nil,
+		func() string { return lifecycle.configFile },
+		func() time.Time { return lifecycle.configLoadTime },
+		func(request utils.ReloadDecision) { lifecycle.reloadChan <- request })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/restart", nil))
+	if rec.Code != 200 {
+		t.Fatalf("CGI status = %d, want 200", rec.Code)
+	}
+
+	var request utils.ReloadDecision
+	select {
+	case request = <-lifecycle.reloadChan:
+	default:
+		t.Fatal("CGI script's reload request was not passed on")
+	}
+	if request.ShouldReload || request.Source != "cgi "+script || request.Reason != "seating chart changed" {
+		t.Errorf("Reload request = %+v", request)
+	}
+	lifecycle.handleReloadRequest(request)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		app, running := appManager.GetApp("boston")
+		if running && app != boston {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("boston was not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if app, _ := appManager.GetApp("raleigh"); app != raleigh {
+		t.Error("raleigh was restarted, but only boston was named")
+	}
+}
+
+func TestValidateOnlyReloadRequest(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "navigator.yml")
+	if err := os.WriteFile(configFile, []byte(`
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      name: boston
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	generated := filepath.Join(tempDir, "navigator-new.yml")
+	if err := os.WriteFile(generated, []byte(`
+applications:
+  tenants:
+    - path: /showcase/2025/raleigh/
+      name: raleigh
+      root: `+filepath.Join(tempDir, "missing")+`
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	lifecycle := &ServerLifecycle{configFile: configFile, cfg: cfg}
+
+	lifecycle.handleReloadRequest(utils.ReloadDecision{
+		ValidateOnly:  true,
+		NewConfigFile: generated,
+		Source:        "cgi generate.cgi",
+	})
+	if lifecycle.cfg != cfg || lifecycle.configFile != configFile {
+		t.Errorf("validate_only reloaded %s", lifecycle.configFile)
+	}
+	status := lifecycle.reloads.Status()
+	if status.Invalid != 1 || status.Reloaded != 0 || len(status.History) != 1 || status.History[0].Outcome != server.ReloadInvalid {
+		t.Errorf("reload status = %+v, want one invalid validation", status)
+	} else if errors := status.History[0].Errors; len(errors) != 1 || !strings.Contains(errors[0], "missing") {
+		t.Errorf("recorded errors = %q, want the missing root", errors)
+	}
+
+	if err := os.MkdirAll(filepath.Join(tempDir, "missing"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lifecycle.handleReloadRequest(utils.ReloadDecision{ValidateOnly: true, NewConfigFile: generated, Source: "cgi generate.cgi"})
+	if status := lifecycle.reloads.Status(); status.Validated != 1 || lifecycle.cfg != cfg {
+		t.Errorf("reload status = %+v, want one valid validation and nothing reloaded", status)
+	}
+}
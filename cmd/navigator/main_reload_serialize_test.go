@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// waitForReadyHooks waits for the signal loop to be told ready hooks finished
+func waitForReadyHooks(t *testing.T, lifecycle *ServerLifecycle) error {
+	t.Helper()
+	select {
+	case err := <-lifecycle.reloadHooksDone:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("Ready hooks did not finish")
+		return nil
+	}
+}
+
+func TestRapidReloadsAreSerialized(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Ready hook uses /bin/sh")
+	}
+
+	tempDir := t.TempDir()
+	output := filepath.Join(tempDir, "prerender.log")
+	configFile := filepath.Join(tempDir, "navigator.yml")
+	if err := os.WriteFile(configFile, []byte(`
+hooks:
+  server:
+    ready:
+      - command: "sleep 1 && echo done >> `+output+`"
+        shell: true
+        timeout: 10s
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	lifecycle := &ServerLifecycle{
+		configFile:      configFile,
+		cfg:             cfg,
+		appManager:      process.NewAppManager(cfg),
+		processManager:  process.NewManager(cfg),
+		idleManager:     idle.NewManager(cfg, configFile, time.Now(), nil),
+		reloadHooksDone: make(chan error, 1),
+	}
+
+	// The first reload starts its slow ready hooks; the second cancels them
+	// and waits, and the third is merged into the second
+	for i := 0; i < 3; i++ {
+		lifecycle.scheduleReload(utils.ReloadDecision{ShouldReload: true, Source: "SIGHUP"})
+	}
+	if status := lifecycle.reloads.Status(); !status.HooksRunning || !status.Pending {
+		t.Errorf("Status = %+v, want hooks running and a reload pending", status)
+	}
+
+	lifecycle.finishReadyHooks(waitForReadyHooks(t, lifecycle))
+	if err := waitForReadyHooks(t, lifecycle); err != nil {
+		t.Errorf("Ready hooks of the second reload failed: %v", err)
+	}
+	lifecycle.finishReadyHooks(nil)
+
+	status := lifecycle.reloads.Status()
+	if status.Reloaded != 2 || status.Cancelled != 1 || status.Coalesced != 1 {
+		t.Errorf("Reloaded %d, cancelled %d, coalesced %d; want 2, 1, 1",
+			status.Reloaded, status.Cancelled, status.Coalesced)
+	}
+	var outcomes []string
+	for _, attempt := range status.History {
+		outcomes = append(outcomes, attempt.Outcome)
+	}
+	want := []string{server.ReloadApplied, server.ReloadCoalesced, server.ReloadCancelled, server.ReloadApplied}
+	if strings.Join(outcomes, " ") != strings.Join(want, " ") {
+		t.Errorf("History = %v, want %v", outcomes, want)
+	}
+	if status.HooksRunning || status.Pending {
+		t.Errorf("Status = %+v, want nothing running or pending", status)
+	}
+
+	// Only the second reload's hooks ran to completion
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Ready hook output: %v", err)
+	}
+	if runs := strings.Count(string(content), "done"); runs != 1 {
+		t.Errorf("Ready hooks completed %d times, want 1", runs)
+	}
+}
+
+func TestMergeReloadRequests(t *testing.T) {
+	pending := utils.ReloadDecision{Source: "cgi /restart", RestartTenants: []string{"boston"}}
+	later := utils.ReloadDecision{
+		ShouldReload:   true,
+		NewConfigFile:  "/etc/navigator-2.yml",
+		RestartTenants: []string{"boston", "raleigh"},
+		Source:         "SIGHUP",
+	}
+
+	merged := mergeReloadRequests(pending, later)
+	if !merged.ShouldReload || merged.NewConfigFile != "/etc/navigator-2.yml" || merged.Source != "cgi /restart" {
+		t.Errorf("Merged request = %+v", merged)
+	}
+	if strings.Join(merged.RestartTenants, ",") != "boston,raleigh" {
+		t.Errorf("RestartTenants = %v, want [boston raleigh]", merged.RestartTenants)
+	}
+}
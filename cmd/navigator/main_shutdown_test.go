@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
+)
+
+// shutdownLog collects JSON log records written during a shutdown
+type shutdownLog struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *shutdownLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+// record returns the first log record with the given message
+func (l *shutdownLog) record(msg string) map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range bytes.Split(l.buf.Bytes(), []byte("\n")) {
+		var record map[string]interface{}
+		if json.Unmarshal(line, &record) == nil && record["msg"] == msg {
+			return record
+		}
+	}
+	return nil
+}
+
+// startBlockedServer serves a lifecycle whose only request blocks until the
+// test ends, and returns once that request is in progress
+func startBlockedServer(t *testing.T, shutdown config.ShutdownConfig) (*ServerLifecycle, *shutdownLog) {
+	t.Helper()
+	server.SetDraining(false)
+	t.Cleanup(func() { server.SetDraining(false) })
+
+	logs := &shutdownLog{}
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(original) })
+
+	cfg := &config.Config{}
+	cfg.Server.Shutdown = shutdown
+	lifecycle := &ServerLifecycle{
+		cfg:            cfg,
+		appManager:     process.NewAppManager(cfg),
+		processManager: process.NewManager(cfg),
+		idleManager:    idle.NewManager(cfg, "", time.Time{}, nil),
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	lifecycle.srv = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}),
+		ConnState: lifecycle.connections.ConnState,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = lifecycle.srv.Serve(listener) }()
+	go func() {
+		if resp, err := http.Get("http://" + listener.Addr().String()); err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not reach the server")
+	}
+	return lifecycle, logs
+}
+
+// assertAborted checks the shutdown log reports one aborted request
+func assertAborted(t *testing.T, logs *shutdownLog) {
+	t.Helper()
+	record := logs.record("Stopped serving requests")
+	if record == nil {
+		t.Fatal("shutdown did not log drained and aborted connections")
+	}
+	if record["drained"] != float64(0) || record["aborted"] != float64(1) {
+		t.Errorf("drained = %v, aborted = %v; want 0 and 1", record["drained"], record["aborted"])
+	}
+}
+
+func TestSecondShutdownSignalAbortsDrain(t *testing.T) {
+	lifecycle, logs := startBlockedServer(t, config.ShutdownConfig{
+		Timeout:         config.Duration(time.Minute),
+		ImmediateSignal: config.ImmediateSignalSecond,
+	})
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- lifecycle.handleShutdown(syscall.SIGINT, signals) }()
+
+	// The first signal drains, waiting for the request to finish
+	select {
+	case <-done:
+		t.Fatal("shutdown finished with a request still in progress")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The second signal aborts it
+	signals <- syscall.SIGINT
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second signal did not abort the drain")
+	}
+	assertAborted(t, logs)
+}
+
+func TestShutdownTimeoutAbortsDrain(t *testing.T) {
+	lifecycle, logs := startBlockedServer(t, config.ShutdownConfig{
+		Timeout: config.Duration(time.Minute),
+	})
+	clk := clock.NewFake(time.Now())
+	lifecycle.clock = clk
+
+	done := make(chan error, 1)
+	go func() { done <- lifecycle.handleShutdown(syscall.SIGTERM, nil) }()
+
+	// The drain waits for the request until the timeout passes
+	clk.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("shutdown finished with a request still in progress")
+	default:
+	}
+	clk.Advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown timeout did not abort the drain")
+	}
+	assertAborted(t, logs)
+}
+
+func TestImmediateSignalSkipsDrain(t *testing.T) {
+	lifecycle, logs := startBlockedServer(t, config.ShutdownConfig{
+		Timeout:         config.Duration(time.Minute),
+		ImmediateSignal: "SIGINT",
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- lifecycle.handleShutdown(syscall.SIGINT, nil) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("immediate signal waited for the request")
+	}
+	assertAborted(t, logs)
+}
+
+func TestIsImmediateSignal(t *testing.T) {
+	tests := []struct {
+		immediate string
+		sig       os.Signal
+		draining  bool
+		want      bool
+	}{
+		{config.ImmediateSignalSecond, syscall.SIGTERM, false, false},
+		{config.ImmediateSignalSecond, syscall.SIGTERM, true, true},
+		{config.ImmediateSignalNone, syscall.SIGINT, true, false},
+		{"SIGINT", syscall.SIGINT, false, true},
+		{"SIGINT", syscall.SIGTERM, false, false},
+		{"SIGINT", syscall.SIGTERM, true, false},
+	}
+	for _, tt := range tests {
+		if got := isImmediateSignal(tt.immediate, tt.sig, tt.draining); got != tt.want {
+			t.Errorf("isImmediateSignal(%q, %v, %v) = %v, want %v", tt.immediate, tt.sig, tt.draining, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +16,7 @@ import (
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/idle"
 	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
 )
 
 func TestSetupLogging(t *testing.T) {
@@ -64,6 +68,77 @@ func TestSetupLogging(t *testing.T) {
 	}
 }
 
+func TestSetupLoggingSeparatesDestinations(t *testing.T) {
+	dir := t.TempDir()
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		setupLogging(&config.Config{})
+		slog.SetDefault(defaultLogger)
+	})
+
+	logPaths := func(generation string) (string, string) {
+		return filepath.Join(dir, "navigator-"+generation+".log"), filepath.Join(dir, "access-"+generation+".log")
+	}
+	load := func(generation string) {
+		appPath, accessPath := logPaths(generation)
+		cfg, err := config.ParseYAML([]byte(`
+logging:
+  format: json
+  app:
+    destination: ` + appPath + `
+    format: text
+    level: debug
+  access:
+    destinations: [` + accessPath + `]
+`))
+		if err != nil {
+			t.Fatalf("ParseYAML failed: %v", err)
+		}
+		setupLogging(cfg)
+	}
+	logBoth := func(message string) {
+		slog.Debug(message)
+		req := httptest.NewRequest("GET", "/showcase/"+message, nil)
+		server.LogRequest(req, http.StatusOK, 2, time.Now(), nil, false)
+		server.FlushAccessLog()
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Reload rebuilds both pipelines, so each generation has its own files
+	load("1")
+	logBoth("first")
+	load("2")
+	logBoth("second")
+
+	for _, generation := range []string{"1", "2"} {
+		message := map[string]string{"1": "first", "2": "second"}[generation]
+		appPath, accessPath := logPaths(generation)
+		appLog, accessLog := read(appPath), read(accessPath)
+
+		if !strings.Contains(appLog, "level=DEBUG msg="+message) {
+			t.Errorf("Operational log %s = %q, want a text debug entry for %s", generation, appLog, message)
+		}
+		if strings.Contains(appLog, "/showcase/") {
+			t.Errorf("Operational log %s contains access entries: %q", generation, appLog)
+		}
+		if !strings.Contains(accessLog, `"uri":"/showcase/`+message+`"`) {
+			t.Errorf("Access log %s = %q, want a JSON entry for %s", generation, accessLog, message)
+		}
+		if strings.Contains(accessLog, "msg=") {
+			t.Errorf("Access log %s contains operational entries: %q", generation, accessLog)
+		}
+		if strings.Count(accessLog, "\n") != 1 {
+			t.Errorf("Access log %s should have exactly one entry: %q", generation, accessLog)
+		}
+	}
+}
+
 func TestInitLogger(t *testing.T) {
 	// Test that initLogger sets up a basic text logger
 	initLogger()
@@ -147,110 +222,26 @@ func TestLogLevelFromEnvironment(t *testing.T) {
 	}
 }
 
-func TestHandleCommandLineArgs(t *testing.T) {
-	tests := []struct {
-		name        string
-		args        []string
-		expectError bool
-		errorText   string
-		shouldExit  bool
-	}{
-		{
-			name:        "No arguments should pass",
-			args:        []string{"navigator"},
-			expectError: false,
-		},
-		{
-			name:        "Config file argument should pass",
-			args:        []string{"navigator", "config.yml"},
-			expectError: false,
-		},
-		{
-			name:        "Invalid -s option should fail",
-			args:        []string{"navigator", "-s"},
-			expectError: true,
-			errorText:   "option -s requires 'reload'",
-		},
-		{
-			name:        "Invalid -s option with wrong arg should fail",
-			args:        []string{"navigator", "-s", "invalid"},
-			expectError: true,
-			errorText:   "option -s requires 'reload'",
-		},
-		{
-			name:        "-s reload should attempt to send signal",
-			args:        []string{"navigator", "-s", "reload"},
-			expectError: false, // Will be handled specially below
-		},
+func TestConfigSchemaAndStrictFlag(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeConfigSchema(&buf); err != nil {
+		t.Fatalf("writeConfigSchema() error = %v", err)
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Save original args
-			oldArgs := os.Args
-			defer func() { os.Args = oldArgs }()
-
-			// Set test args
-			os.Args = tt.args
-
-			err := handleCommandLineArgs()
-
-			// Special handling for reload signal test - it may succeed or fail
-			if tt.name == "-s reload should attempt to send signal" {
-				// The reload command may succeed or fail depending on whether Navigator is running
-				// Both outcomes are acceptable for this test
-				return
-			}
-
-			if tt.expectError && err == nil {
-				t.Errorf("Expected error but got none")
-			}
-			if !tt.expectError && err != nil {
-				t.Errorf("Expected no error but got: %v", err)
-			}
-			if tt.errorText != "" && (err == nil || !strings.Contains(err.Error(), tt.errorText)) {
-				t.Errorf("Expected error containing %q, got: %v", tt.errorText, err)
-			}
-		})
+	var schema map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("Schema is not JSON: %v", err)
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); !ok || properties["applications"] == nil {
+		t.Errorf("Schema missing applications: %v", schema["properties"])
 	}
-}
-
-func TestPrintHelp(t *testing.T) {
-	// Capture stdout
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Call printHelp
-	printHelp()
-
-	// Restore stdout
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// Read captured output
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
 
-	// Verify help text contains expected content
-	expectedTexts := []string{
-		"Navigator - Web application server",
-		"Usage:",
-		"navigator [config-file]",
-		"navigator -s reload",
-		"navigator --help",
-		"Default config file: config/navigator.yml",
-		"Signals:",
-		"SIGHUP",
-		"SIGTERM",
-		"SIGINT",
-	}
-
-	for _, expected := range expectedTexts {
-		if !strings.Contains(output, expected) {
-			t.Errorf("Help text missing expected content: %q\nGot: %s", expected, output)
-		}
+	defer config.SetStrict(false)
+	args := stripConfigFlags([]string{"navigator", "--strict-config", "navigator.yml"})
+	if strings.Join(args, " ") != "navigator navigator.yml" {
+		t.Errorf("stripConfigFlags() = %v", args)
+	}
+	if _, err := config.ParseYAML([]byte("colour: blue\n")); err == nil {
+		t.Error("--strict-config should reject unknown keys")
 	}
 }
 
@@ -412,52 +403,6 @@ logging:
 	}
 }
 
-func TestConfigFilePathLogic(t *testing.T) {
-	// Test the config file path determination logic from main()
-	tests := []struct {
-		name         string
-		args         []string
-		expectedPath string
-	}{
-		{
-			name:         "Default config path with no args",
-			args:         []string{"navigator"},
-			expectedPath: "config/navigator.yml",
-		},
-		{
-			name:         "Custom config path",
-			args:         []string{"navigator", "custom-config.yml"},
-			expectedPath: "custom-config.yml",
-		},
-		{
-			name:         "Ignore flag arguments",
-			args:         []string{"navigator", "-s", "reload"},
-			expectedPath: "config/navigator.yml",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Save original args
-			oldArgs := os.Args
-			defer func() { os.Args = oldArgs }()
-
-			// Set test args
-			os.Args = tt.args
-
-			// Replicate the config file path logic from main()
-			configFile := "config/navigator.yml"
-			if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
-				configFile = os.Args[1]
-			}
-
-			if configFile != tt.expectedPath {
-				t.Errorf("Expected config file path %q, got %q", tt.expectedPath, configFile)
-			}
-		})
-	}
-}
-
 func TestServerConfigReload(t *testing.T) {
 	// Create a temporary config file with server settings
 	tempDir := t.TempDir()
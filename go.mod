@@ -3,13 +3,15 @@ module github.com/rubys/navigator
 go 1.24.1
 
 require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/klauspost/compress v1.18.0
 	github.com/tg123/go-htpasswd v1.2.4
+	golang.org/x/crypto v0.37.0
+	golang.org/x/sys v0.32.0
+	golang.org/x/term v0.31.0
 	gopkg.in/yaml.v3 v3.0.1
+	zgo.at/isbot v1.0.0
 )
 
-require (
-	github.com/GehirnInc/crypt v0.0.0-20230320061759-8cc1b52080c5 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
-	zgo.at/isbot v1.0.0 // indirect
-)
+require github.com/GehirnInc/crypt v0.0.0-20230320061759-8cc1b52080c5 // indirect
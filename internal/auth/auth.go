@@ -5,7 +5,6 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,9 +18,13 @@ type BasicAuth struct {
 	File     *htpasswd.File
 	Realm    string
 	Exclude  []string
+	Paths    []string     // Path patterns covered when this is an auth scope
+	scopes   []*BasicAuth // Path-scoped configurations (site-wide configuration only)
 	filename string       // Path to htpasswd file for reload checks
 	mtime    time.Time    // Last modification time of htpasswd file
 	mu       sync.RWMutex // Protects concurrent access to File, filename, and mtime
+
+	routesOnly bool // Auth is disabled; only routes with auth: required check credentials
 }
 
 // LoadAuthFile loads an htpasswd file for authentication
@@ -138,7 +141,7 @@ func (a *BasicAuth) RequireAuth(w http.ResponseWriter) {
 
 	realm := a.Realm
 	if realm == "" {
-		realm = "Restricted"
+		realm = config.DefaultAuthRealm
 	}
 
 	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
@@ -147,42 +150,20 @@ func (a *BasicAuth) RequireAuth(w http.ResponseWriter) {
 
 // ShouldExcludeFromAuth checks if a path should be excluded from authentication
 func ShouldExcludeFromAuth(path string, cfg *config.Config) bool {
+	_, ok := ExclusionRule(path, cfg)
+	return ok
+}
+
+// ExclusionRule returns the auth.public_paths entry or regex auth pattern that
+// excludes path from authentication
+func ExclusionRule(path string, cfg *config.Config) (string, bool) {
 	// Check simple exclusion paths first (from YAML auth.public_paths)
 	for _, excludePath := range cfg.Auth.PublicPaths {
-		// Handle glob patterns like *.css
-		if strings.HasPrefix(excludePath, "*") {
-			if strings.HasSuffix(path, excludePath[1:]) {
-				slog.Debug("Auth exclusion: glob pattern match",
-					"path", path,
-					"pattern", excludePath)
-				return true
-			}
-		} else if strings.Contains(excludePath, "*") {
-			// Handle patterns like /path/*.ext
-			if matched, _ := filepath.Match(excludePath, path); matched {
-				slog.Debug("Auth exclusion: filepath pattern match",
-					"path", path,
-					"pattern", excludePath)
-				return true
-			}
-		} else {
-			// Check for prefix match (paths ending with /)
-			if strings.HasSuffix(excludePath, "/") {
-				if strings.HasPrefix(path, excludePath) {
-					slog.Debug("Auth exclusion: prefix match",
-						"path", path,
-						"prefix", excludePath)
-					return true
-				}
-			} else {
-				// Exact match
-				if path == excludePath {
-					slog.Debug("Auth exclusion: exact match",
-						"path", path,
-						"match", excludePath)
-					return true
-				}
-			}
+		if kind, ok := matchPathPattern(path, excludePath); ok {
+			slog.Debug("Auth exclusion: "+kind+" match",
+				"path", path,
+				"pattern", excludePath)
+			return excludePath, true
 		}
 	}
 
@@ -193,7 +174,7 @@ func ShouldExcludeFromAuth(path string, cfg *config.Config) bool {
 				"path", path,
 				"pattern", authPattern.Pattern.String(),
 				"action", authPattern.Action)
-			return true
+			return authPattern.Pattern.String(), true
 		}
 	}
 
@@ -201,7 +182,7 @@ func ShouldExcludeFromAuth(path string, cfg *config.Config) bool {
 
 	slog.Debug("Auth required: no exclusion matched",
 		"path", path)
-	return false
+	return "", false
 }
 
 // IsEnabled checks if authentication is enabled
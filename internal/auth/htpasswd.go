@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tg123/go-htpasswd"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrHtpasswdModified is returned by Save when the file changed after it was
+// read, so writing it would discard another edit
+var ErrHtpasswdModified = errors.New("htpasswd file was modified by another process; retry")
+
+// Hash formats reported by HtpasswdFile.Entries
+const (
+	FormatBcrypt      = "bcrypt"
+	FormatApr1        = "apr1"
+	FormatMD5Crypt    = "md5-crypt"
+	FormatSHA         = "sha"
+	FormatSSHA        = "ssha"
+	FormatSHA256Crypt = "sha256-crypt"
+	FormatSHA512Crypt = "sha512-crypt"
+	FormatPlain       = "plain" // Anything else, compared as plain text
+	FormatUnsupported = "unsupported"
+)
+
+// HtpasswdEntry is a user of an htpasswd file and the format of their hash
+type HtpasswdEntry struct {
+	User   string
+	Format string
+}
+
+// HtpasswdFile is an htpasswd file being edited. Lines other than the users
+// changed, including comments and entries Navigator can't read, are written
+// back as they were.
+type HtpasswdFile struct {
+	Path   string
+	lines  []string
+	exists bool
+	mtime  time.Time
+	size   int64
+}
+
+// ReadHtpasswd reads an htpasswd file for editing; a missing file reads as
+// empty and is created by Save
+func ReadHtpasswd(path string) (*HtpasswdFile, error) {
+	file := &HtpasswdFile{Path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	file.exists, file.mtime, file.size = true, stat.ModTime(), stat.Size()
+
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text != "" {
+		file.lines = strings.Split(text, "\n")
+	}
+	return file, nil
+}
+
+// splitEntry returns the user and hash of an htpasswd line, split the way
+// go-htpasswd splits it; comments and lines without a colon aren't entries
+func splitEntry(line string) (user, hash string, ok bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	return strings.Cut(line, ":")
+}
+
+// Entries returns the file's users in order
+func (f *HtpasswdFile) Entries() []HtpasswdEntry {
+	var entries []HtpasswdEntry
+	for _, line := range f.lines {
+		if user, hash, ok := splitEntry(line); ok {
+			entries = append(entries, HtpasswdEntry{User: user, Format: hashFormat(line, hash)})
+		}
+	}
+	return entries
+}
+
+// hashFormat names the format of hash, or FormatUnsupported if the auth
+// loader would reject line
+func hashFormat(line, hash string) string {
+	if _, err := parseLine(line); err != nil {
+		return FormatUnsupported
+	}
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return FormatBcrypt
+	case strings.HasPrefix(hash, "$apr1$"):
+		return FormatApr1
+	case strings.HasPrefix(hash, "$1$"):
+		return FormatMD5Crypt
+	case strings.HasPrefix(hash, "{SHA}"):
+		return FormatSHA
+	case strings.HasPrefix(hash, "{SSHA}"):
+		return FormatSSHA
+	case strings.HasPrefix(hash, "$5$"):
+		return FormatSHA256Crypt
+	case strings.HasPrefix(hash, "$6$"):
+		return FormatSHA512Crypt
+	default:
+		return FormatPlain
+	}
+}
+
+// parseLine reads line with the parsers LoadAuthFile uses, returning an
+// error if they reject it
+func parseLine(line string) (*htpasswd.File, error) {
+	var badLine error
+	file, err := htpasswd.NewFromReader(strings.NewReader(line), htpasswd.DefaultSystems, func(err error) {
+		badLine = err
+	})
+	if err == nil {
+		err = badLine
+	}
+	return file, err
+}
+
+// Verify reports whether password is user's password, checked as the auth
+// loader would check it
+func (f *HtpasswdFile) Verify(user, password string) (bool, error) {
+	var matched *htpasswd.File
+	for _, line := range f.lines {
+		if entryUser, _, ok := splitEntry(line); ok && entryUser == user {
+			file, err := parseLine(line)
+			if err != nil {
+				return false, fmt.Errorf("user %q: %w", user, err)
+			}
+			// go-htpasswd keeps the last entry for a user
+			matched = file
+		}
+	}
+	if matched == nil {
+		return false, fmt.Errorf("user %q not found", user)
+	}
+	return matched.Match(user, password), nil
+}
+
+// SetPassword sets user's password, hashed with bcrypt at cost. An existing
+// user's entry is replaced in place; a new user is appended.
+func (f *HtpasswdFile) SetPassword(user, password string, cost int) error {
+	if user == "" || strings.ContainsAny(user, ":\r\n") || strings.TrimSpace(user) != user || strings.HasPrefix(user, "#") {
+		return fmt.Errorf("invalid user name %q", user)
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return err
+	}
+
+	entry := user + ":" + string(hash)
+	replaced := false
+	lines := f.lines[:0:0]
+	for _, line := range f.lines {
+		if entryUser, _, ok := splitEntry(line); ok && entryUser == user {
+			if replaced {
+				continue // Drop duplicates, which would shadow the new entry
+			}
+			line, replaced = entry, true
+		}
+		lines = append(lines, line)
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+	f.lines = lines
+	return nil
+}
+
+// Delete removes user's entries, reporting whether there were any
+func (f *HtpasswdFile) Delete(user string) bool {
+	lines := f.lines[:0:0]
+	for _, line := range f.lines {
+		if entryUser, _, ok := splitEntry(line); ok && entryUser == user {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	deleted := len(lines) != len(f.lines)
+	f.lines = lines
+	return deleted
+}
+
+// Save writes the file through a temporary file renamed into place, so a
+// running Navigator never reads it half written. It fails with
+// ErrHtpasswdModified if the file changed after it was read.
+func (f *HtpasswdFile) Save() error {
+	mode := fs.FileMode(0640)
+	stat, err := os.Stat(f.Path)
+	switch {
+	case err == nil:
+		if !f.exists || !stat.ModTime().Equal(f.mtime) || stat.Size() != f.size {
+			return ErrHtpasswdModified
+		}
+		mode = stat.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	case f.exists:
+		return ErrHtpasswdModified
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".htpasswd-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	var content string
+	if len(f.lines) > 0 {
+		content = strings.Join(f.lines, "\n") + "\n"
+	}
+	_, err = tmp.WriteString(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, f.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if stat, err := os.Stat(f.Path); err == nil {
+		f.exists, f.mtime, f.size = true, stat.ModTime(), stat.Size()
+	}
+	return nil
+}
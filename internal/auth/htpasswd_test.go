@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mixedHtpasswd has a comment, a blank line, users in several hash formats,
+// and a line the auth loader rejects; every password is "secret"
+const mixedHtpasswd = `# Studio accounts
+apache:$apr1$saltsalt$LrttParrLPdxvgutaSXWJ0
+legacy:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=
+
+broken:{SHA}not-base64
+plain:secret
+no colon here
+`
+
+func TestHtpasswdRoundTrip(t *testing.T) {
+	path := writeHtpasswd(t, "htpasswd", mixedHtpasswd)
+	file, err := ReadHtpasswd(path)
+	if err != nil {
+		t.Fatalf("ReadHtpasswd() error = %v", err)
+	}
+
+	want := []HtpasswdEntry{
+		{"apache", FormatApr1},
+		{"legacy", FormatSHA},
+		{"broken", FormatUnsupported},
+		{"plain", FormatPlain},
+	}
+	if entries := file.Entries(); !reflect.DeepEqual(entries, want) {
+		t.Errorf("Entries() = %v, want %v", entries, want)
+	}
+
+	if err := file.SetPassword("added", "s3cret", bcrypt.MinCost); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if err := file.SetPassword("legacy", "n3w", bcrypt.MinCost); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if !file.Delete("plain") || file.Delete("nobody") {
+		t.Error("Delete() should report only users that existed")
+	}
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "# Studio accounts" || lines[1] != strings.Split(mixedHtpasswd, "\n")[1] ||
+		!strings.HasPrefix(lines[2], "legacy:$2") || lines[4] != "broken:{SHA}not-base64" ||
+		lines[5] != "no colon here" || !strings.HasPrefix(lines[6], "added:$2") {
+		t.Errorf("Saved file:\n%s", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("Mode = %v, want the original 0644", info.Mode().Perm())
+	}
+
+	// The auth loader reads what was written
+	basicAuth, err := LoadAuthFile(path, "test", nil)
+	if err != nil {
+		t.Fatalf("LoadAuthFile() error = %v", err)
+	}
+	for user, password := range map[string]string{"apache": "secret", "legacy": "n3w", "added": "s3cret"} {
+		if !basicAuth.File.Match(user, password) {
+			t.Errorf("Loader rejects %s after the round trip", user)
+		}
+		if matched, err := file.Verify(user, password); err != nil || !matched {
+			t.Errorf("Verify(%s) = %v, %v", user, matched, err)
+		}
+	}
+	if matched, _ := file.Verify("apache", "wrong"); matched {
+		t.Error("Verify() matched a wrong password")
+	}
+	if _, err := file.Verify("broken", "secret"); err == nil {
+		t.Error("Verify() should report an entry the loader rejects")
+	}
+}
+
+func TestHtpasswdDetectsConcurrentEdit(t *testing.T) {
+	path := writeHtpasswd(t, "htpasswd", "apache:$apr1$saltsalt$LrttParrLPdxvgutaSXWJ0\n")
+	file, err := ReadHtpasswd(path)
+	if err != nil {
+		t.Fatalf("ReadHtpasswd() error = %v", err)
+	}
+
+	// Another edit lands between reading and saving
+	if err := os.WriteFile(path, []byte("other:secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.SetPassword("added", "s3cret", bcrypt.MinCost); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if err := file.Save(); !errors.Is(err, ErrHtpasswdModified) {
+		t.Errorf("Save() error = %v, want ErrHtpasswdModified", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "other:secret\n" {
+		t.Errorf("The other edit was overwritten: %q", content)
+	}
+}
+
+func TestHtpasswdCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	file, err := ReadHtpasswd(path)
+	if err != nil {
+		t.Fatalf("ReadHtpasswd() error = %v", err)
+	}
+	for _, user := range []string{"", "a:b", " padded", "#comment"} {
+		if err := file.SetPassword(user, "secret", bcrypt.MinCost); err == nil {
+			t.Errorf("SetPassword(%q) should fail", user)
+		}
+	}
+	if err := file.SetPassword("admin", "secret", bcrypt.MaxCost+1); err == nil {
+		t.Error("SetPassword() should reject an out of range cost")
+	}
+	if err := file.SetPassword("admin", "secret", bcrypt.MinCost); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if entries := file.Entries(); len(entries) != 1 || entries[0].Format != FormatBcrypt {
+		t.Errorf("Entries() = %v", entries)
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// InternalToken marks requests Navigator sends through its own listener,
+// such as scheduled tasks and warmers. The token is generated at startup and
+// never leaves the process, so a client can't forge it.
+type InternalToken struct {
+	header string
+	value  string
+}
+
+// NewInternalToken generates a token carried in header
+func NewInternalToken(header string) *InternalToken {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("cannot generate %s token: %v", header, err))
+	}
+	return &InternalToken{header: header, value: hex.EncodeToString(b)}
+}
+
+// Set adds the token to r
+func (t *InternalToken) Set(r *http.Request) {
+	r.Header.Set(t.header, t.value)
+}
+
+// Valid reports whether r carries the token
+func (t *InternalToken) Valid(r *http.Request) bool {
+	value := r.Header.Get(t.header)
+	return value != "" && subtle.ConstantTimeCompare([]byte(value), []byte(t.value)) == 1
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInternalToken(t *testing.T) {
+	token := NewInternalToken("X-Test-Token")
+	other := NewInternalToken("X-Test-Token")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if token.Valid(req) {
+		t.Error("request without the token is valid")
+	}
+
+	req.Header.Set("X-Test-Token", "guess")
+	if token.Valid(req) {
+		t.Error("request with a forged token is valid")
+	}
+
+	other.Set(req)
+	if token.Valid(req) {
+		t.Error("another token's value is valid")
+	}
+
+	token.Set(req)
+	if !token.Valid(req) {
+		t.Error("request with the token is not valid")
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// LoadAuthConfig loads the site-wide htpasswd file and the htpasswd file of
+// every auth scope. Returns nil if authentication is not configured. With
+// auth disabled, the files are loaded only for routes that set auth:
+// required, and no path is protected otherwise.
+func LoadAuthConfig(cfg *config.AuthConfig) (*BasicAuth, error) {
+	if (!cfg.Enabled && !cfg.RequiredByRoutes) || (cfg.HTPasswd == "" && len(cfg.Scopes) == 0) {
+		return nil, nil
+	}
+
+	realm := cfg.Realm
+	if realm == "" {
+		realm = config.DefaultAuthRealm
+	}
+
+	// Without a site-wide htpasswd file only scoped paths require authentication
+	site := &BasicAuth{Realm: realm, Exclude: cfg.PublicPaths}
+	if cfg.HTPasswd != "" {
+		var err error
+		site, err = LoadAuthFile(cfg.HTPasswd, realm, cfg.PublicPaths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, scope := range cfg.Scopes {
+		scopeRealm := scope.Realm
+		if scopeRealm == "" {
+			scopeRealm = realm
+		}
+		scoped, err := LoadAuthFile(scope.HTPasswd, scopeRealm, scope.PublicPaths)
+		if err != nil {
+			return nil, err
+		}
+		scoped.Paths = scope.Paths
+		site.scopes = append(site.scopes, scoped)
+	}
+
+	site.routesOnly = !cfg.Enabled
+	return site, nil
+}
+
+// Files returns every htpasswd file in use, site-wide first
+func (a *BasicAuth) Files() []string {
+	if a == nil {
+		return nil
+	}
+	var files []string
+	if a.filename != "" {
+		files = append(files, a.filename)
+	}
+	for _, scope := range a.scopes {
+		files = append(files, scope.filename)
+	}
+	return files
+}
+
+// ForPath returns the configuration that protects path: the most specific
+// matching scope, or the site-wide configuration if no scope matches
+func (a *BasicAuth) ForPath(path string) *BasicAuth {
+	if a == nil || a.routesOnly {
+		return nil
+	}
+
+	best, bestSpecificity := a, -1
+	for _, scope := range a.scopes {
+		for _, pattern := range scope.Paths {
+			if _, ok := matchPathPattern(path, pattern); ok {
+				if specificity := config.AuthPathSpecificity(pattern); specificity > bestSpecificity {
+					best, bestSpecificity = scope, specificity
+				}
+			}
+		}
+	}
+	return best
+}
+
+// Scope returns the configuration whose credentials are checked for realm:
+// an auth scope with that realm, or the site-wide configuration. Returns nil
+// if no htpasswd file is loaded for the realm.
+func (a *BasicAuth) Scope(realm string) *BasicAuth {
+	if a == nil {
+		return nil
+	}
+	for _, scope := range a.scopes {
+		if scope.Realm == realm {
+			return scope
+		}
+	}
+	if a.Realm == realm && a.File != nil {
+		return a
+	}
+	return nil
+}
+
+// Required returns the configuration whose credentials a route with auth:
+// required checks: the auth scope with realm, or the site-wide configuration
+// if realm is empty. Returns nil if no htpasswd file is loaded for it.
+func (a *BasicAuth) Required(realm string) *BasicAuth {
+	if a == nil {
+		return nil
+	}
+	if realm != "" {
+		return a.Scope(realm)
+	}
+	if a.File == nil {
+		return nil
+	}
+	return a
+}
+
+// IsPublic reports whether path is exempt from authentication. A scope uses its
+// own public paths in place of auth.public_paths; regex auth patterns apply everywhere.
+func (a *BasicAuth) IsPublic(path string, cfg *config.Config) bool {
+	_, ok := a.PublicRule(path, cfg)
+	return ok
+}
+
+// PublicRule returns the pattern that makes path public, as used by IsPublic
+func (a *BasicAuth) PublicRule(path string, cfg *config.Config) (string, bool) {
+	if a == nil || len(a.Paths) == 0 {
+		return ExclusionRule(path, cfg)
+	}
+
+	for _, pattern := range a.Exclude {
+		if _, ok := matchPathPattern(path, pattern); ok {
+			return pattern, true
+		}
+	}
+	for _, authPattern := range cfg.Auth.AuthPatterns {
+		if authPattern.Pattern.MatchString(path) && authPattern.Action == "off" {
+			return authPattern.Pattern.String(), true
+		}
+	}
+	return "", false
+}
+
+// matchPathPattern matches path against a public_paths style pattern: "*.css"
+// matches a suffix, other patterns containing "*" are globs, patterns ending
+// in "/" match a prefix, and anything else must match exactly. Returns the
+// kind of match for logging.
+func matchPathPattern(path, pattern string) (string, bool) {
+	switch {
+	case strings.HasPrefix(pattern, "*"):
+		return "glob pattern", strings.HasSuffix(path, pattern[1:])
+	case strings.Contains(pattern, "*"):
+		matched, _ := filepath.Match(pattern, path)
+		return "filepath pattern", matched
+	case strings.HasSuffix(pattern, "/"):
+		return "prefix", strings.HasPrefix(path, pattern)
+	default:
+		return "exact", path == pattern
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// writeHtpasswd writes a {SHA} htpasswd file and returns its path
+func writeHtpasswd(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAuthConfigScopes(t *testing.T) {
+	site := writeHtpasswd(t, "site", "user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")    // password
+	admin := writeHtpasswd(t, "admin", "admin:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n") // secret
+
+	cfg := &config.AuthConfig{
+		Enabled:     true,
+		HTPasswd:    site,
+		PublicPaths: []string{"*.css"},
+		Scopes: []config.AuthScope{
+			{Paths: []string{"/showcase/admin/"}, HTPasswd: admin, Realm: "Admin"},
+		},
+	}
+	basicAuth, err := LoadAuthConfig(cfg)
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+	if files := basicAuth.Files(); len(files) != 2 || files[0] != site || files[1] != admin {
+		t.Errorf("Files() = %v", files)
+	}
+
+	tests := []struct {
+		path      string
+		realm     string
+		user      string
+		password  string
+		authorize bool
+	}{
+		{"/showcase/", config.DefaultAuthRealm, "user", "password", true},
+		{"/showcase/", config.DefaultAuthRealm, "admin", "secret", false},
+		{"/showcase/admin/users", "Admin", "admin", "secret", true},
+		{"/showcase/admin/users", "Admin", "user", "password", false},
+	}
+	for _, tt := range tests {
+		scope := basicAuth.ForPath(tt.path)
+		if scope.Realm != tt.realm {
+			t.Errorf("ForPath(%q).Realm = %q, want %q", tt.path, scope.Realm, tt.realm)
+		}
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.SetBasicAuth(tt.user, tt.password)
+		if got := scope.CheckAuth(req); got != tt.authorize {
+			t.Errorf("%s as %s: CheckAuth = %v, want %v", tt.path, tt.user, got, tt.authorize)
+		}
+	}
+
+	// Site-wide public paths don't apply inside a scope
+	fullConfig := &config.Config{Auth: *cfg}
+	if !basicAuth.ForPath("/site.css").IsPublic("/site.css", fullConfig) {
+		t.Error("site-wide public path should be public outside scopes")
+	}
+	if basicAuth.ForPath("/showcase/admin/admin.css").IsPublic("/showcase/admin/admin.css", fullConfig) {
+		t.Error("site-wide public paths should not apply inside a scope")
+	}
+}
+
+func TestForPathMostSpecificScope(t *testing.T) {
+	file := writeHtpasswd(t, "htpasswd", "user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")
+	basicAuth, err := LoadAuthConfig(&config.AuthConfig{
+		Enabled: true,
+		Scopes: []config.AuthScope{
+			{Paths: []string{"/showcase/"}, HTPasswd: file, Realm: "Showcase"},
+			{Paths: []string{"/showcase/admin/"}, HTPasswd: file, Realm: "Admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+
+	tests := map[string]string{
+		"/showcase/2025/":      "Showcase",
+		"/showcase/admin/":     "Admin",
+		"/showcase/admin/edit": "Admin",
+		"/other":               config.DefaultAuthRealm,
+	}
+	for path, realm := range tests {
+		if got := basicAuth.ForPath(path).Realm; got != realm {
+			t.Errorf("ForPath(%q).Realm = %q, want %q", path, got, realm)
+		}
+	}
+
+	// Without a site-wide htpasswd file, paths outside every scope are open
+	if basicAuth.ForPath("/other").IsEnabled() {
+		t.Error("paths outside scopes should not require authentication")
+	}
+}
+
+func TestScopeByRealm(t *testing.T) {
+	site := writeHtpasswd(t, "site", "user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")
+	admin := writeHtpasswd(t, "admin", "admin:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n")
+
+	basicAuth, err := LoadAuthConfig(&config.AuthConfig{
+		Enabled:  true,
+		HTPasswd: site,
+		Scopes:   []config.AuthScope{{Paths: []string{"/uploads/"}, HTPasswd: admin, Realm: "Uploads"}},
+	})
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+
+	if scope := basicAuth.Scope("Uploads"); scope == nil || scope.filename != admin {
+		t.Errorf("Scope(Uploads) = %v, want the scope loaded from %s", scope, admin)
+	}
+	if scope := basicAuth.Scope(config.DefaultAuthRealm); scope != basicAuth {
+		t.Error("Scope(site realm) should return the site-wide configuration")
+	}
+	if scope := basicAuth.Scope("Missing"); scope != nil {
+		t.Errorf("Scope(Missing) = %v, want nil", scope)
+	}
+}
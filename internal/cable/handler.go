@@ -36,6 +36,17 @@ type Connection struct {
 	streamsMu sync.RWMutex
 	send      chan []byte
 	handler   *Handler
+	activity  func() // Called for each message from the client but pongs (nil = not needed)
+}
+
+// activityKey holds the func told about a connection's client messages
+type activityKey struct{}
+
+// WithActivity returns a context under which a connection calls activity
+// for each message its client sends, other than pongs, so idle management
+// can tell a page in use from an abandoned one
+func WithActivity(ctx context.Context, activity func()) context.Context {
+	return context.WithValue(ctx, activityKey{}, activity)
 }
 
 // Handler manages WebSocket connections and broadcasts
@@ -64,6 +75,18 @@ func NewHandler(logger *slog.Logger) *Handler {
 	}
 }
 
+// Stats returns the number of open WebSocket connections and subscribed streams
+func (h *Handler) Stats() (connections, streams int) {
+	h.connectionsMu.RLock()
+	connections = len(h.connections)
+	h.connectionsMu.RUnlock()
+
+	h.streamsMu.RLock()
+	streams = len(h.streams)
+	h.streamsMu.RUnlock()
+	return connections, streams
+}
+
 // ServeHTTP handles WebSocket upgrade requests at /cable
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ws, err := h.upgrader.Upgrade(w, r, nil)
@@ -78,6 +101,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		send:    make(chan []byte, 256),
 		handler: h,
 	}
+	conn.activity, _ = r.Context().Value(activityKey{}).(func())
 
 	h.register(conn)
 	defer h.unregister(conn)
@@ -273,6 +297,9 @@ func (conn *Connection) readPump() {
 		if err := json.Unmarshal(message, &msg); err != nil {
 			continue
 		}
+		if msg.Type != "pong" && conn.activity != nil {
+			conn.activity()
+		}
 
 		switch msg.Type {
 		case "subscribe":
@@ -209,6 +209,41 @@ func TestSubscribeUnsubscribe(t *testing.T) {
 	}
 }
 
+func TestClientActivity(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+	handler := NewHandler(logger)
+
+	activity := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithActivity(r.Context(), func() { activity <- struct{}{} })
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = ws.Close() }()
+
+	// Pongs answer the server and aren't client activity
+	if err := ws.WriteJSON(Message{Type: "pong"}); err != nil {
+		t.Fatalf("Failed to send pong: %v", err)
+	}
+	if err := ws.WriteJSON(Message{Type: "subscribe", Stream: "test-stream"}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	var response Message
+	if err := ws.ReadJSON(&response); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	if got := len(activity); got != 1 {
+		t.Errorf("Expected activity for the subscribe only, got %d calls", got)
+	}
+}
+
 func TestBroadcastToSubscribers(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
 	handler := NewHandler(logger)
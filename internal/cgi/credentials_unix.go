@@ -3,6 +3,7 @@
 package cgi
 
 import (
+	"os"
 	"os/exec"
 	"syscall"
 
@@ -16,3 +17,8 @@ func (h *Handler) setProcessCredentials(cmd *exec.Cmd, cred *process.SysCredenti
 	}
 	cmd.SysProcAttr.Credential = cred
 }
+
+// chownToCredential gives a file Navigator created to the CGI process's user
+func chownToCredential(path string, cred *process.SysCredential) error {
+	return os.Chown(path, int(cred.Uid), int(cred.Gid))
+}
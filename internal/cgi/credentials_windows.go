@@ -13,3 +13,8 @@ func (h *Handler) setProcessCredentials(cmd *exec.Cmd, cred *process.SysCredenti
 	// Windows doesn't use syscall.Credential
 	// Would require different APIs (CreateProcessAsUser, etc.)
 }
+
+// chownToCredential is a no-op on Windows, where CGI scripts run as Navigator
+func chownToCredential(path string, cred *process.SysCredential) error {
+	return nil
+}
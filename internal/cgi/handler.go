@@ -3,6 +3,7 @@ package cgi
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,25 +17,28 @@ import (
 
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/proxy"
 	"github.com/rubys/navigator/internal/utils"
 )
 
 // Handler implements CGI script execution with user switching support
 type Handler struct {
-	Script           string
-	User             string
-	Group            string
-	AllowedUsers     []string
-	Env              map[string]string
-	ReloadConfig     string
-	Timeout          time.Duration
-	CurrentConfigFn  func() string    // Function to get current config file path
-	ConfigLoadTimeFn func() time.Time // Function to get when config was last loaded
-	TriggerReloadFn  func(string)     // Function to trigger config reload
+	Script            string
+	User              string
+	Group             string
+	AllowedUsers      []string
+	Env               map[string]string
+	ReloadConfig      string
+	Timeout           time.Duration
+	IdleOutputTimeout time.Duration              // Abort the script after this long without output (0 = never)
+	KillGrace         time.Duration              // Time between SIGTERM and SIGKILL for an aborted script
+	CurrentConfigFn   func() string              // Function to get current config file path
+	ConfigLoadTimeFn  func() time.Time           // Function to get when config was last loaded
+	TriggerReloadFn   func(utils.ReloadDecision) // Function to trigger config reload or tenant restarts
 }
 
 // NewHandler creates a new CGI handler from configuration
-func NewHandler(cfg *config.CGIScriptConfig, currentConfigFn func() string, configLoadTimeFn func() time.Time, triggerReloadFn func(string)) (*Handler, error) {
+func NewHandler(cfg *config.CGIScriptConfig, currentConfigFn func() string, configLoadTimeFn func() time.Time, triggerReloadFn func(utils.ReloadDecision)) (*Handler, error) {
 	// Validate script path
 	if cfg.Script == "" {
 		return nil, fmt.Errorf("CGI script path is required")
@@ -53,22 +57,19 @@ func NewHandler(cfg *config.CGIScriptConfig, currentConfigFn func() string, conf
 		slog.Warn("CGI script may not be executable", "script", cfg.Script, "mode", info.Mode())
 	}
 
-	// Parse timeout
-	timeout := utils.ParseDurationWithContext(cfg.Timeout, 0, map[string]interface{}{
-		"script": cfg.Script,
-	})
-
 	return &Handler{
-		Script:           cfg.Script,
-		User:             cfg.User,
-		Group:            cfg.Group,
-		AllowedUsers:     cfg.AllowedUsers,
-		Env:              cfg.Env,
-		ReloadConfig:     cfg.ReloadConfig,
-		Timeout:          timeout,
-		CurrentConfigFn:  currentConfigFn,
-		ConfigLoadTimeFn: configLoadTimeFn,
-		TriggerReloadFn:  triggerReloadFn,
+		Script:            cfg.Script,
+		User:              cfg.User,
+		Group:             cfg.Group,
+		AllowedUsers:      cfg.AllowedUsers,
+		Env:               cfg.Env,
+		ReloadConfig:      cfg.ReloadConfig,
+		Timeout:           cfg.Timeout.Std(),
+		IdleOutputTimeout: cfg.IdleOutputTimeout.Std(),
+		KillGrace:         config.CGIKillGrace,
+		CurrentConfigFn:   currentConfigFn,
+		ConfigLoadTimeFn:  configLoadTimeFn,
+		TriggerReloadFn:   triggerReloadFn,
 	}, nil
 }
 
@@ -123,30 +124,42 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			"username", username)
 	}
 
+	// A running script defers the machine's idle action
+	defer utils.BeginActivity(config.IdleDeferCGI)()
+
 	slog.Info("Executing CGI script",
 		"script", h.Script,
 		"method", r.Method,
 		"path", r.URL.Path,
 		"user", h.User)
 
-	// Create command with optional timeout
-	var cmd *exec.Cmd
-	var cancel context.CancelFunc
+	// The script is stopped when the client leaves, the timeout passes, or
+	// it goes idle_output_timeout without output
+	ctx, abort := context.WithCancelCause(r.Context())
+	defer abort(nil)
 	if h.Timeout > 0 {
-		ctx, cancelFn := context.WithTimeout(r.Context(), h.Timeout)
-		cancel = cancelFn
-		cmd = exec.CommandContext(ctx, h.Script)
-	} else {
-		cmd = exec.CommandContext(r.Context(), h.Script)
-	}
-
-	if cancel != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, h.Timeout, fmt.Errorf("timed out after %s", h.Timeout))
 		defer cancel()
 	}
+	cmd := exec.Command(h.Script)
+	setProcessGroup(cmd)
 
 	// Set up CGI environment
 	h.setupCGIEnvironment(cmd, r)
 
+	// The script may write a reload request to this file
+	var reloadFile string
+	if h.TriggerReloadFn != nil {
+		if file, err := utils.NewReloadFile(); err != nil {
+			slog.Warn("Cannot create reload request file", "script", h.Script, "error", err)
+		} else {
+			reloadFile = file
+			defer func() { _ = os.Remove(reloadFile) }()
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", utils.ReloadFileEnv, reloadFile))
+		}
+	}
+
 	// Set user credentials if specified (Unix only)
 	if h.User != "" {
 		cred, err := process.GetUserCredentials(h.User, h.Group)
@@ -160,6 +173,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		if cred != nil {
 			h.setProcessCredentials(cmd, cred)
+			if reloadFile != "" {
+				if err := chownToCredential(reloadFile, cred); err != nil {
+					slog.Warn("Cannot hand reload request file to CGI user", "script", h.Script, "error", err)
+				}
+			}
 		}
 	}
 
@@ -195,6 +213,19 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Stop the script, first gently, once the request is aborted
+	exited := make(chan struct{})
+	go h.stopOnAbort(ctx, cmd, exited)
+
+	output := io.Reader(stdout)
+	if h.IdleOutputTimeout > 0 {
+		idle := time.AfterFunc(h.IdleOutputTimeout, func() {
+			abort(fmt.Errorf("no output for %s", h.IdleOutputTimeout))
+		})
+		defer idle.Stop()
+		output = &outputWatch{Reader: stdout, idle: idle, timeout: h.IdleOutputTimeout}
+	}
+
 	// Copy request body to script's stdin
 	go func() {
 		defer func() { _ = stdin.Close() }()
@@ -210,15 +241,21 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		stderrOutput <- string(data)
 	}()
 
-	// Parse CGI response from stdout
-	err = h.parseAndWriteCGIResponse(w, stdout)
-	if err != nil {
+	// Parse CGI response from stdout, streaming the body to the client
+	committed, written, err := h.parseAndWriteCGIResponse(ctx, w, output)
+	if errors.Is(err, errClientGone) {
+		abort(errClientGone)
+	} else if err != nil {
 		slog.Error("Failed to parse CGI response", "script", h.Script, "error", err)
-		// Don't write error response here - may have already written headers
 	}
 
 	// Wait for command to finish
 	cmdErr := cmd.Wait()
+	close(exited)
+	if ctx.Err() != nil {
+		h.reportAbort(w, r, context.Cause(ctx), committed, written, startTime)
+		return
+	}
 
 	// Log stderr if present
 	select {
@@ -242,19 +279,42 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"script", h.Script,
 		"duration", time.Since(startTime))
 
-	// Check if config should be reloaded
-	if h.ReloadConfig != "" && h.CurrentConfigFn != nil && h.ConfigLoadTimeFn != nil && h.TriggerReloadFn != nil {
-		currentConfig := h.CurrentConfigFn()
-		configLoadTime := h.ConfigLoadTimeFn()
-		decision := utils.ShouldReloadConfig(h.ReloadConfig, currentConfig, configLoadTime)
-		if decision.ShouldReload {
-			slog.Info("CGI script triggered config reload",
-				"script", h.Script,
-				"reason", decision.Reason,
-				"configFile", decision.NewConfigFile)
-			h.TriggerReloadFn(decision.NewConfigFile)
+	// Check if config should be reloaded or tenants restarted
+	h.requestReload(reloadFile)
+}
+
+// requestReload passes the reload request the script wrote, or failing that
+// the reload_config decision, to TriggerReloadFn
+func (h *Handler) requestReload(reloadFile string) {
+	if h.CurrentConfigFn == nil || h.ConfigLoadTimeFn == nil || h.TriggerReloadFn == nil {
+		return
+	}
+	currentConfig := h.CurrentConfigFn()
+	source := "cgi " + h.Script
+
+	var decision utils.ReloadDecision
+	if reloadFile != "" {
+		allowedDirs := utils.ReloadConfigDirs(currentConfig, h.ReloadConfig)
+		request, err := utils.ReadReloadRequest(reloadFile, source, currentConfig, allowedDirs)
+		if err != nil {
+			slog.Warn("Ignoring reload request", "source", source, "error", err)
+		} else if request != nil {
+			decision = *request
 		}
 	}
+	if !decision.Requested() && h.ReloadConfig != "" {
+		decision = utils.ShouldReloadConfig(h.ReloadConfig, currentConfig, h.ConfigLoadTimeFn())
+		decision.Source = source
+	}
+
+	if decision.Requested() {
+		slog.Info("CGI script triggered config reload",
+			"script", h.Script,
+			"reason", decision.Reason,
+			"configFile", decision.NewConfigFile,
+			"restartTenants", decision.RestartTenants)
+		h.TriggerReloadFn(decision)
+	}
 }
 
 // setupCGIEnvironment sets up standard CGI environment variables
@@ -302,8 +362,87 @@ func (h *Handler) setupCGIEnvironment(cmd *exec.Cmd, r *http.Request) {
 	}
 }
 
-// parseAndWriteCGIResponse parses CGI output and writes it to the response writer
-func (h *Handler) parseAndWriteCGIResponse(w http.ResponseWriter, stdout io.Reader) error {
+// errClientGone aborts a script whose response can no longer be delivered
+var errClientGone = errors.New("client disconnected")
+
+// outputWatch restarts the idle output timer whenever the script writes
+type outputWatch struct {
+	io.Reader
+	idle    *time.Timer
+	timeout time.Duration
+}
+
+func (o *outputWatch) Read(p []byte) (int, error) {
+	n, err := o.Reader.Read(p)
+	if n > 0 {
+		o.idle.Reset(o.timeout)
+	}
+	return n, err
+}
+
+// stopOnAbort sends SIGTERM to the script's process group once ctx is done,
+// and SIGKILL if it hasn't exited after the kill grace
+func (h *Handler) stopOnAbort(ctx context.Context, cmd *exec.Cmd, exited <-chan struct{}) {
+	select {
+	case <-exited:
+		return
+	case <-ctx.Done():
+	}
+	if err := terminateProcessGroup(cmd); err != nil {
+		slog.Debug("Cannot terminate CGI script", "script", h.Script, "error", err)
+	}
+	grace := h.KillGrace
+	if grace <= 0 {
+		grace = config.CGIKillGrace
+	}
+	select {
+	case <-exited:
+	case <-time.After(grace):
+		slog.Warn("CGI script ignored SIGTERM, killing it", "script", h.Script, "grace", grace)
+		_ = killProcessGroup(cmd)
+	}
+}
+
+// reportAbort records why a script was stopped. A script that times out
+// before sending headers gets a 504; a response already committed to the
+// client is logged as cgi_aborted with the bytes written.
+func (h *Handler) reportAbort(w http.ResponseWriter, r *http.Request, cause error, committed bool, written int64, startTime time.Time) {
+	clientGone := cause == errClientGone || errors.Is(cause, context.Canceled) && r.Context().Err() != nil
+	if clientGone {
+		cause = errClientGone
+	}
+	recorder, _ := w.(proxy.MetadataSetter)
+	switch {
+	case committed:
+		if recorder != nil {
+			recorder.SetMetadata("response_type", "cgi_aborted")
+			recorder.SetMetadata("error_message", cause.Error())
+		}
+	case clientGone:
+		if recorder != nil {
+			recorder.SetMetadata("response_type", "client_closed")
+		}
+		w.WriteHeader(499)
+	default:
+		if recorder != nil {
+			recorder.SetMetadata("response_type", "error")
+			recorder.SetMetadata("error_message", cause.Error())
+		}
+		http.Error(w, "Gateway Timeout", http.StatusGatewayTimeout)
+	}
+	slog.Warn("CGI script aborted",
+		"script", h.Script,
+		"reason", cause,
+		"committed", committed,
+		"bytes", written,
+		"duration", time.Since(startTime))
+}
+
+// parseAndWriteCGIResponse parses CGI output and writes it to the response
+// writer, flushing the body as it arrives. It reports whether the headers
+// were sent and how many body bytes were written; nothing is sent once ctx
+// is done before the headers end.
+func (h *Handler) parseAndWriteCGIResponse(ctx context.Context, w http.ResponseWriter, stdout io.Reader) (committed bool, written int64, err error) {
 	reader := bufio.NewReader(stdout)
 
 	// Read and parse headers
@@ -311,7 +450,7 @@ func (h *Handler) parseAndWriteCGIResponse(w http.ResponseWriter, stdout io.Read
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil && err != io.EOF {
-			return fmt.Errorf("error reading CGI headers: %w", err)
+			return false, 0, fmt.Errorf("error reading CGI headers: %w", err)
 		}
 
 		line = strings.TrimRight(line, "\r\n")
@@ -345,11 +484,34 @@ func (h *Handler) parseAndWriteCGIResponse(w http.ResponseWriter, stdout io.Read
 		// Set header
 		w.Header().Set(key, value)
 	}
+	if ctx.Err() != nil {
+		return false, 0, nil
+	}
 
 	// Write status code
 	w.WriteHeader(statusCode)
 
-	// Copy body
-	_, err := io.Copy(w, reader)
-	return err
+	// Copy body, flushing each chunk so slow scripts stream
+	controller := http.NewResponseController(w)
+	_ = controller.Flush()
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := reader.Read(buf)
+		if n > 0 {
+			wrote, writeErr := w.Write(buf[:n])
+			written += int64(wrote)
+			if writeErr != nil {
+				return true, written, fmt.Errorf("%w: %v", errClientGone, writeErr)
+			}
+			if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return true, written, fmt.Errorf("%w: %v", errClientGone, err)
+			}
+		}
+		if readErr == io.EOF {
+			return true, written, nil
+		}
+		if readErr != nil {
+			return true, written, readErr
+		}
+	}
 }
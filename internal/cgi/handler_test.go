@@ -1,14 +1,21 @@
 package cgi
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/utils"
 )
 
 func TestNewHandler(t *testing.T) {
@@ -201,9 +208,9 @@ echo "Config updated"
 		cfg,
 		func() string { return configPath },
 		func() time.Time { return configLoadTime },
-		func(path string) {
+		func(decision utils.ReloadDecision) {
 			reloadTriggered = true
-			reloadConfigPath = path
+			reloadConfigPath = decision.NewConfigFile
 		},
 	)
 	if err != nil {
@@ -228,6 +235,96 @@ echo "Config updated"
 	}
 }
 
+func TestHandler_ReloadRequest(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "navigator.yml")
+	newConfigPath := filepath.Join(tmpDir, "navigator-new.yml")
+	for _, path := range []string{configPath, newConfigPath} {
+		if err := os.WriteFile(path, []byte("server:\n  listen: 3000\n"), 0644); err != nil {
+			t.Fatalf("Failed to create config: %v", err)
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "navigator.yml")
+	if err := os.WriteFile(outside, []byte("server:\n  listen: 3000\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request string
+		want    *utils.ReloadDecision
+	}{
+		{
+			name:    "new config and tenant restart",
+			request: `{"should_reload": true, "new_config_file": "navigator-new.yml", "reason": "tenant added", "restart_tenants": ["2025/boston"]}`,
+			want:    &utils.ReloadDecision{ShouldReload: true, NewConfigFile: newConfigPath, Reason: "tenant added", RestartTenants: []string{"2025/boston"}},
+		},
+		{
+			name:    "restart only",
+			request: `{"restart_tenants": ["2025/boston"]}`,
+			want:    &utils.ReloadDecision{RestartTenants: []string{"2025/boston"}},
+		},
+		{
+			name:    "current config",
+			request: `{"should_reload": true}`,
+			want:    &utils.ReloadDecision{ShouldReload: true, NewConfigFile: configPath},
+		},
+		{
+			name:    "validate only",
+			request: `{"validate_only": true, "new_config_file": "navigator-new.yml", "reason": "generated"}`,
+			want:    &utils.ReloadDecision{ValidateOnly: true, NewConfigFile: newConfigPath, Reason: "generated"},
+		},
+		{name: "config outside allowed directories", request: `{"should_reload": true, "new_config_file": "` + outside + `"}`},
+		{name: "validate only with reload", request: `{"validate_only": true, "should_reload": true}`},
+		{name: "unknown field", request: `{"should_reload": true, "restart": ["2025/boston"]}`},
+		{name: "nothing written", request: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scriptPath := filepath.Join(t.TempDir(), "update.cgi")
+			script := "#!/bin/sh\n"
+			if tt.request != "" {
+				script += "cat > \"$" + utils.ReloadFileEnv + "\" <<'EOF'\n" + tt.request + "\nEOF\n"
+			}
+			script += "echo 'Content-Type: text/plain'\necho\necho done\n"
+			if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+				t.Fatalf("Failed to create test script: %v", err)
+			}
+
+			var got *utils.ReloadDecision
+			handler, err := NewHandler(&config.CGIScriptConfig{Path: "/update", Script: scriptPath},
+				func() string { return configPath },
+				func() time.Time { return time.Now() },
+				func(decision utils.ReloadDecision) { got = &decision })
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("POST", "/update", nil))
+			if rec.Code != 200 {
+				t.Fatalf("Status = %d, want 200", rec.Code)
+			}
+
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Reload triggered with %+v, want none", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("Expected reload request to be passed on")
+			}
+			tt.want.Source = "cgi " + scriptPath
+			if tt.want.ShouldReload && tt.want.Reason == "" {
+				tt.want.Reason = "requested by " + tt.want.Source
+			}
+			if !reflect.DeepEqual(*got, *tt.want) {
+				t.Errorf("Reload request = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
 func TestHandler_AccessControl(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -422,3 +519,159 @@ func TestHandler_IsUserAllowed(t *testing.T) {
 		})
 	}
 }
+
+// metadataRecorder records the metadata a handler sets for the access log
+type metadataRecorder struct {
+	*httptest.ResponseRecorder
+	metadata map[string]interface{}
+}
+
+func (m *metadataRecorder) SetMetadata(key string, value interface{}) {
+	m.metadata[key] = value
+}
+
+// newStreamingHandler creates a handler for a shell script body
+func newStreamingHandler(t *testing.T, body string) *Handler {
+	t.Helper()
+	scriptPath := filepath.Join(t.TempDir(), "stream.cgi")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("Failed to create test script: %v", err)
+	}
+	handler, err := NewHandler(&config.CGIScriptConfig{Path: "/stream", Script: scriptPath}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	handler.KillGrace = 200 * time.Millisecond
+	return handler
+}
+
+// waitForFile waits until path exists
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was never written", filepath.Base(path))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandler_StreamsOutput(t *testing.T) {
+	release := filepath.Join(t.TempDir(), "release")
+	handler := newStreamingHandler(t, `echo "Content-Type: text/plain"
+echo
+echo first
+while [ ! -f "`+release+`" ]; do sleep 0.05; done
+echo second
+`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if line, err := reader.ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatalf("First chunk = %q, %v; want it before the script finishes", line, err)
+	}
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rest, _ := io.ReadAll(reader); string(rest) != "second\n" {
+		t.Errorf("Rest = %q, want second", rest)
+	}
+}
+
+func TestHandler_ClientDisconnectTerminatesScript(t *testing.T) {
+	dir := t.TempDir()
+	terminated := filepath.Join(dir, "terminated")
+	handler := newStreamingHandler(t, `trap 'touch "`+terminated+`"; exit 0' TERM
+echo "Content-Type: text/plain"
+echo
+echo first
+while :; do sleep 0.05; done
+`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	if line, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatalf("First chunk = %q, %v", line, err)
+	}
+	_ = resp.Body.Close() // The client goes away mid-response
+	waitForFile(t, terminated)
+}
+
+func TestHandler_KillsScriptIgnoringSIGTERM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no SIGTERM")
+	}
+	handler := newStreamingHandler(t, `trap '' TERM
+echo "Content-Type: text/plain"
+echo
+echo first
+while :; do sleep 0.05; done
+`)
+	handler.Timeout = 200 * time.Millisecond
+
+	rec := &metadataRecorder{ResponseRecorder: httptest.NewRecorder(), metadata: map[string]interface{}{}}
+	started := time.Now()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/stream", nil))
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("ServeHTTP took %s, want the script killed after the grace", elapsed)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "first\n" {
+		t.Errorf("Response = %d %q, want the committed partial response", rec.Code, rec.Body.String())
+	}
+	if rec.metadata["response_type"] != "cgi_aborted" || !strings.Contains(fmt.Sprint(rec.metadata["error_message"]), "timed out") {
+		t.Errorf("Metadata = %v, want a timed out cgi_aborted response", rec.metadata)
+	}
+}
+
+func TestHandler_IdleOutputTimeout(t *testing.T) {
+	t.Run("before headers", func(t *testing.T) {
+		handler := newStreamingHandler(t, "sleep 5\n")
+		handler.IdleOutputTimeout = 200 * time.Millisecond
+
+		rec := &metadataRecorder{ResponseRecorder: httptest.NewRecorder(), metadata: map[string]interface{}{}}
+		started := time.Now()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/stream", nil))
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("Status = %d, want 504", rec.Code)
+		}
+		if elapsed := time.Since(started); elapsed > 3*time.Second {
+			t.Errorf("ServeHTTP took %s, want the script aborted", elapsed)
+		}
+		if !strings.Contains(fmt.Sprint(rec.metadata["error_message"]), "no output for 200ms") {
+			t.Errorf("Metadata = %v, want the idle output timeout", rec.metadata)
+		}
+	})
+
+	t.Run("after a partial response", func(t *testing.T) {
+		handler := newStreamingHandler(t, `echo "Content-Type: text/plain"
+echo
+for i in 1 2 3; do echo "chunk $i"; sleep 0.05; done
+sleep 5
+echo never
+`)
+		handler.IdleOutputTimeout = 300 * time.Millisecond
+
+		rec := &metadataRecorder{ResponseRecorder: httptest.NewRecorder(), metadata: map[string]interface{}{}}
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/stream", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != "chunk 1\nchunk 2\nchunk 3\n" {
+			t.Errorf("Response = %d %q, want the three chunks", rec.Code, rec.Body.String())
+		}
+		if rec.metadata["response_type"] != "cgi_aborted" {
+			t.Errorf("Metadata = %v, want cgi_aborted", rec.metadata)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package cgi
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows, where only the script's own
+// process is stopped
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills the script's process, as Windows has no SIGTERM
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcessGroup kills the script's process
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,44 @@
+// Package clock abstracts time so timers and timeouts can be driven by a
+// fake clock in tests instead of real sleeps. Production code uses Real.
+package clock
+
+import "time"
+
+// Clock tells the time and starts timers
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer // Calls f in its own goroutine once d has passed
+	NewTimer(d time.Duration) Timer            // Sends the time on C once d has passed
+}
+
+// Timer is the subset of *time.Timer the clock's users need
+type Timer interface {
+	C() <-chan time.Time // nil for AfterFunc timers
+	Stop() bool
+}
+
+// Real is the clock of the time package
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// Or returns c, or Real when c is nil, so a zero-valued struct holding a
+// Clock uses real time
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
@@ -0,0 +1,76 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeFiresTimersInOrder(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	var fired []string
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, "2s") })
+	c.AfterFunc(time.Second, func() {
+		fired = append(fired, "1s")
+		if now := c.Now(); !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Now() = %v inside a timer due at 1s", now)
+		}
+	})
+	stopped := c.AfterFunc(1500*time.Millisecond, func() { fired = append(fired, "stopped") })
+	timer := c.NewTimer(3 * time.Second)
+	if !stopped.Stop() {
+		t.Error("Stop() = false for a pending timer")
+	}
+	if pending := c.Pending(); pending != 3 {
+		t.Errorf("Pending() = %d, want 3", pending)
+	}
+
+	c.Advance(2500 * time.Millisecond)
+	if len(fired) != 2 || fired[0] != "1s" || fired[1] != "2s" {
+		t.Errorf("fired = %v, want [1s 2s]", fired)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before it was due")
+	default:
+	}
+
+	c.Advance(time.Second)
+	select {
+	case when := <-timer.C():
+		if !when.Equal(start.Add(3 * time.Second)) {
+			t.Errorf("timer sent %v", when)
+		}
+	default:
+		t.Fatal("timer didn't fire once due")
+	}
+	if pending := c.Pending(); pending != 0 {
+		t.Errorf("Pending() = %d once every timer fired, want 0", pending)
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	c := NewFake(time.Time{})
+	done := make(chan struct{})
+	go func() {
+		<-c.NewTimer(time.Minute).C()
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	if !c.AdvanceToNext() {
+		t.Fatal("AdvanceToNext() = false with a pending timer")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("goroutine waiting on the timer wasn't released")
+	}
+	if now := c.Now(); !now.Equal(time.Time{}.Add(time.Minute)) {
+		t.Errorf("Now() = %v, want a minute in", now)
+	}
+	if c.AdvanceToNext() {
+		t.Error("AdvanceToNext() = true without a pending timer")
+	}
+}
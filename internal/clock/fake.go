@@ -0,0 +1,157 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a manually advanced clock. Timers fire only when Advance moves
+// the clock past them: AfterFunc timers call their function in the
+// goroutine calling Advance, in the order they're due.
+type Fake struct {
+	mu     sync.Mutex
+	armed  *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *Fake
+	when    time.Time
+	f       func()         // AfterFunc timers
+	c       chan time.Time // NewTimer timers
+	stopped bool
+}
+
+// NewFake returns a fake clock set to start
+func NewFake(start time.Time) *Fake {
+	c := &Fake{now: start}
+	c.armed = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Fake) AfterFunc(d time.Duration, f func()) Timer {
+	return c.add(&fakeTimer{clock: c, f: f}, d)
+}
+
+func (c *Fake) NewTimer(d time.Duration) Timer {
+	return c.add(&fakeTimer{clock: c, c: make(chan time.Time, 1)}, d)
+}
+
+func (c *Fake) add(timer *fakeTimer, d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer.when = c.now.Add(d)
+	c.timers = append(c.timers, timer)
+	c.armed.Broadcast()
+	return timer
+}
+
+// Advance moves the clock forward by d, firing due timers in order
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].when.Before(c.timers[j].when) })
+		var due *fakeTimer
+		for _, timer := range c.timers {
+			if !timer.stopped && !timer.when.After(target) {
+				due = timer
+				break
+			}
+		}
+		if due == nil {
+			c.now = target
+			c.prune()
+			c.mu.Unlock()
+			return
+		}
+		due.stopped = true
+		c.now = due.when
+		c.mu.Unlock()
+		if due.f != nil {
+			due.f()
+		} else {
+			due.c <- due.when
+		}
+	}
+}
+
+// AdvanceToNext moves the clock to the earliest pending timer, firing it,
+// and reports whether there was one
+func (c *Fake) AdvanceToNext() bool {
+	c.mu.Lock()
+	var next *fakeTimer
+	for _, timer := range c.timers {
+		if !timer.stopped && (next == nil || timer.when.Before(next.when)) {
+			next = timer
+		}
+	}
+	if next == nil {
+		c.mu.Unlock()
+		return false
+	}
+	d := next.when.Sub(c.now)
+	c.mu.Unlock()
+	c.Advance(d)
+	return true
+}
+
+// Pending returns the number of timers that haven't fired or been stopped
+func (c *Fake) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pendingLocked()
+}
+
+// BlockUntil waits until at least n timers are pending, so a test can
+// advance the clock once the code under test has started its timer
+func (c *Fake) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.pendingLocked() < n {
+		c.armed.Wait()
+	}
+}
+
+func (c *Fake) pendingLocked() int {
+	pending := 0
+	for _, timer := range c.timers {
+		if !timer.stopped {
+			pending++
+		}
+	}
+	return pending
+}
+
+// prune drops timers that fired or were stopped. c.mu must be held.
+func (c *Fake) prune() {
+	active := c.timers[:0]
+	for _, timer := range c.timers {
+		if !timer.stopped {
+			active = append(active, timer)
+		}
+	}
+	clear(c.timers[len(active):])
+	c.timers = active
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseAccessLogSinks validates logging.access.sinks and compiles their
+// filters
+func parseAccessLogSinks(access *AccessLogConfig) error {
+	for i := range access.Sinks {
+		sink := &access.Sinks[i]
+		if sink.Destination == "" {
+			return fmt.Errorf("logging.access.sinks[%d]: destination is required", i)
+		}
+		if !validLogFormat(sink.Format) {
+			return fmt.Errorf("logging.access.sinks[%d]: format must be text, json, or pretty, got %q", i, sink.Format)
+		}
+		if err := parseAccessLogFilter(&sink.Filter); err != nil {
+			return fmt.Errorf("logging.access.sinks[%d]: filter: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// parseAccessLogFilter compiles a filter's status ranges and path patterns
+func parseAccessLogFilter(filter *AccessLogFilter) error {
+	filter.StatusRanges = nil
+	for _, status := range filter.Status {
+		r, err := parseStatusRange(status)
+		if err != nil {
+			return err
+		}
+		filter.StatusRanges = append(filter.StatusRanges, r)
+	}
+
+	var err error
+	if filter.PathPatterns, err = compilePathPatterns("paths", filter.Paths); err != nil {
+		return err
+	}
+	filter.ExcludePathPatterns, err = compilePathPatterns("exclude_paths", filter.ExcludePaths)
+	return err
+}
+
+// parseStatusRange parses a status code, "404", or an inclusive range of
+// them, "400-599"
+func parseStatusRange(status string) (StatusRange, error) {
+	low, high, isRange := strings.Cut(strings.TrimSpace(status), "-")
+	if !isRange {
+		high = low
+	}
+	first, errFirst := strconv.Atoi(strings.TrimSpace(low))
+	last, errLast := strconv.Atoi(strings.TrimSpace(high))
+	if errFirst != nil || errLast != nil || first < 100 || last > 599 || first > last {
+		return StatusRange{}, fmt.Errorf("status must be a status code or a range such as 400-599, got %q", status)
+	}
+	return StatusRange{Min: first, Max: last}, nil
+}
+
+// compilePathPatterns compiles the regex patterns of a filter field
+func compilePathPatterns(field string, patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s pattern %q: %w", field, pattern, err)
+		}
+		compiled = append(compiled, regex)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAccessLogSinks(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+logging:
+  access:
+    format: json
+    sinks:
+      - destination: stdout
+        filter:
+          exclude_response_types: [static]
+      - destination: /var/log/navigator/errors.log
+        format: text
+        filter:
+          status: ["404", "500 - 599"]
+          paths: ['^/showcase/']
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	access := cfg.Logging.Access
+	if len(access.Destinations) != 0 || len(access.Sinks) != 2 {
+		t.Fatalf("Access = %+v, want two sinks and no destinations", access)
+	}
+	errors := access.Sinks[1]
+	if errors.Format != LogFormatText || len(errors.Filter.PathPatterns) != 1 {
+		t.Errorf("errors sink = %+v", errors)
+	}
+	if ranges := errors.Filter.StatusRanges; len(ranges) != 2 || ranges[0] != (StatusRange{404, 404}) || ranges[1] != (StatusRange{500, 599}) {
+		t.Errorf("StatusRanges = %v, want 404 and 500-599", ranges)
+	}
+	if dirs := logDirectories(cfg.Logging); len(dirs) != 1 || dirs[0] != "/var/log/navigator" {
+		t.Errorf("logDirectories() = %v, want the errors sink's directory", dirs)
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"destinations too", "destinations: [stdout]\n    sinks: [{destination: stdout}]", "set only one"},
+		{"no destination", "sinks: [{format: json}]", "destination is required"},
+		{"bad format", "sinks: [{destination: stdout, format: xml}]", "format must be"},
+		{"bad status", "sinks: [{destination: stdout, filter: {status: [4xx]}}]", "status must be"},
+		{"inverted range", "sinks: [{destination: stdout, filter: {status: [599-400]}}]", "status must be"},
+		{"bad path", "sinks: [{destination: stdout, filter: {exclude_paths: ['(']}}]", "exclude_paths pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte("logging:\n  access:\n    " + tt.yaml + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -72,7 +73,7 @@ applications:
 		t.Errorf("Expected idle action suspend, got %s", config.Server.Idle.Action)
 	}
 
-	if config.Server.Idle.Timeout != "20m" {
+	if config.Server.Idle.Timeout != Duration(20*time.Minute) {
 		t.Errorf("Expected idle timeout 20m, got %s", config.Server.Idle.Timeout)
 	}
 
@@ -359,16 +360,16 @@ server:
   static:
     public_dir: public
     cache_control:
-      default: "public, max-age=86400"
+      default: "1d"
       overrides:
         - path: "/assets/"
-          max_age: "public, max-age=86400"
+          max_age: "1d"
         - path: "/docs/"
-          max_age: "public, max-age=3600"
+          max_age: "1h"
         - path: "/images/"
-          max_age: "public, max-age=1800"
+          max_age: "30m"
         - path: "/temp/"
-          max_age: "no-cache"
+          max_age: "0"
     allowed_extensions:
       - html
       - css
@@ -409,8 +410,8 @@ applications:
 	}
 
 	// Verify cache control configuration
-	if config.Server.Static.CacheControl.Default != "public, max-age=86400" {
-		t.Errorf("Expected default cache control 'public, max-age=86400', got %s", config.Server.Static.CacheControl.Default)
+	if d := config.Server.Static.CacheControl.Default; d == nil || d.Std() != 24*time.Hour {
+		t.Errorf("Expected default cache control 1d, got %v", d)
 	}
 
 	if len(config.Server.Static.CacheControl.Overrides) != 4 {
@@ -440,7 +441,7 @@ applications:
 	}
 
 	// Verify timeout strings are preserved
-	if config.Applications.Pools.Timeout != "10m" {
+	if config.Applications.Pools.Timeout != Duration(10*time.Minute) {
 		t.Errorf("Expected pools timeout 10m, got %s", config.Applications.Pools.Timeout)
 	}
 }
@@ -455,7 +456,7 @@ server:
   static:
     public_dir: public
     cache_control:
-      default: "no-cache"
+      default: "0"
     allowed_extensions:
       - html
       - css
@@ -537,9 +538,9 @@ pools:
 		}
 	}
 
-	// Verify cache control is set to no-cache
-	if config.Server.Static.CacheControl.Default != "no-cache" {
-		t.Errorf("Expected cache control 'no-cache', got %s", config.Server.Static.CacheControl.Default)
+	// Verify cache control always revalidates
+	if d := config.Server.Static.CacheControl.Default; d == nil || *d != 0 {
+		t.Errorf("Expected cache control 0, got %v", d)
 	}
 }
 
@@ -552,7 +553,7 @@ server:
   static:
     public_dir: public
     cache_control:
-      default: "public, max-age=86400"
+      default: "1d"
   idle:
     action: suspend
     timeout: "20m"
@@ -607,37 +608,42 @@ hooks:
 
 	// Verify all duration fields are preserved as strings
 
+	// Static cache lifetime
+	if d := config.Server.Static.CacheControl.Default; d == nil || d.Std() != 24*time.Hour {
+		t.Errorf("Expected cache_control default '1d', got %v", d)
+	}
+
 	// Server idle timeout
-	if config.Server.Idle.Timeout != "20m" {
+	if config.Server.Idle.Timeout != Duration(20*time.Minute) {
 		t.Errorf("Expected server idle timeout '20m', got '%s'", config.Server.Idle.Timeout)
 	}
 
 	// Application pool timeout
-	if config.Applications.Pools.Timeout != "5m" {
+	if config.Applications.Pools.Timeout != Duration(5*time.Minute) {
 		t.Errorf("Expected pools timeout '5m', got '%s'", config.Applications.Pools.Timeout)
 	}
 
 	// Managed process start delays
 	if len(config.ManagedProcesses) >= 2 {
 		redis := config.ManagedProcesses[0]
-		if redis.StartDelay != "2s" {
+		if redis.StartDelay != Duration(2*time.Second) {
 			t.Errorf("Expected redis start_delay '2s', got '%s'", redis.StartDelay)
 		}
 
 		worker := config.ManagedProcesses[1]
-		if worker.StartDelay != "5s" {
+		if worker.StartDelay != Duration(5*time.Second) {
 			t.Errorf("Expected worker start_delay '5s', got '%s'", worker.StartDelay)
 		}
 	}
 
 	// Hook timeouts
 	if len(config.Hooks.Start) >= 1 {
-		if config.Hooks.Start[0].Timeout != "10s" {
+		if config.Hooks.Start[0].Timeout != Duration(10*time.Second) {
 			t.Errorf("Expected start hook timeout '10s', got '%s'", config.Hooks.Start[0].Timeout)
 		}
 	}
 	if len(config.Hooks.Ready) >= 1 {
-		if config.Hooks.Ready[0].Timeout != "15s" {
+		if config.Hooks.Ready[0].Timeout != Duration(15*time.Second) {
 			t.Errorf("Expected ready hook timeout '15s', got '%s'", config.Hooks.Ready[0].Timeout)
 		}
 	}
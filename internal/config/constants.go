@@ -29,11 +29,240 @@ const (
 
 	// Hook timeout defaults
 	DefaultHookTimeout = 30 * time.Second
+	HookWaitDelay      = 5 * time.Second // Time to wait for output pipes after a timed-out hook is killed
+
+	// CGI scripts aborted because their client left or they timed out get
+	// SIGTERM, then SIGKILL if still running after this long
+	CGIKillGrace = 5 * time.Second
+
+	// Runtime served by Navigator itself: an in-process backend that echoes
+	// each request as JSON, for testing configurations without the app's
+	// language installed
+	RuntimeInternalEcho = "internal-echo"
+
+	// Web app monitoring
+	IdleCheckInterval  = 30 * time.Second // How often each running web app is checked for idleness and OOM kills
+	AppOutputWaitDelay = 5 * time.Second  // Time to wait for output pipes after a web app exits, in case children hold them open
+
+	// Inline restarts after a tenant crash (restart_on_crash)
+	CrashRestartBackoff    = 1 * time.Second // Wait before a second inline restart; doubles with each consecutive crash
+	CrashRestartMaxBackoff = 1 * time.Minute
+	CrashResetWindow       = 5 * time.Minute        // A crash this long after the previous one is not consecutive
+	CrashExitWait          = 250 * time.Millisecond // How long a reset connection waits to see whether the app's process has exited
+
+	// Tenant start guards (start_guard)
+	DefaultStartGuardTTL     = 30 * time.Second
+	StartGuardRequestTimeout = 5 * time.Second // Limit on each lease request to a start guard URL
+	StartGuardRetryAfter     = 30              // Seconds a client refused by a guard held elsewhere is asked to wait
+
+	// Warm standby instances (tenants[].standby)
+	StandbyCheckInterval       = 5 * time.Second // How often the primary and standby are health checked
+	StandbyHealthCheckTimeout  = 2 * time.Second
+	StandbyHealthCheckFailures = 2 // Consecutive failed checks of the primary before the standby takes over
+
+	// Tenant app startups (applications.pools.max_concurrent_startups)
+	DefaultMaxConcurrentStartups = 4
+
+	// Tenant app lifecycle states
+	AppStateHistorySize    = 10              // State transitions kept per app
+	UnhealthyCheckInterval = 2 * time.Second // How often an app that didn't answer during startup is checked again
+
+	// Tenant recycling (applications.recycle and tenants[].recycle)
+	DefaultRecycleDrainTimeout   = 30 * time.Second
+	DefaultMaxConcurrentRecycles = 1
+	RecycleDrainPollInterval     = 100 * time.Millisecond // How often a draining instance is checked for requests in flight
+	RecycleHistorySize           = 20                     // Recycles reported by the detailed health check
+
+	// Cache warmers (tenants[].warmers)
+	DefaultWarmerInterval = 5 * time.Minute
+	MinWarmerInterval     = 10 * time.Millisecond // Shortest interval, so a typo can't flood a tenant
+	WarmerRequestTimeout  = 30 * time.Second      // Limit on each warming request
+
+	// Tenant names (tenants[].name)
+	MaxTenantNameLength = 64 // Longest file-safe tenant name; longer derived names are truncated
+
+	// Request mirroring (mirror on reverse proxies and tenants)
+	DefaultMirrorPercent       = 100.0
+	DefaultMirrorMaxConcurrent = 10
+	DefaultMirrorMaxBodyBytes  = 64 * 1024 // Larger request bodies aren't buffered for the mirror, so aren't mirrored
+	DefaultMirrorTimeout       = 10 * time.Second
+
+	// Response body filters (response_filter on reverse proxies and tenants)
+	DefaultResponseFilterMaxBodyBytes = 1024 * 1024 // Larger responses aren't buffered for the filter, so are served unmodified
+	DefaultResponseFilterTimeout      = 5 * time.Second
+	DefaultResponseFilterContentType  = "text/html"
+	ResponseFilterEncodedSkip         = "skip"   // Compressed responses are served unmodified
+	ResponseFilterEncodedDecode       = "decode" // Gzip responses are decompressed, filtered, and served uncompressed
+
+	// Tenants paused through server.control_path
+	TenantPauseRetryAfter = 30 // Seconds a client of a paused tenant without a ttl is asked to wait
+
+	// Recent tenant errors listed through server.control_path
+	DefaultErrorHistory  = 50    // Errors kept per tenant (server.error_history)
+	ErrorHistoryBudget   = 10000 // Errors kept across all tenants; the tenants whose last error is oldest are forgotten first
+	ErrorHistoryMaxBytes = 512   // Longer paths and error messages are truncated
+
+	// Configurations validated through server.control_path
+	MaxValidateConfigBody = 10 << 20 // Largest configuration accepted in a validate-config request body
+
+	// Load shedding (server.load_shedding)
+	DefaultLoadSheddingInterval = 5 * time.Second
+	DefaultLoadSheddingRecovery = 0.9 // Fraction of each threshold readings must fall within before shedding stops
+	LoadSheddingRetryAfter      = 10  // Seconds a shed client is asked to wait
+
+	// Lifecycle event delivery defaults
+	DefaultEventHookTimeout    = 10 * time.Second
+	DefaultEventHookRetryDelay = 1 * time.Second
+	EventQueueSize             = 256             // Events queued per destination before new events are dropped
+	EventFlushTimeout          = 5 * time.Second // Time queued events get to be delivered before suspend or shutdown
+
+	// Per-tenant request queue defaults, used when max_concurrent_requests is set
+	DefaultRequestQueueSize    = 100
+	DefaultRequestQueueTimeout = 30 * time.Second
+	RequestQueueRetryAfter     = 5 // Seconds a client rejected by a full queue is asked to wait
 
 	// Buffer sizes
 	DefaultBufferSize    = 4096
 	MaxRetryBufferSize   = 64 * 1024 // 64KB - most responses are smaller
 	DefaultLogBufferSize = 8192
+
+	// Body capture defaults
+	DefaultCaptureMaxBytes = 4096
+
+	// Response header a tenant app names a file to send in, unless
+	// applications.sendfile.header is set
+	DefaultSendfileHeader = "X-Sendfile"
+
+	// Flight recorder defaults
+	DefaultFlightRecorderMaxFiles     = 100
+	DefaultFlightRecorderMaxBytes     = 10 * 1024 * 1024 // 10MB
+	DefaultFlightRecorderMaxBodyBytes = 4096
+	FlightRecorderQueueSize           = 256 // Entries waiting to be written before new ones are dropped
+
+	// Largest file accepted by an upload area unless max_file_size is set
+	DefaultUploadMaxFileSize = 100 * 1024 * 1024 // 100MB
+
+	// Request body decompression
+	DefaultMaxCompressedRequestBody   = 1024 * 1024      // 1MB read before decoding
+	DefaultMaxDecompressedRequestBody = 10 * 1024 * 1024 // 10MB decoded unless max_request_body is set
+
+	// Static file sources
+	StaticSourceDir        = "dir"
+	StaticSourceArchive    = "archive"
+	StaticSourceS3         = "s3"
+	DefaultStaticCacheSize = 64 * 1024 * 1024 // 64MB of extracted or downloaded files
+	DefaultStaticS3TTL     = 5 * time.Minute
+	DefaultStaticS3Region  = "auto"
+	StaticS3RequestTimeout = 10 * time.Second
+
+	// Public directories and tenant roots that disappear at runtime
+	StaticRootUnavailable     = "unavailable"    // Answer static requests with 503 while public_dir is unreachable (default)
+	StaticRootNotFound        = "not_found"      // Treat every file as missing while public_dir is unreachable
+	RootUnavailableRetryAfter = 5                // Seconds a client answered 503 for a missing root is asked to wait
+	StaticRootLogInterval     = 10 * time.Second // Least time between logged "Static root unavailable" errors
+
+	// Single-page applications
+	DefaultSPAFallback     = "index.html"         // Under the SPA's path
+	DefaultSPAAssetsMaxAge = 365 * 24 * time.Hour // Hashed assets never change under the same name
+
+	// Static files with a content hash in their name, e.g. application-1a2b3c4d.css
+	DefaultFingerprintPattern = `-[0-9a-f]{8,}\.[^/]+$`
+	DefaultFingerprintMaxAge  = 365 * 24 * time.Hour
+
+	// Content encodings for precompressed static sidecars and decoded
+	// request bodies
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+
+	// Client connection timeouts (server.timeouts)
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultConnIdleTimeout   = 2 * time.Minute
+	DefaultTCPKeepAlive      = 15 * time.Second
+
+	// Files served from server.well_known
+	DefaultWellKnownCacheControl = "public, max-age=3600"
+	RobotsTxtPath                = "/robots.txt"
+	SecurityTxtPath              = "/.well-known/security.txt"
+
+	// Graceful shutdown defaults
+	DefaultShutdownTimeout = 30 * time.Second
+	ImmediateSignalSecond  = "second" // Another shutdown signal while draining aborts in-flight requests
+	ImmediateSignalNone    = "none"   // Signals never abort draining; only the timeout does
+
+	// Multi-process worker mode
+	WorkerRestartDelay  = 1 * time.Second // Delay before restarting a crashed worker
+	WorkerShutdownGrace = 5 * time.Second // Time workers get to shut down after draining, before being killed
+
+	// Managed processes restarted this often within the window are reported as crash looping
+	CrashLoopRestarts = 5
+	CrashLoopWindow   = 5 * time.Minute
+
+	// Memory held by all in-flight proxy retry buffers combined; requests beyond
+	// it are proxied without buffering and are not retried
+	MaxRetryBufferTotal = 32 * 1024 * 1024 // 32MB
+
+	// Request coalescing defaults
+	DefaultCoalesceMaxResponseSize = 1024 * 1024 // 1MB - larger responses are proxied per request
+
+	// Response cache defaults
+	DefaultResponseCacheTTL           = 60 * time.Second
+	DefaultResponseCacheMaxObjectSize = 1024 * 1024      // 1MB - larger responses are not cached
+	DefaultResponseCacheMaxMemory     = 64 * 1024 * 1024 // 64MB shared by all cached responses
+
+	// Diagnostic bundles
+	DiagnosticsDirName = "navigator-diagnostics" // Created under the system temp directory unless server.diagnostics.dir is set
+
+	// Fly-replay target verification
+	DefaultFlyReplayVerifyTTL = 30 * time.Second // How long a reachability probe result is trusted
+	FlyReplayProbeTimeout     = 2 * time.Second  // Connect timeout for a reachability probe
+	DefaultMaxReplayHops      = 1                // Replays a request may already have had before another is refused
+
+	// Reverse proxy DNS resolution
+	DefaultDNSTTL     = 30 * time.Second
+	DNSResolveTimeout = 5 * time.Second // Limit on each lookup of a target host
+	DNSResolverSystem = "system"
+	DNSTransportLimit = 256 // Target hosts whose transports are kept; the least recently used beyond it are closed
+
+	// Access log lines queued per destination before new lines are dropped
+	AccessLogBufferSize   = 4096
+	AccessLogFlushTimeout = 5 * time.Second // Limit on writing queued lines at shutdown
+
+	// Log limit defaults
+	LogDropSummaryInterval = 10 * time.Second // How often dropped-line summaries are emitted
+
+	// Multiline log entry defaults
+	DefaultMultilineMaxLines = 500
+	DefaultMultilineMaxBytes = 64 * 1024
+	DefaultMultilineTimeout  = 250 * time.Millisecond // Wait for more output before an incomplete entry is written
+
+	// Idle action deferral
+	IdleDeferRecheckInterval = 5 * time.Second // How often deferred idle actions check whether the work is done
+	IdleHookVetoExitCode     = 75              // Exit status (EX_TEMPFAIL) with which a hooks.idle command cancels the idle action
+
+	// Idle state defaults
+	DefaultIdleStateFile     = "navigator-idle.json" // Kept in the directory of server.pid_file
+	DefaultIdleStateInterval = 30 * time.Second
+	DefaultIdleStateMaxAge   = 24 * time.Hour
+
+	// Tenant port state defaults
+	DefaultPortStateFile = "navigator-ports.json" // Kept in the directory of server.pid_file
+
+	// Fly Machines API defaults
+	FlyMachinesAPIURL         = "https://api.machines.dev" // Used with a token; without one, the machine's API socket
+	FlyAPISocket              = "/.fly/api"
+	FlyAPITimeout             = 10 * time.Second
+	DefaultFlyTokenEnv        = "FLY_API_TOKEN"
+	DefaultFlyScaleUpWindow   = time.Minute
+	DefaultFlyScaleUpInterval = 10 * time.Second
+	DefaultFlyScaleUpCooldown = 10 * time.Minute
+
+	// Log disk budget defaults
+	DefaultDiskBudgetInterval = time.Minute // How often the janitor measures log directories
+	DefaultDiskBudgetPattern  = "*.log*"    // Matches app.log and rotated files such as app.log.1 and app.log-20250101.gz
+
+	// Scheduled task defaults
+	DefaultScheduleTimeout = 10 * time.Minute
 )
 
 // Static file extensions that should be served directly
@@ -44,6 +273,141 @@ var StaticFileExtensions = []string{
 	"wav", "flac", "aac", "wasm", "map",
 }
 
+// Default preference order for precompressed static sidecars
+var DefaultPrecompressedEncodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+
+// Request headers that distinguish otherwise identical coalesced requests
+var DefaultCoalesceVaryHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language"}
+
+// Request headers that are part of a response cache key unless vary_headers is set
+var DefaultResponseCacheVaryHeaders = []string{"Accept", "Accept-Encoding"}
+
+// Background work that defers the idle action, for server.idle.defer_for
+const (
+	IdleDeferCGI                    = "cgi"
+	IdleDeferHooks                  = "hooks"
+	IdleDeferManagedProcessRestarts = "managed_process_restarts"
+)
+
+// Background work that defers the idle action unless server.idle.defer_for is set
+var DefaultIdleDeferFor = []string{IdleDeferCGI, IdleDeferHooks, IdleDeferManagedProcessRestarts}
+
+// Values of auth on reverse proxies, CGI scripts, and tenants
+const (
+	RouteAuthInherit  = "inherit"  // Follow the site-wide auth settings (default)
+	RouteAuthRequired = "required" // Require credentials, even when auth is disabled
+	RouteAuthPublic   = "public"   // Never require credentials
+)
+
+// Values of schedule[].overlap and schedule[].hook
+const (
+	ScheduleOverlapSkip  = "skip"  // Don't start a run while the previous one is running (default)
+	ScheduleOverlapAllow = "allow" // Start runs regardless
+	ScheduleHookStart    = "start"
+	ScheduleHookStop     = "stop"
+)
+
+// Percentages of logging.disk_budget.max_bytes at which a warning is logged
+var DefaultDiskBudgetWarnAt = []int{80, 90}
+
+// Signals accepted for managed_process_groups[].reload_signal
+var ReloadSignals = []string{"SIGHUP", "SIGINT", "SIGQUIT", "SIGTERM", "SIGUSR1", "SIGUSR2"}
+
+// Lifecycle event types delivered to hooks.events destinations
+const (
+	EventTenantStarted    = "tenant.started"
+	EventTenantStopped    = "tenant.stopped"
+	EventTenantCrashed    = "tenant.crashed"
+	EventTenantFailover   = "tenant.failover"
+	EventProcessRestarted = "process.restarted"
+	EventProcessCrashLoop = "process.crash_loop"
+	EventReloadSucceeded  = "reload.succeeded"
+	EventReloadFailed     = "reload.failed"
+	EventIdleTriggered    = "idle.triggered"
+)
+
+// Event types accepted for hooks.events[].types
+var EventTypes = []string{
+	EventTenantStarted, EventTenantStopped, EventTenantCrashed, EventTenantFailover,
+	EventProcessRestarted, EventProcessCrashLoop, EventReloadSucceeded, EventReloadFailed,
+	EventIdleTriggered,
+}
+
+// Log destinations that aren't files
+const (
+	LogDestinationStdout = "stdout"
+	LogDestinationStderr = "stderr"
+)
+
+// Log formats for logging.format, logging.app.format and logging.access.format
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatPretty = "pretty" // Concise, colored lines for a developer's terminal
+)
+
+// Start guard types and the default lock file, relative to the tenant root
+const (
+	StartGuardFile        = "file"
+	StartGuardHTTP        = "http"
+	DefaultStartGuardPath = "tmp/navigator.lock"
+)
+
+// Redirect kinds of server.canonical.redirect
+const (
+	CanonicalRedirectPermanent = "permanent" // 301, or 308 for methods other than GET and HEAD
+	CanonicalRedirectTemporary = "temporary" // 302, or 307 for methods other than GET and HEAD
+)
+
+// Values of server.forwarded_precedence, choosing the header style that wins
+// when a request has both
+const (
+	ForwardedPrecedenceForwarded  = "forwarded"   // Prefer RFC 7239 Forwarded over X-Forwarded-* (default)
+	ForwardedPrecedenceXForwarded = "x-forwarded" // Prefer X-Forwarded-* over Forwarded
+)
+
+// Values of server.absolute_uri
+const (
+	AbsoluteURINormalize = "normalize" // Route absolute-form requests by their path alone (default)
+	AbsoluteURIReject    = "reject"    // Reject absolute-form requests with 400 Bad Request
+)
+
+// Values of server.absolute_uri_host_mismatch, applied to an absolute-form
+// request whose URI names a host other than server.hostname
+const (
+	HostMismatchReject      = "reject"       // Reject with 400 Bad Request (default)
+	HostMismatchUseHostname = "use_hostname" // Serve it as a request for server.hostname
+	HostMismatchUseURI      = "use_uri"      // Serve it as a request for the URI's host, as RFC 9112 specifies
+)
+
+// I/O scheduling classes of tenants[].priority.ionice_class
+const (
+	IONiceRealtime     = "realtime"
+	IONiceBestEffort   = "best-effort"
+	IONiceIdle         = "idle"
+	DefaultIONiceLevel = 4 // Level of a realtime or best-effort class given without one
+)
+
+// Request classes rejected by server.load_shedding.shed
+const (
+	ShedUnauthenticated = "unauthenticated"
+	ShedPaths           = "paths"
+	ShedAll             = "all"
+)
+
+// Signals accepted for tenants[].stop_signal
+var StopSignals = []string{"SIGTERM", "SIGINT", "SIGQUIT", "SIGHUP", "SIGKILL"}
+
+// Values accepted for reverse_proxies[].cookie_samesite, by lowercase spelling
+var cookieSameSiteValues = map[string]string{"lax": "Lax", "strict": "Strict", "none": "None"}
+
+// Sidecar file suffix for each precompressed encoding
+var PrecompressedExtensions = map[string]string{
+	EncodingBrotli: ".br",
+	EncodingZstd:   ".zst",
+	EncodingGzip:   ".gz",
+}
+
 // Common MIME types
 var MIMETypes = map[string]string{
 	".html":  "text/html; charset=utf-8",
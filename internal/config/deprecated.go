@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// failOnDeprecated makes every load reject legacy keys, as
+// --fail-on-deprecated asks
+var failOnDeprecated atomic.Bool
+
+// SetFailOnDeprecated makes every configuration load reject legacy keys
+// instead of migrating them with a warning
+func SetFailOnDeprecated(fail bool) {
+	failOnDeprecated.Store(fail)
+}
+
+// Deprecation is a legacy key found in a configuration
+type Deprecation struct {
+	Key         string // Field path of the legacy key, e.g. "server.public_dir"
+	Line        int
+	Replacement string // Field path of the key replacing it; empty when nothing does
+	RemovedIn   string // Release in which the legacy key stops working
+	Ignored     bool   // The value was dropped: nothing replaces the key, or its replacement is also set
+}
+
+func (d Deprecation) String() string {
+	switch {
+	case d.Replacement == "":
+		return fmt.Sprintf("%s (line %d) is no longer supported and is ignored; it will be rejected in %s", d.Key, d.Line, d.RemovedIn)
+	case d.Ignored:
+		return fmt.Sprintf("%s (line %d) is deprecated and ignored because %s is also set; it stops working in %s", d.Key, d.Line, d.Replacement, d.RemovedIn)
+	}
+	return fmt.Sprintf("%s (line %d) is deprecated; use %s instead. It stops working in %s", d.Key, d.Line, d.Replacement, d.RemovedIn)
+}
+
+// legacyKey is a key earlier releases read, and where its value now goes
+type legacyKey struct {
+	path        string // Dotted path of the legacy key
+	replacement string // Dotted path of the key replacing it; empty when nothing does
+	removedIn   string
+	kind        yaml.Kind                   // Only a value of this kind is legacy (0 = any)
+	convert     func(*yaml.Node) *yaml.Node // Rewrites the value for its replacement; a nil result drops it
+	also        []string                    // Scalars set alongside the replacement, as path=value, unless already set
+}
+
+// legacyKeys are migrated in order, so a key moved by an earlier entry is
+// found at its new path by later ones
+var legacyKeys = []legacyKey{
+	{path: "pools", replacement: "applications.pools", removedIn: "v0.14.0"},
+	{path: "applications.pools.idle_timeout", replacement: "applications.pools.timeout", removedIn: "v0.14.0", convert: secondsToDuration},
+	{path: "static", replacement: "server.static", removedIn: "v0.14.0"},
+	{path: "server.public_dir", replacement: "server.static.public_dir", removedIn: "v0.14.0"},
+	{path: "server.static.extensions", replacement: "server.static.allowed_extensions", removedIn: "v0.14.0"},
+	{path: "server.static.try_files", replacement: "server.static.try_files", removedIn: "v0.14.0", kind: yaml.MappingNode, convert: tryFilesSuffixes},
+	{path: "server.static.directories", removedIn: "v0.14.0"},
+	{path: "server.authentication", replacement: "auth.htpasswd", removedIn: "v0.14.0", also: []string{"auth.enabled=true"}},
+	{path: "locations", removedIn: "v0.14.0"},
+}
+
+// secondsToDuration turns a number of seconds, as idle_timeout took, into
+// a duration; other values are kept
+func secondsToDuration(value *yaml.Node) *yaml.Node {
+	if seconds, err := strconv.Atoi(value.Value); err == nil && value.Kind == yaml.ScalarNode {
+		converted := *value
+		converted.Tag, converted.Value = "!!str", strconv.Itoa(seconds)+"s"
+		return &converted
+	}
+	return value
+}
+
+// tryFilesSuffixes turns the {enabled, suffixes} form of try_files into
+// its list of suffixes, or drops it when it wasn't enabled
+func tryFilesSuffixes(value *yaml.Node) *yaml.Node {
+	if enabled := mappingValue(value, "enabled"); enabled == nil || enabled.Value != "true" {
+		return nil
+	}
+	return mappingValue(value, "suffixes")
+}
+
+// migrateDocument moves the legacy keys of a parsed document to their
+// replacements, returning one Deprecation per legacy key found. A mapping
+// moved onto an existing one is merged, keeping the keys already set.
+func migrateDocument(document *yaml.Node) []Deprecation {
+	root := document
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var deprecations []Deprecation
+	for _, legacy := range legacyKeys {
+		parent, index := lookupKey(root, legacy.path)
+		if parent == nil {
+			continue
+		}
+		key, value := parent.Content[index], parent.Content[index+1]
+		if legacy.kind != 0 && value.Kind != legacy.kind {
+			continue
+		}
+		deprecation := Deprecation{Key: legacy.path, Line: key.Line, Replacement: legacy.replacement, RemovedIn: legacy.removedIn}
+		parent.Content = append(parent.Content[:index], parent.Content[index+2:]...)
+
+		if legacy.convert != nil {
+			value = legacy.convert(value)
+		}
+		if legacy.replacement == "" || value == nil {
+			deprecation.Ignored = true
+		} else {
+			deprecation.Ignored = !setKey(root, legacy.replacement, key, value)
+		}
+		if legacy.also != nil && !deprecation.Ignored {
+			for _, assignment := range legacy.also {
+				path, scalar, _ := strings.Cut(assignment, "=")
+				if existing, _ := lookupKey(root, path); existing == nil {
+					setKey(root, path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}, &yaml.Node{Kind: yaml.ScalarNode, Value: scalar})
+				}
+			}
+		}
+		deprecations = append(deprecations, deprecation)
+	}
+	return deprecations
+}
+
+// lookupKey finds a dotted path in a mapping, returning the mapping holding
+// its last key and the key's index in it, or nil if it isn't set
+func lookupKey(root *yaml.Node, path string) (*yaml.Node, int) {
+	node := root
+	keys := strings.Split(path, ".")
+	for i, name := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil, 0
+		}
+		index := mappingIndex(node, name)
+		if index < 0 {
+			return nil, 0
+		}
+		if i == len(keys)-1 {
+			return node, index
+		}
+		node = node.Content[index+1]
+	}
+	return nil, 0
+}
+
+// setKey sets a dotted path to value, creating the mappings on the way.
+// The legacy key node keeps its comments and position for the new key. A
+// mapping is merged into a mapping already there; otherwise a value already
+// set wins, and setKey reports false.
+func setKey(root *yaml.Node, path string, legacyKey, value *yaml.Node) bool {
+	keys := strings.Split(path, ".")
+	node := root
+	for _, name := range keys[:len(keys)-1] {
+		index := mappingIndex(node, name)
+		if index < 0 {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+			index = len(node.Content) - 2
+		}
+		if node.Content[index+1].Kind != yaml.MappingNode {
+			return false
+		}
+		node = node.Content[index+1]
+	}
+
+	name := keys[len(keys)-1]
+	index := mappingIndex(node, name)
+	if index < 0 {
+		key := *legacyKey
+		key.Value, key.Tag = name, "!!str"
+		node.Content = append(node.Content, &key, value)
+		return true
+	}
+	existing := node.Content[index+1]
+	if existing.Kind != yaml.MappingNode || value.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		if mappingIndex(existing, value.Content[i].Value) < 0 {
+			existing.Content = append(existing.Content, value.Content[i], value.Content[i+1])
+		}
+	}
+	return true
+}
+
+// mappingIndex returns the index of a key in a mapping node, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the value of a key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	if i := mappingIndex(mapping, key); i >= 0 {
+		return mapping.Content[i+1]
+	}
+	return nil
+}
+
+// MigrateYAML rewrites a configuration to the current schema, keeping its
+// comments and the order of its keys, and reports the legacy keys it moved
+func MigrateYAML(content []byte) ([]byte, []Deprecation, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	deprecations := migrateDocument(&document)
+	if len(deprecations) == 0 {
+		return content, nil, nil
+	}
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return []byte(out.String()), deprecations, nil
+}
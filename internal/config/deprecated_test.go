@@ -0,0 +1,133 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const legacyTestConfig = `
+server:
+  listen: 3000
+  # Files served before tenants
+  public_dir: public
+  authentication: config/htpasswd
+static:
+  extensions: [css, js]
+  try_files:
+    enabled: true
+    suffixes: [.html, index.html]
+  directories:
+    - path: /assets/
+      dir: assets
+pools:
+  max_size: 5
+  idle_timeout: 300
+applications:
+  tenants:
+    - path: /app/
+locations:
+  - path: /old/
+`
+
+func TestParseYAMLMigratesLegacyKeys(t *testing.T) {
+	cfg, err := ParseYAML([]byte(legacyTestConfig))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if cfg.Server.Static.PublicDir != "public" {
+		t.Errorf("PublicDir = %q, want public", cfg.Server.Static.PublicDir)
+	}
+	if strings.Join(cfg.Server.Static.AllowedExtensions, ",") != "css,js" {
+		t.Errorf("AllowedExtensions = %v", cfg.Server.Static.AllowedExtensions)
+	}
+	if strings.Join(cfg.Server.Static.TryFiles, ",") != ".html,index.html" {
+		t.Errorf("TryFiles = %v", cfg.Server.Static.TryFiles)
+	}
+	if !cfg.Auth.Enabled || cfg.Auth.HTPasswd != "config/htpasswd" {
+		t.Errorf("Auth = %+v, want enabled with config/htpasswd", cfg.Auth)
+	}
+	if cfg.Applications.Pools.MaxSize != 5 || cfg.Applications.Pools.Timeout.Std() != 5*time.Minute {
+		t.Errorf("Pools = %+v, want max_size 5 and timeout 5m", cfg.Applications.Pools)
+	}
+}
+
+func TestMigrateYAMLReportsDeprecations(t *testing.T) {
+	migrated, deprecations, err := MigrateYAML([]byte(legacyTestConfig))
+	if err != nil {
+		t.Fatalf("MigrateYAML() error = %v", err)
+	}
+
+	messages := make([]string, len(deprecations))
+	for i, deprecation := range deprecations {
+		messages[i] = deprecation.String()
+	}
+	want := []string{
+		"pools (line 15) is deprecated; use applications.pools instead. It stops working in v0.14.0",
+		"applications.pools.idle_timeout (line 17) is deprecated; use applications.pools.timeout instead. It stops working in v0.14.0",
+		"static (line 7) is deprecated; use server.static instead. It stops working in v0.14.0",
+		"server.public_dir (line 5) is deprecated; use server.static.public_dir instead. It stops working in v0.14.0",
+		"server.static.extensions (line 8) is deprecated; use server.static.allowed_extensions instead. It stops working in v0.14.0",
+		"server.static.try_files (line 9) is deprecated; use server.static.try_files instead. It stops working in v0.14.0",
+		"server.static.directories (line 12) is no longer supported and is ignored; it will be rejected in v0.14.0",
+		"server.authentication (line 6) is deprecated; use auth.htpasswd instead. It stops working in v0.14.0",
+		"locations (line 21) is no longer supported and is ignored; it will be rejected in v0.14.0",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Deprecations:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+
+	output := string(migrated)
+	for _, expected := range []string{
+		"# Files served before tenants\n",
+		"    public_dir: public\n",
+		"    timeout: 300s\n",
+		"  htpasswd: config/htpasswd\n",
+		"  enabled: true\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Migrated config missing %q:\n%s", expected, output)
+		}
+	}
+	for _, legacy := range []string{"idle_timeout", "authentication", "directories", "locations", " extensions:"} {
+		if strings.Contains(output, legacy) {
+			t.Errorf("Migrated config still contains %q:\n%s", legacy, output)
+		}
+	}
+
+	again, deprecations, err := MigrateYAML(migrated)
+	if err != nil || len(deprecations) != 0 || string(again) != output {
+		t.Errorf("Migrating a migrated config should change nothing, got %v, %v", deprecations, err)
+	}
+}
+
+func TestMigrateYAMLKeepsCurrentKeys(t *testing.T) {
+	_, deprecations, err := MigrateYAML([]byte(`
+server:
+  public_dir: old
+  static:
+    public_dir: new
+`))
+	if err != nil {
+		t.Fatalf("MigrateYAML() error = %v", err)
+	}
+	if len(deprecations) != 1 || !deprecations[0].Ignored {
+		t.Fatalf("Deprecations = %+v, want server.public_dir ignored", deprecations)
+	}
+	if want := "server.public_dir (line 3) is deprecated and ignored because server.static.public_dir is also set; it stops working in v0.14.0"; deprecations[0].String() != want {
+		t.Errorf("String() = %q, want %q", deprecations[0], want)
+	}
+}
+
+func TestParseYAMLFailOnDeprecated(t *testing.T) {
+	SetFailOnDeprecated(true)
+	defer SetFailOnDeprecated(false)
+
+	_, err := ParseYAML([]byte("server:\n  public_dir: public\n"))
+	if err == nil || !strings.Contains(err.Error(), "server.public_dir (line 2) is deprecated; use server.static.public_dir instead") {
+		t.Errorf("ParseYAML() error = %v, want the deprecated key named", err)
+	}
+	if _, err := ParseYAML([]byte("server:\n  static:\n    public_dir: public\n")); err != nil {
+		t.Errorf("Current keys should load, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// parseDiskBudget validates logging.disk_budget and applies its defaults. A
+// budget without directories covers those of the configured log files.
+func (p *ConfigParser) parseDiskBudget() error {
+	budget := &p.config.Logging.DiskBudget
+	if budget.Directory != "" {
+		budget.Directories = append([]string{budget.Directory}, budget.Directories...)
+		budget.Directory = ""
+	}
+	if budget.MaxBytes < 0 || budget.Interval < 0 {
+		return fmt.Errorf("logging.disk_budget max_bytes and interval must not be negative")
+	}
+	if budget.MaxBytes == 0 {
+		return nil
+	}
+
+	if len(budget.Directories) == 0 {
+		budget.Directories = logDirectories(p.config.Logging)
+		if len(budget.Directories) == 0 {
+			return fmt.Errorf("logging.disk_budget needs directories when no log files are configured")
+		}
+	}
+	for i, dir := range budget.Directories {
+		if dir == "" {
+			return fmt.Errorf("logging.disk_budget.directories must not contain an empty entry")
+		}
+		budget.Directories[i] = filepath.Clean(dir)
+	}
+
+	if len(budget.Patterns) == 0 {
+		budget.Patterns = []string{DefaultDiskBudgetPattern}
+	}
+	for _, pattern := range budget.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil || strings.ContainsRune(pattern, filepath.Separator) {
+			return fmt.Errorf("logging.disk_budget pattern %q must be a file name glob", pattern)
+		}
+	}
+	if budget.Interval == 0 {
+		budget.Interval = Duration(DefaultDiskBudgetInterval)
+	}
+	if budget.WarnAt == nil {
+		budget.WarnAt = slices.Clone(DefaultDiskBudgetWarnAt)
+	}
+	for _, percent := range budget.WarnAt {
+		if percent <= 0 || percent > 100 {
+			return fmt.Errorf("logging.disk_budget.warn_at must be between 1 and 100, got %d", percent)
+		}
+	}
+	slices.Sort(budget.WarnAt)
+	return nil
+}
+
+// logDirectories returns the directories of the log files Navigator writes,
+// skipping any named by the tenant in logging.file's {{app}} or
+// logging.tenant_file's {{tenant}}
+func logDirectories(logging LogConfig) []string {
+	files := append([]string{logging.File, logging.TenantFile, logging.App.Destination}, logging.Access.Destinations...)
+	for _, sink := range logging.Access.Sinks {
+		files = append(files, sink.Destination)
+	}
+	var dirs []string
+	for _, file := range files {
+		if file == "" || file == LogDestinationStdout || file == LogDestinationStderr {
+			continue
+		}
+		dir := filepath.Clean(filepath.Dir(file))
+		if !strings.Contains(dir, "{{app}}") && !strings.Contains(dir, "{{tenant}}") && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostResolver looks up the addresses of a host; *net.Resolver implements it
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// newNetResolver returns the resolver for a dns block's resolver setting:
+// the system resolver, or Go's resolver sending every query to server
+func newNetResolver(server string) *net.Resolver {
+	if server == DNSResolverSystem {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// normalizeDNSServer accepts a DNS server IP address with an optional port
+// and returns it as host:port
+func normalizeDNSServer(server string) (string, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = strings.Trim(server, "[]"), "53"
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("resolver must be %q or a DNS server IP address, got %q", DNSResolverSystem, server)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// parseProxyDNS applies defaults to a reverse proxy route's dns block and,
+// with resolve_at_load, checks that its target host resolves
+func parseProxyDNS(route *ProxyRoute) error {
+	dns := route.DNS
+	if dns == nil {
+		return nil
+	}
+	if dns.TTL < 0 {
+		return fmt.Errorf("reverse proxy %q dns: ttl must not be negative", route.Name)
+	}
+	if dns.TTL == 0 {
+		dns.TTL = Duration(DefaultDNSTTL)
+	}
+	if dns.Resolver == "" || strings.EqualFold(dns.Resolver, DNSResolverSystem) {
+		dns.Resolver = DNSResolverSystem
+	} else {
+		server, err := normalizeDNSServer(dns.Resolver)
+		if err != nil {
+			return fmt.Errorf("reverse proxy %q dns: %w", route.Name, err)
+		}
+		dns.Resolver = server
+	}
+	if dns.Lookup == nil {
+		dns.Lookup = newNetResolver(dns.Resolver)
+	}
+
+	if !dns.ResolveAtLoad {
+		return nil
+	}
+	target, err := url.Parse(route.Target)
+	if err != nil || target.Hostname() == "" || strings.Contains(target.Host, "$") {
+		return fmt.Errorf("reverse proxy %q dns: resolve_at_load needs a target with a fixed host, got %q", route.Name, route.Target)
+	}
+	host := target.Hostname()
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DNSResolveTimeout)
+	defer cancel()
+	if _, err := dns.Lookup.LookupIPAddr(ctx, host); err != nil {
+		return fmt.Errorf("reverse proxy %q dns: %w", route.Name, err)
+	}
+	return nil
+}
+
+// ResolvedTTL returns the dns block's TTL as a time.Duration
+func (d *DNSConfig) ResolvedTTL() time.Duration {
+	if d == nil || d.TTL <= 0 {
+		return DefaultDNSTTL
+	}
+	return time.Duration(d.TTL)
+}
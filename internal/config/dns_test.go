@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staticResolver resolves only the hosts it holds
+type staticResolver map[string]string
+
+func (s staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addr, ok := s[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return []net.IPAddr{{IP: net.ParseIP(addr)}}, nil
+}
+
+func TestConfigParser_ParseProxyDNS(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	yamlConfig.Routes.ReverseProxies = []ProxyRoute{
+		{Name: "api", Prefix: "/api/", Target: "http://api.internal:9000", DNS: &DNSConfig{}},
+		{Name: "search", Prefix: "/search/", Target: "http://search.internal:9000", DNS: &DNSConfig{
+			TTL: Duration(5 * time.Second), Resolver: "fdaa::3",
+		}},
+	}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	api := config.Routes.ReverseProxies[0].DNS
+	if api.ResolvedTTL() != DefaultDNSTTL || api.Resolver != DNSResolverSystem || api.Lookup != net.DefaultResolver {
+		t.Errorf("Default dns block = %+v", api)
+	}
+	search := config.Routes.ReverseProxies[1].DNS
+	if search.ResolvedTTL() != 5*time.Second || search.Resolver != "[fdaa::3]:53" {
+		t.Errorf("Custom dns block = %+v", search)
+	}
+
+	for _, resolver := range []string{"dns.internal", "10.0.0.1:dns"} {
+		yamlConfig.Routes.ReverseProxies[1].DNS = &DNSConfig{Resolver: resolver}
+		if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil || !strings.Contains(err.Error(), `reverse proxy "search" dns`) {
+			t.Errorf("Parse() with resolver %q: error = %v", resolver, err)
+		}
+	}
+}
+
+func TestConfigParser_ResolveAtLoad(t *testing.T) {
+	parse := func(target string) error {
+		yamlConfig := YAMLConfig{}
+		yamlConfig.Routes.ReverseProxies = []ProxyRoute{{
+			Name: "api", Prefix: "/api/", Target: target,
+			DNS: &DNSConfig{ResolveAtLoad: true, Lookup: staticResolver{"api.internal": "fdaa::5"}},
+		}}
+		_, err := NewConfigParser(&yamlConfig).Parse()
+		return err
+	}
+
+	for _, target := range []string{"http://api.internal:9000", "http://10.0.0.5:9000"} {
+		if err := parse(target); err != nil {
+			t.Errorf("Parse() with target %s: error = %v", target, err)
+		}
+	}
+	for _, target := range []string{"http://missing.internal:9000", "http://$1.internal/"} {
+		if err := parse(target); err == nil || !strings.Contains(err.Error(), `reverse proxy "api" dns`) {
+			t.Errorf("Parse() with target %s: error = %v, want a dns error", target, err)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a configuration duration such as "30s", "5m", "2d", or "1w".
+// Values are validated when the configuration is loaded; an empty value is zero.
+type Duration time.Duration
+
+// Extended units accepted in addition to those supported by time.ParseDuration
+var extendedDurationUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// ParseDuration parses a configuration duration. In addition to Go's units
+// (ns, us, ms, s, m, h) it accepts d (days), w (weeks), and y (365 days),
+// which may be combined with the standard units (e.g. "1d12h"). Negative
+// durations are rejected.
+func ParseDuration(s string) (time.Duration, error) {
+	input := strings.TrimSpace(s)
+	if input == "" || input == "0" {
+		return 0, nil
+	}
+	if strings.HasPrefix(input, "-") {
+		return 0, fmt.Errorf("invalid duration %q: must not be negative", s)
+	}
+
+	var total time.Duration
+	rest := input
+	for rest != "" {
+		// Leading number, including an optional fraction
+		i := 0
+		for i < len(rest) && (rest[i] == '.' || (rest[i] >= '0' && rest[i] <= '9')) {
+			i++
+		}
+		j := i
+		for j < len(rest) && (rest[j] < '0' || rest[j] > '9') && rest[j] != '.' {
+			j++
+		}
+		number, unit := rest[:i], rest[i:j]
+		if number == "" || unit == "" {
+			return 0, fmt.Errorf("invalid duration %q (use a number and unit such as \"30s\", \"5m\", \"2d\", or \"1w\")", s)
+		}
+
+		if scale, ok := extendedDurationUnits[unit]; ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+			total += time.Duration(value * float64(scale))
+		} else {
+			value, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q (use a number and unit such as \"30s\", \"5m\", \"2d\", or \"1w\")", s)
+			}
+			total += value
+		}
+		rest = rest[j:]
+	}
+	return total, nil
+}
+
+// UnmarshalYAML parses and validates a duration scalar
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: duration must be a string such as \"30s\" or \"5m\"", value.Line)
+	}
+	if value.ShortTag() == "!!null" {
+		*d = 0
+		return nil
+	}
+	parsed, err := ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML writes the duration in Go's string form
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// Std returns the value as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// OrDefault returns the value, or def when the duration is unset (zero)
+func (d Duration) OrDefault(def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return time.Duration(d)
+}
+
+// String formats the duration like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// durationErrors walks a parsed YAML document alongside the type it will be
+// decoded into and reports every invalid duration with its field path
+// (e.g. "applications.tenants[2].startup_timeout")
+func durationErrors(node *yaml.Node, t reflect.Type, path string) []string {
+	var errs []string
+	eachDuration(node, t, path, func(node *yaml.Node, path string) {
+		if node.Kind != yaml.ScalarNode {
+			errs = append(errs, fmt.Sprintf("%s: duration must be a string such as \"30s\" or \"5m\" (line %d)", path, node.Line))
+			return
+		}
+		if node.ShortTag() == "!!null" {
+			return
+		}
+		if _, err := ParseDuration(node.Value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v (line %d)", path, err, node.Line))
+		}
+	})
+	return errs
+}
+
+// ScaleDurations rewrites every duration in a configuration document,
+// multiplied by factor, and leaves everything else as written. A duration
+// that was set stays at least a millisecond, so it isn't turned off. Used
+// to run a configuration's timeouts faster under test.
+func ScaleDurations(content []byte, factor float64) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if err := migrateLegacyKeys(&document); err != nil {
+		return nil, err
+	}
+	var errs []string
+	eachDuration(&document, reflect.TypeOf(YAMLConfig{}), "", func(node *yaml.Node, path string) {
+		if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!null" {
+			return
+		}
+		d, err := ParseDuration(node.Value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v (line %d)", path, err, node.Line))
+			return
+		}
+		if d == 0 {
+			return
+		}
+		node.Value = max(time.Duration(float64(d)*factor), time.Millisecond).String()
+		node.Tag, node.Style = "!!str", 0
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid duration in configuration:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return yaml.Marshal(&document)
+}
+
+// eachDuration walks a parsed YAML document alongside the type it will be
+// decoded into, calling visit with each node that decodes into a Duration
+// and its field path
+func eachDuration(node *yaml.Node, t reflect.Type, path string, visit func(node *yaml.Node, path string)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			eachDuration(child, t, path, visit)
+		}
+		return
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			eachDuration(node.Alias, t, path, visit)
+		}
+		return
+	}
+
+	if t == durationType {
+		visit(node, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if field, ok := yamlField(t, key); ok {
+				eachDuration(node.Content[i+1], field.Type, joinFieldPath(path, key), visit)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			eachDuration(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), visit)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			eachDuration(node.Content[i+1], t.Elem(), joinFieldPath(path, key), visit)
+		}
+	}
+}
+
+// yamlField finds the struct field that yaml.v3 would decode key into
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options := yamlTag(field)
+		if name == "-" {
+			continue
+		}
+		if options == "inline" && field.Type.Kind() == reflect.Struct {
+			if inlined, ok := yamlField(field.Type, key); ok {
+				return inlined, true
+			}
+			continue
+		}
+		if name == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// yamlTag returns the key yaml.v3 decodes a field from and its tag options
+func yamlTag(field reflect.StructField) (name, options string) {
+	name, options, _ = strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, options
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
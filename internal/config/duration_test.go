@@ -0,0 +1,220 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"0s", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"5m", 5 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"100ms", 100 * time.Millisecond, false},
+		{"2d", 48 * time.Hour, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{"1y", 365 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"1d12h", 36 * time.Hour, false},
+		{"1w2d3h", (9*24 + 3) * time.Hour, false},
+		{" 20m ", 20 * time.Minute, false},
+		{"5 minutes", 0, true},
+		{"invalid", 0, true},
+		{"30", 0, true},
+		{"1x", 0, true},
+		{"d", 0, true},
+		{"-5m", 0, true},
+		{"1..5d", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDurationUnmarshalYAML(t *testing.T) {
+	var value struct {
+		Timeout Duration `yaml:"timeout"`
+		Unset   Duration `yaml:"unset"`
+		Null    Duration `yaml:"null"`
+	}
+	if err := yaml.Unmarshal([]byte("timeout: 2w\nnull:\n"), &value); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if value.Timeout.Std() != 14*24*time.Hour {
+		t.Errorf("Timeout = %v, want 336h", value.Timeout)
+	}
+	if value.Unset != 0 || value.Null != 0 {
+		t.Errorf("unset durations should be zero, got %v and %v", value.Unset, value.Null)
+	}
+
+	if err := yaml.Unmarshal([]byte("timeout: 5 minutes\n"), &value); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestDurationOrDefault(t *testing.T) {
+	if got := Duration(0).OrDefault(time.Minute); got != time.Minute {
+		t.Errorf("zero OrDefault = %v, want 1m", got)
+	}
+	if got := Duration(time.Second).OrDefault(time.Minute); got != time.Second {
+		t.Errorf("set OrDefault = %v, want 1s", got)
+	}
+}
+
+func TestParseYAMLReportsDurationFieldPaths(t *testing.T) {
+	content := `
+server:
+  idle:
+    action: suspend
+    timeout: 5 minutes
+  cgi_scripts:
+    - path: /update
+      script: /bin/true
+      timeout: soon
+applications:
+  startup_timeout: 10s
+  tenants:
+    - path: /one/
+    - path: /two/
+      startup_timeout: 1x
+managed_processes:
+  - name: redis
+    command: redis-server
+    start_delay: 2d
+hooks:
+  server:
+    start:
+      - command: echo
+        timeout: forever
+`
+	_, err := ParseYAML([]byte(content))
+	if err == nil {
+		t.Fatal("expected invalid durations to fail loading")
+	}
+
+	for _, path := range []string{
+		"server.idle.timeout",
+		"server.cgi_scripts[0].timeout",
+		"applications.tenants[1].startup_timeout",
+		"hooks.server.start[0].timeout",
+	} {
+		if !strings.Contains(err.Error(), path+": invalid duration") {
+			t.Errorf("error does not mention %s:\n%v", path, err)
+		}
+	}
+	for _, valid := range []string{"applications.startup_timeout", "start_delay"} {
+		if strings.Contains(err.Error(), valid) {
+			t.Errorf("valid field %s reported as invalid:\n%v", valid, err)
+		}
+	}
+}
+
+func TestParseYAMLTypedDurations(t *testing.T) {
+	content := `
+server:
+  idle:
+    action: stop
+    timeout: 1d
+applications:
+  pools:
+    timeout: 1w
+  startup_timeout: 15s
+  tenants:
+    - path: /showcase/2025/boston/
+      startup_timeout: 1m
+hooks:
+  server:
+    ready:
+      - command: echo
+        timeout: 0
+`
+	cfg, err := ParseYAML([]byte(content))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+
+	checks := []struct {
+		name     string
+		got      Duration
+		expected time.Duration
+	}{
+		{"server.idle.timeout", cfg.Server.Idle.Timeout, 24 * time.Hour},
+		{"applications.pools.timeout", cfg.Applications.Pools.Timeout, 7 * 24 * time.Hour},
+		{"applications.startup_timeout", cfg.Applications.StartupTimeout, 15 * time.Second},
+		{"tenant startup_timeout", cfg.Applications.Tenants[0].StartupTimeout, time.Minute},
+		{"hook timeout", cfg.Hooks.Ready[0].Timeout, 0},
+	}
+	for _, check := range checks {
+		if check.got.Std() != check.expected {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.expected)
+		}
+	}
+}
+
+func TestScaleDurations(t *testing.T) {
+	content := `
+server:
+  idle:
+    action: suspend
+    timeout: 20m
+applications:
+  startup_timeout: 1d
+  tenants:
+    - path: /showcase/2025/boston/
+      name: 30s
+      startup_timeout: 1ns
+hooks:
+  server:
+    ready:
+      - command: sleep 10s
+        timeout: 0
+`
+	scaled, err := ScaleDurations([]byte(content), 0.001)
+	if err != nil {
+		t.Fatalf("ScaleDurations: %v", err)
+	}
+	cfg, err := ParseYAML(scaled)
+	if err != nil {
+		t.Fatalf("ParseYAML of scaled config: %v\n%s", err, scaled)
+	}
+
+	checks := []struct {
+		name     string
+		got      Duration
+		expected time.Duration
+	}{
+		{"server.idle.timeout", cfg.Server.Idle.Timeout, 1200 * time.Millisecond},
+		{"applications.startup_timeout", cfg.Applications.StartupTimeout, 86400 * time.Millisecond},
+		{"tenant startup_timeout", cfg.Applications.Tenants[0].StartupTimeout, time.Millisecond},
+		{"hook timeout", cfg.Hooks.Ready[0].Timeout, 0},
+	}
+	for _, check := range checks {
+		if check.got.Std() != check.expected {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.expected)
+		}
+	}
+
+	// Strings that only look like durations are left alone
+	if name, command := cfg.Applications.Tenants[0].Name, cfg.Hooks.Ready[0].Command; name != "30s" || command != "sleep 10s" {
+		t.Errorf("name = %q, command = %q; want them unchanged", name, command)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseEgress validates the pool's default egress and gives each tenant its
+// effective egress: the tenant's settings over the pool's, with ${var}
+// references expanded from the tenant's vars
+func (p *ConfigParser) parseEgress() error {
+	yamlApps := &p.yamlConfig.Applications
+	pool := p.config.Applications.Pools.Egress
+	for i, yamlTenant := range yamlApps.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		egress := mergeEgress(pool, yamlTenant.Egress)
+		if egress == nil {
+			continue
+		}
+
+		allowUndefined := yamlApps.AllowUndefinedVars
+		if yamlTenant.AllowUndefinedVars != nil {
+			allowUndefined = *yamlTenant.AllowUndefinedVars
+		}
+		expander := newVariableExpander(tenant.Var, allowUndefined)
+		for _, field := range []struct {
+			name  string
+			value *string
+			isURL bool
+		}{
+			{"http_proxy", egress.HTTPProxy, true},
+			{"https_proxy", egress.HTTPSProxy, true},
+			{"no_proxy", egress.NoProxy, false},
+		} {
+			if field.value == nil {
+				continue
+			}
+			expanded, err := expander.expand(*field.value, nil)
+			if err != nil {
+				return fmt.Errorf("tenant %q: egress.%s: %w", tenant.Name, field.name, err)
+			}
+			if field.isURL {
+				if err := checkProxyURL(expanded); err != nil {
+					return fmt.Errorf("tenant %q: egress.%s: %w", tenant.Name, field.name, err)
+				}
+			}
+			*field.value = expanded
+		}
+		tenant.Egress = egress
+	}
+	return nil
+}
+
+// mergeEgress returns a copy of the tenant's settings over the pool's, or
+// nil if neither has an egress block
+func mergeEgress(pool, tenant *EgressConfig) *EgressConfig {
+	if pool == nil && tenant == nil {
+		return nil
+	}
+	merged := EgressConfig{}
+	for _, layer := range []*EgressConfig{pool, tenant} {
+		if layer == nil {
+			continue
+		}
+		for _, field := range []struct{ from, to **string }{
+			{&layer.HTTPProxy, &merged.HTTPProxy},
+			{&layer.HTTPSProxy, &merged.HTTPSProxy},
+			{&layer.NoProxy, &merged.NoProxy},
+		} {
+			if *field.from != nil {
+				value := **field.from
+				*field.to = &value
+			}
+		}
+	}
+	return &merged
+}
+
+// checkProxyURL accepts an empty value, for no proxy, or an absolute http,
+// https, or socks5 URL with a host
+func checkProxyURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %w", value, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("proxy URL %q must start with http://, https://, or socks5://", value)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL %q has no host", value)
+	}
+	return nil
+}
+
+// Environment returns the variables egress sets in a tenant's environment,
+// in upper and lower case, as tools disagree on which they read
+func (e *EgressConfig) Environment() map[string]string {
+	if e == nil {
+		return nil
+	}
+	env := make(map[string]string, 6)
+	for _, variable := range []struct {
+		name  string
+		value *string
+	}{
+		{"HTTP_PROXY", e.HTTPProxy},
+		{"HTTPS_PROXY", e.HTTPSProxy},
+		{"NO_PROXY", e.NoProxy},
+	} {
+		value := ""
+		if variable.value != nil {
+			value = *variable.value
+		}
+		env[variable.name] = value
+		env[strings.ToLower(variable.name)] = value
+	}
+	return env
+}
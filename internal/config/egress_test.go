@@ -0,0 +1,72 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEgress(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  pools:
+    egress:
+      http_proxy: http://${proxy_host}:3128
+      no_proxy: localhost,.internal
+  tenants:
+    - name: public
+      path: /public/
+      var:
+        proxy_host: proxy.corp.example
+    - name: direct
+      path: /direct/
+      var:
+        proxy_host: unused.example
+      egress:
+        http_proxy: ""
+        https_proxy: socks5://gateway:1080
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+
+	public := cfg.Applications.Tenants[0].Egress
+	if public == nil || public.HTTPProxy == nil || *public.HTTPProxy != "http://proxy.corp.example:3128" || public.HTTPSProxy != nil {
+		t.Errorf("public Egress = %+v, want the pool's http_proxy expanded", public)
+	}
+	direct := cfg.Applications.Tenants[1].Egress
+	if direct == nil || *direct.HTTPProxy != "" || *direct.HTTPSProxy != "socks5://gateway:1080" || *direct.NoProxy != "localhost,.internal" {
+		t.Errorf("direct Egress = %+v, want the tenant's settings over the pool's", direct)
+	}
+	if *cfg.Applications.Pools.Egress.HTTPProxy != "http://${proxy_host}:3128" {
+		t.Errorf("pool egress was expanded in place: %q", *cfg.Applications.Pools.Egress.HTTPProxy)
+	}
+}
+
+func TestParseEgressErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		egress string
+		want   string
+	}{
+		{"unsupported scheme", "http_proxy: ftp://proxy:21", "egress.http_proxy"},
+		{"missing scheme", "https_proxy: proxy.example:3128", "egress.https_proxy"},
+		{"missing host", "http_proxy: http://", "has no host"},
+		{"undefined variable", "http_proxy: http://${missing}:3128", "egress.http_proxy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - name: app
+      path: /app/
+      egress:
+        ` + tt.egress + `
+`))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
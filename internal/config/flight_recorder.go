@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// parseFlightRecorder applies the flight recorder's defaults and compiles
+// its redaction patterns, which are logging.capture's unless it has its own
+func (p *ConfigParser) parseFlightRecorder() error {
+	recorder := &p.config.Logging.FlightRecorder
+	if !recorder.Enabled {
+		return nil
+	}
+
+	if recorder.Directory == "" {
+		return fmt.Errorf("logging.flight_recorder requires a directory")
+	}
+	if len(recorder.Redact) == 0 {
+		recorder.Redact = p.config.Logging.Capture.Redact
+	}
+	if len(recorder.Redact) == 0 && !recorder.AllowUnredacted {
+		return fmt.Errorf("logging.flight_recorder requires redact rules (set allow_unredacted: true to override)")
+	}
+
+	for _, redact := range recorder.Redact {
+		compiled, err := regexp.Compile(redact)
+		if err != nil {
+			return fmt.Errorf("invalid logging.flight_recorder redact pattern %q: %w", redact, err)
+		}
+		recorder.RedactPatterns = append(recorder.RedactPatterns, compiled)
+	}
+
+	if recorder.MaxFiles <= 0 {
+		recorder.MaxFiles = DefaultFlightRecorderMaxFiles
+	}
+	if recorder.MaxBytes <= 0 {
+		recorder.MaxBytes = DefaultFlightRecorderMaxBytes
+	}
+	if recorder.MaxBodyBytes <= 0 {
+		recorder.MaxBodyBytes = DefaultFlightRecorderMaxBodyBytes
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFlightRecorder(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+logging:
+  capture:
+    redact: ['(?i)authorization: .*']
+  flight_recorder:
+    enabled: true
+    directory: /var/log/navigator/flight
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	recorder := cfg.Logging.FlightRecorder
+	if recorder.MaxFiles != DefaultFlightRecorderMaxFiles || recorder.MaxBytes != DefaultFlightRecorderMaxBytes ||
+		recorder.MaxBodyBytes != DefaultFlightRecorderMaxBodyBytes {
+		t.Errorf("FlightRecorder = %+v, want defaults", recorder)
+	}
+	if len(recorder.RedactPatterns) != 1 || !recorder.RedactPatterns[0].MatchString("Authorization: Bearer x") {
+		t.Errorf("RedactPatterns = %v, want logging.capture's", recorder.RedactPatterns)
+	}
+
+	cfg, err = ParseYAML([]byte(`
+logging:
+  flight_recorder:
+    enabled: true
+    directory: /tmp/flight
+    max_files: 5
+    redact: ['secret', 'token']
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	if recorder := cfg.Logging.FlightRecorder; recorder.MaxFiles != 5 || len(recorder.RedactPatterns) != 2 {
+		t.Errorf("FlightRecorder = %+v, want its own settings", recorder)
+	}
+
+	for _, tc := range []struct{ yaml, want string }{
+		{"logging:\n  flight_recorder:\n    enabled: true\n    redact: [x]\n", "requires a directory"},
+		{"logging:\n  flight_recorder:\n    enabled: true\n    directory: /tmp/flight\n", "requires redact rules"},
+		{"logging:\n  flight_recorder:\n    enabled: true\n    directory: /tmp/flight\n    redact: ['(']\n", "invalid logging.flight_recorder redact pattern"},
+	} {
+		if _, err := ParseYAML([]byte(tc.yaml)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ParseYAML(%q) error = %v, want %q", tc.yaml, err, tc.want)
+		}
+	}
+	if _, err := ParseYAML([]byte("logging:\n  flight_recorder:\n    enabled: true\n    directory: /tmp/flight\n    allow_unredacted: true\n")); err != nil {
+		t.Errorf("allow_unredacted: %v", err)
+	}
+}
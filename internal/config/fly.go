@@ -0,0 +1,34 @@
+package config
+
+import "fmt"
+
+// parseFly applies the defaults of fly.machines_api and checks that a
+// scale-up has somewhere to go
+func (p *ConfigParser) parseFly() error {
+	api := p.yamlConfig.Fly.MachinesAPI
+	if api.TokenEnv == "" {
+		api.TokenEnv = DefaultFlyTokenEnv
+	}
+
+	scaleUp := &api.ScaleUp
+	if scaleUp.Window < 0 || scaleUp.Interval < 0 || scaleUp.Cooldown < 0 {
+		return fmt.Errorf("fly.machines_api.scale_up: window, interval, and cooldown must not be negative")
+	}
+	scaleUp.Window = Duration(scaleUp.Window.OrDefault(DefaultFlyScaleUpWindow))
+	scaleUp.Interval = Duration(scaleUp.Interval.OrDefault(DefaultFlyScaleUpInterval))
+	scaleUp.Cooldown = Duration(scaleUp.Cooldown.OrDefault(DefaultFlyScaleUpCooldown))
+	if scaleUp.Enabled {
+		if !api.Enabled {
+			return fmt.Errorf("fly.machines_api.scale_up requires fly.machines_api.enabled")
+		}
+		if len(scaleUp.Regions) == 0 {
+			return fmt.Errorf("fly.machines_api.scale_up requires regions")
+		}
+		if scaleUp.MaxInFlight <= 0 {
+			return fmt.Errorf("fly.machines_api.scale_up requires a positive max_in_flight")
+		}
+	}
+
+	p.config.Fly.MachinesAPI = api
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFly(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+fly:
+  machines_api:
+    enabled: true
+    scale_up:
+      enabled: true
+      regions: [ord, ams]
+      max_in_flight: 20
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	api := cfg.Fly.MachinesAPI
+	if !api.Enabled || api.TokenEnv != DefaultFlyTokenEnv || api.AllowZero {
+		t.Errorf("MachinesAPI = %+v, want the default token_env", api)
+	}
+	scaleUp := api.ScaleUp
+	if scaleUp.Window.OrDefault(0) != time.Minute || scaleUp.Interval.OrDefault(0) != DefaultFlyScaleUpInterval || scaleUp.Cooldown.OrDefault(0) != DefaultFlyScaleUpCooldown {
+		t.Errorf("ScaleUp = %+v, want the default window, interval, and cooldown", scaleUp)
+	}
+
+	cfg, err = ParseYAML([]byte("server:\n  listen: 3000\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if cfg.Fly.MachinesAPI.Enabled || cfg.Fly.MachinesAPI.ScaleUp.Enabled {
+		t.Errorf("MachinesAPI = %+v, want it off unless configured", cfg.Fly.MachinesAPI)
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"scale_up without the API", "fly:\n  machines_api:\n    scale_up: {enabled: true, regions: [ord], max_in_flight: 5}\n", "requires fly.machines_api.enabled"},
+		{"scale_up without regions", "fly:\n  machines_api:\n    enabled: true\n    scale_up: {enabled: true, max_in_flight: 5}\n", "requires regions"},
+		{"scale_up without a threshold", "fly:\n  machines_api:\n    enabled: true\n    scale_up: {enabled: true, regions: [ord]}\n", "max_in_flight"},
+		{"negative window", "fly:\n  machines_api:\n    scale_up: {window: -1m}\n", "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseYAML([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
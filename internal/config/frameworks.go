@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FrameworkPreset holds the startup defaults for a tenant framework. {{port}}
+// in Args and Env values is replaced with the tenant's port, and {{name}}
+// with its file-safe name (see TenantFileName). A relative PIDFILE is under
+// the tenant's root.
+type FrameworkPreset struct {
+	Runtime     string
+	Server      string
+	Args        []string
+	HealthCheck string
+	Env         map[string]string
+	StopSignal  string // Sent to stop the app; it's killed if still running after AppOutputWaitDelay
+}
+
+// FrameworkPresets are the built-in frameworks a tenant can select with
+// framework. Tenant settings and applications.runtime/server/args entries
+// for the same name take precedence.
+var FrameworkPresets = map[string]FrameworkPreset{
+	"rails": {
+		Runtime:     "ruby",
+		Server:      "bin/rails",
+		Args:        []string{"server", "-b", "0.0.0.0", "-p", "{{port}}"},
+		HealthCheck: "/up",
+		Env:         map[string]string{"PIDFILE": "tmp/pids/{{name}}.pid", "RAILS_LOG_TO_STDOUT": "1"},
+		StopSignal:  "SIGTERM",
+	},
+	"node": {
+		Runtime:     "node",
+		Server:      "server.js",
+		HealthCheck: "/",
+		StopSignal:  "SIGTERM",
+	},
+	"bun": {
+		Runtime:     "bun",
+		Server:      "run",
+		Args:        []string{"start"},
+		HealthCheck: "/",
+		StopSignal:  "SIGTERM",
+	},
+	"django": {
+		Runtime:     "python",
+		Server:      "manage.py",
+		Args:        []string{"runserver", "0.0.0.0:{{port}}", "--noreload"},
+		HealthCheck: "/",
+		Env:         map[string]string{"PYTHONUNBUFFERED": "1"},
+		StopSignal:  "SIGINT",
+	},
+	"fastapi": {
+		Runtime:     "python",
+		Server:      "-m",
+		Args:        []string{"uvicorn", "main:app", "--host", "0.0.0.0", "--port", "{{port}}"},
+		HealthCheck: "/",
+		Env:         map[string]string{"PYTHONUNBUFFERED": "1"},
+		StopSignal:  "SIGTERM",
+	},
+	RuntimeInternalEcho: {
+		Runtime:     RuntimeInternalEcho,
+		HealthCheck: "/",
+	},
+}
+
+// FrameworkPresetNames returns the names of the built-in frameworks, sorted
+func FrameworkPresetNames() []string {
+	names := make([]string, 0, len(FrameworkPresets))
+	for name := range FrameworkPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checkTenantFrameworks rejects tenants whose framework is neither a preset
+// nor defined by applications.runtime, server or args, and normalizes
+// stop_signal
+func (p *ConfigParser) checkTenantFrameworks() error {
+	apps := &p.config.Applications
+	for i := range apps.Tenants {
+		tenant := &apps.Tenants[i]
+		if framework := tenant.Framework; framework != "" {
+			_, preset := FrameworkPresets[framework]
+			_, runtime := apps.Runtime[framework]
+			_, server := apps.Server[framework]
+			_, args := apps.Args[framework]
+			if !preset && !runtime && !server && !args {
+				return fmt.Errorf("tenant %q: unknown framework %q (available presets: %s)",
+					tenant.Name, framework, strings.Join(FrameworkPresetNames(), ", "))
+			}
+		}
+		if tenant.StopSignal != "" {
+			signal, ok := normalizeSignalName(tenant.StopSignal, StopSignals)
+			if !ok {
+				return fmt.Errorf("tenant %q: unsupported stop_signal %q (use one of %s)",
+					tenant.Name, tenant.StopSignal, strings.Join(StopSignals, ", "))
+			}
+			tenant.StopSignal = signal
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package config_test
 import (
 	"github.com/rubys/navigator/internal/config"
 	"testing"
+	"time"
 )
 
 func TestHooksConfigParsing(t *testing.T) {
@@ -80,7 +81,7 @@ applications:
 	if cfg.Applications.Hooks.Stop[0].Command != "/bin/tenant-stop" {
 		t.Errorf("Expected tenant stop hook command '/bin/tenant-stop', got '%s'", cfg.Applications.Hooks.Stop[0].Command)
 	}
-	if cfg.Applications.Hooks.Stop[0].Timeout != "2m" {
+	if cfg.Applications.Hooks.Stop[0].Timeout != config.Duration(2*time.Minute) {
 		t.Errorf("Expected tenant stop hook timeout '2m', got '%s'", cfg.Applications.Hooks.Stop[0].Timeout)
 	}
 }
@@ -140,3 +141,34 @@ hooks:
 		t.Errorf("Expected empty reload_config, got '%s'", hook.ReloadConfig)
 	}
 }
+
+func TestHooksExecutionOptions(t *testing.T) {
+	yamlContent := `
+hooks:
+  server:
+    ready:
+      - name: warm-cache
+        command: curl -s localhost:3000/ | head -1
+        shell: true
+        dir: /rails
+        env:
+          RAILS_ENV: production
+        continue_on_error: true
+`
+
+	cfg, err := config.ParseYAML([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	hook := cfg.Hooks.Ready[0]
+	if hook.Name != "warm-cache" || !hook.Shell || !hook.ContinueOnError {
+		t.Errorf("Unexpected hook options: %+v", hook)
+	}
+	if hook.Dir != "/rails" {
+		t.Errorf("Expected dir '/rails', got '%s'", hook.Dir)
+	}
+	if hook.Env["RAILS_ENV"] != "production" {
+		t.Errorf("Expected env RAILS_ENV=production, got %v", hook.Env)
+	}
+}
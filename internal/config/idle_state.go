@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// parseIdleState applies the defaults of server.idle.state, which keeps its
+// file next to the PID file unless told otherwise
+func (p *ConfigParser) parseIdleState() error {
+	state := p.yamlConfig.Server.Idle.State
+	if state.Interval < 0 || state.MaxAge < 0 {
+		return fmt.Errorf("server.idle.state interval and max_age must not be negative")
+	}
+	if state.File == "" {
+		state.File = filepath.Join(filepath.Dir(p.config.Server.PIDFile), DefaultIdleStateFile)
+	}
+	state.Interval = Duration(state.Interval.OrDefault(DefaultIdleStateInterval))
+	state.MaxAge = Duration(state.MaxAge.OrDefault(DefaultIdleStateMaxAge))
+	p.config.Server.Idle.State = state
+	return nil
+}
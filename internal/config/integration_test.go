@@ -26,6 +26,7 @@ func TestParseAuthConfig_NoWarningsForGlobPatterns(t *testing.T) {
 				Pattern string `yaml:"pattern"`
 				Action  string `yaml:"action"`
 			} `yaml:"auth_patterns"`
+			Scopes []AuthScope `yaml:"scopes"`
 		}{
 			Enabled:  true,
 			HTPasswd: "/etc/htpasswd",
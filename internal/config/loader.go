@@ -1,9 +1,13 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,21 +20,72 @@ func LoadConfig(filename string) (*Config, error) {
 	}
 
 	slog.Debug("Loading YAML configuration")
-	return ParseYAML(content)
+	cfg, err := ParseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	cfg.FileHash = hex.EncodeToString(sum[:])
+	if warning := cfg.ForceHTTPSWarning(); warning != "" {
+		slog.Warn("Canonical redirect may loop", "reason", warning)
+	}
+	return cfg, nil
 }
 
 // ParseYAML parses the new YAML configuration format
 func ParseYAML(content []byte) (*Config, error) {
+	// Report every invalid duration with its field path before decoding
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if err := migrateLegacyKeys(&document); err != nil {
+		return nil, err
+	}
+	if errs := durationErrors(&document, reflect.TypeOf(YAMLConfig{}), ""); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid duration in configuration:\n  %s", strings.Join(errs, "\n  "))
+	}
+
 	var yamlConfig YAMLConfig
-	if err := yaml.Unmarshal(content, &yamlConfig); err != nil {
+	if err := document.Decode(&yamlConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
+	if yamlConfig.Strict || strictConfig.Load() {
+		if errs := unknownKeys(&document, reflect.TypeOf(YAMLConfig{}), ""); len(errs) > 0 {
+			return nil, fmt.Errorf("unknown keys in configuration:\n  %s", strings.Join(errs, "\n  "))
+		}
+	}
 
 	// Use the new parser to convert YAML config to internal Config structure
 	parser := NewConfigParser(&yamlConfig)
 	return parser.Parse()
 }
 
+// migrateLegacyKeys moves legacy keys to their replacements, warning about
+// each, or rejects them when --fail-on-deprecated is set
+func migrateLegacyKeys(document *yaml.Node) error {
+	deprecations := migrateDocument(document)
+	if len(deprecations) == 0 {
+		return nil
+	}
+	if failOnDeprecated.Load() {
+		messages := make([]string, len(deprecations))
+		for i, deprecation := range deprecations {
+			messages[i] = deprecation.String()
+		}
+		return fmt.Errorf("deprecated keys in configuration:\n  %s", strings.Join(messages, "\n  "))
+	}
+	for _, deprecation := range deprecations {
+		slog.Warn("Deprecated configuration key",
+			"key", deprecation.Key,
+			"line", deprecation.Line,
+			"replacement", deprecation.Replacement,
+			"removed_in", deprecation.RemovedIn,
+			"ignored", deprecation.Ignored)
+	}
+	return nil
+}
+
 // UpdateConfig updates configuration dynamically
 func UpdateConfig(currentConfig *Config, newConfig *Config) {
 	currentConfig.LocationConfigMutex.Lock()
@@ -42,6 +97,7 @@ func UpdateConfig(currentConfig *Config, newConfig *Config) {
 	currentConfig.Routes = newConfig.Routes
 	currentConfig.Applications = newConfig.Applications
 	currentConfig.ManagedProcesses = newConfig.ManagedProcesses
+	currentConfig.ProcessGroups = newConfig.ProcessGroups
 	currentConfig.Logging = newConfig.Logging
 	currentConfig.Hooks = newConfig.Hooks
 
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow is a scheduled period of maintenance: either a one-off
+// window between Start and End, or a recurring window that opens whenever
+// Schedule matches and stays open for Duration
+type MaintenanceWindow struct {
+	Start    string   `yaml:"start"`    // RFC3339; empty = already started
+	End      string   `yaml:"end"`      // RFC3339; empty = until removed from the config
+	Schedule string   `yaml:"schedule"` // Cron-style "minute hour day month weekday" at which a recurring window opens
+	Duration Duration `yaml:"duration"` // How long each recurring window lasts
+	TimeZone string   `yaml:"timezone"` // IANA time zone the schedule is evaluated in (default: UTC)
+
+	start, end time.Time
+	schedule   *cronSchedule
+}
+
+// maintenanceState caches the evaluation of a block's windows, which holds
+// until the next window opens or closes
+type maintenanceState struct {
+	mu          sync.Mutex
+	from, to    time.Time // The cached result applies to [from, to); zero to = forever
+	active      bool
+	until       time.Time
+	initialized bool
+}
+
+// Active reports whether the block puts its requests in maintenance at now,
+// and when the maintenance is scheduled to end. until is zero when there's
+// no scheduled end, as with enabled: true.
+func (m *MaintenanceConfig) Active(now time.Time) (active bool, until time.Time) {
+	if m == nil {
+		return false, time.Time{}
+	}
+	if m.Enabled {
+		return true, time.Time{}
+	}
+	if len(m.Windows) == 0 {
+		return false, time.Time{}
+	}
+	if m.state == nil {
+		return m.evaluate(now)
+	}
+
+	state := m.state
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.initialized && !now.Before(state.from) && (state.to.IsZero() || now.Before(state.to)) {
+		return state.active, state.until
+	}
+	active, until, next := m.evaluateWindows(now)
+	state.from, state.to, state.active, state.until, state.initialized = now, next, active, until, true
+	return active, until
+}
+
+// evaluate reports the state at now without caching it
+func (m *MaintenanceConfig) evaluate(now time.Time) (bool, time.Time) {
+	active, until, _ := m.evaluateWindows(now)
+	return active, until
+}
+
+// evaluateWindows combines the windows at now, also returning when the
+// result next changes (zero if it never does)
+func (m *MaintenanceConfig) evaluateWindows(now time.Time) (active bool, until, next time.Time) {
+	for i := range m.Windows {
+		windowActive, windowUntil, windowNext := m.Windows[i].evaluate(now)
+		if windowActive {
+			// Overlapping windows last until the latest one closes; an
+			// open-ended window has no end to report
+			if !active || (!until.IsZero() && (windowUntil.IsZero() || windowUntil.After(until))) {
+				until = windowUntil
+			}
+			active = true
+		}
+		if !windowNext.IsZero() && (next.IsZero() || windowNext.Before(next)) {
+			next = windowNext
+		}
+	}
+	return active, until, next
+}
+
+// evaluate reports whether the window is open at now, when it closes, and
+// when it next opens or closes
+func (w *MaintenanceWindow) evaluate(now time.Time) (active bool, until, next time.Time) {
+	if w.schedule == nil {
+		switch {
+		case !w.start.IsZero() && now.Before(w.start):
+			return false, time.Time{}, w.start
+		case w.end.IsZero() || now.Before(w.end):
+			return true, w.end, w.end
+		default:
+			return false, time.Time{}, time.Time{}
+		}
+	}
+
+	duration := w.Duration.Std()
+	opened := w.schedule.next(now.Add(-duration))
+	if opened.IsZero() {
+		return false, time.Time{}, time.Time{}
+	}
+	if opened.After(now) {
+		return false, time.Time{}, opened
+	}
+	// Consecutive windows that overlap are one maintenance period
+	until = opened.Add(duration)
+	for i := 0; i < 1000; i++ {
+		reopened := w.schedule.next(opened)
+		if reopened.IsZero() || reopened.After(until) {
+			break
+		}
+		opened, until = reopened, reopened.Add(duration)
+	}
+	return true, until, until
+}
+
+// compileMaintenance validates a maintenance block's windows and prepares
+// them for evaluation
+func compileMaintenance(m *MaintenanceConfig) error {
+	if m == nil || len(m.Windows) == 0 {
+		return nil
+	}
+	for i := range m.Windows {
+		window := &m.Windows[i]
+		if err := window.compile(); err != nil {
+			return fmt.Errorf("window %d: %w", i+1, err)
+		}
+	}
+	m.state = &maintenanceState{}
+	return nil
+}
+
+// compile parses a window's timestamps or schedule
+func (w *MaintenanceWindow) compile() error {
+	if w.Schedule != "" {
+		if w.Start != "" || w.End != "" {
+			return fmt.Errorf("schedule can't be combined with start or end")
+		}
+		if w.Duration.Std() <= 0 {
+			return fmt.Errorf("schedule %q needs a duration", w.Schedule)
+		}
+		location := time.UTC
+		if w.TimeZone != "" {
+			var err error
+			if location, err = time.LoadLocation(w.TimeZone); err != nil {
+				return fmt.Errorf("invalid timezone %q: %w", w.TimeZone, err)
+			}
+		}
+		schedule, err := parseCronSchedule(w.Schedule, location)
+		if err != nil {
+			return err
+		}
+		w.schedule = schedule
+		return nil
+	}
+
+	if w.Start == "" && w.End == "" {
+		return fmt.Errorf("needs start, end, or schedule")
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{{"start", w.Start, &w.start}, {"end", w.End, &w.end}} {
+		if field.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, field.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: must be an RFC3339 timestamp", field.name, field.value)
+		}
+		*field.time = parsed
+	}
+	if !w.start.IsZero() && !w.end.IsZero() && !w.end.After(w.start) {
+		return fmt.Errorf("end %s is not after start %s", w.End, w.Start)
+	}
+	return nil
+}
+
+// cronSchedule is a parsed five-field cron expression
+type cronSchedule struct {
+	minute, hour, day, month, weekday uint64 // Bit n set = value n matches
+	dayRestricted, weekdayRestricted  bool
+	location                          *time.Location
+}
+
+var (
+	cronMonthNames   = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	cronWeekdayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// parseCronSchedule parses "minute hour day month weekday". Each field
+// accepts *, numbers, ranges (1-5), lists (1,15), and steps (*/15, 9-17/2);
+// months and weekdays also accept three-letter names, and weekday 7 is Sunday.
+func parseCronSchedule(expr string, location *time.Location) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have five fields: minute hour day month weekday", expr)
+	}
+
+	s := &cronSchedule{location: location}
+	for i, spec := range []struct {
+		bits     *uint64
+		min, max int
+		names    map[string]int
+	}{
+		{&s.minute, 0, 59, nil},
+		{&s.hour, 0, 23, nil},
+		{&s.day, 1, 31, nil},
+		{&s.month, 1, 12, cronMonthNames},
+		{&s.weekday, 0, 7, cronWeekdayNames},
+	} {
+		bits, err := parseCronField(fields[i], spec.min, spec.max, spec.names)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		*spec.bits = bits
+	}
+	if s.weekday&(1<<7) != 0 {
+		s.weekday |= 1 // 7 is another name for Sunday
+	}
+	s.dayRestricted = fields[2] != "*"
+	s.weekdayRestricted = fields[4] != "*"
+	return s, nil
+}
+
+// parseCronField returns the values a cron field matches as a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is not a value from %d to %d", s, min, max)
+		}
+		return n, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = value(from); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = value(to); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				high = max // "5/15" means from 5 to the end, every 15
+			}
+			if high < low {
+				return 0, fmt.Errorf("range %q runs backwards", rangePart)
+			}
+		}
+		for n := low; n <= high; n += step {
+			bits |= 1 << n
+		}
+	}
+	return bits, nil
+}
+
+// matchesDay applies cron's rule that when both day and weekday are
+// restricted, either one matching is enough
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	day := s.day&(1<<t.Day()) != 0
+	weekday := s.weekday&(1<<int(t.Weekday())) != 0
+	if s.dayRestricted && s.weekdayRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// next returns the first minute strictly after t that the schedule matches,
+// or zero if none does within five years
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func compiledMaintenance(t *testing.T, windows ...MaintenanceWindow) *MaintenanceConfig {
+	t.Helper()
+	m := &MaintenanceConfig{Windows: windows}
+	if err := compileMaintenance(m); err != nil {
+		t.Fatalf("compileMaintenance() error = %v", err)
+	}
+	return m
+}
+
+func TestMaintenanceFixedWindow(t *testing.T) {
+	m := compiledMaintenance(t, MaintenanceWindow{Start: "2025-06-01T02:00:00Z", End: "2025-06-01T04:00:00Z"})
+	end := mustParseTime(t, "2025-06-01T04:00:00Z")
+
+	tests := []struct {
+		now    string
+		active bool
+	}{
+		{"2025-06-01T01:59:59Z", false},
+		{"2025-06-01T02:00:00Z", true},
+		{"2025-06-01T03:30:00Z", true},
+		{"2025-06-01T04:00:00Z", false},
+		{"2025-06-02T00:00:00Z", false},
+		{"2025-06-01T03:00:00Z", true}, // Clock moved backwards
+	}
+	for _, tt := range tests {
+		active, until := m.Active(mustParseTime(t, tt.now))
+		if active != tt.active {
+			t.Errorf("Active(%s) = %v, want %v", tt.now, active, tt.active)
+		}
+		if active && !until.Equal(end) {
+			t.Errorf("Active(%s) until = %v, want %v", tt.now, until, end)
+		}
+	}
+}
+
+func TestMaintenanceOpenEndedWindow(t *testing.T) {
+	m := compiledMaintenance(t, MaintenanceWindow{Start: "2025-06-01T02:00:00Z"})
+	if active, _ := m.Active(mustParseTime(t, "2025-06-01T01:00:00Z")); active {
+		t.Error("Window active before its start")
+	}
+	active, until := m.Active(mustParseTime(t, "2026-01-01T00:00:00Z"))
+	if !active || !until.IsZero() {
+		t.Errorf("Active() = %v, %v; want active with no end", active, until)
+	}
+}
+
+func TestMaintenanceRecurringWindow(t *testing.T) {
+	// Sundays from 02:00 to 03:30 New York time
+	m := compiledMaintenance(t, MaintenanceWindow{Schedule: "0 2 * * SUN", Duration: Duration(90 * time.Minute), TimeZone: "America/New_York"})
+
+	tests := []struct {
+		now    string
+		active bool
+		until  string
+	}{
+		{"2025-06-01T05:59:00Z", false, ""}, // Sunday 01:59 EDT
+		{"2025-06-01T06:00:00Z", true, "2025-06-01T07:30:00Z"},
+		{"2025-06-01T07:29:59Z", true, "2025-06-01T07:30:00Z"},
+		{"2025-06-01T07:30:00Z", false, ""},
+		{"2025-06-02T06:30:00Z", false, ""}, // Monday
+		{"2025-06-08T06:15:00Z", true, "2025-06-08T07:30:00Z"},
+	}
+	for _, tt := range tests {
+		active, until := m.Active(mustParseTime(t, tt.now))
+		if active != tt.active {
+			t.Errorf("Active(%s) = %v, want %v", tt.now, active, tt.active)
+		}
+		if tt.until != "" && !until.Equal(mustParseTime(t, tt.until)) {
+			t.Errorf("Active(%s) until = %v, want %s", tt.now, until, tt.until)
+		}
+	}
+}
+
+func TestMaintenanceOverlappingRecurringWindows(t *testing.T) {
+	// Opens every 30 minutes for 45 minutes: one continuous window
+	m := compiledMaintenance(t, MaintenanceWindow{Schedule: "*/30 1 * * *", Duration: Duration(45 * time.Minute)})
+	active, until := m.Active(mustParseTime(t, "2025-06-01T01:10:00Z"))
+	if !active || !until.Equal(mustParseTime(t, "2025-06-01T02:15:00Z")) {
+		t.Errorf("Active() = %v, %v; want active until 02:15", active, until)
+	}
+}
+
+func TestMaintenanceEnabledOverridesWindows(t *testing.T) {
+	m := compiledMaintenance(t, MaintenanceWindow{Start: "2030-01-01T00:00:00Z", End: "2030-01-02T00:00:00Z"})
+	m.Enabled = true
+	active, until := m.Active(mustParseTime(t, "2025-06-01T00:00:00Z"))
+	if !active || !until.IsZero() {
+		t.Errorf("Active() = %v, %v; want active with no end", active, until)
+	}
+
+	var none *MaintenanceConfig
+	if active, _ := none.Active(time.Now()); active {
+		t.Error("nil maintenance block is active")
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"0 2 * * *", "2025-06-01T02:00:00Z", "2025-06-02T02:00:00Z"},
+		{"15,45 9-17/4 * * MON-FRI", "2025-05-30T17:50:00Z", "2025-06-02T09:15:00Z"},
+		{"0 0 1 jan *", "2025-06-01T00:00:00Z", "2026-01-01T00:00:00Z"},
+		{"0 0 13 * 5", "2025-06-01T00:00:00Z", "2025-06-06T00:00:00Z"}, // Day or weekday
+		{"0 0 * * 7", "2025-06-01T00:00:00Z", "2025-06-08T00:00:00Z"},
+		{"0 0 31 2 *", "2025-06-01T00:00:00Z", ""},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCronSchedule(tt.expr, time.UTC)
+		if err != nil {
+			t.Fatalf("parseCronSchedule(%q) error = %v", tt.expr, err)
+		}
+		got := schedule.next(mustParseTime(t, tt.from))
+		if tt.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%q after %s = %v, want never", tt.expr, tt.from, got)
+			}
+			continue
+		}
+		if !got.Equal(mustParseTime(t, tt.want)) {
+			t.Errorf("%q after %s = %v, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestConfigParser_ParseMaintenanceWindows(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	yamlConfig.Maintenance.Windows = []MaintenanceWindow{{Start: "2025-06-01T02:00:00Z", End: "2025-06-01T04:00:00Z"}}
+	yamlConfig.Routes.ReverseProxies = []ProxyRoute{{
+		Name: "api", Prefix: "/api/", Target: "http://localhost:9000",
+		Maintenance: &MaintenanceConfig{Windows: []MaintenanceWindow{{Schedule: "0 3 * * *", Duration: Duration(time.Hour)}}},
+	}}
+
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if active, _ := config.Maintenance.Active(mustParseTime(t, "2025-06-01T03:00:00Z")); !active {
+		t.Error("Global maintenance window not active")
+	}
+	if active, _ := config.Routes.ReverseProxies[0].Maintenance.Active(mustParseTime(t, "2025-06-01T03:30:00Z")); !active {
+		t.Error("Route maintenance window not active")
+	}
+
+	for _, window := range []MaintenanceWindow{
+		{Start: "tomorrow"},
+		{Start: "2025-06-01T04:00:00Z", End: "2025-06-01T02:00:00Z"},
+		{Schedule: "0 3 * *", Duration: Duration(time.Hour)},
+		{Schedule: "0 25 * * *", Duration: Duration(time.Hour)},
+		{Schedule: "0 3 * * *"},
+		{Schedule: "0 3 * * *", Duration: Duration(time.Hour), TimeZone: "Mars/Olympus_Mons"},
+		{},
+	} {
+		yamlConfig.Routes.ReverseProxies[0].Maintenance.Windows = []MaintenanceWindow{window}
+		_, err := NewConfigParser(&yamlConfig).Parse()
+		if err == nil || !strings.Contains(err.Error(), `reverse proxy "api" maintenance`) {
+			t.Errorf("Parse() with window %+v: error = %v", window, err)
+		}
+	}
+}
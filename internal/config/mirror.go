@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseMirrors validates the mirror settings of reverse proxies and tenants
+// and applies their defaults
+func (p *ConfigParser) parseMirrors() error {
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if err := parseMirror(route.Mirror); err != nil {
+			return fmt.Errorf("reverse proxy %q: %w", route.Name, err)
+		}
+	}
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := parseMirror(tenant.Mirror); err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant.Name, err)
+		}
+	}
+	return nil
+}
+
+func parseMirror(mirror *MirrorConfig) error {
+	if mirror == nil {
+		return nil
+	}
+	parsed, err := url.Parse(mirror.Target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.RawQuery != "" {
+		return fmt.Errorf("mirror.target must be an http or https URL without a query, got %q", mirror.Target)
+	}
+	if mirror.Percent < 0 || mirror.Percent > 100 {
+		return fmt.Errorf("mirror.percent must be between 0 and 100, got %v", mirror.Percent)
+	}
+	if mirror.Percent == 0 {
+		mirror.Percent = DefaultMirrorPercent
+	}
+	if mirror.MaxConcurrent < 0 || mirror.MaxBodyBytes < 0 || mirror.Timeout < 0 {
+		return fmt.Errorf("mirror.max_concurrent, max_body_bytes, and timeout must not be negative")
+	}
+	if mirror.MaxConcurrent == 0 {
+		mirror.MaxConcurrent = DefaultMirrorMaxConcurrent
+	}
+	if mirror.MaxBodyBytes == 0 {
+		mirror.MaxBodyBytes = DefaultMirrorMaxBodyBytes
+	}
+	if mirror.Timeout == 0 {
+		mirror.Timeout = Duration(DefaultMirrorTimeout)
+	}
+	if len(mirror.Methods) == 0 {
+		mirror.Methods = []string{"GET"}
+	}
+	for i, method := range mirror.Methods {
+		mirror.Methods[i] = strings.ToUpper(strings.TrimSpace(method))
+	}
+	return nil
+}
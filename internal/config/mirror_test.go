@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMirror(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /studios/boston/
+      mirror:
+        target: http://rewrite.internal
+        methods: [get, Post]
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	want := &MirrorConfig{
+		Target:        "http://rewrite.internal",
+		Percent:       DefaultMirrorPercent,
+		Methods:       []string{"GET", "POST"},
+		MaxConcurrent: DefaultMirrorMaxConcurrent,
+		MaxBodyBytes:  DefaultMirrorMaxBodyBytes,
+		Timeout:       Duration(DefaultMirrorTimeout),
+	}
+	if got := cfg.Applications.Tenants[0].Mirror; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mirror = %+v, want %+v", got, want)
+	}
+
+	cfg, err = ParseYAML([]byte("routes:\n  reverse_proxies:\n    - name: api\n      prefix: /api/\n      target: http://api.internal\n      mirror: {target: 'https://shadow.internal/v2', percent: 5, timeout: 2s}\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if mirror := cfg.Routes.ReverseProxies[0].Mirror; mirror.Percent != 5 || mirror.Timeout.Std() != 2*time.Second || mirror.Methods[0] != "GET" {
+		t.Errorf("Route mirror = %+v", mirror)
+	}
+
+	for mirror, wantErr := range map[string]string{
+		"{target: /shadow}":                                    "must be an http or https URL",
+		"{target: 'http://shadow.internal/?x=1'}":              "without a query",
+		"{target: http://shadow.internal, percent: 150}":       "between 0 and 100",
+		"{target: http://shadow.internal, max_body_bytes: -1}": "must not be negative",
+	} {
+		_, err := ParseYAML([]byte("applications:\n  tenants:\n    - path: /studios/boston/\n      mirror: " + mirror + "\n"))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("mirror %s: error = %v, want %q", mirror, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// parseNegotiation validates the negotiate targets of reverse proxies and
+// tenants, normalizing their media types and compiling their paths
+func (p *ConfigParser) parseNegotiation() error {
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if err := parseNegotiatedTargets(route.Negotiate); err != nil {
+			return fmt.Errorf("reverse proxy %q: %w", route.Name, err)
+		}
+	}
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := parseNegotiatedTargets(tenant.Negotiate); err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant.Name, err)
+		}
+	}
+	return nil
+}
+
+func parseNegotiatedTargets(targets []NegotiatedTarget) error {
+	for i := range targets {
+		target := &targets[i]
+		if len(target.Accept) == 0 && len(target.ContentType) == 0 {
+			return fmt.Errorf("negotiate[%d]: needs accept or content_type", i)
+		}
+		parsed, err := url.Parse(target.Target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("negotiate[%d]: target must be an http or https URL, got %q", i, target.Target)
+		}
+		for j, mediaType := range target.Accept {
+			if target.Accept[j], err = normalizeMediaRange(mediaType); err != nil {
+				return fmt.Errorf("negotiate[%d].accept: %w", i, err)
+			}
+		}
+		for j, mediaType := range target.ContentType {
+			if target.ContentType[j], err = normalizeMediaRange(mediaType); err != nil {
+				return fmt.Errorf("negotiate[%d].content_type: %w", i, err)
+			}
+		}
+		if target.Path != "" {
+			if target.Pattern, err = regexp.Compile(target.Path); err != nil {
+				return fmt.Errorf("negotiate[%d].path: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeMediaRange lowercases a "type/subtype" or "type/*" media range.
+// "*/*" is rejected: the route's own target already serves everything else.
+func normalizeMediaRange(mediaRange string) (string, error) {
+	mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+	mainType, subtype, ok := strings.Cut(mediaRange, "/")
+	if !ok || mainType == "" || subtype == "" || mainType == "*" || strings.ContainsAny(mediaRange, " ;,") ||
+		strings.Contains(subtype, "/") {
+		return "", fmt.Errorf("invalid media type %q (use type/subtype or type/*)", mediaRange)
+	}
+	return mediaRange, nil
+}
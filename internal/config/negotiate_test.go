@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNegotiation(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+routes:
+  reverse_proxies:
+    - name: feed
+      prefix: /feed
+      target: http://rails.internal
+      negotiate:
+        - accept: [" Application/JSON "]
+          target: http://api.internal
+applications:
+  tenants:
+    - path: /studios/boston/
+      negotiate:
+        - path: /feed$
+          content_type: [multipart/*]
+          target: https://uploads.internal
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if accept := cfg.Routes.ReverseProxies[0].Negotiate[0].Accept; accept[0] != "application/json" {
+		t.Errorf("Accept = %q, want normalized media type", accept)
+	}
+	if target := cfg.Applications.Tenants[0].Negotiate[0]; target.Pattern == nil || !target.Pattern.MatchString("/studios/boston/feed") {
+		t.Errorf("Tenant negotiate path not compiled: %+v", target)
+	}
+
+	for name, negotiate := range map[string]string{
+		"no conditions":    "- target: http://api.internal",
+		"relative target":  "- accept: [application/json]\n  target: /api",
+		"any media type":   "- accept: ['*/*']\n  target: http://api.internal",
+		"missing subtype":  "- content_type: [json]\n  target: http://api.internal",
+		"media type param": "- accept: ['application/json; q=1']\n  target: http://api.internal",
+		"invalid path":     "- path: '('\n  accept: [application/json]\n  target: http://api.internal",
+	} {
+		indented := "        " + strings.ReplaceAll(negotiate, "\n", "\n        ")
+		_, err := ParseYAML([]byte("applications:\n  tenants:\n    - path: /studios/boston/\n      negotiate:\n" + indented + "\n"))
+		if err == nil || !strings.Contains(err.Error(), `tenant "/studios/boston": negotiate[0]`) {
+			t.Errorf("%s: got %v, want a negotiate error", name, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"net/textproto"
+	"slices"
+	"strings"
+)
+
+// outboundFixedHeaders are written by Go's transport itself, or looked up by
+// it in canonical form, so header_case can't change their spelling
+var outboundFixedHeaders = []string{
+	"Accept-Encoding", "Connection", "Content-Length", "Host", "Te", "Trailer", "Transfer-Encoding", "User-Agent",
+}
+
+// parseOutbound validates server.outbound and each reverse proxy route's
+// own, giving routes without one the server's
+func (p *ConfigParser) parseOutbound() error {
+	server := &p.config.Server
+	outbound := p.yamlConfig.Server.Outbound
+	if err := checkOutbound("server.outbound", outbound, server.MaxDecompressedBody); err != nil {
+		return err
+	}
+	server.Outbound = outbound
+
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if route.Outbound == nil {
+			route.Outbound = outbound
+			continue
+		}
+		field := fmt.Sprintf("routes.reverse_proxies[%d].outbound", i)
+		if err := checkOutbound(field, route.Outbound, server.MaxDecompressedBody); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOutbound validates an outbound block's header spellings and sets
+// the largest body it buffers to maxBody
+func checkOutbound(field string, outbound *OutboundConfig, maxBody int64) error {
+	if outbound == nil {
+		return nil
+	}
+	for i, name := range outbound.HeaderCase {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("%s.header_case[%d]: invalid header name %q", field, i, outbound.HeaderCase[i])
+		}
+		if slices.Contains(outboundFixedHeaders, textproto.CanonicalMIMEHeaderKey(name)) {
+			return fmt.Errorf("%s.header_case[%d]: the spelling of %s can't be changed", field, i, name)
+		}
+		outbound.HeaderCase[i] = name
+	}
+	outbound.MaxBufferedBody = maxBody
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutbound(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+server:
+  max_request_body: 4096
+  outbound:
+    suppress_accept_encoding: true
+routes:
+  reverse_proxies:
+    - name: device
+      prefix: /device/
+      target: http://10.0.0.5
+      outbound:
+        header_case: [" X-API-Key", SOAPAction]
+        identity_transfer: true
+        disable_retries: true
+    - name: api
+      prefix: /api/
+      target: http://localhost:9001
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	device := cfg.Routes.ReverseProxies[0].Outbound
+	if device == nil || strings.Join(device.HeaderCase, ",") != "X-API-Key,SOAPAction" || !device.IdentityTransfer || !device.DisableRetries || device.SuppressAcceptEncoding {
+		t.Errorf("device Outbound = %+v", device)
+	}
+	if device.MaxBufferedBody != 4096 {
+		t.Errorf("MaxBufferedBody = %d, want max_request_body", device.MaxBufferedBody)
+	}
+	if api := cfg.Routes.ReverseProxies[1].Outbound; api != cfg.Server.Outbound || api == nil || !api.SuppressAcceptEncoding {
+		t.Errorf("api Outbound = %+v, want server.outbound", api)
+	}
+
+	for _, tc := range []struct{ yaml, want string }{
+		{"server:\n  outbound:\n    header_case: [\"X Key\"]\n", "invalid header name"},
+		{"server:\n  outbound:\n    header_case: [user-agent]\n", "can't be changed"},
+		{"routes:\n  reverse_proxies:\n    - prefix: /x/\n      target: http://localhost:1\n      outbound:\n        header_case: [HOST]\n", "routes.reverse_proxies[0].outbound.header_case[0]"},
+	} {
+		if _, err := ParseYAML([]byte(tc.yaml)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ParseYAML(%q) error = %v, want %q", tc.yaml, err, tc.want)
+		}
+	}
+}
@@ -2,7 +2,16 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -21,27 +30,6 @@ func normalizePathWithTrailingSlash(path string) string {
 	return path
 }
 
-// expandVariables expands ${var} placeholders in the given map using provided variables
-func expandVariables(env map[string]string, vars map[string]interface{}) map[string]string {
-	result := make(map[string]string)
-	for key, value := range env {
-		expanded := value
-		for varName, varValue := range vars {
-			// Convert interface{} to string
-			strValue := ""
-			switch v := varValue.(type) {
-			case string:
-				strValue = v
-			default:
-				strValue = fmt.Sprintf("%v", v)
-			}
-			expanded = strings.ReplaceAll(expanded, "${"+varName+"}", strValue)
-		}
-		result[key] = expanded
-	}
-	return result
-}
-
 // ConfigParser handles the parsing of YAML configuration into internal structures
 type ConfigParser struct {
 	yamlConfig *YAMLConfig
@@ -59,18 +47,153 @@ func NewConfigParser(yamlConfig *YAMLConfig) *ConfigParser {
 // Parse converts the YAML configuration to the internal Config structure
 func (p *ConfigParser) Parse() (*Config, error) {
 	p.parseServerConfig()
+	if err := p.parseIdleDeferFor(); err != nil {
+		return nil, err
+	}
+	if err := p.parseIdleState(); err != nil {
+		return nil, err
+	}
+	if err := p.parseHealthCheck(); err != nil {
+		return nil, err
+	}
 	p.parseCableConfig()
 	p.parseAuthConfig()
-	p.parseRoutesConfig()
-	p.parseApplicationConfig()
-	p.parseManagedProcesses()
-	p.parseLoggingConfig()
+	if err := p.parseAuthScopes(); err != nil {
+		return nil, err
+	}
+	if err := p.parseUploads(); err != nil {
+		return nil, err
+	}
+	if err := p.parseStaticSource(); err != nil {
+		return nil, err
+	}
+	if err := p.parseRootUnavailable(); err != nil {
+		return nil, err
+	}
+	if err := p.parseFingerprints(); err != nil {
+		return nil, err
+	}
+	if err := p.parseMIMETypes(); err != nil {
+		return nil, err
+	}
+	if err := p.parseSPA(); err != nil {
+		return nil, err
+	}
+	if err := p.parseRoutesConfig(); err != nil {
+		return nil, err
+	}
+	if err := p.parseApplicationConfig(); err != nil {
+		return nil, err
+	}
+	if err := p.checkTenantFrameworks(); err != nil {
+		return nil, err
+	}
+	if err := p.checkTenantAliases(); err != nil {
+		return nil, err
+	}
+	if err := p.checkTenantNames(); err != nil {
+		return nil, err
+	}
+	if err := p.parseStartGuards(); err != nil {
+		return nil, err
+	}
+	if err := p.parseNegotiation(); err != nil {
+		return nil, err
+	}
+	if err := p.parseResponseFilters(); err != nil {
+		return nil, err
+	}
+	if err := p.parseRouteAuth(); err != nil {
+		return nil, err
+	}
+	if err := p.parseMirrors(); err != nil {
+		return nil, err
+	}
+	if err := p.parseRecycle(); err != nil {
+		return nil, err
+	}
+	if err := p.parseSendfile(); err != nil {
+		return nil, err
+	}
+	if err := p.parseWarmers(); err != nil {
+		return nil, err
+	}
+	if err := p.parsePriorities(); err != nil {
+		return nil, err
+	}
+	if err := p.parseEgress(); err != nil {
+		return nil, err
+	}
+	if err := p.parseTenantPorts(); err != nil {
+		return nil, err
+	}
+	if err := p.parseConcurrencyLimits(); err != nil {
+		return nil, err
+	}
+	if err := p.parseResponseCaches(); err != nil {
+		return nil, err
+	}
+	if err := p.parseManagedProcesses(); err != nil {
+		return nil, err
+	}
+	if err := p.parseLoggingConfig(); err != nil {
+		return nil, err
+	}
 	p.parseHooksConfig()
-	p.parseMaintenanceConfig()
+	if err := p.parseShutdownConfig(); err != nil {
+		return nil, err
+	}
+	if err := p.parseLoadShedding(); err != nil {
+		return nil, err
+	}
+	if err := p.parseCanonical(); err != nil {
+		return nil, err
+	}
+	if err := p.parseForwardedPrecedence(); err != nil {
+		return nil, err
+	}
+	if err := p.parseAbsoluteURI(); err != nil {
+		return nil, err
+	}
+	if err := p.parseTimeouts(); err != nil {
+		return nil, err
+	}
+	if err := p.parseWellKnown(); err != nil {
+		return nil, err
+	}
+	if err := p.parseRequestBody(); err != nil {
+		return nil, err
+	}
+	if err := p.parseOutbound(); err != nil {
+		return nil, err
+	}
+	if err := p.parseRequestID(); err != nil {
+		return nil, err
+	}
+	if err := p.parseProxyProtocol(); err != nil {
+		return nil, err
+	}
+	if err := p.parseEventHooks(); err != nil {
+		return nil, err
+	}
+	if err := p.parseMaintenanceConfig(); err != nil {
+		return nil, err
+	}
+	if err := p.parseSchedule(); err != nil {
+		return nil, err
+	}
+	if err := p.parseFly(); err != nil {
+		return nil, err
+	}
+	p.config.Proxy = p.yamlConfig.Proxy
 
 	// Add automatic trailing slash redirects after all other parsing
 	p.addTrailingSlashRedirects()
 
+	if report := CheckPorts(p.config); len(report.Errors) > 0 {
+		return nil, fmt.Errorf("port conflicts:\n  %s", strings.Join(report.Errors, "\n  "))
+	}
+
 	return p.config, nil
 }
 
@@ -80,12 +203,44 @@ func (p *ConfigParser) parseServerConfig() {
 	// Normalize root_path to always have a trailing slash (unless empty)
 	p.config.Server.RootPath = normalizePathWithTrailingSlash(p.yamlConfig.Server.RootPath)
 	p.config.Server.TrustProxy = p.yamlConfig.Server.TrustProxy
+	p.config.Server.EncodedSlashes = p.yamlConfig.Server.EncodedSlashes
+	p.config.Server.Workers = p.yamlConfig.Server.Workers
+	p.config.Server.AcmeChallengeDir = p.yamlConfig.Server.AcmeChallengeDir
+	p.config.Server.MaxHeaderBytes = p.yamlConfig.Server.MaxHeaderBytes
+	p.config.Server.ControlPath = strings.TrimSuffix(p.yamlConfig.Server.ControlPath, "/")
+	p.config.Server.ErrorHistory = p.yamlConfig.Server.ErrorHistory
+	if p.config.Server.ErrorHistory <= 0 {
+		p.config.Server.ErrorHistory = DefaultErrorHistory
+	}
+	p.config.Server.RegionHeaders = p.yamlConfig.Server.RegionHeaders
+	p.config.Server.Repanic = p.yamlConfig.Server.Repanic
+	p.config.Server.PIDFile = p.yamlConfig.Server.PIDFile
+	if p.config.Server.PIDFile == "" {
+		p.config.Server.PIDFile = NavigatorPIDFile
+	}
+	p.config.Server.RequestHeaders = p.yamlConfig.Server.RequestHeaders
+	// Canonicalize so stripping matches however the header was spelled
+	for i, name := range p.config.Server.RequestHeaders.Strip {
+		p.config.Server.RequestHeaders.Strip[i] = textproto.CanonicalMIMEHeaderKey(name)
+	}
+	p.config.Server.ResponseCache = p.yamlConfig.Server.ResponseCache
+	if p.config.Server.ResponseCache.MaxMemory <= 0 {
+		p.config.Server.ResponseCache.MaxMemory = DefaultResponseCacheMaxMemory
+	}
+	p.config.Server.Diagnostics = p.yamlConfig.Server.Diagnostics
+	if p.config.Server.Diagnostics.Dir == "" {
+		p.config.Server.Diagnostics.Dir = filepath.Join(os.TempDir(), DiagnosticsDirName)
+	}
 
 	// Parse static file configuration
 	p.config.Server.Static.PublicDir = p.yamlConfig.Server.Static.PublicDir
 	p.config.Server.Static.TryFiles = p.yamlConfig.Server.Static.TryFiles
 	p.config.Server.Static.AllowedExtensions = p.yamlConfig.Server.Static.AllowedExtensions
 	p.config.Server.Static.NormalizeTrailingSlashes = p.yamlConfig.Server.Static.NormalizeTrailingSlashes
+	p.config.Server.Static.Precompressed = p.yamlConfig.Server.Static.Precompressed
+	if p.config.Server.Static.Precompressed.Enabled && len(p.config.Server.Static.Precompressed.Encodings) == 0 {
+		p.config.Server.Static.Precompressed.Encodings = DefaultPrecompressedEncodings
+	}
 
 	// Parse cache control
 	p.config.Server.Static.CacheControl.Default = p.yamlConfig.Server.Static.CacheControl.Default
@@ -111,6 +266,11 @@ func (p *ConfigParser) parseServerConfig() {
 	// Set idle configuration
 	p.config.Server.Idle.Action = p.yamlConfig.Server.Idle.Action
 	p.config.Server.Idle.Timeout = p.yamlConfig.Server.Idle.Timeout
+	p.config.Server.Idle.CountStaticRequests = true
+	if p.yamlConfig.Server.Idle.CountStaticRequests != nil {
+		p.config.Server.Idle.CountStaticRequests = *p.yamlConfig.Server.Idle.CountStaticRequests
+	}
+	p.config.Server.Idle.CountHealthChecks = p.yamlConfig.Server.Idle.CountHealthChecks
 
 	// Copy health check configuration
 	p.config.Server.HealthCheck = p.yamlConfig.Server.HealthCheck
@@ -173,14 +333,149 @@ func (p *ConfigParser) parseAuthConfig() {
 	// We don't compile them as regex here since they use glob syntax (e.g., *.css, *.js)
 }
 
-// parseMaintenanceConfig parses maintenance page configuration
-func (p *ConfigParser) parseMaintenanceConfig() {
+// parseAuthScopes validates path-scoped authentication. Scopes are matched
+// most-specific-first, so two scopes with overlapping paths of equal
+// specificity would be ambiguous and are rejected.
+func (p *ConfigParser) parseAuthScopes() error {
+	if !p.yamlConfig.Auth.Enabled && !p.routesRequireAuth() {
+		return nil
+	}
+
+	scopes := p.yamlConfig.Auth.Scopes
+	for i, scope := range scopes {
+		if len(scope.Paths) == 0 {
+			return fmt.Errorf("auth scope %d: paths is required", i+1)
+		}
+		if scope.HTPasswd == "" {
+			return fmt.Errorf("auth scope %d: htpasswd is required", i+1)
+		}
+		for _, other := range scopes[:i] {
+			for _, path := range scope.Paths {
+				for _, otherPath := range other.Paths {
+					if AuthPathSpecificity(path) == AuthPathSpecificity(otherPath) && authPathsOverlap(path, otherPath) {
+						return fmt.Errorf("auth scope %d: path %q overlaps %q with the same specificity", i+1, path, otherPath)
+					}
+				}
+			}
+		}
+	}
+	p.config.Auth.Scopes = scopes
+	return nil
+}
+
+// parseUploads validates upload areas and applies defaults. Uploads always
+// require credentials, so authentication must be enabled.
+func (p *ConfigParser) parseUploads() error {
+	for i, upload := range p.yamlConfig.Server.Static.Uploads {
+		if upload.Path == "" || !strings.HasPrefix(upload.Path, "/") {
+			return fmt.Errorf("upload %d: path must start with /", i+1)
+		}
+		if !p.config.Auth.Enabled {
+			return fmt.Errorf("upload %q: requires auth to be enabled", upload.Path)
+		}
+		if upload.AuthScope != "" && !p.authRealmExists(upload.AuthScope) {
+			return fmt.Errorf("upload %q: no auth scope with realm %q", upload.Path, upload.AuthScope)
+		}
+
+		upload.Path = normalizePathWithTrailingSlash(upload.Path)
+		if upload.Dir == "" {
+			publicDir := p.config.Server.Static.PublicDir
+			if publicDir == "" {
+				publicDir = DefaultPublicDir
+			}
+			urlPath := upload.Path
+			if rootPath := p.config.Server.RootPath; rootPath != "" {
+				urlPath = "/" + strings.TrimPrefix(urlPath, rootPath)
+			}
+			upload.Dir = filepath.Join(publicDir, filepath.FromSlash(urlPath))
+		}
+		upload.Dir = filepath.Clean(upload.Dir)
+		if upload.MaxFileSize <= 0 {
+			upload.MaxFileSize = DefaultUploadMaxFileSize
+		}
+
+		extensions := make([]string, 0, len(upload.AllowedExtensions))
+		for _, ext := range upload.AllowedExtensions {
+			extensions = append(extensions, strings.ToLower(strings.TrimPrefix(ext, ".")))
+		}
+		upload.AllowedExtensions = extensions
+
+		p.config.Server.Static.Uploads = append(p.config.Server.Static.Uploads, upload)
+	}
+	return nil
+}
+
+// authRealmExists reports whether realm names the site-wide auth realm or an auth scope
+func (p *ConfigParser) authRealmExists(realm string) bool {
+	siteRealm := p.config.Auth.Realm
+	if siteRealm == "" {
+		siteRealm = DefaultAuthRealm
+	}
+	if realm == siteRealm && p.config.Auth.HTPasswd != "" {
+		return true
+	}
+	for _, scope := range p.config.Auth.Scopes {
+		if scope.Realm == realm {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthPathSpecificity ranks an auth path pattern: the more literal characters,
+// the more specific
+func AuthPathSpecificity(pattern string) int {
+	return len(strings.ReplaceAll(pattern, "*", ""))
+}
+
+// authPathsOverlap reports whether two auth path patterns could match the same
+// path, comparing the literal text before the first and after the last wildcard
+func authPathsOverlap(a, b string) bool {
+	aPrefix, aSuffix := authPathLiterals(a)
+	bPrefix, bSuffix := authPathLiterals(b)
+	prefixes := strings.HasPrefix(aPrefix, bPrefix) || strings.HasPrefix(bPrefix, aPrefix)
+	suffixes := strings.HasSuffix(aSuffix, bSuffix) || strings.HasSuffix(bSuffix, aSuffix)
+	return prefixes && suffixes
+}
+
+// authPathLiterals returns the text a matching path must start and end with
+func authPathLiterals(pattern string) (prefix, suffix string) {
+	if first := strings.Index(pattern, "*"); first >= 0 {
+		return pattern[:first], pattern[strings.LastIndex(pattern, "*")+1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return pattern, "" // Prefix match
+	}
+	return pattern, pattern // Exact match
+}
+
+// parseMaintenanceConfig parses maintenance page configuration and the
+// maintenance windows of the server, tenants, and reverse proxies
+func (p *ConfigParser) parseMaintenanceConfig() error {
 	p.config.Maintenance.Enabled = p.yamlConfig.Maintenance.Enabled
 	p.config.Maintenance.Page = p.yamlConfig.Maintenance.Page
+	p.config.Maintenance.Windows = p.yamlConfig.Maintenance.Windows
+	if err := compileMaintenance(&p.config.Maintenance); err != nil {
+		return fmt.Errorf("maintenance: %w", err)
+	}
+
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := compileMaintenance(tenant.Maintenance); err != nil {
+			return fmt.Errorf("tenant %q maintenance: %w", tenant.Name, err)
+		}
+	}
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if err := compileMaintenance(route.Maintenance); err != nil {
+			return fmt.Errorf("reverse proxy %q maintenance: %w", route.Name, err)
+		}
+	}
+	return nil
 }
 
 // parseApplicationConfig parses application pool and tenant configuration
-func (p *ConfigParser) parseApplicationConfig() {
+func (p *ConfigParser) parseApplicationConfig() error {
 	apps := &p.config.Applications
 	yamlApps := &p.yamlConfig.Applications
 
@@ -195,6 +490,7 @@ func (p *ConfigParser) parseApplicationConfig() {
 	apps.Server = yamlApps.Server
 	apps.Args = yamlApps.Args
 	apps.HealthCheck = yamlApps.HealthCheck
+	apps.StartupTimeout = yamlApps.StartupTimeout
 
 	// Copy global track_websockets setting (default to true if not set)
 	apps.TrackWebSockets = yamlApps.TrackWebSockets
@@ -206,11 +502,31 @@ func (p *ConfigParser) parseApplicationConfig() {
 		apps.TrackWebSockets = true
 	}
 
+	apps.RestartOnCrash = yamlApps.RestartOnCrash
+	apps.IdleWebSocketGrace = yamlApps.IdleWebSocketGrace
+	apps.CloseStaleWebSockets = yamlApps.CloseStaleWebSockets
+	apps.Recycle = yamlApps.Recycle
+	apps.Sendfile = yamlApps.Sendfile
+	apps.MaxConcurrentRecycles = yamlApps.MaxConcurrentRecycles
+
+	// Copy request coalescing settings with defaults
+	apps.Coalesce = yamlApps.Coalesce
+	if apps.Coalesce.MaxResponseSize <= 0 {
+		apps.Coalesce.MaxResponseSize = DefaultCoalesceMaxResponseSize
+	}
+	if len(apps.Coalesce.VaryHeaders) == 0 {
+		apps.Coalesce.VaryHeaders = DefaultCoalesceVaryHeaders
+	}
+
 	// Process tenants
 	for _, yamlTenant := range yamlApps.Tenants {
-		// Extract tenant name from path (e.g., "/showcase/2025/raleigh/" -> "2025/raleigh")
-		tenantName := strings.TrimPrefix(yamlTenant.Path, "/showcase/")
-		tenantName = strings.TrimSuffix(tenantName, "/")
+		// Extract tenant name from path (e.g., "/showcase/2025/raleigh/" -> "2025/raleigh"),
+		// unless it's named explicitly
+		tenantName := yamlTenant.Name
+		if tenantName == "" {
+			tenantName = strings.TrimPrefix(yamlTenant.Path, "/showcase/")
+			tenantName = strings.TrimSuffix(tenantName, "/")
+		}
 
 		tenant := Tenant{
 			Name:            tenantName,
@@ -224,12 +540,44 @@ func (p *ConfigParser) parseApplicationConfig() {
 			Var:             yamlTenant.Var,
 			Hooks:           yamlTenant.Hooks,
 			HealthCheck:     yamlTenant.HealthCheck,
+			StartupTimeout:  yamlTenant.StartupTimeout,
 			TrackWebSockets: yamlTenant.TrackWebSockets, // nil means use global setting
+			RestartOnCrash:  yamlTenant.RestartOnCrash,  // nil means use global setting
+			StopSignal:      yamlTenant.StopSignal,
+			Cache:           yamlTenant.Cache,
+			AliasRedirect:   yamlTenant.AliasRedirect,
+			MaxHeaderBytes:  yamlTenant.MaxHeaderBytes,
+			Maintenance:     yamlTenant.Maintenance,
+			StartGuard:      yamlTenant.StartGuard,
+			Standby:         yamlTenant.Standby,
+			Negotiate:       yamlTenant.Negotiate,
+			Mirror:          yamlTenant.Mirror,
+			Priority:        yamlTenant.Priority,
+			Port:            yamlTenant.Port,
+		}
+		tenant.IdleWebSocketGrace = yamlTenant.IdleWebSocketGrace
+		tenant.CloseStaleWebSockets = yamlTenant.CloseStaleWebSockets // nil means use global setting
+		tenant.ResponseFilter = yamlTenant.ResponseFilter
+		tenant.Auth, tenant.AuthScope = yamlTenant.Auth, yamlTenant.AuthScope
+		tenant.Recycle = yamlTenant.Recycle
+		tenant.Warmers = yamlTenant.Warmers
+		tenant.DecompressRequests = yamlTenant.DecompressRequests
+		tenant.Sendfile = yamlTenant.Sendfile
+		for _, alias := range yamlTenant.Aliases {
+			tenant.Aliases = append(tenant.Aliases, normalizePathWithTrailingSlash(alias))
 		}
 
 		// Expand environment variables with tenant vars
 		if apps.Env != nil {
-			tenant.Env = expandVariables(apps.Env, tenant.Var)
+			allowUndefined := yamlApps.AllowUndefinedVars
+			if yamlTenant.AllowUndefinedVars != nil {
+				allowUndefined = *yamlTenant.AllowUndefinedVars
+			}
+			env, err := expandTenantEnv(tenantName, apps.Env, tenant.Var, allowUndefined)
+			if err != nil {
+				return err
+			}
+			tenant.Env = env
 		}
 
 		// Merge with tenant-specific environment
@@ -244,16 +592,377 @@ func (p *ConfigParser) parseApplicationConfig() {
 
 		apps.Tenants = append(apps.Tenants, tenant)
 	}
+	return nil
+}
+
+// checkTenantAliases rejects aliases that duplicate another tenant path or alias,
+// since a request could then belong to either tenant
+func (p *ConfigParser) checkTenantAliases() error {
+	owners := make(map[string]string)
+	for _, tenant := range p.config.Applications.Tenants {
+		owners[tenant.Path] = tenant.Name
+	}
+	for _, tenant := range p.config.Applications.Tenants {
+		for _, alias := range tenant.Aliases {
+			if owner, ok := owners[alias]; ok {
+				return fmt.Errorf("tenant %q: alias %s is already used by tenant %q", tenant.Name, alias, owner)
+			}
+			owners[alias] = tenant.Name
+		}
+	}
+	return nil
+}
+
+// parseConcurrencyLimits applies the startup limit's default and resolves
+// each tenant's request concurrency limit: tenant settings override the pool
+// defaults, and queue defaults apply once a limit is set
+func (p *ConfigParser) parseConcurrencyLimits() error {
+	pools := &p.config.Applications.Pools
+	if pools.MaxConcurrentStartups < 0 {
+		return fmt.Errorf("applications.pools.max_concurrent_startups must not be negative")
+	}
+	if pools.MaxConcurrentStartups == 0 {
+		pools.MaxConcurrentStartups = DefaultMaxConcurrentStartups
+	}
+
+	pool := pools.ConcurrencyConfig
+	if err := checkConcurrency("applications.pools", pool); err != nil {
+		return err
+	}
+
+	for i, yamlTenant := range p.yamlConfig.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		override := yamlTenant.ConcurrencyConfig
+		if err := checkConcurrency("tenant "+strconv.Quote(tenant.Name), override); err != nil {
+			return err
+		}
+
+		limit := pool
+		if override.MaxConcurrentRequests > 0 {
+			limit.MaxConcurrentRequests = override.MaxConcurrentRequests
+		}
+		if override.QueueSize > 0 {
+			limit.QueueSize = override.QueueSize
+		}
+		if override.QueueTimeout > 0 {
+			limit.QueueTimeout = override.QueueTimeout
+		}
+		if override.CountWebSockets != nil {
+			limit.CountWebSockets = override.CountWebSockets
+		}
+
+		if limit.MaxConcurrentRequests > 0 {
+			if limit.QueueSize == 0 {
+				limit.QueueSize = DefaultRequestQueueSize
+			}
+			if limit.QueueTimeout == 0 {
+				limit.QueueTimeout = Duration(DefaultRequestQueueTimeout)
+			}
+		}
+		tenant.Concurrency = limit
+	}
+	return nil
 }
 
-// parseManagedProcesses parses managed process configuration
-func (p *ConfigParser) parseManagedProcesses() {
+// checkConcurrency rejects negative concurrency settings
+func checkConcurrency(owner string, limit ConcurrencyConfig) error {
+	if limit.MaxConcurrentRequests < 0 || limit.QueueSize < 0 || limit.QueueTimeout < 0 {
+		return fmt.Errorf("%s: max_concurrent_requests, queue_size, and queue_timeout must not be negative", owner)
+	}
+	return nil
+}
+
+// parseManagedProcesses parses managed process configuration and process groups
+func (p *ConfigParser) parseManagedProcesses() error {
 	p.config.ManagedProcesses = p.yamlConfig.ManagedProcesses
+
+	seen := make(map[string]bool)
+	for _, group := range p.yamlConfig.ProcessGroups {
+		if group.Name == "" {
+			return fmt.Errorf("managed_process_groups: name is required")
+		}
+		if seen[group.Name] {
+			return fmt.Errorf("managed_process_groups: duplicate group %q", group.Name)
+		}
+		seen[group.Name] = true
+
+		if group.ReloadSignal != "" {
+			signal, ok := normalizeSignalName(group.ReloadSignal, ReloadSignals)
+			if !ok {
+				return fmt.Errorf("managed_process_groups: group %q has unsupported reload_signal %q (use one of %s)",
+					group.Name, group.ReloadSignal, strings.Join(ReloadSignals, ", "))
+			}
+			group.ReloadSignal = signal
+		}
+		p.config.ProcessGroups = append(p.config.ProcessGroups, group)
+	}
+	return nil
+}
+
+// normalizeSignalName converts "hup", "HUP", or "SIGHUP" to "SIGHUP"
+func normalizeSignalName(name string, signals []string) (string, bool) {
+	signal := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(signal, "SIG") {
+		signal = "SIG" + signal
+	}
+	for _, supported := range signals {
+		if signal == supported {
+			return signal, true
+		}
+	}
+	return "", false
+}
+
+// parseIdleDeferFor validates server.idle.defer_for, which defaults to every
+// kind of background work
+func (p *ConfigParser) parseIdleDeferFor() error {
+	deferFor := p.yamlConfig.Server.Idle.DeferFor
+	if deferFor == nil {
+		p.config.Server.Idle.DeferFor = slices.Clone(DefaultIdleDeferFor)
+		return nil
+	}
+	for _, kind := range *deferFor {
+		if !slices.Contains(DefaultIdleDeferFor, kind) {
+			return fmt.Errorf("server.idle.defer_for must list cgi, hooks, or managed_process_restarts, got %q", kind)
+		}
+	}
+	p.config.Server.Idle.DeferFor = *deferFor
+	return nil
+}
+
+// parseHealthCheck validates server.health_check
+func (p *ConfigParser) parseHealthCheck() error {
+	health := p.config.Server.HealthCheck
+	if health.Quiet && (health.Path == "" || health.Response == nil) {
+		return fmt.Errorf("server.health_check.quiet requires a path and a synthetic response")
+	}
+	return nil
 }
 
 // parseLoggingConfig parses logging configuration
-func (p *ConfigParser) parseLoggingConfig() {
+func (p *ConfigParser) parseLoggingConfig() error {
 	p.config.Logging = p.yamlConfig.Logging
+	if err := p.parseLogDestinations(); err != nil {
+		return err
+	}
+	if err := p.parseLogLimits(); err != nil {
+		return err
+	}
+	if err := p.parseMultilineConfig(); err != nil {
+		return err
+	}
+	if err := p.parseCaptureConfig(); err != nil {
+		return err
+	}
+	if err := p.parseFlightRecorder(); err != nil {
+		return err
+	}
+	return p.parseDiskBudget()
+}
+
+// parseLogDestinations validates logging.app and logging.access and applies
+// defaults. The flat logging.format still sets the operational log format
+// unless logging.app.format is given; access entries stay JSON by default
+// unless it is pretty. Formats left empty are chosen when the log is opened:
+// pretty on a terminal, else text (or JSON for access entries).
+func (p *ConfigParser) parseLogDestinations() error {
+	logging := &p.config.Logging
+	if !validLogFormat(logging.Format) {
+		return fmt.Errorf("logging.format must be text, json, or pretty, got %q", logging.Format)
+	}
+	app := &logging.App
+	if app.Destination == "" {
+		app.Destination = LogDestinationStdout
+	}
+	if app.Format == "" {
+		app.Format = logging.Format
+	}
+	if !validLogFormat(app.Format) {
+		return fmt.Errorf("logging.app.format must be text, json, or pretty, got %q", app.Format)
+	}
+	app.Level = strings.ToLower(app.Level)
+	switch app.Level {
+	case "", "debug", "info", "warn", "error":
+	case "warning":
+		app.Level = "warn"
+	default:
+		return fmt.Errorf("logging.app.level must be debug, info, warn, or error, got %q", app.Level)
+	}
+
+	if logging.TenantFile != "" && !strings.Contains(logging.TenantFile, "{{tenant}}") {
+		return fmt.Errorf("logging.tenant_file must contain {{tenant}}, so each tenant has its own file, got %q", logging.TenantFile)
+	}
+
+	access := &logging.Access
+	if access.Destination != "" {
+		access.Destinations = append([]string{access.Destination}, access.Destinations...)
+		access.Destination = ""
+	}
+	if len(access.Sinks) > 0 && len(access.Destinations) > 0 {
+		return fmt.Errorf("logging.access.sinks replaces logging.access.destinations; set only one")
+	}
+	if len(access.Destinations) == 0 && len(access.Sinks) == 0 {
+		access.Destinations = []string{LogDestinationStdout}
+	}
+	for _, destination := range access.Destinations {
+		if destination == "" {
+			return fmt.Errorf("logging.access.destinations must not contain an empty entry")
+		}
+	}
+	if access.Format == "" && logging.Format != "" {
+		access.Format = LogFormatJSON
+		if logging.Format == LogFormatPretty {
+			access.Format = LogFormatPretty
+		}
+	}
+	if !validLogFormat(access.Format) {
+		return fmt.Errorf("logging.access.format must be text, json, or pretty, got %q", access.Format)
+	}
+	if rate := access.SampleRate; rate != nil && (*rate < 0 || *rate > 1) {
+		return fmt.Errorf("logging.access.sample_rate must be between 0 and 1, got %g", *rate)
+	}
+	return parseAccessLogSinks(access)
+}
+
+// validLogFormat reports whether format is a log format, or empty
+func validLogFormat(format string) bool {
+	switch format {
+	case "", LogFormatText, LogFormatJSON, LogFormatPretty:
+		return true
+	}
+	return false
+}
+
+// parseMultilineConfig compiles the multiline start pattern and applies
+// defaults. The caps and timeout also bound partial lines in passthrough mode.
+func (p *ConfigParser) parseMultilineConfig() error {
+	multiline := &p.config.Logging.Multiline
+	if multiline.MaxLines < 0 || multiline.MaxBytes < 0 || multiline.Timeout < 0 {
+		return fmt.Errorf("logging.multiline values must not be negative")
+	}
+	if multiline.Start != "" {
+		pattern, err := regexp.Compile(multiline.Start)
+		if err != nil {
+			return fmt.Errorf("invalid logging.multiline start pattern %q: %w", multiline.Start, err)
+		}
+		multiline.StartPattern = pattern
+	}
+	if multiline.MaxLines == 0 {
+		multiline.MaxLines = DefaultMultilineMaxLines
+	}
+	if multiline.MaxBytes == 0 {
+		multiline.MaxBytes = DefaultMultilineMaxBytes
+	}
+	if multiline.Timeout == 0 {
+		multiline.Timeout = Duration(DefaultMultilineTimeout)
+	}
+	return nil
+}
+
+// parseLogLimits validates process output limits and applies defaults
+func (p *ConfigParser) parseLogLimits() error {
+	limits := &p.config.Logging.Limits
+	if limits.MaxLineLength < 0 || limits.RateLimit < 0 || limits.Burst < 0 || limits.MaxBytesPerDay < 0 {
+		return fmt.Errorf("logging.limits values must not be negative")
+	}
+	if limits.RateLimit > 0 && limits.Burst == 0 {
+		limits.Burst = int(math.Ceil(limits.RateLimit))
+	}
+	return nil
+}
+
+// parseCaptureConfig compiles body capture patterns and enforces redaction guard rails
+func (p *ConfigParser) parseCaptureConfig() error {
+	capture := &p.config.Logging.Capture
+	if !capture.Enabled {
+		return nil
+	}
+
+	if capture.Path == "" {
+		return fmt.Errorf("logging.capture requires a path pattern")
+	}
+	if capture.File == "" {
+		return fmt.Errorf("logging.capture requires a file")
+	}
+	if len(capture.Redact) == 0 && !capture.AllowUnredacted {
+		return fmt.Errorf("logging.capture requires redact rules (set allow_unredacted: true to override)")
+	}
+
+	pattern, err := regexp.Compile(capture.Path)
+	if err != nil {
+		return fmt.Errorf("invalid logging.capture path %q: %w", capture.Path, err)
+	}
+	capture.PathPattern = pattern
+
+	for _, redact := range capture.Redact {
+		compiled, err := regexp.Compile(redact)
+		if err != nil {
+			return fmt.Errorf("invalid logging.capture redact pattern %q: %w", redact, err)
+		}
+		capture.RedactPatterns = append(capture.RedactPatterns, compiled)
+	}
+
+	if capture.MaxBytes <= 0 {
+		capture.MaxBytes = DefaultCaptureMaxBytes
+	}
+
+	return nil
+}
+
+// parseResponseCaches applies defaults to the cache settings of reverse proxies
+// and tenants and compiles tenant path patterns
+func (p *ConfigParser) parseResponseCaches() error {
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if route.Cache == nil {
+			continue
+		}
+		if len(route.Cache.Paths) > 0 {
+			return fmt.Errorf("reverse proxy %q: cache.paths is only supported for tenants (use the route's path)", route.Name)
+		}
+		cache, err := normalizeResponseCache(route.Cache)
+		if err != nil {
+			return fmt.Errorf("reverse proxy %q: %w", route.Name, err)
+		}
+		route.Cache = cache
+	}
+
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if tenant.Cache == nil {
+			continue
+		}
+		cache, err := normalizeResponseCache(tenant.Cache)
+		if err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant.Name, err)
+		}
+		tenant.Cache = cache
+	}
+	return nil
+}
+
+// normalizeResponseCache returns a copy of cfg with defaults applied and paths compiled
+func normalizeResponseCache(cfg *ResponseCacheConfig) (*ResponseCacheConfig, error) {
+	cache := *cfg
+	if cache.TTL <= 0 {
+		cache.TTL = Duration(DefaultResponseCacheTTL)
+	}
+	if cache.MaxObjectSize <= 0 {
+		cache.MaxObjectSize = DefaultResponseCacheMaxObjectSize
+	}
+	if len(cache.VaryHeaders) == 0 {
+		cache.VaryHeaders = DefaultResponseCacheVaryHeaders
+	}
+
+	cache.PathPatterns = nil
+	for _, path := range cache.Paths {
+		pattern, err := regexp.Compile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache path %q: %w", path, err)
+		}
+		cache.PathPatterns = append(cache.PathPatterns, pattern)
+	}
+	return &cache, nil
 }
 
 // parseHooksConfig parses lifecycle hooks
@@ -269,30 +978,353 @@ func (p *ConfigParser) parseHooksConfig() {
 	p.config.Applications.Hooks.Stop = p.yamlConfig.Hooks.Tenant.Stop
 }
 
+// parseEventHooks validates lifecycle event destinations and applies defaults
+func (p *ConfigParser) parseEventHooks() error {
+	for _, hook := range p.yamlConfig.Hooks.Events {
+		name := hook.Name
+		if name == "" {
+			name = hook.URL + hook.Command
+		}
+		switch {
+		case hook.URL == "" && hook.Command == "":
+			return fmt.Errorf("event hook %q: url or command is required", name)
+		case hook.URL != "" && hook.Command != "":
+			return fmt.Errorf("event hook %q: url and command are mutually exclusive", name)
+		case hook.Retries < 0:
+			return fmt.Errorf("event hook %q: retries must not be negative", name)
+		}
+		for _, eventType := range hook.Types {
+			if !slices.Contains(EventTypes, eventType) {
+				return fmt.Errorf("event hook %q: unknown event type %q (use one of %s)",
+					name, eventType, strings.Join(EventTypes, ", "))
+			}
+		}
+		if hook.URL != "" {
+			u, err := url.Parse(hook.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("event hook %q: invalid url %q", name, hook.URL)
+			}
+		}
+
+		if hook.Timeout <= 0 {
+			hook.Timeout = Duration(DefaultEventHookTimeout)
+		}
+		if hook.RetryDelay <= 0 {
+			hook.RetryDelay = Duration(DefaultEventHookRetryDelay)
+		}
+		p.config.Hooks.Events = append(p.config.Hooks.Events, hook)
+	}
+	return nil
+}
+
+// parseRootUnavailable applies the default answer while public_dir can't be
+// read and rejects values the static handler wouldn't recognize
+func (p *ConfigParser) parseRootUnavailable() error {
+	switch mode := p.yamlConfig.Server.Static.RootUnavailable; mode {
+	case "":
+		p.config.Server.Static.RootUnavailable = StaticRootUnavailable
+	case StaticRootUnavailable, StaticRootNotFound:
+		p.config.Server.Static.RootUnavailable = mode
+	default:
+		return fmt.Errorf("server.static.root_unavailable %q is not supported (use %s or %s)",
+			mode, StaticRootUnavailable, StaticRootNotFound)
+	}
+	return nil
+}
+
+// parseStaticSource applies static source defaults and checks required settings
+func (p *ConfigParser) parseStaticSource() error {
+	source := p.yamlConfig.Server.Static.Source
+	switch source.Type {
+	case "", StaticSourceDir:
+		source.Type = StaticSourceDir
+	case StaticSourceArchive:
+		if source.Archive == "" {
+			return fmt.Errorf("server.static.source: archive is required for type archive")
+		}
+	case StaticSourceS3:
+		if source.S3.Endpoint == "" || source.S3.Bucket == "" {
+			return fmt.Errorf("server.static.source: s3.endpoint and s3.bucket are required for type s3")
+		}
+		if source.S3.Region == "" {
+			source.S3.Region = DefaultStaticS3Region
+		}
+		source.S3.TTL = Duration(source.S3.TTL.OrDefault(DefaultStaticS3TTL))
+	default:
+		return fmt.Errorf("server.static.source: unsupported type %q (use dir, archive, or s3)", source.Type)
+	}
+	if source.CacheSize <= 0 {
+		source.CacheSize = DefaultStaticCacheSize
+	}
+	p.config.Server.Static.Source = source
+	return nil
+}
+
+// parseFingerprints compiles server.static.fingerprint_patterns, defaulting
+// to names with a hex content hash before the extension
+func (p *ConfigParser) parseFingerprints() error {
+	yamlStatic := &p.yamlConfig.Server.Static
+	static := &p.config.Server.Static
+
+	static.FingerprintPatterns = []string{DefaultFingerprintPattern}
+	if yamlStatic.FingerprintPatterns != nil {
+		static.FingerprintPatterns = *yamlStatic.FingerprintPatterns
+	}
+	static.Fingerprints = nil
+	for _, pattern := range static.FingerprintPatterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("server.static.fingerprint_patterns: %q: %w", pattern, err)
+		}
+		static.Fingerprints = append(static.Fingerprints, regex)
+	}
+
+	static.FingerprintMaxAge = Duration(yamlStatic.FingerprintMaxAge.OrDefault(DefaultFingerprintMaxAge))
+	return nil
+}
+
+// parseMIMETypes normalizes server.static.mime_types to lowercase extensions
+// with their dot, and gives text types without a charset UTF-8
+func (p *ConfigParser) parseMIMETypes() error {
+	static := &p.config.Server.Static
+	static.NoSniff = p.yamlConfig.Server.Static.NoSniff
+	static.MIMETypes = nil
+	for ext, contentType := range p.yamlConfig.Server.Static.MIMETypes {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("server.static.mime_types: %q: invalid type %q: %w", ext, contentType, err)
+		}
+		if strings.HasPrefix(mediaType, "text/") && params["charset"] == "" {
+			contentType += "; charset=utf-8"
+		}
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if static.MIMETypes == nil {
+			static.MIMETypes = make(map[string]string)
+		}
+		static.MIMETypes[ext] = contentType
+	}
+	return nil
+}
+
+// parseShutdownConfig applies shutdown defaults and validates immediate_signal
+func (p *ConfigParser) parseShutdownConfig() error {
+	shutdown := p.yamlConfig.Server.Shutdown
+	shutdown.Timeout = Duration(shutdown.Timeout.OrDefault(DefaultShutdownTimeout))
+	switch strings.ToUpper(shutdown.ImmediateSignal) {
+	case "", strings.ToUpper(ImmediateSignalSecond):
+		shutdown.ImmediateSignal = ImmediateSignalSecond
+	case strings.ToUpper(ImmediateSignalNone):
+		shutdown.ImmediateSignal = ImmediateSignalNone
+	case "SIGINT", "INT":
+		shutdown.ImmediateSignal = "SIGINT"
+	case "SIGTERM", "TERM":
+		shutdown.ImmediateSignal = "SIGTERM"
+	default:
+		return fmt.Errorf("server.shutdown.immediate_signal %q is not supported (use second, SIGINT, SIGTERM, or none)", shutdown.ImmediateSignal)
+	}
+	p.config.Server.Shutdown = shutdown
+	return nil
+}
+
+// parseLoadShedding applies load shedding defaults and rejects thresholds
+// that can't be compared against resource readings
+func (p *ConfigParser) parseLoadShedding() error {
+	shedding := p.yamlConfig.Server.LoadShedding
+	if shedding.MaxRSS < 0 || shedding.MinAvailableMemory < 0 || shedding.MaxOpenFDs < 0 || shedding.MaxGoroutines < 0 {
+		return fmt.Errorf("server.load_shedding: thresholds must not be negative")
+	}
+	shedding.Interval = Duration(shedding.Interval.OrDefault(DefaultLoadSheddingInterval))
+	if shedding.Recovery == 0 {
+		shedding.Recovery = DefaultLoadSheddingRecovery
+	}
+	if shedding.Recovery < 0 || shedding.Recovery > 1 {
+		return fmt.Errorf("server.load_shedding.recovery must be between 0 and 1, got %v", shedding.Recovery)
+	}
+	switch shedding.Shed = strings.ToLower(shedding.Shed); shedding.Shed {
+	case "":
+		shedding.Shed = ShedUnauthenticated
+	case ShedUnauthenticated, ShedAll:
+	case ShedPaths:
+		if len(shedding.Paths) == 0 {
+			return fmt.Errorf("server.load_shedding: shed %q needs paths", ShedPaths)
+		}
+	default:
+		return fmt.Errorf("server.load_shedding.shed %q is not supported (use unauthenticated, paths, or all)", shedding.Shed)
+	}
+	for _, path := range shedding.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("server.load_shedding.paths: %q must start with /", path)
+		}
+	}
+	p.config.Server.LoadShedding = shedding
+	return nil
+}
+
+// parseCanonical applies the default redirect kind and rejects a canonical
+// host that isn't a bare host name
+func (p *ConfigParser) parseCanonical() error {
+	canonical := p.yamlConfig.Server.Canonical
+	canonical.Host = strings.ToLower(strings.TrimSpace(canonical.Host))
+	if strings.ContainsAny(canonical.Host, "/?#@ ") {
+		return fmt.Errorf("server.canonical.host must be a host name such as \"example.com\", got %q", canonical.Host)
+	}
+	switch canonical.Redirect {
+	case "":
+		canonical.Redirect = CanonicalRedirectPermanent
+	case CanonicalRedirectPermanent, CanonicalRedirectTemporary:
+	default:
+		return fmt.Errorf("server.canonical.redirect %q is not supported (use %s or %s)",
+			canonical.Redirect, CanonicalRedirectPermanent, CanonicalRedirectTemporary)
+	}
+	for i, host := range canonical.ExcludeHosts {
+		canonical.ExcludeHosts[i] = strings.ToLower(strings.TrimSpace(host))
+	}
+	p.config.Server.Canonical = canonical
+	return nil
+}
+
+// parseForwardedPrecedence applies the default header precedence and
+// rejects values the server wouldn't recognize
+func (p *ConfigParser) parseForwardedPrecedence() error {
+	precedence := strings.ToLower(p.yamlConfig.Server.ForwardedPrecedence)
+	switch precedence {
+	case "":
+		precedence = ForwardedPrecedenceForwarded
+	case ForwardedPrecedenceForwarded, ForwardedPrecedenceXForwarded:
+	default:
+		return fmt.Errorf("server.forwarded_precedence %q is not supported (use %s or %s)",
+			p.yamlConfig.Server.ForwardedPrecedence, ForwardedPrecedenceForwarded, ForwardedPrecedenceXForwarded)
+	}
+	p.config.Server.ForwardedPrecedence = precedence
+	return nil
+}
+
+// parseAbsoluteURI applies the defaults for absolute-form request targets
+// and rejects values the server wouldn't recognize
+func (p *ConfigParser) parseAbsoluteURI() error {
+	server := &p.config.Server
+	server.AbsoluteURI = p.yamlConfig.Server.AbsoluteURI
+	switch server.AbsoluteURI {
+	case "":
+		server.AbsoluteURI = AbsoluteURINormalize
+	case AbsoluteURINormalize, AbsoluteURIReject:
+	default:
+		return fmt.Errorf("server.absolute_uri %q is not supported (use %s or %s)",
+			server.AbsoluteURI, AbsoluteURINormalize, AbsoluteURIReject)
+	}
+	server.AbsoluteURIHostMismatch = p.yamlConfig.Server.AbsoluteURIHostMismatch
+	switch server.AbsoluteURIHostMismatch {
+	case "":
+		server.AbsoluteURIHostMismatch = HostMismatchReject
+	case HostMismatchReject, HostMismatchUseHostname, HostMismatchUseURI:
+	default:
+		return fmt.Errorf("server.absolute_uri_host_mismatch %q is not supported (use %s, %s, or %s)",
+			server.AbsoluteURIHostMismatch, HostMismatchReject, HostMismatchUseHostname, HostMismatchUseURI)
+	}
+	return nil
+}
+
+// parseTimeouts applies the connection timeout defaults
+func (p *ConfigParser) parseTimeouts() error {
+	timeouts := p.yamlConfig.Server.Timeouts
+	if timeouts.ReadHeader < 0 || timeouts.Read < 0 || timeouts.Write < 0 || timeouts.Idle < 0 ||
+		timeouts.TCPKeepAlive < 0 || timeouts.MaxKeepaliveRequests < 0 {
+		return fmt.Errorf("server.timeouts: values must not be negative")
+	}
+	timeouts.ReadHeader = Duration(timeouts.ReadHeader.OrDefault(DefaultReadHeaderTimeout))
+	timeouts.Idle = Duration(timeouts.Idle.OrDefault(DefaultConnIdleTimeout))
+	timeouts.TCPKeepAlive = Duration(timeouts.TCPKeepAlive.OrDefault(DefaultTCPKeepAlive))
+	p.config.Server.Timeouts = timeouts
+	return nil
+}
+
+// parseProxyProtocol parses the sources trusted to send PROXY protocol
+// headers. A bare address is trusted on its own.
+func (p *ConfigParser) parseProxyProtocol() error {
+	server := &p.config.Server
+	server.ProxyProtocol = p.yamlConfig.Server.ProxyProtocol
+	server.ProxyProtocolTrusted = p.yamlConfig.Server.ProxyProtocolTrusted
+	for _, source := range server.ProxyProtocolTrusted {
+		if !strings.Contains(source, "/") {
+			ip := net.ParseIP(source)
+			if ip == nil {
+				return fmt.Errorf("server.proxy_protocol_trusted: %q is not an address or CIDR", source)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			server.ProxyProtocolNets = append(server.ProxyProtocolNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(source)
+		if err != nil {
+			return fmt.Errorf("server.proxy_protocol_trusted: %q is not an address or CIDR", source)
+		}
+		server.ProxyProtocolNets = append(server.ProxyProtocolNets, network)
+	}
+	return nil
+}
+
 // parseRoutesConfig parses routes configuration
-func (p *ConfigParser) parseRoutesConfig() {
+func (p *ConfigParser) parseRoutesConfig() error {
 	// Copy routes configuration
 	p.config.Routes.Redirects = p.yamlConfig.Routes.Redirects
 	p.config.Routes.Rewrites = p.yamlConfig.Routes.Rewrites
 	p.config.Routes.ReverseProxies = p.yamlConfig.Routes.ReverseProxies
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if err := parseProxyDNS(route); err != nil {
+			return err
+		}
+		if route.CookieSameSite == "" {
+			continue
+		}
+		sameSite, ok := cookieSameSiteValues[strings.ToLower(route.CookieSameSite)]
+		if !ok {
+			return fmt.Errorf("reverse proxy %q: cookie_samesite must be Lax, Strict, or None, got %q", route.Name, route.CookieSameSite)
+		}
+		route.CookieSameSite = sameSite
+	}
+	p.config.Routes.Fly.Replay = p.yamlConfig.Routes.Fly.Replay
+	p.config.Routes.Fly.MaxReplayHops = DefaultMaxReplayHops
+	if hops := p.yamlConfig.Routes.Fly.MaxReplayHops; hops != nil {
+		if *hops <= 0 {
+			return fmt.Errorf("routes.fly.max_replay_hops must be at least 1, got %d", *hops)
+		}
+		p.config.Routes.Fly.MaxReplayHops = *hops
+	}
 
 	// Convert routes to rewrite rules if needed
 	for _, redirect := range p.yamlConfig.Routes.Redirects {
+		conditions, err := compileRewriteConditions(redirect.Conditions)
+		if err != nil {
+			return fmt.Errorf("redirect %q: %w", redirect.From, err)
+		}
 		if pattern, err := regexp.Compile(redirect.From); err == nil {
 			p.config.Server.RewriteRules = append(p.config.Server.RewriteRules, RewriteRule{
 				Pattern:     pattern,
 				Replacement: redirect.To,
 				Flag:        "redirect",
+				Conditions:  conditions,
 			})
 		}
 	}
 
 	for _, rewrite := range p.yamlConfig.Routes.Rewrites {
+		conditions, err := compileRewriteConditions(rewrite.Conditions)
+		if err != nil {
+			return fmt.Errorf("rewrite %q: %w", rewrite.From, err)
+		}
 		if pattern, err := regexp.Compile(rewrite.From); err == nil {
 			p.config.Server.RewriteRules = append(p.config.Server.RewriteRules, RewriteRule{
 				Pattern:     pattern,
 				Replacement: rewrite.To,
 				Flag:        "last",
+				Conditions:  conditions,
 			})
 		}
 	}
@@ -317,13 +1349,77 @@ func (p *ConfigParser) parseRoutesConfig() {
 				status = 307
 			}
 
-			p.config.Server.RewriteRules = append(p.config.Server.RewriteRules, RewriteRule{
+			rule := RewriteRule{
 				Pattern:     pattern,
 				Replacement: flyReplay.Path, // Keep original path for fly-replay
 				Flag:        fmt.Sprintf("fly-replay:%s:%d", target, status),
-			})
+			}
+			if flyReplay.VerifyTarget {
+				port := flyReplay.VerifyPort
+				if port == 0 {
+					port = listenPort(p.config.Server.Listen)
+				}
+				rule.Verify = &ReplayVerification{
+					TTL:            flyReplay.VerifyTTL.OrDefault(DefaultFlyReplayVerifyTTL),
+					Port:           port,
+					FallbackRegion: flyReplay.FallbackRegion,
+				}
+			}
+			p.config.Server.RewriteRules = append(p.config.Server.RewriteRules, rule)
 		}
 	}
+	return nil
+}
+
+// compileRewriteConditions validates conditions and compiles their patterns
+func compileRewriteConditions(conditions []RewriteCondition) ([]RewriteCondition, error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	compiled := make([]RewriteCondition, len(conditions))
+	for i, condition := range conditions {
+		subjects := 0
+		for _, name := range []string{condition.Host, condition.Header, condition.Cookie, condition.Query, condition.Method} {
+			if name != "" {
+				subjects++
+			}
+		}
+		if subjects != 1 {
+			return nil, fmt.Errorf("condition %d: set exactly one of host, header, cookie, query, or method", i+1)
+		}
+		valued := condition.Equals != "" || condition.Matches != "" || condition.Present != nil
+		if (condition.Host != "" || condition.Method != "") && valued {
+			return nil, fmt.Errorf("condition %d: equals, matches, and present do not apply to host or method", i+1)
+		}
+		if condition.Equals != "" && condition.Matches != "" {
+			return nil, fmt.Errorf("condition %d: equals and matches are mutually exclusive", i+1)
+		}
+		if condition.Present != nil && !*condition.Present && (condition.Equals != "" || condition.Matches != "") {
+			return nil, fmt.Errorf("condition %d: present: false cannot be combined with equals or matches", i+1)
+		}
+		if condition.Matches != "" {
+			pattern, err := regexp.Compile(condition.Matches)
+			if err != nil {
+				return nil, fmt.Errorf("condition %d: invalid matches pattern %q: %w", i+1, condition.Matches, err)
+			}
+			condition.Regexp = pattern
+		}
+		compiled[i] = condition
+	}
+	return compiled, nil
+}
+
+// listenPort extracts the port from a listen address such as "3000",
+// ":3000", or "127.0.0.1:3000"
+func listenPort(listen string) int {
+	if _, port, err := net.SplitHostPort(listen); err == nil {
+		listen = port
+	}
+	port, err := strconv.Atoi(listen)
+	if err != nil {
+		return DefaultListenPort
+	}
+	return port
 }
 
 // addTrailingSlashRedirects adds automatic redirects from non-trailing-slash to trailing-slash versions
@@ -355,5 +1451,18 @@ func (p *ConfigParser) addTrailingSlashRedirects() {
 			Replacement: tenant.Path, // The normalized version with trailing slash
 			Flag:        "redirect",
 		})
+
+		// Aliases get the same treatment; redirecting aliases go straight to the tenant path
+		for _, alias := range tenant.Aliases {
+			replacement := alias
+			if tenant.AliasRedirect {
+				replacement = tenant.Path
+			}
+			p.config.Server.RewriteRules = append(p.config.Server.RewriteRules, RewriteRule{
+				Pattern:     regexp.MustCompile("^" + regexp.QuoteMeta(strings.TrimSuffix(alias, "/")) + "$"),
+				Replacement: replacement,
+				Flag:        "redirect",
+			})
+		}
 	}
 }
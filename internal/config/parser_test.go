@@ -1,8 +1,10 @@
 package config
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfigParser_ParseServerConfig(t *testing.T) {
@@ -173,16 +175,10 @@ applications:
 func TestConfigParser_ParseRoutesConfig(t *testing.T) {
 	yamlConfig := func() YAMLConfig {
 		cfg := YAMLConfig{}
-		cfg.Routes.Redirects = []struct {
-			From string `yaml:"from"`
-			To   string `yaml:"to"`
-		}{
+		cfg.Routes.Redirects = []RouteRule{
 			{From: "^/old", To: "/new"},
 		}
-		cfg.Routes.Rewrites = []struct {
-			From string `yaml:"from"`
-			To   string `yaml:"to"`
-		}{
+		cfg.Routes.Rewrites = []RouteRule{
 			{From: "^/api/(.*)", To: "/v1/api/$1"},
 		}
 		return cfg
@@ -224,6 +220,164 @@ func TestConfigParser_ParseRoutesConfig(t *testing.T) {
 	}
 }
 
+func TestConfigParser_ParseRewriteConditions(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	yamlConfig.Routes.Rewrites = []RouteRule{
+		{
+			From: "^/showcase/(.*)",
+			To:   "/prerendered/$1",
+			Conditions: []RewriteCondition{
+				{Header: "User-Agent", Matches: "(?i)bot"},
+				{Method: "GET"},
+			},
+		},
+	}
+
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(config.Server.RewriteRules) != 1 {
+		t.Fatalf("Expected 1 rewrite rule, got %d", len(config.Server.RewriteRules))
+	}
+	conditions := config.Server.RewriteRules[0].Conditions
+	if len(conditions) != 2 || conditions[0].Regexp == nil || !conditions[0].Regexp.MatchString("Googlebot") {
+		t.Errorf("Conditions not compiled: %+v", conditions)
+	}
+
+	absent := false
+	invalid := map[string]RewriteCondition{
+		"no subject":       {Equals: "x"},
+		"two subjects":     {Header: "X-Test", Cookie: "test"},
+		"bad regex":        {Header: "User-Agent", Matches: "("},
+		"host with value":  {Host: "example.com", Equals: "example.com"},
+		"equals and match": {Query: "q", Equals: "a", Matches: "a"},
+		"absent and value": {Cookie: "session", Present: &absent, Equals: "x"},
+	}
+	for name, condition := range invalid {
+		t.Run(name, func(t *testing.T) {
+			yamlConfig := YAMLConfig{}
+			yamlConfig.Routes.Redirects = []RouteRule{{From: "^/old", To: "/new", Conditions: []RewriteCondition{condition}}}
+			if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+				t.Error("Parse() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestConfigParser_ParseShutdownConfig(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Server.Shutdown.ImmediateSignal != ImmediateSignalSecond || time.Duration(config.Server.Shutdown.Timeout) != DefaultShutdownTimeout {
+		t.Errorf("Shutdown defaults = %+v", config.Server.Shutdown)
+	}
+
+	yamlConfig.Server.Shutdown.ImmediateSignal = "int"
+	config, err = NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Server.Shutdown.ImmediateSignal != "SIGINT" {
+		t.Errorf("immediate_signal int = %q, want SIGINT", config.Server.Shutdown.ImmediateSignal)
+	}
+
+	yamlConfig.Server.Shutdown.ImmediateSignal = "SIGKILL"
+	if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+		t.Error("Parse() accepted immediate_signal SIGKILL")
+	}
+}
+
+func TestConfigParser_ParseStaticSource(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Server.Static.Source.Type != StaticSourceDir {
+		t.Errorf("Default source type = %q, want %q", config.Server.Static.Source.Type, StaticSourceDir)
+	}
+
+	yamlConfig.Server.Static.Source = StaticSourceConfig{
+		Type: StaticSourceS3,
+		S3:   S3SourceConfig{Endpoint: "https://storage.example.com", Bucket: "assets"},
+	}
+	config, err = NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	source := config.Server.Static.Source
+	if source.S3.Region != DefaultStaticS3Region || time.Duration(source.S3.TTL) != DefaultStaticS3TTL || source.CacheSize != DefaultStaticCacheSize {
+		t.Errorf("S3 source defaults = %+v", source)
+	}
+
+	for _, invalid := range []StaticSourceConfig{
+		{Type: StaticSourceArchive},
+		{Type: StaticSourceS3, S3: S3SourceConfig{Bucket: "assets"}},
+		{Type: "ftp"},
+	} {
+		yamlConfig.Server.Static.Source = invalid
+		if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+			t.Errorf("Parse() accepted static source %+v", invalid)
+		}
+	}
+}
+
+func TestConfigParser_ParseRequestHeaders(t *testing.T) {
+	yamlContent := `
+server:
+  max_header_bytes: 65536
+  request_headers:
+    max_forwarded_bytes: 16384
+    strip: [x-internal-secret, X-Api-Token]
+routes:
+  reverse_proxies:
+    - name: api
+      prefix: /api/
+      target: http://localhost:4000
+      max_header_bytes: 4096
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      max_header_bytes: 32768
+`
+	config, err := ParseYAML([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if config.Server.MaxHeaderBytes != 65536 || config.Server.RequestHeaders.MaxForwardedBytes != 16384 {
+		t.Errorf("Server header limits = %d, %d", config.Server.MaxHeaderBytes, config.Server.RequestHeaders.MaxForwardedBytes)
+	}
+	if strip := config.Server.RequestHeaders.Strip; len(strip) != 2 || strip[0] != "X-Internal-Secret" || strip[1] != "X-Api-Token" {
+		t.Errorf("Strip = %q, want canonical header names", strip)
+	}
+	if config.Routes.ReverseProxies[0].MaxHeaderBytes != 4096 {
+		t.Errorf("Route max_header_bytes = %d, want 4096", config.Routes.ReverseProxies[0].MaxHeaderBytes)
+	}
+	if config.Applications.Tenants[0].MaxHeaderBytes != 32768 {
+		t.Errorf("Tenant max_header_bytes = %d, want 32768", config.Applications.Tenants[0].MaxHeaderBytes)
+	}
+}
+
+func TestConfigParser_ParseCookieSameSite(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	yamlConfig.Routes.ReverseProxies = []ProxyRoute{{Name: "web", Prefix: "/web/", Target: "http://localhost:8080", CookieSameSite: "none"}}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := config.Routes.ReverseProxies[0].CookieSameSite; got != "None" {
+		t.Errorf("cookie_samesite = %q, want None", got)
+	}
+
+	yamlConfig.Routes.ReverseProxies[0].CookieSameSite = "relaxed"
+	if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+		t.Error("Parse() accepted cookie_samesite: relaxed")
+	}
+}
+
 func TestConfigParser_ParseManagedProcesses(t *testing.T) {
 	yamlConfig := YAMLConfig{
 		ManagedProcesses: []ManagedProcessConfig{
@@ -280,6 +434,175 @@ func TestConfigParser_ParseLoggingConfig(t *testing.T) {
 	}
 }
 
+func TestConfigParser_ParseCaptureConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture CaptureConfig
+		wantErr bool
+	}{
+		{
+			name:    "disabled capture is ignored",
+			capture: CaptureConfig{Path: "["},
+		},
+		{
+			name:    "redaction required",
+			capture: CaptureConfig{Enabled: true, Path: "^/api/", File: "/tmp/capture.log"},
+			wantErr: true,
+		},
+		{
+			name:    "allow_unredacted overrides guard",
+			capture: CaptureConfig{Enabled: true, Path: "^/api/", File: "/tmp/capture.log", AllowUnredacted: true},
+		},
+		{
+			name:    "invalid redact pattern",
+			capture: CaptureConfig{Enabled: true, Path: "^/api/", File: "/tmp/capture.log", Redact: []string{"("}},
+			wantErr: true,
+		},
+		{
+			name:    "file required",
+			capture: CaptureConfig{Enabled: true, Path: "^/api/", Redact: []string{"secret"}},
+			wantErr: true,
+		},
+		{
+			name:    "valid capture",
+			capture: CaptureConfig{Enabled: true, Path: "^/api/", File: "/tmp/capture.log", Redact: []string{"secret"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlConfig := YAMLConfig{Logging: LogConfig{Capture: tt.capture}}
+			config, err := NewConfigParser(&yamlConfig).Parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil || !tt.capture.Enabled {
+				return
+			}
+			capture := config.Logging.Capture
+			if capture.PathPattern == nil {
+				t.Error("expected compiled path pattern")
+			}
+			if len(capture.RedactPatterns) != len(tt.capture.Redact) {
+				t.Errorf("RedactPatterns = %d, want %d", len(capture.RedactPatterns), len(tt.capture.Redact))
+			}
+			if capture.MaxBytes != DefaultCaptureMaxBytes {
+				t.Errorf("MaxBytes = %d, want default %d", capture.MaxBytes, DefaultCaptureMaxBytes)
+			}
+		})
+	}
+}
+
+func TestConfigParser_ParseLogLimits(t *testing.T) {
+	yamlConfig := YAMLConfig{Logging: LogConfig{Limits: LogLimitsConfig{RateLimit: 2.5, MaxLineLength: 4096}}}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Logging.Limits.Burst != 3 {
+		t.Errorf("Burst = %d, want default of 3 (ceil of rate_limit)", config.Logging.Limits.Burst)
+	}
+
+	yamlConfig = YAMLConfig{Logging: LogConfig{Limits: LogLimitsConfig{MaxBytesPerDay: -1}}}
+	if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
+
+func TestConfigParser_ParseMultilineConfig(t *testing.T) {
+	yamlConfig := YAMLConfig{Logging: LogConfig{Multiline: MultilineConfig{Start: `^[A-Z], \[`, MaxLines: 50}}}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	multiline := config.Logging.Multiline
+	if multiline.StartPattern == nil || !multiline.StartPattern.MatchString("E, [2025-06-01T02:00:00] ERROR") {
+		t.Error("Start pattern not compiled")
+	}
+	if multiline.MaxLines != 50 || multiline.MaxBytes != DefaultMultilineMaxBytes || multiline.Timeout != Duration(DefaultMultilineTimeout) {
+		t.Errorf("Multiline defaults = %+v", multiline)
+	}
+
+	yamlConfig = YAMLConfig{Logging: LogConfig{Multiline: MultilineConfig{Start: "(unclosed"}}}
+	if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+		t.Error("expected error for invalid start pattern")
+	}
+}
+
+func TestConfigParser_ParseLogDestinations(t *testing.T) {
+	// The flat fields keep their meaning
+	yamlConfig := YAMLConfig{Logging: LogConfig{Format: "json"}}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	app, access := config.Logging.App, config.Logging.Access
+	if app.Destination != LogDestinationStdout || app.Format != "json" || app.Level != "" {
+		t.Errorf("App log defaults = %+v", app)
+	}
+	if len(access.Destinations) != 1 || access.Destinations[0] != LogDestinationStdout || access.Format != "json" || access.SampleRate != nil {
+		t.Errorf("Access log defaults = %+v", access)
+	}
+
+	yamlConfig = YAMLConfig{Logging: LogConfig{
+		Format: "json",
+		App:    AppLogConfig{Destination: LogDestinationStderr, Format: "text", Level: "WARNING"},
+		Access: AccessLogConfig{Destination: LogDestinationStdout, Destinations: []string{"/var/log/navigator/access.log"}, Format: "text", SampleRate: float64Ptr(0.1)},
+	}}
+	config, err = NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	app, access = config.Logging.App, config.Logging.Access
+	if app.Format != "text" || app.Level != "warn" {
+		t.Errorf("App log = %+v, want its own format and level", app)
+	}
+	if strings.Join(access.Destinations, ",") != "stdout,/var/log/navigator/access.log" {
+		t.Errorf("Access destinations = %q, want both", access.Destinations)
+	}
+
+	// Unset formats are chosen when the log is opened; pretty carries over
+	// to access entries
+	for format, want := range map[string][2]string{
+		"":              {"", ""},
+		LogFormatPretty: {LogFormatPretty, LogFormatPretty},
+		LogFormatText:   {LogFormatText, LogFormatJSON},
+	} {
+		yamlConfig = YAMLConfig{Logging: LogConfig{Format: format}}
+		config, err = NewConfigParser(&yamlConfig).Parse()
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if got := [2]string{config.Logging.App.Format, config.Logging.Access.Format}; got != want {
+			t.Errorf("logging.format %q: app and access formats = %q, want %q", format, got, want)
+		}
+	}
+
+	// A sample rate of 0 logs errors only, rather than falling back to 1
+	config, err = ParseYAML([]byte("logging:\n  access:\n    sample_rate: 0\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if rate := config.Logging.Access.SampleRate; rate == nil || *rate != 0 {
+		t.Errorf("sample_rate 0 = %v, want 0", rate)
+	}
+
+	for _, logging := range []LogConfig{
+		{Format: "colorful"},
+		{App: AppLogConfig{Format: "xml"}},
+		{App: AppLogConfig{Level: "trace"}},
+		{Access: AccessLogConfig{Format: "combined"}},
+		{Access: AccessLogConfig{SampleRate: float64Ptr(1.5)}},
+		{Access: AccessLogConfig{SampleRate: float64Ptr(-0.5)}},
+		{Access: AccessLogConfig{Destinations: []string{""}}},
+	} {
+		yamlConfig = YAMLConfig{Logging: logging}
+		if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+			t.Errorf("Expected an error for %+v", logging)
+		}
+	}
+}
+
 func TestConfigParser_ParseHooksConfig(t *testing.T) {
 	yamlConfig := func() YAMLConfig {
 		cfg := YAMLConfig{}
@@ -354,12 +677,7 @@ func TestConfigParser_ParseAuthConfig(t *testing.T) {
 func TestConfigParser_ParseFlyReplayRoutes(t *testing.T) {
 	yamlConfig := func() YAMLConfig {
 		cfg := YAMLConfig{}
-		cfg.Routes.Fly.Replay = []struct {
-			Path   string `yaml:"path"`
-			App    string `yaml:"app"`
-			Region string `yaml:"region"`
-			Status int    `yaml:"status"`
-		}{
+		cfg.Routes.Fly.Replay = []FlyReplayRoute{
 			{
 				Path:   "^/admin",
 				Region: "lax",
@@ -564,6 +882,10 @@ applications:
 }
 
 // Helper functions for testing pointer values
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
@@ -805,3 +1127,1152 @@ applications:
 		t.Error("Should not create redirect for root_path when it is '/'")
 	}
 }
+
+func TestConfigParser_IdleActivityDefaults(t *testing.T) {
+	config, err := NewConfigParser(&YAMLConfig{}).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !config.Server.Idle.CountStaticRequests {
+		t.Error("count_static_requests should default to true")
+	}
+	if config.Server.Idle.CountHealthChecks {
+		t.Error("count_health_checks should default to false")
+	}
+
+	disabled := false
+	yamlConfig := YAMLConfig{}
+	yamlConfig.Server.Idle.CountStaticRequests = &disabled
+	config, err = NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Server.Idle.CountStaticRequests {
+		t.Error("count_static_requests: false should be honored")
+	}
+}
+
+func TestConfigParser_CoalesceDefaults(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	yamlConfig.Applications.Coalesce.Enabled = true
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	coalesce := config.Applications.Coalesce
+	if !coalesce.Enabled || coalesce.AllowCredentials {
+		t.Errorf("unexpected coalesce flags: %+v", coalesce)
+	}
+	if coalesce.MaxResponseSize != DefaultCoalesceMaxResponseSize {
+		t.Errorf("MaxResponseSize = %d, want %d", coalesce.MaxResponseSize, DefaultCoalesceMaxResponseSize)
+	}
+	if len(coalesce.VaryHeaders) != len(DefaultCoalesceVaryHeaders) {
+		t.Errorf("VaryHeaders = %v, want %v", coalesce.VaryHeaders, DefaultCoalesceVaryHeaders)
+	}
+}
+
+func TestConfigParser_ProcessGroups(t *testing.T) {
+	yamlConfig := YAMLConfig{}
+	yamlConfig.ProcessGroups = []ManagedProcessGroup{
+		{Name: "logging", ReloadSignal: "hup"},
+		{Name: "workers"},
+	}
+	config, err := NewConfigParser(&yamlConfig).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(config.ProcessGroups) != 2 || config.ProcessGroups[0].ReloadSignal != "SIGHUP" || config.ProcessGroups[1].ReloadSignal != "" {
+		t.Errorf("ProcessGroups = %+v, want normalized SIGHUP and no signal", config.ProcessGroups)
+	}
+
+	for _, groups := range [][]ManagedProcessGroup{
+		{{Name: "logging", ReloadSignal: "SIGWINCH"}},
+		{{Name: ""}},
+		{{Name: "a"}, {Name: "a"}},
+	} {
+		yamlConfig := YAMLConfig{ProcessGroups: groups}
+		if _, err := NewConfigParser(&yamlConfig).Parse(); err == nil {
+			t.Errorf("expected error for groups %+v", groups)
+		}
+	}
+}
+
+func TestConfigParser_FlyReplayVerification(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  listen: 3000
+routes:
+  fly:
+    replay:
+      - path: "^/verified/"
+        region: iad
+        verify_target: true
+        fallback_region: ord
+      - path: "^/custom/"
+        region: lax
+        verify_target: true
+        verify_ttl: 2m
+        verify_port: 8080
+      - path: "^/plain/"
+        region: sjc
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	rules := config.Server.RewriteRules
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rewrite rules, got %d", len(rules))
+	}
+	if v := rules[0].Verify; v == nil || v.TTL != DefaultFlyReplayVerifyTTL || v.Port != 3000 || v.FallbackRegion != "ord" {
+		t.Errorf("rules[0].Verify = %+v, want defaults with listen port and fallback", v)
+	}
+	if v := rules[1].Verify; v == nil || v.TTL != 2*time.Minute || v.Port != 8080 {
+		t.Errorf("rules[1].Verify = %+v, want 2m TTL on port 8080", v)
+	}
+	if rules[2].Verify != nil {
+		t.Error("rules without verify_target should not be verified")
+	}
+}
+
+func TestConfigParser_ResponseCache(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  listen: 3000
+  response_cache:
+    purge_path: /_navigator/cache
+routes:
+  reverse_proxies:
+    - name: feeds
+      prefix: /feeds/
+      target: http://localhost:9000
+      cache:
+        ttl: 5m
+        query_params: [year]
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      cache:
+        paths: ['\.ics$']
+        max_object_size: 2048
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	if config.Server.ResponseCache.MaxMemory != DefaultResponseCacheMaxMemory {
+		t.Errorf("MaxMemory = %d, want default", config.Server.ResponseCache.MaxMemory)
+	}
+	if config.Server.ResponseCache.PurgePath != "/_navigator/cache" {
+		t.Errorf("PurgePath = %q", config.Server.ResponseCache.PurgePath)
+	}
+
+	route := config.Routes.ReverseProxies[0].Cache
+	if route == nil || route.TTL.Std() != 5*time.Minute || route.MaxObjectSize != DefaultResponseCacheMaxObjectSize {
+		t.Errorf("route cache = %+v, want 5m TTL and default object size", route)
+	}
+	if len(route.VaryHeaders) != len(DefaultResponseCacheVaryHeaders) {
+		t.Errorf("VaryHeaders = %v, want defaults", route.VaryHeaders)
+	}
+
+	tenant := config.Applications.Tenants[0].Cache
+	if tenant == nil || tenant.TTL.Std() != DefaultResponseCacheTTL || tenant.MaxObjectSize != 2048 {
+		t.Fatalf("tenant cache = %+v, want default TTL and 2048 byte objects", tenant)
+	}
+	if len(tenant.PathPatterns) != 1 || !tenant.PathPatterns[0].MatchString("/showcase/2025/boston/calendar.ics") {
+		t.Errorf("PathPatterns = %v", tenant.PathPatterns)
+	}
+
+	_, err = ParseYAML([]byte(`
+routes:
+  reverse_proxies:
+    - name: feeds
+      prefix: /feeds/
+      target: http://localhost:9000
+      cache:
+        paths: ['\.ics$']
+`))
+	if err == nil {
+		t.Error("expected cache.paths on a reverse proxy to be rejected")
+	}
+}
+
+func TestParseAuthScopes(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+auth:
+  enabled: true
+  htpasswd: /etc/navigator/htpasswd
+  scopes:
+    - paths: [/showcase/admin/]
+      htpasswd: /etc/navigator/admin.htpasswd
+      realm: Admin
+    - paths: [/showcase/]
+      htpasswd: /etc/navigator/showcase.htpasswd
+      public_paths: ['*.css']
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if len(config.Auth.Scopes) != 2 || config.Auth.Scopes[0].Realm != "Admin" {
+		t.Errorf("Scopes = %+v", config.Auth.Scopes)
+	}
+
+	tests := []struct {
+		name   string
+		scopes string
+	}{
+		{"identical paths", `
+    - paths: [/showcase/admin/]
+      htpasswd: /a
+    - paths: [/showcase/admin/]
+      htpasswd: /b`},
+		{"overlapping globs", `
+    - paths: [/showcase/*/admin/]
+      htpasswd: /a
+    - paths: [/showcase/admin/*/]
+      htpasswd: /b`},
+		{"missing htpasswd", `
+    - paths: [/showcase/admin/]`},
+		{"missing paths", `
+    - htpasswd: /a`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte("auth:\n  enabled: true\n  scopes:" + tt.scopes + "\n"))
+			if err == nil {
+				t.Error("expected auth scopes to be rejected")
+			}
+		})
+	}
+
+	// Equal specificity is fine when the paths cannot match the same request
+	_, err = ParseYAML([]byte(`
+auth:
+  enabled: true
+  scopes:
+    - paths: [/showcase/*.pdf]
+      htpasswd: /a
+    - paths: [/showcase/*.ics]
+      htpasswd: /b
+`))
+	if err != nil {
+		t.Errorf("non-overlapping scopes rejected: %v", err)
+	}
+}
+
+func TestParseTenantAliases(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      aliases: [/showcase/2024/boston]
+    - path: /showcase/2025/raleigh/
+      aliases: [/showcase/2024/raleigh/]
+      alias_redirect: true
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	boston := config.Applications.Tenants[0]
+	if len(boston.Aliases) != 1 || boston.Aliases[0] != "/showcase/2024/boston/" {
+		t.Errorf("Aliases = %v, want a trailing slash added", boston.Aliases)
+	}
+	if !config.Applications.Tenants[1].AliasRedirect {
+		t.Error("AliasRedirect not parsed")
+	}
+
+	// Aliases get trailing-slash redirects; redirecting aliases go straight to the tenant path
+	redirects := map[string]string{}
+	for _, rule := range config.Server.RewriteRules {
+		redirects[rule.Pattern.String()] = rule.Replacement
+	}
+	if got := redirects["^/showcase/2024/boston$"]; got != "/showcase/2024/boston/" {
+		t.Errorf("boston alias redirects to %q", got)
+	}
+	if got := redirects["^/showcase/2024/raleigh$"]; got != "/showcase/2025/raleigh/" {
+		t.Errorf("raleigh alias redirects to %q", got)
+	}
+
+	_, err = ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      aliases: [/showcase/2025/raleigh/]
+    - path: /showcase/2025/raleigh/
+`))
+	if err == nil {
+		t.Error("expected an alias that duplicates a tenant path to be rejected")
+	}
+}
+
+func TestParseTenantNames(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /showcase/2025/raleigh/shimmer-shine/
+    - path: /showcase/2025/boston/
+      name: boston
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	derived := config.Applications.Tenants[0]
+	if derived.Name != "2025/raleigh/shimmer-shine" || derived.FileName() != "2025-raleigh-shimmer-shine" {
+		t.Errorf("Derived name = %q, file name %q", derived.Name, derived.FileName())
+	}
+	explicit := config.Applications.Tenants[1]
+	if explicit.Name != "boston" || explicit.Path != "/showcase/2025/boston/" {
+		t.Errorf("Explicit name = %q, path %q; want boston, routed by its path", explicit.Name, explicit.Path)
+	}
+
+	if got := TenantFileName("/" + strings.Repeat("a", 70) + "/"); got != strings.Repeat("a", MaxTenantNameLength) {
+		t.Errorf("TenantFileName of a long name = %q, want it capped", got)
+	}
+
+	for desc, tenants := range map[string]string{
+		"explicit name with a slash": "    - path: /a/\n      name: 2025/boston\n",
+		"derived names collide":      "    - path: /showcase/2025/boston/\n    - path: /showcase/2025-boston/\n",
+		"override collides":          "    - path: /showcase/2025/boston/\n    - path: /boston/\n      name: 2025-boston\n",
+	} {
+		if _, err := ParseYAML([]byte("applications:\n  tenants:\n" + tenants)); err == nil {
+			t.Errorf("%s: expected a config-load error", desc)
+		}
+	}
+}
+
+func TestParseEventHooks(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+hooks:
+  events:
+    - name: ops
+      url: https://ops.example.com/navigator
+      token: secret
+      types: [tenant.crashed, reload.failed]
+      retries: 3
+    - command: /usr/local/bin/notify
+      timeout: 2s
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	if len(config.Hooks.Events) != 2 {
+		t.Fatalf("Events = %d, want 2", len(config.Hooks.Events))
+	}
+	ops := config.Hooks.Events[0]
+	if ops.Token != "secret" || ops.Retries != 3 || len(ops.Types) != 2 {
+		t.Errorf("ops = %+v", ops)
+	}
+	if time.Duration(ops.Timeout) != DefaultEventHookTimeout || time.Duration(ops.RetryDelay) != DefaultEventHookRetryDelay {
+		t.Errorf("defaults not applied: timeout %v, retry delay %v", ops.Timeout, ops.RetryDelay)
+	}
+	if notify := config.Hooks.Events[1]; time.Duration(notify.Timeout) != 2*time.Second {
+		t.Errorf("command timeout = %v, want 2s", notify.Timeout)
+	}
+
+	for name, yaml := range map[string]string{
+		"no destination": "hooks:\n  events:\n    - name: empty\n",
+		"both":           "hooks:\n  events:\n    - url: https://example.com\n      command: notify\n",
+		"bad url":        "hooks:\n  events:\n    - url: ops.example.com\n",
+		"negative retry": "hooks:\n  events:\n    - url: https://example.com\n      retries: -1\n",
+		"unknown type":   "hooks:\n  events:\n    - url: https://example.com\n      types: [tenant.crash]\n",
+	} {
+		if _, err := ParseYAML([]byte(yaml)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseUploads(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+auth:
+  enabled: true
+  htpasswd: /etc/navigator/htpasswd
+  scopes:
+    - paths: [/showcase/uploads/]
+      realm: Photographers
+      htpasswd: /etc/navigator/photographers
+server:
+  root_path: /showcase
+  static:
+    public_dir: /srv/public
+    uploads:
+      - path: /showcase/uploads
+        allowed_extensions: [.PDF, jpg]
+        auth_scope: Photographers
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	upload := config.Server.Static.Uploads[0]
+	if upload.Path != "/showcase/uploads/" {
+		t.Errorf("Path = %q, want a trailing slash added", upload.Path)
+	}
+	if upload.Dir != filepath.FromSlash("/srv/public/uploads") {
+		t.Errorf("Dir = %q, want public_dir plus the path below root_path", upload.Dir)
+	}
+	if upload.MaxFileSize != DefaultUploadMaxFileSize {
+		t.Errorf("MaxFileSize = %d, want the default", upload.MaxFileSize)
+	}
+	if len(upload.AllowedExtensions) != 2 || upload.AllowedExtensions[0] != "pdf" {
+		t.Errorf("AllowedExtensions = %v, want normalized extensions", upload.AllowedExtensions)
+	}
+
+	for name, yaml := range map[string]string{
+		"auth disabled": "server:\n  static:\n    uploads:\n      - path: /uploads/\n",
+		"unknown scope": "auth:\n  enabled: true\n  htpasswd: /etc/htpasswd\nserver:\n  static:\n    uploads:\n      - path: /uploads/\n        auth_scope: Missing\n",
+		"relative path": "auth:\n  enabled: true\n  htpasswd: /etc/htpasswd\nserver:\n  static:\n    uploads:\n      - path: uploads/\n",
+	} {
+		if _, err := ParseYAML([]byte(yaml)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseConcurrencyLimits(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+applications:
+  pools:
+    max_concurrent_requests: 4
+    queue_timeout: 10s
+  tenants:
+    - path: /showcase/2025/boston/
+      max_concurrent_requests: 1
+      count_websockets: true
+    - path: /showcase/2025/raleigh/
+      queue_size: 5
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	boston := config.Applications.Tenants[0].Concurrency
+	if boston.MaxConcurrentRequests != 1 || boston.QueueTimeout.Std() != 10*time.Second || boston.QueueSize != DefaultRequestQueueSize {
+		t.Errorf("boston = %+v, want tenant limit with pool timeout and default queue size", boston)
+	}
+	if boston.CountWebSockets == nil || !*boston.CountWebSockets {
+		t.Errorf("boston CountWebSockets = %v, want true", boston.CountWebSockets)
+	}
+	raleigh := config.Applications.Tenants[1].Concurrency
+	if raleigh.MaxConcurrentRequests != 4 || raleigh.QueueSize != 5 || raleigh.CountWebSockets != nil {
+		t.Errorf("raleigh = %+v, want pool limit with tenant queue size", raleigh)
+	}
+
+	if _, err := ParseYAML([]byte("applications:\n  tenants:\n    - path: /a/\n      max_concurrent_requests: -1\n")); err == nil {
+		t.Error("expected an error for a negative limit")
+	}
+}
+
+func TestConfigParser_TenantFrameworks(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+applications:
+  runtime:
+    phoenix: elixir
+  tenants:
+    - path: /studios/boston/
+      framework: rails
+    - path: /studios/raleigh/
+      framework: phoenix
+      stop_signal: term
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if got := config.Applications.Tenants[1].StopSignal; got != "SIGTERM" {
+		t.Errorf("StopSignal = %q, want SIGTERM", got)
+	}
+
+	_, err = ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /studios/boston/
+      framework: nextjs
+`))
+	if err == nil || !strings.Contains(err.Error(), `unknown framework "nextjs"`) || !strings.Contains(err.Error(), "bun, django, fastapi, internal-echo, node, rails") {
+		t.Errorf("Unknown framework error = %v, want it to list the presets", err)
+	}
+
+	_, err = ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /studios/boston/
+      stop_signal: SIGWINCH
+`))
+	if err == nil || !strings.Contains(err.Error(), "stop_signal") {
+		t.Errorf("Unsupported stop_signal error = %v", err)
+	}
+}
+
+func TestConfigParser_ParseLoadShedding(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  load_shedding:
+    max_goroutines: 20000
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	shedding := config.Server.LoadShedding
+	if !shedding.Enabled() || shedding.Shed != ShedUnauthenticated || shedding.Recovery != DefaultLoadSheddingRecovery || time.Duration(shedding.Interval) != DefaultLoadSheddingInterval {
+		t.Errorf("Load shedding defaults = %+v", shedding)
+	}
+
+	for _, tt := range []struct {
+		shedding string
+		err      string
+	}{
+		{`{max_open_fds: -1}`, "must not be negative"},
+		{`{recovery: 1.5}`, "recovery must be between"},
+		{`{shed: anonymous}`, "not supported"},
+		{`{shed: paths}`, "needs paths"},
+		{`{shed: paths, paths: [showcase]}`, "must start with /"},
+	} {
+		_, err := ParseYAML([]byte("server:\n  load_shedding: " + tt.shedding + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("load_shedding %s: error = %v, want %q", tt.shedding, err, tt.err)
+		}
+	}
+}
+
+func TestConfigParser_MaxReplayHops(t *testing.T) {
+	config, err := ParseYAML([]byte("routes:\n  fly:\n    replay: []\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Routes.Fly.MaxReplayHops != DefaultMaxReplayHops {
+		t.Errorf("MaxReplayHops = %d, want the default", config.Routes.Fly.MaxReplayHops)
+	}
+	config, err = ParseYAML([]byte("routes:\n  fly:\n    max_replay_hops: 3\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Routes.Fly.MaxReplayHops != 3 {
+		t.Errorf("MaxReplayHops = %d, want 3", config.Routes.Fly.MaxReplayHops)
+	}
+	for _, hops := range []string{"0", "-1"} {
+		_, err := ParseYAML([]byte("routes:\n  fly:\n    max_replay_hops: " + hops + "\n"))
+		if err == nil || !strings.Contains(err.Error(), "max_replay_hops") {
+			t.Errorf("max_replay_hops %s: error = %v", hops, err)
+		}
+	}
+}
+
+func TestConfigParser_ParseCanonical(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  canonical:
+    host: " Example.COM "
+    exclude_hosts: [Localhost]
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	canonical := config.Server.Canonical
+	if !canonical.Enabled() || canonical.Host != "example.com" || canonical.Redirect != CanonicalRedirectPermanent || canonical.ExcludeHosts[0] != "localhost" {
+		t.Errorf("Canonical = %+v", canonical)
+	}
+	if warning := config.ForceHTTPSWarning(); warning != "" {
+		t.Errorf("ForceHTTPSWarning() = %q without force_https", warning)
+	}
+
+	// force_https can't see the scheme a load balancer received without
+	// trust_proxy
+	for trust, warns := range map[string]bool{"false": true, "true": false} {
+		config, err := ParseYAML([]byte("server:\n  trust_proxy: " + trust + "\n  canonical: {force_https: true}\n"))
+		if err != nil {
+			t.Fatalf("ParseYAML failed: %v", err)
+		}
+		if warning := config.ForceHTTPSWarning(); (warning != "") != warns {
+			t.Errorf("trust_proxy %s: ForceHTTPSWarning() = %q", trust, warning)
+		}
+	}
+
+	for _, tt := range []struct {
+		canonical string
+		err       string
+	}{
+		{`{host: "https://example.com"}`, "must be a host name"},
+		{`{host: example.com/app}`, "must be a host name"},
+		{`{force_https: true, redirect: moved}`, "not supported"},
+	} {
+		_, err := ParseYAML([]byte("server:\n  canonical: " + tt.canonical + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("canonical %s: error = %v, want %q", tt.canonical, err, tt.err)
+		}
+	}
+}
+
+func TestConfigParser_CacheControlDurations(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  static:
+    cache_control:
+      overrides:
+        - path: /assets/
+          max_age: 1y
+        - path: /live/
+          max_age: 0
+        - path: /plain/
+          immutable: true
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	overrides := config.Server.Static.CacheControl.Overrides
+	if config.Server.Static.CacheControl.Default != nil || overrides[0].MaxAge.Std() != 365*24*time.Hour ||
+		overrides[1].MaxAge == nil || *overrides[1].MaxAge != 0 || overrides[2].MaxAge != nil {
+		t.Errorf("CacheControl = %+v", config.Server.Static.CacheControl)
+	}
+
+	// A Cache-Control value isn't a duration
+	_, err = ParseYAML([]byte("server:\n  static:\n    cache_control:\n      default: no-cache\n"))
+	if err == nil || !strings.Contains(err.Error(), "cache_control.default") {
+		t.Errorf("default: no-cache: error = %v", err)
+	}
+}
+
+func TestConfigParser_ForwardedPrecedence(t *testing.T) {
+	for _, tt := range []struct {
+		server   string
+		expected string
+	}{
+		{"listen: 3000", ForwardedPrecedenceForwarded},
+		{"forwarded_precedence: X-Forwarded", ForwardedPrecedenceXForwarded},
+	} {
+		config, err := ParseYAML([]byte("server:\n  " + tt.server + "\n"))
+		if err != nil {
+			t.Fatalf("%s: ParseYAML failed: %v", tt.server, err)
+		}
+		if config.Server.ForwardedPrecedence != tt.expected {
+			t.Errorf("%s: ForwardedPrecedence = %q, want %q", tt.server, config.Server.ForwardedPrecedence, tt.expected)
+		}
+	}
+
+	_, err := ParseYAML([]byte("server:\n  forwarded_precedence: x-forwarded-for\n"))
+	if err == nil || !strings.Contains(err.Error(), "server.forwarded_precedence") {
+		t.Errorf("error = %v, want server.forwarded_precedence", err)
+	}
+}
+
+func TestConfigParser_RootUnavailable(t *testing.T) {
+	config, err := ParseYAML([]byte("server:\n  listen: 3000\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Server.Static.RootUnavailable != StaticRootUnavailable {
+		t.Errorf("Default = %q, want %q", config.Server.Static.RootUnavailable, StaticRootUnavailable)
+	}
+
+	_, err = ParseYAML([]byte("server:\n  static:\n    root_unavailable: 404\n"))
+	if err == nil || !strings.Contains(err.Error(), "server.static.root_unavailable") {
+		t.Errorf("error = %v, want server.static.root_unavailable", err)
+	}
+}
+
+func TestConfigParser_ParseAbsoluteURI(t *testing.T) {
+	config, err := ParseYAML([]byte("server:\n  listen: 3000\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Server.AbsoluteURI != AbsoluteURINormalize || config.Server.AbsoluteURIHostMismatch != HostMismatchReject {
+		t.Errorf("Defaults = %q, %q", config.Server.AbsoluteURI, config.Server.AbsoluteURIHostMismatch)
+	}
+
+	for _, tt := range []struct {
+		server string
+		err    string
+	}{
+		{"absolute_uri: rejct", "server.absolute_uri"},
+		{"absolute_uri_host_mismatch: use_host", "server.absolute_uri_host_mismatch"},
+	} {
+		_, err := ParseYAML([]byte("server:\n  " + tt.server + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.server, err, tt.err)
+		}
+	}
+}
+
+func TestConfigParser_ParseTimeouts(t *testing.T) {
+	config, err := ParseYAML([]byte("server:\n  listen: 3000\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	timeouts := config.Server.Timeouts
+	if timeouts.ReadHeader.Std() != DefaultReadHeaderTimeout || timeouts.Idle.Std() != DefaultConnIdleTimeout ||
+		timeouts.TCPKeepAlive.Std() != DefaultTCPKeepAlive || timeouts.Read != 0 || timeouts.Write != 0 {
+		t.Errorf("Default timeouts = %+v", timeouts)
+	}
+
+	config, err = ParseYAML([]byte(`
+server:
+  timeouts:
+    read_header: 5s
+    write: 1m
+    max_keepalive_requests: 100
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	timeouts = config.Server.Timeouts
+	if timeouts.ReadHeader.Std() != 5*time.Second || timeouts.Write.Std() != time.Minute || timeouts.MaxKeepaliveRequests != 100 {
+		t.Errorf("Timeouts = %+v", timeouts)
+	}
+
+	if _, err := ParseYAML([]byte("server:\n  timeouts:\n    max_keepalive_requests: -1\n")); err == nil {
+		t.Error("Expected an error for a negative max_keepalive_requests")
+	}
+}
+
+func TestConfigParser_ParseProxyProtocol(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  proxy_protocol: true
+  proxy_protocol_trusted: [10.0.0.0/8, 192.0.2.1, "2001:db8::1"]
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	var nets []string
+	for _, network := range config.Server.ProxyProtocolNets {
+		nets = append(nets, network.String())
+	}
+	if !config.Server.ProxyProtocol || strings.Join(nets, ",") != "10.0.0.0/8,192.0.2.1/32,2001:db8::1/128" {
+		t.Errorf("ProxyProtocolNets = %v", nets)
+	}
+
+	if _, err := ParseYAML([]byte("server:\n  proxy_protocol_trusted: [lb.internal]\n")); err == nil {
+		t.Error("Expected an error for a host name in proxy_protocol_trusted")
+	}
+}
+
+func TestConfigParser_PIDFile(t *testing.T) {
+	config, err := ParseYAML([]byte("server:\n  listen: 3000\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Server.PIDFile != NavigatorPIDFile {
+		t.Errorf("Default PIDFile = %q, want %q", config.Server.PIDFile, NavigatorPIDFile)
+	}
+
+	config, err = ParseYAML([]byte("server:\n  pid_file: /run/navigator/navigator.pid\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Server.PIDFile != "/run/navigator/navigator.pid" {
+		t.Errorf("PIDFile = %q", config.Server.PIDFile)
+	}
+}
+
+func TestParseFingerprintPatterns(t *testing.T) {
+	cfg, err := ParseYAML([]byte("server:\n  static:\n    public_dir: public\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	static := cfg.Server.Static
+	if len(static.Fingerprints) != 1 || static.FingerprintMaxAge.Std() != DefaultFingerprintMaxAge {
+		t.Fatalf("Fingerprints = %v, max age %s; want the defaults", static.Fingerprints, static.FingerprintMaxAge)
+	}
+	for path, want := range map[string]bool{
+		"/assets/application-1a2b3c4d.css":      true,
+		"/assets/application-1a2b3c4d.min.js":   true,
+		"/assets/application.css":               false,
+		"/assets/application-1a2b3c.css":        false,
+		"/assets/application-1a2b3c4d/logo.png": false,
+	} {
+		if got := static.Fingerprints[0].MatchString(path); got != want {
+			t.Errorf("Default pattern matches %s = %v, want %v", path, got, want)
+		}
+	}
+
+	cfg, err = ParseYAML([]byte("server:\n  static:\n    fingerprint_patterns: []\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if len(cfg.Server.Static.Fingerprints) != 0 {
+		t.Errorf("An empty fingerprint_patterns should turn fingerprinting off")
+	}
+
+	if _, err := ParseYAML([]byte("server:\n  static:\n    fingerprint_patterns: ['(']\n")); err == nil || !strings.Contains(err.Error(), "fingerprint_patterns") {
+		t.Errorf("Invalid pattern: error = %v", err)
+	}
+
+	cfg, err = ParseYAML([]byte("server:\n  static:\n    fingerprint_max_age: 30d\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if got := cfg.Server.Static.FingerprintMaxAge.Std(); got != 30*24*time.Hour {
+		t.Errorf("FingerprintMaxAge = %s, want 30d", got)
+	}
+	if _, err := ParseYAML([]byte("server:\n  static:\n    fingerprint_max_age: forever\n")); err == nil || !strings.Contains(err.Error(), "fingerprint_max_age") {
+		t.Errorf("Invalid fingerprint_max_age: error = %v", err)
+	}
+}
+
+func TestParseDiskBudget(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+logging:
+  file: /var/log/navigator/{{app}}.log
+  access:
+    destinations: [stdout, /var/log/access/access.log]
+  disk_budget:
+    max_bytes: 1048576
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	budget := cfg.Logging.DiskBudget
+	if got := strings.Join(budget.Directories, ","); got != filepath.Clean("/var/log/navigator")+","+filepath.Clean("/var/log/access") {
+		t.Errorf("Directories = %v, want those of the log files", budget.Directories)
+	}
+	if budget.Interval.Std() != DefaultDiskBudgetInterval || len(budget.Patterns) != 1 || len(budget.WarnAt) != 2 {
+		t.Errorf("Budget = %+v, want the defaults", budget)
+	}
+
+	for _, yaml := range []string{
+		"logging:\n  disk_budget:\n    max_bytes: 100\n",
+		"logging:\n  disk_budget:\n    max_bytes: 100\n    directory: log\n    warn_at: [120]\n",
+		"logging:\n  disk_budget:\n    max_bytes: 100\n    directory: log\n    patterns: ['a/*.log']\n",
+	} {
+		if _, err := ParseYAML([]byte(yaml)); err == nil || !strings.Contains(err.Error(), "disk_budget") {
+			t.Errorf("ParseYAML(%q) error = %v", yaml, err)
+		}
+	}
+}
+
+func TestParseIdleDeferFor(t *testing.T) {
+	cfg, err := ParseYAML([]byte("server:\n  idle:\n    action: suspend\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if got := strings.Join(cfg.Server.Idle.DeferFor, ","); got != "cgi,hooks,managed_process_restarts" {
+		t.Errorf("DeferFor = %q, want every kind of work", got)
+	}
+
+	cfg, err = ParseYAML([]byte("server:\n  idle:\n    defer_for: []\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if len(cfg.Server.Idle.DeferFor) != 0 {
+		t.Errorf("DeferFor = %v, want none", cfg.Server.Idle.DeferFor)
+	}
+
+	if _, err := ParseYAML([]byte("server:\n  idle:\n    defer_for: [backups]\n")); err == nil || !strings.Contains(err.Error(), "defer_for") {
+		t.Errorf("Unknown kind: error = %v", err)
+	}
+}
+
+func TestParseIdleState(t *testing.T) {
+	cfg, err := ParseYAML([]byte("server:\n  pid_file: /run/navigator/navigator.pid\n  idle:\n    state:\n      enabled: true\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	state := cfg.Server.Idle.State
+	if !state.Enabled || state.File != "/run/navigator/navigator-idle.json" {
+		t.Errorf("State = %+v, want the file next to the PID file", state)
+	}
+	if state.Interval.OrDefault(0) != DefaultIdleStateInterval || state.MaxAge.OrDefault(0) != DefaultIdleStateMaxAge {
+		t.Errorf("State = %+v, want the default interval and max_age", state)
+	}
+
+	cfg, err = ParseYAML([]byte("server:\n  idle:\n    state:\n      file: /var/lib/navigator/idle.json\n      interval: 1m\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if state := cfg.Server.Idle.State; state.File != "/var/lib/navigator/idle.json" || state.Interval.OrDefault(0) != time.Minute {
+		t.Errorf("State = %+v, want the configured file and interval", state)
+	}
+
+	if _, err := ParseYAML([]byte("server:\n  idle:\n    state:\n      max_age: -1h\n")); err == nil || !strings.Contains(err.Error(), "server.idle.state") {
+		t.Errorf("Negative max_age: error = %v", err)
+	}
+}
+
+func TestParseRouteAuth(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+auth:
+  enabled: false
+  htpasswd: ./htpasswd
+routes:
+  reverse_proxies:
+    - name: admin
+      prefix: /admin/
+      target: http://localhost:9000
+      auth: Required
+    - name: assets
+      prefix: /assets/
+      target: http://localhost:9001
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if got := cfg.Routes.ReverseProxies[0].Auth; got != RouteAuthRequired {
+		t.Errorf("Auth = %q, want %q", got, RouteAuthRequired)
+	}
+	if got := cfg.Routes.ReverseProxies[1].Auth; got != RouteAuthInherit {
+		t.Errorf("Auth = %q, want %q", got, RouteAuthInherit)
+	}
+	if !cfg.Auth.RequiredByRoutes {
+		t.Error("RequiredByRoutes should be set by a required route")
+	}
+
+	tests := []struct {
+		name  string
+		yaml  string
+		error string
+	}{
+		{"required without htpasswd", "server:\n  cgi_scripts:\n    - path: /sync\n      script: /bin/true\n      auth: required\n", "needs auth.htpasswd"},
+		{"unknown scope", "auth:\n  htpasswd: ./htpasswd\napplications:\n  tenants:\n    - path: /a/\n      auth: required\n      auth_scope: Admin\n", "no auth scope"},
+		{"scope without required", "auth:\n  htpasswd: ./htpasswd\napplications:\n  tenants:\n    - path: /a/\n      auth: public\n      auth_scope: Admin\n", "auth_scope applies only"},
+		{"unknown value", "routes:\n  reverse_proxies:\n    - name: x\n      prefix: /x/\n      target: http://localhost:1\n      auth: sometimes\n", "inherit, required, or public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseYAML([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.error)
+			}
+		})
+	}
+}
+
+func TestParseMIMETypes(t *testing.T) {
+	cfg, err := ParseYAML([]byte("server:\n  static:\n    mime_types:\n      MD: text/markdown\n      .wasm: application/wasm\n      txt: text/plain; charset=iso-8859-1\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	want := map[string]string{
+		".md":   "text/markdown; charset=utf-8",
+		".wasm": "application/wasm",
+		".txt":  "text/plain; charset=iso-8859-1",
+	}
+	for ext, contentType := range want {
+		if got := cfg.Server.Static.MIMETypes[ext]; got != contentType {
+			t.Errorf("MIMETypes[%q] = %q, want %q", ext, got, contentType)
+		}
+	}
+
+	if _, err := ParseYAML([]byte("server:\n  static:\n    mime_types:\n      md: \"not a type\"\n")); err == nil || !strings.Contains(err.Error(), "mime_types") {
+		t.Errorf("Invalid type: error = %v", err)
+	}
+}
+
+func TestParseRecycle(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  recycle:
+    max_requests: 1000
+    max_memory: 512M
+  tenants:
+    - name: boston
+      path: /boston/
+    - name: raleigh
+      path: /raleigh/
+      recycle:
+        max_lifetime: 6h
+        drain_timeout: 1m
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if cfg.Applications.MaxConcurrentRecycles != DefaultMaxConcurrentRecycles {
+		t.Errorf("MaxConcurrentRecycles = %d, want the default", cfg.Applications.MaxConcurrentRecycles)
+	}
+	boston, raleigh := cfg.Applications.Tenants[0].Recycle, cfg.Applications.Tenants[1].Recycle
+	if boston == nil || boston.MaxRequests != 1000 || boston.MaxMemoryBytes != 512<<20 || boston.DrainTimeout.Std() != DefaultRecycleDrainTimeout {
+		t.Errorf("boston = %+v, want applications.recycle with the default drain_timeout", boston)
+	}
+	if raleigh == nil || raleigh.MaxRequests != 0 || raleigh.MaxLifetime.Std() != 6*time.Hour || raleigh.DrainTimeout.Std() != time.Minute {
+		t.Errorf("raleigh = %+v, want its own policy", raleigh)
+	}
+
+	_, err = ParseYAML([]byte(`
+applications:
+  tenants:
+    - name: boston
+      path: /boston/
+      start_guard:
+        url: http://guard.internal/lease
+      recycle:
+        max_requests: 10
+`))
+	if err == nil || !strings.Contains(err.Error(), "start_guard") {
+		t.Errorf("Recycle with start_guard: error = %v", err)
+	}
+
+	// A replacement can't start on the port its predecessor holds
+	_, err = ParseYAML([]byte(`
+applications:
+  tenants:
+    - name: boston
+      path: /boston/
+      port: 4010
+      recycle:
+        max_requests: 10
+`))
+	if err == nil || !strings.Contains(err.Error(), "pinned port") {
+		t.Errorf("Recycle with a pinned port: error = %v", err)
+	}
+	cfg, err = ParseYAML([]byte(`
+applications:
+  recycle:
+    max_requests: 10
+  tenants:
+    - name: boston
+      path: /boston/
+      port: 4010
+`))
+	if err != nil || cfg.Applications.Tenants[0].Recycle != nil {
+		t.Errorf("Pinned tenant inherited applications.recycle: %v", err)
+	}
+
+	for _, maxMemory := range []string{"512 megs", "0"} {
+		_, err = ParseYAML([]byte("applications:\n  recycle:\n    max_memory: \"" + maxMemory + "\"\n"))
+		if err == nil || !strings.Contains(err.Error(), "max_memory") {
+			t.Errorf("max_memory %q: error = %v", maxMemory, err)
+		}
+	}
+}
+
+func TestParseTenantWarmers(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - name: boston
+      path: /studios/boston/
+      warmers:
+        - path: /heats
+        - path: /dashboard
+          interval: 1m
+          keep_warm: true
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	tenant := &cfg.Applications.Tenants[0]
+	heats, dashboard := tenant.Warmers[0], tenant.Warmers[1]
+	if heats.Interval.Std() != DefaultWarmerInterval || heats.KeepWarm || tenant.WarmerPath(&heats) != "/studios/boston/heats" {
+		t.Errorf("heats = %+v, want the default interval under the tenant's path", heats)
+	}
+	if dashboard.Interval.Std() != time.Minute || !dashboard.KeepWarm {
+		t.Errorf("dashboard = %+v, want its own interval and keep_warm", dashboard)
+	}
+
+	for _, warmer := range []string{"path: heats", "{path: /heats, interval: 1ns}"} {
+		_, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - name: boston
+      path: /studios/boston/
+      warmers:
+        - ` + warmer + `
+`))
+		if err == nil || !strings.Contains(err.Error(), "warmers[0]") {
+			t.Errorf("Warmer %s: error = %v", warmer, err)
+		}
+	}
+}
+
+func TestParseTenantEnvVariables(t *testing.T) {
+	t.Setenv("NAVIGATOR_TEST_DB_HOST", "db.internal")
+	cfg, err := ParseYAML([]byte(`
+applications:
+  env:
+    DATABASE_URL: "postgres://${NAVIGATOR_TEST_DB_HOST}/${database}"
+    REDIS_URL: "${redis:-redis://localhost:6379}"
+    LABEL: "${label}"
+  tenants:
+    - name: boston
+      path: /boston/
+      var:
+        database: "${year}_boston"
+        year: 2025
+        label: null
+    - name: raleigh
+      path: /raleigh/
+      allow_undefined_vars: true
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	boston, raleigh := cfg.Applications.Tenants[0].Env, cfg.Applications.Tenants[1].Env
+	if boston["DATABASE_URL"] != "postgres://db.internal/2025_boston" || boston["REDIS_URL"] != "redis://localhost:6379" || boston["LABEL"] != "" {
+		t.Errorf("boston env = %v, want nested vars, the environment, and fallbacks expanded", boston)
+	}
+	if raleigh["DATABASE_URL"] != "postgres://db.internal/${database}" || raleigh["REDIS_URL"] != "redis://localhost:6379" {
+		t.Errorf("raleigh env = %v, want undefined vars left as they are", raleigh)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "undefined variable",
+			yaml: `
+applications:
+  env:
+    DATABASE_URL: "postgres://localhost/${databse}"
+  tenants:
+    - name: boston
+      path: /boston/
+      var:
+        database: boston
+`,
+			wantErr: `tenant "boston": env DATABASE_URL: undefined variable "databse"`,
+		},
+		{
+			name: "undefined nested variable",
+			yaml: `
+applications:
+  env:
+    DATABASE_URL: "${url}"
+  tenants:
+    - name: boston
+      path: /boston/
+      var:
+        url: "postgres://${host}/boston"
+`,
+			wantErr: `tenant "boston": env DATABASE_URL: undefined variable "host"`,
+		},
+		{
+			name: "cycle",
+			yaml: `
+applications:
+  env:
+    DATABASE_URL: "${a}"
+  tenants:
+    - name: boston
+      path: /boston/
+      var:
+        a: "${b}"
+        b: "${a}"
+`,
+			wantErr: `tenant "boston": env DATABASE_URL: variable cycle a -> b -> a`,
+		},
+		{
+			name: "tenant overrides the opt-out",
+			yaml: `
+applications:
+  allow_undefined_vars: true
+  env:
+    DATABASE_URL: "${database}"
+  tenants:
+    - name: boston
+      path: /boston/
+      allow_undefined_vars: false
+`,
+			wantErr: `tenant "boston": env DATABASE_URL: undefined variable "database"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte(tt.yaml))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParseYAML() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
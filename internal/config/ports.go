@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Owners of ports that are not managed processes
+const (
+	PortOwnerNavigator  = "navigator"
+	PortOwnerTenantPool = "tenant pool"
+)
+
+// PortUse records a port Navigator expects to be in use and who uses it
+type PortUse struct {
+	Port     int    `json:"port"`
+	LastPort int    `json:"last_port"`          // End of a port range (equal to Port for a single port)
+	Owner    string `json:"owner"`              // e.g. "navigator", "tenant pool", "managed process redis"
+	Inferred bool   `json:"inferred,omitempty"` // Guessed from command arguments or environment rather than declared
+}
+
+// PortReport is the result of cross-checking every port in the configuration
+type PortReport struct {
+	Uses     []PortUse
+	Errors   []string // Certain conflicts between declared ports
+	Warnings []string // Likely conflicts found heuristically
+}
+
+// Patterns used to infer ports from managed process arguments
+var (
+	portFlagPattern  = regexp.MustCompile(`(?i)^--?[a-z-]*port$`)
+	portValuePattern = regexp.MustCompile(`(?i)^--?[a-z-]*port=(\d+)$`)
+	hostPortPattern  = regexp.MustCompile(`(?i)(?:localhost|127\.0\.0\.1|0\.0\.0\.0|\[::1?\]|\*):(\d+)`)
+)
+
+// CheckPorts cross-checks Navigator's listen port, the tenant pool range,
+// managed process ports (declared or inferred), and reverse proxy targets
+func CheckPorts(cfg *Config) PortReport {
+	var report PortReport
+
+	listen := listenPort(cfg.Server.Listen)
+	report.Uses = append(report.Uses, PortUse{Port: listen, LastPort: listen, Owner: PortOwnerNavigator})
+
+	poolStart := cfg.Applications.Pools.StartPort
+	if poolStart == 0 {
+		poolStart = DefaultStartPort
+	}
+	pool := PortUse{Port: poolStart, LastPort: poolStart + MaxPortRange, Owner: PortOwnerTenantPool}
+	report.Uses = append(report.Uses, pool)
+
+	for _, proc := range cfg.ManagedProcesses {
+		owner := "managed process " + proc.Name
+		declared := make(map[int]bool)
+		for _, port := range proc.Ports {
+			declared[port] = true
+			report.Uses = append(report.Uses, PortUse{Port: port, LastPort: port, Owner: owner})
+		}
+		for _, port := range inferProcessPorts(proc) {
+			if !declared[port] {
+				declared[port] = true
+				report.Uses = append(report.Uses, PortUse{Port: port, LastPort: port, Owner: owner, Inferred: true})
+			}
+		}
+	}
+
+	// Compare every pair of claims that overlap
+	for i, a := range report.Uses {
+		for _, b := range report.Uses[i+1:] {
+			if a.Owner == b.Owner || a.LastPort < b.Port || b.LastPort < a.Port {
+				continue
+			}
+			message := describePortConflict(a, b)
+			// The pool allocator skips ports that are already bound, so sharing
+			// the range with Navigator's own listener is survivable; a declared
+			// managed process port races with tenant startup and is not
+			navigatorInPool := (a.Owner == PortOwnerNavigator && b.Owner == PortOwnerTenantPool) ||
+				(a.Owner == PortOwnerTenantPool && b.Owner == PortOwnerNavigator)
+			if a.Inferred || b.Inferred || navigatorInPool {
+				report.Warnings = append(report.Warnings, message)
+			} else {
+				report.Errors = append(report.Errors, message)
+			}
+		}
+	}
+
+	for _, route := range cfg.Routes.ReverseProxies {
+		port, ok := localTargetPort(route.Target)
+		if !ok {
+			continue
+		}
+		name := route.Name
+		if name == "" {
+			name = route.Target
+		}
+		if port == listen {
+			report.Warnings = append(report.Warnings,
+				fmt.Sprintf("reverse proxy %s targets Navigator's own port %d", name, port))
+		} else if port >= pool.Port && port <= pool.LastPort {
+			report.Warnings = append(report.Warnings,
+				fmt.Sprintf("reverse proxy %s targets port %d inside the tenant pool range %d-%d", name, port, pool.Port, pool.LastPort))
+		}
+	}
+
+	sort.SliceStable(report.Uses, func(i, j int) bool { return report.Uses[i].Port < report.Uses[j].Port })
+	return report
+}
+
+// describePortConflict explains why two port claims overlap
+func describePortConflict(a, b PortUse) string {
+	if a.Port != a.LastPort || b.Port != b.LastPort {
+		single, ranged := a, b
+		if a.Port != a.LastPort {
+			single, ranged = b, a
+		}
+		return fmt.Sprintf("port %d (%s) is inside the %s range %d-%d",
+			single.Port, describePortOwner(single), ranged.Owner, ranged.Port, ranged.LastPort)
+	}
+	return fmt.Sprintf("port %d is used by both %s and %s", a.Port, describePortOwner(a), describePortOwner(b))
+}
+
+func describePortOwner(use PortUse) string {
+	if use.Inferred {
+		return use.Owner + " (inferred from command)"
+	}
+	return use.Owner
+}
+
+// inferProcessPorts guesses ports from a managed process's arguments and
+// environment: --port 6379, --port=6379, -p 6379, localhost:6379, REDIS_PORT=6379
+func inferProcessPorts(proc ManagedProcessConfig) []int {
+	var ports []int
+	add := func(value string) {
+		if port, err := strconv.Atoi(value); err == nil && port > 0 && port <= 65535 {
+			ports = append(ports, port)
+		}
+	}
+
+	args := append([]string{proc.Command}, proc.Args...)
+	for i, arg := range args {
+		if match := portValuePattern.FindStringSubmatch(arg); match != nil {
+			add(match[1])
+		} else if (portFlagPattern.MatchString(arg) || arg == "-p") && i+1 < len(args) {
+			add(args[i+1])
+		}
+		for _, match := range hostPortPattern.FindAllStringSubmatch(arg, -1) {
+			add(match[1])
+		}
+	}
+
+	for key, value := range proc.Env {
+		if strings.HasSuffix(strings.ToUpper(key), "PORT") {
+			add(value)
+		}
+		for _, match := range hostPortPattern.FindAllStringSubmatch(value, -1) {
+			add(match[1])
+		}
+	}
+	return ports
+}
+
+// localTargetPort returns the port of a reverse proxy target on this machine
+func localTargetPort(target string) (int, bool) {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Port() == "" {
+		return 0, false
+	}
+	switch host := parsed.Hostname(); host {
+	case "localhost", "0.0.0.0":
+	default:
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return 0, false
+		}
+	}
+	port, err := strconv.Atoi(parsed.Port())
+	return port, err == nil
+}
+
+// Log writes the port table and any warnings to the structured log
+func (r PortReport) Log() {
+	for _, use := range r.Uses {
+		ports := strconv.Itoa(use.Port)
+		if use.LastPort != use.Port {
+			ports = fmt.Sprintf("%d-%d", use.Port, use.LastPort)
+		}
+		slog.Info("Port in use", "port", ports, "owner", use.Owner, "inferred", use.Inferred)
+	}
+	for _, warning := range r.Warnings {
+		slog.Warn("Possible port conflict", "conflict", warning)
+	}
+}
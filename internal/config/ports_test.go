@@ -0,0 +1,222 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPorts(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantErrors   []string
+		wantWarnings []string
+	}{
+		{
+			name: "no conflicts",
+			yaml: `
+server:
+  listen: 3000
+managed_processes:
+  - name: redis
+    command: redis-server
+    args: ["--port", "6379"]
+`,
+		},
+		{
+			name: "inferred port inside pool",
+			yaml: `
+server:
+  listen: 3000
+managed_processes:
+  - name: redis
+    command: redis-server
+    args: ["--port", "4010"]
+`,
+			wantWarnings: []string{"port 4010 (managed process redis (inferred from command)) is inside the tenant pool range 4000-4100"},
+		},
+		{
+			name: "inferred port from environment",
+			yaml: `
+server:
+  listen: 3000
+managed_processes:
+  - name: worker
+    command: ./worker
+    env:
+      WORKER_PORT: "3000"
+`,
+			wantWarnings: []string{"port 3000 is used by both navigator and managed process worker (inferred from command)"},
+		},
+		{
+			name: "navigator listens inside pool",
+			yaml: `
+server:
+  listen: 4000
+`,
+			wantWarnings: []string{"port 4000 (navigator) is inside the tenant pool range 4000-4100"},
+		},
+		{
+			name: "navigator listens on a host inside pool",
+			yaml: `
+server:
+  listen: "127.0.0.1:4000"
+`,
+			wantWarnings: []string{"port 4000 (navigator) is inside the tenant pool range 4000-4100"},
+		},
+		{
+			name: "reverse proxy into pool",
+			yaml: `
+server:
+  listen: 3000
+routes:
+  reverse_proxies:
+    - name: api
+      path: "^/api/"
+      target: "http://localhost:4050"
+    - name: external
+      path: "^/ext/"
+      target: "https://example.com:4050"
+`,
+			wantWarnings: []string{"reverse proxy api targets port 4050 inside the tenant pool range 4000-4100"},
+		},
+		{
+			name: "reverse proxy to navigator",
+			yaml: `
+server:
+  listen: 3000
+routes:
+  reverse_proxies:
+    - name: loop
+      path: "^/loop/"
+      target: "http://127.0.0.1:3000"
+`,
+			wantWarnings: []string{"reverse proxy loop targets Navigator's own port 3000"},
+		},
+		{
+			name: "declared ports clash",
+			yaml: `
+server:
+  listen: 3000
+managed_processes:
+  - name: redis
+    command: redis-server
+    ports: [6379]
+  - name: cache
+    command: ./cache
+    ports: [6379]
+`,
+			wantErrors: []string{"port 6379 is used by both managed process redis and managed process cache"},
+		},
+		{
+			name: "declared port inside pool",
+			yaml: `
+server:
+  listen: 3000
+applications:
+  pools:
+    start_port: 5000
+managed_processes:
+  - name: sidecar
+    command: ./sidecar
+    ports: [5050]
+`,
+			wantErrors: []string{"port 5050 (managed process sidecar) is inside the tenant pool range 5000-5100"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseYAML([]byte(tt.yaml))
+			if len(tt.wantErrors) > 0 {
+				// Certain conflicts fail the load
+				if err == nil {
+					t.Fatalf("expected port conflict error")
+				}
+				for _, want := range tt.wantErrors {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q does not mention %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse config: %v", err)
+			}
+
+			report := CheckPorts(cfg)
+			if len(report.Errors) > 0 {
+				t.Errorf("unexpected errors: %q", report.Errors)
+			}
+			if strings.Join(report.Warnings, "\n") != strings.Join(tt.wantWarnings, "\n") {
+				t.Errorf("warnings = %q, want %q", report.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestCheckPortsUsesSorted(t *testing.T) {
+	cfg := &Config{}
+	cfg.Server.Listen = ":9000"
+	cfg.ManagedProcesses = []ManagedProcessConfig{
+		{Name: "redis", Command: "redis-server", Args: []string{"--port=6379"}},
+		{Name: "api", Command: "./api", Ports: []int{8080}},
+	}
+
+	report := CheckPorts(cfg)
+	var owners []string
+	for _, use := range report.Uses {
+		owners = append(owners, use.Owner)
+	}
+	want := []string{PortOwnerTenantPool, "managed process redis", "managed process api", PortOwnerNavigator}
+	if strings.Join(owners, ",") != strings.Join(want, ",") {
+		t.Errorf("owners = %v, want %v", owners, want)
+	}
+	if !report.Uses[1].Inferred || report.Uses[2].Inferred {
+		t.Errorf("expected redis port inferred and api port declared: %+v", report.Uses)
+	}
+}
+
+func TestParseYAMLRejectsPortConflicts(t *testing.T) {
+	_, err := ParseYAML([]byte(`
+server:
+  listen: 3000
+managed_processes:
+  - name: api
+    command: ./api
+    ports: [3000]
+`))
+	if err == nil || !strings.Contains(err.Error(), "port 3000 is used by both navigator and managed process api") {
+		t.Fatalf("expected port conflict error, got %v", err)
+	}
+}
+
+func TestInferProcessPorts(t *testing.T) {
+	tests := []struct {
+		name string
+		proc ManagedProcessConfig
+		want []int
+	}{
+		{"flag and value", ManagedProcessConfig{Command: "redis-server", Args: []string{"--port", "6379"}}, []int{6379}},
+		{"flag with equals", ManagedProcessConfig{Command: "memcached", Args: []string{"--listen-port=11211"}}, []int{11211}},
+		{"short flag", ManagedProcessConfig{Command: "memcached", Args: []string{"-p", "11211"}}, []int{11211}},
+		{"host and port", ManagedProcessConfig{Command: "./app", Args: []string{"--bind", "127.0.0.1:9292"}}, []int{9292}},
+		{"env port", ManagedProcessConfig{Command: "./app", Env: map[string]string{"PORT": "5000"}}, []int{5000}},
+		{"env url", ManagedProcessConfig{Command: "./app", Env: map[string]string{"REDIS_URL": "redis://localhost:6380/0"}}, []int{6380}},
+		{"no ports", ManagedProcessConfig{Command: "./worker", Args: []string{"--verbose"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inferProcessPorts(tt.proc)
+			if len(got) != len(tt.want) {
+				t.Fatalf("inferProcessPorts() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("inferProcessPorts() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import "fmt"
+
+// parsePriorities validates the pool's default priority and gives each
+// tenant its effective priority: the tenant's settings over the pool's
+func (p *ConfigParser) parsePriorities() error {
+	pool := p.config.Applications.Pools.Priority
+	if err := validatePriority(pool); err != nil {
+		return fmt.Errorf("applications.pools.priority: %w", err)
+	}
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := validatePriority(tenant.Priority); err != nil {
+			return fmt.Errorf("tenant %q: priority: %w", tenant.Name, err)
+		}
+		tenant.Priority = mergePriority(pool, tenant.Priority)
+	}
+	return nil
+}
+
+// validatePriority checks each setting against the range the kernel accepts
+func validatePriority(priority *PriorityConfig) error {
+	if priority == nil {
+		return nil
+	}
+	if priority.Nice != nil && (*priority.Nice < -20 || *priority.Nice > 19) {
+		return fmt.Errorf("nice must be between -20 and 19, got %d", *priority.Nice)
+	}
+	switch priority.IONiceClass {
+	case "", IONiceRealtime, IONiceBestEffort:
+	case IONiceIdle:
+		if priority.IONiceLevel != nil {
+			return fmt.Errorf("ionice_level doesn't apply to the idle class")
+		}
+	default:
+		return fmt.Errorf("ionice_class must be realtime, best-effort, or idle, got %q", priority.IONiceClass)
+	}
+	if priority.IONiceLevel != nil && (*priority.IONiceLevel < 0 || *priority.IONiceLevel > 7) {
+		return fmt.Errorf("ionice_level must be between 0 and 7, got %d", *priority.IONiceLevel)
+	}
+	if priority.OOMScoreAdj != nil && (*priority.OOMScoreAdj < -1000 || *priority.OOMScoreAdj > 1000) {
+		return fmt.Errorf("oom_score_adj must be between -1000 and 1000, got %d", *priority.OOMScoreAdj)
+	}
+	return nil
+}
+
+// mergePriority returns the tenant's settings over the pool's, with the I/O
+// class and level completed, or nil if neither sets anything
+func mergePriority(pool, tenant *PriorityConfig) *PriorityConfig {
+	if pool == nil && tenant == nil {
+		return nil
+	}
+	merged := PriorityConfig{}
+	for _, layer := range []*PriorityConfig{pool, tenant} {
+		if layer == nil {
+			continue
+		}
+		if layer.Nice != nil {
+			merged.Nice = layer.Nice
+		}
+		if layer.IONiceClass != "" {
+			merged.IONiceClass = layer.IONiceClass
+			merged.IONiceLevel = nil // A level set with another class doesn't carry over
+		}
+		if layer.IONiceLevel != nil {
+			merged.IONiceLevel = layer.IONiceLevel
+		}
+		if layer.OOMScoreAdj != nil {
+			merged.OOMScoreAdj = layer.OOMScoreAdj
+		}
+	}
+
+	// A level alone selects the best-effort class
+	if merged.IONiceClass == "" && merged.IONiceLevel != nil {
+		merged.IONiceClass = IONiceBestEffort
+	}
+	if merged.IONiceClass != "" && merged.IONiceClass != IONiceIdle && merged.IONiceLevel == nil {
+		level := DefaultIONiceLevel
+		merged.IONiceLevel = &level
+	}
+	if merged.IONiceClass == IONiceIdle {
+		merged.IONiceLevel = nil
+	}
+	return &merged
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePriorities(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+applications:
+  pools:
+    priority:
+      nice: 5
+      oom_score_adj: 200
+  tenants:
+    - name: reports
+      path: /reports/
+      priority:
+        nice: 15
+        ionice_class: idle
+        oom_score_adj: 900
+    - name: boston
+      path: /boston/
+      priority:
+        ionice_level: 2
+    - name: raleigh
+      path: /raleigh/
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	tenants := config.Applications.Tenants
+
+	reports := tenants[0].Priority
+	if *reports.Nice != 15 || reports.IONiceClass != IONiceIdle || reports.IONiceLevel != nil || *reports.OOMScoreAdj != 900 {
+		t.Errorf("reports priority = %+v", reports)
+	}
+	boston := tenants[1].Priority
+	if *boston.Nice != 5 || boston.IONiceClass != IONiceBestEffort || *boston.IONiceLevel != 2 || *boston.OOMScoreAdj != 200 {
+		t.Errorf("boston priority = %+v, want the pool defaults with best-effort level 2", boston)
+	}
+	raleigh := tenants[2].Priority
+	if *raleigh.Nice != 5 || raleigh.IONiceClass != "" || *raleigh.OOMScoreAdj != 200 {
+		t.Errorf("raleigh priority = %+v, want the pool defaults", raleigh)
+	}
+
+	config, err = ParseYAML([]byte("applications:\n  tenants:\n    - name: boston\n      path: /boston/\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	if config.Applications.Tenants[0].Priority != nil {
+		t.Error("Expected no priority without any settings")
+	}
+
+	for _, tt := range []struct {
+		priority string
+		err      string
+	}{
+		{`{nice: 20}`, "nice must be between -20 and 19"},
+		{`{ionice_class: low}`, "ionice_class must be"},
+		{`{ionice_level: 8}`, "ionice_level must be between 0 and 7"},
+		{`{ionice_class: idle, ionice_level: 3}`, "doesn't apply to the idle class"},
+		{`{oom_score_adj: -1001}`, "oom_score_adj must be between -1000 and 1000"},
+	} {
+		_, err := ParseYAML([]byte("applications:\n  tenants:\n    - name: boston\n      path: /boston/\n      priority: " + tt.priority + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("priority %s: error = %v, want %q", tt.priority, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import "fmt"
+
+// parseRecycle validates the recycle policies and applies their defaults.
+// Tenants without a policy of their own get a copy of applications.recycle,
+// unless they have a pinned port.
+func (p *ConfigParser) parseRecycle() error {
+	apps := &p.config.Applications
+	if apps.MaxConcurrentRecycles < 0 {
+		return fmt.Errorf("applications.max_concurrent_recycles must not be negative")
+	}
+	if apps.MaxConcurrentRecycles == 0 {
+		apps.MaxConcurrentRecycles = DefaultMaxConcurrentRecycles
+	}
+	if err := parseRecyclePolicy(&apps.Recycle); err != nil {
+		return fmt.Errorf("applications.recycle: %w", err)
+	}
+	for i := range apps.Tenants {
+		tenant := &apps.Tenants[i]
+		if tenant.Recycle == nil && apps.Recycle.Enabled() && tenant.Port == 0 {
+			policy := apps.Recycle
+			tenant.Recycle = &policy
+		}
+		if !tenant.Recycle.Enabled() {
+			continue
+		}
+		if err := parseRecyclePolicy(tenant.Recycle); err != nil {
+			return fmt.Errorf("tenant %q: recycle: %w", tenant.Name, err)
+		}
+		// The replacement would have to hold the guard or port its
+		// predecessor holds
+		if tenant.StartGuard != nil {
+			return fmt.Errorf("tenant %q: recycle can't be combined with start_guard", tenant.Name)
+		}
+		if tenant.Port != 0 {
+			return fmt.Errorf("tenant %q: recycle can't be combined with a pinned port", tenant.Name)
+		}
+	}
+	return nil
+}
+
+func parseRecyclePolicy(policy *RecycleConfig) error {
+	if policy.MaxRequests < 0 || policy.MaxLifetime < 0 || policy.DrainTimeout < 0 {
+		return fmt.Errorf("max_requests, max_lifetime, and drain_timeout must not be negative")
+	}
+	if policy.DrainTimeout == 0 {
+		policy.DrainTimeout = Duration(DefaultRecycleDrainTimeout)
+	}
+	maxMemory, err := ParseMemorySize(policy.MaxMemory)
+	if err != nil {
+		return fmt.Errorf("max_memory: %w", err)
+	}
+	if policy.MaxMemory != "" && maxMemory <= 0 {
+		return fmt.Errorf("max_memory must be positive, got %q", policy.MaxMemory)
+	}
+	policy.MaxMemoryBytes = maxMemory
+	return nil
+}
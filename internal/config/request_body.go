@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseRequestBody validates the encodings of request bodies Navigator
+// decodes, for the server and for each script, route, and tenant that sets
+// its own, and applies the body size defaults
+func (p *ConfigParser) parseRequestBody() error {
+	server := &p.config.Server
+	yamlServer := &p.yamlConfig.Server
+	if yamlServer.MaxRequestBody < 0 || yamlServer.MaxCompressedRequestBody < 0 {
+		return fmt.Errorf("server.max_request_body and server.max_compressed_request_body must not be negative")
+	}
+
+	encodings, err := parseDecompressEncodings("server.decompress_requests", yamlServer.DecompressRequests)
+	if err != nil {
+		return err
+	}
+	server.DecompressRequests = encodings
+	server.MaxRequestBody = yamlServer.MaxRequestBody
+	server.MaxCompressedRequestBody = yamlServer.MaxCompressedRequestBody
+	if server.MaxCompressedRequestBody == 0 {
+		server.MaxCompressedRequestBody = DefaultMaxCompressedRequestBody
+	}
+	server.MaxDecompressedBody = server.MaxRequestBody
+	if server.MaxDecompressedBody == 0 {
+		server.MaxDecompressedBody = DefaultMaxDecompressedRequestBody
+	}
+
+	override := func(field string, encodings *[]string) error {
+		if encodings == nil {
+			return nil
+		}
+		parsed, err := parseDecompressEncodings(field, *encodings)
+		if err != nil {
+			return err
+		}
+		*encodings = parsed
+		return nil
+	}
+	for i := range server.CGIScripts {
+		if err := override(fmt.Sprintf("server.cgi_scripts[%d].decompress_requests", i), server.CGIScripts[i].DecompressRequests); err != nil {
+			return err
+		}
+	}
+	for i := range p.config.Routes.ReverseProxies {
+		if err := override(fmt.Sprintf("routes.reverse_proxies[%d].decompress_requests", i), p.config.Routes.ReverseProxies[i].DecompressRequests); err != nil {
+			return err
+		}
+	}
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := override(fmt.Sprintf("tenant %q: decompress_requests", tenant.Name), tenant.DecompressRequests); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDecompressEncodings lowercases a list of content codings, which must
+// each be one Navigator can decode
+func parseDecompressEncodings(field string, encodings []string) ([]string, error) {
+	parsed := make([]string, 0, len(encodings))
+	for i, encoding := range encodings {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != EncodingGzip && encoding != EncodingBrotli {
+			return nil, fmt.Errorf("%s[%d]: unsupported encoding %q (use %q or %q)", field, i, encodings[i], EncodingGzip, EncodingBrotli)
+		}
+		parsed = append(parsed, encoding)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDecompressRequests(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+server:
+  decompress_requests: [GZIP, br]
+  cgi_scripts:
+    - path: /sync
+      script: /bin/true
+      decompress_requests: [br]
+routes:
+  reverse_proxies:
+    - name: raw
+      prefix: /raw/
+      target: http://localhost:9000
+      decompress_requests: []
+    - name: api
+      prefix: /api/
+      target: http://localhost:9001
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	server := cfg.Server
+	if strings.Join(server.DecompressRequests, ",") != "gzip,br" {
+		t.Errorf("DecompressRequests = %v", server.DecompressRequests)
+	}
+	if server.MaxRequestBody != 0 || server.MaxCompressedRequestBody != DefaultMaxCompressedRequestBody || server.MaxDecompressedBody != DefaultMaxDecompressedRequestBody {
+		t.Errorf("Limits = %d, %d, %d, want the defaults", server.MaxRequestBody, server.MaxCompressedRequestBody, server.MaxDecompressedBody)
+	}
+	if script := server.CGIScripts[0].DecompressRequests; script == nil || strings.Join(*script, ",") != "br" {
+		t.Errorf("CGI script DecompressRequests = %v", script)
+	}
+	if raw := cfg.Routes.ReverseProxies[0].DecompressRequests; raw == nil || len(*raw) != 0 {
+		t.Errorf("Pass-through route DecompressRequests = %v, want empty", raw)
+	}
+	if api := cfg.Routes.ReverseProxies[1].DecompressRequests; api != nil {
+		t.Errorf("Inheriting route DecompressRequests = %v, want nil", *api)
+	}
+
+	cfg, err = ParseYAML([]byte("server:\n  max_request_body: 2048\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	if cfg.Server.MaxDecompressedBody != 2048 {
+		t.Errorf("MaxDecompressedBody = %d, want max_request_body", cfg.Server.MaxDecompressedBody)
+	}
+}
+
+func TestParseDecompressRequestsErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, yaml, want string
+	}{
+		{"server", "server:\n  decompress_requests: [deflate]\n", `server.decompress_requests[0]: unsupported encoding "deflate"`},
+		{"tenant", "applications:\n  tenants:\n    - path: /a/\n      decompress_requests: [gzip, zstd]\n", `decompress_requests[1]: unsupported encoding "zstd"`},
+		{"negative", "server:\n  max_request_body: -1\n", "must not be negative"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
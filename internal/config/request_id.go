@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// parseRequestID canonicalizes the headers carrying request ids, defaulting
+// to X-Request-Id
+func (p *ConfigParser) parseRequestID() error {
+	requestID := p.yamlConfig.Server.RequestID
+	if requestID.Header == "" {
+		requestID.Header = HeaderRequestID
+	}
+	header, err := requestIDHeader("server.request_id.header", requestID.Header)
+	if err != nil {
+		return err
+	}
+	requestID.Header = header
+
+	forward := make([]string, 0, len(requestID.ForwardHeaders))
+	for i, name := range requestID.ForwardHeaders {
+		header, err := requestIDHeader(fmt.Sprintf("server.request_id.forward_headers[%d]", i), name)
+		if err != nil {
+			return err
+		}
+		forward = append(forward, header)
+	}
+	requestID.ForwardHeaders = forward
+	p.config.Server.RequestID = requestID
+	return nil
+}
+
+// requestIDHeader validates and canonicalizes a header name
+func requestIDHeader(field, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+		return "", fmt.Errorf("%s: invalid header name %q", field, name)
+	}
+	return textproto.CanonicalMIMEHeaderKey(name), nil
+}
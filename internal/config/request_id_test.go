@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRequestID(t *testing.T) {
+	cfg, err := ParseYAML([]byte("server:\n  listen: 3000\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	if cfg.Server.RequestID.Header != HeaderRequestID || len(cfg.Server.RequestID.ForwardHeaders) != 0 {
+		t.Errorf("default RequestID = %+v, want header %s", cfg.Server.RequestID, HeaderRequestID)
+	}
+
+	cfg, err = ParseYAML([]byte(`
+server:
+  request_id:
+    header: x-correlation-id
+    forward_headers: [" x-navigator-request-id"]
+logging:
+  tenant_file: /var/log/navigator/{{tenant}}.log
+  request_id_prefix: true
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	if got := cfg.Server.RequestID; got.Header != "X-Correlation-Id" || strings.Join(got.ForwardHeaders, ",") != "X-Navigator-Request-Id" {
+		t.Errorf("RequestID = %+v, want canonical header names", got)
+	}
+	if cfg.Logging.TenantFile != "/var/log/navigator/{{tenant}}.log" || !cfg.Logging.RequestIDPrefix {
+		t.Errorf("Logging = %+v", cfg.Logging)
+	}
+
+	for _, tc := range []struct{ yaml, want string }{
+		{"server:\n  request_id:\n    header: \"X Id\"\n", "server.request_id.header"},
+		{"server:\n  request_id:\n    forward_headers: [ok, \"bad:name\"]\n", "server.request_id.forward_headers[1]"},
+		{"logging:\n  tenant_file: /var/log/tenants.log\n", "must contain {{tenant}}"},
+	} {
+		if _, err := ParseYAML([]byte(tc.yaml)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ParseYAML(%q) error = %v, want %q", tc.yaml, err, tc.want)
+		}
+	}
+}
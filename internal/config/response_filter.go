@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseResponseFilters validates the response filters of reverse proxies and
+// tenants, compiles their replacements, and applies their defaults
+func (p *ConfigParser) parseResponseFilters() error {
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if err := parseResponseFilter(route.ResponseFilter); err != nil {
+			return fmt.Errorf("reverse proxy %q: %w", route.Name, err)
+		}
+	}
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := parseResponseFilter(tenant.ResponseFilter); err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant.Name, err)
+		}
+	}
+	return nil
+}
+
+func parseResponseFilter(filter *ResponseFilterConfig) error {
+	if filter == nil {
+		return nil
+	}
+	if (filter.Command == "") == (len(filter.Replace) == 0) {
+		return fmt.Errorf("response_filter needs either a command or replace rules")
+	}
+	for i := range filter.Replace {
+		rule := &filter.Replace[i]
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("response_filter.replace pattern %q: %w", rule.Pattern, err)
+		}
+		if rule.MaxReplacements < 0 {
+			return fmt.Errorf("response_filter.replace max_replacements must not be negative")
+		}
+		rule.Regex = regex
+	}
+	if filter.MaxBodyBytes < 0 || filter.Timeout < 0 {
+		return fmt.Errorf("response_filter.max_body_bytes and timeout must not be negative")
+	}
+
+	if len(filter.ContentTypes) == 0 {
+		filter.ContentTypes = []string{DefaultResponseFilterContentType}
+	}
+	for i, contentType := range filter.ContentTypes {
+		filter.ContentTypes[i] = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	if filter.MaxBodyBytes == 0 {
+		filter.MaxBodyBytes = DefaultResponseFilterMaxBodyBytes
+	}
+	if filter.Timeout == 0 {
+		filter.Timeout = Duration(DefaultResponseFilterTimeout)
+	}
+	switch filter.Encoded {
+	case "":
+		filter.Encoded = ResponseFilterEncodedSkip
+	case ResponseFilterEncodedSkip, ResponseFilterEncodedDecode:
+	default:
+		return fmt.Errorf("response_filter.encoded must be %q or %q, got %q",
+			ResponseFilterEncodedSkip, ResponseFilterEncodedDecode, filter.Encoded)
+	}
+	return nil
+}
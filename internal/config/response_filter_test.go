@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseResponseFilter(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /studios/boston/
+      response_filter:
+        replace:
+          - pattern: "</head>"
+            replacement: "<script src=/analytics.js></script></head>"
+            max_replacements: 1
+        content_types: [" Text/HTML "]
+        encoded: decode
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	filter := cfg.Applications.Tenants[0].ResponseFilter
+	if filter.Replace[0].Regex == nil || filter.ContentTypes[0] != "text/html" || filter.Encoded != ResponseFilterEncodedDecode {
+		t.Errorf("ResponseFilter = %+v", filter)
+	}
+	if filter.MaxBodyBytes != DefaultResponseFilterMaxBodyBytes || filter.Timeout.Std() != DefaultResponseFilterTimeout {
+		t.Errorf("MaxBodyBytes = %d, Timeout = %s; want defaults", filter.MaxBodyBytes, filter.Timeout)
+	}
+
+	cfg, err = ParseYAML([]byte("routes:\n  reverse_proxies:\n    - name: docs\n      prefix: /docs/\n      target: http://docs.internal\n      response_filter: {command: /usr/local/bin/rewrite, timeout: 2s}\n"))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if filter := cfg.Routes.ReverseProxies[0].ResponseFilter; filter.Timeout.Std() != 2*time.Second || filter.Encoded != ResponseFilterEncodedSkip || filter.ContentTypes[0] != DefaultResponseFilterContentType {
+		t.Errorf("Route response filter = %+v", filter)
+	}
+
+	for filter, wantErr := range map[string]string{
+		"{timeout: 1s}": "either a command or replace rules",
+		"{command: tidy, replace: [{pattern: a}]}":        "either a command or replace rules",
+		"{replace: [{pattern: '('}]}":                     "pattern",
+		"{replace: [{pattern: a, max_replacements: -1}]}": "must not be negative",
+		"{command: tidy, max_body_bytes: -1}":             "must not be negative",
+		"{command: tidy, encoded: brotli}":                "encoded must be",
+	} {
+		_, err := ParseYAML([]byte("applications:\n  tenants:\n    - path: /studios/boston/\n      response_filter: " + filter + "\n"))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("response_filter %s: error = %v, want %q", filter, err, wantErr)
+		}
+	}
+}
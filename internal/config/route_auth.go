@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseRouteAuth validates the auth settings of reverse proxies, CGI scripts,
+// and tenants. A required route must have credentials to check: auth.htpasswd,
+// or the auth scope it names.
+func (p *ConfigParser) parseRouteAuth() error {
+	for i := range p.config.Routes.ReverseProxies {
+		route := &p.config.Routes.ReverseProxies[i]
+		if err := p.parseAuthSetting(&route.Auth, route.AuthScope); err != nil {
+			return fmt.Errorf("reverse proxy %q: %w", route.Name, err)
+		}
+	}
+	for i := range p.config.Server.CGIScripts {
+		script := &p.config.Server.CGIScripts[i]
+		if err := p.parseAuthSetting(&script.Auth, script.AuthScope); err != nil {
+			return fmt.Errorf("cgi script %q: %w", script.Path, err)
+		}
+	}
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		if err := p.parseAuthSetting(&tenant.Auth, tenant.AuthScope); err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *ConfigParser) parseAuthSetting(setting *string, scope string) error {
+	*setting = strings.ToLower(*setting)
+	switch *setting {
+	case "":
+		*setting = RouteAuthInherit
+	case RouteAuthInherit, RouteAuthPublic:
+	case RouteAuthRequired:
+		p.config.Auth.RequiredByRoutes = true
+		if scope != "" {
+			if !p.authRealmExists(scope) {
+				return fmt.Errorf("auth: required names auth_scope %q, but no auth scope has that realm", scope)
+			}
+			return nil
+		}
+		if p.config.Auth.HTPasswd == "" {
+			return fmt.Errorf("auth: required needs auth.htpasswd or an auth_scope")
+		}
+		return nil
+	default:
+		return fmt.Errorf("auth must be inherit, required, or public, got %q", *setting)
+	}
+	if scope != "" {
+		return fmt.Errorf("auth_scope applies only with auth: required")
+	}
+	return nil
+}
+
+// routesRequireAuth reports whether a reverse proxy, CGI script, or tenant
+// sets auth: required, so auth scopes are needed even when auth is disabled
+func (p *ConfigParser) routesRequireAuth() bool {
+	required := func(setting string) bool {
+		return strings.EqualFold(setting, RouteAuthRequired)
+	}
+	for _, route := range p.yamlConfig.Routes.ReverseProxies {
+		if required(route.Auth) {
+			return true
+		}
+	}
+	for _, script := range p.yamlConfig.Server.CGIScripts {
+		if required(script.Auth) {
+			return true
+		}
+	}
+	for _, tenant := range p.yamlConfig.Applications.Tenants {
+		if required(tenant.Auth) {
+			return true
+		}
+	}
+	return false
+}
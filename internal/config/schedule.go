@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ScheduledTask is a task Navigator runs whenever its cron expression
+// matches: an HTTP request, a command, or a tenant's lifecycle hooks
+type ScheduledTask struct {
+	Name                  string                `yaml:"name" schema:"required"`
+	Cron                  string                `yaml:"cron" schema:"required"`           // "minute hour day month weekday"
+	TimeZone              string                `yaml:"timezone"`                         // IANA time zone the cron expression is evaluated in (default: UTC)
+	Timeout               Duration              `yaml:"timeout"`                          // Limit on each run (default: 10m)
+	Overlap               string                `yaml:"overlap" schema:"enum=skip|allow"` // Whether a run starts while the previous one is still running (default: skip)
+	SkipDuringMaintenance bool                  `yaml:"skip_during_maintenance"`          // Don't run while the server (or the task's tenant) is in maintenance
+	Tenant                string                `yaml:"tenant"`                           // Tenant whose path, env, or hooks the task uses
+	Hook                  string                `yaml:"hook" schema:"enum=start|stop"`    // Run the tenant's start or stop hooks
+	HTTP                  *ScheduledHTTPRequest `yaml:"http"`                             // Send a request through Navigator
+	Command               *HookConfig           `yaml:"command"`                          // Run a command, like a hook
+
+	schedule *cronSchedule
+}
+
+// ScheduledHTTPRequest is a request a scheduled task sends to Navigator's
+// own listener, so it reaches the tenant just as a visitor's request would
+type ScheduledHTTPRequest struct {
+	Path       string `yaml:"path" schema:"required"` // Absolute, or relative to the tenant's path
+	Method     string `yaml:"method"`                 // Default: GET
+	BypassAuth bool   `yaml:"bypass_auth"`            // Skip authentication for this request
+}
+
+// Next returns the first minute after t at which the task runs, or zero if
+// it never does
+func (t *ScheduledTask) Next(after time.Time) time.Time {
+	if t.schedule == nil {
+		return time.Time{}
+	}
+	return t.schedule.next(after)
+}
+
+// parseSchedule validates the scheduled tasks, applies their defaults, and
+// compiles their cron expressions
+func (p *ConfigParser) parseSchedule() error {
+	p.config.Schedule = p.yamlConfig.Schedule
+	names := make(map[string]bool)
+	for i := range p.config.Schedule {
+		task := &p.config.Schedule[i]
+		if task.Name == "" {
+			return fmt.Errorf("schedule entry %d: name is required", i+1)
+		}
+		if names[task.Name] {
+			return fmt.Errorf("schedule %q: name is used twice", task.Name)
+		}
+		names[task.Name] = true
+		if err := p.compileScheduledTask(task); err != nil {
+			return fmt.Errorf("schedule %q: %w", task.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *ConfigParser) compileScheduledTask(task *ScheduledTask) error {
+	location := time.UTC
+	if task.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(task.TimeZone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", task.TimeZone, err)
+		}
+	}
+	schedule, err := parseCronSchedule(task.Cron, location)
+	if err != nil {
+		return err
+	}
+	task.schedule = schedule
+
+	if task.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if task.Timeout == 0 {
+		task.Timeout = Duration(DefaultScheduleTimeout)
+	}
+	switch task.Overlap {
+	case "":
+		task.Overlap = ScheduleOverlapSkip
+	case ScheduleOverlapSkip, ScheduleOverlapAllow:
+	default:
+		return fmt.Errorf("overlap must be %s or %s, got %q", ScheduleOverlapSkip, ScheduleOverlapAllow, task.Overlap)
+	}
+
+	var tenant *Tenant
+	if task.Tenant != "" {
+		if tenant = p.config.TenantByName(task.Tenant); tenant == nil {
+			return fmt.Errorf("no tenant is named %q", task.Tenant)
+		}
+	}
+
+	kinds := 0
+	for _, set := range []bool{task.HTTP != nil, task.Command != nil, task.Hook != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("needs exactly one of http, command, or hook")
+	}
+
+	switch {
+	case task.HTTP != nil:
+		request := task.HTTP
+		if request.Path == "" {
+			return fmt.Errorf("http needs a path")
+		}
+		if !strings.HasPrefix(request.Path, "/") {
+			if tenant == nil {
+				return fmt.Errorf("http path %q is relative, so it needs a tenant", request.Path)
+			}
+			request.Path = strings.TrimSuffix(tenant.Path, "/") + "/" + request.Path
+		}
+		request.Method = strings.ToUpper(request.Method)
+		if request.Method == "" {
+			request.Method = http.MethodGet
+		}
+	case task.Command != nil:
+		if task.Command.Command == "" {
+			return fmt.Errorf("command needs a command")
+		}
+	default:
+		if task.Hook != ScheduleHookStart && task.Hook != ScheduleHookStop {
+			return fmt.Errorf("hook must be %s or %s, got %q", ScheduleHookStart, ScheduleHookStop, task.Hook)
+		}
+		if tenant == nil {
+			return fmt.Errorf("hook needs a tenant")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - name: boston
+      path: /showcase/2025/boston/
+schedule:
+  - name: digest
+    cron: "0 3 * * *"
+    timezone: America/New_York
+    tenant: boston
+    http:
+      path: admin/digest
+  - name: prune
+    cron: "30 4 * * sun"
+    overlap: allow
+    timeout: 1h
+    command:
+      command: find /data/uploads -mtime +30 -delete
+      shell: true
+  - name: warm
+    cron: "*/15 * * * *"
+    tenant: boston
+    hook: start
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if len(cfg.Schedule) != 3 {
+		t.Fatalf("Schedule = %+v, want 3 tasks", cfg.Schedule)
+	}
+
+	digest := cfg.Schedule[0]
+	if digest.HTTP.Path != "/showcase/2025/boston/admin/digest" || digest.HTTP.Method != "GET" {
+		t.Errorf("HTTP = %+v, want GET of the path under the tenant", digest.HTTP)
+	}
+	if digest.Timeout.Std() != DefaultScheduleTimeout || digest.Overlap != ScheduleOverlapSkip {
+		t.Errorf("Timeout = %v, Overlap = %q, want the defaults", digest.Timeout, digest.Overlap)
+	}
+	// 03:00 in New York is 08:00 UTC in January
+	next := digest.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("Next = %v, want %v", next, want)
+	}
+
+	if prune := cfg.Schedule[1]; prune.Overlap != ScheduleOverlapAllow || prune.Timeout.Std() != time.Hour {
+		t.Errorf("prune = %+v, want overlap allowed and a one hour timeout", prune)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tenants := "applications:\n  tenants:\n    - name: boston\n      path: /boston/\n"
+	tests := []struct {
+		name  string
+		yaml  string
+		error string
+	}{
+		{"no name", "schedule:\n  - cron: \"* * * * *\"\n    http: {path: /a}\n", "name is required"},
+		{"duplicate name", "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    http: {path: /a}\n  - name: a\n    cron: \"* * * * *\"\n    http: {path: /b}\n", "used twice"},
+		{"bad cron", "schedule:\n  - name: a\n    cron: \"* * *\"\n    http: {path: /a}\n", `schedule "a"`},
+		{"no task", "schedule:\n  - name: a\n    cron: \"* * * * *\"\n", "exactly one of"},
+		{"two tasks", tenants + "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    tenant: boston\n    hook: start\n    http: {path: /a}\n", "exactly one of"},
+		{"relative path without tenant", "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    http: {path: a}\n", "needs a tenant"},
+		{"unknown tenant", "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    tenant: nowhere\n    hook: start\n", "no tenant is named"},
+		{"hook without tenant", "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    hook: start\n", "hook needs a tenant"},
+		{"unknown hook", tenants + "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    tenant: boston\n    hook: idle\n", "hook must be"},
+		{"unknown overlap", "schedule:\n  - name: a\n    cron: \"* * * * *\"\n    overlap: queue\n    http: {path: /a}\n", "overlap must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseYAML([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.error)
+			}
+		})
+	}
+}
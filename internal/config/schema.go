@@ -0,0 +1,123 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// durationPattern matches the durations ParseDuration accepts
+const durationPattern = `^$|^0$|^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h|d|w|y))+$`
+
+// Schema returns a JSON Schema for navigator.yml, generated from the YAML
+// configuration structs so editors can validate a file as it's typed. Keys
+// come from yaml tags; a schema tag refines a field:
+//
+//	schema:"enum=suspend|stop"    the allowed values
+//	schema:"type=integer|string"  the JSON types of an interface{} field
+//	schema:"required"             the key must be present
+//
+// Options are separated by commas.
+func Schema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(YAMLConfig{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Navigator configuration"
+	return schema
+}
+
+// schemaFor returns the schema of values decoded into t, refined by a
+// field's schema tag; the tag of a slice or map field applies to its values
+func schemaFor(t reflect.Type, tag string) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	options := schemaOptions(tag)
+	if values, ok := options["enum"]; ok && t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
+		enum := []interface{}{}
+		for _, value := range strings.Split(values, "|") {
+			enum = append(enum, value)
+		}
+		return map[string]interface{}{"enum": enum}
+	}
+
+	if t == durationType {
+		return map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string", "pattern": durationPattern},
+				map[string]interface{}{"const": 0},
+			},
+			"description": `Duration such as "30s", "5m", "2d", or "1w"`,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), tag)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), tag)}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		var required []string
+		addProperties(t, properties, &required)
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	// interface{}: any value, unless the tag names its types
+	if types, ok := options["type"]; ok {
+		return map[string]interface{}{"type": strings.Split(types, "|")}
+	}
+	return map[string]interface{}{}
+}
+
+// addProperties adds the keys a struct decodes, including those of inlined
+// structs, to properties
+func addProperties(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options := yamlTag(field)
+		switch {
+		case name == "-":
+			continue
+		case options == "inline" && field.Type.Kind() == reflect.Struct:
+			addProperties(field.Type, properties, required)
+			continue
+		}
+		tag := field.Tag.Get("schema")
+		properties[name] = schemaFor(field.Type, tag)
+		if _, ok := schemaOptions(tag)["required"]; ok {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaOptions parses a schema tag into its options
+func schemaOptions(tag string) map[string]string {
+	options := map[string]string{}
+	for _, option := range strings.Split(tag, ",") {
+		if option == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(option, "=")
+		options[name] = value
+	}
+	return options
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+// parseSendfile validates applications.sendfile, which tenants without their
+// own sendfile settings inherit
+func (p *ConfigParser) parseSendfile() error {
+	apps := &p.config.Applications
+	if apps.Sendfile != nil {
+		if err := parseSendfileConfig(apps.Sendfile); err != nil {
+			return fmt.Errorf("applications.sendfile: %w", err)
+		}
+	}
+	for i := range apps.Tenants {
+		tenant := &apps.Tenants[i]
+		if tenant.Sendfile == nil {
+			tenant.Sendfile = apps.Sendfile
+			continue
+		}
+		if err := parseSendfileConfig(tenant.Sendfile); err != nil {
+			return fmt.Errorf("tenant %q: sendfile: %w", tenant.Name, err)
+		}
+	}
+	return nil
+}
+
+func parseSendfileConfig(sendfile *SendfileConfig) error {
+	if sendfile.Header == "" {
+		sendfile.Header = DefaultSendfileHeader
+	}
+	sendfile.Header = textproto.CanonicalMIMEHeaderKey(sendfile.Header)
+
+	if len(sendfile.Roots) == 0 && len(sendfile.Locations) == 0 {
+		return fmt.Errorf("requires roots or locations")
+	}
+	for i, root := range sendfile.Roots {
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("root %q must be an absolute path", root)
+		}
+		sendfile.Roots[i] = filepath.Clean(root)
+	}
+
+	locations := make(map[string]string, len(sendfile.Locations))
+	for prefix, dir := range sendfile.Locations {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("location %q must start with /", prefix)
+		}
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("location %q must map to an absolute path, got %q", prefix, dir)
+		}
+		locations[normalizePathWithTrailingSlash(prefix)] = filepath.Clean(dir)
+	}
+	sendfile.Locations = locations
+	return nil
+}
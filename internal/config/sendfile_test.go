@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSendfile(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  sendfile:
+    roots: [/rails/storage/]
+  tenants:
+    - name: boston
+      path: /boston/
+    - name: raleigh
+      path: /raleigh/
+      sendfile:
+        header: x-accel-redirect
+        locations:
+          /protected: /rails/storage
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	boston := cfg.Applications.Tenants[0].Sendfile
+	if boston == nil || boston.Header != DefaultSendfileHeader || len(boston.Roots) != 1 || boston.Roots[0] != "/rails/storage" {
+		t.Errorf("boston Sendfile = %+v, want applications.sendfile", boston)
+	}
+	raleigh := cfg.Applications.Tenants[1].Sendfile
+	if raleigh == nil || raleigh.Header != "X-Accel-Redirect" || raleigh.Locations["/protected/"] != "/rails/storage" {
+		t.Errorf("raleigh Sendfile = %+v, want its own header and location", raleigh)
+	}
+
+	tests := []struct {
+		name     string
+		sendfile string
+		want     string
+	}{
+		{"nothing allowed", "{header: X-Sendfile}", "requires roots or locations"},
+		{"relative root", "{roots: [storage]}", "absolute path"},
+		{"relative location prefix", "{locations: {protected/: /rails/storage}}", "must start with /"},
+		{"relative location dir", "{locations: {/protected/: storage}}", "absolute path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte("applications:\n  sendfile: " + tt.sendfile + "\n"))
+			if err == nil || !strings.Contains(err.Error(), "applications.sendfile") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
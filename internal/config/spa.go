@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseSPA validates the single-page applications, applies their defaults,
+// and gives their assets a long cache lifetime unless an override for the
+// same path already sets one
+func (p *ConfigParser) parseSPA() error {
+	static := &p.config.Server.Static
+	static.SPA = nil
+	for _, spa := range p.yamlConfig.Server.Static.SPA {
+		if !strings.HasPrefix(spa.Path, "/") {
+			return fmt.Errorf("server.static.spa: path must start with /, got %q", spa.Path)
+		}
+		spa.Path = normalizePathWithTrailingSlash(spa.Path)
+		if spa.Fallback == "" {
+			spa.Fallback = spa.Path + DefaultSPAFallback
+		} else if !strings.HasPrefix(spa.Fallback, "/") {
+			return fmt.Errorf("server.static.spa %s: fallback must start with /, got %q", spa.Path, spa.Fallback)
+		}
+
+		spa.ExcludePatterns = nil
+		for _, exclude := range spa.Exclude {
+			pattern, err := regexp.Compile(exclude)
+			if err != nil {
+				return fmt.Errorf("server.static.spa %s: invalid exclude pattern %q: %w", spa.Path, exclude, err)
+			}
+			spa.ExcludePatterns = append(spa.ExcludePatterns, pattern)
+		}
+
+		if spa.Assets != "" {
+			spa.Assets = normalizePathWithTrailingSlash(spa.Assets)
+			if !hasCacheControlOverride(static.CacheControl.Overrides, spa.Assets) {
+				maxAge := Duration(DefaultSPAAssetsMaxAge)
+				static.CacheControl.Overrides = append(static.CacheControl.Overrides, CacheControlOverride{
+					Path:      spa.Assets,
+					MaxAge:    &maxAge,
+					Immutable: true,
+				})
+			}
+		}
+		static.SPA = append(static.SPA, spa)
+	}
+	return nil
+}
+
+// hasCacheControlOverride reports whether overrides has one for path
+func hasCacheControlOverride(overrides []CacheControlOverride, path string) bool {
+	for _, override := range overrides {
+		if override.Path == path {
+			return true
+		}
+	}
+	return false
+}
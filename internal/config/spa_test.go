@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSPA(t *testing.T) {
+	config, err := ParseYAML([]byte(`
+server:
+  static:
+    cache_control:
+      overrides:
+        - path: /admin/assets/
+          max_age: 1h
+    spa:
+      - path: /dashboard
+        assets: /dashboard/assets
+        exclude: ["^/dashboard/api/"]
+      - path: /admin/
+        fallback: /admin/app.html
+        assets: /admin/assets/
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+
+	spas := config.Server.Static.SPA
+	if len(spas) != 2 {
+		t.Fatalf("Expected 2 SPAs, got %d", len(spas))
+	}
+	if spas[0].Path != "/dashboard/" || spas[0].Fallback != "/dashboard/index.html" {
+		t.Errorf("SPA = %+v, want the path normalized and the default fallback", spas[0])
+	}
+	if len(spas[0].ExcludePatterns) != 1 || !spas[0].ExcludePatterns[0].MatchString("/dashboard/api/users") {
+		t.Errorf("ExcludePatterns = %v", spas[0].ExcludePatterns)
+	}
+	if spas[1].Fallback != "/admin/app.html" {
+		t.Errorf("Fallback = %q, want /admin/app.html", spas[1].Fallback)
+	}
+
+	// Assets get a long lifetime unless an override for the path sets one
+	overrides := config.Server.Static.CacheControl.Overrides
+	if len(overrides) != 2 {
+		t.Fatalf("Overrides = %+v, want the configured one and one for /dashboard/assets/", overrides)
+	}
+	if overrides[0].MaxAge == nil || overrides[0].MaxAge.Std() != time.Hour {
+		t.Errorf("Configured override changed: %+v", overrides[0])
+	}
+	if assets := overrides[1]; assets.Path != "/dashboard/assets/" || assets.MaxAge == nil ||
+		assets.MaxAge.Std() != DefaultSPAAssetsMaxAge || !assets.Immutable {
+		t.Errorf("Assets override = %+v, want an immutable one for /dashboard/assets/", assets)
+	}
+}
+
+func TestParseSPAErrors(t *testing.T) {
+	tests := map[string]string{
+		"relative path":     "spa:\n      - path: dashboard\n",
+		"relative fallback": "spa:\n      - path: /dashboard/\n        fallback: index.html\n",
+		"invalid exclude":   "spa:\n      - path: /dashboard/\n        exclude: [\"(\"]\n",
+	}
+	for name, spa := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseYAML([]byte("server:\n  static:\n    " + spa)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
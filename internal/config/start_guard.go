@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// parseStartGuards applies defaults to each tenant's start_guard and rejects
+// guards that can't be acquired
+func (p *ConfigParser) parseStartGuards() error {
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		guard := tenant.StartGuard
+		if guard == nil {
+			continue
+		}
+		if tenant.Standby {
+			return fmt.Errorf("tenant %q: start_guard can't be combined with standby, which runs two instances", tenant.Name)
+		}
+		guard.Type = strings.ToLower(guard.Type)
+		if guard.Type == "" {
+			guard.Type = StartGuardFile
+			if guard.URL != "" {
+				guard.Type = StartGuardHTTP
+			}
+		}
+		switch guard.Type {
+		case StartGuardFile:
+			if guard.Path == "" {
+				guard.Path = DefaultStartGuardPath
+			}
+			if !filepath.IsAbs(guard.Path) && tenant.Root == "" {
+				return fmt.Errorf("tenant %q start_guard: relative path %q needs a tenant root", tenant.Name, guard.Path)
+			}
+		case StartGuardHTTP:
+			target, err := url.Parse(guard.URL)
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+				return fmt.Errorf("tenant %q start_guard: url must be an http or https URL, got %q", tenant.Name, guard.URL)
+			}
+			if guard.TTL <= 0 {
+				guard.TTL = Duration(DefaultStartGuardTTL)
+			}
+		default:
+			return fmt.Errorf("tenant %q start_guard: unknown type %q (use %s or %s)",
+				tenant.Name, guard.Type, StartGuardFile, StartGuardHTTP)
+		}
+	}
+	return nil
+}
+
+// LockPath returns the file guard's lock file for a tenant rooted at root
+func (g *StartGuardConfig) LockPath(root string) string {
+	path := g.Path
+	if path == "" {
+		path = DefaultStartGuardPath
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// ResolvedTTL returns the http guard's lease duration as a time.Duration
+func (g *StartGuardConfig) ResolvedTTL() time.Duration {
+	if g.TTL <= 0 {
+		return DefaultStartGuardTTL
+	}
+	return time.Duration(g.TTL)
+}
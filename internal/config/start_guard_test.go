@@ -0,0 +1,52 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigParser_ParseStartGuards(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      root: /rails
+      start_guard: {}
+    - path: /showcase/2025/raleigh/
+      start_guard:
+        url: https://leases.internal/tenants/raleigh
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	boston := cfg.Applications.Tenants[0].StartGuard
+	if boston.Type != StartGuardFile || boston.LockPath("/rails") != filepath.Join("/rails", "tmp/navigator.lock") {
+		t.Errorf("File guard = %+v, want the default lock file under the root", boston)
+	}
+	raleigh := cfg.Applications.Tenants[1].StartGuard
+	if raleigh.Type != StartGuardHTTP || raleigh.ResolvedTTL() != 30*time.Second {
+		t.Errorf("Lease guard = %+v, want http with the default ttl", raleigh)
+	}
+
+	for _, tt := range []struct {
+		guard string
+		err   string
+	}{
+		{`{type: redis}`, "unknown type"},
+		{`{type: http}`, "url must be"},
+		{`{url: "ftp://leases.internal/"}`, "url must be"},
+		{`{path: tmp/boston.lock}`, "needs a tenant root"},
+	} {
+		_, err := ParseYAML([]byte("applications:\n  tenants:\n    - path: /boston/\n      start_guard: " + tt.guard + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("start_guard %s: error = %v, want %q", tt.guard, err, tt.err)
+		}
+	}
+
+	_, err = ParseYAML([]byte("applications:\n  tenants:\n    - path: /boston/\n      root: /rails\n      standby: true\n      start_guard: {}\n"))
+	if err == nil || !strings.Contains(err.Error(), "standby") {
+		t.Errorf("start_guard with standby: error = %v, want it rejected", err)
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// strictConfig makes every load reject unknown keys, as --strict-config asks
+var strictConfig atomic.Bool
+
+// SetStrict makes every configuration load reject unknown keys, as if each
+// file set strict: true
+func SetStrict(strict bool) {
+	strictConfig.Store(strict)
+}
+
+// unknownKeys walks a parsed YAML document alongside the type it will be
+// decoded into and reports every key the decoder would silently drop, with
+// its field path and position, and the known key it most resembles
+func unknownKeys(node *yaml.Node, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		var errs []string
+		for _, child := range node.Content {
+			errs = append(errs, unknownKeys(child, t, path)...)
+		}
+		return errs
+	case yaml.AliasNode:
+		if node.Alias == nil {
+			return nil
+		}
+		return unknownKeys(node.Alias, t, path)
+	}
+
+	var errs []string
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode || t == durationType {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, value := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			if key == "<<" {
+				// Merge keys bring in another mapping's keys
+				errs = append(errs, unknownKeys(value, t, path)...)
+				continue
+			}
+			field, ok := yamlField(t, key)
+			if !ok {
+				message := fmt.Sprintf("%s: unknown key (line %d, column %d)", joinFieldPath(path, key), keyNode.Line, keyNode.Column)
+				if suggestion := closestKey(t, key); suggestion != "" {
+					message += fmt.Sprintf("; did you mean %q?", suggestion)
+				}
+				errs = append(errs, message)
+				continue
+			}
+			errs = append(errs, unknownKeys(value, field.Type, joinFieldPath(path, key))...)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			errs = append(errs, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			errs = append(errs, unknownKeys(node.Content[i+1], t.Elem(), joinFieldPath(path, key))...)
+		}
+	}
+	return errs
+}
+
+// closestKey returns the key of t within two edits of key, if there is one
+func closestKey(t reflect.Type, key string) string {
+	best, bestDistance := "", 3
+	for _, name := range yamlKeys(t) {
+		if distance := editDistance(key, name); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// yamlKeys lists the keys a struct decodes, including those of inlined structs
+func yamlKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options := yamlTag(field)
+		switch {
+		case name == "-":
+		case options == "inline" && field.Type.Kind() == reflect.Struct:
+			keys = append(keys, yamlKeys(field.Type)...)
+		default:
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// editDistance is the Levenshtein distance between two keys
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const strictTestConfig = `
+server:
+  listen: 3000
+  static:
+    allowed_extentions: [css, js]
+applications:
+  tenants:
+    - path: /studios/boston/
+      startup_timout: 30s
+`
+
+func TestParseYAMLStrictRejectsUnknownKeys(t *testing.T) {
+	if _, err := ParseYAML([]byte(strictTestConfig)); err != nil {
+		t.Fatalf("Without strict mode unknown keys are ignored, got %v", err)
+	}
+
+	_, err := ParseYAML([]byte("strict: true\n" + strictTestConfig))
+	if err == nil {
+		t.Fatal("Expected unknown keys to be rejected")
+	}
+	for _, want := range []string{
+		`server.static.allowed_extentions: unknown key (line 6, column 5); did you mean "allowed_extensions"?`,
+		`applications.tenants[0].startup_timout: unknown key (line 10, column 7); did you mean "startup_timeout"?`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error %q missing %q", err, want)
+		}
+	}
+
+	SetStrict(true)
+	defer SetStrict(false)
+	if _, err := ParseYAML([]byte(strictTestConfig)); err == nil || !strings.Contains(err.Error(), "allowed_extentions") {
+		t.Errorf("SetStrict(true): got %v, want the unknown key rejected", err)
+	}
+
+	// Merge keys and keys far from any known one
+	_, err = ParseYAML([]byte(`
+defaults: &defaults
+  root: /rails
+  colour: blue
+applications:
+  tenants:
+    - <<: *defaults
+      path: /studios/boston/
+`))
+	if err == nil || !strings.Contains(err.Error(), "defaults: unknown key (line 2, column 1)") ||
+		!strings.Contains(err.Error(), "applications.tenants[0].colour: unknown key") {
+		t.Errorf("Got %v, want defaults and colour rejected", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("Got %v, want no suggestion", err)
+	}
+}
+
+func TestSchemaValidatesConfig(t *testing.T) {
+	schema := Schema()
+	// The schema must survive a round trip through JSON, as editors read it
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("json.Marshal(Schema()) error = %v", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal error = %v", err)
+	}
+
+	valid := `
+server:
+  listen: 3000
+  idle:
+    action: suspend
+    timeout: 20m
+  static:
+    allowed_extensions: [css, js]
+    precompressed:
+      enabled: true
+      encodings: [br, gzip]
+logging:
+  format: json
+  access:
+    format: text
+applications:
+  env:
+    RAILS_ENV: production
+  tenants:
+    - path: /studios/boston/
+      startup_timeout: 30s
+      var:
+        database: boston
+`
+	if err := validateSchema(decoded, yamlValue(t, valid), ""); err != nil {
+		t.Errorf("Valid config rejected: %v", err)
+	}
+	if _, err := ParseYAML([]byte(valid)); err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	for _, invalid := range []string{
+		strictTestConfig,
+		"server:\n  idle:\n    action: sleep\n",
+		"server:\n  idle:\n    timeout: 5 minutes\n",
+		"server:\n  listen: [3000]\n",
+		"applications:\n  tenants:\n    - root: /rails\n",
+	} {
+		if err := validateSchema(decoded, yamlValue(t, invalid), ""); err == nil {
+			t.Errorf("Invalid config accepted:\n%s", invalid)
+		}
+	}
+}
+
+// yamlValue decodes a YAML document into the generic values JSON produces
+func yamlValue(t *testing.T, content string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(content), &value); err != nil {
+		t.Fatalf("yaml.Unmarshal error = %v", err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("json.Marshal error = %v", err)
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("json.Unmarshal error = %v", err)
+	}
+	return value
+}
+
+// validateSchema checks value against the subset of JSON Schema that Schema
+// generates
+func validateSchema(schema, value interface{}, path string) error {
+	s := schema.(map[string]interface{})
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		for _, option := range anyOf {
+			if validateSchema(option, value, path) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v matches no alternative", path, value)
+	}
+	if constant, ok := s["const"]; ok && value != constant {
+		return fmt.Errorf("%s: %v is not %v", path, value, constant)
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		for _, allowed := range enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+
+	if types, ok := s["type"]; ok {
+		var names []interface{}
+		if list, ok := types.([]interface{}); ok {
+			names = list
+		} else {
+			names = []interface{}{types}
+		}
+		matched := false
+		for _, name := range names {
+			matched = matched || jsonType(value, name.(string))
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not %v", path, value, types)
+		}
+	}
+	if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value.(string)) {
+		return fmt.Errorf("%s: %q does not match %s", path, value, pattern)
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, key := range required {
+				if _, ok := v[key.(string)]; !ok {
+					return fmt.Errorf("%s: missing %s", path, key)
+				}
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		for key, item := range v {
+			property, ok := properties[key]
+			if !ok {
+				property = s["additionalProperties"]
+			}
+			switch property := property.(type) {
+			case bool:
+				if !property {
+					return fmt.Errorf("%s: unknown key %s", path, key)
+				}
+			case map[string]interface{}:
+				if err := validateSchema(property, item, joinFieldPath(path, key)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonType reports whether value has the named JSON Schema type
+func jsonType(value interface{}, name string) bool {
+	switch v := value.(type) {
+	case bool:
+		return name == "boolean"
+	case float64:
+		return name == "number" || (name == "integer" && v == float64(int64(v)))
+	case string:
+		return name == "string"
+	case []interface{}:
+		return name == "array"
+	case map[string]interface{}:
+		return name == "object"
+	}
+	return name == "null"
+}
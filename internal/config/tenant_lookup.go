@@ -0,0 +1,36 @@
+package config
+
+import "strings"
+
+// TenantByName returns the tenant with a name, or with it as its file-safe
+// name, as used in log and PID file names. Returns nil if there's none.
+func (c *Config) TenantByName(name string) *Tenant {
+	if name == "" {
+		return nil
+	}
+	for i := range c.Applications.Tenants {
+		if c.Applications.Tenants[i].Name == name {
+			return &c.Applications.Tenants[i]
+		}
+	}
+	for i := range c.Applications.Tenants {
+		if c.Applications.Tenants[i].FileName() == name {
+			return &c.Applications.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// TenantForPath returns the tenant a request for path is routed to: the one
+// with the longest non-empty path prefixing it. Returns nil if no tenant
+// matches.
+func (c *Config) TenantForPath(path string) *Tenant {
+	var best *Tenant
+	for i := range c.Applications.Tenants {
+		tenant := &c.Applications.Tenants[i]
+		if tenant.Path != "" && strings.HasPrefix(path, tenant.Path) && (best == nil || len(tenant.Path) > len(best.Path)) {
+			best = tenant
+		}
+	}
+	return best
+}
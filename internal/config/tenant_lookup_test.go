@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestTenantForPath(t *testing.T) {
+	cfg := &Config{}
+	cfg.Applications.Tenants = []Tenant{
+		{Name: "unrouted"},
+		{Name: "showcase", Path: "/showcase/"},
+		{Name: "boston", Path: "/showcase/2025/boston/"},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/showcase/2025/boston/heats", "boston"},
+		{"/showcase/studios", "showcase"},
+		{"/showcase", ""},
+		{"/assets/app.css", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if tenant := cfg.TenantForPath(tt.path); tenant != nil {
+			got = tenant.Name
+		}
+		if got != tt.want {
+			t.Errorf("TenantForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTenantByName(t *testing.T) {
+	cfg := &Config{}
+	cfg.Applications.Tenants = []Tenant{
+		{Name: "2025/boston"},
+		{Name: "2025/raleigh"},
+		{Name: "2025-raleigh"},
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"2025/boston", "2025/boston"},
+		{"2025-boston", "2025/boston"},
+		{"2025-raleigh", "2025-raleigh"},
+		{"missing", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if tenant := cfg.TenantByName(tt.name); tenant != nil {
+			got = tenant.Name
+		}
+		if got != tt.want {
+			t.Errorf("TenantByName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
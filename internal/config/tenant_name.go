@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// unsafeNameChars matches runs of characters that aren't safe in file names
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// TenantFileName returns a tenant name in the form used for log files, PID
+// files and other per-tenant resources: runs of slashes and other characters
+// outside letters, digits, '.', '_' and '-' become a dash, and the result is
+// capped at MaxTenantNameLength characters (e.g., "2025/raleigh" ->
+// "2025-raleigh")
+func TenantFileName(name string) string {
+	safe := strings.Trim(unsafeNameChars.ReplaceAllString(name, "-"), "-.")
+	if len(safe) > MaxTenantNameLength {
+		safe = strings.TrimRight(safe[:MaxTenantNameLength], "-.")
+	}
+	return safe
+}
+
+// FileName returns the tenant's name in its file-safe form
+func (t *Tenant) FileName() string {
+	return TenantFileName(t.Name)
+}
+
+// checkTenantNames rejects explicit names that aren't already file-safe, and
+// tenants whose file-safe names collide, since they would share log and PID
+// files
+func (p *ConfigParser) checkTenantNames() error {
+	owners := make(map[string]string)
+	for i, yamlTenant := range p.yamlConfig.Applications.Tenants {
+		tenant := p.config.Applications.Tenants[i]
+		if yamlTenant.Name != "" && TenantFileName(yamlTenant.Name) != yamlTenant.Name {
+			return fmt.Errorf("tenant %q: name may only contain letters, digits, '.', '_' and '-', up to %d characters", yamlTenant.Name, MaxTenantNameLength)
+		}
+		name := tenant.FileName()
+		if owner, ok := owners[name]; ok {
+			return fmt.Errorf("tenants %s and %s both have the name %q; set name on one of them", owner, tenant.Path, name)
+		}
+		owners[name] = tenant.Path
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// parseTenantPorts checks tenants' pinned ports, which must lie in the pool
+// range and be claimed by nothing else, and may not be combined with standby,
+// whose second instance would need the same port, and applies the defaults of
+// applications.pools.port_state. Managed process ports in the pool range are
+// already refused by the port check.
+func (p *ConfigParser) parseTenantPorts() error {
+	pools := &p.config.Applications.Pools
+	start := pools.StartPort
+	if start == 0 {
+		start = DefaultStartPort
+	}
+	end := start + MaxPortRange
+
+	claimed := make(map[int]string)
+	claimed[listenPort(p.config.Server.Listen)] = "used as Navigator's listen port"
+	for _, tenant := range p.config.Applications.Tenants {
+		if tenant.Port == 0 {
+			continue
+		}
+		if tenant.Standby {
+			return fmt.Errorf("tenant %q: standby can't be combined with a pinned port", tenant.Name)
+		}
+		if tenant.Port < start || tenant.Port > end {
+			return fmt.Errorf("tenant %q: port %d is outside the tenant pool range %d-%d", tenant.Name, tenant.Port, start, end)
+		}
+		if owner, ok := claimed[tenant.Port]; ok {
+			return fmt.Errorf("tenant %q: port %d is already %s", tenant.Name, tenant.Port, owner)
+		}
+		claimed[tenant.Port] = fmt.Sprintf("pinned by tenant %q", tenant.Name)
+	}
+
+	if pools.PortState.File == "" {
+		pools.PortState.File = filepath.Join(filepath.Dir(p.config.Server.PIDFile), DefaultPortStateFile)
+	}
+	return nil
+}
+
+// PinnedPorts returns the tenant pinning each pinned port
+func (c *Config) PinnedPorts() map[int]string {
+	pinned := make(map[int]string)
+	for _, tenant := range c.Applications.Tenants {
+		if tenant.Port != 0 {
+			pinned[tenant.Port] = tenant.Name
+		}
+	}
+	return pinned
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTenantPorts(t *testing.T) {
+	cfg, err := ParseYAML([]byte(`
+server:
+  listen: 3000
+  pid_file: /run/navigator/navigator.pid
+applications:
+  pools:
+    start_port: 5000
+    port_state:
+      enabled: true
+  tenants:
+    - name: pinned
+      path: /pinned/
+      port: 5010
+    - name: dynamic
+      path: /dynamic/
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	if got := cfg.PinnedPorts(); len(got) != 1 || got[5010] != "pinned" {
+		t.Errorf("PinnedPorts() = %v", got)
+	}
+	if file := cfg.Applications.Pools.PortState.File; file != filepath.Join("/run/navigator", DefaultPortStateFile) {
+		t.Errorf("port_state.file = %q, want it next to the PID file", file)
+	}
+}
+
+func TestParseTenantPortsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants string
+		want    string
+	}{
+		{"below the pool", "port: 3999", "outside the tenant pool range 4000-4100"},
+		{"above the pool", "port: 4101", "outside the tenant pool range"},
+		{"listen port", "port: 4050", "used as Navigator's listen port"},
+		{"pinned twice", "port: 4010\n    - name: second\n      path: /second/\n      port: 4010", `pinned by tenant "first"`},
+		{"standby", "port: 4010\n      standby: true", "standby can't be combined with a pinned port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte(`
+server:
+  listen: 4050
+applications:
+  tenants:
+    - name: first
+      path: /first/
+      ` + tt.tenants + `
+`))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
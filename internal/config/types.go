@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net"
 	"regexp"
 	"sync"
 	"time"
@@ -39,15 +40,77 @@ type ManagedProcessConfig struct {
 	WorkingDir  string            `yaml:"working_dir"`
 	Env         map[string]string `yaml:"env"`
 	AutoRestart bool              `yaml:"auto_restart"`
-	StartDelay  string            `yaml:"start_delay"` // Duration string like "2s", "1m"
+	StartDelay  Duration          `yaml:"start_delay"`  // Duration like "2s", "1m"
+	Ports       []int             `yaml:"ports"`        // Ports this process listens on (checked for conflicts)
+	Group       string            `yaml:"group"`        // Processes in a group are stopped and started together on reload
+	ConfigFiles []string          `yaml:"config_files"` // Files whose changes trigger a restart or the group's reload_signal
+}
+
+// ManagedProcessGroup configures how a group of managed processes reloads
+type ManagedProcessGroup struct {
+	Name         string `yaml:"name"`
+	ReloadSignal string `yaml:"reload_signal"` // e.g. "SIGHUP": sent instead of restarting when only config files changed
 }
 
 // RewriteRule represents a rewrite rule
 type RewriteRule struct {
 	Pattern     *regexp.Regexp
 	Replacement string
-	Flag        string   // redirect, last, fly-replay:region:status, etc.
-	Methods     []string // Allowed methods for this rule
+	Flag        string              // redirect, last, fly-replay:region:status, etc.
+	Methods     []string            // Allowed methods for this rule
+	Verify      *ReplayVerification // fly-replay only: probe the target before replaying (nil = never)
+	Conditions  []RewriteCondition  // All must match the request for the rule to apply
+}
+
+// RewriteCondition restricts a redirect or rewrite to matching requests. Each
+// condition tests one of Host, Header, Cookie, Query, or Method. Header,
+// cookie, and query conditions pass when the value is present, unless Equals
+// or Matches is given; with Present set to false they pass only when it is
+// missing. Header "Host" tests the request host.
+type RewriteCondition struct {
+	Host    string `yaml:"host"`    // Exact host, case-insensitive, ignoring any port
+	Header  string `yaml:"header"`  // Request header name
+	Cookie  string `yaml:"cookie"`  // Cookie name
+	Query   string `yaml:"query"`   // Query parameter name
+	Method  string `yaml:"method"`  // Exact request method, e.g. "GET"
+	Equals  string `yaml:"equals"`  // Exact value for header, cookie, or query conditions
+	Matches string `yaml:"matches"` // Regex for header, cookie, or query values
+	Present *bool  `yaml:"present"` // false = the header, cookie, or query parameter must be absent
+
+	Regexp *regexp.Regexp `yaml:"-"` // Compiled Matches
+}
+
+// String describes the condition for logs
+func (c RewriteCondition) String() string {
+	var subject string
+	switch {
+	case c.Host != "":
+		return "host=" + c.Host
+	case c.Method != "":
+		return "method=" + c.Method
+	case c.Header != "":
+		subject = "header " + c.Header
+	case c.Cookie != "":
+		subject = "cookie " + c.Cookie
+	default:
+		subject = "query " + c.Query
+	}
+	switch {
+	case c.Present != nil && !*c.Present:
+		return subject + " absent"
+	case c.Matches != "":
+		return subject + "~" + c.Matches
+	case c.Equals != "":
+		return subject + "=" + c.Equals
+	}
+	return subject + " present"
+}
+
+// ReplayVerification configures the reachability check made before a fly-replay
+type ReplayVerification struct {
+	TTL            time.Duration // How long a probe result is trusted before it is refreshed in the background
+	Port           int           // Port probed on the target's .internal address
+	FallbackRegion string        // Region to replay to when the target is unreachable ("" = handle locally)
 }
 
 // AuthPattern represents an auth exclusion pattern
@@ -58,21 +121,153 @@ type AuthPattern struct {
 
 // LogConfig represents logging configuration
 type LogConfig struct {
-	Format string `yaml:"format"` // "text" or "json"
-	File   string `yaml:"file"`   // Optional file output path (supports {{app}} template)
+	Format string          `yaml:"format" schema:"enum=text|json|pretty"` // "text", "json" or "pretty"; app and process output, and the default for app (default: pretty on a terminal, else text)
+	File   string          `yaml:"file"`                                  // Optional file output path for app and process output (supports {{app}} template)
+	App    AppLogConfig    `yaml:"app"`                                   // Navigator's own operational log
+	Access AccessLogConfig `yaml:"access"`                                // HTTP access log
 	Vector struct {
 		Enabled bool   `yaml:"enabled"` // Enable Vector integration
 		Socket  string `yaml:"socket"`  // Unix socket path for Vector
 		Config  string `yaml:"config"`  // Path to vector.toml configuration
 	} `yaml:"vector"`
+	Capture        CaptureConfig        `yaml:"capture"`         // Request/response body capture for debugging
+	FlightRecorder FlightRecorderConfig `yaml:"flight_recorder"` // Requests kept on disk when they fail
+	Limits         LogLimitsConfig      `yaml:"limits"`          // Protection against runaway process output
+
+	// Grouping of app and process output into log entries
+	Multiline       MultilineConfig `yaml:"multiline"`        // Fold continuation lines such as stack traces into one entry
+	JSONPassthrough bool            `yaml:"json_passthrough"` // Merge the fields of JSON lines into Navigator's JSON entry
+
+	// Tenant output, kept apart and correlated with the access log
+	TenantFile      string `yaml:"tenant_file"`       // File each tenant's output goes to instead of file ({{tenant}} is replaced with its name)
+	RequestIDPrefix bool   `yaml:"request_id_prefix"` // Mark each line of tenant output with the id of the latest request proxied to the tenant
+
+	DiskBudget DiskBudgetConfig `yaml:"disk_budget"` // Bound on the disk space taken by log files
+}
+
+// DiskBudgetConfig bounds the disk space taken by log directories. When the
+// files in them outgrow max_bytes, rotated log files are deleted oldest
+// first, then the log files Navigator is writing are truncated; if that isn't
+// enough, file logging stops and falls back to stdout.
+type DiskBudgetConfig struct {
+	Directory   string   `yaml:"directory"`   // Shorthand for a single directory
+	Directories []string `yaml:"directories"` // Directories whose files count toward the budget (default: those of the configured log files)
+	MaxBytes    int64    `yaml:"max_bytes"`   // Total size of the files in the directories (0 = no budget)
+	Patterns    []string `yaml:"patterns"`    // Names of the log files the janitor may delete or truncate (default: *.log*)
+	Interval    Duration `yaml:"interval"`    // How often usage is measured (default: 1m)
+	WarnAt      []int    `yaml:"warn_at"`     // Percentages of max_bytes at which a warning is logged (default: 80, 90)
+}
+
+// AppLogConfig configures Navigator's own operational log
+type AppLogConfig struct {
+	Destination string `yaml:"destination"`                           // "stdout", "stderr", or a file path (default: stdout)
+	Format      string `yaml:"format" schema:"enum=text|json|pretty"` // "text", "json" or "pretty" (default: logging.format, else pretty on a terminal, else text)
+	Level       string `yaml:"level"`                                 // debug, info, warn, or error (default: LOG_LEVEL, else info)
+}
+
+// AccessLogConfig configures the HTTP access log
+type AccessLogConfig struct {
+	Destination  string   `yaml:"destination"`                           // Shorthand for a single destination
+	Destinations []string `yaml:"destinations"`                          // "stdout", "stderr", or file paths, all written (default: stdout)
+	Format       string   `yaml:"format" schema:"enum=json|text|pretty"` // "json", "text" (combined log format) or "pretty" (default: json, or pretty on a terminal unless logging.format is set)
+	SampleRate   *float64 `yaml:"sample_rate"`                           // Fraction of requests below 400 that are logged; 0 logs errors only (nil = 1)
+
+	ExcludeInternal bool `yaml:"exclude_internal"` // Don't log requests Navigator sends itself, such as warmers'
+
+	Sinks []AccessLogSinkConfig `yaml:"sinks"` // Outputs with their own format and filter, in place of destinations
+}
+
+// AccessLogSinkConfig is one access log output, receiving the entries its
+// filter selects in its own format
+type AccessLogSinkConfig struct {
+	Destination string          `yaml:"destination" schema:"required"`         // "stdout", "stderr", or a file path
+	Format      string          `yaml:"format" schema:"enum=json|text|pretty"` // Entry format (default: logging.access.format)
+	Filter      AccessLogFilter `yaml:"filter"`                                // Entries written (default: every entry)
+}
+
+// AccessLogFilter selects access log entries; an entry must match every
+// field given
+type AccessLogFilter struct {
+	Status               []string `yaml:"status"`                 // Status codes or ranges, such as "404" or "400-599" (empty = any)
+	ResponseTypes        []string `yaml:"response_types"`         // Response types, such as proxy or static (empty = any)
+	ExcludeResponseTypes []string `yaml:"exclude_response_types"` // Response types never written
+	Paths                []string `yaml:"paths"`                  // Regex patterns matched against the request path (empty = any)
+	ExcludePaths         []string `yaml:"exclude_paths"`          // Regex patterns of request paths never written
+	Tenants              []string `yaml:"tenants"`                // Tenant names (empty = any, including requests without a tenant)
+
+	// Compiled filters (populated by the parser)
+	StatusRanges        []StatusRange    `yaml:"-"`
+	PathPatterns        []*regexp.Regexp `yaml:"-"`
+	ExcludePathPatterns []*regexp.Regexp `yaml:"-"`
+}
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min, Max int
+}
+
+// MultilineConfig folds lines that don't match Start into the entry before
+// them, so a stack trace is logged as one entry
+type MultilineConfig struct {
+	Start    string   `yaml:"start"`     // Regex matching the first line of an entry (empty = one line per entry)
+	MaxLines int      `yaml:"max_lines"` // Lines per entry before a new one is started (default: 500)
+	MaxBytes int      `yaml:"max_bytes"` // Bytes per entry before a new one is started (default: 65536)
+	Timeout  Duration `yaml:"timeout"`   // How long an incomplete entry waits for more output (default: 250ms)
+
+	// Compiled pattern (populated by the parser)
+	StartPattern *regexp.Regexp `yaml:"-"`
+}
+
+// LogLimitsConfig bounds the output accepted from each managed process or tenant
+type LogLimitsConfig struct {
+	MaxLineLength  int     `yaml:"max_line_length"`   // Truncate lines longer than this many bytes (0 = unlimited)
+	RateLimit      float64 `yaml:"rate_limit"`        // Lines per second per source before dropping (0 = unlimited)
+	Burst          int     `yaml:"burst"`             // Lines allowed above the rate in a burst (default: rate_limit)
+	MaxBytesPerDay int64   `yaml:"max_bytes_per_day"` // Total bytes per source per day (0 = unlimited)
+}
+
+// CaptureConfig represents request/response body capture for debugging specific routes
+type CaptureConfig struct {
+	Enabled         bool     `yaml:"enabled"`          // Enable body capture (default: false)
+	Path            string   `yaml:"path"`             // Regex pattern for request paths to capture
+	File            string   `yaml:"file"`             // File receiving capture entries as JSON lines
+	MaxBytes        int      `yaml:"max_bytes"`        // Maximum bytes captured per body (default: 4096)
+	ContentTypes    []string `yaml:"content_types"`    // Content-Type prefixes to capture (empty = all)
+	Redact          []string `yaml:"redact"`           // Regex patterns whose matches are replaced with [REDACTED]
+	AllowUnredacted bool     `yaml:"allow_unredacted"` // Permit capture without any redaction rules
+
+	// Compiled patterns (populated by the parser)
+	PathPattern    *regexp.Regexp   `yaml:"-"`
+	RedactPatterns []*regexp.Regexp `yaml:"-"`
+}
+
+// FlightRecorderConfig keeps the headers and the start of the body of each
+// request while it is in flight, and writes them to a bounded directory
+// only when the request fails with a 5xx or an upstream error
+type FlightRecorderConfig struct {
+	Enabled         bool     `yaml:"enabled"`          // Enable the flight recorder (default: false)
+	Directory       string   `yaml:"directory"`        // Directory entries are written to
+	MaxFiles        int      `yaml:"max_files"`        // Entries kept, oldest deleted first (default: 100)
+	MaxBytes        int64    `yaml:"max_bytes"`        // Total size of the entries kept (default: 10MB)
+	MaxBodyBytes    int      `yaml:"max_body_bytes"`   // Request body bytes kept per request (default: 4096)
+	Redact          []string `yaml:"redact"`           // Regex patterns whose matches are replaced with [REDACTED] (default: logging.capture.redact)
+	AllowUnredacted bool     `yaml:"allow_unredacted"` // Permit recording without any redaction rules
+
+	// Compiled patterns (populated by the parser)
+	RedactPatterns []*regexp.Regexp `yaml:"-"`
 }
 
 // HookConfig represents a hook command configuration
 type HookConfig struct {
-	Command      string   `yaml:"command"`
-	Args         []string `yaml:"args"`
-	Timeout      string   `yaml:"timeout"`       // Duration string like "30s", "5m", 0 for no timeout
-	ReloadConfig string   `yaml:"reload_config"` // Config file to reload after successful hook execution
+	Name            string            `yaml:"name"` // Name that tags the hook's log output (default: command base name)
+	Command         string            `yaml:"command"`
+	Args            []string          `yaml:"args"`
+	Env             map[string]string `yaml:"env"`               // Additional environment variables for this hook
+	Dir             string            `yaml:"dir"`               // Working directory (default: navigator's working directory)
+	Shell           bool              `yaml:"shell"`             // Run command through /bin/sh -c (args become $1, $2, ...)
+	ContinueOnError bool              `yaml:"continue_on_error"` // Keep running remaining hooks if this one fails
+	Timeout         Duration          `yaml:"timeout"`           // Duration like "30s", "5m", 0 for no timeout
+	ReloadConfig    string            `yaml:"reload_config"`     // Config file to reload after successful hook execution
 }
 
 // CGIScriptConfig represents a CGI script configuration
@@ -85,7 +280,14 @@ type CGIScriptConfig struct {
 	AllowedUsers []string          `yaml:"allowed_users"` // Usernames allowed to access this script (empty = all authenticated users)
 	Env          map[string]string `yaml:"env"`           // Additional environment variables
 	ReloadConfig string            `yaml:"reload_config"` // Config file to reload after successful script execution
-	Timeout      string            `yaml:"timeout"`       // Execution timeout (e.g., "30s", "5m") - 0 means no timeout
+	Timeout      Duration          `yaml:"timeout"`       // Execution timeout (e.g., "30s", "5m") - 0 means no timeout
+
+	IdleOutputTimeout Duration `yaml:"idle_output_timeout"` // Abort the script after this long without output (0 = never)
+
+	Auth      string `yaml:"auth" schema:"enum=inherit|required|public"` // "inherit" (default), "required" even when auth is disabled, or "public"
+	AuthScope string `yaml:"auth_scope"`                                 // Realm of the auth scope a required script checks (default: auth.htpasswd)
+
+	DecompressRequests *[]string `yaml:"decompress_requests"` // Override server.decompress_requests (nil = use it; [] = pass bodies through)
 }
 
 // ServerHooks represents server lifecycle hooks
@@ -94,6 +296,8 @@ type ServerHooks struct {
 	Ready  []HookConfig `yaml:"ready"`
 	Resume []HookConfig `yaml:"resume"`
 	Idle   []HookConfig `yaml:"idle"`
+
+	Events []EventHookConfig `yaml:"events"` // Destinations notified of lifecycle events
 }
 
 // TenantHooks represents tenant lifecycle hooks
@@ -102,37 +306,61 @@ type TenantHooks struct {
 	Stop  []HookConfig `yaml:"stop"`
 }
 
+// EventHookConfig represents a destination for lifecycle events. Each event is
+// delivered as a JSON payload, either POSTed to URL or written to the stdin of
+// Command.
+type EventHookConfig struct {
+	Name       string   `yaml:"name"`
+	URL        string   `yaml:"url"`         // Webhook receiving a JSON POST
+	Token      string   `yaml:"token"`       // Bearer token sent with webhook requests
+	Command    string   `yaml:"command"`     // Local command receiving the payload on stdin
+	Args       []string `yaml:"args"`        // Arguments for Command
+	Types      []string `yaml:"types"`       // Event types delivered to this destination (empty = all)
+	Timeout    Duration `yaml:"timeout"`     // Per-attempt timeout (default: 10s)
+	Retries    int      `yaml:"retries"`     // Additional attempts after a failed delivery (default: 0)
+	RetryDelay Duration `yaml:"retry_delay"` // Delay before the first retry, doubled for each retry after it (default: 1s)
+}
+
+// RouteRule is a redirect or rewrite from a path pattern
+type RouteRule struct {
+	From       string             `yaml:"from"`
+	To         string             `yaml:"to"`
+	Conditions []RewriteCondition `yaml:"conditions"` // Optional; all must match
+}
+
 // RoutesConfig represents routes configuration
 type RoutesConfig struct {
-	Redirects []struct {
-		From string `yaml:"from"`
-		To   string `yaml:"to"`
-	} `yaml:"redirects"`
-	Rewrites []struct {
-		From string `yaml:"from"`
-		To   string `yaml:"to"`
-	} `yaml:"rewrites"`
+	Redirects      []RouteRule  `yaml:"redirects"`
+	Rewrites       []RouteRule  `yaml:"rewrites"`
 	ReverseProxies []ProxyRoute `yaml:"reverse_proxies"`
 	Fly            struct {
-		Replay []struct {
-			Path   string `yaml:"path"`
-			App    string `yaml:"app"`
-			Region string `yaml:"region"`
-			Status int    `yaml:"status"`
-		} `yaml:"replay"`
+		Replay        []FlyReplayRoute `yaml:"replay"`
+		MaxReplayHops int              `yaml:"max_replay_hops"` // Replays a request may already have had before another is refused
 	} `yaml:"fly"`
 }
 
+// FlyReplayRoute represents a routes.fly.replay entry
+type FlyReplayRoute struct {
+	Path           string   `yaml:"path"`
+	App            string   `yaml:"app"`
+	Region         string   `yaml:"region"`
+	Status         int      `yaml:"status"`
+	VerifyTarget   bool     `yaml:"verify_target"`   // Probe the target before replaying
+	VerifyTTL      Duration `yaml:"verify_ttl"`      // How long probe results are cached (default 30s)
+	VerifyPort     int      `yaml:"verify_port"`     // Port to probe (default: server.listen port)
+	FallbackRegion string   `yaml:"fallback_region"` // Region to use when the target is unreachable
+}
+
 // CacheControlOverride represents cache control configuration for specific paths
 type CacheControlOverride struct {
-	Path      string `yaml:"path"`
-	MaxAge    string `yaml:"max_age"`   // Duration format: "24h", "1h", "1y"
-	Immutable bool   `yaml:"immutable"` // Add immutable directive for fingerprinted assets
+	Path      string    `yaml:"path"`
+	MaxAge    *Duration `yaml:"max_age"`   // e.g. "24h", "1h", "1y"; "0" always revalidates (nil = no Cache-Control header)
+	Immutable bool      `yaml:"immutable"` // Add immutable directive for fingerprinted assets
 }
 
 // CacheControl represents cache control configuration
 type CacheControl struct {
-	Default          string                 `yaml:"default"`           // Default cache duration
+	Default          *Duration              `yaml:"default"`           // Default cache duration (nil = no Cache-Control header)
 	DefaultImmutable bool                   `yaml:"default_immutable"` // Default immutable directive
 	Overrides        []CacheControlOverride `yaml:"overrides"`         // Path-specific overrides
 }
@@ -144,6 +372,18 @@ type AuthConfig struct {
 	HTPasswd     string        `yaml:"htpasswd"`
 	PublicPaths  []string      `yaml:"public_paths"`
 	AuthPatterns []AuthPattern `yaml:"auth_patterns"`
+	Scopes       []AuthScope   `yaml:"scopes"` // Path-scoped credentials, most specific first
+
+	RequiredByRoutes bool // A route, CGI script, or tenant sets auth: required, so credentials load even when auth is disabled
+}
+
+// AuthScope protects part of the site with its own htpasswd file and realm.
+// Paths and PublicPaths use the same syntax as auth.public_paths.
+type AuthScope struct {
+	Paths       []string `yaml:"paths"`
+	Realm       string   `yaml:"realm"`
+	HTPasswd    string   `yaml:"htpasswd"`
+	PublicPaths []string `yaml:"public_paths"` // Replaces auth.public_paths within the scope
 }
 
 // StaticConfig represents static file serving configuration
@@ -153,12 +393,89 @@ type StaticConfig struct {
 	TryFiles                 []string `yaml:"try_files"`
 	NormalizeTrailingSlashes bool     `yaml:"normalize_trailing_slashes"` // Automatically redirect paths without trailing slashes to include them
 	CacheControl             CacheControl
+	Precompressed            PrecompressedConfig `yaml:"precompressed"`
+	Uploads                  []UploadConfig      `yaml:"uploads"`
+	Source                   StaticSourceConfig  `yaml:"source"`
+	SPA                      []SPAConfig         `yaml:"spa"`
+
+	FingerprintPatterns []string         // Regexes of content-hashed file names, matched against the URL path
+	FingerprintMaxAge   Duration         // Cache lifetime of fingerprinted files, which are served as immutable
+	Fingerprints        []*regexp.Regexp // Compiled FingerprintPatterns
+
+	MIMETypes map[string]string // Content-Type by lowercase extension with its dot, overriding the built-in types
+	NoSniff   bool              // Send X-Content-Type-Options: nosniff with static files
+
+	RootUnavailable string // "unavailable" (503) or "not_found" while public_dir is missing or unreadable
+}
+
+// SPAConfig serves a single-page application's fallback file for requests
+// under Path that no static file answers, so its client-side router can
+// handle deep links
+type SPAConfig struct {
+	Path     string   `yaml:"path"`     // URL prefix of the application, e.g. "/dashboard/"
+	Fallback string   `yaml:"fallback"` // URL path of the file served instead (default: <path>index.html)
+	Exclude  []string `yaml:"exclude"`  // Regex patterns of paths left to later routing, e.g. API calls
+	Assets   string   `yaml:"assets"`   // URL prefix of hashed assets, cached for a year as immutable
+
+	// Compiled patterns (populated by the parser)
+	ExcludePatterns []*regexp.Regexp `yaml:"-"`
+}
+
+// StaticSourceConfig selects where public files are read from. Allowed
+// extensions, try_files, cache control, and precompressed sidecars apply to
+// every source.
+type StaticSourceConfig struct {
+	Type      string         `yaml:"type" schema:"enum=dir|archive|s3"` // "dir" (default: public_dir), "archive", or "s3"
+	Archive   string         `yaml:"archive"`                           // .tar, .tar.gz, .tar.zst, or .zip file (type: archive)
+	CacheSize int64          `yaml:"cache_size"`                        // Bytes of extracted or downloaded files kept in memory (default: 64MB)
+	S3        S3SourceConfig `yaml:"s3"`
+}
+
+// S3SourceConfig is an S3-compatible bucket holding public files. Requests are
+// signed with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (and
+// AWS_SESSION_TOKEN) from the environment when they are set.
+type S3SourceConfig struct {
+	Endpoint string   `yaml:"endpoint"` // e.g. "https://fly.storage.tigris.dev"
+	Bucket   string   `yaml:"bucket"`
+	Prefix   string   `yaml:"prefix"` // Key prefix prepended to every path, e.g. "releases/v42/"
+	Region   string   `yaml:"region"` // Signing region (default: "auto")
+	TTL      Duration `yaml:"ttl"`    // How long fetched objects and misses are cached (default: 5m)
+}
+
+// UploadConfig represents an authenticated area accepting PUT uploads. Files
+// are written to a temporary file and renamed into place when complete.
+type UploadConfig struct {
+	Path              string   `yaml:"path"`               // URL prefix accepting uploads
+	Dir               string   `yaml:"dir"`                // Target directory (default: public_dir plus path)
+	AllowedExtensions []string `yaml:"allowed_extensions"` // Extensions accepted (empty = all)
+	MaxFileSize       int64    `yaml:"max_file_size"`      // Largest accepted upload in bytes (default: 100MB)
+	AuthScope         string   `yaml:"auth_scope"`         // Realm of the auth scope whose credentials are required (default: the scope covering path)
+	AllowOverwrite    bool     `yaml:"allow_overwrite"`    // Replace existing files
+	WebDAV            bool     `yaml:"webdav"`             // Also accept DELETE and MKCOL
+}
+
+// PrecompressedConfig represents serving of precompressed sidecar files (e.g., app.js.br)
+type PrecompressedConfig struct {
+	Enabled   bool     `yaml:"enabled"`                              // Serve .br/.zst/.gz sidecars when the client accepts them
+	Encodings []string `yaml:"encodings" schema:"enum=br|zstd|gzip"` // Preference order (default: br, zstd, gzip)
 }
 
 // MaintenanceConfig represents maintenance page configuration
 type MaintenanceConfig struct {
-	Enabled bool   `yaml:"enabled"` // Enable maintenance mode (serve maintenance page for all requests)
-	Page    string `yaml:"page"`
+	Enabled bool                `yaml:"enabled"` // Enable maintenance mode (serve maintenance page for all requests)
+	Page    string              `yaml:"page"`
+	Windows []MaintenanceWindow `yaml:"windows"` // Scheduled maintenance, in effect while any window is open
+
+	state *maintenanceState // Cached window evaluation, shared by copies of the block
+}
+
+// StartGuardConfig keeps a tenant from running on two machines at once: the
+// guard must be acquired before the app starts and is held until it exits
+type StartGuardConfig struct {
+	Type string   `yaml:"type" schema:"enum=file|http"` // "file" (flock on Path) or "http" (lease from URL); default: file unless url is set
+	Path string   `yaml:"path"`                         // Lock file, relative to the tenant root (default: tmp/navigator.lock)
+	URL  string   `yaml:"url"`                          // Lease endpoint: PUT acquires and renews, DELETE releases
+	TTL  Duration `yaml:"ttl"`                          // Lease duration for http guards, renewed every third of it (default: 30s)
 }
 
 // BotDetectionConfig represents bot detection configuration
@@ -178,6 +495,10 @@ type CableConfig struct {
 type HealthCheckConfig struct {
 	Path     string               `yaml:"path"`     // Health check path (e.g., "/up")
 	Response *HealthCheckResponse `yaml:"response"` // Optional synthetic response (if nil, proxies to app)
+	Quiet    bool                 `yaml:"quiet"`    // Answer the synthetic response without logging, request IDs or idle tracking
+
+	DetailedPath string   `yaml:"detailed_path"` // JSON readiness endpoint with build info and config hash (e.g., "/_navigator/health")
+	DrainDelay   Duration `yaml:"drain_delay"`   // How long to report ready=false before the listener closes on shutdown
 }
 
 // HealthCheckResponse represents a synthetic health check response
@@ -190,30 +511,79 @@ type HealthCheckResponse struct {
 // Config represents the main configuration
 type Config struct {
 	Server struct {
-		Listen             string `yaml:"listen"`
-		Hostname           string `yaml:"hostname"`
-		RootPath           string `yaml:"root_path"`
-		TrustProxy         bool   `yaml:"trust_proxy"`         // Trust X-Forwarded-* headers from upstream proxy
-		DisableCompression bool   `yaml:"disable_compression"` // Disable automatic compression/decompression in reverse proxy
-		RewriteRules       []RewriteRule
-		Static             StaticConfig
-		BotDetection       BotDetectionConfig `yaml:"bot_detection"`
-		CGIScripts         []CGIScriptConfig  `yaml:"cgi_scripts"`
-		HealthCheck        HealthCheckConfig  `yaml:"health_check"`
-		Idle               struct {
-			Action  string `yaml:"action"`  // "suspend" or "stop"
-			Timeout string `yaml:"timeout"` // Duration string like "30s", "5m"
+		Listen              string `yaml:"listen"`
+		Hostname            string `yaml:"hostname"`
+		RootPath            string `yaml:"root_path"`
+		TrustProxy          bool   `yaml:"trust_proxy"`          // Trust X-Forwarded-* headers from upstream proxy
+		ForwardedPrecedence string `yaml:"forwarded_precedence"` // "forwarded" (default) or "x-forwarded" when both are present
+		EncodedSlashes      string `yaml:"encoded_slashes"`      // "decode" (default) or "reject" for %2F in request paths
+		Workers             int    `yaml:"workers"`              // Number of SO_REUSEPORT worker processes (0 or 1 = single process)
+		AcmeChallengeDir    string `yaml:"acme_challenge_dir"`   // Directory served at /.well-known/acme-challenge/
+		DisableCompression  bool   `yaml:"disable_compression"`  // Disable automatic compression/decompression in reverse proxy
+		MaxHeaderBytes      int    `yaml:"max_header_bytes"`     // Largest request header block the server reads (0 = Go default, 1MB)
+		ControlPath         string `yaml:"control_path"`         // Localhost-only API for pausing and resuming tenants (empty = disabled)
+		PIDFile             string `yaml:"pid_file"`             // Where the PID is written for "navigator -s reload" (default /tmp/navigator.pid)
+		RewriteRules        []RewriteRule
+		RequestHeaders      RequestHeadersConfig `yaml:"request_headers"`
+		RequestID           RequestIDConfig      `yaml:"request_id"`
+		Static              StaticConfig
+		BotDetection        BotDetectionConfig `yaml:"bot_detection"`
+		CGIScripts          []CGIScriptConfig  `yaml:"cgi_scripts"`
+		HealthCheck         HealthCheckConfig  `yaml:"health_check"`
+		ResponseCache       ResponseCacheStore `yaml:"response_cache"`
+		Diagnostics         DiagnosticsConfig  `yaml:"diagnostics"`
+		LoadShedding        LoadSheddingConfig `yaml:"load_shedding"`
+		Shutdown            ShutdownConfig     `yaml:"shutdown"`
+		Canonical           CanonicalConfig    `yaml:"canonical"`
+		Timeouts            TimeoutsConfig     `yaml:"timeouts"`
+		WellKnown           WellKnownConfig    `yaml:"well_known"`
+		Idle                struct {
+			Action              string   `yaml:"action"`                // "suspend" or "stop"
+			Timeout             Duration `yaml:"timeout"`               // Duration like "30s", "5m"
+			CountStaticRequests bool     `yaml:"count_static_requests"` // Static file requests reset the idle timer (default: true)
+			CountHealthChecks   bool     `yaml:"count_health_checks"`   // Health check requests reset the idle timer (default: false)
+
+			DeferFor []string `yaml:"defer_for"` // Background work that defers the idle action until it finishes (default: cgi, hooks, managed_process_restarts)
+
+			State IdleStateConfig `yaml:"state"` // Last activity kept across restarts
 		} `yaml:"idle"`
+
+		AbsoluteURI             string `yaml:"absolute_uri"`               // "normalize" (default) or "reject" for absolute-form request targets
+		AbsoluteURIHostMismatch string `yaml:"absolute_uri_host_mismatch"` // "reject" (default), "use_hostname", or "use_uri" when the URI's host isn't hostname
+
+		ErrorHistory int `yaml:"error_history"` // Recent errors kept per tenant for the control API (default: 50)
+
+		RegionHeaders bool `yaml:"region_headers"` // Send X-Navigator-Region and X-Navigator-Machine with every response
+
+		Repanic bool `yaml:"repanic"` // Raise a recovered panic again once it's logged, for development
+
+		DecompressRequests       []string `yaml:"decompress_requests"`         // Content-Encodings of request bodies decoded before routing: "gzip", "br"
+		MaxRequestBody           int64    `yaml:"max_request_body"`            // Largest request body in bytes, once decoded (0 = no limit)
+		MaxCompressedRequestBody int64    `yaml:"max_compressed_request_body"` // Largest body in bytes accepted for decoding (default: 1MB)
+		MaxDecompressedBody      int64    `yaml:"-"`                           // Largest decoded body: max_request_body, else DefaultMaxDecompressedRequestBody
+
+		Outbound *OutboundConfig `yaml:"outbound"` // How requests are written to reverse proxy targets (nil = Go's defaults)
+
+		ProxyProtocol        bool         `yaml:"proxy_protocol"`         // Read the client address from a PROXY protocol v1/v2 header on each connection
+		ProxyProtocolTrusted []string     `yaml:"proxy_protocol_trusted"` // Addresses or CIDRs allowed to connect (empty = any)
+		ProxyProtocolNets    []*net.IPNet `yaml:"-"`                      // Parsed proxy_protocol_trusted
 	} `yaml:"server"`
 	Cable               CableConfig
 	Auth                AuthConfig
 	Routes              RoutesConfig           `yaml:"routes"`
 	Applications        Applications           `yaml:"applications"`
 	ManagedProcesses    []ManagedProcessConfig `yaml:"managed_processes"`
+	ProcessGroups       []ManagedProcessGroup  `yaml:"managed_process_groups"`
 	Logging             LogConfig              `yaml:"logging"`
 	Hooks               ServerHooks            `yaml:"hooks"`
 	Maintenance         MaintenanceConfig      `yaml:"maintenance"`
+	Fly                 FlyConfig              `yaml:"fly"`
+	Proxy               OutboundProxyConfig    `yaml:"proxy"`
 	LocationConfigMutex sync.RWMutex
+
+	FileHash string // Hex SHA-256 of the file the configuration was loaded from
+
+	Schedule []ScheduledTask `yaml:"schedule"` // Tasks run at the times their cron expressions match
 }
 
 // Applications represents application configuration
@@ -227,18 +597,124 @@ type Applications struct {
 	Server          map[string]string   `yaml:"server"`           // Framework server commands
 	Args            map[string][]string `yaml:"args"`             // Framework command arguments
 	HealthCheck     string              `yaml:"health_check"`     // Default health check endpoint (e.g., "/up")
-	StartupTimeout  string              `yaml:"startup_timeout"`  // Default timeout before showing maintenance page (e.g., "5s")
+	StartupTimeout  Duration            `yaml:"startup_timeout"`  // Default timeout before showing maintenance page (e.g., "5s")
 	TrackWebSockets bool                `yaml:"track_websockets"` // Global default for WebSocket tracking (default: true)
+	Coalesce        CoalesceConfig      `yaml:"coalesce"`         // Share responses for identical requests to starting tenants
+	RestartOnCrash  bool                `yaml:"restart_on_crash"` // Restart a crashed tenant inline and retry idempotent requests
+
+	IdleWebSocketGrace   Duration `yaml:"idle_websocket_grace"`   // WebSockets without client data frames for this long don't keep a tenant running (0 = any open socket does)
+	CloseStaleWebSockets bool     `yaml:"close_stale_websockets"` // Close WebSockets once they are past idle_websocket_grace
+
+	Recycle               RecycleConfig `yaml:"recycle"`                 // Default recycle policy for tenants
+	MaxConcurrentRecycles int           `yaml:"max_concurrent_recycles"` // Tenants recycled at once (default: 1)
+
+	Sendfile *SendfileConfig `yaml:"sendfile"` // Default for tenants: serve files apps name in X-Sendfile (nil = pass the header through)
+}
+
+// SendfileConfig serves the file a tenant app names in an X-Sendfile or
+// X-Accel-Redirect response header from disk, in place of the app's empty
+// body, so large downloads don't tie up the app. Only files inside roots or
+// the directories of locations are served.
+type SendfileConfig struct {
+	Header    string            `yaml:"header"`    // Response header naming the file (default: X-Sendfile; X-Accel-Redirect for nginx-style apps)
+	Roots     []string          `yaml:"roots"`     // Directories a file named by its path must be in
+	Locations map[string]string `yaml:"locations"` // Internal URI prefix (e.g. /protected/) to the directory it maps to
+}
+
+// RecycleConfig restarts a tenant's app gracefully once it has served too
+// many requests, run too long, or grown too large: a replacement is started
+// on a new port, takes over routing, and the old instance is drained
+type RecycleConfig struct {
+	MaxRequests  int      `yaml:"max_requests"`  // Requests an instance serves (0 = no limit)
+	MaxLifetime  Duration `yaml:"max_lifetime"`  // Age of an instance (0 = no limit)
+	MaxMemory    string   `yaml:"max_memory"`    // Resident memory of the app's process, e.g. "512M" (Linux only)
+	DrainTimeout Duration `yaml:"drain_timeout"` // Time the old instance's requests get to finish (default: 30s)
+
+	MaxMemoryBytes int64 `yaml:"-"` // Parsed MaxMemory
+}
+
+// Enabled reports whether any recycle trigger is set
+func (r *RecycleConfig) Enabled() bool {
+	return r != nil && (r.MaxRequests > 0 || r.MaxLifetime > 0 || r.MaxMemory != "")
+}
+
+// WarmerConfig requests a path of a tenant periodically while its app is
+// running, keeping the app's caches hot
+type WarmerConfig struct {
+	Path     string   `yaml:"path"`      // Relative to the tenant's path, e.g. "/dashboard"
+	Interval Duration `yaml:"interval"`  // Time between requests (default: 5m)
+	KeepWarm bool     `yaml:"keep_warm"` // Count the requests as activity, so the app is never idle
+}
+
+// CoalesceConfig controls request coalescing for tenants that are starting.
+// Identical concurrent GET/HEAD requests are proxied once and the response is
+// copied to every waiting client.
+type CoalesceConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	MaxResponseSize  int64    `yaml:"max_response_size"` // Responses larger than this (bytes) are not shared
+	VaryHeaders      []string `yaml:"vary_headers"`      // Request headers that are part of the coalescing key
+	AllowCredentials bool     `yaml:"allow_credentials"` // Also coalesce requests with cookies or Authorization
 }
 
 // Pools represents application pool configuration
 type Pools struct {
-	MaxSize            int    `yaml:"max_size"`
-	Timeout            string `yaml:"timeout"` // Duration string like "5m", "10m"
-	StartPort          int    `yaml:"start_port"`
-	DefaultMemoryLimit string `yaml:"default_memory_limit"` // Default memory limit for tenants (e.g., "512M", "1G")
-	User               string `yaml:"user"`                 // Default user to run tenant processes as
-	Group              string `yaml:"group"`                // Default group to run tenant processes as
+	MaxSize            int      `yaml:"max_size"`
+	Timeout            Duration `yaml:"timeout"` // Duration like "5m", "10m"
+	StartPort          int      `yaml:"start_port"`
+	DefaultMemoryLimit string   `yaml:"default_memory_limit"` // Default memory limit for tenants (e.g., "512M", "1G")
+	User               string   `yaml:"user"`                 // Default user to run tenant processes as
+	Group              string   `yaml:"group"`                // Default group to run tenant processes as
+
+	MaxConcurrentStartups int `yaml:"max_concurrent_startups"` // Tenant apps booting at once; later starts wait in arrival order (default: 4)
+
+	ConcurrencyConfig `yaml:",inline"` // Default request concurrency limit for tenants
+
+	Priority *PriorityConfig `yaml:"priority"` // Default CPU, I/O, and OOM priority of tenant processes
+	Egress   *EgressConfig   `yaml:"egress"`   // Default outbound proxy environment of tenant processes
+
+	PortState PortStateConfig `yaml:"port_state"` // Ports of unpinned tenants kept across restarts
+}
+
+// PortStateConfig keeps the port each tenant without a pinned port last ran
+// on in a file, so a restarted tenant lands on the same port when it's free
+type PortStateConfig struct {
+	Enabled bool   `yaml:"enabled"` // Save and prefer tenants' last ports (default: false)
+	File    string `yaml:"file"`    // Where the ports are kept (default: navigator-ports.json next to pid_file)
+}
+
+// PriorityConfig sets the CPU, I/O, and OOM killer priority of a tenant's
+// process when it starts. Linux only; elsewhere it is ignored with a warning.
+type PriorityConfig struct {
+	Nice        *int   `yaml:"nice" json:"nice,omitempty"`                                                         // -20 (most CPU) to 19 (least)
+	IONiceClass string `yaml:"ionice_class" json:"ionice_class,omitempty" schema:"enum=realtime|best-effort|idle"` // I/O scheduling class
+	IONiceLevel *int   `yaml:"ionice_level" json:"ionice_level,omitempty"`                                         // 0 (most I/O) to 7 (least), for realtime and best-effort (default: 4)
+	OOMScoreAdj *int   `yaml:"oom_score_adj" json:"oom_score_adj,omitempty"`                                       // -1000 (never OOM killed) to 1000 (killed first)
+}
+
+// EgressConfig sets the outbound proxy environment of a tenant's app, so
+// only the tenants that must reach the internet through a proxy use one.
+// Each value is set in both the upper and lower case variable; a value left
+// empty is set empty, overriding Navigator's own environment.
+type EgressConfig struct {
+	HTTPProxy  *string `yaml:"http_proxy" json:"http_proxy,omitempty"`   // Proxy URL for http:// requests
+	HTTPSProxy *string `yaml:"https_proxy" json:"https_proxy,omitempty"` // Proxy URL for https:// requests
+	NoProxy    *string `yaml:"no_proxy" json:"no_proxy,omitempty"`       // Comma-separated hosts, domains, and CIDRs reached directly
+}
+
+// OutboundProxyConfig controls whether Navigator's own requests to tenants
+// and reverse proxy targets go through a proxy
+type OutboundProxyConfig struct {
+	UseEnvProxy bool `yaml:"use_env_proxy"` // Honor HTTP_PROXY, HTTPS_PROXY, and NO_PROXY (default: connect directly)
+}
+
+// ConcurrencyConfig limits how many requests are proxied to a tenant at once.
+// Requests beyond the limit wait for a slot in arrival order; requests beyond
+// the queue are rejected with 503.
+type ConcurrencyConfig struct {
+	MaxConcurrentRequests int      `yaml:"max_concurrent_requests"` // 0 = unlimited
+	QueueSize             int      `yaml:"queue_size"`              // Requests that may wait for a slot (default: 100)
+	QueueTimeout          Duration `yaml:"queue_timeout"`           // Longest wait for a slot before a 503 (default: 30s)
+	CountWebSockets       *bool    `yaml:"count_websockets"`        // WebSocket upgrades hold a slot while open (default: false)
 }
 
 // ProxyRoute represents a proxy route configuration
@@ -251,6 +727,310 @@ type ProxyRoute struct {
 	Headers         map[string]string `yaml:"headers"`          // Headers to add to outgoing request
 	ResponseHeaders map[string]string `yaml:"response_headers"` // Headers to add to response from upstream
 	WebSocket       bool              `yaml:"websocket"`        // Enable WebSocket support
+	MaxHeaderBytes  int               `yaml:"max_header_bytes"` // Limit on forwarded request headers (0 = server default)
+
+	// Set-Cookie rewriting for backends mounted under a prefix
+	CookiePathRewrite   map[string]string `yaml:"cookie_path_rewrite"`   // Backend cookie path prefix -> public prefix
+	CookieDomainRewrite map[string]string `yaml:"cookie_domain_rewrite"` // Backend cookie domain ("*" = any) -> public domain ("" = remove Domain)
+	CookieSecure        bool              `yaml:"cookie_secure"`         // Add Secure to every cookie
+	CookieSameSite      string            `yaml:"cookie_samesite"`       // Set SameSite on every cookie: "Lax", "Strict", or "None"
+
+	// Cache responses in memory (nil = never)
+	Cache *ResponseCacheConfig `yaml:"cache"`
+
+	// Take this route offline (nil = global maintenance only)
+	Maintenance *MaintenanceConfig `yaml:"maintenance"`
+
+	// Resolve the target host and refresh its addresses (nil = Go's default transport)
+	DNS *DNSConfig `yaml:"dns"`
+
+	// Alternative targets chosen by the Accept and Content-Type headers, in
+	// order; Target serves requests none of them match
+	Negotiate []NegotiatedTarget `yaml:"negotiate"`
+
+	// Copy a sample of requests to a secondary target (nil = never)
+	Mirror *MirrorConfig `yaml:"mirror"`
+
+	// Rewrite response bodies, e.g. to inject a snippet (nil = never)
+	ResponseFilter *ResponseFilterConfig `yaml:"response_filter"`
+
+	// Authentication of this route over the site-wide setting
+	Auth      string `yaml:"auth" schema:"enum=inherit|required|public"` // "inherit" (default), "required" even when auth is disabled, or "public"
+	AuthScope string `yaml:"auth_scope"`                                 // Realm of the auth scope a required route checks (default: auth.htpasswd)
+
+	// Override server.decompress_requests (nil = use it; [] = pass bodies
+	// through to the target unchanged)
+	DecompressRequests *[]string `yaml:"decompress_requests"`
+
+	// Override server.outbound (nil = use it)
+	Outbound *OutboundConfig `yaml:"outbound"`
+}
+
+// NegotiatedTarget sends requests that prefer, or send, particular media
+// types to a different backend than a route's target or a tenant's app. A
+// request must meet every condition that's set.
+type NegotiatedTarget struct {
+	Path        string   `yaml:"path"`                     // Regex the request path must match (default: any path)
+	Accept      []string `yaml:"accept"`                   // Media types served; the Accept header must prefer one of them
+	ContentType []string `yaml:"content_type"`             // Request body media types, e.g. "application/json" or "multipart/*"
+	Target      string   `yaml:"target" schema:"required"` // Backend URL; $1, $2, ... are the route's capture groups
+
+	Pattern *regexp.Regexp `yaml:"-"` // Compiled Path (nil = any path)
+}
+
+// MirrorConfig copies a sample of requests to a secondary target, such as a
+// rewritten service being compared with the current one. Copies are sent in
+// the background and their responses discarded.
+type MirrorConfig struct {
+	Target        string   `yaml:"target" schema:"required"` // Secondary target URL; the request's path and query are appended
+	Percent       float64  `yaml:"percent"`                  // Share of eligible requests mirrored, 0-100 (default: 100)
+	Methods       []string `yaml:"methods"`                  // Methods mirrored (default: GET)
+	MaxConcurrent int      `yaml:"max_concurrent"`           // Mirrored requests in flight before more are skipped (default: 10)
+	MaxBodyBytes  int64    `yaml:"max_body_bytes"`           // Requests with larger bodies are skipped (default: 65536)
+	Timeout       Duration `yaml:"timeout"`                  // Limit on each mirrored request (default: 10s)
+}
+
+// ResponseFilterConfig rewrites the bodies of responses from a reverse proxy
+// route or tenant, through either an external command or a list of
+// replacements. Responses of other content types, larger than max_body_bytes,
+// or whose filter fails are served unmodified.
+type ResponseFilterConfig struct {
+	Command      string                `yaml:"command"`                           // Gets the body on stdin; its stdout replaces it
+	Args         []string              `yaml:"args"`                              // Arguments for command
+	Replace      []ResponseReplaceRule `yaml:"replace"`                           // Alternative to command: regex replacements applied in order
+	ContentTypes []string              `yaml:"content_types"`                     // Media types filtered, e.g. "text/*" (default: text/html)
+	MaxBodyBytes int64                 `yaml:"max_body_bytes"`                    // Larger responses are served unmodified (default: 1MB)
+	Timeout      Duration              `yaml:"timeout"`                           // Limit on command (default: 5s)
+	Encoded      string                `yaml:"encoded" schema:"enum=skip|decode"` // Compressed responses: "skip" (default) or "decode" gzip and filter
+}
+
+// ResponseReplaceRule replaces matches of a regex in a response body
+type ResponseReplaceRule struct {
+	Pattern         string `yaml:"pattern" schema:"required"` // Regex to find, e.g. "</head>"
+	Replacement     string `yaml:"replacement"`               // $1, $2, ... expand to capture groups
+	MaxReplacements int    `yaml:"max_replacements"`          // Matches replaced, from the start (0 = all)
+
+	Regex *regexp.Regexp `yaml:"-"` // Compiled Pattern
+}
+
+// DNSConfig controls how a reverse proxy route resolves its target host.
+// Connections go to the resolved addresses, which are refreshed in the
+// background every TTL; idle connections to addresses that disappear are
+// closed.
+type DNSConfig struct {
+	TTL           Duration `yaml:"ttl"`             // How long addresses are used before re-resolving (default: 30s)
+	Resolver      string   `yaml:"resolver"`        // "system" (default) or a DNS server address, e.g. "[fdaa::3]:53" on Fly
+	ResolveAtLoad bool     `yaml:"resolve_at_load"` // Fail the config load if the target host doesn't resolve
+
+	// Looks up the target host (populated by the parser from Resolver)
+	Lookup HostResolver `yaml:"-"`
+}
+
+// OutboundConfig adjusts how requests are written to a reverse proxy
+// route's target, for HTTP/1.1 backends that are strict about the bytes
+// they receive. Go's transport otherwise chooses header spelling, transfer
+// encoding, and Accept-Encoding, and resends some requests that fail on a
+// reused connection.
+type OutboundConfig struct {
+	HeaderCase             []string `yaml:"header_case"`              // Exact spellings of request headers, e.g. X-API-Key (default: canonical, X-Api-Key)
+	IdentityTransfer       bool     `yaml:"identity_transfer"`        // Send every body with Content-Length, never chunked, and no TE header
+	SuppressAcceptEncoding bool     `yaml:"suppress_accept_encoding"` // Don't add Accept-Encoding: gzip to requests without one
+	DisableRetries         bool     `yaml:"disable_retries"`          // Never resend a request on a new connection; also turns off keep-alive
+
+	MaxBufferedBody int64 `yaml:"-"` // Largest body of unknown length read to learn its length (populated by the parser)
+}
+
+// RequestHeadersConfig controls the request headers forwarded to tenants and
+// reverse proxy targets. Hop-by-hop headers and those listed in Strip are
+// removed from every client request; requests whose remaining headers exceed
+// MaxForwardedBytes are answered with 431.
+type RequestHeadersConfig struct {
+	MaxForwardedBytes int      `yaml:"max_forwarded_bytes"` // Total size of forwarded headers (0 = unlimited)
+	Strip             []string `yaml:"strip"`               // Additional headers clients may not send, e.g. internal secrets
+}
+
+// RequestIDConfig names the headers carrying each request's id. The id is
+// taken from Header when a client or upstream proxy sent one, else
+// generated, and sent to tenants and reverse proxy targets in Header,
+// X-Request-Id, and each of ForwardHeaders.
+type RequestIDConfig struct {
+	Header         string   `yaml:"header"`          // Header an incoming id is taken from (default: X-Request-Id)
+	ForwardHeaders []string `yaml:"forward_headers"` // More headers the id is sent in, e.g. one an app's log tags read
+}
+
+// ResponseCacheConfig enables in-memory caching of proxied responses for a
+// reverse proxy route or tenant. Only complete 200 responses to GET requests
+// without Set-Cookie are stored.
+type ResponseCacheConfig struct {
+	TTL                Duration `yaml:"ttl"`                  // How long a response is served from memory (default: 60s)
+	Paths              []string `yaml:"paths"`                // Tenants only: regex patterns for cacheable paths (empty = every path)
+	QueryParams        []string `yaml:"query_params"`         // Query parameters that are part of the key; others are ignored
+	VaryHeaders        []string `yaml:"vary_headers"`         // Request headers that are part of the key
+	MaxObjectSize      int64    `yaml:"max_object_size"`      // Responses larger than this (bytes) are not cached
+	HonorOriginHeaders bool     `yaml:"honor_origin_headers"` // Don't cache when the backend sends Cache-Control: no-store, no-cache or private
+	CacheAuthenticated bool     `yaml:"cache_authenticated"`  // Also cache requests with cookies or Authorization
+
+	// Compiled patterns (populated by the parser)
+	PathPatterns []*regexp.Regexp `yaml:"-"`
+}
+
+// ResponseCacheStore configures the memory shared by every response cache
+type ResponseCacheStore struct {
+	MaxMemory int64  `yaml:"max_memory"` // Total bytes of cached responses before least recently used are evicted
+	PurgePath string `yaml:"purge_path"` // Localhost-only endpoint for cache statistics and purging (empty = disabled)
+}
+
+// DiagnosticsConfig controls the diagnostic bundle written on SIGQUIT or SIGUSR1
+type DiagnosticsConfig struct {
+	Dir  string `yaml:"dir"`  // Directory receiving bundle files (default: <tmp>/navigator-diagnostics)
+	Path string `yaml:"path"` // Localhost-only endpoint returning the bundle as JSON (empty = disabled)
+
+	ExplainPath string `yaml:"explain_path"` // Localhost-only endpoint tracing how a URL would be routed (empty = disabled)
+}
+
+// LoadSheddingConfig rejects a class of requests while Navigator is short of
+// memory, file descriptors, or goroutines. Each threshold is disabled when 0.
+type LoadSheddingConfig struct {
+	Interval           Duration `yaml:"interval"`             // How often resources are sampled (default: 5s)
+	MaxRSS             int64    `yaml:"max_rss"`              // Bytes of Navigator's resident memory
+	MinAvailableMemory int64    `yaml:"min_available_memory"` // Bytes of system memory available (Linux only)
+	MaxOpenFDs         int      `yaml:"max_open_fds"`
+	MaxGoroutines      int      `yaml:"max_goroutines"`
+	Recovery           float64  `yaml:"recovery"`                                     // Fraction of each threshold readings must fall within before shedding stops (default: 0.9)
+	Shed               string   `yaml:"shed" schema:"enum=unauthenticated|paths|all"` // "unauthenticated" (default), "paths", or "all"
+	Paths              []string `yaml:"paths"`                                        // Path prefixes shed when shed is "paths"
+}
+
+// Enabled reports whether any threshold is set
+func (c LoadSheddingConfig) Enabled() bool {
+	return c.MaxRSS > 0 || c.MinAvailableMemory > 0 || c.MaxOpenFDs > 0 || c.MaxGoroutines > 0
+}
+
+// CanonicalConfig redirects requests to one host and scheme. Health checks,
+// ACME challenges, and localhost-only endpoints are answered before it.
+type CanonicalConfig struct {
+	Host         string   `yaml:"host"`                                       // Host requests are redirected to, e.g. "example.com" (empty = keep the host)
+	ForceHTTPS   bool     `yaml:"force_https"`                                // Redirect http requests to https
+	Redirect     string   `yaml:"redirect" schema:"enum=permanent|temporary"` // "permanent" (default) or "temporary"
+	ExcludePaths []string `yaml:"exclude_paths"`                              // Path prefixes never redirected
+	ExcludeHosts []string `yaml:"exclude_hosts"`                              // Hosts never redirected, e.g. "app.internal" or "*.internal"
+}
+
+// Enabled reports whether requests may be redirected
+func (c *CanonicalConfig) Enabled() bool {
+	return c.Host != "" || c.ForceHTTPS
+}
+
+// ForceHTTPSWarning explains why force_https is likely to redirect in a
+// loop, or returns "". Without trust_proxy the forwarded scheme is ignored,
+// so requests a TLS-terminating load balancer forwards over http are always
+// redirected.
+func (c *Config) ForceHTTPSWarning() string {
+	if !c.Server.Canonical.ForceHTTPS || c.Server.TrustProxy {
+		return ""
+	}
+	return "server.canonical.force_https is set without server.trust_proxy, so X-Forwarded-Proto is ignored; behind a TLS-terminating load balancer every request will be redirected to https again"
+}
+
+// WellKnownConfig serves robots.txt, security.txt, and other fixed files
+// from config, ahead of tenants and the public directory
+type WellKnownConfig struct {
+	RobotsTxt    *WellKnownFile      `yaml:"robots_txt"`    // Served at /robots.txt
+	SecurityTxt  *WellKnownFile      `yaml:"security_txt"`  // Served at /.well-known/security.txt
+	Files        []WellKnownFile     `yaml:"files"`         // Other files, each at its own path
+	CacheControl string              `yaml:"cache_control"` // Cache-Control for files that don't set one (default "public, max-age=3600")
+	Overrides    []WellKnownOverride `yaml:"overrides"`     // Other files for some hosts or tenants
+
+	Entries []WellKnownEntry `yaml:"-"` // Resolved files, host-specific ones first
+}
+
+// WellKnownFile is a file's content, inline or read from disk
+type WellKnownFile struct {
+	Path         string `yaml:"path"`          // Request path, e.g. "/.well-known/change-password" (files only)
+	Content      string `yaml:"content"`       // Body; ${VAR} and ${VAR:-fallback} expand from the environment
+	File         string `yaml:"file"`          // File the body is read from when the config loads
+	ContentType  string `yaml:"content_type"`  // Default: from the path's extension, else text/plain
+	CacheControl string `yaml:"cache_control"` // Overrides well_known.cache_control
+}
+
+// WellKnownOverride serves other files to requests for a host, or beneath
+// a tenant's path prefix
+type WellKnownOverride struct {
+	Host        string          `yaml:"host"`   // "staging.example.com" or "*.example.com" (empty = any)
+	Prefix      string          `yaml:"prefix"` // Tenant path prefix the files are served beneath, e.g. "/showcase/"
+	RobotsTxt   *WellKnownFile  `yaml:"robots_txt"`
+	SecurityTxt *WellKnownFile  `yaml:"security_txt"`
+	Files       []WellKnownFile `yaml:"files"`
+}
+
+// WellKnownEntry is a file ready to serve
+type WellKnownEntry struct {
+	Host         string // Empty matches any host
+	Path         string
+	Body         []byte
+	ContentType  string
+	CacheControl string
+}
+
+// ShutdownConfig controls how in-flight requests are drained on SIGTERM or SIGINT
+type ShutdownConfig struct {
+	Timeout         Duration `yaml:"timeout"`          // How long in-flight requests get to finish (default: 30s)
+	ImmediateSignal string   `yaml:"immediate_signal"` // "second" (default), "SIGINT", "SIGTERM", or "none"
+}
+
+// WorkerShutdownTimeout returns how long a supervisor waits for its workers
+// to exit before killing them: long enough for each to report not ready
+// for health_check.drain_delay, drain for shutdown.timeout, and then stop
+func (c *Config) WorkerShutdownTimeout() time.Duration {
+	return c.Server.HealthCheck.DrainDelay.Std() + c.Server.Shutdown.Timeout.OrDefault(DefaultShutdownTimeout) + WorkerShutdownGrace
+}
+
+// IdleStateConfig keeps the machine's and each tenant's last activity in a
+// file, so a restart doesn't start the idle timeout over for a machine that
+// had already been idle for a while
+type IdleStateConfig struct {
+	Enabled  bool     `yaml:"enabled"`  // Save and restore idle state (default: false)
+	File     string   `yaml:"file"`     // Where the state is kept (default: navigator-idle.json next to pid_file)
+	Interval Duration `yaml:"interval"` // How often the state is saved while running (default: 30s)
+	MaxAge   Duration `yaml:"max_age"`  // Saved activity older than this is ignored (default: 24h)
+}
+
+// FlyConfig configures Navigator's use of Fly.io platform APIs
+type FlyConfig struct {
+	MachinesAPI FlyMachinesAPIConfig `yaml:"machines_api"`
+}
+
+// FlyMachinesAPIConfig coordinates scale-to-zero with the app's other
+// machines through the Fly Machines API
+type FlyMachinesAPIConfig struct {
+	Enabled   bool             `yaml:"enabled"`
+	App       string           `yaml:"app"`        // Fly app whose machines are managed (default: FLY_APP_NAME)
+	TokenEnv  string           `yaml:"token_env"`  // Environment variable holding the API token (default: FLY_API_TOKEN)
+	AllowZero bool             `yaml:"allow_zero"` // Let the idle action stop the app's last running machine
+	ScaleUp   FlyScaleUpConfig `yaml:"scale_up"`
+}
+
+// FlyScaleUpConfig starts a sibling machine in another region while a
+// tenant's load stays high
+type FlyScaleUpConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Regions     []string `yaml:"regions"`       // Regions a sibling may run in, in order of preference
+	MaxInFlight int      `yaml:"max_in_flight"` // A tenant's in-flight requests that count as high load
+	Window      Duration `yaml:"window"`        // How long the load must stay high (default: 1m)
+	Interval    Duration `yaml:"interval"`      // How often tenants' load is sampled (default: 10s)
+	Cooldown    Duration `yaml:"cooldown"`      // Time after a scale-up before another (default: 10m)
+}
+
+// TimeoutsConfig bounds client connections. Read and write are applied to
+// each request as deadlines rather than to the whole connection, so
+// WebSocket and event-stream requests can be exempt from them.
+type TimeoutsConfig struct {
+	ReadHeader           Duration `yaml:"read_header"`            // Time a client gets to send request headers (default: 10s)
+	Read                 Duration `yaml:"read"`                   // Time a client gets to send a whole request, body included (0 = unlimited)
+	Write                Duration `yaml:"write"`                  // Time a response gets to be written, from the end of its headers (0 = unlimited)
+	Idle                 Duration `yaml:"idle"`                   // How long a keep-alive connection waits for its next request (default: 2m)
+	TCPKeepAlive         Duration `yaml:"tcp_keepalive"`          // Interval of SO_KEEPALIVE probes detecting dead clients (default: 15s)
+	MaxKeepaliveRequests int      `yaml:"max_keepalive_requests"` // Requests served on one connection before it is closed (0 = unlimited)
 }
 
 // WebApp represents a web application
@@ -278,6 +1058,7 @@ type FrameworkConfig struct {
 type Tenant struct {
 	Name            string                 `yaml:"name"`
 	Path            string                 `yaml:"path"` // URL path prefix for tenant matching
+	Port            int                    `yaml:"port"` // Port the app always runs on, within the pool range (0 = allocated)
 	Root            string                 `yaml:"root"`
 	PublicDir       string                 `yaml:"public_dir"`
 	Env             map[string]string      `yaml:"env"`
@@ -289,16 +1070,49 @@ type Tenant struct {
 	Var             map[string]interface{} `yaml:"var"`
 	Hooks           TenantHooks            `yaml:"hooks"`
 	HealthCheck     string                 `yaml:"health_check"`     // Override health check endpoint for this tenant
-	StartupTimeout  string                 `yaml:"startup_timeout"`  // Override startup timeout for this tenant (e.g., "10s")
+	StartupTimeout  Duration               `yaml:"startup_timeout"`  // Override startup timeout for this tenant (e.g., "10s")
 	TrackWebSockets *bool                  `yaml:"track_websockets"` // Override WebSocket tracking (nil = use global default)
+	RestartOnCrash  *bool                  `yaml:"restart_on_crash"` // Override inline restart after a crash (nil = use global default)
+	StopSignal      string                 `yaml:"stop_signal"`      // Signal that stops the app gracefully (default: the framework preset's, else kill)
 	BotDetection    *BotDetectionConfig    `yaml:"bot_detection"`    // Override bot detection for this tenant (nil = use global default)
 	MemoryLimit     string                 `yaml:"memory_limit"`     // Memory limit for this tenant (e.g., "512M", "1G") - Linux only
 	User            string                 `yaml:"user"`             // User to run this tenant's process as
 	Group           string                 `yaml:"group"`            // Group to run this tenant's process as
+	Cache           *ResponseCacheConfig   `yaml:"cache"`            // Cache selected responses in memory (nil = never)
+	Aliases         []string               `yaml:"aliases"`          // Additional path prefixes served by this tenant
+	AliasRedirect   bool                   `yaml:"alias_redirect"`   // Redirect aliases to Path (301) instead of serving them
+	MaxHeaderBytes  int                    `yaml:"max_header_bytes"` // Limit on forwarded request headers (0 = server default)
+	Maintenance     *MaintenanceConfig     `yaml:"maintenance"`      // Take this tenant offline (nil = global maintenance only)
+	StartGuard      *StartGuardConfig      `yaml:"start_guard"`      // Lock that must be held to run this tenant (nil = none)
+	Standby         bool                   `yaml:"standby"`          // Keep a warm second instance that takes over if the app fails
+	Negotiate       []NegotiatedTarget     `yaml:"negotiate"`        // Backends that serve requests for other media types instead of the app
+	Mirror          *MirrorConfig          `yaml:"mirror"`           // Copy a sample of requests to a secondary target (nil = never)
+	Priority        *PriorityConfig        `yaml:"priority"`         // CPU, I/O, and OOM priority of the app's process, over the pool defaults (nil = inherit Navigator's)
+	Egress          *EgressConfig          `yaml:"egress"`           // Outbound proxy environment of the app, over the pool defaults (nil = inherit Navigator's)
+
+	IdleWebSocketGrace   Duration `yaml:"idle_websocket_grace"`   // Override idle_websocket_grace (0 = use global default)
+	CloseStaleWebSockets *bool    `yaml:"close_stale_websockets"` // Override close_stale_websockets (nil = use global default)
+
+	ResponseFilter *ResponseFilterConfig `yaml:"response_filter"` // Rewrite response bodies (nil = never)
+
+	Auth      string `yaml:"auth"`       // "inherit" (default), "required" even when auth is disabled, or "public"
+	AuthScope string `yaml:"auth_scope"` // Realm of the auth scope a required tenant checks (default: auth.htpasswd)
+
+	Recycle *RecycleConfig `yaml:"recycle"` // Override applications.recycle (nil = use it)
+
+	DecompressRequests *[]string `yaml:"decompress_requests"` // Override server.decompress_requests (nil = use it; [] = pass bodies through)
+
+	Warmers []WarmerConfig `yaml:"warmers"` // Paths requested periodically while the app runs
+
+	Sendfile *SendfileConfig `yaml:"sendfile"` // Override applications.sendfile (nil = use it)
+
+	Concurrency ConcurrencyConfig `yaml:",inline"` // Effective limit: tenant settings over the pool defaults
 }
 
 // YAMLConfig represents the raw YAML configuration structure
 type YAMLConfig struct {
+	Strict bool `yaml:"strict"` // Reject unknown keys instead of ignoring them
+
 	Cable struct {
 		Enabled       *bool  `yaml:"enabled"` // Pointer to distinguish unset from false
 		Path          string `yaml:"path"`
@@ -313,81 +1127,135 @@ type YAMLConfig struct {
 			Pattern string `yaml:"pattern"`
 			Action  string `yaml:"action"`
 		} `yaml:"auth_patterns"`
+		Scopes []AuthScope `yaml:"scopes"`
 	} `yaml:"auth"`
 	Server struct {
-		Listen     interface{}       `yaml:"listen"`
-		Hostname   string            `yaml:"hostname"`
-		RootPath   string            `yaml:"root_path"`
-		TrustProxy bool              `yaml:"trust_proxy"`
-		CGIScripts []CGIScriptConfig `yaml:"cgi_scripts"`
-		Static     struct {
+		Listen              interface{}       `yaml:"listen" schema:"type=integer|string"`
+		Hostname            string            `yaml:"hostname"`
+		RootPath            string            `yaml:"root_path"`
+		TrustProxy          bool              `yaml:"trust_proxy"`
+		ForwardedPrecedence string            `yaml:"forwarded_precedence" schema:"enum=forwarded|x-forwarded"`
+		EncodedSlashes      string            `yaml:"encoded_slashes" schema:"enum=decode|reject"`
+		Workers             int               `yaml:"workers"`
+		AcmeChallengeDir    string            `yaml:"acme_challenge_dir"`
+		MaxHeaderBytes      int               `yaml:"max_header_bytes"`
+		ControlPath         string            `yaml:"control_path"`
+		PIDFile             string            `yaml:"pid_file"`
+		CGIScripts          []CGIScriptConfig `yaml:"cgi_scripts"`
+		Static              struct {
 			PublicDir                string   `yaml:"public_dir"`
 			AllowedExtensions        []string `yaml:"allowed_extensions"`
 			TryFiles                 []string `yaml:"try_files"`
 			NormalizeTrailingSlashes bool     `yaml:"normalize_trailing_slashes"`
 			CacheControl             struct {
-				Default          string `yaml:"default"`
-				DefaultImmutable bool   `yaml:"default_immutable"`
+				Default          *Duration `yaml:"default"`
+				DefaultImmutable bool      `yaml:"default_immutable"`
 				Overrides        []struct {
-					Path      string `yaml:"path"`
-					MaxAge    string `yaml:"max_age"`
-					Immutable bool   `yaml:"immutable"`
+					Path      string    `yaml:"path"`
+					MaxAge    *Duration `yaml:"max_age"`
+					Immutable bool      `yaml:"immutable"`
 				} `yaml:"overrides"`
 			} `yaml:"cache_control"`
+			Precompressed PrecompressedConfig `yaml:"precompressed"`
+			Uploads       []UploadConfig      `yaml:"uploads"`
+			Source        StaticSourceConfig  `yaml:"source"`
+			SPA           []SPAConfig         `yaml:"spa"`
+
+			FingerprintPatterns *[]string `yaml:"fingerprint_patterns"` // nil = default; [] turns fingerprinting off
+			FingerprintMaxAge   Duration  `yaml:"fingerprint_max_age"`
+
+			MIMETypes map[string]string `yaml:"mime_types"` // Extension ("md" or ".md") to Content-Type
+			NoSniff   bool              `yaml:"nosniff"`    // Send X-Content-Type-Options: nosniff with static files
+
+			RootUnavailable string `yaml:"root_unavailable" schema:"enum=unavailable|not_found"` // Answer while public_dir is unreachable (default: unavailable)
 		} `yaml:"static"`
 		Idle struct {
-			Action  string `yaml:"action"`  // "suspend" or "stop"
-			Timeout string `yaml:"timeout"` // Duration string like "30s", "5m"
+			Action              string   `yaml:"action" schema:"enum=suspend|stop"` // "suspend" or "stop"
+			Timeout             Duration `yaml:"timeout"`                           // Duration like "30s", "5m"
+			CountStaticRequests *bool    `yaml:"count_static_requests"`             // nil = default (true)
+			CountHealthChecks   bool     `yaml:"count_health_checks"`
+
+			DeferFor *[]string `yaml:"defer_for"` // nil = default; [] defers for nothing but requests
+
+			State IdleStateConfig `yaml:"state"`
 		} `yaml:"idle"`
-		HealthCheck HealthCheckConfig `yaml:"health_check"`
+		HealthCheck    HealthCheckConfig    `yaml:"health_check"`
+		ResponseCache  ResponseCacheStore   `yaml:"response_cache"`
+		Diagnostics    DiagnosticsConfig    `yaml:"diagnostics"`
+		LoadShedding   LoadSheddingConfig   `yaml:"load_shedding"`
+		Shutdown       ShutdownConfig       `yaml:"shutdown"`
+		Canonical      CanonicalConfig      `yaml:"canonical"`
+		Timeouts       TimeoutsConfig       `yaml:"timeouts"`
+		WellKnown      WellKnownConfig      `yaml:"well_known"`
+		RequestHeaders RequestHeadersConfig `yaml:"request_headers"`
+		RequestID      RequestIDConfig      `yaml:"request_id"`
+
+		AbsoluteURI             string `yaml:"absolute_uri" schema:"enum=normalize|reject"`
+		AbsoluteURIHostMismatch string `yaml:"absolute_uri_host_mismatch" schema:"enum=reject|use_hostname|use_uri"`
+
+		ErrorHistory int `yaml:"error_history"`
+
+		RegionHeaders bool `yaml:"region_headers"`
+
+		Repanic bool `yaml:"repanic"`
+
+		DecompressRequests       []string `yaml:"decompress_requests"`
+		MaxRequestBody           int64    `yaml:"max_request_body"`
+		MaxCompressedRequestBody int64    `yaml:"max_compressed_request_body"`
+
+		Outbound *OutboundConfig `yaml:"outbound"`
+
+		ProxyProtocol        bool     `yaml:"proxy_protocol"`
+		ProxyProtocolTrusted []string `yaml:"proxy_protocol_trusted"`
 	} `yaml:"server"`
 	Routes struct {
-		Redirects []struct {
-			From string `yaml:"from"`
-			To   string `yaml:"to"`
-		} `yaml:"redirects"`
-		Rewrites []struct {
-			From string `yaml:"from"`
-			To   string `yaml:"to"`
-		} `yaml:"rewrites"`
+		Redirects      []RouteRule  `yaml:"redirects"`
+		Rewrites       []RouteRule  `yaml:"rewrites"`
 		ReverseProxies []ProxyRoute `yaml:"reverse_proxies"`
 		Fly            struct {
 			StickySession struct {
 				Enabled        bool     `yaml:"enabled"`
 				CookieName     string   `yaml:"cookie_name"`
-				CookieMaxAge   string   `yaml:"cookie_max_age"`
+				CookieMaxAge   Duration `yaml:"cookie_max_age"`
 				CookieSecure   bool     `yaml:"cookie_secure"`
 				CookieHTTPOnly bool     `yaml:"cookie_httponly"`
 				CookieSameSite string   `yaml:"cookie_samesite"`
 				CookiePath     string   `yaml:"cookie_path"`
 				Paths          []string `yaml:"paths"`
 			} `yaml:"sticky_sessions"`
-			Replay []struct {
-				Path   string `yaml:"path"`
-				App    string `yaml:"app"`
-				Region string `yaml:"region"`
-				Status int    `yaml:"status"`
-			} `yaml:"replay"`
+			Replay        []FlyReplayRoute `yaml:"replay"`
+			MaxReplayHops *int             `yaml:"max_replay_hops"` // nil = default
 		} `yaml:"fly"`
 	} `yaml:"routes"`
 	Applications struct {
 		Pools struct {
-			MaxSize            int    `yaml:"max_size"`
-			Timeout            string `yaml:"timeout"`
-			StartPort          int    `yaml:"start_port"`
-			DefaultMemoryLimit string `yaml:"default_memory_limit"`
-			User               string `yaml:"user"`
-			Group              string `yaml:"group"`
+			MaxSize            int      `yaml:"max_size"`
+			Timeout            Duration `yaml:"timeout"`
+			StartPort          int      `yaml:"start_port"`
+			DefaultMemoryLimit string   `yaml:"default_memory_limit"`
+			User               string   `yaml:"user"`
+			Group              string   `yaml:"group"`
+
+			MaxConcurrentStartups int `yaml:"max_concurrent_startups"`
+
+			ConcurrencyConfig `yaml:",inline"`
+
+			Priority *PriorityConfig `yaml:"priority"`
+			Egress   *EgressConfig   `yaml:"egress"`
+
+			PortState PortStateConfig `yaml:"port_state"`
 		} `yaml:"pools"`
 		Framework struct {
 			Command      string   `yaml:"command"`
 			Args         []string `yaml:"args"`
 			AppDirectory string   `yaml:"app_directory"`
 			PortEnvVar   string   `yaml:"port_env_var"`
-			StartDelay   string   `yaml:"start_delay"`
+			StartDelay   Duration `yaml:"start_delay"`
 		} `yaml:"framework"`
 		Tenants []struct {
-			Path            string                 `yaml:"path"`
+			Name            string                 `yaml:"name"`
+			Path            string                 `yaml:"path" schema:"required"`
+			Port            int                    `yaml:"port"`
 			Root            string                 `yaml:"root"`
 			PublicDir       string                 `yaml:"public_dir"`
 			Env             map[string]string      `yaml:"env"`
@@ -397,29 +1265,75 @@ type YAMLConfig struct {
 			Args            []string               `yaml:"args"`
 			Var             map[string]interface{} `yaml:"var"`
 			HealthCheck     string                 `yaml:"health_check"`
-			StartupTimeout  string                 `yaml:"startup_timeout"`
+			StartupTimeout  Duration               `yaml:"startup_timeout"`
 			TrackWebSockets *bool                  `yaml:"track_websockets"`
+			RestartOnCrash  *bool                  `yaml:"restart_on_crash"`
+			StopSignal      string                 `yaml:"stop_signal"`
 			MemoryLimit     string                 `yaml:"memory_limit"`
 			User            string                 `yaml:"user"`
 			Group           string                 `yaml:"group"`
+			Cache           *ResponseCacheConfig   `yaml:"cache"`
+			Aliases         []string               `yaml:"aliases"`
+			AliasRedirect   bool                   `yaml:"alias_redirect"`
+			MaxHeaderBytes  int                    `yaml:"max_header_bytes"`
+			Maintenance     *MaintenanceConfig     `yaml:"maintenance"`
+			StartGuard      *StartGuardConfig      `yaml:"start_guard"`
+			Standby         bool                   `yaml:"standby"`
+			Negotiate       []NegotiatedTarget     `yaml:"negotiate"`
+			Mirror          *MirrorConfig          `yaml:"mirror"`
+			Priority        *PriorityConfig        `yaml:"priority"`
+			Egress          *EgressConfig          `yaml:"egress"`
 			Hooks           struct {
 				Start []HookConfig `yaml:"start"`
 				Stop  []HookConfig `yaml:"stop"`
 			} `yaml:"hooks"`
+
+			IdleWebSocketGrace   Duration `yaml:"idle_websocket_grace"`
+			CloseStaleWebSockets *bool    `yaml:"close_stale_websockets"`
+
+			ResponseFilter *ResponseFilterConfig `yaml:"response_filter"`
+
+			Auth      string `yaml:"auth" schema:"enum=inherit|required|public"`
+			AuthScope string `yaml:"auth_scope"`
+
+			Recycle *RecycleConfig `yaml:"recycle"`
+
+			Warmers []WarmerConfig `yaml:"warmers"`
+
+			DecompressRequests *[]string `yaml:"decompress_requests"`
+
+			Sendfile *SendfileConfig `yaml:"sendfile"`
+
+			AllowUndefinedVars *bool `yaml:"allow_undefined_vars"`
+
+			ConcurrencyConfig `yaml:",inline"`
 		} `yaml:"tenants"`
 		Env             map[string]string   `yaml:"env"`
 		Runtime         map[string]string   `yaml:"runtime"`
 		Server          map[string]string   `yaml:"server"`
 		Args            map[string][]string `yaml:"args"`
 		HealthCheck     string              `yaml:"health_check"`
-		StartupTimeout  string              `yaml:"startup_timeout"`
+		StartupTimeout  Duration            `yaml:"startup_timeout"`
 		TrackWebSockets bool                `yaml:"track_websockets"`
+		RestartOnCrash  bool                `yaml:"restart_on_crash"`
+		Coalesce        CoalesceConfig      `yaml:"coalesce"`
 		Hooks           struct {
 			Start []HookConfig `yaml:"start"`
 			Stop  []HookConfig `yaml:"stop"`
 		} `yaml:"hooks"`
+
+		IdleWebSocketGrace   Duration `yaml:"idle_websocket_grace"`
+		CloseStaleWebSockets bool     `yaml:"close_stale_websockets"`
+
+		Recycle               RecycleConfig `yaml:"recycle"`
+		MaxConcurrentRecycles int           `yaml:"max_concurrent_recycles"`
+
+		Sendfile *SendfileConfig `yaml:"sendfile"`
+
+		AllowUndefinedVars bool `yaml:"allow_undefined_vars"`
 	} `yaml:"applications"`
 	ManagedProcesses []ManagedProcessConfig `yaml:"managed_processes"`
+	ProcessGroups    []ManagedProcessGroup  `yaml:"managed_process_groups"`
 	Logging          LogConfig              `yaml:"logging"`
 	Hooks            struct {
 		Server struct {
@@ -433,9 +1347,15 @@ type YAMLConfig struct {
 			Start []HookConfig `yaml:"start"`
 			Stop  []HookConfig `yaml:"stop"`
 		} `yaml:"tenant"`
+		Events []EventHookConfig `yaml:"events"`
 	} `yaml:"hooks"`
 	Maintenance struct {
-		Enabled bool   `yaml:"enabled"`
-		Page    string `yaml:"page"`
+		Enabled bool                `yaml:"enabled"`
+		Page    string              `yaml:"page"`
+		Windows []MaintenanceWindow `yaml:"windows"`
 	} `yaml:"maintenance"`
+	Fly   FlyConfig           `yaml:"fly"`
+	Proxy OutboundProxyConfig `yaml:"proxy"`
+
+	Schedule []ScheduledTask `yaml:"schedule"`
 }
@@ -72,7 +72,8 @@ applications:
   pools:
     max_size: 1
 `,
-			expectError: false, // Duration validation happens at runtime
+			expectError: true, // Durations are validated when the config is loaded
+			errorMatch:  `server\.idle\.timeout: invalid duration "invalid-duration"`,
 		},
 		{
 			name: "invalid idle action",
@@ -185,7 +186,7 @@ applications:
     - path: /showcase/duplicate/
       root: /tmp/test2
 `,
-			expectError: false, // Should parse but may cause runtime issues
+			expectError: true, // Tenants would share log and PID files
 		},
 		{
 			name: "extremely deep YAML nesting",
@@ -559,8 +560,9 @@ applications:
 // TestVariableSubstitutionEdgeCases tests edge cases in variable substitution
 func TestVariableSubstitutionEdgeCases(t *testing.T) {
 	tests := []struct {
-		name   string
-		config string
+		name    string
+		config  string
+		wantErr string
 	}{
 		{
 			name: "nested variable references",
@@ -602,8 +604,37 @@ applications:
     - path: /test/
       var:
         VAR1: "value1"
+`,
+			wantErr: `env UNDEFINED_REF: undefined variable "UNDEFINED_VAR"`,
+		},
+		{
+			name: "undefined variables allowed",
+			config: `
+applications:
+  allow_undefined_vars: true
+  env:
+    DEFINED: "value"
+    UNDEFINED_REF: "${UNDEFINED_VAR}"
+  tenants:
+    - path: /test/
+      var:
+        VAR1: "value1"
 `,
 		},
+		{
+			name: "self-referencing variable",
+			config: `
+applications:
+  env:
+    LOOP: "${a}"
+  tenants:
+    - path: /test/
+      var:
+        a: "${b}"
+        b: "x${a}"
+`,
+			wantErr: "variable cycle a -> b -> a",
+		},
 		{
 			name: "variables with special characters",
 			config: `
@@ -647,8 +678,13 @@ applications:
 			}
 			_ = tmpFile.Close()
 
-			// Configuration should load without errors even with problematic variable substitution
 			config, err := LoadConfig(tmpFile.Name())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
 			if err != nil {
 				t.Errorf("Failed to load config with variable edge cases: %v", err)
 			}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// variablePattern matches ${name} and ${name:-fallback}
+var variablePattern = regexp.MustCompile(`\$\{([^{}:]+)(:-([^}]*))?\}`)
+
+// variableExpander expands ${var} references for one tenant. A name is
+// looked up in the tenant's vars, whose values may themselves reference
+// other vars, then in Navigator's own environment.
+type variableExpander struct {
+	vars           map[string]interface{}
+	allowUndefined bool // Leave references to undefined variables as they are
+	resolved       map[string]string
+}
+
+func newVariableExpander(vars map[string]interface{}, allowUndefined bool) *variableExpander {
+	return &variableExpander{vars: vars, allowUndefined: allowUndefined, resolved: make(map[string]string)}
+}
+
+// expand replaces the references in value. chain holds the vars being
+// expanded, outermost first, to detect cycles.
+func (e *variableExpander) expand(value string, chain []string) (string, error) {
+	var failure error
+	expanded := variablePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if failure != nil {
+			return reference
+		}
+		match := variablePattern.FindStringSubmatch(reference)
+		name, hasFallback, fallback := match[1], match[2] != "", match[3]
+		resolved, defined, err := e.lookup(name, chain)
+		switch {
+		case err != nil:
+			failure = err
+			return reference
+		case hasFallback && (!defined || resolved == ""):
+			return fallback
+		case defined:
+			return resolved
+		case e.allowUndefined:
+			return reference
+		}
+		failure = fmt.Errorf("undefined variable %q", name)
+		return reference
+	})
+	return expanded, failure
+}
+
+// lookup returns the expanded value of a variable and whether it's defined
+func (e *variableExpander) lookup(name string, chain []string) (string, bool, error) {
+	if raw, ok := e.vars[name]; ok {
+		if resolved, done := e.resolved[name]; done {
+			return resolved, true, nil
+		}
+		if i := slices.Index(chain, name); i >= 0 {
+			cycle := append(slices.Clone(chain[i:]), name)
+			return "", false, fmt.Errorf("variable cycle %s", strings.Join(cycle, " -> "))
+		}
+		value := ""
+		switch v := raw.(type) {
+		case nil:
+		case string:
+			value = v
+		default:
+			value = fmt.Sprintf("%v", v)
+		}
+		resolved, err := e.expand(value, append(slices.Clone(chain), name))
+		if err != nil {
+			return "", false, err
+		}
+		e.resolved[name] = resolved
+		return resolved, true, nil
+	}
+	value, ok := os.LookupEnv(name)
+	return value, ok, nil
+}
+
+// expandTenantEnv expands the variable references in applications.env for
+// a tenant. Referencing a variable defined neither in the tenant's vars nor
+// in Navigator's environment is an error unless allowUndefined is set.
+func expandTenantEnv(tenantName string, env map[string]string, vars map[string]interface{}, allowUndefined bool) (map[string]string, error) {
+	expander := newVariableExpander(vars, allowUndefined)
+	result := make(map[string]string, len(env))
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys) // Report the same error on every load
+	for _, key := range keys {
+		expanded, err := expander.expand(env[key], nil)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: env %s: %w", tenantName, key, err)
+		}
+		result[key] = expanded
+	}
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseWarmers validates the tenants' warmers and applies their default
+// interval
+func (p *ConfigParser) parseWarmers() error {
+	for i := range p.config.Applications.Tenants {
+		tenant := &p.config.Applications.Tenants[i]
+		for j := range tenant.Warmers {
+			warmer := &tenant.Warmers[j]
+			if !strings.HasPrefix(warmer.Path, "/") {
+				return fmt.Errorf("tenant %q: warmers[%d].path must start with /, got %q", tenant.Name, j, warmer.Path)
+			}
+			if warmer.Interval == 0 {
+				warmer.Interval = Duration(DefaultWarmerInterval)
+			}
+			if warmer.Interval.Std() < MinWarmerInterval {
+				return fmt.Errorf("tenant %q: warmers[%d].interval must be at least %s", tenant.Name, j, MinWarmerInterval)
+			}
+		}
+	}
+	return nil
+}
+
+// WarmerPath returns the path a warmer requests: its path under the tenant's
+func (t *Tenant) WarmerPath(warmer *WarmerConfig) string {
+	return strings.TrimSuffix(t.Path, "/") + warmer.Path
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// parseWellKnown reads the well-known files, expanding environment variables
+// in inline content, and resolves each override's files to their hosts and
+// paths. Overrides come before the defaults, and those for a host before
+// those for any host, so the first entry matching a request wins.
+func (p *ConfigParser) parseWellKnown() error {
+	wellKnown := p.yamlConfig.Server.WellKnown
+	if wellKnown.CacheControl == "" {
+		wellKnown.CacheControl = DefaultWellKnownCacheControl
+	}
+
+	var entries []WellKnownEntry
+	for i := range wellKnown.Overrides {
+		override := &wellKnown.Overrides[i]
+		field := fmt.Sprintf("server.well_known.overrides[%d]", i)
+		override.Host = strings.ToLower(strings.TrimSpace(override.Host))
+		if strings.ContainsAny(override.Host, "/?#@: ") {
+			return fmt.Errorf("%s.host must be a host name such as \"staging.example.com\", got %q", field, override.Host)
+		}
+		if override.Prefix != "" && !strings.HasPrefix(override.Prefix, "/") {
+			return fmt.Errorf("%s.prefix must start with /, got %q", field, override.Prefix)
+		}
+		if override.Host == "" && override.Prefix == "" {
+			return fmt.Errorf("%s needs a host or a prefix", field)
+		}
+		overridden, err := wellKnownEntries(field, override.Host, override.Prefix, override.RobotsTxt, override.SecurityTxt, override.Files, wellKnown.CacheControl)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, overridden...)
+	}
+	defaults, err := wellKnownEntries("server.well_known", "", "", wellKnown.RobotsTxt, wellKnown.SecurityTxt, wellKnown.Files, wellKnown.CacheControl)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, defaults...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Host != "" && entries[j].Host == ""
+	})
+
+	wellKnown.Entries = entries
+	p.config.Server.WellKnown = wellKnown
+	return nil
+}
+
+// wellKnownEntries resolves one set of well-known files, served to host
+// beneath prefix
+func wellKnownEntries(field, host, prefix string, robotsTxt, securityTxt *WellKnownFile, files []WellKnownFile, cacheControl string) ([]WellKnownEntry, error) {
+	var entries []WellKnownEntry
+	add := func(name, urlPath string, file *WellKnownFile) error {
+		if !strings.HasPrefix(urlPath, "/") {
+			return fmt.Errorf("%s.path must start with /, got %q", name, urlPath)
+		}
+		body, err := file.read()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		entry := WellKnownEntry{
+			Host:         host,
+			Path:         urlPath,
+			Body:         body,
+			ContentType:  file.ContentType,
+			CacheControl: file.CacheControl,
+		}
+		if prefix != "" {
+			entry.Path = path.Join(prefix, urlPath)
+		}
+		if entry.ContentType == "" {
+			entry.ContentType = mime.TypeByExtension(path.Ext(urlPath))
+		}
+		if entry.ContentType == "" {
+			entry.ContentType = "text/plain; charset=utf-8"
+		}
+		if entry.CacheControl == "" {
+			entry.CacheControl = cacheControl
+		}
+		entries = append(entries, entry)
+		return nil
+	}
+
+	if robotsTxt != nil {
+		if err := add(field+".robots_txt", RobotsTxtPath, robotsTxt); err != nil {
+			return nil, err
+		}
+	}
+	if securityTxt != nil {
+		if err := add(field+".security_txt", SecurityTxtPath, securityTxt); err != nil {
+			return nil, err
+		}
+	}
+	for i := range files {
+		if err := add(fmt.Sprintf("%s.files[%d]", field, i), files[i].Path, &files[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// read returns the file's body: its content with ${VAR} references to the
+// environment expanded, or what its file holds
+func (f *WellKnownFile) read() ([]byte, error) {
+	expander := newVariableExpander(nil, false)
+	switch {
+	case f.Content != "" && f.File != "":
+		return nil, fmt.Errorf("set content or file, not both")
+	case f.File != "":
+		name, err := expander.expand(f.File, nil)
+		if err != nil {
+			return nil, fmt.Errorf("file: %w", err)
+		}
+		return os.ReadFile(name)
+	case f.Content != "":
+		content, err := expander.expand(f.Content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("content: %w", err)
+		}
+		return []byte(content), nil
+	}
+	return nil, fmt.Errorf("content or file is required")
+}
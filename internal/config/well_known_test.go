@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const wellKnownTestConfig = `
+server:
+  well_known:
+    robots_txt:
+      content: "${ROBOTS_POLICY:-User-agent: *\nAllow: /}\n"
+    security_txt:
+      file: ${SECURITY_TXT_FILE}
+      cache_control: no-cache
+    files:
+      - path: /.well-known/apple-app-site-association
+        content: '{"applinks": {}}'
+        content_type: application/json
+    overrides:
+      - prefix: /showcase/
+        robots_txt: {content: "User-agent: *\nDisallow: /showcase/\n"}
+      - host: "*.Staging.example.com"
+        robots_txt: {content: "User-agent: *\nDisallow: /\n"}
+`
+
+func TestParseWellKnownEnvironments(t *testing.T) {
+	securityTxt := filepath.Join(t.TempDir(), "security.txt")
+	if err := os.WriteFile(securityTxt, []byte("Contact: mailto:security@example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SECURITY_TXT_FILE", securityTxt)
+
+	for _, tt := range []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"production", "", "User-agent: *\nAllow: /\n"},
+		{"staging", "User-agent: *\nDisallow: /", "User-agent: *\nDisallow: /\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ROBOTS_POLICY", tt.policy)
+			cfg, err := ParseYAML([]byte(wellKnownTestConfig))
+			if err != nil {
+				t.Fatalf("ParseYAML() error = %v", err)
+			}
+			var paths []string
+			for _, entry := range cfg.Server.WellKnown.Entries {
+				paths = append(paths, entry.Host+entry.Path)
+			}
+			want := "*.staging.example.com/robots.txt /showcase/robots.txt /robots.txt /.well-known/security.txt /.well-known/apple-app-site-association"
+			if strings.Join(paths, " ") != want {
+				t.Fatalf("Entries = %v, want %s", paths, want)
+			}
+
+			entries := cfg.Server.WellKnown.Entries
+			if robots := entries[2]; string(robots.Body) != tt.want || robots.ContentType != "text/plain; charset=utf-8" || robots.CacheControl != DefaultWellKnownCacheControl {
+				t.Errorf("robots.txt = %+v, want %q", robots, tt.want)
+			}
+			if security := entries[3]; !strings.HasPrefix(string(security.Body), "Contact:") || security.CacheControl != "no-cache" {
+				t.Errorf("security.txt = %+v", security)
+			}
+			if entries[4].ContentType != "application/json" {
+				t.Errorf("ContentType = %q, want application/json", entries[4].ContentType)
+			}
+		})
+	}
+}
+
+func TestParseWellKnownErrors(t *testing.T) {
+	for _, tt := range []struct {
+		config string
+		want   string
+	}{
+		{"robots_txt: {content: x, file: y}", "server.well_known.robots_txt: set content or file, not both"},
+		{"security_txt: {}", "server.well_known.security_txt: content or file is required"},
+		{"robots_txt: {content: '${NAVIGATOR_UNDEFINED_POLICY}'}", `content: undefined variable "NAVIGATOR_UNDEFINED_POLICY"`},
+		{"files: [{path: humans.txt, content: x}]", "server.well_known.files[0].path must start with /"},
+		{"overrides: [{robots_txt: {content: x}}]", "server.well_known.overrides[0] needs a host or a prefix"},
+		{"overrides: [{host: 'example.com:8080', robots_txt: {content: x}}]", "overrides[0].host must be a host name"},
+	} {
+		_, err := ParseYAML([]byte("server:\n  well_known: {" + strings.TrimSuffix(tt.config, "\n") + "}\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package diagnostics collects a snapshot of Navigator's state for debugging
+// production issues without restarting: goroutines, effective configuration,
+// tenant apps, managed processes, WebSockets, retry buffers, and memory.
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/rubys/navigator/internal/cable"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// redactedValue replaces configuration values that may hold secrets
+const redactedValue = "[REDACTED]"
+
+// sensitiveKey matches configuration keys whose values are always redacted
+var sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|key$|^env$)`)
+
+// Sources are the components a bundle is collected from; nil sources are skipped
+type Sources struct {
+	Version   string
+	Config    *config.Config
+	Apps      *process.AppManager
+	Processes *process.Manager
+	Cable     *cable.Handler
+}
+
+// Bundle is a diagnostic snapshot of a running Navigator
+type Bundle struct {
+	Timestamp        time.Time                      `json:"timestamp"`
+	Version          string                         `json:"version,omitempty"`
+	PID              int                            `json:"pid"`
+	Config           interface{}                    `json:"config,omitempty"`
+	Tenants          []process.AppStatus            `json:"tenants"`
+	ManagedProcesses []process.ManagedProcessStatus `json:"managed_processes"`
+	WebSockets       WebSocketStats                 `json:"websockets"`
+	RetryBuffers     proxy.RetryBufferStats         `json:"retry_buffers"`
+	Memory           MemoryStats                    `json:"memory"`
+	OpenFDs          int                            `json:"open_fds,omitempty"` // Omitted where descriptors cannot be counted
+	Goroutines       string                         `json:"goroutines"`
+}
+
+// WebSocketStats counts open WebSocket connections
+type WebSocketStats struct {
+	Cable        int   `json:"cable"`         // Connections to the built-in cable endpoint
+	CableStreams int   `json:"cable_streams"` // Streams with at least one subscriber
+	Tenants      int32 `json:"tenants"`       // Connections proxied to tenant apps
+}
+
+// MemoryStats is a subset of runtime.MemStats
+type MemoryStats struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	NumGC        uint32 `json:"num_gc"`
+	NumGoroutine int    `json:"num_goroutine"`
+}
+
+// Collect gathers a bundle from the given sources
+func Collect(src Sources) *Bundle {
+	bundle := &Bundle{
+		Timestamp:        time.Now(),
+		Version:          src.Version,
+		PID:              os.Getpid(),
+		Tenants:          []process.AppStatus{},
+		ManagedProcesses: []process.ManagedProcessStatus{},
+	}
+
+	if src.Config != nil {
+		bundle.Config = redactConfig(src.Config)
+	}
+	if src.Apps != nil {
+		bundle.Tenants = src.Apps.Status()
+		for _, app := range bundle.Tenants {
+			bundle.WebSockets.Tenants += app.ActiveWebSockets
+		}
+	}
+	if src.Processes != nil {
+		bundle.ManagedProcesses = src.Processes.Status()
+	}
+	if src.Cable != nil {
+		bundle.WebSockets.Cable, bundle.WebSockets.CableStreams = src.Cable.Stats()
+	}
+
+	bundle.RetryBuffers = proxy.GetRetryBufferStats()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	bundle.Memory = MemoryStats{
+		Alloc:        mem.Alloc,
+		TotalAlloc:   mem.TotalAlloc,
+		Sys:          mem.Sys,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		NumGC:        mem.NumGC,
+		NumGoroutine: runtime.NumGoroutine(),
+	}
+
+	if fds := process.OpenFileCount(); fds >= 0 {
+		bundle.OpenFDs = fds
+	}
+
+	var goroutines bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&goroutines, 2)
+	bundle.Goroutines = goroutines.String()
+
+	return bundle
+}
+
+// Write stores the bundle as a JSON file in dir and returns its path
+func Write(dir string, bundle *Bundle) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode diagnostics: %w", err)
+	}
+
+	name := fmt.Sprintf("navigator-%s-%d.json", bundle.Timestamp.Format("20060102-150405.000"), bundle.PID)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write diagnostics: %w", err)
+	}
+	return path, nil
+}
+
+// redactConfig converts the configuration to generic JSON values with
+// environment variables and secret-looking settings redacted
+func redactConfig(cfg *config.Config) interface{} {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	return redact(generic)
+}
+
+// redact replaces the values of sensitive keys, recursing into maps and arrays
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if sensitiveKey.MatchString(key) && child != nil {
+				v[key] = redactAll(child)
+			} else {
+				v[key] = redact(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redact(child)
+		}
+	}
+	return value
+}
+
+// redactAll replaces every string, keeping the structure (e.g. environment variable names)
+func redactAll(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = redactAll(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactAll(child)
+		}
+		return v
+	case string:
+		if v == "" {
+			return v
+		}
+		return redactedValue
+	default:
+		return v
+	}
+}
+
+// IsSignal reports whether sig requests a diagnostic bundle
+func IsSignal(sig os.Signal) bool {
+	for _, s := range Signals {
+		if sig == s {
+			return true
+		}
+	}
+	return false
+}
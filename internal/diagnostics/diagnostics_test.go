@@ -0,0 +1,90 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func TestCollectRedactsConfig(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  listen: 3000
+auth:
+  htpasswd: /etc/htpasswd
+applications:
+  env:
+    DATABASE_URL: postgres://user:hunter2@db/app
+  tenants:
+    - path: /showcase/2025/boston/
+managed_processes:
+  - name: worker
+    command: sleep
+    env:
+      API_TOKEN: abc123
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := Collect(Sources{Version: "1.2.3", Config: cfg, Apps: process.NewAppManager(cfg), Processes: process.NewManager(cfg)})
+	data, err := json.Marshal(bundle.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "abc123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("config contains secret %q", secret)
+		}
+	}
+	for _, kept := range []string{"DATABASE_URL", "API_TOKEN", "/showcase/2025/boston/"} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("config should keep %q", kept)
+		}
+	}
+
+	if bundle.Version != "1.2.3" || bundle.PID != os.Getpid() {
+		t.Errorf("version/pid = %q/%d", bundle.Version, bundle.PID)
+	}
+	if !strings.Contains(bundle.Goroutines, "goroutine ") || bundle.Memory.NumGoroutine == 0 {
+		t.Error("bundle should include a goroutine dump and memory stats")
+	}
+	if bundle.Tenants == nil || bundle.ManagedProcesses == nil {
+		t.Error("empty tables should encode as [] rather than null")
+	}
+	if bundle.RetryBuffers.Limit != config.MaxRetryBufferTotal {
+		t.Errorf("retry buffer limit = %d, want %d", bundle.RetryBuffers.Limit, config.MaxRetryBufferTotal)
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	bundle := Collect(Sources{})
+
+	path, err := Write(dir, bundle)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("bundle written to %s, want %s", path, dir)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("bundle mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	var decoded Bundle
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.PID != bundle.PID {
+		t.Errorf("bundle did not round-trip: %v", err)
+	}
+}
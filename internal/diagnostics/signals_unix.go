@@ -0,0 +1,12 @@
+//go:build unix
+
+package diagnostics
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals request a diagnostic bundle. Handling SIGQUIT replaces the Go
+// runtime's default of dumping goroutines and exiting, so Navigator keeps running.
+var Signals = []os.Signal{syscall.SIGQUIT, syscall.SIGUSR1}
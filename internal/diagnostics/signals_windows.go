@@ -0,0 +1,9 @@
+//go:build windows
+
+package diagnostics
+
+import "os"
+
+// Signals request a diagnostic bundle. Windows has no suitable signal; use
+// the server.diagnostics.path endpoint instead.
+var Signals []os.Signal
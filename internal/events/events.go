@@ -0,0 +1,230 @@
+// Package events delivers lifecycle events to the destinations configured
+// under hooks.events. Emission never blocks: each destination has a bounded
+// queue drained by its own goroutine, and events are dropped when it is full.
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// Lifecycle event types; the config package lists them so hooks.events
+// types can be checked when the configuration is loaded
+const (
+	TenantStarted    = config.EventTenantStarted
+	TenantStopped    = config.EventTenantStopped
+	TenantCrashed    = config.EventTenantCrashed
+	TenantFailover   = config.EventTenantFailover
+	ProcessRestarted = config.EventProcessRestarted
+	ProcessCrashLoop = config.EventProcessCrashLoop
+	ReloadSucceeded  = config.EventReloadSucceeded
+	ReloadFailed     = config.EventReloadFailed
+	IdleTriggered    = config.EventIdleTriggered
+)
+
+// Event is the JSON payload delivered to every destination
+type Event struct {
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// queued is an event waiting for delivery, or a flush marker when flushed is set
+type queued struct {
+	event   Event
+	flushed chan struct{}
+}
+
+// destination delivers events to one webhook or command
+type destination struct {
+	cfg    config.EventHookConfig
+	name   string
+	types  map[string]bool // nil accepts every type
+	queue  chan queued
+	client *http.Client
+}
+
+// dispatcher holds the destinations for the current configuration
+var dispatcher struct {
+	mu           sync.RWMutex
+	destinations []*destination
+}
+
+// Configure replaces the event destinations. Events already queued for the
+// previous destinations are still delivered.
+func Configure(hooks []config.EventHookConfig) {
+	destinations := make([]*destination, 0, len(hooks))
+	for _, hook := range hooks {
+		destinations = append(destinations, newDestination(hook))
+	}
+
+	dispatcher.mu.Lock()
+	old := dispatcher.destinations
+	dispatcher.destinations = destinations
+	dispatcher.mu.Unlock()
+
+	// No Emit can still hold the old destinations once the lock was acquired
+	for _, d := range old {
+		close(d.queue)
+	}
+}
+
+// Emit queues an event for every destination accepting its type
+func Emit(eventType string, details map[string]interface{}) {
+	event := Event{Type: eventType, Timestamp: time.Now().UTC(), Details: details}
+
+	dispatcher.mu.RLock()
+	defer dispatcher.mu.RUnlock()
+	for _, d := range dispatcher.destinations {
+		if d.types != nil && !d.types[eventType] {
+			continue
+		}
+		select {
+		case d.queue <- queued{event: event}:
+		default:
+			logging.LogEventDropped(d.name, eventType)
+		}
+	}
+}
+
+// Flush waits, up to timeout, until events queued before the call have been
+// delivered. Used before the machine is suspended or navigator exits.
+func Flush(timeout time.Duration) {
+	deadline := time.After(timeout)
+
+	dispatcher.mu.RLock()
+	markers := make([]chan struct{}, 0, len(dispatcher.destinations))
+	for _, d := range dispatcher.destinations {
+		flushed := make(chan struct{})
+		select {
+		case d.queue <- queued{flushed: flushed}:
+			markers = append(markers, flushed)
+		case <-deadline:
+			dispatcher.mu.RUnlock()
+			return
+		}
+	}
+	dispatcher.mu.RUnlock()
+
+	for _, flushed := range markers {
+		select {
+		case <-flushed:
+		case <-deadline:
+			return
+		}
+	}
+}
+
+func newDestination(cfg config.EventHookConfig) *destination {
+	d := &destination{
+		cfg:    cfg,
+		name:   cfg.Name,
+		queue:  make(chan queued, config.EventQueueSize),
+		client: &http.Client{},
+	}
+	if d.name == "" {
+		d.name = cfg.URL + cfg.Command
+	}
+	if len(cfg.Types) > 0 {
+		d.types = make(map[string]bool, len(cfg.Types))
+		for _, t := range cfg.Types {
+			d.types[t] = true
+		}
+	}
+	go d.run()
+	return d
+}
+
+// run delivers queued events in order until the queue is closed
+func (d *destination) run() {
+	for item := range d.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		d.deliver(item.event)
+	}
+}
+
+// deliver sends an event, retrying with exponential backoff on failure
+func (d *destination) deliver(event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logging.LogEventDeliveryFailed(d.name, event.Type, 0, err)
+		return
+	}
+
+	delay := time.Duration(d.cfg.RetryDelay)
+	attempts := d.cfg.Retries + 1
+	for attempt := 1; ; attempt++ {
+		if d.cfg.URL != "" {
+			err = d.post(payload)
+		} else {
+			err = d.exec(event.Type, payload)
+		}
+		if err == nil {
+			return
+		}
+		if attempt == attempts {
+			logging.LogEventDeliveryFailed(d.name, event.Type, attempts, err)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post sends the payload to the webhook URL
+func (d *destination) post(payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.cfg.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Navigator")
+	if d.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.cfg.Token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// exec runs the command with the payload on stdin
+func (d *destination) exec(eventType string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.cfg.Timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, d.cfg.Command, d.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), "NAVIGATOR_EVENT="+eventType)
+	cmd.WaitDelay = config.HookWaitDelay
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
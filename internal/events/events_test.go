@@ -0,0 +1,172 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// receivedEvent is a webhook request as seen by the test receiver
+type receivedEvent struct {
+	Event
+	Authorization string
+}
+
+// newReceiver starts a webhook receiver that reports every event it gets
+func newReceiver(t *testing.T) (*httptest.Server, chan receivedEvent) {
+	t.Helper()
+	received := make(chan receivedEvent, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		received <- receivedEvent{Event: event, Authorization: r.Header.Get("Authorization")}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+// configure installs destinations for the duration of the test
+func configure(t *testing.T, hooks ...config.EventHookConfig) {
+	t.Helper()
+	for i := range hooks {
+		if hooks[i].Timeout == 0 {
+			hooks[i].Timeout = config.Duration(time.Second)
+		}
+		if hooks[i].RetryDelay == 0 {
+			hooks[i].RetryDelay = config.Duration(10 * time.Millisecond)
+		}
+	}
+	Configure(hooks)
+	t.Cleanup(func() { Configure(nil) })
+}
+
+func waitForEvent(t *testing.T, received chan receivedEvent) receivedEvent {
+	t.Helper()
+	select {
+	case event := <-received:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return receivedEvent{}
+	}
+}
+
+func TestEmitPostsPayloadWithBearerToken(t *testing.T) {
+	srv, received := newReceiver(t)
+	configure(t, config.EventHookConfig{URL: srv.URL, Token: "secret"})
+
+	before := time.Now().Add(-time.Second)
+	Emit(ReloadFailed, map[string]interface{}{"error": "bad yaml"})
+
+	event := waitForEvent(t, received)
+	if event.Type != ReloadFailed {
+		t.Errorf("type = %q, want %q", event.Type, ReloadFailed)
+	}
+	if event.Details["error"] != "bad yaml" {
+		t.Errorf("details = %v", event.Details)
+	}
+	if event.Timestamp.Before(before) {
+		t.Errorf("timestamp = %v, want a current time", event.Timestamp)
+	}
+	if event.Authorization != "Bearer secret" {
+		t.Errorf("Authorization = %q", event.Authorization)
+	}
+}
+
+func TestEmitFiltersByType(t *testing.T) {
+	srv, received := newReceiver(t)
+	configure(t, config.EventHookConfig{URL: srv.URL, Types: []string{TenantStarted}})
+
+	Emit(TenantStopped, nil)
+	Emit(TenantStarted, map[string]interface{}{"tenant": "boston"})
+
+	if event := waitForEvent(t, received); event.Type != TenantStarted {
+		t.Errorf("type = %q, want only %q", event.Type, TenantStarted)
+	}
+	Flush(time.Second)
+	if len(received) != 0 {
+		t.Errorf("received %d unexpected events", len(received))
+	}
+}
+
+func TestDeliveryRetriesFailedWebhook(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	configure(t, config.EventHookConfig{URL: srv.URL, Retries: 2})
+
+	Emit(ProcessRestarted, nil)
+	Flush(2 * time.Second)
+
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+}
+
+func TestDeliveryToCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	output := filepath.Join(t.TempDir(), "event")
+	configure(t, config.EventHookConfig{
+		Command: "/bin/sh",
+		Args:    []string{"-c", `cat > "$0.json" && printf %s "$NAVIGATOR_EVENT" > "$0.type"`, output},
+	})
+
+	Emit(IdleTriggered, map[string]interface{}{"action": "suspend"})
+	Flush(2 * time.Second)
+
+	data, err := os.ReadFile(output + ".json")
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("invalid payload %q: %v", data, err)
+	}
+	if event.Type != IdleTriggered || event.Details["action"] != "suspend" {
+		t.Errorf("event = %+v", event)
+	}
+	if eventType, _ := os.ReadFile(output + ".type"); string(eventType) != IdleTriggered {
+		t.Errorf("NAVIGATOR_EVENT = %q, want %q", eventType, IdleTriggered)
+	}
+}
+
+func TestEmitDropsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	var delivered atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		delivered.Add(1)
+	}))
+	defer srv.Close()
+	configure(t, config.EventHookConfig{URL: srv.URL, Timeout: config.Duration(5 * time.Second)})
+
+	start := time.Now()
+	for i := 0; i < config.EventQueueSize*2; i++ {
+		Emit(TenantStarted, nil)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Emit blocked for %v with a stalled destination", elapsed)
+	}
+
+	close(release)
+	Flush(5 * time.Second)
+	if n := delivered.Load(); n > config.EventQueueSize+1 {
+		t.Errorf("delivered %d events, want at most %d", n, config.EventQueueSize+1)
+	}
+}
@@ -0,0 +1,227 @@
+// Package flight stores the entries of the flight recorder: requests that
+// failed, with the headers, start of the body, and timing needed to see why,
+// kept in a directory bounded by count and total size.
+package flight
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// Entry is a failed request as the flight recorder kept it
+type Entry struct {
+	ID               string    `json:"id"`
+	Timestamp        time.Time `json:"@timestamp"`
+	RequestID        string    `json:"request_id,omitempty"`
+	Method           string    `json:"method"`
+	URI              string    `json:"uri"`
+	ClientIP         string    `json:"client_ip,omitempty"`
+	Tenant           string    `json:"tenant,omitempty"`
+	ResponseType     string    `json:"response_type,omitempty"`
+	Status           int       `json:"status"`
+	UpstreamError    string    `json:"upstream_error,omitempty"`
+	ErrorMessage     string    `json:"error_message,omitempty"`
+	RequestHeaders   []string  `json:"request_headers,omitempty"`
+	RequestBody      string    `json:"request_body,omitempty"`
+	RequestTruncated bool      `json:"request_truncated,omitempty"`
+	ResponseHeaders  []string  `json:"response_headers,omitempty"`
+	Timing           Timing    `json:"timing"`
+}
+
+// Timing is where a failed request's time went, in milliseconds
+type Timing struct {
+	Total         float64 `json:"total_ms"`
+	FirstByte     float64 `json:"first_byte_ms,omitempty"`     // Until the response status was written
+	Queue         float64 `json:"queue_ms,omitempty"`          // Waiting for a max_concurrent_requests slot
+	StartupQueued float64 `json:"startup_queued_ms,omitempty"` // The app's start waiting for a startup slot
+	Boot          float64 `json:"boot_ms,omitempty"`           // The app booting, on a cold start
+}
+
+// Ring is a directory of entries holding at most MaxFiles entries and
+// MaxBytes bytes; writing an entry deletes the oldest beyond either
+type Ring struct {
+	Dir      string
+	MaxFiles int
+	MaxBytes int64
+}
+
+// entryExt is the extension of entry files
+const entryExt = ".json"
+
+var (
+	writeMutex sync.Mutex // Serializes writing and pruning
+	sequence   atomic.Uint32
+)
+
+// newID returns an ID that sorts in the order entries were written
+func newID(t time.Time) string {
+	return fmt.Sprintf("%s-%04d", t.UTC().Format("20060102-150405.000000"), sequence.Add(1)%10000)
+}
+
+// Write stores entry, giving it an ID, and deletes the oldest entries the
+// ring no longer has room for. It returns the entry's path.
+func (r Ring) Write(entry *Entry) (string, error) {
+	writeMutex.Lock()
+	defer writeMutex.Unlock()
+
+	if err := os.MkdirAll(r.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create flight recorder directory: %w", err)
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.ID = newID(entry.Timestamp)
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode flight recorder entry: %w", err)
+	}
+	path := filepath.Join(r.Dir, entry.ID+entryExt)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write flight recorder entry: %w", err)
+	}
+	return path, r.prune()
+}
+
+// queuedEntry is an entry waiting to be written, or a flush marker when
+// flushed is set
+type queuedEntry struct {
+	ring    Ring
+	entry   *Entry
+	flushed chan struct{}
+}
+
+// queue holds entries for the writer goroutine, started by the first Enqueue
+var queue = struct {
+	once    sync.Once
+	entries chan queuedEntry
+}{entries: make(chan queuedEntry, config.FlightRecorderQueueSize)}
+
+// Enqueue queues entry to be written to the ring on a dedicated goroutine, so
+// a slow file system never delays the request that failed. It reports false,
+// dropping the entry, when the queue is full.
+func (r Ring) Enqueue(entry *Entry) bool {
+	queue.once.Do(func() { go writeQueued() })
+	select {
+	case queue.entries <- queuedEntry{ring: r, entry: entry}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Flush waits until every entry queued before the call has been written.
+// Called on shutdown so the last entries aren't lost.
+func Flush() {
+	queue.once.Do(func() { go writeQueued() })
+	flushed := make(chan struct{})
+	queue.entries <- queuedEntry{flushed: flushed}
+	<-flushed
+}
+
+// writeQueued writes queued entries for the life of the process
+func writeQueued() {
+	for queued := range queue.entries {
+		if queued.flushed != nil {
+			close(queued.flushed)
+			continue
+		}
+		if _, err := queued.ring.Write(queued.entry); err != nil {
+			slog.Warn("Failed to write flight recorder entry", "directory", queued.ring.Dir, "error", err)
+		}
+	}
+}
+
+// prune deletes the oldest entries beyond MaxFiles or MaxBytes, always
+// keeping the newest
+func (r Ring) prune() error {
+	files, err := entryFiles(r.Dir)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	for len(files) > 1 && ((r.MaxFiles > 0 && len(files) > r.MaxFiles) || (r.MaxBytes > 0 && total > r.MaxBytes)) {
+		if err := os.Remove(filepath.Join(r.Dir, files[0].name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete flight recorder entry: %w", err)
+		}
+		total -= files[0].size
+		files = files[1:]
+	}
+	return nil
+}
+
+type entryFile struct {
+	name string
+	size int64
+}
+
+// entryFiles returns the entry files in dir, oldest first
+func entryFiles(dir string) ([]entryFile, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []entryFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), entryExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, entryFile{name: dirEntry.Name(), size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// List returns the entries in dir, oldest first
+func List(dir string) ([]*Entry, error) {
+	files, err := entryFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, file := range files {
+		entry, err := Read(dir, strings.TrimSuffix(file.name, entryExt))
+		if err != nil {
+			// Deleted by a concurrent write since the directory was read
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Read returns the entry in dir with the given ID
+func Read(dir, id string) (*Entry, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id != filepath.Base(id) {
+		return nil, fmt.Errorf("invalid flight recorder entry id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, id+entryExt))
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode flight recorder entry %s: %w", id, err)
+	}
+	return &entry, nil
+}
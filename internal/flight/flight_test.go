@@ -0,0 +1,77 @@
+package flight
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingKeepsNewestEntries(t *testing.T) {
+	dir := t.TempDir()
+	ring := Ring{Dir: dir, MaxFiles: 3}
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		entry := &Entry{Method: "GET", URI: "/" + strings.Repeat("x", i), Status: 502}
+		if _, err := ring.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("List() returned %d entries, want 3", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ID != ids[i+2] {
+			t.Errorf("entries[%d].ID = %s, want %s", i, entry.ID, ids[i+2])
+		}
+	}
+	if _, err := Read(dir, ids[0]); !os.IsNotExist(err) {
+		t.Errorf("Read(oldest) error = %v, want it deleted", err)
+	}
+}
+
+func TestRingBoundsTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	ring := Ring{Dir: dir, MaxFiles: 100, MaxBytes: 1000}
+	for i := 0; i < 5; i++ {
+		if _, err := ring.Write(&Entry{RequestBody: strings.Repeat("b", 300), Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := entryFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	if total > 1000 || len(files) == 0 {
+		t.Errorf("%d entries take %d bytes, want at most 1000", len(files), total)
+	}
+
+	// The newest entry is kept even when it alone is over the budget
+	small := Ring{Dir: dir, MaxBytes: 10}
+	entry := &Entry{RequestBody: "too large"}
+	if _, err := small.Write(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := List(dir); len(entries) != 1 || entries[0].ID != entry.ID {
+		t.Errorf("List() = %d entries, want only the newest", len(entries))
+	}
+}
+
+func TestReadRejectsPaths(t *testing.T) {
+	for _, id := range []string{"", "../secret", "a/b", `a\b`} {
+		if _, err := Read(t.TempDir(), id); err == nil || os.IsNotExist(err) {
+			t.Errorf("Read(%q) error = %v, want an invalid id", id, err)
+		}
+	}
+}
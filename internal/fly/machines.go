@@ -0,0 +1,170 @@
+// Package fly coordinates scale-to-zero with the other machines of the Fly
+// app Navigator runs in, through the Fly Machines API: before the idle
+// action stops the app's last running machine, and by starting a sibling
+// machine in another region while a tenant's load stays high.
+package fly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// Machine states reported by the Machines API
+const (
+	StateStarted   = "started"
+	StateStopped   = "stopped"
+	StateSuspended = "suspended"
+)
+
+// Machine is a machine of the app, as listed by the Machines API
+type Machine struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name,omitempty"`
+	State  string          `json:"state"`
+	Region string          `json:"region"`
+	Config json.RawMessage `json:"config,omitempty"` // Passed through unchanged when cloning
+}
+
+// CreateRequest is the body of a request creating a machine
+type CreateRequest struct {
+	Region string          `json:"region"`
+	Config json.RawMessage `json:"config"`
+}
+
+// MachinesAPI is the part of the Machines API Navigator uses
+type MachinesAPI interface {
+	List(ctx context.Context) ([]Machine, error)
+	Get(ctx context.Context, id string) (*Machine, error)
+	Start(ctx context.Context, id string) error
+	Create(ctx context.Context, req CreateRequest) (*Machine, error)
+}
+
+// Client calls the Machines API for one app. With a token it uses the
+// public endpoint; without one, the API socket Fly provides on each machine.
+type Client struct {
+	baseURL string
+	app     string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client for the app of cfg, reading its token from the
+// environment
+func NewClient(cfg config.FlyMachinesAPIConfig) *Client {
+	app := cfg.App
+	if app == "" {
+		app = os.Getenv("FLY_APP_NAME")
+	}
+	c := &Client{
+		baseURL: config.FlyMachinesAPIURL,
+		app:     app,
+		token:   os.Getenv(cfg.TokenEnv),
+		http:    &http.Client{Timeout: config.FlyAPITimeout},
+	}
+	if c.token == "" {
+		c.baseURL = "http://flaps"
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", config.FlyAPISocket)
+			},
+		}
+	}
+	return c
+}
+
+// List returns the app's machines
+func (c *Client) List(ctx context.Context) ([]Machine, error) {
+	var machines []Machine
+	err := c.do(ctx, http.MethodGet, "/machines", nil, &machines)
+	return machines, err
+}
+
+// Get returns one of the app's machines, with its config
+func (c *Client) Get(ctx context.Context, id string) (*Machine, error) {
+	var machine Machine
+	if err := c.do(ctx, http.MethodGet, "/machines/"+id, nil, &machine); err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+// Start starts a stopped or suspended machine
+func (c *Client) Start(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/machines/"+id+"/start", nil, nil)
+}
+
+// Create creates and starts a machine
+func (c *Client) Create(ctx context.Context, req CreateRequest) (*Machine, error) {
+	var machine Machine
+	if err := c.do(ctx, http.MethodPost, "/machines", req, &machine); err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+// do sends a request for path under the app, encoding body and decoding the
+// response into result when they aren't nil
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	if c.app == "" {
+		return fmt.Errorf("no Fly app: set fly.machines_api.app or FLY_APP_NAME")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/v1/apps/"+c.app+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// OthersRunning reports how many of machines other than self are started
+func OthersRunning(machines []Machine, self string) int {
+	running := 0
+	for _, machine := range machines {
+		if machine.ID != self && machine.State == StateStarted {
+			running++
+		}
+	}
+	return running
+}
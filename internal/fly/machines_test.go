@@ -0,0 +1,64 @@
+package fly
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestClient(t *testing.T) {
+	type call struct{ method, path, auth, body string }
+	var calls []call
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, call{r.Method, r.URL.Path, r.Header.Get("Authorization"), string(body)})
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/apps/showcase/machines":
+			_, _ = w.Write([]byte(`[{"id":"a","state":"started","region":"iad"},{"id":"b","state":"stopped","region":"ord"}]`))
+		case "POST /v1/apps/showcase/machines/b/start":
+			_, _ = w.Write([]byte(`{"previous_state":"stopped"}`))
+		case "POST /v1/apps/showcase/machines":
+			_, _ = w.Write([]byte(`{"id":"c","state":"created","region":"ord"}`))
+		default:
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	t.Setenv("NAVIGATOR_TEST_FLY_TOKEN", "secret")
+	client := NewClient(config.FlyMachinesAPIConfig{App: "showcase", TokenEnv: "NAVIGATOR_TEST_FLY_TOKEN"})
+	client.baseURL = api.URL
+	ctx := context.Background()
+
+	machines, err := client.List(ctx)
+	if err != nil || len(machines) != 2 || machines[1].ID != "b" || machines[1].State != StateStopped {
+		t.Fatalf("List() = %+v, %v", machines, err)
+	}
+	if OthersRunning(machines, "a") != 0 || OthersRunning(machines, "b") != 1 {
+		t.Errorf("OthersRunning() miscounted %+v", machines)
+	}
+	if err := client.Start(ctx, "b"); err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	created, err := client.Create(ctx, CreateRequest{Region: "ord", Config: json.RawMessage(`{"image":"navigator"}`)})
+	if err != nil || created.ID != "c" {
+		t.Errorf("Create() = %+v, %v", created, err)
+	}
+	if _, err := client.Get(ctx, "missing"); err == nil {
+		t.Error("Get() of a missing machine should fail")
+	}
+
+	for _, c := range calls {
+		if c.auth != "Bearer secret" {
+			t.Errorf("%s %s Authorization = %q", c.method, c.path, c.auth)
+		}
+	}
+	if body := calls[2].body; body != `{"region":"ord","config":{"image":"navigator"}}` {
+		t.Errorf("create body = %s", body)
+	}
+}
@@ -0,0 +1,218 @@
+package fly
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// Scaler starts a sibling machine once a tenant's in-flight requests have
+// stayed at or above scale_up.max_in_flight for scale_up.window. It prefers
+// starting a stopped or suspended machine in one of scale_up.regions;
+// without one, it creates a machine there with this machine's config.
+type Scaler struct {
+	mu          sync.Mutex
+	clock       clock.Clock
+	self        utils.FlyInstance
+	api         MachinesAPI
+	cfg         config.FlyScaleUpConfig
+	load        func() map[string]int // Each running tenant's in-flight requests
+	highSince   map[string]time.Time  // When each tenant's load was first sampled high, for as long as it stays high
+	lastScaleUp time.Time             // The last attempt, which starts the cooldown whether or not it succeeded
+	timer       clock.Timer
+	generation  int // Incremented as sampling stops, so a sample in progress doesn't reschedule
+}
+
+// NewScaler returns a scaler with scale-up disabled
+func NewScaler(clk clock.Clock) *Scaler {
+	return &Scaler{clock: clock.Or(clk), self: utils.Fly()}
+}
+
+// defaultScaler scales the app Navigator runs in
+var defaultScaler = NewScaler(clock.Real)
+
+// Configure sets the default scaler up for cfg, with tenants' load reported
+// by load
+func Configure(cfg *config.Config, load func() map[string]int) {
+	api := cfg.Fly.MachinesAPI
+	if !api.Enabled || !api.ScaleUp.Enabled {
+		defaultScaler.Configure(api.ScaleUp, nil, nil)
+		return
+	}
+	defaultScaler.Configure(api.ScaleUp, NewClient(api), load)
+}
+
+// Stop stops the default scaler
+func Stop() {
+	defaultScaler.Stop()
+}
+
+// Configure replaces the scaler's settings and starts sampling tenants'
+// load; with a nil api it stops. The cooldown of an earlier scale-up still
+// applies.
+func (s *Scaler) Configure(cfg config.FlyScaleUpConfig, api MachinesAPI, load func() map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	if api == nil || load == nil || !cfg.Enabled {
+		return
+	}
+	s.cfg = cfg
+	s.api = api
+	s.load = load
+	s.highSince = make(map[string]time.Time)
+	s.schedule()
+}
+
+// Stop stops sampling tenants' load
+func (s *Scaler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+// stop cancels the next sample; s.mu must be held
+func (s *Scaler) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.api = nil
+	s.generation++
+}
+
+// schedule arranges the next sample; s.mu must be held
+func (s *Scaler) schedule() {
+	s.timer = s.clock.AfterFunc(s.cfg.Interval.OrDefault(config.DefaultFlyScaleUpInterval), s.sample)
+}
+
+// sample records which tenants' load is high, and scales up once one has
+// been high for the whole window
+func (s *Scaler) sample() {
+	s.mu.Lock()
+	if s.api == nil {
+		s.mu.Unlock()
+		return
+	}
+	api, load, generation := s.api, s.load, s.generation
+	s.mu.Unlock()
+
+	// Read outside the lock: the app manager takes its own
+	inFlight := load()
+
+	s.mu.Lock()
+	if s.generation != generation {
+		s.mu.Unlock()
+		return
+	}
+	now := s.clock.Now()
+	var tenant string
+	for name, n := range inFlight {
+		if n < s.cfg.MaxInFlight {
+			continue
+		}
+		if _, ok := s.highSince[name]; !ok {
+			s.highSince[name] = now
+		}
+		if now.Sub(s.highSince[name]) >= s.cfg.Window.OrDefault(config.DefaultFlyScaleUpWindow) && (tenant == "" || name < tenant) {
+			tenant = name
+		}
+	}
+	for name := range s.highSince {
+		if inFlight[name] < s.cfg.MaxInFlight {
+			delete(s.highSince, name)
+		}
+	}
+	cooling := !s.lastScaleUp.IsZero() && now.Sub(s.lastScaleUp) < s.cfg.Cooldown.OrDefault(config.DefaultFlyScaleUpCooldown)
+	if tenant == "" || cooling {
+		s.schedule()
+		s.mu.Unlock()
+		return
+	}
+	s.lastScaleUp = now
+	s.highSince = make(map[string]time.Time)
+	regions := s.cfg.Regions
+	s.mu.Unlock()
+
+	slog.Info("Sustained load, scaling up", "tenant", tenant, "in_flight", inFlight[tenant])
+	ctx, cancel := context.WithTimeout(context.Background(), config.FlyAPITimeout)
+	if err := s.scaleUp(ctx, api, regions); err != nil {
+		slog.Error("Failed to scale up", "tenant", tenant, "error", err)
+	}
+	cancel()
+
+	s.mu.Lock()
+	if s.generation == generation {
+		s.schedule()
+	}
+	s.mu.Unlock()
+}
+
+// scaleUp makes sure a sibling machine runs in one of regions, other than
+// this machine's
+func (s *Scaler) scaleUp(ctx context.Context, api MachinesAPI, regions []string) error {
+	var candidates []string
+	for _, region := range regions {
+		if region != s.self.Region {
+			candidates = append(candidates, region)
+		}
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no scale_up region other than %s", s.self.Region)
+	}
+
+	machines, err := api.List(ctx)
+	if err != nil {
+		return err
+	}
+	preference := make(map[string]int, len(candidates))
+	for i, region := range candidates {
+		preference[region] = i + 1
+	}
+	var siblings []Machine
+	for _, machine := range machines {
+		if machine.ID != s.self.MachineID && preference[machine.Region] > 0 {
+			siblings = append(siblings, machine)
+		}
+	}
+	sort.SliceStable(siblings, func(i, j int) bool {
+		return preference[siblings[i].Region] < preference[siblings[j].Region]
+	})
+
+	for _, machine := range siblings {
+		if machine.State == StateStarted {
+			slog.Info("Sibling machine already running", "machine", machine.ID, "region", machine.Region)
+			return nil
+		}
+	}
+	for _, machine := range siblings {
+		if machine.State == StateStopped || machine.State == StateSuspended {
+			if err := api.Start(ctx, machine.ID); err != nil {
+				return err
+			}
+			slog.Info("Started sibling machine", "machine", machine.ID, "region", machine.Region)
+			return nil
+		}
+	}
+
+	if s.self.MachineID == "" {
+		return fmt.Errorf("no sibling machine to start, and FLY_MACHINE_ID isn't set to clone this one")
+	}
+	self, err := api.Get(ctx, s.self.MachineID)
+	if err != nil {
+		return err
+	}
+	created, err := api.Create(ctx, CreateRequest{Region: candidates[0], Config: self.Config})
+	if err != nil {
+		return err
+	}
+	slog.Info("Created sibling machine", "machine", created.ID, "region", candidates[0])
+	return nil
+}
@@ -0,0 +1,161 @@
+package fly
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// fakeMachines is a Machines API with a fixed set of machines that records
+// the machines started and created
+type fakeMachines struct {
+	mu       sync.Mutex
+	machines []Machine
+	started  []string
+	created  []CreateRequest
+}
+
+func (f *fakeMachines) List(ctx context.Context) ([]Machine, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Machine(nil), f.machines...), nil
+}
+
+func (f *fakeMachines) Get(ctx context.Context, id string) (*Machine, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, machine := range f.machines {
+		if machine.ID == id {
+			return &machine, nil
+		}
+	}
+	return nil, context.DeadlineExceeded
+}
+
+func (f *fakeMachines) Start(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = append(f.started, id)
+	return nil
+}
+
+func (f *fakeMachines) Create(ctx context.Context, req CreateRequest) (*Machine, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created = append(f.created, req)
+	return &Machine{ID: "new", Region: req.Region, State: "created"}, nil
+}
+
+// newTestScaler returns a scaler running on machine self in iad that scales
+// up to ord or ams once a tenant has 10 requests in flight for a minute,
+// sampled every 10 seconds
+func newTestScaler(t *testing.T, api MachinesAPI, load func() map[string]int) *Scaler {
+	t.Helper()
+	clk := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s := NewScaler(clk)
+	s.self = utils.FlyInstance{Region: "iad", MachineID: "self"}
+	s.Configure(config.FlyScaleUpConfig{
+		Enabled:     true,
+		Regions:     []string{"iad", "ord", "ams"},
+		MaxInFlight: 10,
+		Window:      config.Duration(time.Minute),
+		Interval:    config.Duration(10 * time.Second),
+		Cooldown:    config.Duration(10 * time.Minute),
+	}, api, load)
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestScaleUpCreatesSibling(t *testing.T) {
+	api := &fakeMachines{machines: []Machine{
+		{ID: "self", Region: "iad", State: StateStarted, Config: json.RawMessage(`{"image":"registry.fly.io/showcase:v42","guest":{"cpus":2}}`)},
+		{ID: "other", Region: "iad", State: StateStopped}, // Same region as self
+	}}
+	var mu sync.Mutex
+	inFlight := 12
+	s := newTestScaler(t, api, func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return map[string]int{"2025/boston": inFlight, "2025/raleigh": 1}
+	})
+	clk := s.clock.(*clock.Fake)
+
+	clk.Advance(time.Minute) // High at 10s, 20s, ... 60s: not yet for a full minute
+	if len(api.created) != 0 {
+		t.Fatal("scaled up before the load was high for the whole window")
+	}
+	clk.Advance(10 * time.Second)
+	if len(api.created) != 1 {
+		t.Fatalf("created %d machines, want 1", len(api.created))
+	}
+	payload, _ := json.Marshal(api.created[0])
+	if string(payload) != `{"region":"ord","config":{"image":"registry.fly.io/showcase:v42","guest":{"cpus":2}}}` {
+		t.Errorf("create payload = %s, want this machine's config in the first other region", payload)
+	}
+
+	// The cooldown holds off another scale-up
+	clk.Advance(5 * time.Minute)
+	if len(api.created) != 1 {
+		t.Errorf("created %d machines during the cooldown, want 1", len(api.created))
+	}
+
+	// Load dropping restarts the window
+	mu.Lock()
+	inFlight = 3
+	mu.Unlock()
+	clk.Advance(5 * time.Minute)
+	mu.Lock()
+	inFlight = 12
+	mu.Unlock()
+	clk.Advance(50 * time.Second)
+	if len(api.created) != 1 {
+		t.Errorf("created %d machines before the load was high for a window again, want 1", len(api.created))
+	}
+}
+
+func TestScaleUpStartsStoppedSibling(t *testing.T) {
+	api := &fakeMachines{machines: []Machine{
+		{ID: "self", Region: "iad", State: StateStarted},
+		{ID: "amsterdam", Region: "ams", State: StateStopped},
+		{ID: "chicago", Region: "ord", State: StateSuspended},
+	}}
+	s := newTestScaler(t, api, func() map[string]int { return map[string]int{"2025/boston": 10} })
+	s.clock.(*clock.Fake).Advance(70 * time.Second)
+
+	if len(api.started) != 1 || api.started[0] != "chicago" || len(api.created) != 0 {
+		t.Errorf("started %v and created %v, want the suspended machine in ord, the preferred region", api.started, api.created)
+	}
+}
+
+func TestScaleUpWithSiblingRunning(t *testing.T) {
+	api := &fakeMachines{machines: []Machine{
+		{ID: "self", Region: "iad", State: StateStarted},
+		{ID: "chicago", Region: "ord", State: StateStopped},
+		{ID: "amsterdam", Region: "ams", State: StateStarted},
+	}}
+	s := newTestScaler(t, api, func() map[string]int { return map[string]int{"2025/boston": 10} })
+	s.clock.(*clock.Fake).Advance(70 * time.Second)
+
+	if len(api.started) != 0 || len(api.created) != 0 {
+		t.Errorf("started %v and created %v, want nothing while a sibling runs", api.started, api.created)
+	}
+}
+
+func TestScalerDisabled(t *testing.T) {
+	api := &fakeMachines{}
+	clk := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s := NewScaler(clk)
+	s.Configure(config.FlyScaleUpConfig{Regions: []string{"ord"}, MaxInFlight: 1}, api, func() map[string]int {
+		t.Error("load sampled while scale_up is disabled")
+		return nil
+	})
+	if clk.Pending() != 0 {
+		t.Errorf("%d timers pending while scale_up is disabled", clk.Pending())
+	}
+}
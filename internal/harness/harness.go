@@ -0,0 +1,299 @@
+// Package harness runs a navigator binary end to end against a copy of a
+// configuration whose durations are scaled down and whose tenants are served
+// by the built-in echo backend, and reports the lifecycle events it emits.
+// Tests use it to check idle timeouts, reloads and the like in seconds, with
+// no application runtime installed.
+package harness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
+)
+
+// Events the harness reports itself, alongside those navigator delivers
+const (
+	Listening = "harness.listening" // Navigator accepts connections
+	Exited    = "harness.exited"    // Navigator exited; details hold the error, if any
+)
+
+// hookName names the event destination the harness adds to the configuration
+const hookName = "harness"
+
+// Options configure a harnessed navigator
+type Options struct {
+	Binary string        // Navigator binary (default: $NAVIGATOR_BINARY, else navigator on the PATH)
+	Config string        // Configuration file to run
+	Scale  float64       // Factor applied to every duration (0 = 1, unscaled)
+	Output io.Writer     // Receives navigator's output (nil = discarded)
+	Ready  time.Duration // How long to wait for navigator to listen (default: 10s)
+}
+
+// Harness is a running navigator
+type Harness struct {
+	URL    string              // Base URL navigator serves
+	Events <-chan events.Event // Events in the order they were delivered
+
+	opts    Options
+	dir     string
+	file    string
+	port    int
+	hookURL string
+	cmd     *exec.Cmd
+	events  chan events.Event
+	hooks   *http.Server
+	exited  chan struct{}
+	stopped sync.Once
+}
+
+// Start prepares the configuration and runs navigator with it, returning
+// once navigator accepts connections
+func Start(opts Options) (*Harness, error) {
+	if opts.Binary == "" {
+		opts.Binary = os.Getenv("NAVIGATOR_BINARY")
+	}
+	if opts.Binary == "" {
+		opts.Binary = "navigator"
+	}
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	if opts.Ready == 0 {
+		opts.Ready = 10 * time.Second
+	}
+
+	h := &Harness{
+		opts:   opts,
+		events: make(chan events.Event, 256),
+		exited: make(chan struct{}),
+	}
+	h.Events = h.events
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for events: %w", err)
+	}
+	h.hookURL = fmt.Sprintf("http://%s/events", listener.Addr())
+	h.hooks = &http.Server{Handler: http.HandlerFunc(h.receive)}
+	go func() { _ = h.hooks.Serve(listener) }()
+
+	if h.port, err = freePort(); err != nil {
+		_ = h.hooks.Close()
+		return nil, err
+	}
+	h.URL = fmt.Sprintf("http://127.0.0.1:%d", h.port)
+
+	if h.dir, err = os.MkdirTemp("", "navigator-harness-"); err != nil {
+		_ = h.hooks.Close()
+		return nil, err
+	}
+	h.file = filepath.Join(h.dir, "navigator.yml")
+	if err := h.prepare(); err != nil {
+		h.cleanup()
+		return nil, err
+	}
+
+	h.cmd = exec.Command(opts.Binary, h.file)
+	h.cmd.Stdout = opts.Output
+	h.cmd.Stderr = opts.Output
+	if err := h.cmd.Start(); err != nil {
+		h.cleanup()
+		return nil, fmt.Errorf("failed to start %s: %w", opts.Binary, err)
+	}
+	go func() {
+		err := h.cmd.Wait()
+		details := map[string]interface{}{}
+		if err != nil {
+			details["error"] = err.Error()
+		}
+		h.emit(Exited, details)
+		close(h.exited)
+	}()
+
+	if err := h.waitListening(); err != nil {
+		_ = h.Stop()
+		return nil, err
+	}
+	h.emit(Listening, map[string]interface{}{"url": h.URL})
+	return h, nil
+}
+
+// Reload prepares the configuration file again, picking up changes to it,
+// and has navigator reload it
+func (h *Harness) Reload() error {
+	if err := h.prepare(); err != nil {
+		return err
+	}
+	return h.cmd.Process.Signal(syscall.SIGHUP)
+}
+
+// Stop shuts navigator down gracefully and waits for it to exit
+func (h *Harness) Stop() error {
+	var err error
+	h.stopped.Do(func() {
+		select {
+		case <-h.exited:
+		default:
+			err = h.cmd.Process.Signal(syscall.SIGTERM)
+			<-h.exited
+		}
+		h.cleanup()
+	})
+	return err
+}
+
+// WaitFor returns the next event of the given type, discarding others
+// delivered before it
+func (h *Harness) WaitFor(eventType string, timeout time.Duration) (events.Event, error) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case event := <-h.events:
+			if event.Type == eventType {
+				return event, nil
+			}
+		case <-deadline:
+			return events.Event{}, fmt.Errorf("no %s event within %v", eventType, timeout)
+		}
+	}
+}
+
+// prepare writes the configuration navigator runs: the source with its
+// durations scaled, listening on the harness's port, with every tenant
+// served by the echo backend and events delivered to the harness. Its PID
+// file is kept beside it, so harnessed navigators can run side by side.
+func (h *Harness) prepare() error {
+	content, err := os.ReadFile(h.opts.Config)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if content, err = config.ScaleDurations(content, h.opts.Scale); err != nil {
+		return err
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if document == nil {
+		document = map[string]interface{}{}
+	}
+
+	server := section(document, "server")
+	server["listen"] = strconv.Itoa(h.port)
+	server["pid_file"] = filepath.Join(h.dir, "navigator.pid")
+
+	applications := section(document, "applications")
+	if tenants, ok := applications["tenants"].([]interface{}); ok {
+		for _, tenant := range tenants {
+			if tenant, ok := tenant.(map[string]interface{}); ok {
+				delete(tenant, "server")
+				delete(tenant, "args")
+				tenant["framework"] = config.RuntimeInternalEcho
+				tenant["runtime"] = config.RuntimeInternalEcho
+			}
+		}
+	}
+
+	hooks := section(document, "hooks")
+	destinations, _ := hooks["events"].([]interface{})
+	hooks["events"] = append(destinations, map[string]interface{}{
+		"name": hookName,
+		"url":  h.hookURL,
+	})
+
+	prepared, err := yaml.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.file, prepared, 0644)
+}
+
+// receive queues an event navigator delivered
+func (h *Harness) receive(w http.ResponseWriter, r *http.Request) {
+	var event events.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.enqueue(event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// emit queues an event of the harness's own
+func (h *Harness) emit(eventType string, details map[string]interface{}) {
+	h.enqueue(events.Event{Type: eventType, Timestamp: time.Now(), Details: details})
+}
+
+// enqueue adds an event to Events, dropping it if nobody is reading
+func (h *Harness) enqueue(event events.Event) {
+	select {
+	case h.events <- event:
+	default:
+	}
+}
+
+// waitListening polls navigator's port until it accepts a connection
+func (h *Harness) waitListening() error {
+	deadline := time.Now().Add(h.opts.Ready)
+	address := fmt.Sprintf("127.0.0.1:%d", h.port)
+	for {
+		conn, err := net.DialTimeout("tcp", address, 100*time.Millisecond)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		select {
+		case <-h.exited:
+			return errors.New("navigator exited before listening")
+		default:
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("navigator not listening within %v: %w", h.opts.Ready, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// cleanup stops receiving events and removes the prepared configuration
+func (h *Harness) cleanup() {
+	_ = h.hooks.Close()
+	_ = os.RemoveAll(h.dir)
+}
+
+// section returns the mapping under key, adding an empty one if missing
+func section(document map[string]interface{}, key string) map[string]interface{} {
+	if m, ok := document[key].(map[string]interface{}); ok {
+		return m
+	}
+	m := map[string]interface{}{}
+	document[key] = m
+	return m
+}
+
+// freePort returns a TCP port nothing is listening on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer func() { _ = listener.Close() }()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,179 @@
+package harness
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/events"
+)
+
+// navigatorBinary is built once for every test, unless NAVIGATOR_BINARY
+// names one; empty when it couldn't be built
+var navigatorBinary string
+
+func TestMain(m *testing.M) {
+	navigatorBinary = os.Getenv("NAVIGATOR_BINARY")
+	dir := ""
+	if navigatorBinary == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "navigator-harness-test-"); err == nil {
+			binary := filepath.Join(dir, "navigator")
+			build := exec.Command("go", "build", "-o", binary, "github.com/rubys/navigator/cmd/navigator")
+			build.Stderr = os.Stderr
+			if build.Run() == nil {
+				navigatorBinary = binary
+			}
+		}
+	}
+	code := m.Run()
+	if dir != "" {
+		_ = os.RemoveAll(dir)
+	}
+	os.Exit(code)
+}
+
+// startHarness runs navigator with content as its configuration, scaled
+func startHarness(t *testing.T, content string, scale float64) (*Harness, string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs navigator end to end")
+	}
+	if navigatorBinary == "" {
+		t.Skip("navigator binary could not be built")
+	}
+	configFile := filepath.Join(t.TempDir(), "navigator.yml")
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := Start(Options{Binary: navigatorBinary, Config: configFile, Scale: scale, Output: testWriter{t}})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = h.Stop() })
+	return h, configFile
+}
+
+// testWriter sends navigator's output to the test log
+type testWriter struct{ t *testing.T }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(string(p))
+	return len(p), nil
+}
+
+func get(t *testing.T, url string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", url, resp.StatusCode)
+	}
+}
+
+func waitFor(t *testing.T, h *Harness, eventType string) events.Event {
+	t.Helper()
+	event, err := h.WaitFor(eventType, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestIdleTimeoutFiresAfterLastRequest(t *testing.T) {
+	// A twenty minute timeout, scaled to 1.2s
+	h, _ := startHarness(t, `
+server:
+  idle:
+    action: suspend
+    timeout: 20m
+applications:
+  tenants:
+    - name: one
+      path: /one/
+      runtime: ruby
+      server: bin/rails
+      args: [server]
+`, 0.001)
+
+	get(t, h.URL+"/one/")
+	started := waitFor(t, h, events.TenantStarted)
+	if started.Details["tenant"] != "one" {
+		t.Errorf("tenant.started details = %v, want tenant one", started.Details)
+	}
+
+	idled := waitFor(t, h, events.IdleTriggered)
+	if idled.Details["action"] != "suspend" {
+		t.Errorf("idle.triggered details = %v, want action suspend", idled.Details)
+	}
+	if waited := idled.Timestamp.Sub(started.Timestamp); waited < time.Second {
+		t.Errorf("idle.triggered %v after the request, want the scaled timeout of 1.2s", waited)
+	}
+}
+
+func TestReloadAppliesChangedConfig(t *testing.T) {
+	content := `
+server:
+  health_check:
+    detailed_path: /_navigator/health
+applications:
+  tenants:
+    - name: one
+      path: /one/
+`
+	h, configFile := startHarness(t, content, 0.01)
+	before := configHash(t, h)
+
+	content += `    - name: two
+      path: /two/
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	waitFor(t, h, events.ReloadSucceeded)
+	if after := configHash(t, h); after == before {
+		t.Errorf("config hash %s unchanged by reload", after)
+	}
+
+	get(t, h.URL+"/two/")
+	if started := waitFor(t, h, events.TenantStarted); started.Details["tenant"] != "two" {
+		t.Errorf("tenant.started details = %v, want tenant two", started.Details)
+	}
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if exited := waitFor(t, h, Exited); exited.Details["error"] != nil {
+		t.Errorf("navigator exited with %v", exited.Details["error"])
+	}
+}
+
+// configHash returns the configuration hash navigator reports
+func configHash(t *testing.T, h *Harness) string {
+	t.Helper()
+	resp, err := http.Get(h.URL + "/_navigator/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var report struct {
+		ConfigHash string `json:"config_sha256"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.ConfigHash == "" {
+		t.Fatalf("no config hash in the health report from %s", h.URL)
+	}
+	return report.ConfigHash
+}
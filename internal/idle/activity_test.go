@@ -0,0 +1,246 @@
+package idle
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/fly"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+func newActivityTestManager(countStatic, countHealthChecks bool) (*Manager, *clock.Fake) {
+	cfg := &config.Config{}
+	cfg.Server.Idle.Action = "suspend"
+	cfg.Server.Idle.Timeout = config.Duration(10 * time.Minute)
+	cfg.Server.Idle.CountStaticRequests = countStatic
+	cfg.Server.Idle.CountHealthChecks = countHealthChecks
+
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := newManagerWithClock(cfg, "", time.Time{}, nil, clk)
+	m.EnableTestMode()
+	return m, clk
+}
+
+func (m *Manager) hasIdleActioned() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.idleActioned
+}
+
+func TestIdleActivityFlags(t *testing.T) {
+	tests := []struct {
+		name              string
+		countStatic       bool
+		countHealthChecks bool
+		kind              RequestKind
+		resetsTimer       bool
+	}{
+		{"dynamic always counts", false, false, RequestDynamic, true},
+		{"static counted", true, false, RequestStatic, true},
+		{"static not counted", false, false, RequestStatic, false},
+		{"static not counted with health checks counted", false, true, RequestStatic, false},
+		{"health check counted", false, true, RequestHealthCheck, true},
+		{"health check not counted", true, false, RequestHealthCheck, false},
+		{"both counted health check", true, true, RequestHealthCheck, true},
+		{"both counted static", true, true, RequestStatic, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, clk := newActivityTestManager(tt.countStatic, tt.countHealthChecks)
+
+			// A request of the given kind 8 minutes into a 10 minute idle timeout
+			clk.Advance(8 * time.Minute)
+			m.RequestStarted()
+			m.RequestCompleted(tt.kind)
+
+			clk.Advance(2*time.Minute + time.Second)
+			if actioned := m.hasIdleActioned(); actioned == tt.resetsTimer {
+				t.Fatalf("after original timeout: idle actioned = %v, want %v", actioned, !tt.resetsTimer)
+			}
+
+			if tt.resetsTimer {
+				clk.Advance(8 * time.Minute)
+				if !m.hasIdleActioned() {
+					t.Error("expected idle action 10 minutes after the counted request")
+				}
+			}
+		})
+	}
+}
+
+func TestIdlePeriodicHealthChecksDoNotKeepMachineAwake(t *testing.T) {
+	m, clk := newActivityTestManager(true, false)
+
+	// Fly-style health checks every 15 seconds for longer than the idle timeout
+	for i := 0; i < 60 && !m.hasIdleActioned(); i++ {
+		m.RequestStarted()
+		m.RequestCompleted(RequestHealthCheck)
+		clk.Advance(15 * time.Second)
+	}
+
+	if !m.hasIdleActioned() {
+		t.Error("health checks should not prevent the idle action")
+	}
+}
+
+func TestIdleInFlightRequestDefersAction(t *testing.T) {
+	for _, kind := range []RequestKind{RequestDynamic, RequestStatic, RequestHealthCheck} {
+		m, clk := newActivityTestManager(false, false)
+
+		// A long static download starts just before the idle timeout
+		clk.Advance(9 * time.Minute)
+		m.RequestStarted()
+		clk.Advance(30 * time.Minute)
+		if m.hasIdleActioned() {
+			t.Fatalf("kind %d: idle action ran while a response was in flight", kind)
+		}
+
+		m.RequestCompleted(kind)
+		if kind == RequestDynamic {
+			// Counted request resets the full timeout
+			clk.Advance(9 * time.Minute)
+			if m.hasIdleActioned() {
+				t.Fatalf("kind %d: idle action ran before timeout after counted request", kind)
+			}
+			clk.Advance(time.Minute)
+		} else {
+			// Uncounted request: the idle period has already elapsed
+			clk.Advance(0)
+		}
+
+		if !m.hasIdleActioned() {
+			t.Errorf("kind %d: expected idle action after the in-flight request completed", kind)
+		}
+	}
+}
+
+func TestIdleZeroTenantsSuspends(t *testing.T) {
+	m, clk := newActivityTestManager(false, false)
+	if len(m.config.Applications.Tenants) != 0 {
+		t.Fatal("expected no tenants")
+	}
+
+	clk.Advance(10 * time.Minute)
+	if !m.hasIdleActioned() {
+		t.Error("machine with zero tenants and no activity should reach idle action")
+	}
+}
+
+func TestIdleActionDeferredByBackgroundWork(t *testing.T) {
+	m, clk := newActivityTestManager(true, false)
+	m.deferFor = config.DefaultIdleDeferFor
+
+	// A long-running CGI script, such as a backup, outlives the idle timeout
+	endCGI := utils.BeginActivity(config.IdleDeferCGI)
+	clk.Advance(10 * time.Minute)
+	clk.Advance(time.Hour)
+	if m.hasIdleActioned() {
+		endCGI()
+		t.Fatal("Idle action should be deferred while a CGI script runs")
+	}
+
+	// Its end counts as activity, restarting the idle timeout
+	endCGI()
+	clk.Advance(config.IdleDeferRecheckInterval)
+	clk.Advance(9 * time.Minute)
+	if m.hasIdleActioned() {
+		t.Fatal("Idle action should wait a full timeout after the script ends")
+	}
+	clk.Advance(time.Minute)
+	if !m.hasIdleActioned() {
+		t.Error("Idle action should run once the timeout passes after the script ends")
+	}
+}
+
+func TestIdleActionNotDeferredForUnlistedWork(t *testing.T) {
+	m, clk := newActivityTestManager(true, false)
+	m.deferFor = []string{config.IdleDeferHooks}
+
+	endCGI := utils.BeginActivity(config.IdleDeferCGI)
+	defer endCGI()
+	clk.Advance(10 * time.Minute)
+	if !m.hasIdleActioned() {
+		t.Error("A CGI script shouldn't defer the idle action when defer_for omits cgi")
+	}
+}
+
+func TestIdleHookVetoesIdleAction(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Idle hook uses /bin/sh")
+	}
+	m, clk := newActivityTestManager(true, false)
+	m.config.Hooks.Idle = []config.HookConfig{{Command: "exit 75", Shell: true}}
+
+	clk.Advance(10 * time.Minute)
+	if m.hasIdleActioned() {
+		t.Fatal("An idle hook exiting 75 should veto the idle action")
+	}
+
+	// The check runs again a full timeout later, when the hook may allow it
+	m.config.Hooks.Idle = []config.HookConfig{{Command: "exit 1", Shell: true}}
+	clk.Advance(10 * time.Minute)
+	if !m.hasIdleActioned() {
+		t.Error("An idle hook failing with another status shouldn't veto the idle action")
+	}
+}
+
+// listMachines is a Machines API that only lists machines
+type listMachines struct {
+	fly.MachinesAPI
+	machines []fly.Machine
+	err      error
+}
+
+func (l *listMachines) List(ctx context.Context) ([]fly.Machine, error) {
+	return l.machines, l.err
+}
+
+func TestLastRunningMachineVetoesIdleAction(t *testing.T) {
+	m, clk := newActivityTestManager(true, false)
+	api := &listMachines{machines: []fly.Machine{{ID: "sibling", Region: "ord", State: fly.StateStopped}}}
+	m.machines = api
+
+	clk.Advance(10 * time.Minute)
+	if m.hasIdleActioned() {
+		t.Fatal("The idle action shouldn't stop the app's last running machine")
+	}
+	if err := m.Suspend(); err == nil || m.hasIdleActioned() {
+		t.Errorf("Suspend() error = %v, want a veto", err)
+	}
+
+	// Checked again a full timeout later, when a sibling is running
+	api.machines[0].State = fly.StateStarted
+	clk.Advance(10 * time.Minute)
+	if !m.hasIdleActioned() {
+		t.Error("The idle action should go ahead while another machine runs")
+	}
+}
+
+func TestLastRunningMachineCheck(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Fly.MachinesAPI.Enabled = true
+	if machinesAPI(cfg) == nil {
+		t.Error("fly.machines_api should check for the last running machine")
+	}
+	cfg.Fly.MachinesAPI.AllowZero = true
+	if machinesAPI(cfg) != nil {
+		t.Error("allow_zero should skip the check")
+	}
+	if machinesAPI(&config.Config{}) != nil {
+		t.Error("Without fly.machines_api there should be no check")
+	}
+
+	// An API failure lets the idle action go ahead
+	m, clk := newActivityTestManager(true, false)
+	m.machines = &listMachines{err: errors.New("unavailable")}
+	clk.Advance(10 * time.Minute)
+	if !m.hasIdleActioned() {
+		t.Error("The idle action should go ahead when the machines can't be listed")
+	}
+}
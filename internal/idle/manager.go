@@ -1,44 +1,79 @@
 package idle
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os/exec"
 	"sync"
 	"time"
 
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
+	"github.com/rubys/navigator/internal/fly"
 	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// RequestKind classifies a request for idle activity accounting
+type RequestKind int
+
+const (
+	RequestDynamic     RequestKind = iota // Application, proxy, CGI and other requests (always activity)
+	RequestStatic                         // Static files served from public_dir
+	RequestHealthCheck                    // Requests to server.health_check.path
 )
 
 // Manager tracks active requests and handles machine idle actions
 type Manager struct {
-	enabled        bool
-	action         string // "suspend" or "stop"
-	idleTimeout    time.Duration
-	activeRequests int64
-	lastActivity   time.Time
-	mutex          sync.RWMutex
-	timer          *time.Timer
-	config         *config.Config
-	configFile     string                  // Current config file path for reload_config support
-	configLoadTime time.Time               // When the config was last loaded (for reload detection)
-	reloadCallback func(configPath string) // Callback to trigger config reload
-	idleActioned   bool                    // Track if idle action was performed
-	resuming       bool                    // Track if resume hooks are currently running
-	resumeCond     *sync.Cond              // Condition variable to wait for resume completion
-	testMode       bool                    // Prevents actual signal sending during tests
+	enabled           bool
+	action            string // "suspend" or "stop"
+	idleTimeout       time.Duration
+	countStatic       bool // Static file requests reset the idle timer
+	countHealthChecks bool // Health check requests reset the idle timer
+	activeRequests    int64
+	lastActivity      time.Time
+	mutex             sync.RWMutex
+	clock             clock.Clock
+	timer             clock.Timer
+	config            *config.Config
+	configFile        string                     // Current config file path for reload_config support
+	configLoadTime    time.Time                  // When the config was last loaded (for reload detection)
+	reloadCallback    func(utils.ReloadDecision) // Callback to trigger config reload
+	idleActioned      bool                       // Track if idle action was performed
+	resuming          bool                       // Track if resume hooks are currently running
+	resumeCond        *sync.Cond                 // Condition variable to wait for resume completion
+	testMode          bool                       // Prevents actual signal sending during tests
+
+	deferFor []string // Kinds of background work that defer the idle action
+	deferred bool     // The last idle check was deferred by background work
+
+	tenants        map[string]time.Time        // Each tenant's last request, as restored and last saved
+	tenantActivity func() map[string]time.Time // Reports running tenants' last requests for saving
+	saveTimer      clock.Timer                 // Next periodic save of the idle state
+
+	machines fly.MachinesAPI // Checked for other running machines before the idle action (nil = never)
 }
 
 // NewManager creates a new idle manager
 // The reloadCallback is called when a resume hook specifies reload_config and the config file was modified
 // configLoadTime is when the config was last loaded (for detecting changes since last load)
-func NewManager(cfg *config.Config, configFile string, configLoadTime time.Time, reloadCallback func(configPath string)) *Manager {
+func NewManager(cfg *config.Config, configFile string, configLoadTime time.Time, reloadCallback func(utils.ReloadDecision)) *Manager {
+	return newManagerWithClock(cfg, configFile, configLoadTime, reloadCallback, clock.Real)
+}
+
+// newManagerWithClock creates an idle manager using the given clock
+func newManagerWithClock(cfg *config.Config, configFile string, configLoadTime time.Time, reloadCallback func(utils.ReloadDecision), clk clock.Clock) *Manager {
 	m := &Manager{
 		config:         cfg,
 		configFile:     configFile,
 		configLoadTime: configLoadTime,
 		reloadCallback: reloadCallback,
-		lastActivity:   time.Now(),
+		clock:          clk,
+		lastActivity:   clk.Now(),
+		machines:       machinesAPI(cfg),
 	}
 
 	// Initialize condition variable
@@ -48,27 +83,29 @@ func NewManager(cfg *config.Config, configFile string, configLoadTime time.Time,
 	if cfg.Server.Idle.Action != "" && (cfg.Server.Idle.Action == "suspend" || cfg.Server.Idle.Action == "stop") {
 		m.enabled = true
 		m.action = cfg.Server.Idle.Action
+		m.countStatic = cfg.Server.Idle.CountStaticRequests
+		m.countHealthChecks = cfg.Server.Idle.CountHealthChecks
+		m.deferFor = cfg.Server.Idle.DeferFor
 
-		// Parse idle timeout
-		if cfg.Server.Idle.Timeout != "" {
-			if duration, err := time.ParseDuration(cfg.Server.Idle.Timeout); err == nil {
-				m.idleTimeout = duration
-			} else {
-				m.idleTimeout = config.DefaultIdleTimeout
-			}
-		} else {
-			m.idleTimeout = config.DefaultIdleTimeout
-		}
+		m.idleTimeout = cfg.Server.Idle.Timeout.OrDefault(config.DefaultIdleTimeout)
 
 		slog.Info("Machine idle management enabled",
 			"action", m.action,
 			"timeout", m.idleTimeout)
 
+		// Pick up the idle clock where the last run left off
+		if cfg.Server.Idle.State.Enabled {
+			m.restoreState()
+		}
+
 		// Start idle timer immediately since activeRequests is 0 at boot
-		m.timer = time.AfterFunc(m.idleTimeout, m.handleIdle)
+		remaining := max(m.idleTimeout-m.clock.Now().Sub(m.lastActivity), 0)
+		m.timer = m.clock.AfterFunc(remaining, m.handleIdle)
 		slog.Info("Started idle timer at boot",
-			"timeout", m.idleTimeout,
+			"timeout", remaining,
 			"action", m.action)
+
+		m.scheduleStateSave()
 	}
 
 	return m
@@ -103,15 +140,15 @@ func (m *Manager) RequestStarted() {
 		// Execute resume hooks asynchronously
 		go func() {
 			slog.Info("Executing server resume hooks")
-			result := process.ExecuteServerHooksWithReload(m.config.Hooks.Resume, "resume", configFile, configLoadTime)
+			result := process.ExecuteServerHooksWithReload(context.Background(), m.config.Hooks.Resume, "resume", configFile, configLoadTime)
 			if result.Error != nil {
 				slog.Error("Failed to execute resume hooks", "error", result.Error)
-			} else if result.ReloadDecision.ShouldReload && reloadCallback != nil {
+			} else if result.ReloadDecision.Requested() && reloadCallback != nil {
 				// Resume hook triggered config reload
 				slog.Info("Resume hook triggered config reload",
 					"reason", result.ReloadDecision.Reason,
 					"configFile", result.ReloadDecision.NewConfigFile)
-				reloadCallback(result.ReloadDecision.NewConfigFile)
+				reloadCallback(result.ReloadDecision)
 			}
 
 			m.mutex.Lock()
@@ -121,8 +158,9 @@ func (m *Manager) RequestStarted() {
 		}()
 	}
 
+	// An in-flight request of any kind defers the idle action until it completes.
+	// Whether it counts as activity is decided when it finishes.
 	m.activeRequests++
-	m.lastActivity = time.Now()
 
 	// Cancel any pending idle timer
 	if m.timer != nil {
@@ -137,6 +175,12 @@ func (m *Manager) RequestStarted() {
 
 // RequestFinished decrements the active request counter and starts idle timer if needed
 func (m *Manager) RequestFinished() {
+	m.RequestCompleted(RequestDynamic)
+}
+
+// RequestCompleted decrements the active request counter, records activity
+// if the request kind counts toward it, and starts the idle timer if needed
+func (m *Manager) RequestCompleted(kind RequestKind) {
 	if !m.enabled {
 		return
 	}
@@ -148,21 +192,39 @@ func (m *Manager) RequestFinished() {
 		m.activeRequests--
 	}
 
-	m.lastActivity = time.Now()
+	if m.countsAsActivity(kind) {
+		m.lastActivity = m.clock.Now()
+	}
 
 	slog.Debug("Request finished",
 		"activeRequests", m.activeRequests,
 		"enabled", m.enabled)
 
-	// If no more active requests, start idle timer
+	// If no more active requests, start idle timer for the remaining idle period
 	if m.activeRequests == 0 && m.timer == nil {
-		m.timer = time.AfterFunc(m.idleTimeout, m.handleIdle)
+		remaining := m.idleTimeout - m.clock.Now().Sub(m.lastActivity)
+		if remaining < 0 {
+			remaining = 0
+		}
+		m.timer = m.clock.AfterFunc(remaining, m.handleIdle)
 		slog.Debug("Started idle timer",
-			"timeout", m.idleTimeout,
+			"timeout", remaining,
 			"action", m.action)
 	}
 }
 
+// countsAsActivity reports whether a finished request of this kind resets the idle timer
+func (m *Manager) countsAsActivity(kind RequestKind) bool {
+	switch kind {
+	case RequestStatic:
+		return m.countStatic
+	case RequestHealthCheck:
+		return m.countHealthChecks
+	default:
+		return true
+	}
+}
+
 // handleIdle performs the configured idle action
 func (m *Manager) handleIdle() {
 	m.mutex.Lock()
@@ -173,10 +235,30 @@ func (m *Manager) handleIdle() {
 	}
 
 	// Check if enough time has passed since last activity
-	if time.Since(m.lastActivity) < m.idleTimeout {
+	if idleFor := m.clock.Now().Sub(m.lastActivity); idleFor < m.idleTimeout {
 		// Reschedule
-		remaining := m.idleTimeout - time.Since(m.lastActivity)
-		m.timer = time.AfterFunc(remaining, m.handleIdle)
+		m.timer = m.clock.AfterFunc(m.idleTimeout-idleFor, m.handleIdle)
+		m.mutex.Unlock()
+		return
+	}
+
+	// Background work such as a CGI script or hook defers the action, checked
+	// again until it is done; its end then counts as activity
+	if busy := utils.ActivityCounts(m.deferFor); len(busy) > 0 {
+		log := slog.Debug
+		if !m.deferred {
+			log = slog.Info
+		}
+		log("Idle action deferred", "action", m.action, "deferred_by", busy)
+		m.deferred = true
+		m.timer = m.clock.AfterFunc(config.IdleDeferRecheckInterval, m.handleIdle)
+		m.mutex.Unlock()
+		return
+	}
+	if m.deferred {
+		m.deferred = false
+		m.lastActivity = m.clock.Now()
+		m.timer = m.clock.AfterFunc(m.idleTimeout, m.handleIdle)
 		m.mutex.Unlock()
 		return
 	}
@@ -185,12 +267,35 @@ func (m *Manager) handleIdle() {
 	m.idleActioned = true // Mark that idle action was performed
 	m.mutex.Unlock()
 
-	// Execute idle hooks
+	events.Emit(events.IdleTriggered, map[string]interface{}{"action": action})
+
+	// Execute idle hooks, any of which may veto the action, as does being the
+	// app's last running machine
 	slog.Info("Executing server idle hooks before machine idle action", "action", action)
-	if err := process.ExecuteServerHooks(m.config.Hooks.Idle, "idle"); err != nil {
-		slog.Error("Failed to execute idle hooks", "error", err)
+	vetoed := m.runIdleHooks()
+	if vetoed {
+		slog.Info("Idle action vetoed by idle hook", "action", action)
+	} else if m.lastRunningMachine() {
+		slog.Info("Idle action vetoed: last running machine of the app", "action", action)
+		vetoed = true
+	}
+	if vetoed {
+		m.mutex.Lock()
+		m.idleActioned = false
+		m.lastActivity = m.clock.Now()
+		if m.activeRequests == 0 {
+			if m.timer != nil {
+				m.timer.Stop()
+			}
+			m.timer = m.clock.AfterFunc(m.idleTimeout, m.handleIdle)
+		}
+		m.mutex.Unlock()
+		return
 	}
 
+	// Deliver queued events while the machine is still running
+	events.Flush(config.EventFlushTimeout)
+
 	// Perform the idle action
 	switch action {
 	case "suspend":
@@ -202,19 +307,71 @@ func (m *Manager) handleIdle() {
 	}
 }
 
+// runIdleHooks runs hooks.idle, reporting whether one vetoed the idle action
+// by exiting with config.IdleHookVetoExitCode. Other failures are logged and
+// the action goes ahead.
+func (m *Manager) runIdleHooks() (vetoed bool) {
+	err := process.ExecuteServerHooks(context.Background(), m.config.Hooks.Idle, "idle")
+	if err == nil {
+		return false
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == config.IdleHookVetoExitCode {
+		return true
+	}
+	slog.Error("Failed to execute idle hooks", "error", err)
+	return false
+}
+
+// machinesAPI returns the client lastRunningMachine checks with, or nil
+// unless fly.machines_api is enabled without allow_zero
+func machinesAPI(cfg *config.Config) fly.MachinesAPI {
+	api := cfg.Fly.MachinesAPI
+	if !api.Enabled || api.AllowZero {
+		return nil
+	}
+	return fly.NewClient(api)
+}
+
+// lastRunningMachine reports whether no other machine of the app is
+// running, so the idle action would scale it to zero. If the machines can't
+// be listed, the action goes ahead.
+func (m *Manager) lastRunningMachine() bool {
+	m.mutex.RLock()
+	api := m.machines
+	m.mutex.RUnlock()
+	if api == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.FlyAPITimeout)
+	defer cancel()
+	machines, err := api.List(ctx)
+	if err != nil {
+		slog.Error("Failed to list the app's machines, idle action goes ahead", "error", err)
+		return false
+	}
+	return fly.OthersRunning(machines, utils.Fly().MachineID) == 0
+}
+
 // suspendMachine and stopMachine are implemented in platform-specific files:
 // - signals_unix.go for Unix/Linux/macOS
 // - signals_windows.go for Windows
 
-// Stop cancels any pending idle timer
+// Stop cancels any pending idle timer and saves the idle state
 func (m *Manager) Stop() {
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
 	if m.timer != nil {
 		m.timer.Stop()
 		m.timer = nil
 	}
+	if m.saveTimer != nil {
+		m.saveTimer.Stop()
+		m.saveTimer = nil
+	}
+	m.mutex.Unlock()
+
+	m.SaveState()
 }
 
 // Suspend suspends the machine immediately (for external trigger)
@@ -227,10 +384,22 @@ func (m *Manager) Suspend() error {
 	m.idleActioned = true
 	m.mutex.Unlock()
 
+	events.Emit(events.IdleTriggered, map[string]interface{}{"action": "suspend"})
+
 	// Execute idle hooks before suspension
-	if err := process.ExecuteServerHooks(m.config.Hooks.Idle, "idle"); err != nil {
-		slog.Error("Failed to execute idle hooks", "error", err)
+	if m.runIdleHooks() {
+		m.mutex.Lock()
+		m.idleActioned = false
+		m.mutex.Unlock()
+		return fmt.Errorf("machine suspension vetoed by idle hook")
 	}
+	if m.lastRunningMachine() {
+		m.mutex.Lock()
+		m.idleActioned = false
+		m.mutex.Unlock()
+		return fmt.Errorf("machine suspension vetoed: last running machine of the app")
+	}
+	events.Flush(config.EventFlushTimeout)
 
 	m.suspendMachine()
 	return nil
@@ -256,23 +425,18 @@ func (m *Manager) UpdateConfig(newConfig *config.Config, configFile string, conf
 	m.config = newConfig
 	m.configFile = configFile
 	m.configLoadTime = configLoadTime
+	m.machines = machinesAPI(newConfig)
 
 	// Re-configure idle settings from new config
 	if newConfig.Server.Idle.Action != "" && (newConfig.Server.Idle.Action == "suspend" || newConfig.Server.Idle.Action == "stop") {
 		wasEnabled := m.enabled
 		m.enabled = true
 		m.action = newConfig.Server.Idle.Action
+		m.countStatic = newConfig.Server.Idle.CountStaticRequests
+		m.countHealthChecks = newConfig.Server.Idle.CountHealthChecks
+		m.deferFor = newConfig.Server.Idle.DeferFor
 
-		// Parse idle timeout
-		if newConfig.Server.Idle.Timeout != "" {
-			if duration, err := time.ParseDuration(newConfig.Server.Idle.Timeout); err == nil {
-				m.idleTimeout = duration
-			} else {
-				m.idleTimeout = config.DefaultIdleTimeout
-			}
-		} else {
-			m.idleTimeout = config.DefaultIdleTimeout
-		}
+		m.idleTimeout = newConfig.Server.Idle.Timeout.OrDefault(config.DefaultIdleTimeout)
 
 		slog.Debug("Updated idle manager configuration",
 			"action", m.action,
@@ -280,11 +444,12 @@ func (m *Manager) UpdateConfig(newConfig *config.Config, configFile string, conf
 
 		// If idle management was just enabled and there are no active requests, start timer
 		if !wasEnabled && m.activeRequests == 0 && m.timer == nil {
-			m.timer = time.AfterFunc(m.idleTimeout, m.handleIdle)
+			m.timer = m.clock.AfterFunc(m.idleTimeout, m.handleIdle)
 			slog.Info("Started idle timer after config reload",
 				"timeout", m.idleTimeout,
 				"action", m.action)
 		}
+		m.scheduleStateSave()
 	} else {
 		m.enabled = false
 		// Cancel any pending idle timer if idle management is disabled
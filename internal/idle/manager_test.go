@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
 )
 
@@ -11,15 +12,14 @@ func TestNewManager(t *testing.T) {
 	tests := []struct {
 		name     string
 		action   string
-		timeout  string
+		timeout  config.Duration
 		expected bool
 	}{
-		{"Valid suspend config", "suspend", "20m", true},
-		{"Valid stop config", "stop", "30m", true},
-		{"Empty action", "", "20m", false},
-		{"Invalid timeout", "suspend", "invalid", true}, // Falls back to default timeout
-		{"Empty timeout", "suspend", "", true},          // Falls back to default timeout
-		{"Invalid action", "invalid", "20m", false},
+		{"Valid suspend config", "suspend", config.Duration(20 * time.Minute), true},
+		{"Valid stop config", "stop", config.Duration(30 * time.Minute), true},
+		{"Empty action", "", config.Duration(20 * time.Minute), false},
+		{"Empty timeout", "suspend", 0, true}, // Falls back to default timeout
+		{"Invalid action", "invalid", config.Duration(20 * time.Minute), false},
 	}
 
 	for _, tt := range tests {
@@ -49,7 +49,7 @@ func TestNewManager(t *testing.T) {
 func TestIdleManagerBasicOperations(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "100ms"
+	cfg.Server.Idle.Timeout = config.Duration(100 * time.Millisecond)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
@@ -81,7 +81,7 @@ func TestIdleManagerDisabled(t *testing.T) {
 	// Test with empty action (should be disabled)
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = ""
-	cfg.Server.Idle.Timeout = "20m"
+	cfg.Server.Idle.Timeout = config.Duration(20 * time.Minute)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 
 	if manager != nil && manager.IsEnabled() {
@@ -97,33 +97,27 @@ func TestIdleManagerDisabled(t *testing.T) {
 }
 
 func TestIdleManagerTimeout(t *testing.T) {
-	// Test very short timeout with test mode to avoid signals
-	cfg := &config.Config{}
-	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "10ms"
-	manager := NewManager(cfg, "", time.Time{}, nil)
-	if manager == nil {
-		t.Fatal("Failed to create IdleManager")
-	}
+	manager, clk := newActivityTestManager(false, false)
 	defer manager.Stop()
 
-	// Enable test mode to prevent actual signals
-	manager.EnableTestMode()
-
 	// Start and finish a request
 	manager.RequestStarted()
 	manager.RequestFinished()
 
-	// Wait for potential idle timeout (this is hard to test precisely)
-	time.Sleep(50 * time.Millisecond)
-
-	// The test mainly verifies no panics occur and timers work
+	clk.Advance(10*time.Minute - time.Second)
+	if manager.hasIdleActioned() {
+		t.Fatal("Idle action before the timeout")
+	}
+	clk.Advance(time.Second)
+	if !manager.hasIdleActioned() {
+		t.Error("Expected the idle action once the timeout passed")
+	}
 }
 
 func TestIdleManagerConcurrency(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "1s"
+	cfg.Server.Idle.Timeout = config.Duration(time.Second)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
@@ -157,7 +151,7 @@ func TestIdleManagerConcurrency(t *testing.T) {
 func TestIdleManagerStop(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "1s"
+	cfg.Server.Idle.Timeout = config.Duration(time.Second)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
@@ -181,7 +175,7 @@ func TestIdleManagerActions(t *testing.T) {
 		t.Run("Action: "+action, func(t *testing.T) {
 			cfg := &config.Config{}
 			cfg.Server.Idle.Action = action
-			cfg.Server.Idle.Timeout = "1m"
+			cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 			manager := NewManager(cfg, "", time.Time{}, nil)
 			if manager == nil {
 				t.Errorf("Failed to create IdleManager for action: %s", action)
@@ -203,83 +197,76 @@ func TestIdleManagerTimeoutParsing(t *testing.T) {
 
 	for _, timeout := range validTimeouts {
 		t.Run("Timeout: "+timeout, func(t *testing.T) {
+			duration, err := config.ParseDuration(timeout)
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) failed: %v", timeout, err)
+			}
 			cfg := &config.Config{}
 			cfg.Server.Idle.Action = "suspend"
-			cfg.Server.Idle.Timeout = timeout
+			cfg.Server.Idle.Timeout = config.Duration(duration)
 			manager := NewManager(cfg, "", time.Time{}, nil)
 			if manager == nil {
 				t.Errorf("Failed to create IdleManager for timeout: %s", timeout)
 			} else {
+				if manager.idleTimeout != duration {
+					t.Errorf("idleTimeout = %v, want %v", manager.idleTimeout, duration)
+				}
 				manager.Stop()
 			}
 		})
 	}
 
+	// Invalid and negative timeouts are rejected when the configuration is loaded
 	invalidTimeouts := []string{
-		"", "invalid", "1x", "abc",
+		"invalid", "1x", "abc", "-5m",
 	}
 
 	for _, timeout := range invalidTimeouts {
 		t.Run("Invalid timeout: "+timeout, func(t *testing.T) {
-			cfg := &config.Config{}
-			cfg.Server.Idle.Action = "suspend"
-			cfg.Server.Idle.Timeout = timeout
-			manager := NewManager(cfg, "", time.Time{}, nil)
-			// Invalid timeouts fall back to default timeout, so manager should still be enabled
-			if manager == nil || !manager.IsEnabled() {
-				t.Errorf("IdleManager should be enabled even with invalid timeout (falls back to default): %s", timeout)
-			} else {
-				manager.Stop()
+			if _, err := config.ParseDuration(timeout); err == nil {
+				t.Errorf("ParseDuration(%q) should fail", timeout)
 			}
 		})
 	}
 
-	// Test negative timeout separately since it's parsed successfully but is unusual
-	t.Run("Invalid timeout: -5m", func(t *testing.T) {
+	// An unset timeout falls back to the default
+	t.Run("Empty timeout", func(t *testing.T) {
 		cfg := &config.Config{}
 		cfg.Server.Idle.Action = "suspend"
-		cfg.Server.Idle.Timeout = "-5m"
 		manager := NewManager(cfg, "", time.Time{}, nil)
-		// -5m is a valid duration but negative, so it should still enable
 		if manager == nil || !manager.IsEnabled() {
-			t.Error("IdleManager should be enabled even with negative timeout")
-		} else {
-			manager.Stop()
+			t.Fatal("IdleManager should be enabled with the default timeout")
 		}
+		if manager.idleTimeout != config.DefaultIdleTimeout {
+			t.Errorf("idleTimeout = %v, want default %v", manager.idleTimeout, config.DefaultIdleTimeout)
+		}
+		manager.Stop()
 	})
 }
 
 func TestIdleManagerLongRunning(t *testing.T) {
 	// Test that long-running requests don't trigger idle
-	cfg := &config.Config{}
-	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "50ms"
-	manager := NewManager(cfg, "", time.Time{}, nil)
-	if manager == nil {
-		t.Fatal("Failed to create IdleManager")
-	}
+	manager, clk := newActivityTestManager(false, false)
 	defer manager.Stop()
 
-	// Enable test mode to prevent signals
-	manager.EnableTestMode()
-
-	// Start a request
 	manager.RequestStarted()
+	clk.Advance(time.Hour)
+	if manager.hasIdleActioned() {
+		t.Fatal("Idle action while a request was in progress")
+	}
 
-	// Wait longer than timeout
-	time.Sleep(100 * time.Millisecond)
-
-	// Should still be active due to ongoing request
-	// (This is more of a behavioral test)
-
-	// Finish the request
+	// The timeout starts over once the request finishes
 	manager.RequestFinished()
+	clk.Advance(10 * time.Minute)
+	if !manager.hasIdleActioned() {
+		t.Error("Expected the idle action once the request finished and the timeout passed")
+	}
 }
 
 func BenchmarkIdleManagerRequestTracking(b *testing.B) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "1m"
+	cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		b.Fatal("Failed to create IdleManager")
@@ -296,7 +283,7 @@ func BenchmarkIdleManagerRequestTracking(b *testing.B) {
 func BenchmarkIdleManagerConcurrentTracking(b *testing.B) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "1m"
+	cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		b.Fatal("Failed to create IdleManager")
@@ -317,7 +304,7 @@ func BenchmarkIdleManagerConcurrentTracking(b *testing.B) {
 func TestGetStats(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "1m"
+	cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
@@ -353,7 +340,7 @@ func TestGetStats(t *testing.T) {
 func TestUpdateConfig(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "1m"
+	cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
@@ -365,7 +352,7 @@ func TestUpdateConfig(t *testing.T) {
 	// Update config with new timeout
 	newCfg := &config.Config{}
 	newCfg.Server.Idle.Action = "suspend"
-	newCfg.Server.Idle.Timeout = "5m"
+	newCfg.Server.Idle.Timeout = config.Duration(5 * time.Minute)
 
 	manager.UpdateConfig(newCfg, "", time.Time{})
 
@@ -377,7 +364,7 @@ func TestUpdateConfig(t *testing.T) {
 	// Update config to disable
 	disabledCfg := &config.Config{}
 	disabledCfg.Server.Idle.Action = ""
-	disabledCfg.Server.Idle.Timeout = "1m"
+	disabledCfg.Server.Idle.Timeout = config.Duration(time.Minute)
 
 	manager.UpdateConfig(disabledCfg, "", time.Time{})
 
@@ -390,11 +377,8 @@ func TestUpdateConfig(t *testing.T) {
 func TestUpdateConfigStartsTimer(t *testing.T) {
 	// Start with disabled idle management (simulating boot with empty config)
 	cfg := &config.Config{}
-	manager := NewManager(cfg, "", time.Time{}, nil)
-	if manager == nil {
-		t.Fatal("Failed to create IdleManager")
-		return
-	}
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := newManagerWithClock(cfg, "", time.Time{}, nil, clk)
 	defer manager.Stop()
 
 	manager.EnableTestMode()
@@ -407,7 +391,7 @@ func TestUpdateConfigStartsTimer(t *testing.T) {
 	// Simulate config reload with idle management enabled
 	enabledCfg := &config.Config{}
 	enabledCfg.Server.Idle.Action = "suspend"
-	enabledCfg.Server.Idle.Timeout = "10ms"
+	enabledCfg.Server.Idle.Timeout = config.Duration(10 * time.Millisecond)
 
 	manager.UpdateConfig(enabledCfg, "", time.Time{})
 
@@ -417,14 +401,8 @@ func TestUpdateConfigStartsTimer(t *testing.T) {
 	}
 
 	// Verify timer was started (should fire after 10ms since no requests)
-	time.Sleep(20 * time.Millisecond)
-
-	// Check that idle action would have been performed (idleActioned flag set)
-	manager.mutex.RLock()
-	actioned := manager.idleActioned
-	manager.mutex.RUnlock()
-
-	if !actioned {
+	clk.Advance(10 * time.Millisecond)
+	if !manager.hasIdleActioned() {
 		t.Error("Expected idle timer to have fired after config reload with no active requests")
 	}
 }
@@ -432,7 +410,7 @@ func TestUpdateConfigStartsTimer(t *testing.T) {
 func TestStopMachine(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "stop"
-	cfg.Server.Idle.Timeout = "10ms"
+	cfg.Server.Idle.Timeout = config.Duration(10 * time.Millisecond)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
@@ -453,7 +431,7 @@ func TestSuspend(t *testing.T) {
 	t.Run("Suspend with valid config", func(t *testing.T) {
 		cfg := &config.Config{}
 		cfg.Server.Idle.Action = "suspend"
-		cfg.Server.Idle.Timeout = "1m"
+		cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 		manager := NewManager(cfg, "", time.Time{}, nil)
 		if manager == nil {
 			t.Fatal("Failed to create IdleManager")
@@ -472,7 +450,7 @@ func TestSuspend(t *testing.T) {
 	t.Run("Suspend with stop action (should fail)", func(t *testing.T) {
 		cfg := &config.Config{}
 		cfg.Server.Idle.Action = "stop"
-		cfg.Server.Idle.Timeout = "1m"
+		cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 		manager := NewManager(cfg, "", time.Time{}, nil)
 		if manager == nil {
 			t.Fatal("Failed to create IdleManager")
@@ -491,7 +469,7 @@ func TestSuspend(t *testing.T) {
 	t.Run("Suspend when disabled", func(t *testing.T) {
 		cfg := &config.Config{}
 		cfg.Server.Idle.Action = ""
-		cfg.Server.Idle.Timeout = "1m"
+		cfg.Server.Idle.Timeout = config.Duration(time.Minute)
 		manager := NewManager(cfg, "", time.Time{}, nil)
 
 		// Manager should be nil or disabled
@@ -507,7 +485,7 @@ func TestSuspend(t *testing.T) {
 func TestRequestStartedWithTimer(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Server.Idle.Action = "suspend"
-	cfg.Server.Idle.Timeout = "100ms"
+	cfg.Server.Idle.Timeout = config.Duration(100 * time.Millisecond)
 	manager := NewManager(cfg, "", time.Time{}, nil)
 	if manager == nil {
 		t.Fatal("Failed to create IdleManager")
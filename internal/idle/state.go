@@ -0,0 +1,171 @@
+package idle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// State is the idle state server.idle.state keeps across restarts
+type State struct {
+	SavedAt      time.Time            `json:"saved_at"`
+	LastActivity time.Time            `json:"last_activity"`     // The machine's idle clock
+	Tenants      map[string]time.Time `json:"tenants,omitempty"` // Each tenant's last request
+}
+
+// LoadState reads the state saved at path
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid idle state file %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// SaveState writes state to path, replacing the file at once so a crash
+// mid-write never leaves a partial one
+func SaveState(path string, state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".navigator-idle-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	return err
+}
+
+// restoreState seeds the idle clock and tenant activity from the state
+// file. Timestamps in the future or older than max_age are ignored, as is a
+// file that can't be read.
+func (m *Manager) restoreState() {
+	cfg := m.config.Server.Idle.State
+	state, err := LoadState(cfg.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		slog.Warn("Ignoring idle state", "file", cfg.File, "error", err)
+		return
+	}
+
+	now := m.clock.Now()
+	usable := func(t time.Time) bool {
+		return !t.IsZero() && !t.After(now) && now.Sub(t) <= cfg.MaxAge.OrDefault(config.DefaultIdleStateMaxAge)
+	}
+
+	var latest time.Time
+	if usable(state.LastActivity) {
+		latest = state.LastActivity
+	}
+	m.tenants = make(map[string]time.Time)
+	for tenant, lastActivity := range state.Tenants {
+		if usable(lastActivity) {
+			m.tenants[tenant] = lastActivity
+			if lastActivity.After(latest) {
+				latest = lastActivity
+			}
+		}
+	}
+	if latest.IsZero() {
+		slog.Info("Ignoring stale idle state", "file", cfg.File, "saved_at", state.SavedAt)
+		return
+	}
+
+	m.lastActivity = latest
+	slog.Info("Restored idle state", "file", cfg.File, "lastActivity", latest, "tenants", len(m.tenants))
+}
+
+// SetTenantActivity sets the function that reports each running tenant's
+// last request, to be saved with the idle state
+func (m *Manager) SetTenantActivity(fn func() map[string]time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.tenantActivity = fn
+}
+
+// SaveState writes the idle state file, if server.idle.state is enabled
+func (m *Manager) SaveState() {
+	m.mutex.Lock()
+	cfg := m.config.Server.Idle.State
+	if !m.enabled || !cfg.Enabled {
+		m.mutex.Unlock()
+		return
+	}
+	now := m.clock.Now()
+	tenantActivity := m.tenantActivity
+	m.mutex.Unlock()
+
+	// Read outside the lock: the app manager takes its own
+	var running map[string]time.Time
+	if tenantActivity != nil {
+		running = tenantActivity()
+	}
+
+	m.mutex.Lock()
+	if m.tenants == nil {
+		m.tenants = make(map[string]time.Time)
+	}
+	for tenant, lastActivity := range running {
+		if lastActivity.After(m.tenants[tenant]) {
+			m.tenants[tenant] = lastActivity
+		}
+	}
+	state := &State{SavedAt: now, LastActivity: m.lastActivity, Tenants: make(map[string]time.Time)}
+	for tenant, lastActivity := range m.tenants {
+		if now.Sub(lastActivity) > cfg.MaxAge.OrDefault(config.DefaultIdleStateMaxAge) {
+			delete(m.tenants, tenant)
+			continue
+		}
+		state.Tenants[tenant] = lastActivity
+	}
+	m.mutex.Unlock()
+
+	if err := SaveState(cfg.File, state); err != nil {
+		slog.Warn("Failed to save idle state", "file", cfg.File, "error", err)
+	}
+}
+
+// scheduleStateSave saves the idle state every server.idle.state.interval
+// for as long as it is enabled. Called with the mutex held.
+func (m *Manager) scheduleStateSave() {
+	cfg := m.config.Server.Idle.State
+	if !m.enabled || !cfg.Enabled || m.saveTimer != nil {
+		return
+	}
+	m.saveTimer = m.clock.AfterFunc(cfg.Interval.OrDefault(config.DefaultIdleStateInterval), func() {
+		m.SaveState()
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.saveTimer == nil {
+			return // Stopped while saving
+		}
+		m.saveTimer = nil
+		m.scheduleStateSave()
+	})
+}
@@ -0,0 +1,157 @@
+package idle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+var stateTestStart = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newStateTestManager starts a manager with a 10 minute idle timeout that
+// keeps its state in file
+func newStateTestManager(t *testing.T, file string) (*Manager, *clock.Fake) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Server.Idle.Action = "suspend"
+	cfg.Server.Idle.Timeout = config.Duration(10 * time.Minute)
+	cfg.Server.Idle.State = config.IdleStateConfig{
+		Enabled:  true,
+		File:     file,
+		Interval: config.Duration(30 * time.Second),
+		MaxAge:   config.Duration(24 * time.Hour),
+	}
+
+	clk := clock.NewFake(stateTestStart)
+	m := newManagerWithClock(cfg, "", time.Time{}, nil, clk)
+	m.EnableTestMode()
+	t.Cleanup(m.Stop)
+	return m, clk
+}
+
+func TestRestoredStateFiresIdleEarlier(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "navigator-idle.json")
+	state := &State{
+		SavedAt:      stateTestStart.Add(-time.Minute),
+		LastActivity: stateTestStart.Add(-8 * time.Minute),
+		Tenants:      map[string]time.Time{"2025/boston": stateTestStart.Add(-9 * time.Minute)},
+	}
+	if err := SaveState(file, state); err != nil {
+		t.Fatal(err)
+	}
+
+	m, clk := newStateTestManager(t, file)
+	if _, lastActivity := m.GetStats(); !lastActivity.Equal(state.LastActivity) {
+		t.Errorf("lastActivity = %v, want the saved %v", lastActivity, state.LastActivity)
+	}
+
+	clk.Advance(2*time.Minute - time.Second)
+	if m.hasIdleActioned() {
+		t.Fatal("idle action fired before the restored timeout ran out")
+	}
+	clk.Advance(time.Second)
+	if !m.hasIdleActioned() {
+		t.Error("idle action should fire 2 minutes after restart, 10 minutes after the saved activity")
+	}
+}
+
+func TestRestoredStateIgnoresUnusableTimestamps(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"in the future", `{"last_activity": "2025-01-01T13:00:00Z"}`},
+		{"older than max_age", `{"last_activity": "2024-12-30T12:00:00Z", "tenants": {"a": "2024-12-30T12:00:00Z"}}`},
+		{"corrupted", `{"last_activity": `},
+		{"empty", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "navigator-idle.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m, clk := newStateTestManager(t, file)
+			if _, lastActivity := m.GetStats(); !lastActivity.Equal(stateTestStart) {
+				t.Errorf("lastActivity = %v, want the start %v", lastActivity, stateTestStart)
+			}
+			clk.Advance(10*time.Minute - time.Second)
+			if m.hasIdleActioned() {
+				t.Error("idle action fired before a full timeout from the start")
+			}
+		})
+	}
+}
+
+func TestSaveStateOnStopAndPeriodically(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "navigator-idle.json")
+	if err := SaveState(file, &State{
+		LastActivity: stateTestStart.Add(-time.Hour),
+		Tenants: map[string]time.Time{
+			"stopped": stateTestStart.Add(-time.Hour),     // Not running, still kept
+			"running": stateTestStart.Add(-2 * time.Hour), // Superseded by the running app
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, clk := newStateTestManager(t, file)
+	running := stateTestStart.Add(time.Second)
+	m.SetTenantActivity(func() map[string]time.Time {
+		return map[string]time.Time{"running": running}
+	})
+
+	m.RequestStarted()
+	clk.Advance(5 * time.Second)
+	m.RequestFinished()
+
+	// Saved by the periodic save
+	clk.Advance(30 * time.Second)
+	state, err := LoadState(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := stateTestStart.Add(5 * time.Second); !state.LastActivity.Equal(want) {
+		t.Errorf("saved last_activity = %v, want %v", state.LastActivity, want)
+	}
+	if !state.Tenants["running"].Equal(running) || !state.Tenants["stopped"].Equal(stateTestStart.Add(-time.Hour)) {
+		t.Errorf("saved tenants = %v", state.Tenants)
+	}
+
+	// Saved on stop, with nothing left behind but the file
+	m.RequestStarted()
+	clk.Advance(10 * time.Second)
+	m.RequestFinished()
+	m.Stop()
+	state, err = LoadState(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := stateTestStart.Add(45 * time.Second); !state.LastActivity.Equal(want) || !state.SavedAt.Equal(want) {
+		t.Errorf("saved state = %+v, want last_activity and saved_at %v", state, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("state directory has %d entries, want only the state file", len(entries))
+	}
+}
+
+func TestSaveStateDisabled(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "navigator-idle.json")
+	cfg := &config.Config{}
+	cfg.Server.Idle.Action = "suspend"
+	cfg.Server.Idle.State.File = file
+	m := newManagerWithClock(cfg, "", time.Time{}, nil, clock.NewFake(stateTestStart))
+	m.EnableTestMode()
+	m.Stop()
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("state file written while server.idle.state is disabled: %v", err)
+	}
+}
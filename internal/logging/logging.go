@@ -1,6 +1,10 @@
 package logging
 
-import "log/slog"
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
 
 // Request logging helpers
 
@@ -90,6 +94,15 @@ func LogProxyResponseBufferDisabled(size int64) {
 		"size", size)
 }
 
+// LogRetryBufferLimitReached logs a request proxied without retry buffering
+// because all retry buffers together are at their memory limit
+func LogRetryBufferLimitReached(size, inUse, limit int64) {
+	slog.Debug("Retry buffer limit reached, request will not be retried",
+		"size", size,
+		"inUse", inUse,
+		"limit", limit)
+}
+
 // Process logging helpers
 
 // LogProcessStart logs process startup
@@ -339,6 +352,14 @@ func LogStaticFileNotFound(fsPath string, err error) {
 		"err", err)
 }
 
+// LogWellKnownShadowsFile logs a server.well_known file served in place of
+// a file of the same name in the public directory
+func LogWellKnownShadowsFile(path, fsPath string) {
+	slog.Debug("Well-known file from config shadows public file",
+		"path", path,
+		"fsPath", fsPath)
+}
+
 // LogStaticFileServe logs static file serving
 func LogStaticFileServe(path, fsPath string) {
 	slog.Debug("Serving static file",
@@ -346,6 +367,39 @@ func LogStaticFileServe(path, fsPath string) {
 		"fsPath", fsPath)
 }
 
+// LogStaticSourceError logs a static source (archive or S3 bucket) that could not be read
+func LogStaticSourceError(sourceType, location string, err error) {
+	slog.Error("Static source error",
+		"type", sourceType,
+		"location", location,
+		"error", err)
+}
+
+// LogStaticRootUnavailable logs a public directory that can't be read, with
+// the number of requests it has failed since this was last logged
+func LogStaticRootUnavailable(dir string, err error, failures int) {
+	slog.Error("Static root unavailable",
+		"dir", dir,
+		"error", err,
+		"failures", failures)
+}
+
+// LogStaticRootAvailable logs a public directory readable again after an
+// outage, and the cached responses dropped because they were stored during it
+func LogStaticRootAvailable(dir string, downFor time.Duration, purged int) {
+	slog.Info("Static root available again",
+		"dir", dir,
+		"downFor", downFor.Round(time.Millisecond),
+		"purgedResponses", purged)
+}
+
+// LogStaticArchiveLoaded logs a static archive index being built
+func LogStaticArchiveLoaded(archive string, files int) {
+	slog.Info("Loaded static archive",
+		"archive", archive,
+		"files", files)
+}
+
 // LogTryFilesCheck logs try_files check
 func LogTryFilesCheck(path string) {
 	slog.Debug("tryFiles checking",
@@ -387,6 +441,22 @@ func LogTryFilesServe(requestPath, fsPath string) {
 		"fsPath", fsPath)
 }
 
+// LogSPAServe logs a single-page application's fallback file being served
+func LogSPAServe(path, fsPath string) {
+	slog.Debug("Serving SPA fallback",
+		"path", path,
+		"fsPath", fsPath)
+}
+
+// LogSPASkip logs why a request under a single-page application doesn't get
+// its fallback file
+func LogSPASkip(path, spaPath, reason string) {
+	slog.Debug("Skipping SPA fallback",
+		"path", path,
+		"spa", spaPath,
+		"reason", reason)
+}
+
 // LogDirectoryRedirect logs when a directory is redirected to include trailing slash
 func LogDirectoryRedirect(path, redirectURL string) {
 	slog.Info("Redirecting directory to trailing slash",
@@ -453,6 +523,36 @@ func LogFlyReplayFailed(failedHeader string, target string) {
 		"target", target)
 }
 
+// LogFlyReplayProbeFailed logs an unreachable fly-replay target
+func LogFlyReplayProbeFailed(address string, err error) {
+	slog.Warn("Fly-replay target unreachable",
+		"address", address,
+		"error", err)
+}
+
+// LogFlyReplayFallbackRegion logs a replay redirected to the fallback region
+func LogFlyReplayFallbackRegion(target, fallback string) {
+	slog.Info("Fly-replay target unreachable, replaying to fallback region",
+		"target", target,
+		"fallback", fallback)
+}
+
+// LogFlyReplayHandledLocally logs a replay skipped because no target is reachable
+func LogFlyReplayHandledLocally(target string) {
+	slog.Info("Fly-replay target unreachable, handling request locally",
+		"target", target)
+}
+
+// LogFlyReplayLoop logs a replay refused because the request has already been replayed too often
+func LogFlyReplayLoop(target string, hops, limit int, srcInstance, srcRegion string) {
+	slog.Warn("Fly-replay loop detected, handling request locally",
+		"target", target,
+		"hops", hops,
+		"maxHops", limit,
+		"srcInstance", srcInstance,
+		"srcRegion", srcRegion)
+}
+
 // LogFlyReplayResponseBody logs fly-replay response body
 func LogFlyReplayResponseBody(body []byte) {
 	slog.Debug("Fly replay response body",
@@ -484,6 +584,79 @@ func LogTenantExtraction(tenantName string, found bool, path string) {
 		"path", path)
 }
 
+// LogRewriteConditionsMatched logs the conditions that let a rewrite rule apply
+func LogRewriteConditionsMatched(path, pattern string, conditions []string) {
+	slog.Debug("Rewrite conditions matched",
+		"path", path,
+		"pattern", pattern,
+		"conditions", conditions)
+}
+
+// LogTenantAliasRouted logs a request routed through a tenant alias
+func LogTenantAliasRouted(aliasPath, primaryPath, tenant string) {
+	slog.Debug("Routing tenant alias",
+		"path", aliasPath,
+		"primaryPath", primaryPath,
+		"tenant", tenant)
+}
+
+// LogTenantAliasRedirect logs a redirect from a tenant alias to the tenant path
+func LogTenantAliasRedirect(aliasPath, location string) {
+	slog.Debug("Redirecting tenant alias",
+		"path", aliasPath,
+		"location", location)
+}
+
+// LogUploadStored logs a file written by an upload
+func LogUploadStored(path, file string, size int64, replaced bool) {
+	slog.Info("Stored upload",
+		"path", path,
+		"file", file,
+		"size", size,
+		"replaced", replaced)
+}
+
+// LogUploadRejected logs an upload request that was refused
+func LogUploadRejected(method, path, reason string) {
+	slog.Warn("Rejected upload",
+		"method", method,
+		"path", path,
+		"reason", reason)
+}
+
+// LogRequestBodyRejected logs a request body that was too large or
+// couldn't be decoded
+func LogRequestBodyRejected(method, path, encoding, reason string) {
+	slog.Warn("Rejected request body",
+		"method", method,
+		"path", path,
+		"encoding", encoding,
+		"reason", reason)
+}
+
+// LogRequestBodyDecoded logs a request body decoded before routing
+func LogRequestBodyDecoded(path, encoding string, compressed, decoded int64) {
+	slog.Debug("Decoded request body",
+		"path", path,
+		"encoding", encoding,
+		"compressed", compressed,
+		"decoded", decoded)
+}
+
+// LogWebAppStartupQueued logs a web app start waiting for a startup slot
+func LogWebAppStartupQueued(tenant string, depth int) {
+	slog.Info("Web app startup queued, waiting for a startup slot",
+		"tenant", tenant,
+		"queueDepth", depth)
+}
+
+// LogWebAppStartupDequeued logs a queued web app start getting its slot
+func LogWebAppStartupDequeued(tenant string, waited time.Duration) {
+	slog.Info("Web app startup slot acquired",
+		"tenant", tenant,
+		"startup_queued_ms", waited.Milliseconds())
+}
+
 // LogAppStartupTimeout logs app startup timeout
 func LogAppStartupTimeout(tenant string, timeout interface{}) {
 	slog.Info("App still starting after timeout, serving maintenance page",
@@ -491,6 +664,14 @@ func LogAppStartupTimeout(tenant string, timeout interface{}) {
 		"timeout", timeout)
 }
 
+// LogAppNotAcceptingRequests logs a request refused because its tenant's
+// app isn't healthy
+func LogAppNotAcceptingRequests(tenant, state string) {
+	slog.Info("App not accepting requests, serving maintenance page",
+		"tenant", tenant,
+		"state", state)
+}
+
 // LogInvalidTimeout logs invalid timeout configuration
 func LogInvalidTimeoutTenant(tenant, value string, err error) {
 	slog.Warn("Invalid tenant startup_timeout, using default",
@@ -505,3 +686,413 @@ func LogInvalidTimeoutGlobal(value string, err error) {
 		"value", value,
 		"error", err)
 }
+
+// LogRequestsCoalesced logs identical requests that waited on a single backend request
+func LogRequestsCoalesced(tenant, path string, waiters int, shared bool) {
+	slog.Info("Coalesced identical requests to starting tenant",
+		"tenant", tenant,
+		"path", path,
+		"waiters", waiters,
+		"shared", shared)
+}
+
+// LogResponseCachePurged logs cached responses removed through the purge endpoint
+func LogResponseCachePurged(prefix string, purged int) {
+	slog.Info("Purged cached responses",
+		"prefix", prefix,
+		"purged", purged)
+}
+
+// LogAccessLogDropped logs access log entries dropped because a destination could not keep up
+func LogAccessLogDropped(destination string, dropped, total int64) {
+	slog.Warn("Dropped access log entries, destination is not keeping up",
+		"destination", destination,
+		"dropped", dropped,
+		"total", total)
+}
+
+// LogEventDropped logs a lifecycle event dropped because a destination's queue is full
+func LogEventDropped(destination, eventType string) {
+	slog.Warn("Dropped lifecycle event, destination is not keeping up",
+		"destination", destination,
+		"event", eventType)
+}
+
+// LogEventDeliveryFailed logs a lifecycle event that could not be delivered
+func LogEventDeliveryFailed(destination, eventType string, attempts int, err error) {
+	slog.Warn("Failed to deliver lifecycle event",
+		"destination", destination,
+		"event", eventType,
+		"attempts", attempts,
+		"error", err)
+}
+
+// LogRequestQueueRejected logs a tenant request refused because every
+// max_concurrent_requests slot stayed busy
+func LogRequestQueueRejected(tenant, path string, queued int, err error) {
+	slog.Warn("Rejected request, tenant is at its concurrency limit",
+		"tenant", tenant,
+		"path", path,
+		"queued", queued,
+		"reason", err)
+}
+
+// LogRequestHeadersTooLarge logs a request refused because the headers it
+// would forward exceed the route's limit, naming the largest header
+func LogRequestHeadersTooLarge(route, path string, size, limit int, header string, headerSize int) {
+	slog.Warn("Rejected request, headers too large to forward",
+		"route", route,
+		"path", path,
+		"size", size,
+		"limit", limit,
+		"header", header,
+		"headerSize", headerSize)
+}
+
+// LogWebAppCrashed logs a web app that exited or stopped accepting
+// connections without being asked to stop. exitCode is -1 when the process
+// was killed by a signal or hasn't been reaped yet.
+func LogWebAppCrashed(tenant string, pid, exitCode int, status, reason, uptime string) {
+	slog.Error("Web app crashed",
+		"tenant", tenant,
+		"pid", pid,
+		"exitCode", exitCode,
+		"status", status,
+		"reason", reason,
+		"uptime", uptime)
+}
+
+// LogWebAppCrashRestart logs the inline restart of a crashed web app, or
+// why it was skipped
+func LogWebAppCrashRestart(tenant string, crashes int, restarted bool, retryIn string) {
+	if restarted {
+		slog.Info("Restarting crashed web app", "tenant", tenant, "crashes", crashes)
+		return
+	}
+	slog.Warn("Not restarting crashed web app during backoff",
+		"tenant", tenant,
+		"crashes", crashes,
+		"retryIn", retryIn)
+}
+
+// LogProxyDNSChanged logs new addresses for a reverse proxy target host
+func LogProxyDNSChanged(host string, old, addrs []string) {
+	slog.Info("Proxy target addresses changed",
+		"host", host,
+		"old", old,
+		"new", addrs)
+}
+
+// LogProxyDNSRefreshFailed logs a failed background re-resolution of a
+// reverse proxy target host; the previous addresses stay in use
+func LogProxyDNSRefreshFailed(host string, err error) {
+	slog.Warn("Failed to re-resolve proxy target, keeping previous addresses",
+		"host", host,
+		"error", err)
+}
+
+// LogStartGuardHeld logs a tenant that wasn't started because its start
+// guard is held elsewhere. This is logged as an error: the same tenant may
+// be running on another machine against shared data.
+func LogStartGuardHeld(tenant, guard, holder string) {
+	slog.Error("Refusing to start web app: start guard held elsewhere",
+		"tenant", tenant,
+		"guard", guard,
+		"holder", holder)
+}
+
+// LogStartGuardLost logs a running tenant stopped because its start guard
+// lease was taken by another holder
+func LogStartGuardLost(tenant, guard, holder string) {
+	slog.Error("Stopping web app: start guard lease lost",
+		"tenant", tenant,
+		"guard", guard,
+		"holder", holder)
+}
+
+// LogStartGuardExpired logs a running tenant stopped because its start
+// guard lease couldn't be renewed before it expires
+func LogStartGuardExpired(tenant, guard string, expires time.Time) {
+	slog.Error("Stopping web app: start guard lease expiring without renewal",
+		"tenant", tenant,
+		"guard", guard,
+		"expires", expires)
+}
+
+// LogStartGuardRenewFailed logs a lease renewal that couldn't be completed;
+// renewal is retried until the lease is lost or about to expire, or the app stops
+func LogStartGuardRenewFailed(tenant, guard string, err error) {
+	slog.Warn("Failed to renew start guard lease",
+		"tenant", tenant,
+		"guard", guard,
+		"error", err)
+}
+
+// LogLoadSheddingStarted logs the start of load shedding and the thresholds
+// that were breached
+func LogLoadSheddingStarted(reasons []string, readings interface{}) {
+	slog.Warn("Resources exhausted, shedding requests",
+		"reasons", reasons,
+		"readings", readings)
+}
+
+// LogLoadSheddingStopped logs the end of load shedding once every reading
+// has recovered
+func LogLoadSheddingStopped(readings interface{}) {
+	slog.Info("Resources recovered, no longer shedding requests",
+		"readings", readings)
+}
+
+// LogStandbyReady logs a tenant's warm standby instance ready to take over
+func LogStandbyReady(tenant string, port int) {
+	slog.Info("Standby web app is ready",
+		"tenant", tenant,
+		"port", port)
+}
+
+// LogStandbyPromoted logs routing flipped from a failed primary instance to
+// the tenant's standby
+func LogStandbyPromoted(tenant string, failedPort, port int, reason string) {
+	slog.Warn("Standby web app took over from failed primary",
+		"tenant", tenant,
+		"failedPort", failedPort,
+		"port", port,
+		"reason", reason)
+}
+
+// LogStandbyFailed logs a standby instance that couldn't be started or
+// failed its health check; a replacement is started
+func LogStandbyFailed(tenant string, port int, err error) {
+	slog.Warn("Standby web app failed, starting a replacement",
+		"tenant", tenant,
+		"port", port,
+		"error", err)
+}
+
+// LogTenantRecycling logs the start of a tenant's recycle and what
+// triggered it
+func LogTenantRecycling(tenant, trigger, detail string) {
+	slog.Info("Recycling web app",
+		"tenant", tenant,
+		"trigger", trigger,
+		"detail", detail)
+}
+
+// LogTenantRecycled logs a recycle that's complete: the replacement took
+// over routing and the old instance was drained and stopped
+func LogTenantRecycled(tenant, trigger string, oldPort, port int, start, drain time.Duration) {
+	slog.Info("Web app recycled",
+		"tenant", tenant,
+		"trigger", trigger,
+		"oldPort", oldPort,
+		"port", port,
+		"startDuration", start.Round(time.Millisecond),
+		"drainDuration", drain.Round(time.Millisecond))
+}
+
+// LogTenantRecycleFailed logs a recycle whose replacement couldn't take
+// over; the old instance keeps serving
+func LogTenantRecycleFailed(tenant, trigger string, err error) {
+	slog.Error("Web app recycle failed, old instance keeps serving",
+		"tenant", tenant,
+		"trigger", trigger,
+		"error", err)
+}
+
+// LogTenantPaused logs a tenant paused through the control API; ttl is empty
+// for a pause that lasts until it's resumed
+func LogTenantPaused(tenant, ttl string, drain bool) {
+	slog.Warn("Tenant paused, rejecting new requests",
+		"tenant", tenant,
+		"ttl", ttl,
+		"drain", drain)
+}
+
+// LogTenantResumed logs a paused tenant accepting requests again; reason is
+// "request", "expired", or "reload"
+func LogTenantResumed(tenant, reason string) {
+	slog.Info("Tenant resumed",
+		"tenant", tenant,
+		"reason", reason)
+}
+
+// LogTenantDrained logs a paused tenant's app stopped once its in-flight
+// requests finished
+func LogTenantDrained(tenant string) {
+	slog.Info("Paused tenant drained, stopping web app",
+		"tenant", tenant)
+}
+
+// LogNegotiatedTarget logs a request sent to a negotiate target because of
+// its Accept or Content-Type header
+func LogNegotiatedTarget(path, target string) {
+	slog.Debug("Selected negotiated target",
+		"path", path,
+		"target", target)
+}
+
+// LogCanonicalRedirect logs a request redirected to the canonical host or
+// scheme
+func LogCanonicalRedirect(host, path, location string, status int) {
+	slog.Debug("Redirecting to canonical host",
+		"host", host,
+		"path", path,
+		"location", location,
+		"status", status)
+}
+
+// LogMirrorSkipped logs a sampled request that wasn't mirrored
+func LogMirrorSkipped(path, target, reason string, skipped int64) {
+	slog.Debug("Skipped mirroring request",
+		"path", path,
+		"target", target,
+		"reason", reason,
+		"skipped", skipped)
+}
+
+// LogMirrorFailed logs a mirrored request that failed or got a server error;
+// errored counts the mirror's failures so far
+func LogMirrorFailed(path, target string, err error, errored int64) {
+	slog.Debug("Mirrored request failed",
+		"path", path,
+		"target", target,
+		"error", err,
+		"errored", errored)
+}
+
+// LogResponseFilterFailed logs a response filter that failed; the response
+// is served unmodified
+func LogResponseFilterFailed(source, path string, err error) {
+	slog.Warn("Response filter failed, serving unmodified body",
+		"source", source,
+		"path", path,
+		"error", err)
+}
+
+// LogSendfileRefused logs a file a tenant app named in its sendfile header
+// that isn't served, because it is outside the allowed directories or can't
+// be opened
+func LogSendfileRefused(source, path, target string, err error) {
+	slog.Error("Refused to send file named by app",
+		"source", source,
+		"path", path,
+		"file", target,
+		"error", err)
+}
+
+// LogPanicRecovered logs a panic recovered while serving a request, with
+// the request's details and the goroutine's stack
+func LogPanicRecovered(value interface{}, requestID, method, path, stage, tenant, stack string) {
+	slog.Error("Recovered panic serving request",
+		"panic", fmt.Sprint(value),
+		"request_id", requestID,
+		"method", method,
+		"path", path,
+		"stage", stage,
+		"tenant", tenant,
+		"stack", stack)
+}
+
+// LogResponseFilterSkipped logs a response the filter applies to that is
+// served unmodified, e.g. because it is too large
+func LogResponseFilterSkipped(source, path, reason string) {
+	slog.Debug("Skipped response filter",
+		"source", source,
+		"path", path,
+		"reason", reason)
+}
+
+// LogDiskBudgetWarning logs log files reaching a warn_at percentage of
+// logging.disk_budget.max_bytes
+func LogDiskBudgetWarning(used, maxBytes int64, percent int) {
+	slog.Warn("Log files approaching disk budget",
+		"used_bytes", used,
+		"max_bytes", maxBytes,
+		"threshold_percent", percent)
+}
+
+// LogDiskBudgetAction logs a log file the disk budget janitor deleted or
+// truncated
+func LogDiskBudgetAction(action, path string, bytes int64) {
+	slog.Info("Disk budget janitor "+action+" log file",
+		"path", path,
+		"bytes", bytes)
+}
+
+// LogDiskBudgetFailed logs a log file the disk budget janitor couldn't
+// delete or truncate
+func LogDiskBudgetFailed(action, path string, err error) {
+	slog.Warn("Disk budget janitor failed to "+action+" log file",
+		"path", path,
+		"error", err)
+}
+
+// LogFileLoggingStopped logs log files still over the disk budget after the
+// janitor ran, so logs written to files go to stdout instead
+func LogFileLoggingStopped(used, maxBytes int64) {
+	slog.Error("Log files exceed disk budget, writing file logs to stdout",
+		"used_bytes", used,
+		"max_bytes", maxBytes)
+}
+
+// LogFileLoggingResumed logs log files back within the disk budget
+func LogFileLoggingResumed(used, maxBytes int64) {
+	slog.Info("Log files within disk budget, resuming file logging",
+		"used_bytes", used,
+		"max_bytes", maxBytes)
+}
+
+// LogRouteAuthUnavailable logs a request refused because the route requires
+// credentials but none are loaded for it
+func LogRouteAuthUnavailable(route, path string) {
+	slog.Error("Route requires auth but no credentials are loaded",
+		"route", route,
+		"path", path)
+}
+
+// LogScheduledTaskFinished logs the outcome of a scheduled task's run
+func LogScheduledTaskFinished(name string, duration time.Duration, err error) {
+	if err != nil {
+		slog.Error("Scheduled task failed",
+			"task", name,
+			"duration", duration.Round(time.Millisecond),
+			"error", err)
+		return
+	}
+	slog.Info("Scheduled task succeeded",
+		"task", name,
+		"duration", duration.Round(time.Millisecond))
+}
+
+// LogScheduledTaskSkipped logs a scheduled run that didn't start
+func LogScheduledTaskSkipped(name, reason string) {
+	slog.Info("Scheduled task skipped",
+		"task", name,
+		"reason", reason)
+}
+
+// LogWarmerRequest logs the timing of a request sent by a tenant's warmer
+func LogWarmerRequest(tenant, path string, status int, duration time.Duration, err error) {
+	if err != nil {
+		slog.Debug("Warmer request failed",
+			"tenant", tenant,
+			"path", path,
+			"status", status,
+			"duration", duration.Round(time.Millisecond),
+			"error", err)
+		return
+	}
+	slog.Debug("Warmer request succeeded",
+		"tenant", tenant,
+		"path", path,
+		"status", status,
+		"duration", duration.Round(time.Millisecond))
+}
+
+// LogProxyProtocolRejected logs a connection dropped because its source
+// isn't trusted to send a PROXY protocol header, or its header is invalid
+func LogProxyProtocolRejected(remoteAddr, reason string) {
+	slog.Warn("Dropped connection without a valid PROXY protocol header",
+		"remote_addr", remoteAddr,
+		"reason", reason)
+}
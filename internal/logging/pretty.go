@@ -0,0 +1,289 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ANSI escape sequences used by the pretty log format
+const (
+	ColorReset   = "\x1b[0m"
+	ColorBold    = "\x1b[1m"
+	ColorDim     = "\x1b[2m"
+	ColorRed     = "\x1b[31m"
+	ColorGreen   = "\x1b[32m"
+	ColorYellow  = "\x1b[33m"
+	ColorBlue    = "\x1b[34m"
+	ColorMagenta = "\x1b[35m"
+	ColorCyan    = "\x1b[36m"
+)
+
+// PrettyTimeFormat is the timestamp that begins each pretty log line
+const PrettyTimeFormat = "15:04:05"
+
+// Colorize wraps s in color
+func Colorize(color, s string) string {
+	return color + s + ColorReset
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// StripANSI removes color escape sequences from b
+func StripANSI(b []byte) []byte {
+	if bytes.IndexByte(b, 0x1b) < 0 {
+		return b
+	}
+	return ansiPattern.ReplaceAll(b, nil)
+}
+
+// IsTerminal reports whether w is a terminal
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorEnabled reports whether output written to w should be colored: w is
+// a terminal and NO_COLOR isn't set
+func ColorEnabled(w io.Writer) bool {
+	return IsTerminal(w) && os.Getenv("NO_COLOR") == ""
+}
+
+// Colors assigned to the sources of forwarded output, in order, as foreman does
+var sourceColors = []string{ColorCyan, ColorYellow, ColorGreen, ColorMagenta, ColorBlue, ColorRed}
+
+// sources assigns each tenant or managed process a color on its first line
+// of output, kept for the life of the server so restarts don't change it
+var sources = struct {
+	mu     sync.Mutex
+	colors map[string]string
+	width  int // Longest source name seen, so the output lines up
+}{colors: make(map[string]string)}
+
+// sourceColor returns the color assigned to source and the width names are
+// padded to
+func sourceColor(source string) (string, int) {
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+	color, ok := sources.colors[source]
+	if !ok {
+		color = sourceColors[len(sources.colors)%len(sourceColors)]
+		sources.colors[source] = color
+		sources.width = max(sources.width, len(source))
+	}
+	return color, sources.width
+}
+
+// PrettyPrefix returns the time and the padded, color-coded source name
+// that begin each pretty line of a tenant's or process's output
+func PrettyPrefix(source string, color bool) string {
+	sourceColor, width := sourceColor(source)
+	prefix := fmt.Sprintf("%-*s |", width, source)
+	timestamp := time.Now().Format(PrettyTimeFormat)
+	if !color {
+		return timestamp + " " + prefix + " "
+	}
+	return Colorize(ColorDim, timestamp) + " " + Colorize(sourceColor, prefix) + " "
+}
+
+// Fields left out of pretty debug entries; they repeat on every request
+var prettyDebugSuppressed = map[string]bool{
+	"request_id": true,
+	"client_ip":  true,
+	"user_agent": true,
+}
+
+// PrettyHandler is a slog.Handler writing concise single-line entries for a
+// developer's terminal: the time, a colored level and the message, then the
+// attributes as key=value pairs. Requests read as "GET /path", and empty
+// attributes are left out.
+type PrettyHandler struct {
+	out    io.Writer
+	mu     *sync.Mutex
+	level  slog.Leveler
+	color  bool
+	attrs  []slog.Attr // Added by WithAttrs, with group prefixes applied
+	prefix string      // Group prefix for attribute keys
+}
+
+// NewPrettyHandler creates a PrettyHandler writing to out, colored if color
+// is set
+func NewPrettyHandler(out io.Writer, opts *slog.HandlerOptions, color bool) *PrettyHandler {
+	h := &PrettyHandler{out: out, mu: &sync.Mutex{}, level: slog.LevelInfo, color: color}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// Enabled reports whether level is logged
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// WithAttrs returns a handler that adds attrs to every entry
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], h.qualify(attrs)...)
+	return &clone
+}
+
+// WithGroup returns a handler that qualifies later attribute keys with name
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// qualify flattens groups and applies the group prefix to attribute keys
+func (h *PrettyHandler) qualify(attrs []slog.Attr) []slog.Attr {
+	var flat []slog.Attr
+	var add func(prefix string, attr slog.Attr)
+	add = func(prefix string, attr slog.Attr) {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() == slog.KindGroup {
+			if attr.Key != "" {
+				prefix += attr.Key + "."
+			}
+			for _, member := range attr.Value.Group() {
+				add(prefix, member)
+			}
+			return
+		}
+		attr.Key = prefix + attr.Key
+		flat = append(flat, attr)
+	}
+	for _, attr := range attrs {
+		add(h.prefix, attr)
+	}
+	return flat
+}
+
+// Handle writes r as one line
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	if !r.Time.IsZero() {
+		buf.WriteString(h.paint(ColorDim, r.Time.Format(PrettyTimeFormat)))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(h.paint(levelColor(r.Level), fmt.Sprintf("%-5s", r.Level.String())))
+	buf.WriteByte(' ')
+	buf.WriteString(r.Message)
+
+	attrs := h.attrs
+	if r.NumAttrs() > 0 {
+		record := make([]slog.Attr, 0, r.NumAttrs())
+		r.Attrs(func(attr slog.Attr) bool {
+			record = append(record, attr)
+			return true
+		})
+		attrs = append(attrs[:len(attrs):len(attrs)], h.qualify(record)...)
+	}
+
+	// A request's method and path read as "GET /path"
+	method, path := -1, -1
+	for i, attr := range attrs {
+		switch attr.Key {
+		case "method":
+			method = i
+		case "path":
+			path = i
+		}
+	}
+	if method >= 0 && path >= 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(h.paint(ColorBold, attrs[method].Value.String()))
+		buf.WriteByte(' ')
+		buf.WriteString(attrs[path].Value.String())
+	}
+
+	for i, attr := range attrs {
+		if (method >= 0 && path >= 0 && (i == method || i == path)) || suppressed(r.Level, attr) {
+			continue
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(h.paint(ColorDim, attr.Key+"="))
+		value := formatValue(attr.Value)
+		if attr.Key == "error" || attr.Key == "err" {
+			value = h.paint(ColorRed, value)
+		}
+		buf.WriteString(value)
+	}
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+// paint colors s when the handler is colored
+func (h *PrettyHandler) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return Colorize(color, s)
+}
+
+// levelColor returns the color of a level's label
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ColorRed
+	case level >= slog.LevelWarn:
+		return ColorYellow
+	case level >= slog.LevelInfo:
+		return ColorGreen
+	}
+	return ColorDim
+}
+
+// suppressed reports whether attr is left out of a pretty entry: it is
+// empty, or it is a field every debug entry about a request repeats
+func suppressed(level slog.Level, attr slog.Attr) bool {
+	if level < slog.LevelInfo && prettyDebugSuppressed[attr.Key] {
+		return true
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return attr.Value.String() == ""
+	case slog.KindAny:
+		value := attr.Value.Any()
+		if value == nil {
+			return true
+		}
+		switch v := reflect.ValueOf(value); v.Kind() {
+		case reflect.Slice, reflect.Map:
+			return v.Len() == 0
+		case reflect.Pointer, reflect.Interface:
+			return v.IsNil()
+		}
+	}
+	return false
+}
+
+// formatValue formats a value, quoting strings that wouldn't read as one
+// word
+func formatValue(value slog.Value) string {
+	s := value.String()
+	if value.Kind() == slog.KindString && (s == "" || strings.ContainsAny(s, " =\"\t\n")) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// capturePretty logs through a colored PrettyHandler and returns the output
+// with colors stripped, and whether any were written
+func capturePretty(level slog.Level, fn func(logger *slog.Logger)) (string, bool) {
+	var buf bytes.Buffer
+	fn(slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: level}, true)))
+	colored := buf.Bytes()
+	return string(StripANSI(colored)), bytes.IndexByte(colored, 0x1b) >= 0
+}
+
+var prettyTime = regexp.MustCompile(`^\d\d:\d\d:\d\d `)
+
+func TestPrettyHandler(t *testing.T) {
+	output, colored := capturePretty(slog.LevelInfo, func(logger *slog.Logger) {
+		logger.Info("Starting web app", "tenant", "boston", "port", 4001, "dir", "", "args", []string{})
+	})
+	if !colored {
+		t.Error("Expected colored output")
+	}
+	if !prettyTime.MatchString(output) {
+		t.Errorf("Expected the entry to start with the time, got %q", output)
+	}
+	if want := "INFO  Starting web app tenant=boston port=4001\n"; !strings.HasSuffix(output, want) {
+		t.Errorf("Entry = %q, want it to end with %q", output, want)
+	}
+}
+
+func TestPrettyHandlerRequestsAndDebugFields(t *testing.T) {
+	output, _ := capturePretty(slog.LevelDebug, func(logger *slog.Logger) {
+		logger.Debug("Request received", "method", "GET", "path", "/showcase/", "request_id", "req-123", "client_ip", "10.0.0.1")
+		logger.Info("Proxy error", "request_id", "req-456", "error", errors.New("connection refused"))
+	})
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", output)
+	}
+	if want := "DEBUG Request received GET /showcase/"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("Debug entry = %q, want it to end with %q", lines[0], want)
+	}
+	if !strings.Contains(lines[1], "request_id=req-456") || !strings.Contains(lines[1], "error=connection refused") {
+		t.Errorf("Info entries keep request_id and errors, got %q", lines[1])
+	}
+}
+
+func TestPrettyHandlerAttrsAndGroups(t *testing.T) {
+	output, _ := capturePretty(slog.LevelInfo, func(logger *slog.Logger) {
+		logger.With("worker", 2).WithGroup("cache").Info("Purged", "reason", "config changed", slog.Group("entries", "count", 3))
+	})
+	if want := `Purged worker=2 cache.reason="config changed" cache.entries.count=3`; !strings.HasSuffix(strings.TrimSpace(output), want) {
+		t.Errorf("Entry = %q, want it to end with %q", output, want)
+	}
+}
+
+func TestPrettyHandlerWithoutColor(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewPrettyHandler(&buf, nil, false)).Warn("Slow hook", "hook", "migrate")
+	if bytes.IndexByte(buf.Bytes(), 0x1b) >= 0 {
+		t.Errorf("Expected no colors, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "WARN  Slow hook hook=migrate") {
+		t.Errorf("Unexpected entry %q", buf.String())
+	}
+}
+
+func TestPrettyPrefix(t *testing.T) {
+	first := PrettyPrefix("pretty-test-boston", true)
+	again := PrettyPrefix("pretty-test-boston", true)
+	other := PrettyPrefix("pretty-test-raleigh", true)
+
+	color := func(prefix string) string {
+		return regexp.MustCompile(`\x1b\[3\dm`).FindString(prefix)
+	}
+	if color(first) == "" || color(first) != color(again) {
+		t.Errorf("Expected a stable color per source, got %q and %q", first, again)
+	}
+	if color(first) == color(other) {
+		t.Errorf("Expected sources to have different colors, both got %q", color(first))
+	}
+
+	plain := PrettyPrefix("pretty-test-boston", false)
+	if strings.ContainsRune(plain, 0x1b) {
+		t.Errorf("Expected no colors, got %q", plain)
+	}
+	if !prettyTime.MatchString(plain) || !strings.Contains(plain, "pretty-test-boston  | ") {
+		t.Errorf("Expected the time and padded source, got %q", plain)
+	}
+}
@@ -0,0 +1,97 @@
+// Package netutil resolves the client address of a request. ClientIP is the
+// one place the trusted-proxy rules live, so the access log, header
+// substitution, and anything else that needs the client agree on it.
+package netutil
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustConfig is how far the headers a request arrives with are believed.
+// X-Forwarded-For and X-Real-IP are used, as they have been since before
+// trust_proxy existed, unless the peer address came from a PROXY protocol
+// header: then the load balancer sent no headers of its own, so any there are
+// the client's, and they're only used when TrustProxy is set. The RFC 7239
+// Forwarded header is only used when TrustProxy is set.
+type TrustConfig struct {
+	TrustProxy      bool // server.trust_proxy: believe the Forwarded header
+	PreferForwarded bool // server.forwarded_precedence: Forwarded wins over X-Forwarded-For
+	ProxyProtocol   bool // server.proxy_protocol: believe X-Forwarded-For and X-Real-IP only with TrustProxy
+}
+
+// ClientIP returns the address of the client that made r. In order, it uses:
+//
+//  1. a trusted Forwarded header, when PreferForwarded is set
+//  2. the left-most valid X-Forwarded-For hop, across every header line,
+//     unless ProxyProtocol is set without TrustProxy
+//  3. X-Real-IP, under the same condition
+//  4. a trusted Forwarded header, when PreferForwarded is not set
+//  5. the host of RemoteAddr
+//
+// Ports and IPv6 brackets are stripped; values that are not IP addresses,
+// such as "unknown" and obfuscated Forwarded nodes, are skipped. If RemoteAddr
+// is not an address either, it is returned unchanged.
+func ClientIP(r *http.Request, trust TrustConfig) string {
+	forwardedIP, hasForwarded := forwardedClientIP(r, trust)
+	if hasForwarded && trust.PreferForwarded {
+		return forwardedIP
+	}
+	if !trust.ProxyProtocol || trust.TrustProxy {
+		for _, line := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(line, ",") {
+				if ip, ok := HostIP(strings.TrimSpace(hop)); ok {
+					return ip
+				}
+			}
+		}
+		if ip, ok := HostIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+			return ip
+		}
+	}
+	if hasForwarded {
+		return forwardedIP
+	}
+	if ip, ok := HostIP(r.RemoteAddr); ok {
+		return ip
+	}
+	return r.RemoteAddr
+}
+
+// forwardedClientIP returns the client IP from the first element of a trusted
+// Forwarded header
+func forwardedClientIP(r *http.Request, trust TrustConfig) (string, bool) {
+	if !trust.TrustProxy {
+		return "", false
+	}
+	elements := ParseForwarded(strings.Join(r.Header.Values("Forwarded"), ","))
+	if len(elements) == 0 {
+		return "", false
+	}
+	return HostIP(elements[0].For)
+}
+
+// HostIP returns the IP address in addr, which may carry a port and, for
+// IPv6, brackets: "192.0.2.1", "192.0.2.1:80", "2001:db8::1", "[2001:db8::1]",
+// or "[2001:db8::1]:4711". It returns false if addr holds no IP address,
+// including the "unknown" and "_obfuscated" nodes of a Forwarded header.
+func HostIP(addr string) (string, bool) {
+	if addr == "" || strings.EqualFold(addr, "unknown") || strings.HasPrefix(addr, "_") {
+		return "", false
+	}
+	if strings.HasPrefix(addr, "[") {
+		end := strings.Index(addr, "]")
+		if end < 0 || (end+1 < len(addr) && addr[end+1] != ':') {
+			return "", false
+		}
+		addr = addr[1:end]
+	} else if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", false
+	}
+	return ip.String(), true
+}
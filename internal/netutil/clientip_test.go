@@ -0,0 +1,298 @@
+package netutil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostIP(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+		ok       bool
+	}{
+		{"192.0.2.1", "192.0.2.1", true},
+		{"192.0.2.1:8080", "192.0.2.1", true},
+		{"2001:db8::1", "2001:db8::1", true},
+		{"2001:DB8::1", "2001:db8::1", true},
+		{"[2001:db8::1]", "2001:db8::1", true},
+		{"[2001:db8::1]:4711", "2001:db8::1", true},
+		{"::ffff:192.0.2.1", "192.0.2.1", true},
+		{"", "", false},
+		{"unknown", "", false},
+		{"UNKNOWN", "", false},
+		{"_gazonk", "", false},
+		{"example.com", "", false},
+		{"example.com:80", "", false},
+		{"[2001:db8::1", "", false},
+		{"[2001:db8::1]junk", "", false},
+		{"[192.0.2.1]x", "", false},
+		{"192.0.2.256", "", false},
+		{"@", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			ip, ok := HostIP(tt.addr)
+			if ip != tt.expected || ok != tt.ok {
+				t.Errorf("HostIP(%q) = (%q, %v), want (%q, %v)", tt.addr, ip, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := TrustConfig{TrustProxy: true, PreferForwarded: true}
+
+	tests := []struct {
+		name       string
+		headers    map[string][]string
+		remoteAddr string
+		trust      TrustConfig
+		expected   string
+	}{
+		// RemoteAddr
+		{
+			name:       "IPv4 RemoteAddr",
+			remoteAddr: "192.0.2.3:5678",
+			expected:   "192.0.2.3",
+		},
+		{
+			name:       "IPv6 RemoteAddr",
+			remoteAddr: "[2001:db8::1]:5678",
+			expected:   "2001:db8::1",
+		},
+		{
+			name:       "RemoteAddr without a port",
+			remoteAddr: "192.0.2.3",
+			expected:   "192.0.2.3",
+		},
+		{
+			name:       "unparseable RemoteAddr returned unchanged",
+			remoteAddr: "@",
+			expected:   "@",
+		},
+
+		// X-Forwarded-For
+		{
+			name:       "X-Forwarded-For",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For left-most hop",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1, 10.0.0.1, 10.0.0.2"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For across header lines",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1", "10.0.0.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For hop with a port",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1:50123, 10.0.0.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For IPv6 hop",
+			headers:    map[string][]string{"X-Forwarded-For": {"2001:db8::17, 10.0.0.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "2001:db8::17",
+		},
+		{
+			name:       "X-Forwarded-For bracketed IPv6 hop with a port",
+			headers:    map[string][]string{"X-Forwarded-For": {"[2001:db8::17]:4711"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "2001:db8::17",
+		},
+		{
+			name:       "X-Forwarded-For invalid hops skipped",
+			headers:    map[string][]string{"X-Forwarded-For": {"unknown, , garbage, 203.0.113.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For with no valid hop falls back",
+			headers:    map[string][]string{"X-Forwarded-For": {"unknown"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "X-Forwarded-For used without trust_proxy",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      TrustConfig{},
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For ignored behind PROXY protocol without trust_proxy",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1"}, "X-Real-IP": {"203.0.113.2"}},
+			remoteAddr: "198.51.100.7:1234",
+			trust:      TrustConfig{ProxyProtocol: true},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "X-Forwarded-For used behind PROXY protocol with trust_proxy",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1"}},
+			remoteAddr: "198.51.100.7:1234",
+			trust:      TrustConfig{TrustProxy: true, ProxyProtocol: true},
+			expected:   "203.0.113.1",
+		},
+
+		// X-Real-IP
+		{
+			name:       "X-Real-IP",
+			headers:    map[string][]string{"X-Real-IP": {"203.0.113.2"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.2",
+		},
+		{
+			name:       "X-Real-IP IPv6",
+			headers:    map[string][]string{"X-Real-IP": {"[2001:db8::2]"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "2001:db8::2",
+		},
+		{
+			name:       "invalid X-Real-IP falls back",
+			headers:    map[string][]string{"X-Real-IP": {"unknown"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "X-Forwarded-For wins over X-Real-IP",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.1"}, "X-Real-IP": {"203.0.113.2"}},
+			remoteAddr: "127.0.0.1:1234",
+			expected:   "203.0.113.1",
+		},
+
+		// Forwarded
+		{
+			name:       "Forwarded ignored without trust_proxy",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.60"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      TrustConfig{PreferForwarded: true},
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "Forwarded IPv4",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.60;proto=https"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "Forwarded IPv4 with a port",
+			headers:    map[string][]string{"Forwarded": {`for="192.0.2.60:8080"`}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "Forwarded bracketed IPv6 with a port",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db8:cafe::17]:4711"`}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "2001:db8:cafe::17",
+		},
+		{
+			name:       "Forwarded bracketed IPv6 without a port",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db8::1]"`}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "2001:db8::1",
+		},
+		{
+			name:       "Forwarded first element wins",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.43, for=198.51.100.17"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "192.0.2.43",
+		},
+		{
+			name:       "Forwarded across header lines",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.43", "for=198.51.100.17"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "192.0.2.43",
+		},
+		{
+			name:       "Forwarded unknown node falls back",
+			headers:    map[string][]string{"Forwarded": {"for=unknown"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "Forwarded obfuscated node falls back",
+			headers:    map[string][]string{"Forwarded": {"for=_gazonk"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "malformed Forwarded falls back",
+			headers:    map[string][]string{"Forwarded": {`for="192.0.2.60`}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "malformed Forwarded falls back to X-Forwarded-For",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db8::1`}, "X-Forwarded-For": {"203.0.113.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "203.0.113.1",
+		},
+
+		// Precedence
+		{
+			name:       "Forwarded preferred over X-Forwarded-For",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.60"}, "X-Forwarded-For": {"203.0.113.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      trusted,
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "X-Forwarded-For preferred when configured",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.60"}, "X-Forwarded-For": {"203.0.113.1"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      TrustConfig{TrustProxy: true},
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Real-IP preferred when X-Forwarded-For is",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.60"}, "X-Real-IP": {"203.0.113.2"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      TrustConfig{TrustProxy: true},
+			expected:   "203.0.113.2",
+		},
+		{
+			name:       "Forwarded used when X-Forwarded-For is preferred but absent",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.60"}},
+			remoteAddr: "127.0.0.1:1234",
+			trust:      TrustConfig{TrustProxy: true},
+			expected:   "192.0.2.60",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, value := range values {
+					req.Header.Add(name, value)
+				}
+			}
+
+			if ip := ClientIP(req, tt.trust); ip != tt.expected {
+				t.Errorf("ClientIP() = %q, want %q", ip, tt.expected)
+			}
+		})
+	}
+}
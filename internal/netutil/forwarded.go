@@ -0,0 +1,100 @@
+package netutil
+
+import (
+	"strings"
+)
+
+// ForwardedElement represents one hop of an RFC 7239 Forwarded header
+type ForwardedElement struct {
+	For   string
+	By    string
+	Proto string
+	Host  string
+}
+
+// ParseForwarded parses an RFC 7239 Forwarded header value into its elements.
+// Returns nil if the value is malformed; callers should then fall back to other sources.
+func ParseForwarded(value string) []ForwardedElement {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	var elements []ForwardedElement
+	for _, rawElement := range splitQuoted(value, ',') {
+		var element ForwardedElement
+		for _, pair := range splitQuoted(rawElement, ';') {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil
+			}
+			val, ok = unquoteForwarded(strings.TrimSpace(val))
+			if !ok {
+				return nil
+			}
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "for":
+				element.For = val
+			case "by":
+				element.By = val
+			case "proto":
+				element.Proto = strings.ToLower(val)
+			case "host":
+				element.Host = val
+			}
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// splitQuoted splits s on sep, ignoring separators inside quoted strings
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	escaped := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\' && inQuotes:
+			escaped = true
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquoteForwarded removes surrounding quotes and backslash escapes from a value
+func unquoteForwarded(s string) (string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, !strings.Contains(s, `"`)
+	}
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return "", false
+	}
+	inner := s[1 : len(s)-1]
+	var b strings.Builder
+	escaped := false
+	for i := 0; i < len(inner); i++ {
+		if escaped {
+			b.WriteByte(inner[i])
+			escaped = false
+			continue
+		}
+		if inner[i] == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String(), !escaped
+}
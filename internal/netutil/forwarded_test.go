@@ -0,0 +1,79 @@
+package netutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseForwarded(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []ForwardedElement
+	}{
+		{
+			name:     "simple for",
+			value:    "for=192.0.2.60",
+			expected: []ForwardedElement{{For: "192.0.2.60"}},
+		},
+		{
+			name:  "all parameters case insensitive",
+			value: "For=192.0.2.60;Proto=HTTPS;By=203.0.113.43;Host=example.com",
+			expected: []ForwardedElement{{
+				For: "192.0.2.60", Proto: "https", By: "203.0.113.43", Host: "example.com",
+			}},
+		},
+		{
+			name:  "multiple elements",
+			value: "for=192.0.2.43, for=198.51.100.17;proto=http",
+			expected: []ForwardedElement{
+				{For: "192.0.2.43"},
+				{For: "198.51.100.17", Proto: "http"},
+			},
+		},
+		{
+			name:     "quoted IPv6 with port",
+			value:    `for="[2001:db8:cafe::17]:4711"`,
+			expected: []ForwardedElement{{For: "[2001:db8:cafe::17]:4711"}},
+		},
+		{
+			name:     "quoted value containing separators",
+			value:    `for=192.0.2.1;host="a,b;c.example"`,
+			expected: []ForwardedElement{{For: "192.0.2.1", Host: "a,b;c.example"}},
+		},
+		{
+			name:     "escaped quote",
+			value:    `for=_hidden;host="ex\"ample"`,
+			expected: []ForwardedElement{{For: "_hidden", Host: `ex"ample`}},
+		},
+		{
+			name:     "empty",
+			value:    "",
+			expected: nil,
+		},
+		{
+			name:     "missing equals",
+			value:    "for",
+			expected: nil,
+		},
+		{
+			name:     "unterminated quote",
+			value:    `for="192.0.2.1`,
+			expected: nil,
+		},
+		{
+			name:     "stray quote in token",
+			value:    `for=192"0.2.1`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseForwarded(tt.value)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseForwarded(%q) = %+v, want %+v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package process
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// AppState is where a web app is in its lifecycle. Only a healthy app is
+// sent new requests; a draining one finishes those it already has.
+type AppState string
+
+const (
+	AppStopped   AppState = "stopped"   // Not running: never started, or stopped for good
+	AppStarting  AppState = "starting"  // Waiting to boot, or booting until its first health check passes
+	AppHealthy   AppState = "healthy"   // Serving requests
+	AppUnhealthy AppState = "unhealthy" // Running, but not answering its health check
+	AppDraining  AppState = "draining"  // Finishing its requests in flight before it stops
+	AppCrashed   AppState = "crashed"   // Exited, or was killed, without being stopped
+)
+
+// appTransitions lists the states each state may move to. A draining app
+// returns to healthy only when a request cancels its idle stop.
+var appTransitions = map[AppState][]AppState{
+	AppStopped:   {AppStarting},
+	AppStarting:  {AppHealthy, AppUnhealthy, AppDraining, AppCrashed, AppStopped},
+	AppHealthy:   {AppUnhealthy, AppDraining, AppCrashed, AppStopped},
+	AppUnhealthy: {AppHealthy, AppDraining, AppCrashed, AppStopped},
+	AppDraining:  {AppHealthy, AppCrashed, AppStopped},
+	AppCrashed:   nil,
+}
+
+// AppTransition is a change of an app's state
+type AppTransition struct {
+	From   AppState  `json:"from"`
+	To     AppState  `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// AppStateInfo is an app's state, since when and why it's in it, and its
+// most recent transitions, oldest first
+type AppStateInfo struct {
+	State       AppState        `json:"state"`
+	Since       time.Time       `json:"since"`
+	Reason      string          `json:"reason,omitempty"`
+	Transitions []AppTransition `json:"transitions,omitempty"`
+}
+
+// State returns the app's lifecycle state
+func (w *WebApp) State() AppState {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.stateLocked()
+}
+
+// StateInfo returns the app's state with its recent transitions
+func (w *WebApp) StateInfo() AppStateInfo {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	info := AppStateInfo{State: w.stateLocked(), Transitions: slices.Clone(w.transitions)}
+	if n := len(w.transitions); n > 0 {
+		info.Since, info.Reason = w.transitions[n-1].At, w.transitions[n-1].Reason
+	}
+	return info
+}
+
+// AcceptsRequests reports whether new requests may be proxied to the app
+func (w *WebApp) AcceptsRequests() bool {
+	return w.State() == AppHealthy
+}
+
+// stateLocked returns the app's state; an app that was never started is
+// stopped. w.mutex must be held.
+func (w *WebApp) stateLocked() AppState {
+	if w.state == "" {
+		return AppStopped
+	}
+	return w.state
+}
+
+// setState moves the app to state to, recording why, and reports whether
+// it did: a transition appTransitions doesn't list is refused, so an app
+// that has crashed or stopped stays that way
+func (w *WebApp) setState(to AppState, reason string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.setStateLocked(to, reason)
+}
+
+// setStateLocked is setState with w.mutex held
+func (w *WebApp) setStateLocked(to AppState, reason string) bool {
+	from := w.stateLocked()
+	tenantName := ""
+	if w.Tenant != nil {
+		tenantName = w.Tenant.Name
+	}
+	if !slices.Contains(appTransitions[from], to) {
+		slog.Debug("Web app state transition refused",
+			"tenant", tenantName, "port", w.Port, "from", from, "to", to, "reason", reason)
+		return false
+	}
+	w.state = to
+	w.idleDrain = false
+	BumpStateVersion()
+	w.transitions = append(w.transitions, AppTransition{From: from, To: to, At: time.Now(), Reason: reason})
+	if len(w.transitions) > config.AppStateHistorySize {
+		w.transitions = w.transitions[len(w.transitions)-config.AppStateHistorySize:]
+	}
+	slog.Debug("Web app state changed",
+		"tenant", tenantName, "port", w.Port, "from", from, "to", to, "reason", reason)
+	return true
+}
+
+// drainForIdle starts draining an idle app, in a way a request arriving
+// before it stops can cancel. Returns false if the app isn't running.
+func (w *WebApp) drainForIdle() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if state := w.stateLocked(); state != AppHealthy && state != AppUnhealthy {
+		return false
+	}
+	w.setStateLocked(AppDraining, "idle")
+	w.idleDrain = true
+	return true
+}
+
+// touch records a request for the app, cancelling an idle stop in progress
+func (w *WebApp) touch() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.LastActivity = time.Now()
+	if w.idleDrain && w.stateLocked() == AppDraining {
+		w.setStateLocked(AppHealthy, "request arrived while stopping for idle")
+	}
+}
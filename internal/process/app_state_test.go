@@ -0,0 +1,136 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestAppStateTransitionsAreGuarded(t *testing.T) {
+	app := &WebApp{}
+	if state := app.State(); state != AppStopped {
+		t.Fatalf("State() = %q for a new app, want stopped", state)
+	}
+	if app.setState(AppHealthy, "skipped starting") {
+		t.Error("A stopped app became healthy without starting")
+	}
+
+	for _, step := range []struct {
+		to     AppState
+		reason string
+	}{
+		{AppStarting, "start requested"},
+		{AppHealthy, "health check passed"},
+		{AppUnhealthy, "health check failed"},
+		{AppHealthy, "health check passed"},
+		{AppCrashed, "exit status 1"},
+	} {
+		if !app.setState(step.to, step.reason) {
+			t.Fatalf("setState(%q) refused", step.to)
+		}
+	}
+	for _, to := range []AppState{AppHealthy, AppStopped, AppStarting} {
+		if app.setState(to, "after crash") {
+			t.Errorf("A crashed app moved to %q", to)
+		}
+	}
+
+	info := app.StateInfo()
+	if info.State != AppCrashed || info.Reason != "exit status 1" || info.Since.IsZero() {
+		t.Errorf("StateInfo() = %+v", info)
+	}
+	if len(info.Transitions) != 5 || info.Transitions[0].From != AppStopped || info.Transitions[4].From != AppHealthy {
+		t.Errorf("Transitions = %+v", info.Transitions)
+	}
+	if app.AcceptsRequests() {
+		t.Error("A crashed app accepts requests")
+	}
+}
+
+func TestAppStateHistoryIsBounded(t *testing.T) {
+	app := &WebApp{}
+	app.setState(AppStarting, "start requested")
+	app.setState(AppHealthy, "health check passed")
+	for i := 0; i < config.AppStateHistorySize; i++ {
+		app.setState(AppUnhealthy, "health check failed")
+		app.setState(AppHealthy, "health check passed")
+	}
+	transitions := app.StateInfo().Transitions
+	if len(transitions) != config.AppStateHistorySize || transitions[len(transitions)-1].To != AppHealthy {
+		t.Errorf("Transitions = %+v, want the last %d", transitions, config.AppStateHistorySize)
+	}
+}
+
+func TestRequestCancelsOnlyAnIdleDrain(t *testing.T) {
+	app := &WebApp{state: AppHealthy}
+	if !app.drainForIdle() || app.AcceptsRequests() {
+		t.Fatal("An idle app should drain and stop accepting requests")
+	}
+	app.touch()
+	if state := app.State(); state != AppHealthy {
+		t.Errorf("State() = %q after a request cancelled the idle stop, want healthy", state)
+	}
+
+	app.setState(AppDraining, "recycled")
+	app.touch()
+	if state := app.State(); state != AppDraining {
+		t.Errorf("State() = %q, a request shouldn't cancel a recycle's drain", state)
+	}
+
+	crashed := &WebApp{state: AppCrashed}
+	if crashed.drainForIdle() {
+		t.Error("A crashed app can't drain for idle")
+	}
+}
+
+func TestAppLifecycleStates(t *testing.T) {
+	m := newStartupManager(t, 4910, 1, 0, 1)
+	app, err := m.GetOrStartApp("tenant0")
+	if err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+	<-app.ReadyChan()
+	if !app.AcceptsRequests() {
+		t.Fatalf("State() = %q once ready, want healthy", app.State())
+	}
+	if status := m.Status(); len(status) != 1 || status[0].State != AppHealthy || status[0].StateSince.IsZero() {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	m.StopApp("tenant0", "control")
+	info := app.StateInfo()
+	var path []AppState
+	for _, transition := range info.Transitions {
+		path = append(path, transition.To)
+	}
+	want := []AppState{AppStarting, AppHealthy, AppDraining, AppStopped}
+	if len(path) != len(want) {
+		t.Fatalf("Transitions = %v, want %v", path, want)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("Transitions = %v, want %v", path, want)
+		}
+	}
+	if info.Reason != "control" {
+		t.Errorf("Reason = %q, want control", info.Reason)
+	}
+
+	// A requested stop is not a crash
+	if app.recordCrash("exit status 1") {
+		t.Error("A stopped app was recorded as crashed")
+	}
+}
+
+func TestTenantStartBumpsStateVersion(t *testing.T) {
+	m := newStartupManager(t, 4915, 1, 0, 1)
+	before := StateVersion()
+	app, err := m.GetOrStartApp("tenant0")
+	if err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+	<-app.ReadyChan()
+	if after := StateVersion(); after < before+2 {
+		t.Errorf("StateVersion() = %d after starting a tenant, want at least %d for starting and healthy", after, before+2)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	"log/slog"
+
+	"github.com/rubys/navigator/internal/config"
 )
 
 const (
@@ -451,49 +453,5 @@ func sanitizeCgroupName(name string) string {
 
 // ParseMemorySize parses memory size strings like "512M", "1G", "2048M"
 func ParseMemorySize(sizeStr string) (int64, error) {
-	if sizeStr == "" {
-		return 0, nil
-	}
-
-	sizeStr = strings.TrimSpace(strings.ToUpper(sizeStr))
-
-	// Match number and optional unit
-	re := regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]I?B?)?$`)
-	matches := re.FindStringSubmatch(sizeStr)
-	if matches == nil {
-		return 0, fmt.Errorf("invalid memory size format: %s", sizeStr)
-	}
-
-	// Parse number
-	value, err := strconv.ParseFloat(matches[1], 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid number in memory size: %s", sizeStr)
-	}
-
-	// Parse unit
-	unit := matches[2]
-	if unit == "" {
-		// No unit, assume bytes
-		return int64(value), nil
-	}
-
-	// Normalize unit (remove 'I' and 'B' if present)
-	unit = strings.TrimSuffix(unit, "IB")
-	unit = strings.TrimSuffix(unit, "B")
-
-	var multiplier int64
-	switch unit {
-	case "K":
-		multiplier = 1024
-	case "M":
-		multiplier = 1024 * 1024
-	case "G":
-		multiplier = 1024 * 1024 * 1024
-	case "T":
-		multiplier = 1024 * 1024 * 1024 * 1024
-	default:
-		return 0, fmt.Errorf("unknown unit in memory size: %s", unit)
-	}
-
-	return int64(value * float64(multiplier)), nil
+	return config.ParseMemorySize(sizeStr)
 }
@@ -0,0 +1,113 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// CommandSpec describes a command Navigator runs: everything needed to build
+// the exec.Cmd, so what would run can be shown without starting it
+type CommandSpec struct {
+	Name    string            `json:"name,omitempty"`
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	Env     map[string]string `json:"env,omitempty"` // Added to Navigator's own environment
+}
+
+// command builds the exec.Cmd for the spec. Without Env the command inherits
+// Navigator's environment unchanged.
+func (s CommandSpec) command(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	cmd.Dir = s.Dir
+	if s.Env != nil {
+		keys := make([]string, 0, len(s.Env))
+		for key := range s.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		cmd.Env = os.Environ()
+		for _, key := range keys {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, s.Env[key]))
+		}
+	}
+	return cmd
+}
+
+// WebAppCommand returns the command that starts a tenant's web app on port
+func (ps *ProcessStarter) WebAppCommand(tenant *config.Tenant, port int) CommandSpec {
+	env := map[string]string{"PORT": strconv.Itoa(port)}
+	for key, value := range presetEnv(tenant, port) {
+		env[key] = value
+	}
+	for key, value := range tenant.Env {
+		env[key] = value
+	}
+	// Egress settles the proxy variables, whatever env or Navigator's own
+	// environment says
+	for key, value := range tenant.Egress.Environment() {
+		env[key] = value
+	}
+	// A tenant with a standby runs two instances, each with its own PID file
+	if pidfile, ok := env["PIDFILE"]; ok && tenant.Standby {
+		ext := filepath.Ext(pidfile)
+		env["PIDFILE"] = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(pidfile, ext), port, ext)
+	}
+	spec := CommandSpec{
+		Name:    tenant.Name,
+		Command: ps.getRuntime(tenant),
+		Dir:     tenant.Root,
+		Env:     env,
+	}
+	// The internal echo runtime is served in-process, so takes no arguments
+	if spec.Command != config.RuntimeInternalEcho {
+		spec.Args = append([]string{ps.getServer(tenant)}, ps.getArgs(tenant, port)...)
+	}
+	return spec
+}
+
+// HookCommand returns the command for a hook, running it through the
+// platform shell when shell is set. Args become the shell's positional
+// parameters ($1, $2, ...). Hook-specific env values take precedence.
+func HookCommand(hook config.HookConfig, env map[string]string) CommandSpec {
+	spec := CommandSpec{Name: hookName(hook), Command: hook.Command, Args: hook.Args, Dir: hook.Dir}
+	if hook.Shell {
+		if runtime.GOOS == "windows" {
+			spec.Command, spec.Args = "cmd", append([]string{"/C", hook.Command}, hook.Args...)
+		} else {
+			spec.Command, spec.Args = "/bin/sh", append([]string{"-c", hook.Command, spec.Name}, hook.Args...)
+		}
+	}
+
+	if env != nil || hook.Env != nil {
+		spec.Env = make(map[string]string, len(env)+len(hook.Env))
+		for key, value := range env {
+			spec.Env[key] = value
+		}
+		for key, value := range hook.Env {
+			spec.Env[key] = value
+		}
+	}
+	return spec
+}
+
+// commandSpec returns the command for a managed process
+func (p *ManagedProcess) commandSpec() CommandSpec {
+	return CommandSpec{
+		Name:    p.Name,
+		Command: p.Command,
+		Args:    p.Args,
+		Dir:     p.WorkingDir,
+		Env:     p.Env,
+	}
+}
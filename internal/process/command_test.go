@@ -0,0 +1,183 @@
+package process
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestWebAppCommand(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Runtime = map[string]string{"python": "python3"}
+	cfg.Applications.Server = map[string]string{"python": "manage.py"}
+	cfg.Applications.Args = map[string][]string{"python": {"runserver", "0.0.0.0:{{port}}"}}
+	tenant := &config.Tenant{
+		Name:      "site",
+		Root:      "/srv/site",
+		Framework: "python",
+		Env:       map[string]string{"SECRET": "value", "PORT": "override"},
+	}
+
+	spec := NewProcessStarter(cfg).WebAppCommand(tenant, 4005)
+	if spec.Command != "python3" || !slices.Equal(spec.Args, []string{"manage.py", "runserver", "0.0.0.0:4005"}) {
+		t.Errorf("WebAppCommand = %s %q", spec.Command, spec.Args)
+	}
+	if spec.Dir != "/srv/site" || spec.Env["SECRET"] != "value" || spec.Env["PORT"] != "override" {
+		t.Errorf("WebAppCommand dir/env = %q %v", spec.Dir, spec.Env)
+	}
+
+	cmd := spec.command(context.Background())
+	if cmd.Dir != "/srv/site" || !slices.Contains(cmd.Env, "SECRET=value") {
+		t.Errorf("command() dir/env = %q, SECRET missing from %d variables", cmd.Dir, len(cmd.Env))
+	}
+}
+
+func TestWebAppCommandEgress(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://inherited:3128")
+	cfg, err := config.ParseYAML([]byte(`
+applications:
+  pools:
+    egress:
+      http_proxy: http://${proxy_host}:3128
+      https_proxy: http://${proxy_host}:3128
+      no_proxy: localhost,.internal
+  tenants:
+    - name: public
+      path: /public/
+      var:
+        proxy_host: proxy.corp.example
+    - name: internal
+      path: /internal/
+      env:
+        HTTP_PROXY: http://env:3128
+      egress:
+        http_proxy: ""
+        https_proxy: ""
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	starter := NewProcessStarter(cfg)
+	tests := []struct {
+		tenant string
+		want   map[string]string
+	}{
+		{"public", map[string]string{
+			"HTTP_PROXY": "http://proxy.corp.example:3128", "http_proxy": "http://proxy.corp.example:3128",
+			"HTTPS_PROXY": "http://proxy.corp.example:3128", "https_proxy": "http://proxy.corp.example:3128",
+			"NO_PROXY": "localhost,.internal", "no_proxy": "localhost,.internal",
+		}},
+		{"internal", map[string]string{
+			"HTTP_PROXY": "", "http_proxy": "",
+			"HTTPS_PROXY": "", "https_proxy": "",
+			"NO_PROXY": "localhost,.internal", "no_proxy": "localhost,.internal",
+		}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			spec := starter.WebAppCommand(&cfg.Applications.Tenants[i], 4005)
+			for key, value := range tt.want {
+				if got, ok := spec.Env[key]; !ok || got != value {
+					t.Errorf("Env[%s] = %q, want %q", key, got, value)
+				}
+			}
+
+			// The egress value overrides Navigator's own environment
+			cmd := spec.command(context.Background())
+			if i := slices.Index(cmd.Env, "HTTP_PROXY="+tt.want["HTTP_PROXY"]); i < slices.Index(cmd.Env, "HTTP_PROXY=http://inherited:3128") {
+				t.Errorf("command() env doesn't set HTTP_PROXY after the inherited value: %v", cmd.Env)
+			}
+		})
+	}
+}
+
+func TestHookCommand(t *testing.T) {
+	spec := HookCommand(config.HookConfig{Command: "bin/sync", Args: []string{"--all"}}, nil)
+	if spec.Command != "bin/sync" || spec.Name != "sync" || spec.Env != nil {
+		t.Errorf("HookCommand = %+v", spec)
+	}
+	if cmd := spec.command(context.Background()); cmd.Env != nil {
+		t.Error("Hook without env did not inherit the environment")
+	}
+
+	spec = HookCommand(config.HookConfig{
+		Command: "echo $1",
+		Args:    []string{"hello"},
+		Shell:   true,
+		Env:     map[string]string{"MODE": "hook"},
+	}, map[string]string{"MODE": "tenant", "TENANT": "boston"})
+	if runtime.GOOS != "windows" && (spec.Command != "/bin/sh" || !slices.Equal(spec.Args, []string{"-c", "echo $1", "sh", "hello"})) {
+		t.Errorf("Shell hook = %s %q", spec.Command, spec.Args)
+	}
+	if spec.Env["MODE"] != "hook" || spec.Env["TENANT"] != "boston" {
+		t.Errorf("Hook env = %v, want hook values to win", spec.Env)
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 6000
+	cfg.Applications.Hooks.Stop = []config.HookConfig{{Command: "bin/backup"}}
+	cfg.Applications.Tenants = []config.Tenant{
+		{Name: "a", Path: "/a/", Args: []string{"-p", "{{port}}"}},
+		{Name: "b", Path: "/b/", Hooks: config.TenantHooks{Stop: []config.HookConfig{{Command: "bin/notify"}}}},
+	}
+	cfg.Hooks.Idle = []config.HookConfig{{Command: "bin/suspend", ContinueOnError: true}}
+	cfg.ManagedProcesses = []config.ManagedProcessConfig{{Name: "redis", Command: "redis-server", AutoRestart: true}}
+
+	plan := NewPlan(cfg)
+	if len(plan.ManagedProcesses) != 1 || !plan.ManagedProcesses[0].AutoRestart {
+		t.Errorf("ManagedProcesses = %+v", plan.ManagedProcesses)
+	}
+	if len(plan.Hooks) != 1 || plan.Hooks[0].Type != "server.idle" || !plan.Hooks[0].ContinueOnError {
+		t.Errorf("Hooks = %+v", plan.Hooks)
+	}
+	if len(plan.Tenants) != 2 || plan.Tenants[1].Port != 6001 || plan.Tenants[0].Command.Args[2] != "6000" {
+		t.Fatalf("Tenants = %+v", plan.Tenants)
+	}
+	var types []string
+	for _, hook := range plan.Tenants[1].Hooks {
+		types = append(types, hook.Type)
+	}
+	if !slices.Equal(types, []string{"tenant.stop.default", "tenant.stop.b"}) {
+		t.Errorf("Tenant b hooks = %q", types)
+	}
+}
+
+func TestNewPlanRedactsSecrets(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name: "a",
+		Path: "/a/",
+		Env: map[string]string{
+			"RAILS_ENV":       "production",
+			"SECRET_KEY_BASE": "abc123",
+			"STRIPE_API_KEY":  "sk_live_123",
+			"DATABASE_URL":    "postgres://app:hunter2@db/app",
+		},
+	}}
+	cfg.ManagedProcesses = []config.ManagedProcessConfig{{Name: "worker", Command: "bin/jobs", Env: map[string]string{"GITHUB_TOKEN": "ghp_123"}}}
+
+	plan := NewPlan(cfg)
+	env := plan.Tenants[0].Command.Env
+	if env["RAILS_ENV"] != "production" {
+		t.Errorf("RAILS_ENV = %q, want it shown", env["RAILS_ENV"])
+	}
+	for _, name := range []string{"SECRET_KEY_BASE", "STRIPE_API_KEY"} {
+		if env[name] != redactedEnvValue {
+			t.Errorf("%s = %q, want it redacted", name, env[name])
+		}
+	}
+	if env["DATABASE_URL"] != "postgres://app:xxxxx@db/app" {
+		t.Errorf("DATABASE_URL = %q, want its password redacted", env["DATABASE_URL"])
+	}
+	if got := plan.ManagedProcesses[0].Env["GITHUB_TOKEN"]; got != redactedEnvValue {
+		t.Errorf("GITHUB_TOKEN = %q, want it redacted", got)
+	}
+	if cfg.Applications.Tenants[0].Env["SECRET_KEY_BASE"] != "abc123" {
+		t.Error("Redacting the plan changed the configuration")
+	}
+}
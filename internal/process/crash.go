@@ -0,0 +1,198 @@
+package process
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// crashHistory counts a tenant's consecutive crashes so inline restarts of
+// a tenant that keeps crashing back off
+type crashHistory struct {
+	count        int       // Consecutive crashes
+	last         time.Time // Most recent crash
+	restartedFor int       // Crash count the last inline restart was for
+	lastRestart  time.Time
+}
+
+// Exited reports whether the app's process has exited
+func (w *WebApp) Exited() bool {
+	if w.exited == nil {
+		return false
+	}
+	select {
+	case <-w.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitExited reports whether the app's process exits within timeout
+func (w *WebApp) WaitExited(timeout time.Duration) bool {
+	if w.exited == nil {
+		return false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-w.exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// recordCrash marks the app crashed, logs the crash, and emits
+// tenant.crashed, once per app. It returns false if the crash was already
+// recorded or the app had been stopped.
+func (w *WebApp) recordCrash(reason string) bool {
+	w.mutex.Lock()
+	if !w.setStateLocked(AppCrashed, reason) {
+		w.mutex.Unlock()
+		return false
+	}
+	pid, exitCode, status := 0, -1, "running"
+	if w.Process != nil && w.Process.Process != nil {
+		pid = w.Process.Process.Pid
+	}
+	if w.Exited() && w.Process != nil && w.Process.ProcessState != nil {
+		exitCode, status = w.Process.ProcessState.ExitCode(), w.Process.ProcessState.String()
+	}
+	uptime := time.Since(w.StartTime)
+	w.mutex.Unlock()
+
+	tenantName := ""
+	if w.Tenant != nil {
+		tenantName = w.Tenant.Name
+	}
+	logging.LogWebAppCrashed(tenantName, pid, exitCode, status, reason, uptime.Round(time.Second).String())
+	events.Emit(events.TenantCrashed, map[string]interface{}{
+		"tenant":    tenantName,
+		"error":     reason,
+		"exit_code": exitCode,
+	})
+	return true
+}
+
+// removeCrashedApp takes a crashed app out of the registry, so the next
+// request starts a fresh instance, and counts the crash for backoff. A ready
+// standby takes its place at once. Only the first call for an app has any
+// effect.
+func (m *AppManager) removeCrashedApp(tenantName string, app *WebApp) {
+	m.mutex.Lock()
+	if app.removed {
+		m.mutex.Unlock()
+		return
+	}
+	app.removed = true
+	registered := m.apps[tenantName] == app
+	var promoted *WebApp
+	if registered {
+		delete(m.apps, tenantName)
+		promoted = m.promoteStandby(tenantName)
+	}
+	history := m.crashes[tenantName]
+	if history == nil {
+		history = &crashHistory{}
+		m.crashes[tenantName] = history
+	}
+	now := time.Now()
+	if now.Sub(history.last) > config.CrashResetWindow {
+		history.count, history.restartedFor = 0, 0
+	}
+	history.count++
+	history.last = now
+	m.mutex.Unlock()
+
+	// An app that's no longer registered has already given up its port
+	if registered {
+		m.portAllocator.ReleasePort(app.Port)
+	}
+	if promoted != nil {
+		m.finishFailover(tenantName, app, promoted, "crash")
+	}
+}
+
+// RecoverCrashedApp is called when a connection to app's port is refused,
+// or reset by a process that has exited; cause is the proxy's error, recorded
+// as the reason for the crash. The app is treated as crashed: its process is
+// stopped if it's still running, and it's removed from the registry. With
+// restart_on_crash, a new instance is started through the usual start path,
+// startup slots included, and returned without waiting for it to be ready;
+// nil means the caller should fail the request. Consecutive crashes delay
+// further inline restarts.
+func (m *AppManager) RecoverCrashedApp(tenantName string, app *WebApp, cause error) *WebApp {
+	if app.recordCrash(cause.Error()) && app.cancel != nil {
+		app.cancel()
+	}
+	// The exit may have been recorded without the app being removed yet
+	m.removeCrashedApp(tenantName, app)
+
+	// A standby that took over serves the request, whether or not the
+	// tenant restarts on crash
+	if app.Tenant != nil && app.Tenant.Standby {
+		if current, ok := m.GetApp(tenantName); ok && current != app {
+			return current
+		}
+	}
+
+	if !m.restartOnCrash(app.Tenant) || !m.allowCrashRestart(tenantName) {
+		return nil
+	}
+	// A guarded tenant can't start again until the old process has exited
+	// and released its start guard
+	if app.Tenant != nil && app.Tenant.StartGuard != nil {
+		app.WaitExited(config.AppOutputWaitDelay + config.StartGuardRequestTimeout)
+	}
+	restarted, err := m.GetOrStartApp(tenantName)
+	if err != nil {
+		slog.Error("Failed to restart crashed web app", "tenant", tenantName, "error", err)
+		return nil
+	}
+	return restarted
+}
+
+// restartOnCrash reports whether a tenant is restarted inline after a crash
+func (m *AppManager) restartOnCrash(tenant *config.Tenant) bool {
+	if tenant != nil && tenant.RestartOnCrash != nil {
+		return *tenant.RestartOnCrash
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.config.Applications.RestartOnCrash
+}
+
+// allowCrashRestart reports whether a crashed tenant may be restarted inline
+// now. Every request that saw the same crash joins one restart; the restart
+// after the next consecutive crash waits CrashRestartBackoff, doubling with
+// each further crash.
+func (m *AppManager) allowCrashRestart(tenantName string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	history := m.crashes[tenantName]
+	if history == nil || history.restartedFor == history.count {
+		return true
+	}
+
+	wait := time.Duration(0)
+	if !history.lastRestart.IsZero() && history.count > 1 {
+		backoff := config.CrashRestartBackoff << (history.count - 2)
+		if backoff > config.CrashRestartMaxBackoff || backoff <= 0 {
+			backoff = config.CrashRestartMaxBackoff
+		}
+		wait = time.Until(history.lastRestart.Add(backoff))
+	}
+	if wait > 0 {
+		logging.LogWebAppCrashRestart(tenantName, history.count, false, wait.Round(time.Millisecond).String())
+		return false
+	}
+
+	history.restartedFor, history.lastRestart = history.count, time.Now()
+	logging.LogWebAppCrashRestart(tenantName, history.count, true, "")
+	return true
+}
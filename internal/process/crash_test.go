@@ -0,0 +1,77 @@
+package process
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestCrashRestartBackoff(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "boston", Runtime: "echo"}}
+	m := NewAppManager(cfg)
+
+	// Every request that saw the first crash joins the same restart
+	m.removeCrashedApp("boston", &WebApp{})
+	if !m.allowCrashRestart("boston") || !m.allowCrashRestart("boston") {
+		t.Fatal("First crash should be restarted inline")
+	}
+
+	// A second crash right after the restart waits out the backoff
+	m.removeCrashedApp("boston", &WebApp{})
+	if m.allowCrashRestart("boston") {
+		t.Error("Second crash restarted without backoff")
+	}
+	m.crashes["boston"].lastRestart = time.Now().Add(-config.CrashRestartBackoff)
+	if !m.allowCrashRestart("boston") {
+		t.Error("Second crash not restarted after backoff")
+	}
+
+	// The backoff doubles, and a crash long after the last one starts over
+	m.removeCrashedApp("boston", &WebApp{})
+	m.crashes["boston"].lastRestart = time.Now().Add(-config.CrashRestartBackoff)
+	if m.allowCrashRestart("boston") {
+		t.Error("Third crash restarted before the doubled backoff")
+	}
+	m.crashes["boston"].last = time.Now().Add(-config.CrashResetWindow - time.Second)
+	m.removeCrashedApp("boston", &WebApp{})
+	if got := m.crashes["boston"].count; got != 1 {
+		t.Errorf("Crash count = %d after a quiet period, want 1", got)
+	}
+	if !m.allowCrashRestart("boston") {
+		t.Error("Crash after a quiet period should restart immediately")
+	}
+}
+
+func TestRestartOnCrashOverride(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.RestartOnCrash = true
+	m := NewAppManager(cfg)
+
+	disabled := false
+	if !m.restartOnCrash(&config.Tenant{Name: "boston"}) {
+		t.Error("Tenant should inherit restart_on_crash")
+	}
+	if m.restartOnCrash(&config.Tenant{Name: "raleigh", RestartOnCrash: &disabled}) {
+		t.Error("Tenant override was ignored")
+	}
+}
+
+func TestRecoverCrashedAppRecordsCause(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "boston", Runtime: "echo", RestartOnCrash: &disabled}}
+	m := NewAppManager(cfg)
+	app := &WebApp{Tenant: &cfg.Applications.Tenants[0], state: AppHealthy}
+
+	cause := errors.New("read tcp 127.0.0.1:4000: connection reset by peer")
+	if restarted := m.RecoverCrashedApp("boston", app, cause); restarted != nil {
+		t.Error("Tenant without restart_on_crash was restarted")
+	}
+	info := app.StateInfo()
+	if info.State != AppCrashed || info.Reason != cause.Error() {
+		t.Errorf("State = %q (%q), want crashed with the proxy error", info.State, info.Reason)
+	}
+}
@@ -0,0 +1,321 @@
+package process
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// Actions recorded by the disk budget janitor
+const (
+	DiskBudgetDeleted   = "deleted"   // A rotated log file was removed
+	DiskBudgetTruncated = "truncated" // A log file Navigator writes was emptied
+	DiskBudgetStopped   = "stopped"   // File logging fell back to stdout
+	DiskBudgetResumed   = "resumed"   // File logging started again
+)
+
+// diskBudgetHistorySize is the number of janitor actions kept for status
+const diskBudgetHistorySize = 20
+
+// logFile is a log file Navigator writes. Open log files are registered so
+// the disk budget janitor truncates them rather than deleting them, and
+// their output goes to fallback while file logging is stopped or the file
+// can't be written.
+type logFile struct {
+	path     string
+	file     *os.File
+	fallback io.Writer // Where output goes instead of the file (nil = dropped, as it is already on stdout)
+	mu       sync.Mutex
+}
+
+// openLogFiles are the log files currently open, and fileLoggingStopped is
+// set while they are over the disk budget
+var (
+	openLogFiles       sync.Map // *logFile -> struct{}
+	fileLoggingStopped atomic.Bool
+)
+
+func openLogFile(path string) (*logFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	f := &logFile{path: path, file: file}
+	openLogFiles.Store(f, struct{}{})
+	return f, nil
+}
+
+func (f *logFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !fileLoggingStopped.Load() {
+		if _, err := f.file.Write(p); err == nil {
+			return len(p), nil
+		}
+	}
+	if f.fallback != nil {
+		_, _ = f.fallback.Write(p)
+	}
+	return len(p), nil
+}
+
+// Close closes the file and stops tracking it
+func (f *logFile) Close() error {
+	openLogFiles.Delete(f)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// openLogPaths returns the paths of the log files currently open
+func openLogPaths() map[string]bool {
+	paths := make(map[string]bool)
+	openLogFiles.Range(func(key, _ any) bool {
+		paths[key.(*logFile).path] = true
+		return true
+	})
+	return paths
+}
+
+// DiskBudgetAction is a change the disk budget janitor made
+type DiskBudgetAction struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Path   string    `json:"path,omitempty"`
+	Bytes  int64     `json:"bytes,omitempty"` // Size of the file deleted or truncated
+}
+
+// DiskBudgetStatus reports the log disk budget for the detailed health check
+type DiskBudgetStatus struct {
+	Directories []string           `json:"directories"`
+	MaxBytes    int64              `json:"max_bytes"`
+	UsedBytes   int64              `json:"used_bytes"`
+	FileLogging bool               `json:"file_logging"` // False while file logs go to stdout
+	CheckedAt   time.Time          `json:"checked_at"`
+	Actions     []DiskBudgetAction `json:"actions,omitempty"` // Most recent last
+}
+
+// diskBudget is the janitor enforcing logging.disk_budget
+var diskBudget struct {
+	mu     sync.Mutex
+	config config.DiskBudgetConfig
+	cancel context.CancelFunc
+	status *DiskBudgetStatus
+	warned int // Highest warn_at percentage already logged
+}
+
+// ConfigureDiskBudget starts, restarts, or stops the janitor that keeps log
+// files within logging.disk_budget. Called at startup and on every reload.
+func ConfigureDiskBudget(budget config.DiskBudgetConfig) {
+	diskBudget.mu.Lock()
+	defer diskBudget.mu.Unlock()
+	if diskBudget.cancel != nil {
+		diskBudget.cancel()
+		diskBudget.cancel = nil
+	}
+	diskBudget.config = budget
+	if budget.MaxBytes <= 0 {
+		diskBudget.status = nil
+		diskBudget.warned = 0
+		fileLoggingStopped.Store(false)
+		return
+	}
+	diskBudget.status = &DiskBudgetStatus{
+		Directories: budget.Directories,
+		MaxBytes:    budget.MaxBytes,
+		FileLogging: !fileLoggingStopped.Load(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	diskBudget.cancel = cancel
+	go runDiskBudget(ctx, budget.Interval.Std())
+}
+
+// runDiskBudget enforces the budget now and then every interval until ctx
+// is cancelled
+func runDiskBudget(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		enforceDiskBudget(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DiskBudgetReport returns the janitor's status, or nil without a budget
+func DiskBudgetReport() *DiskBudgetStatus {
+	diskBudget.mu.Lock()
+	defer diskBudget.mu.Unlock()
+	if diskBudget.status == nil {
+		return nil
+	}
+	status := *diskBudget.status
+	status.Actions = slices.Clone(status.Actions)
+	return &status
+}
+
+// budgetedFile is a file counted toward the disk budget
+type budgetedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	log     bool // Matches the budget's patterns, so the janitor may delete or truncate it
+}
+
+// scanBudgetedFiles returns the regular files in the budget's directories
+func scanBudgetedFiles(budget config.DiskBudgetConfig) []budgetedFile {
+	var files []budgetedFile
+	for _, dir := range budget.Directories {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, budgetedFile{
+				path:    filepath.Join(dir, entry.Name()),
+				size:    info.Size(),
+				modTime: info.ModTime(),
+				log:     matchesAny(budget.Patterns, entry.Name()),
+			})
+		}
+	}
+	return files
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceDiskBudget measures the budget's directories and, while they are
+// over budget, deletes rotated log files oldest first, then truncates open
+// log files largest first. Files that aren't logs are counted but never
+// touched. File logging stops if the directories are still over budget, and
+// resumes once they fit again.
+func enforceDiskBudget(ctx context.Context) {
+	diskBudget.mu.Lock()
+	defer diskBudget.mu.Unlock()
+	if ctx.Err() != nil || diskBudget.status == nil {
+		return
+	}
+	budget, status := diskBudget.config, diskBudget.status
+
+	files := scanBudgetedFiles(budget)
+	var used int64
+	for _, file := range files {
+		used += file.size
+	}
+
+	if used > budget.MaxBytes {
+		open := openLogPaths()
+		var rotated, active []budgetedFile
+		for _, file := range files {
+			switch {
+			case !file.log:
+			case open[file.path]:
+				active = append(active, file)
+			default:
+				rotated = append(rotated, file)
+			}
+		}
+		slices.SortFunc(rotated, func(a, b budgetedFile) int { return a.modTime.Compare(b.modTime) })
+		slices.SortFunc(active, func(a, b budgetedFile) int { return cmp.Compare(b.size, a.size) })
+
+		for _, file := range rotated {
+			if used <= budget.MaxBytes {
+				break
+			}
+			if err := os.Remove(file.path); err != nil {
+				logging.LogDiskBudgetFailed("delete", file.path, err)
+				continue
+			}
+			used -= file.size
+			recordDiskBudgetAction(status, DiskBudgetDeleted, file.path, file.size)
+		}
+		for _, file := range active {
+			if used <= budget.MaxBytes {
+				break
+			}
+			if err := os.Truncate(file.path, 0); err != nil {
+				logging.LogDiskBudgetFailed("truncate", file.path, err)
+				continue
+			}
+			used -= file.size
+			recordDiskBudgetAction(status, DiskBudgetTruncated, file.path, file.size)
+		}
+	}
+
+	switch {
+	case used > budget.MaxBytes && !fileLoggingStopped.Load():
+		logging.LogFileLoggingStopped(used, budget.MaxBytes)
+		fileLoggingStopped.Store(true)
+		recordDiskBudgetAction(status, DiskBudgetStopped, "", 0)
+	case used <= budget.MaxBytes && fileLoggingStopped.Load():
+		fileLoggingStopped.Store(false)
+		logging.LogFileLoggingResumed(used, budget.MaxBytes)
+		recordDiskBudgetAction(status, DiskBudgetResumed, "", 0)
+	}
+
+	// Warn once as usage rises past each threshold
+	percent := int(used * 100 / budget.MaxBytes)
+	reached := 0
+	for _, threshold := range budget.WarnAt {
+		if percent >= threshold {
+			reached = threshold
+		}
+	}
+	if reached > diskBudget.warned {
+		logging.LogDiskBudgetWarning(used, budget.MaxBytes, reached)
+	}
+	diskBudget.warned = reached
+
+	status.UsedBytes = used
+	status.FileLogging = !fileLoggingStopped.Load()
+	status.CheckedAt = time.Now()
+}
+
+// recordDiskBudgetAction logs a janitor action and keeps it for status
+func recordDiskBudgetAction(status *DiskBudgetStatus, action, path string, bytes int64) {
+	if path != "" {
+		logging.LogDiskBudgetAction(action, path, bytes)
+	}
+	status.Actions = append(status.Actions, DiskBudgetAction{
+		Time:   time.Now(),
+		Action: action,
+		Path:   path,
+		Bytes:  bytes,
+	})
+	if len(status.Actions) > diskBudgetHistorySize {
+		status.Actions = status.Actions[len(status.Actions)-diskBudgetHistorySize:]
+	}
+}
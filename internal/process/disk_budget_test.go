@@ -0,0 +1,174 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// startDiskBudget configures a budget over dir and waits for its first run
+func startDiskBudget(t *testing.T, dir string, maxBytes int64) {
+	t.Helper()
+	t.Cleanup(func() { ConfigureDiskBudget(config.DiskBudgetConfig{}) })
+	ConfigureDiskBudget(config.DiskBudgetConfig{
+		Directories: []string{dir},
+		MaxBytes:    maxBytes,
+		Patterns:    []string{config.DefaultDiskBudgetPattern},
+		Interval:    config.Duration(time.Hour),
+		WarnAt:      config.DefaultDiskBudgetWarnAt,
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for DiskBudgetReport().CheckedAt.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("Disk budget janitor did not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// writeAged writes size bytes to name in dir, last modified age ago
+func writeAged(t *testing.T, dir, name string, size int, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestDiskBudgetDeletesOldestRotatedFilesFirst(t *testing.T) {
+	dir := t.TempDir()
+	active, err := createFileWriter(filepath.Join(dir, "{{app}}.log"), "boston")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer active.Close()
+	_, _ = active.Write(bytes.Repeat([]byte("x"), 100))
+
+	newest := writeAged(t, dir, "boston.log.1", 100, time.Hour)
+	oldest := writeAged(t, dir, "boston.log.3.gz", 100, 3*time.Hour)
+	middle := writeAged(t, dir, "boston.log.2.gz", 100, 2*time.Hour)
+	other := writeAged(t, dir, "notes.txt", 50, 4*time.Hour)
+
+	// 450 bytes against a budget of 260: the two oldest rotated files go
+	startDiskBudget(t, dir, 260)
+
+	if exists(oldest) || exists(middle) {
+		t.Error("The two oldest rotated files should have been deleted")
+	}
+	if !exists(newest) || !exists(other) {
+		t.Error("The newest rotated file and the non-log file should remain")
+	}
+	if info, err := os.Stat(active.path); err != nil || info.Size() != 100 {
+		t.Errorf("The active log file should be untouched, got %v, %v", info, err)
+	}
+
+	status := DiskBudgetReport()
+	if status.UsedBytes != 250 || !status.FileLogging {
+		t.Errorf("Status = %+v, want 250 bytes used and file logging on", status)
+	}
+	var actions []string
+	for _, action := range status.Actions {
+		actions = append(actions, action.Action+" "+filepath.Base(action.Path))
+	}
+	want := "deleted boston.log.3.gz, deleted boston.log.2.gz"
+	if got := strings.Join(actions, ", "); got != want {
+		t.Errorf("Actions = %q, want %q", got, want)
+	}
+}
+
+func TestDiskBudgetTruncatesActiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	small, err := createFileWriter(filepath.Join(dir, "small.log"), "navigator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer small.Close()
+	large, err := createFileWriter(filepath.Join(dir, "large.log"), "navigator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer large.Close()
+	_, _ = small.Write(bytes.Repeat([]byte("x"), 50))
+	_, _ = large.Write(bytes.Repeat([]byte("x"), 200))
+	rotated := writeAged(t, dir, "large.log.1", 100, time.Hour)
+
+	startDiskBudget(t, dir, 100)
+
+	if exists(rotated) {
+		t.Error("The rotated file should be deleted before active files are truncated")
+	}
+	if info, _ := os.Stat(large.path); info.Size() != 0 {
+		t.Errorf("The largest active file should be truncated, size %d", info.Size())
+	}
+	if info, _ := os.Stat(small.path); info.Size() != 50 {
+		t.Errorf("The small active file should be untouched, size %d", info.Size())
+	}
+
+	// Writes continue at the start of the truncated file
+	_, _ = large.Write([]byte("after\n"))
+	if content, _ := os.ReadFile(large.path); string(content) != "after\n" {
+		t.Errorf("Truncated file content = %q", content)
+	}
+}
+
+func TestDiskBudgetFallsBackToStdout(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	file, err := createFileWriter(filepath.Join(dir, "access.log"), "navigator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	file.fallback = &stdout
+	_, _ = file.Write([]byte("before\n"))
+
+	// A file the janitor mustn't touch keeps the directory over budget
+	dump := writeAged(t, dir, "core.dump", 500, time.Hour)
+	startDiskBudget(t, dir, 100)
+
+	if status := DiskBudgetReport(); status.FileLogging {
+		t.Fatalf("Status = %+v, want file logging stopped", status)
+	}
+	_, _ = file.Write([]byte("during\n"))
+	if content, _ := os.ReadFile(file.path); len(content) != 0 {
+		t.Errorf("Log file content = %q, want it truncated and left empty", content)
+	}
+	if stdout.String() != "during\n" {
+		t.Errorf("Fallback output = %q, want %q", stdout.String(), "during\n")
+	}
+
+	// File logging resumes once the directory fits the budget again
+	if err := os.Remove(dump); err != nil {
+		t.Fatal(err)
+	}
+	enforceDiskBudget(context.Background())
+	_, _ = file.Write([]byte("after\n"))
+	if content, _ := os.ReadFile(file.path); string(content) != "after\n" {
+		t.Errorf("Log file content = %q, want %q", content, "after\n")
+	}
+
+	var actions []string
+	for _, action := range DiskBudgetReport().Actions {
+		actions = append(actions, action.Action)
+	}
+	want := []string{DiskBudgetTruncated, DiskBudgetStopped, DiskBudgetResumed}
+	if strings.Join(actions, " ") != strings.Join(want, " ") {
+		t.Errorf("Actions = %v, want %v", actions, want)
+	}
+}
@@ -0,0 +1,81 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// EchoResponse is what a tenant with the internal-echo runtime answers
+// every request with
+type EchoResponse struct {
+	Tenant  string            `json:"tenant"`
+	Port    int               `json:"port"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers http.Header       `json:"headers"`
+	Env     map[string]string `json:"env"` // What the app would get on top of Navigator's environment
+}
+
+// startEchoApp serves a tenant with the internal-echo runtime from an
+// in-process handler on the app's port, in place of a process. It stops
+// when ctx is cancelled; releaseGuard is called once it has.
+func startEchoApp(ctx context.Context, app *WebApp, spec CommandSpec, releaseGuard func()) error {
+	logging.LogWebAppStart(spec.Name, app.Port, spec.Command, "", nil)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", app.Port))
+	if err != nil {
+		releaseGuard()
+		return fmt.Errorf("failed to start web app: %w", err)
+	}
+	srv := &http.Server{
+		Handler:           echoHandler(spec.Name, app.Port, spec.Env),
+		ReadHeaderTimeout: config.DefaultReadHeaderTimeout,
+	}
+
+	app.exited = make(chan struct{})
+	app.mutex.Lock()
+	app.spawned = time.Now()
+	app.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.AppOutputWaitDelay)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	// As with a process, stopping without cancel is a crash
+	go func() {
+		err := srv.Serve(listener)
+		releaseGuard()
+		close(app.exited)
+		if ctx.Err() == nil && app.recordCrash(fmt.Sprint(err)) && app.onCrash != nil {
+			app.onCrash()
+		}
+	}()
+	return nil
+}
+
+// echoHandler answers each request with an EchoResponse describing it
+func echoHandler(tenant string, port int, env map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(EchoResponse{
+			Tenant:  tenant,
+			Port:    port,
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Query:   r.URL.RawQuery,
+			Headers: r.Header,
+			Env:     env,
+		})
+	})
+}
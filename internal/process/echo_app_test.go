@@ -0,0 +1,64 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestInternalEchoApp(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name:      "boston",
+		Path:      "/showcase/boston/",
+		Framework: config.RuntimeInternalEcho,
+		Env:       map[string]string{"DATABASE": "boston.sqlite3"},
+	}}
+	appManager := NewAppManager(cfg)
+	defer appManager.Cleanup()
+
+	spec := appManager.processStarter.WebAppCommand(&cfg.Applications.Tenants[0], 4000)
+	if spec.Command != config.RuntimeInternalEcho || spec.Args != nil {
+		t.Errorf("WebAppCommand() = %s %v, want internal-echo without arguments", spec.Command, spec.Args)
+	}
+
+	app, err := appManager.GetOrStartApp("boston")
+	if err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+	<-app.ReadyChan()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/showcase/boston/heats?page=2", app.Port))
+	if err != nil {
+		t.Fatalf("Echo app not serving: %v", err)
+	}
+	defer resp.Body.Close()
+	var echo EchoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil {
+		t.Fatalf("Invalid echo response: %v", err)
+	}
+	if echo.Tenant != "boston" || echo.Path != "/showcase/boston/heats" || echo.Query != "page=2" || echo.Method != http.MethodGet {
+		t.Errorf("Echo = %+v", echo)
+	}
+	if echo.Env["DATABASE"] != "boston.sqlite3" || echo.Env["PORT"] != fmt.Sprint(app.Port) {
+		t.Errorf("Env = %v, want the tenant's env and PORT", echo.Env)
+	}
+
+	// Stopping the app closes its port
+	app.cancel()
+	if !app.WaitExited(5 * time.Second) {
+		t.Fatal("Echo app did not stop")
+	}
+	if conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", app.Port)); err == nil {
+		conn.Close()
+		t.Error("Echo app still listening after it stopped")
+	}
+	if app.State() == AppCrashed {
+		t.Error("A requested stop was recorded as a crash")
+	}
+}
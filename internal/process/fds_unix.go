@@ -0,0 +1,17 @@
+//go:build unix
+
+package process
+
+import "os"
+
+// OpenFileCount returns the number of file descriptors Navigator has open, or
+// -1 if they cannot be counted on this platform
+func OpenFileCount() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			return len(entries) - 1 // Not counting the descriptor used to read the directory
+		}
+	}
+	return -1
+}
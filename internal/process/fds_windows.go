@@ -0,0 +1,8 @@
+//go:build windows
+
+package process
+
+// OpenFileCount returns -1; open handles are not counted on Windows
+func OpenFileCount() int {
+	return -1
+}
@@ -0,0 +1,214 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// names returns the "name" attribute of each record with the given message, in order
+func (c *hookLogCapture) names(msg string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var names []string
+	for _, raw := range bytes.Split(c.buf.Bytes(), []byte("\n")) {
+		var record map[string]interface{}
+		if json.Unmarshal(raw, &record) != nil {
+			continue
+		}
+		if record["msg"] == msg {
+			if name, ok := record["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func sleepProcess(name, group string) config.ManagedProcessConfig {
+	return config.ManagedProcessConfig{Name: name, Command: "sleep", Args: []string{"30"}, Group: group}
+}
+
+// pids returns the current PID of each running managed process by name
+func (m *Manager) pids() map[string]int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	pids := make(map[string]int)
+	for _, proc := range m.processes {
+		proc.mutex.RLock()
+		if proc.Running && proc.Process != nil && proc.Process.Process != nil {
+			pids[proc.Name] = proc.Process.Process.Pid
+		}
+		proc.mutex.RUnlock()
+	}
+	return pids
+}
+
+func waitForRunning(t *testing.T, m *Manager, count int) map[string]int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pids := m.pids(); len(pids) == count {
+			return pids
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("expected %d running processes, got %v", count, m.pids())
+	return nil
+}
+
+func TestProcessGroupRestartsAsUnit(t *testing.T) {
+	skipWithoutShell(t)
+	logs := captureHookLogs(t)
+
+	cfg := &config.Config{ManagedProcesses: []config.ManagedProcessConfig{
+		sleepProcess("first", "svc"),
+		sleepProcess("second", "svc"),
+		sleepProcess("third", "svc"),
+		sleepProcess("standalone", ""),
+	}}
+	manager := NewManager(cfg)
+	defer manager.StopManagedProcesses()
+	_ = manager.StartManagedProcesses()
+	before := waitForRunning(t, manager, 4)
+
+	// Changing one member restarts the whole group but not ungrouped processes
+	changed := sleepProcess("second", "svc")
+	changed.Args = []string{"31"}
+	manager.UpdateManagedProcesses(&config.Config{ManagedProcesses: []config.ManagedProcessConfig{
+		sleepProcess("first", "svc"),
+		changed,
+		sleepProcess("third", "svc"),
+		sleepProcess("standalone", ""),
+	}})
+	after := waitForRunning(t, manager, 4)
+
+	for _, name := range []string{"first", "second", "third"} {
+		if before[name] == after[name] {
+			t.Errorf("%s was not restarted with its group", name)
+		}
+	}
+	if before["standalone"] != after["standalone"] {
+		t.Error("ungrouped process should not be restarted")
+	}
+
+	if got := strings.Join(logs.names("Stopping managed process for group restart"), ","); got != "third,second,first" {
+		t.Errorf("stop order = %s, want third,second,first", got)
+	}
+	if got := strings.Join(logs.names("Restarting managed process"), ","); got != "first,second,third" {
+		t.Errorf("start order = %s, want first,second,third", got)
+	}
+}
+
+func TestProcessReloadUnchangedIsNoop(t *testing.T) {
+	skipWithoutShell(t)
+
+	cfg := &config.Config{ManagedProcesses: []config.ManagedProcessConfig{
+		sleepProcess("first", "svc"),
+		sleepProcess("standalone", ""),
+	}}
+	manager := NewManager(cfg)
+	defer manager.StopManagedProcesses()
+	_ = manager.StartManagedProcesses()
+	before := waitForRunning(t, manager, 2)
+
+	manager.UpdateManagedProcesses(&config.Config{ManagedProcesses: []config.ManagedProcessConfig{
+		sleepProcess("first", "svc"),
+		sleepProcess("standalone", ""),
+	}})
+	after := waitForRunning(t, manager, 2)
+
+	for name, pid := range before {
+		if after[name] != pid {
+			t.Errorf("%s restarted although its configuration did not change", name)
+		}
+	}
+}
+
+func TestProcessGroupReloadSignal(t *testing.T) {
+	skipWithoutShell(t)
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "vector.toml")
+	marker := filepath.Join(dir, "reloaded")
+	if err := os.WriteFile(configFile, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proc := config.ManagedProcessConfig{
+		Name:        "collector",
+		Command:     "sh",
+		Args:        []string{"-c", "trap 'echo reloaded >> " + marker + "' HUP; while true; do sleep 0.05; done"},
+		Group:       "logging",
+		ConfigFiles: []string{configFile},
+	}
+	cfg := &config.Config{
+		ManagedProcesses: []config.ManagedProcessConfig{proc},
+		ProcessGroups:    []config.ManagedProcessGroup{{Name: "logging", ReloadSignal: "SIGHUP"}},
+	}
+	manager := NewManager(cfg)
+	defer manager.StopManagedProcesses()
+	_ = manager.StartManagedProcesses()
+	before := waitForRunning(t, manager, 1)
+	time.Sleep(100 * time.Millisecond) // Let the shell install its trap
+
+	if err := os.WriteFile(configFile, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager.UpdateManagedProcesses(cfg)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(marker); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatal("process did not receive the reload signal")
+	}
+	if after := waitForRunning(t, manager, 1); after["collector"] != before["collector"] {
+		t.Error("process should be signaled, not restarted")
+	}
+
+	// A second reload with no further changes does not signal again
+	manager.UpdateManagedProcesses(cfg)
+	time.Sleep(200 * time.Millisecond)
+	if content, _ := os.ReadFile(marker); strings.Count(string(content), "reloaded") != 1 {
+		t.Errorf("expected exactly one reload, got %q", content)
+	}
+}
+
+func TestConfigFileChangeWithoutSignalRestarts(t *testing.T) {
+	skipWithoutShell(t)
+
+	configFile := filepath.Join(t.TempDir(), "worker.conf")
+	if err := os.WriteFile(configFile, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proc := sleepProcess("worker", "")
+	proc.ConfigFiles = []string{configFile}
+	cfg := &config.Config{ManagedProcesses: []config.ManagedProcessConfig{proc}}
+	manager := NewManager(cfg)
+	defer manager.StopManagedProcesses()
+	_ = manager.StartManagedProcesses()
+	before := waitForRunning(t, manager, 1)
+
+	if err := os.WriteFile(configFile, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager.UpdateManagedProcesses(cfg)
+
+	if after := waitForRunning(t, manager, 1); after["worker"] == before["worker"] {
+		t.Error("process should restart when its config file changes and no reload_signal is set")
+	}
+}
@@ -115,7 +115,7 @@ func TestWaitForReadyWithHealthCheck(t *testing.T) {
 	app := &WebApp{
 		Port:      port,
 		Tenant:    tenant,
-		Starting:  true,
+		state:     AppStarting,
 		readyChan: make(chan struct{}),
 	}
 
@@ -166,7 +166,7 @@ func TestWaitForReadyAcceptsAnyStatusCode(t *testing.T) {
 			app := &WebApp{
 				Port:      port,
 				Tenant:    tenant,
-				Starting:  true,
+				state:     AppStarting,
 				readyChan: make(chan struct{}),
 			}
 
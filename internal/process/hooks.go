@@ -1,76 +1,215 @@
 package process
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"time"
 
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/utils"
 )
 
-// ExecuteHooks executes a list of hook commands with the given environment
-func ExecuteHooks(hooks []config.HookConfig, env map[string]string, hookType string) error {
-	for i, hook := range hooks {
+// ExecuteHooks executes a list of hook commands with the given environment.
+// A failing hook stops the remaining hooks unless it sets continue_on_error.
+// Each hook is bounded by its own timeout and by ctx, whichever ends first;
+// once ctx is done the remaining hooks are skipped.
+func ExecuteHooks(ctx context.Context, hooks []config.HookConfig, env map[string]string, hookType string) error {
+	return executeHooks(ctx, hooks, env, hookType, nil)
+}
+
+// hookReloads collects the reload requests server hooks write to the file
+// named by $NAVIGATOR_RELOAD_FILE
+type hookReloads struct {
+	currentConfigFile string
+	allowedDirs       []string
+	request           *utils.ReloadDecision // The last valid request written
+}
+
+// read takes the request a hook wrote to file, if any
+func (r *hookReloads) read(file, hookType string, hook config.HookConfig) {
+	source := fmt.Sprintf("%s hook %s", hookType, hookName(hook))
+	request, err := utils.ReadReloadRequest(file, source, r.currentConfigFile, r.allowedDirs)
+	if err != nil {
+		slog.Warn("Ignoring reload request", "source", source, "error", err)
+		return
+	}
+	if request != nil {
+		r.request = request
+	}
+}
+
+// executeHooks runs hooks as ExecuteHooks describes; with reloads, each hook
+// may also write a reload request
+func executeHooks(ctx context.Context, hooks []config.HookConfig, env map[string]string, hookType string, reloads *hookReloads) error {
+	var durations []string
+	start := time.Now()
+	defer func() {
+		if len(durations) > 0 {
+			slog.Info("Hooks finished",
+				"type", hookType,
+				"duration", time.Since(start).Round(time.Millisecond),
+				"hooks", durations)
+		}
+	}()
+
+	for _, hook := range hooks {
 		if hook.Command == "" {
 			continue
 		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("hook %s cancelled: %w", hookType, err)
+		}
 
-		// Parse timeout
-		timeout := utils.ParseDurationWithContext(hook.Timeout, 0, map[string]interface{}{
-			"hookType": hookType,
-			"index":    i,
-		})
-
-		// Create command with or without timeout
-		var cmd *exec.Cmd
-		if timeout > 0 {
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			defer cancel()
-			cmd = exec.CommandContext(ctx, hook.Command, hook.Args...)
-		} else {
-			cmd = exec.Command(hook.Command, hook.Args...)
+		hookEnv, reloadFile := env, ""
+		if reloads != nil {
+			if file, err := utils.NewReloadFile(); err != nil {
+				slog.Warn("Cannot create reload request file", "type", hookType, "error", err)
+			} else {
+				reloadFile = file
+				hookEnv = map[string]string{utils.ReloadFileEnv: reloadFile}
+				for key, value := range env {
+					hookEnv[key] = value
+				}
+			}
 		}
 
-		// Set environment if provided
-		if env != nil {
-			cmd.Env = os.Environ()
-			for key, value := range env {
-				cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+		hookStart := time.Now()
+		err := executeHook(ctx, hook, hookEnv, hookType)
+		durations = append(durations, fmt.Sprintf("%s=%s", hookName(hook), time.Since(hookStart).Round(time.Millisecond)))
+		if reloadFile != "" {
+			if err == nil {
+				reloads.read(reloadFile, hookType, hook)
 			}
+			_ = os.Remove(reloadFile)
 		}
+		if err != nil {
+			if hook.ContinueOnError && ctx.Err() == nil {
+				slog.Warn("Hook failed, continuing with remaining hooks",
+					"type", hookType,
+					"hook", hookName(hook),
+					"error", err)
+				continue
+			}
+			return fmt.Errorf("hook %s failed: %w", hookType, err)
+		}
+	}
+	return nil
+}
+
+// executeHook runs a single hook, logging its output line by line. When the
+// hook's timeout or the caller's deadline passes, its whole process group is
+// killed, so children of a shell hook don't outlive it.
+func executeHook(parent context.Context, hook config.HookConfig, env map[string]string, hookType string) error {
+	defer utils.BeginActivity(config.IdleDeferHooks)()
+	timeout := hook.Timeout.Std()
+	name := hookName(hook)
+
+	// The hook's own timeout, bounded by the caller's deadline
+	ctx := parent
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, timeout)
+		defer cancel()
+	}
+	cmd := HookCommand(hook, env).command(ctx)
+	killProcessGroupOnCancel(cmd)
+	// Don't wait forever on output pipes held open by orphaned children
+	cmd.WaitDelay = config.HookWaitDelay
+
+	// Capture output into the structured log
+	stdout := &hookLogWriter{hookType: hookType, name: name, stream: config.StreamStdout}
+	stderr := &hookLogWriter{hookType: hookType, name: name, stream: config.StreamStderr}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Log command execution
+	slog.Info("Executing hook",
+		"type", hookType,
+		"hook", name,
+		"command", hook.Command,
+		"args", hook.Args,
+		"dir", hook.Dir,
+		"shell", hook.Shell,
+		"timeout", timeout)
 
-		// Log command execution
-		slog.Info("Executing hook",
+	// Execute and wait
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if err != nil {
+		exitCode := -1
+		if cmd.ProcessState != nil {
+			exitCode = cmd.ProcessState.ExitCode()
+		}
+		switch {
+		case parent.Err() != nil:
+			err = fmt.Errorf("cancelled by caller: %w", parent.Err())
+		case ctx.Err() != nil:
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		slog.Error("Hook execution failed",
 			"type", hookType,
+			"hook", name,
 			"command", hook.Command,
-			"args", hook.Args,
-			"timeout", timeout)
+			"error", err,
+			"exitCode", exitCode)
+		return err
+	}
+	return nil
+}
 
-		// Execute and wait
-		output, err := cmd.CombinedOutput()
+// hookName returns the name that tags a hook's log entries
+func hookName(hook config.HookConfig) string {
+	if hook.Name != "" {
+		return hook.Name
+	}
+	if hook.Shell {
+		return "sh"
+	}
+	return filepath.Base(hook.Command)
+}
 
-		// Always log output at INFO level if present
-		if len(output) > 0 {
-			slog.Info("Hook output",
-				"type", hookType,
-				"command", hook.Command,
-				"output", string(output))
-		}
+// hookLogWriter logs each line a hook writes to one of its output streams
+type hookLogWriter struct {
+	hookType string
+	name     string
+	stream   string
+	partial  []byte
+}
 
-		if err != nil {
-			slog.Error("Hook execution failed",
-				"type", hookType,
-				"command", hook.Command,
-				"error", err,
-				"exitCode", cmd.ProcessState.ExitCode())
-			return fmt.Errorf("hook %s failed: %w", hookType, err)
+// Write logs complete lines and buffers any trailing partial line
+func (w *hookLogWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
 		}
+		w.logLine(w.partial[:i])
+		w.partial = w.partial[i+1:]
 	}
-	return nil
+	return len(p), nil
+}
+
+// Flush logs any remaining partial line
+func (w *hookLogWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.logLine(w.partial)
+		w.partial = nil
+	}
+}
+
+func (w *hookLogWriter) logLine(line []byte) {
+	slog.Info("Hook output",
+		"type", w.hookType,
+		"hook", w.name,
+		"stream", w.stream,
+		"line", string(bytes.TrimRight(line, "\r")))
 }
 
 // HookResult contains the result of executing hooks, including reload decision
@@ -80,26 +219,39 @@ type HookResult struct {
 }
 
 // ExecuteServerHooks executes server lifecycle hooks
-func ExecuteServerHooks(hooks []config.HookConfig, hookType string) error {
-	return ExecuteHooks(hooks, nil, fmt.Sprintf("server.%s", hookType))
+func ExecuteServerHooks(ctx context.Context, hooks []config.HookConfig, hookType string) error {
+	return ExecuteHooks(ctx, hooks, nil, fmt.Sprintf("server.%s", hookType))
 }
 
 // ExecuteServerHooksWithReload executes server lifecycle hooks and checks for reload_config
 // Returns HookResult containing any error and reload decision
 // configLoadTime is when the current configuration was last loaded - this is used to detect
 // config changes that occurred while the machine was suspended (not just during hook execution)
-func ExecuteServerHooksWithReload(hooks []config.HookConfig, hookType, currentConfigFile string, configLoadTime time.Time) HookResult {
+// A reload request a hook writes to $NAVIGATOR_RELOAD_FILE takes precedence over reload_config.
+func ExecuteServerHooksWithReload(ctx context.Context, hooks []config.HookConfig, hookType, currentConfigFile string, configLoadTime time.Time) HookResult {
+	var reloadConfigs []string
+	for _, hook := range hooks {
+		reloadConfigs = append(reloadConfigs, hook.ReloadConfig)
+	}
+	reloads := &hookReloads{
+		currentConfigFile: currentConfigFile,
+		allowedDirs:       utils.ReloadConfigDirs(currentConfigFile, reloadConfigs...),
+	}
+
 	// Execute all hooks
-	err := ExecuteHooks(hooks, nil, fmt.Sprintf("server.%s", hookType))
+	err := executeHooks(ctx, hooks, nil, fmt.Sprintf("server.%s", hookType), reloads)
 
 	// Only check for reload if hooks succeeded
 	var reloadDecision utils.ReloadDecision
-	if err == nil {
+	if err == nil && reloads.request != nil {
+		reloadDecision = *reloads.request
+	} else if err == nil {
 		// Check if any hook specified reload_config
-		var reloadConfigPath string
+		var reloadConfigPath, source string
 		for _, hook := range hooks {
 			if hook.ReloadConfig != "" {
 				reloadConfigPath = hook.ReloadConfig
+				source = fmt.Sprintf("server.%s hook %s", hookType, hookName(hook))
 				break // Use first non-empty reload_config
 			}
 		}
@@ -107,6 +259,7 @@ func ExecuteServerHooksWithReload(hooks []config.HookConfig, hookType, currentCo
 		// Determine if config should be reloaded
 		// Uses configLoadTime to detect changes since last load, not just during hook execution
 		reloadDecision = utils.ShouldReloadConfig(reloadConfigPath, currentConfigFile, configLoadTime)
+		reloadDecision.Source = source
 	} else {
 		slog.Warn("Skipping config reload due to hook failure",
 			"hookType", hookType,
@@ -120,14 +273,14 @@ func ExecuteServerHooksWithReload(hooks []config.HookConfig, hookType, currentCo
 }
 
 // ExecuteTenantHooks executes tenant lifecycle hooks
-func ExecuteTenantHooks(defaultHooks, specificHooks []config.HookConfig, env map[string]string, tenantName, hookType string) error {
+func ExecuteTenantHooks(ctx context.Context, defaultHooks, specificHooks []config.HookConfig, env map[string]string, tenantName, hookType string) error {
 	// Execute default hooks first
-	if err := ExecuteHooks(defaultHooks, env, fmt.Sprintf("tenant.%s.default", hookType)); err != nil {
+	if err := ExecuteHooks(ctx, defaultHooks, env, fmt.Sprintf("tenant.%s.default", hookType)); err != nil {
 		return err
 	}
 
 	// Then execute tenant-specific hooks
-	if err := ExecuteHooks(specificHooks, env, fmt.Sprintf("tenant.%s.%s", hookType, tenantName)); err != nil {
+	if err := ExecuteHooks(ctx, specificHooks, env, fmt.Sprintf("tenant.%s.%s", hookType, tenantName)); err != nil {
 		return err
 	}
 
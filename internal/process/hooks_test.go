@@ -0,0 +1,255 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// hookLogCapture collects JSON log records emitted while running hooks
+type hookLogCapture struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *hookLogCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+// outputLines returns the logged output lines for a hook and stream
+func (c *hookLogCapture) outputLines(hook, stream string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lines []string
+	for _, raw := range bytes.Split(c.buf.Bytes(), []byte("\n")) {
+		var record map[string]interface{}
+		if json.Unmarshal(raw, &record) != nil {
+			continue
+		}
+		if record["msg"] == "Hook output" && record["hook"] == hook && record["stream"] == stream {
+			lines = append(lines, record["line"].(string))
+		}
+	}
+	return lines
+}
+
+// lastRecord returns the last log record with the given message
+func (c *hookLogCapture) lastRecord(msg string) map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var last map[string]interface{}
+	for _, raw := range bytes.Split(c.buf.Bytes(), []byte("\n")) {
+		var record map[string]interface{}
+		if json.Unmarshal(raw, &record) == nil && record["msg"] == msg {
+			last = record
+		}
+	}
+	return last
+}
+
+func captureHookLogs(t *testing.T) *hookLogCapture {
+	t.Helper()
+	capture := &hookLogCapture{}
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(capture, nil)))
+	t.Cleanup(func() { slog.SetDefault(original) })
+	return capture
+}
+
+func skipWithoutShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use /bin/sh")
+	}
+}
+
+func TestHookOutputCapturedInStructuredLog(t *testing.T) {
+	skipWithoutShell(t)
+	logs := captureHookLogs(t)
+
+	hooks := []config.HookConfig{{
+		Name:    "warmup",
+		Command: "printf 'one\\ntwo\\n'; echo oops >&2; printf partial",
+		Shell:   true,
+	}}
+	if err := ExecuteHooks(context.Background(), hooks, nil, "server.ready"); err != nil {
+		t.Fatalf("ExecuteHooks failed: %v", err)
+	}
+
+	if got := logs.outputLines("warmup", "stdout"); len(got) != 3 || got[0] != "one" || got[1] != "two" || got[2] != "partial" {
+		t.Errorf("stdout lines = %q, want [one two partial]", got)
+	}
+	if got := logs.outputLines("warmup", "stderr"); len(got) != 1 || got[0] != "oops" {
+		t.Errorf("stderr lines = %q, want [oops]", got)
+	}
+}
+
+func TestHookEnvAndDir(t *testing.T) {
+	skipWithoutShell(t)
+	logs := captureHookLogs(t)
+	dir := t.TempDir()
+
+	hooks := []config.HookConfig{{
+		Name:    "env-dir",
+		Command: "/bin/sh",
+		Args:    []string{"-c", "pwd; echo $SHARED $HOOK_ONLY"},
+		Env:     map[string]string{"HOOK_ONLY": "hook", "SHARED": "from-hook"},
+		Dir:     dir,
+	}}
+	env := map[string]string{"SHARED": "from-tenant"}
+	if err := ExecuteHooks(context.Background(), hooks, env, "tenant.start"); err != nil {
+		t.Fatalf("ExecuteHooks failed: %v", err)
+	}
+
+	lines := logs.outputLines("env-dir", "stdout")
+	if len(lines) != 2 {
+		t.Fatalf("stdout lines = %q, want 2 lines", lines)
+	}
+	resolved, _ := filepath.EvalSymlinks(dir)
+	if lines[0] != dir && lines[0] != resolved {
+		t.Errorf("hook ran in %q, want %q", lines[0], dir)
+	}
+	if lines[1] != "from-hook hook" {
+		t.Errorf("env output = %q, want hook env to override shared env", lines[1])
+	}
+}
+
+func TestHookShellPipesAndArgs(t *testing.T) {
+	skipWithoutShell(t)
+	logs := captureHookLogs(t)
+
+	hooks := []config.HookConfig{{
+		Name:    "pipe",
+		Command: `echo "$1 $2" | tr a-z A-Z`,
+		Args:    []string{"hello", "world"},
+		Shell:   true,
+	}}
+	if err := ExecuteHooks(context.Background(), hooks, nil, "server.start"); err != nil {
+		t.Fatalf("ExecuteHooks failed: %v", err)
+	}
+	if got := logs.outputLines("pipe", "stdout"); len(got) != 1 || got[0] != "HELLO WORLD" {
+		t.Errorf("stdout lines = %q, want [HELLO WORLD]", got)
+	}
+}
+
+func TestHookContinueOnError(t *testing.T) {
+	skipWithoutShell(t)
+
+	t.Run("failure aborts remaining hooks by default", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "ran")
+		hooks := []config.HookConfig{
+			{Command: "exit 1", Shell: true},
+			{Command: "touch " + marker, Shell: true},
+		}
+		if err := ExecuteHooks(context.Background(), hooks, nil, "server.ready"); err == nil {
+			t.Error("expected error from failing hook")
+		}
+		if _, err := os.Stat(marker); err == nil {
+			t.Error("hook after failure should not have run")
+		}
+	})
+
+	t.Run("continue_on_error runs remaining hooks", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "ran")
+		hooks := []config.HookConfig{
+			{Name: "optional-warmup", Command: "exit 1", Shell: true, ContinueOnError: true},
+			{Command: "touch " + marker, Shell: true},
+		}
+		if err := ExecuteHooks(context.Background(), hooks, nil, "server.ready"); err != nil {
+			t.Errorf("optional hook failure should not fail the list: %v", err)
+		}
+		if _, err := os.Stat(marker); err != nil {
+			t.Error("hook after optional failure should have run")
+		}
+	})
+}
+
+func TestHookName(t *testing.T) {
+	tests := []struct {
+		hook     config.HookConfig
+		expected string
+	}{
+		{config.HookConfig{Name: "prerender", Command: "bin/prerender"}, "prerender"},
+		{config.HookConfig{Command: "/usr/local/bin/warm-cache"}, "warm-cache"},
+		{config.HookConfig{Command: "echo hi | cat", Shell: true}, "sh"},
+	}
+	for _, tt := range tests {
+		if got := hookName(tt.hook); got != tt.expected {
+			t.Errorf("hookName(%+v) = %q, want %q", tt.hook, got, tt.expected)
+		}
+	}
+}
+
+func TestHookCancelledByCallerDeadline(t *testing.T) {
+	skipWithoutShell(t)
+	logs := captureHookLogs(t)
+
+	// The background child would outlive a kill of the shell alone
+	marker := filepath.Join(t.TempDir(), "survived")
+	hooks := []config.HookConfig{
+		{Name: "slow", Command: "(sleep 1; touch " + marker + ") & wait", Shell: true, Timeout: config.Duration(time.Minute)},
+		{Name: "skipped", Command: "true", Shell: true, ContinueOnError: true},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := ExecuteHooks(ctx, hooks, nil, "server.stop")
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ExecuteHooks() error = %v, want the caller's deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("hook ran for %v after the caller's deadline", elapsed)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("hook's child process survived the hook being killed")
+	}
+
+	summary := logs.lastRecord("Hooks finished")
+	if hooks, _ := summary["hooks"].([]interface{}); len(hooks) != 1 || !strings.HasPrefix(hooks[0].(string), "slow=") {
+		t.Errorf("Hooks finished summary = %v, want only the slow hook's duration", summary)
+	}
+}
+
+func TestServerHookReloadRequest(t *testing.T) {
+	skipWithoutShell(t)
+
+	dir := t.TempDir()
+	current := filepath.Join(dir, "navigator.yml")
+	next := filepath.Join(dir, "navigator-next.yml")
+	for _, file := range []string{current, next} {
+		if err := os.WriteFile(file, []byte("server:\n  listen: 3000\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hooks := []config.HookConfig{
+		{Name: "prerender", Command: `echo '{"should_reload": true, "new_config_file": "navigator-next.yml", "restart_tenants": ["boston"]}' > "$NAVIGATOR_RELOAD_FILE"`, Shell: true},
+		{Name: "escape", Command: `echo '{"should_reload": true, "new_config_file": "/etc/passwd"}' > "$NAVIGATOR_RELOAD_FILE"`, Shell: true},
+	}
+	result := ExecuteServerHooksWithReload(context.Background(), hooks, "ready", current, time.Now())
+	if result.Error != nil {
+		t.Fatalf("ExecuteServerHooksWithReload() error = %v", result.Error)
+	}
+	decision := result.ReloadDecision
+	if !decision.ShouldReload || decision.NewConfigFile != next || len(decision.RestartTenants) != 1 ||
+		decision.Source != "server.ready hook prerender" {
+		t.Errorf("ReloadDecision = %+v, want the prerender hook's request, ignoring the escape", decision)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package process
+
+import "os/exec"
+
+// killProcessGroupOnCancel leaves cmd's default cancellation, which kills
+// only the hook's own process, on Windows
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,152 @@
+package process
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// idleScheduler runs the periodic idle and OOM checks for every web app from
+// a single goroutine. Apps wait in a heap ordered by when they are next due;
+// the goroutine sleeps until the earliest is due, checks every app that is,
+// and exits once no apps remain.
+type idleScheduler struct {
+	mu       sync.Mutex
+	interval time.Duration
+	entries  idleHeap
+	byTenant map[string]*idleEntry
+	wake     chan struct{}
+	running  bool
+
+	// check is called for each due app and returns whether to keep checking it
+	check func(tenantName string) bool
+}
+
+// idleEntry is an app waiting for its next check
+type idleEntry struct {
+	tenant string
+	due    time.Time
+	index  int
+}
+
+func newIdleScheduler(interval time.Duration, check func(string) bool) *idleScheduler {
+	return &idleScheduler{
+		interval: interval,
+		byTenant: make(map[string]*idleEntry),
+		wake:     make(chan struct{}, 1),
+		check:    check,
+	}
+}
+
+// schedule checks an app one interval from now, replacing any pending check
+func (s *idleScheduler) schedule(tenantName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := time.Now().Add(s.interval)
+	if entry, ok := s.byTenant[tenantName]; ok {
+		entry.due = due
+		heap.Fix(&s.entries, entry.index)
+	} else {
+		entry := &idleEntry{tenant: tenantName, due: due}
+		heap.Push(&s.entries, entry)
+		s.byTenant[tenantName] = entry
+	}
+
+	if !s.running {
+		s.running = true
+		go s.run()
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// clear drops every pending check, letting the goroutine exit
+func (s *idleScheduler) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+	s.byTenant = make(map[string]*idleEntry)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pending returns the number of apps waiting for a check
+func (s *idleScheduler) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// run checks apps as they come due until none remain
+func (s *idleScheduler) run() {
+	for {
+		s.mu.Lock()
+		if len(s.entries) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		now := time.Now()
+		var due []string
+		for len(s.entries) > 0 && !s.entries[0].due.After(now) {
+			entry := heap.Pop(&s.entries).(*idleEntry)
+			delete(s.byTenant, entry.tenant)
+			due = append(due, entry.tenant)
+		}
+		var wait time.Duration
+		if len(s.entries) > 0 {
+			wait = s.entries[0].due.Sub(now)
+		}
+		s.mu.Unlock()
+
+		if len(due) > 0 {
+			// Checks run without the lock; they may stop apps or reschedule them
+			for _, tenantName := range due {
+				if s.check(tenantName) {
+					s.schedule(tenantName)
+				}
+			}
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		}
+		timer.Stop()
+	}
+}
+
+// idleHeap orders entries by due time, earliest first
+type idleHeap []*idleEntry
+
+func (h idleHeap) Len() int           { return len(h) }
+func (h idleHeap) Less(i, j int) bool { return h[i].due.Before(h[j].due) }
+
+func (h idleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *idleHeap) Push(x interface{}) {
+	entry := x.(*idleEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *idleHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
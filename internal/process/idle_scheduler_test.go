@@ -0,0 +1,124 @@
+package process
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestIdleSchedulerChecksDueApps(t *testing.T) {
+	var mu sync.Mutex
+	checks := map[string]int{}
+	scheduler := newIdleScheduler(10*time.Millisecond, func(tenant string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		checks[tenant]++
+		return tenant == "busy" && checks[tenant] < 3 // Keep checking busy until its third check
+	})
+
+	scheduler.schedule("busy")
+	scheduler.schedule("idle")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for scheduler.pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Scheduler never drained")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if checks["busy"] != 3 || checks["idle"] != 1 {
+		t.Errorf("checks = %v, want busy 3 and idle 1", checks)
+	}
+}
+
+func TestIdleSchedulerClear(t *testing.T) {
+	scheduler := newIdleScheduler(time.Hour, func(string) bool { return true })
+	scheduler.schedule("a")
+	scheduler.schedule("b")
+	scheduler.schedule("a") // Rescheduling replaces the pending check
+	if scheduler.pending() != 2 {
+		t.Errorf("pending() = %d, want 2", scheduler.pending())
+	}
+
+	scheduler.clear()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		scheduler.mu.Lock()
+		running := scheduler.running
+		scheduler.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Scheduler goroutine did not exit after clear")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitForGoroutines waits for the goroutine count to fall to baseline
+func waitForGoroutines(t *testing.T, baseline int, when string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: %d goroutines, baseline %d", when, runtime.NumGoroutine(), baseline)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestAppManagerGoroutinesReturnToBaseline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts hundreds of processes")
+	}
+
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 14000
+	cfg.Applications.Pools.Timeout = config.Duration(time.Millisecond)
+	for i := 0; i < 100; i++ {
+		cfg.Applications.Tenants = append(cfg.Applications.Tenants, config.Tenant{
+			Name:    fmt.Sprintf("tenant-%d", i),
+			Runtime: "echo",
+			Server:  "started",
+		})
+	}
+
+	manager := NewAppManager(cfg)
+	manager.idle.interval = 10 * time.Millisecond
+	baseline := runtime.NumGoroutine()
+	baselineFDs := OpenFileCount()
+
+	for round := 0; round < 3; round++ {
+		for _, tenant := range cfg.Applications.Tenants {
+			if _, err := manager.GetOrStartApp(tenant.Name); err != nil {
+				t.Fatalf("GetOrStartApp(%s) error = %v", tenant.Name, err)
+			}
+		}
+
+		if round%2 == 0 {
+			// Let the idle scheduler stop every app
+			deadline := time.Now().Add(10 * time.Second)
+			for len(manager.Status()) > 0 {
+				if time.Now().After(deadline) {
+					t.Fatalf("Round %d: %d apps still running after the idle timeout", round, len(manager.Status()))
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		} else {
+			manager.Cleanup()
+		}
+
+		waitForGoroutines(t, baseline, fmt.Sprintf("round %d", round))
+		if fds := OpenFileCount(); fds > baselineFDs {
+			t.Errorf("Round %d: %d open FDs, baseline %d", round, fds, baselineFDs)
+		}
+	}
+}
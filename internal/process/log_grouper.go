@@ -0,0 +1,140 @@
+package process
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// lineGrouper assembles complete lines from a stream's writes and, with a
+// start pattern, folds lines that don't match it into the entry before
+// them. Entries are passed to emit without their trailing newline; an
+// incomplete line or entry is emitted once the stream has been quiet for
+// the timeout, so the last output of a crashing app isn't held back.
+type lineGrouper struct {
+	mutex    sync.Mutex
+	start    *regexp.Regexp // nil = every line is an entry
+	maxLines int
+	maxBytes int
+	timeout  time.Duration
+	emit     func(entry []byte)
+
+	partial []byte // Output after the last newline
+	entry   []byte // Lines of the current entry, joined by newlines
+	lines   int
+	timer   *time.Timer
+}
+
+// groupingEnabled reports whether output must be assembled into entries
+// before it's formatted
+func groupingEnabled(logConfig config.LogConfig) bool {
+	return logConfig.Multiline.Start != "" || logConfig.JSONPassthrough
+}
+
+// newLineGrouper creates a grouper for logging.multiline, passing entries
+// to emit
+func newLineGrouper(multiline config.MultilineConfig, emit func(entry []byte)) *lineGrouper {
+	g := &lineGrouper{
+		start:    multiline.StartPattern,
+		maxLines: multiline.MaxLines,
+		maxBytes: multiline.MaxBytes,
+		timeout:  time.Duration(multiline.Timeout),
+		emit:     emit,
+	}
+	if g.maxLines <= 0 {
+		g.maxLines = config.DefaultMultilineMaxLines
+	}
+	if g.maxBytes <= 0 {
+		g.maxBytes = config.DefaultMultilineMaxBytes
+	}
+	if g.timeout <= 0 {
+		g.timeout = config.DefaultMultilineTimeout
+	}
+	return g
+}
+
+// write adds a stream's output, emitting the entries it completes
+func (g *lineGrouper) write(p []byte) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			g.partial = append(g.partial, p...)
+			if len(g.partial) >= g.maxBytes {
+				g.addLine(g.partial)
+				g.partial = g.partial[:0]
+			}
+			break
+		}
+		if len(g.partial) > 0 {
+			g.partial = append(g.partial, p[:end]...)
+			g.addLine(g.partial)
+			g.partial = g.partial[:0]
+		} else {
+			g.addLine(p[:end])
+		}
+		p = p[end+1:]
+	}
+
+	// Wait for the stream to go quiet before writing what's incomplete
+	if len(g.partial) == 0 && g.lines == 0 {
+		return
+	}
+	if g.timer == nil {
+		g.timer = time.AfterFunc(g.timeout, g.flush)
+	} else {
+		g.timer.Reset(g.timeout)
+	}
+}
+
+// addLine folds a complete line into the current entry, or emits the
+// current entry and starts a new one. Must be called with g.mutex held.
+func (g *lineGrouper) addLine(line []byte) {
+	if g.start == nil {
+		g.emit(line)
+		return
+	}
+	if g.lines == 0 && len(line) == 0 {
+		return
+	}
+	if g.lines > 0 && !g.start.Match(line) && g.lines < g.maxLines && len(g.entry)+1+len(line) <= g.maxBytes {
+		g.entry = append(g.entry, '\n')
+		g.entry = append(g.entry, line...)
+		g.lines++
+		return
+	}
+	g.emitEntry()
+	g.entry = append(g.entry[:0], line...)
+	g.lines = 1
+}
+
+// emitEntry emits the current entry, if any. Must be called with g.mutex held.
+func (g *lineGrouper) emitEntry() {
+	if g.lines == 0 {
+		return
+	}
+	g.emit(g.entry)
+	g.entry = g.entry[:0]
+	g.lines = 0
+}
+
+// flush emits the incomplete line and entry
+func (g *lineGrouper) flush() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	if len(g.partial) > 0 {
+		g.addLine(g.partial)
+		g.partial = g.partial[:0]
+	}
+	g.emitEntry()
+}
@@ -0,0 +1,157 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// syncBuffer is a bytes.Buffer safe to share between stream writers and
+// grouper timers
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+// entries decodes the JSON lines written so far
+func (b *syncBuffer) entries(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// railsMultiline starts an entry at each Rails logger line
+var railsMultiline = config.MultilineConfig{
+	Start:        `^[A-Z], \[`,
+	StartPattern: regexp.MustCompile(`^[A-Z], \[`),
+	Timeout:      config.Duration(time.Hour),
+}
+
+// newGroupedJSONWriters returns stdout and stderr JSON writers for one app
+// sharing an output
+func newGroupedJSONWriters(logConfig config.LogConfig, out *syncBuffer) (*JSONLogWriter, *JSONLogWriter) {
+	stdout := newFormatWriter("boston", "stdout", "", config.LogFormatJSON, logConfig, out).(*JSONLogWriter)
+	stderr := newFormatWriter("boston", "stderr", "", config.LogFormatJSON, logConfig, out).(*JSONLogWriter)
+	return stdout, stderr
+}
+
+func TestMultilineGroupsInterleavedStreams(t *testing.T) {
+	out := &syncBuffer{}
+	stdout, stderr := newGroupedJSONWriters(config.LogConfig{Format: "json", Multiline: railsMultiline}, out)
+
+	_, _ = stderr.Write([]byte("E, [2025-06-01T02:00:00] ERROR -- : NoMethodError\n  app/models/heat.rb:12\n"))
+	_, _ = stdout.Write([]byte("I, [2025-06-01T02:00:00]  INFO -- : Started GET /heats\n"))
+	_, _ = stderr.Write([]byte("  app/controllers/heats_controller.rb:"))
+	_, _ = stdout.Write([]byte("I, [2025-06-01T02:00:01]  INFO -- : Completed 200 OK\n"))
+	_, _ = stderr.Write([]byte("40\n\n  bin/rails:4\nE, [2025-06-01T02:00:02] ERROR -- : second\n"))
+	stdout.group.flush()
+	stderr.group.flush()
+
+	var got []string
+	for _, entry := range out.entries(t) {
+		got = append(got, entry["stream"].(string)+": "+entry["message"].(string))
+	}
+	want := []string{
+		"stdout: I, [2025-06-01T02:00:00]  INFO -- : Started GET /heats",
+		"stderr: E, [2025-06-01T02:00:00] ERROR -- : NoMethodError\n  app/models/heat.rb:12\n  app/controllers/heats_controller.rb:40\n\n  bin/rails:4",
+		"stdout: I, [2025-06-01T02:00:01]  INFO -- : Completed 200 OK",
+		"stderr: E, [2025-06-01T02:00:02] ERROR -- : second",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMultilineCaps(t *testing.T) {
+	multiline := railsMultiline
+	multiline.MaxLines = 3
+	var entries []string
+	g := newLineGrouper(multiline, func(entry []byte) { entries = append(entries, string(entry)) })
+
+	g.write([]byte("E, [1] first\n  a\n  b\n  c\n  d\n"))
+	g.flush()
+	if len(entries) != 2 || entries[0] != "E, [1] first\n  a\n  b" || entries[1] != "  c\n  d" {
+		t.Errorf("Entries = %q, want a new entry after 3 lines", entries)
+	}
+}
+
+func TestMultilineFlushesAfterTimeout(t *testing.T) {
+	multiline := railsMultiline
+	multiline.Timeout = config.Duration(20 * time.Millisecond)
+	out := &syncBuffer{}
+	_, stderr := newGroupedJSONWriters(config.LogConfig{Format: "json", Multiline: multiline}, out)
+
+	// The app dies mid-trace, without a final newline
+	_, _ = stderr.Write([]byte("F, [2025-06-01T02:00:00] FATAL -- : out of memory\n  app/jobs/score.rb:7"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(out.entries(t)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Incomplete entry was never written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := out.entries(t)[0]["message"]; got != "F, [2025-06-01T02:00:00] FATAL -- : out of memory\n  app/jobs/score.rb:7" {
+		t.Errorf("Message = %q", got)
+	}
+}
+
+func TestJSONPassthroughMergesFields(t *testing.T) {
+	out := &syncBuffer{}
+	stdout, stderr := newGroupedJSONWriters(config.LogConfig{Format: "json", JSONPassthrough: true}, out)
+
+	// A JSON line split across writes, interleaved with the other stream
+	_, _ = stdout.Write([]byte(`{"level":"info","message":"Started GET","request_id":"ab`))
+	_, _ = stderr.Write([]byte("plain warning\n"))
+	_, _ = stdout.Write([]byte(`c","source":"app","@timestamp":"2025-06-01T02:00:00Z"}` + "\n[1, 2]\n"))
+	stdout.group.flush()
+	stderr.group.flush()
+
+	entries := out.entries(t)
+	if len(entries) != 3 {
+		t.Fatalf("Got %d entries, want 3: %v", len(entries), entries)
+	}
+	if entries[0]["message"] != "plain warning" || entries[0]["stream"] != "stderr" {
+		t.Errorf("Plain line entry = %v", entries[0])
+	}
+	merged := entries[1]
+	for key, want := range map[string]string{
+		"message":    "Started GET",
+		"level":      "info",
+		"request_id": "abc",
+		"source":     "boston", // Navigator's envelope wins
+		"stream":     "stdout",
+		"@timestamp": "2025-06-01T02:00:00Z", // The app's own timestamp is kept
+	} {
+		if merged[key] != want {
+			t.Errorf("Merged %s = %v, want %q", key, merged[key], want)
+		}
+	}
+	if entries[2]["message"] != "[1, 2]" {
+		t.Errorf("Non-object JSON should be wrapped, got %v", entries[2])
+	}
+}
@@ -0,0 +1,204 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// truncatedLineMarker is appended to lines cut at max_line_length
+const truncatedLineMarker = " [truncated]"
+
+// logLimiter tracks output limits for a single source (app or process name).
+// It is shared by the stdout and stderr writers of that source.
+type logLimiter struct {
+	mu     sync.Mutex
+	limits config.LogLimitsConfig
+	clock  clock.Clock
+
+	// Token bucket for the line rate limit
+	tokens     float64
+	lastRefill time.Time
+
+	// Daily byte cap
+	day      int
+	dayBytes int64
+
+	// Lines dropped since the last summary
+	droppedRate  int
+	droppedBytes int
+
+	summaryOutput io.Writer
+	summaryTimer  clock.Timer
+}
+
+// logLimiters holds one limiter per source so limits span process restarts
+var logLimiters = struct {
+	sync.Mutex
+	bySource map[string]*logLimiter
+}{bySource: make(map[string]*logLimiter)}
+
+// getLogLimiter returns the limiter for source, applying the current limits
+func getLogLimiter(source string, limits config.LogLimitsConfig) *logLimiter {
+	logLimiters.Lock()
+	defer logLimiters.Unlock()
+
+	limiter, exists := logLimiters.bySource[source]
+	if !exists {
+		limiter = newLogLimiter(limits)
+		logLimiters.bySource[source] = limiter
+		return limiter
+	}
+
+	limiter.mu.Lock()
+	limiter.limits = limits
+	if limiter.tokens > float64(limits.Burst) {
+		limiter.tokens = float64(limits.Burst)
+	}
+	limiter.mu.Unlock()
+	return limiter
+}
+
+// newLogLimiter creates a limiter with a full token bucket
+func newLogLimiter(limits config.LogLimitsConfig) *logLimiter {
+	return &logLimiter{
+		limits: limits,
+		clock:  clock.Real,
+		tokens: float64(limits.Burst),
+	}
+}
+
+// limitsEnabled reports whether any output limit is configured
+func limitsEnabled(limits config.LogLimitsConfig) bool {
+	return limits.MaxLineLength > 0 || limits.RateLimit > 0 || limits.MaxBytesPerDay > 0
+}
+
+// allowLine applies the rate limit and daily byte cap to a line of size bytes.
+// Must be called with l.mu held.
+func (l *logLimiter) allowLine(size int) bool {
+	now := l.clock.Now()
+
+	if l.limits.RateLimit > 0 {
+		if !l.lastRefill.IsZero() {
+			l.tokens += now.Sub(l.lastRefill).Seconds() * l.limits.RateLimit
+			if l.tokens > float64(l.limits.Burst) {
+				l.tokens = float64(l.limits.Burst)
+			}
+		}
+		l.lastRefill = now
+		if l.tokens < 1 {
+			l.droppedRate++
+			return false
+		}
+	}
+
+	if l.limits.MaxBytesPerDay > 0 {
+		day := now.Year()*1000 + now.YearDay()
+		if day != l.day {
+			l.day = day
+			l.dayBytes = 0
+		}
+		if l.dayBytes+int64(size) > l.limits.MaxBytesPerDay {
+			l.droppedBytes++
+			return false
+		}
+		l.dayBytes += int64(size)
+	}
+
+	if l.limits.RateLimit > 0 {
+		l.tokens--
+	}
+	return true
+}
+
+// scheduleSummary arranges for a dropped-line summary to be emitted.
+// Must be called with l.mu held.
+func (l *logLimiter) scheduleSummary(output io.Writer) {
+	l.summaryOutput = output
+	if l.summaryTimer == nil {
+		l.summaryTimer = l.clock.AfterFunc(config.LogDropSummaryInterval, l.flushSummary)
+	}
+}
+
+// flushSummary writes a summary of dropped lines to the source's output
+func (l *logLimiter) flushSummary() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.summaryTimer = nil
+	dropped := l.droppedRate + l.droppedBytes
+	if dropped == 0 || l.summaryOutput == nil {
+		return
+	}
+
+	summary := fmt.Sprintf("[navigator] %d lines dropped (%d over rate limit, %d over daily byte cap)\n",
+		dropped, l.droppedRate, l.droppedBytes)
+	l.droppedRate = 0
+	l.droppedBytes = 0
+	_, _ = l.summaryOutput.Write([]byte(summary))
+}
+
+// limitedLogWriter enforces log limits before passing lines to the underlying writer
+type limitedLogWriter struct {
+	limiter *logLimiter
+	output  io.Writer
+	scratch []byte // reused buffer for truncated lines
+}
+
+// Write implements io.Writer interface. Lines within limits are passed through
+// in contiguous runs so the common case adds no allocation.
+func (w *limitedLogWriter) Write(p []byte) (n int, err error) {
+	l := w.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	maxLen := l.limits.MaxLineLength
+	runStart := 0
+	for start := 0; start < len(p); {
+		end := bytes.IndexByte(p[start:], '\n')
+		var next int
+		if end < 0 {
+			end = len(p)
+			next = len(p)
+		} else {
+			end += start
+			next = end + 1
+		}
+		line := p[start:end]
+
+		tooLong := maxLen > 0 && len(line) > maxLen
+		size := len(line)
+		if tooLong {
+			size = maxLen + len(truncatedLineMarker)
+		}
+
+		if !l.allowLine(size) {
+			w.flushRun(p[runStart:start])
+			runStart = next
+			l.scheduleSummary(w.output)
+		} else if tooLong {
+			w.flushRun(p[runStart:start])
+			runStart = next
+			w.scratch = append(w.scratch[:0], line[:maxLen]...)
+			w.scratch = append(w.scratch, truncatedLineMarker...)
+			w.scratch = append(w.scratch, '\n')
+			_, _ = w.output.Write(w.scratch)
+		}
+		start = next
+	}
+	w.flushRun(p[runStart:])
+
+	return len(p), nil
+}
+
+// flushRun writes a run of accepted lines to the underlying writer
+func (w *limitedLogWriter) flushRun(run []byte) {
+	if len(run) > 0 {
+		_, _ = w.output.Write(run)
+	}
+}
@@ -0,0 +1,157 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// newTestLimitedWriter returns a limited writer over a buffer with a fake clock
+func newTestLimitedWriter(limits config.LogLimitsConfig, clk clock.Clock) (*limitedLogWriter, *bytes.Buffer) {
+	var out bytes.Buffer
+	limiter := newLogLimiter(limits)
+	limiter.clock = clk
+	return &limitedLogWriter{
+		limiter: limiter,
+		output:  &LogWriter{source: "runaway", stream: "stdout", output: &out},
+	}, &out
+}
+
+func TestLimitedLogWriterRateLimit(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	writer, out := newTestLimitedWriter(config.LogLimitsConfig{RateLimit: 100, Burst: 100}, fake)
+
+	line := []byte("spinning in a loop\n")
+	for i := 0; i < 10000; i++ {
+		_, _ = writer.Write(line)
+	}
+
+	if got := strings.Count(out.String(), "spinning in a loop"); got != 100 {
+		t.Errorf("expected burst of 100 lines, got %d", got)
+	}
+
+	writer.limiter.flushSummary()
+	if !strings.Contains(out.String(), "[runaway.stdout] [navigator] 9900 lines dropped (9900 over rate limit, 0 over daily byte cap)") {
+		t.Errorf("expected drop summary, got tail %q", out.String()[out.Len()-120:])
+	}
+
+	// Tokens refill as time passes
+	out.Reset()
+	fake.Advance(500 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		_, _ = writer.Write(line)
+	}
+	if got := strings.Count(out.String(), "spinning in a loop"); got != 50 {
+		t.Errorf("expected 50 lines after refill, got %d", got)
+	}
+
+	// Once a summary has been emitted the next flush is silent
+	writer.limiter.flushSummary()
+	out.Reset()
+	writer.limiter.flushSummary()
+	if out.Len() != 0 {
+		t.Errorf("expected no summary without drops, got %q", out.String())
+	}
+}
+
+func TestLimitedLogWriterTruncation(t *testing.T) {
+	writer, out := newTestLimitedWriter(config.LogLimitsConfig{MaxLineLength: 10}, clock.NewFake(time.Now()))
+
+	_, _ = writer.Write([]byte("short\n" + strings.Repeat("x", 50) + "\nafter\n"))
+
+	expected := "[runaway.stdout] short\n" +
+		"[runaway.stdout] xxxxxxxxxx" + truncatedLineMarker + "\n" +
+		"[runaway.stdout] after\n"
+	if out.String() != expected {
+		t.Errorf("got %q, want %q", out.String(), expected)
+	}
+}
+
+func TestLimitedLogWriterDailyByteCap(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 23, 59, 0, 0, time.UTC))
+	writer, out := newTestLimitedWriter(config.LogLimitsConfig{MaxBytesPerDay: 20}, fake)
+
+	for i := 0; i < 5; i++ {
+		_, _ = writer.Write([]byte("0123456789\n"))
+	}
+	if got := strings.Count(out.String(), "0123456789"); got != 2 {
+		t.Errorf("expected 2 lines within daily cap, got %d", got)
+	}
+
+	writer.limiter.flushSummary()
+	if !strings.Contains(out.String(), "3 lines dropped (0 over rate limit, 3 over daily byte cap)") {
+		t.Errorf("expected byte cap summary, got %q", out.String())
+	}
+
+	// Cap resets on the next day
+	out.Reset()
+	fake.Advance(2 * time.Minute)
+	_, _ = writer.Write([]byte("0123456789\n"))
+	if !strings.Contains(out.String(), "0123456789") {
+		t.Error("expected daily cap to reset at midnight")
+	}
+}
+
+func TestLimitedLogWriterNoAllocationUnderLimits(t *testing.T) {
+	limiter := newLogLimiter(config.LogLimitsConfig{MaxLineLength: 1000, MaxBytesPerDay: 1 << 40})
+	var sink countingWriter
+	writer := &limitedLogWriter{limiter: limiter, output: &sink}
+	payload := []byte("line one\nline two\nline three\n")
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		_, _ = writer.Write(payload)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations under limits, got %v", allocs)
+	}
+	if sink.writes == 0 {
+		t.Error("expected output to be written")
+	}
+}
+
+func TestCreateLogWriterAppliesLimitsToJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := config.LogConfig{
+		Format: "json",
+		File:   tempDir + "/{{app}}.log",
+		Limits: config.LogLimitsConfig{MaxLineLength: 5},
+	}
+
+	writer := CreateLogWriter("json-limited-app", "stdout", cfg)
+	if _, ok := writer.(*limitedLogWriter); !ok {
+		t.Fatalf("expected limitedLogWriter, got %T", writer)
+	}
+	_, _ = writer.Write([]byte("abcdefghij\n"))
+
+	var entry LogEntry
+	content, err := os.ReadFile(tempDir + "/json-limited-app.log")
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(content), &entry); err != nil {
+		t.Fatalf("invalid JSON log entry %q: %v", content, err)
+	}
+	if entry.Message != "abcde"+truncatedLineMarker {
+		t.Errorf("Message = %q, want truncated", entry.Message)
+	}
+
+	if _, ok := CreateLogWriter("json-unlimited-app", "stdout", config.LogConfig{}).(*limitedLogWriter); ok {
+		t.Error("expected no limiter when limits are not configured")
+	}
+}
+
+// countingWriter counts writes without retaining data
+type countingWriter struct {
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return len(p), nil
+}
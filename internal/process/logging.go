@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
 )
 
 // LogWriter wraps output streams to add source identification
@@ -21,10 +22,20 @@ type LogWriter struct {
 	source string // app name or process name
 	stream string // "stdout" or "stderr"
 	output io.Writer
+	group  *lineGrouper // Assembles entries before they're written (nil = write each line as it arrives)
+	pretty bool         // Prefix lines with the time and the source's color-coded name, as foreman does
+	color  bool         // Color pretty prefixes
+
+	requestID func() string // ID of the request the output likely belongs to (nil = don't mark it)
 }
 
 // Write implements io.Writer interface, prefixing each line with source metadata
 func (w *LogWriter) Write(p []byte) (n int, err error) {
+	if w.group != nil {
+		w.group.write(p)
+		return len(p), nil
+	}
+
 	// Split input into lines
 	lines := bytes.Split(p, []byte("\n"))
 	for i, line := range lines {
@@ -32,13 +43,25 @@ func (w *LogWriter) Write(p []byte) (n int, err error) {
 		if len(line) == 0 && i == len(lines)-1 {
 			continue
 		}
-		// Write prefixed line
-		prefix := fmt.Sprintf("[%s.%s] ", w.source, w.stream)
+		w.writeEntry(line)
+	}
+	return len(p), nil
+}
+
+// writeEntry writes an entry with each of its lines prefixed
+func (w *LogWriter) writeEntry(entry []byte) {
+	prefix := fmt.Sprintf("[%s.%s] ", w.source, w.stream)
+	if w.pretty {
+		prefix = logging.PrettyPrefix(w.source, w.color)
+	}
+	if id := currentRequestID(w.requestID); id != "" {
+		prefix += "[request_id=" + id + "] "
+	}
+	for _, line := range bytes.Split(entry, []byte("\n")) {
 		_, _ = w.output.Write([]byte(prefix))
 		_, _ = w.output.Write(line)
 		_, _ = w.output.Write([]byte("\n"))
 	}
-	return len(p), nil
 }
 
 // LogEntry represents a structured log entry
@@ -48,48 +71,108 @@ type LogEntry struct {
 	Stream    string `json:"stream"`
 	Message   string `json:"message"`
 	Tenant    string `json:"tenant,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // JSONLogWriter writes structured JSON log entries
 type JSONLogWriter struct {
-	source string
-	stream string
-	tenant string
-	output io.Writer
+	source      string
+	stream      string
+	tenant      string
+	output      io.Writer
+	passthrough bool          // Merge the fields of JSON object lines into the entry
+	group       *lineGrouper  // Assembles entries before they're written (nil = write each line as it arrives)
+	requestID   func() string // ID of the request the output likely belongs to (nil = don't mark it)
 }
 
 // Write implements io.Writer interface, outputting JSON log entries
 func (w *JSONLogWriter) Write(p []byte) (n int, err error) {
+	if w.group != nil {
+		w.group.write(p)
+		return len(p), nil
+	}
+
 	lines := bytes.Split(p, []byte("\n"))
 	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
+		w.writeEntry(line)
+	}
+	return len(p), nil
+}
+
+// writeEntry writes one log entry as a JSON line
+func (w *JSONLogWriter) writeEntry(line []byte) {
+	if len(line) == 0 {
+		return
+	}
 
-		// Check if the line is already valid JSON from the Rails app
-		if json.Valid(line) {
-			// Check if it contains Rails JSON log markers
-			if bytes.Contains(line, []byte(`"@timestamp"`)) && bytes.Contains(line, []byte(`"severity"`)) {
-				// This looks like Rails JSON output - pass it through directly
-				_, _ = w.output.Write(line)
-				_, _ = w.output.Write([]byte("\n"))
-				continue
-			}
+	// Check if the line is already valid JSON from the Rails app
+	if json.Valid(line) {
+		if w.passthrough && w.writeMerged(line) {
+			return
+		}
+		// Check if it contains Rails JSON log markers
+		if bytes.Contains(line, []byte(`"@timestamp"`)) && bytes.Contains(line, []byte(`"severity"`)) {
+			// This looks like Rails JSON output - pass it through directly
+			_, _ = w.output.Write(line)
+			_, _ = w.output.Write([]byte("\n"))
+			return
 		}
+	}
+
+	// Not JSON or not Rails JSON format - wrap it in our JSON structure
+	entry := LogEntry{
+		Timestamp: time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		Source:    w.source,
+		Stream:    w.stream,
+		Message:   string(line),
+		Tenant:    w.tenant,
+		RequestID: currentRequestID(w.requestID),
+	}
+	data, _ := json.Marshal(entry)
+	_, _ = w.output.Write(data)
+	_, _ = w.output.Write([]byte("\n"))
+}
 
-		// Not JSON or not Rails JSON format - wrap it in our JSON structure
-		entry := LogEntry{
-			Timestamp: time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
-			Source:    w.source,
-			Stream:    w.stream,
-			Message:   string(line),
-			Tenant:    w.tenant,
+// writeMerged writes a JSON object from the app as the entry itself, with
+// Navigator's source, stream and tenant fields added. The app's own
+// @timestamp is kept. It returns false if line isn't an object.
+func (w *JSONLogWriter) writeMerged(line []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil || fields == nil {
+		return false
+	}
+	set := func(key, value string) {
+		encoded, _ := json.Marshal(value)
+		fields[key] = encoded
+	}
+	if _, ok := fields["@timestamp"]; !ok {
+		set("@timestamp", time.Now().Format("2006-01-02T15:04:05.000Z07:00"))
+	}
+	set("source", w.source)
+	set("stream", w.stream)
+	if w.tenant != "" {
+		set("tenant", w.tenant)
+	}
+	if _, ok := fields["request_id"]; !ok {
+		if id := currentRequestID(w.requestID); id != "" {
+			set("request_id", id)
 		}
-		data, _ := json.Marshal(entry)
-		_, _ = w.output.Write(data)
-		_, _ = w.output.Write([]byte("\n"))
 	}
-	return len(p), nil
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return false
+	}
+	_, _ = w.output.Write(data)
+	_, _ = w.output.Write([]byte("\n"))
+	return true
+}
+
+// currentRequestID returns requestID's result, or "" when it's nil
+func currentRequestID(requestID func() string) string {
+	if requestID == nil {
+		return ""
+	}
+	return requestID()
 }
 
 // MultiLogWriter writes to multiple outputs simultaneously
@@ -97,6 +180,11 @@ type MultiLogWriter struct {
 	outputs []io.Writer
 }
 
+// NewMultiLogWriter creates a writer that copies each write to every output
+func NewMultiLogWriter(outputs ...io.Writer) *MultiLogWriter {
+	return &MultiLogWriter{outputs: outputs}
+}
+
 // Write implements io.Writer interface, writing to all configured outputs
 func (m *MultiLogWriter) Write(p []byte) (n int, err error) {
 	for _, output := range m.outputs {
@@ -105,6 +193,11 @@ func (m *MultiLogWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// Outputs returns the writers this MultiLogWriter writes to
+func (m *MultiLogWriter) Outputs() []io.Writer {
+	return m.outputs
+}
+
 // VectorWriter writes logs to Vector via Unix socket
 type VectorWriter struct {
 	socket string
@@ -157,10 +250,39 @@ func (v *VectorWriter) Close() error {
 	return nil
 }
 
+// OpenLogDestination returns the writer for a log destination: stdout (the
+// writer given), stderr, or a file opened for append
+func OpenLogDestination(destination string, stdout io.Writer) (io.Writer, error) {
+	switch destination {
+	case "", config.LogDestinationStdout:
+		return stdout, nil
+	case config.LogDestinationStderr:
+		return os.Stderr, nil
+	}
+	file, err := createFileWriter(destination, "navigator")
+	if err != nil {
+		return nil, err
+	}
+	file.fallback = stdout
+	return file, nil
+}
+
 // CreateAccessLogWriter creates a writer for Navigator's HTTP access logs
-// This sends logs to stdout and optionally to Vector for aggregation
+// This sends logs to each logging.access destination (stdout by default) and
+// optionally to Vector for aggregation
 func CreateAccessLogWriter(logConfig config.LogConfig, stdout io.Writer) io.Writer {
-	outputs := []io.Writer{stdout}
+	var outputs []io.Writer
+	for _, destination := range logConfig.Access.Destinations {
+		output, err := OpenLogDestination(destination, stdout)
+		if err != nil {
+			slog.Error("Failed to open access log destination", "destination", destination, "error", err)
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+	if len(logConfig.Access.Destinations) == 0 {
+		outputs = append(outputs, stdout)
+	}
 
 	// Add Vector output if configured
 	if logConfig.Vector.Enabled && logConfig.Vector.Socket != "" {
@@ -173,13 +295,16 @@ func CreateAccessLogWriter(logConfig config.LogConfig, stdout io.Writer) io.Writ
 	if len(outputs) == 1 {
 		return outputs[0]
 	}
-	return &MultiLogWriter{outputs: outputs}
+	return NewMultiLogWriter(outputs...)
 }
 
 // createFileWriter creates a file writer with the specified path
-func createFileWriter(path string, appName string) (io.Writer, error) {
-	// Replace {{app}} template with actual app name
-	logPath := strings.ReplaceAll(path, "{{app}}", appName)
+func createFileWriter(path string, appName string) (*logFile, error) {
+	// Replace the {{app}} and {{tenant}} templates with the app name, made
+	// safe for a file name so a tenant like "2025/raleigh" doesn't produce
+	// nested directories
+	name := config.TenantFileName(appName)
+	logPath := strings.NewReplacer("{{app}}", name, "{{tenant}}", name).Replace(path)
 
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(logPath)
@@ -188,7 +313,7 @@ func createFileWriter(path string, appName string) (io.Writer, error) {
 	}
 
 	// Open file for append (create if doesn't exist)
-	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := openLogFile(logPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file %s: %w", logPath, err)
 	}
@@ -196,58 +321,116 @@ func createFileWriter(path string, appName string) (io.Writer, error) {
 	return file, nil
 }
 
+// ResolveLogFormat returns the format of a log written to output: the
+// LOG_FORMAT environment variable when it names a format, else the
+// configured format, else pretty on a terminal and fallback elsewhere
+func ResolveLogFormat(configured, fallback string, output io.Writer) string {
+	switch format := strings.ToLower(os.Getenv("LOG_FORMAT")); format {
+	case config.LogFormatText, config.LogFormatJSON, config.LogFormatPretty:
+		return format
+	}
+	if configured != "" {
+		return configured
+	}
+	if logging.IsTerminal(output) {
+		return config.LogFormatPretty
+	}
+	return fallback
+}
+
+// newFormatWriter creates the text, pretty or JSON writer for one output,
+// grouping lines into entries when logging.multiline or json_passthrough is set
+func newFormatWriter(source, stream, tenant, format string, logConfig config.LogConfig, output io.Writer) io.Writer {
+	if format == config.LogFormatJSON {
+		w := &JSONLogWriter{
+			source:      source,
+			stream:      stream,
+			tenant:      tenant,
+			output:      output,
+			passthrough: logConfig.JSONPassthrough,
+		}
+		if groupingEnabled(logConfig) {
+			w.group = newLineGrouper(logConfig.Multiline, w.writeEntry)
+		}
+		return w
+	}
+
+	w := &LogWriter{
+		source: source,
+		stream: stream,
+		output: output,
+		pretty: format == config.LogFormatPretty,
+		color:  logging.ColorEnabled(output),
+	}
+	if groupingEnabled(logConfig) {
+		w.group = newLineGrouper(logConfig.Multiline, w.writeEntry)
+	}
+	return w
+}
+
 // CreateLogWriter creates appropriate log writer based on configuration
 func CreateLogWriter(source, stream string, logConfig config.LogConfig) io.Writer {
+	return createLogWriter(source, stream, logConfig.File, nil, logConfig)
+}
+
+// CreateTenantLogWriter creates the log writer for a tenant's output. It
+// writes to logging.tenant_file instead of logging.file when that is set,
+// and marks each entry with the ID lastRequestID returns when
+// logging.request_id_prefix is set.
+func CreateTenantLogWriter(tenant, stream string, logConfig config.LogConfig, lastRequestID func() string) io.Writer {
+	file := logConfig.File
+	if logConfig.TenantFile != "" {
+		file = logConfig.TenantFile
+	}
+	if !logConfig.RequestIDPrefix {
+		lastRequestID = nil
+	}
+	return createLogWriter(tenant, stream, file, lastRequestID, logConfig)
+}
+
+// createLogWriter creates the writer for a source's output to the console,
+// file, and Vector, marking entries with requestID's result unless it's nil
+func createLogWriter(source, stream, file string, requestID func() string, logConfig config.LogConfig) io.Writer {
 	var outputs []io.Writer
+	output := func(tenant, format string, w io.Writer) {
+		formatted := newFormatWriter(source, stream, tenant, format, logConfig, w)
+		switch formatted := formatted.(type) {
+		case *LogWriter:
+			formatted.requestID = requestID
+		case *JSONLogWriter:
+			formatted.requestID = requestID
+		}
+		outputs = append(outputs, formatted)
+	}
 
 	// Always include console output
-	if logConfig.Format == "json" {
-		outputs = append(outputs, &JSONLogWriter{
-			source: source,
-			stream: stream,
-			output: os.Stdout,
-		})
-	} else {
-		outputs = append(outputs, &LogWriter{
-			source: source,
-			stream: stream,
-			output: os.Stdout,
-		})
-	}
+	output("", ResolveLogFormat(logConfig.Format, config.LogFormatText, os.Stdout), os.Stdout)
 
 	// Add file output if configured
-	if logConfig.File != "" {
-		if fileWriter, err := createFileWriter(logConfig.File, source); err == nil {
-			if logConfig.Format == "json" {
-				outputs = append(outputs, &JSONLogWriter{
-					source: source,
-					stream: stream,
-					output: fileWriter,
-				})
-			} else {
-				outputs = append(outputs, &LogWriter{
-					source: source,
-					stream: stream,
-					output: fileWriter,
-				})
-			}
+	if file != "" {
+		if fileWriter, err := createFileWriter(file, source); err == nil {
+			output("", ResolveLogFormat(logConfig.Format, config.LogFormatText, fileWriter), fileWriter)
 		}
 	}
 
 	// Add Vector output if configured
 	if logConfig.Vector.Enabled && logConfig.Vector.Socket != "" {
-		vectorWriter := NewVectorWriter(logConfig.Vector.Socket)
-		outputs = append(outputs, &JSONLogWriter{
-			source: source,
-			stream: stream,
-			tenant: source, // Set tenant to source for tenant web apps
-			output: vectorWriter,
-		})
+		// Set tenant to source for tenant web apps
+		output(source, config.LogFormatJSON, NewVectorWriter(logConfig.Vector.Socket))
 	}
 
 	// Return appropriate writer
+	var writer io.Writer = NewMultiLogWriter(outputs...)
 	if len(outputs) == 1 {
-		return outputs[0]
+		writer = outputs[0]
 	}
-	return &MultiLogWriter{outputs: outputs}
+
+	// Protect the log pipeline from runaway output when limits are configured
+	if limitsEnabled(logConfig.Limits) {
+		writer = &limitedLogWriter{
+			limiter: getLogLimiter(source, logConfig.Limits),
+			output:  writer,
+		}
+	}
+	return writer
 }
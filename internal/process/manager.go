@@ -2,14 +2,18 @@ package process
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"reflect"
 	"sync"
 	"time"
 
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
 	"github.com/rubys/navigator/internal/utils"
 )
 
@@ -22,11 +26,34 @@ type ManagedProcess struct {
 	Env         map[string]string
 	AutoRestart bool
 	StartDelay  time.Duration
+	Group       string
 	Process     *exec.Cmd
 	Cancel      context.CancelFunc
 	Running     bool
 	Stopping    bool // Flag to prevent multiple stop attempts
 	mutex       sync.RWMutex
+
+	spec        config.ManagedProcessConfig // Configuration the process was started from
+	fingerprint string                      // Contents of spec.ConfigFiles when the process was created
+	removed     bool                        // Dropped from the configuration by a reload
+	done        chan struct{}               // Closed when the current run exits
+	restarts    []time.Time                 // Automatic restarts within config.CrashLoopWindow
+}
+
+// newManagedProcess creates a managed process from its configuration
+func newManagedProcess(procConfig config.ManagedProcessConfig) *ManagedProcess {
+	return &ManagedProcess{
+		Name:        procConfig.Name,
+		Command:     procConfig.Command,
+		Args:        procConfig.Args,
+		WorkingDir:  procConfig.WorkingDir,
+		Env:         procConfig.Env,
+		AutoRestart: procConfig.AutoRestart,
+		StartDelay:  procConfig.StartDelay.Std(),
+		Group:       procConfig.Group,
+		spec:        procConfig,
+		fingerprint: configFilesFingerprint(procConfig.ConfigFiles),
+	}
 }
 
 // Manager manages external processes
@@ -57,6 +84,8 @@ func buildManagedProcessConfigs(cfg *config.Config) []config.ManagedProcessConfi
 			Command:     "vector",
 			Args:        []string{"--config", cfg.Logging.Vector.Config},
 			AutoRestart: true,
+			Group:       "vector",
+			ConfigFiles: []string{cfg.Logging.Vector.Config},
 		}
 		processes = append(processes, vectorProc)
 	}
@@ -76,45 +105,44 @@ func (m *Manager) StartManagedProcesses() error {
 	allProcesses := buildManagedProcessConfigs(m.config)
 
 	for _, procConfig := range allProcesses {
-		// Parse start delay
-		startDelay := utils.ParseDurationWithContext(procConfig.StartDelay, 0, map[string]interface{}{
-			"process": procConfig.Name,
-		})
-
-		process := &ManagedProcess{
-			Name:        procConfig.Name,
-			Command:     procConfig.Command,
-			Args:        procConfig.Args,
-			WorkingDir:  procConfig.WorkingDir,
-			Env:         procConfig.Env,
-			AutoRestart: procConfig.AutoRestart,
-			StartDelay:  startDelay,
-		}
-
+		process := newManagedProcess(procConfig)
 		m.processes = append(m.processes, process)
+		m.launch(process)
+	}
 
-		// Start the process with delay if configured
-		if startDelay > 0 {
-			m.wg.Add(1)
-			go func(p *ManagedProcess) {
-				defer m.wg.Done()
-				time.Sleep(p.StartDelay)
-				if err := m.startProcess(p); err != nil {
-					slog.Error("Failed to start managed process after delay",
-						"process", p.Name,
-						"error", err)
-				}
-			}(process)
-		} else {
-			if err := m.startProcess(process); err != nil {
-				slog.Error("Failed to start managed process",
-					"process", process.Name,
-					"error", err)
-			}
+	return nil
+}
+
+// launch starts a process, after its start delay if one is configured
+func (m *Manager) launch(process *ManagedProcess) {
+	if process.StartDelay <= 0 {
+		if err := m.startProcess(process); err != nil {
+			slog.Error("Failed to start managed process",
+				"process", process.Name,
+				"error", err)
 		}
+		return
 	}
 
-	return nil
+	m.wg.Add(1)
+	go func(p *ManagedProcess) {
+		defer m.wg.Done()
+		time.Sleep(p.StartDelay)
+
+		// A reload may have stopped or replaced the process while waiting
+		p.mutex.RLock()
+		cancelled := p.Stopping || p.removed
+		p.mutex.RUnlock()
+		if cancelled {
+			return
+		}
+
+		if err := m.startProcess(p); err != nil {
+			slog.Error("Failed to start managed process after delay",
+				"process", p.Name,
+				"error", err)
+		}
+	}(process)
 }
 
 // startProcess starts a single managed process
@@ -145,16 +173,7 @@ func (m *Manager) startProcess(proc *ManagedProcess) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	proc.Cancel = cancel
 
-	cmd := exec.CommandContext(ctx, proc.Command, proc.Args...)
-	if proc.WorkingDir != "" {
-		cmd.Dir = proc.WorkingDir
-	}
-
-	// Set environment
-	cmd.Env = os.Environ()
-	for key, value := range proc.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
-	}
+	cmd := proc.commandSpec().command(ctx)
 
 	// Create log writers for the process output
 	stdout := CreateLogWriter(proc.Name, "stdout", m.config.Logging)
@@ -168,8 +187,12 @@ func (m *Manager) startProcess(proc *ManagedProcess) error {
 		return fmt.Errorf("failed to start process %s: %w", proc.Name, err)
 	}
 
+	done := make(chan struct{})
+	proc.done = done
+
 	proc.Running = true
 	proc.Stopping = false // Reset stopping flag since we're starting
+	BumpStateVersion()
 	slog.Info("Starting managed process", "name", proc.Name, "command", proc.Command, "args", proc.Args)
 
 	// Monitor process
@@ -183,6 +206,8 @@ func (m *Manager) startProcess(proc *ManagedProcess) error {
 		proc.Stopping = false // Reset stopping flag on exit
 		wasAutoRestart := proc.AutoRestart
 		proc.mutex.Unlock()
+		BumpStateVersion()
+		close(done)
 
 		if err != nil {
 			slog.Error("Process exited with error",
@@ -198,7 +223,21 @@ func (m *Manager) startProcess(proc *ManagedProcess) error {
 		proc.mutex.Unlock()
 
 		if wasAutoRestart && err != nil && !isBeingStopped {
+			if restarts, looping := proc.recordRestart(time.Now()); looping {
+				slog.Warn("Managed process is crash looping",
+					"name", proc.Name,
+					"restarts", restarts,
+					"window", config.CrashLoopWindow)
+				events.Emit(events.ProcessCrashLoop, map[string]interface{}{
+					"process":  proc.Name,
+					"restarts": restarts,
+					"window":   config.CrashLoopWindow.String(),
+					"error":    err.Error(),
+				})
+			}
+
 			slog.Info("Auto-restarting process in 5 seconds", "name", proc.Name)
+			defer utils.BeginActivity(config.IdleDeferManagedProcessRestarts)()
 			time.Sleep(5 * time.Second) // Longer delay to ensure port cleanup
 
 			// Double-check we're still supposed to restart
@@ -207,10 +246,15 @@ func (m *Manager) startProcess(proc *ManagedProcess) error {
 			proc.mutex.Unlock()
 
 			if stillShouldRestart {
-				if err := m.startProcess(proc); err != nil {
+				if restartErr := m.startProcess(proc); restartErr != nil {
 					slog.Error("Failed to restart managed process",
 						"name", proc.Name,
-						"error", err)
+						"error", restartErr)
+				} else {
+					events.Emit(events.ProcessRestarted, map[string]interface{}{
+						"process": proc.Name,
+						"error":   err.Error(),
+					})
 				}
 			}
 		}
@@ -219,6 +263,65 @@ func (m *Manager) startProcess(proc *ManagedProcess) error {
 	return nil
 }
 
+// recordRestart notes an automatic restart and reports whether the process has
+// now restarted config.CrashLoopRestarts times within config.CrashLoopWindow.
+// The count starts over once a crash loop is reported.
+func (p *ManagedProcess) recordRestart(now time.Time) (int, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	recent := p.restarts[:0]
+	for _, t := range p.restarts {
+		if now.Sub(t) < config.CrashLoopWindow {
+			recent = append(recent, t)
+		}
+	}
+	p.restarts = append(recent, now)
+
+	restarts := len(p.restarts)
+	if restarts < config.CrashLoopRestarts {
+		return restarts, false
+	}
+	p.restarts = nil
+	return restarts, true
+}
+
+// ManagedProcessStatus is a point-in-time view of a managed process
+type ManagedProcessStatus struct {
+	Name     string `json:"name"`
+	Group    string `json:"group,omitempty"`
+	Command  string `json:"command"`
+	PID      int    `json:"pid,omitempty"`
+	Running  bool   `json:"running"`
+	Stopping bool   `json:"stopping"`
+}
+
+// Status returns the state of every configured managed process in start order
+func (m *Manager) Status() []ManagedProcessStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status := make([]ManagedProcessStatus, 0, len(m.processes))
+	for _, proc := range m.processes {
+		proc.mutex.RLock()
+		if !proc.removed {
+			entry := ManagedProcessStatus{
+				Name:     proc.Name,
+				Group:    proc.Group,
+				Command:  proc.Command,
+				Running:  proc.Running,
+				Stopping: proc.Stopping,
+			}
+			if proc.Running && proc.Process != nil && proc.Process.Process != nil {
+				entry.PID = proc.Process.Process.Pid
+			}
+			status = append(status, entry)
+		}
+		proc.mutex.RUnlock()
+	}
+	return status
+}
+
 // StopManagedProcesses stops all managed processes
 func (m *Manager) StopManagedProcesses() {
 	m.StopManagedProcessesWithContext(context.Background())
@@ -271,37 +374,117 @@ func (m *Manager) StopManagedProcessesWithContext(ctx context.Context) {
 	}
 }
 
-// UpdateManagedProcesses updates managed processes after configuration reload
+// stopAndWait stops a process and waits for it to exit
+func (m *Manager) stopAndWait(proc *ManagedProcess) {
+	proc.mutex.Lock()
+	running := proc.Running
+	done := proc.done
+	proc.AutoRestart = false
+	proc.Stopping = true
+	if running && proc.Cancel != nil {
+		slog.Info("Stopping process", "name", proc.Name)
+		proc.Cancel()
+	}
+	proc.mutex.Unlock()
+
+	if !running || done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(config.ProcessStopTimeout):
+		slog.Warn("Timeout waiting for managed process to stop", "name", proc.Name)
+	}
+}
+
+// Reload actions for a managed process, decided by comparing configurations
+type reloadAction int
+
+const (
+	reloadNone    reloadAction = iota // Unchanged
+	reloadStart                       // Added by the new configuration
+	reloadRestart                     // Specification or config files changed
+	reloadSignal                      // Only config files changed and the group has a reload_signal
+)
+
+// UpdateManagedProcesses updates managed processes after configuration reload.
+// Each process's full specification is compared with the running one: changed
+// processes are restarted, processes whose config_files changed are sent their
+// group's reload_signal (or restarted if it has none), and unchanged processes
+// are left alone. Grouped processes restart as a unit: every running member is
+// stopped in reverse order, then all members are started in order.
 func (m *Manager) UpdateManagedProcesses(newConfig *config.Config) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	defer utils.BeginActivity(config.IdleDeferManagedProcessRestarts)()
 
-	// Create maps for comparison
+	// Map current processes by name (ignoring ones removed by earlier reloads)
 	oldProcs := make(map[string]*ManagedProcess)
 	for _, proc := range m.processes {
-		oldProcs[proc.Name] = proc
+		if !proc.removed {
+			oldProcs[proc.Name] = proc
+		}
 	}
 
 	// Get complete list including Vector if enabled
 	allNewProcesses := buildManagedProcessConfigs(newConfig)
 
-	newProcs := make(map[string]*config.ManagedProcessConfig)
-	for i := range allNewProcesses {
-		proc := &allNewProcesses[i]
-		newProcs[proc.Name] = proc
+	newNames := make(map[string]bool)
+	changed := make(map[string]bool)
+	actions := make([]reloadAction, len(allNewProcesses))
+	fingerprints := make([]string, len(allNewProcesses))
+	restartGroups := make(map[string]bool)
+	for i, procConfig := range allNewProcesses {
+		newNames[procConfig.Name] = true
+		fingerprints[i] = configFilesFingerprint(procConfig.ConfigFiles)
+
+		old, exists := oldProcs[procConfig.Name]
+		switch {
+		case !exists:
+			actions[i] = reloadStart
+		case !reflect.DeepEqual(old.spec, procConfig):
+			actions[i] = reloadRestart
+			if old.Group != "" {
+				restartGroups[old.Group] = true
+			}
+		case old.fingerprint != fingerprints[i]:
+			actions[i] = reloadRestart
+			if _, ok := groupReloadSignal(newConfig, procConfig.Group); ok {
+				actions[i] = reloadSignal
+			}
+		}
+		if actions[i] == reloadRestart {
+			changed[procConfig.Name] = true
+		}
+		if procConfig.Group != "" && (actions[i] == reloadStart || actions[i] == reloadRestart) {
+			restartGroups[procConfig.Group] = true
+		}
 	}
-
-	// Stop removed processes
 	for name, proc := range oldProcs {
-		if _, exists := newProcs[name]; !exists {
-			slog.Info("Stopping removed managed process", "name", name)
+		if !newNames[name] && proc.Group != "" {
+			restartGroups[proc.Group] = true
+		}
+	}
+
+	// Stop removed, changed, and restarting group members in reverse order
+	for i := len(m.processes) - 1; i >= 0; i-- {
+		proc := m.processes[i]
+		if proc.removed || oldProcs[proc.Name] != proc {
+			continue
+		}
+		switch {
+		case !newNames[proc.Name]:
+			slog.Info("Stopping removed managed process", "name", proc.Name)
+			m.stopAndWait(proc)
 			proc.mutex.Lock()
-			if proc.Running && proc.Cancel != nil {
-				proc.AutoRestart = false
-				proc.Stopping = true
-				proc.Cancel()
-			}
+			proc.removed = true
 			proc.mutex.Unlock()
+		case proc.Group != "" && restartGroups[proc.Group]:
+			slog.Info("Stopping managed process for group restart", "name", proc.Name, "group", proc.Group)
+			m.stopAndWait(proc)
+		case changed[proc.Name]:
+			slog.Info("Stopping changed managed process", "name", proc.Name)
+			m.stopAndWait(proc)
 		}
 	}
 
@@ -309,32 +492,104 @@ func (m *Manager) UpdateManagedProcesses(newConfig *config.Config) {
 	// This ensures startProcess() can access the new config (e.g., for Vector socket cleanup)
 	m.config = newConfig
 
-	// Start new processes
-	for name, procConfig := range newProcs {
-		if _, exists := oldProcs[name]; !exists {
-			slog.Info("Starting new managed process", "name", name)
-
-			var startDelay time.Duration
-			if procConfig.StartDelay != "" {
-				startDelay, _ = time.ParseDuration(procConfig.StartDelay)
-			}
-
-			process := &ManagedProcess{
-				Name:        procConfig.Name,
-				Command:     procConfig.Command,
-				Args:        procConfig.Args,
-				WorkingDir:  procConfig.WorkingDir,
-				Env:         procConfig.Env,
-				AutoRestart: procConfig.AutoRestart,
-				StartDelay:  startDelay,
-			}
+	// Start new, changed, and restarting group members in configuration order
+	for i, procConfig := range allNewProcesses {
+		old := oldProcs[procConfig.Name]
+		action := actions[i]
+		if procConfig.Group != "" && restartGroups[procConfig.Group] && action != reloadStart {
+			action = reloadRestart
+		}
 
+		switch action {
+		case reloadStart:
+			slog.Info("Starting new managed process", "name", procConfig.Name)
+			process := newManagedProcess(procConfig)
 			m.processes = append(m.processes, process)
-			if err := m.startProcess(process); err != nil {
-				slog.Error("Failed to start new managed process",
-					"process", process.Name,
+			m.launch(process)
+		case reloadRestart:
+			slog.Info("Restarting managed process", "name", procConfig.Name, "group", procConfig.Group)
+			process := newManagedProcess(procConfig)
+			m.replaceProcess(old, process)
+			m.launch(process)
+		case reloadSignal:
+			signalName, _ := groupReloadSignal(newConfig, procConfig.Group)
+			if err := m.signalProcess(old, signalName); err != nil {
+				slog.Warn("Failed to signal managed process, restarting instead",
+					"name", procConfig.Name,
+					"signal", signalName,
 					"error", err)
+				m.stopAndWait(old)
+				process := newManagedProcess(procConfig)
+				m.replaceProcess(old, process)
+				m.launch(process)
+				continue
 			}
+			old.mutex.Lock()
+			old.fingerprint = fingerprints[i]
+			old.mutex.Unlock()
+		}
+	}
+}
+
+// replaceProcess swaps old for replacement in the process list
+func (m *Manager) replaceProcess(old, replacement *ManagedProcess) {
+	for i, proc := range m.processes {
+		if proc == old {
+			m.processes[i] = replacement
+			return
+		}
+	}
+	m.processes = append(m.processes, replacement)
+}
+
+// signalProcess sends a named reload signal to a running process
+func (m *Manager) signalProcess(proc *ManagedProcess, signalName string) error {
+	sig, ok := reloadSignalFor(signalName)
+	if !ok {
+		return fmt.Errorf("signal %s is not supported on this platform", signalName)
+	}
+
+	proc.mutex.RLock()
+	defer proc.mutex.RUnlock()
+	if !proc.Running || proc.Process == nil || proc.Process.Process == nil {
+		return fmt.Errorf("process %s is not running", proc.Name)
+	}
+	if err := proc.Process.Process.Signal(sig); err != nil {
+		return err
+	}
+	slog.Info("Signaled managed process after config file change", "name", proc.Name, "signal", signalName)
+	return nil
+}
+
+// groupReloadSignal returns the reload_signal configured for group, if any
+func groupReloadSignal(cfg *config.Config, group string) (string, bool) {
+	if group == "" {
+		return "", false
+	}
+	for _, g := range cfg.ProcessGroups {
+		if g.Name == group && g.ReloadSignal != "" {
+			return g.ReloadSignal, true
+		}
+	}
+	return "", false
+}
+
+// configFilesFingerprint summarizes the contents of a process's config files
+// so a reload can tell whether any of them changed
+func configFilesFingerprint(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	hash := sha256.New()
+	for _, file := range files {
+		hash.Write([]byte(file))
+		hash.Write([]byte{0})
+		if content, err := os.ReadFile(file); err == nil {
+			hash.Write(content)
+		} else {
+			hash.Write([]byte("missing"))
 		}
+		hash.Write([]byte{0})
 	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
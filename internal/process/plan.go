@@ -0,0 +1,165 @@
+package process
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// Plan lists every command a configuration would have Navigator execute,
+// built without running any of them
+type Plan struct {
+	ManagedProcesses []PlannedProcess `json:"managed_processes"`
+	Hooks            []PlannedHook    `json:"hooks"`
+	Tenants          []PlannedTenant  `json:"tenants"`
+}
+
+// secretEnvName matches the names of environment variables whose values
+// are likely credentials, such as SECRET_KEY_BASE or STRIPE_API_KEY
+var secretEnvName = regexp.MustCompile(`(?i)secret|token|passw|credential|private|key|auth`)
+
+// redactedEnvValue replaces the value of a secret-looking variable in a plan
+const redactedEnvValue = "[REDACTED]"
+
+// PlannedProcess is a managed process as it would be started
+type PlannedProcess struct {
+	CommandSpec
+	AutoRestart bool   `json:"auto_restart,omitempty"`
+	StartDelay  string `json:"start_delay,omitempty"`
+	Group       string `json:"group,omitempty"`
+}
+
+// PlannedHook is a hook as it would be run
+type PlannedHook struct {
+	Type string `json:"type"` // e.g. "server.start" or "tenant.stop.default"
+	CommandSpec
+	Timeout         string `json:"timeout,omitempty"`
+	ContinueOnError bool   `json:"continue_on_error,omitempty"`
+	ReloadConfig    string `json:"reload_config,omitempty"`
+}
+
+// PlannedTenant is a tenant's web app command and its lifecycle hooks
+type PlannedTenant struct {
+	Name    string        `json:"name"`
+	Path    string        `json:"path"`
+	Port    int           `json:"port"`
+	Pinned  bool          `json:"pinned,omitempty"` // The tenant's port setting
+	Command CommandSpec   `json:"command"`
+	Hooks   []PlannedHook `json:"hooks,omitempty"`
+}
+
+// NewPlan builds the plan for cfg. Tenants without a pinned port are shown
+// on consecutive ports from the pool's start port, passing over pinned ones;
+// at run time each gets the first free port, or with port_state its last.
+func NewPlan(cfg *config.Config) *Plan {
+	plan := &Plan{
+		ManagedProcesses: []PlannedProcess{},
+		Hooks:            []PlannedHook{},
+		Tenants:          []PlannedTenant{},
+	}
+
+	for _, procConfig := range buildManagedProcessConfigs(cfg) {
+		proc := newManagedProcess(procConfig)
+		planned := PlannedProcess{CommandSpec: redactSpec(proc.commandSpec()), AutoRestart: proc.AutoRestart, Group: proc.Group}
+		if proc.StartDelay > 0 {
+			planned.StartDelay = proc.StartDelay.String()
+		}
+		plan.ManagedProcesses = append(plan.ManagedProcesses, planned)
+	}
+
+	for _, server := range []struct {
+		hookType string
+		hooks    []config.HookConfig
+	}{
+		{"start", cfg.Hooks.Start},
+		{"ready", cfg.Hooks.Ready},
+		{"idle", cfg.Hooks.Idle},
+		{"resume", cfg.Hooks.Resume},
+	} {
+		plan.Hooks = append(plan.Hooks, planHooks(server.hooks, nil, "server."+server.hookType)...)
+	}
+
+	starter := NewProcessStarter(cfg)
+	next := cfg.Applications.Pools.StartPort
+	if next == 0 {
+		next = config.DefaultStartPort
+	}
+	pinned := cfg.PinnedPorts()
+	for i := range cfg.Applications.Tenants {
+		tenant := &cfg.Applications.Tenants[i]
+		port := tenant.Port
+		if port == 0 {
+			for pinned[next] != "" {
+				next++
+			}
+			port = next
+			next++
+		}
+		planned := PlannedTenant{
+			Name:    tenant.Name,
+			Path:    tenant.Path,
+			Port:    port,
+			Pinned:  tenant.Port != 0,
+			Command: redactSpec(starter.WebAppCommand(tenant, port)),
+		}
+		// Same order as ExecuteTenantHooks: defaults first, then the tenant's own
+		for _, hookType := range []string{"start", "stop"} {
+			defaults, specific := cfg.Applications.Hooks.Start, tenant.Hooks.Start
+			if hookType == "stop" {
+				defaults, specific = cfg.Applications.Hooks.Stop, tenant.Hooks.Stop
+			}
+			planned.Hooks = append(planned.Hooks, planHooks(defaults, tenant.Env, fmt.Sprintf("tenant.%s.default", hookType))...)
+			planned.Hooks = append(planned.Hooks, planHooks(specific, tenant.Env, fmt.Sprintf("tenant.%s.%s", hookType, tenant.Name))...)
+		}
+		plan.Tenants = append(plan.Tenants, planned)
+	}
+
+	return plan
+}
+
+// planHooks describes hooks the way ExecuteHooks would run them
+func planHooks(hooks []config.HookConfig, env map[string]string, hookType string) []PlannedHook {
+	var planned []PlannedHook
+	for _, hook := range hooks {
+		if hook.Command == "" {
+			continue
+		}
+		entry := PlannedHook{
+			Type:            hookType,
+			CommandSpec:     redactSpec(HookCommand(hook, env)),
+			ContinueOnError: hook.ContinueOnError,
+			ReloadConfig:    hook.ReloadConfig,
+		}
+		if timeout := hook.Timeout.Std(); timeout > 0 {
+			entry.Timeout = timeout.String()
+		}
+		planned = append(planned, entry)
+	}
+	return planned
+}
+
+// redactSpec hides the values of spec's secret-looking variables, and the
+// passwords of URLs in the others, so a plan can be shared without leaking
+// credentials
+func redactSpec(spec CommandSpec) CommandSpec {
+	if len(spec.Env) == 0 {
+		return spec
+	}
+	env := make(map[string]string, len(spec.Env))
+	for name, value := range spec.Env {
+		switch {
+		case secretEnvName.MatchString(name):
+			value = redactedEnvValue
+		case strings.Contains(value, "://"):
+			if u, err := url.Parse(value); err == nil {
+				value = u.Redacted()
+			}
+		}
+		env[name] = value
+	}
+	spec.Env = env
+	return spec
+}
@@ -11,6 +11,7 @@ type PortAllocator struct {
 	minPort        int
 	maxPort        int
 	allocatedPorts map[int]bool
+	pinned         map[int]string // Port -> tenant pinning it; never allocated to others
 	mutex          sync.Mutex
 }
 
@@ -23,29 +24,79 @@ func NewPortAllocator(minPort, maxPort int) *PortAllocator {
 	}
 }
 
+// SetPinned reserves ports that tenants have pinned, mapped to the tenant
+// pinning each, so they are only handed out by AllocatePinnedPort
+func (pa *PortAllocator) SetPinned(pinned map[int]string) {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+	pa.pinned = pinned
+}
+
+// PinnedBy returns the tenant that pinned port, if any
+func (pa *PortAllocator) PinnedBy(port int) (string, bool) {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+	tenant, ok := pa.pinned[port]
+	return tenant, ok
+}
+
 // AllocatePort finds and reserves an available port in the configured range
 func (pa *PortAllocator) AllocatePort() (int, error) {
+	return pa.AllocatePreferredPort(0)
+}
+
+// AllocatePreferredPort reserves preferred if it's in range, not pinned,
+// and available, and otherwise any available port
+func (pa *PortAllocator) AllocatePreferredPort(preferred int) (int, error) {
 	pa.mutex.Lock()
 	defer pa.mutex.Unlock()
 
+	if preferred >= pa.minPort && preferred <= pa.maxPort && pa.reserve(preferred) {
+		return preferred, nil
+	}
 	for port := pa.minPort; port <= pa.maxPort; port++ {
-		// Skip already allocated ports
-		if pa.allocatedPorts[port] {
-			continue
-		}
-
-		// Try to listen on the port to verify it's available
-		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-		if err == nil {
-			// Port is available - close the test listener and mark as allocated
-			_ = listener.Close()
-			pa.allocatedPorts[port] = true
+		if pa.reserve(port) {
 			return port, nil
 		}
 	}
 	return 0, fmt.Errorf("no available ports in range %d-%d", pa.minPort, pa.maxPort)
 }
 
+// AllocatePinnedPort reserves a tenant's pinned port, failing if it's taken
+func (pa *PortAllocator) AllocatePinnedPort(port int) (int, error) {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	if pa.allocatedPorts[port] {
+		return 0, fmt.Errorf("pinned port %d is already allocated", port)
+	}
+	if !pa.bindable(port) {
+		return 0, fmt.Errorf("pinned port %d is in use", port)
+	}
+	pa.allocatedPorts[port] = true
+	return port, nil
+}
+
+// reserve marks port allocated if it's neither allocated, pinned, nor in
+// use; pa.mutex must be held
+func (pa *PortAllocator) reserve(port int) bool {
+	if pa.allocatedPorts[port] || pa.pinned[port] != "" || !pa.bindable(port) {
+		return false
+	}
+	pa.allocatedPorts[port] = true
+	return true
+}
+
+// bindable tries to listen on port to verify it's available
+func (pa *PortAllocator) bindable(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = listener.Close()
+	return true
+}
+
 // ReleasePort releases a previously allocated port back to the pool
 func (pa *PortAllocator) ReleasePort(port int) {
 	pa.mutex.Lock()
@@ -0,0 +1,124 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// Port assignments reported in AppStatus
+const (
+	PortPinned  = "pinned"  // The tenant's port setting
+	PortDynamic = "dynamic" // Allocated from the pool, preferring the last port with port_state
+)
+
+// portState remembers the port each tenant without a pinned port last ran
+// on, in applications.pools.port_state.file. The zero value remembers
+// nothing.
+type portState struct {
+	mu    sync.Mutex
+	file  string // Empty when port_state is disabled
+	ports map[string]int
+}
+
+// loadPortState reads the saved ports for cfg. A missing or unreadable file
+// starts over empty.
+func loadPortState(cfg config.PortStateConfig) *portState {
+	s := &portState{ports: make(map[string]int)}
+	if !cfg.Enabled {
+		return s
+	}
+	s.file = cfg.File
+	data, err := os.ReadFile(s.file)
+	if err == nil {
+		err = json.Unmarshal(data, &s.ports)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Ignoring tenant port state", "file", s.file, "error", err)
+		s.ports = make(map[string]int)
+	}
+	return s
+}
+
+// preferred returns the port tenant last ran on, or 0
+func (s *portState) preferred(tenant string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ports[tenant]
+}
+
+// record saves port as the one tenant runs on
+func (s *portState) record(tenant string, port int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == "" || s.ports[tenant] == port {
+		return
+	}
+	s.ports[tenant] = port
+	if err := s.save(); err != nil {
+		slog.Warn("Cannot save tenant port state", "file", s.file, "error", err)
+	}
+}
+
+// save writes the ports to the file, replacing it at once so a crash
+// mid-write never leaves a partial one; s.mu must be held
+func (s *portState) save() error {
+	data, err := json.MarshalIndent(s.ports, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.file), ".navigator-ports-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, s.file)
+	}
+	return err
+}
+
+// allocatePort reserves the port tenant's app starts on: its pinned port,
+// or else the port it last ran on when that's still free. A saved port that
+// another tenant has since pinned goes to that tenant.
+func (m *AppManager) allocatePort(tenant *config.Tenant) (int, error) {
+	if tenant.Port != 0 {
+		return m.portAllocator.AllocatePinnedPort(tenant.Port)
+	}
+
+	preferred := m.portState.preferred(tenant.Name)
+	if owner, pinned := m.portAllocator.PinnedBy(preferred); pinned {
+		slog.Warn("Saved tenant port is pinned by another tenant, allocating another",
+			"tenant", tenant.Name, "port", preferred, "pinnedBy", owner)
+	}
+	port, err := m.portAllocator.AllocatePreferredPort(preferred)
+	if err != nil {
+		return 0, fmt.Errorf("no available ports: %w", err)
+	}
+	m.portState.record(tenant.Name, port)
+	return port, nil
+}
+
+// newTenantPortAllocator returns an allocator for cfg's pool range that
+// keeps its tenants' pinned ports for them
+func newTenantPortAllocator(cfg *config.Config) *PortAllocator {
+	startPort := cfg.Applications.Pools.StartPort
+	if startPort == 0 {
+		startPort = config.DefaultStartPort
+	}
+	allocator := NewPortAllocator(startPort, startPort+config.MaxPortRange)
+	allocator.SetPinned(cfg.PinnedPorts())
+	return allocator
+}
@@ -0,0 +1,152 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// newPortTestManager returns a manager whose pool starts at 4930, with
+// pinned 4935 and unpinned b and c remembering their ports in file
+func newPortTestManager(t *testing.T, file string) *AppManager {
+	t.Helper()
+	cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+applications:
+  pools:
+    start_port: 4930
+    port_state:
+      enabled: true
+      file: %s
+  tenants:
+    - name: pinned
+      path: /pinned/
+      port: 4935
+    - name: b
+      path: /b/
+    - name: c
+      path: /c/
+`, file)))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	return NewAppManager(cfg)
+}
+
+// allocateTenantPort allocates the port of the named tenant
+func allocateTenantPort(t *testing.T, m *AppManager, name string) int {
+	t.Helper()
+	for i := range m.config.Applications.Tenants {
+		if tenant := &m.config.Applications.Tenants[i]; tenant.Name == name {
+			port, err := m.allocatePort(tenant)
+			if err != nil {
+				t.Fatalf("allocatePort(%s) error = %v", name, err)
+			}
+			return port
+		}
+	}
+	t.Fatalf("no tenant %s", name)
+	return 0
+}
+
+func readPortState(t *testing.T, file string) map[string]int {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ports map[string]int
+	if err := json.Unmarshal(data, &ports); err != nil {
+		t.Fatal(err)
+	}
+	return ports
+}
+
+func TestPinnedTenantPort(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "navigator-ports.json")
+	m := newPortTestManager(t, file)
+
+	if port := allocateTenantPort(t, m, "pinned"); port != 4935 {
+		t.Errorf("pinned tenant got port %d, want 4935", port)
+	}
+	if _, err := m.allocatePort(&m.config.Applications.Tenants[0]); err == nil {
+		t.Error("pinned port allocated twice")
+	}
+	m.portAllocator.ReleasePort(4935)
+
+	// Dynamic allocation passes over the pin even while its tenant isn't running
+	for i := 0; i < 6; i++ {
+		port, err := m.portAllocator.AllocatePort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port == 4935 {
+			t.Fatal("dynamic allocation handed out a pinned port")
+		}
+	}
+
+	pinned := &m.config.Applications.Tenants[0]
+	dynamic := &m.config.Applications.Tenants[1]
+	m.apps["pinned"] = newWebApp(pinned, 4935)
+	m.apps["b"] = newWebApp(dynamic, 4930)
+	status := m.Status()
+	if status[0].Tenant != "b" || status[0].PortAssignment != PortDynamic || status[1].PortAssignment != PortPinned {
+		t.Errorf("Status() = %+v, want b dynamic and pinned pinned", status)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("pinned ports were saved: %v", err)
+	}
+}
+
+func TestSavedTenantPortReusedAfterRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "navigator-ports.json")
+	if err := os.WriteFile(file, []byte(`{"b": 4933}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newPortTestManager(t, file)
+	if port := allocateTenantPort(t, m, "b"); port != 4933 {
+		t.Errorf("b got port %d, want its saved 4933", port)
+	}
+	if port := allocateTenantPort(t, m, "c"); port != 4930 {
+		t.Errorf("c got port %d, want the first free 4930", port)
+	}
+
+	// A restart allocates in the other order, and each gets its port back
+	restarted := newPortTestManager(t, file)
+	if port := allocateTenantPort(t, restarted, "c"); port != 4930 {
+		t.Errorf("c got port %d after restart, want 4930", port)
+	}
+	if port := allocateTenantPort(t, restarted, "b"); port != 4933 {
+		t.Errorf("b got port %d after restart, want 4933", port)
+	}
+	if ports := readPortState(t, file); ports["b"] != 4933 || ports["c"] != 4930 || len(ports) != 2 {
+		t.Errorf("saved ports = %v", ports)
+	}
+}
+
+func TestSavedTenantPortYieldsToPin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "navigator-ports.json")
+	if err := os.WriteFile(file, []byte(`{"b": 4935}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logs := captureHookLogs(t)
+
+	m := newPortTestManager(t, file)
+	if port := allocateTenantPort(t, m, "b"); port != 4930 {
+		t.Errorf("b got port %d, want 4930 instead of the pinned 4935", port)
+	}
+	if port := allocateTenantPort(t, m, "pinned"); port != 4935 {
+		t.Errorf("pinned tenant got port %d, want 4935", port)
+	}
+	record := logs.lastRecord("Saved tenant port is pinned by another tenant, allocating another")
+	if record == nil || record["tenant"] != "b" || record["pinnedBy"] != "pinned" {
+		t.Errorf("warning = %v", record)
+	}
+	if ports := readPortState(t, file); ports["b"] != 4930 {
+		t.Errorf("saved ports = %v, want b moved to 4930", ports)
+	}
+}
@@ -0,0 +1,82 @@
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// ioprio_set(2) arguments
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+var ioprioClasses = map[string]int{
+	config.IONiceRealtime:   1,
+	config.IONiceBestEffort: 2,
+	config.IONiceIdle:       3,
+}
+
+// applyPriority sets the nice value and I/O priority of every thread of
+// pid, and its OOM score adjustment. Threads and children created later
+// inherit them.
+func applyPriority(pid int, priority *config.PriorityConfig) error {
+	var errs []error
+	threads := processThreads(pid)
+
+	if priority.Nice != nil {
+		for _, tid := range threads {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, *priority.Nice); err != nil {
+				errs = append(errs, fmt.Errorf("nice %d: %w", *priority.Nice, err))
+				break
+			}
+		}
+	}
+
+	if priority.IONiceClass != "" {
+		ioprio := ioprioClasses[priority.IONiceClass] << ioprioClassShift
+		if priority.IONiceLevel != nil {
+			ioprio |= *priority.IONiceLevel
+		}
+		for _, tid := range threads {
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio)); errno != 0 {
+				errs = append(errs, fmt.Errorf("ionice %s: %w", priority.IONiceClass, errno))
+				break
+			}
+		}
+	}
+
+	if priority.OOMScoreAdj != nil {
+		path := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+		if err := os.WriteFile(path, []byte(strconv.Itoa(*priority.OOMScoreAdj)), 0); err != nil {
+			errs = append(errs, fmt.Errorf("oom_score_adj %d: %w", *priority.OOMScoreAdj, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// processThreads returns the thread IDs of pid, or just pid if they can't
+// be listed
+func processThreads(pid int) []int {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return []int{pid}
+	}
+	threads := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			threads = append(threads, tid)
+		}
+	}
+	if len(threads) == 0 {
+		return []int{pid}
+	}
+	return threads
+}
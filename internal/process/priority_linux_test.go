@@ -0,0 +1,84 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// readProcNice returns the nice value of pid from /proc/<pid>/stat
+func readProcNice(t *testing.T, pid int) int {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fields after the parenthesized command name start with the state (field 3)
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	nice, err := strconv.Atoi(fields[19-3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nice
+}
+
+func TestTenantPriorityApplied(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	nice, level, oom := 5, 6, 500
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 4820
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name:    "boston",
+		Root:    t.TempDir(),
+		Runtime: os.Args[0],
+		Server:  "-test.run=^TestStandbyTenantProcess$",
+		Args:    []string{"-test.count=1"},
+		Env:     map[string]string{"NAVIGATOR_STANDBY_TENANT": "1"},
+		Priority: &config.PriorityConfig{
+			Nice:        &nice,
+			IONiceClass: config.IONiceBestEffort,
+			IONiceLevel: &level,
+			OOMScoreAdj: &oom,
+		},
+	}}
+	m := NewAppManager(cfg)
+	t.Cleanup(m.Cleanup)
+
+	app, err := m.GetOrStartApp("boston")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	pid := app.Process.Process.Pid
+
+	if got := readProcNice(t, pid); got != nice {
+		t.Errorf("nice = %d, want %d", got, nice)
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != strconv.Itoa(oom) {
+		t.Errorf("oom_score_adj = %s, want %d", got, oom)
+	}
+	ioprio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		t.Fatalf("ioprio_get: %v", errno)
+	}
+	if want := uintptr(ioprioClasses[config.IONiceBestEffort]<<ioprioClassShift | level); ioprio != want {
+		t.Errorf("ioprio = %#x, want %#x", ioprio, want)
+	}
+
+	status := m.Status()
+	if len(status) != 1 || status[0].Priority == nil || *status[0].Priority.Nice != nice {
+		t.Errorf("Status = %+v, want the applied priority", status)
+	}
+}
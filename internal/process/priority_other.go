@@ -0,0 +1,15 @@
+//go:build !linux
+
+package process
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// applyPriority is not available outside Linux
+func applyPriority(pid int, priority *config.PriorityConfig) error {
+	return fmt.Errorf("process priority is not supported on %s", runtime.GOOS)
+}
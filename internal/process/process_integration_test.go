@@ -21,7 +21,7 @@ func TestManagedProcessAutoRestart(t *testing.T) {
 				Command:     "false", // Command that exits with error
 				Args:        []string{},
 				AutoRestart: true,
-				StartDelay:  "0s",
+				StartDelay:  config.Duration(0 * time.Second),
 			},
 		},
 	}
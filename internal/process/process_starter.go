@@ -2,19 +2,26 @@ package process
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
 	"github.com/rubys/navigator/internal/logging"
 )
 
+// ErrTenantRootUnavailable is returned when a tenant isn't started because
+// its root directory is missing or unreadable
+var ErrTenantRootUnavailable = errors.New("tenant root unavailable")
+
 // ProcessStarter handles starting web application processes
 type ProcessStarter struct {
 	config *config.Config
@@ -29,35 +36,102 @@ func NewProcessStarter(cfg *config.Config) *ProcessStarter {
 
 // StartWebApp starts a web application process
 func (ps *ProcessStarter) StartWebApp(app *WebApp, tenant *config.Tenant) error {
-	// Clean up any existing PID file first
-	if pidfile, ok := tenant.Env["PIDFILE"]; ok {
-		_ = cleanupPidFile(pidfile)
+	// Refuse to start while another machine or process runs this tenant. The
+	// guard is held until the app exits.
+	guard := newStartGuard(tenant)
+	if err := acquireStartGuard(guard, tenant.Name); err != nil {
+		return err
+	}
+	releaseGuard := func() {
+		if guard != nil {
+			guard.release()
+		}
 	}
 
-	// Determine runtime, server, and args
-	runtime := ps.getRuntime(tenant)
-	server := ps.getServer(tenant)
-	args := ps.getArgs(tenant, app.Port)
+	// Determine runtime, server, args, environment, and working directory
+	spec := ps.WebAppCommand(tenant, app.Port)
 
-	// Create command with context
+	// Create the app's context; cancelling it stops the app
 	ctx, cancel := context.WithCancel(context.Background())
 	app.cancel = cancel
 
-	cmd := exec.CommandContext(ctx, runtime, append([]string{server}, args...)...)
+	// The internal echo runtime is served in-process; anything else is exec'd
+	if spec.Command == config.RuntimeInternalEcho {
+		if err := startEchoApp(ctx, app, spec, releaseGuard); err != nil {
+			cancel()
+			return err
+		}
+	} else if err := ps.startProcess(ctx, app, tenant, spec, releaseGuard); err != nil {
+		cancel()
+		return err
+	}
+	tenantName := tenant.Name
+
+	if guard != nil && guard.renewal() > 0 && app.onGuardLost != nil {
+		go renewStartGuard(guard, app, tenantName, app.onGuardLost)
+	}
+
+	// Execute tenant start hooks; a standby's primary, or the instance a
+	// replacement is recycling, has already run them
+	if !app.standby && !app.recycled {
+		if err := ExecuteTenantHooks(ctx, ps.config.Applications.Hooks.Start, tenant.Hooks.Start,
+			tenant.Env, tenantName, "start"); err != nil {
+			slog.Error("Failed to execute tenant start hooks", "tenant", tenantName, "error", err)
+		}
+	}
+
+	// Wait for app to be ready
+	if err := ps.waitForReady(app, tenantName, spec.Command); err != nil {
+		return err
+	}
+
+	if !app.standby && !app.recycled {
+		events.Emit(events.TenantStarted, map[string]interface{}{
+			"tenant": tenantName,
+			"port":   app.Port,
+		})
+	}
+	return nil
+}
+
+// startProcess execs the command that runs a tenant's web app. The app
+// exits when ctx is cancelled; releaseGuard is called once it has.
+func (ps *ProcessStarter) startProcess(ctx context.Context, app *WebApp, tenant *config.Tenant, spec CommandSpec, releaseGuard func()) error {
+	runtime, server, args := spec.Command, spec.Args[0], spec.Args[1:]
+
+	// A root that's gone, as when its volume drops, would fail the exec with
+	// a less helpful error
+	if err := checkTenantRoot(spec.Dir); err != nil {
+		releaseGuard()
+		return err
+	}
+
+	// Clean up any existing PID file first
+	if pidfile, ok := spec.Env["PIDFILE"]; ok {
+		_ = cleanupPidFile(pidfile)
+	}
+
+	cmd := spec.command(ctx)
 
-	// Setup command environment and working directory
-	ps.setupCommand(cmd, tenant, app.Port)
+	// Stop with the framework's graceful signal; WaitDelay bounds the wait
+	if sig, ok := stopSignalFor(ps.getStopSignal(tenant)); ok {
+		cmd.Cancel = func() error { return cmd.Process.Signal(sig) }
+	}
+
+	// Children that inherit stdout/stderr would otherwise keep the log pumps
+	// (and their pipe FDs) alive after the app itself has exited
+	cmd.WaitDelay = config.AppOutputWaitDelay
 
 	// Setup memory limits and user credentials (Linux only)
 	if err := ps.setupCgroupAndCredentials(cmd, app, tenant); err != nil {
-		cancel()
+		releaseGuard()
 		return fmt.Errorf("failed to setup cgroup/credentials: %w", err)
 	}
 
 	// Create log writers for the app output
 	tenantName := tenant.Name
-	stdout := CreateLogWriter(tenantName, "stdout", ps.config.Logging)
-	stderr := CreateLogWriter(tenantName, "stderr", ps.config.Logging)
+	stdout := CreateTenantLogWriter(tenantName, "stdout", ps.config.Logging, app.LastRequestID)
+	stderr := CreateTenantLogWriter(tenantName, "stderr", ps.config.Logging, app.LastRequestID)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
@@ -65,9 +139,24 @@ func (ps *ProcessStarter) StartWebApp(app *WebApp, tenant *config.Tenant) error
 
 	logging.LogWebAppStart(tenantName, app.Port, runtime, server, args)
 
+	app.exited = make(chan struct{})
 	if err := cmd.Start(); err != nil {
+		releaseGuard()
 		return fmt.Errorf("failed to start web app: %w", err)
 	}
+	app.mutex.Lock()
+	app.spawned = time.Now()
+	app.mutex.Unlock()
+
+	// An exit that was not requested through cancel is a crash
+	go func() {
+		err := cmd.Wait()
+		releaseGuard()
+		close(app.exited)
+		if ctx.Err() == nil && app.recordCrash(fmt.Sprint(err)) && app.onCrash != nil {
+			app.onCrash()
+		}
+	}()
 
 	// Add process to cgroup after start (Linux only)
 	if app.CgroupPath != "" {
@@ -79,14 +168,20 @@ func (ps *ProcessStarter) StartWebApp(app *WebApp, tenant *config.Tenant) error
 		}
 	}
 
-	// Execute tenant start hooks
-	if err := ExecuteTenantHooks(ps.config.Applications.Hooks.Start, tenant.Hooks.Start,
-		tenant.Env, tenantName, "start"); err != nil {
-		slog.Error("Failed to execute tenant start hooks", "tenant", tenantName, "error", err)
+	// Apply CPU, I/O, and OOM priority (Linux only)
+	if tenant.Priority != nil {
+		if err := applyPriority(cmd.Process.Pid, tenant.Priority); err != nil {
+			slog.Warn("Failed to apply tenant priority, ignoring it",
+				"tenant", tenantName,
+				"pid", cmd.Process.Pid,
+				"error", err)
+		} else {
+			app.mutex.Lock()
+			app.Priority = tenant.Priority
+			app.mutex.Unlock()
+		}
 	}
-
-	// Wait for app to be ready
-	return ps.waitForReady(app, tenantName, runtime)
+	return nil
 }
 
 // getRuntime determines the runtime command (e.g., "ruby", "python", "node")
@@ -98,6 +193,9 @@ func (ps *ProcessStarter) getRuntime(tenant *config.Tenant) string {
 			runtime = ps.config.Applications.Runtime[tenant.Framework]
 		}
 	}
+	if runtime == "" {
+		runtime = config.FrameworkPresets[tenant.Framework].Runtime
+	}
 	if runtime == "" {
 		runtime = "ruby" // Default to Ruby
 	}
@@ -113,6 +211,9 @@ func (ps *ProcessStarter) getServer(tenant *config.Tenant) string {
 			server = ps.config.Applications.Server[tenant.Framework]
 		}
 	}
+	if server == "" {
+		server = config.FrameworkPresets[tenant.Framework].Server
+	}
 	if server == "" {
 		server = "bin/rails" // Default to Rails
 	}
@@ -128,6 +229,15 @@ func (ps *ProcessStarter) getArgs(tenant *config.Tenant, port int) []string {
 			args = ps.config.Applications.Args[tenant.Framework]
 		}
 	}
+	if len(args) == 0 {
+		// A preset's server may need no arguments
+		if preset, ok := config.FrameworkPresets[tenant.Framework]; ok {
+			args = preset.Args
+			if len(args) == 0 {
+				return nil
+			}
+		}
+	}
 	if len(args) == 0 {
 		// Default Rails server args
 		args = []string{"server", "-b", "0.0.0.0", "-p", strconv.Itoa(port)}
@@ -143,38 +253,31 @@ func (ps *ProcessStarter) getArgs(tenant *config.Tenant, port int) []string {
 	return args
 }
 
-// setupCommand configures the command's working directory and environment
-func (ps *ProcessStarter) setupCommand(cmd *exec.Cmd, tenant *config.Tenant, port int) {
-	// Set working directory
-	if tenant.Root != "" {
-		cmd.Dir = tenant.Root
-	}
-
-	// Set environment
-	cmd.Env = os.Environ()
-
-	// Add PORT environment variable
-	cmd.Env = append(cmd.Env, fmt.Sprintf("PORT=%d", port))
-
-	// Add tenant-specific environment variables
-	for key, value := range tenant.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
-	}
-}
-
-// waitForReady waits for the web app to be ready to accept connections
+// waitForReady waits for the web app to be ready to accept connections. An
+// app that doesn't answer its health check in time is marked unhealthy, and
+// healthy once it does.
 func (ps *ProcessStarter) waitForReady(app *WebApp, tenantName, runtime string) error {
-	// Clear Starting flag and signal ready when done
+	// Record the boot time, and signal that starting is over when done
 	defer func() {
 		app.mutex.Lock()
-		app.Starting = false
+		if !app.spawned.IsZero() {
+			app.bootTime = time.Since(app.spawned)
+		}
 		close(app.readyChan)
 		app.mutex.Unlock()
 	}()
 
+	// The in-process echo backend is listening before it's started
+	if runtime == config.RuntimeInternalEcho {
+		logging.LogWebAppReady(tenantName, app.Port)
+		app.setState(AppHealthy, "started")
+		return nil
+	}
+
 	// Skip readiness check if in test mode with echo command
 	if os.Getenv("NAVIGATOR_TEST_SKIP_READINESS") == "true" || runtime == "echo" {
 		slog.Debug("Skipping readiness check for test", "tenant", tenantName)
+		app.setState(AppHealthy, "readiness check skipped")
 		return nil
 	}
 
@@ -187,11 +290,16 @@ func (ps *ProcessStarter) waitForReady(app *WebApp, tenantName, runtime string)
 
 	for {
 		select {
+		case <-app.exited:
+			return fmt.Errorf("web app exited during startup")
 		case <-readyCtx.Done():
-			// Give app more time but don't fail
-			slog.Warn("App startup timeout reached, continuing anyway",
+			// Give app more time, but send it no requests until it answers
+			slog.Warn("App startup timeout reached, marking it unhealthy until it answers",
 				"tenant", tenantName,
 				"timeout", config.RailsStartupTimeout)
+			if app.setState(AppUnhealthy, "no response to health check during startup") {
+				go ps.awaitHealthy(app, tenantName)
+			}
 			return nil
 		case <-ticker.C:
 			// Determine health check endpoint
@@ -210,12 +318,40 @@ func (ps *ProcessStarter) waitForReady(app *WebApp, tenantName, runtime string)
 					"endpoint", healthCheck,
 					"status", resp.StatusCode)
 				logging.LogWebAppReady(tenantName, app.Port)
+				app.setState(AppHealthy, "health check passed")
 				return nil
 			}
 		}
 	}
 }
 
+// awaitHealthy keeps checking an app that didn't answer its health check
+// during startup, and marks it healthy once it does. It gives up when the
+// app leaves the unhealthy state.
+func (ps *ProcessStarter) awaitHealthy(app *WebApp, tenantName string) {
+	ticker := time.NewTicker(config.UnhealthyCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-app.exited:
+			return
+		case <-ticker.C:
+		}
+		if app.State() != AppUnhealthy {
+			return
+		}
+		// As at startup, any HTTP response means the app is serving requests
+		client := &http.Client{Timeout: 500 * time.Millisecond}
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d%s", app.Port, ps.getHealthCheckEndpoint(app.Tenant)))
+		if err == nil {
+			_ = resp.Body.Close()
+			logging.LogWebAppReady(tenantName, app.Port)
+			app.setState(AppHealthy, "health check passed")
+			return
+		}
+	}
+}
+
 // getHealthCheckEndpoint determines the health check endpoint for a tenant
 func (ps *ProcessStarter) getHealthCheckEndpoint(tenant *config.Tenant) string {
 	// 1. Check tenant-specific health check
@@ -226,10 +362,56 @@ func (ps *ProcessStarter) getHealthCheckEndpoint(tenant *config.Tenant) string {
 	if ps.config.Applications.HealthCheck != "" {
 		return ps.config.Applications.HealthCheck
 	}
-	// 3. Default to root path
+	// 3. Check the framework preset
+	if tenant != nil {
+		if healthCheck := config.FrameworkPresets[tenant.Framework].HealthCheck; healthCheck != "" {
+			return healthCheck
+		}
+	}
+	// 4. Default to root path
 	return "/"
 }
 
+// getStopSignal determines the signal that stops a tenant's app gracefully.
+// Empty means the app is killed.
+func (ps *ProcessStarter) getStopSignal(tenant *config.Tenant) string {
+	if tenant.StopSignal != "" {
+		return tenant.StopSignal
+	}
+	return config.FrameworkPresets[tenant.Framework].StopSignal
+}
+
+// presetEnv returns the framework preset's environment for a tenant on
+// port, with a relative PIDFILE placed under the tenant's root
+func presetEnv(tenant *config.Tenant, port int) map[string]string {
+	preset, ok := config.FrameworkPresets[tenant.Framework]
+	if !ok || len(preset.Env) == 0 {
+		return nil
+	}
+	replacer := strings.NewReplacer(
+		"{{port}}", strconv.Itoa(port),
+		"{{name}}", tenant.FileName(),
+	)
+	env := make(map[string]string, len(preset.Env))
+	for key, value := range preset.Env {
+		value = replacer.Replace(value)
+		if key == "PIDFILE" && !filepath.IsAbs(value) {
+			value = filepath.Join(tenant.Root, value)
+		}
+		env[key] = value
+	}
+	return env
+}
+
+// pidFile returns the PIDFILE a tenant's app is started with, if any
+func pidFile(tenant *config.Tenant) (string, bool) {
+	if pidfile, ok := tenant.Env["PIDFILE"]; ok {
+		return pidfile, true
+	}
+	pidfile, ok := presetEnv(tenant, 0)["PIDFILE"]
+	return pidfile, ok
+}
+
 // setupCgroupAndCredentials configures memory limits and process credentials
 // This is only functional on Linux when running as root
 func (ps *ProcessStarter) setupCgroupAndCredentials(cmd *exec.Cmd, app *WebApp, tenant *config.Tenant) error {
@@ -281,3 +463,19 @@ func (ps *ProcessStarter) setupCgroupAndCredentials(cmd *exec.Cmd, app *WebApp,
 
 	return nil
 }
+
+// checkTenantRoot returns ErrTenantRootUnavailable, with the reason, when
+// dir is set but isn't a readable directory
+func checkTenantRoot(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTenantRootUnavailable, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrTenantRootUnavailable, dir)
+	}
+	return nil
+}
@@ -1,15 +1,26 @@
 package process
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
 )
 
 func TestNewManager(t *testing.T) {
@@ -78,7 +89,7 @@ func TestExecuteHooks(t *testing.T) {
 				{
 					Command: "echo",
 					Args:    []string{"test"},
-					Timeout: "5s",
+					Timeout: config.Duration(5 * time.Second),
 				},
 			},
 			env:         map[string]string{"TEST": "value"},
@@ -98,12 +109,12 @@ func TestExecuteHooks(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name: "Hook with invalid timeout",
+			name: "Hook with zero timeout",
 			hooks: []config.HookConfig{
 				{
 					Command: "echo",
 					Args:    []string{"test"},
-					Timeout: "invalid",
+					Timeout: 0,
 				},
 			},
 			env:         map[string]string{},
@@ -116,7 +127,7 @@ func TestExecuteHooks(t *testing.T) {
 				{
 					Command: "non-existent-command-12345",
 					Args:    []string{},
-					Timeout: "1s",
+					Timeout: config.Duration(time.Second),
 				},
 			},
 			env:         map[string]string{},
@@ -127,9 +138,9 @@ func TestExecuteHooks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ExecuteHooks(tt.hooks, tt.env, tt.hookType)
+			err := ExecuteHooks(context.Background(), tt.hooks, tt.env, tt.hookType)
 			if (err != nil) != tt.expectError {
-				t.Errorf("ExecuteHooks() error = %v, expectError = %v", err, tt.expectError)
+				t.Errorf("ExecuteHooks(context.Background(), ) error = %v, expectError = %v", err, tt.expectError)
 			}
 		})
 	}
@@ -154,7 +165,7 @@ func TestExecuteServerHooks(t *testing.T) {
 				{
 					Command: "echo",
 					Args:    []string{"server", "starting"},
-					Timeout: "2s",
+					Timeout: config.Duration(2 * time.Second),
 				},
 			},
 			hookType:    "start",
@@ -164,9 +175,9 @@ func TestExecuteServerHooks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ExecuteServerHooks(tt.hooks, tt.hookType)
+			err := ExecuteServerHooks(context.Background(), tt.hooks, tt.hookType)
 			if (err != nil) != tt.expectError {
-				t.Errorf("ExecuteServerHooks() error = %v, expectError = %v", err, tt.expectError)
+				t.Errorf("ExecuteServerHooks(context.Background(), ) error = %v, expectError = %v", err, tt.expectError)
 			}
 		})
 	}
@@ -201,7 +212,7 @@ func TestManagerUpdateConfig(t *testing.T) {
 				Args:        []string{"updated"},
 				WorkingDir:  "/tmp",
 				AutoRestart: false,
-				StartDelay:  "2s",
+				StartDelay:  config.Duration(2 * time.Second),
 			},
 		},
 	}
@@ -215,7 +226,7 @@ func TestExecuteTenantHooks(t *testing.T) {
 		{
 			Command: "echo",
 			Args:    []string{"default", "hook"},
-			Timeout: "2s",
+			Timeout: config.Duration(2 * time.Second),
 		},
 	}
 
@@ -223,13 +234,13 @@ func TestExecuteTenantHooks(t *testing.T) {
 		{
 			Command: "echo",
 			Args:    []string{"specific", "hook"},
-			Timeout: "2s",
+			Timeout: config.Duration(2 * time.Second),
 		},
 	}
 
 	env := map[string]string{"TENANT": "test-tenant"}
 
-	err := ExecuteTenantHooks(defaultHooks, specificHooks, env, "test-tenant", "start")
+	err := ExecuteTenantHooks(context.Background(), defaultHooks, specificHooks, env, "test-tenant", "start")
 	if err != nil {
 		t.Errorf("ExecuteTenantHooks should not error: %v", err)
 	}
@@ -279,19 +290,59 @@ func TestCreateLogWriter(t *testing.T) {
 	}
 }
 
+func TestPrettyLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newFormatWriter("boston", "stderr", "", config.LogFormatPretty, config.LogConfig{}, &out)
+	_, _ = w.Write([]byte("Puma starting\nListening on http://0.0.0.0:4001\n"))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", out.String())
+	}
+	prefix := regexp.MustCompile(`^\d{2}:\d{2}:\d{2} boston +\| `)
+	for i, want := range []string{"Puma starting", "Listening on http://0.0.0.0:4001"} {
+		if !prefix.MatchString(lines[i]) || !strings.HasSuffix(lines[i], want) {
+			t.Errorf("Line %d = %q, want the time, source and %q", i, lines[i], want)
+		}
+	}
+	if strings.ContainsRune(out.String(), 0x1b) {
+		t.Errorf("Expected no colors when the output isn't a terminal, got %q", out.String())
+	}
+}
+
+func TestResolveLogFormat(t *testing.T) {
+	var out bytes.Buffer
+	t.Setenv("LOG_FORMAT", "")
+	if got := ResolveLogFormat("", config.LogFormatText, &out); got != config.LogFormatText {
+		t.Errorf("Unset format off a terminal = %q, want text", got)
+	}
+	if got := ResolveLogFormat(config.LogFormatPretty, config.LogFormatText, &out); got != config.LogFormatPretty {
+		t.Errorf("Configured format = %q, want pretty", got)
+	}
+
+	t.Setenv("LOG_FORMAT", "JSON")
+	if got := ResolveLogFormat(config.LogFormatPretty, config.LogFormatText, &out); got != config.LogFormatJSON {
+		t.Errorf("LOG_FORMAT override = %q, want json", got)
+	}
+	t.Setenv("LOG_FORMAT", "fancy")
+	if got := ResolveLogFormat(config.LogFormatText, config.LogFormatJSON, &out); got != config.LogFormatText {
+		t.Errorf("Unknown LOG_FORMAT = %q, want the configured text", got)
+	}
+}
+
 func BenchmarkExecuteHooks(b *testing.B) {
 	hooks := []config.HookConfig{
 		{
 			Command: "echo",
 			Args:    []string{"benchmark", "test"},
-			Timeout: "1s",
+			Timeout: config.Duration(time.Second),
 		},
 	}
 	env := map[string]string{"BENCH": "true"}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = ExecuteHooks(hooks, env, "benchmark")
+		_ = ExecuteHooks(context.Background(), hooks, env, "benchmark")
 	}
 }
 
@@ -325,7 +376,7 @@ func TestManagedProcessLifecycle(t *testing.T) {
 				WorkingDir:  "/tmp",
 				Env:         map[string]string{"TEST": "value"},
 				AutoRestart: false,
-				StartDelay:  "100ms",
+				StartDelay:  config.Duration(100 * time.Millisecond),
 			},
 		},
 	}
@@ -391,7 +442,7 @@ func TestWebAppManagerIntegration(t *testing.T) {
 			Pools: config.Pools{
 				MaxSize:   5,
 				StartPort: 4000,
-				Timeout:   "5m",
+				Timeout:   config.Duration(5 * time.Minute),
 			},
 			Tenants: []config.Tenant{
 				{
@@ -557,12 +608,12 @@ func TestExecuteHooksTimeout(t *testing.T) {
 		{
 			Command: "sleep",
 			Args:    []string{"10"}, // Long sleep to trigger timeout
-			Timeout: "100ms",
+			Timeout: config.Duration(100 * time.Millisecond),
 		},
 	}
 
 	start := time.Now()
-	err := ExecuteHooks(hooks, map[string]string{}, "timeout-test")
+	err := ExecuteHooks(context.Background(), hooks, map[string]string{}, "timeout-test")
 	duration := time.Since(start)
 
 	// Should timeout and return error
@@ -650,7 +701,7 @@ func TestExecuteServerHooksWithReload(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			configLoadTime := time.Now().Add(-1 * time.Hour) // Simulate config was loaded an hour ago
-			result := ExecuteServerHooksWithReload(tt.hooks, "test", tt.currentConfigFile, configLoadTime)
+			result := ExecuteServerHooksWithReload(context.Background(), tt.hooks, "test", tt.currentConfigFile, configLoadTime)
 
 			if (result.Error != nil) != tt.wantError {
 				t.Errorf("Error = %v, wantError = %v", result.Error, tt.wantError)
@@ -673,7 +724,7 @@ func TestWebAppGetApp(t *testing.T) {
 			Pools: config.Pools{
 				MaxSize:   5,
 				StartPort: 4000,
-				Timeout:   "5m",
+				Timeout:   config.Duration(5 * time.Minute),
 			},
 			Tenants: []config.Tenant{
 				{
@@ -701,7 +752,7 @@ func TestWebAppGetApp(t *testing.T) {
 			Pools: config.Pools{
 				MaxSize:   10,
 				StartPort: 4500,
-				Timeout:   "10m",
+				Timeout:   config.Duration(10 * time.Minute),
 			},
 		},
 	}
@@ -720,7 +771,7 @@ func TestStartWebApp(t *testing.T) {
 			Pools: config.Pools{
 				MaxSize:   5,
 				StartPort: 4000,
-				Timeout:   "5m",
+				Timeout:   config.Duration(5 * time.Minute),
 			},
 			Tenants: []config.Tenant{
 				{
@@ -757,6 +808,7 @@ func TestStartWebApp(t *testing.T) {
 		LastActivity:  time.Now(),
 		wsConnections: make(map[string]interface{}),
 		readyChan:     make(chan struct{}),
+		state:         AppHealthy,
 	}
 
 	tenant := &cfg.Applications.Tenants[0]
@@ -773,11 +825,51 @@ func TestStartWebApp(t *testing.T) {
 	}
 }
 
-func TestMonitorAppIdleTimeout(t *testing.T) {
+func TestStartWebAppEmitsTenantStarted(t *testing.T) {
+	received := make(chan events.Event, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event events.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		received <- event
+	}))
+	defer receiver.Close()
+	events.Configure([]config.EventHookConfig{{
+		URL:     receiver.URL,
+		Types:   []string{events.TenantStarted},
+		Timeout: config.Duration(time.Second),
+	}})
+	defer events.Configure(nil)
+
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "boston", Root: "/tmp", Runtime: config.RuntimeInternalEcho}}
+	appManager := NewAppManager(cfg)
+
+	app, err := appManager.GetOrStartApp("boston")
+	if err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+	defer appManager.Cleanup()
+
+	select {
+	case event := <-received:
+		if event.Type != events.TenantStarted {
+			t.Errorf("type = %q, want %q", event.Type, events.TenantStarted)
+		}
+		if event.Details["tenant"] != "boston" || event.Details["port"] != float64(app.Port) {
+			t.Errorf("details = %v, want tenant boston on port %d", event.Details, app.Port)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tenant.started was not delivered")
+	}
+}
+
+func TestCheckIdleApp(t *testing.T) {
 	cfg := &config.Config{
 		Applications: config.Applications{
 			Pools: config.Pools{
-				Timeout: "100ms", // Very short timeout for testing
+				Timeout: config.Duration(100 * time.Millisecond), // Very short timeout for testing
 			},
 			Tenants: []config.Tenant{
 				{
@@ -797,6 +889,7 @@ func TestMonitorAppIdleTimeout(t *testing.T) {
 		LastActivity:  time.Now().Add(-200 * time.Millisecond), // Make it look idle
 		wsConnections: make(map[string]interface{}),
 		readyChan:     make(chan struct{}),
+		state:         AppHealthy,
 	}
 
 	// Add app to manager
@@ -804,19 +897,23 @@ func TestMonitorAppIdleTimeout(t *testing.T) {
 	appManager.apps["idle-test"] = app
 	appManager.mutex.Unlock()
 
-	// Test monitoring (this will run in background)
-	go appManager.monitorAppIdleTimeout("idle-test")
-
-	// Give some time for monitoring to potentially detect idle state
-	time.Sleep(50 * time.Millisecond)
-
-	// The test app should still exist (since echo command finishes quickly)
-	appManager.mutex.RLock()
-	_, exists := appManager.apps["idle-test"]
-	appManager.mutex.RUnlock()
+	// An idle app is not checked again and is stopped in the background
+	if appManager.checkIdleApp("idle-test") {
+		t.Error("checkIdleApp() = true for an idle app, want false")
+	}
 
-	if !exists {
-		t.Log("App was removed due to idle timeout (this is expected behavior)")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		appManager.mutex.RLock()
+		_, exists := appManager.apps["idle-test"]
+		appManager.mutex.RUnlock()
+		if !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Idle app was not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
 
 	// Cleanup
@@ -849,6 +946,7 @@ func TestWebSocketConnectionManagement(t *testing.T) {
 		wsConnections: make(map[string]interface{}),
 		Tenant:        &cfg.Applications.Tenants[0],
 		readyChan:     make(chan struct{}),
+		state:         AppHealthy,
 	}
 
 	appManager.mutex.Lock()
@@ -938,6 +1036,7 @@ func TestStartWebAppDefaultValues(t *testing.T) {
 		LastActivity:  time.Now(),
 		wsConnections: make(map[string]interface{}),
 		readyChan:     make(chan struct{}),
+		state:         AppHealthy,
 	}
 
 	tenant := &cfg.Applications.Tenants[0]
@@ -998,6 +1097,7 @@ func TestStartWebAppWithFrameworkConfig(t *testing.T) {
 		LastActivity:  time.Now(),
 		wsConnections: make(map[string]interface{}),
 		readyChan:     make(chan struct{}),
+		state:         AppHealthy,
 	}
 
 	tenant := &cfg.Applications.Tenants[0]
@@ -1014,6 +1114,91 @@ func TestStartWebAppWithFrameworkConfig(t *testing.T) {
 	}
 }
 
+func TestStartWebAppWithFrameworkPreset(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 4000
+	cfg.Applications.Args = map[string][]string{"django": {"runserver", "{{port}}"}}
+	cfg.Applications.Tenants = []config.Tenant{
+		{Name: "2025/next", Root: "/srv/next", Framework: "node"},
+		{Name: "api", Root: "/srv/api", Framework: "fastapi", Server: "-X", Args: []string{"importtime", "{{port}}"}},
+		{Name: "admin", Root: "/srv/admin", Framework: "django"},
+		{Name: "legacy", Root: "/srv/legacy", Framework: "rails", Env: map[string]string{"PIDFILE": "/run/legacy.pid"}},
+		{Name: "plain", Root: "/srv/plain"},
+	}
+	starter := NewProcessStarter(cfg)
+	tenants := cfg.Applications.Tenants
+
+	tests := []struct {
+		tenant  *config.Tenant
+		command []string
+	}{
+		// Preset runtime and server; node's server takes no arguments
+		{&tenants[0], []string{"node", "server.js"}},
+		// Tenant server and args override the preset, its runtime remains
+		{&tenants[1], []string{"python", "-X", "importtime", "4006"}},
+		// applications.args for the framework name overrides the preset
+		{&tenants[2], []string{"python", "manage.py", "runserver", "4006"}},
+		{&tenants[3], []string{"ruby", "bin/rails", "server", "-b", "0.0.0.0", "-p", "4006"}},
+		// No framework: the Rails defaults
+		{&tenants[4], []string{"ruby", "bin/rails", "server", "-b", "0.0.0.0", "-p", "4006"}},
+	}
+	for _, tt := range tests {
+		spec := starter.WebAppCommand(tt.tenant, 4006)
+		if got := append([]string{spec.Command}, spec.Args...); !slices.Equal(got, tt.command) {
+			t.Errorf("%s command = %q, want %q", tt.tenant.Name, got, tt.command)
+		}
+	}
+
+	if got := starter.WebAppCommand(&tenants[2], 4006).Env["PYTHONUNBUFFERED"]; got != "1" {
+		t.Errorf("django env PYTHONUNBUFFERED = %q, want 1", got)
+	}
+	if pidfile, _ := pidFile(&tenants[3]); pidfile != "/run/legacy.pid" {
+		t.Errorf("Tenant PIDFILE = %q, want the tenant's own", pidfile)
+	}
+	railsTenant := config.Tenant{Name: "2025/boston", Root: "/srv/showcase", Framework: "rails"}
+	if pidfile, _ := pidFile(&railsTenant); pidfile != filepath.Join("/srv/showcase", "tmp/pids/2025-boston.pid") {
+		t.Errorf("Preset PIDFILE = %q", pidfile)
+	}
+	if _, ok := pidFile(&tenants[0]); ok {
+		t.Error("node preset should not set a PIDFILE")
+	}
+
+	// Health check: tenant, then applications.health_check, then the preset
+	if got := starter.getHealthCheckEndpoint(&tenants[3]); got != "/up" {
+		t.Errorf("rails health check = %q, want /up", got)
+	}
+	cfg.Applications.HealthCheck = "/healthz"
+	if got := starter.getHealthCheckEndpoint(&tenants[3]); got != "/healthz" {
+		t.Errorf("rails health check = %q, want the applications default", got)
+	}
+
+	// Stop signal: tenant, then the preset; none means kill
+	if got := starter.getStopSignal(&tenants[2]); got != "SIGINT" {
+		t.Errorf("django stop signal = %q, want SIGINT", got)
+	}
+	tenants[2].StopSignal = "SIGQUIT"
+	if got := starter.getStopSignal(&tenants[2]); got != "SIGQUIT" {
+		t.Errorf("Overridden stop signal = %q, want SIGQUIT", got)
+	}
+	if got := starter.getStopSignal(&tenants[4]); got != "" {
+		t.Errorf("Stop signal without framework = %q, want none", got)
+	}
+
+	// Starting applies the preset to the real command
+	tenant := config.Tenant{Name: "preset-start", Root: t.TempDir(), Framework: "fastapi", Runtime: "echo"}
+	app := &WebApp{Port: 4007, Tenant: &tenant, wsConnections: make(map[string]interface{}), readyChan: make(chan struct{}), state: AppHealthy}
+	if err := starter.StartWebApp(app, &tenant); err != nil {
+		t.Fatalf("StartWebApp() error = %v", err)
+	}
+	defer app.cancel()
+	if !slices.Contains(app.Process.Args, "uvicorn") || !slices.Contains(app.Process.Args, "4007") {
+		t.Errorf("Started %q, want the fastapi preset on port 4007", app.Process.Args)
+	}
+	if runtime.GOOS != "windows" && app.Process.Cancel == nil {
+		t.Error("fastapi preset should stop the app with a signal")
+	}
+}
+
 func TestLoggingComponents(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1206,6 +1391,12 @@ func TestTemplateLogFileNames(t *testing.T) {
 			template:     tempDir + "/{{app}}-{{app}}.log",
 			expectedFile: tempDir + "/test-app-test-app.log",
 		},
+		{
+			name:         "Tenant name with slashes",
+			source:       "2025/raleigh/shimmer-shine",
+			template:     tempDir + "/{{app}}.log",
+			expectedFile: tempDir + "/2025-raleigh-shimmer-shine.log",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1270,6 +1461,27 @@ func TestMultiDestinationLogging(t *testing.T) {
 	}
 }
 
+func TestMultiDestinationAccessLog(t *testing.T) {
+	accessFile := filepath.Join(t.TempDir(), "logs", "access.log")
+	var stdout bytes.Buffer
+
+	cfg := config.LogConfig{Access: config.AccessLogConfig{Destinations: []string{"stdout", accessFile}}}
+	writer, ok := CreateAccessLogWriter(cfg, &stdout).(*MultiLogWriter)
+	if !ok || len(writer.Outputs()) != 2 {
+		t.Fatalf("CreateAccessLogWriter = %T, want a MultiLogWriter with two outputs", writer)
+	}
+	_, _ = writer.Write([]byte(`{"uri":"/"}` + "\n"))
+	_ = writer.Outputs()[1].(io.Closer).Close()
+
+	content, err := os.ReadFile(accessFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != `{"uri":"/"}`+"\n" || string(content) != stdout.String() {
+		t.Errorf("stdout = %q, file = %q, want the entry in both", stdout.String(), content)
+	}
+}
+
 // TestBuildManagedProcessConfigs tests Vector auto-injection
 func TestBuildManagedProcessConfigs(t *testing.T) {
 	tests := []struct {
@@ -1624,8 +1836,33 @@ func createTestConfig() *config.Config {
 				WorkingDir:  "/tmp",
 				Env:         map[string]string{"REDIS_PORT": "6379"},
 				AutoRestart: true,
-				StartDelay:  "1s",
+				StartDelay:  config.Duration(time.Second),
 			},
 		},
 	}
 }
+
+func TestStartWebAppMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "app")
+	tenant := config.Tenant{Name: "missing-root", Root: root, Runtime: "echo", Server: "test"}
+	starter := NewProcessStarter(&config.Config{})
+
+	app := &WebApp{Port: 4008, Tenant: &tenant, wsConnections: make(map[string]interface{}), readyChan: make(chan struct{}), state: AppStarting}
+	err := starter.StartWebApp(app, &tenant)
+	if !errors.Is(err, ErrTenantRootUnavailable) {
+		t.Fatalf("StartWebApp() error = %v, want ErrTenantRootUnavailable", err)
+	}
+	if app.Process != nil {
+		t.Error("process started despite its missing root")
+	}
+
+	// Once the root is back the app starts
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	app = &WebApp{Port: 4008, Tenant: &tenant, wsConnections: make(map[string]interface{}), readyChan: make(chan struct{}), state: AppHealthy}
+	if err := starter.StartWebApp(app, &tenant); err != nil {
+		t.Fatalf("StartWebApp() after recreating the root: %v", err)
+	}
+	app.cancel()
+}
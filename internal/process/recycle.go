@@ -0,0 +1,238 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// What triggered a recycle
+const (
+	RecycleMaxRequests = "max_requests"
+	RecycleMaxLifetime = "max_lifetime"
+	RecycleMaxMemory   = "max_memory"
+)
+
+// RecycleRecord is one recycle of a tenant's app, for the detailed health
+// check
+type RecycleRecord struct {
+	Tenant        string    `json:"tenant"`
+	Trigger       string    `json:"trigger"`
+	Detail        string    `json:"detail"` // The reading that crossed the threshold
+	Started       time.Time `json:"started"`
+	StartDuration float64   `json:"start_seconds"` // Until the replacement was ready
+	DrainDuration float64   `json:"drain_seconds"` // Until the old instance was stopped
+	OldPort       int       `json:"old_port"`
+	Port          int       `json:"port,omitempty"`  // The replacement's, once it took over
+	Error         string    `json:"error,omitempty"` // Why the replacement couldn't take over
+}
+
+// rssReader reads the resident memory of an app's process, or -1 if it
+// can't be read
+type rssReader interface {
+	residentMemory(app *WebApp) int64
+}
+
+// procRSS reads resident memory from /proc (Linux only)
+type procRSS struct{}
+
+func (procRSS) residentMemory(app *WebApp) int64 {
+	app.mutex.Lock()
+	cmd := app.Process
+	app.mutex.Unlock()
+	if cmd == nil || cmd.Process == nil {
+		return -1
+	}
+	return processMemory(cmd.Process.Pid)
+}
+
+// recycleConcurrency returns how many tenants may be recycled at once
+func recycleConcurrency(cfg *config.Config) int {
+	if cfg.Applications.MaxConcurrentRecycles > 0 {
+		return cfg.Applications.MaxConcurrentRecycles
+	}
+	return config.DefaultMaxConcurrentRecycles
+}
+
+// RequestStarted counts a request proxied to app, apart from WebSocket
+// upgrades, so a recycle drains it before stopping the app. The returned
+// function counts the request as finished; the app is recycled once it has
+// served its tenant's max_requests.
+func (m *AppManager) RequestStarted(tenantName string, app *WebApp) (finished func()) {
+	app.inFlight.Add(1)
+	return func() {
+		app.inFlight.Add(-1)
+		served := app.served.Add(1)
+		if policy := app.recyclePolicy(); policy != nil && policy.MaxRequests > 0 && served >= int64(policy.MaxRequests) {
+			m.startRecycle(tenantName, app, RecycleMaxRequests, fmt.Sprintf("%d requests", served))
+		}
+	}
+}
+
+// recyclePolicy returns the app's tenant's recycle policy, or nil
+func (w *WebApp) recyclePolicy() *config.RecycleConfig {
+	if w.Tenant == nil || !w.Tenant.Recycle.Enabled() {
+		return nil
+	}
+	return w.Tenant.Recycle
+}
+
+// checkRecycle recycles an app that has outlived its tenant's max_lifetime
+// or outgrown its max_memory. Run with the idle checks.
+func (m *AppManager) checkRecycle(tenantName string, app *WebApp) {
+	policy := app.recyclePolicy()
+	if policy == nil || !app.ready() {
+		return
+	}
+	if policy.MaxLifetime > 0 {
+		if age := app.now().Sub(app.StartTime); age >= policy.MaxLifetime.Std() {
+			m.startRecycle(tenantName, app, RecycleMaxLifetime, age.Round(time.Second).String())
+			return
+		}
+	}
+	if limit := policy.MaxMemoryBytes; limit > 0 {
+		if rss := m.rss.residentMemory(app); rss > limit {
+			m.startRecycle(tenantName, app, RecycleMaxMemory, formatBytes(rss))
+		}
+	}
+}
+
+// startRecycle begins recycling app in the background, unless it's already
+// being recycled, is no longer the tenant's primary, or as many tenants as
+// max_concurrent_recycles allows are being recycled. A recycle that can't
+// start now is tried again the next time it's triggered.
+func (m *AppManager) startRecycle(tenantName string, app *WebApp, trigger, detail string) {
+	m.mutex.RLock()
+	current := m.apps[tenantName] == app && !app.removed && m.stubPort == 0
+	slots := m.recycleSlots
+	m.mutex.RUnlock()
+	if !current {
+		return
+	}
+
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	if app.recycling || app.stateLocked() != AppHealthy {
+		return
+	}
+	select {
+	case slots <- struct{}{}:
+	default:
+		slog.Debug("Recycle deferred while other tenants recycle", "tenant", tenantName, "trigger", trigger)
+		return
+	}
+	app.recycling = true
+	go func() {
+		defer func() { <-slots }()
+		m.recycle(tenantName, app, trigger, detail)
+	}()
+}
+
+// recycle starts a replacement for old on a new port, switches routing to
+// it, then drains and stops old. If the replacement can't take over, old
+// keeps serving and isn't recycled again.
+func (m *AppManager) recycle(tenantName string, old *WebApp, trigger, detail string) {
+	logging.LogTenantRecycling(tenantName, trigger, detail)
+	record := RecycleRecord{Tenant: tenantName, Trigger: trigger, Detail: detail, Started: old.now(), OldPort: old.Port}
+
+	replacement, err := m.startReplacement(tenantName, old)
+	startDuration := old.now().Sub(record.Started)
+	record.StartDuration = startDuration.Seconds()
+	if err != nil {
+		logging.LogTenantRecycleFailed(tenantName, trigger, err)
+		record.Error = err.Error()
+		m.recordRecycle(record)
+		return
+	}
+	record.Port = replacement.Port
+
+	drainStarted := old.now()
+	m.drainInstance(old, old.Tenant.Recycle.DrainTimeout.Std())
+	drainDuration := old.now().Sub(drainStarted)
+	record.DrainDuration = drainDuration.Seconds()
+
+	logging.LogTenantRecycled(tenantName, trigger, old.Port, replacement.Port, startDuration, drainDuration)
+	m.recordRecycle(record)
+}
+
+// startReplacement starts an instance of the tenant on a new port and, once
+// it's ready, makes it the tenant's primary in place of old
+func (m *AppManager) startReplacement(tenantName string, old *WebApp) (*WebApp, error) {
+	m.mutex.Lock()
+	tenant := m.config.TenantByName(tenantName)
+	if tenant == nil {
+		m.mutex.Unlock()
+		return nil, fmt.Errorf("tenant %s not found", tenantName)
+	}
+	// Tenants with a pinned port aren't recycled, so this is always another
+	// port, recorded in port_state for the next start
+	port, err := m.allocatePort(tenant)
+	processStarter := m.processStarter
+	m.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	app := newWebApp(tenant, port)
+	app.recycled = true
+	app.onCrash = func() { m.removeCrashedApp(tenantName, app) }
+	release := m.acquireStartup()
+	err = processStarter.StartWebApp(app, tenant)
+	release()
+	if err != nil {
+		m.stopInstance(app)
+		return nil, err
+	}
+	if app.Exited() {
+		m.stopInstance(app)
+		return nil, errors.New("replacement exited during startup")
+	}
+
+	// The old instance may have crashed or stopped while the replacement started
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.apps[tenantName] != old || old.removed {
+		go m.stopInstance(app)
+		return nil, errors.New("instance stopped while its replacement started")
+	}
+	old.removed = true
+	old.setState(AppDraining, "recycled")
+	m.apps[tenantName] = app
+	return app, nil
+}
+
+// drainInstance waits, up to timeout, for the requests in flight to an
+// instance that no longer receives new ones, then closes its WebSockets so
+// clients reconnect to the replacement, and stops it
+func (m *AppManager) drainInstance(app *WebApp, timeout time.Duration) {
+	clk := clock.Or(app.clock)
+	deadline := clk.Now().Add(timeout)
+	for app.inFlight.Load() > 0 && clk.Now().Before(deadline) {
+		<-clk.NewTimer(config.RecycleDrainPollInterval).C()
+	}
+	app.CloseWebSockets()
+	m.stopInstance(app)
+}
+
+// recordRecycle adds a recycle to the history, dropping the oldest beyond
+// config.RecycleHistorySize
+func (m *AppManager) recordRecycle(record RecycleRecord) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.recycles = append(m.recycles, record)
+	if len(m.recycles) > config.RecycleHistorySize {
+		m.recycles = m.recycles[len(m.recycles)-config.RecycleHistorySize:]
+	}
+}
+
+// RecycleHistory returns the most recent recycles, oldest first
+func (m *AppManager) RecycleHistory() []RecycleRecord {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return append([]RecycleRecord(nil), m.recycles...)
+}
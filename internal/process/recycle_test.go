@@ -0,0 +1,202 @@
+package process
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// fakeRSS reports the same resident memory for every app
+type fakeRSS int64
+
+func (f fakeRSS) residentMemory(app *WebApp) int64 { return int64(f) }
+
+// newRecycleManager returns a manager for echo tenants with a recycle policy
+func newRecycleManager(t *testing.T, startPort int, policy config.RecycleConfig, names ...string) *AppManager {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = startPort
+	cfg.Applications.MaxConcurrentRecycles = 1
+	for _, name := range names {
+		recycle := policy
+		cfg.Applications.Tenants = append(cfg.Applications.Tenants, config.Tenant{
+			Name:      name,
+			Path:      "/" + name + "/",
+			Framework: config.RuntimeInternalEcho,
+			Recycle:   &recycle,
+		})
+	}
+	m := NewAppManager(cfg)
+	t.Cleanup(m.Cleanup)
+	return m
+}
+
+// startEcho starts a tenant's app and waits until it's ready
+func startEcho(t *testing.T, m *AppManager, name string) *WebApp {
+	t.Helper()
+	app, err := m.GetOrStartApp(name)
+	if err != nil {
+		t.Fatalf("GetOrStartApp(%s) error = %v", name, err)
+	}
+	<-app.ReadyChan()
+	return app
+}
+
+// waitForRecycles polls until the history holds count recycles
+func waitForRecycles(t *testing.T, m *AppManager, count int) []RecycleRecord {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		history := m.RecycleHistory()
+		if len(history) >= count {
+			return history
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Recycles = %+v, want %d", history, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// serving reports whether something answers on port
+func serving(port int) bool {
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/", port))
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return true
+}
+
+func TestRecycleAfterMaxRequestsDrainsOldInstance(t *testing.T) {
+	m := newRecycleManager(t, 4840, config.RecycleConfig{MaxRequests: 2, DrainTimeout: config.Duration(5 * time.Second)}, "boston")
+	old := startEcho(t, m, "boston")
+
+	slow := m.RequestStarted("boston", old) // Still in flight when the recycle begins
+	m.RequestStarted("boston", old)()
+	m.RequestStarted("boston", old)()
+
+	var replacement *WebApp
+	deadline := time.Now().Add(5 * time.Second)
+	for replacement == nil || replacement == old {
+		if time.Now().After(deadline) {
+			t.Fatal("The replacement never took over routing")
+		}
+		time.Sleep(10 * time.Millisecond)
+		replacement, _ = m.GetApp("boston")
+	}
+	if replacement.Port == old.Port || !serving(replacement.Port) {
+		t.Errorf("Replacement port %d should differ from %d and be serving", replacement.Port, old.Port)
+	}
+	if !serving(old.Port) {
+		t.Error("The old instance should keep serving while a request is in flight")
+	}
+
+	slow()
+	record := waitForRecycles(t, m, 1)[0]
+	if record.Trigger != RecycleMaxRequests || record.OldPort != old.Port || record.Port != replacement.Port || record.Error != "" {
+		t.Errorf("Record = %+v, want a max_requests recycle from %d to %d", record, old.Port, replacement.Port)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for serving(old.Port) {
+		if time.Now().After(deadline) {
+			t.Fatal("The old instance should stop once drained")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRecycleRecordsReplacementPort(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 4870
+	cfg.Applications.Pools.PortState = config.PortStateConfig{Enabled: true, File: filepath.Join(t.TempDir(), "ports.json")}
+	cfg.Applications.MaxConcurrentRecycles = 1
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name:      "boston",
+		Path:      "/boston/",
+		Framework: config.RuntimeInternalEcho,
+		Recycle:   &config.RecycleConfig{MaxRequests: 1, DrainTimeout: config.Duration(time.Second)},
+	}}
+	m := NewAppManager(cfg)
+	t.Cleanup(m.Cleanup)
+
+	old := startEcho(t, m, "boston")
+	if got := m.portState.preferred("boston"); got != old.Port {
+		t.Fatalf("Saved port = %d, want %d", got, old.Port)
+	}
+	m.RequestStarted("boston", old)()
+	record := waitForRecycles(t, m, 1)[0]
+	if record.Error != "" || record.Port == old.Port {
+		t.Fatalf("Record = %+v, want a recycle to another port", record)
+	}
+
+	// The next start, as after a restart, prefers the replacement's port
+	if got := m.portState.preferred("boston"); got != record.Port {
+		t.Errorf("Saved port = %d, want the replacement's %d", got, record.Port)
+	}
+	if got := loadPortState(cfg.Applications.Pools.PortState).preferred("boston"); got != record.Port {
+		t.Errorf("Port state file has %d, want %d", got, record.Port)
+	}
+}
+
+func TestRecycleAfterMaxLifetime(t *testing.T) {
+	m := newRecycleManager(t, 4850, config.RecycleConfig{MaxLifetime: config.Duration(time.Hour)}, "boston")
+	app := startEcho(t, m, "boston")
+
+	fake := clock.NewFake(time.Now())
+	app.clock = fake
+	m.checkRecycle("boston", app)
+	if len(m.RecycleHistory()) != 0 {
+		t.Fatal("A young instance should not be recycled")
+	}
+
+	fake.Advance(2 * time.Hour)
+	m.checkRecycle("boston", app)
+	if record := waitForRecycles(t, m, 1)[0]; record.Trigger != RecycleMaxLifetime {
+		t.Errorf("Record = %+v, want a max_lifetime recycle", record)
+	}
+}
+
+func TestRecycleOnMemoryThreshold(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("max_memory is only checked on Linux")
+	}
+	m := newRecycleManager(t, 4860, config.RecycleConfig{MaxMemory: "512M", MaxMemoryBytes: 512 << 20}, "boston")
+	app := startEcho(t, m, "boston")
+
+	m.rss = fakeRSS(256 * 1024 * 1024)
+	m.checkRecycle("boston", app)
+	m.rss = fakeRSS(600 * 1024 * 1024)
+	m.checkRecycle("boston", app)
+	history := waitForRecycles(t, m, 1)
+	if len(history) != 1 || history[0].Trigger != RecycleMaxMemory || history[0].Detail != "600.0 MiB" {
+		t.Errorf("Recycles = %+v, want one max_memory recycle at 600 MiB", history)
+	}
+}
+
+func TestRecycleConcurrencyLimit(t *testing.T) {
+	m := newRecycleManager(t, 4870, config.RecycleConfig{MaxRequests: 1}, "boston", "raleigh")
+	boston := startEcho(t, m, "boston")
+	raleigh := startEcho(t, m, "raleigh")
+
+	m.recycleSlots <- struct{}{} // Another tenant is being recycled
+	m.RequestStarted("boston", boston)()
+	time.Sleep(50 * time.Millisecond)
+	if current, _ := m.GetApp("boston"); current != boston || len(m.RecycleHistory()) != 0 {
+		t.Fatal("No recycle should start while the limit is reached")
+	}
+
+	<-m.recycleSlots
+	m.RequestStarted("boston", boston)()
+	waitForRecycles(t, m, 1)
+	m.RequestStarted("raleigh", raleigh)()
+	if history := waitForRecycles(t, m, 2); history[1].Tenant != "raleigh" {
+		t.Errorf("Recycles = %+v, want boston then raleigh", history)
+	}
+}
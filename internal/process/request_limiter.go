@@ -0,0 +1,143 @@
+package process
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+var (
+	// ErrRequestQueueFull is returned when a request arrives with every slot busy and the queue full
+	ErrRequestQueueFull = errors.New("request queue full")
+
+	// ErrRequestQueueTimeout is returned when a queued request waited queue_timeout without a slot
+	ErrRequestQueueTimeout = errors.New("timed out waiting for a request slot")
+)
+
+// RequestLimiter bounds the requests proxied to one app at once. Requests
+// beyond the limit wait in arrival order; a released slot passes directly to
+// the oldest waiter.
+type RequestLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters list.List // *slotWaiter, oldest first
+}
+
+// slotWaiter is a queued request; ready is closed once it holds a slot
+type slotWaiter struct {
+	ready   chan struct{}
+	granted bool
+	since   time.Time
+}
+
+// Acquire waits for a slot under limit. It returns a release function that
+// must be called exactly once when the request is done, and the number of
+// requests queued ahead of this one including itself (0 if a slot was free).
+// The limit is read on every call so a reload takes effect immediately.
+func (l *RequestLimiter) Acquire(ctx context.Context, limit config.ConcurrencyConfig) (release func(), depth int, err error) {
+	l.mu.Lock()
+	l.limit = limit.MaxConcurrentRequests
+	l.grantLocked()
+	if l.active < l.limit && l.waiters.Len() == 0 {
+		l.active++
+		l.mu.Unlock()
+		return l.releaseFunc(), 0, nil
+	}
+	if l.waiters.Len() >= limit.QueueSize {
+		l.mu.Unlock()
+		return nil, l.waiters.Len(), ErrRequestQueueFull
+	}
+	waiter := &slotWaiter{ready: make(chan struct{}), since: time.Now()}
+	elem := l.waiters.PushBack(waiter)
+	depth = l.waiters.Len()
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Duration(limit.QueueTimeout))
+	defer timer.Stop()
+
+	select {
+	case <-waiter.ready:
+		return l.releaseFunc(), depth, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+		err = ErrRequestQueueTimeout
+	}
+
+	l.mu.Lock()
+	if waiter.granted {
+		// The slot arrived as we gave up; hand it on
+		l.mu.Unlock()
+		l.releaseFunc()()
+		return nil, depth, err
+	}
+	l.waiters.Remove(elem)
+	l.mu.Unlock()
+	return nil, depth, err
+}
+
+// Reserve takes a free slot under limit and returns its release function,
+// or joins the queue and returns a function that waits, however long it
+// takes, for the slot and then returns its release function. Either way the
+// caller's place in arrival order is kept from the moment Reserve returns.
+func (l *RequestLimiter) Reserve(limit int) (release func(), wait func() func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.grantLocked()
+	if l.active < l.limit && l.waiters.Len() == 0 {
+		l.active++
+		return l.releaseFunc(), nil
+	}
+	waiter := &slotWaiter{ready: make(chan struct{}), since: time.Now()}
+	l.waiters.PushBack(waiter)
+	return nil, func() func() {
+		<-waiter.ready
+		return l.releaseFunc()
+	}
+}
+
+// releaseFunc returns a function that frees one slot, at most once
+func (l *RequestLimiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(l.release)
+	}
+}
+
+// release frees a slot and passes it on to the oldest waiter
+func (l *RequestLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	l.grantLocked()
+}
+
+// grantLocked gives free slots to the oldest waiters; l.mu must be held
+func (l *RequestLimiter) grantLocked() {
+	for l.active < l.limit && l.waiters.Len() > 0 {
+		waiter := l.waiters.Remove(l.waiters.Front()).(*slotWaiter)
+		waiter.granted = true
+		close(waiter.ready)
+		l.active++
+	}
+}
+
+// Stats returns the requests holding a slot, the requests queued, and how
+// long the oldest queued request has waited
+func (l *RequestLimiter) Stats() (active, queued int, oldestWait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if front := l.waiters.Front(); front != nil {
+		oldestWait = time.Since(front.Value.(*slotWaiter).since)
+	}
+	return l.active, l.waiters.Len(), oldestWait
+}
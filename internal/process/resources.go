@@ -0,0 +1,22 @@
+package process
+
+import "runtime"
+
+// ResourceUsage is a reading of the resources Navigator itself consumes.
+// Readings that can't be taken on this platform are -1.
+type ResourceUsage struct {
+	RSS             int64 // Navigator's resident memory
+	AvailableMemory int64 // System memory available for new allocations
+	OpenFDs         int
+	Goroutines      int
+}
+
+// SampleResources reads Navigator's current resource usage
+func SampleResources() ResourceUsage {
+	return ResourceUsage{
+		RSS:             residentMemory(),
+		AvailableMemory: availableMemory(),
+		OpenFDs:         OpenFileCount(),
+		Goroutines:      runtime.NumGoroutine(),
+	}
+}
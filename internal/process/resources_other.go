@@ -0,0 +1,18 @@
+//go:build !linux
+
+package process
+
+// residentMemory returns -1; resident memory is only read on Linux
+func residentMemory() int64 {
+	return -1
+}
+
+// availableMemory returns -1; available memory is only read on Linux
+func availableMemory() int64 {
+	return -1
+}
+
+// processMemory returns -1; a process's resident memory is only read on Linux
+func processMemory(pid int) int64 {
+	return -1
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package process
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals maps config.ReloadSignals names to signals
+var reloadSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// reloadSignalFor returns the signal for a normalized signal name
+func reloadSignalFor(name string) (os.Signal, bool) {
+	sig, ok := reloadSignals[name]
+	return sig, ok
+}
+
+// stopSignals maps config.StopSignals names to signals
+var stopSignals = map[string]os.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// stopSignalFor returns the signal for a normalized stop signal name
+func stopSignalFor(name string) (os.Signal, bool) {
+	sig, ok := stopSignals[name]
+	return sig, ok
+}
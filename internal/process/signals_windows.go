@@ -0,0 +1,17 @@
+//go:build windows
+
+package process
+
+import "os"
+
+// reloadSignalFor reports that reload signals are unavailable; Windows
+// processes can only be killed, so signaled groups are restarted instead
+func reloadSignalFor(name string) (os.Signal, bool) {
+	return nil, false
+}
+
+// stopSignalFor reports that stop signals are unavailable; Windows
+// processes are always killed
+func stopSignalFor(name string) (os.Signal, bool) {
+	return nil, false
+}
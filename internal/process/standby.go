@@ -0,0 +1,328 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// errStandbyExited is reported when a standby's process exits on its own
+var errStandbyExited = errors.New("standby process exited")
+
+// standbyPair is a tenant's warm standby: a second instance that receives no
+// traffic until the primary crashes or fails its health check, when routing
+// flips to it and a replacement standby is started. Fields are guarded by
+// AppManager.mutex.
+type standbyPair struct {
+	app       *WebApp       // The standby; nil while a replacement is started
+	failovers int           // Times a standby has taken over
+	wake      chan struct{} // Asks the monitor to start a replacement now
+	stop      chan struct{} // Closed when the pair stops with its primary
+}
+
+// newWebApp returns an app for tenant, not yet started, on port
+func newWebApp(tenant *config.Tenant, port int) *WebApp {
+	app := &WebApp{
+		URL:           fmt.Sprintf("http://localhost:%d", port),
+		Tenant:        tenant,
+		Port:          port,
+		StartTime:     time.Now(),
+		LastActivity:  time.Now(),
+		readyChan:     make(chan struct{}),
+		wsConnections: make(map[string]interface{}),
+	}
+	app.setState(AppStarting, "start requested")
+	return app
+}
+
+// ready reports whether the app has finished starting
+func (w *WebApp) ready() bool {
+	select {
+	case <-w.readyChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// trackStandby starts keeping a warm standby for a tenant whose primary has
+// just started. Called with m.mutex held.
+func (m *AppManager) trackStandby(tenantName string) {
+	if m.standbys[tenantName] != nil {
+		return
+	}
+	if m.standbys == nil {
+		m.standbys = make(map[string]*standbyPair)
+	}
+	pair := &standbyPair{wake: make(chan struct{}, 1), stop: make(chan struct{})}
+	m.standbys[tenantName] = pair
+	go m.monitorStandby(tenantName, pair)
+}
+
+// monitorStandby keeps the pair's standby running and health checks both
+// instances until the pair stops
+func (m *AppManager) monitorStandby(tenantName string, pair *standbyPair) {
+	ticker := time.NewTicker(config.StandbyCheckInterval)
+	defer ticker.Stop()
+	failures := 0
+	for m.replenishStandby(tenantName, pair) {
+		select {
+		case <-pair.stop:
+			return
+		case <-pair.wake:
+			continue
+		case <-ticker.C:
+		}
+		m.checkStandby(tenantName, pair, &failures)
+	}
+}
+
+// replenishStandby starts a standby if the pair has none. It returns false,
+// stopping the pair, once the tenant's primary is no longer running.
+func (m *AppManager) replenishStandby(tenantName string, pair *standbyPair) bool {
+	m.mutex.Lock()
+	if m.standbys[tenantName] != pair {
+		m.mutex.Unlock()
+		return false
+	}
+	primary := m.apps[tenantName]
+	if primary == nil || primary.Tenant == nil || !primary.Tenant.Standby {
+		standby := m.takeStandby(tenantName)
+		m.mutex.Unlock()
+		m.stopInstance(standby)
+		return false
+	}
+	if pair.app != nil {
+		m.mutex.Unlock()
+		return true
+	}
+
+	tenant := primary.Tenant
+	port, err := m.portAllocator.AllocatePort()
+	if err != nil {
+		m.mutex.Unlock()
+		logging.LogStandbyFailed(tenantName, 0, err)
+		return true
+	}
+	app := newWebApp(tenant, port)
+	app.standby = true
+	app.onCrash = func() { m.instanceExited(tenantName, pair, app) }
+	pair.app = app
+	processStarter := m.processStarter
+	m.mutex.Unlock()
+
+	// A standby that can't start is retried at the next check. It boots in
+	// turn with the primaries, so standbys don't add to a startup stampede.
+	release := m.acquireStartup()
+	err = processStarter.StartWebApp(app, tenant)
+	release()
+	if err != nil {
+		m.mutex.Lock()
+		if pair.app == app {
+			pair.app = nil
+		}
+		m.mutex.Unlock()
+		logging.LogStandbyFailed(tenantName, port, err)
+		m.stopInstance(app)
+		return true
+	}
+
+	// The pair may have stopped while the standby started
+	m.mutex.RLock()
+	current := pair.app == app
+	m.mutex.RUnlock()
+	if !current {
+		m.stopInstance(app)
+		return true
+	}
+	logging.LogStandbyReady(tenantName, port)
+	return true
+}
+
+// checkStandby health checks a tenant's primary and standby. The standby
+// takes over once the primary fails StandbyHealthCheckFailures checks in a
+// row; a standby that fails a check is replaced.
+func (m *AppManager) checkStandby(tenantName string, pair *standbyPair, failures *int) {
+	m.mutex.RLock()
+	primary, standby := m.apps[tenantName], pair.app
+	processStarter := m.processStarter
+	m.mutex.RUnlock()
+
+	if primary != nil && primary.ready() {
+		if err := processStarter.checkHealth(primary); err != nil {
+			*failures++
+			if *failures >= config.StandbyHealthCheckFailures {
+				primary.setState(AppUnhealthy, err.Error())
+			}
+		} else {
+			*failures = 0
+			if primary.State() == AppUnhealthy {
+				primary.setState(AppHealthy, "health check passed")
+			}
+		}
+		if *failures >= config.StandbyHealthCheckFailures && m.failover(tenantName, primary, "health_check") {
+			*failures = 0
+		}
+	}
+	if standby != nil && standby.ready() {
+		if err := processStarter.checkHealth(standby); err != nil {
+			m.discardStandby(tenantName, pair, standby, err)
+		}
+	}
+}
+
+// checkHealth requests an instance's health check endpoint. Unlike the
+// readiness check at startup, a 5xx response counts as a failure.
+func (ps *ProcessStarter) checkHealth(app *WebApp) error {
+	client := &http.Client{Timeout: config.StandbyHealthCheckTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d%s", app.Port, ps.getHealthCheckEndpoint(app.Tenant)))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return nil
+}
+
+// promoteStandby makes a tenant's ready standby its primary and returns it,
+// or returns nil if there's no ready standby. Called with m.mutex held.
+func (m *AppManager) promoteStandby(tenantName string) *WebApp {
+	pair := m.standbys[tenantName]
+	if pair == nil || pair.app == nil || !pair.app.ready() || pair.app.Exited() {
+		return nil
+	}
+	standby := pair.app
+	pair.app = nil
+	pair.failovers++
+	standby.mutex.Lock()
+	standby.LastActivity = time.Now()
+	standby.mutex.Unlock()
+	m.apps[tenantName] = standby
+	return standby
+}
+
+// failover flips routing from a primary that failed its health checks to the
+// standby. Returns false if there's no ready standby, or failed is no longer
+// the primary.
+func (m *AppManager) failover(tenantName string, failed *WebApp, reason string) bool {
+	m.mutex.Lock()
+	if m.apps[tenantName] != failed || failed.removed {
+		m.mutex.Unlock()
+		return false
+	}
+	promoted := m.promoteStandby(tenantName)
+	if promoted == nil {
+		m.mutex.Unlock()
+		return false
+	}
+	failed.removed = true
+	m.mutex.Unlock()
+
+	m.portAllocator.ReleasePort(failed.Port)
+	m.finishFailover(tenantName, failed, promoted, reason)
+	return true
+}
+
+// finishFailover reports a standby's takeover, stops the failed primary and
+// closes its WebSockets so clients reconnect to the standby, and starts a
+// replacement standby
+func (m *AppManager) finishFailover(tenantName string, failed, promoted *WebApp, reason string) {
+	logging.LogStandbyPromoted(tenantName, failed.Port, promoted.Port, reason)
+	events.Emit(events.TenantFailover, map[string]interface{}{
+		"tenant":      tenantName,
+		"reason":      reason,
+		"port":        promoted.Port,
+		"failed_port": failed.Port,
+	})
+
+	failed.setState(AppStopped, "replaced by its standby")
+	if failed.cancel != nil {
+		failed.cancel()
+	}
+	failed.CloseWebSockets()
+
+	m.mutex.RLock()
+	pair := m.standbys[tenantName]
+	m.mutex.RUnlock()
+	if pair != nil {
+		select {
+		case pair.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// instanceExited handles the unrequested exit of an instance of a tenant
+// with a standby: the standby is replaced, and a primary is a crash
+func (m *AppManager) instanceExited(tenantName string, pair *standbyPair, app *WebApp) {
+	m.mutex.RLock()
+	isStandby := pair.app == app
+	m.mutex.RUnlock()
+	if isStandby {
+		m.discardStandby(tenantName, pair, app, errStandbyExited)
+		return
+	}
+	m.removeCrashedApp(tenantName, app)
+}
+
+// discardStandby stops a standby that failed and has the monitor start a
+// replacement
+func (m *AppManager) discardStandby(tenantName string, pair *standbyPair, app *WebApp, err error) {
+	m.mutex.Lock()
+	if pair.app != app {
+		m.mutex.Unlock()
+		return
+	}
+	pair.app = nil
+	m.mutex.Unlock()
+
+	logging.LogStandbyFailed(tenantName, app.Port, err)
+	m.stopInstance(app)
+	select {
+	case pair.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stopStandby stops a tenant's standby, if it has one, with its primary
+func (m *AppManager) stopStandby(tenantName string) {
+	m.mutex.Lock()
+	standby := m.takeStandby(tenantName)
+	m.mutex.Unlock()
+	m.stopInstance(standby)
+}
+
+// takeStandby removes a tenant's standby pair, stopping its monitor, and
+// returns the standby instance for the caller to stop. Called with m.mutex
+// held.
+func (m *AppManager) takeStandby(tenantName string) *WebApp {
+	pair := m.standbys[tenantName]
+	if pair == nil {
+		return nil
+	}
+	delete(m.standbys, tenantName)
+	close(pair.stop)
+	standby := pair.app
+	pair.app = nil
+	return standby
+}
+
+// stopInstance stops an instance that isn't registered as a primary and
+// releases its port
+func (m *AppManager) stopInstance(app *WebApp) {
+	if app == nil {
+		return
+	}
+	app.setState(AppStopped, "stopped")
+	if app.cancel != nil {
+		app.cancel()
+	}
+	m.portAllocator.ReleasePort(app.Port)
+}
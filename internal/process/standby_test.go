@@ -0,0 +1,98 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// TestStandbyTenantProcess isn't a real test: run as a tenant with
+// NAVIGATOR_STANDBY_TENANT=1, it serves its PID on $PORT, failing with 503
+// once a file named unhealthy-$PORT exists in its working directory.
+func TestStandbyTenantProcess(t *testing.T) {
+	if os.Getenv("NAVIGATOR_STANDBY_TENANT") != "1" {
+		return
+	}
+	port := os.Getenv("PORT")
+	_ = http.ListenAndServe("localhost:"+port, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat("unhealthy-" + port); err == nil {
+			http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "pid %d", os.Getpid())
+	}))
+	os.Exit(1)
+}
+
+func TestStandbyFailoverOnHealthCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	root := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 4800
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name:    "boston",
+		Root:    root,
+		Runtime: os.Args[0],
+		Server:  "-test.run=^TestStandbyTenantProcess$",
+		Args:    []string{"-test.count=1"},
+		Env:     map[string]string{"NAVIGATOR_STANDBY_TENANT": "1"},
+		Standby: true,
+	}}
+	m := NewAppManager(cfg)
+	t.Cleanup(m.Cleanup)
+
+	primary, err := m.GetOrStartApp("boston")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	var standbyPort int
+	deadline := time.Now().Add(10 * time.Second)
+	for standbyPort == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		standbyPort = m.Status()[0].StandbyPort
+	}
+	if standbyPort == 0 || standbyPort == primary.Port {
+		t.Fatalf("Standby port = %d, primary port = %d", standbyPort, primary.Port)
+	}
+
+	// A WebSocket client of the primary, to be disconnected by the failover
+	client, server := net.Pipe()
+	defer client.Close()
+	primary.RegisterWebSocketConnection("client", server)
+
+	m.mutex.RLock()
+	pair := m.standbys["boston"]
+	m.mutex.RUnlock()
+	if err := os.WriteFile(filepath.Join(root, fmt.Sprintf("unhealthy-%d", primary.Port)), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	failures := 0
+	for i := 0; i < config.StandbyHealthCheckFailures; i++ {
+		if current, _ := m.GetApp("boston"); current != primary {
+			t.Fatalf("Failover after %d failed checks, want %d", i, config.StandbyHealthCheckFailures)
+		}
+		m.checkStandby("boston", pair, &failures)
+	}
+
+	current, _ := m.GetApp("boston")
+	if current.Port != standbyPort {
+		t.Errorf("Serving port %d after failover, want the standby's port %d", current.Port, standbyPort)
+	}
+	if status := m.Status()[0]; status.Failovers != 1 {
+		t.Errorf("Failovers = %d, want 1", status.Failovers)
+	}
+	if !primary.WaitExited(10 * time.Second) {
+		t.Error("Failed primary was not stopped")
+	}
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Error("WebSocket connection to the failed primary is still open")
+	}
+}
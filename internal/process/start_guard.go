@@ -0,0 +1,259 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// ErrStartGuardHeld is returned when a tenant isn't started because its
+// start guard is held by another machine or process
+var ErrStartGuardHeld = errors.New("start guard held elsewhere")
+
+// startGuard is a lock a tenant must hold while its app runs
+type startGuard interface {
+	name() string // Lock file or lease URL, for logs and errors
+	acquire() (held bool, holder string, err error)
+	renewal() time.Duration // How often renew must be called; 0 = never
+	expiry() time.Duration  // How long the guard is held without a renewal; 0 = until released
+	renew() (lost bool, holder string, err error)
+	release()
+}
+
+// startGuardHolder identifies this machine to other holders: the Fly
+// machine ID, or the hostname elsewhere
+func startGuardHolder() string {
+	if machineID := os.Getenv("FLY_MACHINE_ID"); machineID != "" {
+		return machineID
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "unknown"
+}
+
+// acquireStartGuard acquires a tenant's start guard, if it has one. A guard
+// held elsewhere is logged and reported as ErrStartGuardHeld, naming the
+// holder when it's known.
+func acquireStartGuard(guard startGuard, tenantName string) error {
+	if guard == nil {
+		return nil
+	}
+	held, holder, err := guard.acquire()
+	if !held {
+		return err
+	}
+	logging.LogStartGuardHeld(tenantName, guard.name(), holder)
+	if holder == "" {
+		return fmt.Errorf("%w: %s", ErrStartGuardHeld, guard.name())
+	}
+	return fmt.Errorf("%w: %s is held by %s", ErrStartGuardHeld, guard.name(), holder)
+}
+
+// newStartGuard returns the tenant's start guard, or nil if it has none
+func newStartGuard(tenant *config.Tenant) startGuard {
+	guard := tenant.StartGuard
+	if guard == nil {
+		return nil
+	}
+	if guard.Type == config.StartGuardHTTP {
+		return &leaseGuard{
+			url:    guard.URL,
+			tenant: tenant.Name,
+			holder: startGuardHolder(),
+			ttl:    guard.ResolvedTTL(),
+			client: &http.Client{Timeout: config.StartGuardRequestTimeout},
+		}
+	}
+	return &fileGuard{path: guard.LockPath(tenant.Root), holder: startGuardHolder()}
+}
+
+// fileGuard is an advisory lock on a file, released by the operating system
+// if Navigator dies. The holder's identity is written to the file.
+type fileGuard struct {
+	path   string
+	holder string
+	file   *os.File
+}
+
+func (g *fileGuard) name() string                 { return g.path }
+func (g *fileGuard) renewal() time.Duration       { return 0 }
+func (g *fileGuard) expiry() time.Duration        { return 0 }
+func (g *fileGuard) renew() (bool, string, error) { return false, "", nil }
+
+func (g *fileGuard) acquire() (bool, string, error) {
+	if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+		return false, "", fmt.Errorf("start guard %s: %w", g.path, err)
+	}
+	file, err := os.OpenFile(g.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, "", fmt.Errorf("start guard %s: %w", g.path, err)
+	}
+	if err := utils.LockFile(file); err != nil {
+		holder, _ := io.ReadAll(io.LimitReader(file, 256))
+		_ = file.Close()
+		if errors.Is(err, utils.ErrFileLocked) {
+			return true, strings.TrimSpace(string(holder)), nil
+		}
+		return false, "", fmt.Errorf("start guard %s: %w", g.path, err)
+	}
+	_ = file.Truncate(0)
+	_, _ = file.WriteAt([]byte(fmt.Sprintf("%s pid %d\n", g.holder, os.Getpid())), 0)
+	g.file = file
+	return false, "", nil
+}
+
+func (g *fileGuard) release() {
+	if g.file == nil {
+		return
+	}
+	_ = g.file.Truncate(0)
+	_ = g.file.Close() // Closing the file releases the lock
+	g.file = nil
+}
+
+// leaseGuard is a lease from an HTTP endpoint. PUT with the tenant, holder,
+// and ttl in seconds acquires or renews it; 409 Conflict means another
+// holder has it, named by the response's "holder" field. DELETE releases it.
+type leaseGuard struct {
+	url    string
+	tenant string
+	holder string
+	ttl    time.Duration
+	client *http.Client
+
+	mutex sync.Mutex // Keeps a renewal from following the release
+	done  bool       // Released, or lost to another holder
+}
+
+func (g *leaseGuard) name() string           { return g.url }
+func (g *leaseGuard) renewal() time.Duration { return g.ttl / 3 }
+func (g *leaseGuard) expiry() time.Duration  { return g.ttl }
+
+func (g *leaseGuard) acquire() (bool, string, error) {
+	return g.request(http.MethodPut)
+}
+
+func (g *leaseGuard) renew() (bool, string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.done {
+		return false, "", nil
+	}
+	lost, holder, err := g.request(http.MethodPut)
+	g.done = lost
+	return lost, holder, err
+}
+
+func (g *leaseGuard) release() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if !g.done {
+		g.done = true
+		_, _, _ = g.request(http.MethodDelete)
+	}
+}
+
+// request sends a lease request. held reports that another holder has the
+// lease, named by holder when the endpoint says who it is.
+func (g *leaseGuard) request(method string) (held bool, holder string, err error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"tenant": g.tenant,
+		"holder": g.holder,
+		"ttl":    int(g.ttl.Seconds()),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), config.StartGuardRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, g.url, bytes.NewReader(body))
+	if err != nil {
+		return false, "", fmt.Errorf("start guard %s: %w", g.url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("start guard %s: %w", g.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		var lease struct {
+			Holder string `json:"holder"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&lease)
+		return true, lease.Holder, nil
+	case resp.StatusCode >= 300:
+		return false, "", fmt.Errorf("start guard %s: %s", g.url, resp.Status)
+	}
+	return false, "", nil
+}
+
+// renewStartGuard renews a lease until the app exits. If another holder
+// takes it, onLost is called so the app stops. Failed renewals are retried
+// while the lease lasts; once it would expire before the next renewal,
+// another holder may take it at any time, so onLost is called then too.
+func renewStartGuard(guard startGuard, app *WebApp, tenantName string, onLost func()) {
+	ticker := time.NewTicker(guard.renewal())
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-app.exited:
+			return
+		case <-ticker.C:
+			lost, holder, err := guard.renew()
+			if lost {
+				logging.LogStartGuardLost(tenantName, guard.name(), holder)
+				onLost()
+				return
+			}
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			logging.LogStartGuardRenewFailed(tenantName, guard.name(), err)
+			if expiry := guard.expiry(); expiry > 0 && time.Since(renewed)+guard.renewal() >= expiry {
+				logging.LogStartGuardExpired(tenantName, guard.name(), renewed.Add(expiry))
+				onLost()
+				return
+			}
+		}
+	}
+}
+
+// stopForLostGuard stops an app whose start guard was taken by another
+// holder, or couldn't be renewed before it expired, and removes it, so the next request tries to acquire the guard again
+func (m *AppManager) stopForLostGuard(tenantName string, app *WebApp) {
+	app.setState(AppStopped, "start guard lost")
+	if app.cancel != nil {
+		app.cancel()
+	}
+	m.mutex.Lock()
+	registered := m.apps[tenantName] == app
+	if registered {
+		delete(m.apps, tenantName)
+	}
+	m.mutex.Unlock()
+	if registered {
+		m.portAllocator.ReleasePort(app.Port)
+	}
+
+	events.Emit(events.TenantStopped, map[string]interface{}{
+		"tenant": tenantName,
+		"reason": "start_guard",
+	})
+}
@@ -0,0 +1,205 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestFileStartGuardContention(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("NAVIGATOR_TEST_SKIP_READINESS", "true")
+	t.Setenv("FLY_MACHINE_ID", "148ed193b95d89")
+
+	// Two machines sharing one volume
+	root := t.TempDir()
+	newManager := func(startPort int) *AppManager {
+		cfg := &config.Config{}
+		cfg.Applications.Pools.StartPort = startPort
+		cfg.Applications.Tenants = []config.Tenant{{
+			Name:       "boston",
+			Root:       root,
+			Runtime:    "sh",
+			Server:     "-c",
+			Args:       []string{"exec sleep 30"},
+			StartGuard: &config.StartGuardConfig{Type: config.StartGuardFile, Path: config.DefaultStartGuardPath},
+		}}
+		m := NewAppManager(cfg)
+		t.Cleanup(m.Cleanup)
+		return m
+	}
+	first, second := newManager(4700), newManager(4750)
+
+	if _, err := first.GetOrStartApp("boston"); err != nil {
+		t.Fatalf("First start failed: %v", err)
+	}
+	_, err := second.GetOrStartApp("boston")
+	if !errors.Is(err, ErrStartGuardHeld) {
+		t.Fatalf("Second start error = %v, want ErrStartGuardHeld", err)
+	}
+	if !strings.Contains(err.Error(), "148ed193b95d89") {
+		t.Errorf("Error %q should name the holder", err)
+	}
+	if _, ok := second.GetApp("boston"); ok {
+		t.Error("Refused app should not be registered")
+	}
+
+	// Once the first machine's app has exited, the second can start it
+	app, _ := first.GetApp("boston")
+	first.Cleanup()
+	if !app.WaitExited(10 * time.Second) {
+		t.Fatal("First app did not exit")
+	}
+	if _, err := second.GetOrStartApp("boston"); err != nil {
+		t.Errorf("Start after release failed: %v", err)
+	}
+}
+
+// leaseServer is a start guard endpoint granting one holder at a time
+type leaseServer struct {
+	mutex    sync.Mutex
+	holder   string
+	requests []string
+}
+
+func (s *leaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var lease struct {
+		Tenant string `json:"tenant"`
+		Holder string `json:"holder"`
+		TTL    int    `json:"ttl"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&lease)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, r.Method+" "+lease.Tenant)
+	switch {
+	case s.holder != "" && s.holder != lease.Holder:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]string{"holder": s.holder})
+	case r.Method == http.MethodDelete:
+		s.holder = ""
+	default:
+		s.holder = lease.Holder
+	}
+}
+
+func (s *leaseServer) setHolder(holder string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.holder = holder
+}
+
+func TestLeaseStartGuard(t *testing.T) {
+	t.Setenv("FLY_MACHINE_ID", "148ed193b95d89")
+	leases := &leaseServer{}
+	server := httptest.NewServer(leases)
+	defer server.Close()
+
+	tenant := &config.Tenant{Name: "boston", StartGuard: &config.StartGuardConfig{
+		Type: config.StartGuardHTTP,
+		URL:  server.URL,
+		TTL:  config.Duration(30 * time.Millisecond),
+	}}
+
+	// Held by another machine
+	leases.setHolder("3d8d9930b24d89")
+	err := acquireStartGuard(newStartGuard(tenant), tenant.Name)
+	if !errors.Is(err, ErrStartGuardHeld) || !strings.Contains(err.Error(), "3d8d9930b24d89") {
+		t.Fatalf("Acquire error = %v, want held by 3d8d9930b24d89", err)
+	}
+
+	// Acquired and renewed until another machine takes it over
+	leases.setHolder("")
+	guard := newStartGuard(tenant)
+	if err := acquireStartGuard(guard, tenant.Name); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	app := &WebApp{exited: make(chan struct{})}
+	lost := make(chan struct{})
+	go renewStartGuard(guard, app, tenant.Name, func() { close(lost) })
+	time.Sleep(50 * time.Millisecond)
+	leases.setHolder("3d8d9930b24d89")
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lost lease was not noticed")
+	}
+
+	// A lost lease isn't released, so the new holder keeps it
+	guard.release()
+	leases.mutex.Lock()
+	defer leases.mutex.Unlock()
+	if leases.holder != "3d8d9930b24d89" {
+		t.Errorf("Holder = %q after release of a lost lease", leases.holder)
+	}
+	for _, request := range leases.requests {
+		if request != "PUT boston" {
+			t.Errorf("Unexpected request %q", request)
+		}
+	}
+}
+
+func TestLeaseStartGuardExpiresWithoutRenewal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tenant := &config.Tenant{Name: "boston", StartGuard: &config.StartGuardConfig{
+		Type: config.StartGuardHTTP,
+		URL:  server.URL,
+		TTL:  config.Duration(30 * time.Millisecond),
+	}}
+	app := &WebApp{exited: make(chan struct{})}
+	lost := make(chan struct{})
+	start := time.Now()
+	go renewStartGuard(newStartGuard(tenant), app, tenant.Name, func() { close(lost) })
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("App kept running on a lease that could not be renewed")
+	}
+	if elapsed := time.Since(start); elapsed >= 30*time.Millisecond {
+		t.Errorf("Stopped after %v, want before the lease expired", elapsed)
+	}
+}
+
+func TestQueuedStartReportsStartGuardHeld(t *testing.T) {
+	t.Setenv("FLY_MACHINE_ID", "148ed193b95d89")
+	leases := &leaseServer{holder: "3d8d9930b24d89"}
+	server := httptest.NewServer(leases)
+	defer server.Close()
+
+	// tenant1 waits for tenant0's startup slot, then finds its guard held
+	m := newStartupManager(t, 4960, 1, 200*time.Millisecond, 2)
+	m.config.Applications.Tenants[1].StartGuard = &config.StartGuardConfig{
+		Type: config.StartGuardHTTP,
+		URL:  server.URL,
+		TTL:  config.Duration(time.Minute),
+	}
+	go func() { _, _, _ = m.GetOrStartAppForRequest("tenant0") }()
+	time.Sleep(50 * time.Millisecond)
+	app, _, err := m.GetOrStartAppForRequest("tenant1")
+	if err != nil {
+		t.Fatalf("Queued start returned %v; the error should come once it boots", err)
+	}
+	select {
+	case <-app.ReadyChan():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Requests waiting for the queued start were never released")
+	}
+	if err := app.StartError(); !errors.Is(err, ErrStartGuardHeld) {
+		t.Errorf("StartError() = %v, want ErrStartGuardHeld", err)
+	}
+}
@@ -0,0 +1,120 @@
+package process
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// startupConcurrency returns how many tenant apps may boot at once
+func startupConcurrency(cfg *config.Config) int {
+	if cfg.Applications.Pools.MaxConcurrentStartups > 0 {
+		return cfg.Applications.Pools.MaxConcurrentStartups
+	}
+	return config.DefaultMaxConcurrentStartups
+}
+
+// acquireStartup waits for a startup slot, behind the startups queued
+// before it, and returns the function that frees it
+func (m *AppManager) acquireStartup() (release func()) {
+	m.mutex.RLock()
+	limit := startupConcurrency(m.config)
+	m.mutex.RUnlock()
+
+	release, wait := m.startups.Reserve(limit)
+	if release == nil {
+		release = wait()
+	}
+	return release
+}
+
+// StartupQueueDepth returns how many app startups are waiting for a slot
+func (m *AppManager) StartupQueueDepth() int {
+	_, queued, _ := m.startups.Stats()
+	return queued
+}
+
+// startQueued boots an app whose start is queued once wait returns its
+// startup slot, unless the app was stopped while it waited
+func (m *AppManager) startQueued(tenantName string, app *WebApp, tenant *config.Tenant, processStarter *ProcessStarter, wait func() func()) {
+	release := wait()
+	defer release()
+
+	waited := app.dequeueStartup()
+	m.mutex.RLock()
+	current := m.apps[tenantName] == app
+	m.mutex.RUnlock()
+	if !current {
+		return
+	}
+	logging.LogWebAppStartupDequeued(tenantName, waited)
+
+	// Requests waiting for the app time out on their own
+	if err := m.bootApp(tenantName, app, tenant, processStarter); err != nil {
+		slog.Error("Failed to start web app", "tenant", tenantName, "error", err)
+	}
+}
+
+// bootApp starts a registered app and waits for it to be ready. An app that
+// fails to start is removed. One stopped while it started has already given
+// up its port, but its process may have been spawned after the stop.
+func (m *AppManager) bootApp(tenantName string, app *WebApp, tenant *config.Tenant, processStarter *ProcessStarter) error {
+	err := processStarter.StartWebApp(app, tenant)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.apps[tenantName] != app {
+		if app.cancel != nil {
+			app.cancel()
+		}
+		return err
+	}
+	if err != nil {
+		// Requests waiting for the app, such as those queued behind a
+		// startup slot, get the error rather than waiting out their timeout
+		app.failStart(err)
+		delete(m.apps, tenantName)
+		m.portAllocator.ReleasePort(app.Port)
+		return err
+	}
+
+	// Check the app for idleness with all the others
+	m.idle.schedule(tenantName)
+
+	// Keep a warm standby ready to take over
+	if tenant.Standby {
+		m.trackStandby(tenantName)
+	}
+	return nil
+}
+
+// queueStartup records that the app's start is waiting for a startup slot
+func (w *WebApp) queueStartup() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.queuedSince = time.Now()
+}
+
+// dequeueStartup records that the app's start has its startup slot, and
+// returns how long it waited
+func (w *WebApp) dequeueStartup() time.Duration {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.startupQueued = time.Since(w.queuedSince)
+	w.queuedSince = time.Time{}
+	return w.startupQueued
+}
+
+// StartupQueued returns how long the app's start waited, or has waited so
+// far, for a startup slot, and whether it is still waiting
+func (w *WebApp) StartupQueued() (waited time.Duration, queued bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !w.queuedSince.IsZero() {
+		return time.Since(w.queuedSince), true
+	}
+	return w.startupQueued, false
+}
@@ -0,0 +1,140 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// newStartupManager returns a manager for echo tenants whose start hook
+// sleeps for bootDelay, with at most limit of them booting at once
+func newStartupManager(t *testing.T, startPort, limit int, bootDelay time.Duration, count int) *AppManager {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = startPort
+	cfg.Applications.Pools.MaxConcurrentStartups = limit
+	cfg.Applications.Hooks.Start = []config.HookConfig{{Command: "sleep", Args: []string{fmt.Sprint(bootDelay.Seconds())}}}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("tenant%d", i)
+		cfg.Applications.Tenants = append(cfg.Applications.Tenants, config.Tenant{
+			Name:      name,
+			Path:      "/" + name + "/",
+			Framework: config.RuntimeInternalEcho,
+		})
+	}
+	m := NewAppManager(cfg)
+	t.Cleanup(m.Cleanup)
+	return m
+}
+
+func TestMaxConcurrentStartupsIsNeverExceeded(t *testing.T) {
+	const limit, tenants = 2, 6
+	m := newStartupManager(t, 4890, limit, 200*time.Millisecond, tenants)
+
+	// Requests arrive one after another, each for a different cold tenant
+	apps := make([]*WebApp, tenants)
+	var wg sync.WaitGroup
+	for i := range apps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			app, coldStart, err := m.GetOrStartAppForRequest(fmt.Sprintf("tenant%d", i))
+			if err != nil || !coldStart {
+				t.Errorf("GetOrStartAppForRequest(tenant%d) = %v, %v", i, coldStart, err)
+				return
+			}
+			apps[i] = app
+		}(i)
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if depth := m.StartupQueueDepth(); depth != tenants-limit {
+		t.Errorf("StartupQueueDepth() = %d, want %d", depth, tenants-limit)
+	}
+	queued := 0
+	for _, status := range m.Status() {
+		if status.StartupQueued {
+			queued++
+		}
+	}
+	if queued != tenants-limit {
+		t.Errorf("Status() reports %d queued startups, want %d", queued, tenants-limit)
+	}
+
+	wg.Wait()
+	for i, app := range apps {
+		select {
+		case <-app.ReadyChan():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("tenant%d never became ready", i)
+		}
+	}
+
+	// Each boot runs from spawn to ready; no more than limit may overlap
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	var edges []edge
+	for _, app := range apps {
+		edges = append(edges, edge{app.spawned, 1}, edge{app.spawned.Add(app.BootTime()), -1})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+	booting := 0
+	for _, e := range edges {
+		booting += e.delta
+		if booting > limit {
+			t.Fatalf("%d apps booting at once, want at most %d", booting, limit)
+		}
+	}
+
+	// Queued startups boot in arrival order, and report their wait
+	for i := limit + 1; i < tenants; i++ {
+		if apps[i].spawned.Before(apps[i-1].spawned) {
+			t.Errorf("tenant%d booted before tenant%d", i, i-1)
+		}
+	}
+	if waited, queued := apps[tenants-1].StartupQueued(); queued || waited < 200*time.Millisecond {
+		t.Errorf("StartupQueued() = %v, %v, want a wait of at least one boot", waited, queued)
+	}
+	if waited, _ := apps[0].StartupQueued(); waited != 0 {
+		t.Errorf("tenant0 StartupQueued() = %v, want no wait", waited)
+	}
+	if depth := m.StartupQueueDepth(); depth != 0 {
+		t.Errorf("StartupQueueDepth() = %d once all started, want 0", depth)
+	}
+}
+
+func TestStoppedWhileQueuedStartupNeverBoots(t *testing.T) {
+	m := newStartupManager(t, 4900, 1, 200*time.Millisecond, 2)
+
+	go func() { _, _ = m.GetOrStartApp("tenant0") }()
+	time.Sleep(20 * time.Millisecond)
+	app, err := m.GetOrStartApp("tenant1")
+	if err != nil {
+		t.Fatalf("GetOrStartApp(tenant1) error = %v", err)
+	}
+	if _, queued := app.StartupQueued(); !queued {
+		t.Fatal("tenant1 should wait for tenant0 to boot")
+	}
+	if !m.StopApp("tenant1", "test") {
+		t.Fatal("StopApp(tenant1) = false")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if _, exists := m.GetApp("tenant1"); exists {
+		t.Error("tenant1 was started after being stopped")
+	}
+	if serving(app.Port) {
+		t.Error("tenant1 is serving after being stopped")
+	}
+}
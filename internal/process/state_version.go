@@ -0,0 +1,19 @@
+package process
+
+import "sync/atomic"
+
+// stateVersion counts changes to the state Navigator reports about itself
+var stateVersion atomic.Uint64
+
+// StateVersion returns a number that increases whenever a tenant app changes
+// state, a managed process starts or exits, or anything else calling
+// BumpStateVersion changes. Equal versions mean nothing reported changed, so
+// the detailed health check uses it as its ETag.
+func StateVersion() uint64 {
+	return stateVersion.Load()
+}
+
+// BumpStateVersion records a change to the reported state
+func BumpStateVersion() {
+	stateVersion.Add(1)
+}
@@ -0,0 +1,122 @@
+package process
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestTenantLogFilesAreSeparate(t *testing.T) {
+	dir := t.TempDir()
+	logConfig := config.LogConfig{
+		Format:     "text",
+		File:       filepath.Join(dir, "navigator.log"),
+		TenantFile: filepath.Join(dir, "tenants", "{{tenant}}.log"),
+	}
+
+	for _, tenant := range []string{"2025/boston", "2025/raleigh"} {
+		stdout := CreateTenantLogWriter(tenant, "stdout", logConfig, nil)
+		stderr := CreateTenantLogWriter(tenant, "stderr", logConfig, nil)
+		_, _ = stdout.Write([]byte("out from " + tenant + "\n"))
+		_, _ = stderr.Write([]byte("err from " + tenant + "\n"))
+	}
+
+	for tenant, other := range map[string]string{"2025/boston": "2025/raleigh", "2025/raleigh": "2025/boston"} {
+		data, err := os.ReadFile(filepath.Join(dir, "tenants", config.TenantFileName(tenant)+".log"))
+		if err != nil {
+			t.Fatalf("tenant file for %s: %v", tenant, err)
+		}
+		content := string(data)
+		for _, line := range []string{"out from " + tenant, "err from " + tenant} {
+			if !strings.Contains(content, line) {
+				t.Errorf("%s's file lacks %q:\n%s", tenant, line, content)
+			}
+		}
+		if strings.Contains(content, other) {
+			t.Errorf("%s's file has %s's output:\n%s", tenant, other, content)
+		}
+	}
+
+	if _, err := os.Stat(logConfig.File); !os.IsNotExist(err) {
+		t.Errorf("tenant output written to logging.file too (stat error = %v)", err)
+	}
+}
+
+func TestTenantLogRequestIDPrefix(t *testing.T) {
+	requestID := ""
+	lastRequestID := func() string { return requestID }
+
+	tests := []struct {
+		name   string
+		format string
+		prefix bool
+		check  func(t *testing.T, lines []string)
+	}{
+		{
+			name:   "text",
+			format: "text",
+			prefix: true,
+			check: func(t *testing.T, lines []string) {
+				if lines[0] != "[boston.stdout] before" {
+					t.Errorf("line before any request = %q, want no request id", lines[0])
+				}
+				if lines[1] != "[boston.stdout] [request_id=abc123] after" {
+					t.Errorf("line after a request = %q, want it marked with abc123", lines[1])
+				}
+			},
+		},
+		{
+			name:   "json",
+			format: "json",
+			prefix: true,
+			check: func(t *testing.T, lines []string) {
+				var before, after LogEntry
+				_ = json.Unmarshal([]byte(lines[0]), &before)
+				_ = json.Unmarshal([]byte(lines[1]), &after)
+				if before.RequestID != "" || after.RequestID != "abc123" {
+					t.Errorf("request ids = %q, %q, want none then abc123", before.RequestID, after.RequestID)
+				}
+			},
+		},
+		{
+			name:   "not configured",
+			format: "text",
+			prefix: false,
+			check: func(t *testing.T, lines []string) {
+				if strings.Contains(lines[1], "request_id") {
+					t.Errorf("line marked without request_id_prefix: %q", lines[1])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestID = ""
+			file := filepath.Join(t.TempDir(), "{{tenant}}.log")
+			writer := CreateTenantLogWriter("boston", "stdout", config.LogConfig{
+				Format:          tt.format,
+				TenantFile:      file,
+				RequestIDPrefix: tt.prefix,
+			}, lastRequestID)
+
+			_, _ = writer.Write([]byte("before\n"))
+			requestID = "abc123"
+			_, _ = writer.Write([]byte("after\n"))
+
+			data, err := os.ReadFile(strings.ReplaceAll(file, "{{tenant}}", "boston"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d lines, want 2:\n%s", len(lines), data)
+			}
+			tt.check(t, lines)
+		})
+	}
+}
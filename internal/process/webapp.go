@@ -3,10 +3,12 @@ package process
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/url"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,9 +16,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/events"
 	"github.com/rubys/navigator/internal/logging"
-	"github.com/rubys/navigator/internal/utils"
 )
 
 // WebApp represents a web application instance
@@ -27,20 +30,67 @@ type WebApp struct {
 	Port             int
 	StartTime        time.Time
 	LastActivity     time.Time
-	Starting         bool          // True while app is starting up
-	Stopping         bool          // True while app is shutting down
-	readyChan        chan struct{} // Closed when app is ready to accept requests
+	readyChan        chan struct{} // Closed once the app has finished starting, whether or not it became healthy
+	startErr         error         // Why the app failed to start, set before readyChan is closed; guarded by mutex
 	mutex            sync.Mutex
 	cancel           context.CancelFunc
 	wsConnections    map[string]interface{}
 	wsConnectionsMux sync.RWMutex
-	activeWebSockets int32 // Atomic counter for active WebSocket connections
+	activeWebSockets int32                       // Atomic counter for active WebSocket connections
+	websockets       map[*WebSocketActivity]bool // Tracked connections and their client activity; guarded by wsConnectionsMux
+	clock            clock.Clock                 // Clock for idle checks and recycling (nil = real time)
+	requests         RequestLimiter
+
+	// Lifecycle; see AppState
+	state       AppState
+	transitions []AppTransition // Most recent state changes, oldest first
+	idleDrain   bool            // Draining because the app is idle; a request cancels it
+
+	// Crash tracking
+	exited  chan struct{} // Closed when the process has exited
+	removed bool          // Set once the crash has removed the app; guarded by AppManager.mutex
+	onCrash func()        // Called when the process exits without being stopped
+
+	onGuardLost func() // Called when the start guard lease is taken by another holder
+
+	standby bool // Started as a warm standby: no start hooks or tenant.started event
+
+	spawned  time.Time     // When the process was spawned
+	bootTime time.Duration // From spawn to the first successful health check; set once ready
+
+	queuedSince   time.Time     // When the start began waiting for a startup slot; zero once it has one
+	startupQueued time.Duration // How long the start waited for a startup slot
 
 	// Memory limit tracking (Linux only)
 	CgroupPath  string    // Cgroup path for memory limiting (Linux only)
 	MemoryLimit int64     // Memory limit in bytes (0 = no limit)
 	OOMCount    int       // Number of times this tenant has been OOM killed
 	LastOOMTime time.Time // Timestamp of last OOM kill
+
+	Priority *config.PriorityConfig // CPU, I/O, and OOM priority applied to the process (Linux only)
+
+	// Recycling
+	inFlight  atomic.Int32 // Requests being proxied to the instance, apart from WebSockets
+	served    atomic.Int64 // Requests the instance has served
+	recycling bool         // Set once a recycle of the instance has begun; guarded by mutex
+	recycled  bool         // Started to replace a recycled instance: no start hooks or tenant.started event
+
+	lastRequestID atomic.Pointer[string] // ID of the request most recently proxied to the instance
+}
+
+// NoteRequest records the ID of a request being proxied to the app, which
+// its output is marked with when logging.request_id_prefix is set
+func (w *WebApp) NoteRequest(requestID string) {
+	w.lastRequestID.Store(&requestID)
+}
+
+// LastRequestID returns the ID of the request most recently proxied to the
+// app. With requests in parallel, output may belong to an earlier one.
+func (w *WebApp) LastRequestID() string {
+	if id := w.lastRequestID.Load(); id != nil {
+		return *id
+	}
+	return ""
 }
 
 // ReadyChan returns the channel that's closed when the app is ready
@@ -48,6 +98,35 @@ func (w *WebApp) ReadyChan() <-chan struct{} {
 	return w.readyChan
 }
 
+// StartError returns why the app failed to start, once ReadyChan is closed,
+// or nil if it started
+func (w *WebApp) StartError() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.startErr
+}
+
+// failStart records why the app failed to start and releases the requests
+// waiting for it, unless starting had already finished
+func (w *WebApp) failStart(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	select {
+	case <-w.readyChan:
+	default:
+		w.startErr = err
+		close(w.readyChan)
+	}
+}
+
+// BootTime returns how long the app took from spawning its process to
+// answering its first health check, or zero until it's ready
+func (w *WebApp) BootTime() time.Duration {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.bootTime
+}
+
 // GetActiveWebSocketsPtr returns a pointer to the atomic WebSocket counter
 // This allows external packages to track WebSocket connections using atomic operations
 func (w *WebApp) GetActiveWebSocketsPtr() *int32 {
@@ -59,6 +138,11 @@ func (w *WebApp) GetActiveWebSocketCount() int32 {
 	return atomic.LoadInt32(&w.activeWebSockets)
 }
 
+// Requests returns the limiter bounding concurrent requests to this app
+func (w *WebApp) Requests() *RequestLimiter {
+	return &w.requests
+}
+
 // ShouldTrackWebSockets returns whether WebSocket tracking is enabled for this app
 // It checks the tenant-specific setting first, then falls back to the global setting
 func (w *WebApp) ShouldTrackWebSockets(globalSetting bool) bool {
@@ -74,222 +158,352 @@ type AppManager struct {
 	config         *config.Config
 	processStarter *ProcessStarter
 	portAllocator  *PortAllocator
+	portState      *portState
 	mutex          sync.RWMutex
 	idleTimeout    time.Duration
+	idle           *idleScheduler // Runs idle and OOM checks for all apps
+	stubPort       int            // When set, every tenant is answered by a responder on this port
+	crashes        map[string]*crashHistory
+	standbys       map[string]*standbyPair // Warm standbys of tenants with standby set
+
+	startups RequestLimiter // Bounds the apps booting at once, queuing the rest in arrival order
+
+	recycleSlots chan struct{}   // Holds a token for each recycle in progress
+	recycles     []RecycleRecord // Most recent recycles, oldest first
+	rss          rssReader
 }
 
 // NewAppManager creates a new application manager
 func NewAppManager(cfg *config.Config) *AppManager {
 	// Parse idle timeout from config
-	idleTimeout := utils.ParseDurationWithDefault(cfg.Applications.Pools.Timeout, config.DefaultIdleTimeout)
+	idleTimeout := cfg.Applications.Pools.Timeout.OrDefault(config.DefaultIdleTimeout)
 
-	startPort := cfg.Applications.Pools.StartPort
-	if startPort == 0 {
-		startPort = config.DefaultStartPort
-	}
-
-	return &AppManager{
+	m := &AppManager{
 		apps:           make(map[string]*WebApp),
 		config:         cfg,
 		processStarter: NewProcessStarter(cfg),
-		portAllocator:  NewPortAllocator(startPort, startPort+config.MaxPortRange),
+		portAllocator:  newTenantPortAllocator(cfg),
+		portState:      loadPortState(cfg.Applications.Pools.PortState),
 		idleTimeout:    idleTimeout,
+		crashes:        make(map[string]*crashHistory),
+		standbys:       make(map[string]*standbyPair),
+		recycleSlots:   make(chan struct{}, recycleConcurrency(cfg)),
+		rss:            procRSS{},
 	}
+	m.idle = newIdleScheduler(config.IdleCheckInterval, m.checkIdleApp)
+	return m
 }
 
 // GetOrStartApp gets an existing app or starts a new one
 func (m *AppManager) GetOrStartApp(tenantName string) (*WebApp, error) {
+	app, _, err := m.GetOrStartAppForRequest(tenantName)
+	return app, err
+}
+
+// GetOrStartAppForRequest is GetOrStartApp, also reporting whether this call
+// started the app: the request it serves is a cold start
+func (m *AppManager) GetOrStartAppForRequest(tenantName string) (*WebApp, bool, error) {
 	m.mutex.RLock()
 	app, exists := m.apps[tenantName]
 	m.mutex.RUnlock()
 
 	if exists {
-		app.mutex.Lock()
-		isStopping := app.Stopping
-		app.LastActivity = time.Now()
-		// If app is stopping, cancel the shutdown by clearing the flag
-		if isStopping {
-			app.Stopping = false
-		}
-		app.mutex.Unlock()
+		// A request cancels an idle stop in progress
+		app.touch()
 
 		// Return immediately - let caller handle waiting with their own timeout
 		// This allows the handler to serve maintenance page if startup takes too long
-		return app, nil
+		return app, false, nil
 	}
 
 	// Start new app
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
 
 	// Double-check after acquiring write lock
 	if app, exists := m.apps[tenantName]; exists {
-		app.mutex.Lock()
-		app.LastActivity = time.Now()
-		app.mutex.Unlock()
+		m.mutex.Unlock()
+		app.touch()
 
 		// Return immediately - let caller handle waiting with their own timeout
 		// This allows the handler to serve maintenance page if startup takes too long
-		return app, nil
+		return app, false, nil
 	}
 
 	// Find tenant configuration
-	var tenant *config.Tenant
-	for i := range m.config.Applications.Tenants {
-		if m.config.Applications.Tenants[i].Name == tenantName {
-			tenant = &m.config.Applications.Tenants[i]
-			break
-		}
+	tenant := m.config.TenantByName(tenantName)
+	if tenant == nil {
+		m.mutex.Unlock()
+		return nil, false, fmt.Errorf("tenant %s not found", tenantName)
 	}
 
-	if tenant == nil {
-		return nil, fmt.Errorf("tenant %s not found", tenantName)
+	if m.stubPort != 0 {
+		app = newStubApp(tenant, m.stubPort)
+		m.apps[tenantName] = app
+		m.mutex.Unlock()
+		return app, false, nil
 	}
 
-	// Find an available port
-	port, err := m.portAllocator.FindAvailablePort()
+	// Find an available port: the pinned one, or else preferably the last
+	port, err := m.allocatePort(tenant)
 	if err != nil {
-		return nil, fmt.Errorf("no available ports: %w", err)
+		m.mutex.Unlock()
+		return nil, false, err
 	}
 
-	app = &WebApp{
+	app = newWebApp(tenant, port)
+	app.onCrash = func() { m.removeCrashedApp(tenantName, app) }
+	app.onGuardLost = func() { m.stopForLostGuard(tenantName, app) }
+
+	// Register app immediately so other requests can see it's starting
+	m.apps[tenantName] = app
+	processStarter, cfg := m.processStarter, m.config
+	m.mutex.Unlock()
+
+	// Boot now if a startup slot is free. Otherwise the app boots once the
+	// startups queued before it are done, and callers wait with their own
+	// timeout as they would for any app still starting.
+	release, wait := m.startups.Reserve(startupConcurrency(cfg))
+	if release == nil {
+		app.queueStartup()
+		logging.LogWebAppStartupQueued(tenantName, m.StartupQueueDepth())
+		go m.startQueued(tenantName, app, tenant, processStarter, wait)
+		return app, true, nil
+	}
+	defer release()
+
+	if err := m.bootApp(tenantName, app, tenant, processStarter); err != nil {
+		return nil, false, err
+	}
+	return app, true, nil
+}
+
+// StubApps makes every tenant resolve to a responder already listening on
+// port instead of starting its process, so traffic can be replayed without
+// real backends. Stubbed apps are never stopped for idleness.
+func (m *AppManager) StubApps(port int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stubPort = port
+}
+
+// newStubApp returns a ready app for tenant served by the stub on port
+func newStubApp(tenant *config.Tenant, port int) *WebApp {
+	app := &WebApp{
 		URL:           fmt.Sprintf("http://localhost:%d", port),
 		Tenant:        tenant,
 		Port:          port,
 		StartTime:     time.Now(),
 		LastActivity:  time.Now(),
-		Starting:      true, // Mark as starting
 		readyChan:     make(chan struct{}),
 		wsConnections: make(map[string]interface{}),
 	}
+	app.setState(AppStarting, "stubbed")
+	app.setState(AppHealthy, "answered by the stub")
+	close(app.readyChan)
+	return app
+}
 
-	// Register app immediately so other requests can see it's starting
-	m.apps[tenantName] = app
+// checkIdleApp is run by the idle scheduler for each running app. It reports
+// OOM kills and stops apps that have been idle longer than the idle timeout,
+// returning whether the app should be checked again.
+func (m *AppManager) checkIdleApp(tenantName string) bool {
+	m.mutex.RLock()
+	app, exists := m.apps[tenantName]
+	idleTimeout := m.idleTimeout
+	m.mutex.RUnlock()
 
-	// Start the web application (this will clear Starting flag when ready)
-	if err := m.processStarter.StartWebApp(app, tenant); err != nil {
-		// Clean up on error
-		delete(m.apps, tenantName)
-		return nil, err
+	if !exists {
+		return false // App was removed
 	}
 
-	// Start idle cleanup goroutine for this app
-	go m.monitorAppIdleTimeout(tenantName)
+	// Recycle the app if it has run too long or grown too large
+	m.checkRecycle(tenantName, app)
 
-	return app, nil
-}
+	// Check for OOM kills (Linux only)
+	if app.CgroupPath != "" && IsOOMKill(app.CgroupPath) {
+		// Update OOM count and timestamp
+		app.mutex.Lock()
+		app.OOMCount++
+		app.LastOOMTime = time.Now()
+		oomCount := app.OOMCount
+		app.mutex.Unlock()
 
-// monitorAppIdleTimeout monitors and stops idle apps
-func (m *AppManager) monitorAppIdleTimeout(tenantName string) {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+		slog.Error("Tenant OOM killed by kernel",
+			"tenant", tenantName,
+			"limit", formatBytes(app.MemoryLimit),
+			"oomCount", oomCount)
 
-	for range ticker.C {
-		m.mutex.RLock()
-		app, exists := m.apps[tenantName]
-		m.mutex.RUnlock()
+		// Remove from registry
+		// Next request will trigger restart via GetOrStartApp()
+		m.mutex.Lock()
+		delete(m.apps, tenantName)
+		m.mutex.Unlock()
+
+		return false
+	}
 
-		if !exists {
-			return // App was removed
+	app.mutex.Lock()
+	lastActivity := app.LastActivity
+	app.mutex.Unlock()
+	idleTime := app.now().Sub(lastActivity)
+
+	staleWebSockets := 0
+	grace, closeStale := app.idleWebSocketGrace(&m.config.Applications)
+	if grace > 0 {
+		// Only WebSockets whose client sent data recently keep the app running
+		live, stale, lastFrame := app.webSocketActivity(grace)
+		if live > 0 || len(stale) > 0 {
+			slog.Debug("Checked WebSocket activity for idle",
+				"tenant", tenantName,
+				"liveWebSockets", live,
+				"staleWebSockets", len(stale),
+				"grace", grace)
+		}
+		if live > 0 {
+			return true
 		}
+		if !closeStale {
+			staleWebSockets = len(stale)
+		} else if len(stale) > 0 {
+			slog.Info("Closing stale WebSocket connections", "tenant", tenantName, "count", len(stale))
+			for _, activity := range stale {
+				app.UntrackWebSocket(activity)
+				_ = activity.conn.Close()
+			}
+		}
+		// The idle timeout runs from the last request or client data frame
+		if lastFrame.After(lastActivity) {
+			idleTime = app.now().Sub(lastFrame)
+		}
+	} else if activeWS := app.GetActiveWebSocketCount(); activeWS > 0 {
+		// Don't stop if there are active WebSocket connections
+		slog.Debug("App has active WebSocket connections, skipping idle check",
+			"tenant", tenantName,
+			"activeWebSockets", activeWS,
+			"idleTime", idleTime)
+		return true
+	}
 
-		// Check for OOM kills (Linux only)
-		if app.CgroupPath != "" && IsOOMKill(app.CgroupPath) {
-			// Update OOM count and timestamp
-			app.mutex.Lock()
-			app.OOMCount++
-			app.LastOOMTime = time.Now()
-			oomCount := app.OOMCount
-			app.mutex.Unlock()
+	if idleTime <= idleTimeout {
+		return true
+	}
 
-			slog.Error("Tenant OOM killed by kernel",
-				"tenant", tenantName,
-				"limit", formatBytes(app.MemoryLimit),
-				"oomCount", oomCount)
+	logging.LogWebAppIdle(tenantName, idleTime.Round(time.Second).String())
+	if staleWebSockets > 0 {
+		slog.Info("Ignored stale WebSocket connections", "tenant", tenantName, "count", staleWebSockets, "grace", grace)
+	}
 
-			// Remove from registry
-			// Next request will trigger restart via GetOrStartApp()
-			m.mutex.Lock()
-			delete(m.apps, tenantName)
-			m.mutex.Unlock()
+	// Drain, in a way requests can cancel, unless the app is no longer running
+	if !app.drainForIdle() {
+		return false
+	}
 
-			return // Exit the monitoring goroutine
-		}
+	// Stop hooks can be slow; run them without holding up checks of other apps
+	go m.stopIdleApp(tenantName, app)
+	return false
+}
 
-		app.mutex.Lock()
-		idleTime := time.Since(app.LastActivity)
-		app.mutex.Unlock()
+// stopIdleApp runs the stop hooks for an idle app and stops it, unless a
+// request arrives meanwhile, in which case the app goes back to being monitored
+func (m *AppManager) stopIdleApp(tenantName string, app *WebApp) {
+	// Execute tenant stop hooks before removing from registry
+	if app.Tenant != nil {
+		_ = ExecuteTenantHooks(context.Background(), m.config.Applications.Hooks.Stop, app.Tenant.Hooks.Stop,
+			app.Tenant.Env, tenantName, "stop")
+	}
 
-		// Don't stop if there are active WebSocket connections
-		activeWS := app.GetActiveWebSocketCount()
-		if activeWS > 0 {
-			slog.Debug("App has active WebSocket connections, skipping idle check",
-				"tenant", tenantName,
-				"activeWebSockets", activeWS,
-				"idleTime", idleTime)
-			continue
+	// Check if a request came in during hooks and cancelled the shutdown
+	shutdownCancelled := app.State() != AppDraining
+
+	if shutdownCancelled {
+		slog.Info("App shutdown cancelled due to new request", "tenant", tenantName)
+		// Run start hooks to restore app to normal state
+		if app.Tenant != nil {
+			if err := ExecuteTenantHooks(context.Background(), m.config.Applications.Hooks.Start, app.Tenant.Hooks.Start,
+				app.Tenant.Env, tenantName, "start"); err != nil {
+				slog.Error("Failed to execute tenant start hooks after shutdown cancellation",
+					"tenant", tenantName, "error", err)
+			}
 		}
+		m.idle.schedule(tenantName) // Go back to monitoring
+		return
+	}
 
-		if idleTime > m.idleTimeout {
-			logging.LogWebAppIdle(tenantName, idleTime.Round(time.Second).String())
+	m.removeStoppedApp(tenantName, app, "idle")
+}
 
-			// Mark as stopping so requests can cancel the shutdown
-			app.mutex.Lock()
-			app.Stopping = true
-			app.mutex.Unlock()
+// StopApp runs a running tenant's stop hooks and stops its app, emitting
+// tenant.stopped with reason. The next request starts it again. Returns
+// false if the tenant isn't running.
+func (m *AppManager) StopApp(tenantName, reason string) bool {
+	m.mutex.RLock()
+	app, exists := m.apps[tenantName]
+	m.mutex.RUnlock()
+	if !exists {
+		return false
+	}
 
-			// Execute tenant stop hooks before removing from registry
-			if app.Tenant != nil {
-				_ = ExecuteTenantHooks(m.config.Applications.Hooks.Stop, app.Tenant.Hooks.Stop,
-					app.Tenant.Env, tenantName, "stop")
-			}
+	app.setState(AppDraining, reason)
+	if app.Tenant != nil {
+		_ = ExecuteTenantHooks(context.Background(), m.config.Applications.Hooks.Stop, app.Tenant.Hooks.Stop,
+			app.Tenant.Env, tenantName, "stop")
+	}
+	m.removeStoppedApp(tenantName, app, reason)
+	return true
+}
 
-			// Check if a request came in during hooks and cancelled the shutdown
-			app.mutex.Lock()
-			shutdownCancelled := !app.Stopping
-			app.mutex.Unlock()
-
-			if shutdownCancelled {
-				slog.Info("App shutdown cancelled due to new request", "tenant", tenantName)
-				// Run start hooks to restore app to normal state
-				if app.Tenant != nil {
-					if err := ExecuteTenantHooks(m.config.Applications.Hooks.Start, app.Tenant.Hooks.Start,
-						app.Tenant.Env, tenantName, "start"); err != nil {
-						slog.Error("Failed to execute tenant start hooks after shutdown cancellation",
-							"tenant", tenantName, "error", err)
-					}
-				}
-				continue // Skip stopping, go back to monitoring
-			}
+// RestartApp stops a running tenant's app, as StopApp does, and starts it
+// again in the background. Returns false if the tenant isn't running.
+func (m *AppManager) RestartApp(tenantName, reason string) bool {
+	if !m.StopApp(tenantName, reason) {
+		return false
+	}
+	go func() {
+		if _, err := m.GetOrStartApp(tenantName); err != nil {
+			slog.Error("Failed to restart web app", "tenant", tenantName, "error", err)
+		}
+	}()
+	return true
+}
 
-			// Stop the process
-			if app.cancel != nil {
-				app.cancel()
-			}
+// removeStoppedApp stops an app whose stop hooks have run and removes it,
+// with its standby, from the registry
+func (m *AppManager) removeStoppedApp(tenantName string, app *WebApp, reason string) {
+	// Stop the process
+	app.setState(AppStopped, reason)
+	if app.cancel != nil {
+		app.cancel()
+	}
 
-			// Release the port back to the allocator
-			m.portAllocator.ReleasePort(app.Port)
+	// Release the port back to the allocator
+	m.portAllocator.ReleasePort(app.Port)
 
-			// Remove from registry only after fully stopped
-			m.mutex.Lock()
-			delete(m.apps, tenantName)
-			m.mutex.Unlock()
+	// Remove from registry only after fully stopped
+	m.mutex.Lock()
+	if m.apps[tenantName] == app {
+		delete(m.apps, tenantName)
+	}
+	m.mutex.Unlock()
 
-			// Log memory statistics (Linux only)
-			if app.CgroupPath != "" {
-				LogMemoryStats(app.CgroupPath, tenantName)
-			}
+	// The standby stops along with its primary
+	m.stopStandby(tenantName)
 
-			// Clean up PID file
-			if app.Tenant != nil {
-				if pidfile, ok := app.Tenant.Env["PIDFILE"]; ok {
-					if err := os.Remove(pidfile); err != nil && !os.IsNotExist(err) {
-						slog.Warn("Error removing PID file", "file", pidfile, "error", err)
-					}
-				}
-			}
+	events.Emit(events.TenantStopped, map[string]interface{}{
+		"tenant": tenantName,
+		"reason": reason,
+	})
+
+	// Log memory statistics (Linux only)
+	if app.CgroupPath != "" {
+		LogMemoryStats(app.CgroupPath, tenantName)
+	}
 
-			return // Exit the monitoring goroutine
+	// Clean up PID file
+	if app.Tenant != nil {
+		if pidfile, ok := pidFile(app.Tenant); ok {
+			if err := os.Remove(pidfile); err != nil && !os.IsNotExist(err) {
+				slog.Warn("Error removing PID file", "file", pidfile, "error", err)
+			}
 		}
 	}
 }
@@ -310,6 +524,19 @@ func (app *WebApp) UnregisterWebSocketConnection(connID string) {
 	slog.Debug("Unregistered WebSocket connection", "app", app.Tenant.Name, "connID", connID, "remaining", len(app.wsConnections))
 }
 
+// CloseWebSockets closes the app's registered WebSocket connections, so
+// their clients reconnect to whichever instance now serves the tenant
+func (app *WebApp) CloseWebSockets() {
+	app.wsConnectionsMux.Lock()
+	defer app.wsConnectionsMux.Unlock()
+	for connID, conn := range app.wsConnections {
+		if closer, ok := conn.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		delete(app.wsConnections, connID)
+	}
+}
+
 // UpdateConfig updates the AppManager configuration after a reload
 func (m *AppManager) UpdateConfig(newConfig *config.Config) {
 	m.mutex.Lock()
@@ -319,14 +546,18 @@ func (m *AppManager) UpdateConfig(newConfig *config.Config) {
 	m.processStarter = NewProcessStarter(newConfig)
 
 	// Update idle timeout if changed
-	m.idleTimeout = utils.ParseDurationWithDefault(newConfig.Applications.Pools.Timeout, config.DefaultIdleTimeout)
+	m.idleTimeout = newConfig.Applications.Pools.Timeout.OrDefault(config.DefaultIdleTimeout)
 
 	// Update port range if changed
 	startPort := newConfig.Applications.Pools.StartPort
 	if startPort == 0 {
 		startPort = config.DefaultStartPort
 	}
-	m.portAllocator = NewPortAllocator(startPort, startPort+config.MaxPortRange)
+	m.portAllocator = newTenantPortAllocator(newConfig)
+	m.portState = loadPortState(newConfig.Applications.Pools.PortState)
+	if concurrency := recycleConcurrency(newConfig); concurrency != cap(m.recycleSlots) {
+		m.recycleSlots = make(chan struct{}, concurrency) // Recycles in progress release their old slots
+	}
 
 	slog.Info("Updated AppManager configuration",
 		"idleTimeout", m.idleTimeout,
@@ -351,22 +582,24 @@ func (m *AppManager) CleanupWithContext(ctx context.Context) {
 
 		for tenantName, app := range m.apps {
 			logging.LogWebAppStop(tenantName)
+			app.setState(AppDraining, "shutdown")
 
 			// Execute tenant stop hooks
 			if app.Tenant != nil {
-				_ = ExecuteTenantHooks(m.config.Applications.Hooks.Stop, app.Tenant.Hooks.Stop,
+				_ = ExecuteTenantHooks(ctx, m.config.Applications.Hooks.Stop, app.Tenant.Hooks.Stop,
 					app.Tenant.Env, tenantName, "stop")
 			}
 
 			// Clean up PID file
 			if app.Tenant != nil {
-				if pidfile, ok := app.Tenant.Env["PIDFILE"]; ok {
+				if pidfile, ok := pidFile(app.Tenant); ok {
 					if err := os.Remove(pidfile); err != nil && !os.IsNotExist(err) {
 						slog.Warn("Error removing PID file", "file", pidfile, "error", err)
 					}
 				}
 			}
 
+			app.setState(AppStopped, "shutdown")
 			if app.cancel != nil {
 				app.cancel()
 			}
@@ -374,6 +607,11 @@ func (m *AppManager) CleanupWithContext(ctx context.Context) {
 			// Release the port back to the allocator
 			m.portAllocator.ReleasePort(app.Port)
 
+			events.Emit(events.TenantStopped, map[string]interface{}{
+				"tenant": tenantName,
+				"reason": "shutdown",
+			})
+
 			// Log memory statistics and cleanup cgroup on shutdown (Linux only)
 			if app.CgroupPath != "" {
 				LogMemoryStats(app.CgroupPath, tenantName)
@@ -385,8 +623,14 @@ func (m *AppManager) CleanupWithContext(ctx context.Context) {
 			}
 		}
 
-		// Clear the apps map
+		// Stop standbys with their primaries
+		for tenantName := range m.standbys {
+			m.stopInstance(m.takeStandby(tenantName))
+		}
+
+		// Clear the apps map and their pending idle checks
 		m.apps = make(map[string]*WebApp)
+		m.idle.clear()
 
 		// Give processes a moment to exit cleanly
 		time.Sleep(500 * time.Millisecond)
@@ -410,6 +654,105 @@ func (m *AppManager) GetApp(tenantName string) (*WebApp, bool) {
 	return app, exists
 }
 
+// AppStatus is a point-in-time view of a running web app
+type AppStatus struct {
+	Tenant           string    `json:"tenant"`
+	Port             int       `json:"port"`
+	PortAssignment   string    `json:"port_assignment"` // PortPinned or PortDynamic
+	PID              int       `json:"pid,omitempty"`
+	StartTime        time.Time `json:"start_time"`
+	LastActivity     time.Time `json:"last_activity"`
+	State            AppState  `json:"state"`
+	StateSince       time.Time `json:"state_since"`
+	StateReason      string    `json:"state_reason,omitempty"`
+	ActiveWebSockets int32     `json:"active_websockets"`
+	MemoryLimit      int64     `json:"memory_limit,omitempty"`
+	OOMCount         int       `json:"oom_count,omitempty"`
+
+	Priority *config.PriorityConfig `json:"priority,omitempty"` // Priority applied to the process
+
+	ActiveRequests int     `json:"active_requests,omitempty"`    // Requests holding a max_concurrent_requests slot
+	QueuedRequests int     `json:"queued_requests,omitempty"`    // Requests waiting for a slot
+	QueueWait      float64 `json:"queue_wait_seconds,omitempty"` // How long the oldest queued request has waited
+
+	StartupQueued bool `json:"startup_queued,omitempty"` // Waiting for a startup slot before booting
+
+	StandbyPort int `json:"standby_port,omitempty"` // Port of the warm standby, once it is ready
+	Failovers   int `json:"failovers,omitempty"`    // Times a standby has taken over from a failed primary
+}
+
+// Status returns the state of every web app, sorted by tenant name
+func (m *AppManager) Status() []AppStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status := make([]AppStatus, 0, len(m.apps))
+	for name, app := range m.apps {
+		app.mutex.Lock()
+		entry := AppStatus{
+			Tenant:           name,
+			Port:             app.Port,
+			PortAssignment:   PortDynamic,
+			StartTime:        app.StartTime,
+			LastActivity:     app.LastActivity,
+			State:            app.stateLocked(),
+			ActiveWebSockets: app.GetActiveWebSocketCount(),
+			MemoryLimit:      app.MemoryLimit,
+			OOMCount:         app.OOMCount,
+			Priority:         app.Priority,
+			StartupQueued:    !app.queuedSince.IsZero(),
+		}
+		if n := len(app.transitions); n > 0 {
+			entry.StateSince, entry.StateReason = app.transitions[n-1].At, app.transitions[n-1].Reason
+		}
+		if app.Process != nil && app.Process.Process != nil {
+			entry.PID = app.Process.Process.Pid
+		}
+		if app.Tenant != nil && app.Tenant.Port != 0 && app.Tenant.Port == app.Port {
+			entry.PortAssignment = PortPinned
+		}
+		app.mutex.Unlock()
+		if pair := m.standbys[name]; pair != nil {
+			entry.Failovers = pair.failovers
+			if pair.app != nil && pair.app.ready() {
+				entry.StandbyPort = pair.app.Port
+			}
+		}
+		active, queued, wait := app.requests.Stats()
+		entry.ActiveRequests, entry.QueuedRequests, entry.QueueWait = active, queued, wait.Seconds()
+		status = append(status, entry)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Tenant < status[j].Tenant })
+	return status
+}
+
+// LastActivity returns when each running web app last served a request
+func (m *AppManager) LastActivity() map[string]time.Time {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	activity := make(map[string]time.Time, len(m.apps))
+	for name, app := range m.apps {
+		app.mutex.Lock()
+		activity[name] = app.LastActivity
+		app.mutex.Unlock()
+	}
+	return activity
+}
+
+// InFlight returns the requests being proxied to each running tenant's app,
+// apart from WebSockets
+func (m *AppManager) InFlight() map[string]int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	inFlight := make(map[string]int, len(m.apps))
+	for name, app := range m.apps {
+		inFlight[name] = int(app.inFlight.Load())
+	}
+	return inFlight
+}
+
 // Helper functions
 
 // cleanupPidFile checks for and removes stale PID file
@@ -0,0 +1,102 @@
+package process
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// WebSocketActivity records when a tracked WebSocket connection last carried
+// a data frame from its client, so idle management can tell a page in use
+// from an abandoned tab. Pings, pongs, and frames sent to the client don't
+// count: servers send those to idle clients too.
+type WebSocketActivity struct {
+	lastActive atomic.Int64 // UnixNano of the last client data frame, or of opening
+	clock      clock.Clock
+	conn       io.Closer // Set once the connection is tracked
+}
+
+// Frame records a data frame from the client
+func (a *WebSocketActivity) Frame() {
+	a.lastActive.Store(a.clock.Now().UnixNano())
+}
+
+// LastActive returns when the client last sent a data frame, or when the
+// connection opened if it hasn't
+func (a *WebSocketActivity) LastActive() time.Time {
+	return time.Unix(0, a.lastActive.Load())
+}
+
+// now returns the current time on the app's clock, for WebSocket activity,
+// idle checks, and recycling
+func (w *WebApp) now() time.Time {
+	return clock.Or(w.clock).Now()
+}
+
+// NewWebSocketActivity returns the activity record of a WebSocket connection
+// being opened; it is counted once TrackWebSocket is called with the
+// connection
+func (w *WebApp) NewWebSocketActivity() *WebSocketActivity {
+	activity := &WebSocketActivity{clock: clock.Or(w.clock)}
+	activity.Frame()
+	return activity
+}
+
+// TrackWebSocket counts an open WebSocket connection, conn, for idle
+// decisions until UntrackWebSocket is called
+func (w *WebApp) TrackWebSocket(activity *WebSocketActivity, conn io.Closer) {
+	w.wsConnectionsMux.Lock()
+	defer w.wsConnectionsMux.Unlock()
+	if w.websockets == nil {
+		w.websockets = make(map[*WebSocketActivity]bool)
+	}
+	activity.conn = conn
+	w.websockets[activity] = true
+}
+
+// UntrackWebSocket stops counting a closed WebSocket connection
+func (w *WebApp) UntrackWebSocket(activity *WebSocketActivity) {
+	w.wsConnectionsMux.Lock()
+	defer w.wsConnectionsMux.Unlock()
+	delete(w.websockets, activity)
+}
+
+// webSocketActivity splits the tracked WebSocket connections into live ones,
+// whose client sent a data frame within grace, and stale ones, returning
+// also when the most recent client data frame was sent
+func (w *WebApp) webSocketActivity(grace time.Duration) (live int, stale []*WebSocketActivity, lastActive time.Time) {
+	w.wsConnectionsMux.RLock()
+	defer w.wsConnectionsMux.RUnlock()
+	now := w.now()
+	for activity := range w.websockets {
+		active := activity.LastActive()
+		if active.After(lastActive) {
+			lastActive = active
+		}
+		if now.Sub(active) < grace {
+			live++
+		} else {
+			stale = append(stale, activity)
+		}
+	}
+	return live, stale, lastActive
+}
+
+// idleWebSocketGrace returns how long a WebSocket connection keeps the app
+// running without client data frames, and whether connections are closed
+// after that: the tenant's settings over the global ones
+func (w *WebApp) idleWebSocketGrace(apps *config.Applications) (time.Duration, bool) {
+	grace, closeStale := apps.IdleWebSocketGrace, apps.CloseStaleWebSockets
+	if w.Tenant != nil {
+		if w.Tenant.IdleWebSocketGrace > 0 {
+			grace = w.Tenant.IdleWebSocketGrace
+		}
+		if w.Tenant.CloseStaleWebSockets != nil {
+			closeStale = *w.Tenant.CloseStaleWebSockets
+		}
+	}
+	return grace.Std(), closeStale
+}
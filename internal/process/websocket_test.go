@@ -3,7 +3,9 @@ package process
 import (
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
 )
 
@@ -130,7 +132,7 @@ func TestWebAppWebSocketConcurrency(t *testing.T) {
 // TestWebAppWebSocketIntegration tests integration with AppManager
 func TestWebAppWebSocketIntegration(t *testing.T) {
 	cfg := &config.Config{}
-	cfg.Applications.Pools.Timeout = "5m"
+	cfg.Applications.Pools.Timeout = config.Duration(5 * time.Minute)
 	cfg.Applications.Tenants = []config.Tenant{
 		{Name: "test-tenant"},
 	}
@@ -243,6 +245,116 @@ func TestWebAppShouldTrackWebSockets(t *testing.T) {
 	}
 }
 
+// closeRecorder is a WebSocket connection recording whether it was closed
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+// newGraceTestApp returns an AppManager with a 15m idle timeout and a 10m
+// WebSocket grace, and a tenant app on a fake clock that was last requested
+// an hour ago
+func newGraceTestApp(closeStale bool) (*AppManager, *WebApp, *clock.Fake) {
+	cfg := &config.Config{
+		Applications: config.Applications{
+			Pools:                config.Pools{Timeout: config.Duration(15 * time.Minute)},
+			IdleWebSocketGrace:   config.Duration(10 * time.Minute),
+			CloseStaleWebSockets: closeStale,
+			Tenants:              []config.Tenant{{Name: "grace-test", Root: "/tmp"}},
+		},
+	}
+	appManager := NewAppManager(cfg)
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	app := &WebApp{
+		Tenant:        &cfg.Applications.Tenants[0],
+		LastActivity:  fake.Now().Add(-time.Hour),
+		wsConnections: make(map[string]interface{}),
+		readyChan:     make(chan struct{}),
+		clock:         fake,
+		state:         AppHealthy,
+	}
+	appManager.mutex.Lock()
+	appManager.apps["grace-test"] = app
+	appManager.mutex.Unlock()
+	return appManager, app, fake
+}
+
+func TestCheckIdleAppWebSocketGrace(t *testing.T) {
+	appManager, app, fake := newGraceTestApp(false)
+	defer appManager.Cleanup()
+
+	conn := &closeRecorder{}
+	activity := app.NewWebSocketActivity()
+	app.TrackWebSocket(activity, conn)
+
+	// A newly opened connection keeps the app running
+	fake.Advance(9 * time.Minute)
+	if !appManager.checkIdleApp("grace-test") {
+		t.Error("checkIdleApp() = false with a WebSocket inside its grace, want true")
+	}
+
+	// As does one whose client keeps sending data
+	activity.Frame()
+	fake.Advance(9 * time.Minute)
+	if !appManager.checkIdleApp("grace-test") {
+		t.Error("checkIdleApp() = false with a WebSocket sending data, want true")
+	}
+
+	// Past the grace, the idle timeout runs from the last frame
+	fake.Advance(2 * time.Minute)
+	if !appManager.checkIdleApp("grace-test") {
+		t.Error("checkIdleApp() = false within the idle timeout of the last frame, want true")
+	}
+	fake.Advance(5 * time.Minute)
+	if appManager.checkIdleApp("grace-test") {
+		t.Error("checkIdleApp() = true with only stale WebSockets, want false")
+	}
+	if conn.closed {
+		t.Error("Stale WebSocket closed without close_stale_websockets")
+	}
+}
+
+func TestCheckIdleAppClosesStaleWebSockets(t *testing.T) {
+	appManager, app, fake := newGraceTestApp(true)
+	defer appManager.Cleanup()
+
+	conn := &closeRecorder{}
+	app.TrackWebSocket(app.NewWebSocketActivity(), conn)
+
+	fake.Advance(16 * time.Minute)
+	if appManager.checkIdleApp("grace-test") {
+		t.Error("checkIdleApp() = true with only stale WebSockets, want false")
+	}
+	if !conn.closed {
+		t.Error("Expected the stale WebSocket to be closed")
+	}
+	if live, stale, _ := app.webSocketActivity(10 * time.Minute); live+len(stale) != 0 {
+		t.Errorf("Expected closed WebSockets to be untracked, got %d live and %d stale", live, len(stale))
+	}
+}
+
+func TestIdleWebSocketGraceTenantOverride(t *testing.T) {
+	apps := &config.Applications{IdleWebSocketGrace: config.Duration(10 * time.Minute)}
+	app := &WebApp{Tenant: &config.Tenant{
+		IdleWebSocketGrace:   config.Duration(time.Minute),
+		CloseStaleWebSockets: boolPtr(true),
+	}}
+
+	grace, closeStale := app.idleWebSocketGrace(apps)
+	if grace != time.Minute || !closeStale {
+		t.Errorf("idleWebSocketGrace() = %v, %v; want 1m0s, true", grace, closeStale)
+	}
+
+	app.Tenant = &config.Tenant{}
+	grace, closeStale = app.idleWebSocketGrace(apps)
+	if grace != 10*time.Minute || closeStale {
+		t.Errorf("idleWebSocketGrace() = %v, %v; want the global 10m0s, false", grace, closeStale)
+	}
+}
+
 // Helper function for creating bool pointers
 func boolPtr(b bool) *bool {
 	return &b
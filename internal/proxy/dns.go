@@ -0,0 +1,260 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// dnsTransportKey identifies the transports that can share connections and
+// resolved addresses, so they survive across requests and config reloads
+type dnsTransportKey struct {
+	host               string
+	ttl                time.Duration
+	resolver           string
+	disableCompression bool
+}
+
+// dnsTransportEntry is a cached transport and the key it's cached under
+type dnsTransportEntry struct {
+	key       dnsTransportKey
+	transport *DNSTransport
+}
+
+// Transports are kept for the most recently used target hosts only: hosts
+// substituted from the request path would otherwise grow the cache without
+// bound
+var (
+	dnsTransportsMutex sync.Mutex
+	dnsTransportOrder  = list.New() // Most recently used at the front
+	dnsTransports      = make(map[dnsTransportKey]*list.Element)
+)
+
+// DNSTransport is an http.Transport for a reverse proxy route that dials
+// the addresses it resolved for the target host instead of resolving on
+// every dial. While the route is in use the addresses are refreshed in the
+// background every TTL, so no request waits on DNS after the first; when
+// they change, idle connections to the old addresses are closed.
+type DNSTransport struct {
+	*http.Transport
+	host   string
+	ttl    time.Duration
+	lookup config.HostResolver
+
+	mutex      sync.Mutex
+	addrs      []string
+	resolvedAt time.Time
+	used       bool              // Dialed since the last refresh
+	timer      *time.Timer       // Pending refresh (nil while the route is idle)
+	dialers    []*http.Transport // Other transports dialing through this one
+	closed     bool              // Evicted from the cache; no more refreshes
+}
+
+// TransportForDNS returns the shared transport for a route whose target
+// host is hostport and whose dns block is dns
+func TransportForDNS(hostport string, dns *config.DNSConfig) *DNSTransport {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	key := dnsTransportKey{
+		host:               host,
+		ttl:                dns.ResolvedTTL(),
+		resolver:           dns.Resolver,
+		disableCompression: GetDisableCompression(),
+	}
+
+	dnsTransportsMutex.Lock()
+	if element, ok := dnsTransports[key]; ok {
+		dnsTransportOrder.MoveToFront(element)
+		dnsTransportsMutex.Unlock()
+		return element.Value.(*dnsTransportEntry).transport
+	}
+	lookup := dns.Lookup
+	if lookup == nil {
+		lookup = net.DefaultResolver
+	}
+	t := newDNSTransport(host, key.ttl, lookup)
+	t.DisableCompression = key.disableCompression
+	dnsTransports[key] = dnsTransportOrder.PushFront(&dnsTransportEntry{key: key, transport: t})
+	var evicted []*DNSTransport
+	for dnsTransportOrder.Len() > config.DNSTransportLimit {
+		entry := dnsTransportOrder.Remove(dnsTransportOrder.Back()).(*dnsTransportEntry)
+		delete(dnsTransports, entry.key)
+		evicted = append(evicted, entry.transport)
+	}
+	dnsTransportsMutex.Unlock()
+
+	for _, old := range evicted {
+		old.close()
+	}
+	return t
+}
+
+// close stops refreshing an evicted transport and closes its idle
+// connections and those of the transports dialing through it. Requests
+// still using it finish normally; their connections close once idle.
+func (t *DNSTransport) close() {
+	forgetOutboundTransports(t)
+	t.mutex.Lock()
+	t.closed = true
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	dialers := t.dialers
+	t.mutex.Unlock()
+
+	t.CloseIdleConnections()
+	for _, dialer := range dialers {
+		dialer.CloseIdleConnections()
+	}
+}
+
+// newDNSTransport creates a transport resolving host with lookup
+func newDNSTransport(host string, ttl time.Duration, lookup config.HostResolver) *DNSTransport {
+	t := &DNSTransport{
+		Transport: NewTransport(),
+		host:      host,
+		ttl:       ttl,
+		lookup:    lookup,
+	}
+	t.Transport.DialContext = t.dial
+	return t
+}
+
+// dial connects to the first reachable resolved address of the target
+// host; other hosts are dialed as usual
+func (t *DNSTransport) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != t.host || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := t.addresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range addrs {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// dialFor has transport dial through t, closing its idle connections too
+// when the addresses change
+func (t *DNSTransport) dialFor(transport *http.Transport) {
+	transport.DialContext = t.dial
+	t.mutex.Lock()
+	t.dialers = append(t.dialers, transport)
+	t.mutex.Unlock()
+}
+
+// DialContext dials the target host like the transport does, for
+// connections made outside it such as WebSocket upgrades
+func (t *DNSTransport) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return t.dial(ctx, network, addr)
+}
+
+// addresses returns the target host's addresses, resolving them on first
+// use and scheduling a background refresh if none is pending
+func (t *DNSTransport) addresses(ctx context.Context) ([]string, error) {
+	t.mutex.Lock()
+	addrs := t.addrs
+	t.mutex.Unlock()
+
+	if addrs == nil {
+		resolved, err := t.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t.mutex.Lock()
+		if t.addrs == nil {
+			t.addrs, t.resolvedAt = resolved, time.Now()
+		}
+		addrs = t.addrs
+		t.mutex.Unlock()
+	}
+
+	t.mutex.Lock()
+	t.used = true
+	if t.timer == nil && !t.closed {
+		t.timer = time.AfterFunc(t.ttl-time.Since(t.resolvedAt), t.refresh)
+	}
+	t.mutex.Unlock()
+	return addrs, nil
+}
+
+// resolve looks up the target host's addresses
+func (t *DNSTransport) resolve(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.DNSResolveTimeout)
+	defer cancel()
+	ipAddrs, err := t.lookup.LookupIPAddr(ctx, t.host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ipAddrs) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", t.host)
+	}
+	addrs := make([]string, 0, len(ipAddrs))
+	for _, ipAddr := range ipAddrs {
+		addrs = append(addrs, ipAddr.IP.String())
+	}
+	return addrs, nil
+}
+
+// refresh re-resolves the target host. Idle connections are closed when
+// the addresses change; on failure the old addresses stay in use. The
+// refresh reschedules itself only if the route was used since the last one.
+func (t *DNSTransport) refresh() {
+	t.mutex.Lock()
+	if !t.used || t.closed {
+		t.timer = nil
+		t.mutex.Unlock()
+		return
+	}
+	t.used = false
+	old := t.addrs
+	t.mutex.Unlock()
+
+	addrs, err := t.resolve(context.Background())
+	if err != nil {
+		logging.LogProxyDNSRefreshFailed(t.host, err)
+		addrs = old
+	}
+
+	changed := !sameAddresses(old, addrs)
+	t.mutex.Lock()
+	t.addrs, t.resolvedAt = addrs, time.Now()
+	if !t.closed {
+		t.timer = time.AfterFunc(t.ttl, t.refresh)
+	}
+	dialers := t.dialers
+	t.mutex.Unlock()
+
+	if changed {
+		logging.LogProxyDNSChanged(t.host, old, addrs)
+		t.CloseIdleConnections()
+		for _, dialer := range dialers {
+			dialer.CloseIdleConnections()
+		}
+	}
+}
+
+// sameAddresses reports whether two address lists hold the same addresses,
+// in any order
+func sameAddresses(a, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
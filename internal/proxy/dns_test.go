@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// fakeResolver answers every lookup with addrs, counting lookups
+type fakeResolver struct {
+	mutex   sync.Mutex
+	addrs   []string
+	err     error
+	lookups int
+}
+
+func (f *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lookups++
+	if f.err != nil {
+		return nil, f.err
+	}
+	var result []net.IPAddr
+	for _, addr := range f.addrs {
+		result = append(result, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return result, nil
+}
+
+func (f *fakeResolver) set(err error, addrs ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.addrs, f.err = addrs, err
+}
+
+func (f *fakeResolver) count() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.lookups
+}
+
+// startBackends serves each body on its own loopback address, all on one
+// port, and returns the port
+func startBackends(t *testing.T, bodies map[string]string) int {
+	t.Helper()
+	port := 0
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2"} {
+		listener, err := net.Listen("tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err != nil {
+			t.Skipf("Can't listen on %s: %v", ip, err)
+		}
+		port = listener.Addr().(*net.TCPAddr).Port
+		body := bodies[ip]
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, body)
+		})}
+		go func() { _ = server.Serve(listener) }()
+		t.Cleanup(func() { _ = server.Close() })
+	}
+	return port
+}
+
+func getBody(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestDNSTransportFollowsAddressChange(t *testing.T) {
+	port := startBackends(t, map[string]string{"127.0.0.1": "old", "127.0.0.2": "new"})
+	resolver := &fakeResolver{addrs: []string{"127.0.0.1"}}
+	transport := newDNSTransport("backend.internal", time.Hour, resolver)
+	client := &http.Client{Transport: transport}
+	url := "http://backend.internal:" + strconv.Itoa(port) + "/"
+
+	if got := getBody(t, client, url); got != "old" {
+		t.Fatalf("First request reached %q, want old", got)
+	}
+	getBody(t, client, url)
+	if got := resolver.count(); got != 1 {
+		t.Errorf("Lookups = %d, want 1: requests shouldn't resolve", got)
+	}
+
+	// The refresh closes the idle connection to the old address
+	resolver.set(nil, "127.0.0.2")
+	transport.refresh()
+	if got := getBody(t, client, url); got != "new" {
+		t.Errorf("Request after the address changed reached %q, want new", got)
+	}
+
+	// A failed lookup keeps the addresses in use
+	resolver.set(errors.New("SERVFAIL"))
+	transport.refresh()
+	if got := getBody(t, client, url); got != "new" {
+		t.Errorf("Request after a failed refresh reached %q, want new", got)
+	}
+}
+
+func TestDNSTransportRefreshesInBackground(t *testing.T) {
+	port := startBackends(t, map[string]string{"127.0.0.1": "old", "127.0.0.2": "new"})
+	resolver := &fakeResolver{addrs: []string{"127.0.0.1"}}
+	transport := newDNSTransport("backend.internal", 20*time.Millisecond, resolver)
+	client := &http.Client{Transport: transport}
+	url := "http://backend.internal:" + strconv.Itoa(port) + "/"
+
+	getBody(t, client, url)
+	resolver.set(nil, "127.0.0.2")
+	deadline := time.Now().Add(5 * time.Second)
+	for getBody(t, client, url) != "new" {
+		if time.Now().After(deadline) {
+			t.Fatal("Address change was never picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Refreshing stops once the route is idle
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		transport.mutex.Lock()
+		idle := transport.timer == nil
+		transport.mutex.Unlock()
+		if idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Refresh timer still running for an idle route")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDNSTransportLookupFailure(t *testing.T) {
+	resolver := &fakeResolver{err: errors.New("no such host")}
+	transport := newDNSTransport("missing.internal", time.Hour, resolver)
+	if _, err := transport.DialContext(context.Background(), "tcp", "missing.internal:80"); err == nil {
+		t.Error("Dial succeeded without addresses")
+	}
+	resolver.set(nil)
+	if _, err := transport.DialContext(context.Background(), "tcp", "missing.internal:80"); err == nil {
+		t.Error("Dial succeeded with an empty answer")
+	}
+}
+
+func TestTransportForDNSEvictsLeastRecentlyUsed(t *testing.T) {
+	dns := &config.DNSConfig{Lookup: &fakeResolver{addrs: []string{"127.0.0.1"}}}
+	first := TransportForDNS("host-0.internal:80", dns)
+	outbound := TransportForOutbound(first, &config.OutboundConfig{IdentityTransfer: true})
+	first.mutex.Lock()
+	first.used, first.timer = true, time.AfterFunc(time.Hour, first.refresh)
+	first.mutex.Unlock()
+
+	for i := 1; i <= config.DNSTransportLimit; i++ {
+		TransportForDNS("host-"+strconv.Itoa(i)+".internal:80", dns)
+	}
+	dnsTransportsMutex.Lock()
+	size := len(dnsTransports)
+	dnsTransportsMutex.Unlock()
+	if size != config.DNSTransportLimit {
+		t.Errorf("Cached transports = %d, want %d", size, config.DNSTransportLimit)
+	}
+
+	first.mutex.Lock()
+	stopped := first.closed && first.timer == nil
+	first.mutex.Unlock()
+	if !stopped {
+		t.Error("Evicted transport is still refreshing")
+	}
+	if TransportForDNS("host-0.internal:80", dns) == first {
+		t.Error("Evicted transport was returned again")
+	}
+	if TransportForOutbound(first, &config.OutboundConfig{IdentityTransfer: true}) == outbound {
+		t.Error("Outbound transport dialing through the evicted transport was kept")
+	}
+}
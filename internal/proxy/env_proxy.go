@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// useEnvProxy indicates whether requests to tenants and reverse proxy
+// targets honor HTTP_PROXY, HTTPS_PROXY, and NO_PROXY. Off by default, so a
+// proxy set in the environment for tenant apps never captures Navigator's
+// own backend traffic.
+var useEnvProxy atomic.Bool
+
+// environmentProxy reads the proxy environment variables; tests replace it,
+// since Go reads them only once
+var environmentProxy = http.ProxyFromEnvironment
+
+// defaultTransport is Go's default transport with EnvProxy as its proxy,
+// used where a backend request has no transport of its own
+var defaultTransport = NewTransport()
+
+// NewTransport returns a copy of Go's default transport with EnvProxy as its
+// proxy, for backend requests that keep connections of their own
+func NewTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = EnvProxy
+	return t
+}
+
+// SetUseEnvProxy configures whether backend requests honor the proxy
+// environment variables (proxy.use_env_proxy)
+func SetUseEnvProxy(use bool) {
+	useEnvProxy.Store(use)
+}
+
+// EnvProxy is the Proxy of every transport Navigator sends tenant and
+// reverse proxy traffic with: the environment's proxy with
+// proxy.use_env_proxy, otherwise none
+func EnvProxy(req *http.Request) (*url.URL, error) {
+	if !useEnvProxy.Load() {
+		return nil, nil
+	}
+	return environmentProxy(req)
+}
+
+// DefaultTransport returns the transport for backend requests that need no
+// other settings: one that doesn't ask for compressed responses while
+// disable_compression is set, otherwise a shared one
+func DefaultTransport() http.RoundTripper {
+	if disableCompression.Load() {
+		return &http.Transport{DisableCompression: true, Proxy: EnvProxy}
+	}
+	return defaultTransport
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestEnvProxy(t *testing.T) {
+	corporate, _ := url.Parse("http://proxy.corp.example:3128")
+	saved := environmentProxy
+	environmentProxy = func(*http.Request) (*url.URL, error) { return corporate, nil }
+	t.Cleanup(func() {
+		environmentProxy = saved
+		SetUseEnvProxy(false)
+	})
+
+	req := httptest.NewRequest("GET", "http://localhost:4001/", nil)
+	transports := map[string]func(*http.Request) (*url.URL, error){
+		"EnvProxy":             EnvProxy,
+		"NewTransport":         NewTransport().Proxy,
+		"TransportForOutbound": TransportForOutbound(nil, &config.OutboundConfig{}).transport.Proxy,
+	}
+	for name, proxy := range transports {
+		got, err := proxy(req)
+		if err != nil || got != nil {
+			t.Errorf("%s = %v, %v without use_env_proxy, want no proxy", name, got, err)
+		}
+	}
+
+	SetUseEnvProxy(true)
+	for name, proxy := range transports {
+		got, err := proxy(req)
+		if err != nil || got != corporate {
+			t.Errorf("%s = %v, %v with use_env_proxy, want %v", name, got, err, corporate)
+		}
+	}
+}
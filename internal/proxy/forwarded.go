@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/netutil"
+)
+
+// preferXForwarded indicates X-Forwarded-* headers win when both header styles are present
+var preferXForwarded atomic.Bool
+
+// SetForwardedPrecedence configures which header style wins when both are present
+func SetForwardedPrecedence(precedence string) {
+	preferXForwarded.Store(strings.EqualFold(precedence, config.ForwardedPrecedenceXForwarded))
+}
+
+// PreferForwarded reports whether the Forwarded header takes precedence over X-Forwarded-*
+func PreferForwarded() bool {
+	return !preferXForwarded.Load()
+}
+
+// proxyProtocol indicates connections' peer addresses come from PROXY
+// protocol headers
+var proxyProtocol atomic.Bool
+
+// SetProxyProtocol configures whether server.proxy_protocol is enabled
+func SetProxyProtocol(enabled bool) {
+	proxyProtocol.Store(enabled)
+}
+
+// ClientIPTrust returns the trust settings netutil.ClientIP resolves the
+// client address with, from server.trust_proxy, server.forwarded_precedence,
+// and server.proxy_protocol
+func ClientIPTrust() netutil.TrustConfig {
+	return netutil.TrustConfig{TrustProxy: trustProxy.Load(), PreferForwarded: PreferForwarded(), ProxyProtocol: proxyProtocol.Load()}
+}
+
+// trustedForwarded returns the parsed Forwarded header if trust_proxy allows it
+func trustedForwarded(r *http.Request) []netutil.ForwardedElement {
+	if !trustProxy.Load() {
+		return nil
+	}
+	return netutil.ParseForwarded(strings.Join(r.Header.Values("Forwarded"), ","))
+}
+
+// ForwardedProto returns the original scheme from a trusted Forwarded header
+func ForwardedProto(r *http.Request) (string, bool) {
+	elements := trustedForwarded(r)
+	if len(elements) == 0 || elements[0].Proto == "" {
+		return "", false
+	}
+	return elements[0].Proto, true
+}
+
+// ForwardedHost returns the original Host from a trusted Forwarded header
+func ForwardedHost(r *http.Request) (string, bool) {
+	elements := trustedForwarded(r)
+	if len(elements) == 0 || elements[0].Host == "" {
+		return "", false
+	}
+	return elements[0].Host, true
+}
+
+// formatForwardedNode formats an IP for a Forwarded "for" parameter,
+// quoting and bracketing IPv6 addresses as RFC 7239 requires
+func formatForwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return `"[` + ip + `]"`
+	}
+	return ip
+}
+
+// quoteForwardedValue quotes a value if it contains characters outside the token set
+func quoteForwardedValue(v string) string {
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0) {
+			return `"` + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), `"`, `\"`) + `"`
+		}
+	}
+	return v
+}
+
+// BuildForwardedHeader returns the Forwarded header to send to a backend:
+// prior hops (only when trust_proxy is enabled) followed by Navigator's own entry
+func BuildForwardedHeader(r *http.Request) string {
+	var hops []string
+	if trustProxy.Load() {
+		prior := strings.Join(r.Header.Values("Forwarded"), ",")
+		if netutil.ParseForwarded(prior) != nil {
+			hops = append(hops, strings.TrimSpace(prior))
+		}
+	}
+
+	// Navigator's own hop names the peer it was connected to
+	clientIP, _ := netutil.HostIP(r.RemoteAddr)
+
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+
+	entry := []string{}
+	if clientIP != "" {
+		entry = append(entry, "for="+formatForwardedNode(clientIP))
+	} else {
+		entry = append(entry, "for=unknown")
+	}
+	entry = append(entry, "proto="+proto)
+	if r.Host != "" {
+		entry = append(entry, "host="+quoteForwardedValue(r.Host))
+	}
+
+	return strings.Join(append(hops, strings.Join(entry, ";")), ", ")
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestClientIPTrust(t *testing.T) {
+	defer SetTrustProxy(false)
+	defer SetForwardedPrecedence("")
+
+	SetTrustProxy(true)
+	if trust := ClientIPTrust(); !trust.TrustProxy || !trust.PreferForwarded {
+		t.Errorf("ClientIPTrust() = %+v, want trust_proxy and Forwarded preferred", trust)
+	}
+	SetTrustProxy(false)
+	SetForwardedPrecedence(config.ForwardedPrecedenceXForwarded)
+	if trust := ClientIPTrust(); trust.TrustProxy || trust.PreferForwarded {
+		t.Errorf("ClientIPTrust() = %+v, want neither", trust)
+	}
+}
+
+func TestBuildForwardedHeader(t *testing.T) {
+	defer SetTrustProxy(false)
+
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  string
+		remoteAddr string
+		expected   string
+	}{
+		{
+			name:       "no prior hops",
+			trustProxy: true,
+			remoteAddr: "192.0.2.1:1234",
+			expected:   "for=192.0.2.1;proto=http;host=example.com",
+		},
+		{
+			name:       "prior hops preserved when trusted",
+			trustProxy: true,
+			forwarded:  "for=198.51.100.17;proto=https",
+			remoteAddr: "192.0.2.1:1234",
+			expected:   "for=198.51.100.17;proto=https, for=192.0.2.1;proto=http;host=example.com",
+		},
+		{
+			name:       "prior hops dropped when untrusted",
+			trustProxy: false,
+			forwarded:  "for=198.51.100.17",
+			remoteAddr: "192.0.2.1:1234",
+			expected:   "for=192.0.2.1;proto=http;host=example.com",
+		},
+		{
+			name:       "malformed prior hops dropped",
+			trustProxy: true,
+			forwarded:  `for="198.51.100.17`,
+			remoteAddr: "192.0.2.1:1234",
+			expected:   "for=192.0.2.1;proto=http;host=example.com",
+		},
+		{
+			name:       "IPv6 client is bracketed and quoted",
+			trustProxy: true,
+			remoteAddr: "[2001:db8::1]:1234",
+			expected:   `for="[2001:db8::1]";proto=http;host=example.com`,
+		},
+		{
+			name:       "unparseable remote address",
+			trustProxy: true,
+			remoteAddr: "pipe",
+			expected:   "for=unknown;proto=http;host=example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetTrustProxy(tt.trustProxy)
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("Forwarded", tt.forwarded)
+			}
+
+			result := BuildForwardedHeader(req)
+			if result != tt.expected {
+				t.Errorf("BuildForwardedHeader() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestForwardedPrecedence(t *testing.T) {
+	defer SetForwardedPrecedence("")
+
+	if !PreferForwarded() {
+		t.Error("expected Forwarded to take precedence by default")
+	}
+	SetForwardedPrecedence(config.ForwardedPrecedenceXForwarded)
+	if PreferForwarded() {
+		t.Error("expected X-Forwarded-* to take precedence")
+	}
+	SetForwardedPrecedence(config.ForwardedPrecedenceForwarded)
+	if !PreferForwarded() {
+		t.Error("expected Forwarded to take precedence")
+	}
+}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// ErrBodyTooLarge is returned for a request whose body, of unknown length,
+// is larger than an outbound transport will read to learn its length
+var ErrBodyTooLarge = errors.New("request body too large to send with a Content-Length")
+
+// outboundTransportKey identifies the transports that can share connections
+type outboundTransportKey struct {
+	dns                    *DNSTransport
+	headerCase             string
+	identityTransfer       bool
+	suppressAcceptEncoding bool
+	disableRetries         bool
+	disableCompression     bool
+	maxBufferedBody        int64
+}
+
+var (
+	outboundTransportsMutex sync.Mutex
+	outboundTransports      = make(map[outboundTransportKey]*OutboundTransport)
+)
+
+// OutboundTransport writes requests to a reverse proxy route's target as
+// its outbound block asks. It always speaks HTTP/1.1, since the options
+// concern how an HTTP/1.1 request is written on the wire.
+type OutboundTransport struct {
+	transport *http.Transport
+	spellings map[string]string // Canonical header name -> spelling sent
+	identity  bool
+	maxBody   int64
+}
+
+// TransportForOutbound returns the shared transport for a route whose
+// outbound block is outbound, dialing through dns when the route has a dns
+// block (nil = dial as Go does)
+func TransportForOutbound(dns *DNSTransport, outbound *config.OutboundConfig) *OutboundTransport {
+	key := outboundTransportKey{
+		dns:                    dns,
+		headerCase:             strings.Join(outbound.HeaderCase, "\n"),
+		identityTransfer:       outbound.IdentityTransfer,
+		suppressAcceptEncoding: outbound.SuppressAcceptEncoding,
+		disableRetries:         outbound.DisableRetries,
+		disableCompression:     GetDisableCompression(),
+		maxBufferedBody:        outbound.MaxBufferedBody,
+	}
+
+	outboundTransportsMutex.Lock()
+	defer outboundTransportsMutex.Unlock()
+	if t, ok := outboundTransports[key]; ok {
+		return t
+	}
+	t := newOutboundTransport(outbound)
+	if dns != nil {
+		dns.dialFor(t.transport)
+	}
+	t.transport.DisableCompression = key.disableCompression || outbound.SuppressAcceptEncoding
+	outboundTransports[key] = t
+	return t
+}
+
+// forgetOutboundTransports drops the transports dialing through dns, once
+// it has been evicted, so they go with it
+func forgetOutboundTransports(dns *DNSTransport) {
+	outboundTransportsMutex.Lock()
+	defer outboundTransportsMutex.Unlock()
+	for key := range outboundTransports {
+		if key.dns == dns {
+			delete(outboundTransports, key)
+		}
+	}
+}
+
+// newOutboundTransport creates a transport writing requests as outbound asks
+func newOutboundTransport(outbound *config.OutboundConfig) *OutboundTransport {
+	t := &OutboundTransport{
+		transport: NewTransport(),
+		spellings: make(map[string]string, len(outbound.HeaderCase)),
+		identity:  outbound.IdentityTransfer,
+		maxBody:   outbound.MaxBufferedBody,
+	}
+	if t.maxBody <= 0 {
+		t.maxBody = config.DefaultMaxDecompressedRequestBody
+	}
+	for _, name := range outbound.HeaderCase {
+		t.spellings[http.CanonicalHeaderKey(name)] = name
+	}
+
+	// Go resends a failed request on a new connection only when the one it
+	// used was reused, so not reusing connections rules resending out
+	t.transport.DisableKeepAlives = outbound.DisableRetries
+	t.transport.ForceAttemptHTTP2 = false
+	t.transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	return t
+}
+
+// RoundTrip writes req with its headers spelled as configured and, for
+// identity transfer, a Content-Length and no TE header
+func (t *OutboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if t.identity {
+		out.Header.Del("Te")
+		out.TransferEncoding = nil
+		if out.ContentLength < 0 && out.Body != nil {
+			body, err := io.ReadAll(io.LimitReader(out.Body, t.maxBody+1))
+			_ = req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			if int64(len(body)) > t.maxBody {
+				return nil, ErrBodyTooLarge
+			}
+			out.ContentLength = int64(len(body))
+			out.Body = http.NoBody
+			out.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+			if len(body) > 0 {
+				out.Body = io.NopCloser(bytes.NewReader(body))
+				out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+			}
+		}
+	}
+	for canonical, spelling := range t.spellings {
+		if values, ok := out.Header[canonical]; ok && canonical != spelling {
+			delete(out.Header, canonical)
+			out.Header[spelling] = values
+		}
+	}
+	return t.transport.RoundTrip(out)
+}
+
+// CloseIdleConnections closes the transport's idle connections
+func (t *OutboundTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// rawBackend is a TCP server recording the exact bytes of each request it
+// reads. It answers each with 200, except that it hangs up without an
+// answer on the requests numbered in hangUp.
+type rawBackend struct {
+	listener net.Listener
+	hangUp   map[int]bool
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newRawBackend(t *testing.T, hangUp ...int) *rawBackend {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &rawBackend{listener: listener, hangUp: map[int]bool{}}
+	for _, n := range hangUp {
+		b.hangUp[n] = true
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *rawBackend) url() string {
+	return "http://" + b.listener.Addr().String()
+}
+
+func (b *rawBackend) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	for {
+		raw, err := readRawRequest(reader)
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.requests = append(b.requests, raw)
+		n := len(b.requests)
+		b.mu.Unlock()
+		if b.hangUp[n] {
+			return
+		}
+		if _, err := io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"); err != nil {
+			return
+		}
+	}
+}
+
+// received returns the requests read so far
+func (b *rawBackend) received() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.requests...)
+}
+
+// readRawRequest reads one request, with a Content-Length or chunked body,
+// exactly as it was sent
+func readRawRequest(r *bufio.Reader) (string, error) {
+	var raw strings.Builder
+	length, chunked := 0, false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		raw.WriteString(line)
+		if line == "\r\n" {
+			break
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch strings.ToLower(name) {
+		case "content-length":
+			length, _ = strconv.Atoi(strings.TrimSpace(value))
+		case "transfer-encoding":
+			chunked = strings.Contains(strings.ToLower(value), "chunked")
+		}
+	}
+	if !chunked {
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return "", err
+		}
+		raw.Write(body)
+		return raw.String(), nil
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		raw.WriteString(line)
+		size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		if err != nil {
+			return "", err
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return "", err
+		}
+		raw.Write(chunk)
+		if size == 0 {
+			return raw.String(), nil
+		}
+	}
+}
+
+func roundTrip(t *testing.T, transport http.RoundTripper, req *http.Request) error {
+	t.Helper()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return nil
+}
+
+func TestOutboundHeaderCase(t *testing.T) {
+	backend := newRawBackend(t)
+	transport := newOutboundTransport(&config.OutboundConfig{HeaderCase: []string{"X-API-Key", "SOAPAction"}})
+
+	req, _ := http.NewRequest("GET", backend.url()+"/device", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Soapaction", "urn:status")
+	req.Header.Set("X-Other", "unchanged")
+	if err := roundTrip(t, transport, req); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := backend.received()[0]
+	for _, line := range []string{"\r\nX-API-Key: secret\r\n", "\r\nSOAPAction: urn:status\r\n", "\r\nX-Other: unchanged\r\n"} {
+		if !strings.Contains(raw, line) {
+			t.Errorf("request lacks %q:\n%s", strings.TrimSpace(line), raw)
+		}
+	}
+	if strings.Contains(raw, "X-Api-Key") {
+		t.Errorf("canonical spelling sent:\n%s", raw)
+	}
+	if req.Header.Get("X-Api-Key") != "secret" {
+		t.Error("RoundTrip modified the caller's request headers")
+	}
+}
+
+func TestOutboundIdentityTransfer(t *testing.T) {
+	backend := newRawBackend(t)
+	transport := newOutboundTransport(&config.OutboundConfig{IdentityTransfer: true, MaxBufferedBody: 16})
+
+	// A body of unknown length is read to learn its Content-Length
+	req, _ := http.NewRequest("POST", backend.url()+"/upload", io.NopCloser(strings.NewReader("hello")))
+	req.ContentLength = -1
+	req.Header.Set("Te", "trailers")
+	if err := roundTrip(t, transport, req); err != nil {
+		t.Fatal(err)
+	}
+	raw := backend.received()[0]
+	if !strings.Contains(raw, "\r\nContent-Length: 5\r\n") {
+		t.Errorf("request lacks Content-Length: 5:\n%s", raw)
+	}
+	if !strings.HasSuffix(raw, "\r\n\r\nhello") {
+		t.Errorf("body not sent as is:\n%q", raw)
+	}
+	if strings.Contains(raw, "Transfer-Encoding") || strings.Contains(raw, "\r\nTe:") {
+		t.Errorf("request has Transfer-Encoding or TE:\n%s", raw)
+	}
+
+	// A body too large to buffer is refused before anything is sent
+	req, _ = http.NewRequest("POST", backend.url()+"/upload", io.NopCloser(bytes.NewReader(make([]byte, 17))))
+	req.ContentLength = -1
+	if err := roundTrip(t, transport, req); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("RoundTrip() error = %v, want ErrBodyTooLarge", err)
+	}
+	if n := len(backend.received()); n != 1 {
+		t.Errorf("backend read %d requests, want 1", n)
+	}
+
+	// Without identity transfer Go chunks it
+	req, _ = http.NewRequest("POST", backend.url()+"/upload", io.NopCloser(strings.NewReader("hello")))
+	req.ContentLength = -1
+	if err := roundTrip(t, newOutboundTransport(&config.OutboundConfig{}), req); err != nil {
+		t.Fatal(err)
+	}
+	if raw := backend.received()[1]; !strings.Contains(raw, "\r\nTransfer-Encoding: chunked\r\n") {
+		t.Errorf("default transport didn't chunk:\n%s", raw)
+	}
+}
+
+func TestOutboundSuppressAcceptEncoding(t *testing.T) {
+	backend := newRawBackend(t)
+	for _, suppress := range []bool{false, true} {
+		transport := TransportForOutbound(nil, &config.OutboundConfig{SuppressAcceptEncoding: suppress})
+		req, _ := http.NewRequest("GET", backend.url()+"/", nil)
+		if err := roundTrip(t, transport, req); err != nil {
+			t.Fatal(err)
+		}
+		transport.CloseIdleConnections()
+	}
+	requests := backend.received()
+	if !strings.Contains(requests[0], "\r\nAccept-Encoding: gzip\r\n") {
+		t.Errorf("Go didn't add Accept-Encoding:\n%s", requests[0])
+	}
+	if strings.Contains(requests[1], "Accept-Encoding") {
+		t.Errorf("Accept-Encoding sent despite suppress_accept_encoding:\n%s", requests[1])
+	}
+}
+
+func TestOutboundDisableRetries(t *testing.T) {
+	for _, disable := range []bool{false, true} {
+		t.Run(fmt.Sprintf("disable_retries=%v", disable), func(t *testing.T) {
+			// The second request read is dropped without an answer
+			backend := newRawBackend(t, 2)
+			transport := newOutboundTransport(&config.OutboundConfig{DisableRetries: disable})
+			defer transport.CloseIdleConnections()
+
+			var errs []error
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest("GET", backend.url()+"/status", nil)
+				errs = append(errs, roundTrip(t, transport, req))
+			}
+			requests := backend.received()
+
+			if !disable {
+				// Go resends the GET that failed on the reused connection
+				if errs[1] != nil || len(requests) != 3 {
+					t.Errorf("second request error = %v after %d requests, want a retry succeeding", errs[1], len(requests))
+				}
+				return
+			}
+			if errs[0] != nil || errs[1] == nil {
+				t.Errorf("errors = %v, want only the dropped request to fail", errs)
+			}
+			if len(requests) != 2 {
+				t.Errorf("backend read %d requests, want 2 with no retry", len(requests))
+			}
+			for _, raw := range requests {
+				if !strings.Contains(raw, "\r\nConnection: close\r\n") {
+					t.Errorf("request doesn't close its connection:\n%s", raw)
+				}
+			}
+		})
+	}
+}
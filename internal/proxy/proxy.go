@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -11,11 +12,14 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/netutil"
 )
 
 // trustProxy indicates whether to trust X-Forwarded-* headers from upstream proxy
@@ -28,6 +32,10 @@ var trustProxy atomic.Bool
 // This is recommended for reverse proxy scenarios to ensure pure pass-through behavior.
 var disableCompression atomic.Bool
 
+// retryClock times the retry backoff and its overall limit; tests replace it
+// with a fake clock
+var retryClock = clock.Real
+
 // SetTrustProxy configures whether to trust X-Forwarded-* headers
 func SetTrustProxy(trust bool) {
 	trustProxy.Store(trust)
@@ -59,23 +67,29 @@ func createProxyErrorHandler(targetURL string) func(http.ResponseWriter, *http.R
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		// Check if client disconnected during the request
 		if r.Context().Err() == context.Canceled {
-			// Client closed connection (similar to nginx 499)
-			logging.LogProxyClientDisconnected(targetURL, err)
-
-			// Update metadata if ResponseWriter supports it
-			if recorder, ok := w.(MetadataSetter); ok {
-				recorder.SetMetadata("response_type", "client_closed")
-			}
-
-			w.WriteHeader(499)
+			ClientClosed(w, targetURL, err)
 			return
 		}
 		// Actual proxy error
 		logging.LogProxyError(targetURL, err)
+		if recorder, ok := w.(MetadataSetter); ok {
+			recorder.SetMetadata("error_message", err.Error())
+			recorder.SetMetadata("upstream_error", err.Error())
+		}
 		http.Error(w, "Bad Gateway", http.StatusBadGateway)
 	}
 }
 
+// ClientClosed answers a request whose client disconnected before the
+// backend's response was complete, with nginx's 499
+func ClientClosed(w http.ResponseWriter, targetURL string, err error) {
+	logging.LogProxyClientDisconnected(targetURL, err)
+	if recorder, ok := w.(MetadataSetter); ok {
+		recorder.SetMetadata("response_type", "client_closed")
+	}
+	w.WriteHeader(499)
+}
+
 // HandleProxy handles proxying requests to a target URL
 func HandleProxy(w http.ResponseWriter, r *http.Request, targetURL string) {
 	target, err := url.Parse(targetURL)
@@ -87,12 +101,8 @@ func HandleProxy(w http.ResponseWriter, r *http.Request, targetURL string) {
 	// Create reverse proxy
 	proxy := httputil.NewSingleHostReverseProxy(target)
 
-	// Apply custom transport if compression should be disabled
-	if disableCompression.Load() {
-		proxy.Transport = &http.Transport{
-			DisableCompression: true,
-		}
-	}
+	// Honor disable_compression and proxy.use_env_proxy
+	proxy.Transport = DefaultTransport()
 
 	// Customize the director to modify the request
 	originalDirector := proxy.Director
@@ -101,7 +111,7 @@ func HandleProxy(w http.ResponseWriter, r *http.Request, targetURL string) {
 
 		// Preserve X-Forwarded headers
 		if req.Header.Get("X-Forwarded-For") == "" {
-			req.Header.Set("X-Forwarded-For", r.RemoteAddr)
+			req.Header.Set("X-Forwarded-For", netutil.ClientIP(r, ClientIPTrust()))
 		}
 
 		// DEBUG: Log trust_proxy state and incoming X-Forwarded-Host
@@ -126,6 +136,9 @@ func HandleProxy(w http.ResponseWriter, r *http.Request, targetURL string) {
 			req.Header.Set("X-Forwarded-Proto", "http")
 		}
 
+		// Emit RFC 7239 Forwarded header combining prior hops with our own entry
+		req.Header.Set("Forwarded", BuildForwardedHeader(r))
+
 		// Path rewriting removed with legacy Location configuration
 	}
 
@@ -144,32 +157,38 @@ func HandleProxyWithRetry(w http.ResponseWriter, r *http.Request, targetURL stri
 		return
 	}
 
-	// Only retry for safe methods
-	canRetry := r.Method == "GET" || r.Method == "HEAD"
+	// GET and HEAD are retried by re-issuing the request, so their responses
+	// stream straight through. Other idempotent methods are retried only when
+	// their body can be buffered for replay, and their response is buffered
+	// until it succeeds. POST and PATCH are never retried.
+	canRetry := r.Method == http.MethodGet || r.Method == http.MethodHead
 
-	// Use RetryResponseWriter for safe methods
-	var responseWriter http.ResponseWriter
+	var responseWriter http.ResponseWriter = w
 	var retryWriter *RetryResponseWriter
-	if canRetry {
-		retryWriter = NewRetryResponseWriter(w)
-		responseWriter = retryWriter
-	} else {
-		responseWriter = w
+	var body *requestBodyBuffer
+	if replayableMethod(r.Method) {
+		var ok bool
+		if body, ok = bufferRequestBody(r); ok {
+			defer body.release()
+			canRetry = true
+			retryWriter = NewRetryResponseWriter(w)
+			defer retryWriter.release()
+			responseWriter = retryWriter
+		}
 	}
 
+	// Headers set before proxying; a failed attempt's headers are discarded
+	initialHeader := w.Header().Clone()
+
 	// Use default transport - no custom connection timeout needed
 	// The 500ms ProxyRetryMaxDelay is for retry backoff, not connection timeout
 	proxy := httputil.NewSingleHostReverseProxy(target)
 
-	// Apply custom transport if compression should be disabled
-	if disableCompression.Load() {
-		proxy.Transport = &http.Transport{
-			DisableCompression: true,
-		}
-	}
+	// Honor disable_compression and proxy.use_env_proxy
+	proxy.Transport = DefaultTransport()
 
 	// Implement retry logic
-	startTime := time.Now()
+	startTime := retryClock.Now()
 	attempt := 0
 	initialDelay := config.ProxyRetryInitialDelay
 	maxDelay := config.ProxyRetryMaxDelay
@@ -187,9 +206,12 @@ func HandleProxyWithRetry(w http.ResponseWriter, r *http.Request, targetURL stri
 			}
 			retryWriter.Reset()
 		}
+		if body != nil {
+			body.rewind(r)
+		}
 
 		// Try the proxy request
-		success := tryProxy(proxy, responseWriter, r)
+		success, committed := tryProxy(proxy, responseWriter, r)
 		if success {
 			// Commit the buffered response if using retry writer
 			if retryWriter != nil {
@@ -198,6 +220,18 @@ func HandleProxyWithRetry(w http.ResponseWriter, r *http.Request, targetURL stri
 			return
 		}
 
+		// A streamed response that already reached the client can't be retried
+		if committed && retryWriter == nil {
+			return
+		}
+		resetHeader(w.Header(), initialHeader)
+
+		// Nobody is waiting for a retry once the client has gone
+		if r.Context().Err() != nil {
+			ClientClosed(w, targetURL, r.Context().Err())
+			return
+		}
+
 		// If we can't retry, fail immediately
 		if !canRetry {
 			http.Error(w, "Bad Gateway", http.StatusBadGateway)
@@ -205,8 +239,8 @@ func HandleProxyWithRetry(w http.ResponseWriter, r *http.Request, targetURL stri
 		}
 
 		// Check if we've exceeded max retry duration
-		if time.Since(startTime) >= maxRetryDuration {
-			logging.LogProxyRetryExhausted(targetURL, attempt, time.Since(startTime))
+		if elapsed := retryClock.Now().Sub(startTime); elapsed >= maxRetryDuration {
+			logging.LogProxyRetryExhausted(targetURL, attempt, elapsed)
 			http.Error(w, "Bad Gateway", http.StatusBadGateway)
 			return
 		}
@@ -219,12 +253,40 @@ func HandleProxyWithRetry(w http.ResponseWriter, r *http.Request, targetURL stri
 
 		logging.LogProxyRetry(targetURL, attempt, delay)
 
-		time.Sleep(delay)
+		timer := retryClock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-r.Context().Done():
+			timer.Stop()
+			ClientClosed(w, targetURL, r.Context().Err())
+			return
+		}
+	}
+}
+
+// replayableMethod reports whether a method is idempotent but carries a body
+// that must be buffered to retry it
+func replayableMethod(method string) bool {
+	switch method {
+	case http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// resetHeader restores header to a copy of saved
+func resetHeader(header, saved http.Header) {
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range saved {
+		header[key] = append([]string(nil), values...)
 	}
 }
 
-// tryProxy attempts a single proxy request
-func tryProxy(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request) bool {
+// tryProxy attempts a single proxy request, reporting whether it succeeded and
+// whether anything was written to w
+func tryProxy(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request) (bool, bool) {
 	// Use a custom response writer to capture errors
 	recorder := &proxyRecorder{
 		ResponseWriter: w,
@@ -237,13 +299,14 @@ func tryProxy(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Reque
 	}
 
 	proxy.ServeHTTP(recorder, r)
-	return recorder.success
+	return recorder.success, recorder.committed
 }
 
 // proxyRecorder captures proxy success/failure
 type proxyRecorder struct {
 	http.ResponseWriter
 	success    bool
+	committed  bool // Set once a status or body has been passed to ResponseWriter
 	statusCode int
 }
 
@@ -257,6 +320,7 @@ func (pr *proxyRecorder) WriteHeader(statusCode int) {
 		return
 	}
 	// Propagate non-502 status codes (success or non-retryable errors)
+	pr.committed = true
 	pr.ResponseWriter.WriteHeader(statusCode)
 }
 
@@ -266,6 +330,7 @@ func (pr *proxyRecorder) Write(b []byte) (int, error) {
 		// Don't propagate response body for failed requests that may be retried
 		return len(b), nil
 	}
+	pr.committed = true
 	return pr.ResponseWriter.Write(b)
 }
 
@@ -280,6 +345,7 @@ type WebSocketTracker struct {
 	http.ResponseWriter
 	ActiveWebSockets *int32
 	Cleaned          bool
+	Frame            func() // Called for each data frame from the client (nil = not needed)
 }
 
 // Hijack implements http.Hijacker interface for WebSocket support
@@ -292,12 +358,17 @@ func (w *WebSocketTracker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 			return nil, nil, err
 		}
 
-		// Wrap the connection to detect when it's closed
-		return &webSocketConn{
+		// Wrap the connection to detect when it's closed. When w wraps the
+		// server's ResponseRecorder, conn is its counting connection, so the
+		// counter is decremented in the same Close that emits the access log.
+		wsConn := &webSocketConn{
 			Conn:             conn,
 			ActiveWebSockets: w.ActiveWebSockets,
-			Cleaned:          false,
-		}, rw, nil
+		}
+		if w.Frame != nil {
+			wsConn.frames = &frameScanner{onData: w.Frame}
+		}
+		return wsConn, rw, nil
 	}
 	return nil, nil, fmt.Errorf("ResponseWriter does not support hijacking")
 }
@@ -314,21 +385,53 @@ func (w *WebSocketTracker) cleanup() {
 type webSocketConn struct {
 	net.Conn
 	ActiveWebSockets *int32
-	Cleaned          bool
+	once             sync.Once
+	frames           *frameScanner // Follows the client's frames (nil = not needed)
 }
 
-func (c *webSocketConn) Close() error {
-	if !c.Cleaned && c.ActiveWebSockets != nil {
-		atomic.AddInt32(c.ActiveWebSockets, -1)
-		c.Cleaned = true
-		logging.LogWebSocketConnectionClosed(atomic.LoadInt32(c.ActiveWebSockets))
+// Read reads from the client, following its frames
+func (c *webSocketConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if c.frames != nil && n > 0 {
+		c.frames.scan(p[:n])
 	}
+	return n, err
+}
+
+// Close decrements the counter exactly once, even if the copy goroutines
+// race to close the connection
+func (c *webSocketConn) Close() error {
+	c.once.Do(func() {
+		if c.ActiveWebSockets != nil {
+			atomic.AddInt32(c.ActiveWebSockets, -1)
+			logging.LogWebSocketConnectionClosed(atomic.LoadInt32(c.ActiveWebSockets))
+		}
+	})
 	return c.Conn.Close()
 }
 
 // ProxyWithWebSocketSupport handles both HTTP and WebSocket proxying
 // Note: Health checks ensure apps are ready before proxying, so no retry needed
 func ProxyWithWebSocketSupport(w http.ResponseWriter, r *http.Request, targetURL string, activeWebSockets *int32) {
+	ProxyToApp(w, r, targetURL, activeWebSockets, nil)
+}
+
+// IsConnectionRefused reports whether err is a dial to a port nothing listens on
+func IsConnectionRefused(err error) bool {
+	return errors.Is(err, errConnectionRefused)
+}
+
+// IsConnectionReset reports whether err is a connection dropped by the
+// peer, such as a kept-alive connection to a process that has died
+func IsConnectionReset(err error) bool {
+	return errors.Is(err, errConnectionReset)
+}
+
+// ProxyToApp proxies like ProxyWithWebSocketSupport. When the app's port
+// refuses or resets the connection, nothing has been written yet, so
+// onUnreachable is first called with the original request and the error; it
+// returns false if it didn't handle the request, which then fails with 502.
+func ProxyToApp(w http.ResponseWriter, r *http.Request, targetURL string, activeWebSockets *int32, onUnreachable func(http.ResponseWriter, *http.Request, error) bool) {
 	target, err := url.Parse(targetURL)
 	if err != nil {
 		http.Error(w, "Invalid proxy target", http.StatusInternalServerError)
@@ -345,7 +448,7 @@ func ProxyWithWebSocketSupport(w http.ResponseWriter, r *http.Request, targetURL
 
 		// Preserve X-Forwarded headers
 		if req.Header.Get("X-Forwarded-For") == "" {
-			req.Header.Set("X-Forwarded-For", r.RemoteAddr)
+			req.Header.Set("X-Forwarded-For", netutil.ClientIP(r, ClientIPTrust()))
 		}
 
 		// DEBUG: Log trust_proxy state and incoming X-Forwarded-Host
@@ -369,10 +472,22 @@ func ProxyWithWebSocketSupport(w http.ResponseWriter, r *http.Request, targetURL
 		if req.Header.Get("X-Forwarded-Proto") == "" {
 			req.Header.Set("X-Forwarded-Proto", "http")
 		}
+
+		// Emit RFC 7239 Forwarded header combining prior hops with our own entry
+		req.Header.Set("Forwarded", BuildForwardedHeader(r))
 	}
 
 	// Set error handler
 	proxy.ErrorHandler = createProxyErrorHandler(targetURL)
+	if onUnreachable != nil && !IsWebSocketRequest(r) {
+		handleError := proxy.ErrorHandler
+		proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+			if (IsConnectionRefused(err) || IsConnectionReset(err)) && r.Context().Err() == nil && onUnreachable(w, r, err) {
+				return
+			}
+			handleError(w, req, err)
+		}
+	}
 
 	// Check if this is a WebSocket request and tracking is enabled
 	if IsWebSocketRequest(r) && activeWebSockets != nil {
@@ -385,6 +500,7 @@ func ProxyWithWebSocketSupport(w http.ResponseWriter, r *http.Request, targetURL
 			ResponseWriter:   w,
 			ActiveWebSockets: activeWebSockets,
 			Cleaned:          false,
+			Frame:            frameActivity(r.Context()),
 		}
 	}
 
@@ -394,7 +510,8 @@ func ProxyWithWebSocketSupport(w http.ResponseWriter, r *http.Request, targetURL
 
 // RetryResponseWriter buffers responses to enable retry on failure
 // Note: Only buffers responses up to MaxRetryBufferSize (64KB) to prevent memory issues
-// Large responses automatically switch to streaming mode
+// Large responses, or any response once the global retry buffer limit is
+// reached, automatically switch to streaming mode
 type RetryResponseWriter struct {
 	http.ResponseWriter
 	statusCode     int
@@ -402,6 +519,7 @@ type RetryResponseWriter struct {
 	headers        http.Header
 	written        bool
 	bufferLimitHit bool
+	reserved       int64 // Bytes claimed from the global retry buffer budget
 }
 
 // MaxRetryBufferSize limits how much response data we buffer for retries
@@ -441,8 +559,9 @@ func (w *RetryResponseWriter) Write(b []byte) (int, error) {
 			buffered := 0
 			if !w.bufferLimitHit && w.body.Len() < MaxRetryBufferSize {
 				remaining := MaxRetryBufferSize - w.body.Len()
-				w.body.Write(b[:remaining])
-				buffered = remaining
+				if w.buffer(b[:remaining]) {
+					buffered = remaining
+				}
 			}
 			w.bufferLimitHit = true
 			// Commit the buffer before switching to streaming mode
@@ -461,11 +580,33 @@ func (w *RetryResponseWriter) Write(b []byte) (int, error) {
 			// All bytes were buffered
 			return buffered, nil
 		}
-		return w.body.Write(b)
+		if w.buffer(b) {
+			return len(b), nil
+		}
+
+		// The global retry buffer limit was reached: stream from here on
+		w.bufferLimitHit = true
+		w.Commit()
 	}
 	return w.ResponseWriter.Write(b)
 }
 
+// buffer appends b to the body if the global retry buffer budget allows it
+func (w *RetryResponseWriter) buffer(b []byte) bool {
+	if !reserveRetryBuffer(int64(len(b))) {
+		return false
+	}
+	w.reserved += int64(len(b))
+	w.body.Write(b)
+	return true
+}
+
+// release returns the buffered body's memory to the global budget
+func (w *RetryResponseWriter) release() {
+	releaseRetryBuffer(w.reserved)
+	w.reserved = 0
+}
+
 // Commit writes the buffered response to the underlying ResponseWriter
 func (w *RetryResponseWriter) Commit() {
 	if w.written {
@@ -487,12 +628,15 @@ func (w *RetryResponseWriter) Commit() {
 	if w.body.Len() > 0 {
 		_, _ = w.ResponseWriter.Write(w.body.Bytes())
 	}
+	w.body.Reset()
+	w.release()
 }
 
 // Reset clears the buffer for retry
 func (w *RetryResponseWriter) Reset() {
 	w.statusCode = 0
 	w.body.Reset()
+	w.release()
 	w.headers = make(http.Header)
 	w.bufferLimitHit = false
 }
@@ -9,8 +9,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/rubys/navigator/internal/clock"
 )
 
 func TestIsWebSocketRequest(t *testing.T) {
@@ -392,8 +395,8 @@ func TestHandleProxy(t *testing.T) {
 
 				// Verify forwarded headers were set
 				body := recorder.Body.String()
-				if !strings.Contains(body, "192.168.1.100") {
-					t.Error("X-Forwarded-For not set correctly")
+				if !strings.Contains(body, "192.168.1.100") || strings.Contains(body, "192.168.1.100:") {
+					t.Errorf("X-Forwarded-For not set correctly: %s", body)
 				}
 				if !strings.Contains(body, "example.com") {
 					t.Error("X-Forwarded-Host not set correctly")
@@ -428,22 +431,35 @@ func TestHandleProxyWithRetry(t *testing.T) {
 		t.Errorf("Expected 'success', got %q", body)
 	}
 
-	// Test 2: Invalid URL that will cause connection failure
+	// Test 2: A backend that drops every connection is retried with backoff
+	// until the retry duration has passed, then 502 Bad Gateway
+	clk := useFakeRetryClock(t)
+	attempts := droppingBackend(t)
 	req2 := httptest.NewRequest("GET", "/api/test", nil)
 	recorder2 := httptest.NewRecorder()
 
-	HandleProxyWithRetry(recorder2, req2, "http://invalid-host-that-does-not-exist:12345", 1*time.Second)
+	start := clk.Now()
+	advanceWhileRunning(clk, func() {
+		HandleProxyWithRetry(recorder2, req2, attempts.url, 1*time.Second)
+	})
 
-	// Should result in 502 Bad Gateway after retries
 	if recorder2.Code != http.StatusBadGateway {
 		t.Errorf("Expected status 502 for invalid backend, got %d", recorder2.Code)
 	}
+	// Backoff of 100ms, 200ms, 400ms, then 500ms: the fifth attempt is
+	// past the retry duration
+	if n := attempts.count.Load(); n != 5 {
+		t.Errorf("Backend saw %d attempts, want 5", n)
+	}
+	if waited := clk.Now().Sub(start); waited != 1200*time.Millisecond {
+		t.Errorf("Backoff waited %v, want 1.2s", waited)
+	}
 
 	// Test 3: POST request should not retry even on failure
 	req3 := httptest.NewRequest("POST", "/api/test", nil)
 	recorder3 := httptest.NewRecorder()
 
-	start := time.Now()
+	start = time.Now()
 	HandleProxyWithRetry(recorder3, req3, "http://invalid-host-that-does-not-exist:12345", 3*time.Second)
 	duration := time.Since(start)
 
@@ -466,6 +482,62 @@ func TestHandleProxyWithRetry(t *testing.T) {
 	}
 }
 
+// useFakeRetryClock times the retry backoff with a fake clock for the test
+func useFakeRetryClock(t *testing.T) *clock.Fake {
+	t.Helper()
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	retryClock = clk
+	t.Cleanup(func() { retryClock = clock.Real })
+	return clk
+}
+
+// advanceWhileRunning runs fn, advancing clk past each timer fn waits on
+func advanceWhileRunning(clk *clock.Fake, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if !clk.AdvanceToNext() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// dropped counts the connections a dropping backend accepted
+type dropped struct {
+	url   string
+	count atomic.Int32
+}
+
+// droppingBackend accepts connections and closes them without answering
+func droppingBackend(t *testing.T) *dropped {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	backend := &dropped{url: "http://" + listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			backend.count.Add(1)
+			_ = conn.Close()
+		}
+	}()
+	return backend
+}
+
 func TestProxyWithWebSocketSupport(t *testing.T) {
 	// Create WebSocket backend
 	wsBackend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
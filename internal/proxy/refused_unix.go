@@ -0,0 +1,11 @@
+//go:build unix
+
+package proxy
+
+import "syscall"
+
+// errConnectionRefused is the dial error when nothing listens on a port
+var errConnectionRefused error = syscall.ECONNREFUSED
+
+// errConnectionReset is the error when the peer drops an open connection
+var errConnectionReset error = syscall.ECONNRESET
@@ -0,0 +1,13 @@
+//go:build windows
+
+package proxy
+
+import "syscall"
+
+// errConnectionRefused is the dial error when nothing listens on a port
+// (WSAECONNREFUSED, which syscall doesn't name)
+var errConnectionRefused error = syscall.Errno(10061)
+
+// errConnectionReset is the error when the peer drops an open connection
+// (WSAECONNRESET)
+var errConnectionReset error = syscall.Errno(10054)
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// retryBuffers accounts for memory held by every in-flight retry buffer so a
+// burst of concurrent requests can't hold more than the limit between them
+var retryBuffers = struct {
+	limit   atomic.Int64
+	inUse   atomic.Int64
+	skipped atomic.Int64 // Requests proxied without buffering because the limit was reached
+}{}
+
+func init() {
+	retryBuffers.limit.Store(config.MaxRetryBufferTotal)
+}
+
+// RetryBufferStats reports memory used by proxy retry buffers
+type RetryBufferStats struct {
+	InUse   int64 `json:"in_use"`  // Bytes currently buffered
+	Limit   int64 `json:"limit"`   // Maximum bytes buffered at once
+	Skipped int64 `json:"skipped"` // Requests that were not buffered (and so not retryable) because of the limit
+}
+
+// GetRetryBufferStats returns current retry buffer usage
+func GetRetryBufferStats() RetryBufferStats {
+	return RetryBufferStats{
+		InUse:   retryBuffers.inUse.Load(),
+		Limit:   retryBuffers.limit.Load(),
+		Skipped: retryBuffers.skipped.Load(),
+	}
+}
+
+// reserveRetryBuffer claims n bytes of the global retry buffer budget,
+// returning false if that would exceed the limit
+func reserveRetryBuffer(n int64) bool {
+	for {
+		used := retryBuffers.inUse.Load()
+		if used+n > retryBuffers.limit.Load() {
+			retryBuffers.skipped.Add(1)
+			logging.LogRetryBufferLimitReached(n, used, retryBuffers.limit.Load())
+			return false
+		}
+		if retryBuffers.inUse.CompareAndSwap(used, used+n) {
+			return true
+		}
+	}
+}
+
+// releaseRetryBuffer returns n bytes to the global retry buffer budget
+func releaseRetryBuffer(n int64) {
+	if n > 0 {
+		retryBuffers.inUse.Add(-n)
+	}
+}
+
+// requestBodyBuffer holds a request body in memory so it can be replayed on retry
+type requestBodyBuffer struct {
+	data     []byte
+	reserved int64
+}
+
+// bufferRequestBody reads r's body into memory so the request can be retried.
+// Returns false, leaving the body readable, when the body is larger than
+// MaxRetryBufferSize or the global retry buffer limit has been reached.
+func bufferRequestBody(r *http.Request) (*requestBodyBuffer, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return &requestBodyBuffer{}, true
+	}
+	if r.ContentLength > MaxRetryBufferSize {
+		return nil, false
+	}
+
+	// Reserve the most the body can be before reading it
+	reserve := int64(MaxRetryBufferSize)
+	if r.ContentLength >= 0 {
+		reserve = r.ContentLength
+	}
+	if !reserveRetryBuffer(reserve) {
+		return nil, false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxRetryBufferSize+1))
+	if err != nil || len(data) > MaxRetryBufferSize {
+		// Too large (or unreadable) to replay: hand back what was read followed by the rest
+		releaseRetryBuffer(reserve)
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+		return nil, false
+	}
+
+	// Give back whatever the body didn't use
+	releaseRetryBuffer(reserve - int64(len(data)))
+	return &requestBodyBuffer{data: data, reserved: int64(len(data))}, true
+}
+
+// rewind sets r's body to a fresh reader over the buffered data
+func (b *requestBodyBuffer) rewind(r *http.Request) {
+	if b.data == nil {
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b.data))
+	r.ContentLength = int64(len(b.data))
+}
+
+// release returns the buffer's memory to the global budget
+func (b *requestBodyBuffer) release() {
+	releaseRetryBuffer(b.reserved)
+	b.reserved = 0
+	b.data = nil
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// setRetryBufferLimit changes the global retry buffer limit for the duration of a test
+func setRetryBufferLimit(t *testing.T, limit int64) {
+	t.Helper()
+	retryBuffers.limit.Store(limit)
+	t.Cleanup(func() { retryBuffers.limit.Store(config.MaxRetryBufferTotal) })
+}
+
+func TestRetryBufferGlobalLimitUnderConcurrentLoad(t *testing.T) {
+	const (
+		limit    = 256 * 1024
+		requests = 50
+		size     = 60 * 1024 // Under MaxRetryBufferSize, but 50 of them are far over limit
+	)
+	setRetryBufferLimit(t, limit)
+	skippedBefore := retryBuffers.skipped.Load()
+
+	release := make(chan struct{})
+	var arrived atomic.Int32
+	payload := bytes.Repeat([]byte("r"), size)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		arrived.Add(1)
+		<-release
+		_, _ = w.Write(payload)
+	}))
+	defer backend.Close()
+
+	// Sample usage while requests hold their buffers
+	var peak atomic.Int64
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			if used := GetRetryBufferStats().InUse; used > peak.Load() {
+				peak.Store(used)
+			}
+			select {
+			case <-stop:
+				return
+			default:
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	codes := make([]int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPut, "/upload", bytes.NewReader(bytes.Repeat([]byte("b"), size)))
+			recorder := httptest.NewRecorder()
+			HandleProxyWithRetry(recorder, req, backend.URL, time.Second)
+			codes[i] = recorder.Code
+			if recorder.Body.Len() != size {
+				t.Errorf("request %d: body length %d, want %d", i, recorder.Body.Len(), size)
+			}
+		}(i)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for arrived.Load() < requests && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(stop)
+	<-sampled
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: status %d, want 200", i, code)
+		}
+	}
+	if p := peak.Load(); p > limit {
+		t.Errorf("peak retry buffer usage %d exceeded limit %d", p, limit)
+	}
+	if retryBuffers.skipped.Load() == skippedBefore {
+		t.Error("expected some requests to skip buffering once the limit was reached")
+	}
+	if used := GetRetryBufferStats().InUse; used != 0 {
+		t.Errorf("retry buffers still hold %d bytes after all requests finished", used)
+	}
+}
+
+func TestRetryGETStreamsWithoutBuffering(t *testing.T) {
+	var attempts atomic.Int32
+	var usedDuringResponse atomic.Int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("X-Attempt", "first")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("X-Attempt", "second")
+		_, _ = w.Write(bytes.Repeat([]byte("g"), 32*1024))
+		w.(http.Flusher).Flush()
+		usedDuringResponse.Store(GetRetryBufferStats().InUse)
+	}))
+	defer backend.Close()
+
+	recorder := httptest.NewRecorder()
+	HandleProxyWithRetry(recorder, httptest.NewRequest(http.MethodGet, "/page", nil), backend.URL, 2*time.Second)
+
+	if recorder.Code != http.StatusOK || recorder.Body.Len() != 32*1024 {
+		t.Errorf("status = %d, body length = %d", recorder.Code, recorder.Body.Len())
+	}
+	if got := recorder.Header().Values("X-Attempt"); len(got) != 1 || got[0] != "second" {
+		t.Errorf("X-Attempt = %v, want only the successful attempt's header", got)
+	}
+	if used := usedDuringResponse.Load(); used != 0 {
+		t.Errorf("GET response used %d bytes of retry buffer", used)
+	}
+}
+
+func TestRetryPUTReplaysBody(t *testing.T) {
+	var attempts atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer backend.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/record", bytes.NewReader([]byte("replayed body")))
+	recorder := httptest.NewRecorder()
+	HandleProxyWithRetry(recorder, req, backend.URL, 2*time.Second)
+
+	if attempts.Load() != 2 {
+		t.Errorf("attempts = %d, want 2", attempts.Load())
+	}
+	if recorder.Body.String() != "replayed body" {
+		t.Errorf("retried request body = %q", recorder.Body.String())
+	}
+}
+
+func TestRetryPUTWithoutBufferIsNotRetried(t *testing.T) {
+	setRetryBufferLimit(t, 0)
+
+	var attempts atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/record", bytes.NewReader([]byte("body")))
+	recorder := httptest.NewRecorder()
+	HandleProxyWithRetry(recorder, req, backend.URL, 2*time.Second)
+
+	if attempts.Load() != 1 {
+		t.Errorf("attempts = %d, want 1 when the body could not be buffered", attempts.Load())
+	}
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", recorder.Code)
+	}
+}
+
+func TestRetryPUTAbortsWhenClientDisconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		cancel() // The client gives up while the backend is failing
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/record", bytes.NewReader([]byte("body"))).WithContext(ctx)
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	HandleProxyWithRetry(recorder, req, backend.URL, 2*time.Second)
+
+	if attempts.Load() != 1 {
+		t.Errorf("attempts = %d, want no retry once the client has gone", attempts.Load())
+	}
+	if recorder.Code != 499 {
+		t.Errorf("status = %d, want 499", recorder.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v, want an immediate abort", elapsed)
+	}
+}
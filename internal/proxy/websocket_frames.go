@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+)
+
+// frameActivityKey holds the func told about a proxied WebSocket's client
+// data frames
+type frameActivityKey struct{}
+
+// WithFrameActivity returns a context under which a tracked WebSocket
+// connection calls frame for each data frame its client sends
+func WithFrameActivity(ctx context.Context, frame func()) context.Context {
+	return context.WithValue(ctx, frameActivityKey{}, frame)
+}
+
+// frameActivity returns the func set by WithFrameActivity, or nil
+func frameActivity(ctx context.Context) func() {
+	frame, _ := ctx.Value(frameActivityKey{}).(func())
+	return frame
+}
+
+// frameScanner follows the frames of a WebSocket stream as it is copied,
+// calling onData at the start of each data frame. Control frames (close,
+// ping, and pong) are skipped.
+type frameScanner struct {
+	onData func()
+	header []byte // Header of the next frame, as far as it has been read
+	skip   uint64 // Payload bytes of the current frame still to pass
+}
+
+// scan follows the frames in p, the next bytes of the stream
+func (s *frameScanner) scan(p []byte) {
+	for len(p) > 0 {
+		if s.skip > 0 {
+			n := min(uint64(len(p)), s.skip)
+			s.skip -= n
+			p = p[n:]
+			continue
+		}
+
+		s.header = append(s.header, p[0])
+		p = p[1:]
+		if len(s.header) < 2 || len(s.header) < frameHeaderSize(s.header[1]) {
+			continue
+		}
+
+		// Opcodes 0x0-0x7 are continuation, text, binary, and reserved data
+		// frames; 0x8-0xF are control frames
+		if s.header[0]&0x0f < 0x8 {
+			s.onData()
+		}
+		s.skip = framePayloadLength(s.header)
+		s.header = s.header[:0]
+	}
+}
+
+// frameHeaderSize returns the size of a frame header from its second byte:
+// the extended payload length and masking key follow the first two bytes
+func frameHeaderSize(b byte) int {
+	size := 2
+	switch b & 0x7f {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if b&0x80 != 0 {
+		size += 4
+	}
+	return size
+}
+
+// framePayloadLength returns the payload length of a complete frame header
+func framePayloadLength(header []byte) uint64 {
+	switch length := header[1] & 0x7f; length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(header[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(header[2:10])
+	default:
+		return uint64(length)
+	}
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+)
+
+// clientFrame builds a masked frame, as a client sends it, with a payload
+// of size bytes
+func clientFrame(opcode byte, size int) []byte {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case size < 126:
+		frame = append(frame, 0x80|byte(size))
+	case size <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(size))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(size))
+	}
+	frame = append(frame, 1, 2, 3, 4) // Masking key
+	return append(frame, bytes.Repeat([]byte{0x7f}, size)...)
+}
+
+func TestFrameScannerCountsClientDataFrames(t *testing.T) {
+	var stream []byte
+	stream = append(stream, clientFrame(0x1, 5)...)     // Text
+	stream = append(stream, clientFrame(0x9, 0)...)     // Ping
+	stream = append(stream, clientFrame(0x2, 300)...)   // Binary, 16-bit length
+	stream = append(stream, clientFrame(0xA, 4)...)     // Pong
+	stream = append(stream, clientFrame(0x1, 70000)...) // Text, 64-bit length
+	stream = append(stream, clientFrame(0x8, 2)...)     // Close
+
+	for _, chunk := range []int{len(stream), 1, 3, 1000} {
+		frames := 0
+		scanner := &frameScanner{onData: func() { frames++ }}
+		for p := stream; len(p) > 0; {
+			n := min(chunk, len(p))
+			scanner.scan(p[:n])
+			p = p[n:]
+		}
+		if frames != 3 {
+			t.Errorf("Reading %d bytes at a time: counted %d data frames, want 3", chunk, frames)
+		}
+	}
+}
+
+func TestFrameActivityContext(t *testing.T) {
+	if frameActivity(context.Background()) != nil {
+		t.Error("Expected no frame activity func without WithFrameActivity")
+	}
+
+	called := false
+	ctx := WithFrameActivity(context.Background(), func() { called = true })
+	frameActivity(ctx)()
+	if !called {
+		t.Error("Expected the func set by WithFrameActivity")
+	}
+}
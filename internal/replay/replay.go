@@ -0,0 +1,275 @@
+// Package replay sends the requests recorded in a Navigator access log
+// through an in-process handler, to compare routing and latency between
+// configurations without production traffic.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
+)
+
+// Request is a request reconstructed from an access log entry
+type Request struct {
+	Method    string
+	URI       string
+	ClientIP  string
+	Referer   string
+	UserAgent string
+}
+
+// ParseLog reads JSON access log entries, one per line. Lines that aren't
+// access log entries, such as other log output, are counted and skipped.
+func ParseLog(r io.Reader) (requests []Request, skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry server.AccessLogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Method == "" || !strings.HasPrefix(entry.URI, "/") {
+			skipped++
+			continue
+		}
+		requests = append(requests, Request{
+			Method:    entry.Method,
+			URI:       entry.URI,
+			ClientIP:  entry.ClientIP,
+			Referer:   entry.Referer,
+			UserAgent: entry.UserAgent,
+		})
+	}
+	return requests, skipped, scanner.Err()
+}
+
+// Options controls how requests are replayed
+type Options struct {
+	Rate float64 // Requests per second; 0 sends each request when the previous one finishes
+	Stub bool    // Answer reverse proxies and tenants from a synthetic responder
+}
+
+// Report summarizes a replay
+type Report struct {
+	Requests int            `json:"requests"`
+	Skipped  int            `json:"skipped,omitempty"` // Log lines that weren't access log entries
+	Duration string         `json:"duration"`
+	Latency  Latency        `json:"latency"`
+	Status   map[int]int    `json:"status"`
+	Routes   map[string]int `json:"routes"` // Requests matched by each route, keyed by disposition and rule
+}
+
+// Latency holds response time percentiles in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// Run replays requests against a handler built from cfg. With Stub set,
+// reverse proxy targets and tenants are answered by a local responder, so
+// nothing is started; otherwise tenants are started as usual and stopped
+// when the replay ends. Managed processes and server hooks never run.
+func Run(cfg *config.Config, requests []Request, opts Options) (*Report, error) {
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth file: %w", err)
+	}
+
+	appManager := process.NewAppManager(cfg)
+	if opts.Stub {
+		stub, err := startStub()
+		if err != nil {
+			return nil, err
+		}
+		defer stub.Close()
+		stubProxyTargets(cfg, stub.Addr().String())
+		appManager.StubApps(stub.Addr().(*net.TCPAddr).Port)
+	} else {
+		defer appManager.Cleanup()
+	}
+
+	handler := server.CreateHandler(cfg, appManager, basicAuth, &idle.Manager{}, nil,
+		func() string { return "" }, time.Now, nil)
+
+	// Routes are traced through the pipeline of the handler the requests
+	// are replayed against
+	tracer := handler.(*server.Handler)
+	report := &Report{Requests: len(requests), Status: map[int]int{}, Routes: map[string]int{}}
+	for _, req := range requests {
+		httpReq, err := req.httpRequest()
+		if err != nil {
+			continue
+		}
+		report.Routes[routeKey(tracer.Explain(httpReq))]++
+	}
+
+	latencies := make([]time.Duration, len(requests))
+	statuses := make([]int, len(requests))
+	serve := func(i int) {
+		httpReq, err := requests[i].httpRequest()
+		if err != nil {
+			statuses[i] = http.StatusBadRequest
+			return
+		}
+		w := &discardWriter{header: http.Header{}, status: http.StatusOK}
+		start := time.Now()
+		handler.ServeHTTP(w, httpReq)
+		latencies[i], statuses[i] = time.Since(start), w.status
+	}
+
+	start := time.Now()
+	if opts.Rate > 0 {
+		interval := time.Duration(float64(time.Second) / opts.Rate)
+		var wg sync.WaitGroup
+		for i := range requests {
+			time.Sleep(time.Until(start.Add(time.Duration(i) * interval)))
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				serve(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range requests {
+			serve(i)
+		}
+	}
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	for _, status := range statuses {
+		report.Status[status]++
+	}
+	report.Latency = percentiles(latencies)
+	return report, nil
+}
+
+// httpRequest builds the request to replay. Bodies aren't logged, so
+// requests are replayed without one.
+func (r Request) httpRequest() (*http.Request, error) {
+	req, err := http.NewRequest(r.Method, "http://localhost"+r.URI, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.UserAgent != "" {
+		req.Header.Set("User-Agent", r.UserAgent)
+	}
+	if r.Referer != "" {
+		req.Header.Set("Referer", r.Referer)
+	}
+	if net.ParseIP(r.ClientIP) != nil {
+		req.RemoteAddr = net.JoinHostPort(r.ClientIP, "0")
+	} else {
+		req.RemoteAddr = "192.0.2.1:0" // Documentation address: never localhost
+	}
+	return req, nil
+}
+
+// routeKey names the route that decided a trace, stable across runs and
+// machines: static files are not keyed by their filesystem path
+func routeKey(trace *server.RouteTrace) string {
+	var rule string
+	for i := len(trace.Steps) - 1; i >= 0; i-- {
+		if step := trace.Steps[i]; step.Matched && step.Stage != "auth" {
+			rule = step.Rule
+			if step.Stage == "reverse_proxies" {
+				rule = step.Detail
+			}
+			break
+		}
+	}
+
+	switch trace.Disposition {
+	case "tenant":
+		return trace.Disposition + " " + trace.Target
+	case "static":
+		return trace.Disposition
+	}
+	if rule != "" {
+		return trace.Disposition + " " + rule
+	}
+	return trace.Disposition
+}
+
+// percentiles summarizes latencies using the nearest-rank method
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) float64 {
+		index := int(p*float64(len(sorted))+0.5) - 1
+		if index < 0 {
+			index = 0
+		}
+		if index >= len(sorted) {
+			index = len(sorted) - 1
+		}
+		return float64(sorted[index].Microseconds()) / 1000
+	}
+	return Latency{P50: at(0.50), P90: at(0.90), P99: at(0.99), Max: at(1)}
+}
+
+// stubProxyTargets points every reverse proxy at the stub, keeping the
+// target's path so capture group substitution still applies
+func stubProxyTargets(cfg *config.Config, stubHost string) {
+	for i := range cfg.Routes.ReverseProxies {
+		route := &cfg.Routes.ReverseProxies[i]
+		target, err := url.Parse(route.Target)
+		if err != nil || target.Host == "" {
+			continue
+		}
+		target.Scheme, target.Host = "http", stubHost
+		route.Target = target.String()
+	}
+}
+
+// startStub starts the synthetic responder for stubbed backends
+func startStub() (net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start stub responder: %w", err)
+	}
+	stub := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "stub response for "+r.URL.Path+"\n")
+	})}
+	go func() { _ = stub.Serve(listener) }()
+	return listener, nil
+}
+
+// discardWriter records the status of a replayed response and drops the body
+type discardWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+}
+
+func (w *discardWriter) Header() http.Header { return w.header }
+
+func (w *discardWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+}
+
+func (w *discardWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(p), nil
+}
+
+func (w *discardWriter) Flush() {}
@@ -0,0 +1,160 @@
+package replay
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/replay.golden.json")
+
+// golden is the part of a report that must stay stable across refactors;
+// durations and latencies vary from run to run
+type golden struct {
+	Requests int            `json:"requests"`
+	Skipped  int            `json:"skipped"`
+	Status   map[int]int    `json:"status"`
+	Routes   map[string]int `json:"routes"`
+}
+
+func TestReplayGolden(t *testing.T) {
+	cfg, err := config.LoadConfig("testdata/navigator.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	file, err := os.Open("testdata/access.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	requests, skipped, err := ParseLog(file)
+	if err != nil {
+		t.Fatalf("Failed to parse log: %v", err)
+	}
+
+	report, err := Run(cfg, requests, Options{Stub: true})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	got := golden{Requests: report.Requests, Skipped: skipped, Status: report.Status, Routes: report.Routes}
+
+	const goldenFile = "testdata/replay.golden.json"
+	if *update {
+		data, _ := json.MarshalIndent(got, "", "  ")
+		if err := os.WriteFile(goldenFile, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	var want golden
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("Invalid golden file: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("Replay changed; if intended, rerun with -update.\nGot:\n%s\nWant:\n%s", gotJSON, data)
+	}
+}
+
+func TestReplayAtRate(t *testing.T) {
+	cfg, err := config.LoadConfig("testdata/navigator.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	requests := []Request{
+		{Method: "GET", URI: "/up"},
+		{Method: "GET", URI: "/api/v1/events"},
+		{Method: "GET", URI: "/studios/boston/"},
+		{Method: "GET", URI: "/assets/app.css"},
+	}
+
+	start := time.Now()
+	report, err := Run(cfg, requests, Options{Rate: 100, Stub: true})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Four requests at 100/s took %v, want at least 30ms", elapsed)
+	}
+	if report.Status[200] != len(requests) {
+		t.Errorf("Status = %v, want all 200", report.Status)
+	}
+}
+
+func TestReplayRoutesFollowThePipeline(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  well_known:
+    robots_txt: {content: "User-agent: *\nDisallow: /\n"}
+applications:
+  tenants:
+    - name: main
+      path: /
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(cfg, []Request{{Method: "GET", URI: "/robots.txt"}, {Method: "GET", URI: "/home"}}, Options{Stub: true})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if want := map[string]int{"well-known": 1, "tenant main": 1}; !reflect.DeepEqual(report.Routes, want) {
+		t.Errorf("Routes = %v, want %v", report.Routes, want)
+	}
+}
+
+func TestParseLogSkipsOtherLines(t *testing.T) {
+	log := strings.Join([]string{
+		`time=2025-06-01T12:00:00.000Z level=INFO msg="Navigator starting"`,
+		`{"method":"GET","uri":"/studios/boston/","client_ip":"203.0.113.5","user_agent":"curl/8.0"}`,
+		`{"level":"INFO","msg":"Tenant started"}`,
+		`{"method":"CONNECT","uri":"example.com:443"}`,
+		``,
+		`{"method":"POST","uri":"/api/v1/events","referer":"https://example.com/"}`,
+	}, "\n")
+
+	requests, skipped, err := ParseLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseLog failed: %v", err)
+	}
+	if skipped != 4 {
+		t.Errorf("Skipped = %d, want 4", skipped)
+	}
+	want := []Request{
+		{Method: "GET", URI: "/studios/boston/", ClientIP: "203.0.113.5", UserAgent: "curl/8.0"},
+		{Method: "POST", URI: "/api/v1/events", Referer: "https://example.com/"},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("Requests = %+v, want %+v", requests, want)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	got := percentiles(latencies)
+	want := Latency{P50: 50, P90: 90, P99: 99, Max: 100}
+	if got != want {
+		t.Errorf("percentiles = %+v, want %+v", got, want)
+	}
+	if latencies[0] != 100*time.Millisecond {
+		t.Error("percentiles reordered its input")
+	}
+	if (percentiles(nil) != Latency{}) {
+		t.Error("percentiles of no latencies should be zero")
+	}
+}
@@ -0,0 +1,60 @@
+package replay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/server"
+)
+
+// SmokeResult is how a tenant answered a request for its root path
+type SmokeResult struct {
+	Tenant   string `json:"tenant"`
+	URI      string `json:"uri"`
+	Status   int    `json:"status"`
+	Duration string `json:"duration"` // Including the time to start the tenant
+	OK       bool   `json:"ok"`
+}
+
+// Smoke starts every tenant once by requesting its root path through an
+// in-process handler, without auth, and stops them when done. A tenant passes unless it
+// answers with a 5xx, which includes the maintenance page served when it
+// doesn't start in time. Managed processes and server hooks never run.
+func Smoke(cfg *config.Config) ([]SmokeResult, error) {
+	if _, err := auth.LoadAuthConfig(&cfg.Auth); err != nil {
+		return nil, fmt.Errorf("failed to load auth file: %w", err)
+	}
+
+	appManager := process.NewAppManager(cfg)
+	defer appManager.Cleanup()
+	handler := server.CreateHandler(cfg, appManager, nil, &idle.Manager{}, nil,
+		func() string { return "" }, time.Now, nil)
+
+	results := make([]SmokeResult, 0, len(cfg.Applications.Tenants))
+	for _, tenant := range cfg.Applications.Tenants {
+		uri := tenant.Path
+		if uri == "" {
+			uri = "/"
+		}
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		req.RemoteAddr = "127.0.0.1:0"
+
+		w := &discardWriter{header: http.Header{}, status: http.StatusOK}
+		start := time.Now()
+		handler.ServeHTTP(w, req)
+		results = append(results, SmokeResult{
+			Tenant:   tenant.Name,
+			URI:      uri,
+			Status:   w.status,
+			Duration: time.Since(start).Round(time.Millisecond).String(),
+			OK:       w.status < http.StatusInternalServerError,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,40 @@
+package replay
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestSmoke(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+auth:
+  htpasswd: testdata/htpasswd
+applications:
+  tenants:
+    - name: boston
+      path: /showcase/boston/
+      framework: internal-echo
+    - name: broken
+      path: /showcase/broken/
+      runtime: /nonexistent/navigator-runtime
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	results, err := Smoke(cfg)
+	if err != nil {
+		t.Fatalf("Smoke() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Results = %+v, want one per tenant", results)
+	}
+	if boston := results[0]; boston.Tenant != "boston" || boston.URI != "/showcase/boston/" || boston.Status != http.StatusOK || !boston.OK {
+		t.Errorf("boston = %+v, want started and answering 200 despite auth", boston)
+	}
+	if broken := results[1]; broken.OK || broken.Status < http.StatusInternalServerError {
+		t.Errorf("broken = %+v, want a failure", broken)
+	}
+}
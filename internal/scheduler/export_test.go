@@ -0,0 +1,7 @@
+package scheduler
+
+// NewWithClock lets tests outside the package drive a scheduler's clock
+var NewWithClock = newWithClock
+
+// Wait waits for the runs the scheduler started
+func (s *Scheduler) Wait() { s.runs.Wait() }
@@ -0,0 +1,62 @@
+package scheduler_test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/scheduler"
+	"github.com/rubys/navigator/internal/server"
+)
+
+// Scheduled tasks connect to Navigator's own listener from loopback, without
+// the PROXY protocol header the load balancer sends
+func TestSchedulerReachesProxyProtocolListener(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  proxy_protocol: true
+  proxy_protocol_trusted: [10.0.0.0/8, 127.0.0.0/8]
+schedule:
+  - name: prune
+    cron: "0 * * * *"
+    http:
+      path: /prune
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.ConfigureProxyProtocol(cfg)
+	t.Cleanup(func() { server.ConfigureProxyProtocol(&config.Config{}) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned := make(chan struct{}, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/prune" {
+			pruned <- struct{}{}
+		}
+	})}
+	go func() { _ = srv.Serve(server.ProxyProtocolListener(listener)) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := scheduler.NewWithClock(clk)
+	s.Configure(cfg, "http://"+listener.Addr().String())
+	t.Cleanup(s.Stop)
+
+	clk.Advance(time.Hour)
+	s.Wait()
+	select {
+	case <-pruned:
+	default:
+		t.Fatal("The scheduled request never reached the handler")
+	}
+	if status := s.Status(); len(status) != 1 || status[0].LastRun == nil || status[0].LastRun.Outcome != scheduler.OutcomeSucceeded {
+		t.Errorf("Status = %+v, want the run to succeed", status)
+	}
+}
@@ -0,0 +1,320 @@
+// Package scheduler runs the tasks configured under schedule: HTTP requests
+// sent through Navigator, commands, and tenant hooks, each whenever its cron
+// expression matches. A single timer waits for the earliest task; each run
+// has its own goroutine, bounded by the task's timeout.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// TokenHeader carries the token with which scheduled HTTP requests that set
+// bypass_auth skip authentication
+const TokenHeader = "X-Navigator-Schedule-Token"
+
+// Outcomes of a scheduled run
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeSkipped   = "skipped"
+)
+
+// Reasons a scheduled run is skipped
+const (
+	skippedOverlap     = "previous run still running"
+	skippedMaintenance = "maintenance"
+)
+
+// token marks the scheduled requests that bypass authentication
+var token = auth.NewInternalToken(TokenHeader)
+
+// Authorize adds the token with which r bypasses authentication
+func Authorize(r *http.Request) {
+	token.Set(r)
+}
+
+// Authorized reports whether r was sent by a scheduled task that bypasses
+// authentication
+func Authorized(r *http.Request) bool {
+	return token.Valid(r)
+}
+
+// RunResult is the outcome of a task's most recent run or skipped run
+type RunResult struct {
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+	Outcome  string    `json:"outcome"`
+	Error    string    `json:"error,omitempty"` // Why the run failed or was skipped
+}
+
+// TaskStatus reports a scheduled task for the detailed health check
+type TaskStatus struct {
+	Name    string     `json:"name"`
+	Cron    string     `json:"cron"`
+	NextRun time.Time  `json:"next_run"`
+	Running int        `json:"running"` // Runs in progress
+	LastRun *RunResult `json:"last_run,omitempty"`
+}
+
+// entry is a task waiting for its next run
+type entry struct {
+	task *config.ScheduledTask
+	next time.Time
+}
+
+// Scheduler runs the scheduled tasks of the current configuration. Running
+// runs and the last results are kept by task name, so they survive reloads.
+type Scheduler struct {
+	mu         sync.Mutex
+	clock      clock.Clock
+	client     *http.Client
+	config     *config.Config
+	baseURL    string // Where HTTP tasks send requests
+	entries    []*entry
+	timer      clock.Timer
+	generation int // Incremented whenever the schedule is rebuilt, so stale timers do nothing
+	running    map[string]int
+	last       map[string]*RunResult
+	runs       sync.WaitGroup
+}
+
+// New creates a scheduler with no tasks
+func New() *Scheduler {
+	return newWithClock(clock.Real)
+}
+
+func newWithClock(clk clock.Clock) *Scheduler {
+	return &Scheduler{
+		clock:   clk,
+		client:  &http.Client{},
+		running: make(map[string]int),
+		last:    make(map[string]*RunResult),
+	}
+}
+
+// defaultScheduler runs the tasks of the configuration Navigator serves
+var defaultScheduler = New()
+
+// Configure replaces the default scheduler's tasks with those of cfg
+func Configure(cfg *config.Config, baseURL string) {
+	defaultScheduler.Configure(cfg, baseURL)
+}
+
+// Stop stops the default scheduler starting runs
+func Stop() {
+	defaultScheduler.Stop()
+}
+
+// Report returns the default scheduler's tasks, or nil without any
+func Report() []TaskStatus {
+	return defaultScheduler.Status()
+}
+
+// Configure rebuilds the schedule from cfg's tasks in one step. Runs in
+// progress finish; a task keeps its overlap tracking and last result if a
+// task with its name is still configured.
+func (s *Scheduler) Configure(cfg *config.Config, baseURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopTimer()
+	s.config, s.baseURL = cfg, baseURL
+
+	now := s.clock.Now()
+	s.entries = nil
+	names := make(map[string]bool)
+	for i := range cfg.Schedule {
+		task := &cfg.Schedule[i]
+		s.entries = append(s.entries, &entry{task: task, next: task.Next(now)})
+		names[task.Name] = true
+	}
+	for name := range s.last {
+		if !names[name] {
+			delete(s.last, name)
+		}
+	}
+	s.arm(now)
+}
+
+// Stop stops starting runs; runs in progress finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopTimer()
+	s.entries = nil
+}
+
+// Status reports every task, in configuration order
+func (s *Scheduler) Status() []TaskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var status []TaskStatus
+	for _, e := range s.entries {
+		task := TaskStatus{
+			Name:    e.task.Name,
+			Cron:    e.task.Cron,
+			NextRun: e.next,
+			Running: s.running[e.task.Name],
+		}
+		if last := s.last[e.task.Name]; last != nil {
+			result := *last
+			task.LastRun = &result
+		}
+		status = append(status, task)
+	}
+	return status
+}
+
+// stopTimer stops the pending timer; s.mu must be held
+func (s *Scheduler) stopTimer() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.generation++
+}
+
+// arm sets the timer for the earliest task; s.mu must be held
+func (s *Scheduler) arm(now time.Time) {
+	var earliest time.Time
+	for _, e := range s.entries {
+		if !e.next.IsZero() && (earliest.IsZero() || e.next.Before(earliest)) {
+			earliest = e.next
+		}
+	}
+	if earliest.IsZero() {
+		return
+	}
+	generation := s.generation
+	s.timer = s.clock.AfterFunc(earliest.Sub(now), func() { s.fire(generation) })
+}
+
+// fire starts the tasks that are due and rearms the timer
+func (s *Scheduler) fire(generation int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if generation != s.generation {
+		return
+	}
+	now := s.clock.Now()
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		s.start(e.task, now)
+		e.next = e.task.Next(now)
+	}
+	s.arm(now)
+}
+
+// start runs task in its own goroutine, unless it must be skipped; s.mu
+// must be held
+func (s *Scheduler) start(task *config.ScheduledTask, now time.Time) {
+	reason := ""
+	switch {
+	case task.Overlap != config.ScheduleOverlapAllow && s.running[task.Name] > 0:
+		reason = skippedOverlap
+	case task.SkipDuringMaintenance && s.inMaintenance(task, now):
+		reason = skippedMaintenance
+	}
+	if reason != "" {
+		logging.LogScheduledTaskSkipped(task.Name, reason)
+		s.last[task.Name] = &RunResult{Started: now, Outcome: OutcomeSkipped, Error: reason}
+		return
+	}
+
+	s.running[task.Name]++
+	s.runs.Add(1)
+	cfg, baseURL := s.config, s.baseURL
+	go func() {
+		defer s.runs.Done()
+		err := s.run(task, cfg, baseURL)
+		duration := s.clock.Now().Sub(now)
+		logging.LogScheduledTaskFinished(task.Name, duration, err)
+
+		result := &RunResult{Started: now, Duration: duration.Seconds(), Outcome: OutcomeSucceeded}
+		if err != nil {
+			result.Outcome, result.Error = OutcomeFailed, err.Error()
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running[task.Name]--
+		if s.running[task.Name] == 0 {
+			delete(s.running, task.Name)
+		}
+		s.last[task.Name] = result
+	}()
+}
+
+// inMaintenance reports whether the server, or the task's tenant, is in
+// maintenance at now; s.mu must be held
+func (s *Scheduler) inMaintenance(task *config.ScheduledTask, now time.Time) bool {
+	if active, _ := s.config.Maintenance.Active(now); active {
+		return true
+	}
+	if tenant := s.config.TenantByName(task.Tenant); tenant != nil {
+		active, _ := tenant.Maintenance.Active(now)
+		return active
+	}
+	return false
+}
+
+// run performs one run of task, bounded by its timeout
+func (s *Scheduler) run(task *config.ScheduledTask, cfg *config.Config, baseURL string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), task.Timeout.Std())
+	defer cancel()
+
+	tenant := cfg.TenantByName(task.Tenant)
+	hookType := "schedule." + task.Name
+	switch {
+	case task.HTTP != nil:
+		return s.request(ctx, task.HTTP, cfg, baseURL)
+	case task.Command != nil:
+		var env map[string]string
+		if tenant != nil {
+			env = tenant.Env
+		}
+		return process.ExecuteHooks(ctx, []config.HookConfig{*task.Command}, env, hookType)
+	case tenant == nil:
+		return fmt.Errorf("tenant %q is no longer configured", task.Tenant)
+	case task.Hook == config.ScheduleHookStop:
+		return process.ExecuteTenantHooks(ctx, cfg.Applications.Hooks.Stop, tenant.Hooks.Stop, tenant.Env, tenant.Name, config.ScheduleHookStop)
+	default:
+		return process.ExecuteTenantHooks(ctx, cfg.Applications.Hooks.Start, tenant.Hooks.Start, tenant.Env, tenant.Name, config.ScheduleHookStart)
+	}
+}
+
+// request sends an HTTP task's request through Navigator's listener; any
+// status of 400 or above is a failure
+func (s *Scheduler) request(ctx context.Context, request *config.ScheduledHTTPRequest, cfg *config.Config, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, request.Method, baseURL+request.Path, nil)
+	if err != nil {
+		return err
+	}
+	if cfg.Server.Hostname != "" {
+		req.Host = cfg.Server.Hostname
+	}
+	if request.BypassAuth {
+		Authorize(req)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s %s: %s", request.Method, request.Path, resp.Status)
+	}
+	return nil
+}
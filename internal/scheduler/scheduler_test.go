@@ -0,0 +1,286 @@
+package scheduler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// newTestScheduler configures a scheduler with a fake clock from yaml
+func newTestScheduler(t *testing.T, yaml, baseURL string) (*Scheduler, *clock.Fake) {
+	t.Helper()
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newWithClock(clk)
+	s.Configure(parseConfig(t, yaml), baseURL)
+	t.Cleanup(func() {
+		s.Stop()
+		s.runs.Wait()
+	})
+	return s, clk
+}
+
+func parseConfig(t *testing.T, yaml string) *config.Config {
+	t.Helper()
+	cfg, err := config.ParseYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	return cfg
+}
+
+// status returns the status of the task with a name
+func status(t *testing.T, s *Scheduler, name string) TaskStatus {
+	t.Helper()
+	for _, task := range s.Status() {
+		if task.Name == name {
+			return task
+		}
+	}
+	t.Fatalf("No task named %q in %+v", name, s.Status())
+	return TaskStatus{}
+}
+
+func TestSchedulerFiresAtCronTimes(t *testing.T) {
+	var hits atomic.Int32
+	var bypass atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		bypass.Store(Authorized(r))
+		if r.Method != http.MethodPost || r.URL.Path != "/showcase/2025/boston/admin/digest" {
+			t.Errorf("Request = %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer backend.Close()
+
+	s, clk := newTestScheduler(t, `
+applications:
+  tenants:
+    - name: boston
+      path: /showcase/2025/boston/
+schedule:
+  - name: digest
+    cron: "*/5 * * * *"
+    tenant: boston
+    http:
+      path: admin/digest
+      method: post
+      bypass_auth: true
+`, backend.URL)
+
+	if next := status(t, s, "digest").NextRun; !next.Equal(clk.Now().Add(5 * time.Minute)) {
+		t.Errorf("NextRun = %v, want 00:05", next)
+	}
+
+	clk.Advance(4 * time.Minute)
+	s.runs.Wait()
+	if hits.Load() != 0 {
+		t.Fatalf("Task ran %d times before it was due", hits.Load())
+	}
+
+	for i := 1; i <= 3; i++ {
+		clk.Advance(5 * time.Minute)
+		s.runs.Wait()
+		if int(hits.Load()) != i {
+			t.Fatalf("After %d periods the task ran %d times", i, hits.Load())
+		}
+	}
+	if !bypass.Load() {
+		t.Error("bypass_auth requests should carry the scheduler's token")
+	}
+
+	task := status(t, s, "digest")
+	if task.LastRun == nil || task.LastRun.Outcome != OutcomeSucceeded {
+		t.Errorf("LastRun = %+v, want succeeded", task.LastRun)
+	}
+	if want := time.Date(2025, 1, 1, 0, 20, 0, 0, time.UTC); !task.NextRun.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", task.NextRun, want)
+	}
+}
+
+func TestSchedulerReportsFailures(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	s, clk := newTestScheduler(t, `
+schedule:
+  - name: prune
+    cron: "0 * * * *"
+    http:
+      path: /prune
+`, backend.URL)
+
+	clk.Advance(time.Hour)
+	s.runs.Wait()
+	task := status(t, s, "prune")
+	if task.LastRun == nil || task.LastRun.Outcome != OutcomeFailed || !strings.Contains(task.LastRun.Error, "500") {
+		t.Errorf("LastRun = %+v, want a failure reporting the status", task.LastRun)
+	}
+}
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+	}))
+	defer backend.Close()
+
+	s, clk := newTestScheduler(t, `
+schedule:
+  - name: slow
+    cron: "* * * * *"
+    http:
+      path: /slow
+`, backend.URL)
+
+	clk.Advance(time.Minute)
+	waitFor(t, func() bool { return hits.Load() == 1 })
+	clk.Advance(time.Minute)
+
+	task := status(t, s, "slow")
+	if task.Running != 1 || task.LastRun == nil || task.LastRun.Outcome != OutcomeSkipped {
+		t.Errorf("Status = %+v, want one run in progress and the next skipped", task)
+	}
+
+	close(release)
+	s.runs.Wait()
+	if hits.Load() != 1 {
+		t.Errorf("Task ran %d times, want 1", hits.Load())
+	}
+	if task := status(t, s, "slow"); task.Running != 0 || task.LastRun.Outcome != OutcomeSucceeded {
+		t.Errorf("Status = %+v, want the run finished", task)
+	}
+}
+
+func TestSchedulerSkipsDuringMaintenance(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer backend.Close()
+
+	s, clk := newTestScheduler(t, `
+maintenance:
+  enabled: true
+schedule:
+  - name: opted-in
+    cron: "* * * * *"
+    skip_during_maintenance: true
+    http:
+      path: /a
+  - name: regardless
+    cron: "* * * * *"
+    http:
+      path: /b
+`, backend.URL)
+
+	clk.Advance(time.Minute)
+	s.runs.Wait()
+	if hits.Load() != 1 {
+		t.Errorf("Requests = %d, want only the task that runs regardless", hits.Load())
+	}
+	if last := status(t, s, "opted-in").LastRun; last == nil || last.Outcome != OutcomeSkipped || last.Error != skippedMaintenance {
+		t.Errorf("LastRun = %+v, want skipped for maintenance", last)
+	}
+}
+
+func TestSchedulerReloadRebuildsSchedule(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits[r.URL.Path]++
+	}))
+	defer backend.Close()
+
+	s, clk := newTestScheduler(t, `
+schedule:
+  - name: often
+    cron: "*/5 * * * *"
+    http:
+      path: /often
+  - name: removed
+    cron: "*/5 * * * *"
+    http:
+      path: /removed
+`, backend.URL)
+
+	clk.Advance(5 * time.Minute)
+	s.runs.Wait()
+
+	s.Configure(parseConfig(t, `
+schedule:
+  - name: often
+    cron: "0 * * * *"
+    http:
+      path: /often
+  - name: added
+    cron: "*/10 * * * *"
+    http:
+      path: /added
+`), backend.URL)
+
+	var names []string
+	for _, task := range s.Status() {
+		names = append(names, task.Name)
+	}
+	if strings.Join(names, ",") != "often,added" {
+		t.Errorf("Tasks = %v, want often and added", names)
+	}
+	if last := status(t, s, "often").LastRun; last == nil || last.Outcome != OutcomeSucceeded {
+		t.Errorf("A task kept across the reload should keep its last run, got %+v", last)
+	}
+	if next := status(t, s, "often").NextRun; !next.Equal(time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextRun = %v, want 01:00 from the new cron expression", next)
+	}
+
+	for i := 0; i < 11; i++ {
+		clk.Advance(5 * time.Minute)
+		s.runs.Wait()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// often ran at 00:05 and 01:00, removed only at 00:05, and added every
+	// ten minutes from 00:10
+	if hits["/often"] != 2 || hits["/removed"] != 1 || hits["/added"] != 6 {
+		t.Errorf("Requests = %v, want often 2, removed 1, added 6", hits)
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if Authorized(req) {
+		t.Error("A request without the token should not be authorized")
+	}
+	req.Header.Set(TokenHeader, "guess")
+	if Authorized(req) {
+		t.Error("A request with the wrong token should not be authorized")
+	}
+	Authorize(req)
+	if !Authorized(req) {
+		t.Error("A request with the token should be authorized")
+	}
+}
+
+// waitFor polls until done reports true
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -3,11 +3,17 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"io"
+	"math/rand/v2"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/netutil"
+	"github.com/rubys/navigator/internal/proxy"
+	"github.com/rubys/navigator/internal/utils"
 )
 
 // AccessLogEntry represents a structured access log entry matching nginx format
@@ -26,21 +32,31 @@ type AccessLogEntry struct {
 	UserAgent     string `json:"user_agent"`
 	FlyRequestID  string `json:"fly_request_id"`
 	Tenant        string `json:"tenant,omitempty"`
-	ResponseType  string `json:"response_type,omitempty"` // Type of response: proxy, static, redirect, fly-replay, auth-failure, error
-	Destination   string `json:"destination,omitempty"`   // For fly-replay or redirect responses
-	ProxyBackend  string `json:"proxy_backend,omitempty"` // For proxy responses
-	FilePath      string `json:"file_path,omitempty"`     // For static file responses
-	ErrorMessage  string `json:"error_message,omitempty"` // For error responses
-}
+	ResponseType  string `json:"response_type,omitempty"`     // Type of response: proxy, static, redirect, fly-replay, auth-failure, error
+	Destination   string `json:"destination,omitempty"`       // For fly-replay or redirect responses
+	ProxyBackend  string `json:"proxy_backend,omitempty"`     // For proxy responses
+	FilePath      string `json:"file_path,omitempty"`         // For static file responses
+	ErrorMessage  string `json:"error_message,omitempty"`     // For error responses
+	Coalesced     int    `json:"coalesced,omitempty"`         // Identical requests that received a copy of this response
+	BytesReceived int64  `json:"bytes_received,omitempty"`    // Bytes read from the client on a hijacked (WebSocket) connection or by an upload
+	ReplayedFrom  string `json:"replayed_from,omitempty"`     // Region that fly-replayed this request here
+	AuthRealm     string `json:"auth_realm,omitempty"`        // Realm whose credentials authenticated remote_user
+	QueueDepth    int    `json:"queue_depth,omitempty"`       // Position in the tenant's request queue on arrival
+	QueueTime     string `json:"queue_time,omitempty"`        // Seconds spent waiting for a max_concurrent_requests slot
+	ColdStart     bool   `json:"cold_start,omitempty"`        // The request started its tenant's app
+	BootMs        int64  `json:"boot_ms,omitempty"`           // Milliseconds the app took to boot, on a cold start
+	StartupQueued int64  `json:"startup_queued_ms,omitempty"` // Milliseconds the app's start waited for a startup slot
+	AppState      string `json:"app_state,omitempty"`         // State of an app that wasn't accepting requests
+	Mirror        string `json:"mirror,omitempty"`            // "mirrored" or "skipped" when the request was sampled for a mirror
 
-// accessLogWriter is the configured output destination for access logs
-var accessLogWriter io.Writer = os.Stdout
+	Disconnected      bool   `json:"client_disconnected,omitempty"` // The client went away before the response was complete
+	DisconnectedAfter string `json:"disconnected_after,omitempty"`  // Seconds after the request arrived that the client went away
 
-// SetAccessLogWriter configures the output destination for access logs
-func SetAccessLogWriter(writer io.Writer) {
-	if writer != nil {
-		accessLogWriter = writer
-	}
+	Internal bool `json:"internal,omitempty"` // Sent by Navigator itself, such as by a tenant's warmer
+
+	FlyRegion    string `json:"fly_region,omitempty"` // Where Navigator runs, on Fly.io
+	FlyMachineID string `json:"fly_machine_id,omitempty"`
+	FlyAllocID   string `json:"fly_alloc_id,omitempty"`
 }
 
 // LogRequest logs an HTTP request in JSON format matching nginx/legacy navigator format
@@ -50,16 +66,19 @@ func LogRequest(req *http.Request, statusCode, bodySize int, startTime time.Time
 		return
 	}
 
-	// Get client IP (prefer X-Forwarded-For if available)
-	clientIP := req.Header.Get("X-Forwarded-For")
-	if clientIP == "" {
-		clientIP = req.RemoteAddr
+	// Internal requests may be dropped; errors are always logged; other
+	// requests may be sampled
+	sampleRate, excludeInternal := accessLogSettings()
+	internal, _ := metadata["internal"].(bool)
+	if internal && excludeInternal {
+		return
 	}
-	// Clean up client IP (remove port if present)
-	if idx := strings.LastIndex(clientIP, ":"); idx > 0 && strings.Count(clientIP, ":") == 1 {
-		clientIP = clientIP[:idx]
+	if sampleRate < 1 && statusCode < 400 && rand.Float64() >= sampleRate {
+		return
 	}
 
+	clientIP := netutil.ClientIP(req, proxy.ClientIPTrust())
+
 	// Get remote user from basic auth or headers
 	remoteUser := "-"
 	if user, _, ok := req.BasicAuth(); ok && user != "" {
@@ -103,6 +122,11 @@ func LogRequest(req *http.Request, statusCode, bodySize int, startTime time.Time
 		UserAgent:     req.Header.Get("User-Agent"),
 		FlyRequestID:  flyRequestID,
 	}
+	fly := utils.Fly()
+	entry.FlyRegion, entry.FlyMachineID, entry.FlyAllocID = fly.Region, fly.MachineID, fly.AllocID
+	if src, replayed := ParseFlyReplaySrc(req); replayed {
+		entry.ReplayedFrom = src.Region
+	}
 
 	// Add metadata from the recorder
 	if tenant, ok := metadata["tenant"].(string); ok {
@@ -123,8 +147,120 @@ func LogRequest(req *http.Request, statusCode, bodySize int, startTime time.Time
 	if errorMessage, ok := metadata["error_message"].(string); ok {
 		entry.ErrorMessage = errorMessage
 	}
+	if coalesced, ok := metadata["coalesced"].(int); ok {
+		entry.Coalesced = coalesced
+	}
+	if bytesReceived, ok := metadata["bytes_received"].(int64); ok {
+		entry.BytesReceived = bytesReceived
+	}
+	if authRealm, ok := metadata["auth_realm"].(string); ok {
+		entry.AuthRealm = authRealm
+	}
+	if queueDepth, ok := metadata["queue_depth"].(int); ok {
+		entry.QueueDepth = queueDepth
+	}
+	if queueTime, ok := metadata["queue_time"].(time.Duration); ok {
+		entry.QueueTime = fmt.Sprintf("%.3f", queueTime.Seconds())
+	}
+	if coldStart, ok := metadata["cold_start"].(bool); ok {
+		entry.ColdStart = coldStart
+	}
+	if bootMs, ok := metadata["boot_ms"].(int64); ok {
+		entry.BootMs = bootMs
+	}
+	if startupQueued, ok := metadata["startup_queued_ms"].(int64); ok {
+		entry.StartupQueued = startupQueued
+	}
+	if appState, ok := metadata["app_state"].(string); ok {
+		entry.AppState = appState
+	}
+	if mirror, ok := metadata["mirror"].(string); ok {
+		entry.Mirror = mirror
+	}
+	if disconnected, ok := metadata["client_disconnected"].(bool); ok {
+		entry.Disconnected = disconnected
+	}
+	if disconnectedAfter, ok := metadata["disconnected_after"].(time.Duration); ok {
+		entry.DisconnectedAfter = fmt.Sprintf("%.3f", disconnectedAfter.Seconds())
+	}
+	entry.Internal = internal
 
-	// Output JSON log entry (matching nginx/rails format)
-	data, _ := json.Marshal(entry)
-	_, _ = fmt.Fprintln(accessLogWriter, string(data))
+	writeAccessLogEntry(&entry, req.URL.Path)
+}
+
+// format serializes the entry as one line in format: json, text (combined
+// log format) or pretty
+func (e *AccessLogEntry) format(format string) []byte {
+	switch format {
+	case config.LogFormatText:
+		return e.combined()
+	case config.LogFormatPretty:
+		return e.pretty()
+	}
+
+	// JSON, matching the nginx/rails format
+	data, _ := json.Marshal(e)
+	return append(data, '\n')
+}
+
+// combined formats the entry as an nginx combined log line, followed by the
+// request time in seconds
+func (e *AccessLogEntry) combined() []byte {
+	timestamp := e.Timestamp
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z07:00", e.Timestamp); err == nil {
+		timestamp = t.Format("02/Jan/2006:15:04:05 -0700")
+	}
+	referer := e.Referer
+	if referer == "" {
+		referer = "-"
+	}
+	return []byte(fmt.Sprintf("%s - %s [%s] %q %d %d %q %q %s\n",
+		e.ClientIP, e.RemoteUser, timestamp, e.Method+" "+e.URI+" "+e.Protocol,
+		e.Status, e.BodyBytesSent, referer, e.UserAgent, e.RequestTime))
+}
+
+// pretty formats the entry as one colored line for a developer's terminal,
+// "15:04:05 GET /path → 200 12ms", followed by the tenant, response type and
+// any error
+func (e *AccessLogEntry) pretty() []byte {
+	timestamp := e.Timestamp
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z07:00", e.Timestamp); err == nil {
+		timestamp = t.Format(logging.PrettyTimeFormat)
+	}
+	duration := e.RequestTime
+	if seconds, err := strconv.ParseFloat(e.RequestTime, 64); err == nil {
+		duration = time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+	}
+
+	statusColor := logging.ColorGreen
+	switch {
+	case e.Status >= 500:
+		statusColor = logging.ColorRed
+	case e.Status >= 400:
+		statusColor = logging.ColorYellow
+	case e.Status >= 300:
+		statusColor = logging.ColorCyan
+	}
+
+	line := fmt.Sprintf("%s %s %s → %s %s",
+		logging.Colorize(logging.ColorDim, timestamp),
+		logging.Colorize(logging.ColorBold, e.Method), e.URI,
+		logging.Colorize(statusColor, strconv.Itoa(e.Status)), duration)
+
+	var details []string
+	for _, detail := range []string{e.Tenant, e.ResponseType} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if e.ColdStart {
+		details = append(details, fmt.Sprintf("cold start %s", time.Duration(e.BootMs)*time.Millisecond))
+	}
+	if len(details) > 0 {
+		line += " " + logging.Colorize(logging.ColorDim, "("+strings.Join(details, ", ")+")")
+	}
+	if e.ErrorMessage != "" {
+		line += " " + logging.Colorize(logging.ColorRed, e.ErrorMessage)
+	}
+	return []byte(line + "\n")
 }
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// accessLogLine is a queued log line, or a flush marker when flushed is set
+type accessLogLine struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// asyncLogWriter writes access log lines to one destination on a dedicated
+// goroutine so a slow file system or blocked pipe never delays requests.
+// When the buffer is full, lines are dropped and counted.
+type asyncLogWriter struct {
+	out     io.Writer
+	plain   bool // Strip the colors of pretty lines; out isn't a terminal
+	lines   chan accessLogLine
+	done    chan struct{}
+	closeMu sync.Mutex // Keeps a flush from sending on a closed queue
+	closed  bool
+	dropped atomic.Int64 // Total lines dropped
+	pending atomic.Int64 // Lines dropped since the last report
+}
+
+func newAsyncLogWriter(out io.Writer, size int) *asyncLogWriter {
+	w := &asyncLogWriter{
+		out:   out,
+		plain: !logging.ColorEnabled(out),
+		lines: make(chan accessLogLine, size),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run writes queued lines until the queue is closed, reporting drops periodically
+func (w *asyncLogWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(config.LogDropSummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				w.reportDropped()
+				return
+			}
+			if line.flushed != nil {
+				close(line.flushed)
+				continue
+			}
+			data := line.data
+			if w.plain {
+				data = logging.StripANSI(data)
+			}
+			_, _ = w.out.Write(data)
+		case <-ticker.C:
+			w.reportDropped()
+		}
+	}
+}
+
+// write queues a line without blocking, dropping it if the buffer is full
+func (w *asyncLogWriter) write(data []byte) {
+	select {
+	case w.lines <- accessLogLine{data: data}:
+	default:
+		w.dropped.Add(1)
+		w.pending.Add(1)
+	}
+}
+
+// flush waits until every line queued before the call has been written, or
+// until deadline, reporting whether they were. A closed writer has already
+// written its lines.
+func (w *asyncLogWriter) flush(deadline time.Time) bool {
+	timeout := time.NewTimer(time.Until(deadline))
+	defer timeout.Stop()
+
+	flushed := make(chan struct{})
+	w.closeMu.Lock()
+	if w.closed {
+		w.closeMu.Unlock()
+		<-w.done
+		return true
+	}
+	select {
+	case w.lines <- accessLogLine{flushed: flushed}:
+	case <-timeout.C:
+		w.closeMu.Unlock()
+		return false
+	}
+	w.closeMu.Unlock()
+
+	select {
+	case <-flushed:
+		return true
+	case <-w.done:
+		return true
+	case <-timeout.C:
+		return false
+	}
+}
+
+// close writes the remaining lines and stops the goroutine. A file or
+// Vector connection opened for the destination is closed too, so a reload
+// reopens rotated files.
+func (w *asyncLogWriter) close() {
+	w.closeMu.Lock()
+	w.closed = true
+	close(w.lines)
+	w.closeMu.Unlock()
+	<-w.done
+	if closer, ok := w.out.(io.Closer); ok && w.out != os.Stdout && w.out != os.Stderr {
+		_ = closer.Close()
+	}
+}
+
+// reportDropped logs how many lines were dropped since the last report
+func (w *asyncLogWriter) reportDropped() {
+	if n := w.pending.Swap(0); n > 0 {
+		logging.LogAccessLogDropped(fmt.Sprintf("%T", w.out), n, w.dropped.Load())
+	}
+}
+
+// accessLogSink is one access log output: its asynchronous writer, and the
+// format and filter of the entries it receives
+type accessLogSink struct {
+	writer *asyncLogWriter
+	format string                  // Empty = the access log's format
+	filter *config.AccessLogFilter // nil = every entry
+}
+
+// accessLog holds the access log sinks, and how entries are formatted and
+// sampled
+var accessLog = struct {
+	mu         sync.RWMutex
+	sinks      []accessLogSink
+	format     string  // "json", "text" (combined log format) or "pretty"
+	sampleRate float64 // Fraction of requests below 400 logged
+
+	excludeInternal bool // Drop entries of requests Navigator sent itself
+}{sinks: []accessLogSink{{writer: newAsyncLogWriter(os.Stdout, config.AccessLogBufferSize)}}, sampleRate: 1}
+
+// ConfigureAccessLog sets the access log format and sampling from
+// logging.access
+func ConfigureAccessLog(access config.AccessLogConfig) {
+	sampleRate := 1.0
+	if access.SampleRate != nil {
+		sampleRate = *access.SampleRate
+	}
+	accessLog.mu.Lock()
+	defer accessLog.mu.Unlock()
+	accessLog.format = access.Format
+	accessLog.sampleRate = sampleRate
+	accessLog.excludeInternal = access.ExcludeInternal
+}
+
+// accessLogSettings returns the sample rate, and whether internal requests
+// are dropped
+func accessLogSettings() (float64, bool) {
+	accessLog.mu.RLock()
+	defer accessLog.mu.RUnlock()
+	return accessLog.sampleRate, accessLog.excludeInternal
+}
+
+// AccessLogSink is an access log output with its own format and filter
+type AccessLogSink struct {
+	Output io.Writer
+	Format string                  // "json", "text" or "pretty" (empty = logging.access.format)
+	Filter *config.AccessLogFilter // nil = every entry
+}
+
+// SetAccessLogSinks replaces every access log sink at once, so no entry is
+// written to a mix of old and new ones. Entries already queued for the
+// previous sinks are written before they are closed, without holding up
+// requests logging to the new ones.
+func SetAccessLogSinks(sinks []AccessLogSink) {
+	replacement := make([]accessLogSink, len(sinks))
+	for i, sink := range sinks {
+		replacement[i] = accessLogSink{
+			writer: newAsyncLogWriter(sink.Output, config.AccessLogBufferSize),
+			format: sink.Format,
+			filter: sink.Filter,
+		}
+	}
+
+	accessLog.mu.Lock()
+	old := accessLog.sinks
+	accessLog.sinks = replacement
+	accessLog.mu.Unlock()
+
+	for _, sink := range old {
+		sink.writer.close()
+	}
+}
+
+// SetAccessLogWriter configures the output destination for access logs, in
+// place of any sinks. Each output of a process.MultiLogWriter gets its own
+// queue.
+func SetAccessLogWriter(writer io.Writer) {
+	if writer == nil {
+		return
+	}
+
+	outputs := []io.Writer{writer}
+	if multi, ok := writer.(*process.MultiLogWriter); ok {
+		outputs = multi.Outputs()
+	}
+	sinks := make([]AccessLogSink, len(outputs))
+	for i, output := range outputs {
+		sinks[i] = AccessLogSink{Output: output}
+	}
+	SetAccessLogSinks(sinks)
+}
+
+// FlushAccessLog waits until every queued access log entry has been written,
+// or for at most config.AccessLogFlushTimeout when a destination is stuck.
+// Called on shutdown so the last entries aren't lost.
+func FlushAccessLog() {
+	accessLog.mu.RLock()
+	sinks := accessLog.sinks
+	accessLog.mu.RUnlock()
+
+	deadline := time.Now().Add(config.AccessLogFlushTimeout)
+	for _, sink := range sinks {
+		if !sink.writer.flush(deadline) {
+			slog.Warn("Access log entries not written in time", "destination", fmt.Sprintf("%T", sink.writer.out), "timeout", config.AccessLogFlushTimeout)
+		}
+	}
+}
+
+// writeAccessLogEntry queues entry for every sink whose filter selects it,
+// formatting it once per format
+func writeAccessLogEntry(entry *AccessLogEntry, path string) {
+	accessLog.mu.RLock()
+	defer accessLog.mu.RUnlock()
+	lines := make(map[string][]byte, 1)
+	for _, sink := range accessLog.sinks {
+		if !accessLogFilterMatches(sink.filter, entry, path) {
+			continue
+		}
+		format := sink.format
+		if format == "" {
+			format = accessLog.format
+		}
+		line, ok := lines[format]
+		if !ok {
+			line = entry.format(format)
+			lines[format] = line
+		}
+		sink.writer.write(line)
+	}
+}
+
+// accessLogFilterMatches reports whether filter selects entry, the entry of a
+// request for path
+func accessLogFilterMatches(filter *config.AccessLogFilter, entry *AccessLogEntry, path string) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.StatusRanges) > 0 && !slices.ContainsFunc(filter.StatusRanges, func(r config.StatusRange) bool {
+		return entry.Status >= r.Min && entry.Status <= r.Max
+	}) {
+		return false
+	}
+	if len(filter.ResponseTypes) > 0 && !slices.Contains(filter.ResponseTypes, entry.ResponseType) {
+		return false
+	}
+	if slices.Contains(filter.ExcludeResponseTypes, entry.ResponseType) {
+		return false
+	}
+	if len(filter.Tenants) > 0 && !slices.Contains(filter.Tenants, entry.Tenant) {
+		return false
+	}
+	matchesPath := func(pattern *regexp.Regexp) bool { return pattern.MatchString(path) }
+	if len(filter.PathPatterns) > 0 && !slices.ContainsFunc(filter.PathPatterns, matchesPath) {
+		return false
+	}
+	return !slices.ContainsFunc(filter.ExcludePathPatterns, matchesPath)
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// blockingWriter blocks every write until release is closed
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+// slowWriter simulates a destination with per-write latency
+type slowWriter struct {
+	delay time.Duration
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return len(p), nil
+}
+
+func TestAccessLogNoLossUnderNormalLoad(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	const workers, perWorker = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				req := httptest.NewRequest("GET", fmt.Sprintf("/w%d/%d", worker, j), nil)
+				LogRequest(req, 200, 0, time.Now(), nil, false)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if entries := parseAccessLog(t, buf); len(entries) != workers*perWorker {
+		t.Errorf("logged %d entries, want %d", len(entries), workers*perWorker)
+	}
+}
+
+func TestAsyncLogWriterDropsWhenBlocked(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	w := newAsyncLogWriter(out, 4)
+
+	// One line is held by the blocked write, four fill the buffer, the rest drop
+	for i := 0; i < 10; i++ {
+		w.write([]byte("line\n"))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for w.dropped.Load() < 5 && time.Now().Before(deadline) {
+		w.write([]byte("line\n"))
+		time.Sleep(time.Millisecond)
+	}
+	if w.dropped.Load() < 5 {
+		t.Errorf("dropped = %d, want at least 5", w.dropped.Load())
+	}
+
+	close(out.release)
+	w.close()
+}
+
+func TestSetAccessLogWriterDrainsPreviousWriter(t *testing.T) {
+	var first bytes.Buffer
+	SetAccessLogWriter(&first)
+	t.Cleanup(func() { SetAccessLogWriter(os.Stdout) })
+
+	for i := 0; i < 50; i++ {
+		LogRequest(httptest.NewRequest("GET", "/first", nil), http.StatusOK, 0, time.Now(), nil, false)
+	}
+	var second bytes.Buffer
+	SetAccessLogWriter(&second)
+	LogRequest(httptest.NewRequest("GET", "/second", nil), http.StatusOK, 0, time.Now(), nil, false)
+	FlushAccessLog()
+
+	if n := strings.Count(first.String(), `"uri":"/first"`); n != 50 {
+		t.Errorf("previous writer received %d lines, want 50", n)
+	}
+	if entries := parseAccessLog(t, &second); len(entries) != 1 || entries[0].URI != "/second" {
+		t.Errorf("new writer received %q", second.String())
+	}
+}
+
+func TestSetAccessLogWriterSplitsMultiLogWriter(t *testing.T) {
+	var stdout, vector bytes.Buffer
+	SetAccessLogWriter(process.NewMultiLogWriter(&stdout, &vector))
+	t.Cleanup(func() { SetAccessLogWriter(os.Stdout) })
+
+	LogRequest(httptest.NewRequest("GET", "/entry", nil), http.StatusOK, 0, time.Now(), nil, false)
+	FlushAccessLog()
+
+	if stdout.String() == "" || stdout.String() != vector.String() {
+		t.Errorf("outputs = %q, %q; want the entry in both", stdout.String(), vector.String())
+	}
+}
+
+func BenchmarkAccessLog(b *testing.B) {
+	req := httptest.NewRequest("GET", "/showcase/2025/boston/", nil)
+	slow := slowWriter{delay: 50 * time.Microsecond}
+
+	// The request path waits for every write, as access logging did before buffering
+	b.Run("sync", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			data, _ := json.Marshal(AccessLogEntry{Method: req.Method, URI: req.URL.Path, Status: 200})
+			_, _ = slow.Write(append(data, '\n'))
+		}
+	})
+
+	b.Run("async", func(b *testing.B) {
+		SetAccessLogWriter(slow)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			LogRequest(req, 200, 0, time.Now(), nil, false)
+		}
+		b.StopTimer()
+		SetAccessLogWriter(os.Stdout)
+	})
+}
+
+func TestAccessLogTextFormatAndSampling(t *testing.T) {
+	buf := captureAccessLog(t)
+	t.Cleanup(func() { ConfigureAccessLog(config.AccessLogConfig{}) })
+
+	ConfigureAccessLog(config.AccessLogConfig{Format: "text"})
+	req := httptest.NewRequest("GET", "/showcase/2025/boston/heats?sort=asc", nil)
+	req.RemoteAddr = "192.0.2.1:45678"
+	req.Header.Set("User-Agent", "Test-Agent/1.0")
+	LogRequest(req, http.StatusOK, 512, time.Now(), nil, false)
+	FlushAccessLog()
+
+	line := buf.String()
+	pattern := regexp.MustCompile(`^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /showcase/2025/boston/heats\?sort=asc HTTP/1\.1" 200 512 "-" "Test-Agent/1\.0" \d+\.\d{3}\n$`)
+	if !pattern.MatchString(line) {
+		t.Errorf("Text entry = %q, want combined log format", line)
+	}
+
+	// With sampling, errors are still always logged; a rate of 0 logs
+	// nothing else
+	for _, rate := range []float64{0.000001, 0} {
+		buf.Reset()
+		ConfigureAccessLog(config.AccessLogConfig{Format: "json", SampleRate: &rate})
+		for i := 0; i < 50; i++ {
+			LogRequest(req, http.StatusOK, 0, time.Now(), nil, false)
+		}
+		LogRequest(req, http.StatusBadGateway, 0, time.Now(), nil, false)
+		entries := parseAccessLog(t, buf)
+		if len(entries) != 1 || entries[0].Status != http.StatusBadGateway {
+			t.Errorf("Entries sampled at %g = %+v, want only the 502", rate, entries)
+		}
+	}
+}
+
+func TestAccessLogPrettyFormat(t *testing.T) {
+	buf := captureAccessLog(t)
+	t.Cleanup(func() { ConfigureAccessLog(config.AccessLogConfig{}) })
+
+	ConfigureAccessLog(config.AccessLogConfig{Format: config.LogFormatPretty})
+	req := httptest.NewRequest("POST", "/showcase/2025/boston/heats?sort=asc", nil)
+	LogRequest(req, http.StatusBadGateway, 0, time.Now().Add(-12*time.Millisecond),
+		map[string]interface{}{"tenant": "boston", "response_type": "proxy", "error_message": "connection refused"}, false)
+	FlushAccessLog()
+
+	// The buffer isn't a terminal, so the line is written without colors
+	line := buf.String()
+	pattern := regexp.MustCompile(`^\d{2}:\d{2}:\d{2} POST /showcase/2025/boston/heats\?sort=asc → 502 1\dms \(boston, proxy\) connection refused\n$`)
+	if !pattern.MatchString(line) {
+		t.Errorf("Pretty entry = %q", line)
+	}
+}
+
+func TestAccessLogSinksFilterIndependently(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+logging:
+  access:
+    sinks:
+      - destination: stdout
+        filter:
+          exclude_response_types: [static]
+      - destination: /var/log/navigator/errors.log
+        format: text
+        filter:
+          status: ["400-599"]
+          exclude_response_types: [static]
+          exclude_paths: ['^/up$']
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	sinks := cfg.Logging.Access.Sinks
+	var full, errors bytes.Buffer
+	SetAccessLogSinks([]AccessLogSink{
+		{Output: &full, Format: sinks[0].Format, Filter: &sinks[0].Filter},
+		{Output: &errors, Format: sinks[1].Format, Filter: &sinks[1].Filter},
+	})
+	t.Cleanup(func() { SetAccessLogWriter(os.Stdout) })
+
+	log := func(path string, status int, responseType string) {
+		LogRequest(httptest.NewRequest("GET", path, nil), status, 0, time.Now(),
+			map[string]interface{}{"response_type": responseType}, false)
+	}
+	log("/showcase/", http.StatusOK, "proxy")
+	log("/showcase/assets/app.css", http.StatusOK, "static")
+	log("/showcase/assets/missing.css", http.StatusNotFound, "static")
+	log("/showcase/2025/boston/", http.StatusNotFound, "proxy")
+	log("/showcase/2025/raleigh/", http.StatusBadGateway, "error")
+	log("/up", http.StatusServiceUnavailable, "proxy")
+
+	var uris []string
+	for _, entry := range parseAccessLog(t, &full) {
+		uris = append(uris, entry.URI)
+	}
+	want := []string{"/showcase/", "/showcase/2025/boston/", "/showcase/2025/raleigh/", "/up"}
+	if strings.Join(uris, " ") != strings.Join(want, " ") {
+		t.Errorf("full sink entries = %v, want %v", uris, want)
+	}
+
+	lines := strings.Split(strings.TrimSpace(errors.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"GET /showcase/2025/boston/ HTTP/1.1" 404`) ||
+		!strings.Contains(lines[1], `"GET /showcase/2025/raleigh/ HTTP/1.1" 502`) {
+		t.Errorf("error sink entries = %q, want the 404 and 502 in combined log format", lines)
+	}
+}
+
+func TestAccessLogFilterMatches(t *testing.T) {
+	entry := &AccessLogEntry{Status: http.StatusNotFound, ResponseType: "proxy", Tenant: "2025/boston"}
+	tests := []struct {
+		name   string
+		filter config.AccessLogFilter
+		want   bool
+	}{
+		{"empty", config.AccessLogFilter{}, true},
+		{"status in range", config.AccessLogFilter{StatusRanges: []config.StatusRange{{Min: 200, Max: 299}, {Min: 400, Max: 499}}}, true},
+		{"status outside range", config.AccessLogFilter{StatusRanges: []config.StatusRange{{Min: 500, Max: 599}}}, false},
+		{"response type", config.AccessLogFilter{ResponseTypes: []string{"proxy"}}, true},
+		{"other response type", config.AccessLogFilter{ResponseTypes: []string{"static"}}, false},
+		{"excluded response type", config.AccessLogFilter{ExcludeResponseTypes: []string{"proxy"}}, false},
+		{"tenant", config.AccessLogFilter{Tenants: []string{"2025/boston"}}, true},
+		{"other tenant", config.AccessLogFilter{Tenants: []string{"2025/raleigh"}}, false},
+		{"path", config.AccessLogFilter{PathPatterns: []*regexp.Regexp{regexp.MustCompile(`^/showcase/`)}}, true},
+		{"other path", config.AccessLogFilter{PathPatterns: []*regexp.Regexp{regexp.MustCompile(`^/assets/`)}}, false},
+		{"excluded path", config.AccessLogFilter{ExcludePathPatterns: []*regexp.Regexp{regexp.MustCompile(`boston`)}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accessLogFilterMatches(&tt.filter, entry, "/showcase/2025/boston/"); got != tt.want {
+				t.Errorf("accessLogFilterMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessLogSinkSwapDoesNotWaitForOldSinks(t *testing.T) {
+	stuck := &blockingWriter{release: make(chan struct{})}
+	SetAccessLogSinks([]AccessLogSink{{Output: stuck}})
+	t.Cleanup(func() { SetAccessLogWriter(os.Stdout) })
+	LogRequest(httptest.NewRequest("GET", "/old", nil), 200, 0, time.Now(), nil, false)
+
+	// The old sink closes once its queued entry is written; meanwhile
+	// requests log to the new one
+	var replacement bytes.Buffer
+	swapped := make(chan struct{})
+	go func() {
+		SetAccessLogSinks([]AccessLogSink{{Output: &replacement}})
+		close(swapped)
+	}()
+	logged := make(chan struct{})
+	go func() {
+		for {
+			accessLog.mu.RLock()
+			current := len(accessLog.sinks) == 1 && accessLog.sinks[0].writer.out == &replacement
+			accessLog.mu.RUnlock()
+			if current {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		LogRequest(httptest.NewRequest("GET", "/new", nil), 200, 0, time.Now(), nil, false)
+		FlushAccessLog()
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Logging to the new sink waited for the old one to close")
+	}
+	close(stuck.release)
+	<-swapped
+	if !strings.Contains(replacement.String(), "/new") {
+		t.Errorf("New sink = %q, want the /new entry", replacement.String())
+	}
+}
+
+func TestAccessLogFlushGivesUpOnStuckDestination(t *testing.T) {
+	stuck := &blockingWriter{release: make(chan struct{})}
+	w := newAsyncLogWriter(stuck, 1)
+	defer w.close()
+	defer close(stuck.release)
+	w.write([]byte("line\n"))
+	w.write([]byte("line\n"))
+
+	start := time.Now()
+	if w.flush(start.Add(50 * time.Millisecond)) {
+		t.Error("flush() reported success for a stuck destination")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("flush() took %v, want it bounded by its deadline", elapsed)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// acmeChallengePrefix is the path prefix for ACME HTTP-01 challenges (RFC 8555 section 8.3)
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// maxACMETokenLength bounds token names; real tokens are 43 characters
+const maxACMETokenLength = 256
+
+// isACMEToken reports whether token uses only the base64url alphabet, which
+// also rules out path separators and "." or ".." segments
+func isACMEToken(token string) bool {
+	if token == "" || len(token) > maxACMETokenLength {
+		return false
+	}
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// handleACMEChallenge serves key authorizations written by an external ACME
+// client (e.g. certbot --webroot) to server.acme_challenge_dir. Returns false
+// if the request is not an ACME challenge.
+func (h *Handler) handleACMEChallenge(w http.ResponseWriter, r *http.Request) bool {
+	dir := h.config.Server.AcmeChallengeDir
+	if dir == "" || !strings.HasPrefix(r.URL.Path, acmeChallengePrefix) {
+		return false
+	}
+
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "acme-challenge")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return true
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, acmeChallengePrefix)
+	if !isACMEToken(token) {
+		http.NotFound(w, r)
+		return true
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, token))
+	if err != nil {
+		http.NotFound(w, r)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(content)
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestIsACMEToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"LoqXcYV8q5ONbJQxbmR7SCTNo3tiAXDfowyjxAjEuX0", true},
+		{"abc-DEF_123", true},
+		{"", false},
+		{"..", false},
+		{"../secret", false},
+		{"sub/token", false},
+		{"token.txt", false},
+		{"token%2F", false},
+		{strings.Repeat("a", maxACMETokenLength+1), false},
+	}
+
+	for _, tt := range tests {
+		if got := isACMEToken(tt.token); got != tt.want {
+			t.Errorf("isACMEToken(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestACMEChallenge(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "valid-token_1"), []byte("valid-token_1.thumbprint"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A file outside the challenge directory that traversal must not reach
+	parent := filepath.Dir(dir)
+	secret := filepath.Join(parent, "acme-secret")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(secret)
+
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("admin:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	basicAuth, err := auth.LoadAuthFile(htpasswd, "Restricted", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Applications: config.Applications{
+			Tenants: []config.Tenant{{Path: "/", Var: map[string]interface{}{}}},
+		},
+	}
+	cfg.Server.AcmeChallengeDir = dir
+	cfg.Maintenance.Enabled = true
+	cfg.Server.RewriteRules = []config.RewriteRule{{
+		Pattern:     mustCompile("^/(.*)$"),
+		Replacement: "/elsewhere/$1",
+		Flag:        "redirect",
+	}}
+
+	handler := CreateTestHandler(cfg, nil, basicAuth, nil)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"known token bypasses auth, rewrites, and maintenance", "GET", "/.well-known/acme-challenge/valid-token_1", http.StatusOK, "valid-token_1.thumbprint"},
+		{"head request", "HEAD", "/.well-known/acme-challenge/valid-token_1", http.StatusOK, ""},
+		{"unknown token", "GET", "/.well-known/acme-challenge/missing", http.StatusNotFound, ""},
+		{"empty token", "GET", "/.well-known/acme-challenge/", http.StatusNotFound, ""},
+		{"traversal normalizes out of the challenge path", "GET", "/.well-known/acme-challenge/..%2F..%2Facme-secret", http.StatusUnauthorized, ""},
+		{"nested path", "GET", "/.well-known/acme-challenge/a/b", http.StatusNotFound, ""},
+		{"post rejected", "POST", "/.well-known/acme-challenge/valid-token_1", http.StatusMethodNotAllowed, ""},
+		{"other paths still require auth", "GET", "/.well-known/other", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				return
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got := rec.Header().Get("Content-Type"); got != "text/plain" {
+					t.Errorf("Content-Type = %q, want text/plain", got)
+				}
+				if got := rec.Body.String(); got != tt.wantBody {
+					t.Errorf("body = %q, want %q", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestACMEChallengeDisabled(t *testing.T) {
+	cfg := &config.Config{}
+	handler := &Handler{config: cfg}
+
+	req := httptest.NewRequest("GET", "/.well-known/acme-challenge/token", nil)
+	if handler.handleACMEChallenge(httptest.NewRecorder(), req) {
+		t.Error("expected challenge handling to be skipped without acme_challenge_dir")
+	}
+}
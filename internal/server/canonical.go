@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// canonicalRedirect returns where server.canonical sends r and the status to
+// send it with, or "" when r is already on the canonical host and scheme.
+// The scheme and host come from the forwarding headers only with
+// trust_proxy, so requests that reached a TLS-terminating load balancer over
+// https don't loop, and a client can't claim https to skip the redirect.
+func canonicalRedirect(canonical *config.CanonicalConfig, r *http.Request) (string, int) {
+	if !canonical.Enabled() {
+		return "", 0
+	}
+	for _, prefix := range canonical.ExcludePaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return "", 0
+		}
+	}
+
+	host := strings.ToLower(getHost(r))
+	hostname := host
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		hostname = name
+	}
+	if hostname == "" {
+		return "", 0
+	}
+	for _, excluded := range canonical.ExcludeHosts {
+		if excluded != "" && matchHost(excluded, hostname) {
+			return "", 0
+		}
+	}
+
+	scheme, _, _ := strings.Cut(canonicalScheme(r), ",")
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	targetScheme, targetHost := scheme, host
+	if canonical.ForceHTTPS && scheme != "https" {
+		// The port served plain http, so it can't be the https one
+		targetScheme, targetHost = "https", hostname
+		if strings.Contains(hostname, ":") {
+			targetHost = "[" + hostname + "]"
+		}
+	}
+	if canonical.Host != "" && !isCanonicalHost(canonical.Host, host, hostname) {
+		targetHost = canonical.Host
+	}
+	if targetScheme == scheme && targetHost == host {
+		return "", 0
+	}
+
+	status := http.StatusMovedPermanently
+	preserveMethod := r.Method != http.MethodGet && r.Method != http.MethodHead
+	switch {
+	case canonical.Redirect == config.CanonicalRedirectTemporary && preserveMethod:
+		status = http.StatusTemporaryRedirect
+	case canonical.Redirect == config.CanonicalRedirectTemporary:
+		status = http.StatusFound
+	case preserveMethod:
+		status = http.StatusPermanentRedirect
+	}
+	return targetScheme + "://" + targetHost + requestTarget(r), status
+}
+
+// canonicalScheme returns the scheme r reached the site with: the forwarded
+// one with trust_proxy, otherwise the connection's
+func canonicalScheme(r *http.Request) string {
+	if proxy.GetTrustProxy() {
+		return getScheme(r)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// isCanonicalHost reports whether the request's host (with and without its
+// port) is the canonical one. A canonical host without a port matches any.
+func isCanonicalHost(canonicalHost, host, hostname string) bool {
+	if strings.Contains(canonicalHost, ":") && !strings.HasPrefix(canonicalHost, "[") {
+		return canonicalHost == host
+	}
+	return canonicalHost == hostname || canonicalHost == host
+}
+
+// requestTarget returns the path and query exactly as the client sent them,
+// before normalization, so redirects don't change what the app will see
+func requestTarget(r *http.Request) string {
+	target := r.RequestURI
+	if target != "" && !strings.HasPrefix(target, "/") {
+		// Absolute form: drop the scheme and authority
+		if _, rest, ok := strings.Cut(target, "://"); ok {
+			target = "/"
+			if i := strings.IndexAny(rest, "/?"); i >= 0 {
+				target = "/" + strings.TrimPrefix(rest[i:], "/")
+			}
+		}
+	}
+	if !strings.HasPrefix(target, "/") {
+		target = r.URL.RequestURI()
+	}
+	return target
+}
+
+// serveCanonicalRedirect redirects r to the canonical host and scheme,
+// returning false when it's already there
+func (h *Handler) serveCanonicalRedirect(recorder *ResponseRecorder, r *http.Request) bool {
+	location, status := canonicalRedirect(&h.config.Server.Canonical, r)
+	if location == "" {
+		return false
+	}
+	recorder.SetMetadata("response_type", "redirect")
+	recorder.SetMetadata("destination", location)
+	logging.LogCanonicalRedirect(getHost(r), r.URL.Path, location, status)
+	http.Redirect(recorder, r, location, status)
+	return true
+}
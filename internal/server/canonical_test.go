@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	proxypkg "github.com/rubys/navigator/internal/proxy"
+)
+
+func TestCanonicalRedirect(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  health_check:
+    path: /up
+  canonical:
+    host: example.com
+    force_https: true
+    exclude_paths: [/internal/]
+    exclude_hosts: [localhost, "*.internal"]
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	appManager := process.NewAppManager(cfg)
+	t.Cleanup(appManager.Cleanup)
+	h := CreateTestHandler(cfg, appManager, nil, &idle.Manager{})
+	proxypkg.SetTrustProxy(true)
+	defer proxypkg.SetTrustProxy(false)
+
+	tests := []struct {
+		name, method, target, host, proto string
+		wantStatus                        int
+		wantLocation                      string
+	}{
+		{"http to https", "GET", "/studios?b=2&a=1", "example.com", "", http.StatusMovedPermanently, "https://example.com/studios?b=2&a=1"},
+		{"http with port", "GET", "/", "example.com:8080", "", http.StatusMovedPermanently, "https://example.com/"},
+		{"www to apex", "GET", "/a%2Fb/../c?q=%20x", "www.example.com", "https", http.StatusMovedPermanently, "https://example.com/a%2Fb/../c?q=%20x"},
+		{"post keeps method", "POST", "/form", "www.example.com", "https", http.StatusPermanentRedirect, "https://example.com/form"},
+		{"absolute form", "GET", "http://Example.COM/x?y", "Example.COM", "", http.StatusMovedPermanently, "https://example.com/x?y"},
+		{"behind load balancer", "GET", "/studios", "EXAMPLE.com", "https", 0, ""},
+		{"excluded path", "GET", "/internal/status", "www.example.com", "", 0, ""},
+		{"excluded host", "GET", "/", "localhost:3000", "", 0, ""},
+		{"excluded wildcard host", "GET", "/", "app.internal", "", 0, ""},
+		{"health check", "GET", "/up", "www.example.com", "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Host = tt.host
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if tt.wantStatus == 0 {
+				if location := rec.Header().Get("Location"); location != "" {
+					t.Errorf("redirected to %q with status %d, want no redirect", location, rec.Code)
+				}
+				return
+			}
+			if rec.Code != tt.wantStatus || rec.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestCanonicalRedirectIgnoresForwardedSchemeWithoutTrustProxy(t *testing.T) {
+	canonical := &config.CanonicalConfig{ForceHTTPS: true}
+	for _, trust := range []bool{false, true} {
+		proxypkg.SetTrustProxy(trust)
+		req := httptest.NewRequest("GET", "/studios", nil)
+		req.Host = "example.com"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		location, _ := canonicalRedirect(canonical, req)
+		if want := map[bool]string{false: "https://example.com/studios", true: ""}[trust]; location != want {
+			t.Errorf("trust_proxy %v: redirected to %q, want %q", trust, location, want)
+		}
+	}
+	proxypkg.SetTrustProxy(false)
+
+	// A TLS connection is https whatever the headers say
+	req := httptest.NewRequest("GET", "/studios", nil)
+	req.Host = "example.com"
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set("X-Forwarded-Proto", "http")
+	if location, _ := canonicalRedirect(canonical, req); location != "" {
+		t.Errorf("TLS request redirected to %q", location)
+	}
+}
+
+func TestCanonicalRedirectTemporary(t *testing.T) {
+	canonical := &config.CanonicalConfig{Host: "example.com:8443", Redirect: config.CanonicalRedirectTemporary}
+	tests := []struct {
+		method, host string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"GET", "example.com", http.StatusFound, "http://example.com:8443/path"},
+		{"DELETE", "www.example.com:8443", http.StatusTemporaryRedirect, "http://example.com:8443/path"},
+		{"GET", "example.com:8443", 0, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/path", nil)
+		req.Host = tt.host
+		location, status := canonicalRedirect(canonical, req)
+		if location != tt.wantLocation || status != tt.wantStatus {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.host, status, location, tt.wantStatus, tt.wantLocation)
+		}
+	}
+}
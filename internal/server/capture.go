@@ -0,0 +1,300 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// redactedMarker replaces any text matched by a capture redact pattern
+const redactedMarker = "[REDACTED]"
+
+// CaptureEntry represents a captured request/response pair written as a JSON line
+type CaptureEntry struct {
+	Timestamp         string   `json:"@timestamp"`
+	RequestID         string   `json:"request_id"`
+	Method            string   `json:"method"`
+	URI               string   `json:"uri"`
+	Status            int      `json:"status"`
+	RequestHeaders    []string `json:"request_headers,omitempty"`
+	RequestBody       string   `json:"request_body,omitempty"`
+	RequestTruncated  bool     `json:"request_truncated,omitempty"`
+	ResponseEncoding  string   `json:"response_encoding,omitempty"`
+	ResponseBody      string   `json:"response_body,omitempty"`
+	ResponseTruncated bool     `json:"response_truncated,omitempty"`
+}
+
+// captureOutput holds the destination for capture entries
+var captureOutput struct {
+	mu     sync.Mutex
+	path   string
+	writer io.Writer
+	closer io.Closer
+}
+
+// ConfigureBodyCapture opens (or closes) the capture file to match the configuration.
+// Called at startup and on every reload so capture can be removed without a restart.
+func ConfigureBodyCapture(cfg config.CaptureConfig) error {
+	captureOutput.mu.Lock()
+	defer captureOutput.mu.Unlock()
+
+	if !cfg.Enabled {
+		closeCaptureOutput()
+		return nil
+	}
+
+	if captureOutput.path == cfg.File && captureOutput.writer != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0755); err != nil {
+		return fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open capture file %s: %w", cfg.File, err)
+	}
+
+	closeCaptureOutput()
+	captureOutput.path = cfg.File
+	captureOutput.writer = file
+	captureOutput.closer = file
+	slog.Warn("Request/response body capture enabled", "path", cfg.Path, "file", cfg.File)
+	return nil
+}
+
+// SetCaptureWriter configures the output destination for capture entries (used by tests)
+func SetCaptureWriter(writer io.Writer) {
+	captureOutput.mu.Lock()
+	defer captureOutput.mu.Unlock()
+	closeCaptureOutput()
+	captureOutput.writer = writer
+}
+
+// closeCaptureOutput closes the current capture file; caller must hold captureOutput.mu
+func closeCaptureOutput() {
+	if captureOutput.closer != nil {
+		_ = captureOutput.closer.Close()
+	}
+	captureOutput.path = ""
+	captureOutput.writer = nil
+	captureOutput.closer = nil
+}
+
+// cappedBuffer buffers at most limit bytes, recording whether anything was dropped
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write buffers data up to the limit and always reports success
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining <= 0 {
+		if len(p) > 0 {
+			b.truncated = true
+		}
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+// captureReadCloser copies request body bytes into a capped buffer as they are read
+type captureReadCloser struct {
+	io.ReadCloser
+	buffer *cappedBuffer
+}
+
+// Read reads from the underlying body, teeing into the capture buffer
+func (c *captureReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = c.buffer.Write(p[:n])
+	}
+	return n, err
+}
+
+// bodyCapture collects request and response bodies for a single matching request
+type bodyCapture struct {
+	config   *config.CaptureConfig
+	request  *cappedBuffer
+	response cappedBuffer
+
+	responseStarted bool         // The response's headers have been looked at
+	skipResponse    bool         // The response's content type isn't captured
+	gzip            *gzipCapture // Decoder for a gzip response, while it's written
+}
+
+// gzipCapture decodes a gzip response as it's written, so the capture limit
+// applies to the decoded body rather than to the compressed bytes
+type gzipCapture struct {
+	writer *io.PipeWriter
+	done   chan struct{}
+	err    error // Why the body couldn't be decoded, if it couldn't
+}
+
+// newBodyCapture returns a capture for the request, or nil when capture is disabled
+// or the path does not match. Non-matching requests allocate nothing.
+func newBodyCapture(cfg *config.CaptureConfig, r *http.Request) *bodyCapture {
+	if cfg == nil || !cfg.Enabled || cfg.PathPattern == nil {
+		return nil
+	}
+	if !cfg.PathPattern.MatchString(r.URL.Path) {
+		return nil
+	}
+
+	c := &bodyCapture{
+		config:   cfg,
+		response: cappedBuffer{limit: cfg.MaxBytes},
+	}
+
+	if r.Body != nil && r.Body != http.NoBody && c.allowedContentType(r.Header.Get("Content-Type")) {
+		c.request = &cappedBuffer{limit: cfg.MaxBytes}
+		r.Body = &captureReadCloser{ReadCloser: r.Body, buffer: c.request}
+	}
+
+	return c
+}
+
+// allowedContentType checks a Content-Type against the configured allowlist
+func (c *bodyCapture) allowedContentType(contentType string) bool {
+	if len(c.config.ContentTypes) == 0 {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	for _, allowed := range c.config.ContentTypes {
+		if strings.HasPrefix(contentType, strings.ToLower(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact replaces every match of the configured redaction patterns
+func (c *bodyCapture) redact(s string) string {
+	return redactString(c.config.RedactPatterns, s)
+}
+
+// redactString replaces every match of patterns with the redacted marker
+func redactString(patterns []*regexp.Regexp, s string) string {
+	for _, pattern := range patterns {
+		s = pattern.ReplaceAllString(s, redactedMarker)
+	}
+	return s
+}
+
+// writeResponse records response body bytes. The headers are final by the
+// first write, so that's when the content type and encoding are checked.
+func (c *bodyCapture) writeResponse(header http.Header, p []byte) {
+	if !c.responseStarted {
+		c.responseStarted = true
+		c.skipResponse = !c.allowedContentType(header.Get("Content-Type"))
+		if !c.skipResponse && strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+			c.gzip = c.startGzip()
+		}
+	}
+	switch {
+	case c.skipResponse:
+	case c.gzip != nil:
+		// Fails once the decoder has all it needs; the rest is dropped
+		_, _ = c.gzip.writer.Write(p)
+	default:
+		_, _ = c.response.Write(p)
+	}
+}
+
+// startGzip decodes the response into the capture buffer in the background,
+// reading no more than one byte past the limit so truncation is noticed
+// without inflating the rest of the body
+func (c *bodyCapture) startGzip() *gzipCapture {
+	reader, writer := io.Pipe()
+	g := &gzipCapture{writer: writer, done: make(chan struct{})}
+	go func() {
+		defer close(g.done)
+		zr, err := gzip.NewReader(reader)
+		if err == nil {
+			// A truncated gzip stream yields what it can before erroring
+			_, _ = io.Copy(&c.response, io.LimitReader(zr, int64(c.config.MaxBytes)+1))
+		}
+		g.err = err
+		_ = reader.CloseWithError(io.ErrClosedPipe)
+	}()
+	return g
+}
+
+// responseBody returns the captured response body, waiting for a gzip
+// response to finish decoding
+func (c *bodyCapture) responseBody() (string, bool) {
+	if c.gzip != nil {
+		_ = c.gzip.writer.Close()
+		<-c.gzip.done
+		if c.gzip.err != nil {
+			return fmt.Sprintf("[undecodable gzip body: %v]", c.gzip.err), false
+		}
+	}
+	return c.response.buf.String(), c.response.truncated
+}
+
+// finish builds the capture entry and writes it to the capture output
+func (c *bodyCapture) finish(req *http.Request, header http.Header, statusCode int) {
+	captureOutput.mu.Lock()
+	defer captureOutput.mu.Unlock()
+	if captureOutput.writer == nil {
+		return
+	}
+
+	uri := req.URL.Path
+	if req.URL.RawQuery != "" {
+		uri += "?" + req.URL.RawQuery
+	}
+
+	entry := CaptureEntry{
+		Timestamp: time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		RequestID: req.Header.Get("X-Request-Id"),
+		Method:    req.Method,
+		URI:       c.redact(uri),
+		Status:    statusCode,
+	}
+
+	for name, values := range req.Header {
+		for _, value := range values {
+			entry.RequestHeaders = append(entry.RequestHeaders, c.redact(name+": "+value))
+		}
+	}
+	sort.Strings(entry.RequestHeaders)
+
+	if c.request != nil {
+		entry.RequestBody = c.redact(c.request.buf.String())
+		entry.RequestTruncated = c.request.truncated
+	}
+
+	if c.allowedContentType(header.Get("Content-Type")) {
+		entry.ResponseEncoding = header.Get("Content-Encoding")
+		body, truncated := c.responseBody()
+		entry.ResponseBody = c.redact(body)
+		entry.ResponseTruncated = truncated
+	}
+
+	data, _ := json.Marshal(entry)
+	_, _ = fmt.Fprintln(captureOutput.writer, string(data))
+}
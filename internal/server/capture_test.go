@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func captureTestConfig(maxBytes int) *config.CaptureConfig {
+	return &config.CaptureConfig{
+		Enabled:     true,
+		Path:        "^/api/",
+		File:        "unused",
+		MaxBytes:    maxBytes,
+		PathPattern: regexp.MustCompile("^/api/"),
+		RedactPatterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)authorization: .*`),
+			regexp.MustCompile(`\b\d{4}-\d{4}-\d{4}-\d{4}\b`),
+		},
+	}
+}
+
+func runCapture(t *testing.T, cfg *config.CaptureConfig, req *http.Request, respond func(w http.ResponseWriter)) CaptureEntry {
+	t.Helper()
+
+	var out bytes.Buffer
+	SetCaptureWriter(&out)
+	defer SetCaptureWriter(nil)
+
+	recorder := NewTestResponseRecorder(httptest.NewRecorder(), nil, req)
+	recorder.capture = newBodyCapture(cfg, req)
+	if recorder.capture == nil {
+		t.Fatal("expected capture for matching request")
+	}
+
+	// Simulate a backend consuming the request body
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+	}
+	respond(recorder)
+	recorder.Finish(req)
+
+	var entry CaptureEntry
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse capture entry %q: %v", out.String(), err)
+	}
+	return entry
+}
+
+func TestBodyCaptureRedaction(t *testing.T) {
+	cfg := captureTestConfig(1024)
+	req := httptest.NewRequest("POST", "/api/pay", strings.NewReader(`{"card":"4111-1111-1111-1111"}`))
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Request-Id", "req-123")
+
+	entry := runCapture(t, cfg, req, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"charged":"4111-1111-1111-1111"}`))
+	})
+
+	if entry.RequestID != "req-123" {
+		t.Errorf("RequestID = %q, want req-123", entry.RequestID)
+	}
+	if entry.Status != http.StatusCreated {
+		t.Errorf("Status = %d, want 201", entry.Status)
+	}
+	if strings.Contains(entry.RequestBody, "4111") || strings.Contains(entry.ResponseBody, "4111") {
+		t.Errorf("card number not redacted: req=%q resp=%q", entry.RequestBody, entry.ResponseBody)
+	}
+	for _, header := range entry.RequestHeaders {
+		if strings.Contains(header, "secret-token") {
+			t.Errorf("Authorization header not redacted: %q", header)
+		}
+	}
+	if !strings.Contains(entry.RequestBody, redactedMarker) {
+		t.Errorf("expected redaction marker in request body, got %q", entry.RequestBody)
+	}
+}
+
+func TestBodyCaptureTruncation(t *testing.T) {
+	cfg := captureTestConfig(10)
+	req := httptest.NewRequest("POST", "/api/upload", strings.NewReader(strings.Repeat("a", 100)))
+
+	entry := runCapture(t, cfg, req, func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte(strings.Repeat("b", 50)))
+		_, _ = w.Write([]byte(strings.Repeat("c", 50)))
+	})
+
+	if entry.RequestBody != strings.Repeat("a", 10) || !entry.RequestTruncated {
+		t.Errorf("request body = %q (truncated=%v), want 10 bytes truncated", entry.RequestBody, entry.RequestTruncated)
+	}
+	if entry.ResponseBody != strings.Repeat("b", 10) || !entry.ResponseTruncated {
+		t.Errorf("response body = %q (truncated=%v), want 10 bytes truncated", entry.ResponseBody, entry.ResponseTruncated)
+	}
+}
+
+func TestBodyCaptureGzipResponse(t *testing.T) {
+	cfg := captureTestConfig(64)
+	req := httptest.NewRequest("GET", "/api/data", nil)
+
+	// Compressed, the body is still larger than the limit, so it must be
+	// decoded before it's truncated
+	var plain strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&plain, "line %d\n", i*7919%1000)
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(plain.String()))
+	_ = zw.Close()
+	if compressed.Len() <= 64 {
+		t.Fatalf("compressed body is %d bytes, want more than the limit", compressed.Len())
+	}
+
+	entry := runCapture(t, cfg, req, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Encoding", "gzip")
+		for data := compressed.Bytes(); len(data) > 0; data = data[min(16, len(data)):] {
+			_, _ = w.Write(data[:min(16, len(data))])
+		}
+	})
+
+	if entry.ResponseBody != plain.String()[:64] {
+		t.Errorf("expected decoded body truncated to 64 bytes, got %q", entry.ResponseBody)
+	}
+	if !entry.ResponseTruncated {
+		t.Error("expected response to be marked truncated")
+	}
+	if entry.ResponseEncoding != "gzip" {
+		t.Errorf("ResponseEncoding = %q, want gzip", entry.ResponseEncoding)
+	}
+}
+
+func TestBodyCaptureUndecodableGzipResponse(t *testing.T) {
+	cfg := captureTestConfig(64)
+	req := httptest.NewRequest("GET", "/api/data", nil)
+
+	entry := runCapture(t, cfg, req, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write([]byte("not gzip at all"))
+	})
+
+	if !strings.HasPrefix(entry.ResponseBody, "[undecodable gzip body:") {
+		t.Errorf("ResponseBody = %q, want undecodable marker", entry.ResponseBody)
+	}
+}
+
+func TestBodyCaptureContentTypeAllowlist(t *testing.T) {
+	cfg := captureTestConfig(1024)
+	cfg.ContentTypes = []string{"application/json"}
+	req := httptest.NewRequest("POST", "/api/form", strings.NewReader("a=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	entry := runCapture(t, cfg, req, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	if entry.RequestBody != "" {
+		t.Errorf("expected form body to be skipped, got %q", entry.RequestBody)
+	}
+	if entry.ResponseBody != `{"ok":true}` {
+		t.Errorf("expected JSON response to be captured, got %q", entry.ResponseBody)
+	}
+}
+
+func TestBodyCaptureNonMatchingRoute(t *testing.T) {
+	cfg := captureTestConfig(1024)
+	body := strings.NewReader("payload")
+	req := httptest.NewRequest("POST", "/other", body)
+	originalBody := req.Body
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if newBodyCapture(cfg, req) != nil {
+			t.Fatal("expected no capture for non-matching route")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("non-matching route allocated %v times, want 0", allocs)
+	}
+	if req.Body != originalBody {
+		t.Error("request body should not be wrapped for non-matching route")
+	}
+
+	if newBodyCapture(&config.CaptureConfig{}, req) != nil {
+		t.Error("expected no capture when disabled")
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// coalescedResponse is a complete response shared with waiting requests
+type coalescedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// writeTo replays the shared response to another client
+func (c *coalescedResponse) writeTo(w http.ResponseWriter) {
+	for key, values := range c.header {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.WriteHeader(c.status)
+	_, _ = w.Write(c.body)
+}
+
+// coalescedCall is an in-flight request that identical requests wait on
+type coalescedCall struct {
+	done     chan struct{}
+	waiters  int
+	response *coalescedResponse // nil when the response could not be shared
+}
+
+// requestCoalescer groups identical concurrent requests so only one reaches the backend
+type requestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// tenantCoalescer is shared across handler reloads so in-flight calls survive a SIGHUP
+var tenantCoalescer = &requestCoalescer{calls: make(map[string]*coalescedCall)}
+
+// join returns the in-flight call for key and whether the caller leads it
+func (c *requestCoalescer) join(key string) (*coalescedCall, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, ok := c.calls[key]; ok {
+		call.waiters++
+		return call, false
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[key] = call
+	return call, true
+}
+
+// finish publishes the leader's response, releases waiters, and returns how many there were
+func (c *requestCoalescer) finish(key string, call *coalescedCall, response *coalescedResponse) int {
+	c.mu.Lock()
+	delete(c.calls, key)
+	waiters := call.waiters
+	call.response = response
+	c.mu.Unlock()
+
+	close(call.done)
+	return waiters
+}
+
+// canCoalesce reports whether a request is safe to answer with another client's response
+func canCoalesce(r *http.Request, cfg config.CoalesceConfig) bool {
+	if !cfg.Enabled {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if proxy.IsWebSocketRequest(r) || r.Header.Get("Range") != "" {
+		return false
+	}
+	if !cfg.AllowCredentials && (r.Header.Get("Cookie") != "" || r.Header.Get("Authorization") != "") {
+		return false
+	}
+	return true
+}
+
+// coalesceKey identifies requests that would receive the same response
+func coalesceKey(r *http.Request, varyHeaders []string) string {
+	var key strings.Builder
+	key.WriteString(r.Method)
+	key.WriteByte(' ')
+	key.WriteString(r.Host)
+	key.WriteString(r.URL.RequestURI())
+	for _, name := range varyHeaders {
+		key.WriteByte('\n')
+		key.WriteString(http.CanonicalHeaderKey(name))
+		key.WriteString(": ")
+		key.WriteString(strings.Join(r.Header.Values(name), ", "))
+	}
+	return key.String()
+}
+
+// coalesceWriter passes the leader's response through to its client while
+// keeping a copy for waiting requests, up to a size limit
+type coalesceWriter struct {
+	http.ResponseWriter
+	limit  int64
+	status int
+	header http.Header
+	body   bytes.Buffer
+	shared bool // False once the response is too large or a write failed
+}
+
+func newCoalesceWriter(w http.ResponseWriter, limit int64) *coalesceWriter {
+	return &coalesceWriter{ResponseWriter: w, limit: limit, shared: true}
+}
+
+// WriteHeader records the status and headers as sent to the client
+func (c *coalesceWriter) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+		c.header = c.ResponseWriter.Header().Clone()
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+// Write copies body bytes into the shared buffer until the limit is exceeded
+func (c *coalesceWriter) Write(data []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	n, err := c.ResponseWriter.Write(data)
+	if err != nil || n < len(data) {
+		c.shared = false
+	}
+	if c.shared {
+		if int64(c.body.Len()+n) > c.limit {
+			c.shared = false
+			c.body = bytes.Buffer{}
+		} else {
+			c.body.Write(data[:n])
+		}
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher for streaming responses
+func (c *coalesceWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (c *coalesceWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// sharedResponse returns the captured response if it can be given to other clients
+func (c *coalesceWriter) sharedResponse(cfg config.CoalesceConfig) *coalescedResponse {
+	if !c.shared || c.status == 0 || c.status >= http.StatusInternalServerError {
+		return nil
+	}
+	// A Set-Cookie would hand one client's session to everyone else
+	if !cfg.AllowCredentials && len(c.header.Values("Set-Cookie")) > 0 {
+		return nil
+	}
+	return &coalescedResponse{status: c.status, header: c.header, body: c.body.Bytes()}
+}
+
+// proxyCoalesced proxies a request to a starting tenant, sharing a single
+// backend request among identical concurrent requests
+func proxyCoalesced(recorder *ResponseRecorder, r *http.Request, cfg config.CoalesceConfig, key, tenantName, targetURL string) {
+	call, leader := tenantCoalescer.join(key)
+	if !leader {
+		select {
+		case <-call.done:
+		case <-r.Context().Done():
+			recorder.SetMetadata("response_type", "client_closed")
+			recorder.WriteHeader(499)
+			return
+		}
+
+		if call.response != nil {
+			recorder.SetMetadata("response_type", "coalesced")
+			call.response.writeTo(recorder)
+			return
+		}
+
+		// The leader's response could not be shared; proxy this request on its own
+		proxy.ProxyWithWebSocketSupport(recorder, r, targetURL, nil)
+		return
+	}
+
+	writer := newCoalesceWriter(recorder, cfg.MaxResponseSize)
+	proxy.ProxyWithWebSocketSupport(writer, r, targetURL, nil)
+
+	var response *coalescedResponse
+	if r.Context().Err() == nil {
+		response = writer.sharedResponse(cfg)
+	}
+	if waiters := tenantCoalescer.finish(key, call, response); waiters > 0 {
+		recorder.SetMetadata("coalesced", waiters)
+		logging.LogRequestsCoalesced(tenantName, r.URL.Path, waiters, response != nil)
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func testCoalesceConfig() config.CoalesceConfig {
+	return config.CoalesceConfig{
+		Enabled:         true,
+		MaxResponseSize: config.DefaultCoalesceMaxResponseSize,
+		VaryHeaders:     config.DefaultCoalesceVaryHeaders,
+	}
+}
+
+// waitForWaiters blocks until n requests are waiting on key
+func waitForWaiters(t *testing.T, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		tenantCoalescer.mu.Lock()
+		call := tenantCoalescer.calls[key]
+		waiting := call != nil && call.waiters >= n
+		tenantCoalescer.mu.Unlock()
+		if waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d coalesced requests", n)
+}
+
+// runCoalesced issues n identical requests against a backend that holds its
+// response until every follower is waiting, returning the responses and hit count
+func runCoalesced(t *testing.T, cfg config.CoalesceConfig, n int, respond func(w http.ResponseWriter)) ([]*httptest.ResponseRecorder, []*ResponseRecorder, int32) {
+	t.Helper()
+
+	var hits int32
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			<-release
+		}
+		respond(w)
+	}))
+	defer backend.Close()
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "/showcase/2025/boston/", nil)
+		req.Header.Set("Accept", "text/html")
+		return req
+	}
+	key := coalesceKey(newRequest(), cfg.VaryHeaders)
+
+	responses := make([]*httptest.ResponseRecorder, n)
+	recorders := make([]*ResponseRecorder, n)
+	var wg sync.WaitGroup
+	start := func(i int) {
+		req := newRequest()
+		responses[i] = httptest.NewRecorder()
+		recorders[i] = NewTestResponseRecorder(responses[i], nil, req)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proxyCoalesced(recorders[i], req, cfg, key, "2025/boston", backend.URL)
+		}()
+	}
+
+	// Start the leader first so it is the one that reaches the backend
+	start(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&hits) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i < n; i++ {
+		start(i)
+	}
+	waitForWaiters(t, key, n-1)
+	close(release)
+	wg.Wait()
+
+	return responses, recorders, atomic.LoadInt32(&hits)
+}
+
+func TestCoalescedRequestsShareOneBackendRequest(t *testing.T) {
+	responses, recorders, hits := runCoalesced(t, testCoalesceConfig(), 20, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("X-Backend", "rails")
+		_, _ = w.Write([]byte("<h1>Boston</h1>"))
+	})
+
+	if hits != 1 {
+		t.Fatalf("backend saw %d requests, want exactly 1", hits)
+	}
+	for i, rec := range responses {
+		if rec.Code != http.StatusOK || rec.Body.String() != "<h1>Boston</h1>" {
+			t.Errorf("response %d = %d %q, want copy of leader response", i, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("X-Backend") != "rails" {
+			t.Errorf("response %d missing backend headers", i)
+		}
+	}
+
+	if waiters := recorders[0].metadata["coalesced"]; waiters != 19 {
+		t.Errorf("leader coalesced = %v, want 19", waiters)
+	}
+	for i := 1; i < len(recorders); i++ {
+		if recorders[i].metadata["response_type"] != "coalesced" {
+			t.Errorf("follower %d response_type = %v, want coalesced", i, recorders[i].metadata["response_type"])
+		}
+	}
+}
+
+func TestCoalescedResponsesNotShared(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(*config.CoalesceConfig)
+		respond func(w http.ResponseWriter)
+	}{
+		{
+			name: "response over size cap",
+			cfg:  func(cfg *config.CoalesceConfig) { cfg.MaxResponseSize = 16 },
+			respond: func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte(strings.Repeat("x", 64)))
+			},
+		},
+		{
+			name: "response sets a cookie",
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Set-Cookie", "_session=abc")
+				_, _ = w.Write([]byte("page"))
+			},
+		},
+		{
+			name: "server error",
+			respond: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testCoalesceConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			responses, _, hits := runCoalesced(t, cfg, 5, tt.respond)
+
+			// Waiters fall back to their own backend request
+			if hits != 5 {
+				t.Errorf("backend saw %d requests, want 5", hits)
+			}
+			for i, rec := range responses {
+				if rec.Code == 0 {
+					t.Errorf("response %d was not written", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCanCoalesce(t *testing.T) {
+	enabled := testCoalesceConfig()
+	withCredentials := testCoalesceConfig()
+	withCredentials.AllowCredentials = true
+
+	tests := []struct {
+		name     string
+		cfg      config.CoalesceConfig
+		method   string
+		headers  map[string]string
+		expected bool
+	}{
+		{"disabled", config.CoalesceConfig{}, "GET", nil, false},
+		{"anonymous GET", enabled, "GET", nil, true},
+		{"HEAD", enabled, "HEAD", nil, true},
+		{"POST", enabled, "POST", nil, false},
+		{"cookie", enabled, "GET", map[string]string{"Cookie": "_session=abc"}, false},
+		{"authorization", enabled, "GET", map[string]string{"Authorization": "Basic dTpw"}, false},
+		{"cookie allowed", withCredentials, "GET", map[string]string{"Cookie": "_session=abc"}, true},
+		{"range", enabled, "GET", map[string]string{"Range": "bytes=0-10"}, false},
+		{"websocket", enabled, "GET", map[string]string{"Upgrade": "websocket", "Connection": "Upgrade"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/showcase/2025/boston/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			if result := canCoalesce(req, tt.cfg); result != tt.expected {
+				t.Errorf("canCoalesce = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	vary := config.DefaultCoalesceVaryHeaders
+	base := httptest.NewRequest("GET", "/showcase/page?x=1", nil)
+
+	same := httptest.NewRequest("GET", "/showcase/page?x=1", nil)
+	if coalesceKey(base, vary) != coalesceKey(same, vary) {
+		t.Error("identical requests should share a key")
+	}
+
+	differentQuery := httptest.NewRequest("GET", "/showcase/page?x=2", nil)
+	differentLanguage := httptest.NewRequest("GET", "/showcase/page?x=1", nil)
+	differentLanguage.Header.Set("Accept-Language", "fr")
+	head := httptest.NewRequest("HEAD", "/showcase/page?x=1", nil)
+	for name, req := range map[string]*http.Request{"query": differentQuery, "vary header": differentLanguage, "method": head} {
+		if coalesceKey(base, vary) == coalesceKey(req, vary) {
+			t.Errorf("requests differing by %s should not share a key", name)
+		}
+	}
+}
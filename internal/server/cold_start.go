@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rubys/navigator/internal/process"
+)
+
+// Headers telling a tenant's app that a request started it, so cold starts
+// can be told apart in the app's own metrics
+const (
+	// HeaderColdStart is "true" on the request that started its tenant
+	HeaderColdStart = "X-Navigator-Cold-Start"
+
+	// HeaderBootMs is the milliseconds from spawning the tenant's process to
+	// its first successful health check
+	HeaderBootMs = "X-Navigator-Boot-Ms"
+)
+
+// markColdStart tells the app, and the access log, whether r is the request
+// that started its tenant. Clients can't set the headers themselves.
+func markColdStart(recorder *ResponseRecorder, r *http.Request, coldStart bool, bootTime time.Duration) {
+	r.Header.Del(HeaderColdStart)
+	r.Header.Del(HeaderBootMs)
+	if !coldStart {
+		return
+	}
+
+	bootMs := bootTime.Milliseconds()
+	r.Header.Set(HeaderColdStart, "true")
+	r.Header.Set(HeaderBootMs, strconv.FormatInt(bootMs, 10))
+	recorder.SetMetadata("cold_start", true)
+	recorder.SetMetadata("boot_ms", bootMs)
+}
+
+// markStartupQueued records in the access log how long the app's start
+// waited for a startup slot, if it waited at all
+func markStartupQueued(recorder *ResponseRecorder, app *process.WebApp) {
+	if waited, _ := app.StartupQueued(); waited > 0 {
+		recorder.SetMetadata("startup_queued_ms", waited.Milliseconds())
+	}
+}
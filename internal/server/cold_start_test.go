@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestColdStartHeaderOnStartingRequestOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	buf := captureAccessLog(t)
+	handler, _ := newCrashTestHandler(t, false, false)
+	handler.(*Handler).disableLog = false
+
+	coldStarts := 0
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/studios/boston/heats", nil)
+		if i == 2 {
+			// Clients can't claim a cold start
+			req.Header.Set(HeaderColdStart, "true")
+			req.Header.Set(HeaderBootMs, "5")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: status %d", i, rec.Code)
+		}
+		coldStart, bootMs := rec.Header().Get("X-Echo-Cold-Start"), rec.Header().Get("X-Echo-Boot-Ms")
+		switch {
+		case coldStart == "true":
+			coldStarts++
+			if i != 0 {
+				t.Errorf("Request %d to the warm app was marked as a cold start", i)
+			}
+			if ms, err := strconv.Atoi(bootMs); err != nil || ms < 0 {
+				t.Errorf("%s = %q, want milliseconds", HeaderBootMs, bootMs)
+			}
+		case coldStart != "" || bootMs != "":
+			t.Errorf("Request %d: %s = %q, %s = %q; want neither", i, HeaderColdStart, coldStart, HeaderBootMs, bootMs)
+		}
+	}
+	if coldStarts != 1 {
+		t.Errorf("Cold start header seen %d times, want exactly once", coldStarts)
+	}
+
+	entries := parseAccessLog(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Logged %d entries, want 3", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ColdStart != (i == 0) {
+			t.Errorf("Entry %d: cold_start = %v", i, entry.ColdStart)
+		}
+		if i > 0 && entry.BootMs != 0 {
+			t.Errorf("Entry %d: boot_ms = %d, want none", i, entry.BootMs)
+		}
+	}
+}
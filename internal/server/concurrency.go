@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// acquireTenantSlot waits for one of the app's max_concurrent_requests slots.
+// The slot is released once the request, or the connection it was upgraded
+// to, is done. Returns false if a response was written instead.
+func (h *Handler) acquireTenantSlot(recorder *ResponseRecorder, r *http.Request, app *process.WebApp, tenantName string) bool {
+	var limit config.ConcurrencyConfig
+	if tenant := h.config.TenantByName(tenantName); tenant != nil {
+		limit = tenant.Concurrency
+	}
+	if limit.MaxConcurrentRequests == 0 {
+		return true
+	}
+	if proxy.IsWebSocketRequest(r) && (limit.CountWebSockets == nil || !*limit.CountWebSockets) {
+		return true
+	}
+
+	start := time.Now()
+	release, depth, err := app.Requests().Acquire(r.Context(), limit)
+	if depth > 0 {
+		recorder.SetMetadata("queue_depth", depth)
+		recorder.SetMetadata("queue_time", time.Since(start))
+	}
+
+	switch {
+	case err == nil:
+		recorder.releaseWhenDone(release)
+		return true
+
+	case errors.Is(err, process.ErrRequestQueueFull), errors.Is(err, process.ErrRequestQueueTimeout):
+		logging.LogRequestQueueRejected(tenantName, r.URL.Path, depth, err)
+		recorder.SetMetadata("tenant", tenantName)
+		recorder.SetMetadata("response_type", "overloaded")
+		recorder.SetMetadata("error_message", err.Error())
+		recorder.Header().Set("Retry-After", strconv.Itoa(config.RequestQueueRetryAfter))
+		http.Error(recorder, "Service Unavailable", http.StatusServiceUnavailable)
+
+	default:
+		// Client closed connection while queued (similar to nginx 499)
+		recorder.SetMetadata("tenant", tenantName)
+		recorder.SetMetadata("response_type", "client_closed")
+		recorder.WriteHeader(499)
+	}
+	return false
+}
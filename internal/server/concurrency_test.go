@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// newLimitedHandler returns a handler whose one tenant, 2025/boston, allows a
+// single request at a time
+func newLimitedHandler(queueSize int, queueTimeout time.Duration) *Handler {
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name: "2025/boston",
+		Path: "/showcase/2025/boston/",
+		Concurrency: config.ConcurrencyConfig{
+			MaxConcurrentRequests: 1,
+			QueueSize:             queueSize,
+			QueueTimeout:          config.Duration(queueTimeout),
+		},
+	}}
+	return &Handler{config: cfg}
+}
+
+// slowBackend holds every request until gate is closed, recording the order
+// requests arrived in and the most that were in flight at once
+type slowBackend struct {
+	*httptest.Server
+	gate        chan struct{}
+	mu          sync.Mutex
+	order       []string
+	inFlight    int32
+	maxInFlight int32
+}
+
+func newSlowBackend(t *testing.T) *slowBackend {
+	b := &slowBackend{gate: make(chan struct{})}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&b.inFlight, 1)
+		defer atomic.AddInt32(&b.inFlight, -1)
+		b.mu.Lock()
+		b.order = append(b.order, r.URL.Query().Get("seq"))
+		if n > b.maxInFlight {
+			b.maxInFlight = n
+		}
+		b.mu.Unlock()
+		select {
+		case <-b.gate:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// serveLimited runs the tail of handleWebAppProxy: wait for a slot, then proxy
+func serveLimited(h *Handler, app *process.WebApp, req *http.Request, backendURL string) (*httptest.ResponseRecorder, *ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	recorder := NewTestResponseRecorder(rec, nil, req)
+	defer recorder.Finish(req)
+	if h.acquireTenantSlot(recorder, req, app, "2025/boston") {
+		proxy.ProxyWithWebSocketSupport(recorder, req, backendURL, nil)
+	}
+	return rec, recorder
+}
+
+// waitForRequests waits until the app has the given active and queued requests
+func waitForRequests(t *testing.T, app *process.WebApp, active, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if a, q, _ := app.Requests().Stats(); a == active && q == queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	a, q, _ := app.Requests().Stats()
+	t.Fatalf("active, queued = %d, %d; want %d, %d", a, q, active, queued)
+}
+
+func TestTenantConcurrencyQueuesInOrder(t *testing.T) {
+	h := newLimitedHandler(10, 5*time.Second)
+	backend := newSlowBackend(t)
+	app := &process.WebApp{}
+
+	const n = 5
+	recorders := make([]*ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/showcase/2025/boston/?seq="+strconv.Itoa(i), nil)
+			_, recorders[i] = serveLimited(h, app, req, backend.URL)
+		}(i)
+		// Each request is queued before the next one arrives
+		waitForRequests(t, app, 1, i)
+	}
+	close(backend.gate)
+	wg.Wait()
+
+	if got := backend.order; len(got) != n || got[0] != "0" || got[1] != "1" || got[2] != "2" || got[3] != "3" || got[4] != "4" {
+		t.Errorf("backend order = %v, want arrival order", got)
+	}
+	if backend.maxInFlight != 1 {
+		t.Errorf("backend saw %d concurrent requests, want 1", backend.maxInFlight)
+	}
+	for i := 1; i < n; i++ {
+		if depth := recorders[i].metadata["queue_depth"]; depth != i {
+			t.Errorf("request %d queue_depth = %v, want %d", i, depth, i)
+		}
+		if _, ok := recorders[i].metadata["queue_time"].(time.Duration); !ok {
+			t.Errorf("request %d missing queue_time", i)
+		}
+	}
+	waitForRequests(t, app, 0, 0)
+}
+
+func TestTenantConcurrencyRejectsOverflow(t *testing.T) {
+	h := newLimitedHandler(1, 5*time.Second)
+	backend := newSlowBackend(t)
+	app := &process.WebApp{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveLimited(h, app, httptest.NewRequest("GET", "/showcase/2025/boston/", nil), backend.URL)
+		}()
+		waitForRequests(t, app, 1, i)
+	}
+
+	rec, recorder := serveLimited(h, app, httptest.NewRequest("GET", "/showcase/2025/boston/", nil), backend.URL)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if rec.Header().Get("Retry-After") != strconv.Itoa(config.RequestQueueRetryAfter) {
+		t.Errorf("Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+	if recorder.metadata["response_type"] != "overloaded" {
+		t.Errorf("response_type = %v, want overloaded", recorder.metadata["response_type"])
+	}
+
+	close(backend.gate)
+	wg.Wait()
+
+	// A queued request that waits longer than queue_timeout is rejected too
+	h = newLimitedHandler(1, 20*time.Millisecond)
+	backend = newSlowBackend(t)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serveLimited(h, app, httptest.NewRequest("GET", "/showcase/2025/boston/", nil), backend.URL)
+	}()
+	waitForRequests(t, app, 1, 0)
+	if rec, _ := serveLimited(h, app, httptest.NewRequest("GET", "/showcase/2025/boston/", nil), backend.URL); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("timed out status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	close(backend.gate)
+	wg.Wait()
+}
+
+func TestTenantConcurrencyCanceledClientReleasesSlot(t *testing.T) {
+	h := newLimitedHandler(10, 5*time.Second)
+	backend := newSlowBackend(t)
+	app := &process.WebApp{}
+
+	// The first request holds the slot; the second waits for it
+	activeCtx, cancelActive := context.WithCancel(context.Background())
+	queuedCtx, cancelQueued := context.WithCancel(context.Background())
+	done := make(chan *httptest.ResponseRecorder, 2)
+	for i, ctx := range []context.Context{activeCtx, queuedCtx} {
+		req := httptest.NewRequest("GET", "/showcase/2025/boston/", nil).WithContext(ctx)
+		go func() {
+			rec, _ := serveLimited(h, app, req, backend.URL)
+			done <- rec
+		}()
+		waitForRequests(t, app, 1, i)
+	}
+
+	// A client leaving the queue gives up its place
+	cancelQueued()
+	if rec := <-done; rec.Code != 499 {
+		t.Errorf("canceled queued request status = %d, want 499", rec.Code)
+	}
+	waitForRequests(t, app, 1, 0)
+
+	// A client disconnecting mid-request frees its slot
+	cancelActive()
+	<-done
+	waitForRequests(t, app, 0, 0)
+
+	close(backend.gate)
+	if rec, _ := serveLimited(h, app, httptest.NewRequest("GET", "/showcase/2025/boston/", nil), backend.URL); rec.Code != http.StatusOK {
+		t.Errorf("status after release = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestTenantConcurrencyHijackHoldsSlotUntilClose(t *testing.T) {
+	h := newLimitedHandler(10, 5*time.Second)
+	countWebSockets := true
+	h.config.Applications.Tenants[0].Concurrency.CountWebSockets = &countWebSockets
+	app := &process.WebApp{}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	req := httptest.NewRequest("GET", "/showcase/2025/boston/cable", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	recorder := NewTestResponseRecorder(&mockHijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}, nil, req)
+
+	if !h.acquireTenantSlot(recorder, req, app, "2025/boston") {
+		t.Fatal("upgrade did not get a slot")
+	}
+	conn, _, err := recorder.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack failed: %v", err)
+	}
+
+	// The handler returning does not free the slot while the connection is open
+	recorder.Finish(req)
+	waitForRequests(t, app, 1, 0)
+
+	_ = conn.Close()
+	waitForRequests(t, app, 0, 0)
+}
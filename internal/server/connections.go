@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConnectionTracker follows the state of every client connection so shutdown
+// can report how many requests were drained and how many were aborted. Install
+// ConnState as the http.Server's ConnState hook.
+type ConnectionTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+}
+
+// ConnState records a connection state change
+func (t *ConnectionTracker) ConnState(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		// Hijacked connections (WebSockets) are no longer the server's to drain
+		delete(t.conns, conn)
+	default:
+		if t.conns == nil {
+			t.conns = make(map[net.Conn]http.ConnState)
+		}
+		t.conns[conn] = state
+	}
+}
+
+// Busy returns the number of connections with a request in progress
+func (t *ConnectionTracker) Busy() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	busy := 0
+	for _, state := range t.conns {
+		if state == http.StateNew || state == http.StateActive {
+			busy++
+		}
+	}
+	return busy
+}
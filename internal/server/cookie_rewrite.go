@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// rewritesCookies reports whether a route changes the cookies its backend sets
+func rewritesCookies(route *config.ProxyRoute) bool {
+	return len(route.CookiePathRewrite) > 0 || len(route.CookieDomainRewrite) > 0 ||
+		route.CookieSecure || route.CookieSameSite != ""
+}
+
+// rewriteSetCookies rewrites each Set-Cookie header of a backend response
+// independently, according to the route's cookie settings
+func rewriteSetCookies(header http.Header, route *config.ProxyRoute) {
+	cookies := header["Set-Cookie"]
+	for i, cookie := range cookies {
+		cookies[i] = rewriteSetCookie(cookie, route)
+	}
+}
+
+// rewriteSetCookie rewrites the Path and Domain attributes of one Set-Cookie
+// value and adds the forced Secure and SameSite attributes. Attribute names
+// match case-insensitively; attributes that aren't rewritten keep their
+// original order and spelling. Malformed cookies are returned unchanged.
+func rewriteSetCookie(value string, route *config.ProxyRoute) string {
+	if _, err := http.ParseSetCookie(value); err != nil {
+		return value
+	}
+
+	parts := strings.Split(value, ";")
+	kept := parts[:1]
+	hasSecure, hasSameSite := false, false
+	for _, part := range parts[1:] {
+		name, attr, _ := strings.Cut(part, "=")
+		attr = strings.TrimSpace(attr)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "path":
+			if rewritten, ok := rewriteCookiePath(attr, route.CookiePathRewrite); ok {
+				part = " Path=" + rewritten
+			}
+		case "domain":
+			if rewritten, ok := rewriteCookieDomain(attr, route.CookieDomainRewrite); ok {
+				if rewritten == "" {
+					continue // Remove Domain so the cookie belongs to the public host
+				}
+				part = " Domain=" + rewritten
+			}
+		case "secure":
+			hasSecure = true
+		case "samesite":
+			hasSameSite = true
+			if route.CookieSameSite != "" {
+				part = " SameSite=" + route.CookieSameSite
+			}
+		}
+		kept = append(kept, part)
+	}
+
+	if route.CookieSameSite != "" && !hasSameSite {
+		kept = append(kept, " SameSite="+route.CookieSameSite)
+	}
+	// Browsers reject SameSite=None without Secure
+	if (route.CookieSecure || route.CookieSameSite == "None") && !hasSecure {
+		kept = append(kept, " Secure")
+	}
+	return strings.Join(kept, ";")
+}
+
+// rewriteCookiePath maps a cookie path onto the public prefix of the longest
+// matching backend prefix
+func rewriteCookiePath(path string, rewrites map[string]string) (string, bool) {
+	match := ""
+	for from := range rewrites {
+		if len(from) > len(match) && cookiePathHasPrefix(path, from) {
+			match = from
+		}
+	}
+	if match == "" {
+		return "", false
+	}
+	rest := strings.TrimPrefix(path, match)
+	if rest == "" {
+		return rewrites[match], true
+	}
+	return singleJoiningSlash(rewrites[match], rest), true
+}
+
+// cookiePathHasPrefix reports whether prefix covers path on a segment boundary
+func cookiePathHasPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// rewriteCookieDomain maps a cookie domain, ignoring case and any leading
+// dot; "*" matches every domain
+func rewriteCookieDomain(domain string, rewrites map[string]string) (string, bool) {
+	bare := strings.TrimPrefix(domain, ".")
+	for from, to := range rewrites {
+		if strings.EqualFold(strings.TrimPrefix(from, "."), bare) {
+			return to, true
+		}
+	}
+	if to, ok := rewrites["*"]; ok {
+		return to, true
+	}
+	return "", false
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func TestRewriteSetCookie(t *testing.T) {
+	route := &config.ProxyRoute{
+		CookiePathRewrite:   map[string]string{"/": "/web/", "/admin": "/web/admin-panel"},
+		CookieDomainRewrite: map[string]string{"internal.local": "example.com", "legacy.local": ""},
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+		want   string
+	}{
+		{"root path", "session=abc; Path=/; HttpOnly", "session=abc; Path=/web/; HttpOnly"},
+		{"nested path", "prefs=1; Path=/settings", "prefs=1; Path=/web/settings"},
+		{"longest prefix wins", "a=1; Path=/admin/users", "a=1; Path=/web/admin-panel/users"},
+		{"prefix only on segment boundary", "a=1; Path=/administrator", "a=1; Path=/web/administrator"},
+		{"lowercase attributes", "id=7; path=/; domain=internal.local", "id=7; Path=/web/; Domain=example.com"},
+		{"attributes before path", "id=7; Secure; Max-Age=60; PATH=/; SameSite=Lax", "id=7; Secure; Max-Age=60; Path=/web/; SameSite=Lax"},
+		{"leading dot domain", "id=7; Domain=.INTERNAL.local; Path=/", "id=7; Domain=example.com; Path=/web/"},
+		{"domain removed", "id=7; Domain=legacy.local; Path=/x", "id=7; Path=/web/x"},
+		{"other domains untouched", "id=7; Domain=partner.com", "id=7; Domain=partner.com"},
+		{"no path attribute", "id=7; HttpOnly", "id=7; HttpOnly"},
+		{"malformed without equals", "garbage; Path=/", "garbage; Path=/"},
+		{"malformed empty name", "=value; Path=/", "=value; Path=/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteSetCookie(tt.cookie, route); got != tt.want {
+				t.Errorf("rewriteSetCookie(%q) = %q, want %q", tt.cookie, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteSetCookieForcedAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  config.ProxyRoute
+		cookie string
+		want   string
+	}{
+		{"secure added", config.ProxyRoute{CookieSecure: true}, "a=1; Path=/", "a=1; Path=/; Secure"},
+		{"secure not repeated", config.ProxyRoute{CookieSecure: true}, "a=1; secure; Path=/", "a=1; secure; Path=/"},
+		{"samesite added", config.ProxyRoute{CookieSameSite: "Strict"}, "a=1", "a=1; SameSite=Strict"},
+		{"samesite replaced", config.ProxyRoute{CookieSameSite: "Lax"}, "a=1; samesite=none; Secure", "a=1; SameSite=Lax; Secure"},
+		{"samesite none implies secure", config.ProxyRoute{CookieSameSite: "None"}, "a=1; HttpOnly", "a=1; HttpOnly; SameSite=None; Secure"},
+		{"wildcard domain stripped", config.ProxyRoute{CookieDomainRewrite: map[string]string{"*": ""}}, "a=1; Domain=anything.test; HttpOnly", "a=1; HttpOnly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteSetCookie(tt.cookie, &tt.route); got != tt.want {
+				t.Errorf("rewriteSetCookie(%q) = %q, want %q", tt.cookie, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverseProxyRewritesEachSetCookie(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "session=abc; Path=/; Domain=backend.internal; HttpOnly")
+		w.Header().Add("Set-Cookie", "theme=dark; path=/prefs")
+		w.Header().Add("Set-Cookie", "broken")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{}
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{{
+		Name:                "web",
+		Prefix:              "/web/",
+		Target:              backend.URL,
+		StripPath:           true,
+		CookiePathRewrite:   map[string]string{"/": "/web/"},
+		CookieDomainRewrite: map[string]string{"backend.internal": ""},
+		CookieSecure:        true,
+	}}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/web/login", nil))
+
+	want := []string{
+		"session=abc; Path=/web/; HttpOnly; Secure",
+		"theme=dark; Path=/web/prefs; Secure",
+		"broken",
+	}
+	got := rec.Header().Values("Set-Cookie")
+	if len(got) != len(want) {
+		t.Fatalf("Set-Cookie = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Set-Cookie[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// TestEchoTenantProcess isn't a real test: run as a tenant with
+// NAVIGATOR_ECHO_TENANT=1, it serves its PID on $PORT, echoing the cold start
+// headers it received as X-Echo-Cold-Start and X-Echo-Boot-Ms.
+func TestEchoTenantProcess(t *testing.T) {
+	if os.Getenv("NAVIGATOR_ECHO_TENANT") != "1" {
+		return
+	}
+	listener, err := net.Listen("tcp", "localhost:"+os.Getenv("PORT"))
+	if err != nil {
+		os.Exit(1)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Echo-Cold-Start", r.Header.Get(HeaderColdStart))
+			w.Header().Set("X-Echo-Boot-Ms", r.Header.Get(HeaderBootMs))
+			fmt.Fprintf(w, "pid %d", os.Getpid())
+		}),
+	}
+	_ = server.Serve(listener)
+	select {}
+}
+
+// newCrashTestHandler serves the boston tenant from the echo tenant process,
+// optionally with a warm standby
+func newCrashTestHandler(t *testing.T, restartOnCrash, standby bool) (http.Handler, *process.AppManager) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = 4600
+	cfg.Applications.RestartOnCrash = restartOnCrash
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name:    "boston",
+		Path:    "/studios/boston/",
+		Root:    t.TempDir(),
+		Runtime: os.Args[0],
+		Server:  "-test.run=^TestEchoTenantProcess$",
+		Args:    []string{"-test.count=1"},
+		Env:     map[string]string{"NAVIGATOR_ECHO_TENANT": "1"},
+		Standby: standby,
+	}}
+	appManager := process.NewAppManager(cfg)
+	t.Cleanup(appManager.Cleanup)
+	return CreateTestHandler(cfg, appManager, nil, &idle.Manager{}), appManager
+}
+
+// getTenantPID requests path and returns the responding process's PID
+func getTenantPID(t *testing.T, handler http.Handler, path string) (int, int) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	body, _ := io.ReadAll(rec.Body)
+	var pid int
+	_, _ = fmt.Sscanf(string(body), "pid %d", &pid)
+	return rec.Code, pid
+}
+
+// killTenant kills the boston tenant's process and waits for it to exit
+func killTenant(t *testing.T, appManager *process.AppManager) *process.WebApp {
+	t.Helper()
+	app, ok := appManager.GetApp("boston")
+	if !ok {
+		t.Fatal("boston isn't running")
+	}
+	if err := app.Process.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	if !app.WaitExited(10 * time.Second) {
+		t.Fatal("Killed process didn't exit")
+	}
+	return app
+}
+
+// serveRefused answers a request the way the handler does when app's port
+// refuses the connection, and returns the status and responding PID
+func serveRefused(handler http.Handler, app *process.WebApp) (int, int) {
+	req := httptest.NewRequest("GET", "/studios/boston/heats", nil)
+	rec := httptest.NewRecorder()
+	recorder := NewResponseRecorder(rec, nil, req)
+	refused := fmt.Errorf("dial tcp 127.0.0.1:%d: connect: connection refused", app.Port)
+	handler.(*Handler).retryCrashedApp(recorder, req, recorder, "boston", app, refused)
+
+	var pid int
+	_, _ = fmt.Sscanf(rec.Body.String(), "pid %d", &pid)
+	return rec.Code, pid
+}
+
+func TestCrashedTenantRestartedInline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	handler, appManager := newCrashTestHandler(t, true, false)
+
+	status, firstPID := getTenantPID(t, handler, "/studios/boston/")
+	if status != http.StatusOK || firstPID == 0 {
+		t.Fatalf("First request: status %d, pid %d", status, firstPID)
+	}
+	app := killTenant(t, appManager)
+
+	// The refused request is retried against a new instance
+	status, secondPID := serveRefused(handler, app)
+	if status != http.StatusOK {
+		t.Fatalf("Request after crash: status %d, want 200 from the restarted tenant", status)
+	}
+	if secondPID == 0 || secondPID == firstPID {
+		t.Errorf("Request was answered by pid %d, want a new process (crashed pid %d)", secondPID, firstPID)
+	}
+}
+
+func TestKilledTenantRestarted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	handler, appManager := newCrashTestHandler(t, true, false)
+
+	status, firstPID := getTenantPID(t, handler, "/studios/boston/")
+	if status != http.StatusOK {
+		t.Fatalf("First request: status %d", status)
+	}
+	app, _ := appManager.GetApp("boston")
+	if err := app.Process.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Whether the exit is noticed first or the dial is refused, the next
+	// request reaches a new instance
+	status, secondPID := getTenantPID(t, handler, "/studios/boston/")
+	if status != http.StatusOK || secondPID == firstPID {
+		t.Errorf("Request after kill: status %d, pid %d (crashed pid %d)", status, secondPID, firstPID)
+	}
+
+	// The exit is noticed without any request
+	deadline := time.Now().Add(10 * time.Second)
+	for !app.Exited() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if current, ok := appManager.GetApp("boston"); ok && current == app {
+		t.Error("Crashed app is still registered")
+	}
+}
+
+func TestCrashedTenantWithoutRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	handler, appManager := newCrashTestHandler(t, false, false)
+
+	if status, _ := getTenantPID(t, handler, "/studios/boston/"); status != http.StatusOK {
+		t.Fatalf("First request: status %d", status)
+	}
+	app := killTenant(t, appManager)
+
+	if status, _ := serveRefused(handler, app); status != http.StatusBadGateway {
+		t.Errorf("Request after crash: status %d, want 502", status)
+	}
+	// The crashed app was removed, so the next request starts a new one
+	if status, _ := getTenantPID(t, handler, "/studios/boston/heats"); status != http.StatusOK {
+		t.Errorf("Following request: status %d, want 200", status)
+	}
+}
+
+func TestIsIdempotentRequest(t *testing.T) {
+	tests := []struct {
+		method, body string
+		want         bool
+	}{
+		{"GET", "", true},
+		{"HEAD", "", true},
+		{"DELETE", "", true},
+		{"PUT", "data", false},
+		{"POST", "", false},
+	}
+	for _, tt := range tests {
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		if got := isIdempotentRequest(httptest.NewRequest(tt.method, "/", body)); got != tt.want {
+			t.Errorf("isIdempotentRequest(%s with %q) = %v, want %v", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestStandbyTakesOverCrashedTenant(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts tenant processes")
+	}
+	handler, appManager := newCrashTestHandler(t, false, true)
+
+	status, firstPID := getTenantPID(t, handler, "/studios/boston/")
+	if status != http.StatusOK {
+		t.Fatalf("First request: status %d", status)
+	}
+	standbyPort := waitForStandby(t, appManager, 0)
+
+	// Requests keep flowing while the primary is killed
+	var failed, answered int
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if status, _ := getTenantPID(t, handler, "/studios/boston/heats"); status != http.StatusOK {
+				failed++
+			}
+			answered++
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	app, _ := appManager.GetApp("boston")
+	if err := app.Process.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(stop)
+	<-done
+	if failed > 0 {
+		t.Errorf("%d of %d requests failed during failover", failed, answered)
+	}
+
+	status, secondPID := getTenantPID(t, handler, "/studios/boston/")
+	if status != http.StatusOK || secondPID == firstPID {
+		t.Errorf("Request after kill: status %d, pid %d (crashed pid %d)", status, secondPID, firstPID)
+	}
+	current, _ := appManager.GetApp("boston")
+	if current.Port != standbyPort {
+		t.Errorf("Serving port %d, want the standby's port %d", current.Port, standbyPort)
+	}
+
+	// A replacement standby is started in the background
+	waitForStandby(t, appManager, standbyPort)
+	if failovers := appManager.Status()[0].Failovers; failovers != 1 {
+		t.Errorf("Failovers = %d, want 1", failovers)
+	}
+}
+
+// waitForStandby waits for boston's standby to be ready on a port other than
+// previous, and returns its port
+func waitForStandby(t *testing.T, appManager *process.AppManager, previous int) int {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := appManager.Status(); len(status) == 1 && status[0].StandbyPort != 0 && status[0].StandbyPort != previous {
+			return status[0].StandbyPort
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Standby did not become ready")
+	return 0
+}
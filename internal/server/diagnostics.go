@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/rubys/navigator/internal/diagnostics"
+)
+
+// diagnosticsProvider collects the bundle served at server.diagnostics.path
+var diagnosticsProvider struct {
+	mu      sync.RWMutex
+	collect func() *diagnostics.Bundle
+}
+
+// SetDiagnosticsProvider configures how the diagnostics endpoint collects its
+// bundle; collect may return nil when no bundle can be produced
+func SetDiagnosticsProvider(collect func() *diagnostics.Bundle) {
+	diagnosticsProvider.mu.Lock()
+	defer diagnosticsProvider.mu.Unlock()
+	diagnosticsProvider.collect = collect
+}
+
+// handleDiagnostics returns the diagnostic bundle as JSON
+func handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	diagnosticsProvider.mu.RLock()
+	collect := diagnosticsProvider.collect
+	diagnosticsProvider.mu.RUnlock()
+
+	var bundle *diagnostics.Bundle
+	if collect != nil {
+		bundle = collect()
+	}
+	if bundle == nil {
+		http.Error(w, "Diagnostics not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(bundle)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// parseAcceptEncoding parses an Accept-Encoding header into a map of
+// content-coding to q-value. Elements with an invalid q-value are ignored.
+func parseAcceptEncoding(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, element := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(element, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		if coding == "x-gzip" {
+			coding = config.EncodingGzip
+		}
+
+		q := 1.0
+		valid := true
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+
+		// First occurrence of a coding wins
+		if _, exists := accepted[coding]; valid && !exists {
+			accepted[coding] = q
+		}
+	}
+	return accepted
+}
+
+// negotiateEncoding selects the best encoding from available (in server
+// preference order) for the given Accept-Encoding header. Returns "" when
+// the response should be sent without a content-coding (identity).
+func negotiateEncoding(header string, available []string) string {
+	if strings.TrimSpace(header) == "" {
+		return ""
+	}
+
+	accepted := parseAcceptEncoding(header)
+	best := ""
+	bestQ := 0.0
+	for _, encoding := range available {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		// Strictly greater keeps server preference order on ties
+		if ok && q > bestQ {
+			best = encoding
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// availablePrecompressed returns the configured encodings that have a sidecar
+// file next to name, in preference order
+func (s *StaticFileHandler) availablePrecompressed(name string) []string {
+	var available []string
+	for _, encoding := range s.config.Server.Static.Precompressed.Encodings {
+		ext, known := config.PrecompressedExtensions[encoding]
+		if !known {
+			continue
+		}
+		if info, err := s.source.Stat(name + ext); err == nil && !info.IsDir() {
+			available = append(available, encoding)
+		}
+	}
+	return available
+}
+
+// serveNegotiated serves name from the static source, substituting a
+// precompressed sidecar when enabled and acceptable to the client
+func (s *StaticFileHandler) serveNegotiated(w http.ResponseWriter, r *http.Request, name string, fingerprinted bool) {
+	if !s.config.Server.Static.Precompressed.Enabled {
+		s.serveSourceFile(w, r, name, fingerprinted)
+		return
+	}
+
+	available := s.availablePrecompressed(name)
+	if len(available) == 0 {
+		s.serveSourceFile(w, r, name, fingerprinted)
+		return
+	}
+
+	// The representation depends on Accept-Encoding whenever sidecars exist
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	serveName := name
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), available)
+	if encoding != "" {
+		serveName = name + config.PrecompressedExtensions[encoding]
+		w.Header().Set("Content-Encoding", encoding)
+	}
+
+	// ETag varies by selected encoding so conditional requests match the right representation
+	if !fingerprinted {
+		if info, err := s.source.Stat(serveName); err == nil {
+			w.Header().Set("ETag", staticETag(info, encoding))
+		}
+	}
+
+	s.serveSourceFile(w, r, serveName, fingerprinted)
+}
+
+// serveSourceFile serves name from the static source. A fingerprinted file
+// never changes under its name, so it's served without Last-Modified or
+// conditional request handling; its long max-age makes them unnecessary.
+func (s *StaticFileHandler) serveSourceFile(w http.ResponseWriter, r *http.Request, name string, fingerprinted bool) {
+	if fingerprinted {
+		if file, err := s.source.Open(name); err == nil {
+			defer file.Close()
+			if content, ok := file.(io.ReadSeeker); ok {
+				http.ServeContent(w, r, name, time.Time{}, content)
+				return
+			}
+		}
+	}
+	http.ServeFileFS(w, r, s.source, name)
+}
+
+// staticETag builds a strong ETag from file metadata and content-coding
+func staticETag(info fs.FileInfo, encoding string) string {
+	if encoding == "" {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+	}
+	return fmt.Sprintf(`"%x-%x-%s"`, info.ModTime().UnixNano(), info.Size(), encoding)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	all := []string{"br", "zstd", "gzip"}
+
+	tests := []struct {
+		name      string
+		header    string
+		available []string
+		expected  string
+	}{
+		{"empty header", "", all, ""},
+		{"browser default", "gzip, deflate, br, zstd", all, "br"},
+		{"server order breaks ties", "gzip, zstd", all, "zstd"},
+		{"q-values override server order", "br;q=0.5, gzip;q=0.9", all, "gzip"},
+		{"only gzip sidecar", "gzip, deflate, br, zstd", []string{"gzip"}, "gzip"},
+		{"forbidden encoding", "br;q=0, gzip", all, "gzip"},
+		{"wildcard", "*", all, "br"},
+		{"wildcard forbidden", "*;q=0", all, ""},
+		{"wildcard forbidden with explicit allow", "*;q=0, gzip;q=0.1", all, "gzip"},
+		{"explicit beats wildcard", "br;q=0, *;q=0.5", all, "zstd"},
+		{"identity only", "identity", all, ""},
+		{"x-gzip alias", "x-gzip", all, "gzip"},
+		{"case and whitespace", "  BR ; Q=0.8 ,GZIP;q=0.7", all, "br"},
+		{"invalid q-value ignored", "br;q=2, gzip;q=abc, zstd;q=0.1", all, "zstd"},
+		{"no acceptable sidecar", "deflate", all, ""},
+		{"no sidecars", "br, gzip", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := negotiateEncoding(tt.header, tt.available); result != tt.expected {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestServePrecompressedSidecars(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"app.js":        "console.log('identity')",
+		"app.js.br":     "brotli-bytes",
+		"app.js.zst":    "zstd-bytes",
+		"app.js.gz":     "gzip-bytes",
+		"plain.css":     "body{}",
+		"gz-only.js":    "identity",
+		"gz-only.js.gz": "gzip-only",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = tempDir
+	cfg.Server.Static.Precompressed = config.PrecompressedConfig{
+		Enabled:   true,
+		Encodings: config.DefaultPrecompressedEncodings,
+	}
+	handler := NewStaticFileHandler(cfg)
+
+	serve := func(path, acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		if !handler.ServeStatic(rec, req) {
+			t.Fatalf("expected %s to be served", path)
+		}
+		return rec
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		body           string
+		encoding       string
+		vary           bool
+	}{
+		{"brotli preferred", "/app.js", "gzip, deflate, br, zstd", "brotli-bytes", "br", true},
+		{"zstd when br forbidden", "/app.js", "br;q=0, zstd, gzip", "zstd-bytes", "zstd", true},
+		{"identity when all forbidden", "/app.js", "*;q=0", "console.log('identity')", "", true},
+		{"identity without header", "/app.js", "", "console.log('identity')", "", true},
+		{"only available sidecar", "/gz-only.js", "br, zstd, gzip", "gzip-only", "gzip", true},
+		{"no sidecars", "/plain.css", "br, gzip", "body{}", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.path, tt.acceptEncoding, "")
+			if rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.vary {
+				t.Errorf("Vary = %q, want Accept-Encoding: %v", rec.Header().Get("Vary"), tt.vary)
+			}
+			if ct := rec.Header().Get("Content-Type"); tt.path == "/app.js" && !strings.Contains(ct, "javascript") {
+				t.Errorf("Content-Type = %q, want original file type", ct)
+			}
+		})
+	}
+
+	t.Run("ETag varies by encoding", func(t *testing.T) {
+		brETag := serve("/app.js", "br", "").Header().Get("ETag")
+		identityETag := serve("/app.js", "identity", "").Header().Get("ETag")
+		if brETag == "" || identityETag == "" || brETag == identityETag {
+			t.Fatalf("expected distinct ETags, got br=%q identity=%q", brETag, identityETag)
+		}
+
+		if rec := serve("/app.js", "br", brETag); rec.Code != http.StatusNotModified {
+			t.Errorf("matching ETag returned %d, want 304", rec.Code)
+		}
+		if rec := serve("/app.js", "gzip", brETag); rec.Code != http.StatusOK {
+			t.Errorf("brotli ETag for gzip response returned %d, want 200", rec.Code)
+		}
+	})
+
+	t.Run("disabled ignores sidecars", func(t *testing.T) {
+		cfg.Server.Static.Precompressed.Enabled = false
+		defer func() { cfg.Server.Static.Precompressed.Enabled = true }()
+
+		rec := serve("/app.js", "br", "")
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "console.log('identity')" {
+			t.Errorf("expected identity response when disabled, got encoding %q", rec.Header().Get("Content-Encoding"))
+		}
+	})
+}
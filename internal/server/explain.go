@@ -0,0 +1,306 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// RouteTrace describes how a request would be routed, stage by stage, in the
+// order of the request pipeline
+type RouteTrace struct {
+	Method         string      `json:"method"`
+	Path           string      `json:"path"`
+	NormalizedPath string      `json:"normalized_path"`
+	Steps          []TraceStep `json:"steps"`
+	Disposition    string      `json:"disposition"`
+	Target         string      `json:"target,omitempty"`
+	Status         int         `json:"status,omitempty"`
+
+	current int // Index of the step of the stage being traced
+}
+
+// TraceStep is one pipeline stage and the rule that decided it
+type TraceStep struct {
+	Stage   string        `json:"stage"`
+	Matched bool          `json:"matched"`
+	Rule    string        `json:"rule,omitempty"`
+	Detail  string        `json:"detail,omitempty"`
+	From    string        `json:"from,omitempty"`
+	To      string        `json:"to,omitempty"`
+	Files   []FileAttempt `json:"files,omitempty"`
+}
+
+// FileAttempt is a file checked while looking for a static response
+type FileAttempt struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	IsDir  bool   `json:"is_dir,omitempty"`
+	name   string // Name within the static source
+	err    error
+}
+
+// Explain traces how cfg would route r without serving it. Nothing is
+// started, proxied, executed, or written; requests needing credentials are
+// traced as if the credentials were valid.
+func Explain(cfg *config.Config, basicAuth *auth.BasicAuth, r *http.Request) *RouteTrace {
+	h := &Handler{
+		config:        cfg,
+		auth:          basicAuth,
+		staticHandler: NewStaticFileHandler(cfg),
+	}
+	h.setupCGIHandlers(nil, nil, nil)
+	return h.Explain(r)
+}
+
+// Explain runs r through the handler's own pipeline in trace mode: each
+// stage records a step, stages that would start, proxy, execute, or change
+// something stop with the disposition instead of acting, and the rest answer
+// into a discarded response the disposition is read from.
+func (h *Handler) Explain(req *http.Request) *RouteTrace {
+	r := req.Clone(req.Context())
+	trace := &RouteTrace{Method: r.Method, Path: r.URL.Path}
+	recorder := NewTestResponseRecorder(&discardResponse{header: http.Header{}}, nil, r)
+	recorder.trace = trace
+
+	p := &pipelineRequest{recorder: recorder, r: r}
+	runChain(h.stages(), p)
+
+	if trace.NormalizedPath == "" {
+		trace.NormalizedPath = r.URL.Path
+	}
+	if trace.Disposition == "" {
+		trace.answered(recorder, p.stage)
+	}
+	return trace
+}
+
+// handleExplain returns the route trace for the URL in the url query
+// parameter, using the method in the method parameter (default GET) and the
+// Accept and Content-Type headers in the accept and content_type parameters
+func (h *Handler) handleExplain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := r.URL.Query().Get("url")
+	if target == "" {
+		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		return
+	}
+	method := strings.ToUpper(r.URL.Query().Get("method"))
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		http.Error(w, "Invalid url parameter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for param, header := range map[string]string{"accept": "Accept", "content_type": "Content-Type"} {
+		if value := r.URL.Query().Get(param); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(h.Explain(req))
+}
+
+// tracing returns the trace of a request being explained rather than
+// served, or nil
+func tracing(w http.ResponseWriter) *RouteTrace {
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		return recorder.trace
+	}
+	return nil
+}
+
+// step returns the step of the stage being traced
+func (t *RouteTrace) step() *TraceStep {
+	return &t.Steps[t.current]
+}
+
+// dispose ends the trace where serving the request would have an effect
+// beyond answering it, and reports the request answered
+func (t *RouteTrace) dispose(disposition, target string, status int) bool {
+	t.Disposition, t.Target, t.Status = disposition, target, status
+	return true
+}
+
+// answered sets the disposition from the response a stage wrote
+func (t *RouteTrace) answered(recorder *ResponseRecorder, stage string) {
+	t.Status = recorder.statusCode
+	responseType, _ := recorder.metadata["response_type"].(string)
+	switch {
+	case responseType == "static":
+		t.Disposition = "static"
+		t.Target, _ = recorder.metadata["file_path"].(string)
+	case t.Status >= 300 && t.Status < 400:
+		t.Disposition = "redirect"
+		if t.Target, _ = recorder.metadata["destination"].(string); t.Target == "" {
+			t.Target = recorder.Header().Get("Location")
+		}
+	case responseType == "error":
+		t.Disposition = "rejected"
+	case responseType != "":
+		t.Disposition = responseType
+	case t.Status == http.StatusNotFound:
+		t.Disposition = "not-found"
+	case t.Status >= 400:
+		t.Disposition = "rejected"
+	default:
+		t.Disposition = strings.ReplaceAll(stage, "_", "-")
+	}
+}
+
+// tracedStage records a step of the route trace for the stage it wraps
+type tracedStage struct {
+	stage
+}
+
+func (s tracedStage) serve(p *pipelineRequest) bool {
+	trace := p.recorder.trace
+	trace.current = len(trace.Steps)
+	trace.Steps = append(trace.Steps, TraceStep{Stage: s.name()})
+
+	from := p.r.URL.Path
+	served := s.stage.serve(p)
+
+	step := &trace.Steps[trace.current]
+	if !served && p.r.URL.Path != from {
+		step.Matched, step.From, step.To = true, from, p.r.URL.Path
+	}
+	if served {
+		step.Matched = true
+		if message, ok := p.recorder.metadata["error_message"].(string); ok && step.Detail == "" {
+			step.Detail = message
+		}
+	}
+	return served
+}
+
+// discardResponse is the writer behind a traced request's recorder
+type discardResponse struct {
+	header http.Header
+}
+
+func (w *discardResponse) Header() http.Header         { return w.header }
+func (w *discardResponse) WriteHeader(int)             {}
+func (w *discardResponse) Write(p []byte) (int, error) { return len(p), nil }
+
+// traceAuth records how the auth stage decided a traced request
+func traceAuth(step *TraceStep, decision authDecision) {
+	step.Matched, step.Rule = decision.public, decision.rule
+	switch {
+	case decision.public:
+		step.Detail = "public path"
+	case decision.needsAuth() && decision.scope.IsEnabled():
+		step.Detail = "credentials required for realm " + decision.scope.Realm
+	case decision.needsAuth():
+		step.Detail = "credentials required but not loaded; refused"
+	default:
+		step.Detail = "authentication disabled"
+	}
+}
+
+// traceTenant ends the trace of a request for tenant, or for no tenant
+func traceTenant(trace *RouteTrace, r *http.Request, tenant *config.Tenant) bool {
+	if tenant == nil {
+		return trace.dispose("not-found", "", http.StatusNotFound)
+	}
+	trace.step().Rule = tenant.Path
+	if target := negotiatedTarget(r, tenant.Negotiate); target != nil {
+		trace.Steps = append(trace.Steps, negotiateStep(target))
+		return trace.dispose("proxy", target.Target, 0)
+	}
+	return trace.dispose("tenant", tenant.Name, 0)
+}
+
+// traceReverseProxy ends the trace of a request for a reverse proxy route
+func traceReverseProxy(trace *RouteTrace, r *http.Request, proxy *config.ProxyRoute) bool {
+	step := trace.step()
+	step.Rule, step.Detail = proxy.Path, proxy.Name
+	if step.Rule == "" {
+		step.Rule = proxy.Prefix
+	}
+	if target := negotiatedTarget(r, proxy.Negotiate); target != nil {
+		trace.Steps = append(trace.Steps, negotiateStep(target))
+		return trace.dispose("proxy", target.Target, 0)
+	}
+	return trace.dispose("proxy", proxy.Target, 0)
+}
+
+// traceFiles records the files a static stage checked for a traced request
+func traceFiles(w http.ResponseWriter, attempts ...FileAttempt) {
+	if trace := tracing(w); trace != nil {
+		trace.step().Files = append(trace.step().Files, attempts...)
+	}
+}
+
+// negotiateStep records the negotiate target a request's Accept or
+// Content-Type header selected
+func negotiateStep(target *config.NegotiatedTarget) TraceStep {
+	mediaTypes := append(append([]string(nil), target.Accept...), target.ContentType...)
+	return TraceStep{Stage: "negotiate", Matched: true, Rule: target.Path, Detail: strings.Join(mediaTypes, ", ")}
+}
+
+// RouteTable lists the routing rules of a configuration in the order
+// ServeHTTP consults them
+type RouteTable struct {
+	Rewrites       []RouteEntry `json:"rewrites"`
+	ReverseProxies []RouteEntry `json:"reverse_proxies"`
+	Tenants        []RouteEntry `json:"tenants"`
+}
+
+// RouteEntry is one rewrite, reverse proxy, or tenant route
+type RouteEntry struct {
+	Name       string   `json:"name,omitempty"`
+	Match      string   `json:"match"` // Pattern, prefix, or tenant path
+	Target     string   `json:"target,omitempty"`
+	Flag       string   `json:"flag,omitempty"`
+	Methods    []string `json:"methods,omitempty"`
+	Conditions []string `json:"conditions,omitempty"`
+	WebSocket  bool     `json:"websocket,omitempty"`
+	Aliases    []string `json:"aliases,omitempty"`
+}
+
+// Routes returns the routing table of cfg
+func Routes(cfg *config.Config) *RouteTable {
+	table := &RouteTable{Rewrites: []RouteEntry{}, ReverseProxies: []RouteEntry{}, Tenants: []RouteEntry{}}
+
+	for _, rule := range cfg.Server.RewriteRules {
+		entry := RouteEntry{Match: rule.Pattern.String(), Target: rule.Replacement, Flag: rule.Flag, Methods: rule.Methods}
+		for _, condition := range rule.Conditions {
+			entry.Conditions = append(entry.Conditions, condition.String())
+		}
+		table.Rewrites = append(table.Rewrites, entry)
+	}
+
+	for _, proxy := range cfg.Routes.ReverseProxies {
+		match := proxy.Path
+		if match == "" {
+			match = proxy.Prefix
+		}
+		table.ReverseProxies = append(table.ReverseProxies, RouteEntry{
+			Name:      proxy.Name,
+			Match:     match,
+			Target:    proxy.Target,
+			WebSocket: proxy.WebSocket,
+		})
+	}
+
+	for _, tenant := range cfg.Applications.Tenants {
+		table.Tenants = append(table.Tenants, RouteEntry{Name: tenant.Name, Match: tenant.Path, Aliases: tenant.Aliases})
+	}
+
+	return table
+}
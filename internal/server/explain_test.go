@@ -0,0 +1,332 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newExplainConfig returns a config with one tenant whose public paths are
+// served from public/ through try_files
+func newExplainConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	heats := filepath.Join(dir, "showcase", "2025", "boston")
+	if err := os.MkdirAll(heats, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(heats, "heats.htm"), []byte("heats"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = dir
+	cfg.Server.Static.TryFiles = []string{".html", ".htm"}
+	cfg.Auth.PublicPaths = []string{"/showcase/2025/boston/"}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "2025/boston", Path: "/showcase/2025/boston/"}}
+	return cfg
+}
+
+func explainPath(cfg *config.Config, method, path string) *RouteTrace {
+	return Explain(cfg, nil, httptest.NewRequest(method, path, nil))
+}
+
+// traceStep returns the step a trace recorded for stage
+func traceStep(t *testing.T, trace *RouteTrace, stage string) TraceStep {
+	t.Helper()
+	for _, step := range trace.Steps {
+		if step.Stage == stage {
+			return step
+		}
+	}
+	t.Fatalf("No %s step in %+v", stage, trace.Steps)
+	return TraceStep{}
+}
+
+func TestExplainNamesMatchingTryFilesCandidate(t *testing.T) {
+	cfg := newExplainConfig(t)
+
+	trace := explainPath(cfg, http.MethodGet, "/showcase/2025/boston/heats")
+	want := filepath.Join(cfg.Server.Static.PublicDir, "showcase", "2025", "boston", "heats.htm")
+	if trace.Disposition != "static" || trace.Target != want {
+		t.Fatalf("disposition = %q %q, want static %q", trace.Disposition, trace.Target, want)
+	}
+
+	step := trace.Steps[len(trace.Steps)-1]
+	if step.Stage != "try_files" || !step.Matched || step.Rule != ".htm" {
+		t.Fatalf("last step = %+v, want matched try_files .htm", step)
+	}
+	if len(step.Files) != 2 || step.Files[0].Exists || !step.Files[1].Exists || step.Files[1].Path != want {
+		t.Errorf("files = %+v, want a missing .html then the .htm candidate", step.Files)
+	}
+	if auth := traceStep(t, trace, "auth"); !auth.Matched || auth.Rule != "/showcase/2025/boston/" {
+		t.Errorf("auth step = %+v, want the public path", auth)
+	}
+
+	// Without a candidate the request falls through to the tenant
+	trace = explainPath(cfg, http.MethodGet, "/showcase/2025/boston/../boston/solos")
+	if trace.NormalizedPath != "/showcase/2025/boston/solos" {
+		t.Errorf("normalized path = %q", trace.NormalizedPath)
+	}
+	if trace.Disposition != "tenant" || trace.Target != "2025/boston" {
+		t.Errorf("disposition = %q %q, want tenant 2025/boston", trace.Disposition, trace.Target)
+	}
+}
+
+func TestExplainPrecedence(t *testing.T) {
+	cfg := newExplainConfig(t)
+	cfg.Server.RewriteRules = []config.RewriteRule{
+		{Pattern: regexp.MustCompile(`^/old/(.*)$`), Replacement: "/showcase/2025/boston/$1", Flag: "last"},
+		{Pattern: regexp.MustCompile(`^/moved$`), Replacement: "/showcase/", Flag: "redirect"},
+	}
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{{Name: "api", Prefix: "/showcase/2025/boston/api/", Target: "http://localhost:4000"}}
+
+	tests := []struct {
+		name        string
+		path        string
+		disposition string
+		target      string
+	}{
+		// Auth is decided for the path an internal rewrite leads to, so
+		// the public path /old/ leads to is served by try_files
+		{"rewrite then auth", "/old/heats", "static", filepath.Join(cfg.Server.Static.PublicDir, "showcase", "2025", "boston", "heats.htm")},
+		{"rewrite to a tenant", "/old/solos", "tenant", "2025/boston"},
+		{"redirect", "/moved", "redirect", "/showcase/"},
+		{"proxy before tenant", "/showcase/2025/boston/api/heats", "proxy", "http://localhost:4000"},
+		{"no match", "/elsewhere", "not-found", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := explainPath(cfg, http.MethodGet, tt.path)
+			if trace.Disposition != tt.disposition || trace.Target != tt.target {
+				t.Errorf("disposition = %q %q, want %q %q", trace.Disposition, trace.Target, tt.disposition, tt.target)
+			}
+		})
+	}
+
+	// try_files only serves public paths
+	cfg.Auth.PublicPaths = nil
+	trace := explainPath(cfg, http.MethodGet, "/showcase/2025/boston/heats")
+	if trace.Disposition != "tenant" {
+		t.Errorf("protected path disposition = %q, want tenant", trace.Disposition)
+	}
+}
+
+func TestExplainEndpoint(t *testing.T) {
+	cfg := newExplainConfig(t)
+	cfg.Server.Diagnostics.ExplainPath = "/_navigator/explain"
+	handler := CreateTestHandler(cfg, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/_navigator/explain?url=/showcase/2025/boston/heats", nil)
+	req.RemoteAddr = "127.0.0.1:4321"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var trace RouteTrace
+	if err := json.Unmarshal(rec.Body.Bytes(), &trace); err != nil {
+		t.Fatalf("invalid trace %q: %v", rec.Body.String(), err)
+	}
+	if trace.Disposition != "static" {
+		t.Errorf("disposition = %q, want static", trace.Disposition)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/_navigator/explain?url=/", nil)
+	req.RemoteAddr = "203.0.113.9:4321"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("remote status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestExplainTracesThePipeline(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  well_known:
+    robots_txt: {content: "User-agent: *\nDisallow: /\n"}
+applications:
+  tenants:
+    - name: main
+      path: /
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := explainPath(cfg, http.MethodGet, "/robots.txt")
+	if trace.Disposition != "well-known" || trace.Status != http.StatusOK {
+		t.Errorf("disposition = %q %d, want well-known 200", trace.Disposition, trace.Status)
+	}
+	if step := traceStep(t, trace, "well_known"); !step.Matched {
+		t.Errorf("well_known step = %+v, want matched", step)
+	}
+
+	// Every stage of the handler's chain up to the answering one is traced
+	h := &Handler{config: cfg, staticHandler: NewStaticFileHandler(cfg)}
+	trace = h.Explain(httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if trace.Disposition != "tenant" || trace.Target != "main" {
+		t.Errorf("disposition = %q %q, want tenant main", trace.Disposition, trace.Target)
+	}
+	var stages []string
+	for _, step := range trace.Steps {
+		stages = append(stages, step.Stage)
+	}
+	if got, want := strings.Join(stages, " "), stageNames(h.stages()); got != want {
+		t.Errorf("traced stages = %s\nwant %s", got, want)
+	}
+}
+
+// answeringStage records the stage that answered a request the real
+// pipeline served
+type answeringStage struct {
+	stage
+	answered *string
+}
+
+func (s answeringStage) serve(p *pipelineRequest) bool {
+	if !s.stage.serve(p) {
+		return false
+	}
+	*s.answered = s.name()
+	return true
+}
+
+func TestExplainAgreesWithThePipeline(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("backend " + r.URL.Path))
+	}))
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	publicDir := t.TempDir()
+	for name, content := range map[string]string{
+		"about.html":      "about",
+		"assets/app.css":  "body {}",
+		"docs/index.html": "docs",
+		"app/index.html":  "spa",
+	} {
+		file := filepath.Join(publicDir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configs := map[string]string{
+		"tenants": `
+server:
+  static:
+    public_dir: ` + publicDir + `
+    try_files: [.html]
+    normalize_trailing_slashes: true
+  health_check:
+    path: /up
+    response: {status: 200, body: OK}
+  well_known:
+    robots_txt: {content: "User-agent: *\nDisallow: /\n"}
+auth:
+  public_paths: [/about, /docs]
+routes:
+  redirects:
+    - from: ^/old/(.*)$
+      to: /studios/$1
+  rewrites:
+    - from: ^/legacy/(.*)$
+      to: /studios/boston/$1
+  reverse_proxies:
+    - name: api
+      prefix: /api/
+      target: ` + backend.URL + `
+applications:
+  tenants:
+    - name: boston
+      path: /studios/boston/
+      aliases: [/boston/]
+    - name: raleigh
+      path: /studios/raleigh/
+      maintenance: {enabled: true}
+`,
+		"static only": `
+server:
+  static:
+    public_dir: ` + publicDir + `
+    spa:
+      - path: /app/
+        fallback: /app/index.html
+        exclude: ["^/app/api/"]
+`,
+		"canonical": `
+server:
+  canonical:
+    host: www.example.com
+`,
+	}
+	paths := []string{
+		"/", "/up", "/robots.txt", "/about", "/docs", "/assets/app.css", "/assets/missing.css",
+		"/old/boston", "/legacy/heats", "/api/events", "/studios/boston/heats", "/boston/heats",
+		"/studios/raleigh/", "/studios/../studios/boston/", "/app/users/1", "/app/api/users", "/elsewhere",
+	}
+
+	for name, yaml := range configs {
+		cfg, err := config.ParseYAML([]byte(yaml))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		appManager := process.NewAppManager(cfg)
+		appManager.StubApps(backendPort)
+		h := CreateHandler(cfg, appManager, nil, &idle.Manager{}, nil, nil, nil, nil).(*Handler)
+		h.disableLog = true
+
+		var answered string
+		chain := h.assembleChain()
+		for i := range chain {
+			chain[i] = answeringStage{stage: chain[i], answered: &answered}
+		}
+		h.chain = chain
+
+		for _, path := range paths {
+			t.Run(name+" "+path, func(t *testing.T) {
+				request := func() *http.Request {
+					r := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+					r.RemoteAddr = "192.0.2.1:1234"
+					return r
+				}
+
+				answered = ""
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, request())
+				trace := h.Explain(request())
+
+				var traced string
+				for _, step := range trace.Steps {
+					if step.Stage != "negotiate" {
+						traced = step.Stage
+					}
+				}
+				if traced != answered {
+					t.Errorf("Explain answered in %s (%s), the pipeline in %s (%d)", traced, trace.Disposition, answered, rec.Code)
+				}
+				if trace.Status != 0 && trace.Status != rec.Code {
+					t.Errorf("Explain status %d, pipeline status %d", trace.Status, rec.Code)
+				}
+				if trace.Disposition == "redirect" && trace.Target != rec.Header().Get("Location") {
+					t.Errorf("Explain redirects to %q, pipeline to %q", trace.Target, rec.Header().Get("Location"))
+				}
+			})
+		}
+	}
+}
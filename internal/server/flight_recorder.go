@@ -0,0 +1,134 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/flight"
+	"github.com/rubys/navigator/internal/netutil"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// flightRecording holds what the flight recorder needs of a request while
+// it is in flight. Nothing is copied or written unless the request fails:
+// headers are read when it is done, and only the start of the body is kept.
+type flightRecording struct {
+	config    *config.FlightRecorderConfig
+	requestID string
+	body      *cappedBuffer
+	firstByte time.Duration // Since the start, when the status was written
+}
+
+// newFlightRecording returns a recording for the request, or nil when the
+// flight recorder is disabled
+func newFlightRecording(cfg *config.FlightRecorderConfig, r *http.Request, requestID string) *flightRecording {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	f := &flightRecording{config: cfg, requestID: requestID}
+	if r.Body != nil && r.Body != http.NoBody {
+		f.body = &cappedBuffer{limit: cfg.MaxBodyBytes}
+		r.Body = &captureReadCloser{ReadCloser: r.Body, buffer: f.body}
+	}
+	return f
+}
+
+// wroteHeader records when the response status was written
+func (f *flightRecording) wroteHeader(start time.Time) {
+	if f.firstByte == 0 {
+		f.firstByte = time.Since(start)
+	}
+}
+
+// flightFailed reports whether a request is kept: it answered with a 5xx, or a
+// proxy reported an upstream error
+func flightFailed(status int, metadata map[string]interface{}) bool {
+	_, upstream := metadata["upstream_error"].(string)
+	return status >= 500 || upstream
+}
+
+// finish writes the entry for a failed request
+func (f *flightRecording) finish(req *http.Request, header http.Header, status int, start time.Time, metadata map[string]interface{}) {
+	if !flightFailed(status, metadata) {
+		return
+	}
+
+	uri := req.URL.Path
+	if req.URL.RawQuery != "" {
+		uri += "?" + req.URL.RawQuery
+	}
+	patterns := f.config.RedactPatterns
+	entry := &flight.Entry{
+		Timestamp:       start,
+		RequestID:       f.requestID,
+		Method:          req.Method,
+		ClientIP:        netutil.ClientIP(req, proxy.ClientIPTrust()),
+		URI:             redactString(patterns, uri),
+		Status:          status,
+		RequestHeaders:  redactedHeaders(patterns, req.Header),
+		ResponseHeaders: redactedHeaders(patterns, header),
+		Timing: flight.Timing{
+			Total:     milliseconds(time.Since(start)),
+			FirstByte: milliseconds(f.firstByte),
+		},
+	}
+	if f.body != nil {
+		entry.RequestBody = redactString(patterns, f.body.buf.String())
+		entry.RequestTruncated = f.body.truncated
+	}
+
+	entry.Tenant, _ = metadata["tenant"].(string)
+	entry.ResponseType, _ = metadata["response_type"].(string)
+	entry.ErrorMessage, _ = metadata["error_message"].(string)
+	if upstream, ok := metadata["upstream_error"].(string); ok {
+		entry.UpstreamError = redactString(patterns, upstream)
+	}
+	if queueTime, ok := metadata["queue_time"].(time.Duration); ok {
+		entry.Timing.Queue = milliseconds(queueTime)
+	}
+	if startupQueued, ok := metadata["startup_queued_ms"].(int64); ok {
+		entry.Timing.StartupQueued = float64(startupQueued)
+	}
+	if bootMs, ok := metadata["boot_ms"].(int64); ok {
+		entry.Timing.Boot = float64(bootMs)
+	}
+
+	ring := flight.Ring{Dir: f.config.Directory, MaxFiles: f.config.MaxFiles, MaxBytes: f.config.MaxBytes}
+	if !ring.Enqueue(entry) {
+		slog.Warn("Flight recorder queue full, dropped entry", "directory", f.config.Directory, "request_id", f.requestID)
+	}
+}
+
+// credentialHeaders are always redacted from flight recorder entries,
+// whatever the redact patterns say
+var credentialHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+}
+
+// redactedHeaders returns header as sorted "Name: value" lines with the
+// values of credential headers, and every match of patterns, redacted
+func redactedHeaders(patterns []*regexp.Regexp, header http.Header) []string {
+	var lines []string
+	for name, values := range header {
+		for _, value := range values {
+			if credentialHeaders[http.CanonicalHeaderKey(name)] {
+				lines = append(lines, name+": "+redactedMarker)
+				continue
+			}
+			lines = append(lines, redactString(patterns, name+": "+value))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
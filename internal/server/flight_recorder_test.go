@@ -0,0 +1,138 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/flight"
+)
+
+func flightTestConfig(t *testing.T, routes ...config.ProxyRoute) *config.Config {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Logging.FlightRecorder = config.FlightRecorderConfig{
+		Enabled:        true,
+		Directory:      t.TempDir(),
+		MaxFiles:       10,
+		MaxBytes:       1 << 20,
+		MaxBodyBytes:   8,
+		RedactPatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)^x-api-key: .*`)},
+	}
+	cfg.Routes.ReverseProxies = routes
+	return cfg
+}
+
+func TestFlightRecorderKeepsFailedRequests(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	// A port nothing listens on, for an upstream error
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := "http://" + listener.Addr().String()
+	_ = listener.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer healthy.Close()
+
+	cfg := flightTestConfig(t,
+		config.ProxyRoute{Name: "failing", Prefix: "/failing/", Target: failing.URL},
+		config.ProxyRoute{Name: "down", Prefix: "/down/", Target: down},
+		config.ProxyRoute{Name: "healthy", Prefix: "/healthy/", Target: healthy.URL},
+	)
+	handler := CreateTestHandler(cfg, nil, nil, nil)
+	dir := cfg.Logging.FlightRecorder.Directory
+
+	send := func(path string) int {
+		req := httptest.NewRequest("POST", path+"?id=7", strings.NewReader("name=ada&card=4111"))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		req.Header.Set("Cookie", "session=abc")
+		req.Header.Set("Proxy-Authorization", "Basic cHJveHk6c2VjcmV0")
+		req.Header.Set("X-Api-Key", "key-123")
+		req.Header.Set("X-Trace", "visible")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send("/healthy/ping"); code != http.StatusOK {
+		t.Fatalf("healthy request = %d, want 200", code)
+	}
+	flight.Flush()
+	if entries, _ := flight.List(dir); len(entries) != 0 {
+		t.Fatalf("successful request was kept: %+v", entries[0])
+	}
+
+	if code := send("/failing/charge"); code != http.StatusInternalServerError {
+		t.Fatalf("failing request = %d, want 500", code)
+	}
+	if code := send("/down/charge"); code != http.StatusBadGateway {
+		t.Fatalf("request to a down backend = %d, want 502", code)
+	}
+
+	flight.Flush()
+	entries, err := flight.List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("flight recorder kept %d entries, want 2", len(entries))
+	}
+
+	failed, unreachable := entries[0], entries[1]
+	if failed.Status != 500 || failed.URI != "/failing/charge?id=7" || failed.Method != "POST" {
+		t.Errorf("first entry = %d %s %s, want 500 POST /failing/charge?id=7", failed.Status, failed.Method, failed.URI)
+	}
+	if failed.RequestBody != "name=ada" || !failed.RequestTruncated {
+		t.Errorf("request body = %q (truncated=%v), want the first 8 bytes", failed.RequestBody, failed.RequestTruncated)
+	}
+	if failed.Timing.Total <= 0 || failed.Timing.FirstByte <= 0 {
+		t.Errorf("timing = %+v, want total and first byte", failed.Timing)
+	}
+	if !slices.Contains(failed.ResponseHeaders, "Content-Type: text/plain; charset=utf-8") {
+		t.Errorf("response headers = %v", failed.ResponseHeaders)
+	}
+
+	if unreachable.Status != 502 || !strings.Contains(unreachable.UpstreamError, "connection refused") {
+		t.Errorf("second entry = %d with upstream error %q, want 502 and connection refused", unreachable.Status, unreachable.UpstreamError)
+	}
+
+	for _, entry := range entries {
+		for _, header := range entry.RequestHeaders {
+			if strings.Contains(header, "secret-token") || strings.Contains(header, "session=abc") ||
+				strings.Contains(header, "cHJveHk6c2VjcmV0") || strings.Contains(header, "key-123") {
+				t.Errorf("credential not redacted: %q", header)
+			}
+		}
+		// Credential headers are redacted without a pattern naming them;
+		// patterns redact more
+		for _, want := range []string{"Authorization: " + redactedMarker, "Cookie: " + redactedMarker,
+			"Proxy-Authorization: " + redactedMarker, redactedMarker, "X-Trace: visible"} {
+			if !slices.Contains(entry.RequestHeaders, want) {
+				t.Errorf("request headers = %v, want %q", entry.RequestHeaders, want)
+			}
+		}
+	}
+}
+
+func TestFlightRecorderDisabled(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("body"))
+	body := req.Body
+	if f := newFlightRecording(&config.FlightRecorderConfig{}, req, ""); f != nil || req.Body != body {
+		t.Error("a disabled flight recorder should leave the request alone")
+	}
+}
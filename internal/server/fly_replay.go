@@ -68,6 +68,15 @@ func HandleFlyReplay(w http.ResponseWriter, r *http.Request, target string, stat
 		return true
 	}
 
+	// Refuse to replay again once the hop limit is reached, so two machines
+	// whose rules point at each other cannot ping-pong a request
+	hops := replayHops(r)
+	if limit := maxReplayHops(config); hops >= limit {
+		src, _ := ParseFlyReplaySrc(r)
+		logging.LogFlyReplayLoop(target, hops, limit, src.Instance, src.Region)
+		return false
+	}
+
 	w.Header().Set("Content-Type", "application/vnd.fly.replay+json")
 	statusCode := http.StatusTemporaryRedirect
 	if code, err := strconv.Atoi(status); err == nil {
@@ -83,6 +92,7 @@ func HandleFlyReplay(w http.ResponseWriter, r *http.Request, target string, stat
 	buildTransformHeaders := func() []map[string]string {
 		headers := []map[string]string{
 			{"name": "X-Navigator-Retry", "value": "true"},
+			{"name": HeaderReplayHops, "value": strconv.Itoa(hops + 1)},
 		}
 
 		// Explicitly preserve Authorization header if present
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// Headers used to detect requests that have already been replayed
+const (
+	// HeaderFlyReplaySrc is set by the Fly proxy on requests delivered by a replay
+	HeaderFlyReplaySrc = "Fly-Replay-Src"
+
+	// HeaderReplayHops carries the number of replays a request has had; Navigator
+	// sets it through the replay transform so every hop can see the count
+	HeaderReplayHops = "X-Navigator-Replay-Hops"
+)
+
+// FlyReplaySrc is the parsed Fly-Replay-Src header, describing the machine that
+// replayed this request. The zero value means the request was not replayed.
+// Its other fields, such as state, reach tenants in the header unchanged.
+type FlyReplaySrc struct {
+	Instance string // Machine ID that issued the replay
+	Region   string // Region of that machine
+}
+
+// ParseFlyReplaySrc parses the instance and region from the request's
+// "instance=...;region=...;t=...;state=..." header
+func ParseFlyReplaySrc(r *http.Request) (FlyReplaySrc, bool) {
+	header := r.Header.Get(HeaderFlyReplaySrc)
+	if header == "" {
+		return FlyReplaySrc{}, false
+	}
+
+	var src FlyReplaySrc
+	for _, field := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "instance":
+			src.Instance = value
+		case "region":
+			src.Region = value
+		}
+	}
+	return src, true
+}
+
+// replayHops returns how many times the request has already been replayed: the
+// count Navigator propagated, or at least one if Fly reports a replay source
+func replayHops(r *http.Request) int {
+	hops, _ := strconv.Atoi(r.Header.Get(HeaderReplayHops))
+	if _, replayed := ParseFlyReplaySrc(r); replayed && hops < 1 {
+		hops = 1
+	}
+	return hops
+}
+
+// maxReplayHops returns the configured hop limit, defaulting to one replay
+func maxReplayHops(cfg *config.Config) int {
+	if cfg.Routes.Fly.MaxReplayHops > 0 {
+		return cfg.Routes.Fly.MaxReplayHops
+	}
+	return config.DefaultMaxReplayHops
+}
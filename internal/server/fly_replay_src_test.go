@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestParseFlyReplaySrc(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, ok := ParseFlyReplaySrc(req); ok {
+		t.Fatal("request without header should not be reported as replayed")
+	}
+
+	req.Header.Set(HeaderFlyReplaySrc, "instance=abc123; region=iad;t=1700000000123456;state=user=42")
+	src, ok := ParseFlyReplaySrc(req)
+	if !ok {
+		t.Fatal("expected header to be parsed")
+	}
+	want := FlyReplaySrc{Instance: "abc123", Region: "iad"}
+	if src != want {
+		t.Errorf("ParseFlyReplaySrc = %+v, want %+v", src, want)
+	}
+}
+
+func TestReplayHops(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		hops string
+		want int
+	}{
+		{"not replayed", "", "", 0},
+		{"replayed by fly", "instance=abc;region=iad", "", 1},
+		{"hop count propagated", "instance=abc;region=iad", "2", 2},
+		{"invalid hop count", "instance=abc;region=iad", "many", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.src != "" {
+				req.Header.Set(HeaderFlyReplaySrc, tt.src)
+			}
+			if tt.hops != "" {
+				req.Header.Set(HeaderReplayHops, tt.hops)
+			}
+			if got := replayHops(req); got != tt.want {
+				t.Errorf("replayHops = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleFlyReplayLoopPrevention(t *testing.T) {
+	t.Setenv("FLY_APP_NAME", "smooth")
+
+	replayed := func() *http.Request {
+		req := httptest.NewRequest("GET", "/showcase/2025/boston/", nil)
+		req.Header.Set(HeaderFlyReplaySrc, "instance=abc123;region=ord;t=1700000000000000")
+		return req
+	}
+
+	t.Run("first replay carries hop count", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if !HandleFlyReplay(rec, httptest.NewRequest("GET", "/showcase/2025/boston/", nil), "iad", "307", &config.Config{}) {
+			t.Fatal("expected the request to be replayed")
+		}
+		if body := rec.Body.String(); !strings.Contains(body, `"name":"X-Navigator-Replay-Hops","value":"1"`) {
+			t.Errorf("replay response missing hop count: %s", body)
+		}
+	})
+
+	t.Run("replayed request is handled locally", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if HandleFlyReplay(rec, replayed(), "iad", "307", &config.Config{}) {
+			t.Error("expected a replayed request not to be replayed again")
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("nothing should be written when handling locally, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("higher limit allows another hop", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.Routes.Fly.MaxReplayHops = 2
+		rec := httptest.NewRecorder()
+		if !HandleFlyReplay(rec, replayed(), "iad", "307", cfg) {
+			t.Fatal("expected a second replay to be allowed")
+		}
+		if body := rec.Body.String(); !strings.Contains(body, `"name":"X-Navigator-Replay-Hops","value":"2"`) {
+			t.Errorf("replay response missing hop count: %s", body)
+		}
+
+		req := replayed()
+		req.Header.Set(HeaderReplayHops, "2")
+		if HandleFlyReplay(httptest.NewRecorder(), req, "iad", "307", cfg) {
+			t.Error("expected the third replay to be refused")
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// ReplayProber checks whether a fly-replay target is reachable
+type ReplayProber interface {
+	Probe(ctx context.Context, address string) error
+}
+
+// dialProber probes by opening a TCP connection to the target's .internal address
+type dialProber struct{}
+
+func (dialProber) Probe(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// replayHealth is a cached probe result
+type replayHealth struct {
+	healthy    bool
+	checked    time.Time
+	refreshing bool
+}
+
+// replayHealthCache caches probe results per address. Only the first request
+// for an address waits for a probe; stale results are returned immediately
+// while a background probe refreshes them.
+type replayHealthCache struct {
+	prober  ReplayProber
+	timeout time.Duration
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*replayHealth
+}
+
+func newReplayHealthCache(prober ReplayProber, timeout time.Duration) *replayHealthCache {
+	return &replayHealthCache{
+		prober:  prober,
+		timeout: timeout,
+		clock:   clock.Real,
+		entries: make(map[string]*replayHealth),
+	}
+}
+
+// replayTargets is shared across handler reloads so cached results survive a SIGHUP
+var replayTargets = newReplayHealthCache(dialProber{}, config.FlyReplayProbeTimeout)
+
+// Healthy reports whether address was reachable as of the last probe
+func (c *replayHealthCache) Healthy(address string, ttl time.Duration) bool {
+	c.mu.Lock()
+	entry, ok := c.entries[address]
+	if ok {
+		healthy := entry.healthy
+		if c.clock.Now().Sub(entry.checked) >= ttl && !entry.refreshing {
+			entry.refreshing = true
+			go c.probe(address)
+		}
+		c.mu.Unlock()
+		return healthy
+	}
+	c.mu.Unlock()
+
+	// First miss: nothing cached yet, so this request waits for the probe
+	return c.probe(address)
+}
+
+// probe checks address and records the result
+func (c *replayHealthCache) probe(address string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	err := c.prober.Probe(ctx, address)
+
+	c.mu.Lock()
+	c.entries[address] = &replayHealth{healthy: err == nil, checked: c.clock.Now()}
+	c.mu.Unlock()
+
+	if err != nil {
+		logging.LogFlyReplayProbeFailed(address, err)
+	}
+	return err == nil
+}
+
+// replayProbeAddress returns the private network address for a fly-replay target:
+// region targets probe <region>.<app>.internal, app targets <app>.internal, and
+// machine targets <machine>.vm.<app>.internal
+func replayProbeAddress(target string, port int) (string, bool) {
+	var host string
+	switch {
+	case strings.HasPrefix(target, "app="):
+		host = strings.TrimPrefix(target, "app=") + ".internal"
+	case strings.HasPrefix(target, "machine="):
+		machine, app, ok := strings.Cut(strings.TrimPrefix(target, "machine="), ":")
+		if !ok {
+			return "", false
+		}
+		host = fmt.Sprintf("%s.vm.%s.internal", machine, app)
+	default:
+		app := os.Getenv("FLY_APP_NAME")
+		if app == "" {
+			return "", false
+		}
+		host = fmt.Sprintf("%s.%s.internal", target, app)
+	}
+	return net.JoinHostPort(host, fmt.Sprint(port)), true
+}
+
+// replayTargetHealthy reports whether target appears reachable. Targets that
+// cannot be probed (e.g. outside Fly) are assumed healthy.
+func replayTargetHealthy(target string, verify *config.ReplayVerification) bool {
+	address, ok := replayProbeAddress(target, verify.Port)
+	if !ok {
+		return true
+	}
+	return replayTargets.Healthy(address, verify.TTL)
+}
+
+// verifyReplayTarget chooses where to replay: the target if reachable, else the
+// fallback region if reachable. Returns false if the request should be handled locally.
+func verifyReplayTarget(target string, verify *config.ReplayVerification) (string, bool) {
+	if replayTargetHealthy(target, verify) {
+		return target, true
+	}
+	if verify.FallbackRegion != "" && replayTargetHealthy(verify.FallbackRegion, verify) {
+		logging.LogFlyReplayFallbackRegion(target, verify.FallbackRegion)
+		return verify.FallbackRegion, true
+	}
+	logging.LogFlyReplayHandledLocally(target)
+	return "", false
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// fakeProber reports a configurable result per address and counts probes
+type fakeProber struct {
+	mu      sync.Mutex
+	healthy map[string]bool
+	probes  map[string]int
+	block   chan struct{} // When non-nil, probes wait for it to be closed
+}
+
+func newFakeProber() *fakeProber {
+	return &fakeProber{healthy: make(map[string]bool), probes: make(map[string]int)}
+}
+
+func (p *fakeProber) Probe(ctx context.Context, address string) error {
+	p.mu.Lock()
+	p.probes[address]++
+	block := p.block
+	p.mu.Unlock()
+
+	if block != nil {
+		<-block
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.healthy[address] {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (p *fakeProber) set(address string, healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.healthy[address] = healthy
+}
+
+func (p *fakeProber) count(address string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.probes[address]
+}
+
+// useFakeProber replaces the shared probe cache for the duration of a test
+func useFakeProber(t *testing.T) (*fakeProber, *replayHealthCache) {
+	t.Helper()
+	prober := newFakeProber()
+	cache := newReplayHealthCache(prober, time.Second)
+	original := replayTargets
+	replayTargets = cache
+	t.Cleanup(func() { replayTargets = original })
+	return prober, cache
+}
+
+func TestReplayProbeAddress(t *testing.T) {
+	t.Setenv("FLY_APP_NAME", "smooth")
+
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"iad", "iad.smooth.internal:3000", true},
+		{"app=smooth-pdf", "smooth-pdf.internal:3000", true},
+		{"machine=abc123:smooth", "abc123.vm.smooth.internal:3000", true},
+		{"machine=abc123", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := replayProbeAddress(tt.target, 3000)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("replayProbeAddress(%q) = %q, %v; want %q, %v", tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	t.Setenv("FLY_APP_NAME", "")
+	if _, ok := replayProbeAddress("iad", 3000); ok {
+		t.Error("region targets cannot be probed without FLY_APP_NAME")
+	}
+}
+
+func TestVerifyReplayTarget(t *testing.T) {
+	t.Setenv("FLY_APP_NAME", "smooth")
+	verify := &config.ReplayVerification{TTL: time.Minute, Port: 3000}
+	withFallback := &config.ReplayVerification{TTL: time.Minute, Port: 3000, FallbackRegion: "ord"}
+
+	t.Run("healthy target is cached", func(t *testing.T) {
+		prober, _ := useFakeProber(t)
+		prober.set("iad.smooth.internal:3000", true)
+
+		for i := 0; i < 3; i++ {
+			if target, ok := verifyReplayTarget("iad", verify); !ok || target != "iad" {
+				t.Fatalf("verifyReplayTarget = %q, %v; want iad", target, ok)
+			}
+		}
+		if n := prober.count("iad.smooth.internal:3000"); n != 1 {
+			t.Errorf("probes = %d, want 1 (later requests use the cache)", n)
+		}
+	})
+
+	t.Run("unhealthy target is handled locally", func(t *testing.T) {
+		prober, _ := useFakeProber(t)
+		prober.set("iad.smooth.internal:3000", false)
+
+		if _, ok := verifyReplayTarget("iad", verify); ok {
+			t.Error("expected replay to be skipped")
+		}
+	})
+
+	t.Run("unhealthy target uses fallback region", func(t *testing.T) {
+		prober, _ := useFakeProber(t)
+		prober.set("iad.smooth.internal:3000", false)
+		prober.set("ord.smooth.internal:3000", true)
+
+		if target, ok := verifyReplayTarget("iad", withFallback); !ok || target != "ord" {
+			t.Errorf("verifyReplayTarget = %q, %v; want ord", target, ok)
+		}
+	})
+
+	t.Run("unhealthy fallback is handled locally", func(t *testing.T) {
+		prober, _ := useFakeProber(t)
+		prober.set("iad.smooth.internal:3000", false)
+		prober.set("ord.smooth.internal:3000", false)
+
+		if _, ok := verifyReplayTarget("iad", withFallback); ok {
+			t.Error("expected replay to be skipped")
+		}
+	})
+}
+
+func TestReplayHealthCacheStaleRefreshesInBackground(t *testing.T) {
+	prober := newFakeProber()
+	cache := newReplayHealthCache(prober, time.Second)
+	fake := clock.NewFake(time.Now())
+	cache.clock = fake
+	const address = "iad.smooth.internal:3000"
+
+	prober.set(address, true)
+	if !cache.Healthy(address, time.Minute) {
+		t.Fatal("expected healthy after first probe")
+	}
+
+	// The target goes down and the cached result goes stale
+	prober.set(address, false)
+	prober.mu.Lock()
+	prober.block = make(chan struct{})
+	prober.mu.Unlock()
+	fake.Advance(2 * time.Minute)
+
+	// A stale entry answers immediately from the cache, even while the probe is stuck
+	done := make(chan bool)
+	go func() { done <- cache.Healthy(address, time.Minute) }()
+	select {
+	case healthy := <-done:
+		if !healthy {
+			t.Error("stale lookup should return the cached result")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stale lookup waited for the probe")
+	}
+
+	// Concurrent stale lookups start only one refresh
+	cache.Healthy(address, time.Minute)
+	close(prober.block)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for cache.Healthy(address, time.Minute) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cache.Healthy(address, time.Minute) {
+		t.Error("background refresh should record the target as unhealthy")
+	}
+	if n := prober.count(address); n != 2 {
+		t.Errorf("probes = %d, want 2", n)
+	}
+}
+
+func TestHandleRewritesFlyReplayVerification(t *testing.T) {
+	t.Setenv("FLY_APP_NAME", "smooth")
+	prober, _ := useFakeProber(t)
+
+	cfg := &config.Config{}
+	cfg.Server.RewriteRules = []config.RewriteRule{{
+		Pattern:     regexp.MustCompile("^/showcase/2025/boston/"),
+		Replacement: "^/showcase/2025/boston/",
+		Flag:        "fly-replay:iad:307",
+		Verify:      &config.ReplayVerification{TTL: time.Minute, Port: 3000},
+	}}
+	handler := &Handler{config: cfg}
+
+	prober.set("iad.smooth.internal:3000", true)
+	rec := httptest.NewRecorder()
+	if !handler.handleRewrites(rec, httptest.NewRequest("GET", "/showcase/2025/boston/", nil)) {
+		t.Fatal("expected fly-replay when the target is healthy")
+	}
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("status = %d, want 307", rec.Code)
+	}
+
+	// A fresh cache whose prober reports every target down
+	useFakeProber(t)
+	rec = httptest.NewRecorder()
+	if handler.handleRewrites(rec, httptest.NewRequest("GET", "/showcase/2025/boston/", nil)) {
+		t.Error("expected the request to be handled locally when the target is down")
+	}
+}
@@ -2,21 +2,28 @@ package server
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rubys/navigator/internal/auth"
 	"github.com/rubys/navigator/internal/cgi"
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/idle"
 	"github.com/rubys/navigator/internal/logging"
 	"github.com/rubys/navigator/internal/process"
 	"github.com/rubys/navigator/internal/proxy"
 	"github.com/rubys/navigator/internal/utils"
+	"github.com/rubys/navigator/internal/warmer"
 	"zgo.at/isbot"
 )
 
@@ -27,7 +34,7 @@ type CableHandler interface {
 }
 
 // CreateHandler creates the main HTTP handler for Navigator
-func CreateHandler(cfg *config.Config, appManager *process.AppManager, basicAuth *auth.BasicAuth, idleManager *idle.Manager, cableHandler CableHandler, currentConfigFn func() string, configLoadTimeFn func() time.Time, triggerReloadFn func(string)) http.Handler {
+func CreateHandler(cfg *config.Config, appManager *process.AppManager, basicAuth *auth.BasicAuth, idleManager *idle.Manager, cableHandler CableHandler, currentConfigFn func() string, configLoadTimeFn func() time.Time, triggerReloadFn func(utils.ReloadDecision)) http.Handler {
 	h := &Handler{
 		config:        cfg,
 		appManager:    appManager,
@@ -35,8 +42,16 @@ func CreateHandler(cfg *config.Config, appManager *process.AppManager, basicAuth
 		idleManager:   idleManager,
 		cableHandler:  cableHandler,
 		staticHandler: NewStaticFileHandler(cfg),
+
+		currentConfigFn: currentConfigFn,
 	}
 	h.setupCGIHandlers(currentConfigFn, configLoadTimeFn, triggerReloadFn)
+	h.chain = h.assembleChain()
+	cachedResponses.setMaxMemory(cfg.Server.ResponseCache.MaxMemory)
+	loadShedding.configure(cfg.Server.LoadShedding)
+	tenantPauses.reconcile(cfg.Applications.Tenants)
+	tenantErrors.configure(cfg.Server.ErrorHistory, cfg.Applications.Tenants)
+	mirrors.configure(cfg)
 	return h
 }
 
@@ -64,12 +79,23 @@ type Handler struct {
 	staticHandler *StaticFileHandler
 	cgiHandlers   map[string]*cgiRoute // Path -> CGI handler mapping
 	disableLog    bool                 // When true, suppresses access log output (for tests)
+	clock         clock.Clock          // Clock for maintenance windows (nil = real time)
+
+	currentConfigFn func() string // The config file being served, for validate-config paths (nil = none)
+
+	chain     []stage   // Request pipeline (nil = every stage the config uses)
+	chainOnce sync.Once // Assembles chain on the first request when it's nil
 }
 
 // cgiRoute represents a CGI route with method filtering
 type cgiRoute struct {
 	handler *cgi.Handler
 	method  string // Empty string means all methods
+	script  string
+
+	auth, authScope string // The script's auth setting over the site-wide one
+
+	decompress *[]string // The script's decompress_requests over the server's (nil = inherit)
 }
 
 // shouldBlockBot checks if the request should be blocked based on bot detection config
@@ -131,127 +157,68 @@ func (h *Handler) shouldBlockBot(r *http.Request, tenant *config.Tenant) bool {
 
 // ServeHTTP handles all incoming HTTP requests
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Generate request ID if not present
-	requestID := r.Header.Get("X-Request-Id")
-	if requestID == "" {
-		requestID = utils.GenerateRequestID()
-		r.Header.Set("X-Request-Id", requestID)
-	}
-
-	// Create response recorder for logging and tracking
-	recorder := NewResponseRecorder(w, h.idleManager, r)
-	recorder.disableLog = h.disableLog
-	defer recorder.Finish(r)
-
-	// Start idle tracking
-	recorder.StartTracking()
-
-	// Log request start
-	logging.LogRequest(r.Method, r.URL.Path, requestID)
+	// Bound the request by server.timeouts, which follow reloads with the handler
+	setRequestDeadlines(w, r, h.config.Server.Timeouts)
 
-	// Handle health check endpoint (if configured)
-	if h.config.Server.HealthCheck.Path != "" && r.URL.Path == h.config.Server.HealthCheck.Path {
-		h.handleHealthCheck(recorder, r)
+	// A quiet health check is answered before any logging, request ID or
+	// idle tracking work
+	if health := &h.config.Server.HealthCheck; health.Quiet && r.URL.Path == health.Path {
+		writeHealthCheckResponse(w, health.Response)
 		return
 	}
 
-	// Handle broadcast endpoint BEFORE authentication (localhost-only)
-	// This allows tenant Rails processes to broadcast without credentials
-	if h.cableHandler != nil && h.config.Cable.Enabled && h.config.Cable.BroadcastPath != "" && r.URL.Path == h.config.Cable.BroadcastPath {
-		// Verify request is from localhost for security
-		remoteAddr := r.RemoteAddr
-		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
-			remoteAddr = host
-		}
-		if remoteAddr == "127.0.0.1" || remoteAddr == "::1" || remoteAddr == "localhost" {
-			recorder.SetMetadata("response_type", "broadcast")
-			h.cableHandler.HandleBroadcast(recorder, r)
-			return
-		}
-		// Not from localhost, return 403 Forbidden
-		http.Error(recorder, "Forbidden: "+h.config.Cable.BroadcastPath+" is only accessible from localhost", http.StatusForbidden)
-		return
-	}
+	// Drop hop-by-hop and untrusted headers before anything reads them
+	sanitizeRequestHeaders(r, h.config.Server.RequestHeaders.Strip)
 
-	// Check authentication EARLY - before any routing decisions
-	// This prevents authentication bypass via reverse proxies, fly-replay, etc.
-	isPublic := auth.ShouldExcludeFromAuth(r.URL.Path, h.config)
-	needsAuth := h.auth.IsEnabled() && !isPublic
+	// Take the request ID from its header, or generate one, and send it on
+	// in every header that carries it
+	requestID := setRequestID(r, h.config.Server.RequestID)
 
-	if needsAuth && !h.auth.CheckAuth(r) {
-		recorder.SetMetadata("response_type", "auth-failure")
-		h.auth.RequireAuth(recorder)
-		return
+	// Identify the machine that served the response
+	if h.config.Server.RegionHeaders {
+		setRegionHeaders(w.Header())
 	}
 
-	// Handle WebSocket endpoint (after auth check)
-	if h.cableHandler != nil && h.config.Cable.Enabled && h.config.Cable.Path != "" && r.URL.Path == h.config.Cable.Path {
-		recorder.SetMetadata("response_type", "websocket")
-		h.cableHandler.ServeHTTP(recorder, r)
-		return
-	}
+	// Create response recorder for logging and tracking
+	recorder := NewResponseRecorder(w, h.idleManager, r)
+	recorder.disableLog = h.disableLog
+	defer recorder.Finish(r)
 
-	// Handle rewrites and redirects
-	if h.handleRewrites(recorder, r) {
-		return
-	}
+	// Keep an archive of public files open until the request is done, even
+	// if a reload replaces it meanwhile
+	defer h.staticHandler.useSource()()
 
-	// Handle CGI scripts
-	if h.handleCGI(recorder, r) {
-		return
-	}
+	// A client that goes away cancels the request's context, and with it
+	// the request to any backend
+	stopWatching := recorder.watchDisconnect(r.Context())
+	defer stopWatching()
 
-	// Handle reverse proxies (including WebSockets)
-	if h.handleReverseProxies(recorder, r) {
-		return
+	// Requests from tenants' warmers are flagged as internal, and are only
+	// activity if their warmer keeps the app warm
+	recorder.warming, recorder.keepWarm = warmer.Warming(r)
+	if recorder.warming {
+		recorder.SetMetadata("internal", true)
 	}
 
-	// Try to serve static files (even during maintenance mode)
-	if h.staticHandler.ServeStatic(recorder, r) {
-		return
-	}
-
-	// Try files for public paths (even during maintenance mode)
-	if isPublic && h.staticHandler.TryFiles(recorder, r) {
-		return
-	}
-
-	// Check if maintenance mode is enabled
-	// Static files are served above, so only dynamic requests reach here
-	if h.config.Maintenance.Enabled {
-		recorder.SetMetadata("response_type", "maintenance")
-		ServeMaintenancePage(recorder, r, h.config)
-		return
+	// Start idle tracking
+	if !recorder.warming || recorder.keepWarm {
+		recorder.StartTracking()
 	}
 
-	// Handle web application proxy
-	if len(h.config.Applications.Tenants) > 0 {
-		// Check bot detection before proxying to web app
-		// Extract tenant to check for tenant-specific bot detection override
-		tenantName, found := h.extractTenantFromPath(r.URL.Path)
-		var tenant *config.Tenant
-		if found {
-			// Find the tenant config
-			for i := range h.config.Applications.Tenants {
-				if h.config.Applications.Tenants[i].Name == tenantName || h.config.Applications.Tenants[i].Path == tenantName {
-					tenant = &h.config.Applications.Tenants[i]
-					break
-				}
-			}
-		}
+	// A panic is answered with a 500 and reported before the request is logged
+	p := &pipelineRequest{recorder: recorder, r: r, requestID: requestID}
+	defer h.recoverPanic(p)
 
-		// Check if this bot should be blocked
-		if h.shouldBlockBot(r, tenant) {
-			recorder.SetMetadata("response_type", "bot-blocked")
-			http.Error(w, "Forbidden: Bot access not allowed", http.StatusForbidden)
-			return
-		}
+	runChain(h.stages(), p)
+}
 
-		h.handleWebAppProxy(recorder, r)
-	} else {
-		// No tenants configured - check for static fallback
-		h.staticHandler.ServeFallback(recorder, r)
+// isLocalhostRequest reports whether a request came from the loopback interface
+func isLocalhostRequest(r *http.Request) bool {
+	remoteAddr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
 	}
+	return remoteAddr == "127.0.0.1" || remoteAddr == "::1" || remoteAddr == "localhost"
 }
 
 // handleHealthCheck handles the health check endpoint
@@ -260,21 +227,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	// If synthetic response is configured, use it
 	if h.config.Server.HealthCheck.Response != nil {
-		resp := h.config.Server.HealthCheck.Response
-
-		// Set custom headers
-		for key, value := range resp.Headers {
-			w.Header().Set(key, value)
-		}
-
-		// Set default content type if not specified
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", "text/plain")
-		}
-
-		// Write status and body
-		w.WriteHeader(resp.Status)
-		_, _ = w.Write([]byte(resp.Body))
+		writeHealthCheckResponse(w, h.config.Server.HealthCheck.Response)
 		return
 	}
 
@@ -283,31 +236,41 @@ func (h *Handler) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	h.handleWebAppProxy(w, r)
 }
 
+// writeHealthCheckResponse writes a synthetic health check response
+func writeHealthCheckResponse(w http.ResponseWriter, resp *config.HealthCheckResponse) {
+	// Set custom headers
+	fly := utils.Fly()
+	for key, value := range resp.Headers {
+		w.Header().Set(key, fly.Expand(value))
+	}
+
+	// Set default content type if not specified
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/plain")
+	}
+
+	// Write status and body
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write([]byte(fly.Expand(resp.Body)))
+}
+
 // handleRewrites processes rewrite rules
 func (h *Handler) handleRewrites(w http.ResponseWriter, r *http.Request) bool {
-	for _, rule := range h.config.Server.RewriteRules {
-		if !rule.Pattern.MatchString(r.URL.Path) {
+	for i := range h.config.Server.RewriteRules {
+		rule := &h.config.Server.RewriteRules[i]
+		if !rewriteRuleApplies(rule, r) {
 			continue
 		}
 
-		// Check method restrictions
-		if len(rule.Methods) > 0 {
-			allowed := false
-			for _, method := range rule.Methods {
-				if r.Method == method {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				continue
-			}
+		if trace := tracing(w); trace != nil {
+			trace.step().Rule = rule.Pattern.String()
+			trace.step().Detail = rule.Flag
 		}
 
 		// Handle different rewrite flags
 		switch {
 		case rule.Flag == "redirect":
-			newPath := rule.Pattern.ReplaceAllString(r.URL.Path, rule.Replacement)
+			newPath := rewriteTarget(rule, r.URL.Path)
 			http.Redirect(w, r, newPath, http.StatusFound)
 			return true
 
@@ -318,6 +281,20 @@ func (h *Handler) handleRewrites(w http.ResponseWriter, r *http.Request) bool {
 				target := parts[1]
 				status := parts[2]
 
+				// Verified targets are checked per request; a trace never probes them
+				if trace := tracing(w); trace != nil {
+					return trace.dispose("fly-replay", target, 0)
+				}
+
+				// Skip the replay (handling the request locally) if the target is down
+				if rule.Verify != nil {
+					verified, ok := verifyReplayTarget(target, rule.Verify)
+					if !ok {
+						continue
+					}
+					target = verified
+				}
+
 				// Use the full fly-replay implementation
 				return HandleFlyReplay(w, r, target, status, h.config)
 			}
@@ -325,7 +302,7 @@ func (h *Handler) handleRewrites(w http.ResponseWriter, r *http.Request) bool {
 
 		case rule.Flag == "last":
 			// Internal rewrite
-			r.URL.Path = rule.Pattern.ReplaceAllString(r.URL.Path, rule.Replacement)
+			r.URL.Path = rewriteTarget(rule, r.URL.Path)
 			// Continue processing with new path
 		}
 	}
@@ -333,6 +310,65 @@ func (h *Handler) handleRewrites(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+// rewrittenRequest returns r as the internal rewrites will leave it, so it
+// can be authenticated for the route that will serve it. Rewriting stops at
+// the first redirect or fly-replay, which answers r on the path it has then.
+func (h *Handler) rewrittenRequest(r *http.Request) *http.Request {
+	rewritten := r
+	for i := range h.config.Server.RewriteRules {
+		rule := &h.config.Server.RewriteRules[i]
+		if !rewriteRuleApplies(rule, rewritten) {
+			continue
+		}
+		if rule.Flag != "last" {
+			break
+		}
+		if rewritten == r {
+			clone, u := *r, *r.URL
+			clone.URL = &u
+			rewritten = &clone
+		}
+		rewritten.URL.Path = rewriteTarget(rule, rewritten.URL.Path)
+	}
+	return rewritten
+}
+
+// rewriteTarget applies a rewrite rule's replacement to path, after
+// substituting $fly_region and $fly_machine
+func rewriteTarget(rule *config.RewriteRule, path string) string {
+	replacement := rule.Replacement
+	if strings.Contains(replacement, "$fly_") {
+		fly := utils.Fly()
+		escaped := utils.FlyInstance{
+			Region:    strings.ReplaceAll(fly.Region, "$", "$$"),
+			MachineID: strings.ReplaceAll(fly.MachineID, "$", "$$"),
+		}
+		replacement = escaped.Expand(replacement)
+	}
+	return rule.Pattern.ReplaceAllString(path, replacement)
+}
+
+// rewriteRuleApplies reports whether a rewrite rule matches the request's
+// path, method, and conditions
+func rewriteRuleApplies(rule *config.RewriteRule, r *http.Request) bool {
+	if !rule.Pattern.MatchString(r.URL.Path) {
+		return false
+	}
+	if len(rule.Methods) > 0 {
+		allowed := false
+		for _, method := range rule.Methods {
+			if r.Method == method {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return rewriteConditionsMatch(rule, r)
+}
+
 // findBestLocation removed - use Routes.ReverseProxies instead
 // serveStaticFile removed - use staticHandler.ServeStatic instead
 // tryFiles removed - use staticHandler.TryFiles instead
@@ -342,62 +378,78 @@ func (h *Handler) handleRewrites(w http.ResponseWriter, r *http.Request) bool {
 // Priority: tenant-specific > global applications config > default
 func (h *Handler) getStartupTimeout(tenant *config.Tenant) time.Duration {
 	// 1. Check tenant-specific override
-	if tenant != nil && tenant.StartupTimeout != "" {
-		timeout := utils.ParseDurationWithContext(tenant.StartupTimeout, 0, map[string]interface{}{
-			"tenant": tenant.Name,
-			"type":   "startup_timeout",
-		})
-		if timeout > 0 {
-			return timeout
-		}
-	}
-
-	// 2. Check global applications config
-	if h.config.Applications.StartupTimeout != "" {
-		timeout := utils.ParseDurationWithDefault(h.config.Applications.StartupTimeout, 0)
-		if timeout > 0 {
-			return timeout
-		}
+	if tenant != nil && tenant.StartupTimeout > 0 {
+		return tenant.StartupTimeout.Std()
 	}
 
-	// 3. Use default
-	return config.DefaultStartupTimeout
-}
-
-// extractTenantFromPath extracts the tenant name from the URL path
-// Returns (tenantName, found) where found indicates if a tenant was matched
-func (h *Handler) extractTenantFromPath(path string) (string, bool) {
-	// Find the longest matching tenant path (most specific match)
-	var bestMatch string
-	bestMatchLen := 0
-	found := false
-
-	for _, tenant := range h.config.Applications.Tenants {
-		if strings.HasPrefix(path, tenant.Path) && len(tenant.Path) > bestMatchLen {
-			bestMatch = tenant.Name
-			bestMatchLen = len(tenant.Path)
-			found = true
-		}
-	}
-	return bestMatch, found
+	// 2. Check global applications config, then 3. use default
+	return h.config.Applications.StartupTimeout.OrDefault(config.DefaultStartupTimeout)
 }
 
 // handleWebAppProxy proxies requests to web applications
 func (h *Handler) handleWebAppProxy(w http.ResponseWriter, r *http.Request) {
 	recorder := w.(*ResponseRecorder)
 
-	// Extract tenant name from path
-	tenantName, found := h.extractTenantFromPath(r.URL.Path)
+	// Secondary workers hand tenant requests to the primary worker
+	if forwardToPrimary(w, r) {
+		return
+	}
 
-	logging.LogTenantExtraction(tenantName, found, r.URL.Path)
+	// Find the tenant from the path
+	tenant := h.config.TenantForPath(r.URL.Path)
+	var tenantName string
+	if tenant != nil {
+		tenantName = tenant.Name
+	}
 
-	if !found {
+	logging.LogTenantExtraction(tenantName, tenant != nil, r.URL.Path)
+
+	if tenant == nil {
 		http.NotFound(w, r)
 		return
 	}
 
+	// Cached responses are served without starting the tenant; warmers
+	// always reach the app
+	if !recorder.warming && serveFromResponseCache(recorder, r, tenant.Cache, tenantName) {
+		recorder.SetMetadata("tenant", tenantName)
+		return
+	}
+
+	// Refuse headers too large to forward before starting the tenant
+	if rejectOversizedHeaders(w, r, tenantName, h.headerLimit(tenant.MaxHeaderBytes)) {
+		recorder.SetMetadata("tenant", tenantName)
+		return
+	}
+
 	// Get or start the web app
-	app, err := h.appManager.GetOrStartApp(tenantName)
+	var app *process.WebApp
+	var coldStart bool
+	var err error
+	if recorder.warming {
+		app, err = h.warmingApp(tenantName, recorder.keepWarm)
+	} else {
+		app, coldStart, err = h.appManager.GetOrStartAppForRequest(tenantName)
+	}
+	if errors.Is(err, errAppNotRunning) {
+		recorder.SetMetadata("tenant", tenantName)
+		recorder.SetMetadata("response_type", "warmer_skipped")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, process.ErrStartGuardHeld) {
+		serveStartGuardHeld(w, recorder, tenantName, err)
+		return
+	}
+	if errors.Is(err, process.ErrTenantRootUnavailable) {
+		// The root may be on a volume that's coming back
+		recorder.SetMetadata("tenant", tenantName)
+		recorder.SetMetadata("response_type", "root_unavailable")
+		recorder.SetMetadata("error_message", err.Error())
+		w.Header().Set("Retry-After", strconv.Itoa(config.RootUnavailableRetryAfter))
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		recorder.SetMetadata("response_type", "error")
 		recorder.SetMetadata("error_message", err.Error())
@@ -405,6 +457,83 @@ func (h *Handler) handleWebAppProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Identical requests arriving while the tenant starts may share one backend request
+	coalesce := h.config.Applications.Coalesce
+	var coalesceWith string
+	if canCoalesce(r, coalesce) && !isAppReady(app) && app.Tenant.ResponseFilter == nil && app.Tenant.Sendfile == nil {
+		coalesceWith = coalesceKey(r, coalesce.VaryHeaders)
+	}
+
+	if !h.awaitApp(w, r, recorder, tenantName, app) {
+		return
+	}
+
+	// Wait for a slot if the tenant limits concurrent requests
+	if !h.acquireTenantSlot(recorder, r, app, tenantName) {
+		return
+	}
+
+	// Set metadata for logging
+	recorder.SetMetadata("tenant", tenantName)
+	recorder.SetMetadata("response_type", "proxy")
+	recorder.SetMetadata("proxy_backend", fmt.Sprintf("tenant:%s", tenantName))
+	markColdStart(recorder, r, coldStart, app.BootTime())
+	if coldStart {
+		markStartupQueued(recorder, app)
+	}
+	tenantErrors.observe(tenantName, app.StartTime)
+
+	// Count the request so recycling the app drains it first
+	if !proxy.IsWebSocketRequest(r) {
+		defer h.appManager.RequestStarted(tenantName, app)()
+	}
+
+	// Register WebSocket connections so a failover to a standby can close them
+	if proxy.IsWebSocketRequest(r) {
+		recorder.onHijack = func(conn net.Conn) {
+			connID := fmt.Sprintf("%p", conn)
+			app.RegisterWebSocketConnection(connID, conn)
+			recorder.releaseWhenDone(func() { app.UnregisterWebSocketConnection(connID) })
+		}
+	}
+
+	// Determine if WebSocket tracking is enabled for this tenant
+	var wsPtr *int32
+	if app.ShouldTrackWebSockets(h.config.Applications.TrackWebSockets) {
+		wsPtr = app.GetActiveWebSocketsPtr()
+		if proxy.IsWebSocketRequest(r) {
+			r = r.WithContext(proxy.WithFrameActivity(r.Context(), trackWebSocketActivity(recorder, app)))
+		}
+	}
+
+	// Proxy to the web app with retry support and optional WebSocket tracking
+	app.NoteRequest(r.Header.Get(config.HeaderRequestID))
+	targetURL := fmt.Sprintf("http://localhost:%d", app.Port)
+	if coalesceWith != "" {
+		proxyCoalesced(recorder, r, coalesce, coalesceWith, tenantName, targetURL)
+		return
+	}
+	if sendfile := app.Tenant.Sendfile; sendfile != nil && !proxy.IsWebSocketRequest(r) {
+		setSendfileRequestHeaders(r, sendfile)
+		sw := newSendfileWriter(w, r, sendfile, h.config.Server.Static.MIMETypes, "tenant "+tenantName)
+		defer sw.finish()
+		w = sw
+	}
+	if filter := app.Tenant.ResponseFilter; filter != nil && !proxy.IsWebSocketRequest(r) {
+		fw := newFilterWriter(w, r, filter, "tenant "+tenantName)
+		defer fw.finish()
+		w = fw
+	}
+	proxy.ProxyToApp(w, r, targetURL, wsPtr, func(w http.ResponseWriter, r *http.Request, err error) bool {
+		return h.retryCrashedApp(w, r, recorder, tenantName, app, err)
+	})
+}
+
+// awaitApp waits for app to finish starting, up to the tenant's startup
+// timeout, and reports whether the request can be proxied to it. Otherwise
+// the request has been answered: the client went away, the start failed, or
+// the app isn't healthy.
+func (h *Handler) awaitApp(w http.ResponseWriter, r *http.Request, recorder *ResponseRecorder, tenantName string, app *process.WebApp) bool {
 	// Determine startup timeout (tenant-specific override, then global, then default)
 	startupTimeout := h.getStartupTimeout(app.Tenant)
 
@@ -417,45 +546,145 @@ func (h *Handler) handleWebAppProxy(w http.ResponseWriter, r *http.Request) {
 			recorder.SetMetadata("tenant", tenantName)
 			recorder.SetMetadata("response_type", "client_closed")
 			w.WriteHeader(499) // Use nginx convention for client closed connection
-			return
+			return false
 		}
-		// Client still connected, continue with proxy
+		// A start queued behind a startup slot found its guard held
+		if err := app.StartError(); errors.Is(err, process.ErrStartGuardHeld) {
+			serveStartGuardHeld(w, recorder, tenantName, err)
+			return false
+		}
+		// Client still connected, continue with proxy unless the app isn't
+		// healthy: it didn't answer during startup, crashed, or is stopping
+		if !app.AcceptsRequests() {
+			logging.LogAppNotAcceptingRequests(tenantName, string(app.State()))
+			recorder.SetMetadata("tenant", tenantName)
+			recorder.SetMetadata("response_type", "maintenance")
+			recorder.SetMetadata("app_state", string(app.State()))
+			ServeMaintenancePage(w, r, h.config)
+			return false
+		}
+	case <-r.Context().Done():
+		// Client closed connection while the app started
+		recorder.SetMetadata("tenant", tenantName)
+		recorder.SetMetadata("response_type", "client_closed")
+		w.WriteHeader(499)
+		return false
 	case <-time.After(startupTimeout):
 		// Timeout waiting for app to be ready, serve maintenance page
 		logging.LogAppStartupTimeout(tenantName, startupTimeout)
 		recorder.SetMetadata("tenant", tenantName)
 		recorder.SetMetadata("response_type", "maintenance")
+		markStartupQueued(recorder, app)
 		ServeMaintenancePage(w, r, h.config)
-		return
+		return false
 	}
+	return true
+}
 
-	// Set metadata for logging
+// serveStartGuardHeld answers a request for a tenant that isn't started
+// because it's running elsewhere: starting it here could corrupt its data
+func serveStartGuardHeld(w http.ResponseWriter, recorder *ResponseRecorder, tenantName string, err error) {
 	recorder.SetMetadata("tenant", tenantName)
-	recorder.SetMetadata("response_type", "proxy")
-	recorder.SetMetadata("proxy_backend", fmt.Sprintf("tenant:%s", tenantName))
+	recorder.SetMetadata("response_type", "start_guard")
+	recorder.SetMetadata("error_message", err.Error())
+	w.Header().Set("Retry-After", strconv.Itoa(config.StartGuardRetryAfter))
+	http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+}
 
-	// Determine if WebSocket tracking is enabled for this tenant
-	var wsPtr *int32
-	if app.ShouldTrackWebSockets(h.config.Applications.TrackWebSockets) {
-		wsPtr = app.GetActiveWebSocketsPtr()
+// retryCrashedApp handles a request refused by a tenant's port, or reset by
+// a process that has exited: the app has crashed. If it's restarted
+// (restart_on_crash), an idempotent request is retried once against the new
+// instance, waiting for it as a cold start would; otherwise the request
+// fails with 502. A reset from an app that's still running isn't a crash,
+// and is left to the caller.
+func (h *Handler) retryCrashedApp(w http.ResponseWriter, r *http.Request, recorder *ResponseRecorder, tenantName string, app *process.WebApp, err error) bool {
+	if proxy.IsConnectionReset(err) && !app.WaitExited(config.CrashExitWait) {
+		return false
 	}
 
-	// Proxy to the web app with retry support and optional WebSocket tracking
-	targetURL := fmt.Sprintf("http://localhost:%d", app.Port)
-	proxy.ProxyWithWebSocketSupport(w, r, targetURL, wsPtr)
+	restarted := h.appManager.RecoverCrashedApp(tenantName, app, err)
+	if restarted == nil || !isIdempotentRequest(r) {
+		recorder.SetMetadata("error_message", err.Error())
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return true
+	}
+
+	if h.awaitApp(w, r, recorder, tenantName, restarted) {
+		proxy.ProxyWithWebSocketSupport(w, r, fmt.Sprintf("http://localhost:%d", restarted.Port), nil)
+	}
+	return true
+}
+
+// isIdempotentRequest reports whether a request can be sent again: an
+// idempotent method without a body to replay
+func isIdempotentRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return r.Body == nil || r.Body == http.NoBody
+	}
+	return false
+}
+
+// errAppNotRunning refuses a warming request for a tenant whose app isn't
+// running
+var errAppNotRunning = errors.New("app not running")
+
+// warmingApp returns the running app a warmer's request is proxied to.
+// Warmers never start an app; only those with keep_warm count as activity.
+func (h *Handler) warmingApp(tenantName string, keepWarm bool) (*process.WebApp, error) {
+	app, running := h.appManager.GetApp(tenantName)
+	if !running {
+		return nil, errAppNotRunning
+	}
+	if keepWarm {
+		app, _, err := h.appManager.GetOrStartAppForRequest(tenantName)
+		return app, err
+	}
+	return app, nil
+}
+
+// isAppReady reports whether the app has finished starting
+func isAppReady(app *process.WebApp) bool {
+	select {
+	case <-app.ReadyChan():
+		return true
+	default:
+		return false
+	}
 }
 
 // ResponseRecorder wraps http.ResponseWriter to capture response details
 type ResponseRecorder struct {
 	http.ResponseWriter
 	statusCode  int
+	wroteHeader bool // The status has been sent to the client
 	size        int
 	startTime   time.Time
 	metadata    map[string]interface{}
 	idleManager *idle.Manager
 	tracked     bool
-	disableLog  bool // When true, suppresses access log output
+	requestKind idle.RequestKind // How this request counts toward idle activity
+	disableLog  bool             // When true, suppresses access log output
 	request     *http.Request
+	capture     *bodyCapture     // Non-nil only for requests matching logging.capture
+	flight      *flightRecording // Non-nil only when logging.flight_recorder is enabled
+	cacheFill   *cacheFill       // Non-nil only for response cache misses that may be stored
+	onDone      []func()         // Run once the request, and any hijacked connection, is done
+	onHijack    func(net.Conn)   // Called with the connection once it is hijacked
+
+	warming  bool // Sent by a tenant's warmer
+	keepWarm bool // Sent by a warmer with keep_warm, so it counts as activity
+
+	trace *RouteTrace // Non-nil only for requests being explained rather than served
+
+	disconnected atomic.Int64 // Nanoseconds after the start at which the client went away, if it did
+
+	// Hijacked connections are logged when both the handler has returned and
+	// the connection has closed, whichever happens last
+	hijackMu     sync.Mutex
+	hijacked     *countingConn
+	handlerDone  bool
+	hijackClosed bool
 }
 
 // NewResponseRecorder creates a new response recorder
@@ -480,13 +709,27 @@ func NewTestResponseRecorder(w http.ResponseWriter, idleManager *idle.Manager, r
 // WriteHeader captures the status code
 func (r *ResponseRecorder) WriteHeader(code int) {
 	r.statusCode = code
+	r.wroteHeader = r.wroteHeader || code >= http.StatusOK
+	if r.flight != nil {
+		r.flight.wroteHeader(r.startTime)
+	}
 	r.ResponseWriter.WriteHeader(code)
 }
 
 // Write captures the response size and logs incomplete writes
 func (r *ResponseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	if r.flight != nil {
+		r.flight.wroteHeader(r.startTime)
+	}
 	n, err := r.ResponseWriter.Write(data)
 	r.size += n
+	if r.capture != nil && n > 0 {
+		r.capture.writeResponse(r.Header(), data[:n])
+	}
+	if r.cacheFill != nil {
+		r.cacheFill.write(data[:n], err == nil && n == len(data))
+	}
 
 	// Log partial writes or errors (only when write fails or is incomplete)
 	if err != nil || n < len(data) {
@@ -517,26 +760,102 @@ func (r *ResponseRecorder) Write(data []byte) (int, error) {
 	return n, err
 }
 
+// Flush sends buffered response data to the client, so streamed responses
+// such as CGI output arrive as they're written
+func (r *ResponseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // SetMetadata sets metadata for logging
 func (r *ResponseRecorder) SetMetadata(key string, value interface{}) {
 	r.metadata[key] = value
 }
 
-// Hijack implements the http.Hijacker interface for WebSocket support
+// Hijack implements the http.Hijacker interface for WebSocket support. The
+// returned connection counts bytes in each direction; the access log entry is
+// deferred until it closes.
 func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hijacker, ok := r.ResponseWriter.(http.Hijacker); ok {
 		conn, rw, err := hijacker.Hijack()
-		if err == nil {
-			// WebSocket connection hijacked successfully
-			// Finish tracking the HTTP request since it's now handled by WebSocket
-			logging.LogWebSocketHijacked()
-			r.finishTracking()
+		if err != nil {
+			return conn, rw, err
+		}
+
+		// WebSocket connection hijacked successfully
+		// Finish tracking the HTTP request since it's now handled by WebSocket
+		logging.LogWebSocketHijacked()
+		r.finishTracking()
+
+		counted := newCountingConn(conn, r.connectionClosed)
+		r.hijackMu.Lock()
+		r.hijacked = counted
+		r.statusCode = http.StatusSwitchingProtocols
+		r.metadata["response_type"] = "websocket"
+		r.hijackMu.Unlock()
+		if r.onHijack != nil {
+			r.onHijack(counted)
 		}
-		return conn, rw, err
+		return counted, rw, nil
 	}
 	return nil, nil, fmt.Errorf("ResponseWriter does not support hijacking")
 }
 
+// connectionClosed is called once when a hijacked connection closes
+func (r *ResponseRecorder) connectionClosed() {
+	r.hijackMu.Lock()
+	r.hijackClosed = true
+	ready := r.handlerDone
+	r.hijackMu.Unlock()
+
+	if ready {
+		r.logHijacked()
+		r.runOnDone()
+	}
+}
+
+// releaseWhenDone registers fn to run when the request is done. For a
+// hijacked connection that is when both the handler has returned and the
+// connection has closed.
+func (r *ResponseRecorder) releaseWhenDone(fn func()) {
+	r.onDone = append(r.onDone, fn)
+}
+
+func (r *ResponseRecorder) runOnDone() {
+	for _, fn := range r.onDone {
+		fn()
+	}
+}
+
+// logHijacked writes the access log entry for a hijacked connection
+func (r *ResponseRecorder) logHijacked() {
+	bytesIn, bytesOut := r.hijacked.counts()
+	r.metadata["bytes_received"] = bytesIn
+	LogRequest(r.request, r.statusCode, r.size+int(bytesOut), r.startTime, r.metadata, r.disableLog)
+}
+
+// watchDisconnect records when the client goes away, which cancels ctx,
+// before the request is done. The returned function stops watching.
+func (r *ResponseRecorder) watchDisconnect(ctx context.Context) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			r.disconnected.CompareAndSwap(0, int64(time.Since(r.startTime)))
+		}
+	})
+}
+
+// markDisconnected sets client_disconnected and disconnected_after for a
+// request whose client went away before it was done
+func (r *ResponseRecorder) markDisconnected(req *http.Request) {
+	if !errors.Is(req.Context().Err(), context.Canceled) {
+		return
+	}
+	r.disconnected.CompareAndSwap(0, int64(time.Since(r.startTime)))
+	r.metadata["client_disconnected"] = true
+	r.metadata["disconnected_after"] = time.Duration(r.disconnected.Load())
+}
+
 func (r *ResponseRecorder) finishTracking() {
 	if r.idleManager != nil && r.tracked {
 		r.idleManager.RequestFinished()
@@ -555,15 +874,44 @@ func (r *ResponseRecorder) StartTracking() {
 // Finish completes the request and logs it
 func (r *ResponseRecorder) Finish(req *http.Request) {
 	if r.idleManager != nil && r.tracked {
-		r.idleManager.RequestFinished()
+		r.idleManager.RequestCompleted(r.requestKind)
+	}
+
+	r.hijackMu.Lock()
+	r.handlerDone = true
+	hijacked, closed := r.hijacked != nil, r.hijackClosed
+	r.hijackMu.Unlock()
+
+	// Log the request using the access logging module. Hijacked connections
+	// are logged when they close so the entry reflects the whole session.
+	if !hijacked {
+		r.markDisconnected(req)
+		LogRequest(req, r.statusCode, r.size, r.startTime, r.metadata, r.disableLog)
+		tenantErrors.recordResponse(req, r.statusCode, r.metadata)
+		r.runOnDone()
+	} else if closed {
+		r.logHijacked()
+		r.runOnDone()
+	}
+
+	// Write body capture entry if this request matched logging.capture
+	if r.capture != nil {
+		r.capture.finish(req, r.Header(), r.statusCode)
 	}
 
-	// Log the request using the access logging module
-	LogRequest(req, r.statusCode, r.size, r.startTime, r.metadata, r.disableLog)
+	// Keep the request if it failed and the flight recorder is enabled
+	if r.flight != nil && !hijacked {
+		r.flight.finish(req, r.Header(), r.statusCode, r.startTime, r.metadata)
+	}
+
+	// Store the response if this request was a response cache miss
+	if r.cacheFill != nil && !hijacked {
+		r.cacheFill.finish(req, r.statusCode, r.Header())
+	}
 }
 
 // setupCGIHandlers initializes CGI handlers from configuration
-func (h *Handler) setupCGIHandlers(currentConfigFn func() string, configLoadTimeFn func() time.Time, triggerReloadFn func(string)) {
+func (h *Handler) setupCGIHandlers(currentConfigFn func() string, configLoadTimeFn func() time.Time, triggerReloadFn func(utils.ReloadDecision)) {
 	if len(h.config.Server.CGIScripts) == 0 {
 		return
 	}
@@ -584,6 +932,12 @@ func (h *Handler) setupCGIHandlers(currentConfigFn func() string, configLoadTime
 		h.cgiHandlers[scriptCfg.Path] = &cgiRoute{
 			handler: handler,
 			method:  scriptCfg.Method,
+			script:  scriptCfg.Script,
+
+			auth:      scriptCfg.Auth,
+			authScope: scriptCfg.AuthScope,
+
+			decompress: scriptCfg.DecompressRequests,
 		}
 
 		slog.Info("Registered CGI script",
@@ -594,15 +948,11 @@ func (h *Handler) setupCGIHandlers(currentConfigFn func() string, configLoadTime
 	}
 }
 
-// handleCGI handles CGI script requests
-func (h *Handler) handleCGI(w http.ResponseWriter, r *http.Request) bool {
-	if len(h.cgiHandlers) == 0 {
-		return false
-	}
-
+// matchCGI returns the CGI script registered for the request's path and method
+func (h *Handler) matchCGI(r *http.Request) (*cgiRoute, bool) {
 	route, exists := h.cgiHandlers[r.URL.Path]
 	if !exists {
-		return false
+		return nil, false
 	}
 
 	// Check method if specified
@@ -611,11 +961,27 @@ func (h *Handler) handleCGI(w http.ResponseWriter, r *http.Request) bool {
 			"path", r.URL.Path,
 			"expected", route.method,
 			"got", r.Method)
+		return nil, false
+	}
+	return route, true
+}
+
+// handleCGI handles CGI script requests
+func (h *Handler) handleCGI(w http.ResponseWriter, r *http.Request) bool {
+	route, ok := h.matchCGI(r)
+	if !ok {
 		return false
 	}
 
+	if trace := tracing(w); trace != nil {
+		trace.step().Rule = r.URL.Path
+		return trace.dispose("cgi", route.script, 0)
+	}
+
 	// Execute CGI script
-	w.(*ResponseRecorder).SetMetadata("response_type", "cgi")
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "cgi")
+	}
 	route.handler.ServeHTTP(w, r)
 	return true
 }
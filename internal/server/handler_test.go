@@ -634,6 +634,53 @@ func TestHandler_ServeHTTP_RequestID(t *testing.T) {
 	}
 }
 
+func TestHandler_ServeHTTP_RequestIDHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{}
+	cfg.Server.RequestID = config.RequestIDConfig{
+		Header:         "X-Correlation-Id",
+		ForwardHeaders: []string{"X-Navigator-Request-Id"},
+	}
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{{Name: "api", Prefix: "/api/", Target: backend.URL}}
+	handler := CreateTestHandler(cfg, nil, nil, nil)
+
+	tests := []struct {
+		name     string
+		clientID string
+	}{
+		{"Generated", ""},
+		{"Taken from the configured header", "corr-42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/status", nil)
+			req.Header.Set("X-Request-Id", "ignored")
+			req.Header.Set("X-Navigator-Request-Id", "spoofed")
+			if tt.clientID != "" {
+				req.Header.Set("X-Correlation-Id", tt.clientID)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			header := <-received
+			id := header.Get("X-Correlation-Id")
+			if id == "" || (tt.clientID != "" && id != tt.clientID) {
+				t.Fatalf("X-Correlation-Id = %q, want %q or a generated id", id, tt.clientID)
+			}
+			for _, name := range []string{"X-Request-Id", "X-Navigator-Request-Id"} {
+				if got := header.Get(name); got != id {
+					t.Errorf("%s = %q, want %q", name, got, id)
+				}
+			}
+		})
+	}
+}
+
 func TestHandler_ServeHTTP_Authentication(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Auth.Enabled = true
@@ -1296,7 +1343,7 @@ func TestJSONAccessLogging(t *testing.T) {
 	cfg.Server.Static.PublicDir = "public"
 
 	// Create handler with logging enabled (not using CreateTestHandler)
-	handler := CreateHandler(cfg, nil, nil, nil, nil, func() string { return "" }, func() time.Time { return time.Now() }, func(string) {})
+	handler := CreateHandler(cfg, nil, nil, nil, nil, func() string { return "" }, func() time.Time { return time.Now() }, nil)
 
 	// Capture stdout to test JSON log output
 	oldStdout := os.Stdout
@@ -1321,13 +1368,13 @@ func TestJSONAccessLogging(t *testing.T) {
 	// Make request (will return 404 but should log)
 	handler.ServeHTTP(rr, req)
 
+	// Restore access log writer, writing any queued entries to the pipe
+	SetAccessLogWriter(oldStdout)
+
 	// Close writer and restore stdout
 	_ = w.Close()
 	os.Stdout = oldStdout
 
-	// Restore access log writer
-	SetAccessLogWriter(oldStdout)
-
 	// Read captured output
 	output := make([]byte, 1024)
 	n, _ := r.Read(output)
@@ -1858,3 +1905,171 @@ func TestHandler_HandleRewritesMultipleRules(t *testing.T) {
 		})
 	}
 }
+
+// TestHandler_HandleRewritesBotToStatic tests rewriting bots to prerendered pages
+func TestHandler_HandleRewritesBotToStatic(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.RewriteRules = []config.RewriteRule{
+		{
+			Pattern:     regexp.MustCompile(`^/showcase/(.+)$`),
+			Replacement: "/prerendered/showcase/$1.html",
+			Flag:        "last",
+			Conditions: []config.RewriteCondition{
+				{Header: "User-Agent", Matches: "(?i)bot|crawler|spider", Regexp: regexp.MustCompile("(?i)bot|crawler|spider")},
+				{Method: "GET"},
+			},
+		},
+	}
+
+	handler := &Handler{
+		config:        cfg,
+		staticHandler: NewStaticFileHandler(cfg),
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		userAgent    string
+		expectedPath string
+	}{
+		{
+			name:         "Bot is rewritten to the prerendered page",
+			method:       "GET",
+			userAgent:    "Mozilla/5.0 (compatible; Googlebot/2.1)",
+			expectedPath: "/prerendered/showcase/studios.html",
+		},
+		{
+			name:         "Browser reaches the app",
+			method:       "GET",
+			userAgent:    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)",
+			expectedPath: "/showcase/studios",
+		},
+		{
+			name:         "Missing User-Agent reaches the app",
+			method:       "GET",
+			expectedPath: "/showcase/studios",
+		},
+		{
+			name:         "Bot POST fails the method condition",
+			method:       "POST",
+			userAgent:    "Googlebot",
+			expectedPath: "/showcase/studios",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/showcase/studios", nil)
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			} else {
+				req.Header.Del("User-Agent")
+			}
+			recorder := httptest.NewRecorder()
+
+			if handler.handleRewrites(recorder, req) {
+				t.Error("Expected internal rewrite not to return true (should continue processing)")
+			}
+			if req.URL.Path != tt.expectedPath {
+				t.Errorf("Expected path %q, got %q", tt.expectedPath, req.URL.Path)
+			}
+		})
+	}
+}
+
+// TestHandler_HandleRewritesCookieGatedRedirect tests redirects that only
+// apply without a session cookie on a given host
+func TestHandler_HandleRewritesCookieGatedRedirect(t *testing.T) {
+	absent := false
+	cfg := &config.Config{}
+	cfg.Server.RewriteRules = []config.RewriteRule{
+		{
+			Pattern:     regexp.MustCompile(`^/account/(.*)$`),
+			Replacement: "/login?return=/account/$1",
+			Flag:        "redirect",
+			Conditions: []config.RewriteCondition{
+				{Host: "showcase.example.com"},
+				{Cookie: "_session", Present: &absent},
+			},
+		},
+		{
+			Pattern:     regexp.MustCompile(`^/beta/(.*)$`),
+			Replacement: "/preview/$1",
+			Flag:        "redirect",
+			Conditions: []config.RewriteCondition{
+				{Cookie: "channel", Equals: "beta"},
+				{Query: "preview"},
+			},
+		},
+	}
+
+	handler := &Handler{
+		config:        cfg,
+		staticHandler: NewStaticFileHandler(cfg),
+	}
+
+	tests := []struct {
+		name             string
+		host             string
+		path             string
+		cookies          []*http.Cookie
+		expectedLocation string
+	}{
+		{
+			name:             "No session cookie redirects to login",
+			host:             "showcase.example.com:443",
+			path:             "/account/settings",
+			expectedLocation: "/login?return=/account/settings",
+		},
+		{
+			name:    "Session cookie skips the redirect",
+			host:    "showcase.example.com",
+			path:    "/account/settings",
+			cookies: []*http.Cookie{{Name: "_session", Value: "abc"}},
+		},
+		{
+			name: "Other host skips the redirect",
+			host: "admin.example.com",
+			path: "/account/settings",
+		},
+		{
+			name:             "Cookie value and query parameter both match",
+			host:             "showcase.example.com",
+			path:             "/beta/heats?preview=1",
+			cookies:          []*http.Cookie{{Name: "channel", Value: "beta"}},
+			expectedLocation: "/preview/heats",
+		},
+		{
+			name:    "Cookie value differs",
+			host:    "showcase.example.com",
+			path:    "/beta/heats?preview=1",
+			cookies: []*http.Cookie{{Name: "channel", Value: "stable"}},
+		},
+		{
+			name:    "Query parameter missing",
+			host:    "showcase.example.com",
+			path:    "/beta/heats",
+			cookies: []*http.Cookie{{Name: "channel", Value: "beta"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Host = tt.host
+			for _, cookie := range tt.cookies {
+				req.AddCookie(cookie)
+			}
+			recorder := httptest.NewRecorder()
+
+			handled := handler.handleRewrites(recorder, req)
+
+			if handled != (tt.expectedLocation != "") {
+				t.Fatalf("Expected handled=%v, got %v", tt.expectedLocation != "", handled)
+			}
+			if location := recorder.Header().Get("Location"); location != tt.expectedLocation {
+				t.Errorf("Expected Location %q, got %q", tt.expectedLocation, location)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// untrustedRequestHeaders are never accepted from clients: they are either
+// meaningless beyond the first hop or claim to come from a proxy in front
+var untrustedRequestHeaders = []string{"Proxy-Connection", "X-Forwarded-Server"}
+
+// singletonRequestHeaders carry a single value; repeats are dropped so
+// backends don't each pick a different one
+var singletonRequestHeaders = []string{
+	"Authorization",
+	"Content-Type",
+	"From",
+	"If-Modified-Since",
+	"If-Range",
+	"If-Unmodified-Since",
+	"Max-Forwards",
+	"Origin",
+	"Proxy-Authorization",
+	"Range",
+	"Referer",
+	"User-Agent",
+	"X-Request-Id",
+}
+
+// sanitizeRequestHeaders removes headers that must not reach a backend and
+// collapses duplicates that carry no meaning. Headers listed in Connection
+// apply only to the client's hop; the connection tokens themselves are kept
+// so WebSocket upgrades and keep-alive still work.
+func sanitizeRequestHeaders(r *http.Request, strip []string) {
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			name := http.CanonicalHeaderKey(strings.TrimSpace(token))
+			switch name {
+			case "", "Close", "Keep-Alive", "Upgrade":
+				continue
+			}
+			r.Header.Del(name)
+		}
+	}
+
+	for _, name := range untrustedRequestHeaders {
+		r.Header.Del(name)
+	}
+	for _, name := range strip {
+		r.Header.Del(name)
+	}
+
+	for _, name := range singletonRequestHeaders {
+		if values := r.Header[name]; len(values) > 1 {
+			r.Header[name] = values[:1]
+		}
+	}
+
+	// HTTP/2 clients may send each cookie as its own field
+	if cookies := r.Header["Cookie"]; len(cookies) > 1 {
+		r.Header["Cookie"] = []string{strings.Join(cookies, "; ")}
+	}
+}
+
+// forwardedHeaderSize returns the size of the request headers as they are
+// sent to a backend, along with the largest header and its size
+func forwardedHeaderSize(r *http.Request) (total int, largest string, largestSize int) {
+	total = len("Host: \r\n") + len(r.Host)
+	for name, values := range r.Header {
+		size := 0
+		for _, value := range values {
+			size += len(name) + len(value) + len(": \r\n")
+		}
+		total += size
+		if size > largestSize {
+			largest, largestSize = name, size
+		}
+	}
+	return total, largest, largestSize
+}
+
+// headerLimit returns the forwarded header limit for a route or tenant,
+// falling back to the server default
+func (h *Handler) headerLimit(override int) int {
+	if override > 0 {
+		return override
+	}
+	return h.config.Server.RequestHeaders.MaxForwardedBytes
+}
+
+// rejectOversizedHeaders answers 431 when the headers of r would exceed
+// limit bytes once forwarded (0 = unlimited), returning true if it did
+func rejectOversizedHeaders(w http.ResponseWriter, r *http.Request, route string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	total, largest, largestSize := forwardedHeaderSize(r)
+	if total <= limit {
+		return false
+	}
+
+	logging.LogRequestHeadersTooLarge(route, r.URL.Path, total, limit, largest, largestSize)
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "error")
+		recorder.SetMetadata("error_message", "request headers too large: "+largest)
+	}
+	http.Error(w, "Request Header Fields Too Large", http.StatusRequestHeaderFieldsTooLarge)
+	return true
+}
+
+// setRegionHeaders identifies the Fly.io region and machine that served a
+// response; nothing is set off Fly
+func setRegionHeaders(header http.Header) {
+	fly := utils.Fly()
+	if fly.Region != "" {
+		header.Set("X-Navigator-Region", fly.Region)
+	}
+	if fly.MachineID != "" {
+		header.Set("X-Navigator-Machine", fly.MachineID)
+	}
+}
+
+// setRequestID takes the request's ID from the configured header, or
+// generates one, and sets it in that header, X-Request-Id, and each forward
+// header, replacing whatever the client sent in them. Returns the ID.
+func setRequestID(r *http.Request, cfg config.RequestIDConfig) string {
+	header := cfg.Header
+	if header == "" {
+		header = config.HeaderRequestID
+	}
+	requestID := r.Header.Get(header)
+	if requestID == "" {
+		requestID = utils.GenerateRequestID()
+	}
+	r.Header.Set(header, requestID)
+	r.Header.Set(config.HeaderRequestID, requestID)
+	for _, name := range cfg.ForwardHeaders {
+		r.Header.Set(name, requestID)
+	}
+	return requestID
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newHeaderTestHandler proxies /api/ to a backend that records the headers it receives
+func newHeaderTestHandler(t *testing.T, routeLimit int) (http.Handler, *http.Header) {
+	t.Helper()
+	received := &http.Header{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(backend.Close)
+
+	cfg := &config.Config{}
+	cfg.Server.RequestHeaders = config.RequestHeadersConfig{
+		MaxForwardedBytes: 8192,
+		Strip:             []string{"X-Internal-Secret"},
+	}
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{
+		{Name: "api", Prefix: "/api/", Target: backend.URL, MaxHeaderBytes: routeLimit},
+	}
+	return CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{}), received
+}
+
+func TestOversizedHeadersRejected(t *testing.T) {
+	handler, received := newHeaderTestHandler(t, 1024)
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-Large", strings.Repeat("x", 2048))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("Status = %d, want 431", rec.Code)
+	}
+	if len(*received) != 0 {
+		t.Error("Oversized request reached the backend")
+	}
+
+	_, name, size := forwardedHeaderSize(req)
+	if name != "X-Large" || size < 2048 {
+		t.Errorf("Largest header = %s (%d bytes), want X-Large", name, size)
+	}
+}
+
+func TestLargeCookiesUnderLimitForwarded(t *testing.T) {
+	handler, received := newHeaderTestHandler(t, 0) // Server default of 8192
+
+	session := strings.Repeat("s", 4000)
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Add("Cookie", "session="+session)
+	req.Header.Add("Cookie", "theme=dark")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	cookies := received.Values("Cookie")
+	if len(cookies) != 1 || cookies[0] != "session="+session+"; theme=dark" {
+		t.Errorf("Backend received cookies %q, want one joined header", cookies)
+	}
+}
+
+func TestUntrustedHeadersStripped(t *testing.T) {
+	handler, received := newHeaderTestHandler(t, 0)
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("x-internal-secret", "forged")
+	req.Header.Set("X-Forwarded-Server", "trusted-proxy")
+	req.Header.Set("Connection", "keep-alive, X-Hop-Only")
+	req.Header.Set("X-Hop-Only", "1")
+	req.Header.Add("User-Agent", "first")
+	req.Header.Add("User-Agent", "second")
+	req.Header.Set("X-Kept", "yes")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	for _, name := range []string{"X-Internal-Secret", "X-Forwarded-Server", "X-Hop-Only"} {
+		if value := received.Get(name); value != "" {
+			t.Errorf("Backend received %s: %q", name, value)
+		}
+	}
+	if agents := received.Values("User-Agent"); len(agents) != 1 || agents[0] != "first" {
+		t.Errorf("Backend received User-Agent %q, want only the first", agents)
+	}
+	if received.Get("X-Kept") != "yes" {
+		t.Error("Ordinary header was not forwarded")
+	}
+}
+
+func TestSanitizeKeepsUpgradeTokens(t *testing.T) {
+	req := httptest.NewRequest("GET", "/cable", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	sanitizeRequestHeaders(req, nil)
+
+	if req.Header.Get("Upgrade") != "websocket" || req.Header.Get("Connection") != "Upgrade" {
+		t.Errorf("WebSocket upgrade headers were removed: %v", req.Header)
+	}
+}
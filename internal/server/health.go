@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/scheduler"
+	"github.com/rubys/navigator/internal/utils"
+	"github.com/rubys/navigator/internal/warmer"
+)
+
+// BuildInfo identifies the running Navigator binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// DetailedHealth is the readiness report served at server.health_check.detailed_path
+type DetailedHealth struct {
+	Ready bool `json:"ready"`
+	BuildInfo
+	Uptime           float64                  `json:"uptime_seconds"`
+	ConfigHash       string                   `json:"config_sha256,omitempty"`
+	RunningTenants   int                      `json:"running_tenants"`
+	TenantStates     map[process.AppState]int `json:"tenant_states,omitempty"` // Tenant apps in each lifecycle state
+	ManagedProcesses int                      `json:"managed_processes"`       // Managed processes currently running
+	Goroutines       int                      `json:"goroutines"`
+	OpenFDs          int                      `json:"open_fds,omitempty"`               // Omitted where descriptors cannot be counted
+	RSS              int64                    `json:"rss_bytes,omitempty"`              // Omitted where resident memory cannot be read
+	AvailableMemory  int64                    `json:"available_memory_bytes,omitempty"` // Omitted where available memory cannot be read
+
+	LoadShedding  *LoadSheddingStatus `json:"load_shedding,omitempty"`  // Omitted unless server.load_shedding sets a threshold
+	PausedTenants []TenantPauseStatus `json:"paused_tenants,omitempty"` // Tenants paused through server.control_path
+	Mirrors       []MirrorStatus      `json:"mirrors,omitempty"`        // Counts of requests mirrored by reverse proxies and tenants
+	Reloads       *ReloadStatus       `json:"reloads,omitempty"`        // Omitted until the configuration is reloaded
+	Ports         []config.PortUse    `json:"ports,omitempty"`          // Every port the configuration uses, and by whom
+
+	DiskBudget *process.DiskBudgetStatus `json:"disk_budget,omitempty"` // Omitted unless logging.disk_budget sets max_bytes
+	Schedule   []scheduler.TaskStatus    `json:"schedule,omitempty"`    // Scheduled tasks and when they next run
+	Warmers    []warmer.Status           `json:"warmers,omitempty"`     // Tenants' warmers and their requests so far
+
+	Recycles     []process.RecycleRecord `json:"recycles,omitempty"`      // Most recent tenant recycles
+	StartupQueue int                     `json:"startup_queue,omitempty"` // Tenant app startups waiting for a slot
+
+	Fly *utils.FlyInstance `json:"fly,omitempty"` // The Fly.io machine Navigator runs on; omitted elsewhere
+
+	StateVersion uint64 `json:"state_version"` // Increases as tenants, processes, pauses or the configuration change
+}
+
+// healthSources describe the binary and its managed processes
+var healthSources struct {
+	mu        sync.RWMutex
+	build     BuildInfo
+	processes *process.Manager
+	reloads   *ReloadHistory
+}
+
+var (
+	startTime = time.Now()
+	draining  atomic.Bool
+)
+
+// SetBuildInfo records the version reported by the detailed health check
+func SetBuildInfo(info BuildInfo) {
+	healthSources.mu.Lock()
+	defer healthSources.mu.Unlock()
+	healthSources.build = info
+}
+
+// SetManagedProcessSource configures where the detailed health check counts
+// running managed processes
+func SetManagedProcessSource(manager *process.Manager) {
+	healthSources.mu.Lock()
+	defer healthSources.mu.Unlock()
+	healthSources.processes = manager
+	process.BumpStateVersion()
+}
+
+// SetReloadHistory configures where the detailed health check reports
+// configuration reloads
+func SetReloadHistory(history *ReloadHistory) {
+	healthSources.mu.Lock()
+	defer healthSources.mu.Unlock()
+	healthSources.reloads = history
+	process.BumpStateVersion()
+}
+
+// SetDraining marks Navigator as shutting down; while draining, the detailed
+// health check reports ready=false so load balancers stop sending traffic
+func SetDraining(value bool) {
+	draining.Store(value)
+	process.BumpStateVersion()
+}
+
+// detailedHealth reports the current readiness of Navigator
+func (h *Handler) detailedHealth() DetailedHealth {
+	healthSources.mu.RLock()
+	report := DetailedHealth{
+		Ready:      !draining.Load(),
+		BuildInfo:  healthSources.build,
+		Uptime:     time.Since(startTime).Seconds(),
+		ConfigHash: h.config.FileHash,
+		Goroutines: runtime.NumGoroutine(),
+
+		LoadShedding:  loadShedding.status(),
+		PausedTenants: tenantPauses.status(),
+		Mirrors:       mirrors.status(),
+		Reloads:       healthSources.reloads.Status(),
+		Ports:         config.CheckPorts(h.config).Uses,
+
+		DiskBudget: process.DiskBudgetReport(),
+		Schedule:   scheduler.Report(),
+		Warmers:    warmer.Report(),
+	}
+	resources := process.SampleResources()
+	if resources.OpenFDs >= 0 {
+		report.OpenFDs = resources.OpenFDs
+	}
+	if resources.RSS >= 0 {
+		report.RSS = resources.RSS
+	}
+	if resources.AvailableMemory >= 0 {
+		report.AvailableMemory = resources.AvailableMemory
+	}
+	if fly := utils.Fly(); fly != (utils.FlyInstance{}) {
+		report.Fly = &fly
+	}
+	processes := healthSources.processes
+	healthSources.mu.RUnlock()
+
+	if h.appManager != nil {
+		report.Recycles = h.appManager.RecycleHistory()
+		report.StartupQueue = h.appManager.StartupQueueDepth()
+		for _, app := range h.appManager.Status() {
+			if app.State == process.AppHealthy || app.State == process.AppUnhealthy {
+				report.RunningTenants++
+			}
+			if report.TenantStates == nil {
+				report.TenantStates = make(map[process.AppState]int)
+			}
+			report.TenantStates[app.State]++
+		}
+	}
+	if processes != nil {
+		for _, proc := range processes.Status() {
+			if proc.Running {
+				report.ManagedProcesses++
+			}
+		}
+	}
+	return report
+}
+
+// handleDetailedHealthCheck returns the readiness report as JSON, with status
+// 503 once shutdown has begun. Its ETag is a hash of what the report says,
+// so a probe sending it back in If-None-Match gets a 304 until something
+// reported changes, including sources that don't bump the state version,
+// such as schedules, warmers, and pauses whose ttl has passed; counters
+// such as uptime and goroutines aren't part of it.
+func (h *Handler) handleDetailedHealthCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := h.detailedHealth()
+	report.StateVersion = process.StateVersion()
+	etag := report.etag()
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if !draining.Load() && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(report)
+}
+
+// etag returns the report's ETag: its state version, and a hash of the
+// report without the counters that change on every request
+func (report DetailedHealth) etag() string {
+	report.Uptime, report.Goroutines = 0, 0
+	report.OpenFDs, report.RSS, report.AvailableMemory = 0, 0, 0
+	data, _ := json.Marshal(report)
+	hash := fnv.New64a()
+	_, _ = hash.Write(data)
+	return fmt.Sprintf(`W/"state-%d-%016x"`, report.StateVersion, hash.Sum64())
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 specifies for it
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || (candidate != "" && strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
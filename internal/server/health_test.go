@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func TestDetailedHealthCheck(t *testing.T) {
+	SetBuildInfo(BuildInfo{Version: "1.2.3", Commit: "abc123", BuildTime: "2025-01-01"})
+	t.Cleanup(func() { SetBuildInfo(BuildInfo{}) })
+
+	// The endpoint is answered before authentication, like the simple health check
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{FileHash: "d1e8"}
+	cfg.Server.Listen = "127.0.0.1:3000"
+	cfg.Server.HealthCheck.DetailedPath = "/_navigator/health"
+	cfg.Auth = config.AuthConfig{Enabled: true, HTPasswd: htpasswd}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{})
+
+	get := func() (*httptest.ResponseRecorder, DetailedHealth) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_navigator/health", nil))
+		var report DetailedHealth
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+		}
+		return rec, report
+	}
+
+	rec, report := get()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !report.Ready || report.Version != "1.2.3" || report.Commit != "abc123" || report.ConfigHash != "d1e8" {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Uptime <= 0 {
+		t.Errorf("uptime = %v, want a positive duration", report.Uptime)
+	}
+	wantPorts := []config.PortUse{
+		{Port: 3000, LastPort: 3000, Owner: config.PortOwnerNavigator},
+		{Port: 4000, LastPort: 4100, Owner: config.PortOwnerTenantPool},
+	}
+	if !reflect.DeepEqual(report.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", report.Ports, wantPorts)
+	}
+
+	SetDraining(true)
+	t.Cleanup(func() { SetDraining(false) })
+	rec, report = get()
+	if rec.Code != http.StatusServiceUnavailable || report.Ready {
+		t.Errorf("while draining: status = %d, ready = %v; want %d and false", rec.Code, report.Ready, http.StatusServiceUnavailable)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/_navigator/health", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestDetailedHealthReportsReloads(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.HealthCheck.DetailedPath = "/_navigator/health"
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	var history ReloadHistory
+	SetReloadHistory(&history)
+	t.Cleanup(func() { SetReloadHistory(nil) })
+
+	get := func() DetailedHealth {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_navigator/health", nil))
+		var report DetailedHealth
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+		}
+		return report
+	}
+
+	if report := get(); report.Reloads != nil {
+		t.Errorf("reloads = %+v before any reload, want none", report.Reloads)
+	}
+
+	for i := 0; i < reloadHistorySize; i++ {
+		history.Record(ReloadAttempt{Source: "SIGHUP", Outcome: ReloadApplied})
+	}
+	history.Record(ReloadAttempt{Source: "cgi /update", Outcome: ReloadCoalesced})
+	history.SetState(true, true)
+
+	reloads := get().Reloads
+	if reloads == nil || reloads.Reloaded != int64(reloadHistorySize) || reloads.Coalesced != 1 || !reloads.HooksRunning || !reloads.Pending {
+		t.Fatalf("reloads = %+v", reloads)
+	}
+	if len(reloads.History) != reloadHistorySize || reloads.History[reloadHistorySize-1].Source != "cgi /update" {
+		t.Errorf("history = %+v, want the last %d attempts", reloads.History, reloadHistorySize)
+	}
+}
+
+func TestDetailedHealthConditionalGet(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.HealthCheck.DetailedPath = "/_navigator/health"
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/_navigator/health", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	etag := rec.Header().Get("ETag")
+	var report DetailedHealth
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, fmt.Sprintf(`W/"state-%d-`, report.StateVersion)) {
+		t.Fatalf("GET = %d with ETag %q and state_version %d", rec.Code, etag, report.StateVersion)
+	}
+
+	if rec := get(`"other", ` + etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("conditional GET = %d %q, want an empty 304", rec.Code, rec.Body.String())
+	}
+
+	process.BumpStateVersion()
+	rec = get(etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("conditional GET after a change = %d with ETag %q, want 200 and a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// A pause whose ttl passes changes the ETag without a request for the
+	// tenant, or anything else bumping the state version
+	fake := clock.NewFake(time.Now())
+	tenantPauses.clock = fake
+	t.Cleanup(func() {
+		tenantPauses.clock = clock.Real
+		tenantPauses.resume("2025/boston")
+	})
+	tenantPauses.pause(config.Tenant{Name: "2025/boston"}, "", time.Minute, false)
+	etag = get("").Header().Get("ETag")
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("conditional GET while paused = %d, want 304", rec.Code)
+	}
+	fake.Advance(2 * time.Minute)
+	if rec := get(etag); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "paused_tenants") {
+		t.Errorf("conditional GET after the pause expired = %d %q, want 200 without the pause", rec.Code, rec.Body.String())
+	}
+
+	// A draining Navigator always reports its 503
+	SetDraining(true)
+	t.Cleanup(func() { SetDraining(false) })
+	if rec := get(get("").Header().Get("ETag")); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("conditional GET while draining = %d, want 503", rec.Code)
+	}
+}
+
+func TestQuietHealthCheck(t *testing.T) {
+	buf := captureAccessLog(t)
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  health_check:
+    path: /up
+    quiet: true
+    response:
+      status: 200
+      body: OK
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+	handler.(*Handler).disableLog = false
+
+	req := httptest.NewRequest(http.MethodGet, "/up", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "OK" {
+		t.Errorf("GET /up = %d %q", rec.Code, rec.Body.String())
+	}
+	if req.Header.Get("X-Request-Id") != "" {
+		t.Error("a quiet health check was given a request ID")
+	}
+	if entries := parseAccessLog(t, buf); len(entries) != 0 {
+		t.Errorf("access log = %+v, want no entries", entries)
+	}
+
+	if _, err := config.ParseYAML([]byte("server:\n  health_check:\n    path: /up\n    quiet: true\n")); err == nil {
+		t.Error("quiet without a synthetic response should be rejected")
+	}
+}
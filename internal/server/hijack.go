@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// countingConn wraps a hijacked connection to count bytes in each direction
+// and report when it closes
+type countingConn struct {
+	net.Conn
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	once     sync.Once
+	onClose  func()
+}
+
+func newCountingConn(conn net.Conn, onClose func()) *countingConn {
+	return &countingConn{Conn: conn, onClose: onClose}
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.bytesIn.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.bytesOut.Add(int64(n))
+	return n, err
+}
+
+// Close closes the connection and reports it the first time it is called
+func (c *countingConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.onClose)
+	return err
+}
+
+// counts returns the bytes read from and written to the client
+func (c *countingConn) counts() (in, out int64) {
+	return c.bytesIn.Load(), c.bytesOut.Load()
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// mockHijackableRecorder hands out one end of an in-memory pipe on Hijack
+type mockHijackableRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (m *mockHijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return m.conn, bufio.NewReadWriter(bufio.NewReader(m.conn), bufio.NewWriter(m.conn)), nil
+}
+
+// captureAccessLog redirects access log output to a buffer for the test
+func captureAccessLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	SetAccessLogWriter(&buf)
+	t.Cleanup(func() { SetAccessLogWriter(os.Stdout) })
+	return &buf
+}
+
+func parseAccessLog(t *testing.T, buf *bytes.Buffer) []AccessLogEntry {
+	t.Helper()
+	FlushAccessLog()
+	var entries []AccessLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry AccessLogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid access log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestHijackedConnectionLogsOnClose(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	req := httptest.NewRequest("GET", "/cable", nil)
+	recorder := NewResponseRecorder(&mockHijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}, nil, req)
+	recorder.SetMetadata("response_type", "proxy")
+	recorder.SetMetadata("tenant", "2025/boston")
+
+	conn, _, err := recorder.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack failed: %v", err)
+	}
+
+	// Client sends 5 bytes, server replies with 11
+	go func() {
+		_, _ = client.Write([]byte("hello"))
+		_, _ = io.ReadFull(client, make([]byte, 11))
+	}()
+	if _, err := io.ReadFull(conn, make([]byte, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("hello back!")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Handler returns while the connection is still open: no entry yet
+	recorder.Finish(req)
+	FlushAccessLog()
+	if buf.Len() != 0 {
+		t.Fatalf("expected no access log before the connection closes, got %s", buf.String())
+	}
+
+	_ = conn.Close()
+	_ = conn.Close() // Closing twice logs once
+
+	entries := parseAccessLog(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 access log entry, got %d: %s", len(entries), buf.String())
+	}
+	entry := entries[0]
+	if entry.Status != http.StatusSwitchingProtocols {
+		t.Errorf("status = %d, want 101", entry.Status)
+	}
+	if entry.ResponseType != "websocket" {
+		t.Errorf("response_type = %q, want websocket", entry.ResponseType)
+	}
+	if entry.BodyBytesSent != 11 || entry.BytesReceived != 5 {
+		t.Errorf("bytes sent/received = %d/%d, want 11/5", entry.BodyBytesSent, entry.BytesReceived)
+	}
+	if entry.Tenant != "2025/boston" {
+		t.Errorf("tenant = %q, want metadata preserved", entry.Tenant)
+	}
+}
+
+func TestHijackedConnectionClosedBeforeHandlerReturns(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	req := httptest.NewRequest("GET", "/cable", nil)
+	recorder := NewResponseRecorder(&mockHijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}, nil, req)
+
+	// Same wrapping as a tracked tenant WebSocket proxy
+	var active int32 = 1
+	tracker := &proxy.WebSocketTracker{ResponseWriter: recorder, ActiveWebSockets: &active}
+	conn, _, err := tracker.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack failed: %v", err)
+	}
+
+	_ = conn.Close()
+	FlushAccessLog()
+	if buf.Len() != 0 {
+		t.Fatalf("expected access log to wait for the handler, got %s", buf.String())
+	}
+	if got := atomic.LoadInt32(&active); got != 0 {
+		t.Errorf("active WebSockets = %d after close, want 0", got)
+	}
+
+	recorder.Finish(req)
+	entries := parseAccessLog(t, buf)
+	if len(entries) != 1 || entries[0].ResponseType != "websocket" {
+		t.Fatalf("expected a single websocket entry, got %s", buf.String())
+	}
+}
+
+func TestFinishWithoutHijackLogsImmediately(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	recorder := NewResponseRecorder(httptest.NewRecorder(), nil, req)
+	recorder.WriteHeader(http.StatusNoContent)
+	recorder.Finish(req)
+
+	entries := parseAccessLog(t, buf)
+	if len(entries) != 1 || entries[0].Status != http.StatusNoContent {
+		t.Fatalf("expected one 204 entry, got %s", buf.String())
+	}
+}
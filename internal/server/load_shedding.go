@@ -0,0 +1,172 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// loadShedder samples resource usage and, while a server.load_shedding
+// threshold is breached, rejects the configured class of requests. Shedding
+// stops once every reading is back within the recovery fraction of its
+// threshold, so readings hovering at a threshold don't flap.
+type loadShedder struct {
+	mu       sync.RWMutex
+	config   config.LoadSheddingConfig
+	sample   func() process.ResourceUsage
+	readings process.ResourceUsage
+	reasons  []string // Thresholds breached while shedding
+	shedding bool
+	stop     chan struct{}
+	shed     atomic.Int64 // Requests rejected
+}
+
+var loadShedding = &loadShedder{sample: process.SampleResources}
+
+// LoadSheddingStatus is the load shedding state reported by the detailed
+// health check
+type LoadSheddingStatus struct {
+	Active  bool     `json:"active"`
+	Reasons []string `json:"reasons,omitempty"`
+	Shed    int64    `json:"shed_requests"`
+}
+
+// configure replaces the thresholds and restarts sampling; with no
+// thresholds set, sampling stops and nothing is shed
+func (s *loadShedder) configure(cfg config.LoadSheddingConfig) {
+	if cfg.Recovery <= 0 || cfg.Recovery > 1 {
+		cfg.Recovery = config.DefaultLoadSheddingRecovery
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.config = cfg
+	if !cfg.Enabled() {
+		if s.shedding {
+			logging.LogLoadSheddingStopped(s.readings)
+		}
+		s.shedding, s.reasons = false, nil
+		return
+	}
+
+	s.stop = make(chan struct{})
+	go s.run(cfg.Interval.OrDefault(config.DefaultLoadSheddingInterval), s.stop)
+}
+
+// run samples resources until stopped
+func (s *loadShedder) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check takes a reading and starts or stops shedding
+func (s *loadShedder) check() {
+	readings := s.sample()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readings = readings
+	if !s.shedding {
+		if breached := breachedThresholds(s.config, readings, 1); len(breached) > 0 {
+			s.shedding, s.reasons = true, breached
+			logging.LogLoadSheddingStarted(breached, readings)
+		}
+		return
+	}
+	s.reasons = breachedThresholds(s.config, readings, s.config.Recovery)
+	if len(s.reasons) == 0 {
+		s.shedding = false
+		logging.LogLoadSheddingStopped(readings)
+	}
+}
+
+// breachedThresholds names the thresholds a reading is beyond, with each
+// threshold scaled toward safety by scale. Readings that couldn't be taken
+// never breach.
+func breachedThresholds(cfg config.LoadSheddingConfig, r process.ResourceUsage, scale float64) []string {
+	var breached []string
+	if cfg.MaxRSS > 0 && r.RSS >= 0 && float64(r.RSS) > float64(cfg.MaxRSS)*scale {
+		breached = append(breached, "max_rss")
+	}
+	if cfg.MinAvailableMemory > 0 && r.AvailableMemory >= 0 && float64(r.AvailableMemory) < float64(cfg.MinAvailableMemory)/scale {
+		breached = append(breached, "min_available_memory")
+	}
+	if cfg.MaxOpenFDs > 0 && r.OpenFDs >= 0 && float64(r.OpenFDs) > float64(cfg.MaxOpenFDs)*scale {
+		breached = append(breached, "max_open_fds")
+	}
+	if cfg.MaxGoroutines > 0 && float64(r.Goroutines) > float64(cfg.MaxGoroutines)*scale {
+		breached = append(breached, "max_goroutines")
+	}
+	return breached
+}
+
+// sheds reports whether a request belongs to the class rejected while shedding
+func (s *loadShedder) sheds(r *http.Request, authenticated bool) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.shedding {
+		return false
+	}
+	switch s.config.Shed {
+	case config.ShedAll:
+		return true
+	case config.ShedPaths:
+		for _, prefix := range s.config.Paths {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return true
+			}
+		}
+		return false
+	default:
+		return !authenticated
+	}
+}
+
+// status reports the shedding state, or nil when load shedding isn't configured
+func (s *loadShedder) status() *LoadSheddingStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.config.Enabled() {
+		return nil
+	}
+	return &LoadSheddingStatus{Active: s.shedding, Reasons: s.reasons, Shed: s.shed.Load()}
+}
+
+// shedLoad rejects a request with 503 while resources are exhausted and the
+// request is of the class being shed. Static files are still served, since
+// they are cheap. Returns true if a response was written.
+func (h *Handler) shedLoad(recorder *ResponseRecorder, r *http.Request, authenticated, isPublic bool) bool {
+	if !loadShedding.sheds(r, authenticated) {
+		return false
+	}
+	if h.staticHandler.ServeStatic(recorder, r) || (isPublic && h.staticHandler.TryFiles(recorder, r)) {
+		recorder.requestKind = idle.RequestStatic
+		return true
+	}
+
+	loadShedding.shed.Add(1)
+	recorder.SetMetadata("response_type", "load_shedding")
+	recorder.Header().Set("Retry-After", strconv.Itoa(config.LoadSheddingRetryAfter))
+	http.Error(recorder, "Service Unavailable", http.StatusServiceUnavailable)
+	return true
+}
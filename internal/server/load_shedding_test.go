@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// useLoadShedder replaces the shared shedder with one reading from a fake sampler
+func useLoadShedder(t *testing.T, cfg config.LoadSheddingConfig, readings *process.ResourceUsage) *loadShedder {
+	shedder := &loadShedder{config: cfg, sample: func() process.ResourceUsage { return *readings }}
+	previous := loadShedding
+	loadShedding = shedder
+	t.Cleanup(func() { loadShedding = previous })
+	return shedder
+}
+
+func TestLoadSheddingHysteresis(t *testing.T) {
+	readings := process.ResourceUsage{RSS: -1, AvailableMemory: 900 << 20, OpenFDs: 100, Goroutines: 50}
+	shedder := useLoadShedder(t, config.LoadSheddingConfig{
+		MinAvailableMemory: 256 << 20,
+		MaxGoroutines:      1000,
+		Recovery:           0.9,
+	}, &readings)
+
+	steps := []struct {
+		goroutines int
+		available  int64
+		shedding   bool
+	}{
+		{500, 900 << 20, false},
+		{1001, 900 << 20, true}, // Over max_goroutines
+		{950, 900 << 20, true},  // Below the threshold, but not within recovery
+		{850, 200 << 20, true},  // Goroutines recovered, memory now short
+		{850, 270 << 20, true},  // Above min_available_memory, but not by enough
+		{850, 300 << 20, false}, // Both recovered
+		{950, 300 << 20, false}, // Within the threshold again
+	}
+	for i, step := range steps {
+		readings.Goroutines, readings.AvailableMemory = step.goroutines, step.available
+		shedder.check()
+		if status := shedder.status(); status.Active != step.shedding {
+			t.Errorf("step %d: shedding = %v (reasons %v), want %v", i, status.Active, status.Reasons, step.shedding)
+		}
+	}
+}
+
+func TestLoadSheddingRequests(t *testing.T) {
+	publicDir := t.TempDir()
+	for _, name := range []string{"app.css", "signup/app.css"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(publicDir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(publicDir, name), []byte("body {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = publicDir
+	cfg.Server.HealthCheck.Path = "/up"
+	cfg.Server.HealthCheck.Response = &config.HealthCheckResponse{Status: http.StatusOK, Body: "OK"}
+	cfg.Server.HealthCheck.DetailedPath = "/_navigator/health"
+	cfg.Auth = config.AuthConfig{Enabled: true, HTPasswd: htpasswd, PublicPaths: []string{"/signup/"}}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{})
+
+	readings := process.ResourceUsage{RSS: -1, AvailableMemory: -1, OpenFDs: 5000, Goroutines: 50}
+	shedder := useLoadShedder(t, config.LoadSheddingConfig{MaxOpenFDs: 4000, Recovery: 0.9, Shed: config.ShedUnauthenticated}, &readings)
+	shedder.check()
+
+	get := func(path string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authenticated {
+			req.SetBasicAuth("user", "password")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/signup/", false)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Unauthenticated request: status = %d, Retry-After = %q; want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	for path, authenticated := range map[string]bool{"/up": false, "/signup/app.css": false, "/app.css": true, "/studios/": true} {
+		if rec := get(path, authenticated); rec.Code == http.StatusServiceUnavailable {
+			t.Errorf("%s was shed", path)
+		}
+	}
+
+	// The detailed health check reports the readings and the shedding state
+	var report DetailedHealth
+	if err := json.Unmarshal(get("/_navigator/health", false).Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid health report: %v", err)
+	}
+	if report.LoadShedding == nil || !report.LoadShedding.Active || report.LoadShedding.Shed != 1 || report.LoadShedding.Reasons[0] != "max_open_fds" {
+		t.Errorf("load_shedding = %+v, want active for max_open_fds with one request shed", report.LoadShedding)
+	}
+
+	// Shedding by path applies to authenticated requests too
+	shedder.config.Shed, shedder.config.Paths = config.ShedPaths, []string{"/studios/"}
+	if rec := get("/studios/", true); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/studios/ status = %d, want 503", rec.Code)
+	}
+	if rec := get("/signup/", false); rec.Code == http.StatusServiceUnavailable {
+		t.Error("/signup/ was shed, but isn't a shed path")
+	}
+}
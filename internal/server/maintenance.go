@@ -2,37 +2,71 @@ package server
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/rubys/navigator/internal/clock"
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/logging"
 )
 
 // ServeMaintenancePage serves a maintenance/503 page
 func ServeMaintenancePage(w http.ResponseWriter, r *http.Request, config *config.Config) {
+	serveMaintenancePage(w, r, config, config.Maintenance.Page, 0)
+}
+
+// serveMaintenance serves the maintenance page if a tenant's or route's
+// maintenance block is in effect. The block's page overrides the global one,
+// and a scheduled end becomes Retry-After.
+func (h *Handler) serveMaintenance(w http.ResponseWriter, r *http.Request, maintenance *config.MaintenanceConfig) bool {
+	now := clock.Or(h.clock).Now()
+	active, until := maintenance.Active(now)
+	if !active {
+		return false
+	}
+	page := maintenance.Page
+	if page == "" {
+		page = h.config.Maintenance.Page
+	}
+	var retryAfter time.Duration
+	if !until.IsZero() {
+		retryAfter = until.Sub(now)
+	}
+	serveMaintenancePage(w, r, h.config, page, retryAfter)
+	return true
+}
+
+// statusPagePath returns the path of the custom page for status,
+// <public_dir>/<status>.html
+func statusPagePath(cfg *config.Config, status int) string {
+	return filepath.Join(publicDir(cfg), fmt.Sprintf("%d.html", status))
+}
+
+// serveMaintenancePage serves page, or the default 503.html, with 503
+func serveMaintenancePage(w http.ResponseWriter, r *http.Request, config *config.Config, page string, retryAfter time.Duration) {
 	// Set metadata for maintenance page
 	if recorder, ok := w.(*ResponseRecorder); ok {
 		recorder.SetMetadata("response_type", "maintenance")
 	}
 
-	// Try to serve custom 503.html if available
-	publicDir := "public" // Default fallback
-	if config.Server.Static.PublicDir != "" {
-		publicDir = config.Server.Static.PublicDir
-	}
-
 	// Check for custom 503.html - use configured maintenance page if set
-	maintenancePage := fmt.Sprintf("%s/503.html", publicDir)
-	if config.Maintenance.Page != "" {
+	maintenancePage := statusPagePath(config, http.StatusServiceUnavailable)
+	if page != "" {
 		// If it's an absolute path, use it directly, otherwise append to publicDir
-		if strings.HasPrefix(config.Maintenance.Page, "/") {
-			maintenancePage = fmt.Sprintf("%s%s", publicDir, config.Maintenance.Page)
+		if strings.HasPrefix(page, "/") {
+			maintenancePage = publicDir(config) + page
 		} else {
-			maintenancePage = config.Maintenance.Page
+			maintenancePage = page
 		}
 	}
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
 	if content, err := os.ReadFile(maintenancePage); err == nil {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newMaintenanceTestHandler serves a tenant and an API route from a backend,
+// with a maintenance window from 02:00 to 04:00 on each and a fake clock
+// starting at 01:00
+func newMaintenanceTestHandler(t *testing.T) (*Handler, *clock.Fake) {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("backend"))
+	}))
+	t.Cleanup(backend.Close)
+
+	publicDir := t.TempDir()
+	for name, content := range map[string]string{
+		"503.html":                "global maintenance",
+		"boston-503.html":         "boston maintenance",
+		"studios/boston/app.css":  "body {}",
+		"studios/raleigh/app.css": "body {}",
+	} {
+		path := filepath.Join(publicDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  static:
+    public_dir: ` + publicDir + `
+routes:
+  reverse_proxies:
+    - name: api
+      prefix: /api/
+      target: ` + backend.URL + `
+      maintenance:
+        windows:
+          - start: "2025-06-01T02:00:00Z"
+            end: "2025-06-01T04:00:00Z"
+applications:
+  tenants:
+    - path: /studios/boston/
+      maintenance:
+        page: /boston-503.html
+        windows:
+          - start: "2025-06-01T02:00:00Z"
+            end: "2025-06-01T04:00:00Z"
+    - path: /studios/raleigh/
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	appManager := process.NewAppManager(cfg)
+	appManager.StubApps(backend.Listener.Addr().(*net.TCPAddr).Port)
+	t.Cleanup(appManager.Cleanup)
+
+	fake := clock.NewFake(time.Date(2025, 6, 1, 1, 0, 0, 0, time.UTC))
+	h := CreateTestHandler(cfg, appManager, nil, &idle.Manager{}).(*Handler)
+	h.clock = fake
+	return h, fake
+}
+
+func TestTenantMaintenanceWindow(t *testing.T) {
+	h, fake := newMaintenanceTestHandler(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	// Before the window
+	if rec := get("/studios/boston/heats"); rec.Code != http.StatusOK || rec.Body.String() != "backend" {
+		t.Errorf("Before window: %d %q, want the backend", rec.Code, rec.Body.String())
+	}
+
+	// During the window: the tenant's own page, with the time left
+	fake.Advance(150 * time.Minute) // 03:30
+	rec := get("/studios/boston/heats")
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "boston maintenance" {
+		t.Errorf("During window: %d %q, want the tenant's maintenance page", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1800" {
+		t.Errorf("Retry-After = %q, want 1800", got)
+	}
+	if rec := get("/studios/boston/app.css"); rec.Code != http.StatusOK {
+		t.Errorf("Static asset during window: %d, want 200", rec.Code)
+	}
+	if rec := get("/studios/raleigh/heats"); rec.Code != http.StatusOK || rec.Body.String() != "backend" {
+		t.Errorf("Other tenant during window: %d %q, want the backend", rec.Code, rec.Body.String())
+	}
+
+	// After the window
+	fake.Advance(30 * time.Minute) // 04:00
+	if rec := get("/studios/boston/heats"); rec.Code != http.StatusOK || rec.Body.String() != "backend" {
+		t.Errorf("After window: %d %q, want the backend", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRouteMaintenanceWindow(t *testing.T) {
+	h, fake := newMaintenanceTestHandler(t)
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/events", nil))
+		return rec
+	}
+
+	if rec := get(); rec.Code != http.StatusOK {
+		t.Errorf("Before window: %d, want 200", rec.Code)
+	}
+
+	fake.Advance(time.Hour) // 02:00
+	rec := get()
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "global maintenance") {
+		t.Errorf("During window: %d %q, want the global maintenance page", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "7200" {
+		t.Errorf("Retry-After = %q, want 7200", got)
+	}
+
+	fake.Advance(3 * time.Hour) // 05:00
+	if rec := get(); rec.Code != http.StatusOK {
+		t.Errorf("After window: %d, want 200", rec.Code)
+	}
+}
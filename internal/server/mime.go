@@ -1,30 +1,93 @@
 package server
 
 import (
+	"bytes"
+	"io"
 	"mime"
 	"net/http"
 	"path/filepath"
+	"strings"
 )
 
+// sniffLength is how much of a file http.DetectContentType considers
+const sniffLength = 512
+
 // SetContentType sets the appropriate Content-Type header based on file extension
 func SetContentType(w http.ResponseWriter, fsPath string) {
-	ext := filepath.Ext(fsPath)
+	if contentType := contentTypeByExtension(fsPath, nil); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+}
+
+// contentTypeByExtension returns the Content-Type for a file name's
+// extension: from overrides (keyed by lowercase extension with its dot),
+// else the built-in types, or "" if the extension is unknown
+func contentTypeByExtension(name string, overrides map[string]string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if contentType, ok := overrides[ext]; ok {
+		return contentType
+	}
+
+	// HTML is always UTF-8, whatever the system's MIME database says
+	switch ext {
+	case "":
+		return ""
+	case ".html", ".htm":
+		return "text/html; charset=utf-8"
+	}
 
 	// Try standard library first
 	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
-		w.Header().Set("Content-Type", mimeType)
-		return
+		return mimeType
 	}
 
 	// Fallback for extensions not in standard library
 	switch ext {
 	case ".woff":
-		w.Header().Set("Content-Type", "font/woff")
+		return "font/woff"
 	case ".woff2":
-		w.Header().Set("Content-Type", "font/woff2")
+		return "font/woff2"
 	case ".ttf":
-		w.Header().Set("Content-Type", "font/ttf")
+		return "font/ttf"
 	case ".eot":
-		w.Header().Set("Content-Type", "application/vnd.ms-fontobject")
+		return "application/vnd.ms-fontobject"
+	}
+	return ""
+}
+
+// sniffContentType detects the Content-Type of content from its first bytes.
+// http.DetectContentType doesn't know SVG, which it reports as XML or text.
+func sniffContentType(content io.Reader) string {
+	head := make([]byte, sniffLength)
+	n, _ := io.ReadFull(content, head)
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if (strings.HasPrefix(contentType, "text/xml") || strings.HasPrefix(contentType, "text/plain")) &&
+		bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return contentType
+}
+
+// setContentType sets the Content-Type of a static file by its extension,
+// using server.static.mime_types first, and sniffs its content when the
+// extension is missing or unknown. The content is sniffed here rather than
+// by http.ServeContent so a precompressed sidecar is never what's sniffed.
+func (s *StaticFileHandler) setContentType(w http.ResponseWriter, name string) {
+	static := &s.config.Server.Static
+	if static.NoSniff {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+
+	contentType := contentTypeByExtension(name, static.MIMETypes)
+	if contentType == "" {
+		file, err := s.source.Open(name)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		contentType = sniffContentType(file)
 	}
+	w.Header().Set("Content-Type", contentType)
 }
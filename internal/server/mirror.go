@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// HeaderMirror marks the copies of requests sent to a mirror target
+const HeaderMirror = "X-Navigator-Mirror"
+
+// MirrorStatus reports a reverse proxy's or tenant's mirror in the detailed
+// health check
+type MirrorStatus struct {
+	Route    string `json:"route,omitempty"`
+	Tenant   string `json:"tenant,omitempty"`
+	Target   string `json:"target"`
+	Mirrored int64  `json:"mirrored"`  // Copies sent
+	Skipped  int64  `json:"skipped"`   // Sampled requests not sent: body too large or too many in flight
+	Errored  int64  `json:"errored"`   // Copies that failed or got a 5xx response
+	InFlight int64  `json:"in_flight"` // Copies awaiting a response
+}
+
+// mirrorKey identifies the route or tenant a mirror belongs to
+type mirrorKey struct {
+	route  string
+	tenant string
+}
+
+// mirrorState counts one mirror's requests
+type mirrorState struct {
+	target   atomic.Value // string
+	mirrored atomic.Int64
+	skipped  atomic.Int64
+	errored  atomic.Int64
+	inFlight atomic.Int64
+}
+
+// mirrorRegistry holds the state of every mirror; it outlives the handler,
+// so counts survive a config reload
+type mirrorRegistry struct {
+	mu     sync.Mutex
+	states map[mirrorKey]*mirrorState
+}
+
+var mirrors = newMirrorRegistry()
+
+func newMirrorRegistry() *mirrorRegistry {
+	return &mirrorRegistry{states: make(map[mirrorKey]*mirrorState)}
+}
+
+// mirrorClient sends mirrored requests; redirects are not followed
+var mirrorClient = &http.Client{
+	Transport: proxy.NewTransport(),
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// state returns the state of the mirror for key, creating it if needed
+func (m *mirrorRegistry) state(key mirrorKey, target string) *mirrorState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[key]
+	if !ok {
+		state = &mirrorState{}
+		m.states[key] = state
+	}
+	state.target.Store(target)
+	return state
+}
+
+// configure forgets the mirrors cfg no longer has, so routes and tenants
+// whose mirror was removed by a reload drop out of the status
+func (m *mirrorRegistry) configure(cfg *config.Config) {
+	configured := make(map[mirrorKey]bool)
+	for _, route := range cfg.Routes.ReverseProxies {
+		if route.Mirror != nil {
+			configured[mirrorKey{route: route.Name}] = true
+		}
+	}
+	for _, tenant := range cfg.Applications.Tenants {
+		if tenant.Mirror != nil {
+			configured[mirrorKey{tenant: tenant.Name}] = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.states {
+		if !configured[key] {
+			delete(m.states, key)
+		}
+	}
+}
+
+// status reports every mirror that has sampled a request, tenants first
+func (m *mirrorRegistry) status() []MirrorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var statuses []MirrorStatus
+	for key, state := range m.states {
+		statuses = append(statuses, MirrorStatus{
+			Route:    key.route,
+			Tenant:   key.tenant,
+			Target:   state.target.Load().(string),
+			Mirrored: state.mirrored.Load(),
+			Skipped:  state.skipped.Load(),
+			Errored:  state.errored.Load(),
+			InFlight: state.inFlight.Load(),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Route != statuses[j].Route {
+			return statuses[i].Route < statuses[j].Route
+		}
+		return statuses[i].Tenant < statuses[j].Tenant
+	})
+	return statuses
+}
+
+// mirrorRequest sends a copy of r to mirror's target in the background when
+// r is sampled, and records "mirrored" or "skipped" for the access log. The
+// copy's response is discarded and never affects r's.
+func mirrorRequest(w http.ResponseWriter, r *http.Request, key mirrorKey, mirror *config.MirrorConfig) {
+	if mirror == nil || isWebSocketRequest(r) || !slices.Contains(mirror.Methods, r.Method) {
+		return
+	}
+	if mirror.Percent < 100 && rand.Float64()*100 >= mirror.Percent {
+		return
+	}
+
+	state := mirrors.state(key, mirror.Target)
+	recorder, _ := w.(*ResponseRecorder)
+	skip := func(reason string) {
+		skipped := state.skipped.Add(1)
+		if recorder != nil {
+			recorder.SetMetadata("mirror", "skipped")
+		}
+		logging.LogMirrorSkipped(r.URL.Path, mirror.Target, reason, skipped)
+	}
+
+	body, ok := bufferMirrorBody(r, mirror.MaxBodyBytes)
+	if !ok {
+		skip("body too large")
+		return
+	}
+	if state.inFlight.Add(1) > int64(mirror.MaxConcurrent) {
+		state.inFlight.Add(-1)
+		skip("too many in flight")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mirror.Timeout.Std())
+	req, err := newMirrorRequest(ctx, r, mirror.Target, body)
+	if err != nil {
+		cancel()
+		state.inFlight.Add(-1)
+		logging.LogMirrorFailed(r.URL.Path, mirror.Target, err, state.errored.Add(1))
+		return
+	}
+	state.mirrored.Add(1)
+	if recorder != nil {
+		recorder.SetMetadata("mirror", "mirrored")
+	}
+
+	path := r.URL.Path
+	go func() {
+		defer state.inFlight.Add(-1)
+		defer cancel()
+		resp, err := mirrorClient.Do(req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode >= http.StatusInternalServerError {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+		}
+		if err != nil {
+			logging.LogMirrorFailed(path, mirror.Target, err, state.errored.Add(1))
+		}
+	}()
+}
+
+// bufferMirrorBody reads r's body for the mirrored copy, leaving r able to
+// read it all again. It reports false, after reading at most maxBytes+1
+// bytes, when the body is larger than maxBytes.
+func bufferMirrorBody(r *http.Request, maxBytes int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	if r.ContentLength > maxBytes {
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || int64(len(body)) > maxBytes {
+		return nil, false
+	}
+	return body, true
+}
+
+// newMirrorRequest copies r, with body, for target: the request's path and
+// query are appended to target's
+func newMirrorRequest(ctx context.Context, r *http.Request, target string, body []byte) (*http.Request, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = singleJoiningSlash(u.Path, r.URL.Path)
+	u.RawPath = ""
+	u.RawQuery = r.URL.RawQuery
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Set("X-Forwarded-Host", getHost(r))
+	req.Header.Set("X-Forwarded-Proto", getScheme(r))
+	req.Header.Set(HeaderMirror, "true")
+	return req, nil
+}
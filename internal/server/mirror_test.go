@@ -0,0 +1,230 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// mirrorTarget records the requests a mirror receives
+type mirrorTarget struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+	count    atomic.Int64
+}
+
+func newMirrorTarget(t *testing.T, delay time.Duration) *mirrorTarget {
+	target := &mirrorTarget{}
+	target.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		time.Sleep(delay)
+		target.mu.Lock()
+		target.requests = append(target.requests, fmt.Sprintf("%s %s %s %s", r.Method, r.URL.RequestURI(), body, r.Header.Get(HeaderMirror)))
+		target.mu.Unlock()
+		target.count.Add(1)
+		http.Error(w, "mirror response", http.StatusInternalServerError)
+	}))
+	t.Cleanup(target.Close)
+	return target
+}
+
+// useMirrors replaces the shared mirror registry with an empty one
+func useMirrors(t *testing.T) *mirrorRegistry {
+	registry := newMirrorRegistry()
+	previous := mirrors
+	mirrors = registry
+	t.Cleanup(func() { mirrors = previous })
+	return registry
+}
+
+// newMirrorHandler proxies /api/ to primary, with yaml's mirror settings
+func newMirrorHandler(t *testing.T, name, primary, mirror string) http.Handler {
+	t.Helper()
+	cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+routes:
+  reverse_proxies:
+    - name: %s
+      prefix: /api/
+      target: %s
+%s`, name, primary, mirror)))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	appManager := process.NewAppManager(cfg)
+	t.Cleanup(appManager.Cleanup)
+	return CreateTestHandler(cfg, appManager, nil, &idle.Manager{})
+}
+
+func waitForMirrors(t *testing.T, route string) MirrorStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, status := range mirrors.status() {
+			if status.Route == route && status.InFlight == 0 {
+				return status
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mirror of %s still has requests in flight", route)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMirrorLeavesPrimaryResponseUnchanged(t *testing.T) {
+	useMirrors(t)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Primary", "yes")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s body=%q mirror=%q", r.Method, r.URL.RequestURI(), body, r.Header.Get(HeaderMirror))
+	}))
+	t.Cleanup(primary.Close)
+	mirror := newMirrorTarget(t, 0)
+
+	plain := newMirrorHandler(t, "unmirrored", primary.URL, "")
+	mirrored := newMirrorHandler(t, "mirrored", primary.URL, fmt.Sprintf(`      mirror:
+        target: %s/shadow
+        methods: [get, post]
+        max_body_bytes: 16
+`, mirror.URL))
+
+	requests := []struct {
+		method, target, body string
+		chunked              bool
+	}{
+		{"GET", "/api/items?page=2", "", false},
+		{"POST", "/api/items", "small=body", false},
+		{"POST", "/api/items", strings.Repeat("large body ", 10), false},
+		{"POST", "/api/items", strings.Repeat("chunked body ", 10), true},
+		{"DELETE", "/api/items/1", "", false},
+	}
+	serve := func(handler http.Handler, method, target, body string, chunked bool) *httptest.ResponseRecorder {
+		var reader io.Reader = strings.NewReader(body)
+		if chunked {
+			reader = io.MultiReader(reader) // hides the length
+		}
+		req := httptest.NewRequest(method, target, reader)
+		if chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, tt := range requests {
+		want := serve(plain, tt.method, tt.target, tt.body, tt.chunked)
+		got := serve(mirrored, tt.method, tt.target, tt.body, tt.chunked)
+		if got.Code != want.Code || got.Body.String() != want.Body.String() || got.Header().Get("X-Primary") != "yes" {
+			t.Errorf("%s %s: mirrored response %d %q, want %d %q", tt.method, tt.target, got.Code, got.Body.String(), want.Code, want.Body.String())
+		}
+	}
+
+	status := waitForMirrors(t, "mirrored")
+	if status.Mirrored != 2 || status.Skipped != 2 || status.Errored != 2 {
+		t.Errorf("status = %+v, want 2 mirrored (both 5xx), 2 skipped for their bodies", status)
+	}
+	mirror.mu.Lock()
+	defer mirror.mu.Unlock()
+	want := []string{"GET /shadow/api/items?page=2  true", "POST /shadow/api/items small=body true"}
+	if strings.Join(mirror.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("mirror received %q, want %q", mirror.requests, want)
+	}
+}
+
+func TestMirrorSampledShare(t *testing.T) {
+	useMirrors(t)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	t.Cleanup(primary.Close)
+	mirror := newMirrorTarget(t, 0)
+	handler := newMirrorHandler(t, "sampled", primary.URL, fmt.Sprintf(`      mirror:
+        target: %s
+        percent: 25
+        max_concurrent: 1000
+`, mirror.URL))
+
+	const requests = 1000
+	for i := 0; i < requests; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/items", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("primary status = %d", rec.Code)
+		}
+	}
+
+	status := waitForMirrors(t, "sampled")
+	if received := mirror.count.Load(); received != status.Mirrored || status.Skipped != 0 {
+		t.Errorf("mirror received %d requests, status = %+v", received, status)
+	}
+	// 250 expected; the bounds are five standard deviations away
+	if status.Mirrored < 180 || status.Mirrored > 320 {
+		t.Errorf("mirrored %d of %d requests, want about 25%%", status.Mirrored, requests)
+	}
+}
+
+func TestMirrorDoesNotDelayPrimary(t *testing.T) {
+	useMirrors(t)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	t.Cleanup(primary.Close)
+	mirror := newMirrorTarget(t, 500*time.Millisecond)
+	handler := newMirrorHandler(t, "slow-mirror", primary.URL, fmt.Sprintf(`      mirror:
+        target: %s
+        max_concurrent: 1
+`, mirror.URL))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/items", nil))
+		if rec.Body.String() != "ok" {
+			t.Fatalf("primary body = %q", rec.Body.String())
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("primary requests took %v waiting on a slow mirror", elapsed)
+	}
+
+	status := waitForMirrors(t, "slow-mirror")
+	if status.Mirrored != 1 || status.Skipped != 2 {
+		t.Errorf("status = %+v, want 1 mirrored and 2 skipped at max_concurrent 1", status)
+	}
+}
+
+func TestMirrorRegistryForgetsRemovedMirrors(t *testing.T) {
+	registry := useMirrors(t)
+	registry.state(mirrorKey{route: "api"}, "http://shadow")
+	registry.state(mirrorKey{route: "old"}, "http://shadow")
+	registry.state(mirrorKey{tenant: "boston"}, "http://shadow")
+	registry.state(mirrorKey{tenant: "raleigh"}, "http://shadow")
+
+	cfg := &config.Config{}
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{
+		{Name: "api", Mirror: &config.MirrorConfig{Target: "http://shadow"}},
+		{Name: "old"},
+	}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "boston", Mirror: &config.MirrorConfig{Target: "http://shadow"}}}
+	registry.configure(cfg)
+
+	var kept []string
+	for _, status := range registry.status() {
+		kept = append(kept, status.Route+status.Tenant)
+	}
+	if strings.Join(kept, ",") != "boston,api" {
+		t.Errorf("mirrors after reload = %q, want boston and api", kept)
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// mediaRange is one element of an Accept header
+type mediaRange struct {
+	mainType string
+	subtype  string
+	q        float64
+}
+
+// parseAccept parses an Accept header into its media ranges, in order.
+// Elements that aren't type/subtype or have an invalid q-value are ignored.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, element := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(element, ";")
+		mainType, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+		if !ok || mainType == "" || subtype == "" || (mainType == "*" && subtype != "*") {
+			continue
+		}
+
+		q := 1.0
+		valid := true
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+		if valid {
+			ranges = append(ranges, mediaRange{mainType: mainType, subtype: subtype, q: q})
+		}
+	}
+	return ranges
+}
+
+// acceptQuality returns the q-value the most specific range in ranges gives
+// mediaType, which may itself be a type/* range. Only ranges naming the main
+// type count: */* accepts anything, so it never selects a negotiated target.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	mainType, subtype, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, 0
+	for _, r := range ranges {
+		if r.mainType != mainType {
+			continue
+		}
+		rangeSpecificity := 1
+		if r.subtype != "*" {
+			if subtype != "*" && r.subtype != subtype {
+				continue
+			}
+			rangeSpecificity = 2
+		}
+		// The most specific range decides; among equals the first wins
+		if rangeSpecificity > specificity {
+			quality, specificity = r.q, rangeSpecificity
+		}
+	}
+	return quality
+}
+
+// prefersAny reports whether the Accept header ranges prefer one of
+// mediaTypes: a range naming it has a nonzero q-value that no other range,
+// including wildcards, exceeds
+func prefersAny(ranges []mediaRange, mediaTypes []string) bool {
+	best := 0.0
+	for _, mediaType := range mediaTypes {
+		best = max(best, acceptQuality(ranges, mediaType))
+	}
+	if best == 0 {
+		return false
+	}
+	for _, r := range ranges {
+		if r.q > best {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMediaType reports whether mediaType is one of mediaTypes, which may
+// include type/* ranges
+func matchesMediaType(mediaTypes []string, mediaType string) bool {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	for _, candidate := range mediaTypes {
+		if candidate == mediaType || candidate == mainType+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiatedTarget returns the first of targets whose conditions r meets, or
+// nil when the route's own target or the tenant's app should serve it.
+// WebSocket upgrades are never negotiated.
+func negotiatedTarget(r *http.Request, targets []config.NegotiatedTarget) *config.NegotiatedTarget {
+	if len(targets) == 0 || isWebSocketRequest(r) {
+		return nil
+	}
+	ranges := parseAccept(strings.Join(r.Header.Values("Accept"), ","))
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	for i := range targets {
+		target := &targets[i]
+		if target.Pattern != nil && !target.Pattern.MatchString(r.URL.Path) {
+			continue
+		}
+		if len(target.ContentType) > 0 && (contentType == "" || !matchesMediaType(target.ContentType, contentType)) {
+			continue
+		}
+		if len(target.Accept) > 0 && !prefersAny(ranges, target.Accept) {
+			continue
+		}
+		return target
+	}
+	return nil
+}
+
+// varyNegotiation adds the request headers that targets choose between
+// backends on for r's path to the Vary header, whichever backend serves it
+func varyNegotiation(header http.Header, r *http.Request, targets []config.NegotiatedTarget) {
+	for i := range targets {
+		target := &targets[i]
+		if target.Pattern != nil && !target.Pattern.MatchString(r.URL.Path) {
+			continue
+		}
+		if len(target.Accept) > 0 {
+			addVary(header, "Accept")
+		}
+		if len(target.ContentType) > 0 {
+			addVary(header, "Content-Type")
+		}
+	}
+}
+
+// addVary adds name to the Vary header unless it's already listed
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, listed := range strings.Split(value, ",") {
+			if listed = strings.TrimSpace(listed); listed == "*" || strings.EqualFold(listed, name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
+// serveNegotiatedTarget proxies a tenant's request to the negotiate target
+// it selects, returning false when the tenant's app should serve it
+func (h *Handler) serveNegotiatedTarget(recorder *ResponseRecorder, r *http.Request, tenant *config.Tenant) bool {
+	varyNegotiation(recorder.Header(), r, tenant.Negotiate)
+	target := negotiatedTarget(r, tenant.Negotiate)
+	if target == nil {
+		return false
+	}
+
+	logging.LogNegotiatedTarget(r.URL.Path, target.Target)
+	recorder.SetMetadata("tenant", tenant.Name)
+	if rejectOversizedHeaders(recorder, r, tenant.Name, h.headerLimit(tenant.MaxHeaderBytes)) {
+		return true
+	}
+	h.handleHTTPProxy(recorder, r, &config.ProxyRoute{
+		Name:   tenant.Name,
+		Path:   target.Path,
+		Target: target.Target,
+	})
+	return true
+}
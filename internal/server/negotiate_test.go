@@ -0,0 +1,140 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func TestNegotiatedTargetSelection(t *testing.T) {
+	cfg, err := config.ParseYAML([]byte(`
+routes:
+  reverse_proxies:
+    - name: feed
+      prefix: /feed
+      target: http://rails.internal
+      negotiate:
+        - accept: [application/json]
+          target: http://api.internal
+        - accept: [text/csv, application/vnd.ms-excel]
+          target: http://export.internal
+        - content_type: [multipart/*]
+          target: http://uploads.internal
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	targets := cfg.Routes.ReverseProxies[0].Negotiate
+
+	tests := []struct {
+		accept      string
+		contentType string
+		want        string
+	}{
+		{"application/json", "", "http://api.internal"},
+		{"application/JSON; charset=utf-8", "", "http://api.internal"},
+		{"application/*", "", "http://api.internal"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", ""},
+		{"*/*", "", ""},
+		{"", "", ""},
+		{"text/html, application/json;q=0.9", "", ""},
+		{"text/html;q=0.5, application/json", "", "http://api.internal"},
+		{"application/json;q=0.5, */*", "", ""},
+		{"application/json;q=0, */*", "", ""},
+		{"application/json;q=0, application/*", "", "http://export.internal"},
+		{"application/json;q=0.8, text/csv;q=0.8", "", "http://api.internal"},
+		{"application/json;q=0.7, text/csv;q=0.8", "", "http://export.internal"},
+		{"application/vnd.ms-excel", "", "http://export.internal"},
+		{"application/json;q=bogus, text/csv", "", "http://export.internal"},
+		{"text/html", "multipart/form-data; boundary=x", "http://uploads.internal"},
+		{"application/json", "multipart/form-data; boundary=x", "http://api.internal"},
+		{"text/html", "application/x-www-form-urlencoded", ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s|%s", tt.accept, tt.contentType), func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/feed", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			got := ""
+			if target := negotiatedTarget(req, targets); target != nil {
+				got = target.Target
+			}
+			if got != tt.want {
+				t.Errorf("negotiatedTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiatedTargetRouting(t *testing.T) {
+	backend := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Vary", "Accept-Encoding")
+			fmt.Fprint(w, name)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	api, rails, app := backend("api"), backend("rails"), backend("app")
+
+	cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+routes:
+  reverse_proxies:
+    - name: legacy
+      prefix: /legacy/
+      target: %s
+      negotiate:
+        - accept: [application/json]
+          target: %s
+applications:
+  tenants:
+    - path: /showcase/2025/boston/
+      name: boston
+      negotiate:
+        - path: ^/showcase/.*/feed$
+          accept: [application/json]
+          target: %s
+`, rails.URL, api.URL, api.URL)))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	appManager := process.NewAppManager(cfg)
+	appManager.StubApps(app.Listener.Addr().(*net.TCPAddr).Port)
+	t.Cleanup(appManager.Cleanup)
+	h := CreateTestHandler(cfg, appManager, nil, &idle.Manager{})
+
+	tests := []struct {
+		path, accept string
+		want, vary   string
+	}{
+		{"/legacy/items", "application/json", "api", "Accept"},
+		{"/legacy/items", "text/html", "rails", "Accept"},
+		{"/showcase/2025/boston/feed", "application/json", "api", "Accept"},
+		{"/showcase/2025/boston/feed", "text/html,*/*;q=0.8", "app", "Accept"},
+		{"/showcase/2025/boston/heats", "application/json", "app", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Body.String() != tt.want {
+			t.Errorf("%s (Accept %s): served by %q, want %q", tt.path, tt.accept, rec.Body.String(), tt.want)
+		}
+		if vary := rec.Header().Values("Vary"); tt.vary != "" && (len(vary) != 2 || vary[0] != tt.vary) {
+			t.Errorf("%s (Accept %s): Vary = %q, want %s and the backend's", tt.path, tt.accept, vary, tt.vary)
+		} else if tt.vary == "" && len(vary) != 1 {
+			t.Errorf("%s (Accept %s): Vary = %q, want only the backend's", tt.path, tt.accept, vary)
+		}
+	}
+}
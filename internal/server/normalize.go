@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Values for server.encoded_slashes
+const (
+	EncodedSlashesDecode = "decode" // Treat %2F as a path separator before matching (default)
+	EncodedSlashesReject = "reject" // Reject requests containing %2F with 400 Bad Request
+)
+
+// allowedMethods is advertised in response to OPTIONS *
+const allowedMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// isServerWideOptions reports whether the request is "OPTIONS *" (RFC 9110 section 9.3.7)
+func isServerWideOptions(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.RequestURI == "*"
+}
+
+// handleServerWideOptions answers OPTIONS * without involving routes or applications
+func handleServerWideOptions(w http.ResponseWriter) {
+	w.Header().Set("Allow", allowedMethods)
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hasEncodedSlash reports whether the raw request path contains %2F
+func hasEncodedSlash(r *http.Request) bool {
+	escaped := r.URL.EscapedPath()
+	return strings.Contains(escaped, "%2F") || strings.Contains(escaped, "%2f")
+}
+
+// normalizePath collapses duplicate slashes and resolves "." and ".." segments.
+// The input is the decoded URL path; the result always begins with "/", never
+// climbs above the root, and keeps a trailing slash when the input had one.
+func normalizePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	segments := make([]string, 0, strings.Count(path, "/"))
+	trailingSlash := false
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "":
+			trailingSlash = true
+			continue
+		case ".":
+			trailingSlash = true
+		case "..":
+			trailingSlash = true
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			trailingSlash = false
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "/"
+	}
+	normalized := "/" + strings.Join(segments, "/")
+	if trailingSlash {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// normalizeRequest rewrites r.URL.Path in place so auth, rewrites, routes, and
+// static serving all match against the same canonical path. Returns false if
+// the request must be rejected.
+func normalizeRequest(r *http.Request, encodedSlashes string) bool {
+	if encodedSlashes == EncodedSlashesReject && hasEncodedSlash(r) {
+		return false
+	}
+
+	normalized := normalizePath(r.URL.Path)
+	if normalized != r.URL.Path {
+		r.URL.Path = normalized
+		// Raw form no longer corresponds to the path; let EscapedPath re-encode it
+		r.URL.RawPath = ""
+	}
+	return true
+}
@@ -0,0 +1,527 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/scheduler"
+)
+
+// pipelineRequest is a request passing through the pipeline's stages
+type pipelineRequest struct {
+	recorder  *ResponseRecorder
+	r         *http.Request
+	requestID string
+	stage     string // The stage serving the request, for panic reports
+
+	// Decided by the auth stage for the stages after it, and again after
+	// an internal rewrite
+	needsAuth  bool
+	public     bool
+	bypassAuth bool            // Sent by a scheduled task or warmer
+	authPath   string          // Path authentication was decided for
+	authorized *auth.BasicAuth // Scope whose credentials the request passed
+
+	tenant        *config.Tenant // Tenant the request is routed to, once matched
+	tenantMatched bool
+}
+
+// routedTenant returns the tenant the request is routed to, or nil. It's
+// matched on first use, by the stages after rewrites have settled the path,
+// so every stage agrees on it.
+func (p *pipelineRequest) routedTenant(cfg *config.Config) *config.Tenant {
+	if !p.tenantMatched {
+		p.tenant, p.tenantMatched = cfg.TenantForPath(p.r.URL.Path), true
+	}
+	return p.tenant
+}
+
+// stage is a named step of the request pipeline
+type stage interface {
+	name() string
+
+	// serve answers the request and returns true, or returns false to pass
+	// it on to the next stage
+	serve(p *pipelineRequest) bool
+}
+
+// handlerStage is a stage served by one of a Handler's methods
+type handlerStage struct {
+	stageName string
+	handler   *Handler
+	fn        func(*Handler, *pipelineRequest) bool
+}
+
+func (s handlerStage) name() string { return s.stageName }
+
+func (s handlerStage) serve(p *pipelineRequest) bool { return s.fn(s.handler, p) }
+
+// pipelineStage is a stage of the request pipeline. A stage whose enabled
+// returns false is left out of chains for a config that doesn't use it.
+type pipelineStage struct {
+	name    string
+	enabled func(*Handler) bool // nil = always
+	serve   func(*Handler, *pipelineRequest) bool
+}
+
+// pipelineStages orders the request pipeline. It's set by init because the
+// explain stage traces requests through the pipeline.
+var pipelineStages []pipelineStage
+
+func init() {
+	pipelineStages = []pipelineStage{
+		{"options", nil, (*Handler).serveOptionsStage},
+		{"request_target", nil, (*Handler).serveRequestTargetStage},
+		{"normalize", nil, (*Handler).serveNormalizeStage},
+		{"acme_challenge", func(h *Handler) bool { return h.config.Server.AcmeChallengeDir != "" }, (*Handler).serveACMEStage},
+		{"well_known", func(h *Handler) bool { return len(h.config.Server.WellKnown.Entries) > 0 }, (*Handler).serveWellKnownStage},
+		{"health_check", func(h *Handler) bool { return h.config.Server.HealthCheck.Path != "" }, (*Handler).serveHealthCheckStage},
+		{"detailed_health_check", func(h *Handler) bool { return h.config.Server.HealthCheck.DetailedPath != "" }, (*Handler).serveDetailedHealthCheckStage},
+		{"cable_broadcast", (*Handler).cableEnabled, (*Handler).serveBroadcastStage},
+		{"response_cache_purge", func(h *Handler) bool { return h.config.Server.ResponseCache.PurgePath != "" }, (*Handler).serveCachePurgeStage},
+		{"diagnostics", func(h *Handler) bool { return h.config.Server.Diagnostics.Path != "" }, (*Handler).serveDiagnosticsStage},
+		{"explain", func(h *Handler) bool { return h.config.Server.Diagnostics.ExplainPath != "" }, (*Handler).serveExplainStage},
+		{"control", func(h *Handler) bool { return h.config.Server.ControlPath != "" }, (*Handler).serveControlStage},
+		{"canonical", func(h *Handler) bool { return h.config.Server.Canonical.Enabled() }, (*Handler).serveCanonicalStage},
+		{"tenant_alias", nil, (*Handler).serveTenantAliasStage},
+		{"upload", nil, (*Handler).serveUploadStage},
+		{"auth", nil, (*Handler).serveAuthStage},
+		{"cable", (*Handler).cableEnabled, (*Handler).serveCableStage},
+		{"rewrites", nil, (*Handler).serveRewritesStage},
+		{"load_shedding", nil, (*Handler).serveLoadSheddingStage},
+		{"request_body", (*Handler).requestBodyEnabled, (*Handler).serveRequestBodyStage},
+		{"cgi", nil, (*Handler).serveCGIStage},
+		{"reverse_proxies", nil, (*Handler).serveReverseProxiesStage},
+		{"static", nil, (*Handler).serveStaticStage},
+		{"try_files", nil, (*Handler).serveTryFilesStage},
+		{"spa", nil, (*Handler).serveSPAStage},
+		{"maintenance", nil, (*Handler).serveMaintenanceStage},
+		{"tenants", func(h *Handler) bool { return len(h.config.Applications.Tenants) > 0 }, (*Handler).serveTenantsStage},
+		{"fallback", func(h *Handler) bool { return len(h.config.Applications.Tenants) == 0 }, (*Handler).serveFallbackStage},
+	}
+}
+
+// assembleChain returns the named stages, or all of them, in pipeline
+// order, leaving out those the handler's config doesn't use
+func (h *Handler) assembleChain(names ...string) []stage {
+	var chain []stage
+	for _, s := range pipelineStages {
+		if len(names) > 0 && !slices.Contains(names, s.name) {
+			continue
+		}
+		if s.enabled != nil && !s.enabled(h) {
+			continue
+		}
+		chain = append(chain, handlerStage{stageName: s.name, handler: h, fn: s.serve})
+	}
+	return chain
+}
+
+// stages returns the handler's chain, assembling the whole pipeline for a
+// handler that wasn't given one
+func (h *Handler) stages() []stage {
+	h.chainOnce.Do(func() {
+		if h.chain == nil {
+			h.chain = h.assembleChain()
+		}
+	})
+	return h.chain
+}
+
+// runChain passes the request through each stage until one answers it
+func runChain(chain []stage, p *pipelineRequest) {
+	for _, s := range chain {
+		if p.recorder.trace != nil {
+			s = tracedStage{s}
+		}
+		p.stage = s.name()
+		if s.serve(p) {
+			return
+		}
+	}
+}
+
+// cableEnabled reports whether Navigator serves Action Cable itself
+func (h *Handler) cableEnabled() bool {
+	return h.cableHandler != nil && h.config.Cable.Enabled
+}
+
+// serveOptionsStage answers server-wide OPTIONS * before any path-based routing
+func (h *Handler) serveOptionsStage(p *pipelineRequest) bool {
+	if !isServerWideOptions(p.r) {
+		return false
+	}
+	p.recorder.SetMetadata("response_type", "options")
+	handleServerWideOptions(p.recorder)
+	return true
+}
+
+// serveRequestTargetStage refuses CONNECT and reduces absolute-form targets
+// to their path before anything routes on them
+func (h *Handler) serveRequestTargetStage(p *pipelineRequest) bool {
+	status, reason := checkRequestTarget(p.r, h.config.Server.AbsoluteURI, h.config.Server.AbsoluteURIHostMismatch, h.config.Server.Hostname)
+	if status == 0 {
+		return false
+	}
+	p.recorder.SetMetadata("response_type", "error")
+	p.recorder.SetMetadata("error_message", reason)
+	if status == http.StatusMethodNotAllowed {
+		p.recorder.Header().Set("Allow", allowedMethods)
+	}
+	http.Error(p.recorder, http.StatusText(status), status)
+	return true
+}
+
+// serveNormalizeStage normalizes the path once so auth, rewrites, routes,
+// and static serving agree, then starts body capture and logs the request
+func (h *Handler) serveNormalizeStage(p *pipelineRequest) bool {
+	if !normalizeRequest(p.r, h.config.Server.EncodedSlashes) {
+		p.recorder.SetMetadata("response_type", "error")
+		p.recorder.SetMetadata("error_message", "encoded slash in path")
+		http.Error(p.recorder, "Bad Request", http.StatusBadRequest)
+		return true
+	}
+
+	if trace := p.recorder.trace; trace != nil {
+		trace.NormalizedPath = p.r.URL.Path
+		return false
+	}
+
+	p.recorder.capture = newBodyCapture(&h.config.Logging.Capture, p.r)
+	p.recorder.flight = newFlightRecording(&h.config.Logging.FlightRecorder, p.r, p.requestID)
+
+	// Log request start
+	logging.LogRequest(p.r.Method, p.r.URL.Path, p.requestID)
+	return false
+}
+
+// serveACMEStage answers ACME HTTP-01 challenges before auth, rewrites,
+// maintenance, and tenants
+func (h *Handler) serveACMEStage(p *pipelineRequest) bool {
+	if !h.handleACMEChallenge(p.recorder, p.r) {
+		return false
+	}
+	p.recorder.requestKind = idle.RequestStatic
+	return true
+}
+
+// serveWellKnownStage serves configured robots.txt, security.txt, and other
+// well-known files
+func (h *Handler) serveWellKnownStage(p *pipelineRequest) bool {
+	if !h.handleWellKnown(p.recorder, p.r) {
+		return false
+	}
+	p.recorder.requestKind = idle.RequestStatic
+	return true
+}
+
+// serveHealthCheckStage handles the health check endpoint
+func (h *Handler) serveHealthCheckStage(p *pipelineRequest) bool {
+	if h.config.Server.HealthCheck.Path == "" || p.r.URL.Path != h.config.Server.HealthCheck.Path {
+		return false
+	}
+	if trace := p.recorder.trace; trace != nil {
+		return trace.dispose("health-check", "", 0)
+	}
+	p.recorder.requestKind = idle.RequestHealthCheck
+	h.handleHealthCheck(p.recorder, p.r)
+	return true
+}
+
+// serveDetailedHealthCheckStage handles the JSON readiness endpoint
+func (h *Handler) serveDetailedHealthCheckStage(p *pipelineRequest) bool {
+	if h.config.Server.HealthCheck.DetailedPath == "" || p.r.URL.Path != h.config.Server.HealthCheck.DetailedPath {
+		return false
+	}
+	if trace := p.recorder.trace; trace != nil {
+		return trace.dispose("health-check", "", 0)
+	}
+	p.recorder.requestKind = idle.RequestHealthCheck
+	h.handleDetailedHealthCheck(p.recorder, p.r)
+	return true
+}
+
+// serveBroadcastStage handles the broadcast endpoint before authentication,
+// so tenant Rails processes can broadcast without credentials
+func (h *Handler) serveBroadcastStage(p *pipelineRequest) bool {
+	if !h.cableEnabled() || h.config.Cable.BroadcastPath == "" || p.r.URL.Path != h.config.Cable.BroadcastPath {
+		return false
+	}
+	// Verify request is from localhost for security
+	if !isLocalhostRequest(p.r) {
+		http.Error(p.recorder, "Forbidden: "+h.config.Cable.BroadcastPath+" is only accessible from localhost", http.StatusForbidden)
+		return true
+	}
+	if trace := p.recorder.trace; trace != nil {
+		return trace.dispose("broadcast", "", 0)
+	}
+	p.recorder.SetMetadata("response_type", "broadcast")
+	h.cableHandler.HandleBroadcast(p.recorder, p.r)
+	return true
+}
+
+// serveLocalhostOnly answers a request for a localhost-only endpoint at
+// path, or at a path beneath it when prefix is set
+func serveLocalhostOnly(p *pipelineRequest, path string, prefix bool, responseType string, serve func(http.ResponseWriter, *http.Request)) bool {
+	if path == "" {
+		return false
+	}
+	if prefix && !strings.HasPrefix(p.r.URL.Path, path+"/") || !prefix && p.r.URL.Path != path {
+		return false
+	}
+	if !isLocalhostRequest(p.r) {
+		http.Error(p.recorder, "Forbidden: "+path+" is only accessible from localhost", http.StatusForbidden)
+		return true
+	}
+	if trace := p.recorder.trace; trace != nil {
+		return trace.dispose(responseType, "", 0)
+	}
+	p.recorder.SetMetadata("response_type", responseType)
+	serve(p.recorder, p.r)
+	return true
+}
+
+// serveCachePurgeStage handles response cache statistics and purging
+func (h *Handler) serveCachePurgeStage(p *pipelineRequest) bool {
+	return serveLocalhostOnly(p, h.config.Server.ResponseCache.PurgePath, false, "cache-purge", handleResponseCachePurge)
+}
+
+// serveDiagnosticsStage handles the diagnostics endpoint
+func (h *Handler) serveDiagnosticsStage(p *pipelineRequest) bool {
+	return serveLocalhostOnly(p, h.config.Server.Diagnostics.Path, false, "diagnostics", handleDiagnostics)
+}
+
+// serveExplainStage handles the route explain endpoint
+func (h *Handler) serveExplainStage(p *pipelineRequest) bool {
+	return serveLocalhostOnly(p, h.config.Server.Diagnostics.ExplainPath, false, "explain", h.handleExplain)
+}
+
+// serveControlStage handles the control API
+func (h *Handler) serveControlStage(p *pipelineRequest) bool {
+	return serveLocalhostOnly(p, h.config.Server.ControlPath, true, "control", h.handleControl)
+}
+
+// serveCanonicalStage sends requests to the canonical host and scheme
+// before auth, rewrites, and tenants see them
+func (h *Handler) serveCanonicalStage(p *pipelineRequest) bool {
+	return h.serveCanonicalRedirect(p.recorder, p.r)
+}
+
+// serveTenantAliasStage resolves tenant aliases before auth so public paths,
+// try_files, and tenant routing treat an alias exactly like the tenant's
+// primary path
+func (h *Handler) serveTenantAliasStage(p *pipelineRequest) bool {
+	return h.handleTenantAlias(p.recorder, p.r)
+}
+
+// serveUploadStage handles uploads, which check the credentials of their own
+// auth scope, before the general check and before anything else can answer
+// PUT, DELETE, or MKCOL
+func (h *Handler) serveUploadStage(p *pipelineRequest) bool {
+	return h.handleUpload(p.recorder, p.r)
+}
+
+// serveAuthStage checks authentication before any routing decisions, which
+// prevents authentication bypass via reverse proxies, fly-replay, etc. The
+// most specific auth scope covering the path decides credentials and realm,
+// unless the route serving the request sets its own auth.
+func (h *Handler) serveAuthStage(p *pipelineRequest) bool {
+	// Scheduled tasks with bypass_auth carry a token that is never passed
+	// on, as do warmers
+	p.bypassAuth = scheduler.Authorized(p.r) || p.recorder.warming
+	p.r.Header.Del(scheduler.TokenHeader)
+
+	var step *TraceStep
+	if trace := p.recorder.trace; trace != nil {
+		step = trace.step()
+	}
+	return h.authorize(p, h.rewrittenRequest(p.r), step)
+}
+
+// authorize decides how the request is authenticated for the route target
+// leads to, and answers it if its credentials are missing or wrong. The
+// decision is traced to step, if the request is being traced.
+func (h *Handler) authorize(p *pipelineRequest, target *http.Request, step *TraceStep) bool {
+	p.authPath = target.URL.Path
+	decision := h.decideAuth(target)
+	scope := decision.scope
+	p.public = decision.public
+	p.needsAuth = decision.needsAuth() && !p.bypassAuth
+
+	trace := p.recorder.trace
+	if trace != nil {
+		traceAuth(step, decision)
+	}
+
+	if !p.needsAuth || (scope != nil && scope == p.authorized) {
+		return false
+	}
+	if !scope.IsEnabled() {
+		// Never serve a required route anonymously, even if its auth file failed to load
+		logging.LogRouteAuthUnavailable(decision.rule, p.r.URL.Path)
+		http.Error(p.recorder, "Forbidden", http.StatusForbidden)
+		return true
+	}
+	// A trace assumes valid credentials
+	if trace != nil {
+		return false
+	}
+	if !scope.CheckAuth(p.r) {
+		p.recorder.SetMetadata("response_type", "auth-failure")
+		scope.RequireAuth(p.recorder)
+		return true
+	}
+	p.recorder.SetMetadata("auth_realm", scope.Realm)
+	p.authorized = scope
+	return false
+}
+
+// serveCableStage handles the WebSocket endpoint, after the auth check
+func (h *Handler) serveCableStage(p *pipelineRequest) bool {
+	if !h.cableEnabled() || h.config.Cable.Path == "" || p.r.URL.Path != h.config.Cable.Path {
+		return false
+	}
+	if trace := p.recorder.trace; trace != nil {
+		return trace.dispose("websocket", "", 0)
+	}
+	p.recorder.SetMetadata("response_type", "websocket")
+	h.cableHandler.ServeHTTP(p.recorder, h.trackCableActivity(p.recorder, p.r))
+	return true
+}
+
+// serveRewritesStage handles rewrites and redirects
+func (h *Handler) serveRewritesStage(p *pipelineRequest) bool {
+	if h.handleRewrites(p.recorder, p.r) {
+		return true
+	}
+	if p.r.URL.Path == p.authPath {
+		return false
+	}
+
+	// The rewrites led somewhere the auth stage didn't foresee, such as
+	// past a fly-replay whose target was down: authenticate the request
+	// again for the route it now leads to
+	var step *TraceStep
+	if trace := p.recorder.trace; trace != nil {
+		trace.Steps = append(trace.Steps, TraceStep{Stage: "auth"})
+		step = &trace.Steps[len(trace.Steps)-1]
+	}
+	return h.authorize(p, p.r, step)
+}
+
+// serveLoadSheddingStage sheds load while resources are exhausted; health
+// checks and localhost endpoints were answered before it, and static files
+// are still served
+func (h *Handler) serveLoadSheddingStage(p *pipelineRequest) bool {
+	return h.shedLoad(p.recorder, p.r, p.needsAuth, p.public)
+}
+
+// serveRequestBodyStage decodes compressed request bodies and enforces the
+// body size limits before CGI scripts, reverse proxies, and tenants read them
+func (h *Handler) serveRequestBodyStage(p *pipelineRequest) bool {
+	return h.limitRequestBody(p.recorder, p.r, p.routedTenant(h.config))
+}
+
+// serveCGIStage handles CGI scripts
+func (h *Handler) serveCGIStage(p *pipelineRequest) bool {
+	return h.handleCGI(p.recorder, p.r)
+}
+
+// serveReverseProxiesStage handles reverse proxies, including WebSockets
+func (h *Handler) serveReverseProxiesStage(p *pipelineRequest) bool {
+	return h.handleReverseProxies(p.recorder, p.r)
+}
+
+// serveStaticStage serves static files, even during maintenance mode
+func (h *Handler) serveStaticStage(p *pipelineRequest) bool {
+	if !h.staticHandler.ServeStatic(p.recorder, p.r) {
+		return false
+	}
+	p.recorder.requestKind = idle.RequestStatic
+	return true
+}
+
+// serveTryFilesStage tries files for public paths, even during maintenance
+// mode
+func (h *Handler) serveTryFilesStage(p *pipelineRequest) bool {
+	if trace := p.recorder.trace; trace != nil && !p.public {
+		trace.step().Detail = "path is not public"
+	}
+	if !p.public || !h.staticHandler.TryFiles(p.recorder, p.r) {
+		return false
+	}
+	p.recorder.requestKind = idle.RequestStatic
+	return true
+}
+
+// serveSPAStage answers single-page applications' deep links with their
+// fallback file
+func (h *Handler) serveSPAStage(p *pipelineRequest) bool {
+	if !h.staticHandler.ServeSPA(p.recorder, p.r) {
+		return false
+	}
+	p.recorder.requestKind = idle.RequestStatic
+	return true
+}
+
+// serveMaintenanceStage answers while maintenance mode is enabled or a
+// maintenance window is open. Static files were served before it, so only
+// dynamic requests reach it.
+func (h *Handler) serveMaintenanceStage(p *pipelineRequest) bool {
+	return h.serveMaintenance(p.recorder, p.r, &h.config.Maintenance)
+}
+
+// serveTenantsStage proxies the request to its tenant's web app
+func (h *Handler) serveTenantsStage(p *pipelineRequest) bool {
+	recorder, r := p.recorder, p.r
+
+	// The tenant's settings override bot detection, pausing and maintenance
+	tenant := p.routedTenant(h.config)
+
+	// A paused tenant rejects new requests while in-flight ones finish
+	if tenant != nil && h.servePausedTenant(recorder, r, tenant) {
+		return true
+	}
+
+	// A tenant in maintenance answers like global maintenance mode
+	if tenant != nil && h.serveMaintenance(recorder, r, tenant.Maintenance) {
+		return true
+	}
+
+	// Check if this bot should be blocked
+	if h.shouldBlockBot(r, tenant) {
+		recorder.SetMetadata("response_type", "bot-blocked")
+		http.Error(recorder, "Forbidden: Bot access not allowed", http.StatusForbidden)
+		return true
+	}
+
+	// A trace ends where the request would reach a backend
+	if trace := recorder.trace; trace != nil {
+		return traceTenant(trace, r, tenant)
+	}
+
+	// Copy a sample of the tenant's requests to its mirror target
+	if tenant != nil {
+		mirrorRequest(recorder, r, mirrorKey{tenant: tenant.Name}, tenant.Mirror)
+	}
+
+	// Requests for other media types may go to another backend
+	if tenant != nil && h.serveNegotiatedTarget(recorder, r, tenant) {
+		return true
+	}
+
+	h.handleWebAppProxy(recorder, r)
+	return true
+}
+
+// serveFallbackStage serves the static fallback when no tenants are
+// configured
+func (h *Handler) serveFallbackStage(p *pipelineRequest) bool {
+	h.staticHandler.ServeFallback(p.recorder, p.r)
+	return true
+}
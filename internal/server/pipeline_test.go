@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func stageNames(chain []stage) string {
+	names := make([]string, len(chain))
+	for i, s := range chain {
+		names[i] = s.name()
+	}
+	return strings.Join(names, " ")
+}
+
+func TestPipelineStagesHaveUniqueNames(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, s := range pipelineStages {
+		if seen[s.name] {
+			t.Errorf("Stage %q appears twice", s.name)
+		}
+		seen[s.name] = true
+	}
+}
+
+func TestAssembleChainLeavesOutUnusedStages(t *testing.T) {
+	cfg := &config.Config{}
+	h := &Handler{config: cfg, staticHandler: NewStaticFileHandler(cfg)}
+	want := "options request_target normalize tenant_alias upload auth rewrites load_shedding cgi reverse_proxies static try_files spa maintenance fallback"
+	if got := stageNames(h.assembleChain()); got != want {
+		t.Errorf("Chain = %s\nwant %s", got, want)
+	}
+
+	cfg.Server.HealthCheck.Path = "/up"
+	cfg.Server.Canonical.ForceHTTPS = true
+	cfg.Applications.Tenants = []config.Tenant{{Name: "app", Path: "/"}}
+	if got := stageNames(h.assembleChain("tenants", "health_check", "canonical", "fallback")); got != "health_check canonical tenants" {
+		t.Errorf("Partial chain = %s, want the stages in pipeline order", got)
+	}
+}
+
+func TestPartialChain(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.HealthCheck.Path = "/up"
+	cfg.Server.HealthCheck.Response = &config.HealthCheckResponse{Status: http.StatusOK, Body: "healthy"}
+
+	h := &Handler{config: cfg, staticHandler: NewStaticFileHandler(cfg), disableLog: true}
+	h.chain = h.assembleChain("health_check")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/up", nil))
+	if rec.Body.String() != "healthy" {
+		t.Errorf("Body = %q, want the health check's", rec.Body.String())
+	}
+
+	// Nothing in the chain answers other paths
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/other", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("Unanswered request = %d %q, want an empty response", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRoutedTenantIsMatchedOnce(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "boston", Path: "/boston/"}, {Name: "raleigh", Path: "/raleigh/"}}
+
+	p := &pipelineRequest{r: httptest.NewRequest("POST", "/boston/entries", nil)}
+	if tenant := p.routedTenant(cfg); tenant == nil || tenant.Name != "boston" {
+		t.Fatalf("routedTenant = %v, want boston", tenant)
+	}
+
+	// Later stages see the tenant the request was routed to
+	p.r.URL.Path = "/raleigh/entries"
+	if tenant := p.routedTenant(cfg); tenant == nil || tenant.Name != "boston" {
+		t.Errorf("routedTenant after a path change = %v, want boston", tenant)
+	}
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -10,7 +12,9 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/netutil"
 	proxypkg "github.com/rubys/navigator/internal/proxy"
+	"github.com/rubys/navigator/internal/utils"
 )
 
 var upgrader = websocket.Upgrader{
@@ -36,53 +40,83 @@ func singleJoiningSlash(a, b string) string {
 
 // handleReverseProxies checks and handles reverse proxy routes
 func (h *Handler) handleReverseProxies(w http.ResponseWriter, r *http.Request) bool {
-	if h.config.Routes.ReverseProxies == nil {
+	proxy := h.matchReverseProxy(r.URL.Path)
+	if proxy == nil {
 		return false
 	}
 
-	for _, proxy := range h.config.Routes.ReverseProxies {
-		matched := false
+	logging.LogProxyMatch(r.URL.Path, proxy.Target, proxy.WebSocket)
 
-		// Check path pattern match
-		if proxy.Path != "" {
-			if pattern, err := regexp.Compile(proxy.Path); err == nil {
-				if pattern.MatchString(r.URL.Path) {
-					matched = true
-				}
-			}
-		} else if proxy.Prefix != "" {
-			// Simple prefix matching
-			if strings.HasPrefix(r.URL.Path, proxy.Prefix) {
-				matched = true
-			}
-		}
+	if h.serveMaintenance(w, r, proxy.Maintenance) {
+		return true
+	}
 
-		if !matched {
-			continue
+	// Handle CORS preflight (OPTIONS) if response headers are configured
+	if r.Method == "OPTIONS" && len(proxy.ResponseHeaders) > 0 {
+		// Add configured response headers for CORS
+		for key, value := range proxy.ResponseHeaders {
+			w.Header().Set(key, value)
 		}
+		w.WriteHeader(http.StatusOK)
+		return true
+	}
 
-		logging.LogProxyMatch(r.URL.Path, proxy.Target, proxy.WebSocket)
+	// A trace ends where the request would be proxied
+	if trace := tracing(w); trace != nil {
+		return traceReverseProxy(trace, r, proxy)
+	}
 
-		// Handle CORS preflight (OPTIONS) if response headers are configured
-		if r.Method == "OPTIONS" && len(proxy.ResponseHeaders) > 0 {
-			// Add configured response headers for CORS
-			for key, value := range proxy.ResponseHeaders {
-				w.Header().Set(key, value)
-			}
-			w.WriteHeader(http.StatusOK)
-			return true
-		}
+	// Serve from the response cache if this route has one
+	if recorder, ok := w.(*ResponseRecorder); ok && serveFromResponseCache(recorder, r, proxy.Cache, "") {
+		return true
+	}
 
-		// Handle the proxy
-		if proxy.WebSocket && isWebSocketRequest(r) {
-			h.handleWebSocketProxy(w, r, &proxy)
-		} else {
-			h.handleHTTPProxy(w, r, &proxy)
-		}
+	if rejectOversizedHeaders(w, r, proxy.Name, h.headerLimit(proxy.MaxHeaderBytes)) {
 		return true
 	}
 
-	return false
+	// Copy a sample of requests to the mirror target
+	mirrorRequest(w, r, mirrorKey{route: proxy.Name}, proxy.Mirror)
+
+	// Send requests for other media types to the negotiated target
+	varyNegotiation(w.Header(), r, proxy.Negotiate)
+	if target := negotiatedTarget(r, proxy.Negotiate); target != nil {
+		logging.LogNegotiatedTarget(r.URL.Path, target.Target)
+		negotiated := *proxy
+		negotiated.Target = target.Target
+		negotiated.Negotiate = nil
+		proxy = &negotiated
+	}
+
+	// Handle the proxy
+	if proxy.WebSocket && isWebSocketRequest(r) {
+		h.handleWebSocketProxy(w, r, proxy)
+	} else if proxy.ResponseFilter != nil && !isWebSocketRequest(r) {
+		fw := newFilterWriter(w, r, proxy.ResponseFilter, "reverse proxy "+proxy.Name)
+		h.handleHTTPProxy(fw, r, proxy)
+		fw.finish()
+	} else {
+		h.handleHTTPProxy(w, r, proxy)
+	}
+	return true
+}
+
+// matchReverseProxy returns the first reverse proxy route matching path
+func (h *Handler) matchReverseProxy(path string) *config.ProxyRoute {
+	for i := range h.config.Routes.ReverseProxies {
+		proxy := &h.config.Routes.ReverseProxies[i]
+
+		// Check path pattern match
+		if proxy.Path != "" {
+			if pattern, err := regexp.Compile(proxy.Path); err == nil && pattern.MatchString(path) {
+				return proxy
+			}
+		} else if proxy.Prefix != "" && strings.HasPrefix(path, proxy.Prefix) {
+			// Simple prefix matching
+			return proxy
+		}
+	}
+	return nil
 }
 
 // handleHTTPProxy handles regular HTTP reverse proxy
@@ -116,11 +150,18 @@ func (h *Handler) handleHTTPProxy(w http.ResponseWriter, r *http.Request, route
 	// Create reverse proxy
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 
-	// Apply custom transport if compression should be disabled
-	if proxypkg.GetDisableCompression() {
-		proxy.Transport = &http.Transport{
-			DisableCompression: true,
-		}
+	// Dial resolved addresses for routes with a dns block, and write requests
+	// as the outbound block asks; otherwise use the default backend transport
+	var dns *proxypkg.DNSTransport
+	if route.DNS != nil {
+		dns = proxypkg.TransportForDNS(targetURL.Host, route.DNS)
+	}
+	if route.Outbound != nil {
+		proxy.Transport = proxypkg.TransportForOutbound(dns, route.Outbound)
+	} else if dns != nil {
+		proxy.Transport = dns
+	} else {
+		proxy.Transport = proxypkg.DefaultTransport()
 	}
 
 	// Customize the director to modify the request
@@ -168,31 +209,49 @@ func (h *Handler) handleHTTPProxy(w http.ResponseWriter, r *http.Request, route
 			req.URL.RawPath = ""
 		}
 
+		// Emit RFC 7239 Forwarded header combining prior hops with our own entry
+		req.Header.Set("Forwarded", proxypkg.BuildForwardedHeader(r))
+
 		// Apply custom headers
 		for key, value := range route.Headers {
 			// Replace variables
-			headerValue := strings.ReplaceAll(value, "$remote_addr", getClientIP(req))
-			headerValue = strings.ReplaceAll(headerValue, "$scheme", getScheme(req))
-			headerValue = strings.ReplaceAll(headerValue, "$host", req.Host)
+			headerValue := strings.ReplaceAll(value, "$remote_addr", netutil.ClientIP(r, proxypkg.ClientIPTrust()))
+			headerValue = strings.ReplaceAll(headerValue, "$scheme", getScheme(r))
+			headerValue = strings.ReplaceAll(headerValue, "$host", getHost(r))
+			headerValue = utils.Fly().Expand(headerValue)
 			req.Header.Set(key, headerValue)
 		}
 
 		logging.LogProxyHTTPRequest(req.Method, r.URL.Path, req.URL.String())
 	}
 
-	// Add response headers from upstream
-	if len(route.ResponseHeaders) > 0 {
+	// Add response headers and rewrite cookies from upstream
+	if len(route.ResponseHeaders) > 0 || rewritesCookies(route) {
 		proxy.ModifyResponse = func(resp *http.Response) error {
 			for key, value := range route.ResponseHeaders {
 				resp.Header.Set(key, value)
 			}
+			rewriteSetCookies(resp.Header, route)
 			return nil
 		}
 	}
 
 	// Handle errors
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if errors.Is(r.Context().Err(), context.Canceled) {
+			proxypkg.ClientClosed(w, route.Target, err)
+			return
+		}
+		if errors.Is(err, proxypkg.ErrBodyTooLarge) {
+			logging.LogRequestBodyRejected(r.Method, r.URL.Path, "", err.Error())
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		logging.LogProxyError(route.Target, err)
+		if recorder, ok := w.(proxypkg.MetadataSetter); ok {
+			recorder.SetMetadata("error_message", err.Error())
+			recorder.SetMetadata("upstream_error", err.Error())
+		}
 		http.Error(w, "Bad Gateway", http.StatusBadGateway)
 	}
 
@@ -273,15 +332,24 @@ func (h *Handler) handleWebSocketProxy(w http.ResponseWriter, r *http.Request, r
 		}
 	}
 
+	// Emit RFC 7239 Forwarded header combining prior hops with our own entry
+	backendHeader.Set("Forwarded", proxypkg.BuildForwardedHeader(r))
+
 	// Apply custom headers
 	for key, value := range route.Headers {
-		headerValue := strings.ReplaceAll(value, "$remote_addr", getClientIP(r))
+		headerValue := strings.ReplaceAll(value, "$remote_addr", netutil.ClientIP(r, proxypkg.ClientIPTrust()))
 		headerValue = strings.ReplaceAll(headerValue, "$scheme", getScheme(r))
-		headerValue = strings.ReplaceAll(headerValue, "$host", r.Host)
+		headerValue = strings.ReplaceAll(headerValue, "$host", getHost(r))
+		headerValue = utils.Fly().Expand(headerValue)
 		backendHeader.Set(key, headerValue)
 	}
 
-	backendConn, backendResp, err := websocket.DefaultDialer.Dial(targetURL.String(), backendHeader)
+	dialer := *websocket.DefaultDialer
+	dialer.Proxy = proxypkg.EnvProxy
+	if route.DNS != nil {
+		dialer.NetDialContext = proxypkg.TransportForDNS(targetURL.Host, route.DNS).DialContext
+	}
+	backendConn, backendResp, err := dialer.Dial(targetURL.String(), backendHeader)
 	if err != nil {
 		logging.LogWebSocketBackendConnectError(targetURL.String(), err)
 		if backendResp != nil {
@@ -404,25 +472,39 @@ func isWebSocketHeader(header string) bool {
 		strings.ToLower(header) == "sec-websocket-protocol"
 }
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-		parts := strings.Split(ip, ",")
-		return strings.TrimSpace(parts[0])
-	}
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		return ip
-	}
-	return strings.Split(r.RemoteAddr, ":")[0]
-}
-
 // getScheme determines the request scheme
 func getScheme(r *http.Request) string {
+	forwardedProto, hasForwarded := proxypkg.ForwardedProto(r)
+	if hasForwarded && proxypkg.PreferForwarded() {
+		return forwardedProto
+	}
 	if scheme := r.Header.Get("X-Forwarded-Proto"); scheme != "" {
 		return scheme
 	}
+	if hasForwarded {
+		return forwardedProto
+	}
 	if r.TLS != nil {
 		return "https"
 	}
 	return "http"
 }
+
+// getHost determines the original request host. Like the client address,
+// it's taken from Forwarded or X-Forwarded-Host only with trust_proxy, and
+// forwarded_precedence decides between them when both are present.
+func getHost(r *http.Request) string {
+	forwardedHost, hasForwarded := proxypkg.ForwardedHost(r)
+	if hasForwarded && proxypkg.PreferForwarded() {
+		return forwardedHost
+	}
+	if proxypkg.GetTrustProxy() {
+		if host, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Host"), ","); strings.TrimSpace(host) != "" {
+			return strings.TrimSpace(host)
+		}
+	}
+	if hasForwarded {
+		return forwardedHost
+	}
+	return r.Host
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// proxyProtocolV2Signature starts every PROXY protocol v2 header
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtocolV1MaxLength is the longest v1 header, including its CRLF
+const proxyProtocolV1MaxLength = 107
+
+// proxyProtocolSettings say whether connections start with a PROXY protocol
+// header, and from which sources
+type proxyProtocolSettings struct {
+	trusted []*net.IPNet  // Empty = any source
+	timeout time.Duration // Limit on reading the header
+}
+
+// proxyProtocol holds the settings of server.proxy_protocol; nil when it's
+// disabled
+var proxyProtocol atomic.Pointer[proxyProtocolSettings]
+
+// ConfigureProxyProtocol applies server.proxy_protocol to connections
+// accepted from now on
+func ConfigureProxyProtocol(cfg *config.Config) {
+	if !cfg.Server.ProxyProtocol {
+		proxyProtocol.Store(nil)
+		return
+	}
+	proxyProtocol.Store(&proxyProtocolSettings{
+		trusted: cfg.Server.ProxyProtocolNets,
+		timeout: cfg.Server.Timeouts.ReadHeader.OrDefault(config.DefaultReadHeaderTimeout),
+	})
+}
+
+// errMissingProxyHeader is returned for a connection that doesn't start with
+// a header
+var errMissingProxyHeader = errors.New("missing header")
+
+// isLoopback reports whether addr is a loopback address, such as the one
+// Navigator's own scheduled tasks and warmers connect from
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
+
+// trusts reports whether a connection from addr may send a header
+func (s *proxyProtocolSettings) trusts(addr net.Addr) bool {
+	if len(s.trusted) == 0 {
+		return true
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range s.trusted {
+		if network.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyProtocolListener wraps a listener so that, while server.proxy_protocol
+// is enabled, every connection must start with a PROXY protocol header, whose
+// source address becomes the connection's remote address. Connections from
+// untrusted sources are dropped as they're accepted; the header is read by
+// the connection's own goroutine, on its first read, and connections without
+// a valid one are dropped then.
+//
+// Loopback connections, which come from Navigator itself or from the same
+// machine rather than through the load balancer, may omit the header: those
+// from a trusted source are read as usual when they start with one, and
+// those from an untrusted one are served as they are.
+func ProxyProtocolListener(l net.Listener) net.Listener {
+	return &proxyProtocolListener{Listener: l}
+}
+
+type proxyProtocolListener struct {
+	net.Listener
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		settings := proxyProtocol.Load()
+		if settings == nil {
+			return conn, nil
+		}
+		loopback := isLoopback(conn.RemoteAddr())
+		if !settings.trusts(conn.RemoteAddr()) {
+			if loopback {
+				return conn, nil
+			}
+			logging.LogProxyProtocolRejected(conn.RemoteAddr().String(), "untrusted source")
+			_ = conn.Close()
+			continue
+		}
+		return &proxyProtocolConn{Conn: conn, timeout: settings.timeout, optional: loopback}, nil
+	}
+}
+
+// proxyProtocolConn is a connection whose remote address is read from the
+// PROXY protocol header it starts with
+type proxyProtocolConn struct {
+	net.Conn
+	timeout  time.Duration
+	optional bool // The header may be omitted
+
+	once   sync.Once
+	reader *bufio.Reader
+	remote net.Addr // Nil when the header carries no address, as for health checks
+	err    error
+}
+
+// readHeader reads the header once, closing the connection if it's invalid
+func (c *proxyProtocolConn) readHeader() {
+	c.once.Do(func() {
+		c.reader = bufio.NewReader(c.Conn)
+		if c.timeout > 0 {
+			_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+		}
+		c.remote, c.err = readProxyHeader(c.reader)
+		if c.optional && errors.Is(c.err, errMissingProxyHeader) {
+			c.err = nil
+		}
+		if c.err != nil {
+			logging.LogProxyProtocolRejected(c.Conn.RemoteAddr().String(), c.err.Error())
+			_ = c.Conn.Close()
+		}
+	})
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readProxyHeader reads a v1 or v2 PROXY protocol header, returning the
+// source address it carries, or nil for LOCAL and UNKNOWN connections
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	start, err := r.Peek(len(proxyProtocolV2Signature))
+	switch {
+	case bytes.HasPrefix(start, []byte("PROXY ")):
+		return readProxyHeaderV1(r)
+	case bytes.Equal(start, proxyProtocolV2Signature):
+		return readProxyHeaderV2(r)
+	case err != nil:
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	return nil, errMissingProxyHeader
+}
+
+// readProxyHeaderV1 reads a header such as
+// "PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\n"
+func readProxyHeaderV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == proxyProtocolV1MaxLength {
+			return nil, errors.New("v1 header too long")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading v1 header: %w", err)
+		}
+		line = append(line, b)
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (ip.To4() != nil) != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("malformed v1 header %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyHeaderV2 reads a binary header: the signature, version and
+// command, address family, and the length of the addresses that follow
+func readProxyHeaderV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading v2 header: %w", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported version %d", header[12]>>4)
+	}
+	addresses := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, addresses); err != nil {
+		return nil, fmt.Errorf("reading v2 addresses: %w", err)
+	}
+	switch command := header[12] & 0x0f; command {
+	case 0x0: // LOCAL: sent by the load balancer itself
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported command %d", command)
+	}
+
+	var ipLength int
+	switch family := header[13] >> 4; family {
+	case 0x1:
+		ipLength = net.IPv4len
+	case 0x2:
+		ipLength = net.IPv6len
+	default: // Unspecified or Unix socket addresses carry no client IP
+		return nil, nil
+	}
+	if len(addresses) < 2*ipLength+4 {
+		return nil, errors.New("v2 addresses too short")
+	}
+	ip := net.IP(addresses[:ipLength])
+	port := binary.BigEndian.Uint16(addresses[2*ipLength:])
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
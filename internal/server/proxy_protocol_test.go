@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// serveProxyProtocol serves each request's RemoteAddr behind a listener
+// reading PROXY protocol headers from the trusted sources
+func serveProxyProtocol(t *testing.T, trusted ...string) string {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Server.ProxyProtocol = true
+	for _, source := range trusted {
+		_, network, err := net.ParseCIDR(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Server.ProxyProtocolNets = append(cfg.Server.ProxyProtocolNets, network)
+	}
+	cfg.Server.Timeouts.ReadHeader = config.Duration(time.Second)
+	ConfigureProxyProtocol(cfg)
+	t.Cleanup(func() { ConfigureProxyProtocol(&config.Config{}) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.RemoteAddr)
+	})}
+	go func() { _ = srv.Serve(ProxyProtocolListener(listener)) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return listener.Addr().String()
+}
+
+// sendWithHeader sends header and then a request on a new connection,
+// returning the response body, or an error if the connection was dropped
+func sendWithHeader(t *testing.T, addr string, header []byte) (string, error) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	request := "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"
+	if _, err := conn.Write(append(header, request...)); err != nil {
+		return "", err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+// proxyHeaderV2 builds a v2 header for a TCP connection from src
+func proxyHeaderV2(command byte, src net.IP, port uint16) []byte {
+	family, dst := byte(0x11), net.IPv4(10, 0, 0, 1).To4()
+	if src.To4() == nil {
+		family, dst = 0x21, net.ParseIP("fd00::1")
+	} else {
+		src = src.To4()
+	}
+	addresses := append(append([]byte{}, src...), dst...)
+	addresses = binary.BigEndian.AppendUint16(addresses, port)
+	addresses = binary.BigEndian.AppendUint16(addresses, 80)
+	header := append([]byte{}, proxyProtocolV2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func TestProxyProtocolRecoversClientAddress(t *testing.T) {
+	addr := serveProxyProtocol(t)
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\n"), "203.0.113.7:51234"},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::7 fd00::1 51234 80\r\n"), "[2001:db8::7]:51234"},
+		{"v2 TCP4", proxyHeaderV2(0x1, net.ParseIP("198.51.100.9"), 40000), "198.51.100.9:40000"},
+		{"v2 TCP6", proxyHeaderV2(0x1, net.ParseIP("2001:db8::9"), 40000), "[2001:db8::9]:40000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sendWithHeader(t, addr, tt.header)
+			if err != nil || got != tt.want {
+				t.Errorf("RemoteAddr = %q (error %v), want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// Headers without a client address keep the connection's own
+	for _, header := range [][]byte{[]byte("PROXY UNKNOWN\r\n"), proxyHeaderV2(0x0, net.ParseIP("198.51.100.9"), 40000)} {
+		if got, err := sendWithHeader(t, addr, header); err != nil || !strings.HasPrefix(got, "127.0.0.1:") {
+			t.Errorf("RemoteAddr for %q = %q (error %v), want the connection's", header, got, err)
+		}
+	}
+}
+
+func TestProxyProtocolDropsInvalidConnections(t *testing.T) {
+	addr := serveProxyProtocol(t)
+	for _, header := range []string{"PROXY TCP4 not-an-ip 10.0.0.1 1 80\r\n", "PROXY " + strings.Repeat("x", 120)} {
+		if got, err := sendWithHeader(t, addr, []byte(header)); err == nil {
+			t.Errorf("Header %q: got %q, want the connection dropped", header, got)
+		}
+	}
+
+	// The test's connections are from loopback, so an untrusted one is
+	// served as it is, its header read as a malformed request
+	untrusted := serveProxyProtocol(t, "10.0.0.0/8")
+	if got, err := sendWithHeader(t, untrusted, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\n")); err == nil && strings.Contains(got, "203.0.113.7") {
+		t.Errorf("Untrusted source: RemoteAddr = %q, want the header ignored", got)
+	}
+	trusted := serveProxyProtocol(t, "127.0.0.0/8")
+	if got, err := sendWithHeader(t, trusted, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\n")); err != nil || got != "203.0.113.7:51234" {
+		t.Errorf("Trusted source: RemoteAddr = %q (error %v)", got, err)
+	}
+}
+
+func TestProxyProtocolServesLoopbackWithoutHeader(t *testing.T) {
+	for _, trusted := range [][]string{nil, {"127.0.0.0/8"}, {"10.0.0.0/8"}} {
+		addr := serveProxyProtocol(t, trusted...)
+		if got, err := sendWithHeader(t, addr, nil); err != nil || !strings.HasPrefix(got, "127.0.0.1:") {
+			t.Errorf("Trusted %v: RemoteAddr = %q (error %v), want the connection's", trusted, got, err)
+		}
+	}
+}
@@ -1,15 +1,20 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rubys/navigator/internal/config"
+	proxypkg "github.com/rubys/navigator/internal/proxy"
 )
 
 // Test HTTP Proxy Functionality
@@ -693,50 +698,64 @@ func TestIsWebSocketHeader(t *testing.T) {
 	}
 }
 
-func TestGetClientIP(t *testing.T) {
+// TestRemoteAddrMatchesAccessLog checks the client a backend is told about
+// through $remote_addr is the one the access log records
+func TestRemoteAddrMatchesAccessLog(t *testing.T) {
+	received := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Real-IP")
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{}
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{{
+		Name:    "backend",
+		Prefix:  "/api/",
+		Target:  backend.URL,
+		Headers: map[string]string{"X-Real-IP": "$remote_addr"},
+	}}
+	handler := CreateHandler(cfg, nil, nil, nil, nil, func() string { return "" }, time.Now, nil)
+
+	defer proxypkg.SetTrustProxy(false)
+	proxypkg.SetTrustProxy(true)
+
 	tests := []struct {
 		name       string
 		headers    map[string]string
 		remoteAddr string
 		expected   string
 	}{
-		{
-			name:       "X-Forwarded-For header",
-			headers:    map[string]string{"X-Forwarded-For": "192.168.1.1, 10.0.0.1"},
-			remoteAddr: "127.0.0.1:1234",
-			expected:   "192.168.1.1",
-		},
-		{
-			name:       "X-Real-IP header",
-			headers:    map[string]string{"X-Real-IP": "192.168.1.2"},
-			remoteAddr: "127.0.0.1:1234",
-			expected:   "192.168.1.2",
-		},
-		{
-			name:       "RemoteAddr fallback",
-			headers:    map[string]string{},
-			remoteAddr: "192.168.1.3:5678",
-			expected:   "192.168.1.3",
-		},
-		{
-			name:       "X-Forwarded-For takes precedence",
-			headers:    map[string]string{"X-Forwarded-For": "192.168.1.4", "X-Real-IP": "192.168.1.5"},
-			remoteAddr: "127.0.0.1:1234",
-			expected:   "192.168.1.4",
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/", nil)
-			req.RemoteAddr = test.remoteAddr
-			for key, value := range test.headers {
-				req.Header.Set(key, value)
+		{"IPv4 RemoteAddr", nil, "192.0.2.1:45678", "192.0.2.1"},
+		{"IPv6 RemoteAddr", nil, "[2001:db8::1]:45678", "2001:db8::1"},
+		{"multiple X-Forwarded-For hops", map[string]string{"X-Forwarded-For": "203.0.113.1, 10.0.0.1"}, "10.0.0.2:1234", "203.0.113.1"},
+		{"IPv6 X-Forwarded-For hop", map[string]string{"X-Forwarded-For": "[2001:db8::17]:4711, 10.0.0.1"}, "10.0.0.2:1234", "2001:db8::17"},
+		{"X-Forwarded-For hop with a port", map[string]string{"X-Forwarded-For": "203.0.113.1:50123"}, "10.0.0.2:1234", "203.0.113.1"},
+		{"X-Real-IP", map[string]string{"X-Real-IP": "203.0.113.2"}, "10.0.0.2:1234", "203.0.113.2"},
+		{"trusted Forwarded", map[string]string{"Forwarded": `for="[2001:db8:cafe::17]:4711"`, "X-Forwarded-For": "203.0.113.1"}, "10.0.0.2:1234", "2001:db8:cafe::17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureAccessLog(t)
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
 			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
 
-			result := getClientIP(req)
-			if result != test.expected {
-				t.Errorf("Expected %s, got %s", test.expected, result)
+			var forwarded string
+			select {
+			case forwarded = <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("backend was not reached")
+			}
+			entries := parseAccessLog(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 access log entry, got %d: %s", len(entries), buf.String())
+			}
+			if forwarded != tt.expected || entries[0].ClientIP != tt.expected {
+				t.Errorf("X-Real-IP = %q, access log client_ip = %q, want both %q", forwarded, entries[0].ClientIP, tt.expected)
 			}
 		})
 	}
@@ -791,6 +810,40 @@ func TestGetScheme(t *testing.T) {
 	}
 }
 
+func TestGetHost(t *testing.T) {
+	defer proxypkg.SetTrustProxy(false)
+	defer proxypkg.SetForwardedPrecedence("")
+
+	both := map[string]string{"Forwarded": "host=forwarded.example", "X-Forwarded-Host": "x-forwarded.example, proxy.internal"}
+	tests := []struct {
+		name       string
+		trustProxy bool
+		precedence string
+		headers    map[string]string
+		expected   string
+	}{
+		{"untrusted", false, "", both, "example.com"},
+		{"Forwarded preferred", true, config.ForwardedPrecedenceForwarded, both, "forwarded.example"},
+		{"X-Forwarded preferred", true, config.ForwardedPrecedenceXForwarded, both, "x-forwarded.example"},
+		{"X-Forwarded-Host only", true, config.ForwardedPrecedenceForwarded, map[string]string{"X-Forwarded-Host": "x-forwarded.example"}, "x-forwarded.example"},
+		{"Forwarded only", true, config.ForwardedPrecedenceXForwarded, map[string]string{"Forwarded": "host=forwarded.example"}, "forwarded.example"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxypkg.SetTrustProxy(test.trustProxy)
+			proxypkg.SetForwardedPrecedence(test.precedence)
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			if result := getHost(req); result != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, result)
+			}
+		})
+	}
+}
+
 // Benchmark tests
 
 func BenchmarkHTTPProxy(b *testing.B) {
@@ -835,3 +888,94 @@ func BenchmarkHeaderProcessing(b *testing.B) {
 		}
 	}
 }
+
+func TestClientDisconnectCancelsBackendRequest(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	responding := make(chan struct{})
+	backendCancelled := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Generating report"))
+		w.(http.Flusher).Flush()
+		close(responding)
+		select {
+		case <-r.Context().Done():
+			close(backendCancelled)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{{Name: "reports", Prefix: "/reports/", Target: backend.URL}},
+		},
+	}
+	handler := CreateTestHandler(cfg, nil, nil, nil)
+	handler.(*Handler).disableLog = false
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/reports/annual", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-responding
+	cancel() // The user closes the tab mid-response
+	resp.Body.Close()
+
+	select {
+	case <-backendCancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The backend's request context didn't fire when the client went away")
+	}
+
+	server.Close() // Waits for the handler to finish
+	entries := parseAccessLog(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Logged %d entries, want 1", len(entries))
+	}
+	if !entries[0].Disconnected || entries[0].DisconnectedAfter == "" {
+		t.Errorf("client_disconnected = %v, disconnected_after = %q", entries[0].Disconnected, entries[0].DisconnectedAfter)
+	}
+}
+
+func TestHTTPProxy_OutboundIdentityTransfer(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%d %v %s", r.ContentLength, r.TransferEncoding, body)
+	}))
+	defer backend.Close()
+
+	outbound := &config.OutboundConfig{IdentityTransfer: true, MaxBufferedBody: 8}
+	cfg := &config.Config{
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{{Name: "device", Prefix: "/device/", Target: backend.URL, Outbound: outbound}},
+		},
+	}
+	handler := &Handler{config: cfg}
+
+	for _, tc := range []struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"reading", http.StatusOK, "7 [] reading"},
+		{"too long to buffer", http.StatusRequestEntityTooLarge, "Request Entity Too Large\n"},
+	} {
+		// A chunked request has no Content-Length to forward
+		req := httptest.NewRequest("POST", "/device/upload", io.NopCloser(strings.NewReader(tc.body)))
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+		w := httptest.NewRecorder()
+		handler.handleReverseProxies(w, req)
+		if w.Code != tc.wantStatus || w.Body.String() != tc.wantBody {
+			t.Errorf("POST %q = %d %q, want %d %q", tc.body, w.Code, w.Body.String(), tc.wantStatus, tc.wantBody)
+		}
+	}
+}
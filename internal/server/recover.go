@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// recoverPanic answers a request whose handling panicked with a 500 and
+// logs the panic with the request's details and stack. The recorder is
+// finished afterwards, so the access log, flight recorder, and tenant
+// errors see the 500 too. With server.repanic, the panic is raised again
+// once it's logged, leaving it to net/http as before.
+func (h *Handler) recoverPanic(p *pipelineRequest) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	if value == http.ErrAbortHandler {
+		panic(value) // A deliberate abort, such as the reverse proxy's when a copy fails
+	}
+
+	recorder := p.recorder
+	tenant, _ := recorder.metadata["tenant"].(string)
+	logging.LogPanicRecovered(value, p.requestID, p.r.Method, p.r.URL.Path, p.stage, tenant, string(debug.Stack()))
+	recorder.SetMetadata("response_type", "panic")
+	recorder.SetMetadata("error_message", fmt.Sprintf("panic: %v", value))
+
+	if h.config.Server.Repanic {
+		panic(value)
+	}
+
+	recorder.hijackMu.Lock()
+	hijacked := recorder.hijacked != nil
+	recorder.hijackMu.Unlock()
+	if hijacked {
+		return
+	}
+	if recorder.wroteHeader {
+		// Too late for an error page: cut the response short, so the client
+		// doesn't mistake it for a whole one
+		recorder.statusCode = http.StatusInternalServerError
+		panic(http.ErrAbortHandler)
+	}
+
+	header := recorder.Header()
+	for name := range header {
+		delete(header, name)
+	}
+	if h.config.Server.RegionHeaders {
+		setRegionHeaders(header)
+	}
+	h.serveErrorPage(recorder, http.StatusInternalServerError)
+}
+
+// serveErrorPage answers with status and <public_dir>/<status>.html, or
+// the status text when there's no such page
+func (h *Handler) serveErrorPage(w http.ResponseWriter, status int) {
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	if content, err := os.ReadFile(statusPagePath(h.config, status)); err == nil {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write(content)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// newPanicTestHandler returns a handler whose pipeline starts with a stage
+// that panics for /panic, after setting the tenant and a header, followed
+// by a health check at /up. Its access log is written to the returned buffer.
+func newPanicTestHandler(t *testing.T, cfg *config.Config, panicking func(w http.ResponseWriter)) (*Handler, *bytes.Buffer) {
+	t.Helper()
+	cfg.Server.HealthCheck.Path = "/up"
+	cfg.Server.HealthCheck.Response = &config.HealthCheckResponse{Status: http.StatusOK, Body: "healthy"}
+
+	h := &Handler{config: cfg, staticHandler: NewStaticFileHandler(cfg)}
+	panicStage := handlerStage{stageName: "panic_test", handler: h, fn: func(h *Handler, p *pipelineRequest) bool {
+		if p.r.URL.Path != "/panic" {
+			return false
+		}
+		p.recorder.SetMetadata("tenant", "2025/boston")
+		p.recorder.Header().Set("Content-Disposition", "attachment")
+		panicking(p.recorder)
+		return true
+	}}
+	h.chain = append([]stage{panicStage}, h.assembleChain("health_check")...)
+	return h, captureAccessLog(t)
+}
+
+// captureErrorLog sends slog's output, as JSON, to the returned buffer
+func captureErrorLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(original) })
+	return &buf
+}
+
+func TestRecoverPanic(t *testing.T) {
+	publicDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(publicDir, "500.html"), []byte("<h1>Something went wrong</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = publicDir
+	h, accessLog := newPanicTestHandler(t, cfg, func(w http.ResponseWriter) {
+		var tenants map[string]int
+		tenants["boston"]++ // nil map
+	})
+	errorLog := captureErrorLog(t)
+
+	req := httptest.NewRequest("POST", "/panic", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != "<h1>Something went wrong</h1>" {
+		t.Errorf("Response = %d %q, want 500 with public/500.html", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Disposition") != "" {
+		t.Error("Headers set before the panic should be dropped")
+	}
+
+	var report map[string]interface{}
+	for _, line := range strings.Split(errorLog.String(), "\n") {
+		if strings.Contains(line, "Recovered panic") {
+			if err := json.Unmarshal([]byte(line), &report); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if report == nil {
+		t.Fatalf("No panic report in %s", errorLog)
+	}
+	want := map[string]string{"level": "ERROR", "request_id": "req-123", "method": "POST", "path": "/panic", "stage": "panic_test", "tenant": "2025/boston"}
+	for key, value := range want {
+		if report[key] != value {
+			t.Errorf("Report %s = %v, want %q", key, report[key], value)
+		}
+	}
+	if !strings.Contains(report["panic"].(string), "nil map") || !strings.Contains(report["stack"].(string), "recover_test.go") {
+		t.Errorf("Report = %v, want the panic value and the stack", report)
+	}
+
+	entries := parseAccessLog(t, accessLog)
+	if len(entries) != 1 || entries[0].Status != http.StatusInternalServerError || entries[0].ResponseType != "panic" || !strings.Contains(entries[0].ErrorMessage, "nil map") {
+		t.Errorf("Access log = %+v, want the 500", entries)
+	}
+
+	// The server keeps serving
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/up", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "healthy" {
+		t.Errorf("Next request = %d %q, want the health check", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecoverPanicWithoutErrorPage(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = t.TempDir()
+	h, _ := newPanicTestHandler(t, cfg, func(w http.ResponseWriter) { panic("boom") })
+	captureErrorLog(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+	if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != "Internal Server Error" {
+		t.Errorf("Response = %d %q, want a plain 500", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecoverPanicAfterResponseStarted(t *testing.T) {
+	h, accessLog := newPanicTestHandler(t, &config.Config{}, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	})
+	captureErrorLog(t)
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/panic")
+	if err == nil {
+		_, err = bytes.NewBuffer(nil).ReadFrom(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("A response cut short by a panic should fail to read")
+	}
+
+	entries := parseAccessLog(t, accessLog)
+	if len(entries) != 1 || entries[0].Status != http.StatusInternalServerError || entries[0].ResponseType != "panic" {
+		t.Errorf("Access log = %+v, want the request logged as a 500", entries)
+	}
+
+	resp, err = http.Get(server.URL + "/up")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Next request = %v, %v, want the health check", resp, err)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+}
+
+func TestRepanic(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Repanic = true
+	h, _ := newPanicTestHandler(t, cfg, func(w http.ResponseWriter) { panic("boom") })
+	errorLog := captureErrorLog(t)
+
+	defer func() {
+		if value := recover(); value != "boom" {
+			t.Errorf("recover() = %v, want the panic raised again", value)
+		}
+		if !strings.Contains(errorLog.String(), "Recovered panic") {
+			t.Error("The panic should be reported before it's raised again")
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/process"
+)
+
+// Outcomes of a reload attempt in the reload history
+const (
+	ReloadApplied   = "reloaded"
+	ReloadFailed    = "failed"
+	ReloadCoalesced = "coalesced" // Merged into a reload already waiting its turn
+	ReloadCancelled = "cancelled" // Ready hooks stopped by a newer reload
+	ReloadValidated = "validated" // A validate_only request found the config valid
+	ReloadInvalid   = "invalid"   // A validate_only request found errors
+)
+
+// reloadHistorySize is the number of reload attempts kept for the detailed
+// health check
+const reloadHistorySize = 20
+
+// ReloadAttempt is one reload request and what came of it
+type ReloadAttempt struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	Outcome    string    `json:"outcome"`
+	Reason     string    `json:"reason,omitempty"`
+	ConfigFile string    `json:"config_file,omitempty"`
+	Errors     []string  `json:"errors,omitempty"`   // What a validate_only request found wrong
+	Warnings   []string  `json:"warnings,omitempty"` // What it found worth a look
+}
+
+// ReloadStatus reports configuration reloads in the detailed health check
+type ReloadStatus struct {
+	HooksRunning bool            `json:"hooks_running"` // Ready hooks of the last reload are still running
+	Pending      bool            `json:"pending"`       // A reload is waiting for them
+	Reloaded     int64           `json:"reloaded"`
+	Failed       int64           `json:"failed"`
+	Coalesced    int64           `json:"coalesced"`
+	Cancelled    int64           `json:"cancelled"`
+	Validated    int64           `json:"validated"`
+	Invalid      int64           `json:"invalid"`
+	History      []ReloadAttempt `json:"history"` // Most recent last
+}
+
+// ReloadHistory records reload attempts; the zero value is ready to use
+type ReloadHistory struct {
+	mu     sync.Mutex
+	status ReloadStatus
+}
+
+// Record adds an attempt to the history, timestamping it if needed
+func (h *ReloadHistory) Record(attempt ReloadAttempt) {
+	if attempt.Time.IsZero() {
+		attempt.Time = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch attempt.Outcome {
+	case ReloadApplied:
+		h.status.Reloaded++
+	case ReloadFailed:
+		h.status.Failed++
+	case ReloadCoalesced:
+		h.status.Coalesced++
+	case ReloadCancelled:
+		h.status.Cancelled++
+	case ReloadValidated:
+		h.status.Validated++
+	case ReloadInvalid:
+		h.status.Invalid++
+	}
+	h.status.History = append(h.status.History, attempt)
+	if len(h.status.History) > reloadHistorySize {
+		h.status.History = h.status.History[len(h.status.History)-reloadHistorySize:]
+	}
+	process.BumpStateVersion()
+}
+
+// SetState records whether ready hooks are running and a reload is pending
+func (h *ReloadHistory) SetState(hooksRunning, pending bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.HooksRunning = hooksRunning
+	h.status.Pending = pending
+	process.BumpStateVersion()
+}
+
+// Status returns a copy of the history, or nil before the first attempt
+func (h *ReloadHistory) Status() *ReloadStatus {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.status.History) == 0 {
+		return nil
+	}
+	status := h.status
+	status.History = append([]ReloadAttempt(nil), h.status.History...)
+	return &status
+}
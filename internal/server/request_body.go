@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// errBodyTooLarge is returned while reading a request body past its limit
+var errBodyTooLarge = errors.New("request body too large")
+
+// limitedBodyReader reads at most limit bytes, failing with errBodyTooLarge
+// when the body holds more
+type limitedBodyReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (c *limitedBodyReader) Read(p []byte) (int, error) {
+	if c.n > c.limit {
+		return 0, errBodyTooLarge
+	}
+	if remaining := c.limit + 1 - c.n; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.n > c.limit {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// requestBodyEnabled reports whether request bodies are limited or decoded
+// anywhere in the handler's config
+func (h *Handler) requestBodyEnabled() bool {
+	if h.config.Server.MaxRequestBody > 0 || len(h.config.Server.DecompressRequests) > 0 {
+		return true
+	}
+	for _, script := range h.config.Server.CGIScripts {
+		if script.DecompressRequests != nil && len(*script.DecompressRequests) > 0 {
+			return true
+		}
+	}
+	for _, proxy := range h.config.Routes.ReverseProxies {
+		if proxy.DecompressRequests != nil && len(*proxy.DecompressRequests) > 0 {
+			return true
+		}
+	}
+	for _, tenant := range h.config.Applications.Tenants {
+		if tenant.DecompressRequests != nil && len(*tenant.DecompressRequests) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// decompressEncodings returns the content codings decoded for r: those of
+// the route that will serve it (a CGI script, else a reverse proxy, else the
+// tenant the pipeline routes it to) when it sets its own, or the server's
+func (h *Handler) decompressEncodings(r *http.Request, tenant *config.Tenant) []string {
+	var override *[]string
+	if script, ok := h.matchCGI(r); ok {
+		override = script.decompress
+	} else if proxy := h.matchReverseProxy(r.URL.Path); proxy != nil {
+		override = proxy.DecompressRequests
+	} else if tenant != nil {
+		override = tenant.DecompressRequests
+	}
+	if override != nil {
+		return *override
+	}
+	return h.config.Server.DecompressRequests
+}
+
+// limitRequestBody decodes a request body compressed with one of its
+// route's decompress_requests encodings, so CGI scripts and backends
+// receive it plain, and holds bodies to max_request_body, measured once
+// decoded. A compressed body is read into memory, up to
+// max_compressed_request_body, and decoded up to the decoded limit, so a
+// small body can't expand without bound. Answers 413 for a body over
+// either limit and 400 for one that can't be decoded; returns false to pass
+// the request on.
+func (h *Handler) limitRequestBody(recorder *ResponseRecorder, r *http.Request, tenant *config.Tenant) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	server := &h.config.Server
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+	reject := func(status int, reason string) bool {
+		logging.LogRequestBodyRejected(r.Method, r.URL.Path, encoding, reason)
+		recorder.SetMetadata("response_type", "error")
+		recorder.SetMetadata("error_message", reason)
+		http.Error(recorder, http.StatusText(status), status)
+		return true
+	}
+
+	if encoding == "" || !slices.Contains(h.decompressEncodings(r, tenant), encoding) {
+		// Passed through as received, limited as it's read
+		if server.MaxRequestBody <= 0 {
+			return false
+		}
+		if r.ContentLength > server.MaxRequestBody {
+			return reject(http.StatusRequestEntityTooLarge, errBodyTooLarge.Error())
+		}
+		r.Body = http.MaxBytesReader(recorder, r.Body, server.MaxRequestBody)
+		return false
+	}
+
+	if r.ContentLength > server.MaxCompressedRequestBody {
+		return reject(http.StatusRequestEntityTooLarge, "compressed request body too large")
+	}
+	compressed := &limitedBodyReader{r: r.Body, limit: server.MaxCompressedRequestBody}
+	decoded, err := decodeBody(compressed, encoding, server.MaxDecompressedBody)
+	switch {
+	case compressed.n > compressed.limit:
+		return reject(http.StatusRequestEntityTooLarge, "compressed request body too large")
+	case errors.Is(err, errBodyTooLarge):
+		return reject(http.StatusRequestEntityTooLarge, "decompressed request body too large")
+	case err != nil:
+		return reject(http.StatusBadRequest, fmt.Sprintf("invalid %s request body: %v", encoding, err))
+	}
+
+	logging.LogRequestBodyDecoded(r.URL.Path, encoding, compressed.n, int64(len(decoded)))
+	r.Body = io.NopCloser(bytes.NewReader(decoded))
+	r.ContentLength = int64(len(decoded))
+	r.TransferEncoding = nil
+	r.Header.Del("Content-Encoding")
+	r.Header.Set("Content-Length", strconv.Itoa(len(decoded)))
+	return false
+}
+
+// decodeBody decodes body, compressed with encoding, returning
+// errBodyTooLarge once it holds more than limit bytes
+func decodeBody(body io.Reader, encoding string, limit int64) ([]byte, error) {
+	var decoder io.Reader
+	switch encoding {
+	case config.EncodingGzip:
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = gz.Close() }()
+		decoder = gz
+	case config.EncodingBrotli:
+		decoder = brotli.NewReader(body)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return io.ReadAll(&limitedBodyReader{r: decoder, limit: limit})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func gzipBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func brotliBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	br := brotli.NewWriter(&buf)
+	if _, err := br.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := br.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newRequestBodyHandler returns a handler for yaml, with an echo CGI script
+// at /echo that prints its CONTENT_LENGTH and the body it read
+func newRequestBodyHandler(t *testing.T, yaml string) *Handler {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "echo.sh")
+	echo := "#!/bin/sh\necho 'Content-Type: text/plain'\necho\necho \"$CONTENT_LENGTH:$HTTP_CONTENT_ENCODING\"\ncat\n"
+	if err := os.WriteFile(script, []byte(echo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.ParseYAML([]byte(strings.ReplaceAll(yaml, "$SCRIPT", script)))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	return CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{}).(*Handler)
+}
+
+func postBody(h http.Handler, path, encoding string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "http://example.com"+path, bytes.NewReader(body))
+	req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestDecompressedBodyReachesCGIScript(t *testing.T) {
+	handler := newRequestBodyHandler(t, `
+server:
+  decompress_requests: [gzip, br]
+  cgi_scripts:
+    - path: /echo
+      script: $SCRIPT
+`)
+	payload := []byte(`{"scores": [1, 2, 3]}`)
+	want := fmt.Sprintf("%d:\n%s", len(payload), payload)
+
+	for _, tt := range []struct {
+		encoding string
+		body     []byte
+	}{
+		{"gzip", gzipBody(t, payload)},
+		{"br", brotliBody(t, payload)},
+		{"", payload},
+	} {
+		recorder := postBody(handler, "/echo", tt.encoding, tt.body)
+		if recorder.Code != http.StatusOK || recorder.Body.String() != want {
+			t.Errorf("%q: status = %d, body = %q, want %q", tt.encoding, recorder.Code, recorder.Body.String(), want)
+		}
+	}
+
+	if recorder := postBody(handler, "/echo", "gzip", payload); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Invalid gzip body: status = %d, want 400", recorder.Code)
+	}
+}
+
+func TestDecompressedBodyLimits(t *testing.T) {
+	handler := newRequestBodyHandler(t, `
+server:
+  decompress_requests: [gzip]
+  max_request_body: 65536
+  max_compressed_request_body: 32768
+  cgi_scripts:
+    - path: /echo
+      script: $SCRIPT
+`)
+
+	// A few kilobytes that expand to 10MB stop at max_request_body
+	bomb := gzipBody(t, make([]byte, 10*1024*1024))
+	if len(bomb) > 32768 {
+		t.Fatalf("bomb is %d bytes compressed, want it under the compressed limit", len(bomb))
+	}
+	if recorder := postBody(handler, "/echo", "gzip", bomb); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Zip bomb: status = %d, want 413", recorder.Code)
+	}
+
+	random := make([]byte, 40000)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	if recorder := postBody(handler, "/echo", "gzip", gzipBody(t, random)); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Over max_compressed_request_body: status = %d, want 413", recorder.Code)
+	}
+
+	// The limit applies to the decoded size: 60KB compresses well under
+	// the compressed limit and is accepted
+	plain := bytes.Repeat([]byte("a"), 60*1024)
+	if recorder := postBody(handler, "/echo", "gzip", gzipBody(t, plain)); recorder.Code != http.StatusOK {
+		t.Errorf("Under max_request_body: status = %d, want 200", recorder.Code)
+	}
+	if recorder := postBody(handler, "/echo", "", make([]byte, 70*1024)); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Plain body over max_request_body: status = %d, want 413", recorder.Code)
+	}
+}
+
+func TestDecompressRequestsPassThrough(t *testing.T) {
+	type received struct {
+		encoding string
+		body     []byte
+	}
+	got := make(chan received, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.Header.Get("Content-Encoding"), body}
+	}))
+	defer backend.Close()
+
+	handler := newRequestBodyHandler(t, fmt.Sprintf(`
+server:
+  decompress_requests: [gzip]
+routes:
+  reverse_proxies:
+    - name: raw
+      prefix: /raw/
+      target: %[1]s
+      decompress_requests: []
+    - name: api
+      prefix: /api/
+      target: %[1]s
+`, backend.URL))
+
+	payload := []byte(`{"event": "checkin"}`)
+	compressed := gzipBody(t, payload)
+
+	if recorder := postBody(handler, "/raw/events", "gzip", compressed); recorder.Code != http.StatusOK {
+		t.Fatalf("Pass-through: status = %d", recorder.Code)
+	}
+	if r := <-got; r.encoding != "gzip" || !bytes.Equal(r.body, compressed) {
+		t.Errorf("Pass-through route received %q encoded %q, want the compressed body", r.body, r.encoding)
+	}
+
+	if recorder := postBody(handler, "/api/events", "gzip", compressed); recorder.Code != http.StatusOK {
+		t.Fatalf("Decoded: status = %d", recorder.Code)
+	}
+	if r := <-got; r.encoding != "" || !bytes.Equal(r.body, payload) {
+		t.Errorf("Route received %q encoded %q, want the decoded body", r.body, r.encoding)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// isAbsoluteForm reports whether the request target was an absolute URI,
+// as in "GET http://example.com/ HTTP/1.1", rather than a path. Go's server
+// takes the host from such a URI and discards the Host header.
+func isAbsoluteForm(r *http.Request) bool {
+	return r.URL.IsAbs() && !strings.HasPrefix(r.RequestURI, "/")
+}
+
+// checkRequestTarget refuses CONNECT, which Navigator never tunnels, and
+// applies server.absolute_uri and absolute_uri_host_mismatch to
+// absolute-form requests. An accepted absolute-form request is rewritten in
+// place to origin form, so auth, rewrites, and routing see only its path.
+// Returns the status and reason for a refused request, or 0.
+func checkRequestTarget(r *http.Request, absoluteURI, hostMismatch, hostname string) (int, string) {
+	if r.Method == http.MethodConnect {
+		return http.StatusMethodNotAllowed, "CONNECT not supported"
+	}
+	if !isAbsoluteForm(r) {
+		return 0, ""
+	}
+	if absoluteURI == config.AbsoluteURIReject {
+		return http.StatusBadRequest, "absolute-form request target"
+	}
+
+	if hostname != "" && !strings.EqualFold(r.URL.Hostname(), hostname) {
+		switch hostMismatch {
+		case config.HostMismatchUseURI:
+		case config.HostMismatchUseHostname:
+			r.Host = hostname
+		default:
+			return http.StatusBadRequest, "request target host does not match server.hostname"
+		}
+	}
+
+	r.RequestURI = r.URL.RequestURI()
+	r.URL.Scheme, r.URL.Host = "", ""
+	return 0, ""
+}
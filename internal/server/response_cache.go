@@ -0,0 +1,380 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// cachedResponse is a stored response served to later requests
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// size approximates the memory held by the entry
+func (c *cachedResponse) size() int64 {
+	size := len(c.key) + len(c.body)
+	for name, values := range c.header {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return int64(size)
+}
+
+// writeTo serves the stored response; HEAD requests receive headers only
+func (c *cachedResponse) writeTo(w http.ResponseWriter, r *http.Request, now time.Time) {
+	for key, values := range c.header {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.Header().Set("X-Cache", "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(c.stored).Seconds())))
+	w.WriteHeader(c.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(c.body)
+	}
+}
+
+// ResponseCacheStats reports response cache usage
+type ResponseCacheStats struct {
+	Entries   int     `json:"entries"`
+	Bytes     int64   `json:"bytes"`
+	MaxBytes  int64   `json:"max_bytes"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Stores    int64   `json:"stores"`
+	Evictions int64   `json:"evictions"`
+	HitRatio  float64 `json:"hit_ratio"`
+}
+
+// responseCache holds cached responses, evicting the least recently used once
+// the total size exceeds maxMemory
+type responseCache struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	maxMemory int64
+	used      int64
+	order     *list.List // Most recently used at the front
+	entries   map[string]*list.Element
+
+	hits, misses, stores, evictions int64
+}
+
+func newResponseCache(maxMemory int64) *responseCache {
+	return &responseCache{
+		clock:     clock.Real,
+		maxMemory: maxMemory,
+		order:     list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+}
+
+// cachedResponses is shared across handler reloads so cached entries survive a SIGHUP
+var cachedResponses = newResponseCache(config.DefaultResponseCacheMaxMemory)
+
+// setMaxMemory changes the memory bound, evicting entries if it shrank
+func (c *responseCache) setMaxMemory(maxMemory int64) {
+	if maxMemory <= 0 {
+		maxMemory = config.DefaultResponseCacheMaxMemory
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxMemory = maxMemory
+	c.evict()
+}
+
+// get returns the unexpired entry for key, counting a hit or a miss
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cachedResponse)
+		if c.clock.Now().Before(entry.expires) {
+			c.order.MoveToFront(element)
+			c.hits++
+			return entry, true
+		}
+		c.remove(element)
+	}
+	c.misses++
+	return nil, false
+}
+
+// put stores entry, replacing any previous entry with the same key
+func (c *responseCache) put(entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.size() > c.maxMemory {
+		return
+	}
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.used += entry.size()
+	c.stores++
+	c.evict()
+}
+
+// purge removes every entry whose key starts with prefix and returns how many there were
+func (c *responseCache) purge(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	purged := 0
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+			purged++
+		}
+	}
+	return purged
+}
+
+// purgeStoredSince removes every entry stored at or after since and returns
+// how many there were
+func (c *responseCache) purgeStoredSince(since time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	purged := 0
+	for _, element := range c.entries {
+		if !element.Value.(*cachedResponse).stored.Before(since) {
+			c.remove(element)
+			purged++
+		}
+	}
+	return purged
+}
+
+// stats returns a snapshot of the cache counters
+func (c *responseCache) stats() ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := ResponseCacheStats{
+		Entries:   len(c.entries),
+		Bytes:     c.used,
+		MaxBytes:  c.maxMemory,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Stores:    c.stores,
+		Evictions: c.evictions,
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		stats.HitRatio = float64(c.hits) / float64(lookups)
+	}
+	return stats
+}
+
+// evict drops least recently used entries until the cache fits; c.mu must be held
+func (c *responseCache) evict() {
+	for c.used > c.maxMemory {
+		oldest := c.order.Back()
+		if oldest == nil {
+			return
+		}
+		c.remove(oldest)
+		c.evictions++
+	}
+}
+
+// remove deletes an entry; c.mu must be held
+func (c *responseCache) remove(element *list.Element) {
+	entry := element.Value.(*cachedResponse)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	c.used -= entry.size()
+}
+
+// cacheableRequest reports whether a request may be answered from, or stored in, the cache
+func cacheableRequest(r *http.Request, cfg *config.ResponseCacheConfig) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if proxy.IsWebSocketRequest(r) || r.Header.Get("Range") != "" {
+		return false
+	}
+	if !cfg.CacheAuthenticated && (r.Header.Get("Cookie") != "" || r.Header.Get("Authorization") != "") {
+		return false
+	}
+	if len(cfg.PathPatterns) == 0 {
+		return true
+	}
+	for _, pattern := range cfg.PathPatterns {
+		if pattern.MatchString(r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseCacheKey identifies requests that receive the same response. Keys
+// begin with the path so entries can be purged by path prefix; the host and
+// tenant follow, so the same path on another host or tenant is never served
+// a response cached for this one.
+func responseCacheKey(r *http.Request, cfg *config.ResponseCacheConfig, tenantName string) string {
+	var key strings.Builder
+	key.WriteString(r.URL.Path)
+
+	query := r.URL.Query()
+	separator := byte('?')
+	for _, name := range cfg.QueryParams {
+		if !query.Has(name) {
+			continue
+		}
+		key.WriteByte(separator)
+		separator = '&'
+		key.WriteString(url.QueryEscape(name))
+		key.WriteByte('=')
+		key.WriteString(url.QueryEscape(query.Get(name)))
+	}
+
+	key.WriteString("\nHost: ")
+	key.WriteString(getHost(r))
+	if tenantName != "" {
+		key.WriteString("\nTenant: ")
+		key.WriteString(tenantName)
+	}
+
+	for _, name := range cfg.VaryHeaders {
+		key.WriteByte('\n')
+		key.WriteString(http.CanonicalHeaderKey(name))
+		key.WriteString(": ")
+		key.WriteString(strings.Join(r.Header.Values(name), ", "))
+	}
+	return key.String()
+}
+
+// cacheFill collects a response as it is written so it can be stored when the
+// handler finishes
+type cacheFill struct {
+	key     string
+	cfg     *config.ResponseCacheConfig
+	body    bytes.Buffer
+	written bool
+	failed  bool // Set once the response is too large or a write failed
+}
+
+// write copies body bytes written to the client, up to the object size limit
+func (f *cacheFill) write(data []byte, complete bool) {
+	f.written = true
+	if !complete {
+		f.failed = true
+	}
+	if f.failed {
+		return
+	}
+	if int64(f.body.Len()+len(data)) > f.cfg.MaxObjectSize {
+		f.failed = true
+		f.body = bytes.Buffer{}
+		return
+	}
+	f.body.Write(data)
+}
+
+// finish stores the response if it is complete and safe to share
+func (f *cacheFill) finish(r *http.Request, status int, header http.Header) {
+	if f.failed || !f.written || status != http.StatusOK || r.Context().Err() != nil {
+		return
+	}
+	// A Set-Cookie would hand one client's session to everyone else
+	if len(header.Values("Set-Cookie")) > 0 {
+		return
+	}
+	if length := header.Get("Content-Length"); length != "" && length != strconv.Itoa(f.body.Len()) {
+		return
+	}
+	if f.cfg.HonorOriginHeaders && forbidsCaching(header.Get("Cache-Control")) {
+		return
+	}
+
+	stored := header.Clone()
+	stored.Del("X-Cache")
+	now := cachedResponses.clock.Now()
+	cachedResponses.put(&cachedResponse{
+		key:     f.key,
+		status:  status,
+		header:  stored,
+		body:    bytes.Clone(f.body.Bytes()),
+		stored:  now,
+		expires: now.Add(f.cfg.TTL.Std()),
+	})
+}
+
+// forbidsCaching reports whether a Cache-Control header rules out a shared cache
+func forbidsCaching(cacheControl string) bool {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return true
+		}
+	}
+	return false
+}
+
+// serveFromResponseCache answers r from the cache when possible. On a miss the
+// recorder is set up to store the response once the handler finishes.
+// tenantName is empty for reverse proxy routes.
+func serveFromResponseCache(recorder *ResponseRecorder, r *http.Request, cfg *config.ResponseCacheConfig, tenantName string) bool {
+	if cfg == nil || !cacheableRequest(r, cfg) {
+		return false
+	}
+
+	key := responseCacheKey(r, cfg, tenantName)
+	if entry, ok := cachedResponses.get(key); ok {
+		recorder.SetMetadata("response_type", "cache-hit")
+		entry.writeTo(recorder, r, cachedResponses.clock.Now())
+		return true
+	}
+
+	recorder.Header().Set("X-Cache", "MISS")
+	if r.Method == http.MethodGet {
+		recorder.cacheFill = &cacheFill{key: key, cfg: cfg}
+	}
+	return false
+}
+
+// handleResponseCachePurge reports cache statistics (GET) or purges entries
+// whose key starts with the prefix query parameter (POST or DELETE)
+func handleResponseCachePurge(w http.ResponseWriter, r *http.Request) {
+	var result interface{}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		result = cachedResponses.stats()
+	case http.MethodPost, http.MethodDelete:
+		prefix := r.URL.Query().Get("prefix")
+		purged := cachedResponses.purge(prefix)
+		logging.LogResponseCachePurged(prefix, purged)
+		result = map[string]int{"purged": purged}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// useResponseCache replaces the shared response cache for the duration of a test
+func useResponseCache(t *testing.T, maxMemory int64) *responseCache {
+	t.Helper()
+	cache := newResponseCache(maxMemory)
+	original := cachedResponses
+	cachedResponses = cache
+	t.Cleanup(func() { cachedResponses = original })
+	return cache
+}
+
+func testCacheEntry(key string, body string, ttl time.Duration) *cachedResponse {
+	now := time.Now()
+	return &cachedResponse{key: key, status: http.StatusOK, header: http.Header{}, body: []byte(body), stored: now, expires: now.Add(ttl)}
+}
+
+func TestResponseCacheLRUEviction(t *testing.T) {
+	entry := testCacheEntry("/a", "0123456789", time.Minute)
+	cache := newResponseCache(3 * entry.size())
+
+	cache.put(testCacheEntry("/a", "0123456789", time.Minute))
+	cache.put(testCacheEntry("/b", "0123456789", time.Minute))
+	cache.put(testCacheEntry("/c", "0123456789", time.Minute))
+
+	// Using /a makes /b the least recently used
+	if _, ok := cache.get("/a"); !ok {
+		t.Fatal("expected /a to be cached")
+	}
+	cache.put(testCacheEntry("/d", "0123456789", time.Minute))
+
+	if _, ok := cache.get("/b"); ok {
+		t.Error("least recently used entry should have been evicted")
+	}
+	for _, key := range []string{"/a", "/c", "/d"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("expected %s to remain cached", key)
+		}
+	}
+
+	stats := cache.stats()
+	if stats.Entries != 3 || stats.Evictions != 1 || stats.Bytes > stats.MaxBytes {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.Hits != 4 || stats.Misses != 1 || stats.HitRatio != 0.8 {
+		t.Errorf("hits/misses/ratio = %d/%d/%v, want 4/1/0.8", stats.Hits, stats.Misses, stats.HitRatio)
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := newResponseCache(1024)
+	fake := clock.NewFake(time.Now())
+	cache.clock = fake
+
+	cache.put(testCacheEntry("/feed", "data", time.Minute))
+	if _, ok := cache.get("/feed"); !ok {
+		t.Fatal("expected fresh entry")
+	}
+	fake.Advance(2 * time.Minute)
+	if _, ok := cache.get("/feed"); ok {
+		t.Error("expired entry should not be served")
+	}
+	if stats := cache.stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("expired entry should be removed, got %+v", stats)
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	cfg := &config.ResponseCacheConfig{QueryParams: []string{"year", "format"}, VaryHeaders: []string{"accept"}}
+
+	a := httptest.NewRequest("GET", "http://example.com/schedule?format=ics&utm_source=mail&year=2025", nil)
+	b := httptest.NewRequest("GET", "http://example.com/schedule?year=2025&format=ics", nil)
+	if responseCacheKey(a, cfg, "boston") != responseCacheKey(b, cfg, "boston") {
+		t.Error("unlisted query parameters and parameter order should not change the key")
+	}
+	if key := responseCacheKey(a, cfg, "boston"); key != "/schedule?year=2025&format=ics\nHost: example.com\nTenant: boston\nAccept: " {
+		t.Errorf("key = %q", key)
+	}
+	if responseCacheKey(a, cfg, "boston") == responseCacheKey(b, cfg, "raleigh") {
+		t.Error("the tenant should be part of the key")
+	}
+
+	b.Host = "other.example.com"
+	if responseCacheKey(a, cfg, "boston") == responseCacheKey(b, cfg, "boston") {
+		t.Error("the host should be part of the key")
+	}
+
+	b.Host = a.Host
+	b.Header.Set("Accept", "application/json")
+	if responseCacheKey(a, cfg, "boston") == responseCacheKey(b, cfg, "boston") {
+		t.Error("vary headers should be part of the key")
+	}
+}
+
+func TestCacheableRequest(t *testing.T) {
+	cfg := &config.ResponseCacheConfig{PathPatterns: []*regexp.Regexp{regexp.MustCompile(`\.ics$`)}}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		want   bool
+	}{
+		{"matching GET", "GET", "/2025/boston/calendar.ics", "", true},
+		{"matching HEAD", "HEAD", "/2025/boston/calendar.ics", "", true},
+		{"POST", "POST", "/2025/boston/calendar.ics", "", false},
+		{"path not listed", "GET", "/2025/boston/heats", "", false},
+		{"authenticated", "GET", "/2025/boston/calendar.ics", "Authorization", false},
+		{"cookie", "GET", "/2025/boston/calendar.ics", "Cookie", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, "x")
+			}
+			if got := cacheableRequest(req, cfg); got != tt.want {
+				t.Errorf("cacheableRequest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	authenticated := *cfg
+	authenticated.CacheAuthenticated = true
+	req := httptest.NewRequest("GET", "/2025/boston/calendar.ics", nil)
+	req.Header.Set("Authorization", "Basic eA==")
+	if !cacheableRequest(req, &authenticated) {
+		t.Error("cache_authenticated should allow authenticated requests")
+	}
+}
+
+func TestReverseProxyResponseCache(t *testing.T) {
+	useResponseCache(t, config.DefaultResponseCacheMaxMemory)
+
+	var requests int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/api/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"request":%d}`, n)
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{}
+	cfg.Server.ResponseCache.PurgePath = "/_navigator/cache"
+	cfg.Routes.ReverseProxies = []config.ProxyRoute{{
+		Name:   "api",
+		Prefix: "/api/",
+		Target: backend.URL,
+		Cache: &config.ResponseCacheConfig{
+			TTL:                config.Duration(time.Minute),
+			MaxObjectSize:      1024,
+			HonorOriginHeaders: true,
+		},
+	}}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("/api/schedule")
+	second := get("/api/schedule")
+	if first.Header().Get("X-Cache") != "MISS" || second.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("X-Cache = %q then %q, want MISS then HIT", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("hit served %q (%s), want %q", second.Body.String(), second.Header().Get("Content-Type"), first.Body.String())
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("backend requests = %d, want 1", n)
+	}
+
+	// Cache-Control: no-store from the backend disables caching
+	get("/api/private")
+	if rec := get("/api/private"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Error("no-store response should not be cached")
+	}
+
+	// Authenticated requests bypass the cache entirely
+	if rec := get("/api/schedule", "Authorization", "Basic eA=="); rec.Header().Get("X-Cache") != "" {
+		t.Errorf("authenticated request X-Cache = %q, want none", rec.Header().Get("X-Cache"))
+	}
+
+	// Purge by key prefix from localhost
+	purge := httptest.NewRequest("POST", "/_navigator/cache?prefix=/api/sched", nil)
+	purge.RemoteAddr = "127.0.0.1:40000"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, purge)
+	var purged map[string]int
+	if err := json.Unmarshal(rec.Body.Bytes(), &purged); err != nil || purged["purged"] != 1 {
+		t.Fatalf("purge response = %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get("/api/schedule"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Error("purged entry should be fetched again")
+	}
+
+	// Statistics are available from localhost only
+	stats := httptest.NewRequest("GET", "/_navigator/cache", nil)
+	stats.RemoteAddr = "127.0.0.1:40000"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, stats)
+	var snapshot ResponseCacheStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil || snapshot.Hits != 1 {
+		t.Errorf("stats = %q", rec.Body.String())
+	}
+
+	remote := httptest.NewRequest("POST", "/_navigator/cache", nil)
+	remote.RemoteAddr = "203.0.113.9:40000"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, remote)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("remote purge status = %d, want 403", rec.Code)
+	}
+}
+
+func TestResponseCacheSkipsLargeAndUncacheableResponses(t *testing.T) {
+	useResponseCache(t, config.DefaultResponseCacheMaxMemory)
+	cfg := &config.ResponseCacheConfig{TTL: config.Duration(time.Minute), MaxObjectSize: 8}
+
+	tests := []struct {
+		name    string
+		status  int
+		cookie  bool
+		body    string
+		wantHit bool
+	}{
+		{"small ok response", http.StatusOK, false, "small", true},
+		{"too large", http.StatusOK, false, "much too large", false},
+		{"not found", http.StatusNotFound, false, "missing", false},
+		{"set-cookie", http.StatusOK, true, "session", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/" + strings.ReplaceAll(tt.name, " ", "-")
+			req := httptest.NewRequest("GET", path, nil)
+			recorder := NewTestResponseRecorder(httptest.NewRecorder(), nil, req)
+			if serveFromResponseCache(recorder, req, cfg, "") {
+				t.Fatal("unexpected hit on empty cache")
+			}
+			if tt.cookie {
+				recorder.Header().Set("Set-Cookie", "session=abc")
+			}
+			recorder.WriteHeader(tt.status)
+			_, _ = recorder.Write([]byte(tt.body))
+			recorder.Finish(req)
+
+			again := NewTestResponseRecorder(httptest.NewRecorder(), nil, req)
+			if hit := serveFromResponseCache(again, req, cfg, ""); hit != tt.wantHit {
+				t.Errorf("second request hit = %v, want %v", hit, tt.wantHit)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// filterWriter buffers a response whose body a response_filter rewrites, and
+// writes the filtered body once the proxy is done. Responses the filter
+// doesn't apply to pass straight through, as do ones that outgrow
+// max_body_bytes; a filter that fails leaves the body unmodified.
+type filterWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	filter    *config.ResponseFilterConfig
+	source    string // Route or tenant, for logs
+	status    int
+	buffering bool // The response is held for the filter
+	gzipped   bool // The held body is gzip encoded, to be decoded first
+	body      bytes.Buffer
+}
+
+func newFilterWriter(w http.ResponseWriter, r *http.Request, filter *config.ResponseFilterConfig, source string) *filterWriter {
+	return &filterWriter{ResponseWriter: w, r: r, filter: filter, source: source}
+}
+
+// WriteHeader decides whether the response is filtered, holding it if so
+func (f *filterWriter) WriteHeader(status int) {
+	if f.status != 0 {
+		return
+	}
+	f.status = status
+	if reason := f.skipReason(); reason != "" {
+		if reason != "not filtered" {
+			logging.LogResponseFilterSkipped(f.source, f.r.URL.Path, reason)
+		}
+		f.ResponseWriter.WriteHeader(status)
+		return
+	}
+	f.buffering = true
+}
+
+// skipReason returns why the response is served unmodified, or "" if it is
+// filtered
+func (f *filterWriter) skipReason() string {
+	if f.r.Method == http.MethodHead || f.status < http.StatusOK ||
+		f.status == http.StatusNoContent || f.status == http.StatusNotModified {
+		return "not filtered"
+	}
+
+	header := f.Header()
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if !matchesMediaType(f.filter.ContentTypes, mediaType) {
+		return "not filtered"
+	}
+
+	switch encoding := strings.ToLower(header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+	case config.EncodingGzip:
+		if f.filter.Encoded != config.ResponseFilterEncodedDecode {
+			return "compressed response"
+		}
+		f.gzipped = true
+	default:
+		return "unsupported content encoding " + encoding
+	}
+
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && length > f.filter.MaxBodyBytes {
+		return "response too large"
+	}
+	return ""
+}
+
+func (f *filterWriter) Write(p []byte) (int, error) {
+	if f.status == 0 {
+		f.WriteHeader(http.StatusOK)
+	}
+	if !f.buffering {
+		return f.ResponseWriter.Write(p)
+	}
+	if int64(f.body.Len()+len(p)) > f.filter.MaxBodyBytes {
+		logging.LogResponseFilterSkipped(f.source, f.r.URL.Path, "response too large")
+		f.release(f.body.Bytes())
+		return f.ResponseWriter.Write(p)
+	}
+	return f.body.Write(p)
+}
+
+// Flush implements http.Flusher; a held response is flushed once filtered
+func (f *filterWriter) Flush() {
+	if f.buffering {
+		return
+	}
+	if flusher, ok := f.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (f *filterWriter) Unwrap() http.ResponseWriter {
+	return f.ResponseWriter
+}
+
+// release stops holding the response and writes its headers and body
+func (f *filterWriter) release(body []byte) {
+	f.buffering = false
+	f.ResponseWriter.WriteHeader(f.status)
+	_, _ = f.ResponseWriter.Write(body)
+	f.body = bytes.Buffer{}
+}
+
+// finish filters a held response and writes it
+func (f *filterWriter) finish() {
+	if !f.buffering {
+		return
+	}
+	original := f.body.Bytes()
+	filtered, err := f.apply(original)
+	if err != nil {
+		logging.LogResponseFilterFailed(f.source, f.r.URL.Path, err)
+		f.release(original)
+		return
+	}
+
+	header := f.Header()
+	if f.gzipped {
+		header.Del("Content-Encoding")
+	}
+	header.Set("Content-Length", strconv.Itoa(len(filtered)))
+	// The body changed, so it is no longer byte-for-byte what a strong ETag named
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	f.release(filtered)
+}
+
+// apply runs the filter on body, decoding it first if it is gzipped
+func (f *filterWriter) apply(body []byte) ([]byte, error) {
+	if f.gzipped {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("decoding gzip response: %w", err)
+		}
+		decoded, err := io.ReadAll(io.LimitReader(reader, f.filter.MaxBodyBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("decoding gzip response: %w", err)
+		}
+		if int64(len(decoded)) > f.filter.MaxBodyBytes {
+			return nil, errors.New("decoded response larger than max_body_bytes")
+		}
+		body = decoded
+	}
+
+	if f.filter.Command != "" {
+		return runFilterCommand(f.r, f.filter, f.Header().Get("Content-Type"), body)
+	}
+	return applyReplacements(f.filter.Replace, body), nil
+}
+
+// runFilterCommand pipes body through the filter's command, returning its
+// output
+func runFilterCommand(r *http.Request, filter *config.ResponseFilterConfig, contentType string, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), filter.Timeout.Std())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, filter.Command, filter.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"REQUEST_METHOD="+r.Method,
+		"REQUEST_URI="+r.URL.RequestURI(),
+		"CONTENT_TYPE="+contentType)
+	cmd.WaitDelay = config.HookWaitDelay
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%s timed out after %s", filter.Command, filter.Timeout.Std())
+		}
+		return nil, fmt.Errorf("%s: %w: %s", filter.Command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// applyReplacements applies each replace rule in order
+func applyReplacements(rules []config.ResponseReplaceRule, body []byte) []byte {
+	for _, rule := range rules {
+		limit := rule.MaxReplacements
+		if limit == 0 {
+			limit = -1
+		}
+		matches := rule.Regex.FindAllSubmatchIndex(body, limit)
+		if len(matches) == 0 {
+			continue
+		}
+		var out []byte
+		last := 0
+		for _, match := range matches {
+			out = append(out, body[last:match[0]]...)
+			out = rule.Regex.Expand(out, []byte(rule.Replacement), body, match)
+			last = match[1]
+		}
+		body = append(out, body[last:]...)
+	}
+	return body
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newResponseFilterHandler proxies /docs/ to backend, with yaml's
+// response_filter settings
+func newResponseFilterHandler(t *testing.T, backend http.Handler, filter string) http.Handler {
+	t.Helper()
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+	cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+routes:
+  reverse_proxies:
+    - name: docs
+      prefix: /docs/
+      target: %s
+      response_filter: %s
+`, server.URL, filter)))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	return CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+}
+
+func servePage(contentType string, body []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(body)
+	})
+}
+
+func TestResponseFilterInjectsSnippet(t *testing.T) {
+	page := "<html><head><title>Docs</title></head><body></head></body></html>"
+	handler := newResponseFilterHandler(t, servePage("text/html; charset=utf-8", []byte(page)),
+		`{replace: [{pattern: "</head>", replacement: "<script src=/a.js></script></head>", max_replacements: 1}]}`)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/docs/", nil))
+
+	want := "<html><head><title>Docs</title><script src=/a.js></script></head><body></head></body></html>"
+	if body := recorder.Body.String(); body != want {
+		t.Errorf("Body = %q, want %q", body, want)
+	}
+	if length := recorder.Header().Get("Content-Length"); length != fmt.Sprint(len(want)) {
+		t.Errorf("Content-Length = %s, want %d", length, len(want))
+	}
+	if etag := recorder.Header().Get("ETag"); etag != `W/"v1"` {
+		t.Errorf("ETag = %s, want a weak ETag", etag)
+	}
+}
+
+func TestResponseFilterCommandTimeoutServesOriginal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Filter command uses sleep")
+	}
+	page := "<html><head></head></html>"
+	handler := newResponseFilterHandler(t, servePage("text/html", []byte(page)),
+		`{command: sleep, args: ["5"], timeout: 100ms}`)
+
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/docs/", nil))
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Request took %s, want the filter timed out", elapsed)
+	}
+	if recorder.Code != http.StatusOK || recorder.Body.String() != page {
+		t.Errorf("Response = %d %q, want the unmodified page", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestResponseFilterLeavesBinaryAndEncodedResponses(t *testing.T) {
+	image := []byte("\x89PNG</head>\x00\x01")
+	handler := newResponseFilterHandler(t, servePage("image/png", image),
+		`{replace: [{pattern: "</head>", replacement: "injected"}]}`)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/docs/logo.png", nil))
+	if !bytes.Equal(recorder.Body.Bytes(), image) {
+		t.Errorf("Body = %q, want the image untouched", recorder.Body.Bytes())
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte("<head></head>"))
+	_ = zw.Close()
+	gzipped := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressed.Bytes())
+	})
+
+	for encoded, want := range map[string]string{
+		"skip":   "<head></head>",
+		"decode": "<head>injected</head>",
+	} {
+		handler := newResponseFilterHandler(t, gzipped,
+			`{replace: [{pattern: "</head>", replacement: "injected</head>"}], encoded: `+encoded+`}`)
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest("GET", "/docs/", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		handler.ServeHTTP(recorder, request)
+
+		body := recorder.Body.Bytes()
+		if recorder.Header().Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("encoded %s: %v", encoded, err)
+			}
+			body, _ = io.ReadAll(reader)
+		}
+		if string(body) != want {
+			t.Errorf("encoded %s: body = %q, want %q", encoded, body, want)
+		}
+	}
+}
@@ -225,7 +225,7 @@ func TestReverseProxyCustomHeaders(t *testing.T) {
 		{
 			name:          "Remote address variable substitution",
 			headerName:    "X-Real-IP",
-			expectedValue: "203.0.113.45", // Port is stripped by getClientIP
+			expectedValue: "203.0.113.45", // Port is stripped by netutil.ClientIP
 		},
 		{
 			name:          "Static header value",
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// rewriteConditionsMatch reports whether every condition of a rule passes,
+// logging the conditions that let the rule apply
+func rewriteConditionsMatch(rule *config.RewriteRule, r *http.Request) bool {
+	if len(rule.Conditions) == 0 {
+		return true
+	}
+	for i := range rule.Conditions {
+		if !rewriteConditionMatches(&rule.Conditions[i], r) {
+			return false
+		}
+	}
+	matched := make([]string, len(rule.Conditions))
+	for i, condition := range rule.Conditions {
+		matched[i] = condition.String()
+	}
+	logging.LogRewriteConditionsMatched(r.URL.Path, rule.Pattern.String(), matched)
+	return true
+}
+
+// rewriteConditionMatches tests a single condition against the request
+func rewriteConditionMatches(condition *config.RewriteCondition, r *http.Request) bool {
+	switch {
+	case condition.Host != "":
+		return strings.EqualFold(requestHostname(r), condition.Host)
+	case condition.Method != "":
+		return strings.EqualFold(r.Method, condition.Method)
+	}
+
+	value, present := rewriteConditionValue(condition, r)
+	switch {
+	case condition.Present != nil && !*condition.Present:
+		return !present
+	case !present:
+		return false
+	case condition.Regexp != nil:
+		return condition.Regexp.MatchString(value)
+	case condition.Equals != "":
+		return value == condition.Equals
+	}
+	return true
+}
+
+// rewriteConditionValue returns the header, cookie, or query value a
+// condition tests and whether the request has it at all
+func rewriteConditionValue(condition *config.RewriteCondition, r *http.Request) (string, bool) {
+	switch {
+	case strings.EqualFold(condition.Header, "Host"):
+		return r.Host, r.Host != ""
+	case condition.Header != "":
+		values := r.Header.Values(condition.Header)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	case condition.Cookie != "":
+		cookie, err := r.Cookie(condition.Cookie)
+		if err != nil {
+			return "", false
+		}
+		return cookie.Value, true
+	default:
+		values, ok := r.URL.Query()[condition.Query]
+		if !ok || len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	}
+}
+
+// requestHostname returns the request host without any port
+func requestHostname(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return r.Host
+}
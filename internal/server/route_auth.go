@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// authDecision is how a request is authenticated
+type authDecision struct {
+	scope    *auth.BasicAuth // Credentials checked, unless public
+	public   bool
+	rule     string // Public path pattern, or the route whose auth setting decided
+	required bool   // A route requires credentials, whether or not auth is enabled
+}
+
+// needsAuth reports whether credentials are checked
+func (d authDecision) needsAuth() bool {
+	return d.required || (d.scope.IsEnabled() && !d.public)
+}
+
+// decideAuth decides how r is authenticated. The auth setting of the route
+// that will serve r (a CGI script, else a reverse proxy, else the tenant with
+// the longest matching path) overrides the site-wide decision: public skips
+// authentication, and required checks the credentials of its auth_scope even
+// when auth is disabled.
+func (h *Handler) decideAuth(r *http.Request) authDecision {
+	scope := h.auth.ForPath(r.URL.Path)
+	rule, public := scope.PublicRule(r.URL.Path, h.config)
+	decision := authDecision{scope: scope, public: public, rule: rule}
+
+	setting, authScope, route := h.routeAuth(r)
+	switch setting {
+	case config.RouteAuthPublic:
+		decision.public, decision.rule = true, route
+	case config.RouteAuthRequired:
+		decision.scope = h.auth.Required(authScope)
+		decision.public, decision.rule, decision.required = false, route, true
+	}
+	return decision
+}
+
+// routeAuth returns the auth setting and auth scope of the route that will
+// serve r, and the route's description, or "" if it inherits the site-wide
+// settings
+func (h *Handler) routeAuth(r *http.Request) (setting, scope, route string) {
+	if script, ok := h.matchCGI(r); ok {
+		return script.auth, script.authScope, "cgi script " + r.URL.Path
+	}
+	if proxy := h.matchReverseProxy(r.URL.Path); proxy != nil {
+		return proxy.Auth, proxy.AuthScope, "reverse proxy " + proxy.Name
+	}
+	if tenant := h.config.TenantForPath(r.URL.Path); tenant != nil {
+		return tenant.Auth, tenant.AuthScope, "tenant " + tenant.Name
+	}
+	return "", "", ""
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func TestRouteAuthOverridesGlobalAuth(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil { // password
+		t.Fatal(err)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+auth:
+  enabled: %v
+  htpasswd: %s
+routes:
+  rewrites:
+    - from: ^/legacy/admin/(.*)$
+      to: /showcase/admin/$1
+    - from: ^/legacy/assets/(.*)$
+      to: /showcase/assets/$1
+  reverse_proxies:
+    - name: admin
+      prefix: /showcase/admin/
+      target: %s
+      auth: required
+    - name: assets
+      prefix: /showcase/assets/
+      target: %[3]s
+      auth: public
+    - name: showcase
+      prefix: /showcase/
+      target: %[3]s
+`, enabled, htpasswd, backend.URL)))
+		if err != nil {
+			t.Fatalf("ParseYAML: %v", err)
+		}
+		basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+		if err != nil {
+			t.Fatalf("LoadAuthConfig: %v", err)
+		}
+		handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{}).(*Handler)
+		handler.disableLog = false
+		buf := captureAccessLog(t)
+
+		inherited := http.StatusOK
+		if enabled {
+			inherited = http.StatusUnauthorized
+		}
+		tests := []struct {
+			name         string
+			path         string
+			credentials  bool
+			expectStatus int
+		}{
+			{"required", "/showcase/admin/", false, http.StatusUnauthorized},
+			{"required_credentials", "/showcase/admin/", true, http.StatusOK},
+			{"public", "/showcase/assets/app.css", false, http.StatusOK},
+			{"inherit", "/showcase/", false, inherited},
+			{"inherit_credentials", "/showcase/", true, http.StatusOK},
+			// A rewrite is authenticated for the route it leads to
+			{"rewrite_into_required", "/legacy/admin/", false, http.StatusUnauthorized},
+			{"rewrite_into_required_credentials", "/legacy/admin/", true, http.StatusOK},
+			{"rewrite_into_public", "/legacy/assets/app.css", false, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s_enabled_%v", tt.name, enabled), func(t *testing.T) {
+				req := httptest.NewRequest("GET", "http://example.com"+tt.path, nil)
+				if tt.credentials {
+					req.SetBasicAuth("user", "password")
+				}
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				if recorder.Code != tt.expectStatus {
+					t.Errorf("status = %d, want %d", recorder.Code, tt.expectStatus)
+				}
+			})
+		}
+
+		// The access log names the user a required route authenticated
+		found := false
+		for _, entry := range parseAccessLog(t, buf) {
+			if entry.URI == "/showcase/admin/" && entry.Status == http.StatusOK {
+				found = entry.RemoteUser == "user" && entry.AuthRealm == config.DefaultAuthRealm
+			}
+		}
+		if !found {
+			t.Errorf("enabled=%v: access log should record user and realm for the required route", enabled)
+		}
+	}
+}
+
+func TestRequiredRouteWithoutCredentialsIsForbidden(t *testing.T) {
+	cfg := &config.Config{
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{
+				{Name: "admin", Prefix: "/admin/", Target: "http://127.0.0.1:1", Auth: config.RouteAuthRequired},
+			},
+		},
+	}
+	// The htpasswd file failed to load, so there is nothing to check
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	req := httptest.NewRequest("GET", "http://example.com/admin/", nil)
+	req.SetBasicAuth("user", "password")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusForbidden)
+	}
+}
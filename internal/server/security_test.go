@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/rubys/navigator/internal/auth"
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/idle"
 	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/scheduler"
 )
 
 // TestSecurityHeaders tests proper handling of security-related headers
@@ -773,3 +777,406 @@ func TestRateLimitingBehavior(t *testing.T) {
 		t.Logf("No rate limiting detected in rapid request test")
 	}
 }
+
+// TestPathNormalization tests canonicalization of request paths before matching
+func TestPathNormalization(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		expected    string
+		description string
+	}{
+		{"root", "/", "/", "Root path is unchanged"},
+		{"empty", "", "/", "Empty path becomes root"},
+		{"plain", "/admin/panel", "/admin/panel", "Clean path is unchanged"},
+		{"duplicate_slashes", "//admin//panel", "/admin/panel", "Duplicate slashes collapse"},
+		{"trailing_slash", "/admin/", "/admin/", "Trailing slash is preserved"},
+		{"dot_segment", "/admin/./panel", "/admin/panel", "Single-dot segments are removed"},
+		{"dotdot_segment", "/public/../admin", "/admin", "Double-dot segments climb one level"},
+		{"dotdot_above_root", "/../../etc/passwd", "/etc/passwd", "Cannot climb above root"},
+		{"trailing_dotdot", "/admin/panel/..", "/admin/", "Trailing dot-dot keeps directory form"},
+		{"dots_in_names", "/assets/app..js", "/assets/app..js", "Dots inside names are untouched"},
+		{"triple_dot", "/a/.../b", "/a/.../b", "Triple dot is a normal segment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := normalizePath(tt.path); result != tt.expected {
+				t.Errorf("%s: normalizePath(%q) = %q, want %q", tt.description, tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestAuthScopesChallengeWithScopeRealm tests that each auth scope checks its
+// own credentials and challenges with its own realm
+func TestAuthScopesChallengeWithScopeRealm(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	dir := t.TempDir()
+	siteFile := filepath.Join(dir, "site.htpasswd")
+	adminFile := filepath.Join(dir, "admin.htpasswd")
+	if err := os.WriteFile(siteFile, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil { // password
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(adminFile, []byte("admin:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0644); err != nil { // secret
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{
+				{Name: "everything", Prefix: "/", Target: backend.URL},
+			},
+		},
+	}
+	cfg.Auth = config.AuthConfig{
+		Enabled:  true,
+		Realm:    "Showcase",
+		HTPasswd: siteFile,
+		Scopes: []config.AuthScope{
+			{Paths: []string{"/showcase/admin/"}, HTPasswd: adminFile, Realm: "Showcase Admin"},
+		},
+	}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+
+	handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{}).(*Handler)
+	handler.disableLog = false
+	buf := captureAccessLog(t)
+
+	tests := []struct {
+		name         string
+		path         string
+		user, pass   string
+		expectStatus int
+		expectRealm  string
+	}{
+		{"site_no_credentials", "/showcase/", "", "", http.StatusUnauthorized, "Showcase"},
+		{"site_credentials", "/showcase/", "user", "password", http.StatusOK, ""},
+		{"admin_no_credentials", "/showcase/admin/", "", "", http.StatusUnauthorized, "Showcase Admin"},
+		{"admin_with_site_credentials", "/showcase/admin/", "user", "password", http.StatusUnauthorized, "Showcase Admin"},
+		{"admin_credentials", "/showcase/admin/", "admin", "secret", http.StatusOK, ""},
+		{"site_with_admin_credentials", "/showcase/", "admin", "secret", http.StatusUnauthorized, "Showcase"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com"+tt.path, nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.expectStatus)
+			}
+			if tt.expectRealm != "" {
+				want := `Basic realm="` + tt.expectRealm + `"`
+				if got := recorder.Header().Get("WWW-Authenticate"); got != want {
+					t.Errorf("WWW-Authenticate = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+
+	// The access log records which realm authenticated each user
+	realms := map[string]string{}
+	for _, entry := range parseAccessLog(t, buf) {
+		if entry.Status == http.StatusOK {
+			realms[entry.RemoteUser] = entry.AuthRealm
+		}
+	}
+	if realms["user"] != "Showcase" || realms["admin"] != "Showcase Admin" {
+		t.Errorf("auth realms = %v", realms)
+	}
+}
+
+// TestNormalizationPreventsAuthBypass tests that path tricks cannot reach
+// protected routes through public path prefixes
+func TestNormalizationPreventsAuthBypass(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Received-Path", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	htpasswdFile := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswdFile, []byte("user1:$2y$05$HhAkLv4T/hijhH3KQUtfWuuFm15Wwpf4qmdcbZnZILZ0zR3P6bBEG\n"), 0644); err != nil {
+		t.Fatalf("Failed to write htpasswd: %v", err)
+	}
+	basicAuth, err := auth.LoadAuthFile(htpasswdFile, "test", nil)
+	if err != nil {
+		t.Fatalf("Failed to load htpasswd: %v", err)
+	}
+
+	cfg := &config.Config{
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{
+				{Name: "everything", Prefix: "/", Target: backend.URL},
+			},
+		},
+	}
+	cfg.Auth.Enabled = true
+	cfg.Auth.PublicPaths = []string{"/public/"}
+
+	handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{})
+
+	tests := []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectPath   string
+		description  string
+	}{
+		{"public_path", "/public/logo.png", http.StatusOK, "/public/logo.png", "Public path is served without auth"},
+		{"dotdot_bypass", "/public/../admin/panel", http.StatusUnauthorized, "", "Dot-dot out of public prefix requires auth"},
+		{"encoded_dotdot_bypass", "/public/%2e%2e/admin", http.StatusUnauthorized, "", "Encoded dot-dot requires auth"},
+		{"mixed_case_encoding_bypass", "/public/%2E%2e/admin", http.StatusUnauthorized, "", "Mixed-case encoded dot-dot requires auth"},
+		{"encoded_slash_bypass", "/public/..%2Fadmin", http.StatusUnauthorized, "", "Encoded slash with dot-dot requires auth"},
+		{"dot_segment_public", "/public/./logo.png", http.StatusOK, "/public/logo.png", "Dot segments inside public prefix stay public"},
+		{"duplicate_slash_public", "//public//logo.png", http.StatusOK, "/public/logo.png", "Duplicate slashes are collapsed before matching"},
+		{"dotdot_back_into_public", "/admin/../public/logo.png", http.StatusOK, "/public/logo.png", "Resolved path decides public access"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com"+tt.path, nil)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectStatus {
+				t.Errorf("%s: status = %d, want %d", tt.description, recorder.Code, tt.expectStatus)
+			}
+			if received := recorder.Header().Get("Received-Path"); received != tt.expectPath {
+				t.Errorf("%s: backend received %q, want %q", tt.description, received, tt.expectPath)
+			}
+		})
+	}
+}
+
+// TestEncodedSlashHandling tests the server.encoded_slashes setting
+func TestEncodedSlashHandling(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Received-Path", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name           string
+		encodedSlashes string
+		path           string
+		expectStatus   int
+		expectPath     string
+	}{
+		{"decode_default", "", "/api/a%2Fb", http.StatusOK, "/api/a/b"},
+		{"decode_resolves_traversal", EncodedSlashesDecode, "/api/x%2F..%2F..%2Fsecret", http.StatusNotFound, ""},
+		{"reject_uppercase", EncodedSlashesReject, "/api/a%2Fb", http.StatusBadRequest, ""},
+		{"reject_lowercase", EncodedSlashesReject, "/api/a%2fb", http.StatusBadRequest, ""},
+		{"reject_allows_plain", EncodedSlashesReject, "/api/a/b", http.StatusOK, "/api/a/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Routes: config.RoutesConfig{
+					ReverseProxies: []config.ProxyRoute{
+						{Name: "api", Prefix: "/api/", Target: backend.URL},
+					},
+				},
+			}
+			cfg.Server.EncodedSlashes = tt.encodedSlashes
+			handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+			req := httptest.NewRequest("GET", "http://example.com"+tt.path, nil)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.expectStatus)
+			}
+			if received := recorder.Header().Get("Received-Path"); received != tt.expectPath {
+				t.Errorf("backend received %q, want %q", received, tt.expectPath)
+			}
+		})
+	}
+}
+
+// TestServerWideOptions tests that OPTIONS * gets a sane response
+func TestServerWideOptions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.Enabled = true
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	req := httptest.NewRequest("OPTIONS", "*", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("OPTIONS * status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+	if allow := recorder.Header().Get("Allow"); !strings.Contains(allow, "OPTIONS") || !strings.Contains(allow, "GET") {
+		t.Errorf("Allow header = %q, want list of supported methods", allow)
+	}
+	if recorder.Body.Len() != 0 {
+		t.Errorf("OPTIONS * should have empty body, got %q", recorder.Body.String())
+	}
+
+	// OPTIONS on a regular path is routed normally
+	req = httptest.NewRequest("OPTIONS", "/anything", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code == http.StatusNoContent && recorder.Header().Get("Allow") == allowedMethods {
+		t.Error("OPTIONS /anything should not be treated as OPTIONS *")
+	}
+}
+
+// TestConnectRejected tests that CONNECT is refused before auth and routing
+func TestConnectRejected(t *testing.T) {
+	cfg := &config.Config{
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{
+				{Name: "everything", Prefix: "/", Target: "http://127.0.0.1:1"},
+			},
+		},
+	}
+	cfg.Auth.Enabled = true
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	req := httptest.NewRequest("CONNECT", "evil.example:443", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("CONNECT status = %d, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := recorder.Header().Get("Allow"); allow != allowedMethods {
+		t.Errorf("Allow header = %q, want %q", allow, allowedMethods)
+	}
+}
+
+// TestAbsoluteFormRequests tests server.absolute_uri and
+// server.absolute_uri_host_mismatch
+func TestAbsoluteFormRequests(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Received-Path", r.URL.Path)
+		w.Header().Set("Received-Host", r.Host)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	htpasswdFile := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswdFile, []byte("user1:$2y$05$HhAkLv4T/hijhH3KQUtfWuuFm15Wwpf4qmdcbZnZILZ0zR3P6bBEG\n"), 0644); err != nil {
+		t.Fatalf("Failed to write htpasswd: %v", err)
+	}
+	basicAuth, err := auth.LoadAuthFile(htpasswdFile, "test", nil)
+	if err != nil {
+		t.Fatalf("Failed to load htpasswd: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		absoluteURI  string
+		hostMismatch string
+		hostname     string
+		target       string
+		expectStatus int
+		expectPath   string
+		expectHost   string
+	}{
+		{"origin_form_unaffected", config.AbsoluteURIReject, "", "showcase.example", "/public/logo.png", http.StatusOK, "/public/logo.png", "example.com"},
+		{"reject", config.AbsoluteURIReject, "", "", "http://showcase.example/public/logo.png", http.StatusBadRequest, "", ""},
+		{"normalize_routes_by_path", "", "", "", "http://evil.example/public/logo.png", http.StatusOK, "/public/logo.png", "evil.example"},
+		{"normalize_keeps_auth", config.AbsoluteURINormalize, "", "", "http://evil.example/admin/panel", http.StatusUnauthorized, "", ""},
+		{"normalize_resolves_traversal", "", "", "", "http://evil.example/public/../admin", http.StatusUnauthorized, "", ""},
+		{"matching_host", "", "", "showcase.example", "http://Showcase.example:8080/public/logo.png", http.StatusOK, "/public/logo.png", "Showcase.example:8080"},
+		{"mismatch_rejected_by_default", "", "", "showcase.example", "http://evil.example/public/logo.png", http.StatusBadRequest, "", ""},
+		{"mismatch_reject", "", config.HostMismatchReject, "showcase.example", "http://evil.example/public/logo.png", http.StatusBadRequest, "", ""},
+		{"mismatch_use_hostname", "", config.HostMismatchUseHostname, "showcase.example", "http://evil.example/public/logo.png", http.StatusOK, "/public/logo.png", "showcase.example"},
+		{"mismatch_use_uri", "", config.HostMismatchUseURI, "showcase.example", "http://evil.example/public/logo.png", http.StatusOK, "/public/logo.png", "evil.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Routes: config.RoutesConfig{
+					ReverseProxies: []config.ProxyRoute{
+						{Name: "everything", Prefix: "/", Target: backend.URL},
+					},
+				},
+			}
+			cfg.Server.Hostname = tt.hostname
+			cfg.Server.AbsoluteURI = tt.absoluteURI
+			cfg.Server.AbsoluteURIHostMismatch = tt.hostMismatch
+			cfg.Auth.Enabled = true
+			cfg.Auth.PublicPaths = []string{"/public/"}
+			handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{})
+
+			// Like Go's server, NewRequest takes the host from an absolute URI
+			req := httptest.NewRequest("GET", tt.target, nil)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.expectStatus)
+			}
+			if received := recorder.Header().Get("Received-Path"); received != tt.expectPath {
+				t.Errorf("backend received path %q, want %q", received, tt.expectPath)
+			}
+			if received := recorder.Header().Get("Received-Host"); received != tt.expectHost {
+				t.Errorf("backend received host %q, want %q", received, tt.expectHost)
+			}
+		})
+	}
+}
+
+func TestScheduledRequestsBypassAuth(t *testing.T) {
+	var forwarded string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(scheduler.TokenHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Auth: config.AuthConfig{Enabled: true, Realm: "Restricted", HTPasswd: htpasswd},
+		Routes: config.RoutesConfig{
+			ReverseProxies: []config.ProxyRoute{{Name: "admin", Prefix: "/admin/", Target: backend.URL}},
+		},
+	}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{})
+
+	req := httptest.NewRequest("POST", "http://example.com/admin/digest", nil)
+	req.Header.Set(scheduler.TokenHeader, "guess")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Wrong token: status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest("POST", "http://example.com/admin/digest", nil)
+	scheduler.Authorize(req)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Scheduled request: status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if forwarded != "" {
+		t.Errorf("The token should not be passed on, got %q", forwarded)
+	}
+}
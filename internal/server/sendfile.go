@@ -0,0 +1,202 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// Request headers Rack::Sendfile reads to decide whether, and how, to name a
+// file instead of sending it
+const (
+	headerSendfileType = "X-Sendfile-Type"
+	headerAccelMapping = "X-Accel-Mapping"
+)
+
+// setSendfileRequestHeaders tells the app which header Navigator serves files
+// from, and how directories map to X-Accel-Redirect locations. Values a
+// client sent are replaced, so a client can't choose them.
+func setSendfileRequestHeaders(r *http.Request, sendfile *config.SendfileConfig) {
+	r.Header.Set(headerSendfileType, sendfile.Header)
+	r.Header.Del(headerAccelMapping)
+	if len(sendfile.Locations) == 0 {
+		return
+	}
+	var mappings []string
+	for prefix, dir := range sendfile.Locations {
+		mappings = append(mappings, dir+"/="+prefix)
+	}
+	sort.Strings(mappings)
+	r.Header.Set(headerAccelMapping, strings.Join(mappings, ","))
+}
+
+// sendfileWriter serves the file a tenant app names in its sendfile header
+// in place of the app's body. Responses without the header pass straight
+// through.
+type sendfileWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	sendfile  *config.SendfileConfig
+	mimeTypes map[string]string // server.static.mime_types
+	source    string            // Tenant, for logs
+	status    int
+	target    string // The header's value, once the response is intercepted
+}
+
+func newSendfileWriter(w http.ResponseWriter, r *http.Request, sendfile *config.SendfileConfig, mimeTypes map[string]string, source string) *sendfileWriter {
+	return &sendfileWriter{ResponseWriter: w, r: r, sendfile: sendfile, mimeTypes: mimeTypes, source: source}
+}
+
+// WriteHeader intercepts a successful response naming a file
+func (f *sendfileWriter) WriteHeader(status int) {
+	if f.status != 0 {
+		return
+	}
+	f.status = status
+	header := f.Header()
+	if target := header.Get(f.sendfile.Header); target != "" && status >= 200 && status < 300 {
+		f.target = target
+		header.Del(f.sendfile.Header)
+		return
+	}
+	header.Del(f.sendfile.Header) // Never reveal a file's path to the client
+	f.ResponseWriter.WriteHeader(status)
+}
+
+// Write discards the app's body of an intercepted response
+func (f *sendfileWriter) Write(p []byte) (int, error) {
+	if f.status == 0 {
+		f.WriteHeader(http.StatusOK)
+	}
+	if f.target != "" {
+		return len(p), nil
+	}
+	return f.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher; an intercepted response is sent once the
+// app is done
+func (f *sendfileWriter) Flush() {
+	if f.target != "" {
+		return
+	}
+	if flusher, ok := f.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (f *sendfileWriter) Unwrap() http.ResponseWriter {
+	return f.ResponseWriter
+}
+
+// finish serves the file an intercepted response named, with Range,
+// conditional request, and ETag support. A file outside the allowed
+// directories, or missing, is a 404.
+func (f *sendfileWriter) finish() {
+	if f.target == "" {
+		return
+	}
+	header := f.Header()
+	for _, name := range []string{"Content-Length", "Content-Encoding", "Content-Range", "Transfer-Encoding", "ETag", "Last-Modified"} {
+		header.Del(name)
+	}
+
+	path, err := f.resolve(f.target)
+	var file *os.File
+	var info os.FileInfo
+	if err == nil {
+		file, err = os.Open(path)
+	}
+	if err == nil {
+		defer file.Close()
+		info, err = file.Stat()
+		if err == nil && info.IsDir() {
+			err = errors.New("is a directory")
+		}
+	}
+	if err != nil {
+		logging.LogSendfileRefused(f.source, f.r.URL.Path, f.target, err)
+		header.Del("Content-Type")
+		header.Del("Content-Disposition")
+		http.Error(f.ResponseWriter, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	if recorder, ok := f.ResponseWriter.(proxy.MetadataSetter); ok {
+		recorder.SetMetadata("response_type", "sendfile")
+		recorder.SetMetadata("file_path", path)
+	}
+	// The app's Content-Type wins, as it does for Content-Disposition
+	if header.Get("Content-Type") == "" {
+		contentType := contentTypeByExtension(path, f.mimeTypes)
+		if contentType == "" {
+			contentType = sniffContentType(file)
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				http.Error(f.ResponseWriter, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("ETag", staticETag(info, ""))
+	http.ServeContent(f.ResponseWriter, f.r, info.Name(), info.ModTime(), file)
+}
+
+// errSendfileOutsideRoots refuses a file outside the allowed directories
+var errSendfileOutsideRoots = errors.New("outside the allowed sendfile roots and locations")
+
+// resolve returns the file target names: the directory of the longest
+// location prefix it starts with, joined with the rest of it, or else
+// target itself as a path, which must be inside one of the roots. Symbolic
+// links are followed before the check, so none can lead outside.
+func (f *sendfileWriter) resolve(target string) (string, error) {
+	allowed := f.sendfile.Roots
+	if prefix := f.matchLocation(target); prefix != "" {
+		rest := strings.TrimPrefix(target, prefix)
+		if decoded, err := url.PathUnescape(rest); err == nil {
+			rest = decoded
+		}
+		dir := f.sendfile.Locations[prefix]
+		target = filepath.Join(dir, filepath.FromSlash(rest))
+		allowed = []string{dir}
+	}
+	if !filepath.IsAbs(target) {
+		return "", errSendfileOutsideRoots
+	}
+
+	path, err := filepath.EvalSymlinks(filepath.Clean(target))
+	if err != nil {
+		return "", err
+	}
+	for _, root := range allowed {
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(resolvedRoot, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+	return "", errSendfileOutsideRoots
+}
+
+// matchLocation returns the longest location prefix target starts with
+func (f *sendfileWriter) matchLocation(target string) string {
+	longest := ""
+	for prefix := range f.sendfile.Locations {
+		if strings.HasPrefix(target, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	return longest
+}
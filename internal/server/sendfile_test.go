@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newSendfileTestHandler returns a handler with tenant downloads served by a
+// backend that answers every request with an empty body naming the file in
+// its X-Sendfile or X-Accel-Redirect header, and the directory that may be
+// sent from
+func newSendfileTestHandler(t *testing.T, header string, name func(root string, r *http.Request) string) (http.Handler, string) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "report.pdf"), []byte("0123456789abcdef"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Sendfile-Type") != header {
+			http.Error(w, "X-Sendfile-Type not sent", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(header, name(root, r))
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="report.pdf"`)
+		w.Header().Set("Content-Length", "0")
+	}))
+	t.Cleanup(backend.Close)
+
+	cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+applications:
+  sendfile:
+    header: %s
+    roots: [%s]
+    locations:
+      /protected/: %s
+  tenants:
+    - path: /downloads/
+      name: downloads
+`, header, root, root)))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	appManager := process.NewAppManager(cfg)
+	appManager.StubApps(backend.Listener.Addr().(*net.TCPAddr).Port)
+	t.Cleanup(appManager.Cleanup)
+	return CreateTestHandler(cfg, appManager, nil, &idle.Manager{}), root
+}
+
+func TestSendfileServesNamedFile(t *testing.T) {
+	handler, _ := newSendfileTestHandler(t, "X-Sendfile", func(root string, r *http.Request) string {
+		return filepath.Join(root, "report.pdf")
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/downloads/report", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789abcdef" {
+		t.Fatalf("GET = %d %q, want the file", rec.Code, rec.Body.String())
+	}
+	header := rec.Header()
+	if header.Get("X-Sendfile") != "" {
+		t.Error("X-Sendfile header leaked to the client")
+	}
+	if header.Get("Content-Type") != "application/pdf" || header.Get("Content-Disposition") != `attachment; filename="report.pdf"` {
+		t.Errorf("headers = %v, want the app's Content-Type and Content-Disposition", header)
+	}
+	if header.Get("Content-Length") != "16" || header.Get("Accept-Ranges") != "bytes" || header.Get("Last-Modified") == "" {
+		t.Errorf("headers = %v, want Content-Length, Accept-Ranges and Last-Modified", header)
+	}
+
+	etag := header.Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	req := httptest.NewRequest("GET", "/downloads/report", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", rec.Code)
+	}
+}
+
+func TestSendfileRange(t *testing.T) {
+	handler, _ := newSendfileTestHandler(t, "X-Accel-Redirect", func(root string, r *http.Request) string {
+		return "/protected/report.pdf"
+	})
+
+	req := httptest.NewRequest("GET", "/downloads/report", nil)
+	req.Header.Set("Range", "bytes=4-7")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "4567" {
+		t.Fatalf("ranged GET = %d %q, want 206 4567", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 4-7/16" {
+		t.Errorf("Content-Range = %q", got)
+	}
+}
+
+func TestSendfileRefusesFilesOutsideRoots(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		target func(root string) string
+	}{
+		{"absolute path outside roots", "X-Sendfile", func(string) string { return outside }},
+		{"traversal out of a root", "X-Sendfile", func(root string) string { return root + "/../" + filepath.Base(filepath.Dir(outside)) + "/secret.txt" }},
+		{"traversal out of a location", "X-Accel-Redirect", func(string) string { return "/protected/../../../../../../" + outside }},
+		{"encoded traversal out of a location", "X-Accel-Redirect", func(string) string { return "/protected/%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e" + outside }},
+		{"symlink out of a root", "X-Sendfile", func(root string) string {
+			link := filepath.Join(root, "link.txt")
+			if err := os.Symlink(outside, link); err != nil {
+				t.Skip("symlinks unavailable:", err)
+			}
+			return link
+		}},
+		{"missing file", "X-Sendfile", func(root string) string { return filepath.Join(root, "missing.pdf") }},
+		{"relative path", "X-Sendfile", func(string) string { return "report.pdf" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newSendfileTestHandler(t, tt.header, func(root string, r *http.Request) string {
+				return tt.target(root)
+			})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/downloads/report", nil))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("GET = %d %q, want 404", rec.Code, rec.Body.String())
+			}
+			if rec.Header().Get("Content-Disposition") != "" || rec.Header().Get(tt.header) != "" {
+				t.Errorf("headers = %v, want the download's headers dropped", rec.Header())
+			}
+		})
+	}
+}
+
+func TestSendfileRequestHeaders(t *testing.T) {
+	sendfile := &config.SendfileConfig{
+		Header:    "X-Accel-Redirect",
+		Locations: map[string]string{"/protected/": "/rails/storage", "/exports/": "/srv/exports"},
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Sendfile-Type", "X-Sendfile")
+	req.Header.Set("X-Accel-Mapping", "/=/protected/")
+	setSendfileRequestHeaders(req, sendfile)
+
+	if got := req.Header.Get("X-Sendfile-Type"); got != "X-Accel-Redirect" {
+		t.Errorf("X-Sendfile-Type = %q", got)
+	}
+	if got := req.Header.Get("X-Accel-Mapping"); got != "/rails/storage/=/protected/,/srv/exports/=/exports/" {
+		t.Errorf("X-Accel-Mapping = %q", got)
+	}
+}
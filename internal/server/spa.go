@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// spaResult is the outcome of matching a request against the single-page
+// applications
+type spaResult struct {
+	spa      *config.SPAConfig // Application whose path covers the request, if any
+	detail   string            // Why the fallback isn't served, if it isn't
+	fallback FileAttempt       // The fallback file, when it is
+}
+
+// resolveSPA finds the fallback file of the single-page application covering
+// r, unless the request is excluded from it: it isn't a GET or HEAD, names a
+// file (its last segment has an extension), or matches an exclude pattern
+func (s *StaticFileHandler) resolveSPA(r *http.Request) spaResult {
+	var result spaResult
+	for i := range s.config.Server.Static.SPA {
+		spa := &s.config.Server.Static.SPA[i]
+		if (strings.HasPrefix(r.URL.Path, spa.Path) || r.URL.Path+"/" == spa.Path) &&
+			(result.spa == nil || len(spa.Path) > len(result.spa.Path)) {
+			result.spa = spa
+		}
+	}
+	if result.spa == nil {
+		return result
+	}
+
+	switch {
+	case r.Method != http.MethodGet && r.Method != http.MethodHead:
+		result.detail = "method " + r.Method
+		return result
+	case path.Ext(r.URL.Path) != "":
+		result.detail = "path names a file"
+		return result
+	}
+	for _, pattern := range result.spa.ExcludePatterns {
+		if pattern.MatchString(r.URL.Path) {
+			result.detail = "excluded by " + pattern.String()
+			return result
+		}
+	}
+
+	result.fallback = statFile(s.source, sourceName(s.stripRootPath(result.spa.Fallback)))
+	if !result.fallback.Exists || result.fallback.IsDir {
+		result.detail = "fallback not found"
+	}
+	return result
+}
+
+// ServeSPA serves the fallback file of the single-page application covering
+// the request, for paths no static file answered. The fallback is revalidated
+// on every request so a deploy is picked up at once.
+func (s *StaticFileHandler) ServeSPA(w http.ResponseWriter, r *http.Request) bool {
+	result := s.resolveSPA(r)
+	if result.spa == nil {
+		return false
+	}
+	if trace := tracing(w); trace != nil {
+		trace.step().Rule, trace.step().Detail = result.spa.Path, result.detail
+		if result.fallback.Path != "" {
+			traceFiles(w, result.fallback)
+		}
+	}
+	if result.detail != "" {
+		logging.LogSPASkip(r.URL.Path, result.spa.Path, result.detail)
+		// A fallback that was looked for may be missing with its directory
+		return result.fallback.Path != "" && s.serveRootUnavailable(w, r)
+	}
+
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "static")
+		recorder.SetMetadata("file_path", result.fallback.Path)
+	}
+	s.setContentType(w, result.fallback.name)
+	w.Header().Set("Cache-Control", "no-cache")
+	s.serveNegotiated(w, r, result.fallback.name, false)
+	logging.LogSPAServe(r.URL.Path, result.fallback.Path)
+	return true
+}
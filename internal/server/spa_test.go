@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// spaHandler serves a dashboard SPA from a temporary public directory
+func spaHandler(t *testing.T, extra string) (http.Handler, *config.Config) {
+	publicDir := t.TempDir()
+	files := map[string]string{
+		"dashboard/index.html":           "<div id=app></div>",
+		"dashboard/assets/app-3f9a2c.js": "createApp()",
+		"dashboard/favicon.ico":          "icon",
+	}
+	for name, body := range files {
+		file := filepath.Join(publicDir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.ParseYAML([]byte(fmt.Sprintf(`
+server:
+  static:
+    public_dir: %s
+    spa:
+      - path: /dashboard
+        assets: /dashboard/assets/
+        exclude: ["^/dashboard/api/"]
+%s`, publicDir, extra)))
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+	var basicAuth *auth.BasicAuth
+	if cfg.Auth.Enabled {
+		if basicAuth, err = auth.LoadAuthConfig(&cfg.Auth); err != nil {
+			t.Fatalf("LoadAuthConfig: %v", err)
+		}
+	}
+	return CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{}), cfg
+}
+
+func TestSPAFallback(t *testing.T) {
+	handler, _ := spaHandler(t, "")
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		status       int
+		body         string
+		cacheControl string
+	}{
+		{"deep link", "GET", "/dashboard/users/42/edit", http.StatusOK, "<div id=app></div>", "no-cache"},
+		{"application root", "GET", "/dashboard", http.StatusOK, "<div id=app></div>", "no-cache"},
+		{"head request", "HEAD", "/dashboard/settings", http.StatusOK, "", "no-cache"},
+		{"hashed asset", "GET", "/dashboard/assets/app-3f9a2c.js", http.StatusOK, "createApp()", "public, max-age=31536000, immutable"},
+		{"other file", "GET", "/dashboard/favicon.ico", http.StatusOK, "icon", ""},
+		{"missing asset", "GET", "/dashboard/assets/app-000000.js", http.StatusNotFound, "", ""},
+		{"excluded API path", "GET", "/dashboard/api/users", http.StatusNotFound, "", ""},
+		{"other method", "POST", "/dashboard/users", http.StatusNotFound, "", ""},
+		{"outside the prefix", "GET", "/dashboards", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+			if recorder.Code != tt.status {
+				t.Fatalf("Status = %d, want %d", recorder.Code, tt.status)
+			}
+			if tt.body != "" && recorder.Body.String() != tt.body {
+				t.Errorf("Body = %q, want %q", recorder.Body.String(), tt.body)
+			}
+			if got := recorder.Header().Get("Cache-Control"); tt.status == http.StatusOK && got != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+			if tt.body == "<div id=app></div>" && !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+				t.Errorf("Content-Type = %q, want text/html", recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestSPAFallbackWithAuthPublicPaths(t *testing.T) {
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler, _ := spaHandler(t, fmt.Sprintf(`
+auth:
+  enabled: true
+  htpasswd: %s
+  public_paths: ["/dashboard/login", "/dashboard/assets/"]
+`, htpasswd))
+
+	get := func(path string, authenticated bool) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authenticated {
+			req.SetBasicAuth("user", "password")
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if status := get("/dashboard/login", false); status != http.StatusOK {
+		t.Errorf("Public deep link: status = %d, want 200", status)
+	}
+	if status := get("/dashboard/assets/app-3f9a2c.js", false); status != http.StatusOK {
+		t.Errorf("Public asset: status = %d, want 200", status)
+	}
+	if status := get("/dashboard/users/42", false); status != http.StatusUnauthorized {
+		t.Errorf("Protected deep link: status = %d, want 401", status)
+	}
+	if status := get("/dashboard/users/42", true); status != http.StatusOK {
+		t.Errorf("Authenticated deep link: status = %d, want 200", status)
+	}
+}
+
+func TestExplainSPAFallback(t *testing.T) {
+	_, cfg := spaHandler(t, "")
+
+	trace := Explain(cfg, nil, httptest.NewRequest("GET", "/dashboard/users/42", nil))
+	if trace.Disposition != "static" || !strings.HasSuffix(trace.Target, filepath.Join("dashboard", "index.html")) {
+		t.Errorf("Result = %s %s, want the SPA fallback", trace.Disposition, trace.Target)
+	}
+
+	trace = Explain(cfg, nil, httptest.NewRequest("GET", "/dashboard/api/users", nil))
+	spa := traceStep(t, trace, "spa")
+	if trace.Disposition != "not-found" || spa.Matched || !strings.HasPrefix(spa.Detail, "excluded by") {
+		t.Errorf("Result = %s, spa step = %+v; want not-found after an excluded spa step", trace.Disposition, spa)
+	}
+}
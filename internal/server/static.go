@@ -3,31 +3,51 @@ package server
 import (
 	"fmt"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/rubys/navigator/internal/config"
 	"github.com/rubys/navigator/internal/logging"
-	"github.com/rubys/navigator/internal/utils"
 )
 
 // StaticFileHandler handles serving static files
 type StaticFileHandler struct {
 	config *config.Config
+	source staticSource // Public directory, archive, or S3 bucket
 }
 
 // NewStaticFileHandler creates a new static file handler
 func NewStaticFileHandler(cfg *config.Config) *StaticFileHandler {
-	return &StaticFileHandler{
+	s := &StaticFileHandler{
 		config: cfg,
 	}
+	s.source = openStaticSource(cfg, s.getPublicDir())
+	return s
+}
+
+// useSource keeps the static source open while a request is served; the
+// returned function ends its use. A request reaching a handler only after a
+// reload closed its source can read just the files already cached.
+func (s *StaticFileHandler) useSource() (done func()) {
+	if s != nil {
+		if shared, ok := s.source.(sharedSource); ok && shared.acquire() {
+			return shared.release
+		}
+	}
+	return func() {}
 }
 
 // getPublicDir returns the configured public directory or the default
 func (s *StaticFileHandler) getPublicDir() string {
-	if s.config.Server.Static.PublicDir != "" {
-		return s.config.Server.Static.PublicDir
+	return publicDir(s.config)
+}
+
+// publicDir returns cfg's public directory or the default
+func publicDir(cfg *config.Config) string {
+	if cfg.Server.Static.PublicDir != "" {
+		return cfg.Server.Static.PublicDir
 	}
 	return config.DefaultPublicDir
 }
@@ -58,18 +78,19 @@ func (s *StaticFileHandler) ServeStatic(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check if file has a static extension
-	if !s.hasStaticExtension(path) {
+	name, ok := s.staticFileName(path)
+	if !ok {
 		return false
 	}
 
-	// Use server-level public directory
-	fsPath := filepath.Join(s.getPublicDir(), path)
-
 	// Check if file exists
+	fsPath := s.source.location(name)
 	logging.LogStaticFileExistenceCheck(fsPath, path)
-	if info, err := os.Stat(fsPath); os.IsNotExist(err) || info.IsDir() {
-		logging.LogStaticFileNotFound(fsPath, err)
-		return false
+	attempt := statFile(s.source, name)
+	traceFiles(w, attempt)
+	if !attempt.Exists || attempt.IsDir {
+		logging.LogStaticFileNotFound(fsPath, attempt.err)
+		return s.serveRootUnavailable(w, r)
 	}
 
 	// Set response metadata for logging
@@ -79,15 +100,36 @@ func (s *StaticFileHandler) ServeStatic(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Set content type and cache control headers
-	SetContentType(w, fsPath)
-	s.setCacheControl(w, r.URL.Path)
+	s.setContentType(w, name)
+	fingerprinted := s.setCacheControl(w, r.URL.Path)
 
-	// Serve the file
-	http.ServeFile(w, r, fsPath)
+	// Serve the file (or a precompressed sidecar)
+	s.serveNegotiated(w, r, name, fingerprinted)
 	logging.LogStaticFileServe(path, fsPath)
 	return true
 }
 
+// staticFileName returns the name in the static source that ServeStatic
+// would serve for a root-stripped path, or false if the extension is not static
+func (s *StaticFileHandler) staticFileName(path string) (string, bool) {
+	if !s.hasStaticExtension(path) {
+		return "", false
+	}
+	return sourceName(path), true
+}
+
+// statFile checks whether a candidate file exists in the static source
+func statFile(src staticSource, name string) FileAttempt {
+	attempt := FileAttempt{Path: src.location(name), name: name}
+	info, err := src.Stat(name)
+	if err != nil {
+		attempt.err = err
+		return attempt
+	}
+	attempt.Exists, attempt.IsDir = true, info.IsDir()
+	return attempt
+}
+
 // hasStaticExtension checks if the path has a static file extension
 func (s *StaticFileHandler) hasStaticExtension(path string) bool {
 	ext := strings.TrimPrefix(filepath.Ext(path), ".")
@@ -135,13 +177,15 @@ func (s *StaticFileHandler) TryFiles(w http.ResponseWriter, r *http.Request) boo
 		return true // Found and served static file
 	}
 
+	// The files may only be missing because the public directory is
+	if s.serveRootUnavailable(w, r) {
+		return true
+	}
+
 	// No static file found - skip if this is a tenant path
 	// (let tenant handle dynamic requests)
-	for _, tenant := range s.config.Applications.Tenants {
-		if strings.HasPrefix(path, tenant.Path) {
-			logging.LogTryFilesSkipTenant(tenant.Path)
-			return false
-		}
+	if tenant := s.config.TenantForPath(path); tenant != nil {
+		logging.LogTryFilesSkipTenant(tenant.Path)
 	}
 
 	return false
@@ -152,53 +196,86 @@ func (s *StaticFileHandler) getTryFileExtensions() []string {
 	return s.config.Server.Static.TryFiles
 }
 
-// tryPublicDirFiles attempts to serve files from the public directory
-func (s *StaticFileHandler) tryPublicDirFiles(w http.ResponseWriter, r *http.Request, extensions []string, path string) bool {
-	logging.LogTryFilesSearching(path)
+// tryFilesResult is the outcome of resolving a path against try_files
+type tryFilesResult struct {
+	redirect    string        // Trailing slash redirect target, if any
+	name        string        // Name in the static source of the file to serve, if any
+	fsPath      string        // Location of that file, for logs
+	requestPath string        // Root-stripped path of the file to serve
+	attempts    []FileAttempt // Every file checked, in order
+}
 
-	publicDir := s.getPublicDir()
-	strippedPath := s.stripRootPath(path)
+// resolveTryFiles finds the static source file try_files would serve for
+// urlPath without writing a response
+func (s *StaticFileHandler) resolveTryFiles(urlPath string, extensions []string) tryFilesResult {
+	var result tryFilesResult
+	strippedPath := s.stripRootPath(urlPath)
 
 	// STEP 1: Check if the path (without trailing slash) is a directory
 	// If normalize_trailing_slashes is enabled, redirect to path WITH trailing slash
 	// This ensures relative paths in the HTML work correctly
-	if s.config.Server.Static.NormalizeTrailingSlashes && !strings.HasSuffix(path, "/") {
-		dirPath := filepath.Join(publicDir, strippedPath)
-		if info, err := os.Stat(dirPath); err == nil && info.IsDir() {
+	if s.config.Server.Static.NormalizeTrailingSlashes && !strings.HasSuffix(urlPath, "/") {
+		dirName := sourceName(strippedPath)
+		if dir := statFile(s.source, dirName); dir.IsDir {
 			// Check if index.html exists in this directory
-			indexPath := filepath.Join(dirPath, "index.html")
-			if _, err := os.Stat(indexPath); err == nil {
+			index := statFile(s.source, path.Join(dirName, "index.html"))
+			result.attempts = append(result.attempts, index)
+			if index.Exists {
 				// Directory has index.html - redirect to path with trailing slash
-				// This ensures relative paths in the HTML work correctly
-				redirectURL := path + "/"
-
-				// Set metadata for logging
-				if recorder, ok := w.(*ResponseRecorder); ok {
-					recorder.SetMetadata("response_type", "redirect")
-					recorder.SetMetadata("destination", redirectURL)
-				}
-
-				http.Redirect(w, r, redirectURL, http.StatusMovedPermanently)
-				logging.LogDirectoryRedirect(path, redirectURL)
-				return true
+				result.redirect = urlPath + "/"
+				return result
 			}
 		}
 	}
 
 	// STEP 2: Try each extension (for paths that already have trailing slash or aren't directories)
 	for _, ext := range extensions {
-		fsPath := filepath.Join(publicDir, strippedPath+ext)
-		logging.LogTryFilesCheckingPath(fsPath)
-		if info, err := os.Stat(fsPath); err == nil && !info.IsDir() {
-			return s.serveFile(w, r, fsPath, strippedPath+ext)
+		attempt := statFile(s.source, sourceName(strippedPath+ext))
+		result.attempts = append(result.attempts, attempt)
+		if attempt.Exists && !attempt.IsDir {
+			result.name = attempt.name
+			result.fsPath = attempt.Path
+			result.requestPath = strippedPath + ext
+			return result
 		}
 	}
 
+	return result
+}
+
+// tryPublicDirFiles attempts to serve files from the public directory
+func (s *StaticFileHandler) tryPublicDirFiles(w http.ResponseWriter, r *http.Request, extensions []string, path string) bool {
+	logging.LogTryFilesSearching(path)
+
+	result := s.resolveTryFiles(path, extensions)
+	for _, attempt := range result.attempts {
+		logging.LogTryFilesCheckingPath(attempt.Path)
+	}
+	traceFiles(w, result.attempts...)
+
+	if result.redirect != "" {
+		// Set metadata for logging
+		if recorder, ok := w.(*ResponseRecorder); ok {
+			recorder.SetMetadata("response_type", "redirect")
+			recorder.SetMetadata("destination", result.redirect)
+		}
+
+		http.Redirect(w, r, result.redirect, http.StatusMovedPermanently)
+		logging.LogDirectoryRedirect(path, result.redirect)
+		return true
+	}
+
+	if result.fsPath != "" {
+		if trace := tracing(w); trace != nil {
+			trace.step().Rule = strings.TrimPrefix(result.requestPath, s.stripRootPath(path))
+		}
+		return s.serveFile(w, r, result.name, result.fsPath, result.requestPath)
+	}
 	return false
 }
 
 // serveFile serves a specific file with appropriate headers
-func (s *StaticFileHandler) serveFile(w http.ResponseWriter, r *http.Request, fsPath, requestPath string) bool {
+func (s *StaticFileHandler) serveFile(w http.ResponseWriter, r *http.Request, name, fsPath, requestPath string) bool {
 	// Set metadata for static file response
 	if recorder, ok := w.(*ResponseRecorder); ok {
 		recorder.SetMetadata("response_type", "static")
@@ -206,26 +283,31 @@ func (s *StaticFileHandler) serveFile(w http.ResponseWriter, r *http.Request, fs
 	}
 
 	// Set appropriate content type
-	SetContentType(w, fsPath)
+	s.setContentType(w, name)
 
 	// Set cache control headers
-	s.setCacheControl(w, r.URL.Path)
+	fingerprinted := s.setCacheControl(w, r.URL.Path)
 
-	// Serve the file
-	http.ServeFile(w, r, fsPath)
+	// Serve the file (or a precompressed sidecar)
+	s.serveNegotiated(w, r, name, fingerprinted)
 	logging.LogTryFilesServe(requestPath, fsPath)
 	return true
 }
 
-// setCacheControl sets Cache-Control headers based on configuration
-func (s *StaticFileHandler) setCacheControl(w http.ResponseWriter, path string) {
+// setCacheControl sets Cache-Control headers based on configuration, and
+// reports whether path is a fingerprinted file. The most specific setting
+// wins: an override naming the file itself, then a fingerprint pattern, then
+// the override with the longest matching prefix, then the default.
+func (s *StaticFileHandler) setCacheControl(w http.ResponseWriter, path string) bool {
+	static := &s.config.Server.Static
+
 	// Find the most specific cache control override
-	var maxAge string
+	var maxAge *config.Duration
 	var immutable bool
 	var matched bool
 	bestMatchLen := 0
 
-	for _, override := range s.config.Server.Static.CacheControl.Overrides {
+	for _, override := range static.CacheControl.Overrides {
 		if strings.HasPrefix(path, override.Path) && len(override.Path) > bestMatchLen {
 			maxAge = override.MaxAge
 			immutable = override.Immutable
@@ -234,25 +316,45 @@ func (s *StaticFileHandler) setCacheControl(w http.ResponseWriter, path string)
 		}
 	}
 
+	// Content-hashed names never change, so they're cached for good
+	if bestMatchLen < len(path) && isFingerprinted(path, static.Fingerprints) {
+		maxAge := static.FingerprintMaxAge
+		setMaxAge(w, &maxAge, true)
+		return true
+	}
+
 	// Use default if no override matched
 	if !matched {
-		maxAge = s.config.Server.Static.CacheControl.Default
-		immutable = s.config.Server.Static.CacheControl.DefaultImmutable
+		maxAge = static.CacheControl.Default
+		immutable = static.CacheControl.DefaultImmutable
 	}
+	setMaxAge(w, maxAge, immutable)
+	return false
+}
 
-	// Set Cache-Control header if configured
-	if maxAge != "" {
-		// Parse duration and convert to seconds
-		duration := utils.ParseDurationWithDefault(maxAge, 0)
-		seconds := int(duration.Seconds())
-
-		// Build Cache-Control header with optional immutable directive
-		cacheControl := fmt.Sprintf("public, max-age=%d", seconds)
-		if immutable {
-			cacheControl += ", immutable"
+// isFingerprinted reports whether path matches one of the fingerprint patterns
+func isFingerprinted(path string, fingerprints []*regexp.Regexp) bool {
+	for _, fingerprint := range fingerprints {
+		if fingerprint.MatchString(path) {
+			return true
 		}
-		w.Header().Set("Cache-Control", cacheControl)
 	}
+	return false
+}
+
+// setMaxAge sets a public Cache-Control header for maxAge, if configured
+func setMaxAge(w http.ResponseWriter, maxAge *config.Duration, immutable bool) {
+	if maxAge == nil {
+		return
+	}
+	seconds := int(maxAge.Std().Seconds())
+
+	// Build Cache-Control header with optional immutable directive
+	cacheControl := fmt.Sprintf("public, max-age=%d", seconds)
+	if immutable {
+		cacheControl += ", immutable"
+	}
+	w.Header().Set("Cache-Control", cacheControl)
 }
 
 // ServeFallback serves a 404 response when no tenants are configured
@@ -0,0 +1,334 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archive formats, detected from the file extension
+const (
+	archiveTar     = "tar"
+	archiveTarGzip = "tar.gz"
+	archiveTarZstd = "tar.zst"
+	archiveZip     = "zip"
+)
+
+// archiveSource serves public files from a tar or zip archive. The archive is
+// indexed when opened; file contents are extracted on first request and kept
+// in a bounded cache. A compressed tar is decompressed once, into a temporary
+// file, as it's indexed.
+type archiveSource struct {
+	path      string
+	format    string
+	file      *os.File // Kept open for random access: the archive, or a compressed tar's contents
+	extracted bool     // file is a temporary copy of the uncompressed tar, removed on close
+	zip       *zip.Reader
+	entries   map[string]*archiveEntry
+	files     int
+	cache     *contentCache
+
+	mu      sync.Mutex
+	users   int  // Requests reading from the archive
+	retired bool // Replaced by a reload; closed once no request is using it
+	closed  bool
+}
+
+// archiveEntry is a file or directory in the archive index
+type archiveEntry struct {
+	memFileInfo
+	offset  int64     // Start of the content in the uncompressed tar
+	zipFile *zip.File // Content of a zip entry
+}
+
+// archiveFormat detects the format of an archive from its name
+func archiveFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGzip, nil
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return archiveTarZstd, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip, nil
+	}
+	return "", fmt.Errorf("unsupported archive format %q (expected .tar, .tar.gz, .tgz, .tar.zst, or .zip)", name)
+}
+
+// openArchiveSource indexes the archive at archivePath
+func openArchiveSource(archivePath string, cacheSize int64) (*archiveSource, error) {
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &archiveSource{
+		path:    archivePath,
+		format:  format,
+		file:    file,
+		entries: map[string]*archiveEntry{".": {memFileInfo: memFileInfo{name: ".", dir: true}}},
+		cache:   newContentCache(cacheSize),
+	}
+	if format == archiveZip {
+		err = a.indexZip()
+	} else {
+		err = a.indexTar()
+	}
+	if err != nil {
+		_ = a.closeFile()
+		return nil, fmt.Errorf("reading %s: %w", archivePath, err)
+	}
+	return a, nil
+}
+
+// indexZip records every entry of a zip archive
+func (a *archiveSource) indexZip() error {
+	info, err := a.file.Stat()
+	if err != nil {
+		return err
+	}
+	a.zip, err = zip.NewReader(a.file, info.Size())
+	if err != nil {
+		return err
+	}
+	for _, f := range a.zip.File {
+		entry := &archiveEntry{
+			memFileInfo: memFileInfo{size: int64(f.UncompressedSize64), modTime: f.Modified, dir: f.FileInfo().IsDir()},
+			zipFile:     f,
+		}
+		a.add(f.Name, entry)
+	}
+	return nil
+}
+
+// indexTar records the regular files and directories of a tar archive along
+// with where each file's content starts in the uncompressed tar
+func (a *archiveSource) indexTar() error {
+	var stream io.Reader = a.file
+	if a.format != archiveTar {
+		// A compressed stream can't seek, so the tar is copied out as it's
+		// read and files are later read from the copy
+		decompressed, err := a.decompress()
+		if err != nil {
+			return err
+		}
+		defer decompressed.Close()
+		copied, err := os.CreateTemp("", "navigator-static-*.tar")
+		if err != nil {
+			return err
+		}
+		defer a.file.Close()
+		a.file, a.extracted = copied, true
+		stream = io.TeeReader(decompressed, copied)
+	}
+
+	counter := &countingReader{r: stream}
+	reader := tar.NewReader(counter)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			a.add(header.Name, &archiveEntry{
+				memFileInfo: memFileInfo{size: header.Size, modTime: header.ModTime},
+				offset:      counter.n,
+			})
+		case tar.TypeDir:
+			a.add(header.Name, &archiveEntry{memFileInfo: memFileInfo{modTime: header.ModTime, dir: true}})
+		}
+	}
+}
+
+// add indexes an entry under its cleaned name, creating any parent
+// directories the archive does not list explicitly
+func (a *archiveSource) add(name string, entry *archiveEntry) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" || !fs.ValidPath(name) {
+		return
+	}
+	entry.name = name
+	existing, ok := a.entries[name]
+	if ok && existing.dir && entry.dir {
+		existing.modTime = entry.modTime
+		return
+	}
+	if !entry.dir && (!ok || existing.dir) {
+		a.files++
+	}
+	a.entries[name] = entry
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := a.entries[dir]; ok {
+			break
+		}
+		a.entries[dir] = &archiveEntry{memFileInfo: memFileInfo{name: dir, modTime: entry.modTime, dir: true}}
+	}
+}
+
+// decompress reads the compressed archive as an uncompressed tar stream
+func (a *archiveSource) decompress() (io.ReadCloser, error) {
+	if a.format == archiveTarZstd {
+		zr, err := zstd.NewReader(a.file)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{Reader: zr, close: func() error {
+			zr.Close()
+			return nil
+		}}, nil
+	}
+	return gzip.NewReader(a.file)
+}
+
+// Stat returns the index entry for name
+func (a *archiveSource) Stat(name string) (fs.FileInfo, error) {
+	entry, err := a.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return &entry.memFileInfo, nil
+}
+
+// Open returns the content of name, extracting it if it is not cached
+func (a *archiveSource) Open(name string) (fs.File, error) {
+	entry, err := a.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.dir {
+		return newMemFile(&entry.memFileInfo, nil), nil
+	}
+	if data, ok := a.cache.get(entry.name, time.Time{}); ok {
+		return newMemFile(&entry.memFileInfo, data.([]byte)), nil
+	}
+
+	data, err := a.extract(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	a.cache.put(entry.name, data, int64(len(data)), time.Time{})
+	return newMemFile(&entry.memFileInfo, data), nil
+}
+
+func (a *archiveSource) lookup(op, name string) (*archiveEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := a.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+// extract reads the content of a file entry from the archive
+func (a *archiveSource) extract(entry *archiveEntry) ([]byte, error) {
+	data := make([]byte, entry.size)
+	switch a.format {
+	case archiveZip:
+		content, err := entry.zipFile.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+		_, err = io.ReadFull(content, data)
+		return data, err
+
+	}
+	_, err := a.file.ReadAt(data, entry.offset)
+	return data, err
+}
+
+func (a *archiveSource) location(name string) string {
+	return a.path + ":" + name
+}
+
+// acquire keeps the archive open for a request until release is called.
+// Returns false if it has already been closed.
+func (a *archiveSource) acquire() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return false
+	}
+	a.users++
+	return true
+}
+
+// release ends a request's use of the archive, closing it if it was retired
+func (a *archiveSource) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users--
+	if a.retired && a.users == 0 {
+		_ = a.closeFile()
+	}
+}
+
+// Close retires the archive: it's closed at once if no request is reading
+// from it, otherwise when the last one finishes
+func (a *archiveSource) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.retired = true
+	if a.users > 0 {
+		return nil
+	}
+	return a.closeFile()
+}
+
+// closeFile closes the archive file and removes an extracted copy
+func (a *archiveSource) closeFile() error {
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	err := a.file.Close()
+	if a.extracted {
+		_ = os.Remove(a.file.Name())
+	}
+	return err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readCloser pairs a decompressor with the function that releases it
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
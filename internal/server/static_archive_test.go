@@ -0,0 +1,268 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// archiveTestFiles are packed into every archive format under test
+var archiveTestFiles = map[string]string{
+	"index.html":             "<h1>home</h1>",
+	"assets/app.css":         "body { color: red }",
+	"assets/app.js":          strings.Repeat("console.log('archive');\n", 200),
+	"docs/guide/index.html":  "<h1>guide</h1>",
+	"docs/guide/intro.html":  "<p>intro</p>",
+	"studios/millbrae.html":  "<p>millbrae</p>",
+	"images/logo.svg":        "<svg></svg>",
+	"images/empty-file.txt":  "",
+	"deep/a/b/c/d/file.json": `{"deep":true}`,
+}
+
+// writeTestArchive packs archiveTestFiles into dir/name in the format implied by the extension
+func writeTestArchive(t *testing.T, dir, name string) string {
+	t.Helper()
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if strings.HasSuffix(name, ".zip") {
+		zw := zip.NewWriter(&buf)
+		for file, content := range archiveTestFiles {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Deflate, Modified: modTime})
+			if err != nil {
+				t.Fatalf("Failed to add %s: %v", file, err)
+			}
+			io.WriteString(w, content)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Failed to finish zip: %v", err)
+		}
+	} else {
+		var out io.WriteCloser = nopWriteCloser{&buf}
+		switch {
+		case strings.HasSuffix(name, ".tar.gz"):
+			out = gzip.NewWriter(&buf)
+		case strings.HasSuffix(name, ".tar.zst"):
+			zw, err := zstd.NewWriter(&buf)
+			if err != nil {
+				t.Fatalf("Failed to create zstd writer: %v", err)
+			}
+			out = zw
+		}
+		tw := tar.NewWriter(out)
+		// Only one directory is listed explicitly; the rest are implied
+		tw.WriteHeader(&tar.Header{Name: "assets/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime})
+		for file, content := range archiveTestFiles {
+			header := &tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content)), ModTime: modTime}
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatalf("Failed to add %s: %v", file, err)
+			}
+			io.WriteString(tw, content)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("Failed to finish tar: %v", err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatalf("Failed to finish compression: %v", err)
+		}
+	}
+
+	archivePath := filepath.Join(dir, name)
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	return archivePath
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestStaticArchiveServesFiles(t *testing.T) {
+	for _, name := range []string{"site.tar", "site.tar.gz", "site.tar.zst", "site.zip"} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Server.Static.TryFiles = []string{".html"}
+			cfg.Server.Static.NormalizeTrailingSlashes = true
+			cfg.Server.Static.Source = config.StaticSourceConfig{
+				Type:    config.StaticSourceArchive,
+				Archive: writeTestArchive(t, t.TempDir(), name),
+			}
+			handler := NewStaticFileHandler(cfg)
+
+			for file, content := range archiveTestFiles {
+				if strings.HasSuffix(file, "index.html") {
+					continue // http.ServeFileFS redirects these to the directory
+				}
+				req := httptest.NewRequest("GET", "/"+file, nil)
+				rec := httptest.NewRecorder()
+				if !handler.ServeStatic(rec, req) {
+					t.Fatalf("ServeStatic(%s) returned false", file)
+				}
+				if rec.Code != http.StatusOK {
+					t.Errorf("%s: status = %d, want 200", file, rec.Code)
+				}
+				if rec.Body.String() != content {
+					t.Errorf("%s: body differs from the archived file", file)
+				}
+			}
+
+			// Missing files and directories fall through
+			for _, path := range []string{"/missing.css", "/assets", "/assets/"} {
+				req := httptest.NewRequest("GET", path, nil)
+				if handler.ServeStatic(httptest.NewRecorder(), req) {
+					t.Errorf("ServeStatic(%s) served a response, want fall through", path)
+				}
+			}
+
+			// try_files finds extensionless paths inside the archive
+			req := httptest.NewRequest("GET", "/studios/millbrae", nil)
+			rec := httptest.NewRecorder()
+			if !handler.TryFiles(rec, req) || rec.Body.String() != "<p>millbrae</p>" {
+				t.Errorf("TryFiles(/studios/millbrae) = %d %q", rec.Code, rec.Body.String())
+			}
+
+			// Implied directories with an index redirect to a trailing slash
+			req = httptest.NewRequest("GET", "/docs/guide", nil)
+			rec = httptest.NewRecorder()
+			if !handler.TryFiles(rec, req) || rec.Code != http.StatusMovedPermanently {
+				t.Errorf("TryFiles(/docs/guide) = %d, want 301", rec.Code)
+			}
+
+			req = httptest.NewRequest("GET", "/nowhere", nil)
+			if handler.TryFiles(httptest.NewRecorder(), req) {
+				t.Error("TryFiles(/nowhere) served a response, want fall through")
+			}
+
+			// Range requests are answered from the extracted content
+			req = httptest.NewRequest("GET", "/assets/app.css", nil)
+			req.Header.Set("Range", "bytes=0-3")
+			rec = httptest.NewRecorder()
+			handler.ServeStatic(rec, req)
+			if rec.Code != http.StatusPartialContent || rec.Body.String() != "body" {
+				t.Errorf("Range request = %d %q, want 206 \"body\"", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStaticArchiveReloadsChangedArchive(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := writeTestArchive(t, dir, "site.tar")
+
+	cfg := &config.Config{}
+	cfg.Server.Static.Source = config.StaticSourceConfig{Type: config.StaticSourceArchive, Archive: archivePath}
+	first := NewStaticFileHandler(cfg)
+	if second := NewStaticFileHandler(cfg); second.source != first.source {
+		t.Error("Unchanged archive was indexed again")
+	}
+
+	// Replace the archive with one holding a single new file
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "new.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 3})
+	io.WriteString(tw, "new")
+	tw.Close()
+	os.WriteFile(archivePath, buf.Bytes(), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(archivePath, later, later)
+
+	handler := NewStaticFileHandler(cfg)
+	if handler.source == first.source {
+		t.Fatal("Changed archive was not indexed again")
+	}
+	if attempt := statFile(handler.source, "new.txt"); !attempt.Exists {
+		t.Error("new.txt missing from the replaced archive")
+	}
+	if attempt := statFile(handler.source, "index.html"); attempt.Exists {
+		t.Error("index.html still served from the old archive")
+	}
+}
+
+func TestStaticArchiveClosesAfterLastRequest(t *testing.T) {
+	archive, err := openArchiveSource(writeTestArchive(t, t.TempDir(), "site.tar.zst"), 1024)
+	if err != nil {
+		t.Fatalf("openArchiveSource: %v", err)
+	}
+	// The compressed tar is decompressed once, as it's indexed
+	if !archive.extracted {
+		t.Fatal("compressed archive was not extracted")
+	}
+	extracted := archive.file.Name()
+
+	// A reload retires the archive while a request is still reading it
+	if !archive.acquire() {
+		t.Fatal("acquire failed on an open archive")
+	}
+	archive.Close()
+	file, err := archive.Open("assets/app.css")
+	if err != nil {
+		t.Fatalf("Open after retiring with a request in progress: %v", err)
+	}
+	if data, _ := io.ReadAll(file); string(data) != archiveTestFiles["assets/app.css"] {
+		t.Errorf("content = %q", data)
+	}
+
+	archive.release()
+	if _, err := archive.Open("assets/app.js"); err == nil {
+		t.Error("archive still readable after the last request finished")
+	}
+	if _, err := os.Stat(extracted); !os.IsNotExist(err) {
+		t.Errorf("extracted copy left behind: %v", err)
+	}
+	if archive.acquire() {
+		t.Error("acquire succeeded on a closed archive")
+	}
+}
+
+func TestStaticArchiveMissingFile(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Static.Source = config.StaticSourceConfig{
+		Type:    config.StaticSourceArchive,
+		Archive: filepath.Join(t.TempDir(), "missing.tar"),
+	}
+	handler := NewStaticFileHandler(cfg)
+
+	req := httptest.NewRequest("GET", "/index.html", nil)
+	if handler.ServeStatic(httptest.NewRecorder(), req) {
+		t.Error("ServeStatic served a file from a missing archive")
+	}
+}
+
+func TestContentCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newContentCache(10)
+	now := time.Now()
+	cache.put("a", "a", 4, time.Time{})
+	cache.put("b", "b", 4, time.Time{})
+	cache.get("a", now)
+	cache.put("c", "c", 4, time.Time{})
+
+	if _, ok := cache.get("b", now); ok {
+		t.Error("Least recently used entry b was not evicted")
+	}
+	if _, ok := cache.get("a", now); !ok {
+		t.Error("Recently used entry a was evicted")
+	}
+
+	cache.put("big", "big", 11, time.Time{})
+	if _, ok := cache.get("big", now); ok {
+		t.Error("Entry larger than the cache was stored")
+	}
+
+	cache.put("ttl", "ttl", 1, now.Add(time.Second))
+	if _, ok := cache.get("ttl", now.Add(2*time.Second)); ok {
+		t.Error("Expired entry was returned")
+	}
+}
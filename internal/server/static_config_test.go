@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rubys/navigator/internal/config"
 )
@@ -246,11 +247,15 @@ func TestServerCacheControl(t *testing.T) {
 	// Create config with cache control
 	cfg := &config.Config{}
 	cfg.Server.Static.PublicDir = tempDir
-	cfg.Server.Static.CacheControl.Default = "1h"
+	maxAge := func(d time.Duration) *config.Duration {
+		duration := config.Duration(d)
+		return &duration
+	}
+	cfg.Server.Static.CacheControl.Default = maxAge(time.Hour)
 	cfg.Server.Static.CacheControl.Overrides = []config.CacheControlOverride{
-		{Path: "/assets/", MaxAge: "24h"},
-		{Path: "/images/", MaxAge: "12h"},
-		{Path: "/docs/", MaxAge: "30m"},
+		{Path: "/assets/", MaxAge: maxAge(24 * time.Hour)},
+		{Path: "/images/", MaxAge: maxAge(12 * time.Hour)},
+		{Path: "/docs/", MaxAge: maxAge(30 * time.Minute)},
 	}
 
 	handler := NewStaticFileHandler(cfg)
@@ -624,3 +629,128 @@ func TestDirectoryRedirect(t *testing.T) {
 		}
 	})
 }
+
+func TestFingerprintedAssetsCachedAsImmutable(t *testing.T) {
+	tempDir := t.TempDir()
+	assetsDir := filepath.Join(tempDir, "assets")
+	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"application.css", "application-1a2b3c4d.css", "vendor-0123456789abcdef.js"} {
+		if err := os.WriteFile(filepath.Join(assetsDir, name), []byte("body{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  static:
+    public_dir: ` + tempDir + `
+    cache_control:
+      default: 5m
+      overrides:
+        - path: /assets/
+          max_age: 1h
+        - path: /assets/vendor-0123456789abcdef.js
+          max_age: 1d
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	handler := NewStaticFileHandler(cfg)
+
+	tests := []struct {
+		path         string
+		cacheControl string
+		conditional  bool // Last-Modified is sent and If-Modified-Since honored
+	}{
+		{"/assets/application-1a2b3c4d.css", "public, max-age=31536000, immutable", false},
+		{"/assets/application.css", "public, max-age=3600", true},
+		// An override naming the file is more specific than the pattern
+		{"/assets/vendor-0123456789abcdef.js", "public, max-age=86400", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		recorder := httptest.NewRecorder()
+		if !handler.ServeStatic(NewResponseRecorder(recorder, nil, nil), req) {
+			t.Fatalf("%s was not served", tt.path)
+		}
+
+		if got := recorder.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: Cache-Control = %q, want %q", tt.path, got, tt.cacheControl)
+		}
+		if hasLastModified := recorder.Header().Get("Last-Modified") != ""; hasLastModified != tt.conditional {
+			t.Errorf("%s: Last-Modified = %q", tt.path, recorder.Header().Get("Last-Modified"))
+		}
+		wantStatus := http.StatusOK
+		if tt.conditional {
+			wantStatus = http.StatusNotModified
+		}
+		if recorder.Code != wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.path, recorder.Code, wantStatus)
+		}
+	}
+}
+
+func TestStaticContentType(t *testing.T) {
+	publicDir := t.TempDir()
+	files := map[string]string{
+		"about":       "<!DOCTYPE html><html><body>About</body></html>",
+		"logo":        `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"></svg>`,
+		"badge":       `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+		"notes.md":    "# Notes",
+		"report.html": "not really html",
+		"data":        "\x00\x01\x02\x03",
+		"page.HTM":    "<p>hi</p>",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(publicDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  static:
+    public_dir: ` + publicDir + `
+    try_files: [""]
+    allowed_extensions: [md, html, HTM]
+    nosniff: true
+    mime_types:
+      MD: text/markdown
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	handler := NewStaticFileHandler(cfg)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+	}{
+		{"extensionless html", "/about", "text/html; charset=utf-8"},
+		{"svg without extension", "/logo", "image/svg+xml"},
+		{"svg with xml declaration", "/badge", "image/svg+xml"},
+		{"custom mapping", "/notes.md", "text/markdown; charset=utf-8"},
+		{"html by extension", "/report.html", "text/html; charset=utf-8"},
+		{"uppercase extension", "/page.HTM", "text/html; charset=utf-8"},
+		{"binary", "/data", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			recorder := httptest.NewRecorder()
+			if !handler.ServeStatic(recorder, req) && !handler.TryFiles(recorder, req) {
+				t.Fatalf("%s was not served", tt.path)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := recorder.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+		})
+	}
+}
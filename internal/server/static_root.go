@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// staticRoot tracks whether a public directory can be read. It becomes
+// unavailable when a stat fails because the directory itself is gone or
+// unreadable, as when the volume it's mounted from drops, and available
+// again once a stat shows it is back.
+type staticRoot struct {
+	dir string
+
+	mu          sync.Mutex
+	unavailable bool
+	since       time.Time // When it became unavailable
+	lastLogged  time.Time
+	failures    int // Stats failed since the outage was last logged
+}
+
+// staticRoots keeps each directory's state across handler reloads
+var staticRoots sync.Map // dir -> *staticRoot
+
+// staticRootFor returns the state of the public directory dir
+func staticRootFor(dir string) *staticRoot {
+	root, _ := staticRoots.LoadOrStore(dir, &staticRoot{dir: dir})
+	return root.(*staticRoot)
+}
+
+// observe updates the directory's state from the result of a stat within it
+func (s *staticRoot) observe(err error) {
+	if err != nil {
+		if cause := s.rootError(err); cause != nil {
+			s.markUnavailable(cause)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	if !s.unavailable {
+		s.mu.Unlock()
+		return
+	}
+	s.unavailable = false
+	downFor := time.Since(s.since)
+	since := s.since
+	s.mu.Unlock()
+
+	// Responses stored while files were missing may be fallbacks or errors
+	purged := cachedResponses.purgeStoredSince(since)
+	logging.LogStaticRootAvailable(s.dir, downFor, purged)
+}
+
+// rootError returns why the directory itself can't be read when err, from
+// a stat within it, is due to that rather than a missing file
+func (s *staticRoot) rootError(err error) error {
+	// I/O errors and dropped network mounts affect the whole directory
+	if errors.Is(err, syscall.EIO) || errors.Is(err, syscall.ENOTCONN) || errors.Is(err, syscall.ESTALE) {
+		return err
+	}
+	if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, syscall.ENOTDIR) {
+		return nil
+	}
+	info, statErr := os.Stat(s.dir)
+	if statErr != nil {
+		return statErr
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.dir)
+	}
+	return nil
+}
+
+// markUnavailable records a failed stat, logging the outage when it starts
+// and at most once per StaticRootLogInterval while it lasts
+func (s *staticRoot) markUnavailable(err error) {
+	s.mu.Lock()
+	now := time.Now()
+	if !s.unavailable {
+		s.unavailable, s.since, s.lastLogged = true, now, time.Time{}
+	}
+	s.failures++
+	if now.Sub(s.lastLogged) < config.StaticRootLogInterval {
+		s.mu.Unlock()
+		return
+	}
+	failures := s.failures
+	s.lastLogged, s.failures = now, 0
+	s.mu.Unlock()
+
+	logging.LogStaticRootUnavailable(s.dir, err, failures)
+}
+
+// isUnavailable reports whether the directory couldn't be read when last statted
+func (s *staticRoot) isUnavailable() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unavailable
+}
+
+// serveRootUnavailable answers a request no static file was found for with
+// 503 while the public directory can't be read, so clients retry instead of
+// caching a 404. Requests under a tenant's path are left to the tenant, and
+// root_unavailable: not_found leaves every request to later routing.
+func (s *StaticFileHandler) serveRootUnavailable(w http.ResponseWriter, r *http.Request) bool {
+	dir, ok := s.source.(dirSource)
+	if !ok || !dir.root.isUnavailable() || s.config.Server.Static.RootUnavailable == config.StaticRootNotFound {
+		return false
+	}
+	if s.config.TenantForPath(r.URL.Path) != nil {
+		return false
+	}
+
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "static_unavailable")
+		recorder.SetMetadata("file_path", dir.dir)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(config.RootUnavailableRetryAfter))
+	w.Header().Set("Cache-Control", "no-store")
+	http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	return true
+}
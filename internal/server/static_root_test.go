@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestStaticRootDisappears(t *testing.T) {
+	publicDir := filepath.Join(t.TempDir(), "public")
+	writePublic := func() {
+		if err := os.MkdirAll(publicDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(publicDir, "app.css"), []byte("body {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePublic()
+
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = publicDir
+	cfg.Server.Static.TryFiles = []string{".html"}
+	cfg.Server.Static.RootUnavailable = config.StaticRootUnavailable
+	// A tenant without a path is never routed to, so it doesn't claim requests
+	cfg.Applications.Tenants = []config.Tenant{{Name: "unrouted"}, {Name: "boston", Path: "/boston/"}}
+	static := NewStaticFileHandler(cfg)
+
+	serve := func(path string) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		served := static.ServeStatic(w, req) || static.TryFiles(w, req)
+		return w, served
+	}
+
+	if w, served := serve("/app.css"); !served || w.Code != http.StatusOK {
+		t.Fatalf("before removal: served=%v status=%d, want 200", served, w.Code)
+	}
+
+	// A response cached during the outage is dropped once the root is back
+	cachedResponses.put(&cachedResponse{key: "before", body: []byte("x"), stored: time.Now().Add(-time.Minute), expires: time.Now().Add(time.Hour)})
+	if err := os.RemoveAll(publicDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/app.css", "/about"} {
+		w, served := serve(path)
+		if !served || w.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s while removed: served=%v status=%d, want 503", path, served, w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("GET %s: no Retry-After", path)
+		}
+	}
+	if _, served := serve("/boston/logo.png"); served {
+		t.Error("request under a tenant's path answered instead of left to the tenant")
+	}
+	cachedResponses.put(&cachedResponse{key: "during", body: []byte("x"), stored: time.Now(), expires: time.Now().Add(time.Hour)})
+
+	cfg.Server.Static.RootUnavailable = config.StaticRootNotFound
+	if _, served := serve("/app.css"); served {
+		t.Error("root_unavailable: not_found answered the request")
+	}
+	cfg.Server.Static.RootUnavailable = config.StaticRootUnavailable
+
+	writePublic()
+	if w, served := serve("/app.css"); !served || w.Code != http.StatusOK {
+		t.Fatalf("after recreation: served=%v status=%d, want 200", served, w.Code)
+	}
+	if _, ok := cachedResponses.get("during"); ok {
+		t.Error("response cached during the outage kept")
+	}
+	if _, ok := cachedResponses.get("before"); !ok {
+		t.Error("response cached before the outage dropped")
+	}
+	cachedResponses.purge("")
+
+	// Missing files in a readable root are plain misses
+	if _, served := serve("/missing.css"); served {
+		t.Error("missing file in an available root answered")
+	}
+}
+
+func TestStaticRootError(t *testing.T) {
+	root := &staticRoot{dir: t.TempDir()}
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"missing file", &fs.PathError{Op: "stat", Path: "a.css", Err: syscall.ENOENT}, false},
+		{"I/O error", &fs.PathError{Op: "stat", Path: "a.css", Err: syscall.EIO}, true},
+		{"stale mount", fmt.Errorf("wrapped: %w", syscall.ESTALE), true},
+		{"permission", &fs.PathError{Op: "stat", Path: "a.css", Err: syscall.EACCES}, false},
+	}
+	for _, tt := range tests {
+		if got := root.rootError(tt.err) != nil; got != tt.unavailable {
+			t.Errorf("%s: rootError != nil is %v, want %v", tt.name, got, tt.unavailable)
+		}
+	}
+
+	root.dir = filepath.Join(root.dir, "gone")
+	if root.rootError(&fs.PathError{Op: "stat", Path: "a.css", Err: syscall.ENOENT}) == nil {
+		t.Error("missing root not reported")
+	}
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// emptyPayloadHash is the hex SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Source serves public files from an S3-compatible bucket using path-style
+// requests. Objects, directories, and misses are cached for the configured TTL.
+type s3Source struct {
+	config config.S3SourceConfig
+	client *http.Client
+	cache  *contentCache
+	clock  clock.Clock
+}
+
+// s3Object is a cached lookup; info is nil when the object does not exist
+type s3Object struct {
+	info *memFileInfo
+	data []byte
+}
+
+// s3ListResult is the part of a ListObjectsV2 response used to detect directories
+type s3ListResult struct {
+	KeyCount int `xml:"KeyCount"`
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+func newS3Source(cfg config.S3SourceConfig, cacheSize int64) *s3Source {
+	if cfg.Region == "" {
+		cfg.Region = config.DefaultStaticS3Region
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = config.Duration(config.DefaultStaticS3TTL)
+	}
+	return &s3Source{
+		config: cfg,
+		client: &http.Client{Timeout: config.StaticS3RequestTimeout},
+		cache:  newContentCache(cacheSize),
+		clock:  clock.Real,
+	}
+}
+
+// Stat looks up name in the bucket
+func (s *s3Source) Stat(name string) (fs.FileInfo, error) {
+	object, err := s.object("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return object.info, nil
+}
+
+// Open returns the content of name, downloading it if it is not cached
+func (s *s3Source) Open(name string) (fs.File, error) {
+	object, err := s.object("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return newMemFile(object.info, object.data), nil
+}
+
+func (s *s3Source) location(name string) string {
+	return s.objectURL(name)
+}
+
+// object returns the cached lookup for name, fetching it when absent or expired
+func (s *s3Source) object(op, name string) (*s3Object, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &s3Object{info: &memFileInfo{name: ".", dir: true}}, nil
+	}
+
+	now := s.clock.Now()
+	var object *s3Object
+	if cached, ok := s.cache.get(name, now); ok {
+		object = cached.(*s3Object)
+	} else {
+		fetched, err := s.fetch(name)
+		if err != nil {
+			logging.LogStaticSourceError(config.StaticSourceS3, s.objectURL(name), err)
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		// Misses are charged for their key so they count against the cache too
+		s.cache.put(name, fetched, int64(len(fetched.data)+len(name)), now.Add(time.Duration(s.config.TTL)))
+		object = fetched
+	}
+
+	if object.info == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return object, nil
+}
+
+// fetch downloads the object for name; a missing object is checked for being
+// a directory (a prefix of other keys)
+func (s *s3Source) fetch(name string) (*s3Object, error) {
+	resp, err := s.get(s.objectURL(name))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return &s3Object{info: &memFileInfo{name: name, size: int64(len(data)), modTime: modTime}, data: data}, nil
+
+	case http.StatusNotFound, http.StatusForbidden:
+		// Buckets without list permission answer 403 for missing keys
+		isDir, err := s.isDir(name)
+		if err != nil {
+			return nil, err
+		}
+		if isDir {
+			return &s3Object{info: &memFileInfo{name: name, dir: true}}, nil
+		}
+		return &s3Object{}, nil
+	}
+	return nil, fmt.Errorf("GET %s: %s", s.objectURL(name), resp.Status)
+}
+
+// isDir reports whether any key starts with name followed by a slash
+func (s *s3Source) isDir(name string) (bool, error) {
+	query := url.Values{
+		"list-type": {"2"},
+		"max-keys":  {"1"},
+		"prefix":    {s.config.Prefix + name + "/"},
+	}
+	resp, err := s.get(s.bucketURL() + "?" + canonicalQuery(query))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("list %s: %s", s.bucketURL(), resp.Status)
+	}
+	var result s3ListResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("list %s: %w", s.bucketURL(), err)
+	}
+	return result.KeyCount > 0 || len(result.Contents) > 0, nil
+}
+
+// get sends a signed GET request
+func (s *s3Source) get(target string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	signS3Request(req, s.config.Region, s.clock.Now())
+	return s.client.Do(req)
+}
+
+func (s *s3Source) bucketURL() string {
+	return strings.TrimRight(s.config.Endpoint, "/") + "/" + s3Escape(s.config.Bucket, false)
+}
+
+func (s *s3Source) objectURL(name string) string {
+	return s.bucketURL() + "/" + s3Escape(s.config.Prefix+name, false)
+}
+
+// signS3Request adds an AWS Signature Version 4 Authorization header using
+// credentials from the environment. Without credentials the request is sent
+// unsigned, which works for public buckets.
+func signS3Request(req *http.Request, region string, now time.Time) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	}
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	// Sign the host, range, and every x-amz-* header
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters (and slashes,
+// unless encodeSlash is set) as S3 signing expects
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
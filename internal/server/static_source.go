@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// staticSource is where public files are read from: the public directory, an
+// archive, or an S3-compatible bucket. Names are slash-separated paths
+// relative to the public root, as accepted by fs.ValidPath.
+type staticSource interface {
+	fs.StatFS
+
+	// location describes name in logs and the access log
+	location(name string) string
+}
+
+// sharedSource is a static source holding open files. A reload replaces it
+// without closing it under requests still reading from it.
+type sharedSource interface {
+	acquire() bool
+	release()
+}
+
+// sourceName converts a root-stripped URL path to a name within a static source
+func sourceName(urlPath string) string {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// dirSource reads public files from a directory on disk
+type dirSource struct {
+	fs.StatFS
+	dir  string
+	root *staticRoot // Whether dir itself can be read
+}
+
+func newDirSource(dir string) dirSource {
+	return dirSource{StatFS: os.DirFS(dir).(fs.StatFS), dir: dir, root: staticRootFor(dir)}
+}
+
+// Stat stats name, noting whether the directory itself could be read
+func (d dirSource) Stat(name string) (fs.FileInfo, error) {
+	info, err := d.StatFS.Stat(name)
+	d.root.observe(err)
+	return info, err
+}
+
+func (d dirSource) location(name string) string {
+	return filepath.Join(d.dir, filepath.FromSlash(name))
+}
+
+// missingSource stands in for a source that could not be opened; every file is missing
+type missingSource struct {
+	description string
+	err         error
+}
+
+func (m missingSource) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: m.err}
+}
+
+func (m missingSource) Stat(name string) (fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: m.err}
+}
+
+func (m missingSource) location(name string) string {
+	return m.description + ":" + name
+}
+
+// staticSources keeps the archive index or S3 cache across handler reloads;
+// it is replaced when the source settings (or the archive file) change
+var staticSources struct {
+	mu      sync.Mutex
+	key     string
+	current staticSource
+}
+
+// openStaticSource returns the source configured for public files
+func openStaticSource(cfg *config.Config, publicDir string) staticSource {
+	source := cfg.Server.Static.Source
+	if source.Type != config.StaticSourceArchive && source.Type != config.StaticSourceS3 {
+		return newDirSource(publicDir)
+	}
+	cacheSize := source.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = config.DefaultStaticCacheSize
+	}
+
+	key := fmt.Sprintf("%s|%d|%+v", source.Type, cacheSize, source.S3)
+	if source.Type == config.StaticSourceArchive {
+		info, err := os.Stat(source.Archive)
+		if err != nil {
+			logging.LogStaticSourceError(source.Type, source.Archive, err)
+			return missingSource{description: source.Archive, err: err}
+		}
+		key = fmt.Sprintf("%s|%d|%s|%d|%d", source.Type, cacheSize, source.Archive, info.Size(), info.ModTime().UnixNano())
+	}
+
+	staticSources.mu.Lock()
+	defer staticSources.mu.Unlock()
+	if staticSources.current != nil && staticSources.key == key {
+		return staticSources.current
+	}
+
+	var opened staticSource
+	switch source.Type {
+	case config.StaticSourceArchive:
+		archive, err := openArchiveSource(source.Archive, cacheSize)
+		if err != nil {
+			logging.LogStaticSourceError(source.Type, source.Archive, err)
+			return missingSource{description: source.Archive, err: err}
+		}
+		logging.LogStaticArchiveLoaded(source.Archive, archive.files)
+		opened = archive
+	case config.StaticSourceS3:
+		opened = newS3Source(source.S3, cacheSize)
+	}
+	// A shared source is closed once the requests using it have finished
+	if closer, ok := staticSources.current.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	staticSources.key, staticSources.current = key, opened
+	return opened
+}
+
+// memFileInfo describes a file or directory held by an archive or S3 source
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i *memFileInfo) Name() string       { return path.Base(i.name) }
+func (i *memFileInfo) Size() int64        { return i.size }
+func (i *memFileInfo) ModTime() time.Time { return i.modTime }
+func (i *memFileInfo) IsDir() bool        { return i.dir }
+func (i *memFileInfo) Sys() interface{}   { return nil }
+
+func (i *memFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// memFile is an open file whose content is held in memory; it is seekable so
+// http.ServeFileFS can answer range requests
+type memFile struct {
+	*bytes.Reader
+	info *memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// Read fails for directories, which are never served
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.info.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	return f.Reader.Read(p)
+}
+
+// newMemFile returns an open file for info with the given content
+func newMemFile(info *memFileInfo, data []byte) *memFile {
+	return &memFile{Reader: bytes.NewReader(data), info: info}
+}
+
+// contentCache holds file contents read from an archive or S3, evicting the
+// least recently used once the total size exceeds maxBytes
+type contentCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	order    *list.List // Most recently used at the front
+	entries  map[string]*list.Element
+}
+
+// contentCacheEntry is one cached value; expires is zero for entries that never expire
+type contentCacheEntry struct {
+	key     string
+	value   interface{}
+	size    int64
+	expires time.Time
+}
+
+func newContentCache(maxBytes int64) *contentCache {
+	return &contentCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the unexpired value stored for key
+func (c *contentCache) get(key string, now time.Time) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*contentCacheEntry)
+	if !entry.expires.IsZero() && !now.Before(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// put stores value under key unless it is larger than the whole cache
+func (c *contentCache) put(key string, value interface{}, size int64, expires time.Time) {
+	if size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&contentCacheEntry{key: key, value: value, size: size, expires: expires})
+	c.used += size
+	for c.used > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry; c.mu must be held
+func (c *contentCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*contentCacheEntry)
+	delete(c.entries, entry.key)
+	c.used -= entry.size
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// HeaderOriginalPath carries the path a client requested when it was routed
+// through a tenant alias, so the app can tell which URL was used
+const HeaderOriginalPath = "X-Original-Path"
+
+// matchTenantAlias returns the tenant and alias for a path that falls under a
+// tenant alias. A longer tenant path or alias takes precedence, the same
+// longest-prefix rule config.TenantForPath applies.
+func (h *Handler) matchTenantAlias(path string) (*config.Tenant, string, bool) {
+	var match *config.Tenant
+	var matchAlias string
+	bestMatchLen := 0
+
+	for i := range h.config.Applications.Tenants {
+		tenant := &h.config.Applications.Tenants[i]
+		if strings.HasPrefix(path, tenant.Path) && len(tenant.Path) > bestMatchLen {
+			match, matchAlias, bestMatchLen = nil, "", len(tenant.Path)
+		}
+		for _, alias := range tenant.Aliases {
+			if strings.HasPrefix(path, alias) && len(alias) > bestMatchLen {
+				match, matchAlias, bestMatchLen = tenant, alias, len(alias)
+			}
+		}
+	}
+	return match, matchAlias, match != nil
+}
+
+// handleTenantAlias serves requests for a tenant alias. In redirect mode the
+// client is sent to the tenant path with a 301; otherwise the request is
+// rewritten onto the tenant path so auth, try_files, and tenant routing all
+// see the primary path. Returns true if a redirect was sent.
+func (h *Handler) handleTenantAlias(w http.ResponseWriter, r *http.Request) bool {
+	// Only Navigator sets the original path; don't trust one from the client
+	r.Header.Del(HeaderOriginalPath)
+
+	tenant, alias, found := h.matchTenantAlias(r.URL.Path)
+	if !found {
+		return false
+	}
+
+	primaryPath := tenant.Path + strings.TrimPrefix(r.URL.Path, alias)
+
+	if tenant.AliasRedirect {
+		location := primaryPath
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		if recorder, ok := w.(*ResponseRecorder); ok {
+			recorder.SetMetadata("response_type", "redirect")
+			recorder.SetMetadata("destination", location)
+		}
+		logging.LogTenantAliasRedirect(r.URL.Path, location)
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
+		return true
+	}
+
+	logging.LogTenantAliasRouted(r.URL.Path, primaryPath, tenant.Name)
+	r.Header.Set(HeaderOriginalPath, r.URL.Path)
+	r.URL.Path = primaryPath
+	r.URL.RawPath = ""
+	return false
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+func aliasConfig(redirect bool) *config.Config {
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{
+		{Name: "index", Path: "/showcase/"},
+		{
+			Name:          "2025/boston",
+			Path:          "/showcase/2025/boston/",
+			Aliases:       []string{"/showcase/2024/boston/"},
+			AliasRedirect: redirect,
+		},
+	}
+	return cfg
+}
+
+func TestMatchTenantAlias(t *testing.T) {
+	cfg := aliasConfig(false)
+	cfg.Applications.Tenants = append(cfg.Applications.Tenants,
+		config.Tenant{Name: "2024/boston/archive", Path: "/showcase/2024/boston/archive/"})
+	h := &Handler{config: cfg}
+
+	tests := []struct {
+		path   string
+		alias  string
+		tenant string
+	}{
+		{"/showcase/2024/boston/", "/showcase/2024/boston/", "2025/boston"},
+		{"/showcase/2024/boston/heats/1", "/showcase/2024/boston/", "2025/boston"},
+		{"/showcase/2025/boston/heats", "", ""},
+		{"/showcase/2024/boston/archive/", "", ""}, // A longer tenant path wins
+		{"/showcase/2024/raleigh/", "", ""},
+	}
+	for _, tt := range tests {
+		tenant, alias, found := h.matchTenantAlias(tt.path)
+		if found != (tt.alias != "") || alias != tt.alias || (found && tenant.Name != tt.tenant) {
+			t.Errorf("matchTenantAlias(%q) = %v, %q, %v; want %q, %q", tt.path, tenant, alias, found, tt.tenant, tt.alias)
+		}
+	}
+}
+
+func TestTenantAliasInternalRouting(t *testing.T) {
+	h := &Handler{config: aliasConfig(false)}
+
+	req := httptest.NewRequest("GET", "/showcase/2024/boston/heats?page=2", nil)
+	req.Header.Set(HeaderOriginalPath, "/spoofed")
+	if h.handleTenantAlias(httptest.NewRecorder(), req) {
+		t.Fatal("internal routing should not respond")
+	}
+	if req.URL.Path != "/showcase/2025/boston/heats" || req.URL.RawQuery != "page=2" {
+		t.Errorf("routed to %s?%s", req.URL.Path, req.URL.RawQuery)
+	}
+	if got := req.Header.Get(HeaderOriginalPath); got != "/showcase/2024/boston/heats" {
+		t.Errorf("%s = %q", HeaderOriginalPath, got)
+	}
+
+	// Requests for the primary path don't carry a client-supplied original path
+	req = httptest.NewRequest("GET", "/showcase/2025/boston/heats", nil)
+	req.Header.Set(HeaderOriginalPath, "/spoofed")
+	h.handleTenantAlias(httptest.NewRecorder(), req)
+	if got := req.Header.Get(HeaderOriginalPath); got != "" {
+		t.Errorf("client-supplied %s was forwarded: %q", HeaderOriginalPath, got)
+	}
+}
+
+func TestTenantAliasRedirect(t *testing.T) {
+	handler := CreateTestHandler(aliasConfig(true), &process.AppManager{}, nil, &idle.Manager{})
+
+	req := httptest.NewRequest("GET", "/showcase/2024/boston/heats?page=2", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusMovedPermanently {
+		t.Errorf("status = %d, want 301", recorder.Code)
+	}
+	if got := recorder.Header().Get("Location"); got != "/showcase/2025/boston/heats?page=2" {
+		t.Errorf("Location = %q", got)
+	}
+}
+
+func TestTenantAliasServesStaticAndPublicPaths(t *testing.T) {
+	publicDir := t.TempDir()
+	bostonDir := filepath.Join(publicDir, "showcase", "2025", "boston")
+	if err := os.MkdirAll(bostonDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bostonDir, "heats.html"), []byte("Boston heats"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := aliasConfig(false)
+	cfg.Server.Static.PublicDir = publicDir
+	cfg.Server.Static.TryFiles = []string{".html"}
+	cfg.Auth.PublicPaths = []string{"/showcase/2025/boston/"}
+	handler := CreateTestHandler(cfg, &process.AppManager{}, nil, &idle.Manager{})
+
+	// The alias finds the prerendered page through the primary path's public path and try_files
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/showcase/2024/boston/heats", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "Boston heats") {
+		t.Errorf("alias: status = %d, body = %q", recorder.Code, recorder.Body.String())
+	}
+}
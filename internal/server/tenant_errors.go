@@ -0,0 +1,228 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// TenantErrorRestarted is the event of the entry that starts a tenant's
+// errors after its app restarts
+const TenantErrorRestarted = "restarted"
+
+// TenantError is a request to a tenant that failed with a 5xx status, or
+// the marker of a restart
+type TenantError struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event,omitempty"` // "restarted" for the marker; empty for errors
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"` // Why the tenant's app couldn't answer, such as a refused connection
+	ColdStart bool      `json:"cold_start,omitempty"`
+}
+
+// TenantErrorsStatus is a tenant's recent errors in the control API. The
+// rate covers the window from the oldest entry until now.
+type TenantErrorsStatus struct {
+	Tenant          string        `json:"tenant"`
+	Count           int           `json:"count"` // Errors, not counting a restart marker
+	WindowSeconds   float64       `json:"window_seconds"`
+	ErrorsPerMinute float64       `json:"errors_per_minute"`
+	Errors          []TenantError `json:"errors"` // Oldest first
+}
+
+// errorRing holds a tenant's most recent errors, overwriting the oldest
+type errorRing struct {
+	entries []TenantError
+	next    int // Where the next entry is written
+	full    bool
+	updated time.Time
+}
+
+func (r *errorRing) add(entry TenantError) {
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	r.full = r.full || r.next == 0
+	r.updated = entry.Time
+}
+
+// list returns the entries, oldest first
+func (r *errorRing) list() []TenantError {
+	if !r.full {
+		return append([]TenantError(nil), r.entries[:r.next]...)
+	}
+	return append(append([]TenantError(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// errorHistory holds each tenant's most recent errors for the control API.
+// Rings are created on a tenant's first error; across tenants they never
+// hold more than config.ErrorHistoryBudget entries. It outlives handlers, so
+// errors survive config reloads.
+type errorHistory struct {
+	mu      sync.Mutex
+	size    int
+	rings   map[string]*errorRing
+	started map[string]time.Time // Start time of each tenant's app, to notice restarts
+	tenants map[string]bool      // Configured tenants; no others are recorded
+	clock   clock.Clock
+}
+
+var tenantErrors = &errorHistory{
+	size:    config.DefaultErrorHistory,
+	rings:   make(map[string]*errorRing),
+	started: make(map[string]time.Time),
+	clock:   clock.Real,
+}
+
+// configure sets the number of errors kept per tenant and forgets tenants
+// that are no longer configured. Kept rings are resized, keeping their
+// newest entries.
+func (e *errorHistory) configure(size int, tenants []config.Tenant) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if size <= 0 {
+		size = config.DefaultErrorHistory
+	}
+	e.tenants = make(map[string]bool, len(tenants))
+	for i := range tenants {
+		e.tenants[tenants[i].Name] = true
+	}
+	for name := range e.started {
+		if !e.tenants[name] {
+			delete(e.started, name)
+		}
+	}
+	for name, ring := range e.rings {
+		if !e.tenants[name] {
+			delete(e.rings, name)
+			continue
+		}
+		if size != e.size {
+			entries := ring.list()
+			if len(entries) > size {
+				entries = entries[len(entries)-size:]
+			}
+			resized := &errorRing{entries: make([]TenantError, size)}
+			for _, entry := range entries {
+				resized.add(entry)
+			}
+			e.rings[name] = resized
+		}
+	}
+	e.size = size
+}
+
+// observe notes the start time of the tenant's app serving a request. When
+// it differs from the last one seen, the app has restarted: the tenant's
+// errors are cleared, leaving a marker.
+func (e *errorHistory) observe(tenant string, started time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.tenants != nil && !e.tenants[tenant] {
+		return
+	}
+	previous, seen := e.started[tenant]
+	e.started[tenant] = started
+	if !seen || previous.Equal(started) {
+		return
+	}
+	if _, ok := e.rings[tenant]; ok {
+		ring := &errorRing{entries: make([]TenantError, e.size)}
+		ring.add(TenantError{Time: e.clock.Now(), Event: TenantErrorRestarted})
+		e.rings[tenant] = ring
+	}
+}
+
+// recordResponse records a request answered for a tenant with a 5xx status,
+// using the metadata of its access log entry
+func (e *errorHistory) recordResponse(r *http.Request, status int, metadata map[string]interface{}) {
+	tenant, _ := metadata["tenant"].(string)
+	if tenant == "" || status < http.StatusInternalServerError {
+		return
+	}
+	message, _ := metadata["error_message"].(string)
+	coldStart, _ := metadata["cold_start"].(bool)
+	e.record(tenant, TenantError{
+		RequestID: r.Header.Get("X-Request-Id"),
+		Method:    r.Method,
+		Path:      truncateErrorText(r.URL.Path),
+		Status:    status,
+		Error:     truncateErrorText(message),
+		ColdStart: coldStart,
+	})
+}
+
+// record adds an error to a tenant's ring, creating the ring if needed
+func (e *errorHistory) record(tenant string, entry TenantError) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.tenants != nil && !e.tenants[tenant] {
+		return
+	}
+	entry.Time = e.clock.Now()
+	ring := e.rings[tenant]
+	if ring == nil {
+		e.makeRoom()
+		ring = &errorRing{entries: make([]TenantError, e.size)}
+		e.rings[tenant] = ring
+	}
+	ring.add(entry)
+}
+
+// makeRoom forgets the tenants whose last error is oldest until another
+// ring fits in the budget. Called with e.mu held.
+func (e *errorHistory) makeRoom() {
+	for len(e.rings) > 0 && (len(e.rings)+1)*e.size > config.ErrorHistoryBudget {
+		var oldest string
+		for name, ring := range e.rings {
+			if oldest == "" || ring.updated.Before(e.rings[oldest].updated) {
+				oldest = name
+			}
+		}
+		delete(e.rings, oldest)
+	}
+}
+
+// status returns a tenant's recent errors
+func (e *errorHistory) status(tenant string) TenantErrorsStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := TenantErrorsStatus{Tenant: tenant, Errors: []TenantError{}}
+	ring := e.rings[tenant]
+	if ring == nil {
+		return status
+	}
+	status.Errors = ring.list()
+	for _, entry := range status.Errors {
+		if entry.Event == "" {
+			status.Count++
+		}
+	}
+	if len(status.Errors) > 0 {
+		window := e.clock.Now().Sub(status.Errors[0].Time)
+		status.WindowSeconds = window.Seconds()
+		if window > 0 {
+			status.ErrorsPerMinute = float64(status.Count) / window.Minutes()
+		}
+	}
+	return status
+}
+
+// truncateErrorText caps text at ErrorHistoryMaxBytes, cutting before a
+// rune that would be split so the JSON listing stays valid UTF-8
+func truncateErrorText(text string) string {
+	if len(text) <= config.ErrorHistoryMaxBytes {
+		return text
+	}
+	end := config.ErrorHistoryMaxBytes
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end]
+}
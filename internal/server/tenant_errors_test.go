@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+)
+
+// useErrorHistory replaces the shared error history with an empty one
+// keeping size errors per tenant
+func useErrorHistory(t *testing.T, size int) *errorHistory {
+	history := &errorHistory{
+		size:    size,
+		rings:   make(map[string]*errorRing),
+		started: make(map[string]time.Time),
+		clock:   clock.Real,
+	}
+	previous := tenantErrors
+	tenantErrors = history
+	t.Cleanup(func() { tenantErrors = previous })
+	return history
+}
+
+// tenantErrorsOf reads a tenant's errors through the control API
+func tenantErrorsOf(t *testing.T, h *Handler, tenant string) TenantErrorsStatus {
+	t.Helper()
+	rec := control(h, "GET", "/tenants/"+tenant+"/errors")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("errors: %d %s", rec.Code, rec.Body.String())
+	}
+	var status TenantErrorsStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("errors: %v in %s", err, rec.Body.String())
+	}
+	return status
+}
+
+func TestTenantErrorsRecordsFailedResponses(t *testing.T) {
+	useErrorHistory(t, 3)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer backend.Close()
+	h, appManager := newPauseTestHandler(t, backend)
+
+	get := func(path, requestID string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Request-Id", requestID)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	get("/studios/boston/ok", "ok")
+	for _, id := range []string{"a", "b", "c", "d"} {
+		get("/studios/boston/fail", id)
+	}
+	get("/studios/raleigh/fail", "r")
+
+	status := tenantErrorsOf(t, h, "boston")
+	var ids []string
+	for _, entry := range status.Errors {
+		ids = append(ids, entry.RequestID)
+		if entry.Status != http.StatusInternalServerError || entry.Path != "/studios/boston/fail" {
+			t.Errorf("Entry = %+v, want a 500 for /studios/boston/fail", entry)
+		}
+	}
+	if strings.Join(ids, ",") != "b,c,d" || status.Count != 3 {
+		t.Errorf("Errors = %v (count %d), want the newest three", ids, status.Count)
+	}
+	if status := tenantErrorsOf(t, h, "raleigh"); status.Count != 1 {
+		t.Errorf("raleigh has %d errors, want 1", status.Count)
+	}
+
+	// A restart clears the tenant's errors, leaving a marker
+	appManager.StopApp("boston", "test")
+	get("/studios/boston/ok", "after")
+	status = tenantErrorsOf(t, h, "boston")
+	if len(status.Errors) != 1 || status.Errors[0].Event != TenantErrorRestarted || status.Count != 0 {
+		t.Errorf("Errors after restart = %+v, want only the restart marker", status.Errors)
+	}
+}
+
+func TestTenantErrorsRecordsUnreachableBackend(t *testing.T) {
+	useErrorHistory(t, config.DefaultErrorHistory)
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close() // Nothing listens on the port, so the connection is refused
+	h, _ := newPauseTestHandler(t, backend)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/studios/boston/heats", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("Status = %d, want 502", rec.Code)
+	}
+
+	status := tenantErrorsOf(t, h, "boston")
+	if status.Count != 1 || status.Errors[0].Status != http.StatusBadGateway || !strings.Contains(status.Errors[0].Error, "refused") {
+		t.Errorf("Errors = %+v, want a 502 reporting the refused connection", status.Errors)
+	}
+	if rec := control(h, "POST", "/tenants/boston/errors"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST errors = %d, want 405", rec.Code)
+	}
+}
+
+func TestErrorHistoryStaysWithinBudget(t *testing.T) {
+	history := useErrorHistory(t, config.ErrorHistoryBudget/2)
+	fake := clock.NewFake(time.Now())
+	history.clock = fake
+	for _, tenant := range []string{"a", "b", "c"} {
+		fake.Advance(time.Second)
+		history.record(tenant, TenantError{Status: http.StatusBadGateway})
+	}
+	if _, kept := history.rings["a"]; kept || len(history.rings) != 2 {
+		t.Errorf("Rings = %v, want the tenant whose last error is oldest forgotten", history.rings)
+	}
+
+	history.configure(config.ErrorHistoryBudget/2, []config.Tenant{{Name: "c"}})
+	if _, kept := history.rings["b"]; kept || len(history.rings) != 1 {
+		t.Errorf("Rings = %v, want unconfigured tenants forgotten", history.rings)
+	}
+	history.record("b", TenantError{Status: http.StatusBadGateway})
+	if _, kept := history.rings["b"]; kept {
+		t.Error("Errors of unconfigured tenants should not be recorded")
+	}
+}
+
+func TestTruncateErrorTextKeepsRunesWhole(t *testing.T) {
+	// Each "é" is two bytes; the cap falls inside one after the "x"
+	text := "x" + strings.Repeat("é", config.ErrorHistoryMaxBytes)
+	got := truncateErrorText(text)
+	if !utf8.ValidString(got) {
+		t.Errorf("truncateErrorText split a rune: %q", got[len(got)-4:])
+	}
+	if len(got) != config.ErrorHistoryMaxBytes-1 {
+		t.Errorf("len(truncateErrorText()) = %d, want %d", len(got), config.ErrorHistoryMaxBytes-1)
+	}
+	if short := "/showcase/é"; truncateErrorText(short) != short {
+		t.Errorf("truncateErrorText(%q) = %q, want it unchanged", short, truncateErrorText(short))
+	}
+}
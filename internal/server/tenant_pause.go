@@ -0,0 +1,340 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// pauseGate holds the tenants paused through the control API, and counts
+// each tenant's in-flight requests so a draining tenant's app is stopped
+// once its last request finishes. It outlives handlers, so pauses survive
+// config reloads.
+type pauseGate struct {
+	mu       sync.Mutex
+	paused   map[string]*tenantPause
+	inFlight map[string]int
+	clock    clock.Clock
+}
+
+// tenantPause is one tenant's pause
+type tenantPause struct {
+	message string
+	since   time.Time
+	until   time.Time // Zero for a pause that lasts until it's resumed
+	drain   bool      // Stop the app once in-flight requests finish
+	drained bool
+
+	// Where the tenant was routed when paused; a reload that changes either
+	// resumes the tenant
+	path, root string
+}
+
+// TenantPauseStatus describes a paused tenant in the control API and the
+// detailed health check
+type TenantPauseStatus struct {
+	Tenant   string     `json:"tenant"`
+	Message  string     `json:"message,omitempty"`
+	Since    time.Time  `json:"since"`
+	Until    *time.Time `json:"until,omitempty"`
+	Drain    bool       `json:"drain,omitempty"`
+	Drained  bool       `json:"drained,omitempty"`
+	InFlight int        `json:"in_flight"`
+}
+
+var tenantPauses = &pauseGate{
+	paused:   make(map[string]*tenantPause),
+	inFlight: make(map[string]int),
+	clock:    clock.Real,
+}
+
+// pause pauses a tenant, replacing any earlier pause, and returns true if the
+// tenant should be drained now because nothing is in flight
+func (g *pauseGate) pause(tenant config.Tenant, message string, ttl time.Duration, drain bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p := &tenantPause{message: message, since: g.clock.Now(), drain: drain, path: tenant.Path, root: tenant.Root}
+	if ttl > 0 {
+		p.until = p.since.Add(ttl)
+	}
+	g.paused[tenant.Name] = p
+	process.BumpStateVersion()
+	return g.drainNow(tenant.Name, p)
+}
+
+// resume lifts a tenant's pause, reporting whether it was paused
+func (g *pauseGate) resume(name string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.current(name) == nil {
+		return false
+	}
+	delete(g.paused, name)
+	process.BumpStateVersion()
+	return true
+}
+
+// current returns a tenant's pause, or nil, lifting the pause if its ttl has
+// passed. Called with g.mu held.
+func (g *pauseGate) current(name string) *tenantPause {
+	p := g.paused[name]
+	if p != nil && !p.until.IsZero() && !g.clock.Now().Before(p.until) {
+		delete(g.paused, name)
+		process.BumpStateVersion()
+		logging.LogTenantResumed(name, "expired")
+		return nil
+	}
+	return p
+}
+
+// drainNow reports, once, that a draining tenant has no requests in flight.
+// Called with g.mu held.
+func (g *pauseGate) drainNow(name string, p *tenantPause) bool {
+	if !p.drain || p.drained || g.inFlight[name] > 0 {
+		return false
+	}
+	p.drained = true
+	return true
+}
+
+// enter admits a request to a tenant unless it's paused, counting it as in
+// flight when count is set. A paused tenant's message and how many seconds
+// the client should wait are returned.
+func (g *pauseGate) enter(name string, count bool) (paused bool, message string, retryAfter int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p := g.current(name)
+	if p == nil {
+		if count {
+			g.inFlight[name]++
+		}
+		return false, "", 0
+	}
+	retryAfter = config.TenantPauseRetryAfter
+	if !p.until.IsZero() {
+		retryAfter = int(math.Ceil(p.until.Sub(g.clock.Now()).Seconds()))
+	}
+	return true, p.message, retryAfter
+}
+
+// leave counts a request admitted by enter as done, returning true if the
+// tenant should be drained now
+func (g *pauseGate) leave(name string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.inFlight[name]--; g.inFlight[name] <= 0 {
+		delete(g.inFlight, name)
+	}
+	if p := g.current(name); p != nil {
+		return g.drainNow(name, p)
+	}
+	return false
+}
+
+// reconcile resumes paused tenants that a reloaded config removed, moved to
+// another path, or gave another root. Other settings may change while a
+// tenant stays paused.
+func (g *pauseGate) reconcile(tenants []config.Tenant) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for name, p := range g.paused {
+		changed := true
+		for i := range tenants {
+			if tenants[i].Name == name {
+				changed = tenants[i].Path != p.path || tenants[i].Root != p.root
+				break
+			}
+		}
+		if changed {
+			delete(g.paused, name)
+			process.BumpStateVersion()
+			logging.LogTenantResumed(name, "reload")
+		}
+	}
+}
+
+// status lists the paused tenants, sorted by name
+func (g *pauseGate) status() []TenantPauseStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var status []TenantPauseStatus
+	for name := range g.paused {
+		p := g.current(name)
+		if p == nil {
+			continue
+		}
+		entry := TenantPauseStatus{
+			Tenant:   name,
+			Message:  p.message,
+			Since:    p.since,
+			Drain:    p.drain,
+			Drained:  p.drained,
+			InFlight: g.inFlight[name],
+		}
+		if !p.until.IsZero() {
+			until := p.until
+			entry.Until = &until
+		}
+		status = append(status, entry)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Tenant < status[j].Tenant })
+	return status
+}
+
+// servePausedTenant rejects a request to a paused tenant with 503. Other
+// requests, apart from WebSocket upgrades, are counted until they're done so
+// a draining tenant stops once the last one finishes. Returns true if a
+// response was written.
+func (h *Handler) servePausedTenant(recorder *ResponseRecorder, r *http.Request, tenant *config.Tenant) bool {
+	count := !proxy.IsWebSocketRequest(r)
+	paused, message, retryAfter := tenantPauses.enter(tenant.Name, count)
+	if !paused {
+		if count {
+			recorder.releaseWhenDone(func() {
+				if tenantPauses.leave(tenant.Name) {
+					go h.drainTenant(tenant.Name)
+				}
+			})
+		}
+		return false
+	}
+
+	if message == "" {
+		message = "Service Unavailable"
+	}
+	recorder.SetMetadata("tenant", tenant.Name)
+	recorder.SetMetadata("response_type", "paused")
+	recorder.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(recorder, message, http.StatusServiceUnavailable)
+	return true
+}
+
+// drainTenant stops a paused tenant's app once nothing is in flight
+func (h *Handler) drainTenant(tenantName string) {
+	logging.LogTenantDrained(tenantName)
+	if h.appManager != nil {
+		h.appManager.StopApp(tenantName, "paused")
+	}
+}
+
+// handleControl serves the control API under server.control_path:
+//
+//	GET  <control_path>/tenants                lists paused tenants
+//	POST <control_path>/tenants/<name>/pause   pauses a tenant (query: message, ttl, drain)
+//	POST <control_path>/tenants/<name>/resume  resumes a tenant
+//	GET  <control_path>/tenants/<name>/errors  lists a tenant's recent errors
+//	POST <control_path>/validate-config        validates a configuration (body or query: path)
+//
+// A tenant may be named by its name or its file-safe name.
+func (h *Handler) handleControl(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, h.config.Server.ControlPath)
+	if path == "/validate-config" {
+		h.handleValidateConfig(w, r)
+		return
+	}
+	if path == "/tenants" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeControlResponse(w, tenantPauses.status())
+		return
+	}
+
+	// Tenant names may contain slashes; the action is the last segment
+	rest, found := strings.CutPrefix(path, "/tenants/")
+	slash := strings.LastIndex(rest, "/")
+	if !found || slash <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	name, action := rest[:slash], rest[slash+1:]
+	if action != "pause" && action != "resume" && action != "errors" {
+		http.NotFound(w, r)
+		return
+	}
+	if action == "errors" && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if action != "errors" && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tenant := h.config.TenantByName(name)
+	if tenant == nil {
+		http.Error(w, "Unknown tenant "+strconv.Quote(name), http.StatusNotFound)
+		return
+	}
+
+	if action == "errors" {
+		// A restart since the tenant's last request still clears its errors
+		if h.appManager != nil {
+			if app, ok := h.appManager.GetApp(tenant.Name); ok {
+				tenantErrors.observe(tenant.Name, app.StartTime)
+			}
+		}
+		writeControlResponse(w, tenantErrors.status(tenant.Name))
+		return
+	}
+
+	if action == "resume" {
+		resumed := tenantPauses.resume(tenant.Name)
+		if resumed {
+			logging.LogTenantResumed(tenant.Name, "request")
+		}
+		writeControlResponse(w, map[string]interface{}{"tenant": tenant.Name, "resumed": resumed})
+		return
+	}
+
+	query := r.URL.Query()
+	var ttl time.Duration
+	if value := query.Get("ttl"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil || ttl <= 0 {
+			http.Error(w, "Invalid ttl parameter: "+strconv.Quote(value), http.StatusBadRequest)
+			return
+		}
+	}
+	drain := false
+	if value := query.Get("drain"); value != "" {
+		var err error
+		if drain, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid drain parameter: "+strconv.Quote(value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	logging.LogTenantPaused(tenant.Name, query.Get("ttl"), drain)
+	if tenantPauses.pause(*tenant, query.Get("message"), ttl, drain) {
+		go h.drainTenant(tenant.Name)
+	}
+	writeControlResponse(w, map[string]interface{}{"tenant": tenant.Name, "paused": true})
+}
+
+// writeControlResponse writes a control API result as JSON
+func writeControlResponse(w http.ResponseWriter, result interface{}) {
+	writeControlStatus(w, http.StatusOK, result)
+}
+
+// writeControlStatus writes a control API result as JSON with status
+func writeControlStatus(w http.ResponseWriter, status int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(result)
+}
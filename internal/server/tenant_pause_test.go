@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/clock"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// usePauseGate replaces the shared pause gate with an empty one on a fake clock
+func usePauseGate(t *testing.T) *clock.Fake {
+	fake := clock.NewFake(time.Now())
+	previous := tenantPauses
+	tenantPauses = &pauseGate{
+		paused:   make(map[string]*tenantPause),
+		inFlight: make(map[string]int),
+		clock:    fake,
+	}
+	t.Cleanup(func() { tenantPauses = previous })
+	return fake
+}
+
+// newPauseTestHandler returns a handler with tenants boston and raleigh served
+// by backend, and the control API at /_navigator/control
+func newPauseTestHandler(t *testing.T, backend *httptest.Server) (*Handler, *process.AppManager) {
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  control_path: /_navigator/control
+applications:
+  tenants:
+    - path: /studios/boston/
+      name: boston
+    - path: /studios/raleigh/
+      name: raleigh
+`))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	appManager := process.NewAppManager(cfg)
+	appManager.StubApps(backend.Listener.Addr().(*net.TCPAddr).Port)
+	t.Cleanup(appManager.Cleanup)
+	return CreateTestHandler(cfg, appManager, nil, &idle.Manager{}).(*Handler), appManager
+}
+
+// control sends a request to the control API from localhost
+func control(h *Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/_navigator/control"+path, nil)
+	req.RemoteAddr = "127.0.0.1:4321"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTenantPauseLetsInFlightRequestsFinish(t *testing.T) {
+	fake := usePauseGate(t)
+	backend := newSlowBackend(t)
+	h, _ := newPauseTestHandler(t, backend.Server)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() { inFlight <- get("/studios/boston/heats") }()
+	for atomic.LoadInt32(&backend.inFlight) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if rec := control(h, "POST", "/tenants/boston/pause?message=Migrating&ttl=90s"); rec.Code != http.StatusOK {
+		t.Fatalf("pause: %d %s", rec.Code, rec.Body.String())
+	}
+	rec := get("/studios/boston/heats")
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "Migrating\n" || rec.Header().Get("Retry-After") != "90" {
+		t.Errorf("New request: %d %q, Retry-After %q; want 503 with the message", rec.Code, rec.Body.String(), rec.Header().Get("Retry-After"))
+	}
+	upgrade := httptest.NewRequest("GET", "/studios/boston/cable", nil)
+	upgrade.Header.Set("Connection", "Upgrade")
+	upgrade.Header.Set("Upgrade", "websocket")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, upgrade)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("WebSocket upgrade: %d, want 503", rec.Code)
+	}
+
+	var status []TenantPauseStatus
+	if err := json.Unmarshal(control(h, "GET", "/tenants").Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid status: %v", err)
+	}
+	if len(status) != 1 || status[0].Tenant != "boston" || status[0].InFlight != 1 || status[0].Until == nil {
+		t.Errorf("status = %+v, want boston with one request in flight", status)
+	}
+
+	// The request that was already in flight completes normally
+	close(backend.gate)
+	if rec := <-inFlight; rec.Code != http.StatusOK {
+		t.Errorf("In-flight request: %d, want 200", rec.Code)
+	}
+	if rec := get("/studios/raleigh/heats"); rec.Code != http.StatusOK {
+		t.Errorf("Other tenant: %d, want 200", rec.Code)
+	}
+
+	if rec := control(h, "POST", "/tenants/boston/resume"); rec.Code != http.StatusOK {
+		t.Fatalf("resume: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/studios/boston/heats"); rec.Code != http.StatusOK {
+		t.Errorf("After resume: %d, want 200", rec.Code)
+	}
+
+	// A pause with a ttl lifts itself
+	control(h, "POST", "/tenants/boston/pause?ttl=1m")
+	fake.Advance(time.Minute)
+	if rec := get("/studios/boston/heats"); rec.Code != http.StatusOK {
+		t.Errorf("After ttl: %d, want 200", rec.Code)
+	}
+
+	for path, code := range map[string]int{
+		"/tenants/denver/pause":           http.StatusNotFound,
+		"/tenants/boston/stop":            http.StatusNotFound,
+		"/tenants/boston/pause?ttl=never": http.StatusBadRequest,
+	} {
+		if rec := control(h, "POST", path); rec.Code != code {
+			t.Errorf("POST %s: %d, want %d", path, rec.Code, code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/_navigator/control/tenants/boston/pause", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Remote pause: %d, want 403", rec.Code)
+	}
+}
+
+func TestTenantPauseDrainsAndSurvivesReload(t *testing.T) {
+	usePauseGate(t)
+	backend := newSlowBackend(t)
+	h, appManager := newPauseTestHandler(t, backend.Server)
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/studios/boston/heats", nil))
+		done <- rec.Code
+	}()
+	for atomic.LoadInt32(&backend.inFlight) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	control(h, "POST", "/tenants/boston/pause?drain=true")
+	if _, running := appManager.GetApp("boston"); !running {
+		t.Fatal("App stopped while a request was in flight")
+	}
+	close(backend.gate)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("In-flight request: %d, want 200", code)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for _, running := appManager.GetApp("boston"); running && time.Now().Before(deadline); _, running = appManager.GetApp("boston") {
+		time.Sleep(time.Millisecond)
+	}
+	if _, running := appManager.GetApp("boston"); running {
+		t.Error("Drained tenant's app is still running")
+	}
+
+	// A reload keeps the pause while the tenant is unchanged
+	tenants := append([]config.Tenant(nil), h.config.Applications.Tenants...)
+	tenantPauses.reconcile(tenants)
+	if status := tenantPauses.status(); len(status) != 1 || !status[0].Drained {
+		t.Errorf("After reload: %+v, want boston still paused", status)
+	}
+	tenants[0].Root = "/rails/boston"
+	tenantPauses.reconcile(tenants)
+	if status := tenantPauses.status(); len(status) != 0 {
+		t.Errorf("After changing the tenant: %+v, want it resumed", status)
+	}
+}
+
+func TestTenantPauseSurvivesReloadingTheSameConfig(t *testing.T) {
+	fake := usePauseGate(t)
+	yaml := []byte(`
+applications:
+  tenants:
+    - name: boston
+      path: /studios/boston/
+      maintenance:
+        windows:
+          - start: "2030-01-01T00:00:00Z"
+            end: "2030-01-01T01:00:00Z"
+`)
+	load := func() []config.Tenant {
+		cfg, err := config.ParseYAML(yaml)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.Applications.Tenants
+	}
+
+	// Requests evaluate the paused tenant's maintenance windows
+	paused := load()[0]
+	paused.Maintenance.Active(fake.Now())
+	tenantPauses.pause(paused, "", 0, false)
+	tenantPauses.reconcile(load())
+	if status := tenantPauses.status(); len(status) != 1 {
+		t.Errorf("After reloading an unchanged config: %+v, want boston still paused", status)
+	}
+
+	tenants := load()
+	tenants[0].Path = "/boston/"
+	tenantPauses.reconcile(tenants)
+	if status := tenantPauses.status(); len(status) != 0 {
+		t.Errorf("After moving the tenant: %+v, want it resumed", status)
+	}
+}
@@ -147,8 +147,8 @@ func TestTenantRoutingIntegration(t *testing.T) {
 	}
 }
 
-// TestExtractTenantFromPath tests the extractTenantFromPath function with various scenarios
-func TestExtractTenantFromPath(t *testing.T) {
+// TestTenantForPath tests config.TenantForPath with various scenarios
+func TestTenantForPath(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Applications.Tenants = []config.Tenant{
 		{Name: "", Path: "/showcase/"},
@@ -215,17 +215,11 @@ func TestExtractTenantFromPath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Manually test the extraction logic
 			var bestMatch string
-			bestMatchLen := 0
-			found := false
-
-			for _, tenant := range cfg.Applications.Tenants {
-				if strings.HasPrefix(tt.requestPath, tenant.Path) && len(tenant.Path) > bestMatchLen {
-					bestMatch = tenant.Name
-					bestMatchLen = len(tenant.Path)
-					found = true
-				}
+			tenant := cfg.TenantForPath(tt.requestPath)
+			found := tenant != nil
+			if found {
+				bestMatch = tenant.Name
 			}
 
 			if found != tt.expectedFound {
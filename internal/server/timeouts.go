@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// ApplyTimeouts configures srv's connection timeouts from server.timeouts:
+// read_header and idle. The handler sets read and write as deadlines on each
+// request instead, so WebSocket and event-stream requests aren't cut off by
+// them and a reload changes them along with the handler.
+func ApplyTimeouts(srv *http.Server, timeouts config.TimeoutsConfig) {
+	srv.ReadHeaderTimeout = timeouts.ReadHeader.Std()
+	srv.IdleTimeout = timeouts.Idle.Std()
+	// Connections count their requests even when max_keepalive_requests is
+	// unset, so a reload can set it
+	srv.ConnContext = countConnRequests
+}
+
+// Listen opens the TCP listener for addr, probing idle client connections
+// with SO_KEEPALIVE at server.timeouts.tcp_keepalive
+func Listen(addr string, timeouts config.TimeoutsConfig) (net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: timeouts.TCPKeepAlive.Std()}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// connRequestsKey holds the count of requests served on a connection
+type connRequestsKey struct{}
+
+// countConnRequests gives each connection a request counter, for
+// max_keepalive_requests
+func countConnRequests(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connRequestsKey{}, new(atomic.Int64))
+}
+
+// setRequestDeadlines sets the per-request deadlines of server.timeouts and
+// closes connections that have served max_keepalive_requests
+func setRequestDeadlines(w http.ResponseWriter, r *http.Request, timeouts config.TimeoutsConfig) {
+	if timeouts.Read == 0 && timeouts.Write == 0 && timeouts.MaxKeepaliveRequests == 0 {
+		return
+	}
+	streaming := isStreamingRequest(r)
+
+	if limit := timeouts.MaxKeepaliveRequests; limit > 0 && !streaming {
+		if count, ok := r.Context().Value(connRequestsKey{}).(*atomic.Int64); ok && count.Add(1) >= int64(limit) {
+			w.Header().Set("Connection", "close")
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	if timeouts.Write > 0 {
+		// A deadline left by an earlier request on the connection would
+		// otherwise still apply to a stream
+		deadline := time.Time{}
+		if !streaming {
+			deadline = time.Now().Add(timeouts.Write.Std())
+		}
+		_ = rc.SetWriteDeadline(deadline)
+	}
+
+	// The read deadline bounds the request body only: once the body has been
+	// read it is cleared, so a slow app isn't cut off. Requests without a
+	// body have nothing left to read after their headers.
+	if timeouts.Read > 0 && !streaming && r.Body != nil && r.Body != http.NoBody {
+		if rc.SetReadDeadline(time.Now().Add(timeouts.Read.Std())) == nil {
+			r.Body = &deadlineBody{ReadCloser: r.Body, rc: rc}
+		}
+	}
+}
+
+// isStreamingRequest reports whether r opens a WebSocket or an event stream,
+// which are exempt from the read and write deadlines
+func isStreamingRequest(r *http.Request) bool {
+	return proxy.IsWebSocketRequest(r) || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// deadlineBody clears the connection's read deadline once the request body
+// has been read
+type deadlineBody struct {
+	io.ReadCloser
+	rc      *http.ResponseController
+	cleared bool
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF && !b.cleared {
+		b.cleared = true
+		_ = b.rc.SetReadDeadline(time.Time{})
+	}
+	return n, err
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// startTimeoutServer starts a test server configured with timeouts
+func startTimeoutServer(t *testing.T, timeouts config.TimeoutsConfig, handler http.HandlerFunc) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setRequestDeadlines(w, r, timeouts)
+		handler(w, r)
+	}))
+	ApplyTimeouts(ts.Config, timeouts)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestReadHeaderTimeoutDisconnectsStalledClient(t *testing.T) {
+	ts := startTimeoutServer(t, config.TimeoutsConfig{ReadHeader: config.Duration(100 * time.Millisecond)},
+		func(w http.ResponseWriter, r *http.Request) {})
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Send part of the headers, then stall
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	_, err = io.ReadAll(conn)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("Stalled client was not disconnected")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Stalled client disconnected after %v, want about 100ms", elapsed)
+	}
+}
+
+func TestWriteTimeoutSparesEventStreams(t *testing.T) {
+	ts := startTimeoutServer(t, config.TimeoutsConfig{Write: config.Duration(100 * time.Millisecond)},
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/events":
+				w.Header().Set("Content-Type", "text/event-stream")
+				for i := 0; i < 5; i++ {
+					_, _ = fmt.Fprintf(w, "data: %d\n\n", i)
+					_ = http.NewResponseController(w).Flush()
+					time.Sleep(50 * time.Millisecond)
+				}
+			case "/slow":
+				time.Sleep(250 * time.Millisecond)
+				_, _ = io.WriteString(w, "late")
+			default:
+				_, _ = io.WriteString(w, "ok")
+			}
+		})
+	client := ts.Client()
+
+	// A quick request leaves a write deadline on the keep-alive connection
+	resp, err := client.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	// The stream outlives the write timeout, on the same connection
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || strings.Count(string(body), "data: ") != 5 {
+		t.Errorf("Event stream = %q, %v; want all 5 events", body, err)
+	}
+
+	// Other responses are bounded by it
+	resp, err = client.Get(ts.URL + "/slow")
+	if err == nil {
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && string(body) == "late" {
+			t.Error("Expected a response written after the write timeout to be cut off")
+		}
+	}
+}
+
+func TestReadTimeoutBoundsRequestBodyOnly(t *testing.T) {
+	readErrors := make(chan error, 1)
+	ts := startTimeoutServer(t, config.TimeoutsConfig{Read: config.Duration(100 * time.Millisecond)},
+		func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.ReadAll(r.Body)
+			if err != nil {
+				readErrors <- err
+				return
+			}
+			// A slow app isn't cut off once the body is read
+			time.Sleep(250 * time.Millisecond)
+			if r.Context().Err() != nil {
+				t.Error("Request context cancelled after the body was read")
+			}
+			_, _ = io.WriteString(w, "ok")
+		})
+
+	resp, err := ts.Client().Post(ts.URL, "text/plain", strings.NewReader("heat sheet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("Body = %q, want ok", body)
+	}
+
+	// A client that stalls sending its body is cut off
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 10\r\n\r\nhe"))
+	select {
+	case <-readErrors:
+	case <-time.After(2 * time.Second):
+		t.Error("Stalled request body was not cut off")
+	}
+}
+
+func TestMaxKeepaliveRequests(t *testing.T) {
+	ts := startTimeoutServer(t, config.TimeoutsConfig{MaxKeepaliveRequests: 2},
+		func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, "ok") })
+	client := ts.Client()
+
+	for i, wantClose := range []bool{false, true, false} {
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.Close != wantClose {
+			t.Errorf("Request %d: connection closed = %v, want %v", i+1, resp.Close, wantClose)
+		}
+	}
+}
+
+func TestWriteTimeoutSurvivesReload(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(250 * time.Millisecond)
+		_, _ = io.WriteString(w, "late")
+	}))
+	defer backend.Close()
+
+	load := func() *config.Config {
+		cfg, err := config.ParseYAML([]byte(`
+server:
+  timeouts:
+    write: 100ms
+routes:
+  reverse_proxies:
+    - name: slow
+      prefix: /slow
+      target: ` + backend.URL + `
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	cfg := load()
+	ts := httptest.NewUnstartedServer(CreateTestHandler(cfg, nil, nil, nil))
+	ApplyTimeouts(ts.Config, cfg.Server.Timeouts)
+	ts.Start()
+	defer ts.Close()
+
+	// A reload replaces the handler, as cmd/navigator does
+	ts.Config.Handler = CreateTestHandler(load(), nil, nil, nil)
+
+	resp, err := ts.Client().Get(ts.URL + "/slow")
+	if err == nil {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && string(body) == "late" {
+			t.Error("Expected the slow route to be cut off by the write timeout after a reload")
+		}
+	}
+}
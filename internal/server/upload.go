@@ -0,0 +1,258 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// methodMkcol is the WebDAV method that creates a directory
+const methodMkcol = "MKCOL"
+
+// matchUpload returns the upload area with the longest prefix covering path
+func (h *Handler) matchUpload(path string) *config.UploadConfig {
+	var best *config.UploadConfig
+	for i := range h.config.Server.Static.Uploads {
+		upload := &h.config.Server.Static.Uploads[i]
+		if strings.HasPrefix(path, upload.Path) && (best == nil || len(upload.Path) > len(best.Path)) {
+			best = upload
+		}
+	}
+	return best
+}
+
+// handleUpload handles PUT requests, and DELETE and MKCOL requests when WebDAV
+// is enabled, for configured upload areas. Uploads always require the
+// credentials of the area's auth scope, even on public paths. Returns true if
+// the request was handled.
+func (h *Handler) handleUpload(w http.ResponseWriter, r *http.Request) bool {
+	upload := h.matchUpload(r.URL.Path)
+	if upload == nil {
+		return false
+	}
+	switch r.Method {
+	case http.MethodPut, http.MethodDelete, methodMkcol:
+	default:
+		return false
+	}
+
+	recorder, ok := w.(*ResponseRecorder)
+	if !ok {
+		// Outside the handler's pipeline there's no access log entry to
+		// annotate; the recorder only passes the response through
+		recorder = NewResponseRecorder(w, nil, r)
+	}
+	recorder.SetMetadata("response_type", "upload")
+
+	if r.Method != http.MethodPut && !upload.WebDAV {
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return true
+	}
+
+	scope := h.auth.ForPath(r.URL.Path)
+	if upload.AuthScope != "" {
+		scope = h.auth.Scope(upload.AuthScope)
+	}
+	if !scope.IsEnabled() {
+		// Never accept anonymous uploads, even if auth files failed to load
+		logging.LogUploadRejected(r.Method, r.URL.Path, "no credentials configured")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	}
+	// A trace assumes valid credentials, and writes nothing
+	if trace := tracing(w); trace != nil {
+		trace.step().Rule = upload.Path
+		return trace.dispose("upload", upload.Dir, 0)
+	}
+	if !scope.CheckAuth(r) {
+		recorder.SetMetadata("response_type", "auth-failure")
+		scope.RequireAuth(w)
+		return true
+	}
+	recorder.SetMetadata("auth_realm", scope.Realm)
+
+	target, ok := uploadTarget(upload, r.URL.Path)
+	if !ok {
+		logging.LogUploadRejected(r.Method, r.URL.Path, "invalid path")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	}
+	recorder.SetMetadata("file_path", target)
+
+	switch r.Method {
+	case http.MethodPut:
+		putUpload(recorder, r, upload, target)
+	case http.MethodDelete:
+		deleteUpload(w, r, target)
+	case methodMkcol:
+		mkcolUpload(w, r, target)
+	}
+	return true
+}
+
+// uploadTarget maps a request path onto the upload directory. Returns false
+// for paths that could escape the directory, directly or through a symlink.
+func uploadTarget(upload *config.UploadConfig, urlPath string) (string, bool) {
+	rel := strings.TrimSuffix(strings.TrimPrefix(urlPath, upload.Path), "/")
+	if rel == "" || strings.ContainsAny(rel, "\\:\x00") {
+		return "", false
+	}
+	for _, segment := range strings.Split(rel, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+
+	target := filepath.Join(upload.Dir, filepath.FromSlash(rel))
+	if !withinDir(upload.Dir, target) {
+		return "", false
+	}
+
+	// The parent may be a symlink pointing elsewhere; compare resolved paths
+	root, err := filepath.EvalSymlinks(upload.Dir)
+	if err != nil {
+		return "", false
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err == nil && parent != root && !withinDir(root, parent) {
+		return "", false
+	}
+	return target, true
+}
+
+// withinDir reports whether path is strictly inside dir
+func withinDir(dir, path string) bool {
+	return strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator))
+}
+
+// uploadExtensionAllowed reports whether the upload area accepts target's extension
+func uploadExtensionAllowed(upload *config.UploadConfig, target string) bool {
+	if len(upload.AllowedExtensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(target), "."))
+	for _, allowed := range upload.AllowedExtensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// putUpload streams the request body into a temporary file next to target and
+// renames it into place once complete, so a partial upload is never served
+func putUpload(recorder *ResponseRecorder, r *http.Request, upload *config.UploadConfig, target string) {
+	reject := func(status int, reason string) {
+		logging.LogUploadRejected(r.Method, r.URL.Path, reason)
+		http.Error(recorder, http.StatusText(status), status)
+	}
+
+	if !uploadExtensionAllowed(upload, target) {
+		reject(http.StatusUnsupportedMediaType, "extension not allowed")
+		return
+	}
+	if r.ContentLength > upload.MaxFileSize {
+		reject(http.StatusRequestEntityTooLarge, "file too large")
+		return
+	}
+
+	info, err := os.Stat(target)
+	exists := err == nil
+	switch {
+	case exists && info.IsDir():
+		reject(http.StatusConflict, "target is a directory")
+		return
+	case exists && !upload.AllowOverwrite:
+		reject(http.StatusConflict, "file exists")
+		return
+	}
+	if info, err := os.Stat(filepath.Dir(target)); err != nil || !info.IsDir() {
+		reject(http.StatusConflict, "parent directory does not exist")
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*.part")
+	if err != nil {
+		reject(http.StatusInternalServerError, err.Error())
+		return
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	size, err := io.Copy(tmp, http.MaxBytesReader(recorder, r.Body, upload.MaxFileSize))
+	recorder.SetMetadata("bytes_received", size)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		reject(http.StatusRequestEntityTooLarge, "file too large")
+		return
+	case err != nil:
+		reject(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		reject(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if upload.AllowOverwrite {
+		err = os.Rename(tmpName, target)
+	} else {
+		// Link fails if another upload created target in the meantime
+		err = os.Link(tmpName, target)
+	}
+	if err != nil {
+		if os.IsExist(err) {
+			reject(http.StatusConflict, "file exists")
+		} else {
+			reject(http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.LogUploadStored(r.URL.Path, target, size, exists)
+	if exists {
+		recorder.WriteHeader(http.StatusNoContent)
+	} else {
+		recorder.WriteHeader(http.StatusCreated)
+	}
+}
+
+// deleteUpload removes a file or an empty directory
+func deleteUpload(w http.ResponseWriter, r *http.Request, target string) {
+	if err := os.Remove(target); err != nil {
+		status := http.StatusConflict // e.g. a directory that is not empty
+		if os.IsNotExist(err) {
+			status = http.StatusNotFound
+		}
+		logging.LogUploadRejected(r.Method, r.URL.Path, err.Error())
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mkcolUpload creates a directory whose parent already exists
+func mkcolUpload(w http.ResponseWriter, r *http.Request, target string) {
+	if err := os.Mkdir(target, 0755); err != nil {
+		status := http.StatusConflict // Parent does not exist
+		if os.IsExist(err) {
+			status = http.StatusMethodNotAllowed
+		}
+		logging.LogUploadRejected(r.Method, r.URL.Path, err.Error())
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
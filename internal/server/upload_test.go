@@ -0,0 +1,213 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newUploadHandler returns a handler with one upload area at /uploads/ and
+// site-wide credentials user:password
+func newUploadHandler(t *testing.T, upload config.UploadConfig) (*Handler, string) {
+	t.Helper()
+	dir := t.TempDir()
+	htpasswd := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0644); err != nil { // password
+		t.Fatal(err)
+	}
+	uploadDir := filepath.Join(dir, "uploads")
+	if err := os.Mkdir(uploadDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	upload.Path = "/uploads/"
+	upload.Dir = uploadDir
+	if upload.MaxFileSize == 0 {
+		upload.MaxFileSize = 1024
+	}
+	cfg := &config.Config{}
+	cfg.Server.Static.PublicDir = dir
+	cfg.Server.Static.Uploads = []config.UploadConfig{upload}
+	cfg.Auth = config.AuthConfig{Enabled: true, HTPasswd: htpasswd}
+	basicAuth, err := auth.LoadAuthConfig(&cfg.Auth)
+	if err != nil {
+		t.Fatalf("LoadAuthConfig: %v", err)
+	}
+
+	return CreateTestHandler(cfg, &process.AppManager{}, basicAuth, &idle.Manager{}).(*Handler), uploadDir
+}
+
+func doUpload(h *Handler, method, path string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.SetBasicAuth("user", "password")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// assertNoPartialFiles fails if an upload left a temporary file behind
+func assertNoPartialFiles(t *testing.T, dir string) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(dir, ".upload-*"))
+	if len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestUploadPut(t *testing.T) {
+	h, dir := newUploadHandler(t, config.UploadConfig{AllowedExtensions: []string{"pdf"}})
+	h.disableLog = false
+	buf := captureAccessLog(t)
+
+	rec := doUpload(h, http.MethodPut, "/uploads/heat-sheet.pdf", strings.NewReader("%PDF-1.7 heats"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "heat-sheet.pdf"))
+	if err != nil || string(data) != "%PDF-1.7 heats" {
+		t.Errorf("stored file = %q, %v", data, err)
+	}
+	assertNoPartialFiles(t, dir)
+
+	entries := parseAccessLog(t, buf)
+	if len(entries) != 1 || entries[0].ResponseType != "upload" || entries[0].BytesReceived != 14 {
+		t.Errorf("access log = %+v, want one upload entry with 14 bytes received", entries)
+	}
+
+	// Existing files are only replaced with allow_overwrite
+	if rec := doUpload(h, http.MethodPut, "/uploads/heat-sheet.pdf", strings.NewReader("new")); rec.Code != http.StatusConflict {
+		t.Errorf("overwrite status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	h.config.Server.Static.Uploads[0].AllowOverwrite = true
+	if rec := doUpload(h, http.MethodPut, "/uploads/heat-sheet.pdf", strings.NewReader("new")); rec.Code != http.StatusNoContent {
+		t.Errorf("overwrite status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "heat-sheet.pdf")); string(data) != "new" {
+		t.Errorf("overwritten file = %q", data)
+	}
+
+	if rec := doUpload(h, http.MethodPut, "/uploads/script.sh", strings.NewReader("#!/bin/sh")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("disallowed extension status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/uploads/anonymous.pdf", strings.NewReader("x"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUploadWithoutRecorder(t *testing.T) {
+	h, dir := newUploadHandler(t, config.UploadConfig{})
+
+	// Called directly, the response isn't wrapped in a ResponseRecorder
+	req := httptest.NewRequest(http.MethodPut, "/uploads/notes.txt", strings.NewReader("notes"))
+	req.SetBasicAuth("user", "password")
+	rec := httptest.NewRecorder()
+	if !h.handleUpload(rec, req) {
+		t.Fatal("upload was not handled")
+	}
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "notes.txt")); err != nil || string(data) != "notes" {
+		t.Errorf("stored file = %q, %v", data, err)
+	}
+}
+
+func TestUploadRejectsOversize(t *testing.T) {
+	h, dir := newUploadHandler(t, config.UploadConfig{MaxFileSize: 16})
+
+	rec := doUpload(h, http.MethodPut, "/uploads/big.pdf", strings.NewReader(strings.Repeat("x", 17)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// Without a Content-Length the limit applies while streaming
+	req := httptest.NewRequest(http.MethodPut, "/uploads/chunked.pdf", strings.NewReader(strings.Repeat("x", 64)))
+	req.ContentLength = -1
+	req.SetBasicAuth("user", "password")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	for _, name := range []string{"big.pdf", "chunked.pdf"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was stored", name)
+		}
+	}
+	assertNoPartialFiles(t, dir)
+}
+
+func TestUploadRejectsTraversal(t *testing.T) {
+	h, dir := newUploadHandler(t, config.UploadConfig{WebDAV: true})
+	outside := filepath.Dir(dir)
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"dot dot", "/uploads/../escape.pdf"},
+		{"encoded dot dot", "/uploads/%2e%2e/escape.pdf"},
+		{"backslash", "/uploads/..%5cescape.pdf"},
+		{"symlink", "/uploads/link/escape.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doUpload(h, http.MethodPut, tt.path, strings.NewReader("escaped"))
+			if rec.Code == http.StatusCreated || rec.Code == http.StatusNoContent {
+				t.Errorf("status = %d, want the upload refused", rec.Code)
+			}
+			if _, err := os.Stat(filepath.Join(outside, "escape.pdf")); !os.IsNotExist(err) {
+				t.Error("file written outside the upload directory")
+			}
+		})
+	}
+
+	if rec := doUpload(h, http.MethodDelete, "/uploads/link/htpasswd", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("DELETE through symlink status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "htpasswd")); err != nil {
+		t.Error("file outside the upload directory was deleted")
+	}
+}
+
+func TestUploadWebDAV(t *testing.T) {
+	h, dir := newUploadHandler(t, config.UploadConfig{WebDAV: true})
+
+	if rec := doUpload(h, http.MethodPut, "/uploads/2025/heats.pdf", strings.NewReader("x")); rec.Code != http.StatusConflict {
+		t.Errorf("PUT without parent status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := doUpload(h, methodMkcol, "/uploads/2025/", nil); rec.Code != http.StatusCreated {
+		t.Errorf("MKCOL status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if rec := doUpload(h, http.MethodPut, "/uploads/2025/heats.pdf", strings.NewReader("x")); rec.Code != http.StatusCreated {
+		t.Errorf("PUT status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if rec := doUpload(h, http.MethodDelete, "/uploads/2025/heats.pdf", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2025", "heats.pdf")); !os.IsNotExist(err) {
+		t.Error("file was not deleted")
+	}
+
+	h.config.Server.Static.Uploads[0].WebDAV = false
+	if rec := doUpload(h, http.MethodDelete, "/uploads/2025/", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE without webdav status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/utils"
+)
+
+// ConfigValidation reports whether a configuration would load and start
+// cleanly, without applying it
+type ConfigValidation struct {
+	Valid    bool     `json:"valid"`
+	Config   string   `json:"config,omitempty"` // The file validated, when one was named
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// ValidateConfigFile validates the configuration in file; see ValidateConfig
+func ValidateConfigFile(file string, live *config.Config) *ConfigValidation {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return &ConfigValidation{Config: file, Errors: []string{err.Error()}, Warnings: []string{}}
+	}
+	report := ValidateConfig(content, live)
+	report.Config = file
+	return report
+}
+
+// ValidateConfig parses content as loading a config file would, then checks
+// it against the running server and its environment: ports must be free or
+// already in use by live, the directories and scripts it names must exist,
+// and its htpasswd files must be readable. live may be nil.
+func ValidateConfig(content []byte, live *config.Config) *ConfigValidation {
+	report := &ConfigValidation{Errors: []string{}, Warnings: []string{}}
+	defer func() { report.Valid = len(report.Errors) == 0 }()
+
+	cfg, err := config.ParseYAML(content)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	ports := config.CheckPorts(cfg)
+	report.Errors = append(report.Errors, ports.Errors...)
+	report.Warnings = append(report.Warnings, ports.Warnings...)
+	report.checkPortsFree(ports.Uses, live)
+	if warning := cfg.ForceHTTPSWarning(); warning != "" {
+		report.Warnings = append(report.Warnings, warning)
+	}
+
+	for _, tenant := range cfg.Applications.Tenants {
+		if tenant.Root != "" {
+			report.checkDirectory(fmt.Sprintf("tenant %q: root", tenant.Name), tenant.Root, true)
+		}
+	}
+	if publicDir := cfg.Server.Static.PublicDir; publicDir != "" {
+		report.checkDirectory("server.static.public_dir", publicDir, false)
+	}
+	for _, proc := range cfg.ManagedProcesses {
+		if proc.WorkingDir != "" {
+			report.checkDirectory(fmt.Sprintf("managed process %q: working_dir", proc.Name), proc.WorkingDir, true)
+		}
+	}
+	for _, script := range cfg.Server.CGIScripts {
+		if _, err := os.Stat(script.Script); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("cgi script %s: %v", script.Path, err))
+		}
+	}
+
+	if _, err := auth.LoadAuthConfig(&cfg.Auth); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("auth: %v", err))
+	}
+	return report
+}
+
+// checkPortsFree reports ports other than the tenant pool's that something
+// besides Navigator is listening on. The pool allocator skips bound ports,
+// so the pool range isn't checked.
+func (r *ConfigValidation) checkPortsFree(uses []config.PortUse, live *config.Config) {
+	ours := make(map[int]bool)
+	if live != nil {
+		for _, use := range config.CheckPorts(live).Uses {
+			if use.Owner != config.PortOwnerTenantPool {
+				ours[use.Port] = true
+			}
+		}
+	}
+	for _, use := range uses {
+		if use.Owner == config.PortOwnerTenantPool || ours[use.Port] {
+			continue
+		}
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(use.Port))
+		if err == nil {
+			_ = listener.Close()
+			continue
+		}
+		message := fmt.Sprintf("port %d (%s) is in use by another process", use.Port, use.Owner)
+		if use.Inferred {
+			r.Warnings = append(r.Warnings, message)
+		} else {
+			r.Errors = append(r.Errors, message)
+		}
+	}
+}
+
+// checkDirectory reports a path that isn't an existing directory, as an
+// error when required and otherwise as a warning
+func (r *ConfigValidation) checkDirectory(name, path string, required bool) {
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("%s is not a directory", path)
+	}
+	if err == nil {
+		return
+	}
+	message := fmt.Sprintf("%s: %v", name, err)
+	if required {
+		r.Errors = append(r.Errors, message)
+	} else {
+		r.Warnings = append(r.Warnings, message)
+	}
+}
+
+// Log writes the report to the structured log on behalf of source
+func (r *ConfigValidation) Log(source string) {
+	for _, warning := range r.Warnings {
+		slog.Warn("Configuration validation warning", "source", source, "config", r.Config, "warning", warning)
+	}
+	if !r.Valid {
+		slog.Error("Configuration failed validation", "source", source, "config", r.Config, "errors", r.Errors)
+		return
+	}
+	slog.Info("Configuration validated", "source", source, "config", r.Config, "warnings", len(r.Warnings))
+}
+
+// handleValidateConfig serves POST <control_path>/validate-config: the
+// configuration in the request body, or in the file the path parameter
+// names next to the current config file, is validated and nothing is
+// applied. An invalid configuration gets 422 with the same report.
+func (h *Handler) handleValidateConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var report *ConfigValidation
+	if path := r.URL.Query().Get("path"); path != "" {
+		var dirs []string
+		if h.currentConfigFn != nil {
+			dirs = utils.ReloadConfigDirs(h.currentConfigFn())
+		}
+		file, err := utils.AllowedConfigFile(path, dirs)
+		if err != nil {
+			http.Error(w, "Invalid path parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		report = ValidateConfigFile(file, h.config)
+	} else {
+		content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxValidateConfigBody))
+		if err != nil {
+			http.Error(w, "Cannot read configuration: "+err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if len(content) == 0 {
+			http.Error(w, "A configuration body or path parameter is required", http.StatusBadRequest)
+			return
+		}
+		report = ValidateConfig(content, h.config)
+	}
+
+	status := http.StatusOK
+	if !report.Valid {
+		status = http.StatusUnprocessableEntity
+	}
+	writeControlStatus(w, status, report)
+}
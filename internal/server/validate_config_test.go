@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+)
+
+// validateConfig posts a configuration to the control API from localhost
+func validateConfig(h *Handler, query, body string) (*httptest.ResponseRecorder, *ConfigValidation) {
+	req := httptest.NewRequest("POST", "/_navigator/control/validate-config"+query, strings.NewReader(body))
+	req.RemoteAddr = "127.0.0.1:4321"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var report ConfigValidation
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		return rec, nil
+	}
+	return rec, &report
+}
+
+func TestValidateConfigEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	liveFile := filepath.Join(dir, "navigator.yml")
+	live := `
+server:
+  listen: 3000
+  control_path: /_navigator/control
+applications:
+  tenants:
+    - path: /studios/boston/
+      name: boston
+`
+	if err := os.WriteFile(liveFile, []byte(live), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.ParseYAML([]byte(live))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	h := CreateTestHandler(cfg, nil, nil, &idle.Manager{}).(*Handler)
+	h.currentConfigFn = func() string { return liveFile }
+
+	htpasswd := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte("admin:$apr1$salt$hash\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	valid := fmt.Sprintf(`
+server:
+  listen: 3000
+  control_path: /_navigator/control
+auth:
+  enabled: true
+  htpasswd: %s
+applications:
+  tenants:
+    - path: /studios/boston/
+      name: boston
+      root: %s
+    - path: /studios/raleigh/
+      name: raleigh
+`, htpasswd, dir)
+
+	rec, report := validateConfig(h, "", valid)
+	if rec.Code != http.StatusOK || report == nil || !report.Valid || len(report.Errors) != 0 {
+		t.Fatalf("valid config = %d %s, want 200 and no errors", rec.Code, rec.Body.String())
+	}
+
+	// A port taken by something other than Navigator
+	taken, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	takenPort := taken.Addr().(*net.TCPAddr).Port
+
+	broken := fmt.Sprintf(`
+server:
+  listen: 3000
+  control_path: /_navigator/control
+auth:
+  enabled: true
+  htpasswd: %s
+applications:
+  tenants:
+    - path: /studios/boston/
+      name: boston
+      root: %s
+managed_processes:
+  - name: redis
+    command: redis-server
+    ports: [%d]
+`, filepath.Join(dir, "missing-htpasswd"), filepath.Join(dir, "missing"), takenPort)
+
+	rec, report = validateConfig(h, "", broken)
+	if rec.Code != http.StatusUnprocessableEntity || report == nil || report.Valid {
+		t.Fatalf("broken config = %d %s, want 422 and invalid", rec.Code, rec.Body.String())
+	}
+	for _, want := range []string{
+		`tenant "boston": root`,
+		fmt.Sprintf("port %d (managed process redis) is in use by another process", takenPort),
+		"auth: ",
+	} {
+		found := false
+		for _, e := range report.Errors {
+			found = found || strings.Contains(e, want)
+		}
+		if !found {
+			t.Errorf("errors = %q, want one mentioning %q", report.Errors, want)
+		}
+	}
+
+	rec, report = validateConfig(h, "", "server: [unclosed")
+	if rec.Code != http.StatusUnprocessableEntity || report == nil || len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "failed to parse YAML") {
+		t.Errorf("unparseable config = %d %s, want the parse error", rec.Code, rec.Body.String())
+	}
+
+	// Nothing was applied
+	if h.config != cfg || len(cfg.Applications.Tenants) != 1 || cfg.Auth.Enabled {
+		t.Errorf("live config changed by validation: %+v", cfg.Applications.Tenants)
+	}
+}
+
+func TestValidateConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	liveFile := filepath.Join(dir, "navigator.yml")
+	if err := os.WriteFile(liveFile, []byte("server:\n  control_path: /_navigator/control\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "navigator-new.yml"), []byte("server:\n  static:\n    public_dir: "+filepath.Join(dir, "missing")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(liveFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	h := CreateTestHandler(cfg, nil, nil, &idle.Manager{}).(*Handler)
+	h.currentConfigFn = func() string { return liveFile }
+
+	rec, report := validateConfig(h, "?path=navigator-new.yml", "")
+	if rec.Code != http.StatusOK || report == nil || report.Config != filepath.Join(dir, "navigator-new.yml") {
+		t.Fatalf("path config = %d %s", rec.Code, rec.Body.String())
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "server.static.public_dir") {
+		t.Errorf("warnings = %q, want the missing public_dir", report.Warnings)
+	}
+
+	outside := filepath.Join(t.TempDir(), "navigator.yml")
+	if err := os.WriteFile(outside, []byte("server:\n  listen: 3000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := validateConfig(h, "?path="+outside, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("path outside the config directory = %d, want 400", rec.Code)
+	}
+	if rec, _ := validateConfig(h, "", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty body = %d, want 400", rec.Code)
+	}
+}
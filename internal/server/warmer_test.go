@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/idle"
+	"github.com/rubys/navigator/internal/process"
+	"github.com/rubys/navigator/internal/warmer"
+)
+
+// newWarmedEcho serves an echo tenant with one warmer through a handler
+// listening on a test server, and starts its warmers
+func newWarmedEcho(t *testing.T, startPort int, keepWarm bool) (*warmer.Warmers, *process.AppManager) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Applications.Pools.StartPort = startPort
+	cfg.Applications.Tenants = []config.Tenant{{
+		Name:      "boston",
+		Path:      "/studios/boston/",
+		Framework: config.RuntimeInternalEcho,
+		Warmers:   []config.WarmerConfig{{Path: "/heats", Interval: config.Duration(20 * time.Millisecond), KeepWarm: keepWarm}},
+	}}
+	appManager := process.NewAppManager(cfg)
+	t.Cleanup(appManager.Cleanup)
+	srv := httptest.NewServer(CreateTestHandler(cfg, appManager, nil, &idle.Manager{}))
+	t.Cleanup(srv.Close)
+
+	warmers := warmer.New()
+	warmers.Configure(cfg, srv.URL, appManager)
+	t.Cleanup(warmers.Stop)
+	return warmers, appManager
+}
+
+// lastActivity returns when the tenant's app last served a request
+func lastActivity(t *testing.T, appManager *process.AppManager) time.Time {
+	t.Helper()
+	for _, status := range appManager.Status() {
+		if status.Tenant == "boston" {
+			return status.LastActivity
+		}
+	}
+	t.Fatal("boston is not running")
+	return time.Time{}
+}
+
+// waitForWarmerRequests polls until the warmer has sent count requests
+func waitForWarmerRequests(t *testing.T, warmers *warmer.Warmers, count int64) warmer.Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := warmers.Status()[0]
+		if status.Requests >= count {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Warmer = %+v, want %d requests", status, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWarmerRequestsDontKeepTenantBusy(t *testing.T) {
+	warmers, appManager := newWarmedEcho(t, 4880, false)
+
+	time.Sleep(100 * time.Millisecond)
+	if status := warmers.Status()[0]; status.Requests != 0 {
+		t.Fatalf("Warmer = %+v, want no requests while the app isn't running", status)
+	}
+	if _, running := appManager.GetApp("boston"); running {
+		t.Fatal("A warmer should never start the app")
+	}
+
+	app, err := appManager.GetOrStartApp("boston")
+	if err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+	<-app.ReadyChan()
+	started := lastActivity(t, appManager)
+
+	status := waitForWarmerRequests(t, warmers, 3)
+	if status.Failures != 0 || status.LastRun.Status != 200 || status.Path != "/studios/boston/heats" {
+		t.Errorf("Warmer = %+v, want successful requests of /studios/boston/heats", status)
+	}
+	if last := lastActivity(t, appManager); !last.Equal(started) {
+		t.Errorf("LastActivity moved from %v to %v; the idle timeout would never fire", started, last)
+	}
+}
+
+func TestWarmerWithKeepWarmCountsAsActivity(t *testing.T) {
+	warmers, appManager := newWarmedEcho(t, 4890, true)
+
+	app, err := appManager.GetOrStartApp("boston")
+	if err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+	<-app.ReadyChan()
+	started := lastActivity(t, appManager)
+
+	waitForWarmerRequests(t, warmers, 2)
+	if last := lastActivity(t, appManager); !last.After(started) {
+		t.Errorf("LastActivity = %v, want it to advance past %v with keep_warm", last, started)
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/rubys/navigator/internal/cable"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// trackWebSocketActivity counts the WebSocket connection being opened toward
+// app's idle decisions once it is hijacked, until it closes. It returns the
+// func recording the client's data frames.
+func trackWebSocketActivity(recorder *ResponseRecorder, app *process.WebApp) func() {
+	activity := app.NewWebSocketActivity()
+	previous := recorder.onHijack
+	recorder.onHijack = func(conn net.Conn) {
+		if previous != nil {
+			previous(conn)
+		}
+		app.TrackWebSocket(activity, conn)
+		recorder.releaseWhenDone(func() { app.UntrackWebSocket(activity) })
+	}
+	return activity.Frame
+}
+
+// trackCableActivity counts a cable connection toward the idle decisions of
+// the running tenant whose path covers the cable path, if it tracks
+// WebSockets
+func (h *Handler) trackCableActivity(recorder *ResponseRecorder, r *http.Request) *http.Request {
+	tenant := h.config.TenantForPath(r.URL.Path)
+	if tenant == nil || h.appManager == nil {
+		return r
+	}
+	app, running := h.appManager.GetApp(tenant.Name)
+	if !running || !app.ShouldTrackWebSockets(h.config.Applications.TrackWebSockets) {
+		return r
+	}
+	return r.WithContext(cable.WithActivity(r.Context(), trackWebSocketActivity(recorder, app)))
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+)
+
+// matchHost reports whether hostname matches pattern: a lowercase host
+// name, "*.example.com" for any subdomain of example.com, or "" for any host
+func matchHost(pattern, hostname string) bool {
+	if pattern == "" || pattern == hostname {
+		return true
+	}
+	return strings.HasPrefix(pattern, "*.") && strings.HasSuffix(hostname, pattern[1:])
+}
+
+// lookupWellKnown returns the server.well_known file for r, if any
+func lookupWellKnown(wellKnown *config.WellKnownConfig, r *http.Request) *config.WellKnownEntry {
+	if len(wellKnown.Entries) == 0 {
+		return nil
+	}
+	hostname := strings.ToLower(getHost(r))
+	if name, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = name
+	}
+	for i := range wellKnown.Entries {
+		entry := &wellKnown.Entries[i]
+		if entry.Path == r.URL.Path && matchHost(entry.Host, hostname) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// handleWellKnown serves robots.txt, security.txt, and the other files in
+// server.well_known, ahead of files of the same name in the public
+// directory. Returns false if the request isn't for one of them.
+func (h *Handler) handleWellKnown(w http.ResponseWriter, r *http.Request) bool {
+	entry := lookupWellKnown(&h.config.Server.WellKnown, r)
+	if entry == nil {
+		return false
+	}
+
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "well-known")
+	}
+	if attempt := statFile(h.staticHandler.source, sourceName(h.staticHandler.stripRootPath(r.URL.Path))); attempt.Exists && !attempt.IsDir {
+		logging.LogWellKnownShadowsFile(r.URL.Path, attempt.Path)
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return true
+	}
+
+	w.Header().Set("Content-Type", entry.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	w.Header().Set("Cache-Control", entry.CacheControl)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(entry.Body)
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+func TestWellKnownShadowsPublicFiles(t *testing.T) {
+	publicDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(publicDir, "robots.txt"), []byte("User-agent: *\nAllow: /\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(publicDir, "humans.txt"), []byte("From disk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.ParseYAML([]byte(`
+server:
+  static:
+    public_dir: ` + publicDir + `
+    allowed_extensions: [txt]
+  well_known:
+    robots_txt: {content: "User-agent: *\nDisallow: /\n"}
+    security_txt: {content: "Contact: mailto:security@example.com\n", cache_control: no-cache}
+    overrides:
+      - host: "*.example.com"
+        robots_txt: {content: "User-agent: *\nAllow: /\n"}
+      - prefix: /showcase/
+        robots_txt: {content: "User-agent: *\nDisallow: /showcase/private/\n"}
+applications:
+  tenants:
+    - path: /showcase/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := CreateTestHandler(cfg, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		method     string
+		host       string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantCache  string
+	}{
+		{"config wins over the public file", "GET", "staging.internal", "/robots.txt", http.StatusOK, "User-agent: *\nDisallow: /\n", "public, max-age=3600"},
+		{"host override", "GET", "www.example.com:8080", "/robots.txt", http.StatusOK, "User-agent: *\nAllow: /\n", "public, max-age=3600"},
+		{"tenant prefix override", "GET", "staging.internal", "/showcase/robots.txt", http.StatusOK, "User-agent: *\nDisallow: /showcase/private/\n", "public, max-age=3600"},
+		{"security.txt", "GET", "staging.internal", "/.well-known/security.txt", http.StatusOK, "Contact: mailto:security@example.com\n", "no-cache"},
+		{"head request", "HEAD", "staging.internal", "/robots.txt", http.StatusOK, "", "public, max-age=3600"},
+		{"post rejected", "POST", "staging.internal", "/robots.txt", http.StatusMethodNotAllowed, "Method Not Allowed\n", ""},
+		{"other files come from disk", "GET", "staging.internal", "/humans.txt", http.StatusOK, "From disk\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusOK && tt.wantCache != "" {
+				if got := rec.Header().Get("Cache-Control"); got != tt.wantCache {
+					t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+				}
+				if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+					t.Errorf("Content-Type = %q, want text/plain; charset=utf-8", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/rubys/navigator/internal/proxy"
+)
+
+// primaryWorkerURL is set on secondary workers (server.workers > 1). Tenant
+// requests are forwarded there because only the primary worker runs tenants.
+var primaryWorkerURL atomic.Pointer[string]
+
+// SetPrimaryWorkerURL configures the primary worker that tenant requests are
+// forwarded to. An empty URL serves tenants from this process.
+func SetPrimaryWorkerURL(url string) {
+	if url == "" {
+		primaryWorkerURL.Store(nil)
+		return
+	}
+	primaryWorkerURL.Store(&url)
+}
+
+// forwardToPrimary proxies the request to the primary worker when this
+// process is a secondary worker. Returns true if the request was handled.
+func forwardToPrimary(w http.ResponseWriter, r *http.Request) bool {
+	target := primaryWorkerURL.Load()
+	if target == nil {
+		return false
+	}
+
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		recorder.SetMetadata("response_type", "worker")
+		recorder.SetMetadata("destination", *target)
+	}
+	proxy.ProxyWithWebSocketSupport(w, r, *target, nil)
+	return true
+}
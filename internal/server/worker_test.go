@@ -0,0 +1,41 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwardToPrimary(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "primary "+r.URL.Path)
+	}))
+	defer primary.Close()
+
+	t.Run("single process serves tenants itself", func(t *testing.T) {
+		SetPrimaryWorkerURL("")
+		rec := httptest.NewRecorder()
+		if forwardToPrimary(rec, httptest.NewRequest("GET", "/showcase/2025/", nil)) {
+			t.Error("expected request not to be forwarded")
+		}
+	})
+
+	t.Run("secondary worker forwards to primary", func(t *testing.T) {
+		SetPrimaryWorkerURL(primary.URL)
+		defer SetPrimaryWorkerURL("")
+
+		req := httptest.NewRequest("GET", "/showcase/2025/", nil)
+		rec := httptest.NewRecorder()
+		recorder := NewTestResponseRecorder(rec, nil, req)
+		if !forwardToPrimary(recorder, req) {
+			t.Fatal("expected request to be forwarded")
+		}
+		if body := rec.Body.String(); body != "primary /showcase/2025/" {
+			t.Errorf("body = %q, want response from primary", body)
+		}
+		if recorder.metadata["response_type"] != "worker" {
+			t.Errorf("response_type = %v, want worker", recorder.metadata["response_type"])
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"sync"
+)
+
+// activity counts background work in progress by kind, such as CGI scripts
+// and hooks, so the idle manager can defer the machine's idle action until
+// it finishes
+var activity struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// BeginActivity records work of kind starting. Call the returned function
+// when it ends; calls after the first do nothing.
+func BeginActivity(kind string) (end func()) {
+	activity.mu.Lock()
+	if activity.counts == nil {
+		activity.counts = make(map[string]int)
+	}
+	activity.counts[kind]++
+	activity.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			activity.mu.Lock()
+			defer activity.mu.Unlock()
+			if activity.counts[kind]--; activity.counts[kind] <= 0 {
+				delete(activity.counts, kind)
+			}
+		})
+	}
+}
+
+// ActivityCounts returns the work in progress of each of kinds, omitting
+// kinds with none
+func ActivityCounts(kinds []string) map[string]int {
+	activity.mu.Lock()
+	defer activity.mu.Unlock()
+	counts := make(map[string]int)
+	for _, kind := range kinds {
+		if count := activity.counts[kind]; count > 0 {
+			counts[kind] = count
+		}
+	}
+	return counts
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "testing"
+
+func TestBeginActivity(t *testing.T) {
+	endFirst := BeginActivity("test-work")
+	endSecond := BeginActivity("test-work")
+	if counts := ActivityCounts([]string{"test-work", "other-work"}); counts["test-work"] != 2 || len(counts) != 1 {
+		t.Errorf("ActivityCounts = %v, want two test-work", counts)
+	}
+
+	endFirst()
+	endFirst() // Ending twice counts once
+	if counts := ActivityCounts([]string{"test-work"}); counts["test-work"] != 1 {
+		t.Errorf("ActivityCounts = %v, want one test-work", counts)
+	}
+	endSecond()
+	if counts := ActivityCounts([]string{"test-work"}); len(counts) != 0 {
+		t.Errorf("ActivityCounts = %v, want none", counts)
+	}
+}
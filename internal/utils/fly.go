@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// FlyInstance identifies the Fly.io machine Navigator runs on. Fields are
+// empty when it doesn't run on Fly.
+type FlyInstance struct {
+	Region    string `json:"region,omitempty"`
+	MachineID string `json:"machine_id,omitempty"`
+	AllocID   string `json:"alloc_id,omitempty"`
+}
+
+var flyInstance = sync.OnceValue(readFlyInstance)
+
+// Fly returns the Fly.io machine Navigator runs on, read from FLY_REGION,
+// FLY_MACHINE_ID, and FLY_ALLOC_ID the first time it's called
+func Fly() FlyInstance {
+	return flyInstance()
+}
+
+func readFlyInstance() FlyInstance {
+	return FlyInstance{
+		Region:    os.Getenv("FLY_REGION"),
+		MachineID: os.Getenv("FLY_MACHINE_ID"),
+		AllocID:   os.Getenv("FLY_ALLOC_ID"),
+	}
+}
+
+// Expand replaces $fly_region and $fly_machine in s
+func (f FlyInstance) Expand(s string) string {
+	if !strings.Contains(s, "$fly_") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "$fly_region", f.Region)
+	return strings.ReplaceAll(s, "$fly_machine", f.MachineID)
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestReadFlyInstance(t *testing.T) {
+	t.Setenv("FLY_REGION", "ord")
+	t.Setenv("FLY_MACHINE_ID", "148e21ea7e1289")
+	t.Setenv("FLY_ALLOC_ID", "148e21ea7e1289")
+	fly := readFlyInstance()
+	if fly != (FlyInstance{Region: "ord", MachineID: "148e21ea7e1289", AllocID: "148e21ea7e1289"}) {
+		t.Errorf("readFlyInstance() = %+v", fly)
+	}
+	if got := fly.Expand("region=$fly_region machine=$fly_machine"); got != "region=ord machine=148e21ea7e1289" {
+		t.Errorf("Expand() = %q", got)
+	}
+
+	t.Setenv("FLY_REGION", "")
+	t.Setenv("FLY_MACHINE_ID", "")
+	t.Setenv("FLY_ALLOC_ID", "")
+	fly = readFlyInstance()
+	if fly != (FlyInstance{}) {
+		t.Errorf("readFlyInstance() off Fly = %+v, want empty", fly)
+	}
+	if got := fly.Expand("/$fly_region/index.html"); got != "//index.html" {
+		t.Errorf("Expand() off Fly = %q, want the variables empty", got)
+	}
+	if got := fly.Expand("$remote_addr"); got != "$remote_addr" {
+		t.Errorf("Expand() = %q, want other variables untouched", got)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ErrFileLocked is returned by LockFile when another process holds the lock
+var ErrFileLocked = errors.New("file is locked")
+
+// LockFile takes an exclusive advisory lock on file without waiting; it's
+// released when the file is closed
+func LockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrFileLocked
+	}
+	return err
+}
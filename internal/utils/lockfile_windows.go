@@ -0,0 +1,37 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ErrFileLocked is returned by LockFile when another process holds the lock
+var ErrFileLocked = errors.New("file is locked")
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// LockFile takes an exclusive lock on file without waiting; it's released
+// when the file is closed. The locked byte is far past the holder's identity
+// so others can still read it.
+func LockFile(file *os.File) error {
+	overlapped := syscall.Overlapped{OffsetHigh: 0x7fffffff}
+	r, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately,
+		0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return ErrFileLocked
+	}
+	return err
+}
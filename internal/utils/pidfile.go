@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// PIDFile is a PID file locked for the life of the process that wrote it, so
+// a second instance can't clobber it and a stale file is recognizable: no
+// running Navigator holds its lock.
+type PIDFile struct {
+	path string
+	file *os.File
+}
+
+// AcquirePIDFile writes the current PID to path and locks it. It fails with
+// an "already running" error if another process holds the lock; a stale
+// file left by a Navigator that crashed is taken over.
+func AcquirePIDFile(path string) (*PIDFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PID file: %w", err)
+	}
+	if err := LockFile(file); err != nil {
+		pid, _ := readPID(file)
+		_ = file.Close()
+		if errors.Is(err, ErrFileLocked) {
+			return nil, fmt.Errorf("navigator is already running (pid %d, PID file %s)", pid, path)
+		}
+		return nil, fmt.Errorf("failed to lock PID file %s: %w", path, err)
+	}
+
+	if pid, err := readPID(file); err == nil && pid != os.Getpid() {
+		slog.Info("Taking over stale PID file", "path", path, "stalePid", pid)
+	}
+	if err := file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return &PIDFile{path: path, file: file}, nil
+}
+
+// Release removes the PID file and releases its lock. It may be called more
+// than once, and on a nil PIDFile.
+func (p *PIDFile) Release() {
+	if p == nil || p.file == nil {
+		return
+	}
+	// Removing before closing keeps the lock until the file is gone, so a
+	// starting instance never has its new file removed; Windows can't remove
+	// an open file, so it is removed again after closing
+	removed := os.Remove(p.path) == nil
+	_ = p.file.Close()
+	if !removed {
+		_ = os.Remove(p.path)
+	}
+	p.file = nil
+}
+
+// readPID reads the PID a PID file holds
+func readPID(r io.ReaderAt) (int, error) {
+	buf := make([]byte, 32)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+}
+
+// SendReloadSignal sends a HUP signal to the running navigator process. It
+// refuses to signal the process a stale PID file names: one whose lock no
+// Navigator holds and whose process is gone or isn't Navigator.
+func SendReloadSignal(pidFile string) error {
+	pid, err := runningNavigator(pidFile)
+	if err != nil {
+		return err
+	}
+
+	// Find the process
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %v", pid, err)
+	}
+
+	// Send HUP signal
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("navigator is not running (process %d not found)", pid)
+		}
+		return fmt.Errorf("failed to send signal to process %d: %v", pid, err)
+	}
+
+	return nil
+}
+
+// runningNavigator returns the PID of the Navigator that wrote pidFile,
+// removing the file if it is stale
+func runningNavigator(pidFile string) (int, error) {
+	file, err := os.OpenFile(pidFile, os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("navigator is not running (PID file %s not found)", pidFile)
+		}
+		return 0, fmt.Errorf("failed to read PID file: %v", err)
+	}
+	defer file.Close()
+
+	pid, err := readPID(file)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PID in file %s: %v", pidFile, err)
+	}
+
+	// A running Navigator holds the lock
+	if err := LockFile(file); errors.Is(err, ErrFileLocked) {
+		return pid, nil
+	}
+
+	// Nobody does: the file is stale, unless it was written by a Navigator
+	// too old to lock it
+	if !processAlive(pid) {
+		_ = os.Remove(pidFile)
+		return 0, fmt.Errorf("navigator is not running (stale PID file %s names exited process %d)", pidFile, pid)
+	}
+	if command, err := processCommand(pid); err == nil && !looksLikeNavigator(command) {
+		return 0, fmt.Errorf("process %d named by %s is %q, not navigator; the PID file is stale", pid, pidFile, command)
+	}
+	return pid, nil
+}
+
+// looksLikeNavigator reports whether command, a process's executable, is
+// Navigator: it is named navigator, or like this executable
+func looksLikeNavigator(command string) bool {
+	name := strings.TrimSuffix(filepath.Base(command), ".exe")
+	if strings.Contains(strings.ToLower(name), "navigator") {
+		return true
+	}
+	self, err := os.Executable()
+	return err == nil && strings.TrimSuffix(filepath.Base(self), ".exe") == name
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A PID no process has: above Linux's pid_max and macOS's PID_MAX
+const deadPID = 2147483646
+
+func TestAcquirePIDFileRejectsSecondInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "navigator.pid")
+
+	pidFile, err := AcquirePIDFile(path)
+	if err != nil {
+		t.Fatalf("AcquirePIDFile failed: %v", err)
+	}
+	if _, err := AcquirePIDFile(path); err == nil || !strings.Contains(err.Error(), "already running (pid "+strconv.Itoa(os.Getpid())) {
+		t.Errorf("Second AcquirePIDFile error = %v, want already running", err)
+	}
+	if data, _ := os.ReadFile(path); strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("PID file = %q, want this process's PID", data)
+	}
+
+	pidFile.Release()
+	pidFile.Release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("PID file should be removed on release")
+	}
+
+	again, err := AcquirePIDFile(path)
+	if err != nil {
+		t.Fatalf("AcquirePIDFile after release failed: %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquirePIDFileTakesOverStaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "navigator.pid")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(deadPID)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pidFile, err := AcquirePIDFile(path)
+	if err != nil {
+		t.Fatalf("AcquirePIDFile should take over a stale file: %v", err)
+	}
+	defer pidFile.Release()
+	if data, _ := os.ReadFile(path); string(data) != strconv.Itoa(os.Getpid())+"\n" {
+		t.Errorf("PID file = %q, want this process's PID", data)
+	}
+}
+
+func TestSendReloadSignalStalePIDFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process command lines are not available on windows")
+	}
+	path := filepath.Join(t.TempDir(), "navigator.pid")
+
+	// The process exited
+	if err := os.WriteFile(path, []byte(strconv.Itoa(deadPID)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SendReloadSignal(path); err == nil || !strings.Contains(err.Error(), "stale PID file") {
+		t.Errorf("SendReloadSignal error = %v, want stale PID file", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Stale PID file should be removed")
+	}
+
+	// The PID was reused by another program, which must not be signalled
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep unavailable: %v", err)
+	}
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+	if err := os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SendReloadSignal(path); err == nil || !strings.Contains(err.Error(), "not navigator") {
+		t.Errorf("SendReloadSignal error = %v, want not navigator", err)
+	}
+	if !processAlive(cmd.Process.Pid) {
+		t.Error("Unrelated process was signalled")
+	}
+
+	// Missing file
+	if err := SendReloadSignal(filepath.Join(t.TempDir(), "missing.pid")); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("SendReloadSignal error = %v, want not running", err)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+// processAlive reports whether a process with pid exists
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
+
+// processCommand isn't available on Windows; callers assume Navigator
+func processCommand(pid int) (string, error) {
+	return "", errors.New("process command lines are not available on windows")
+}
@@ -1,16 +1,142 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
-// ReloadDecision contains the result of checking whether config should be reloaded
+// ReloadFileEnv names the environment variable holding the file a hook or
+// CGI script may write a reload request to, as a JSON ReloadDecision
+const ReloadFileEnv = "NAVIGATOR_RELOAD_FILE"
+
+// ReloadDecision contains the result of checking whether config should be
+// reloaded. Hooks and CGI scripts can also write one, as JSON, to the file
+// named by $NAVIGATOR_RELOAD_FILE.
 type ReloadDecision struct {
-	ShouldReload  bool
-	Reason        string
-	NewConfigFile string
+	ShouldReload   bool     `json:"should_reload"`
+	Reason         string   `json:"reason,omitempty"`
+	NewConfigFile  string   `json:"new_config_file,omitempty"`
+	RestartTenants []string `json:"restart_tenants,omitempty"` // Tenants restarted once any reload is done
+	ValidateOnly   bool     `json:"validate_only,omitempty"`   // Validate the config file instead of reloading it
+	Source         string   `json:"-"`                         // The hook or CGI script that asked for it
+}
+
+// Requested reports whether the decision asks for a reload, a restart, or a
+// validation
+func (d ReloadDecision) Requested() bool {
+	return d.ShouldReload || len(d.RestartTenants) > 0 || d.ValidateOnly
+}
+
+// NewReloadFile creates the empty file a command may write a reload request
+// to. The caller removes it.
+func NewReloadFile() (string, error) {
+	file, err := os.CreateTemp("", "navigator-reload-*.json")
+	if err != nil {
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+// ReadReloadRequest reads the reload request a command wrote to path, or
+// returns nil if it wrote none. A new_config_file must lie within one of
+// allowedDirs; a relative one is resolved against the first. A request to
+// reload without a new config file reloads currentConfigFile. With
+// validate_only, new_config_file (or currentConfigFile) is validated and
+// nothing is reloaded or restarted.
+func ReadReloadRequest(path, source, currentConfigFile string, allowedDirs []string) (*ReloadDecision, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var request ReloadDecision
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("invalid reload request: %w", err)
+	}
+	request.Source = source
+
+	if request.ValidateOnly && (request.ShouldReload || len(request.RestartTenants) > 0) {
+		return nil, fmt.Errorf("validate_only can't be combined with should_reload or restart_tenants")
+	}
+
+	loads := request.ShouldReload || request.ValidateOnly
+	if request.NewConfigFile != "" {
+		if !loads {
+			return nil, fmt.Errorf("new_config_file %q given without should_reload", request.NewConfigFile)
+		}
+		if request.NewConfigFile, err = AllowedConfigFile(request.NewConfigFile, allowedDirs); err != nil {
+			return nil, fmt.Errorf("new_config_file: %w", err)
+		}
+	} else if loads {
+		request.NewConfigFile = currentConfigFile
+	}
+	if loads && request.Reason == "" {
+		request.Reason = "requested by " + source
+	}
+	return &request, nil
+}
+
+// AllowedConfigFile returns the path of an existing config file within one
+// of dirs, following symlinks so none can lead outside them. A relative path
+// is resolved against the first.
+func AllowedConfigFile(configFile string, dirs []string) (string, error) {
+	if len(dirs) == 0 {
+		return "", fmt.Errorf("%q is not allowed: no config directories", configFile)
+	}
+	if !filepath.IsAbs(configFile) {
+		configFile = filepath.Join(dirs[0], configFile)
+	}
+	resolved, err := filepath.EvalSymlinks(configFile)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return "", err
+	}
+	for _, dir := range dirs {
+		if dir, err = filepath.EvalSymlinks(dir); err != nil {
+			continue
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return configFile, nil
+		}
+	}
+	return "", fmt.Errorf("%q is outside the allowed directories %q", configFile, dirs)
+}
+
+// ReloadConfigDirs returns the directories a reload request may name a config
+// file in: those of the current config file and of any configured
+// reload_config paths
+func ReloadConfigDirs(currentConfigFile string, reloadConfigs ...string) []string {
+	var dirs []string
+	for _, file := range append([]string{currentConfigFile}, reloadConfigs...) {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		seen := false
+		for _, existing := range dirs {
+			seen = seen || existing == dir
+		}
+		if !seen {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
 }
 
 // ShouldReloadConfig determines if configuration should be reloaded after a command execution
@@ -3,11 +3,7 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
-	"os"
-	"strconv"
 	"strings"
-	"syscall"
 
 	"github.com/rubys/navigator/internal/config"
 )
@@ -64,53 +60,6 @@ func ExtractTenantName(path string) string {
 	return ""
 }
 
-// WritePIDFile writes the current process PID to a file
-func WritePIDFile(pidFile string) error {
-	pid := os.Getpid()
-	return os.WriteFile(pidFile, []byte(strconv.Itoa(pid)), 0644)
-}
-
-// RemovePIDFile removes the PID file
-func RemovePIDFile(pidFile string) {
-	_ = os.Remove(pidFile)
-}
-
-// SendReloadSignal sends a HUP signal to the running navigator process
-func SendReloadSignal(pidFile string) error {
-	// Read PID from file
-	pidData, err := os.ReadFile(pidFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("navigator is not running (PID file not found)")
-		}
-		return fmt.Errorf("failed to read PID file: %v", err)
-	}
-
-	pid, err := strconv.Atoi(strings.TrimSpace(string(pidData)))
-	if err != nil {
-		return fmt.Errorf("invalid PID in file: %v", err)
-	}
-
-	// Find the process
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return fmt.Errorf("failed to find process %d: %v", pid, err)
-	}
-
-	// Send HUP signal
-	if err := process.Signal(syscall.SIGHUP); err != nil {
-		// Check if process exists
-		if err.Error() == "os: process already finished" {
-			// Clean up stale PID file
-			RemovePIDFile(pidFile)
-			return fmt.Errorf("navigator is not running (process %d not found)", pid)
-		}
-		return fmt.Errorf("failed to send signal to process %d: %v", pid, err)
-	}
-
-	return nil
-}
-
 // GetDefaultMaintenancePage returns the maintenance page content
 func GetDefaultMaintenancePage() string {
 	return `<!DOCTYPE html>
@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"os"
 	"strings"
 	"testing"
 
@@ -54,42 +53,6 @@ func TestExtractTenantName(t *testing.T) {
 	}
 }
 
-func TestWritePIDFile(t *testing.T) {
-	// Test with temporary file
-	tmpFile := "/tmp/test-navigator.pid"
-	defer func() { _ = os.Remove(tmpFile) }()
-
-	err := WritePIDFile(tmpFile)
-	if err != nil {
-		t.Errorf("WritePIDFile should not error: %v", err)
-	}
-
-	// Check file exists
-	if _, err := os.Stat(tmpFile); os.IsNotExist(err) {
-		t.Error("PID file should exist after WritePIDFile")
-	}
-}
-
-func TestRemovePIDFile(t *testing.T) {
-	// Create a test PID file
-	tmpFile := "/tmp/test-navigator-remove.pid"
-	err := os.WriteFile(tmpFile, []byte("12345"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test PID file: %v", err)
-	}
-
-	// Test removing it
-	RemovePIDFile(tmpFile)
-
-	// Check file is gone
-	if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
-		t.Error("PID file should be removed after RemovePIDFile")
-	}
-
-	// Test removing non-existent file (should not panic)
-	RemovePIDFile("/non/existent/file.pid")
-}
-
 func TestGetDefaultMaintenancePage(t *testing.T) {
 	page := GetDefaultMaintenancePage()
 
@@ -0,0 +1,308 @@
+// Package warmer sends the requests of the tenants' warmers: each warmer
+// requests a path of its tenant through Navigator's listener at its
+// interval, while the tenant's app is running, so the request exercises
+// routing and the app's caches stay hot. A tenant's warmers send one
+// request at a time; a warmer due while another is running is skipped.
+package warmer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rubys/navigator/internal/auth"
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/logging"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// TokenHeader carries the token that marks a request as sent by a warmer.
+// KeepWarmHeader is set as well when the warmer has keep_warm set.
+const (
+	TokenHeader    = "X-Navigator-Warmer-Token"
+	KeepWarmHeader = "X-Navigator-Keep-Warm"
+)
+
+// token marks the requests warmers send
+var token = auth.NewInternalToken(TokenHeader)
+
+// Authorize marks r as sent by a warmer; with keepWarm, r counts as activity
+// of the tenant's app
+func Authorize(r *http.Request, keepWarm bool) {
+	token.Set(r)
+	if keepWarm {
+		r.Header.Set(KeepWarmHeader, "true")
+	}
+}
+
+// Warming reports whether r was sent by a warmer, and whether that warmer
+// keeps its tenant's app warm. The headers are removed, so they are never
+// passed on.
+func Warming(r *http.Request) (warming, keepWarm bool) {
+	warming = token.Valid(r)
+	keepWarm = warming && r.Header.Get(KeepWarmHeader) == "true"
+	r.Header.Del(TokenHeader)
+	r.Header.Del(KeepWarmHeader)
+	return warming, keepWarm
+}
+
+// Apps finds the running app of a tenant
+type Apps interface {
+	GetApp(tenantName string) (*process.WebApp, bool)
+}
+
+// RunResult is the outcome of a warmer's most recent request
+type RunResult struct {
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"` // Why the request failed
+}
+
+// Status reports a warmer for the detailed health check
+type Status struct {
+	Tenant   string     `json:"tenant"`
+	Path     string     `json:"path"`
+	Interval string     `json:"interval"`
+	KeepWarm bool       `json:"keep_warm,omitempty"`
+	Requests int64      `json:"requests"`
+	Failures int64      `json:"failures"`
+	Skipped  int64      `json:"skipped"` // Not sent while another of the tenant's warmers was running
+	LastRun  *RunResult `json:"last_run,omitempty"`
+}
+
+// entry is a configured warmer
+type entry struct {
+	tenant   string
+	path     string // The full path requested
+	interval time.Duration
+	keepWarm bool
+	slot     chan struct{} // Shared by the tenant's warmers
+}
+
+// key identifies a warmer across reloads
+func (e *entry) key() string {
+	return e.tenant + " " + e.path
+}
+
+// counts are a warmer's requests so far
+type counts struct {
+	requests, failures, skipped int64
+	last                        *RunResult
+}
+
+// Warmers runs the warmers of the current configuration. Counts are kept by
+// tenant and path, so they survive reloads.
+type Warmers struct {
+	mu      sync.Mutex
+	client  *http.Client
+	entries []*entry
+	counts  map[string]*counts
+	cancel  context.CancelFunc // Stops the current configuration's warmers
+}
+
+// New creates a set of warmers with none configured
+func New() *Warmers {
+	return &Warmers{
+		client: &http.Client{Timeout: config.WarmerRequestTimeout},
+		counts: make(map[string]*counts),
+	}
+}
+
+// defaultWarmers runs the warmers of the configuration Navigator serves
+var defaultWarmers = New()
+
+// Configure replaces the default warmers with those of cfg
+func Configure(cfg *config.Config, baseURL string, apps Apps) {
+	defaultWarmers.Configure(cfg, baseURL, apps)
+}
+
+// Stop stops the default warmers
+func Stop() {
+	defaultWarmers.Stop()
+}
+
+// Report returns the default warmers, or nil without any
+func Report() []Status {
+	return defaultWarmers.Status()
+}
+
+// Configure stops the current warmers and starts those of cfg, which send
+// their requests to baseURL. A warmer keeps its counts if a warmer for the
+// same tenant and path is still configured.
+func (w *Warmers) Configure(cfg *config.Config, baseURL string, apps Apps) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	keys := make(map[string]bool)
+	for i := range cfg.Applications.Tenants {
+		tenant := &cfg.Applications.Tenants[i]
+		slot := make(chan struct{}, 1)
+		for j := range tenant.Warmers {
+			warmer := &tenant.Warmers[j]
+			e := &entry{
+				tenant:   tenant.Name,
+				path:     tenant.WarmerPath(warmer),
+				interval: warmer.Interval.Std(),
+				keepWarm: warmer.KeepWarm,
+				slot:     slot,
+			}
+			if e.interval <= 0 {
+				e.interval = config.DefaultWarmerInterval
+			}
+			w.entries = append(w.entries, e)
+			keys[e.key()] = true
+			if w.counts[e.key()] == nil {
+				w.counts[e.key()] = &counts{}
+			}
+			go w.loop(ctx, e, cfg.Server.Hostname, baseURL, apps)
+		}
+	}
+	for key := range w.counts {
+		if !keys[key] {
+			delete(w.counts, key)
+		}
+	}
+}
+
+// Stop stops the warmers, cancelling requests in progress
+func (w *Warmers) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stop()
+}
+
+// stop cancels the current warmers; w.mu must be held
+func (w *Warmers) stop() {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.entries = nil
+}
+
+// Status reports every warmer, in configuration order
+func (w *Warmers) Status() []Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var status []Status
+	for _, e := range w.entries {
+		c := w.counts[e.key()]
+		warmer := Status{
+			Tenant:   e.tenant,
+			Path:     e.path,
+			Interval: e.interval.String(),
+			KeepWarm: e.keepWarm,
+			Requests: c.requests,
+			Failures: c.failures,
+			Skipped:  c.skipped,
+		}
+		if c.last != nil {
+			result := *c.last
+			warmer.LastRun = &result
+		}
+		status = append(status, warmer)
+	}
+	return status
+}
+
+// loop warms e at its interval until ctx is cancelled
+func (w *Warmers) loop(ctx context.Context, e *entry, hostname, baseURL string, apps Apps) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.warm(ctx, e, hostname, baseURL, apps)
+		}
+	}
+}
+
+// warm sends e's request if its tenant's app is running and none of the
+// tenant's other warmers is sending one
+func (w *Warmers) warm(ctx context.Context, e *entry, hostname, baseURL string, apps Apps) {
+	if !running(apps, e.tenant) {
+		return
+	}
+	select {
+	case e.slot <- struct{}{}:
+		defer func() { <-e.slot }()
+	default:
+		w.record(e, func(c *counts) { c.skipped++ })
+		return
+	}
+
+	started := time.Now()
+	status, err := w.request(ctx, e, hostname, baseURL)
+	duration := time.Since(started)
+	if ctx.Err() != nil {
+		return // Stopped or reconfigured while the request was in progress
+	}
+	logging.LogWarmerRequest(e.tenant, e.path, status, duration, err)
+
+	result := &RunResult{Started: started, Duration: duration.Seconds(), Status: status}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	w.record(e, func(c *counts) {
+		c.requests++
+		if err != nil {
+			c.failures++
+		}
+		c.last = result
+	})
+}
+
+// request sends e's request through Navigator's listener; any status of
+// 400 or above is a failure
+func (w *Warmers) request(ctx context.Context, e *entry, hostname, baseURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+e.path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if hostname != "" {
+		req.Host = hostname
+	}
+	Authorize(req, e.keepWarm)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode, fmt.Errorf("GET %s: %s", e.path, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// record updates e's counts, unless e is no longer configured
+func (w *Warmers) record(e *entry, update func(*counts)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if c := w.counts[e.key()]; c != nil {
+		update(c)
+	}
+}
+
+// running reports whether the tenant's app has finished starting
+func running(apps Apps, tenant string) bool {
+	app, ok := apps.GetApp(tenant)
+	if !ok {
+		return false
+	}
+	select {
+	case <-app.ReadyChan():
+		return true
+	default:
+		return false
+	}
+}
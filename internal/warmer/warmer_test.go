@@ -0,0 +1,104 @@
+package warmer
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+	"github.com/rubys/navigator/internal/process"
+)
+
+// newStubbedWarmers starts warmers for a tenant whose running app is a stub
+// answered by backend, which also stands in for Navigator's listener
+func newStubbedWarmers(t *testing.T, backend *httptest.Server, warmers ...config.WarmerConfig) *Warmers {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Applications.Tenants = []config.Tenant{{Name: "boston", Path: "/studios/boston/", Warmers: warmers}}
+	apps := process.NewAppManager(cfg)
+	apps.StubApps(backend.Listener.Addr().(*net.TCPAddr).Port)
+	t.Cleanup(apps.Cleanup)
+	if _, err := apps.GetOrStartApp("boston"); err != nil {
+		t.Fatalf("GetOrStartApp() error = %v", err)
+	}
+
+	w := New()
+	w.Configure(cfg, backend.URL, apps)
+	t.Cleanup(w.Stop)
+	return w
+}
+
+func every(path string, interval time.Duration) config.WarmerConfig {
+	return config.WarmerConfig{Path: path, Interval: config.Duration(interval)}
+}
+
+func TestWarmersSendOneRequestPerTenantAtATime(t *testing.T) {
+	var inFlight, most atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if warming, _ := Warming(r); !warming {
+			t.Error("Warmer requests should carry the token")
+		}
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > most.Load() {
+			most.Store(n)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}))
+	defer backend.Close()
+	w := newStubbedWarmers(t, backend, every("/a", 10*time.Millisecond), every("/b", 10*time.Millisecond))
+
+	time.Sleep(300 * time.Millisecond)
+	var requests, skipped int64
+	for _, status := range w.Status() {
+		requests += status.Requests
+		skipped += status.Skipped
+	}
+	if most.Load() != 1 {
+		t.Errorf("%d requests were in flight at once, want 1", most.Load())
+	}
+	if requests == 0 || skipped == 0 {
+		t.Errorf("requests = %d, skipped = %d; want both counted", requests, skipped)
+	}
+}
+
+func TestWarmerFailuresAreCounted(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+	w := newStubbedWarmers(t, backend, every("/fail", 10*time.Millisecond))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Status()[0].Failures < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Warmer = %+v, want failures to keep being counted", w.Status()[0])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	status := w.Status()[0]
+	if status.LastRun.Status != http.StatusInternalServerError || status.LastRun.Error == "" {
+		t.Errorf("LastRun = %+v, want the 500 reported", status.LastRun)
+	}
+}
+
+func TestWarmingStripsHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	Authorize(r, true)
+	if warming, keepWarm := Warming(r); !warming || !keepWarm {
+		t.Errorf("Warming() = %v, %v; want true, true", warming, keepWarm)
+	}
+	if r.Header.Get(TokenHeader) != "" || r.Header.Get(KeepWarmHeader) != "" {
+		t.Error("The warmer headers should be removed")
+	}
+
+	forged := httptest.NewRequest("GET", "/", nil)
+	forged.Header.Set(TokenHeader, "guess")
+	forged.Header.Set(KeepWarmHeader, "true")
+	if warming, keepWarm := Warming(forged); warming || keepWarm {
+		t.Errorf("Warming() = %v, %v for a forged token; want false, false", warming, keepWarm)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package worker
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+)
+
+// Supported reports whether multi-process worker mode is available on this platform
+func Supported() bool {
+	return false
+}
+
+// Listen is not available without SO_REUSEPORT support
+func Listen(addr string, keepAlive time.Duration) (net.Listener, error) {
+	return nil, fmt.Errorf("server.workers is not supported on %s", runtime.GOOS)
+}
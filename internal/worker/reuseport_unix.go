@@ -0,0 +1,36 @@
+//go:build linux || darwin
+
+package worker
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Supported reports whether multi-process worker mode is available on this platform
+func Supported() bool {
+	return true
+}
+
+// Listen opens a TCP listener with SO_REUSEPORT so several workers can share
+// addr, probing idle client connections with SO_KEEPALIVE every keepAlive
+func Listen(addr string, keepAlive time.Duration) (net.Listener, error) {
+	lc := net.ListenConfig{
+		KeepAlive: keepAlive,
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
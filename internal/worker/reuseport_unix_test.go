@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package worker
+
+import "testing"
+
+func TestListenSharesPort(t *testing.T) {
+	first, err := Listen("127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("first Listen failed: %v", err)
+	}
+	defer first.Close()
+
+	second, err := Listen(first.Addr().String(), 0)
+	if err != nil {
+		t.Fatalf("second Listen on %s failed: %v", first.Addr(), err)
+	}
+	defer second.Close()
+}
@@ -0,0 +1,258 @@
+// Package worker implements Navigator's multi-process mode (server.workers).
+//
+// The parent process becomes a supervisor that starts N copies of itself. Each
+// worker listens on the shared port with SO_REUSEPORT so the kernel spreads
+// connections across them. Worker 0 is the primary: it owns tenant processes,
+// managed processes, hooks, and idle management. Secondary workers serve
+// static files, rewrites, and reverse proxy routes themselves and forward
+// tenant requests to the primary over a loopback address.
+package worker
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rubys/navigator/internal/config"
+)
+
+// Environment variables passed from the supervisor to its workers
+const (
+	EnvWorkerIndex = "NAVIGATOR_WORKER"       // Index of this worker (0 = primary)
+	EnvPrimaryAddr = "NAVIGATOR_PRIMARY_ADDR" // Loopback address where the primary accepts forwarded requests
+)
+
+// Index returns this process's worker index and whether it is running as a worker
+func Index() (int, bool) {
+	value := os.Getenv(EnvWorkerIndex)
+	if value == "" {
+		return 0, false
+	}
+	index, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// IsWorker reports whether this process was started by a supervisor
+func IsWorker() bool {
+	_, ok := Index()
+	return ok
+}
+
+// IsSecondary reports whether this process is a worker that does not own tenants
+func IsSecondary() bool {
+	index, ok := Index()
+	return ok && index != 0
+}
+
+// PrimaryAddr returns the loopback address of the primary worker's internal listener
+func PrimaryAddr() string {
+	return os.Getenv(EnvPrimaryAddr)
+}
+
+// AllocatePrimaryAddr picks a free loopback address for the primary's internal listener
+func AllocatePrimaryAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate primary worker address: %w", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	return addr, nil
+}
+
+// Command returns the command used to start worker index, re-executing the
+// current binary with the same arguments
+func Command(index int, primaryAddr string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate navigator executable: %w", err)
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", EnvWorkerIndex, index),
+		fmt.Sprintf("%s=%s", EnvPrimaryAddr, primaryAddr),
+	)
+	return cmd, nil
+}
+
+// Supervisor starts worker processes, restarts them if they crash, and
+// forwards signals to them
+type Supervisor struct {
+	count           int
+	command         func(index int) (*exec.Cmd, error)
+	restartDelay    time.Duration
+	shutdownTimeout time.Duration // Time workers get to exit before being killed
+
+	mu       sync.Mutex
+	workers  []*exec.Cmd
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+// NewSupervisor creates a supervisor for count workers built by command,
+// which are killed if they haven't exited shutdownTimeout after being stopped
+func NewSupervisor(count int, shutdownTimeout time.Duration, command func(index int) (*exec.Cmd, error)) *Supervisor {
+	return &Supervisor{
+		count:           count,
+		command:         command,
+		restartDelay:    config.WorkerRestartDelay,
+		shutdownTimeout: shutdownTimeout,
+		workers:         make([]*exec.Cmd, count),
+	}
+}
+
+// Start launches all workers and begins supervising them
+func (s *Supervisor) Start() error {
+	for i := 0; i < s.count; i++ {
+		cmd, err := s.startWorker(i)
+		if err != nil {
+			s.Stop(syscall.SIGTERM, s.shutdownTimeout)
+			return err
+		}
+		s.wg.Add(1)
+		go s.supervise(i, cmd)
+	}
+	slog.Info("Started workers", "count", s.count)
+	return nil
+}
+
+// startWorker starts a single worker process
+func (s *Supervisor) startWorker(index int) (*exec.Cmd, error) {
+	cmd, err := s.command(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start worker %d: %w", index, err)
+	}
+
+	s.mu.Lock()
+	s.workers[index] = cmd
+	s.mu.Unlock()
+
+	slog.Info("Worker started", "worker", index, "pid", cmd.Process.Pid)
+	return cmd, nil
+}
+
+// supervise waits for a worker to exit and restarts it unless stopping
+func (s *Supervisor) supervise(index int, cmd *exec.Cmd) {
+	defer s.wg.Done()
+
+	for {
+		err := cmd.Wait()
+
+		s.mu.Lock()
+		s.workers[index] = nil
+		s.mu.Unlock()
+
+		if s.isStopping() {
+			return
+		}
+
+		slog.Error("Worker exited unexpectedly, restarting",
+			"worker", index,
+			"error", err,
+			"delay", s.restartDelay)
+
+		for {
+			time.Sleep(s.restartDelay)
+			if s.isStopping() {
+				return
+			}
+			next, err := s.startWorker(index)
+			if err == nil {
+				cmd = next
+				break
+			}
+			slog.Error("Failed to restart worker", "worker", index, "error", err)
+		}
+	}
+}
+
+// isStopping reports whether the supervisor is shutting down
+func (s *Supervisor) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+// Signal forwards sig to every running worker
+func (s *Supervisor) Signal(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for index, cmd := range s.workers {
+		if cmd == nil || cmd.Process == nil {
+			continue
+		}
+		if err := cmd.Process.Signal(sig); err != nil {
+			slog.Warn("Failed to signal worker", "worker", index, "signal", sig, "error", err)
+		}
+	}
+}
+
+// Stop signals all workers to exit and waits up to timeout before killing them
+func (s *Supervisor) Stop(sig os.Signal, timeout time.Duration) {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	s.Signal(sig)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("Workers did not exit in time, killing", "timeout", timeout)
+		s.Signal(os.Kill)
+		<-done
+	}
+}
+
+// Run supervises workers until a shutdown signal arrives on signals and
+// they have exited. SIGHUP is forwarded to all workers so each reloads its
+// configuration. Signals arriving while the workers stop are forwarded too,
+// so a second SIGTERM or SIGINT aborts their draining as it would for a
+// single process.
+func (s *Supervisor) Run(signals <-chan os.Signal) {
+	for sig := range signals {
+		switch sig {
+		case syscall.SIGHUP:
+			slog.Info("Forwarding reload to workers")
+			s.Signal(sig)
+		case syscall.SIGTERM, syscall.SIGINT:
+			slog.Info("Stopping workers", "signal", sig, "timeout", s.shutdownTimeout)
+			stopped := make(chan struct{})
+			go func() {
+				s.Stop(sig, s.shutdownTimeout)
+				close(stopped)
+			}()
+			for {
+				select {
+				case <-stopped:
+					return
+				case sig := <-signals:
+					slog.Info("Forwarding signal to stopping workers", "signal", sig)
+					s.Signal(sig)
+				}
+			}
+		}
+	}
+}
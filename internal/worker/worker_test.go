@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestHelperWorker is not a real test. It is re-executed by the supervisor
+// tests to act as a worker process.
+func TestHelperWorker(t *testing.T) {
+	dir := os.Getenv("NAVIGATOR_TEST_WORKER_DIR")
+	if dir == "" {
+		return
+	}
+	index, _ := Index()
+	marker := filepath.Join(dir, fmt.Sprintf("started-%d", index))
+
+	// Crash on first start when asked, to exercise restarts
+	if os.Getenv("NAVIGATOR_TEST_WORKER_CRASH") != "" {
+		if _, err := os.Stat(marker); os.IsNotExist(err) {
+			_ = os.WriteFile(marker, []byte("1"), 0644)
+			os.Exit(1)
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM)
+	_ = os.WriteFile(marker+"-ready", nil, 0644)
+
+	// Drain on the first shutdown signal when asked, exiting on the second
+	draining := os.Getenv("NAVIGATOR_TEST_WORKER_DRAIN") == ""
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("reloaded-%d", index)), nil, 0644)
+			continue
+		}
+		if !draining {
+			draining = true
+			_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("draining-%d", index)), nil, 0644)
+			continue
+		}
+		os.Exit(0)
+	}
+}
+
+func helperCommand(dir string, crash bool) func(index int) (*exec.Cmd, error) {
+	return func(index int) (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperWorker$")
+		cmd.Env = append(os.Environ(),
+			"NAVIGATOR_TEST_WORKER_DIR="+dir,
+			fmt.Sprintf("%s=%d", EnvWorkerIndex, index),
+		)
+		if crash {
+			cmd.Env = append(cmd.Env, "NAVIGATOR_TEST_WORKER_CRASH=1")
+		}
+		return cmd, nil
+	}
+}
+
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", filepath.Base(path))
+}
+
+func skipWithoutSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("worker signal forwarding requires Unix signals")
+	}
+}
+
+func TestSupervisorForwardsReloadToAllWorkers(t *testing.T) {
+	skipWithoutSignals(t)
+	dir := t.TempDir()
+
+	supervisor := NewSupervisor(3, 5*time.Second, helperCommand(dir, false))
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		waitForFile(t, filepath.Join(dir, fmt.Sprintf("started-%d-ready", i)))
+	}
+
+	signals := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	go func() {
+		supervisor.Run(signals)
+		close(done)
+	}()
+
+	signals <- syscall.SIGHUP
+	for i := 0; i < 3; i++ {
+		waitForFile(t, filepath.Join(dir, fmt.Sprintf("reloaded-%d", i)))
+	}
+
+	signals <- syscall.SIGTERM
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor did not stop after SIGTERM")
+	}
+
+	for index, cmd := range supervisor.workers {
+		if cmd != nil {
+			t.Errorf("worker %d still registered after stop", index)
+		}
+	}
+}
+
+func TestSupervisorForwardsSecondShutdownSignal(t *testing.T) {
+	skipWithoutSignals(t)
+	dir := t.TempDir()
+	t.Setenv("NAVIGATOR_TEST_WORKER_DRAIN", "1")
+
+	supervisor := NewSupervisor(2, time.Minute, helperCommand(dir, false))
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		waitForFile(t, filepath.Join(dir, fmt.Sprintf("started-%d-ready", i)))
+	}
+
+	signals := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	go func() {
+		supervisor.Run(signals)
+		close(done)
+	}()
+
+	// The workers drain after the first signal; the second stops them
+	// without waiting out the shutdown timeout
+	signals <- syscall.SIGTERM
+	for i := 0; i < 2; i++ {
+		waitForFile(t, filepath.Join(dir, fmt.Sprintf("draining-%d", i)))
+	}
+	signals <- syscall.SIGTERM
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor did not forward the second SIGTERM")
+	}
+}
+
+func TestSupervisorRestartsCrashedWorker(t *testing.T) {
+	skipWithoutSignals(t)
+	dir := t.TempDir()
+
+	supervisor := NewSupervisor(1, 5*time.Second, helperCommand(dir, true))
+	supervisor.restartDelay = 10 * time.Millisecond
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer supervisor.Stop(syscall.SIGTERM, 5*time.Second)
+
+	// The first start crashes; the restarted worker becomes ready
+	waitForFile(t, filepath.Join(dir, "started-0-ready"))
+}
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		value     string
+		index     int
+		worker    bool
+		secondary bool
+	}{
+		{"", 0, false, false},
+		{"0", 0, true, false},
+		{"2", 2, true, true},
+		{"bogus", 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv(EnvWorkerIndex, tt.value)
+		index, ok := Index()
+		if index != tt.index || ok != tt.worker {
+			t.Errorf("Index() with %q = (%d, %v), want (%d, %v)", tt.value, index, ok, tt.index, tt.worker)
+		}
+		if IsSecondary() != tt.secondary {
+			t.Errorf("IsSecondary() with %q = %v, want %v", tt.value, IsSecondary(), tt.secondary)
+		}
+	}
+}